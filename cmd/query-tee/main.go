@@ -74,9 +74,11 @@ func mimirReadRoutes(cfg Config) []querytee.Route {
 	}
 
 	samplesComparator := querytee.NewSamplesComparator(querytee.SampleComparisonOptions{
-		Tolerance:         cfg.ProxyConfig.ValueComparisonTolerance,
-		UseRelativeError:  cfg.ProxyConfig.UseRelativeError,
-		SkipRecentSamples: cfg.ProxyConfig.SkipRecentSamples,
+		Tolerance:          cfg.ProxyConfig.ValueComparisonTolerance,
+		UseRelativeError:   cfg.ProxyConfig.UseRelativeError,
+		SkipRecentSamples:  cfg.ProxyConfig.SkipRecentSamples,
+		SkipFunctions:      cfg.ProxyConfig.CompareSkipFunctions,
+		FunctionTolerances: cfg.ProxyConfig.CompareFunctionTolerances,
 	})
 	return []querytee.Route{
 		{Path: prefix + "/api/v1/query", RouteName: "api_v1_query", Methods: []string{"GET", "POST"}, ResponseComparator: samplesComparator},
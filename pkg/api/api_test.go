@@ -34,7 +34,7 @@ func TestNewApiWithoutSourceIPExtractor(t *testing.T) {
 	server, err := server.New(serverCfg)
 	require.NoError(t, err)
 
-	api, err := New(cfg, serverCfg, server, &FakeLogger{})
+	api, err := New(cfg, serverCfg, server, &FakeLogger{}, nil)
 	require.NoError(t, err)
 	require.Nil(t, api.sourceIPs)
 }
@@ -48,7 +48,7 @@ func TestNewApiWithSourceIPExtractor(t *testing.T) {
 	server, err := server.New(serverCfg)
 	require.NoError(t, err)
 
-	api, err := New(cfg, serverCfg, server, &FakeLogger{})
+	api, err := New(cfg, serverCfg, server, &FakeLogger{}, nil)
 	require.NoError(t, err)
 	require.NotNil(t, api.sourceIPs)
 }
@@ -65,7 +65,7 @@ func TestNewApiWithInvalidSourceIPExtractor(t *testing.T) {
 		MetricsNamespace:   "with_invalid_source_ip_extractor",
 	}
 
-	api, err := New(cfg, serverCfg, &s, &FakeLogger{})
+	api, err := New(cfg, serverCfg, &s, &FakeLogger{}, nil)
 	require.Error(t, err)
 	require.Nil(t, api)
 }
@@ -78,7 +78,7 @@ func TestApiGzip(t *testing.T) {
 	go func() { _ = srv.Run() }()
 	t.Cleanup(srv.Stop)
 
-	api, err := New(cfg, serverCfg, srv, log.NewNopLogger())
+	api, err := New(cfg, serverCfg, srv, log.NewNopLogger(), nil)
 	require.NoError(t, err)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/util/gziphandler"
+)
+
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+)
+
+// queryResponseCompression compresses the responses of the query API with the encoding negotiated with the client
+// via the Accept-Encoding header: zstd, if enabled and preferred by the client, or gzip otherwise.
+type queryResponseCompression struct {
+	gzip        func(http.Handler) http.Handler
+	zstdEnabled bool
+	zstdPool    sync.Pool
+
+	compressedBytes *prometheus.CounterVec
+	savedBytes      *prometheus.CounterVec
+}
+
+func newQueryResponseCompression(cfg Config, reg prometheus.Registerer) (*queryResponseCompression, error) {
+	gzipLevel := cfg.QueryResponseGzipLevel
+	if gzipLevel == 0 {
+		gzipLevel = -1 // The default gzip compression level.
+	}
+	gzipWrapper, err := gziphandler.GzipHandlerWithOpts(gziphandler.CompressionLevel(gzipLevel))
+	if err != nil {
+		return nil, err
+	}
+
+	return &queryResponseCompression{
+		gzip:        gzipWrapper,
+		zstdEnabled: cfg.QueryResponseZstdEnabled,
+		zstdPool: sync.Pool{
+			New: func() interface{} {
+				// NewWriter only returns an error on invalid options.
+				enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+				return enc
+			},
+		},
+		compressedBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_response_compressed_bytes_total",
+			Help: "Total number of bytes of the compressed query API responses, after compression.",
+		}, []string{"encoding"}),
+		savedBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_response_compression_saved_bytes_total",
+			Help: "Total number of bytes saved by the compression of the query API responses.",
+		}, []string{"encoding"}),
+	}, nil
+}
+
+// Wrap returns a handler compressing the responses of the next handler.
+func (c *queryResponseCompression) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed := &countingResponseWriter{ResponseWriter: w}
+		uncompressed := &countingResponseWriter{}
+
+		encoding := encodingGzip
+		if c.zstdEnabled && prefersZstd(r.Header.Get("Accept-Encoding")) {
+			encoding = encodingZstd
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			zw := &zstdResponseWriter{ResponseWriter: compressed, pool: &c.zstdPool, minSize: gziphandler.DefaultMinSize}
+			uncompressed.ResponseWriter = zw
+			next.ServeHTTP(uncompressed, r)
+			if err := zw.Close(); err != nil {
+				return
+			}
+		} else {
+			c.gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uncompressed.ResponseWriter = w
+				next.ServeHTTP(uncompressed, r)
+			})).ServeHTTP(compressed, r)
+		}
+
+		// The responses already encoded by the next handler, or not compressed because too small, are not tracked.
+		if w.Header().Get("Content-Encoding") == encoding {
+			c.compressedBytes.WithLabelValues(encoding).Add(float64(compressed.n))
+			if saved := uncompressed.n - compressed.n; saved > 0 {
+				c.savedBytes.WithLabelValues(encoding).Add(float64(saved))
+			}
+		}
+	})
+}
+
+// prefersZstd returns whether the Accept-Encoding header accepts zstd with a qvalue not lower than gzip.
+func prefersZstd(acceptEncoding string) bool {
+	var zstdQ, gzipQ float64
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case encodingZstd:
+			zstdQ = q
+		case encodingGzip:
+			gzipQ = q
+		}
+	}
+	return zstdQ > 0 && zstdQ >= gzipQ
+}
+
+// countingResponseWriter counts the bytes written to the wrapped http.ResponseWriter.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n += n
+	return n, err
+}
+
+func (w *countingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// zstdResponseWriter compresses the response with zstd. The responses smaller than minSize, or already encoded,
+// are written without compression.
+type zstdResponseWriter struct {
+	http.ResponseWriter
+	pool    *sync.Pool
+	minSize int
+
+	enc   *zstd.Encoder
+	code  int    // The status code, written once it's known whether the response is compressed.
+	buf   []byte // The beginning of the response, until it's known whether the response is compressed.
+	plain bool   // Whether the response is written without compression.
+}
+
+func (w *zstdResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *zstdResponseWriter) Write(b []byte) (int, error) {
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	if w.plain {
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if w.Header().Get("Content-Encoding") != "" {
+		return len(b), w.startPlain()
+	}
+	if len(w.buf) < w.minSize {
+		return len(b), nil
+	}
+	return len(b), w.startZstd()
+}
+
+func (w *zstdResponseWriter) startZstd() error {
+	w.Header().Set("Content-Encoding", encodingZstd)
+	w.Header().Del("Content-Length")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	w.writeHeader()
+
+	w.enc = w.pool.Get().(*zstd.Encoder)
+	w.enc.Reset(w.ResponseWriter)
+	n, err := w.enc.Write(w.buf)
+	if err == nil && n < len(w.buf) {
+		err = io.ErrShortWrite
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *zstdResponseWriter) startPlain() error {
+	w.plain = true
+	w.writeHeader()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	n, err := w.ResponseWriter.Write(w.buf)
+	if err == nil && n < len(w.buf) {
+		err = io.ErrShortWrite
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *zstdResponseWriter) writeHeader() {
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+		w.code = 0
+	}
+}
+
+// Close writes the remaining of the response and releases the encoder.
+func (w *zstdResponseWriter) Close() error {
+	if w.enc == nil {
+		if w.plain {
+			return nil
+		}
+		if err := w.startPlain(); err != nil {
+			return fmt.Errorf("zstd: write to regular responseWriter at close gets error: %w", err)
+		}
+		return nil
+	}
+
+	err := w.enc.Close()
+	w.pool.Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// Flush flushes the compressed data written so far, once it's known whether the response is compressed.
+func (w *zstdResponseWriter) Flush() {
+	if w.enc == nil && !w.plain {
+		return
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryResponseCompression(t *testing.T) {
+	body := strings.Repeat(`{"metric":{"__name__":"up"},"value":[1,"1"]}`, 100)
+
+	tests := map[string]struct {
+		cfg                Config
+		acceptEncoding     string
+		respBody           string
+		respEncoding       string
+		expectedEncoding   string
+		expectedCompressed bool
+	}{
+		"gzip": {
+			acceptEncoding:     "gzip",
+			respBody:           body,
+			expectedEncoding:   "gzip",
+			expectedCompressed: true,
+		},
+		"gzip at a configured level": {
+			cfg:                Config{QueryResponseGzipLevel: gzip.BestSpeed},
+			acceptEncoding:     "gzip",
+			respBody:           body,
+			expectedEncoding:   "gzip",
+			expectedCompressed: true,
+		},
+		"zstd accepted but disabled": {
+			acceptEncoding:     "zstd, gzip",
+			respBody:           body,
+			expectedEncoding:   "gzip",
+			expectedCompressed: true,
+		},
+		"zstd": {
+			cfg:                Config{QueryResponseZstdEnabled: true},
+			acceptEncoding:     "gzip, zstd",
+			respBody:           body,
+			expectedEncoding:   "zstd",
+			expectedCompressed: true,
+		},
+		"zstd with a lower qvalue than gzip": {
+			cfg:                Config{QueryResponseZstdEnabled: true},
+			acceptEncoding:     "gzip;q=1, zstd;q=0.5",
+			respBody:           body,
+			expectedEncoding:   "gzip",
+			expectedCompressed: true,
+		},
+		"zstd with a small response": {
+			cfg:            Config{QueryResponseZstdEnabled: true},
+			acceptEncoding: "zstd",
+			respBody:       "ok",
+		},
+		"zstd with an already encoded response": {
+			cfg:              Config{QueryResponseZstdEnabled: true},
+			acceptEncoding:   "zstd",
+			respBody:         body,
+			respEncoding:     "snappy",
+			expectedEncoding: "snappy",
+		},
+		"no accepted encoding": {
+			cfg:      Config{QueryResponseZstdEnabled: true},
+			respBody: body,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			compression, err := newQueryResponseCompression(testData.cfg, reg)
+			require.NoError(t, err)
+
+			handler := compression.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if testData.respEncoding != "" {
+					w.Header().Set("Content-Encoding", testData.respEncoding)
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(testData.respBody))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+			if testData.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", testData.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, testData.expectedEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, testData.respBody, string(decodeBody(t, rec.Header().Get("Content-Encoding"), rec.Body.Bytes())))
+
+			if !testData.expectedCompressed {
+				assert.Equal(t, 0, testutil.CollectAndCount(compression.compressedBytes))
+				assert.Equal(t, 0, testutil.CollectAndCount(compression.savedBytes))
+				return
+			}
+			assert.Equal(t, float64(rec.Body.Len()), testutil.ToFloat64(compression.compressedBytes.WithLabelValues(testData.expectedEncoding)))
+			assert.Equal(t, float64(len(testData.respBody)-rec.Body.Len()), testutil.ToFloat64(compression.savedBytes.WithLabelValues(testData.expectedEncoding)))
+		})
+	}
+}
+
+func TestQueryResponseCompression_InvalidGzipLevel(t *testing.T) {
+	cfg := Config{QueryResponseGzipLevel: gzip.BestCompression + 1}
+	assert.Equal(t, errInvalidQueryResponseGzipLevel, cfg.Validate())
+
+	_, err := newQueryResponseCompression(cfg, nil)
+	assert.Error(t, err)
+}
+
+func TestPrefersZstd(t *testing.T) {
+	for acceptEncoding, expected := range map[string]bool{
+		"":                         false,
+		"*":                        false,
+		"gzip":                     false,
+		"zstd":                     true,
+		"gzip, zstd":               true,
+		"gzip;q=0.5, zstd;q=0.8":   true,
+		"gzip;q=1, zstd;q=0.5":     false,
+		"zstd;q=0":                 false,
+		"ZSTD":                     true,
+		"gzip, deflate, br, zstd ": true,
+	} {
+		assert.Equal(t, expected, prefersZstd(acceptEncoding), acceptEncoding)
+	}
+}
+
+func decodeBody(t *testing.T, encoding string, body []byte) []byte {
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		return body
+	}
+
+	decoded, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return decoded
+}
@@ -10,8 +10,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
 )
 
 type Type string
@@ -33,6 +36,9 @@ const (
 type apiError struct {
 	Type    Type
 	Message string
+
+	// If positive, the suggested time after which the request can be retried.
+	RetryAfter time.Duration
 }
 
 func (e *apiError) Error() string {
@@ -82,12 +88,17 @@ func HTTPResponseFromError(err error) (*httpgrpc.HTTPResponse, bool) {
 		return nil, false
 	}
 
+	headers := []*httpgrpc.Header{
+		{Key: "Content-Type", Values: []string{"application/json"}},
+	}
+	if apiErr.RetryAfter > 0 {
+		headers = append(headers, httpgrpcutil.RetryAfterHeader(apiErr.RetryAfter))
+	}
+
 	return &httpgrpc.HTTPResponse{
-		Code: int32(apiErr.statusCode()),
-		Body: body,
-		Headers: []*httpgrpc.Header{
-			{Key: "Content-Type", Values: []string{"application/json"}},
-		},
+		Code:    int32(apiErr.statusCode()),
+		Body:    body,
+		Headers: headers,
 	}, true
 }
 
@@ -104,9 +115,24 @@ func Newf(typ Type, tmpl string, args ...interface{}) error {
 	return New(typ, fmt.Sprintf(tmpl, args...))
 }
 
+// NewWithRetryAfter creates a new apiError whose HTTP response suggests to retry the request after retryAfter.
+func NewWithRetryAfter(typ Type, msg string, retryAfter time.Duration) error {
+	return &apiError{
+		Message:    msg,
+		Type:       typ,
+		RetryAfter: retryAfter,
+	}
+}
+
 // IsAPIError returns true if the error provided is an apiError.
 // This implies that HTTPResponseFromError will succeed.
 func IsAPIError(err error) bool {
 	apiErr := &apiError{}
 	return errors.As(err, &apiErr)
 }
+
+// IsType returns true if the error provided is an apiError of the given type.
+func IsType(err error, typ Type) bool {
+	apiErr := &apiError{}
+	return errors.As(err, &apiErr) && apiErr.Type == typ
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cache
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+var errUnsupportedPreviousCompression = errors.New("unsupported previous compression")
+
+// CompressionMigrationConfig configures the migration of the cached data from a previous compression.
+type CompressionMigrationConfig struct {
+	DualReadEnabled     bool   `yaml:"dual_read_enabled" category:"experimental"`
+	PreviousCompression string `yaml:"previous_compression" category:"experimental"`
+}
+
+// RegisterFlagsWithPrefix registers flags with provided prefix.
+func (cfg *CompressionMigrationConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.BoolVar(&cfg.DualReadEnabled, prefix+"dual-read-enabled", false, "Read the entries missing in the cache from the entries cached with the previous compression, while storing the entries with the current compression only. Enable it while changing the compression, until the entries cached with the previous compression expire, to not start from an empty cache.")
+	f.StringVar(&cfg.PreviousCompression, prefix+"previous-compression", "", fmt.Sprintf("The compression of the entries read when dual read is enabled. Supported values are: %s, or empty for no compression.", strings.Join(supportedCompressions, ", ")))
+}
+
+func (cfg *CompressionMigrationConfig) Validate() error {
+	if cfg.PreviousCompression != "" && !util.StringsContain(supportedCompressions, cfg.PreviousCompression) {
+		return errUnsupportedPreviousCompression
+	}
+
+	return nil
+}
+
+// DualRead cache reads the keys missing in the current cache from the previous cache, and stores to the current
+// cache only. It allows changing the format of the cached data, like its compression, without starting from an
+// empty cache: the data cached with the previous format keeps being read until it expires.
+type DualRead struct {
+	current  Cache
+	previous Cache
+
+	previousRequests prometheus.Counter
+	previousHits     prometheus.Counter
+}
+
+// NewDualRead creates a new DualRead cache. The current and previous caches must use different keys, for example
+// with versioned keys.
+func NewDualRead(current, previous Cache, reg prometheus.Registerer) *DualRead {
+	return &DualRead{
+		current:  current,
+		previous: previous,
+		previousRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_cache_dual_read_previous_requests_total",
+			Help:        "Total number of requests to the cache with the previous format, for the keys missing in the cache with the current format.",
+			ConstLabels: map[string]string{"name": current.Name()},
+		}),
+		previousHits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_cache_dual_read_previous_hits_total",
+			Help:        "Total number of requests to the cache with the previous format that were a hit.",
+			ConstLabels: map[string]string{"name": current.Name()},
+		}),
+	}
+}
+
+func (c *DualRead) Store(ctx context.Context, data map[string][]byte, ttl time.Duration) {
+	c.current.Store(ctx, data, ttl)
+}
+
+func (c *DualRead) Fetch(ctx context.Context, keys []string) map[string][]byte {
+	res := c.current.Fetch(ctx, keys)
+	if len(res) == len(keys) {
+		return res
+	}
+
+	misses := make([]string, 0, len(keys)-len(res))
+	for _, k := range keys {
+		if _, ok := res[k]; !ok {
+			misses = append(misses, k)
+		}
+	}
+
+	previousRes := c.previous.Fetch(ctx, misses)
+	c.previousRequests.Add(float64(len(misses)))
+	c.previousHits.Add(float64(len(previousRes)))
+
+	for k, v := range previousRes {
+		res[k] = v
+	}
+	return res
+}
+
+func (c *DualRead) Name() string {
+	return c.current.Name()
+}
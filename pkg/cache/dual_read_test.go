@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualRead(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMockCache()
+
+	// Cache some data with the previous format, before the migration.
+	previous := NewCompression(CompressionConfig{}, NewVersionedWithFormat(backend, 1, "", false), log.NewNopLogger())
+	previous.Store(ctx, map[string][]byte{"previous": []byte("previous"), "both": []byte("both-previous")}, time.Minute)
+
+	reg := prometheus.NewPedanticRegistry()
+	current := NewCompression(CompressionConfig{Compression: CompressionSnappy}, NewVersionedWithFormat(backend, 1, CompressionSnappy, false), log.NewNopLogger())
+	c := NewDualRead(current, previous, reg)
+	c.Store(ctx, map[string][]byte{"current": []byte("current"), "both": []byte("both-current")}, time.Minute)
+
+	actual := c.Fetch(ctx, []string{"previous", "current", "both", "miss"})
+	assert.Equal(t, map[string][]byte{
+		"previous": []byte("previous"),
+		"current":  []byte("current"),
+		"both":     []byte("both-current"),
+	}, actual)
+
+	// The data is stored with the current format only.
+	assert.Equal(t, map[string][]byte{"both": []byte("both-previous")}, previous.Fetch(ctx, []string{"current", "both"}))
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_cache_dual_read_previous_hits_total Total number of requests to the cache with the previous format that were a hit.
+		# TYPE cortex_cache_dual_read_previous_hits_total counter
+		cortex_cache_dual_read_previous_hits_total{name="mock"} 1
+
+		# HELP cortex_cache_dual_read_previous_requests_total Total number of requests to the cache with the previous format, for the keys missing in the cache with the current format.
+		# TYPE cortex_cache_dual_read_previous_requests_total counter
+		cortex_cache_dual_read_previous_requests_total{name="mock"} 2
+	`)))
+}
@@ -0,0 +1,348 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cache
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/thanos/pkg/cacheutil"
+	"github.com/thanos-io/thanos/pkg/extprom"
+	"github.com/thanos-io/thanos/pkg/gate"
+)
+
+const (
+	redisOpGetMulti = "getmulti"
+	redisOpSet      = "set"
+
+	redisReasonMaxItemSize     = "max-item-size"
+	redisReasonAsyncBufferFull = "async-buffer-full"
+	redisReasonTimeout         = "timeout"
+	redisReasonServerError     = "server-error"
+	redisReasonNetworkError    = "network-error"
+	redisReasonOther           = "other"
+
+	// The max number of enqueued items written to redis in a single pipeline.
+	redisMaxSetBatchSize = 100
+)
+
+var (
+	errRedisAsyncBufferFull = errors.New("the async buffer is full")
+)
+
+type redisSetOp struct {
+	key   string
+	value []byte
+	ttl   time.Duration
+}
+
+// redisClient is a cacheutil.RemoteCacheClient backed by a Redis server or by a Redis Cluster.
+// Both the reads and the asynchronous writes are pipelined: a batch of keys is sent in a single
+// round-trip to each server.
+type redisClient struct {
+	logger log.Logger
+	client redis.UniversalClient
+
+	maxItemSize          int
+	maxGetMultiBatchSize int
+	getMultiGate         gate.Gate
+
+	asyncQueue chan redisSetOp
+	stop       chan struct{}
+	workers    sync.WaitGroup
+
+	// Metrics.
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	skipped    *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// NewRedisClient makes a new redis client, connecting to a Redis Cluster if enabled in the config. The connections
+// are established lazily.
+func NewRedisClient(logger log.Logger, name string, cfg RedisConfig, reg prometheus.Registerer) (cacheutil.RemoteCacheClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:        cfg.GetAddresses(),
+		DB:           cfg.DB,
+		Username:     cfg.Username,
+		Password:     cfg.Password.String(),
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		PoolSize:     cfg.ConnectionPoolSize,
+		MinIdleConns: cfg.MinIdleConnections,
+	}
+	if cfg.TLSEnabled {
+		tlsConfig, err := cfg.TLS.GetTLSConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the redis TLS config")
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	var client redis.UniversalClient
+	if cfg.ClusterEnabled {
+		client = redis.NewClusterClient(opts.Cluster())
+	} else {
+		client = redis.NewClient(opts.Simple())
+	}
+
+	if reg != nil {
+		reg = prometheus.WrapRegistererWith(prometheus.Labels{"name": name}, reg)
+	}
+	return newRedisClientWithBackend(logger, name, client, cfg, reg), nil
+}
+
+func newRedisClientWithBackend(logger log.Logger, name string, client redis.UniversalClient, cfg RedisConfig, reg prometheus.Registerer) *redisClient {
+	c := &redisClient{
+		logger:               log.With(logger, "name", name),
+		client:               client,
+		maxItemSize:          cfg.MaxItemSize,
+		maxGetMultiBatchSize: cfg.MaxGetMultiBatchSize,
+		getMultiGate:         gate.NewNoop(),
+		asyncQueue:           make(chan redisSetOp, cfg.MaxAsyncBufferSize),
+		stop:                 make(chan struct{}),
+	}
+	if cfg.MaxGetMultiConcurrency > 0 {
+		c.getMultiGate = gate.New(extprom.WrapRegistererWithPrefix("thanos_redis_getmulti_", reg), cfg.MaxGetMultiConcurrency)
+	}
+
+	c.operations = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_operations_total",
+		Help: "Total number of operations against redis.",
+	}, []string{"operation"})
+	c.operations.WithLabelValues(redisOpGetMulti)
+	c.operations.WithLabelValues(redisOpSet)
+
+	c.failures = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_operation_failures_total",
+		Help: "Total number of operations against redis that failed.",
+	}, []string{"operation", "reason"})
+	for _, op := range []string{redisOpGetMulti, redisOpSet} {
+		for _, reason := range []string{redisReasonTimeout, redisReasonServerError, redisReasonNetworkError, redisReasonOther} {
+			c.failures.WithLabelValues(op, reason)
+		}
+	}
+
+	c.skipped = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_operation_skipped_total",
+		Help: "Total number of operations against redis that have been skipped.",
+	}, []string{"operation", "reason"})
+	c.skipped.WithLabelValues(redisOpGetMulti, redisReasonMaxItemSize)
+	c.skipped.WithLabelValues(redisOpSet, redisReasonMaxItemSize)
+	c.skipped.WithLabelValues(redisOpSet, redisReasonAsyncBufferFull)
+
+	c.duration = promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_redis_operation_duration_seconds",
+		Help:    "Duration of operations against redis.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.2, 0.5, 1, 3, 6, 10},
+	}, []string{"operation"})
+	c.duration.WithLabelValues(redisOpGetMulti)
+	c.duration.WithLabelValues(redisOpSet)
+
+	// Start the asynchronous workers.
+	concurrency := cfg.MaxAsyncConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	c.workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go c.asyncQueueProcessLoop()
+	}
+
+	return c
+}
+
+// GetMulti fetches the keys from redis, in pipelined batches of at most maxGetMultiBatchSize keys, and returns
+// the items found. The keys that failed to be fetched are reported as missing.
+func (c *redisClient) GetMulti(ctx context.Context, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	batchSize := c.maxGetMultiBatchSize
+	if batchSize <= 0 {
+		batchSize = len(keys)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		hits = make(map[string][]byte, len(keys))
+	)
+
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := c.getMultiGate.Start(ctx); err != nil {
+			level.Warn(c.logger).Log("msg", "failed to wait for turn, cannot read from redis", "err", err)
+			break
+		}
+
+		wg.Add(1)
+		go func(batch []string) {
+			defer wg.Done()
+			defer c.getMultiGate.Done()
+
+			items := c.getMultiBatch(ctx, batch)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			for key, value := range items {
+				hits[key] = value
+			}
+		}(keys[start:end])
+	}
+
+	wg.Wait()
+	return hits
+}
+
+func (c *redisClient) getMultiBatch(ctx context.Context, keys []string) map[string][]byte {
+	start := time.Now()
+	c.operations.WithLabelValues(redisOpGetMulti).Inc()
+
+	// The pipeline error is the error of the first failed command, which is redis.Nil for a missing key:
+	// the errors are checked on each command instead.
+	cmds := make([]*redis.StringCmd, 0, len(keys))
+	_, _ = c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			cmds = append(cmds, pipe.Get(ctx, key))
+		}
+		return nil
+	})
+
+	var firstErr error
+	items := make(map[string][]byte, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Bytes()
+		if err != nil {
+			// The failed commands are reported as a miss.
+			if !errors.Is(err, redis.Nil) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if c.maxItemSize > 0 && len(value) > c.maxItemSize {
+			c.skipped.WithLabelValues(redisOpGetMulti, redisReasonMaxItemSize).Inc()
+			continue
+		}
+		items[keys[i]] = value
+	}
+
+	if firstErr != nil {
+		level.Debug(c.logger).Log("msg", "failed to get items from redis", "numKeys", len(keys), "firstKey", keys[0], "err", firstErr)
+		c.trackError(redisOpGetMulti, firstErr)
+	}
+
+	c.duration.WithLabelValues(redisOpGetMulti).Observe(time.Since(start).Seconds())
+	return items
+}
+
+// SetAsync enqueues the item to be stored in redis, and returns immediately.
+func (c *redisClient) SetAsync(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	// Skip hitting redis at all if the item is bigger than the max allowed size.
+	if c.maxItemSize > 0 && len(value) > c.maxItemSize {
+		c.skipped.WithLabelValues(redisOpSet, redisReasonMaxItemSize).Inc()
+		return nil
+	}
+
+	select {
+	case c.asyncQueue <- redisSetOp{key: key, value: value, ttl: ttl}:
+		return nil
+	default:
+		c.skipped.WithLabelValues(redisOpSet, redisReasonAsyncBufferFull).Inc()
+		return errRedisAsyncBufferFull
+	}
+}
+
+func (c *redisClient) asyncQueueProcessLoop() {
+	defer c.workers.Done()
+
+	batch := make([]redisSetOp, 0, redisMaxSetBatchSize)
+	for {
+		select {
+		case op := <-c.asyncQueue:
+			// Pipeline the items already enqueued, up to the max batch size.
+			batch = append(batch[:0], op)
+		drain:
+			for len(batch) < redisMaxSetBatchSize {
+				select {
+				case op := <-c.asyncQueue:
+					batch = append(batch, op)
+				default:
+					break drain
+				}
+			}
+			c.setBatch(batch)
+
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *redisClient) setBatch(batch []redisSetOp) {
+	start := time.Now()
+	c.operations.WithLabelValues(redisOpSet).Add(float64(len(batch)))
+
+	ctx := context.Background()
+	cmds, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, op := range batch {
+			pipe.Set(ctx, op.key, op.value, op.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to store items to redis", "numKeys", len(batch), "firstKey", batch[0].key, "err", err)
+		for _, cmd := range cmds {
+			if cmd.Err() != nil {
+				c.trackError(redisOpSet, cmd.Err())
+			}
+		}
+	}
+
+	c.duration.WithLabelValues(redisOpSet).Observe(time.Since(start).Seconds())
+}
+
+// Stop the asynchronous workers and close the connections. The enqueued items not stored yet are discarded.
+func (c *redisClient) Stop() {
+	close(c.stop)
+
+	// Wait until all workers have terminated.
+	c.workers.Wait()
+
+	if err := c.client.Close(); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to close the redis client", "err", err)
+	}
+}
+
+func (c *redisClient) trackError(op string, err error) {
+	var netErr net.Error
+	var redisErr redis.Error
+	switch {
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			c.failures.WithLabelValues(op, redisReasonTimeout).Inc()
+		} else {
+			c.failures.WithLabelValues(op, redisReasonNetworkError).Inc()
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		c.failures.WithLabelValues(op, redisReasonTimeout).Inc()
+	case errors.As(err, &redisErr):
+		c.failures.WithLabelValues(op, redisReasonServerError).Inc()
+	default:
+		c.failures.WithLabelValues(op, redisReasonOther).Inc()
+	}
+}
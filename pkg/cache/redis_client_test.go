@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisClient_SetAsyncAndGetMulti(t *testing.T) {
+	server := newFakeRedisServer(t)
+
+	client, err := NewRedisClient(log.NewNopLogger(), "test", RedisConfig{
+		Addresses:            server.addr(),
+		Timeout:              time.Second,
+		ConnectionPoolSize:   10,
+		MaxAsyncConcurrency:  2,
+		MaxAsyncBufferSize:   100,
+		MaxGetMultiBatchSize: 2,
+		MaxItemSize:          10,
+	}, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	t.Cleanup(client.Stop)
+
+	ctx := context.Background()
+	require.NoError(t, client.SetAsync(ctx, "key-1", []byte("value-1"), time.Hour))
+	require.NoError(t, client.SetAsync(ctx, "key-2", []byte("value-2"), time.Hour))
+	require.NoError(t, client.SetAsync(ctx, "key-3", []byte("value-3"), 0))
+
+	// Items bigger than the max item size are not stored.
+	require.NoError(t, client.SetAsync(ctx, "key-4", []byte("a too big value"), time.Hour))
+
+	expected := map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+		"key-3": []byte("value-3"),
+	}
+	require.Eventually(t, func() bool {
+		return len(client.GetMulti(ctx, []string{"key-1", "key-2", "key-3", "key-4", "missing"})) == len(expected)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, client.GetMulti(ctx, []string{"key-1", "key-2", "key-3", "key-4", "missing"}))
+
+	assert.Equal(t, time.Hour, server.ttl("key-1"))
+	assert.Equal(t, time.Duration(0), server.ttl("key-3"))
+	assert.False(t, server.has("key-4"))
+
+	rc := client.(*redisClient)
+	assert.Equal(t, float64(1), promtest.ToFloat64(rc.skipped.WithLabelValues(redisOpSet, redisReasonMaxItemSize)))
+	assert.Equal(t, float64(3), promtest.ToFloat64(rc.operations.WithLabelValues(redisOpSet)))
+	assert.Equal(t, float64(0), promtest.ToFloat64(rc.failures.WithLabelValues(redisOpGetMulti, redisReasonOther)))
+}
+
+func TestRedisClient_GetMultiShouldReportMissesOnFailure(t *testing.T) {
+	server := newFakeRedisServer(t)
+
+	client, err := NewRedisClient(log.NewNopLogger(), "test", RedisConfig{
+		Addresses:           server.addr(),
+		Timeout:             time.Second,
+		MaxAsyncConcurrency: 1,
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(client.Stop)
+
+	server.setErr("ERR the server is failing")
+	assert.Empty(t, client.GetMulti(context.Background(), []string{"key-1", "key-2"}))
+
+	rc := client.(*redisClient)
+	assert.Equal(t, float64(1), promtest.ToFloat64(rc.failures.WithLabelValues(redisOpGetMulti, redisReasonServerError)))
+}
+
+func TestRedisCache_StoreAndFetch(t *testing.T) {
+	server := newFakeRedisServer(t)
+
+	reg := prometheus.NewPedanticRegistry()
+	c, err := CreateClient("test", BackendConfig{
+		Backend: BackendRedis,
+		Redis: RedisConfig{
+			Addresses:           server.addr(),
+			Timeout:             time.Second,
+			MaxAsyncConcurrency: 1,
+			MaxAsyncBufferSize:  10,
+		},
+	}, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c.Store(ctx, map[string][]byte{"key": []byte("value")}, time.Minute)
+	require.Eventually(t, func() bool {
+		return len(c.Fetch(ctx, []string{"key"})) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	redisCache := c.(*RedisCache)
+	assert.Equal(t, float64(1), promtest.ToFloat64(redisCache.hits))
+}
+
+// fakeRedisServer is a minimal server speaking the redis protocol, supporting the GET and SET commands only.
+type fakeRedisServer struct {
+	listener net.Listener
+
+	mtx    sync.Mutex
+	items  map[string][]byte
+	ttls   map[string]time.Duration
+	errMsg string
+}
+
+func newFakeRedisServer(t *testing.T) *fakeRedisServer {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	s := &fakeRedisServer{
+		listener: listener,
+		items:    map[string][]byte{},
+		ttls:     map[string]time.Duration{},
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeRedisServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedisServer) has(key string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.items[key]
+	return ok
+}
+
+func (s *fakeRedisServer) ttl(key string) time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.ttls[key]
+}
+
+func (s *fakeRedisServer) setErr(msg string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.errMsg = msg
+}
+
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRedisCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.handle(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedisServer) handle(args []string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.errMsg != "" {
+		return "-" + s.errMsg + "\r\n"
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "get":
+		value, ok := s.items[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+
+	case "set":
+		s.items[args[1]] = []byte(args[2])
+		s.ttls[args[1]] = 0
+		if len(args) == 5 {
+			n, _ := strconv.Atoi(args[4])
+			switch strings.ToLower(args[3]) {
+			case "ex":
+				s.ttls[args[1]] = time.Duration(n) * time.Second
+			case "px":
+				s.ttls[args[1]] = time.Duration(n) * time.Millisecond
+			}
+		}
+		return "+OK\r\n"
+
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// readRedisCommand reads a command, sent as an array of bulk strings.
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command: %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cache
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/grafana/dskit/crypto/tls"
+	"github.com/grafana/dskit/flagext"
+)
+
+var (
+	ErrNoRedisAddresses = errors.New("no redis addresses configured")
+)
+
+type RedisConfig struct {
+	Addresses              string           `yaml:"addresses" category:"experimental"`
+	ClusterEnabled         bool             `yaml:"cluster_enabled" category:"experimental"`
+	Username               string           `yaml:"username" category:"experimental"`
+	Password               flagext.Secret   `yaml:"password" category:"experimental"`
+	DB                     int              `yaml:"db" category:"experimental"`
+	Timeout                time.Duration    `yaml:"timeout" category:"experimental"`
+	ConnectionPoolSize     int              `yaml:"connection_pool_size" category:"experimental"`
+	MinIdleConnections     int              `yaml:"min_idle_connections" category:"experimental"`
+	MaxAsyncConcurrency    int              `yaml:"max_async_concurrency" category:"experimental"`
+	MaxAsyncBufferSize     int              `yaml:"max_async_buffer_size" category:"experimental"`
+	MaxGetMultiConcurrency int              `yaml:"max_get_multi_concurrency" category:"experimental"`
+	MaxGetMultiBatchSize   int              `yaml:"max_get_multi_batch_size" category:"experimental"`
+	MaxItemSize            int              `yaml:"max_item_size" category:"experimental"`
+	TLSEnabled             bool             `yaml:"tls_enabled" category:"experimental"`
+	TLS                    tls.ClientConfig `yaml:",inline"`
+}
+
+func (cfg *RedisConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.StringVar(&cfg.Addresses, prefix+"addresses", "", "Comma-separated list of redis addresses, in the host:port format. When the cluster mode is enabled, the addresses are used to discover the Redis Cluster nodes.")
+	f.BoolVar(&cfg.ClusterEnabled, prefix+"cluster-enabled", false, "Connect to a Redis Cluster. The keys are sharded across the cluster nodes, following the cluster redirections.")
+	f.StringVar(&cfg.Username, prefix+"username", "", "The username to authenticate with, when using the Redis ACL system.")
+	f.Var(&cfg.Password, prefix+"password", "The password to authenticate with.")
+	f.IntVar(&cfg.DB, prefix+"db", 0, "The database to select after connecting to the server. Not supported in the cluster mode.")
+	f.DurationVar(&cfg.Timeout, prefix+"timeout", 200*time.Millisecond, "The socket read/write timeout.")
+	f.IntVar(&cfg.ConnectionPoolSize, prefix+"connection-pool-size", 100, "The maximum number of connections in the pool, per address.")
+	f.IntVar(&cfg.MinIdleConnections, prefix+"min-idle-connections", 10, "The minimum number of idle connections that will be maintained per address.")
+	f.IntVar(&cfg.MaxAsyncConcurrency, prefix+"max-async-concurrency", 50, "The maximum number of concurrent asynchronous operations can occur.")
+	f.IntVar(&cfg.MaxAsyncBufferSize, prefix+"max-async-buffer-size", 25000, "The maximum number of enqueued asynchronous operations allowed.")
+	f.IntVar(&cfg.MaxGetMultiConcurrency, prefix+"max-get-multi-concurrency", 100, "The maximum number of concurrent pipelines running get operations. If set to 0, concurrency is unlimited.")
+	f.IntVar(&cfg.MaxGetMultiBatchSize, prefix+"max-get-multi-batch-size", 100, "The maximum number of keys a single pipeline of get operations should run. If more keys are specified, internally keys are split into multiple batches and fetched concurrently, honoring the max concurrency. If set to 0, the max batch size is unlimited.")
+	f.IntVar(&cfg.MaxItemSize, prefix+"max-item-size", 16*1024*1024, "The maximum size of an item stored in redis. Bigger items are not stored. If set to 0, no maximum size is enforced.")
+	f.BoolVar(&cfg.TLSEnabled, prefix+"tls-enabled", false, "Enable connecting to redis with TLS.")
+	cfg.TLS.RegisterFlagsWithPrefix(strings.TrimSuffix(prefix, "."), f)
+}
+
+func (cfg *RedisConfig) GetAddresses() []string {
+	if cfg.Addresses == "" {
+		return []string{}
+	}
+
+	return strings.Split(cfg.Addresses, ",")
+}
+
+// Validate the config.
+func (cfg *RedisConfig) Validate() error {
+	if len(cfg.GetAddresses()) == 0 {
+		return ErrNoRedisAddresses
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg      RedisConfig
+		expected error
+	}{
+		"no addresses": {
+			cfg:      RedisConfig{},
+			expected: ErrNoRedisAddresses,
+		},
+		"one address": {
+			cfg: RedisConfig{
+				Addresses: "localhost:6379",
+			},
+		},
+		"cluster addresses": {
+			cfg: RedisConfig{
+				Addresses:      "redis-1:6379,redis-2:6379",
+				ClusterEnabled: true,
+			},
+		},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, testData.cfg.Validate())
+		})
+	}
+}
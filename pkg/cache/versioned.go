@@ -14,6 +14,9 @@ import (
 type Versioned struct {
 	cache         Cache
 	versionPrefix string
+
+	// legacyPrefix is the prefix of the keys read for the keys missing with the version prefix, if not empty.
+	legacyPrefix string
 }
 
 // NewVersioned creates a new Versioned cache.
@@ -24,6 +27,27 @@ func NewVersioned(c Cache, version uint) Versioned {
 	}
 }
 
+// NewVersionedWithFormat creates a new Versioned cache whose keys also include the format of the cached data, like
+// its compression, so that the data cached with different formats doesn't collide. The keys of the default format,
+// identified by an empty string, are the same as the ones of NewVersioned.
+//
+// If readUnformatted is true, the keys missing with the format are also read from the keys of NewVersioned, where
+// the data of any format was cached before the keys included the format, so that the data cached before the
+// upgrade isn't lost. The data cached there in another format can't be decoded, and must be skipped by the caller.
+func NewVersionedWithFormat(c Cache, version uint, format string, readUnformatted bool) Versioned {
+	if format == "" {
+		return NewVersioned(c, version)
+	}
+	v := Versioned{
+		cache:         c,
+		versionPrefix: fmt.Sprintf("%d@%s@", version, format),
+	}
+	if readUnformatted {
+		v.legacyPrefix = fmt.Sprintf("%d@", version)
+	}
+	return v
+}
+
 func (c Versioned) Store(ctx context.Context, data map[string][]byte, ttl time.Duration) {
 	versioned := make(map[string][]byte, len(data))
 	for k, v := range data {
@@ -42,6 +66,20 @@ func (c Versioned) Fetch(ctx context.Context, keys []string) map[string][]byte {
 	for k, v := range versionedRes {
 		res[c.removeVersion(k)] = v
 	}
+
+	if c.legacyPrefix == "" || len(res) == len(keys) {
+		return res
+	}
+
+	legacyKeys := make([]string, 0, len(keys)-len(res))
+	for _, k := range keys {
+		if _, ok := res[k]; !ok {
+			legacyKeys = append(legacyKeys, c.legacyPrefix+k)
+		}
+	}
+	for k, v := range c.cache.Fetch(ctx, legacyKeys) {
+		res[strings.TrimPrefix(k, c.legacyPrefix)] = v
+	}
 	return res
 }
 
@@ -34,4 +34,34 @@ func TestVersioned(t *testing.T) {
 		resV2 := v2.Fetch(context.Background(), []string{"hit", "miss"})
 		assert.Equal(t, v2Data, resV2)
 	})
+
+	t.Run("different formats use different datasets", func(t *testing.T) {
+		cache := NewMockCache()
+		plain := NewVersionedWithFormat(cache, 1, "", false)
+		plainData := map[string][]byte{"hit": []byte(`plain`)}
+		plain.Store(context.Background(), plainData, time.Minute)
+		snappy := NewVersionedWithFormat(cache, 1, CompressionSnappy, false)
+		snappyData := map[string][]byte{"hit": []byte(`snappy`)}
+		snappy.Store(context.Background(), snappyData, time.Minute)
+
+		assert.Equal(t, plainData, plain.Fetch(context.Background(), []string{"hit", "miss"}))
+		assert.Equal(t, snappyData, snappy.Fetch(context.Background(), []string{"hit", "miss"}))
+
+		// The default format uses the same keys of the unformatted versioned cache.
+		assert.Equal(t, plainData, NewVersioned(cache, 1).Fetch(context.Background(), []string{"hit", "miss"}))
+	})
+
+	t.Run("the keys missing with a format are read from the keys cached before the format was included", func(t *testing.T) {
+		cache := NewMockCache()
+		legacy := NewVersioned(cache, 1)
+		legacy.Store(context.Background(), map[string][]byte{"legacy": []byte(`legacy`), "hit": []byte(`legacy`)}, time.Minute)
+		snappy := NewVersionedWithFormat(cache, 1, CompressionSnappy, true)
+		snappy.Store(context.Background(), map[string][]byte{"hit": []byte(`snappy`)}, time.Minute)
+
+		assert.Equal(t, map[string][]byte{"legacy": []byte(`legacy`), "hit": []byte(`snappy`)}, snappy.Fetch(context.Background(), []string{"legacy", "hit", "miss"}))
+
+		// The data is stored with the format only.
+		snappy.Store(context.Background(), map[string][]byte{"new": []byte(`snappy`)}, time.Minute)
+		assert.Empty(t, legacy.Fetch(context.Background(), []string{"new"}))
+	})
 }
@@ -204,11 +204,25 @@ func (c *MultitenantCompactor) UploadBlockFile(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if r.ContentLength < 0 {
+		// The size is required to check it against the max allowed one and the one specified in meta.json.
+		http.Error(w, "file size is required", http.StatusLengthRequired)
+		return
+	}
+
 	if r.ContentLength == 0 {
 		http.Error(w, "file cannot be empty", http.StatusBadRequest)
 		return
 	}
 
+	if maxSize := c.compactorCfg.BlockUploadValidation.MaxFileSizeBytes; maxSize > 0 && r.ContentLength > maxSize {
+		http.Error(w, fmt.Sprintf("file size exceeds the maximum allowed size of %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Never read more than the declared size, whatever the server the handler is used with.
+	r.Body = http.MaxBytesReader(w, r.Body, r.ContentLength)
+
 	const op = "block file upload"
 
 	ctx := r.Context()
@@ -245,10 +259,16 @@ func (c *MultitenantCompactor) UploadBlockFile(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	reader, cleanup, err := c.validateBlockFile(ctx, logger, tenantID, blockID, pth, r)
+	if err != nil {
+		writeBlockUploadError(err, op, "while validating block file", logger, w)
+		return
+	}
+	defer cleanup()
+
 	dst := path.Join(blockID.String(), pth)
 
 	level.Debug(logger).Log("msg", "uploading block file to bucket", "destination", dst, "size", r.ContentLength)
-	reader := bodyReader{r: r}
 	if err := userBkt.Upload(ctx, dst, reader); err != nil {
 		level.Error(logger).Log("msg", "failed uploading block file to bucket", "operation", op, "destination", dst, "err", err)
 		// We don't know what caused the error; it could be the client's fault (e.g. killed
@@ -11,7 +11,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -1050,6 +1052,204 @@ func TestMultitenantCompactor_UploadBlockFile(t *testing.T) {
 	}
 }
 
+func TestMultitenantCompactor_UploadBlockFile_Validation(t *testing.T) {
+	const tenantID = "test"
+	const blockID = "01G3FZ0JWJYJC0ZM6Y9778P6KD"
+	uploadingMetaPath := path.Join(tenantID, blockID, uploadingMetaFilename)
+
+	validIndex := string([]byte{0xBA, 0xAA, 0xD7, 0x00, 0x02})
+	validChunks := string([]byte{0x85, 0xBD, 0x40, 0xDD, 0x01})
+
+	testCases := map[string]struct {
+		cfg              BlockUploadValidationConfig
+		scannerStatus    int
+		path             string
+		content          string
+		unknownSize      bool
+		expStatusCode    int
+		expBody          string
+		expScannerCalled bool
+	}{
+		"validation disabled": {
+			path:          "chunks/000001",
+			content:       "content",
+			expStatusCode: http.StatusOK,
+		},
+		"file not exceeding the max size": {
+			cfg:           BlockUploadValidationConfig{MaxFileSizeBytes: 7},
+			path:          "chunks/000001",
+			content:       "content",
+			expStatusCode: http.StatusOK,
+		},
+		"file exceeding the max size": {
+			cfg:           BlockUploadValidationConfig{MaxFileSizeBytes: 6},
+			path:          "chunks/000001",
+			content:       "content",
+			expStatusCode: http.StatusRequestEntityTooLarge,
+			expBody:       "file size exceeds the maximum allowed size of 6 bytes",
+		},
+		"file of unknown size": {
+			cfg:           BlockUploadValidationConfig{MaxFileSizeBytes: 6},
+			path:          "chunks/000001",
+			content:       "content",
+			unknownSize:   true,
+			expStatusCode: http.StatusLengthRequired,
+			expBody:       "file size is required",
+		},
+		"valid index file type": {
+			cfg:           BlockUploadValidationConfig{FileTypeCheckEnabled: true},
+			path:          "index",
+			content:       validIndex,
+			expStatusCode: http.StatusOK,
+		},
+		"valid chunks file type": {
+			cfg:           BlockUploadValidationConfig{FileTypeCheckEnabled: true},
+			path:          "chunks/000001",
+			content:       validChunks,
+			expStatusCode: http.StatusOK,
+		},
+		"invalid index file type": {
+			cfg:           BlockUploadValidationConfig{FileTypeCheckEnabled: true},
+			path:          "index",
+			content:       validChunks,
+			expStatusCode: http.StatusBadRequest,
+			expBody:       "file content doesn't match the file type: index",
+		},
+		"invalid chunks file type": {
+			cfg:           BlockUploadValidationConfig{FileTypeCheckEnabled: true},
+			path:          "chunks/000001",
+			content:       "content",
+			expStatusCode: http.StatusBadRequest,
+			expBody:       "file content doesn't match the file type: chunks/000001",
+		},
+		"file smaller than the magic number": {
+			cfg:           BlockUploadValidationConfig{FileTypeCheckEnabled: true},
+			path:          "chunks/000001",
+			content:       "c",
+			expStatusCode: http.StatusBadRequest,
+			expBody:       "file content doesn't match the file type: chunks/000001",
+		},
+		"file accepted by the scanning service": {
+			cfg:              BlockUploadValidationConfig{ScannerURL: "set-by-test", FileTypeCheckEnabled: true},
+			scannerStatus:    http.StatusOK,
+			path:             "chunks/000001",
+			content:          validChunks,
+			expStatusCode:    http.StatusOK,
+			expScannerCalled: true,
+		},
+		"file rejected by the scanning service": {
+			cfg:              BlockUploadValidationConfig{ScannerURL: "set-by-test"},
+			scannerStatus:    http.StatusNotAcceptable,
+			path:             "chunks/000001",
+			content:          "content",
+			expStatusCode:    http.StatusUnprocessableEntity,
+			expBody:          "file rejected by the scanning service: chunks/000001",
+			expScannerCalled: true,
+		},
+		"scanning service failure": {
+			cfg:              BlockUploadValidationConfig{ScannerURL: "set-by-test"},
+			scannerStatus:    http.StatusServiceUnavailable,
+			path:             "chunks/000001",
+			content:          "content",
+			expStatusCode:    http.StatusInternalServerError,
+			expBody:          "internal server error",
+			expScannerCalled: true,
+		},
+		"the file type is checked before calling the scanning service": {
+			cfg:           BlockUploadValidationConfig{ScannerURL: "set-by-test", FileTypeCheckEnabled: true},
+			scannerStatus: http.StatusOK,
+			path:          "chunks/000001",
+			content:       "content",
+			expStatusCode: http.StatusBadRequest,
+			expBody:       "file content doesn't match the file type: chunks/000001",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			scannerCalled := false
+			if tc.cfg.ScannerURL != "" {
+				scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					scannerCalled = true
+					assert.Equal(t, tenantID, r.URL.Query().Get("tenant"))
+					assert.Equal(t, blockID, r.URL.Query().Get("block"))
+					assert.Equal(t, tc.path, r.URL.Query().Get("path"))
+
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, tc.content, string(body))
+
+					w.WriteHeader(tc.scannerStatus)
+				}))
+				t.Cleanup(scanner.Close)
+
+				tc.cfg.ScannerURL = scanner.URL
+				tc.cfg.ScannerTimeout = time.Minute
+			}
+
+			bkt := objstore.NewInMemBucket()
+			marshalAndUploadJSON(t, bkt, uploadingMetaPath, metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID: ulid.MustParse(blockID),
+				},
+				Thanos: metadata.Thanos{
+					Files: []metadata.File{{RelPath: tc.path, SizeBytes: int64(len(tc.content))}},
+				},
+			})
+
+			cfgProvider := newMockConfigProvider()
+			cfgProvider.blockUploadEnabled[tenantID] = true
+			c := &MultitenantCompactor{
+				compactorCfg: Config{DataDir: t.TempDir(), BlockUploadValidation: tc.cfg},
+				logger:       log.NewNopLogger(),
+				bucketClient: bkt,
+				cfgProvider:  cfgProvider,
+			}
+
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/files?path=%s", blockID, url.QueryEscape(tc.path)), strings.NewReader(tc.content))
+			if tc.unknownSize {
+				r.ContentLength = -1
+			}
+			r = mux.SetURLVars(r, map[string]string{"block": blockID})
+			r = r.WithContext(user.InjectOrgID(r.Context(), tenantID))
+			w := httptest.NewRecorder()
+			c.UploadBlockFile(w, r)
+
+			resp := w.Result()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.expScannerCalled, scannerCalled)
+
+			// The file is written to the bucket only if accepted.
+			exists, err := bkt.Exists(context.Background(), path.Join(tenantID, blockID, tc.path))
+			require.NoError(t, err)
+			if tc.expStatusCode == http.StatusOK {
+				assert.Empty(t, string(body))
+				require.True(t, exists)
+
+				rdr, err := bkt.Get(context.Background(), path.Join(tenantID, blockID, tc.path))
+				require.NoError(t, err)
+				t.Cleanup(func() {
+					_ = rdr.Close()
+				})
+				content, err := io.ReadAll(rdr)
+				require.NoError(t, err)
+				assert.Equal(t, tc.content, string(content))
+			} else {
+				assert.Equal(t, tc.expBody+"\n", string(body))
+				assert.False(t, exists)
+			}
+
+			// The buffered files are removed.
+			entries, err := os.ReadDir(filepath.Join(c.compactorCfg.DataDir, blockUploadsDirName))
+			if err == nil {
+				assert.Empty(t, entries)
+			}
+		})
+	}
+}
+
 func setUpGet(bkt *bucket.ClientMock, pth string, content []byte, err error) {
 	bkt.On("Get", mock.Anything, pth).Return(func(_ context.Context, _ string) (io.ReadCloser, error) {
 		return io.NopCloser(bytes.NewReader(content)), err
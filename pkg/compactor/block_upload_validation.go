@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/thanos-io/thanos/pkg/block"
+)
+
+const blockUploadsDirName = "block-uploads"
+
+// BlockUploadValidationConfig configures the validation of the files of the blocks uploaded through the
+// block upload API, before they're written to the tenant's bucket.
+type BlockUploadValidationConfig struct {
+	MaxFileSizeBytes     int64         `yaml:"max_file_size_bytes" category:"experimental"`
+	FileTypeCheckEnabled bool          `yaml:"file_type_check_enabled" category:"experimental"`
+	ScannerURL           string        `yaml:"scanner_url" category:"experimental"`
+	ScannerTimeout       time.Duration `yaml:"scanner_timeout" category:"experimental"`
+}
+
+// RegisterFlagsWithPrefix registers the BlockUploadValidationConfig flags.
+func (cfg *BlockUploadValidationConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.Int64Var(&cfg.MaxFileSizeBytes, prefix+"max-file-size-bytes", 0, "Maximum size of each file of an uploaded block. 0 to disable.")
+	f.BoolVar(&cfg.FileTypeCheckEnabled, prefix+"file-type-check-enabled", false, "Reject the files of an uploaded block whose content doesn't start with the magic number of their type, either index or chunks segment file.")
+	f.StringVar(&cfg.ScannerURL, prefix+"scanner-url", "", "URL of an external scanning service, like an antivirus, to send each file of an uploaded block to with a POST request before writing it to the bucket. The file is rejected unless the service responds with a 2xx status code. The file is stored in the compactor data directory while scanned. Empty to disable.")
+	f.DurationVar(&cfg.ScannerTimeout, prefix+"scanner-timeout", 30*time.Second, "Timeout of the requests to the external scanning service.")
+}
+
+func (cfg *BlockUploadValidationConfig) Validate() error {
+	if cfg.ScannerURL != "" {
+		if u, err := url.Parse(cfg.ScannerURL); err != nil || !u.IsAbs() || u.Host == "" {
+			return errInvalidBlockUploadScannerURL
+		}
+	}
+	return nil
+}
+
+// validateBlockFile validates the block file uploaded with the request, and returns the reader of the file to write
+// to the bucket, with a function to call once done with it. The file is rejected with an httpError.
+func (c *MultitenantCompactor) validateBlockFile(ctx context.Context, logger log.Logger, tenantID string, blockID ulid.ULID, pth string, r *http.Request) (io.Reader, func(), error) {
+	cfg := c.compactorCfg.BlockUploadValidation
+
+	if cfg.ScannerURL == "" {
+		if cfg.FileTypeCheckEnabled {
+			header := make([]byte, 4)
+			n, err := io.ReadFull(r.Body, header)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+				return nil, nil, errors.Wrap(err, "read file header")
+			}
+			if err := checkBlockFileType(pth, header[:n]); err != nil {
+				return nil, nil, err
+			}
+
+			// Keep streaming the whole file to the bucket.
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(header[:n]), r.Body))
+		}
+		return bodyReader{r: r}, func() {}, nil
+	}
+
+	// The file must be fully received before being scanned, so it's buffered on disk.
+	dir := filepath.Join(c.compactorCfg.DataDir, blockUploadsDirName)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, nil, errors.Wrap(err, "create block uploads directory")
+	}
+	f, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create block upload file")
+	}
+	cleanup := func() {
+		if err := f.Close(); err != nil {
+			level.Warn(logger).Log("msg", "failed to close block upload file", "file", f.Name(), "err", err)
+		}
+		if err := os.Remove(f.Name()); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove block upload file", "file", f.Name(), "err", err)
+		}
+	}
+
+	size, err := io.Copy(f, r.Body)
+	if err != nil {
+		cleanup()
+		return nil, nil, errors.Wrap(err, "receive file")
+	}
+	if size != r.ContentLength {
+		cleanup()
+		return nil, nil, httpError{message: "file size doesn't match the content length", statusCode: http.StatusBadRequest}
+	}
+
+	if cfg.FileTypeCheckEnabled {
+		header := make([]byte, 4)
+		n, err := f.ReadAt(header, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			cleanup()
+			return nil, nil, errors.Wrap(err, "read file header")
+		}
+		if err := checkBlockFileType(pth, header[:n]); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	if err := c.scanBlockFile(ctx, tenantID, blockID, pth, io.NewSectionReader(f, 0, size), size); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, errors.Wrap(err, "rewind file")
+	}
+	return f, cleanup, nil
+}
+
+// checkBlockFileType checks that the content of the block file, whose first bytes are given, starts with the magic
+// number of the file type.
+func checkBlockFileType(pth string, header []byte) error {
+	magic := uint32(chunks.MagicChunks)
+	if pth == block.IndexFilename {
+		magic = index.MagicIndex
+	}
+
+	if len(header) < 4 || binary.BigEndian.Uint32(header) != magic {
+		return httpError{
+			message:    fmt.Sprintf("file content doesn't match the file type: %s", pth),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+// scanBlockFile sends the block file to the external scanning service, and returns an httpError if the service
+// rejects it.
+func (c *MultitenantCompactor) scanBlockFile(ctx context.Context, tenantID string, blockID ulid.ULID, pth string, body io.Reader, size int64) error {
+	cfg := c.compactorCfg.BlockUploadValidation
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ScannerTimeout)
+	defer cancel()
+
+	u, err := url.Parse(cfg.ScannerURL)
+	if err != nil {
+		return errors.Wrap(err, "parse scanner URL")
+	}
+	q := u.Query()
+	q.Set("tenant", tenantID)
+	q.Set("block", blockID.String())
+	q.Set("path", pth)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return errors.Wrap(err, "create scanner request")
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "scan file")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode/100 == 4:
+		return httpError{
+			message:    fmt.Sprintf("file rejected by the scanning service: %s", pth),
+			statusCode: http.StatusUnprocessableEntity,
+		}
+	default:
+		return errors.Errorf("unexpected scanning service response status: %s", resp.Status)
+	}
+}
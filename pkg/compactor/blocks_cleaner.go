@@ -8,6 +8,8 @@ package compactor
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -42,6 +44,7 @@ type BlocksCleanerConfig struct {
 	CleanupConcurrency      int
 	TenantCleanupDelay      time.Duration // Delay before removing tenant deletion mark and "debug".
 	DeleteBlocksConcurrency int
+	ColdStorage             *bucket.TieredBucketClient // The old blocks are moved to its cold bucket. Nil to disable.
 }
 
 type BlocksCleaner struct {
@@ -66,10 +69,17 @@ type BlocksCleaner struct {
 	blocksFailedTotal              prometheus.Counter
 	blocksMarkedForDeletion        prometheus.Counter
 	partialBlocksMarkedForDeletion prometheus.Counter
+	blocksArchivedTotal            prometheus.Counter
+	blocksArchiveFailedTotal       prometheus.Counter
 	tenantBlocks                   *prometheus.GaugeVec
 	tenantMarkedBlocks             *prometheus.GaugeVec
 	tenantPartialBlocks            *prometheus.GaugeVec
+	tenantColdStorageBlocks        *prometheus.GaugeVec
 	tenantBucketIndexLastUpdate    *prometheus.GaugeVec
+	tenantBlocksBytes              *prometheus.GaugeVec
+	tenantBlocksByCompactionLevel  *prometheus.GaugeVec
+	tenantBlocksMinTime            *prometheus.GaugeVec
+	tenantBlocksMaxTime            *prometheus.GaugeVec
 }
 
 func NewBlocksCleaner(cfg BlocksCleanerConfig, bucketClient objstore.Bucket, ownUser func(userID string) (bool, error), cfgProvider ConfigProvider, logger log.Logger, reg prometheus.Registerer) *BlocksCleaner {
@@ -114,6 +124,14 @@ func NewBlocksCleaner(cfg BlocksCleanerConfig, bucketClient objstore.Bucket, own
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "partial"},
 		}),
+		blocksArchivedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_archived_to_cold_storage_total",
+			Help: "Total number of blocks moved to the cold storage.",
+		}),
+		blocksArchiveFailedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_archive_to_cold_storage_failures_total",
+			Help: "Total number of blocks failed to be moved to the cold storage.",
+		}),
 
 		// The following metrics don't have the "cortex_compactor" prefix because not strictly related to
 		// the compactor. They're just tracked by the compactor because it's the most logical place where these
@@ -130,10 +148,30 @@ func NewBlocksCleaner(cfg BlocksCleanerConfig, bucketClient objstore.Bucket, own
 			Name: "cortex_bucket_blocks_partials_count",
 			Help: "Total number of partial blocks.",
 		}, []string{"user"}),
+		tenantColdStorageBlocks: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_bucket_blocks_cold_storage_count",
+			Help: "Total number of blocks moved to the cold storage.",
+		}, []string{"user"}),
 		tenantBucketIndexLastUpdate: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
 			Name: "cortex_bucket_index_last_successful_update_timestamp_seconds",
 			Help: "Timestamp of the last successful update of a tenant's bucket index.",
 		}, []string{"user"}),
+		tenantBlocksBytes: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_bucket_blocks_bytes",
+			Help: "Total size of the blocks in the bucket, in bytes. Includes blocks marked for deletion, but not partial blocks.",
+		}, []string{"user"}),
+		tenantBlocksByCompactionLevel: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_bucket_blocks_compaction_level_count",
+			Help: "Total number of blocks in the bucket by compaction level. Includes blocks marked for deletion, but not partial blocks.",
+		}, []string{"user", "level"}),
+		tenantBlocksMinTime: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_bucket_blocks_min_time_seconds",
+			Help: "Unix timestamp of the minimum time of the oldest block in the bucket.",
+		}, []string{"user"}),
+		tenantBlocksMaxTime: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_bucket_blocks_max_time_seconds",
+			Help: "Unix timestamp of the maximum time of the newest block in the bucket.",
+		}, []string{"user"}),
 	}
 
 	c.Service = services.NewTimerService(cfg.CleanupInterval, c.starting, c.ticker, nil)
@@ -190,7 +228,9 @@ func (c *BlocksCleaner) cleanUsers(ctx context.Context) error {
 			c.tenantBlocks.DeleteLabelValues(userID)
 			c.tenantMarkedBlocks.DeleteLabelValues(userID)
 			c.tenantPartialBlocks.DeleteLabelValues(userID)
+			c.tenantColdStorageBlocks.DeleteLabelValues(userID)
 			c.tenantBucketIndexLastUpdate.DeleteLabelValues(userID)
+			c.deleteTenantStorageMetrics(userID)
 		}
 	}
 	c.lastOwnedUsers = allUsers
@@ -222,6 +262,7 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 		return err
 	}
 	c.tenantBucketIndexLastUpdate.DeleteLabelValues(userID)
+	c.deleteTenantStorageMetrics(userID)
 
 	var deletedBlocks, failed int
 	err := userBucket.Iter(ctx, "", func(name string) error {
@@ -267,6 +308,7 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 	c.tenantBlocks.DeleteLabelValues(userID)
 	c.tenantMarkedBlocks.DeleteLabelValues(userID)
 	c.tenantPartialBlocks.DeleteLabelValues(userID)
+	c.tenantColdStorageBlocks.DeleteLabelValues(userID)
 
 	if deletedBlocks > 0 {
 		level.Info(userLogger).Log("msg", "deleted blocks for tenant marked for deletion", "deletedBlocks", deletedBlocks)
@@ -368,6 +410,14 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string) (returnErr
 		c.cleanUserPartialBlocks(ctx, partials, idx, partialDeletionCutoffTime, userBucket, userLogger)
 	}
 
+	// Move the old blocks to the cold storage. This is a best effort, so we don't return error if it fails:
+	// the blocks failed to be moved are moved in the next run.
+	if c.cfg.ColdStorage != nil {
+		if archiveAfter := c.cfgProvider.CompactorColdStorageArchiveAfter(userID); archiveAfter > 0 {
+			c.archiveBlocksToColdStorage(ctx, idx, userID, time.Now().Add(-archiveAfter), userLogger)
+		}
+	}
+
 	// Upload the updated index to the storage.
 	if err := bucketindex.WriteIndex(ctx, c.bucketClient, userID, c.cfgProvider, idx); err != nil {
 		return err
@@ -376,11 +426,41 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string) (returnErr
 	c.tenantBlocks.WithLabelValues(userID).Set(float64(len(idx.Blocks)))
 	c.tenantMarkedBlocks.WithLabelValues(userID).Set(float64(len(idx.BlockDeletionMarks)))
 	c.tenantPartialBlocks.WithLabelValues(userID).Set(float64(len(partials)))
+	if c.cfg.ColdStorage != nil {
+		c.tenantColdStorageBlocks.WithLabelValues(userID).Set(float64(countColdStorageBlocks(idx)))
+	}
 	c.tenantBucketIndexLastUpdate.WithLabelValues(userID).SetToCurrentTime()
+	c.updateTenantStorageMetrics(userID, idx.Stats())
 
 	return nil
 }
 
+// updateTenantStorageMetrics updates the per-tenant storage metrics tracked from the bucket index statistics.
+func (c *BlocksCleaner) updateTenantStorageMetrics(userID string, stats bucketindex.Stats) {
+	c.tenantBlocksBytes.WithLabelValues(userID).Set(float64(stats.BlocksBytes))
+
+	// The compaction levels change over time, so the series of the levels without blocks anymore are removed.
+	c.tenantBlocksByCompactionLevel.DeletePartialMatch(prometheus.Labels{"user": userID})
+	for compactionLevel, count := range stats.BlocksByCompactionLevel {
+		c.tenantBlocksByCompactionLevel.WithLabelValues(userID, strconv.Itoa(compactionLevel)).Set(float64(count))
+	}
+
+	if stats.Blocks == 0 {
+		c.tenantBlocksMinTime.DeleteLabelValues(userID)
+		c.tenantBlocksMaxTime.DeleteLabelValues(userID)
+		return
+	}
+	c.tenantBlocksMinTime.WithLabelValues(userID).Set(float64(stats.MinTime) / 1000)
+	c.tenantBlocksMaxTime.WithLabelValues(userID).Set(float64(stats.MaxTime) / 1000)
+}
+
+func (c *BlocksCleaner) deleteTenantStorageMetrics(userID string) {
+	c.tenantBlocksBytes.DeleteLabelValues(userID)
+	c.tenantBlocksByCompactionLevel.DeletePartialMatch(prometheus.Labels{"user": userID})
+	c.tenantBlocksMinTime.DeleteLabelValues(userID)
+	c.tenantBlocksMaxTime.DeleteLabelValues(userID)
+}
+
 // Concurrently deletes blocks marked for deletion, and removes blocks from index.
 func (c *BlocksCleaner) deleteBlocksMarkedForDeletion(ctx context.Context, idx *bucketindex.Index, userBucket objstore.Bucket, userLogger log.Logger) {
 	blocksToDelete := make([]ulid.ULID, 0, len(idx.BlockDeletionMarks))
@@ -549,3 +629,96 @@ func findMostRecentModifiedTimeForBlock(ctx context.Context, blockID ulid.ULID,
 	}, objstore.WithRecursiveIter)
 	return result, err
 }
+
+// archiveBlocksToColdStorage moves the blocks whose max time is before the threshold, and not already marked for
+// deletion, to the cold storage, and flags them in the bucket index.
+func (c *BlocksCleaner) archiveBlocksToColdStorage(ctx context.Context, idx *bucketindex.Index, userID string, threshold time.Time, userLogger log.Logger) {
+	hot := bucket.NewUserBucketClient(userID, c.cfg.ColdStorage.HotBucket(), c.cfgProvider)
+	cold := bucket.NewUserBucketClient(userID, c.cfg.ColdStorage.ColdBucket(), c.cfgProvider)
+
+	for _, b := range listBlocksOutsideRetentionPeriod(idx, threshold) {
+		if b.ColdStorage {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		moved, err := moveBlockToColdStorage(ctx, b.ID, hot, cold)
+		if err != nil {
+			c.blocksArchiveFailedTotal.Inc()
+			level.Warn(userLogger).Log("msg", "failed to move block to the cold storage", "block", b.ID, "err", err)
+			continue
+		}
+		if moved {
+			c.blocksArchivedTotal.Inc()
+			level.Info(userLogger).Log("msg", "moved block to the cold storage", "block", b.ID, "maxTime", b.MaxTime)
+		}
+		b.ColdStorage = true
+	}
+}
+
+// moveBlockToColdStorage copies the files of the block from the hot bucket to the cold bucket, and then deletes
+// them from the hot bucket. The meta.json is copied last and deleted first, so the block is complete in the cold
+// bucket once its meta.json is there. It returns false if the block has already been copied by a previous run.
+func moveBlockToColdStorage(ctx context.Context, id ulid.ULID, hot, cold objstore.Bucket) (bool, error) {
+	metaName := path.Join(id.String(), block.MetaFilename)
+
+	var (
+		names   []string
+		hasMeta bool
+	)
+	if err := hot.Iter(ctx, id.String(), func(name string) error {
+		if name == metaName {
+			hasMeta = true
+		} else {
+			names = append(names, name)
+		}
+		return nil
+	}, objstore.WithRecursiveIter); err != nil {
+		return false, errors.Wrap(err, "list block files")
+	}
+
+	if hasMeta {
+		for _, name := range append(names, metaName) {
+			if err := copyObject(ctx, hot, cold, name); err != nil {
+				return false, errors.Wrapf(err, "copy %s", name)
+			}
+		}
+		names = append([]string{metaName}, names...)
+	} else if len(names) > 0 {
+		// The block has been copied by a previous run, which failed to delete all its files from the hot bucket.
+		if ok, err := cold.Exists(ctx, metaName); err != nil {
+			return false, errors.Wrap(err, "check meta.json in the cold storage")
+		} else if !ok {
+			return false, errors.New("meta.json not found in the cold storage")
+		}
+	}
+
+	for _, name := range names {
+		if err := hot.Delete(ctx, name); err != nil && !hot.IsObjNotFoundErr(err) {
+			return false, errors.Wrapf(err, "delete %s", name)
+		}
+	}
+	return hasMeta, nil
+}
+
+func copyObject(ctx context.Context, src, dst objstore.Bucket, name string) error {
+	r, err := src.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	return dst.Upload(ctx, name, r)
+}
+
+func countColdStorageBlocks(idx *bucketindex.Index) int {
+	count := 0
+	for _, b := range idx.Blocks {
+		if b.ColdStorage {
+			count++
+		}
+	}
+	return count
+}
@@ -35,6 +35,7 @@ import (
 	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/test"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 type testBlocksCleanerOptions struct {
@@ -372,10 +373,25 @@ func TestBlocksCleaner_ShouldRemoveMetricsForTenantsNotBelongingAnymoreToTheShar
 		# TYPE cortex_bucket_blocks_partials_count gauge
 		cortex_bucket_blocks_partials_count{user="user-1"} 0
 		cortex_bucket_blocks_partials_count{user="user-2"} 0
+		# HELP cortex_bucket_blocks_compaction_level_count Total number of blocks in the bucket by compaction level. Includes blocks marked for deletion, but not partial blocks.
+		# TYPE cortex_bucket_blocks_compaction_level_count gauge
+		cortex_bucket_blocks_compaction_level_count{level="1",user="user-1"} 2
+		cortex_bucket_blocks_compaction_level_count{level="1",user="user-2"} 1
+		# HELP cortex_bucket_blocks_min_time_seconds Unix timestamp of the minimum time of the oldest block in the bucket.
+		# TYPE cortex_bucket_blocks_min_time_seconds gauge
+		cortex_bucket_blocks_min_time_seconds{user="user-1"} 0.01
+		cortex_bucket_blocks_min_time_seconds{user="user-2"} 0.03
+		# HELP cortex_bucket_blocks_max_time_seconds Unix timestamp of the maximum time of the newest block in the bucket.
+		# TYPE cortex_bucket_blocks_max_time_seconds gauge
+		cortex_bucket_blocks_max_time_seconds{user="user-1"} 0.03
+		cortex_bucket_blocks_max_time_seconds{user="user-2"} 0.04
 	`),
 		"cortex_bucket_blocks_count",
 		"cortex_bucket_blocks_marked_for_deletion_count",
 		"cortex_bucket_blocks_partials_count",
+		"cortex_bucket_blocks_compaction_level_count",
+		"cortex_bucket_blocks_min_time_seconds",
+		"cortex_bucket_blocks_max_time_seconds",
 	))
 
 	// Override the users scanner to reconfigure it to only return a subset of users.
@@ -397,10 +413,22 @@ func TestBlocksCleaner_ShouldRemoveMetricsForTenantsNotBelongingAnymoreToTheShar
 		# HELP cortex_bucket_blocks_partials_count Total number of partial blocks.
 		# TYPE cortex_bucket_blocks_partials_count gauge
 		cortex_bucket_blocks_partials_count{user="user-1"} 0
+		# HELP cortex_bucket_blocks_compaction_level_count Total number of blocks in the bucket by compaction level. Includes blocks marked for deletion, but not partial blocks.
+		# TYPE cortex_bucket_blocks_compaction_level_count gauge
+		cortex_bucket_blocks_compaction_level_count{level="1",user="user-1"} 3
+		# HELP cortex_bucket_blocks_min_time_seconds Unix timestamp of the minimum time of the oldest block in the bucket.
+		# TYPE cortex_bucket_blocks_min_time_seconds gauge
+		cortex_bucket_blocks_min_time_seconds{user="user-1"} 0.01
+		# HELP cortex_bucket_blocks_max_time_seconds Unix timestamp of the maximum time of the newest block in the bucket.
+		# TYPE cortex_bucket_blocks_max_time_seconds gauge
+		cortex_bucket_blocks_max_time_seconds{user="user-1"} 0.05
 	`),
 		"cortex_bucket_blocks_count",
 		"cortex_bucket_blocks_marked_for_deletion_count",
 		"cortex_bucket_blocks_partials_count",
+		"cortex_bucket_blocks_compaction_level_count",
+		"cortex_bucket_blocks_min_time_seconds",
+		"cortex_bucket_blocks_max_time_seconds",
 	))
 }
 
@@ -689,6 +717,112 @@ func TestBlocksCleaner_ShouldRemoveBlocksOutsideRetentionPeriod(t *testing.T) {
 	}
 }
 
+func TestBlocksCleaner_ShouldMoveBlocksToColdStorage(t *testing.T) {
+	hot, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	cold, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	tiered := bucket.NewTieredBucketClient(hot, cold, 0)
+	bucketClient := bucketindex.BucketWithGlobalMarkers(tiered)
+
+	ts := func(hours int) int64 {
+		return time.Now().Add(time.Duration(hours)*time.Hour).Unix() * 1000
+	}
+
+	block1 := createTSDBBlock(t, bucketClient, "user-1", ts(-10), ts(-8), 2, nil)
+	block2 := createTSDBBlock(t, bucketClient, "user-1", ts(-8), ts(-6), 2, nil)
+	block3 := createTSDBBlock(t, bucketClient, "user-1", ts(-12), ts(-10), 2, nil)
+	block4 := createTSDBBlock(t, bucketClient, "user-2", ts(-10), ts(-8), 2, nil)
+	createDeletionMark(t, bucketClient, "user-1", block3, time.Now())
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		DeleteBlocksConcurrency: 1,
+		ColdStorage:             tiered,
+	}
+
+	ctx := context.Background()
+	logger := test.NewTestingLogger(t)
+	reg := prometheus.NewPedanticRegistry()
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.coldStorageArchiveAfter["user-1"] = 7 * time.Hour
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, cfgProvider, logger, reg)
+
+	assertBlockExists := func(bkt objstore.Bucket, user string, block ulid.ULID, expectExists bool) {
+		exists, err := bkt.Exists(ctx, path.Join(user, block.String(), metadata.MetaFilename))
+		require.NoError(t, err)
+		assert.Equal(t, expectExists, exists)
+		exists, err = bkt.Exists(ctx, path.Join(user, block.String(), "index"))
+		require.NoError(t, err)
+		assert.Equal(t, expectExists, exists)
+	}
+
+	// Only the block of user-1 older than the archive period, and not marked for deletion, is moved.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, cleaner.cleanUsers(ctx))
+
+		assertBlockExists(hot, "user-1", block1, false)
+		assertBlockExists(cold, "user-1", block1, true)
+		assertBlockExists(hot, "user-1", block2, true)
+		assertBlockExists(cold, "user-1", block2, false)
+		assertBlockExists(hot, "user-1", block3, true)
+		assertBlockExists(cold, "user-1", block3, false)
+		assertBlockExists(hot, "user-2", block4, true)
+		assertBlockExists(cold, "user-2", block4, false)
+	}
+
+	idx, err := bucketindex.ReadIndex(ctx, bucketClient, "user-1", nil, logger)
+	require.NoError(t, err)
+	require.Len(t, idx.Blocks, 3)
+	for _, b := range idx.Blocks {
+		assert.Equal(t, b.ID == block1, b.ColdStorage, b.ID.String())
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_bucket_blocks_cold_storage_count Total number of blocks moved to the cold storage.
+		# TYPE cortex_bucket_blocks_cold_storage_count gauge
+		cortex_bucket_blocks_cold_storage_count{user="user-1"} 1
+		cortex_bucket_blocks_cold_storage_count{user="user-2"} 0
+		# HELP cortex_compactor_blocks_archived_to_cold_storage_total Total number of blocks moved to the cold storage.
+		# TYPE cortex_compactor_blocks_archived_to_cold_storage_total counter
+		cortex_compactor_blocks_archived_to_cold_storage_total 1
+		# HELP cortex_compactor_blocks_archive_to_cold_storage_failures_total Total number of blocks failed to be moved to the cold storage.
+		# TYPE cortex_compactor_blocks_archive_to_cold_storage_failures_total counter
+		cortex_compactor_blocks_archive_to_cold_storage_failures_total 0
+		`),
+		"cortex_bucket_blocks_cold_storage_count",
+		"cortex_compactor_blocks_archived_to_cold_storage_total",
+		"cortex_compactor_blocks_archive_to_cold_storage_failures_total",
+	))
+}
+
+func TestMoveBlockToColdStorage_ShouldCompleteAPreviousMove(t *testing.T) {
+	hot, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	cold, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	ctx := context.Background()
+
+	blockID := createTSDBBlock(t, hot, "user-1", 10, 20, 2, nil)
+	userHot := bucket.NewUserBucketClient("user-1", hot, nil)
+	userCold := bucket.NewUserBucketClient("user-1", cold, nil)
+
+	// Simulate a previous move which failed after deleting the meta.json from the hot bucket.
+	require.NoError(t, copyObject(ctx, userHot, userCold, path.Join(blockID.String(), "index")))
+	require.NoError(t, copyObject(ctx, userHot, userCold, path.Join(blockID.String(), metadata.MetaFilename)))
+	require.NoError(t, userHot.Delete(ctx, path.Join(blockID.String(), metadata.MetaFilename)))
+
+	moved, err := moveBlockToColdStorage(ctx, blockID, userHot, userCold)
+	require.NoError(t, err)
+	assert.False(t, moved)
+
+	var remaining []string
+	require.NoError(t, userHot.Iter(ctx, blockID.String(), func(name string) error {
+		remaining = append(remaining, name)
+		return nil
+	}, objstore.WithRecursiveIter))
+	assert.Empty(t, remaining)
+}
+
 func checkBlock(t *testing.T, user string, bucketClient objstore.Bucket, block ulid.ULID, metaJSONExists bool, markedForDeletion bool) {
 	exists, err := bucketClient.Exists(context.Background(), path.Join(user, block.String(), metadata.MetaFilename))
 	require.NoError(t, err)
@@ -950,23 +1084,33 @@ func (m *mockBucketFailure) Delete(ctx context.Context, name string) error {
 }
 
 type mockConfigProvider struct {
-	userRetentionPeriods         map[string]time.Duration
-	splitAndMergeShards          map[string]int
-	instancesShardSize           map[string]int
-	splitGroups                  map[string]int
-	blockUploadEnabled           map[string]bool
-	userPartialBlockDelay        map[string]time.Duration
-	userPartialBlockDelayInvalid map[string]bool
+	userRetentionPeriods           map[string]time.Duration
+	splitAndMergeShards            map[string]int
+	instancesShardSize             map[string]int
+	splitGroups                    map[string]int
+	blockUploadEnabled             map[string]bool
+	userPartialBlockDelay          map[string]time.Duration
+	userPartialBlockDelayInvalid   map[string]bool
+	allowedTimeWindows             map[string]validation.TimeWindows
+	firstLevelCompactionWaitPeriod map[string]time.Duration
+	coldStorageArchiveAfter        map[string]time.Duration
+	maxMetadataPerBlock            map[string]int
+	maxExemplarsPerBlock           map[string]int
 }
 
 func newMockConfigProvider() *mockConfigProvider {
 	return &mockConfigProvider{
-		userRetentionPeriods:         make(map[string]time.Duration),
-		splitAndMergeShards:          make(map[string]int),
-		splitGroups:                  make(map[string]int),
-		blockUploadEnabled:           make(map[string]bool),
-		userPartialBlockDelay:        make(map[string]time.Duration),
-		userPartialBlockDelayInvalid: make(map[string]bool),
+		userRetentionPeriods:           make(map[string]time.Duration),
+		splitAndMergeShards:            make(map[string]int),
+		splitGroups:                    make(map[string]int),
+		blockUploadEnabled:             make(map[string]bool),
+		userPartialBlockDelay:          make(map[string]time.Duration),
+		userPartialBlockDelayInvalid:   make(map[string]bool),
+		allowedTimeWindows:             make(map[string]validation.TimeWindows),
+		firstLevelCompactionWaitPeriod: make(map[string]time.Duration),
+		coldStorageArchiveAfter:        make(map[string]time.Duration),
+		maxMetadataPerBlock:            make(map[string]int),
+		maxExemplarsPerBlock:           make(map[string]int),
 	}
 }
 
@@ -1006,6 +1150,26 @@ func (m *mockConfigProvider) CompactorPartialBlockDeletionDelay(user string) (ti
 	return m.userPartialBlockDelay[user], !m.userPartialBlockDelayInvalid[user]
 }
 
+func (m *mockConfigProvider) CompactorAllowedTimeWindows(user string) validation.TimeWindows {
+	return m.allowedTimeWindows[user]
+}
+
+func (m *mockConfigProvider) CompactorFirstLevelCompactionWaitPeriod(user string) time.Duration {
+	return m.firstLevelCompactionWaitPeriod[user]
+}
+
+func (m *mockConfigProvider) CompactorColdStorageArchiveAfter(user string) time.Duration {
+	return m.coldStorageArchiveAfter[user]
+}
+
+func (m *mockConfigProvider) MaxMetadataPerBlock(user string) int {
+	return m.maxMetadataPerBlock[user]
+}
+
+func (m *mockConfigProvider) MaxExemplarsPerBlock(user string) int {
+	return m.maxExemplarsPerBlock[user]
+}
+
 func (m *mockConfigProvider) S3SSEType(user string) string {
 	return ""
 }
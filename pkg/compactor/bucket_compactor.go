@@ -31,9 +31,12 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/runutil"
 	"go.uber.org/atomic"
+	"golang.org/x/sync/semaphore"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	mimit_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bloom"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 )
 
@@ -388,6 +391,34 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 	elapsed = time.Since(compactionBegin)
 	level.Info(jobLogger).Log("msg", "compacted blocks", "new", fmt.Sprintf("%v", compIDs), "blocks", fmt.Sprintf("%v", blocksToCompactDirs), "duration", elapsed, "duration_ms", elapsed.Milliseconds())
 
+	// The compacted blocks keep the metric metadata of all the source blocks.
+	var metricMetadata []mimirpb.MetricMetadata
+	if c.maxMetadataPerBlock > 0 {
+		sets := make([][]mimirpb.MetricMetadata, 0, len(blocksToCompactDirs))
+		for _, bdir := range blocksToCompactDirs {
+			md, err := mimit_tsdb.ReadMetricMetadataFile(bdir)
+			if err != nil {
+				return false, nil, errors.Wrapf(err, "read metric metadata of block %s", bdir)
+			}
+			sets = append(sets, md)
+		}
+		metricMetadata = mimit_tsdb.MergeMetricMetadata(c.maxMetadataPerBlock, sets...)
+	}
+
+	// The compacted blocks keep the most recent exemplars of the source blocks.
+	var exemplars []mimirpb.TimeSeries
+	if c.maxExemplarsPerBlock > 0 {
+		sets := make([][]mimirpb.TimeSeries, 0, len(blocksToCompactDirs))
+		for _, bdir := range blocksToCompactDirs {
+			series, err := mimit_tsdb.ReadExemplarsFile(bdir)
+			if err != nil {
+				return false, nil, errors.Wrapf(err, "read exemplars of block %s", bdir)
+			}
+			sets = append(sets, series)
+		}
+		exemplars = mimit_tsdb.MergeExemplars(c.maxExemplarsPerBlock, sets...)
+	}
+
 	uploadBegin := time.Now()
 	uploadedBlocks := atomic.NewInt64(0)
 
@@ -425,6 +456,28 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 			return errors.Wrapf(err, "invalid result block %s", bdir)
 		}
 
+		if len(c.bloomFilterLabelNames) > 0 {
+			filter, err := bloom.BuildFromIndex(index, c.bloomFilterLabelNames, bloom.DefaultFalsePositiveRate)
+			if err != nil {
+				return errors.Wrapf(err, "failed to build bloom filter for block %s", bdir)
+			}
+			if err := bloom.WriteFile(bdir, filter); err != nil {
+				return errors.Wrapf(err, "failed to write bloom filter for block %s", bdir)
+			}
+		}
+
+		if len(metricMetadata) > 0 {
+			if err := mimit_tsdb.WriteMetricMetadataFile(bdir, metricMetadata); err != nil {
+				return errors.Wrapf(err, "failed to write metric metadata for block %s", bdir)
+			}
+		}
+
+		if blockExemplars := exemplarsForShard(exemplars, job.UseSplitting(), blockToUpload.shardIndex, len(compIDs)); len(blockExemplars) > 0 {
+			if err := mimit_tsdb.WriteExemplarsFile(bdir, blockExemplars); err != nil {
+				return errors.Wrapf(err, "failed to write exemplars for block %s", bdir)
+			}
+		}
+
 		begin := time.Now()
 		if err := mimit_tsdb.UploadBlock(ctx, jobLogger, c.bkt, bdir, nil); err != nil {
 			return errors.Wrapf(err, "upload of %s failed", blockToUpload.ulid)
@@ -474,6 +527,22 @@ func convertCompactionResultToForEachJobs(compactedBlocks []ulid.ULID, splitJob
 	return result
 }
 
+// exemplarsForShard returns the exemplars of the series of the given shard, when splitting. The series are sharded
+// the same way the compactor splits them among the output blocks.
+func exemplarsForShard(exemplars []mimirpb.TimeSeries, splitJob bool, shardIndex, shardCount int) []mimirpb.TimeSeries {
+	if !splitJob || shardCount <= 1 {
+		return exemplars
+	}
+
+	var filtered []mimirpb.TimeSeries
+	for _, s := range exemplars {
+		if mimirpb.FromLabelAdaptersToLabels(s.Labels).Hash()%uint64(shardCount) == uint64(shardIndex) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
 type ulidWithShardIndex struct {
 	ulid       ulid.ULID
 	shardIndex int
@@ -648,10 +717,15 @@ type BucketCompactor struct {
 	compactDir                     string
 	bkt                            objstore.Bucket
 	concurrency                    int
+	jobsLimiter                    *semaphore.Weighted
 	skipBlocksWithOutOfOrderChunks bool
 	ownJob                         ownCompactionJobFunc
 	sortJobs                       JobsOrderFunc
 	blockSyncConcurrency           int
+	bloomFilterLabelNames          []string
+	maxMetadataPerBlock            int
+	maxExemplarsPerBlock           int
+	waitPeriod                     time.Duration
 	metrics                        *BucketCompactorMetrics
 }
 
@@ -665,10 +739,15 @@ func NewBucketCompactor(
 	compactDir string,
 	bkt objstore.Bucket,
 	concurrency int,
+	jobsLimiter *semaphore.Weighted,
 	skipBlocksWithOutOfOrderChunks bool,
 	ownJob ownCompactionJobFunc,
 	sortJobs JobsOrderFunc,
 	blockSyncConcurrency int,
+	bloomFilterLabelNames []string,
+	maxMetadataPerBlock int,
+	maxExemplarsPerBlock int,
+	waitPeriod time.Duration,
 	metrics *BucketCompactorMetrics,
 ) (*BucketCompactor, error) {
 	if concurrency <= 0 {
@@ -683,10 +762,15 @@ func NewBucketCompactor(
 		compactDir:                     compactDir,
 		bkt:                            bkt,
 		concurrency:                    concurrency,
+		jobsLimiter:                    jobsLimiter,
 		skipBlocksWithOutOfOrderChunks: skipBlocksWithOutOfOrderChunks,
 		ownJob:                         ownJob,
 		sortJobs:                       sortJobs,
 		blockSyncConcurrency:           blockSyncConcurrency,
+		bloomFilterLabelNames:          bloomFilterLabelNames,
+		maxMetadataPerBlock:            maxMetadataPerBlock,
+		maxExemplarsPerBlock:           maxExemplarsPerBlock,
+		waitPeriod:                     waitPeriod,
 		metrics:                        metrics,
 	}, nil
 }
@@ -741,9 +825,20 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 						continue
 					}
 
+					// The number of jobs running concurrently may be limited across multiple bucket compactors.
+					if c.jobsLimiter != nil {
+						if err := c.jobsLimiter.Acquire(workCtx, 1); err != nil {
+							errChan <- errors.Wrapf(err, "group %s", g.Key())
+							return
+						}
+					}
+
 					c.metrics.groupCompactionRunsStarted.Inc()
 
 					shouldRerunJob, compactedBlockIDs, err := c.runCompactionJob(workCtx, g)
+					if c.jobsLimiter != nil {
+						c.jobsLimiter.Release(1)
+					}
 					if err == nil {
 						c.metrics.groupCompactionRunsCompleted.Inc()
 						if hasNonZeroULIDs(compactedBlockIDs) {
@@ -816,6 +911,9 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 			return err
 		}
 
+		// Skip the jobs whose first-level blocks have been uploaded within the wait period.
+		jobs = c.filterJobsByWaitPeriod(ctx, jobs)
+
 		// Sort jobs based on the configured ordering algorithm.
 		jobs = c.sortJobs(jobs)
 
@@ -885,6 +983,48 @@ func (c *BucketCompactor) filterOwnJobs(jobs []*Job) ([]*Job, error) {
 	return jobs, nil
 }
 
+// filterJobsByWaitPeriod filters out the jobs containing first-level blocks uploaded within the wait period.
+func (c *BucketCompactor) filterJobsByWaitPeriod(ctx context.Context, jobs []*Job) []*Job {
+	for ix := 0; ix < len(jobs); {
+		if elapsed, notElapsedBlock, err := jobWaitPeriodElapsed(ctx, jobs[ix], c.waitPeriod, c.bkt); err != nil {
+			level.Warn(c.logger).Log("msg", "not enforcing the compaction wait period because checking the upload time of the job blocks failed", "groupKey", jobs[ix].Key(), "err", err)
+			ix++
+		} else if !elapsed {
+			level.Info(c.logger).Log("msg", "skipped compaction because the job contains blocks uploaded within the wait period", "groupKey", jobs[ix].Key(), "waitPeriod", c.waitPeriod, "block", notElapsedBlock.String())
+			jobs = append(jobs[:ix], jobs[ix+1:]...)
+		} else {
+			ix++
+		}
+	}
+	return jobs
+}
+
+// jobWaitPeriodElapsed returns whether the wait period has elapsed since the upload of all the first-level
+// blocks of the job. If not, it also returns the ID of the first block uploaded within the wait period.
+func jobWaitPeriodElapsed(ctx context.Context, job *Job, waitPeriod time.Duration, bkt objstore.Bucket) (bool, ulid.ULID, error) {
+	if waitPeriod <= 0 || job.MinCompactionLevel() > 1 {
+		return true, ulid.ULID{}, nil
+	}
+
+	threshold := time.Now().Add(-waitPeriod)
+	for _, meta := range job.metasByMinTime {
+		if meta.Compaction.Level > 1 {
+			continue
+		}
+
+		metaPath := path.Join(meta.ULID.String(), block.MetaFilename)
+		attrs, err := bkt.Attributes(ctx, metaPath)
+		if err != nil {
+			return false, meta.ULID, errors.Wrapf(err, "get attributes of %s", metaPath)
+		}
+		if attrs.LastModified.After(threshold) {
+			return false, meta.ULID, nil
+		}
+	}
+
+	return true, ulid.ULID{}, nil
+}
+
 var _ block.MetadataFilter = &NoCompactionMarkFilter{}
 
 // NoCompactionMarkFilter is a block.Fetcher filter that finds all blocks with no-compact marker files, and optionally
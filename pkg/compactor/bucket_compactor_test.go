@@ -7,14 +7,18 @@ package compactor
 
 import (
 	"context"
+	"fmt"
+	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/oklog/ulid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +28,7 @@ import (
 
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 )
 
@@ -120,7 +125,7 @@ func TestFilterOwnJobs(t *testing.T) {
 	m := NewBucketCompactorMetrics(promauto.With(nil).NewCounter(prometheus.CounterOpts{}), nil)
 	for testName, testCase := range tests {
 		t.Run(testName, func(t *testing.T) {
-			bc, err := NewBucketCompactor(log.NewNopLogger(), nil, nil, nil, nil, "", nil, 2, false, testCase.ownJob, nil, 4, m)
+			bc, err := NewBucketCompactor(log.NewNopLogger(), nil, nil, nil, nil, "", nil, 2, nil, false, testCase.ownJob, nil, 4, nil, 0, 0, 0, m)
 			require.NoError(t, err)
 
 			res, err := bc.filterOwnJobs(jobsFn())
@@ -131,6 +136,68 @@ func TestFilterOwnJobs(t *testing.T) {
 	}
 }
 
+func TestJobWaitPeriodElapsed(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	// Blocks are uploaded now, so the upload time of their meta.json is within any non-zero wait period.
+	level1 := &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(1, nil), Compaction: tsdb.BlockMetaCompaction{Level: 1}}}
+	level2 := &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(2, nil), Compaction: tsdb.BlockMetaCompaction{Level: 2}}}
+	missing := &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(3, nil), Compaction: tsdb.BlockMetaCompaction{Level: 1}}}
+	for _, meta := range []*metadata.Meta{level1, level2} {
+		require.NoError(t, bkt.Upload(ctx, path.Join(meta.ULID.String(), block.MetaFilename), strings.NewReader("{}")))
+	}
+
+	tests := map[string]struct {
+		metas           []*metadata.Meta
+		waitPeriod      time.Duration
+		expectedElapsed bool
+		expectedBlock   ulid.ULID
+		expectedErr     bool
+	}{
+		"wait period disabled": {
+			metas:           []*metadata.Meta{level1},
+			waitPeriod:      0,
+			expectedElapsed: true,
+		},
+		"first-level block uploaded within the wait period": {
+			metas:           []*metadata.Meta{level1, level2},
+			waitPeriod:      time.Hour,
+			expectedElapsed: false,
+			expectedBlock:   level1.ULID,
+		},
+		"no first-level blocks in the job": {
+			metas:           []*metadata.Meta{level2},
+			waitPeriod:      time.Hour,
+			expectedElapsed: true,
+		},
+		"first-level block meta.json missing from the bucket": {
+			metas:         []*metadata.Meta{missing},
+			waitPeriod:    time.Hour,
+			expectedBlock: missing.ULID,
+			expectedErr:   true,
+		},
+	}
+
+	for testName, testCase := range tests {
+		t.Run(testName, func(t *testing.T) {
+			job := NewJob("user", "key", nil, 0, metadata.NoneFunc, false, 0, "")
+			for _, meta := range testCase.metas {
+				require.NoError(t, job.AppendMeta(meta))
+			}
+
+			elapsed, notElapsedBlock, err := jobWaitPeriodElapsed(ctx, job, testCase.waitPeriod, bkt)
+			if testCase.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, testCase.expectedElapsed, elapsed)
+			assert.Equal(t, testCase.expectedBlock, notElapsedBlock)
+		})
+	}
+}
+
 func TestNoCompactionMarkFilter(t *testing.T) {
 	ctx := context.Background()
 	// Use bucket with global markers to make sure that our custom filters work correctly.
@@ -253,3 +320,26 @@ func TestConvertCompactionResultToForEachJobs(t *testing.T) {
 	require.Equal(t, ulidWithShardIndex{ulid: ulid1, shardIndex: 1}, res[0])
 	require.Equal(t, ulidWithShardIndex{ulid: ulid2, shardIndex: 3}, res[1])
 }
+
+func TestExemplarsForShard(t *testing.T) {
+	var exemplars []mimirpb.TimeSeries
+	for i := 0; i < 10; i++ {
+		exemplars = append(exemplars, mimirpb.TimeSeries{
+			Labels:    []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: fmt.Sprintf("series_%d", i)}},
+			Exemplars: []mimirpb.Exemplar{{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "123"}}, Value: 1, TimestampMs: int64(i)}},
+		})
+	}
+
+	// All the exemplars are kept when not splitting.
+	require.Equal(t, exemplars, exemplarsForShard(exemplars, false, 0, 1))
+
+	// Each series is kept in the shard the compactor splits it to.
+	var sharded []mimirpb.TimeSeries
+	for shardIndex := 0; shardIndex < 3; shardIndex++ {
+		for _, s := range exemplarsForShard(exemplars, true, shardIndex, 3) {
+			require.Equal(t, uint64(shardIndex), mimirpb.FromLabelAdaptersToLabels(s.Labels).Hash()%3)
+			sharded = append(sharded, s)
+		}
+	}
+	require.ElementsMatch(t, exemplars, sharded)
+}
@@ -15,11 +15,13 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/concurrency"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
@@ -28,12 +30,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/objstore"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -52,6 +56,8 @@ var (
 	errInvalidMaxOpeningBlocksConcurrency = fmt.Errorf("invalid max-opening-blocks-concurrency value, must be positive")
 	errInvalidMaxClosingBlocksConcurrency = fmt.Errorf("invalid max-closing-blocks-concurrency value, must be positive")
 	errInvalidSymbolFlushersConcurrency   = fmt.Errorf("invalid symbols-flushers-concurrency value, must be positive")
+	errInvalidTenantConcurrency           = fmt.Errorf("invalid tenant-concurrency value, must be positive")
+	errInvalidBlockUploadScannerURL       = fmt.Errorf("invalid block-upload-scanner-url value, must be an absolute URL")
 	RingOp                                = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 )
 
@@ -83,6 +89,7 @@ type Config struct {
 	CompactionInterval    time.Duration           `yaml:"compaction_interval" category:"advanced"`
 	CompactionRetries     int                     `yaml:"compaction_retries" category:"advanced"`
 	CompactionConcurrency int                     `yaml:"compaction_concurrency" category:"advanced"`
+	TenantConcurrency     int                     `yaml:"tenant_concurrency" category:"experimental"`
 	CleanupInterval       time.Duration           `yaml:"cleanup_interval" category:"advanced"`
 	CleanupConcurrency    int                     `yaml:"cleanup_concurrency" category:"advanced"`
 	DeletionDelay         time.Duration           `yaml:"deletion_delay" category:"advanced"`
@@ -97,11 +104,15 @@ type Config struct {
 	EnabledTenants  flagext.StringSliceCSV `yaml:"enabled_tenants" category:"advanced"`
 	DisabledTenants flagext.StringSliceCSV `yaml:"disabled_tenants" category:"advanced"`
 
+	BloomFilterLabelNames flagext.StringSliceCSV `yaml:"bloom_filter_label_names" category:"experimental"`
+
 	// Compactors sharding.
 	ShardingRing RingConfig `yaml:"sharding_ring"`
 
 	CompactionJobsOrder string `yaml:"compaction_jobs_order" category:"advanced"`
 
+	BlockUploadValidation BlockUploadValidationConfig `yaml:"block_upload_validation"`
+
 	// No need to add options to customize the retry backoff,
 	// given the defaults should be fine, but allow to override
 	// it in tests.
@@ -116,6 +127,7 @@ type Config struct {
 // RegisterFlags registers the MultitenantCompactor flags.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.ShardingRing.RegisterFlags(f, logger)
+	cfg.BlockUploadValidation.RegisterFlagsWithPrefix(f, "compactor.block-upload-")
 
 	cfg.BlockRanges = mimir_tsdb.DurationList{2 * time.Hour, 12 * time.Hour, 24 * time.Hour}
 	cfg.retryMinBackoff = 10 * time.Second
@@ -130,6 +142,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.MaxCompactionTime, "compactor.max-compaction-time", time.Hour, "Max time for starting compactions for a single tenant. After this time no new compactions for the tenant are started before next compaction cycle. This can help in multi-tenant environments to avoid single tenant using all compaction time, but also in single-tenant environments to force new discovery of blocks more often. 0 = disabled.")
 	f.IntVar(&cfg.CompactionRetries, "compactor.compaction-retries", 3, "How many times to retry a failed compaction within a single compaction run.")
 	f.IntVar(&cfg.CompactionConcurrency, "compactor.compaction-concurrency", 1, "Max number of concurrent compactions running.")
+	f.IntVar(&cfg.TenantConcurrency, "compactor.tenant-concurrency", 1, "Max number of tenants whose blocks metadata are synced and compaction jobs are planned concurrently. The compaction jobs of all the tenants share the -compactor.compaction-concurrency limit.")
 	f.DurationVar(&cfg.CleanupInterval, "compactor.cleanup-interval", 15*time.Minute, "How frequently compactor should run blocks cleanup and maintenance, as well as update the bucket index.")
 	f.IntVar(&cfg.CleanupConcurrency, "compactor.cleanup-concurrency", 20, "Max number of tenants for which blocks cleanup and maintenance should run concurrently.")
 	f.StringVar(&cfg.CompactionJobsOrder, "compactor.compaction-jobs-order", CompactionOrderOldestFirst, fmt.Sprintf("The sorting to use when deciding which compaction jobs should run first for a given tenant. Supported values are: %s.", strings.Join(CompactionOrders, ", ")))
@@ -144,6 +157,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.Var(&cfg.EnabledTenants, "compactor.enabled-tenants", "Comma separated list of tenants that can be compacted. If specified, only these tenants will be compacted by compactor, otherwise all tenants can be compacted. Subject to sharding.")
 	f.Var(&cfg.DisabledTenants, "compactor.disabled-tenants", "Comma separated list of tenants that cannot be compacted by this compactor. If specified, and compactor would normally pick given tenant for compaction (via -compactor.enabled-tenants or sharding), it will be ignored instead.")
+	f.Var(&cfg.BloomFilterLabelNames, "compactor.bloom-filter-label-names", "Comma separated list of label names for which the compactor builds a per-block bloom filter over the label values, stored alongside the block index. Store-gateways can use the filter to skip blocks that cannot match equality matchers on these labels. Only high-cardinality labels, like pod names or trace IDs, benefit from this.")
 }
 
 func (cfg *Config) Validate() error {
@@ -163,12 +177,15 @@ func (cfg *Config) Validate() error {
 	if cfg.SymbolsFlushersConcurrency < 1 {
 		return errInvalidSymbolFlushersConcurrency
 	}
+	if cfg.TenantConcurrency < 1 {
+		return errInvalidTenantConcurrency
+	}
 
 	if !util.StringsContain(CompactionOrders, cfg.CompactionJobsOrder) {
 		return errInvalidCompactionOrder
 	}
 
-	return nil
+	return cfg.BlockUploadValidation.Validate()
 }
 
 // ConfigProvider defines the per-tenant config provider for the MultitenantCompactor.
@@ -195,6 +212,24 @@ type ConfigProvider interface {
 
 	// CompactorBlockUploadEnabled returns whether block upload is enabled for a given tenant.
 	CompactorBlockUploadEnabled(tenantID string) bool
+
+	// CompactorAllowedTimeWindows returns the daily UTC time windows during which the compaction
+	// of the tenant's blocks may start. An empty list allows the compaction at any time.
+	CompactorAllowedTimeWindows(userID string) validation.TimeWindows
+
+	// CompactorFirstLevelCompactionWaitPeriod returns how long to wait before compacting first-level blocks
+	// of a given user, since their upload.
+	CompactorFirstLevelCompactionWaitPeriod(userID string) time.Duration
+
+	// CompactorColdStorageArchiveAfter returns how old the blocks of a given user are when moved to the cold
+	// storage. 0 to disable.
+	CompactorColdStorageArchiveAfter(userID string) time.Duration
+
+	// MaxMetadataPerBlock returns the maximum number of metric metadata persisted in each block of a given user.
+	MaxMetadataPerBlock(userID string) int
+
+	// MaxExemplarsPerBlock returns the maximum number of exemplars persisted in each block of a given user.
+	MaxExemplarsPerBlock(userID string) int
 }
 
 // MultitenantCompactor is a multi-tenant TSDB blocks compactor based on Thanos.
@@ -233,6 +268,9 @@ type MultitenantCompactor struct {
 	shardingStrategy shardingStrategy
 	jobsOrder        JobsOrderFunc
 
+	// Limits the number of compaction jobs running concurrently across all tenants.
+	compactionJobsLimiter *semaphore.Weighted
+
 	// Metrics.
 	compactionRunsStarted          prometheus.Counter
 	compactionRunsCompleted        prometheus.Counter
@@ -255,7 +293,7 @@ type MultitenantCompactor struct {
 // NewMultitenantCompactor makes a new MultitenantCompactor.
 func NewMultitenantCompactor(compactorCfg Config, storageCfg mimir_tsdb.BlocksStorageConfig, cfgProvider ConfigProvider, logger log.Logger, registerer prometheus.Registerer) (*MultitenantCompactor, error) {
 	bucketClientFactory := func(ctx context.Context) (objstore.Bucket, error) {
-		return bucket.NewClient(ctx, storageCfg.Bucket, "compactor", logger, registerer)
+		return mimir_tsdb.NewBucketClient(ctx, storageCfg, "compactor", logger, registerer)
 	}
 
 	// Configure the compactor and grouper factories.
@@ -342,6 +380,7 @@ func newMultitenantCompactor(
 	}
 
 	c.bucketCompactorMetrics = NewBucketCompactorMetrics(c.blocksMarkedForDeletion, registerer)
+	c.compactionJobsLimiter = semaphore.NewWeighted(int64(compactorCfg.CompactionConcurrency))
 
 	if len(compactorCfg.EnabledTenants) > 0 {
 		level.Info(c.logger).Log("msg", "compactor using enabled users", "enabled", strings.Join(compactorCfg.EnabledTenants, ", "))
@@ -379,6 +418,9 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 		return errors.Wrap(err, "failed to initialize compactor dependencies")
 	}
 
+	// The blocks cleaner moves the old blocks to the cold storage, if the bucket client also reads from it.
+	coldStorage, _ := c.bucketClient.(*bucket.TieredBucketClient)
+
 	// Wrap the bucket client to write block deletion marks in the global location too.
 	c.bucketClient = bucketindex.BucketWithGlobalMarkers(c.bucketClient)
 
@@ -447,6 +489,7 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 		CleanupConcurrency:      c.compactorCfg.CleanupConcurrency,
 		TenantCleanupDelay:      c.compactorCfg.TenantCleanupDelay,
 		DeleteBlocksConcurrency: defaultDeleteBlocksConcurrency,
+		ColdStorage:             coldStorage,
 	}, c.bucketClient, c.shardingStrategy.blocksCleanerOwnUser, c.cfgProvider, c.parentLogger, c.registerer)
 
 	// Start blocks cleaner asynchronously, don't wait until initial cleanup is finished.
@@ -527,47 +570,62 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 
 	// Keep track of users owned by this shard, so that we can delete the local files for all other users.
 	ownedUsers := map[string]struct{}{}
-	for _, userID := range users {
-		// Ensure the context has not been canceled (ie. compactor shutdown has been triggered).
-		if ctx.Err() != nil {
-			level.Info(c.logger).Log("msg", "interrupting compaction of user blocks", "err", err)
-			return
-		}
+	mtx := sync.Mutex{}
 
+	// Multiple tenants may be synced and planned concurrently, while the number of compaction jobs
+	// running across all tenants is limited by the compaction jobs limiter.
+	_ = concurrency.ForEachUser(ctx, users, c.compactorCfg.TenantConcurrency, func(ctx context.Context, userID string) error {
 		// Ensure the user ID belongs to our shard.
 		if owned, err := c.shardingStrategy.compactorOwnUser(userID); err != nil {
 			c.compactionRunSkippedTenants.Inc()
 			level.Warn(c.logger).Log("msg", "unable to check if user is owned by this shard", "user", userID, "err", err)
-			continue
+			return nil
 		} else if !owned {
 			c.compactionRunSkippedTenants.Inc()
 			level.Debug(c.logger).Log("msg", "skipping user because it is not owned by this shard", "user", userID)
-			continue
+			return nil
 		}
 
+		mtx.Lock()
 		ownedUsers[userID] = struct{}{}
+		mtx.Unlock()
 
 		if markedForDeletion, err := mimir_tsdb.TenantDeletionMarkExists(ctx, c.bucketClient, userID); err != nil {
 			c.compactionRunSkippedTenants.Inc()
 			level.Warn(c.logger).Log("msg", "unable to check if user is marked for deletion", "user", userID, "err", err)
-			continue
+			return nil
 		} else if markedForDeletion {
 			c.compactionRunSkippedTenants.Inc()
 			level.Debug(c.logger).Log("msg", "skipping user because it is marked for deletion", "user", userID)
-			continue
+			return nil
+		}
+
+		if windows := c.cfgProvider.CompactorAllowedTimeWindows(userID); !windows.Contains(time.Now()) {
+			c.compactionRunSkippedTenants.Inc()
+			level.Info(c.logger).Log("msg", "skipping user because the current time is outside of the user's allowed compaction time windows", "user", userID, "windows", windows.String())
+			return nil
 		}
 
 		level.Info(c.logger).Log("msg", "starting compaction of user blocks", "user", userID)
 
-		if err = c.compactUserWithRetries(ctx, userID); err != nil {
+		if err := c.compactUserWithRetries(ctx, userID); err != nil {
 			c.compactionRunFailedTenants.Inc()
+			mtx.Lock()
 			compactionErrorCount++
+			mtx.Unlock()
 			level.Error(c.logger).Log("msg", "failed to compact user blocks", "user", userID, "err", err)
-			continue
+			return nil
 		}
 
 		c.compactionRunSucceededTenants.Inc()
 		level.Info(c.logger).Log("msg", "successfully compacted user blocks", "user", userID)
+		return nil
+	})
+
+	// Ensure the context has not been canceled (ie. compactor shutdown has been triggered).
+	if ctx.Err() != nil {
+		level.Info(c.logger).Log("msg", "interrupting compaction of user blocks", "err", ctx.Err())
+		return
 	}
 
 	// Delete local files for unowned tenants, if there are any. This cleans up
@@ -677,19 +735,31 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "failed to create syncer")
 	}
 
+	compactDir := path.Join(c.compactorCfg.DataDir, "compact")
+	if c.compactorCfg.TenantConcurrency > 1 {
+		// Tenants compacted concurrently need their own working directory, because the
+		// working directory is cleaned up at the end of each tenant compaction.
+		compactDir = path.Join(compactDir, userID)
+	}
+
 	compactor, err := NewBucketCompactor(
 		ulogger,
 		syncer,
 		c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, ulogger, reg),
 		c.blocksPlanner,
 		c.blocksCompactor,
-		path.Join(c.compactorCfg.DataDir, "compact"),
+		compactDir,
 		bucket,
 		c.compactorCfg.CompactionConcurrency,
+		c.compactionJobsLimiter,
 		true, // Skip blocks with out of order chunks, and mark them for no-compaction.
 		c.shardingStrategy.ownJob,
 		c.jobsOrder,
 		c.compactorCfg.BlockSyncConcurrency,
+		c.compactorCfg.BloomFilterLabelNames,
+		c.cfgProvider.MaxMetadataPerBlock(userID),
+		c.cfgProvider.MaxExemplarsPerBlock(userID),
+		c.cfgProvider.CompactorFirstLevelCompactionWaitPeriod(userID),
 		c.bucketCompactorMetrics,
 	)
 	if err != nil {
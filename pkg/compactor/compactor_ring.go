@@ -16,8 +16,11 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/ringobserver"
 )
 
 // RingConfig masks the ring lifecycler config which contains
@@ -114,3 +117,19 @@ func (cfg *RingConfig) ToLifecyclerConfig() ring.LifecyclerConfig {
 
 	return lc
 }
+
+// NewRingWatcher returns a service watching the compactors ring, to observe its membership changes
+// without running the component itself.
+func NewRingWatcher(cfg RingConfig, logger log.Logger, reg prometheus.Registerer) (*ringobserver.Watcher, error) {
+	kvClient, err := kv.NewClient(
+		cfg.KVStore,
+		ring.GetCodec(),
+		kv.RegistererWithKVName(prometheus.WrapRegistererWithPrefix("cortex_", reg), "compactor-ring-watcher"),
+		logger,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "create KV store client")
+	}
+
+	return ringobserver.NewWatcher("compactor", CompactorRingKey, kvClient, logger), nil
+}
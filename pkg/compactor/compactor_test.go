@@ -131,6 +131,18 @@ func TestConfig_Validate(t *testing.T) {
 			setup:    func(cfg *Config) { cfg.SymbolsFlushersConcurrency = 0 },
 			expected: errInvalidSymbolFlushersConcurrency.Error(),
 		},
+		"should fail on invalid value of tenant-concurrency": {
+			setup:    func(cfg *Config) { cfg.TenantConcurrency = 0 },
+			expected: errInvalidTenantConcurrency.Error(),
+		},
+		"should fail on invalid value of block-upload-scanner-url": {
+			setup:    func(cfg *Config) { cfg.BlockUploadValidation.ScannerURL = "scanner:8080/scan" },
+			expected: errInvalidBlockUploadScannerURL.Error(),
+		},
+		"should pass with a valid block-upload-scanner-url": {
+			setup:    func(cfg *Config) { cfg.BlockUploadValidation.ScannerURL = "http://scanner:8080/scan" },
+			expected: "",
+		},
 	}
 
 	for testName, testData := range tests {
@@ -617,6 +629,60 @@ func TestMultitenantCompactor_ShouldIterateOverUsersAndRunCompaction(t *testing.
 	`), testedMetrics...))
 }
 
+func TestMultitenantCompactor_ShouldCompactUsersConcurrently(t *testing.T) {
+	t.Parallel()
+
+	const numUsers = 4
+
+	bucketClient := &bucket.ClientMock{}
+	var userIDs []string
+	for i := 1; i <= numUsers; i++ {
+		userIDs = append(userIDs, fmt.Sprintf("user-%d", i))
+	}
+
+	bucketClient.MockIter("", userIDs, nil)
+	for _, userID := range userIDs {
+		blockIDs := []string{ulid.MustNew(1, nil).String(), ulid.MustNew(2, nil).String()}
+
+		bucketClient.MockExists(path.Join(userID, mimir_tsdb.TenantDeletionMarkPath), false, nil)
+		bucketClient.MockIter(userID+"/", []string{path.Join(userID, blockIDs[0]), path.Join(userID, blockIDs[1])}, nil)
+		for _, blockID := range blockIDs {
+			bucketClient.MockGet(path.Join(userID, blockID, "meta.json"), mockBlockMetaJSON(blockID), nil)
+			bucketClient.MockGet(path.Join(userID, blockID, "deletion-mark.json"), "", nil)
+			bucketClient.MockGet(path.Join(userID, blockID, "no-compact-mark.json"), "", nil)
+		}
+		bucketClient.MockGet(path.Join(userID, "bucket-index.json.gz"), "", nil)
+		bucketClient.MockIter(userID+"/markers/", nil, nil)
+		bucketClient.MockUpload(path.Join(userID, "bucket-index.json.gz"), nil)
+	}
+
+	cfg := prepareConfig(t)
+	cfg.TenantConcurrency = 2
+	cfg.CompactionConcurrency = 1
+
+	c, _, tsdbPlanner, logs, _ := prepare(t, cfg, bucketClient)
+
+	// Mock the planner as if there's no compaction to do.
+	tsdbPlanner.On("Plan", mock.Anything, mock.Anything).Return([]*metadata.Meta{}, nil)
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+
+	// Wait until a run has completed.
+	test.Poll(t, time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+	})
+
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+
+	// Ensure a plan has been executed for the blocks of each user.
+	tsdbPlanner.AssertNumberOfCalls(t, "Plan", numUsers)
+	assert.Equal(t, float64(0), prom_testutil.ToFloat64(c.compactionRunFailedTenants))
+
+	for _, userID := range userIDs {
+		assert.Contains(t, logs.String(), fmt.Sprintf(`msg="successfully compacted user blocks" user=%s`, userID))
+	}
+}
+
 func TestMultitenantCompactor_ShouldStopCompactingTenantOnReachingMaxCompactionTime(t *testing.T) {
 	t.Parallel()
 
@@ -959,6 +1025,53 @@ func TestMultitenantCompactor_ShouldNotCompactBlocksForUsersMarkedForDeletion(t
 	`), testedMetrics...))
 }
 
+func TestMultitenantCompactor_ShouldNotCompactBlocksForUsersOutsideOfAllowedTimeWindows(t *testing.T) {
+	t.Parallel()
+
+	// Mock the bucket to contain a single user with one block.
+	bucketClient := &bucket.ClientMock{}
+	bucketClient.MockIter("", []string{"user-1"}, nil)
+	bucketClient.MockExists(path.Join("user-1", mimir_tsdb.TenantDeletionMarkPath), false, nil)
+	bucketClient.MockIter("user-1/", []string{"user-1/01DTVP434PA9VFXSW2JKB3392D"}, nil)
+	bucketClient.MockIter("user-1/markers/", nil, nil)
+	bucketClient.MockGet("user-1/01DTVP434PA9VFXSW2JKB3392D/meta.json", mockBlockMetaJSON("01DTVP434PA9VFXSW2JKB3392D"), nil)
+	bucketClient.MockGet("user-1/01DTVP434PA9VFXSW2JKB3392D/deletion-mark.json", "", nil)
+	bucketClient.MockGet("user-1/01DTVP434PA9VFXSW2JKB3392D/no-compact-mark.json", "", nil)
+	bucketClient.MockGet("user-1/bucket-index.json.gz", "", nil)
+	bucketClient.MockUpload("user-1/bucket-index.json.gz", nil)
+
+	// Allow the compaction only in a window starting a couple of hours from now.
+	now := time.Now().UTC()
+	start := time.Duration((now.Hour()+2)%24) * time.Hour
+	window := validation.TimeWindow{Start: start, End: (start + time.Hour) % (24 * time.Hour)}
+
+	limits := newMockConfigProvider()
+	limits.allowedTimeWindows = map[string]validation.TimeWindows{"user-1": {window}}
+
+	c, _, tsdbPlanner, logs, _ := prepareWithConfigProvider(t, prepareConfig(t), bucketClient, limits)
+	tsdbPlanner.On("Plan", mock.Anything, mock.Anything).Return([]*metadata.Meta{}, nil)
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+
+	// Wait until a run has completed.
+	test.Poll(t, time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+	})
+
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+
+	// The only user is outside of its allowed time windows, so it's not compacted.
+	tsdbPlanner.AssertNumberOfCalls(t, "Plan", 0)
+
+	assert.ElementsMatch(t, []string{
+		`level=info component=compactor msg="waiting until compactor is ACTIVE in the ring"`,
+		`level=info component=compactor msg="compactor is ACTIVE in the ring"`,
+		`level=info component=compactor msg="discovering users from bucket"`,
+		`level=info component=compactor msg="discovered users from bucket" users=1`,
+		fmt.Sprintf(`level=info component=compactor msg="skipping user because the current time is outside of the user's allowed compaction time windows" user=user-1 windows=%s`, window.String()),
+	}, removeIgnoredLogs(strings.Split(strings.TrimSpace(logs.String()), "\n")))
+}
+
 func TestMultitenantCompactor_ShouldCompactAllUsersOnShardingEnabledButOnlyOneInstanceRunning(t *testing.T) {
 	t.Parallel()
 
@@ -99,6 +99,17 @@ func (job *Job) MaxTime() int64 {
 	return max
 }
 
+// MinCompactionLevel returns the minimum compaction level across all the job's blocks.
+func (job *Job) MinCompactionLevel() int {
+	min := math.MaxInt
+	for _, m := range job.metasByMinTime {
+		if m.Compaction.Level < min {
+			min = m.Compaction.Level
+		}
+	}
+	return min
+}
+
 // Labels returns the external labels for the output block(s) of this job.
 func (job *Job) Labels() labels.Labels {
 	return job.labels
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type TenantStorageStatsResponse struct {
+	TenantID                      string         `json:"tenant_id"`
+	BlocksCount                   int            `json:"blocks_count"`
+	BlocksMarkedForDeletionCount  int            `json:"blocks_marked_for_deletion_count"`
+	SizeBytes                     int64          `json:"size_bytes"`
+	BlocksCountByCompactionLevel  map[string]int `json:"blocks_count_by_compaction_level"`
+	MinTime                       int64          `json:"min_time"`
+	MaxTime                       int64          `json:"max_time"`
+	BucketIndexUpdatedAtTimestamp int64          `json:"bucket_index_updated_at"`
+}
+
+// TenantStorageStats returns the storage statistics of the tenant, computed from its bucket index. The statistics
+// are empty if the tenant has no bucket index yet.
+func (c *MultitenantCompactor) TenantStorageStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := TenantStorageStatsResponse{
+		TenantID:                     userID,
+		BlocksCountByCompactionLevel: map[string]int{},
+	}
+
+	idx, err := bucketindex.ReadIndex(ctx, c.bucketClient, userID, c.cfgProvider, c.logger)
+	if errors.Is(err, bucketindex.ErrIndexNotFound) {
+		util.WriteJSONResponse(w, result)
+		return
+	}
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to read bucket index", "user", userID, "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stats := idx.Stats()
+	result.BlocksCount = stats.Blocks
+	result.BlocksMarkedForDeletionCount = stats.BlocksMarkedForDeletion
+	result.SizeBytes = stats.BlocksBytes
+	result.MinTime = stats.MinTime
+	result.MaxTime = stats.MaxTime
+	result.BucketIndexUpdatedAtTimestamp = idx.UpdatedAt
+	for compactionLevel, count := range stats.BlocksByCompactionLevel {
+		result.BlocksCountByCompactionLevel[strconv.Itoa(compactionLevel)] = count
+	}
+
+	util.WriteJSONResponse(w, result)
+}
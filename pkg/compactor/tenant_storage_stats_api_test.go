@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestTenantStorageStats(t *testing.T) {
+	const userID = "user-1"
+
+	bkt := objstore.NewInMemBucket()
+	cfg := prepareConfig(t)
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	{
+		resp := httptest.NewRecorder()
+		c.TenantStorageStats(resp, &http.Request{})
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	}
+
+	{
+		// The stats are empty if the tenant has no bucket index.
+		req := (&http.Request{}).WithContext(user.InjectOrgID(context.Background(), userID))
+		resp := httptest.NewRecorder()
+		c.TenantStorageStats(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{
+			"tenant_id": "user-1",
+			"blocks_count": 0,
+			"blocks_marked_for_deletion_count": 0,
+			"size_bytes": 0,
+			"blocks_count_by_compaction_level": {},
+			"min_time": 0,
+			"max_time": 0,
+			"bucket_index_updated_at": 0
+		}`, resp.Body.String())
+	}
+
+	{
+		block1 := ulid.MustNew(1, nil)
+		block2 := ulid.MustNew(2, nil)
+		block3 := ulid.MustNew(3, nil)
+
+		idx := &bucketindex.Index{
+			Version: bucketindex.IndexVersion3,
+			Blocks: bucketindex.Blocks{
+				{ID: block1, MinTime: 10, MaxTime: 20, SizeBytes: 100, CompactionLevel: 1},
+				{ID: block2, MinTime: 20, MaxTime: 30, SizeBytes: 200, CompactionLevel: 1},
+				{ID: block3, MinTime: 10, MaxTime: 30, SizeBytes: 250, CompactionLevel: 2},
+			},
+			BlockDeletionMarks: bucketindex.BlockDeletionMarks{
+				{ID: block1, DeletionTime: time.Now().Unix()},
+			},
+			UpdatedAt: 1234,
+		}
+		require.NoError(t, bucketindex.WriteIndex(context.Background(), bkt, userID, nil, idx))
+
+		req := (&http.Request{}).WithContext(user.InjectOrgID(context.Background(), userID))
+		resp := httptest.NewRecorder()
+		c.TenantStorageStats(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{
+			"tenant_id": "user-1",
+			"blocks_count": 3,
+			"blocks_marked_for_deletion_count": 1,
+			"size_bytes": 550,
+			"blocks_count_by_compaction_level": {"1": 2, "2": 1},
+			"min_time": 10,
+			"max_time": 30,
+			"bucket_index_updated_at": 1234
+		}`, resp.Body.String())
+	}
+}
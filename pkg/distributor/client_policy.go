@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/mtime"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/push"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// clientPolicyMiddleware enforces the per-tenant ingestion client policies, matching them against the
+// HTTP headers of the push request. Requests not received through the HTTP push handler are not affected.
+func (d *Distributor) clientPolicyMiddleware(next push.Func) push.Func {
+	return func(ctx context.Context, req *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		policies := d.limits.IngestionClientPolicies(userID)
+		headers := push.RequestHeadersFromContext(ctx)
+		if len(policies) == 0 || headers == nil {
+			return next(ctx, req, cleanup)
+		}
+
+		for idx, policy := range policies {
+			value := headers.Get(policy.Header)
+			if !policy.Matches(value) {
+				continue
+			}
+
+			switch policy.Action {
+			case validation.ClientPolicyActionDeny:
+				cleanup()
+				d.discardRequest(validation.ReasonIngestionClientDenied, userID, req)
+				return nil, httpgrpc.Errorf(http.StatusForbidden, validation.NewIngestionClientDeniedError(policy.Header, value).Error())
+
+			case validation.ClientPolicyActionRateLimit:
+				if !d.clientPolicyLimiters.allow(mtime.Now(), userID, idx, policy) {
+					cleanup()
+					d.discardRequest(validation.ReasonIngestionClientRateLimited, userID, req)
+					return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewIngestionClientRateLimitedError(policy.Header, policy.Regex, policy.Rate, policy.Burst).Error())
+				}
+			}
+
+			// The first matching policy applies.
+			break
+		}
+
+		return next(ctx, req, cleanup)
+	}
+}
+
+type clientPolicyLimiterKey struct {
+	userID string
+	policy int
+}
+
+// clientPolicyLimiters holds the rate limiters of the ingestion client policies with the ratelimit action,
+// one for each tenant and policy. The limits are local to each distributor.
+type clientPolicyLimiters struct {
+	mtx      sync.Mutex
+	limiters map[clientPolicyLimiterKey]*rate.Limiter
+}
+
+func newClientPolicyLimiters() *clientPolicyLimiters {
+	return &clientPolicyLimiters{limiters: map[clientPolicyLimiterKey]*rate.Limiter{}}
+}
+
+// allow returns whether a request matching the tenant's policy at the given index is allowed by the policy rate limit.
+func (l *clientPolicyLimiters) allow(now time.Time, userID string, idx int, policy validation.ClientPolicy) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	key := clientPolicyLimiterKey{userID: userID, policy: idx}
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(policy.Rate), policy.Burst)
+		l.limiters[key] = limiter
+	}
+
+	// The policies may have been changed by a runtime config reload.
+	if limiter.Limit() != rate.Limit(policy.Rate) {
+		limiter.SetLimitAt(now, rate.Limit(policy.Rate))
+	}
+	if limiter.Burst() != policy.Burst {
+		limiter.SetBurstAt(now, policy.Burst)
+	}
+
+	return limiter.AllowN(now, 1)
+}
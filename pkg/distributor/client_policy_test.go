@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/util/push"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestDistributor_PushIngestionClientPolicies(t *testing.T) {
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- regex: good-agent/.*
+  action: allow
+- regex: .*agent/.*
+  action: deny
+- header: X-Client-ID
+  regex: noisy
+  action: ratelimit
+  rate: 0.1
+  burst: 1
+`), &limits.IngestionClientPolicies))
+
+	distributors, _, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+		limits:          limits,
+	})
+	handler := push.Handler(100000, nil, false, distributors[0].PushWithMiddlewares)
+
+	body, err := makeWriteRequest(0, 1, 0, false).Marshal()
+	require.NoError(t, err)
+
+	doPush := func(headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/push", bytes.NewReader(snappy.Encode(nil, body)))
+		req = req.WithContext(user.InjectOrgID(context.Background(), "user"))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// The first matching policy applies.
+	assert.Equal(t, http.StatusOK, doPush(map[string]string{"User-Agent": "good-agent/1.0"}))
+	assert.Equal(t, http.StatusForbidden, doPush(map[string]string{"User-Agent": "bad-agent/1.0"}))
+
+	// Requests not matching any policy are accepted.
+	assert.Equal(t, http.StatusOK, doPush(map[string]string{"User-Agent": "Prometheus/2.38.0"}))
+
+	// Requests matching the ratelimit policy are rejected once exceeding the burst.
+	assert.Equal(t, http.StatusOK, doPush(map[string]string{"X-Client-ID": "noisy"}))
+	assert.Equal(t, http.StatusTooManyRequests, doPush(map[string]string{"X-Client-ID": "noisy"}))
+	assert.Equal(t, http.StatusOK, doPush(map[string]string{"X-Client-ID": "quiet"}))
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package deadletter
+
+import (
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// Config configures the dead-letter storage, where the distributor writes the series it rejected.
+type Config struct {
+	Enabled                    bool          `yaml:"enabled" category:"experimental"`
+	FlushPeriod                time.Duration `yaml:"flush_period" category:"experimental"`
+	MaxBufferedSeriesPerTenant int           `yaml:"max_buffered_series_per_tenant" category:"experimental"`
+	Storage                    bucket.Config `yaml:"storage"`
+}
+
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, "distributor.dead-letter.enabled", false, "Enables the feature to write the series rejected by the distributor validation or by the request and ingestion rate limits, together with the rejection reason, to the dead-letter storage. The samples rejected by the ingesters are not written. The feature needs to be enabled for each tenant with -distributor.rejected-series-dead-letter-enabled.")
+	f.DurationVar(&c.FlushPeriod, "distributor.dead-letter.flush-period", time.Minute, "How frequently the rejected series buffered in memory are written to the dead-letter storage.")
+	f.IntVar(&c.MaxBufferedSeriesPerTenant, "distributor.dead-letter.max-buffered-series-per-tenant", 10000, "Maximum number of rejected series buffered in memory for each tenant between two flushes. Rejected series exceeding the limit are not written to the dead-letter storage.")
+	c.Storage.RegisterFlagsWithPrefixAndDefaultDirectory("distributor.dead-letter.storage.", "dead-letter", f)
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.FlushPeriod <= 0 {
+		return errors.New("distributor.dead-letter.flush-period must be greater than 0")
+	}
+	if c.MaxBufferedSeriesPerTenant < 1 {
+		return errors.New("distributor.dead-letter.max-buffered-series-per-tenant must be greater than 0")
+	}
+	return c.Storage.Validate()
+}
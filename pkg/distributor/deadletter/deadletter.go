@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package deadletter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// ObjectExtension is the extension of the objects written to the dead-letter storage. Each object
+// contains the rejected series of a single tenant, one JSON-encoded Series per line.
+const ObjectExtension = ".ndjson"
+
+type Writer interface {
+	services.Service
+	Write(userID string, ts mimirpb.PreallocTimeseries, reason string)
+}
+
+// Series is a rejected series, as written to the dead-letter storage.
+type Series struct {
+	Labels     string   `json:"labels"`
+	Samples    []Sample `json:"samples,omitempty"`
+	Reason     string   `json:"reason"`
+	RejectedAt int64    `json:"rejected_at_ms"`
+}
+
+type Sample struct {
+	TimestampMs int64   `json:"timestamp_ms"`
+	Value       float64 `json:"value"`
+}
+
+type writer struct {
+	services.Service
+
+	cfg    Config
+	bucket objstore.Bucket
+	log    log.Logger
+
+	mtx    sync.Mutex
+	series map[string][]Series
+
+	seriesWrittenTotal prometheus.Counter
+	seriesDroppedTotal prometheus.Counter
+	flushFailuresTotal prometheus.Counter
+}
+
+// NewWriter returns a new dead-letter writer, if the dead-letter is disabled it returns nil.
+func NewWriter(cfg Config, reg prometheus.Registerer, log log.Logger) (Writer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	bkt, err := bucket.NewClient(context.Background(), cfg.Storage, "distributor-dead-letter", log, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create dead-letter storage bucket client")
+	}
+
+	return newWriter(cfg, bkt, reg, log), nil
+}
+
+func newWriter(cfg Config, bkt objstore.Bucket, reg prometheus.Registerer, log log.Logger) *writer {
+	w := &writer{
+		cfg:    cfg,
+		bucket: bkt,
+		log:    log,
+		series: map[string][]Series{},
+
+		seriesWrittenTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_dead_letter_series_written_total",
+			Help:      "The total number of rejected series the distributor wrote to the dead-letter storage.",
+		}),
+		seriesDroppedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_dead_letter_series_dropped_total",
+			Help:      "The total number of rejected series the distributor didn't write to the dead-letter storage because the tenant's buffer was full or the write failed.",
+		}),
+		flushFailuresTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_dead_letter_flush_failures_total",
+			Help:      "The total number of failures writing rejected series to the dead-letter storage.",
+		}),
+	}
+
+	w.Service = services.NewTimerService(cfg.FlushPeriod, nil, w.iteration, w.stop)
+	return w
+}
+
+// Write buffers a rejected series to be written to the dead-letter storage at the next flush.
+// The series is copied, so the input can be reused once the function returns.
+func (w *writer) Write(userID string, ts mimirpb.PreallocTimeseries, reason string) {
+	series := Series{
+		Labels:     mimirpb.FromLabelAdaptersToLabels(ts.Labels).String(),
+		Samples:    make([]Sample, 0, len(ts.Samples)),
+		Reason:     reason,
+		RejectedAt: time.Now().UnixMilli(),
+	}
+	for _, s := range ts.Samples {
+		series.Samples = append(series.Samples, Sample{TimestampMs: s.TimestampMs, Value: s.Value})
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if len(w.series[userID]) >= w.cfg.MaxBufferedSeriesPerTenant {
+		w.seriesDroppedTotal.Inc()
+		return
+	}
+	w.series[userID] = append(w.series[userID], series)
+}
+
+func (w *writer) iteration(ctx context.Context) error {
+	w.flush(ctx)
+	return nil
+}
+
+func (w *writer) stop(_ error) error {
+	// Write the rejected series still buffered before shutting down.
+	w.flush(context.Background())
+	return nil
+}
+
+func (w *writer) flush(ctx context.Context) {
+	w.mtx.Lock()
+	toFlush := w.series
+	w.series = map[string][]Series{}
+	w.mtx.Unlock()
+
+	for userID, series := range toFlush {
+		if err := w.upload(ctx, userID, series); err != nil {
+			level.Warn(w.log).Log("msg", "failed to write rejected series to the dead-letter storage", "user", userID, "series", len(series), "err", err)
+			w.flushFailuresTotal.Inc()
+			w.seriesDroppedTotal.Add(float64(len(series)))
+			continue
+		}
+		w.seriesWrittenTotal.Add(float64(len(series)))
+	}
+}
+
+func (w *writer) upload(ctx context.Context, userID string, series []Series) error {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	for _, s := range series {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+
+	// The object name is a ULID, so that objects are sorted by time and never clash between distributors.
+	name := ulid.MustNew(ulid.Now(), rand.Reader).String() + ObjectExtension
+	return bucket.NewUserBucketClient(userID, w.bucket, nil).Upload(ctx, name, &buf)
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package deadletter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestWriter(t *testing.T) {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	cfg.Enabled = true
+	cfg.FlushPeriod = time.Hour
+	cfg.MaxBufferedSeriesPerTenant = 2
+
+	bkt := objstore.NewInMemBucket()
+	reg := prometheus.NewPedanticRegistry()
+	w := newWriter(cfg, bkt, reg, log.NewNopLogger())
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), w))
+
+	series := func(name string) mimirpb.PreallocTimeseries {
+		return mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("__name__", name)),
+			Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}},
+		}}
+	}
+
+	w.Write("user-1", series("foo"), "invalid sample")
+	w.Write("user-1", series("bar"), "invalid sample")
+	w.Write("user-1", series("baz"), "dropped because the buffer is full")
+	w.Write("user-2", series("foo"), "another reason")
+
+	// The buffered series are written when stopping.
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), w))
+
+	readSeries := func(userID string) []Series {
+		var out []Series
+		require.NoError(t, bkt.Iter(context.Background(), userID+"/", func(name string) error {
+			assert.True(t, strings.HasSuffix(name, ObjectExtension), name)
+
+			r, err := bkt.Get(context.Background(), name)
+			require.NoError(t, err)
+			defer r.Close()
+
+			scanner := bufio.NewScanner(r)
+			for scanner.Scan() {
+				var s Series
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &s))
+				assert.NotZero(t, s.RejectedAt)
+				s.RejectedAt = 0
+				out = append(out, s)
+			}
+			return scanner.Err()
+		}))
+		return out
+	}
+
+	assert.Equal(t, []Series{
+		{Labels: `{__name__="foo"}`, Samples: []Sample{{TimestampMs: 1000, Value: 1}}, Reason: "invalid sample"},
+		{Labels: `{__name__="bar"}`, Samples: []Sample{{TimestampMs: 1000, Value: 1}}, Reason: "invalid sample"},
+	}, readSeries("user-1"))
+	assert.Equal(t, []Series{
+		{Labels: `{__name__="foo"}`, Samples: []Sample{{TimestampMs: 1000, Value: 1}}, Reason: "another reason"},
+	}, readSeries("user-2"))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_dead_letter_series_written_total The total number of rejected series the distributor wrote to the dead-letter storage.
+		# TYPE cortex_distributor_dead_letter_series_written_total counter
+		cortex_distributor_dead_letter_series_written_total 3
+		# HELP cortex_distributor_dead_letter_series_dropped_total The total number of rejected series the distributor didn't write to the dead-letter storage because the tenant's buffer was full or the write failed.
+		# TYPE cortex_distributor_dead_letter_series_dropped_total counter
+		cortex_distributor_dead_letter_series_dropped_total 1
+	`), "cortex_distributor_dead_letter_series_written_total", "cortex_distributor_dead_letter_series_dropped_total"))
+}
@@ -13,6 +13,7 @@ import (
 	"math"
 	"net/http"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -40,6 +41,7 @@ import (
 
 	"github.com/grafana/dskit/tenant"
 
+	"github.com/grafana/mimir/pkg/distributor/deadletter"
 	"github.com/grafana/mimir/pkg/distributor/forwarding"
 	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
@@ -91,6 +93,7 @@ type Distributor struct {
 	ingesterPool  *ring_client.Pool
 	limits        *validation.Overrides
 	forwarder     forwarding.Forwarder
+	deadLetter    deadletter.Writer
 
 	// The global rate limiter requires a distributors ring to count
 	// the number of healthy instances
@@ -105,6 +108,12 @@ type Distributor struct {
 	requestRateLimiter   *limiter.RateLimiter
 	ingestionRateLimiter *limiter.RateLimiter
 
+	// Per-user and per-policy rate limiters of the ingestion client policies.
+	clientPolicyLimiters *clientPolicyLimiters
+
+	// Live tail subscriptions of the incoming series.
+	liveTailer *liveTailer
+
 	// Manager for subservices (HA Tracker, distributor ring, forwarder and client pool)
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -119,6 +128,7 @@ type Distributor struct {
 	queryDuration                    *instrument.HistogramCollector
 	ingesterChunksDeduplicated       prometheus.Counter
 	ingesterChunksTotal              prometheus.Counter
+	partialResults                   prometheus.Counter
 	receivedRequests                 *prometheus.CounterVec
 	receivedSamples                  *prometheus.CounterVec
 	receivedExemplars                *prometheus.CounterVec
@@ -165,6 +175,9 @@ type Config struct {
 
 	// Configuration for forwarding of metrics to alternative ingestion endpoint.
 	Forwarding forwarding.Config
+
+	// Configuration for writing the rejected series to a dead-letter storage.
+	DeadLetter deadletter.Config `yaml:"dead_letter"`
 }
 
 type InstanceLimits struct {
@@ -179,6 +192,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.HATrackerConfig.RegisterFlags(f)
 	cfg.DistributorRing.RegisterFlags(f, logger)
 	cfg.Forwarding.RegisterFlags(f)
+	cfg.DeadLetter.RegisterFlags(f)
 
 	f.IntVar(&cfg.MaxRecvMsgSize, "distributor.max-recv-msg-size", 100<<20, "Max message size in bytes that the distributors will accept for incoming push requests to the remote write API. If exceeded, the request will be rejected.")
 	f.DurationVar(&cfg.RemoteTimeout, "distributor.remote-timeout", 20*time.Second, "Timeout for downstream ingesters.")
@@ -198,7 +212,11 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 		return err
 	}
 
-	return cfg.Forwarding.Validate()
+	if err := cfg.Forwarding.Validate(); err != nil {
+		return err
+	}
+
+	return cfg.DeadLetter.Validate()
 }
 
 const (
@@ -251,6 +269,11 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 			Name:      "distributor_query_ingester_chunks_total",
 			Help:      "Number of chunks transferred at query time from ingesters.",
 		}),
+		partialResults: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_query_partial_results_total",
+			Help:      "Number of read requests served with partial results from the ingesters of the available zones, because too many zones are unavailable.",
+		}),
 		receivedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Namespace: "cortex",
 			Name:      "distributor_received_requests_total",
@@ -395,6 +418,8 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 
 	d.requestRateLimiter = limiter.NewRateLimiter(requestRateStrategy, 10*time.Second)
 	d.ingestionRateLimiter = limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second)
+	d.clientPolicyLimiters = newClientPolicyLimiters()
+	d.liveTailer = newLiveTailer()
 	d.distributorsLifecycler = distributorsLifecycler
 	d.distributorsRing = distributorsRing
 
@@ -407,6 +432,15 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		subservices = append(subservices, d.forwarder)
 	}
 
+	// The dead-letter is an optional feature, if it's disabled then d.deadLetter will be nil.
+	d.deadLetter, err = deadletter.NewWriter(cfg.DeadLetter, reg, log)
+	if err != nil {
+		return nil, err
+	}
+	if d.deadLetter != nil {
+		subservices = append(subservices, d.deadLetter)
+	}
+
 	d.PushWithMiddlewares = d.wrapPushWithMiddlewares(d.PushWithCleanup)
 
 	subservices = append(subservices, d.ingesterPool, d.activeUsers)
@@ -515,7 +549,7 @@ func (d *Distributor) stopping(_ error) error {
 }
 
 func (d *Distributor) tokenForLabels(userID string, labels []mimirpb.LabelAdapter) (uint32, error) {
-	return shardByAllLabels(userID, labels), nil
+	return ingester_client.ShardByAllLabels(userID, labels), nil
 }
 
 func (d *Distributor) tokenForMetadata(userID string, metricName string) uint32 {
@@ -536,16 +570,6 @@ func shardByUser(userID string) uint32 {
 	return h
 }
 
-// This function generates different values for different order of same labels.
-func shardByAllLabels(userID string, labels []mimirpb.LabelAdapter) uint32 {
-	h := shardByUser(userID)
-	for _, label := range labels {
-		h = ingester_client.HashAdd32(h, label.Name)
-		h = ingester_client.HashAdd32(h, label.Value)
-	}
-	return h
-}
-
 // Remove the label labelname from a slice of LabelPairs if it exists.
 func removeLabel(labelName string, labels *[]mimirpb.LabelAdapter) {
 	for i := 0; i < len(*labels); i++ {
@@ -642,8 +666,12 @@ func (d *Distributor) wrapPushWithMiddlewares(next push.Func) push.Func {
 	// result from previous call.
 	middlewares = append(middlewares, d.instanceLimitsMiddleware) // should run first
 	middlewares = append(middlewares, d.metricsMiddleware)
+	middlewares = append(middlewares, d.maintenanceModeMiddleware)
+	middlewares = append(middlewares, d.clientPolicyMiddleware)
+	middlewares = append(middlewares, d.requestLimitsMiddleware)
 	middlewares = append(middlewares, d.prePushHaDedupeMiddleware)
 	middlewares = append(middlewares, d.prePushRelabelMiddleware)
+	middlewares = append(middlewares, d.liveTailMiddleware)
 	middlewares = append(middlewares, d.prePushForwardingMiddleware)
 
 	for ix := len(middlewares) - 1; ix >= 0; ix-- {
@@ -843,6 +871,101 @@ func (d *Distributor) metricsMiddleware(next push.Func) push.Func {
 	}
 }
 
+// maintenanceModeMiddleware rejects all push requests for tenants whose ingestion is paused for maintenance.
+// The request is rejected with a 5xx status code, so that the client keeps the data and retries it later.
+func (d *Distributor) maintenanceModeMiddleware(next push.Func) push.Func {
+	return func(ctx context.Context, req *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		if d.limits.IngestionMaintenanceMode(userID) {
+			cleanup()
+			return nil, newIngestionMaintenanceModeError(d.limits.IngestionMaintenanceRetryAfter(userID))
+		}
+
+		return next(ctx, req, cleanup)
+	}
+}
+
+func newIngestionMaintenanceModeError(retryAfter time.Duration) error {
+	return httpgrpc.ErrorFromHTTPResponse(&httpgrpc.HTTPResponse{
+		Code: http.StatusServiceUnavailable,
+		Body: []byte(validation.NewIngestionMaintenanceModeError(retryAfter).Error()),
+		Headers: []*httpgrpc.Header{
+			{Key: "Retry-After", Values: []string{strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))}},
+		},
+	})
+}
+
+// requestLimitsMiddleware checks the per-tenant limits on the size of a single push request and
+// rejects the request if it's too large.
+func (d *Distributor) requestLimitsMiddleware(next push.Func) push.Func {
+	return func(ctx context.Context, req *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+		cleanupInDefer := true
+		defer func() {
+			if cleanupInDefer {
+				cleanup()
+			}
+		}()
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if limit := d.limits.MaxSeriesPerRequest(userID); limit > 0 && len(req.Timeseries) > limit {
+			d.discardRequest(validation.ReasonMaxSeriesPerRequest, userID, req)
+			return nil, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, validation.NewMaxSeriesPerRequestError(len(req.Timeseries), limit).Error())
+		}
+
+		if limit := d.limits.MaxLabelsBytesPerRequest(userID); limit > 0 {
+			if size := labelsBytes(req.Timeseries); size > limit {
+				d.discardRequest(validation.ReasonMaxLabelsBytesPerRequest, userID, req)
+				return nil, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, validation.NewMaxLabelsBytesPerRequestError(size, limit).Error())
+			}
+		}
+
+		if limit := d.limits.MaxPushRequestBytes(userID); limit > 0 {
+			if size := req.Size(); size > limit {
+				d.discardRequest(validation.ReasonMaxPushRequestBytes, userID, req)
+				return nil, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, validation.NewMaxPushRequestBytesError(size, limit).Error())
+			}
+		}
+
+		cleanupInDefer = false
+		return next(ctx, req, cleanup)
+	}
+}
+
+// labelsBytes returns the total size in bytes of the label names and values of the input series.
+func labelsBytes(timeseries []mimirpb.PreallocTimeseries) int {
+	size := 0
+	for _, ts := range timeseries {
+		for _, l := range ts.Labels {
+			size += len(l.Name) + len(l.Value)
+		}
+	}
+	return size
+}
+
+// discardRequest tracks the whole request, and all the data it contains, as discarded for the given reason.
+func (d *Distributor) discardRequest(reason, userID string, req *mimirpb.WriteRequest) {
+	numSamples := 0
+	numExemplars := 0
+	for _, ts := range req.Timeseries {
+		numSamples += len(ts.Samples)
+		numExemplars += len(ts.Exemplars)
+	}
+
+	validation.DiscardedRequests.WithLabelValues(reason, userID).Inc()
+	validation.DiscardedSamples.WithLabelValues(reason, userID).Add(float64(numSamples))
+	validation.DiscardedExemplars.WithLabelValues(reason, userID).Add(float64(numExemplars))
+	validation.DiscardedMetadata.WithLabelValues(reason, userID).Add(float64(len(req.Metadata)))
+}
+
 // instanceLimitsMiddleware checks for instance limits and rejects request if this instance cannot process it at the moment.
 func (d *Distributor) instanceLimitsMiddleware(next push.Func) push.Func {
 	return func(ctx context.Context, req *mimirpb.WriteRequest, callerCleanup func()) (*mimirpb.WriteResponse, error) {
@@ -929,10 +1052,13 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 	if !d.requestRateLimiter.AllowN(now, userID, 1) {
 		validation.DiscardedRequests.WithLabelValues(validation.ReasonRateLimited, userID).Add(1)
 
+		rateLimitedErr := validation.NewRequestRateLimitedError(d.limits.RequestRate(userID), d.limits.RequestBurstSize(userID))
+		d.writeDeadLetter(userID, req.Timeseries, rateLimitedErr.Error())
+
 		// Return a 429 here to tell the client it is going too fast.
 		// Client may discard the data or slow down and re-send.
 		// Prometheus v2.26 added a remote-write option 'retry_on_http_429'.
-		return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewRequestRateLimitedError(d.limits.RequestRate(userID), d.limits.RequestBurstSize(userID)).Error())
+		return nil, httpgrpc.Errorf(http.StatusTooManyRequests, rateLimitedErr.Error())
 	}
 
 	d.receivedRequests.WithLabelValues(userID).Add(1)
@@ -1000,6 +1126,9 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 				// use case because we format it calling Error() and then we discard it.
 				firstPartialErr = httpgrpc.Errorf(http.StatusBadRequest, validationErr.Error())
 			}
+			if d.deadLetterEnabled(userID) {
+				d.deadLetter.Write(userID, ts, validationErr.Error())
+			}
 			continue
 		}
 
@@ -1037,10 +1166,14 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 		validation.DiscardedSamples.WithLabelValues(validation.ReasonRateLimited, userID).Add(float64(validatedSamples))
 		validation.DiscardedExemplars.WithLabelValues(validation.ReasonRateLimited, userID).Add(float64(validatedExemplars))
 		validation.DiscardedMetadata.WithLabelValues(validation.ReasonRateLimited, userID).Add(float64(len(validatedMetadata)))
+
+		rateLimitedErr := validation.NewIngestionRateLimitedError(d.limits.IngestionRate(userID), d.limits.IngestionBurstSize(userID))
+		d.writeDeadLetter(userID, validatedTimeseries, rateLimitedErr.Error())
+
 		// Return a 429 here to tell the client it is going too fast.
 		// Client may discard the data or slow down and re-send.
 		// Prometheus v2.26 added a remote-write option 'retry_on_http_429'.
-		return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewIngestionRateLimitedError(d.limits.IngestionRate(userID), d.limits.IngestionBurstSize(userID)).Error())
+		return nil, httpgrpc.Errorf(http.StatusTooManyRequests, rateLimitedErr.Error())
 	}
 
 	// totalN included samples and metadata. Ingester follows this pattern when computing its ingestion rate.
@@ -1061,6 +1194,10 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 	keys := append(seriesKeys, metadataKeys...)
 	initialMetadataIndex := len(seriesKeys)
 
+	// The validation can only drop samples, so the earliest and latest sample timestamps computed on the
+	// received request are still bounds of the samples sent to each ingester.
+	hints := timestampHints{set: earliestSampleTimestampMs != math.MaxInt64, minTimestampMs: earliestSampleTimestampMs, maxTimestampMs: latestSampleTimestampMs}
+
 	// we must not re-use buffers now until all DoBatch goroutines have finished,
 	// so set this flag false and pass cleanup() to DoBatch.
 	cleanupInDefer = false
@@ -1077,7 +1214,7 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 			}
 		}
 
-		return d.send(localCtx, ingester, timeseries, metadata, req.Source)
+		return d.send(localCtx, ingester, timeseries, metadata, req.Source, hints)
 	}, func() { cleanup(); cancel() })
 
 	if err != nil {
@@ -1086,6 +1223,23 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 	return &mimirpb.WriteResponse{}, firstPartialErr
 }
 
+// deadLetterEnabled returns whether the series rejected for the tenant are written to the dead-letter storage.
+func (d *Distributor) deadLetterEnabled(userID string) bool {
+	return d.deadLetter != nil && d.limits.RejectedSeriesDeadLetterEnabled(userID)
+}
+
+// writeDeadLetter writes the series rejected for the given reason to the dead-letter storage, if enabled for the tenant.
+func (d *Distributor) writeDeadLetter(userID string, series []mimirpb.PreallocTimeseries, reason string) {
+	if !d.deadLetterEnabled(userID) {
+		return
+	}
+	for _, ts := range series {
+		if len(ts.Labels) > 0 {
+			d.deadLetter.Write(userID, ts, reason)
+		}
+	}
+}
+
 func copyString(s string) string {
 	return string([]byte(s))
 }
@@ -1112,7 +1266,15 @@ func sortLabelsIfNeeded(labels []mimirpb.LabelAdapter) {
 	})
 }
 
-func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, timeseries []mimirpb.PreallocTimeseries, metadata []*mimirpb.MetricMetadata, source mimirpb.WriteRequest_SourceEnum) error {
+// timestampHints are the bounds of the timestamps of the samples pushed to the ingesters, which allow the
+// ingesters to skip the per-sample bound checks.
+type timestampHints struct {
+	set            bool
+	minTimestampMs int64
+	maxTimestampMs int64
+}
+
+func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, timeseries []mimirpb.PreallocTimeseries, metadata []*mimirpb.MetricMetadata, source mimirpb.WriteRequest_SourceEnum, hints timestampHints) error {
 	h, err := d.ingesterPool.GetClientFor(ingester.Addr)
 	if err != nil {
 		return err
@@ -1124,6 +1286,11 @@ func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, time
 		Metadata:   metadata,
 		Source:     source,
 	}
+	if hints.set && len(timeseries) > 0 {
+		req.MinTimestampMs = hints.minTimestampMs
+		req.MaxTimestampMs = hints.maxTimestampMs
+		req.TimestampHintsSet = true
+	}
 	_, err = c.Push(ctx, &req)
 
 	return err
@@ -1512,6 +1679,49 @@ func (d *Distributor) MetricsForLabelMatchers(ctx context.Context, from, through
 	return result, nil
 }
 
+// ActiveSeries returns the series of the tenant matching the matchers which are tracked as active by the ingesters,
+// sorted by labels. If limit is greater than 0, only the first limit series are returned: each ingester only returns
+// its first limit series too, which is enough to find the first limit series across all of them.
+func (d *Distributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher, limit int) ([]labels.Labels, error) {
+	replicationSet, err := d.GetIngesters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reqMatchers, err := ingester_client.ToLabelMatchers(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ingester_client.ActiveSeriesRequest{Matchers: reqMatchers, Limit: int32(limit)}
+	resps, err := d.forReplicationSet(ctx, replicationSet, func(ctx context.Context, client ingester_client.IngesterClient) (interface{}, error) {
+		return client.ActiveSeries(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := map[uint64]labels.Labels{}
+	for _, resp := range resps {
+		for _, m := range resp.(*ingester_client.ActiveSeriesResponse).Metric {
+			lbls := mimirpb.FromLabelAdaptersToLabels(m.Labels)
+			series[lbls.Hash()] = lbls
+		}
+	}
+
+	result := make([]labels.Labels, 0, len(series))
+	for _, lbls := range series {
+		result = append(result, lbls)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return labels.Compare(result[i], result[j]) < 0
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
 // MetricsMetadata returns all metric metadata of a user.
 func (d *Distributor) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error) {
 	replicationSet, err := d.GetIngesters(ctx)
@@ -1551,6 +1761,80 @@ func (d *Distributor) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetad
 	return result, nil
 }
 
+// HeadCardinalityStats returns the statistics about the cardinality of the in-memory series of the tenant, keeping
+// the top limit items of each statistic. The series counts are adjusted to the replication factor, and the label values
+// count of a label name is the highest count reported by an ingester, which is a lower bound of the actual count. The
+// statistics are approximated, since each ingester only reports its own top items.
+func (d *Distributor) HeadCardinalityStats(ctx context.Context, limit int) (*ingester_client.HeadCardinalityStatsResponse, error) {
+	replicationSet, err := d.GetIngesters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure we get a successful response from all of them.
+	replicationSet.MaxErrors = 0
+	replicationSet.MaxUnavailableZones = 0
+
+	req := &ingester_client.HeadCardinalityStatsRequest{Limit: int32(limit)}
+	resps, err := d.forReplicationSet(ctx, replicationSet, func(ctx context.Context, client ingester_client.IngesterClient) (interface{}, error) {
+		return client.HeadCardinalityStats(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		numSeries                   uint64
+		labelValueCountByLabelName  = map[string]uint64{}
+		seriesCountByMetricName     = map[string]uint64{}
+		seriesCountByLabelValuePair = map[string]uint64{}
+	)
+	for _, resp := range resps {
+		r := resp.(*ingester_client.HeadCardinalityStatsResponse)
+		numSeries += r.NumSeries
+		for _, item := range r.LabelValueCountByLabelName {
+			if item.Value > labelValueCountByLabelName[item.Name] {
+				labelValueCountByLabelName[item.Name] = item.Value
+			}
+		}
+		for _, item := range r.SeriesCountByMetricName {
+			seriesCountByMetricName[item.Name] += item.Value
+		}
+		for _, item := range r.SeriesCountByLabelValuePair {
+			seriesCountByLabelValuePair[item.Name] += item.Value
+		}
+	}
+
+	replicationFactor := uint64(d.ingestersRing.ReplicationFactor())
+	return &ingester_client.HeadCardinalityStatsResponse{
+		NumSeries:                   numSeries / replicationFactor,
+		LabelValueCountByLabelName:  toTopCardinalityStatsItems(labelValueCountByLabelName, 1, limit),
+		SeriesCountByMetricName:     toTopCardinalityStatsItems(seriesCountByMetricName, replicationFactor, limit),
+		SeriesCountByLabelValuePair: toTopCardinalityStatsItems(seriesCountByLabelValuePair, replicationFactor, limit),
+	}, nil
+}
+
+// toTopCardinalityStatsItems returns the limit items with the highest values, divided by the divisor, sorted by value
+// in descending order and then by name.
+func toTopCardinalityStatsItems(values map[string]uint64, divisor uint64, limit int) []*ingester_client.CardinalityStatsItem {
+	items := make([]*ingester_client.CardinalityStatsItem, 0, len(values))
+	for name, value := range values {
+		items = append(items, &ingester_client.CardinalityStatsItem{Name: name, Value: value / divisor})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Value != items[j].Value {
+			return items[i].Value > items[j].Value
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
 // UserStats returns statistics about the current user.
 func (d *Distributor) UserStats(ctx context.Context) (*UserStats, error) {
 	replicationSet, err := d.GetIngesters(ctx)
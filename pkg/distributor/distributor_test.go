@@ -594,6 +594,124 @@ func TestDistributor_PushIngestionRateLimiter(t *testing.T) {
 	}
 }
 
+func TestDistributor_PushRequestLimits(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	tests := map[string]struct {
+		maxPushRequestBytes      int
+		maxSeriesPerRequest      int
+		maxLabelsBytesPerRequest int
+		samples                  int
+		expectedError            func(req *mimirpb.WriteRequest) error
+		expectedReason           string
+	}{
+		"no limits": {
+			samples: 10,
+		},
+		"series per request below the limit": {
+			maxSeriesPerRequest: 10,
+			samples:             10,
+		},
+		"series per request above the limit": {
+			maxSeriesPerRequest: 5,
+			samples:             10,
+			expectedError: func(*mimirpb.WriteRequest) error {
+				return httpgrpc.Errorf(http.StatusRequestEntityTooLarge, validation.NewMaxSeriesPerRequestError(10, 5).Error())
+			},
+			expectedReason: validation.ReasonMaxSeriesPerRequest,
+		},
+		"request size below the limit": {
+			maxPushRequestBytes: 100000,
+			samples:             10,
+		},
+		"request size above the limit": {
+			maxPushRequestBytes: 100,
+			samples:             10,
+			expectedError: func(req *mimirpb.WriteRequest) error {
+				return httpgrpc.Errorf(http.StatusRequestEntityTooLarge, validation.NewMaxPushRequestBytesError(req.Size(), 100).Error())
+			},
+			expectedReason: validation.ReasonMaxPushRequestBytes,
+		},
+		"labels bytes per request below the limit": {
+			maxLabelsBytesPerRequest: 100000,
+			samples:                  10,
+		},
+		"labels bytes per request above the limit": {
+			maxLabelsBytesPerRequest: 100,
+			samples:                  10,
+			expectedError: func(req *mimirpb.WriteRequest) error {
+				return httpgrpc.Errorf(http.StatusRequestEntityTooLarge, validation.NewMaxLabelsBytesPerRequestError(labelsBytes(req.Timeseries), 100).Error())
+			},
+			expectedReason: validation.ReasonMaxLabelsBytesPerRequest,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.MaxPushRequestBytes = testData.maxPushRequestBytes
+			limits.MaxSeriesPerRequest = testData.maxSeriesPerRequest
+			limits.MaxLabelsBytesPerRequest = testData.maxLabelsBytesPerRequest
+
+			distributors, _, _ := prepare(t, prepConfig{
+				numIngesters:    3,
+				happyIngesters:  3,
+				numDistributors: 1,
+				limits:          limits,
+			})
+
+			request := makeWriteRequest(0, testData.samples, 0, false)
+			var expectedErr error
+			if testData.expectedError != nil {
+				expectedErr = testData.expectedError(request)
+			}
+
+			response, err := distributors[0].Push(ctx, request)
+			if expectedErr == nil {
+				assert.Equal(t, emptyResponse, response)
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Nil(t, response)
+			assert.Equal(t, expectedErr, err)
+			assert.Equal(t, float64(testData.samples), testutil.ToFloat64(validation.DiscardedSamples.WithLabelValues(testData.expectedReason, "user")))
+		})
+	}
+}
+
+func TestDistributor_PushIngestionMaintenanceMode(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+	limits.IngestionMaintenanceMode = true
+	limits.IngestionMaintenanceRetryAfter = model.Duration(90 * time.Second)
+
+	distributors, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+		limits:          limits,
+	})
+
+	response, err := distributors[0].Push(ctx, makeWriteRequest(0, 10, 0, false))
+	assert.Nil(t, response)
+
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusServiceUnavailable), resp.Code)
+	assert.Equal(t, validation.NewIngestionMaintenanceModeError(90*time.Second).Error(), string(resp.Body))
+	assert.Equal(t, []*httpgrpc.Header{{Key: "Retry-After", Values: []string{"90"}}}, resp.Headers)
+
+	// No series should have been pushed to ingesters.
+	for i := range ingesters {
+		assert.Empty(t, ingesters[i].series())
+	}
+}
+
 func TestDistributor_PushInstanceLimits(t *testing.T) {
 	type testPush struct {
 		samples       int
@@ -1432,6 +1550,124 @@ func TestDistributor_Push_LabelNameValidation(t *testing.T) {
 	}
 }
 
+type mockDeadLetterWriter struct {
+	services.Service
+
+	reasons map[string][]string
+}
+
+func (m *mockDeadLetterWriter) Write(userID string, _ mimirpb.PreallocTimeseries, reason string) {
+	m.reasons[userID] = append(m.reasons[userID], reason)
+}
+
+func TestDistributor_Push_RejectedSeriesDeadLetter(t *testing.T) {
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+	limits.RejectedSeriesDeadLetterEnabled = true
+
+	ds, _, _ := prepare(t, prepConfig{
+		numIngesters:    2,
+		happyIngesters:  2,
+		numDistributors: 1,
+		limits:          limits,
+	})
+	deadLetter := &mockDeadLetterWriter{reasons: map[string][]string{}}
+	ds[0].deadLetter = deadLetter
+
+	req := mockWriteRequest(labels.Labels{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "999.illegal", Value: "baz"}}, 42, 100000)
+	_, err := ds[0].Push(user.InjectOrgID(context.Background(), "user"), req)
+	require.Error(t, err)
+
+	// Valid series are not written to the dead-letter.
+	req = mockWriteRequest(labels.Labels{{Name: model.MetricNameLabel, Value: "foo"}}, 42, 100000)
+	_, err = ds[0].Push(user.InjectOrgID(context.Background(), "user"), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"user": {`received a series with an invalid label: '999.illegal' series: 'foo{999.illegal="baz"}' (err-mimir-label-invalid)`},
+	}, deadLetter.reasons)
+}
+
+func TestDistributor_Push_RateLimitedSeriesDeadLetter(t *testing.T) {
+	tests := map[string]struct {
+		configure      func(limits *validation.Limits)
+		expectedReason string
+	}{
+		"request rate limited": {
+			configure: func(limits *validation.Limits) {
+				limits.RequestRate = 1
+				limits.RequestBurstSize = 1
+			},
+			expectedReason: validation.NewRequestRateLimitedError(1, 1).Error(),
+		},
+		"ingestion rate limited": {
+			configure: func(limits *validation.Limits) {
+				limits.IngestionRate = 1
+				limits.IngestionBurstSize = 1
+			},
+			expectedReason: validation.NewIngestionRateLimitedError(1, 1).Error(),
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.RejectedSeriesDeadLetterEnabled = true
+			testData.configure(limits)
+
+			ds, _, _ := prepare(t, prepConfig{
+				numIngesters:    2,
+				happyIngesters:  2,
+				numDistributors: 1,
+				limits:          limits,
+			})
+			deadLetter := &mockDeadLetterWriter{reasons: map[string][]string{}}
+			ds[0].deadLetter = deadLetter
+
+			// The first request consumes the whole burst, so the second one is rejected.
+			req := mockWriteRequest(labels.Labels{{Name: model.MetricNameLabel, Value: "foo"}}, 42, 100000)
+			_, err := ds[0].Push(user.InjectOrgID(context.Background(), "user"), req)
+			require.NoError(t, err)
+
+			req = mockWriteRequest(labels.Labels{{Name: model.MetricNameLabel, Value: "foo"}}, 43, 100001)
+			_, err = ds[0].Push(user.InjectOrgID(context.Background(), "user"), req)
+			resp, ok := httpgrpc.HTTPResponseFromError(err)
+			require.True(t, ok)
+			assert.Equal(t, int32(http.StatusTooManyRequests), resp.Code)
+
+			assert.Equal(t, map[string][]string{"user": {testData.expectedReason}}, deadLetter.reasons)
+		})
+	}
+}
+
+func TestDistributor_Push_TimestampHints(t *testing.T) {
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+	})
+
+	// The series have samples with timestamps from 1000 to 1009, plus an invalid series which is dropped.
+	req := makeWriteRequest(1000, 10, 0, false)
+	req.Timeseries = append(req.Timeseries, makeWriteRequestTimeseries([]mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "999.illegal", Value: "baz"}}, 500, 1))
+
+	_, err := ds[0].Push(user.InjectOrgID(context.Background(), "user"), req)
+	require.Error(t, err)
+
+	// The hints are bounds of the samples received by the distributor, even if some of them have been dropped.
+	// The push to the last ingester may complete after the quorum has been reached, so wait for it.
+	for i := range ingesters {
+		test.Poll(t, time.Second, timestampHints{set: true, minTimestampMs: 500, maxTimestampMs: 1009}, func() interface{} {
+			ingesters[i].Lock()
+			defer ingesters[i].Unlock()
+			return ingesters[i].timestampHints
+		})
+	}
+}
+
 func TestDistributor_Push_ExemplarValidation(t *testing.T) {
 	ctx := user.InjectOrgID(context.Background(), "user")
 	manyLabels := []string{model.MetricNameLabel, "test"}
@@ -2730,6 +2966,172 @@ func TestDistributor_LabelValuesCardinality(t *testing.T) {
 	}
 }
 
+func TestDistributor_ActiveSeries(t *testing.T) {
+	const numIngesters = 3
+	const replicationFactor = 3
+
+	series1 := labels.FromStrings(labels.MetricName, "test_1", "status", "200")
+	series2 := labels.FromStrings(labels.MetricName, "test_1", "status", "500")
+	series3 := labels.FromStrings(labels.MetricName, "test_2")
+
+	tests := map[string]struct {
+		matchers       []*labels.Matcher
+		limit          int
+		happyIngesters int
+		expectedResult []labels.Labels
+		expectedErr    error
+	}{
+		"should return the deduplicated active series sorted by labels": {
+			happyIngesters: numIngesters,
+			expectedResult: []labels.Labels{series1, series2, series3},
+		},
+		"should only return the first active series up to the limit": {
+			limit:          2,
+			happyIngesters: numIngesters,
+			expectedResult: []labels.Labels{series1, series2},
+		},
+		"should only return the active series matching the matchers": {
+			matchers:       []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "test_1")},
+			happyIngesters: numIngesters,
+			expectedResult: []labels.Labels{series1, series2},
+		},
+		"should succeed if an ingester fails": {
+			happyIngesters: numIngesters - 1,
+			expectedResult: []labels.Labels{series1, series2, series3},
+		},
+		"should fail if the quorum of the ingesters fails": {
+			happyIngesters: 1,
+			expectedErr:    errFail,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ds, ingesters, _ := prepare(t, prepConfig{
+				numIngesters:      numIngesters,
+				happyIngesters:    testData.happyIngesters,
+				numDistributors:   1,
+				replicationFactor: replicationFactor,
+			})
+
+			ctx := user.InjectOrgID(context.Background(), "active-series")
+			if testData.expectedErr != nil {
+				_, err := ds[0].ActiveSeries(ctx, testData.matchers, testData.limit)
+				require.ErrorIs(t, err, testData.expectedErr)
+				return
+			}
+
+			for _, series := range []labels.Labels{series3, series2, series1} {
+				_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 100000))
+				require.NoError(t, err)
+			}
+
+			// Since the Push() response is sent as soon as the quorum is reached, when we reach this point
+			// the final ingester may not have received series yet.
+			// To avoid flaky test we retry the assertions until we hit the desired state within a reasonable timeout.
+			test.Poll(t, time.Second, testData.expectedResult, func() interface{} {
+				result, err := ds[0].ActiveSeries(ctx, testData.matchers, testData.limit)
+				require.NoError(t, err)
+				return result
+			})
+
+			assert.GreaterOrEqual(t, countMockIngestersCalls(ingesters, "ActiveSeries"), numIngesters-1)
+		})
+	}
+}
+
+func TestDistributor_HeadCardinalityStats(t *testing.T) {
+	const numIngesters = 3
+	const replicationFactor = 3
+
+	fixtures := []labels.Labels{
+		{{Name: labels.MetricName, Value: "test_1"}, {Name: "status", Value: "200"}},
+		{{Name: labels.MetricName, Value: "test_1"}, {Name: "status", Value: "500"}, {Name: "reason", Value: "broken"}},
+		{{Name: labels.MetricName, Value: "test_2"}},
+	}
+
+	tests := map[string]struct {
+		limit          int
+		happyIngesters int
+		expectedResult *client.HeadCardinalityStatsResponse
+		expectedErr    error
+	}{
+		"should return the statistics adjusted to the replication factor": {
+			limit:          10,
+			happyIngesters: numIngesters,
+			expectedResult: &client.HeadCardinalityStatsResponse{
+				NumSeries: 3,
+				LabelValueCountByLabelName: []*client.CardinalityStatsItem{
+					{Name: labels.MetricName, Value: 2},
+					{Name: "status", Value: 2},
+					{Name: "reason", Value: 1},
+				},
+				SeriesCountByMetricName: []*client.CardinalityStatsItem{
+					{Name: "test_1", Value: 2},
+					{Name: "test_2", Value: 1},
+				},
+				SeriesCountByLabelValuePair: []*client.CardinalityStatsItem{
+					{Name: "__name__=test_1", Value: 2},
+					{Name: "__name__=test_2", Value: 1},
+					{Name: "reason=broken", Value: 1},
+					{Name: "status=200", Value: 1},
+					{Name: "status=500", Value: 1},
+				},
+			},
+		},
+		"should return the top limit items of each statistic": {
+			limit:          1,
+			happyIngesters: numIngesters,
+			expectedResult: &client.HeadCardinalityStatsResponse{
+				NumSeries:                   3,
+				LabelValueCountByLabelName:  []*client.CardinalityStatsItem{{Name: labels.MetricName, Value: 2}},
+				SeriesCountByMetricName:     []*client.CardinalityStatsItem{{Name: "test_1", Value: 2}},
+				SeriesCountByLabelValuePair: []*client.CardinalityStatsItem{{Name: "__name__=test_1", Value: 2}},
+			},
+		},
+		"should fail if an ingester fails": {
+			limit:          10,
+			happyIngesters: numIngesters - 1,
+			expectedErr:    errFail,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ds, ingesters, _ := prepare(t, prepConfig{
+				numIngesters:      numIngesters,
+				happyIngesters:    testData.happyIngesters,
+				numDistributors:   1,
+				replicationFactor: replicationFactor,
+			})
+
+			ctx := user.InjectOrgID(context.Background(), "head-cardinality-stats")
+			if testData.expectedErr != nil {
+				_, err := ds[0].HeadCardinalityStats(ctx, testData.limit)
+				require.ErrorIs(t, err, testData.expectedErr)
+				return
+			}
+
+			for _, series := range fixtures {
+				_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 100000))
+				require.NoError(t, err)
+			}
+
+			// Since the Push() response is sent as soon as the quorum is reached, when we reach this point
+			// the final ingester may not have received series yet.
+			// To avoid flaky test we retry the assertions until we hit the desired state within a reasonable timeout.
+			test.Poll(t, time.Second, testData.expectedResult, func() interface{} {
+				result, err := ds[0].HeadCardinalityStats(ctx, testData.limit)
+				require.NoError(t, err)
+				return result
+			})
+
+			// Make sure all the ingesters have been queried.
+			assert.GreaterOrEqual(t, countMockIngestersCalls(ingesters, "HeadCardinalityStats"), numIngesters)
+		})
+	}
+}
+
 func TestDistributor_LabelValuesCardinalityLimit(t *testing.T) {
 	fixtures := []struct {
 		labels    labels.Labels
@@ -3651,6 +4053,7 @@ type mockIngester struct {
 	seriesCountTotal uint64
 	zone             string
 	responseDelay    time.Duration
+	timestampHints   timestampHints
 }
 
 func (i *mockIngester) series() map[uint32]*mimirpb.PreallocTimeseries {
@@ -3701,9 +4104,11 @@ func (i *mockIngester) Push(ctx context.Context, req *mimirpb.WriteRequest, opts
 		return nil, err
 	}
 
+	i.timestampHints = timestampHints{set: req.TimestampHintsSet, minTimestampMs: req.MinTimestampMs, maxTimestampMs: req.MaxTimestampMs}
+
 	for j := range req.Timeseries {
 		series := req.Timeseries[j]
-		hash := shardByAllLabels(orgid, series.Labels)
+		hash := client.ShardByAllLabels(orgid, series.Labels)
 		existing, ok := i.timeseries[hash]
 		if !ok {
 			// Make a copy because the request Timeseries are reused
@@ -3995,6 +4400,69 @@ func (s *labelValuesCardinalityStream) Recv() (*client.LabelValuesCardinalityRes
 	return result, nil
 }
 
+func (i *mockIngester) HeadCardinalityStats(ctx context.Context, req *client.HeadCardinalityStatsRequest, opts ...grpc.CallOption) (*client.HeadCardinalityStatsResponse, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.trackCall("HeadCardinalityStats")
+
+	if !i.happy {
+		return nil, errFail
+	}
+
+	labelValues := map[string]map[string]struct{}{}
+	seriesCountByMetricName := map[string]uint64{}
+	seriesCountByLabelValuePair := map[string]uint64{}
+	for _, ts := range i.timeseries {
+		for _, lbl := range ts.Labels {
+			if _, ok := labelValues[lbl.Name]; !ok {
+				labelValues[lbl.Name] = map[string]struct{}{}
+			}
+			labelValues[lbl.Name][lbl.Value] = struct{}{}
+			seriesCountByLabelValuePair[lbl.Name+"="+lbl.Value]++
+			if lbl.Name == labels.MetricName {
+				seriesCountByMetricName[lbl.Value]++
+			}
+		}
+	}
+
+	labelValueCountByLabelName := map[string]uint64{}
+	for name, values := range labelValues {
+		labelValueCountByLabelName[name] = uint64(len(values))
+	}
+
+	return &client.HeadCardinalityStatsResponse{
+		NumSeries:                   uint64(len(i.timeseries)),
+		LabelValueCountByLabelName:  toTopCardinalityStatsItems(labelValueCountByLabelName, 1, int(req.Limit)),
+		SeriesCountByMetricName:     toTopCardinalityStatsItems(seriesCountByMetricName, 1, int(req.Limit)),
+		SeriesCountByLabelValuePair: toTopCardinalityStatsItems(seriesCountByLabelValuePair, 1, int(req.Limit)),
+	}, nil
+}
+
+func (i *mockIngester) ActiveSeries(ctx context.Context, req *client.ActiveSeriesRequest, opts ...grpc.CallOption) (*client.ActiveSeriesResponse, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.trackCall("ActiveSeries")
+
+	if !i.happy {
+		return nil, errFail
+	}
+
+	matchers, err := client.FromLabelMatchers(req.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	response := client.ActiveSeriesResponse{}
+	for _, ts := range i.timeseries {
+		if match(ts.Labels, matchers) {
+			response.Metric = append(response.Metric, &mimirpb.Metric{Labels: ts.Labels})
+		}
+	}
+	return &response, nil
+}
+
 func (i *mockIngester) trackCall(name string) {
 	if i.calls == nil {
 		i.calls = map[string]int{}
@@ -4277,13 +4745,13 @@ func TestRemoveReplicaLabel(t *testing.T) {
 
 // This is not great, but we deal with unsorted labels when validating labels.
 func TestShardByAllLabelsReturnsWrongResultsForUnsortedLabels(t *testing.T) {
-	val1 := shardByAllLabels("test", []mimirpb.LabelAdapter{
+	val1 := client.ShardByAllLabels("test", []mimirpb.LabelAdapter{
 		{Name: "__name__", Value: "foo"},
 		{Name: "bar", Value: "baz"},
 		{Name: "sample", Value: "1"},
 	})
 
-	val2 := shardByAllLabels("test", []mimirpb.LabelAdapter{
+	val2 := client.ShardByAllLabels("test", []mimirpb.LabelAdapter{
 		{Name: "__name__", Value: "foo"},
 		{Name: "sample", Value: "1"},
 		{Name: "bar", Value: "baz"},
@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/push"
+)
+
+const (
+	liveTailDefaultDuration  = time.Minute
+	liveTailMaxDuration      = 10 * time.Minute
+	liveTailDefaultRateLimit = 10
+	liveTailMaxRateLimit     = 1000
+
+	// Size of the buffer of series waiting to be written to the client. Series are dropped when the buffer is full,
+	// so that a slow client never slows down the ingestion.
+	liveTailBufferSize = 100
+)
+
+// liveTailSeries is a single series sent to live tail clients.
+type liveTailSeries struct {
+	Labels  string                 `json:"labels"`
+	Samples []liveTailSample       `json:"samples,omitempty"`
+	Dropped *liveTailDroppedSeries `json:"dropped,omitempty"`
+}
+
+type liveTailSample struct {
+	TimestampMs int64   `json:"timestamp_ms"`
+	Value       float64 `json:"value"`
+}
+
+// liveTailDroppedSeries is periodically sent to the client to report the series matching the selector
+// but not sent because of the rate limit or the buffer being full.
+type liveTailDroppedSeries struct {
+	RateLimited int64 `json:"rate_limited"`
+	BufferFull  int64 `json:"buffer_full"`
+}
+
+type liveTailSubscription struct {
+	matchers    []*labels.Matcher
+	sampleRatio float64
+	limiter     *rate.Limiter
+	series      chan liveTailSeries
+
+	droppedRateLimited atomic.Int64
+	droppedBufferFull  atomic.Int64
+}
+
+// liveTailer keeps track of the live tail subscriptions and dispatches the incoming series to them.
+type liveTailer struct {
+	// Number of active subscriptions, used to skip any work in the write path when there are none.
+	active atomic.Int64
+
+	mtx           sync.RWMutex
+	subscriptions map[string]map[*liveTailSubscription]struct{}
+}
+
+func newLiveTailer() *liveTailer {
+	return &liveTailer{subscriptions: map[string]map[*liveTailSubscription]struct{}{}}
+}
+
+func (t *liveTailer) subscribe(userID string, sub *liveTailSubscription) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.subscriptions[userID] == nil {
+		t.subscriptions[userID] = map[*liveTailSubscription]struct{}{}
+	}
+	t.subscriptions[userID][sub] = struct{}{}
+	t.active.Inc()
+}
+
+func (t *liveTailer) unsubscribe(userID string, sub *liveTailSubscription) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	delete(t.subscriptions[userID], sub)
+	if len(t.subscriptions[userID]) == 0 {
+		delete(t.subscriptions, userID)
+	}
+	t.active.Dec()
+}
+
+// publish sends the series matching the tenant's subscriptions to them. The series are copied,
+// so the input can be reused once the function returns.
+func (t *liveTailer) publish(userID string, timeseries []mimirpb.PreallocTimeseries) {
+	if t.active.Load() == 0 {
+		return
+	}
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	for sub := range t.subscriptions[userID] {
+		for _, ts := range timeseries {
+			if sub.sampleRatio < 1 && rand.Float64() >= sub.sampleRatio {
+				continue
+			}
+
+			lbls := mimirpb.FromLabelAdaptersToLabels(ts.Labels)
+			if !matchesAll(sub.matchers, lbls) {
+				continue
+			}
+
+			if !sub.limiter.Allow() {
+				sub.droppedRateLimited.Inc()
+				continue
+			}
+
+			series := liveTailSeries{Labels: lbls.String(), Samples: make([]liveTailSample, 0, len(ts.Samples))}
+			for _, s := range ts.Samples {
+				series.Samples = append(series.Samples, liveTailSample{TimestampMs: s.TimestampMs, Value: s.Value})
+			}
+
+			select {
+			case sub.series <- series:
+			default:
+				sub.droppedBufferFull.Inc()
+			}
+		}
+	}
+}
+
+func matchesAll(matchers []*labels.Matcher, lbls labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// liveTailMiddleware sends the incoming series to the live tail subscriptions, if any.
+func (d *Distributor) liveTailMiddleware(next push.Func) push.Func {
+	return func(ctx context.Context, req *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+		if d.liveTailer.active.Load() > 0 {
+			if userID, err := tenant.TenantID(ctx); err == nil {
+				d.liveTailer.publish(userID, req.Timeseries)
+			}
+		}
+
+		return next(ctx, req, cleanup)
+	}
+}
+
+// LiveTailHandler streams a sample of the series received by this distributor for a tenant and matching
+// the input selector, as newline delimited JSON, until the requested duration elapses or the client disconnects.
+func (d *Distributor) LiveTailHandler(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["tenant"]
+	if userID == "" {
+		http.Error(w, "missing tenant", http.StatusBadRequest)
+		return
+	}
+
+	sub, duration, err := parseLiveTailRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	d.liveTailer.subscribe(userID, sub)
+	defer d.liveTailer.unsubscribe(userID, sub)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithTimeout(r.Context(), duration)
+	defer cancel()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	enc := json.NewEncoder(w)
+	for {
+		var series liveTailSeries
+
+		select {
+		case <-ctx.Done():
+			return
+		case series = <-sub.series:
+		case <-ticker.C:
+			rateLimited, bufferFull := sub.droppedRateLimited.Swap(0), sub.droppedBufferFull.Swap(0)
+			if rateLimited == 0 && bufferFull == 0 {
+				continue
+			}
+			series.Dropped = &liveTailDroppedSeries{RateLimited: rateLimited, BufferFull: bufferFull}
+		}
+
+		if err := enc.Encode(series); err != nil {
+			level.Warn(d.log).Log("msg", "failed to write live tail series", "user", userID, "err", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func parseLiveTailRequest(r *http.Request) (*liveTailSubscription, time.Duration, error) {
+	selector := r.FormValue("selector")
+	if selector == "" {
+		return nil, 0, fmt.Errorf("missing selector")
+	}
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	sampleRatio := 1.0
+	if v := r.FormValue("sample_ratio"); v != "" {
+		sampleRatio, err = strconv.ParseFloat(v, 64)
+		if err != nil || sampleRatio <= 0 || sampleRatio > 1 {
+			return nil, 0, fmt.Errorf("invalid sample_ratio, it must be a number in the range (0, 1]")
+		}
+	}
+
+	rateLimit := liveTailDefaultRateLimit
+	if v := r.FormValue("rate_limit"); v != "" {
+		rateLimit, err = strconv.Atoi(v)
+		if err != nil || rateLimit <= 0 || rateLimit > liveTailMaxRateLimit {
+			return nil, 0, fmt.Errorf("invalid rate_limit, it must be an integer in the range [1, %d]", liveTailMaxRateLimit)
+		}
+	}
+
+	duration := liveTailDefaultDuration
+	if v := r.FormValue("duration"); v != "" {
+		duration, err = time.ParseDuration(v)
+		if err != nil || duration <= 0 || duration > liveTailMaxDuration {
+			return nil, 0, fmt.Errorf("invalid duration, it must be a positive duration up to %s", liveTailMaxDuration)
+		}
+	}
+
+	return &liveTailSubscription{
+		matchers:    matchers,
+		sampleRatio: sampleRatio,
+		limiter:     rate.NewLimiter(rate.Limit(rateLimit), rateLimit),
+		series:      make(chan liveTailSeries, liveTailBufferSize),
+	}, duration, nil
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestDistributor_LiveTailHandler(t *testing.T) {
+	distributors, _, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+	})
+	d := distributors[0]
+
+	router := mux.NewRouter()
+	router.Path("/distributor/tenant/{tenant}/live_tail").Handler(http.HandlerFunc(d.LiveTailHandler))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, params := range []string{
+			"",
+			"selector=" + url.QueryEscape("{foo"),
+			"selector=up&sample_ratio=2",
+			"selector=up&rate_limit=0",
+			"selector=up&duration=1h",
+		} {
+			resp, err := http.Get(server.URL + "/distributor/tenant/user/live_tail?" + params)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, params)
+		}
+	})
+
+	t.Run("streams the matching series", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/distributor/tenant/user/live_tail?duration=10s&selector=" + url.QueryEscape(`{__name__="foo", bar="1"}`))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Eventually(t, func() bool { return d.liveTailer.active.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+		ctx := user.InjectOrgID(context.Background(), "user")
+		req := mimirpb.ToWriteRequest([]labels.Labels{
+			labels.FromStrings("__name__", "foo", "bar", "2"),
+			labels.FromStrings("__name__", "foo", "bar", "1"),
+		}, []mimirpb.Sample{{TimestampMs: 1000, Value: 1}, {TimestampMs: 2000, Value: 2}}, nil, nil, mimirpb.API)
+		_, err = d.Push(ctx, req)
+		require.NoError(t, err)
+
+		// Series for another tenant are not streamed.
+		_, err = d.Push(user.InjectOrgID(context.Background(), "another"), mimirpb.ToWriteRequest([]labels.Labels{
+			labels.FromStrings("__name__", "foo", "bar", "1"),
+		}, []mimirpb.Sample{{TimestampMs: 3000, Value: 3}}, nil, nil, mimirpb.API))
+		require.NoError(t, err)
+
+		scanner := bufio.NewScanner(resp.Body)
+		require.True(t, scanner.Scan())
+
+		var series liveTailSeries
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &series))
+		assert.Equal(t, liveTailSeries{
+			Labels:  `{__name__="foo", bar="1"}`,
+			Samples: []liveTailSample{{TimestampMs: 2000, Value: 2}},
+		}, series)
+
+		// Closing the connection removes the subscription.
+		require.NoError(t, resp.Body.Close())
+		require.Eventually(t, func() bool { return d.liveTailer.active.Load() == 0 }, 5*time.Second, 10*time.Millisecond)
+	})
+}
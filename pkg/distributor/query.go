@@ -11,17 +11,21 @@ import (
 	"sort"
 	"time"
 
+	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/tenant"
 	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/weaveworks/common/instrument"
 
 	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
 	"github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/util/limiter"
+	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -91,11 +95,49 @@ func (d *Distributor) GetIngesters(ctx context.Context) (ring.ReplicationSet, er
 	shardSize := d.limits.IngestionTenantShardSize(userID)
 	lookbackPeriod := d.cfg.ShuffleShardingLookbackPeriod
 
+	r := d.ingestersRing
 	if shardSize > 0 && lookbackPeriod > 0 {
-		return d.ingestersRing.ShuffleShardWithLookback(userID, shardSize, lookbackPeriod, time.Now()).GetReplicationSetForOperation(ring.Read)
+		r = r.ShuffleShardWithLookback(userID, shardSize, lookbackPeriod, time.Now())
 	}
 
-	return d.ingestersRing.GetReplicationSetForOperation(ring.Read)
+	replicationSet, err := r.GetReplicationSetForOperation(ring.Read)
+
+	// The partial results are only served to the callers collecting the warnings, because the other ones can't
+	// report to the client that the results may be incomplete.
+	warnings := querywarnings.FromContext(ctx)
+	if errors.Is(err, ring.ErrTooManyUnhealthyInstances) && warnings != nil && d.limits.ZoneOutagePartialResultsEnabled(userID) {
+		if partialSet, ok := partialResultsReplicationSet(r); ok {
+			level.Warn(util_log.WithContext(ctx, d.log)).Log("msg", "serving the read request with partial results from the ingesters of the available zones", "err", err, "healthy_ingesters", len(partialSet.Instances))
+			d.partialResults.Inc()
+			warnings.Add(errPartialResultsIngesterZones)
+			return partialSet, nil
+		}
+	}
+	return replicationSet, err
+}
+
+var errPartialResultsIngesterZones = errors.New("the ingesters of too many zones are unavailable, the results read from the ingesters only include the data of the ingesters of the available zones and may be incomplete")
+
+// partialResultsReplicationSet returns the replication set including the healthy ingesters of the zones with at least
+// one healthy ingester, which only succeeds once all of them succeed, and true. Waiting for all the healthy ingesters,
+// instead of the ones of any zone, makes sure the results aren't read from the few healthy ingesters of a barely
+// available zone. It returns false if the zone-awareness is disabled or if there's no healthy ingester.
+func partialResultsReplicationSet(r ring.ReadRing) (ring.ReplicationSet, bool) {
+	healthy, err := r.GetAllHealthy(ring.Read)
+	if err != nil || len(healthy.Instances) == 0 {
+		return ring.ReplicationSet{}, false
+	}
+
+	for _, instance := range healthy.Instances {
+		if instance.Zone == "" {
+			return ring.ReplicationSet{}, false
+		}
+	}
+
+	return ring.ReplicationSet{
+		Instances:           healthy.Instances,
+		MaxUnavailableZones: 0,
+	}, true
 }
 
 // mergeExemplarSets merges and dedupes two sets of already sorted exemplar pairs.
@@ -180,9 +222,10 @@ func mergeExemplarQueryResponses(results []interface{}) *ingester_client.Exempla
 // queryIngesterStream queries the ingesters using the new streaming API.
 func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ring.ReplicationSet, req *ingester_client.QueryRequest) (*ingester_client.QueryStreamResponse, error) {
 	var (
-		queryLimiter = limiter.QueryLimiterFromContextWithFallback(ctx)
-		reqStats     = stats.FromContext(ctx)
-		results      = make(chan *ingester_client.QueryStreamResponse)
+		queryLimiter  = limiter.QueryLimiterFromContextWithFallback(ctx)
+		memoryTracker = limiter.MemoryConsumptionTrackerFromContextWithFallback(ctx)
+		reqStats      = stats.FromContext(ctx)
+		results       = make(chan *ingester_client.QueryStreamResponse)
 		// Note we can't signal goroutines to stop by closing 'results', because it has multiple concurrent senders.
 		stop        = make(chan struct{}) // Signal all background goroutines to stop.
 		doneReading = make(chan struct{}) // Signal that the reader has stopped.
@@ -278,6 +321,10 @@ func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ri
 				return nil, validation.LimitError(chunkBytesLimitErr.Error())
 			}
 
+			if memoryLimitErr := memoryTracker.IncreaseMemoryConsumption(uint64(resp.ChunksSize())); memoryLimitErr != nil {
+				return nil, validation.LimitError(memoryLimitErr.Error())
+			}
+
 			for _, series := range resp.Timeseries {
 				if limitErr := queryLimiter.AddSeries(series.Labels); limitErr != nil {
 					return nil, validation.LimitError(limitErr.Error())
@@ -315,7 +362,10 @@ func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ri
 
 	reqStats.AddFetchedSeries(uint64(len(resp.Chunkseries) + len(resp.Timeseries)))
 	reqStats.AddFetchedChunkBytes(uint64(resp.ChunksSize()))
-	reqStats.AddFetchedChunks(uint64(resp.ChunksCount()))
+	chunksCount := uint64(resp.ChunksCount())
+	reqStats.AddFetchedChunks(chunksCount)
+	reqStats.AddFetchedIngesterChunks(chunksCount)
+	reqStats.AddFetchedSamples(uint64(resp.SamplesCount()))
 
 	return resp, nil
 }
@@ -6,17 +6,26 @@
 package distributor
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
 
 	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 func TestMergeSamplesIntoFirstDuplicates(t *testing.T) {
@@ -220,3 +229,91 @@ func BenchmarkMergeExemplars(b *testing.B) {
 		mergeExemplarQueryResponses([]interface{}{input, input, input})
 	}
 }
+
+// unhealthyZonesRing is a ring in which the ingesters of too many zones are unhealthy.
+type unhealthyZonesRing struct {
+	ring.ReadRing
+	healthy []ring.InstanceDesc
+}
+
+func (r unhealthyZonesRing) GetReplicationSetForOperation(ring.Operation) (ring.ReplicationSet, error) {
+	return ring.ReplicationSet{}, ring.ErrTooManyUnhealthyInstances
+}
+
+func (r unhealthyZonesRing) GetAllHealthy(ring.Operation) (ring.ReplicationSet, error) {
+	return ring.ReplicationSet{Instances: r.healthy}, nil
+}
+
+func TestDistributor_GetIngesters_ZoneOutagePartialResults(t *testing.T) {
+	tests := map[string]struct {
+		partialResultsEnabled bool
+		withoutCollector      bool
+		healthy               []ring.InstanceDesc
+		expectedErr           error
+		expectedSet           ring.ReplicationSet
+	}{
+		"partial results disabled": {
+			healthy:     []ring.InstanceDesc{{Addr: "1", Zone: "zone-a"}},
+			expectedErr: ring.ErrTooManyUnhealthyInstances,
+		},
+		"partial results enabled, single available zone": {
+			partialResultsEnabled: true,
+			healthy:               []ring.InstanceDesc{{Addr: "1", Zone: "zone-a"}, {Addr: "2", Zone: "zone-a"}},
+			expectedSet:           ring.ReplicationSet{Instances: []ring.InstanceDesc{{Addr: "1", Zone: "zone-a"}, {Addr: "2", Zone: "zone-a"}}},
+		},
+		"partial results enabled, multiple available zones": {
+			partialResultsEnabled: true,
+			healthy:               []ring.InstanceDesc{{Addr: "1", Zone: "zone-a"}, {Addr: "2", Zone: "zone-b"}},
+			expectedSet:           ring.ReplicationSet{Instances: []ring.InstanceDesc{{Addr: "1", Zone: "zone-a"}, {Addr: "2", Zone: "zone-b"}}},
+		},
+		"partial results enabled, the warnings aren't collected": {
+			partialResultsEnabled: true,
+			withoutCollector:      true,
+			healthy:               []ring.InstanceDesc{{Addr: "1", Zone: "zone-a"}},
+			expectedErr:           ring.ErrTooManyUnhealthyInstances,
+		},
+		"partial results enabled, zone-awareness disabled": {
+			partialResultsEnabled: true,
+			healthy:               []ring.InstanceDesc{{Addr: "1"}},
+			expectedErr:           ring.ErrTooManyUnhealthyInstances,
+		},
+		"partial results enabled, no healthy ingester": {
+			partialResultsEnabled: true,
+			expectedErr:           ring.ErrTooManyUnhealthyInstances,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := validation.Limits{}
+			flagext.DefaultValues(&limits)
+			limits.ZoneOutagePartialResultsEnabled = testData.partialResultsEnabled
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			d := &Distributor{
+				log:            log.NewNopLogger(),
+				ingestersRing:  unhealthyZonesRing{healthy: testData.healthy},
+				limits:         overrides,
+				partialResults: prometheus.NewCounter(prometheus.CounterOpts{}),
+			}
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			collector := &querywarnings.Collector{}
+			if !testData.withoutCollector {
+				collector, ctx = querywarnings.ContextWithCollector(ctx)
+			}
+			set, err := d.GetIngesters(ctx)
+			if testData.expectedErr != nil {
+				require.ErrorIs(t, err, testData.expectedErr)
+				assert.Empty(t, collector.Warnings())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedSet, set)
+			require.Len(t, collector.Warnings(), 1)
+			assert.Contains(t, collector.Warnings()[0].Error(), "the ingesters of too many zones are unavailable")
+		})
+	}
+}
@@ -253,7 +253,7 @@ func testFrontend(t *testing.T, config CombinedFrontendConfig, handler http.Hand
 	r.PathPrefix("/").Handler(middleware.Merge(
 		middleware.AuthenticateUser,
 		middleware.Tracer{},
-	).Wrap(transport.NewHandler(config.Handler, rt, logger, nil)))
+	).Wrap(transport.NewHandler(config.Handler, rt, nil, nil, nil, logger, nil)))
 
 	httpServer := http.Server{
 		Handler: r,
@@ -289,3 +289,15 @@ type limits struct {
 func (l limits) MaxQueriersPerUser(_ string) int {
 	return l.queriers
 }
+
+func (l limits) QuerierCapacityWeight(_ string) int {
+	return 1
+}
+
+func (l limits) MaxOutstandingRequestsPerTenant(_ string) int {
+	return 0
+}
+
+func (l limits) RulerMaxOutstandingRequestsPerTenant(_ string) int {
+	return 0
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queryaudit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/frontend/queryrecorder"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+// AuditLog writes an audit log entry for every query.
+type AuditLog interface {
+	services.Service
+	Log(r *http.Request, params url.Values, responseTime time.Duration, statusCode int, stats *querier_stats.Stats)
+}
+
+type auditLog struct {
+	services.Service
+
+	cfg  Config
+	log  log.Logger
+	file *os.File
+
+	// The destination of the audit log entries.
+	entries log.Logger
+
+	writeFailuresTotal prometheus.Counter
+}
+
+// NewAuditLog returns a new query audit log, if the query audit log is disabled it returns nil.
+func NewAuditLog(cfg Config, reg prometheus.Registerer, logger log.Logger) AuditLog {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	a := &auditLog{
+		cfg: cfg,
+		log: logger,
+		writeFailuresTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "query_frontend_audit_log_write_failures_total",
+			Help:      "The total number of query audit log entries the query-frontend failed to write.",
+		}),
+	}
+	a.Service = services.NewIdleService(a.starting, a.stopping)
+	return a
+}
+
+func (a *auditLog) starting(_ context.Context) error {
+	if a.cfg.File == "" {
+		a.entries = level.Info(log.With(a.log, "msg", "query audit"))
+		return nil
+	}
+
+	file, err := os.OpenFile(a.cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return errors.Wrap(err, "open query audit log file")
+	}
+	a.file = file
+	a.entries = log.With(log.NewJSONLogger(log.NewSyncWriter(file)), "ts", log.DefaultTimestampUTC)
+	return nil
+}
+
+func (a *auditLog) stopping(_ error) error {
+	if a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// Log writes the audit log entry of the query. The stats are omitted if nil.
+func (a *auditLog) Log(r *http.Request, params url.Values, responseTime time.Duration, statusCode int, stats *querier_stats.Stats) {
+	if a.entries == nil {
+		return
+	}
+
+	entry := []interface{}{
+		"method", r.Method,
+		"path", r.URL.Path,
+	}
+	if tenantIDs, err := tenant.TenantIDs(r.Context()); err == nil {
+		entry = append(entry, "user", tenant.JoinTenantIDs(tenantIDs))
+	}
+	for _, header := range a.cfg.IdentityHeaders {
+		if value := r.Header.Get(header); value != "" {
+			entry = append(entry, "header_"+strings.ToLower(strings.ReplaceAll(header, "-", "_")), value)
+		}
+	}
+
+	entry = append(entry,
+		"query_fingerprint", queryrecorder.Fingerprint(r.URL.Path, params),
+		"query_hash", queryHash(params),
+	)
+	for _, param := range []string{"start", "end", "time", "step"} {
+		if value := params.Get(param); value != "" {
+			entry = append(entry, "param_"+param, value)
+		}
+	}
+
+	entry = append(entry,
+		"status_code", statusCode,
+		"response_time_seconds", responseTime.Seconds(),
+	)
+	if stats != nil {
+		entry = append(entry,
+			"query_wall_time_seconds", stats.LoadWallTime().Seconds(),
+			"queue_time_seconds", stats.LoadQueueTime().Seconds(),
+			"fetched_series_count", stats.LoadFetchedSeries(),
+			"fetched_chunk_bytes", stats.LoadFetchedChunkBytes(),
+			"fetched_chunks_count", stats.LoadFetchedChunks(),
+			"fetched_samples_count", stats.LoadFetchedSamples(),
+			"sharded_queries", stats.LoadShardedQueries(),
+			"split_queries", stats.LoadSplitQueries(),
+		)
+	}
+
+	if err := a.entries.Log(entry...); err != nil {
+		a.writeFailuresTotal.Inc()
+		level.Warn(a.log).Log("msg", "failed to write query audit log entry", "err", err)
+	}
+}
+
+// queryHash returns the hex-encoded SHA-256 hash of the query text: the query of the instant and range queries,
+// or the series selectors of the series, label names and label values queries. It returns an empty string if
+// there's no query text.
+func queryHash(params url.Values) string {
+	var values []string
+	values = append(values, params["query"]...)
+	values = append(values, params["match[]"]...)
+	if len(values) == 0 {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(h[:])
+}
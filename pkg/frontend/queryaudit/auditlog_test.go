@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queryaudit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/frontend/queryrecorder"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+func TestNewAuditLog_ShouldReturnNilIfDisabled(t *testing.T) {
+	assert.Nil(t, NewAuditLog(Config{}, nil, log.NewNopLogger()))
+}
+
+func TestAuditLog_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	a := NewAuditLog(Config{Enabled: true, File: file, IdentityHeaders: []string{"X-Grafana-User"}}, prometheus.NewPedanticRegistry(), log.NewNopLogger())
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), a))
+
+	stats := &querier_stats.Stats{}
+	stats.AddFetchedSeries(10)
+	stats.AddShardedQueries(4)
+
+	params := url.Values{"query": []string{"sum(up)"}, "start": []string{"1"}, "end": []string{"3601"}, "step": []string{"60"}}
+	a.Log(newRequest("/api/v1/query_range", map[string]string{"X-Grafana-User": "admin", "X-Other": "other"}), params, 2*time.Second, http.StatusOK, stats)
+	a.Log(newRequest("/api/v1/labels", nil), url.Values{}, time.Second, http.StatusBadRequest, nil)
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), a))
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	entry := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.NotEmpty(t, entry["ts"])
+	delete(entry, "ts")
+	assert.Equal(t, map[string]interface{}{
+		"method":                  "GET",
+		"path":                    "/api/v1/query_range",
+		"user":                    "user-1",
+		"header_x_grafana_user":   "admin",
+		"query_fingerprint":       queryrecorder.Fingerprint("/api/v1/query_range", params),
+		"query_hash":              queryHash(params),
+		"param_start":             "1",
+		"param_end":               "3601",
+		"param_step":              "60",
+		"status_code":             float64(http.StatusOK),
+		"response_time_seconds":   float64(2),
+		"query_wall_time_seconds": float64(0),
+		"queue_time_seconds":      float64(0),
+		"fetched_series_count":    float64(10),
+		"fetched_chunk_bytes":     float64(0),
+		"fetched_chunks_count":    float64(0),
+		"fetched_samples_count":   float64(0),
+		"sharded_queries":         float64(4),
+		"split_queries":           float64(0),
+	}, entry)
+
+	entry = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "/api/v1/labels", entry["path"])
+	assert.Equal(t, float64(http.StatusBadRequest), entry["status_code"])
+	assert.Equal(t, "", entry["query_hash"])
+	assert.NotContains(t, entry, "fetched_series_count")
+}
+
+func TestAuditLog_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAuditLog(Config{Enabled: true}, prometheus.NewPedanticRegistry(), log.NewLogfmtLogger(&buf))
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), a))
+	defer func() { require.NoError(t, services.StopAndAwaitTerminated(context.Background(), a)) }()
+
+	a.Log(newRequest("/api/v1/query", nil), url.Values{"query": []string{"up"}}, time.Second, http.StatusOK, nil)
+	assert.Contains(t, buf.String(), `level=info msg="query audit" method=GET path=/api/v1/query user=user-1`)
+	assert.Contains(t, buf.String(), "query_hash="+queryHash(url.Values{"query": []string{"up"}}))
+}
+
+func TestQueryHash(t *testing.T) {
+	assert.Equal(t, "", queryHash(url.Values{"start": []string{"1"}}))
+	assert.Len(t, queryHash(url.Values{"query": []string{"up"}}), 64)
+	assert.Equal(t, queryHash(url.Values{"query": []string{"up"}, "time": []string{"1"}}), queryHash(url.Values{"query": []string{"up"}, "time": []string{"2"}}))
+	assert.NotEqual(t, queryHash(url.Values{"query": []string{"up"}}), queryHash(url.Values{"query": []string{"down"}}))
+	assert.NotEqual(t, queryHash(url.Values{"match[]": []string{"up", "down"}}), queryHash(url.Values{"match[]": []string{"up"}}))
+}
+
+func newRequest(path string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+}
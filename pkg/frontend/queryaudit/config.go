@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queryaudit
+
+import (
+	"flag"
+
+	"github.com/grafana/dskit/flagext"
+)
+
+type Config struct {
+	Enabled         bool                   `yaml:"enabled" category:"experimental"`
+	File            string                 `yaml:"file" category:"experimental"`
+	IdentityHeaders flagext.StringSliceCSV `yaml:"identity_headers" category:"experimental"`
+}
+
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	c.IdentityHeaders = []string{"X-Grafana-User", "X-Forwarded-For"}
+
+	f.BoolVar(&c.Enabled, "query-frontend.query-audit-log.enabled", false, "Enables the audit log of the queries received by the query-frontend. An entry is written for every query, with the tenant, the identity headers, the fingerprint and the hash of the query, the time range, the status code and the statistics of the query.")
+	f.StringVar(&c.File, "query-frontend.query-audit-log.file", "", "Path of the file the audit log entries are appended to, one JSON object per line. If empty, the entries are written to the logs of the query-frontend.")
+	f.Var(&c.IdentityHeaders, "query-frontend.query-audit-log.identity-headers", "Comma-separated list of the request headers identifying the user who issued the query, as forwarded by the proxies and the clients, included in the audit log entries.")
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 )
 
@@ -46,6 +47,12 @@ var FuncsWithDefaultTimeArg = []string{
 	"year",
 }
 
+// labelsModifyingFuncs is the list of functions that modify the labels of the series, other than dropping the metric name.
+var labelsModifyingFuncs = []string{
+	"label_join",
+	"label_replace",
+}
+
 // CanParallelize tests if a subtree is parallelizable.
 // A subtree is parallelizable if all of its components are parallelizable.
 func CanParallelize(expr parser.Expr, logger log.Logger) bool {
@@ -66,6 +73,11 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 		return err == nil && !nestedAggrs && CanParallelize(e.Expr, logger)
 
 	case *parser.BinaryExpr:
+		// If e.VectorMatching is not nil, then both hands are vector operators.
+		if e.VectorMatching != nil {
+			return canParallelizeVectorMatching(e, logger)
+		}
+
 		// Binary expressions can be parallelised when:
 		// - It's not a bool expr: bool expression should yield only one result, but sharding would provide many.
 		// - One of the sides is a constant scalar value
@@ -80,9 +92,7 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 		parallelisable := func(a, b parser.Expr) bool {
 			return CanParallelize(a, logger) && noAggregates(a) && !isConstantScalar(a) && isConstantScalar(b)
 		}
-		// If e.VectorMatching is not nil, then both hands are vector operators, so none of them is a constant scalar, so we can't shard it.
-		// It is just a shortcut, but the other two operations should imply the same.
-		return e.VectorMatching == nil && !e.ReturnBool && (parallelisable(e.LHS, e.RHS) || parallelisable(e.RHS, e.LHS))
+		return !e.ReturnBool && (parallelisable(e.LHS, e.RHS) || parallelisable(e.RHS, e.LHS))
 
 	case *parser.Call:
 		if e.Func == nil {
@@ -120,6 +130,83 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 	}
 }
 
+// canParallelizeVectorMatching tests if a binary expression between two instant vectors is parallelizable.
+// Series are assigned to shards by the hash of all their labels, including the metric name, while
+// they're matched on all labels but the metric name. The binary expression is parallelizable only if
+// the matching series are guaranteed to belong to the same shard, so when:
+// - The series are matched on all their labels: there's no on() or ignoring() with labels.
+// - All vector selectors on both hands select the same metric name.
+// - The labels of the series are not modified before matching: there are no aggregations, label_replace() or label_join().
+func canParallelizeVectorMatching(e *parser.BinaryExpr, logger log.Logger) bool {
+	if e.VectorMatching.On || len(e.VectorMatching.MatchingLabels) > 0 {
+		return false
+	}
+
+	if !selectsUniqueMetricName(e) {
+		return false
+	}
+
+	if !noAggregates(e) || containsLabelsModifyingFunc(e) {
+		return false
+	}
+
+	return CanParallelize(e.LHS, logger) && CanParallelize(e.RHS, logger)
+}
+
+// selectsUniqueMetricName returns true if all vector selectors in the input expression select the same metric
+// name through an equality matcher. It returns false if there's no vector selector at all.
+func selectsUniqueMetricName(e parser.Expr) bool {
+	var (
+		name  string
+		found bool
+		ok    = true
+	)
+
+	visitNode(e, func(node parser.Node) {
+		selector, isSelector := node.(*parser.VectorSelector)
+		if !isSelector || !ok {
+			return
+		}
+
+		selectorName, hasName := equalMetricNameMatcher(selector)
+		if !hasName || (found && selectorName != name) {
+			ok = false
+			return
+		}
+		name, found = selectorName, true
+	})
+
+	return ok && found
+}
+
+// equalMetricNameMatcher returns the metric name matched by the equality matcher of the vector selector, if any.
+func equalMetricNameMatcher(selector *parser.VectorSelector) (string, bool) {
+	for _, m := range selector.LabelMatchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value, true
+		}
+	}
+	return "", false
+}
+
+// containsLabelsModifyingFunc returns true if the given expr contains a call to a function
+// modifying the labels of the series, other than dropping the metric name.
+func containsLabelsModifyingFunc(e parser.Expr) bool {
+	contains, _ := anyNode(e, func(node parser.Node) (bool, error) {
+		call, ok := node.(*parser.Call)
+		if !ok || call.Func == nil {
+			return false, nil
+		}
+		for _, name := range labelsModifyingFuncs {
+			if call.Func.Name == name {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	return contains
+}
+
 // containsAggregateExpr returns true if the given expr contains an aggregate expression within its children.
 func containsAggregateExpr(e parser.Expr) bool {
 	containsAggregate, _ := anyNode(e, isAggregateExpr)
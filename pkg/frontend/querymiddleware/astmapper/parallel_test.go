@@ -186,6 +186,50 @@ func TestCanParallel_String(t *testing.T) {
 			[10m:])`,
 			false,
 		},
+		{
+			`rate(metric_counter[5m]) / rate(metric_counter[5m] offset 1h)`,
+			true,
+		},
+		{
+			`metric_counter > bool metric_counter offset 1h`,
+			true,
+		},
+		{
+			`metric_counter{foo="bar"} or {__name__="metric_counter",bar="baz"}`,
+			true,
+		},
+		{
+			`metric_counter / ignoring() group_left metric_counter offset 1h`,
+			true,
+		},
+		{
+			`max_over_time((rate(metric_counter[1m]) / rate(metric_counter[1m] offset 1h))[10m:1m])`,
+			true,
+		},
+		{
+			`metric_counter / on(foo) metric_counter offset 1h`,
+			false,
+		},
+		{
+			`metric_counter / ignoring(foo) metric_counter offset 1h`,
+			false,
+		},
+		{
+			`metric_counter / other_metric`,
+			false,
+		},
+		{
+			`{__name__=~"metric_.*"} / metric_counter`,
+			false,
+		},
+		{
+			`sum(metric_counter) / sum(metric_counter offset 1h)`,
+			false,
+		},
+		{
+			`label_replace(metric_counter, "foo", "$1", "bar", "(.*)") / metric_counter`,
+			false,
+		},
 	}
 
 	for i, c := range testExpr {
@@ -402,6 +402,16 @@ func (summer *shardSummer) shardAndSquashAggregateExpr(expr *parser.AggregateExp
 
 // shardBinOp attempts to shard the given binary operation expression.
 func (summer *shardSummer) shardBinOp(expr *parser.BinaryExpr) (mapped parser.Expr, finished bool, err error) {
+	// A parallelizable binary operation between two instant vectors is guaranteed to match
+	// series selected from the same shard, so we can shard both legs whatever the operation is.
+	if expr.VectorMatching != nil {
+		mapped, err = summer.shardAndSquashBinOp(expr)
+		if err != nil {
+			return nil, false, err
+		}
+		return mapped, true, nil
+	}
+
 	switch expr.Op {
 	case parser.GTR,
 		parser.GTE,
@@ -421,10 +431,10 @@ func (summer *shardSummer) shardBinOp(expr *parser.BinaryExpr) (mapped parser.Ex
 // queries, where N is the number of shards and each sub-query queries a different shard
 // with the same binary operation.
 func (summer *shardSummer) shardAndSquashBinOp(expr *parser.BinaryExpr) (parser.Expr, error) {
-	if expr.VectorMatching != nil {
-		// We shouldn't ever reach this point with a vector matching binary expression,
+	if expr.VectorMatching != nil && !canParallelizeVectorMatching(expr, summer.logger) {
+		// We shouldn't ever reach this point with a non parallelizable vector matching binary expression,
 		// but it's better to check twice than completely mess it up with the results.
-		return nil, fmt.Errorf("tried to shard a bin op with vector matching: %s", expr)
+		return nil, fmt.Errorf("tried to shard a bin op with non parallelizable vector matching: %s", expr)
 	}
 
 	children := make([]parser.Expr, 0, summer.shards)
@@ -440,10 +450,11 @@ func (summer *shardSummer) shardAndSquashBinOp(expr *parser.BinaryExpr) (parser.
 		}
 
 		children = append(children, &parser.BinaryExpr{
-			LHS:        shardedLHS,
-			Op:         expr.Op,
-			RHS:        shardedRHS,
-			ReturnBool: expr.ReturnBool,
+			LHS:            shardedLHS,
+			Op:             expr.Op,
+			RHS:            shardedRHS,
+			VectorMatching: expr.VectorMatching,
+			ReturnBool:     expr.ReturnBool,
 		})
 	}
 
@@ -404,6 +404,38 @@ func TestShardSummer(t *testing.T) {
 			concat(`foo > bar`),
 			0,
 		},
+		{
+			// foo{__query_shard__="1_of_3"} always has the matching labels in foo{__query_shard__="1_of_3"} offset 1h.
+			`foo / foo offset 1h`,
+			concatShards(3, `foo{__query_shard__="x_of_y"} / foo{__query_shard__="x_of_y"} offset 1h`),
+			3,
+		},
+		{
+			`rate(foo[1m]) > bool rate(foo[5m])`,
+			concatShards(3, `rate(foo{__query_shard__="x_of_y"}[1m]) > bool rate(foo{__query_shard__="x_of_y"}[5m])`),
+			3,
+		},
+		{
+			`foo{bar="1"} unless foo{baz="2"}`,
+			concatShards(3, `foo{__query_shard__="x_of_y",bar="1"} unless foo{__query_shard__="x_of_y",baz="2"}`),
+			3,
+		},
+		{
+			`sum by (bar) (foo / foo offset 1h)`,
+			`sum by (bar) (` + concatShards(3, `sum by (bar) (foo{__query_shard__="x_of_y"} / foo{__query_shard__="x_of_y"} offset 1h)`) + `)`,
+			3,
+		},
+		{
+			`max_over_time((rate(foo[1m]) - rate(foo[1m] offset 1h))[10m:1m])`,
+			concatShards(3, `max_over_time((rate(foo{__query_shard__="x_of_y"}[1m]) - rate(foo{__query_shard__="x_of_y"}[1m] offset 1h))[10m:1m])`),
+			3,
+		},
+		{
+			// can't shard foo / on(bar) foo offset 1h, because matching series could have different labels and belong to different shards.
+			`foo / on(bar) foo offset 1h`,
+			concat(`foo / on(bar) foo offset 1h`),
+			0,
+		},
 		{
 			// we could shard foo * 2, but since it doesn't reduce the data set, we don't.
 			`foo * 2`,
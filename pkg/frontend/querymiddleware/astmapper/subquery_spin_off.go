@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package astmapper
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	// SubquerySpinOffMetricName is a reserved metric name denoting a special metric which contains a subquery
+	// spun off into a range query.
+	SubquerySpinOffMetricName = "__subquery_spinoff__"
+
+	// SubqueryQueryLabelName is a reserved label name containing the expression of the spun off subquery.
+	SubqueryQueryLabelName = "__query__"
+
+	// SubqueryStepLabelName is a reserved label name containing the step of the spun off subquery.
+	SubqueryStepLabelName = "__step__"
+
+	// subquerySpinOffMinSteps is the minimum number of steps of a subquery to be spun off: the subqueries with
+	// fewer steps are cheap enough to be run as part of the downstream instant queries.
+	subquerySpinOffMinSteps = 10
+)
+
+var errSubqueryNotSpunOff = errors.New("the query has a range vector which can't be run downstream")
+
+// SubquerySpinOffMapperStats holds the statistics of the subquery spin-off mapper.
+type SubquerySpinOffMapperStats struct {
+	spunOffSubqueries int // counter of subqueries spun off into range queries
+	downstreamQueries int // counter of the embedded queries run downstream as instant queries
+}
+
+func NewSubquerySpinOffMapperStats() *SubquerySpinOffMapperStats {
+	return &SubquerySpinOffMapperStats{}
+}
+
+// AddSpunOffSubquery increments the number of subqueries spun off into range queries.
+func (s *SubquerySpinOffMapperStats) AddSpunOffSubquery() {
+	s.spunOffSubqueries++
+}
+
+// GetSpunOffSubqueries returns the number of subqueries spun off into range queries.
+func (s *SubquerySpinOffMapperStats) GetSpunOffSubqueries() int {
+	return s.spunOffSubqueries
+}
+
+// AddDownstreamQuery increments the number of embedded queries run downstream.
+func (s *SubquerySpinOffMapperStats) AddDownstreamQuery() {
+	s.downstreamQueries++
+}
+
+// GetDownstreamQueries returns the number of embedded queries run downstream.
+func (s *SubquerySpinOffMapperStats) GetDownstreamQueries() int {
+	return s.downstreamQueries
+}
+
+type subquerySpinOffMapper struct {
+	stats *SubquerySpinOffMapperStats
+}
+
+// NewSubquerySpinOffMapper creates a mapper which spins off the expensive subqueries of an instant query into
+// range queries: each subquery is replaced by a matrix selector of the SubquerySpinOffMetricName metric, whose
+// labels hold the subquery expression and step. The other subtrees of the query with vector selectors are
+// embedded into queries which are run downstream as instant queries.
+//
+// A subquery is spun off if it has an explicit step, its range has at least subquerySpinOffMinSteps steps,
+// it reads some series and it doesn't use the @ modifier. The subqueries nested into other subqueries are
+// not spun off.
+func NewSubquerySpinOffMapper(stats *SubquerySpinOffMapperStats) ASTMapper {
+	return &subquerySpinOffASTMapper{mapper: NewASTExprMapper(&subquerySpinOffMapper{stats: stats})}
+}
+
+type subquerySpinOffASTMapper struct {
+	mapper ASTExprMapper
+}
+
+// Map implements ASTMapper. The input expr is not mutated.
+func (m *subquerySpinOffASTMapper) Map(expr parser.Expr) (parser.Expr, error) {
+	return cloneAndMap(m.mapper, expr)
+}
+
+// MapExpr implements ExprMapper.
+func (m *subquerySpinOffMapper) MapExpr(expr parser.Expr) (mapped parser.Expr, finished bool, err error) {
+	if subquery, ok := expr.(*parser.SubqueryExpr); ok && canSpinOffSubquery(subquery) {
+		m.stats.AddSpunOffSubquery()
+		mapped, err := spinOffSubquery(subquery)
+		return mapped, true, err
+	}
+
+	// Keep on mapping the subtrees with subqueries which can be spun off.
+	if hasSpinOffSubqueries(expr) {
+		return expr, false, nil
+	}
+
+	hasVectorSelector, err := anyNode(expr, isVectorSelector)
+	if err != nil {
+		return nil, true, err
+	}
+	if !hasVectorSelector {
+		return expr, true, nil
+	}
+
+	// Only the instant vectors can be run downstream, as instant queries.
+	if expr.Type() == parser.ValueTypeVector {
+		m.stats.AddDownstreamQuery()
+		mapped, err := vectorSquasher(expr)
+		return mapped, true, err
+	}
+
+	switch expr.(type) {
+	case *parser.SubqueryExpr, *parser.MatrixSelector:
+		return nil, true, errSubqueryNotSpunOff
+	default:
+		// Map the subtrees of the scalars, like the argument of the scalar() function.
+		return expr, false, nil
+	}
+}
+
+// canSpinOffSubquery returns whether the subquery can be spun off into a range query.
+func canSpinOffSubquery(subquery *parser.SubqueryExpr) bool {
+	if subquery.Step <= 0 || subquery.Range/subquery.Step < subquerySpinOffMinSteps {
+		return false
+	}
+
+	// The @ modifier of a range query is relative to the range query time range, not to the subquery one.
+	if subquery.Timestamp != nil || subquery.StartOrEnd != 0 {
+		return false
+	}
+
+	hasVectorSelector := false
+	hasAtModifier := false
+	visitNode(subquery.Expr, func(node parser.Node) {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			hasVectorSelector = true
+			if n.Timestamp != nil || n.StartOrEnd != 0 {
+				hasAtModifier = true
+			}
+		case *parser.SubqueryExpr:
+			if n.Timestamp != nil || n.StartOrEnd != 0 {
+				hasAtModifier = true
+			}
+		}
+	})
+	return hasVectorSelector && !hasAtModifier
+}
+
+// hasSpinOffSubqueries returns whether the expr has subqueries which can be spun off. The subqueries
+// nested into other subqueries are not taken into account.
+func hasSpinOffSubqueries(expr parser.Node) bool {
+	switch e := expr.(type) {
+	case *parser.SubqueryExpr:
+		return canSpinOffSubquery(e)
+	case nil:
+		return false
+	}
+
+	for _, child := range parser.Children(expr) {
+		if hasSpinOffSubqueries(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// spinOffSubquery returns the matrix selector replacing the subquery, selecting the subquery results
+// over the same range and offset.
+func spinOffSubquery(subquery *parser.SubqueryExpr) (parser.Expr, error) {
+	queryMatcher, err := labels.NewMatcher(labels.MatchEqual, SubqueryQueryLabelName, subquery.Expr.String())
+	if err != nil {
+		return nil, err
+	}
+	stepMatcher, err := labels.NewMatcher(labels.MatchEqual, SubqueryStepLabelName, model.Duration(subquery.Step).String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser.MatrixSelector{
+		VectorSelector: &parser.VectorSelector{
+			Name:           SubquerySpinOffMetricName,
+			LabelMatchers:  []*labels.Matcher{queryMatcher, stepMatcher},
+			OriginalOffset: subquery.OriginalOffset,
+		},
+		Range: subquery.Range,
+	}, nil
+}
+
+// ParseSubqueryStep parses the step of a spun off subquery, from the value of the SubqueryStepLabelName label.
+func ParseSubqueryStep(value string) (time.Duration, error) {
+	step, err := model.ParseDuration(value)
+	return time.Duration(step), err
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package astmapper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubquerySpinOffMapper(t *testing.T) {
+	for _, tt := range []struct {
+		in                        string
+		out                       string
+		expectedSpunOffSubqueries int
+		expectedDownstreamQueries int
+	}{
+		{
+			in:                        `max_over_time((rate(foo[5m]))[1d:1m])`,
+			out:                       `max_over_time(` + spunOffSubquery(`(rate(foo[5m]))`, "1m") + `[1d])`,
+			expectedSpunOffSubqueries: 1,
+		},
+		{
+			in:                        `avg_over_time(sum by (pod) (rate(foo[5m]))[6h:5m] offset 1h)`,
+			out:                       `avg_over_time(` + spunOffSubquery(`sum by(pod) (rate(foo[5m]))`, "5m") + `[6h] offset 1h)`,
+			expectedSpunOffSubqueries: 1,
+		},
+		{
+			in:                        `sum_over_time(foo[1h:1m]) / sum_over_time(bar[1h:1m])`,
+			out:                       `sum_over_time(` + spunOffSubquery(`foo`, "1m") + `[1h]) / sum_over_time(` + spunOffSubquery(`bar`, "1m") + `[1h])`,
+			expectedSpunOffSubqueries: 2,
+		},
+		{
+			in:                        `max_over_time(rate(foo[5m])[1h:1m]) > on (pod) group_left sum by (pod) (bar)`,
+			out:                       `max_over_time(` + spunOffSubquery(`rate(foo[5m])`, "1m") + `[1h]) > on (pod) group_left ` + concat(`sum by (pod) (bar)`),
+			expectedSpunOffSubqueries: 1,
+			expectedDownstreamQueries: 1,
+		},
+		{
+			in:                        `max_over_time(rate(foo[5m])[1h:1m]) * scalar(count(bar))`,
+			out:                       `max_over_time(` + spunOffSubquery(`rate(foo[5m])`, "1m") + `[1h]) * scalar(` + concat(`count(bar)`) + `)`,
+			expectedSpunOffSubqueries: 1,
+			expectedDownstreamQueries: 1,
+		},
+		{
+			in:                        `max_over_time(rate(foo[5m])[1h:1m]) * 2`,
+			out:                       `max_over_time(` + spunOffSubquery(`rate(foo[5m])`, "1m") + `[1h]) * 2`,
+			expectedSpunOffSubqueries: 1,
+		},
+		{
+			// The subqueries nested into a spun off subquery are not spun off.
+			in:                        `max_over_time(max_over_time(foo[1h:1m])[1d:1h])`,
+			out:                       `max_over_time(` + spunOffSubquery(`max_over_time(foo[1h:1m])`, "1h") + `[1d])`,
+			expectedSpunOffSubqueries: 1,
+		},
+		// Queries without subqueries to spin off are fully run downstream.
+		{
+			in:                        `max_over_time(rate(foo[5m])[1h:10m])`,
+			out:                       concat(`max_over_time(rate(foo[5m])[1h:10m])`),
+			expectedDownstreamQueries: 1,
+		},
+		{
+			in:                        `max_over_time(rate(foo[5m])[1h:])`,
+			out:                       concat(`max_over_time(rate(foo[5m])[1h:])`),
+			expectedDownstreamQueries: 1,
+		},
+		{
+			in:                        `max_over_time(rate(foo[5m] @ 1000)[1h:1m])`,
+			out:                       concat(`max_over_time(rate(foo[5m] @ 1000)[1h:1m])`),
+			expectedDownstreamQueries: 1,
+		},
+		{
+			in:                        `sum(rate(foo[5m]))`,
+			out:                       concat(`sum(rate(foo[5m]))`),
+			expectedDownstreamQueries: 1,
+		},
+		{
+			in:  `max_over_time(vector(1)[1h:1m])`,
+			out: `max_over_time(vector(1)[1h:1m])`,
+		},
+	} {
+		tt := tt
+
+		t.Run(tt.in, func(t *testing.T) {
+			stats := NewSubquerySpinOffMapperStats()
+			mapper := NewSubquerySpinOffMapper(stats)
+
+			expr, err := parser.ParseExpr(tt.in)
+			require.NoError(t, err)
+			out, err := parser.ParseExpr(tt.out)
+			require.NoError(t, err)
+
+			mapped, err := mapper.Map(expr)
+			require.NoError(t, err)
+			require.Equal(t, out.String(), mapped.String())
+
+			// The input expression is not mutated.
+			reparsed, err := parser.ParseExpr(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, reparsed.String(), expr.String())
+
+			assert.Equal(t, tt.expectedSpunOffSubqueries, stats.GetSpunOffSubqueries())
+			assert.Equal(t, tt.expectedDownstreamQueries, stats.GetDownstreamQueries())
+		})
+	}
+}
+
+func TestSubquerySpinOffMapper_ShouldFailOnRangeVectorsWhichCantBeRunDownstream(t *testing.T) {
+	for _, query := range []string{
+		`quantile_over_time(scalar(max_over_time(foo[1h:1m])), bar[5m])`,
+		`quantile_over_time(scalar(max_over_time(foo[1h:1m])), bar[1h:10m])`,
+	} {
+		t.Run(query, func(t *testing.T) {
+			expr, err := parser.ParseExpr(query)
+			require.NoError(t, err)
+
+			_, err = NewSubquerySpinOffMapper(NewSubquerySpinOffMapperStats()).Map(expr)
+			require.ErrorIs(t, err, errSubqueryNotSpunOff)
+		})
+	}
+}
+
+func spunOffSubquery(query, step string) string {
+	return fmt.Sprintf(`%s{%s=%q,%s=%q}`, SubquerySpinOffMetricName, SubqueryQueryLabelName, query, SubqueryStepLabelName, step)
+}
@@ -28,6 +28,7 @@ import (
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/downsampling"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 )
@@ -48,6 +49,7 @@ const (
 	statusError = "error"
 
 	totalShardsControlHeader = "Sharding-Control"
+	totalShardsControlParam  = "total_shards"
 
 	// Instant query specific options
 	instantSplitControlHeader = "Instant-Split-Control"
@@ -136,15 +138,34 @@ func (prometheusCodec) MergeResponse(responses ...Response) (Response, error) {
 	// Merge the responses.
 	sort.Sort(byFirstTime(promResponses))
 
+	var warnings [][]string
+	for _, pr := range promResponses {
+		warnings = append(warnings, pr.Warnings)
+	}
+
 	return &PrometheusResponse{
 		Status: statusSuccess,
 		Data: &PrometheusData{
 			ResultType: model.ValMatrix.String(),
 			Result:     matrixMerge(promResponses),
 		},
+		Warnings: mergeWarnings(warnings...),
 	}, nil
 }
 
+// mergeWarnings returns the input warnings deduplicated, preserving their order.
+func mergeWarnings(sets ...[]string) []string {
+	var merged []string
+	for _, set := range sets {
+		for _, w := range set {
+			if !util.StringsContain(merged, w) {
+				merged = append(merged, w)
+			}
+		}
+	}
+	return merged
+}
+
 func (c prometheusCodec) DecodeRequest(_ context.Context, r *http.Request) (Request, error) {
 	switch {
 	case isRangeQuery(r.URL.Path):
@@ -217,6 +238,12 @@ func decodeOptions(r *http.Request, opts *Options) {
 		}
 	}
 
+	// The results of the queries overriding the resolution of the downsampled data aren't cached, because the
+	// cache key doesn't include it.
+	if r.FormValue(downsampling.MaxSourceResolutionParam) != "" {
+		opts.CacheDisabled = true
+	}
+
 	for _, value := range r.Header.Values(totalShardsControlHeader) {
 		shards, err := strconv.ParseInt(value, 10, 32)
 		if err != nil {
@@ -228,6 +255,14 @@ func decodeOptions(r *http.Request, opts *Options) {
 		}
 	}
 
+	// The query parameter takes precedence over the header.
+	if value := r.FormValue(totalShardsControlParam); value != "" {
+		if shards, err := strconv.ParseInt(value, 10, 32); err == nil {
+			opts.TotalShards = int32(shards)
+			opts.ShardingDisabled = opts.TotalShards < 1
+		}
+	}
+
 	for _, value := range r.Header.Values(instantSplitControlHeader) {
 		splitInterval, err := time.ParseDuration(value)
 		if err != nil {
@@ -285,7 +320,7 @@ func (prometheusCodec) DecodeResponse(ctx context.Context, r *http.Response, _ R
 			Body: mustReadAllBody(r),
 		})
 	} else if r.StatusCode == http.StatusTooManyRequests {
-		return nil, apierror.New(apierror.TypeTooManyRequests, string(mustReadAllBody(r)))
+		return nil, apierror.NewWithRetryAfter(apierror.TypeTooManyRequests, string(mustReadAllBody(r)), parseRetryAfter(r.Header))
 	} else if r.StatusCode == http.StatusRequestEntityTooLarge {
 		return nil, apierror.New(apierror.TypeTooLargeEntry, string(mustReadAllBody(r)))
 	}
@@ -462,3 +497,13 @@ func mustReadAllBody(r *http.Response) []byte {
 	body, _ := bodyBuffer(r)
 	return body
 }
+
+// parseRetryAfter returns the duration of the Retry-After header, in seconds, or 0 if it's missing or not a number
+// of seconds.
+func parseRetryAfter(h http.Header) time.Duration {
+	seconds, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
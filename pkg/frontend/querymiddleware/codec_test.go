@@ -12,6 +12,7 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
@@ -133,6 +134,7 @@ func TestDecodeFailedResponse(t *testing.T) {
 	t.Run("too many requests", func(t *testing.T) {
 		_, err := PrometheusCodec.DecodeResponse(context.Background(), &http.Response{
 			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"5"}},
 			Body:       io.NopCloser(strings.NewReader("something failed")),
 		}, nil, log.NewNopLogger())
 		require.Error(t, err)
@@ -141,6 +143,7 @@ func TestDecodeFailedResponse(t *testing.T) {
 		resp, ok := apierror.HTTPResponseFromError(err)
 		require.True(t, ok, "Error should have an HTTPResponse encoded")
 		require.Equal(t, int32(http.StatusTooManyRequests), resp.Code)
+		require.Contains(t, resp.Headers, &httpgrpc.Header{Key: "Retry-After", Values: []string{"5"}})
 	})
 
 	t.Run("too large entry", func(t *testing.T) {
@@ -398,6 +401,36 @@ func TestMergeAPIResponses(t *testing.T) {
 			},
 		},
 
+		{
+			name: "Merging of responses with warnings should deduplicate the warnings.",
+			input: []Response{
+				&PrometheusResponse{
+					Status: statusSuccess,
+					Data: &PrometheusData{
+						ResultType: matrix,
+						Result:     []SampleStream{},
+					},
+					Warnings: []string{"warning 1", "warning 2"},
+				},
+				&PrometheusResponse{
+					Status: statusSuccess,
+					Data: &PrometheusData{
+						ResultType: matrix,
+						Result:     []SampleStream{},
+					},
+					Warnings: []string{"warning 2", "warning 3"},
+				},
+			},
+			expected: &PrometheusResponse{
+				Status: statusSuccess,
+				Data: &PrometheusData{
+					ResultType: matrix,
+					Result:     []SampleStream{},
+				},
+				Warnings: []string{"warning 1", "warning 2", "warning 3"},
+			},
+		},
+
 		{
 			name: "Basic merging of two responses.",
 			input: []Response{
@@ -722,6 +755,16 @@ func Test_DecodeOptions(t *testing.T) {
 				CacheDisabled: true,
 			},
 		},
+		{
+			name: "disable cache when overriding the max source resolution",
+			input: &http.Request{
+				URL:    &url.URL{RawQuery: "max_source_resolution=1h"},
+				Header: http.Header{},
+			},
+			expected: &Options{
+				CacheDisabled: true,
+			},
+		},
 		{
 			name: "custom sharding",
 			input: &http.Request{
@@ -744,6 +787,38 @@ func Test_DecodeOptions(t *testing.T) {
 				ShardingDisabled: true,
 			},
 		},
+		{
+			name: "custom sharding via query parameter",
+			input: &http.Request{
+				URL:    &url.URL{RawQuery: "total_shards=64"},
+				Header: http.Header{},
+			},
+			expected: &Options{
+				TotalShards: 64,
+			},
+		},
+		{
+			name: "custom sharding via query parameter overriding the header",
+			input: &http.Request{
+				URL: &url.URL{RawQuery: "total_shards=64"},
+				Header: http.Header{
+					totalShardsControlHeader: []string{"0"},
+				},
+			},
+			expected: &Options{
+				TotalShards: 64,
+			},
+		},
+		{
+			name: "disable sharding via query parameter",
+			input: &http.Request{
+				URL:    &url.URL{RawQuery: "total_shards=0"},
+				Header: http.Header{},
+			},
+			expected: &Options{
+				ShardingDisabled: true,
+			},
+		},
 		{
 			name: "custom instant query splitting",
 			input: &http.Request{
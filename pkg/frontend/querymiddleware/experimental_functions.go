@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier"
+	"github.com/grafana/mimir/pkg/querier/engine"
+)
+
+// newExperimentalFunctionsMiddleware creates a middleware rejecting the queries using experimental PromQL functions
+// which aren't enabled for all the tenants of the query. It must run before the middlewares evaluating parts of the
+// query in the query-frontend, like the subquery spin-off and the query sharding, whose own queries don't contain
+// all the functions of the original query.
+func newExperimentalFunctionsMiddleware(limits Limits, logger log.Logger) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, r Request) (Response, error) {
+			if !engine.UsesExperimentalFunctions(r.GetQuery()) {
+				return next.Do(ctx, r)
+			}
+
+			expr, err := parser.ParseExpr(r.GetQuery())
+			if err != nil {
+				// The parsing error is returned by the next middlewares.
+				return next.Do(ctx, r)
+			}
+
+			tenantIDs, err := tenant.TenantIDs(ctx)
+			if err != nil {
+				return nil, apierror.New(apierror.TypeBadData, err.Error())
+			}
+
+			if err := querier.CheckExperimentalFunctions(limits, tenantIDs, engine.UsedExperimentalFunctions(expr)); err != nil {
+				level.Debug(logger).Log("msg", "rejecting query using experimental PromQL functions", "query", r.GetQuery(), "err", err)
+				// Same status code as the querier failing the query.
+				return nil, apierror.New(apierror.TypeExec, err.Error())
+			}
+
+			return next.Do(ctx, r)
+		})
+	})
+}
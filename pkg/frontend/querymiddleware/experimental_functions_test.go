@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier/engine"
+)
+
+func TestTripperware_ShouldRejectTheExperimentalFunctionsNotEnabledForTheTenant(t *testing.T) {
+	engine.RegisterExperimentalFunctions()
+
+	tests := map[string]struct {
+		path  string
+		query string
+	}{
+		"range query": {
+			path:  "/api/v1/query_range?start=1536673680&end=1536760080&step=120",
+			query: `mad_over_time(up[5m])`,
+		},
+		"sharded range query": {
+			path:  "/api/v1/query_range?start=1536673680&end=1536760080&step=120",
+			query: `sum(mad_over_time(up[5m]))`,
+		},
+		"sharded instant query": {
+			path:  "/api/v1/query?time=1536673680",
+			query: `sum(mad_over_time(up[5m]))`,
+		},
+		"instant query split by interval": {
+			path:  "/api/v1/query?time=1536673680",
+			query: `sum(mad_over_time(up[3d]))`,
+		},
+		"instant query whose subqueries are spun off": {
+			path:  "/api/v1/query?time=1536673680",
+			query: `mad_over_time(sum(rate(up[5m]))[7d:1m])`,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, enabled := range []bool{false, true} {
+				limits := mockLimits{
+					totalShards:                 4,
+					splitInstantQueriesInterval: 24 * time.Hour,
+					subquerySpinOff:             true,
+				}
+				if enabled {
+					limits.experimentalFunctions = []string{engine.MadOverTimeFunction}
+				}
+
+				tw, err := NewTripperware(
+					Config{ShardedQueries: true, SplitQueriesByInterval: 24 * time.Hour},
+					log.NewNopLogger(),
+					limits,
+					PrometheusCodec,
+					nil,
+					promql.EngineOpts{
+						Logger:     log.NewNopLogger(),
+						MaxSamples: 1000,
+						Timeout:    time.Minute,
+					},
+					nil,
+				)
+				require.NoError(t, err)
+
+				downstreamCalls := atomic.NewInt64(0)
+				rt := tw(RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					downstreamCalls.Inc()
+					return PrometheusCodec.EncodeResponse(r.Context(), &PrometheusResponse{
+						Status: "success",
+						Data:   &PrometheusData{ResultType: "matrix", Result: []SampleStream{}},
+					})
+				}))
+
+				req := httptest.NewRequest(http.MethodGet, testData.path+"&query="+url.QueryEscape(testData.query), nil)
+				req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+				resp, err := rt.RoundTrip(req)
+
+				if enabled {
+					assert.Greater(t, downstreamCalls.Load(), int64(0))
+					continue
+				}
+
+				require.Error(t, err)
+				assert.True(t, apierror.IsAPIError(err))
+				assert.Contains(t, err.Error(), "the query uses the experimental PromQL function mad_over_time")
+				assert.Nil(t, resp)
+				assert.Equal(t, int64(0), downstreamCalls.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+const (
+	// heavyQueriesPathSuffix is the path suffix of the endpoint listing the heaviest queries of the tenant.
+	heavyQueriesPathSuffix = "/api/v1/heavy_queries"
+
+	// The headers set by Grafana on the queries of the dashboard panels.
+	dashboardUIDHeader = "X-Dashboard-Uid"
+	panelIDHeader      = "X-Panel-Id"
+
+	heavyQueriesDefaultLimit = 10
+	heavyQueriesSortWallTime = "wall_time"
+	heavyQueriesSortSamples  = "samples"
+)
+
+type heavyQueriesResponse struct {
+	Status string              `json:"status"`
+	Data   []heavyQueryDetails `json:"data"`
+}
+
+// heavyQueryDetails is the aggregation of the statistics of the runs of a query fingerprint.
+type heavyQueryDetails struct {
+	Query               string    `json:"query"`
+	Type                string    `json:"type"`
+	Count               uint64    `json:"count"`
+	FailedCount         uint64    `json:"failed_count,omitempty"`
+	WallTimeSeconds     float64   `json:"wall_time_seconds"`
+	FetchedSamplesCount uint64    `json:"fetched_samples_count"`
+	LastSeen            time.Time `json:"last_seen"`
+	DashboardUID        string    `json:"dashboard_uid,omitempty"`
+	PanelID             string    `json:"panel_id,omitempty"`
+}
+
+// heavyQuery is the aggregation of the statistics of the runs of a query fingerprint in a window.
+type heavyQuery struct {
+	fingerprint    string
+	query          string
+	queryType      string
+	count          uint64
+	failedCount    uint64
+	wallTime       time.Duration
+	fetchedSamples uint64
+	lastSeen       time.Time
+	dashboardUID   string
+	panelID        string
+
+	// The index of the query in the heavyQueries heap.
+	index int
+}
+
+// heavyQueries are the queries of a tenant in a window, indexed by fingerprint and ordered in a min-heap by wall
+// time, so that the lightest query can be evicted in logarithmic time. It implements heap.Interface.
+type heavyQueries struct {
+	byFingerprint map[string]*heavyQuery
+	byWallTime    []*heavyQuery
+}
+
+func newHeavyQueries() *heavyQueries {
+	return &heavyQueries{byFingerprint: map[string]*heavyQuery{}}
+}
+
+func (h *heavyQueries) Len() int { return len(h.byWallTime) }
+
+func (h *heavyQueries) Less(i, j int) bool { return h.byWallTime[i].wallTime < h.byWallTime[j].wallTime }
+
+func (h *heavyQueries) Swap(i, j int) {
+	h.byWallTime[i], h.byWallTime[j] = h.byWallTime[j], h.byWallTime[i]
+	h.byWallTime[i].index = i
+	h.byWallTime[j].index = j
+}
+
+func (h *heavyQueries) Push(x interface{}) {
+	q := x.(*heavyQuery)
+	q.index = len(h.byWallTime)
+	h.byWallTime = append(h.byWallTime, q)
+	h.byFingerprint[q.fingerprint] = q
+}
+
+func (h *heavyQueries) Pop() interface{} {
+	last := len(h.byWallTime) - 1
+	q := h.byWallTime[last]
+	h.byWallTime[last] = nil
+	h.byWallTime = h.byWallTime[:last]
+	delete(h.byFingerprint, q.fingerprint)
+	return q
+}
+
+// heavyQueriesTracker aggregates, per tenant, the querier wall time and the samples fetched by the query fingerprints,
+// so that the heaviest queries of a tenant, and the dashboards running them, can be listed.
+//
+// The aggregation is rolling: the queries are aggregated in the current window, while the previous window is kept to
+// be listed along with the current one, so the listed statistics span between one and two windows. The memory is
+// bounded by the max number of query fingerprints tracked per tenant in each window: once reached, the fingerprint
+// with the lowest wall time is evicted to track a new one. The failed queries are tracked too, since the heaviest
+// queries are often the ones failing, for example because of the timeout or of the limits.
+type heavyQueriesTracker struct {
+	maxQueries int
+	window     time.Duration
+	now        func() time.Time
+
+	mtx       sync.Mutex
+	rotatedAt time.Time
+	current   map[string]*heavyQueries // Tenant ID -> queries.
+	previous  map[string]*heavyQueries
+}
+
+func newHeavyQueriesTracker(maxQueries int, window time.Duration, now func() time.Time) *heavyQueriesTracker {
+	return &heavyQueriesTracker{
+		maxQueries: maxQueries,
+		window:     window,
+		now:        now,
+		rotatedAt:  now(),
+		current:    map[string]*heavyQueries{},
+		previous:   map[string]*heavyQueries{},
+	}
+}
+
+// add accounts a run of the query to the fingerprint of the query, whether the run failed or not.
+func (t *heavyQueriesTracker) add(tenantID, queryType, query string, wallTime time.Duration, fetchedSamples uint64, failed bool, dashboard queryDashboard) {
+	query = normalizeHeavyQuery(query)
+	fingerprint := queryType + ":" + query
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	now := t.now()
+	t.rotate(now)
+
+	queries, ok := t.current[tenantID]
+	if !ok {
+		queries = newHeavyQueries()
+		t.current[tenantID] = queries
+	}
+
+	q, ok := queries.byFingerprint[fingerprint]
+	if !ok {
+		if queries.Len() >= t.maxQueries {
+			// Evict the lightest query.
+			heap.Pop(queries)
+		}
+		q = &heavyQuery{fingerprint: fingerprint, query: query, queryType: queryType}
+	}
+
+	q.count++
+	if failed {
+		q.failedCount++
+	}
+	q.wallTime += wallTime
+	q.fetchedSamples += fetchedSamples
+	q.lastSeen = now
+	if dashboard.uid != "" {
+		q.dashboardUID, q.panelID = dashboard.uid, dashboard.panelID
+	}
+
+	if ok {
+		heap.Fix(queries, q.index)
+	} else {
+		heap.Push(queries, q)
+	}
+}
+
+// top returns the heaviest queries of the tenant over the current and previous windows, sorted by the given criteria.
+func (t *heavyQueriesTracker) top(tenantID, sortBy string, limit int) []heavyQueryDetails {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.rotate(t.now())
+
+	merged := map[string]*heavyQueryDetails{}
+	for _, queries := range []*heavyQueries{t.previous[tenantID], t.current[tenantID]} {
+		if queries == nil {
+			continue
+		}
+		for fingerprint, q := range queries.byFingerprint {
+			details, ok := merged[fingerprint]
+			if !ok {
+				details = &heavyQueryDetails{Query: q.query, Type: q.queryType}
+				merged[fingerprint] = details
+			}
+
+			details.Count += q.count
+			details.FailedCount += q.failedCount
+			details.WallTimeSeconds += q.wallTime.Seconds()
+			details.FetchedSamplesCount += q.fetchedSamples
+			// The current window is merged last, so it has the most recent run.
+			details.LastSeen = q.lastSeen
+			if q.dashboardUID != "" {
+				details.DashboardUID, details.PanelID = q.dashboardUID, q.panelID
+			}
+		}
+	}
+
+	result := make([]heavyQueryDetails, 0, len(merged))
+	for _, details := range merged {
+		result = append(result, *details)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if sortBy == heavyQueriesSortSamples && result[i].FetchedSamplesCount != result[j].FetchedSamplesCount {
+			return result[i].FetchedSamplesCount > result[j].FetchedSamplesCount
+		}
+		if result[i].WallTimeSeconds != result[j].WallTimeSeconds {
+			return result[i].WallTimeSeconds > result[j].WallTimeSeconds
+		}
+		if !result[i].LastSeen.Equal(result[j].LastSeen) {
+			return result[i].LastSeen.After(result[j].LastSeen)
+		}
+		if result[i].Query != result[j].Query {
+			return result[i].Query < result[j].Query
+		}
+		return result[i].Type < result[j].Type
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// rotate starts a new window if the current one has elapsed. The tenants which haven't run any query in the
+// last two windows are dropped. It must be called with the lock held.
+func (t *heavyQueriesTracker) rotate(now time.Time) {
+	elapsed := now.Sub(t.rotatedAt)
+	if elapsed < t.window {
+		return
+	}
+
+	if elapsed < 2*t.window {
+		t.previous = t.current
+	} else {
+		t.previous = map[string]*heavyQueries{}
+	}
+	t.current = map[string]*heavyQueries{}
+	t.rotatedAt = now
+}
+
+// normalizeHeavyQuery returns the query formatted by the PromQL parser, if it can be parsed, so that the formatting
+// of the query doesn't change its fingerprint. The fingerprint of a query is the same for the runs of the same query
+// expression, regardless of their time range and step, like the refreshes of a dashboard panel.
+func normalizeHeavyQuery(query string) string {
+	if expr, err := parser.ParseExpr(query); err == nil {
+		return expr.String()
+	}
+	return query
+}
+
+// heavyQueriesMiddleware tracks the querier wall time and the samples fetched by the queries, from the query
+// statistics, to the heavyQueriesTracker.
+type heavyQueriesMiddleware struct {
+	next      Handler
+	tracker   *heavyQueriesTracker
+	queryType string
+}
+
+// newHeavyQueriesMiddleware makes a new heavyQueriesMiddleware tracking the queries of the given type.
+func newHeavyQueriesMiddleware(tracker *heavyQueriesTracker, queryType string) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &heavyQueriesMiddleware{
+			next:      next,
+			tracker:   tracker,
+			queryType: queryType,
+		}
+	})
+}
+
+func (m *heavyQueriesMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	// The queries can't be tracked if the query statistics are disabled.
+	stats := querier_stats.FromContext(ctx)
+	if stats == nil {
+		return m.next.Do(ctx, req)
+	}
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	wallTimeBefore, fetchedSamplesBefore := stats.LoadWallTime(), stats.LoadFetchedSamples()
+	resp, err := m.next.Do(ctx, req)
+
+	m.tracker.add(
+		tenant.JoinTenantIDs(tenantIDs),
+		m.queryType,
+		req.GetQuery(),
+		stats.LoadWallTime()-wallTimeBefore,
+		stats.LoadFetchedSamples()-fetchedSamplesBefore,
+		err != nil,
+		queryDashboardFromContext(ctx),
+	)
+	return resp, err
+}
+
+// newHeavyQueriesRoundTripper returns a http.RoundTripper listing the heaviest queries of the tenant.
+func newHeavyQueriesRoundTripper(tracker *heavyQueriesTracker) http.RoundTripper {
+	return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		tenantIDs, err := tenant.TenantIDs(r.Context())
+		if err != nil {
+			return nil, apierror.New(apierror.TypeBadData, err.Error())
+		}
+
+		if err := r.ParseForm(); err != nil {
+			return nil, apierror.New(apierror.TypeBadData, err.Error())
+		}
+
+		limit := heavyQueriesDefaultLimit
+		if value := r.Form.Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, apierror.New(apierror.TypeBadData, "invalid limit: the limit must be a positive integer")
+			}
+		}
+
+		sortBy := r.Form.Get("sort")
+		switch sortBy {
+		case "":
+			sortBy = heavyQueriesSortWallTime
+		case heavyQueriesSortWallTime, heavyQueriesSortSamples:
+		default:
+			return nil, apierror.Newf(apierror.TypeBadData, "invalid sort: the sort must be either %s or %s", heavyQueriesSortWallTime, heavyQueriesSortSamples)
+		}
+
+		body, err := json.Marshal(heavyQueriesResponse{
+			Status: statusSuccess,
+			Data:   tracker.top(tenant.JoinTenantIDs(tenantIDs), sortBy, limit),
+		})
+		if err != nil {
+			return nil, apierror.New(apierror.TypeInternal, err.Error())
+		}
+
+		return &http.Response{
+			Status:        http.StatusText(http.StatusOK),
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       r,
+		}, nil
+	})
+}
+
+// isHeavyQueries returns whether the request path is the one of the endpoint listing the heaviest queries.
+func isHeavyQueries(path string) bool {
+	return strings.HasSuffix(path, heavyQueriesPathSuffix)
+}
+
+// queryDashboard is the dashboard and panel a query has been run from, as set by Grafana in the request headers.
+type queryDashboard struct {
+	uid     string
+	panelID string
+}
+
+type queryDashboardContextKey struct{}
+
+// contextWithQueryDashboard returns a context carrying the dashboard the query request has been run from, if any.
+func contextWithQueryDashboard(ctx context.Context, r *http.Request) context.Context {
+	uid := r.Header.Get(dashboardUIDHeader)
+	if uid == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, queryDashboardContextKey{}, queryDashboard{uid: uid, panelID: r.Header.Get(panelIDHeader)})
+}
+
+// queryDashboardFromContext returns the dashboard the query request of the context has been run from, if any.
+func queryDashboardFromContext(ctx context.Context) queryDashboard {
+	dashboard, _ := ctx.Value(queryDashboardContextKey{}).(queryDashboard)
+	return dashboard
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+func TestHeavyQueriesTracker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := newHeavyQueriesTracker(2, time.Hour, func() time.Time { return now })
+
+	tracker.add("user-1", queryExplainTypeRange, `sum(rate(foo[1m]))`, 2*time.Second, 100, false, queryDashboard{})
+	// The formatting of the query doesn't change its fingerprint.
+	tracker.add("user-1", queryExplainTypeRange, `sum( rate(foo[1m]) )`, time.Second, 50, false, queryDashboard{uid: "dashboard-1", panelID: "3"})
+	tracker.add("user-1", queryExplainTypeInstant, `up`, 500*time.Millisecond, 1000, false, queryDashboard{})
+	tracker.add("user-2", queryExplainTypeRange, `bar`, time.Minute, 1, false, queryDashboard{})
+
+	assert.Equal(t, []heavyQueryDetails{
+		{Query: `sum(rate(foo[1m]))`, Type: queryExplainTypeRange, Count: 2, WallTimeSeconds: 3, FetchedSamplesCount: 150, LastSeen: now, DashboardUID: "dashboard-1", PanelID: "3"},
+		{Query: `up`, Type: queryExplainTypeInstant, Count: 1, WallTimeSeconds: 0.5, FetchedSamplesCount: 1000, LastSeen: now},
+	}, tracker.top("user-1", heavyQueriesSortWallTime, 10))
+
+	// The queries can be sorted by samples fetched and limited.
+	assert.Equal(t, []heavyQueryDetails{
+		{Query: `up`, Type: queryExplainTypeInstant, Count: 1, WallTimeSeconds: 0.5, FetchedSamplesCount: 1000, LastSeen: now},
+	}, tracker.top("user-1", heavyQueriesSortSamples, 1))
+
+	// The same query of another type has another fingerprint, and the lightest query is evicted to track it.
+	tracker.add("user-1", queryExplainTypeInstant, `sum(rate(foo[1m]))`, 4*time.Second, 10, false, queryDashboard{})
+	top := tracker.top("user-1", heavyQueriesSortWallTime, 10)
+	require.Len(t, top, 2)
+	assert.Equal(t, queryExplainTypeInstant, top[0].Type)
+	assert.Equal(t, queryExplainTypeRange, top[1].Type)
+
+	// The failed runs are counted.
+	tracker.add("user-1", queryExplainTypeInstant, `sum(rate(foo[1m]))`, time.Second, 10, true, queryDashboard{})
+	top = tracker.top("user-1", heavyQueriesSortWallTime, 10)
+	require.Len(t, top, 2)
+	assert.Equal(t, uint64(2), top[0].Count)
+	assert.Equal(t, uint64(1), top[0].FailedCount)
+
+	// The queries of the previous window are merged with the ones of the current window.
+	now = now.Add(time.Hour)
+	tracker.add("user-1", queryExplainTypeRange, `sum(rate(foo[1m]))`, time.Second, 50, false, queryDashboard{})
+	top = tracker.top("user-1", heavyQueriesSortWallTime, 10)
+	require.Len(t, top, 2)
+	assert.Equal(t, heavyQueryDetails{Query: `sum(rate(foo[1m]))`, Type: queryExplainTypeInstant, Count: 2, FailedCount: 1, WallTimeSeconds: 5, FetchedSamplesCount: 20, LastSeen: now.Add(-time.Hour)}, top[0])
+	assert.Equal(t, heavyQueryDetails{Query: `sum(rate(foo[1m]))`, Type: queryExplainTypeRange, Count: 3, WallTimeSeconds: 4, FetchedSamplesCount: 200, LastSeen: now, DashboardUID: "dashboard-1", PanelID: "3"}, top[1])
+
+	// The queries older than the previous window are dropped.
+	now = now.Add(2 * time.Hour)
+	assert.Empty(t, tracker.top("user-1", heavyQueriesSortWallTime, 10))
+	assert.Empty(t, tracker.top("user-2", heavyQueriesSortWallTime, 10))
+}
+
+func TestHeavyQueriesTracker_ShouldEvictTheLightestQueries(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := newHeavyQueriesTracker(3, time.Hour, func() time.Time { return now })
+
+	// The wall times are added in an order unrelated to the wall time of the queries.
+	for _, seconds := range []int{5, 2, 8, 1, 9, 3, 7} {
+		tracker.add("user-1", queryExplainTypeInstant, fmt.Sprintf("up{id=\"%d\"}", seconds), time.Duration(seconds)*time.Second, 0, false, queryDashboard{})
+	}
+	// A tracked query becoming heavier is not evicted.
+	tracker.add("user-1", queryExplainTypeInstant, `up{id="7"}`, 10*time.Second, 0, false, queryDashboard{})
+	tracker.add("user-1", queryExplainTypeInstant, `up{id="6"}`, 6*time.Second, 0, false, queryDashboard{})
+
+	var queries []string
+	for _, q := range tracker.top("user-1", heavyQueriesSortWallTime, 10) {
+		queries = append(queries, q.Query)
+	}
+	assert.Equal(t, []string{`up{id="7"}`, `up{id="9"}`, `up{id="6"}`}, queries)
+}
+
+func TestHeavyQueriesMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		statsDisabled   bool
+		downstreamErr   error
+		expectedTracked bool
+	}{
+		"should track the query": {
+			expectedTracked: true,
+		},
+		"should not track the query if the query statistics are disabled": {
+			statsDisabled: true,
+		},
+		"should track the query if it failed": {
+			downstreamErr:   errors.New("failed"),
+			expectedTracked: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			downstream := HandlerFunc(func(ctx context.Context, _ Request) (Response, error) {
+				if stats := querier_stats.FromContext(ctx); stats != nil {
+					stats.AddWallTime(time.Second)
+					stats.AddFetchedSamples(100)
+				}
+				if testData.downstreamErr != nil {
+					return nil, testData.downstreamErr
+				}
+				return &PrometheusResponse{Status: statusSuccess}, nil
+			})
+
+			tracker := newHeavyQueriesTracker(10, time.Hour, time.Now)
+			handler := newHeavyQueriesMiddleware(tracker, queryExplainTypeRange).Wrap(downstream)
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			if !testData.statsDisabled {
+				stats, statsCtx := querier_stats.ContextWithEmptyStats(ctx)
+				// The statistics already accounted by another query aren't tracked.
+				stats.AddWallTime(time.Minute)
+				ctx = statsCtx
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+			req.Header.Set(dashboardUIDHeader, "dashboard-1")
+			req.Header.Set(panelIDHeader, "2")
+			ctx = contextWithQueryDashboard(ctx, req)
+
+			_, err := handler.Do(ctx, &PrometheusRangeQueryRequest{Query: `up`, Start: 0, End: time.Hour.Milliseconds(), Step: time.Minute.Milliseconds()})
+			if testData.downstreamErr != nil {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			top := tracker.top("user-1", heavyQueriesSortWallTime, 10)
+			if !testData.expectedTracked {
+				assert.Empty(t, top)
+				return
+			}
+
+			require.Len(t, top, 1)
+			assert.Equal(t, `up`, top[0].Query)
+			assert.Equal(t, uint64(1), top[0].Count)
+			if testData.downstreamErr != nil {
+				assert.Equal(t, uint64(1), top[0].FailedCount)
+			} else {
+				assert.Zero(t, top[0].FailedCount)
+			}
+			assert.Equal(t, 1.0, top[0].WallTimeSeconds)
+			assert.Equal(t, uint64(100), top[0].FetchedSamplesCount)
+			assert.Equal(t, "dashboard-1", top[0].DashboardUID)
+			assert.Equal(t, "2", top[0].PanelID)
+		})
+	}
+}
+
+func TestHeavyQueriesRoundTripper(t *testing.T) {
+	now := time.Unix(1000, 0).UTC()
+	tracker := newHeavyQueriesTracker(10, time.Hour, func() time.Time { return now })
+	tracker.add("user-1", queryExplainTypeRange, `foo`, 2*time.Second, 10, false, queryDashboard{uid: "dashboard-1", panelID: "1"})
+	tracker.add("user-1", queryExplainTypeInstant, `bar`, time.Second, 20, false, queryDashboard{})
+	tracker.add("user-2", queryExplainTypeRange, `baz`, time.Second, 20, false, queryDashboard{})
+
+	tests := map[string]struct {
+		query         string
+		expectedBody  string
+		expectedError bool
+	}{
+		"should list the heaviest queries by wall time": {
+			expectedBody: `{"status":"success","data":[` +
+				`{"query":"foo","type":"range","count":1,"wall_time_seconds":2,"fetched_samples_count":10,"last_seen":"1970-01-01T00:16:40Z","dashboard_uid":"dashboard-1","panel_id":"1"},` +
+				`{"query":"bar","type":"instant","count":1,"wall_time_seconds":1,"fetched_samples_count":20,"last_seen":"1970-01-01T00:16:40Z"}]}`,
+		},
+		"should list the heaviest queries by samples fetched": {
+			query: "?sort=samples&limit=1",
+			expectedBody: `{"status":"success","data":[` +
+				`{"query":"bar","type":"instant","count":1,"wall_time_seconds":1,"fetched_samples_count":20,"last_seen":"1970-01-01T00:16:40Z"}]}`,
+		},
+		"should fail on an invalid limit": {
+			query:         "?limit=0",
+			expectedError: true,
+		},
+		"should fail on an invalid sort": {
+			query:         "?sort=cpu",
+			expectedError: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/prometheus/api/v1/heavy_queries"+testData.query, nil)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+			resp, err := newHeavyQueriesRoundTripper(tracker).RoundTrip(req)
+			if testData.expectedError {
+				require.Error(t, err)
+				assert.True(t, apierror.IsType(err, apierror.TypeBadData))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, testData.expectedBody, string(body))
+		})
+	}
+}
+
+func TestIsHeavyQueries(t *testing.T) {
+	assert.True(t, isHeavyQueries("/prometheus/api/v1/heavy_queries"))
+	assert.False(t, isHeavyQueries("/prometheus/api/v1/query"))
+}
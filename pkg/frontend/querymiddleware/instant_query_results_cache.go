@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// instantQueryResultsCacheInvalidationPathSuffix is the path suffix of the endpoint invalidating
+	// the cached instant query results of the tenant.
+	instantQueryResultsCacheInvalidationPathSuffix = "/api/v1/cache/instant_queries"
+)
+
+type ruleEvaluationContextKey struct{}
+
+// contextWithRuleEvaluation returns a context marking the request as sent by the ruler to evaluate the rules.
+func contextWithRuleEvaluation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ruleEvaluationContextKey{}, true)
+}
+
+// isRuleEvaluationContext returns whether the request of the context has been sent by the ruler to evaluate the rules.
+func isRuleEvaluationContext(ctx context.Context) bool {
+	v, ok := ctx.Value(ruleEvaluationContextKey{}).(bool)
+	return ok && v
+}
+
+type instantQueryResultsCacheMiddlewareMetrics struct {
+	requests      prometheus.Counter
+	hits          prometheus.Counter
+	invalidations prometheus.Counter
+}
+
+func newInstantQueryResultsCacheMiddlewareMetrics(reg prometheus.Registerer) *instantQueryResultsCacheMiddlewareMetrics {
+	return &instantQueryResultsCacheMiddlewareMetrics{
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_results_cache_requests_total",
+			Help: "Total number of instant queries looked up in the results cache.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_results_cache_hits_total",
+			Help: "Total number of instant queries whose result has been returned from the results cache.",
+		}),
+		invalidations: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_results_cache_invalidations_total",
+			Help: "Total number of invalidations of the cached instant query results of a tenant.",
+		}),
+	}
+}
+
+// instantQueryResultsCacheMiddleware caches, with a short per-tenant TTL, the whole responses of the instant
+// queries, so that the identical queries re-issued many times per minute, like the ones of the dashboards and
+// of the alert previews, are not run again and again. The evaluation time of the queries is aligned to the TTL,
+// so that the queries evaluated at close times share the same cached result. Unlike the results cache, the most
+// recent data is cached too.
+//
+// The cached results of a tenant are invalidated by bumping the tenant's cache generation, which is stored in the
+// cache too: a cached result is only returned if it was stored with the current generation of all its tenants.
+type instantQueryResultsCacheMiddleware struct {
+	next    Handler
+	limits  Limits
+	cache   cache.Cache
+	logger  log.Logger
+	metrics *instantQueryResultsCacheMiddlewareMetrics
+}
+
+// newInstantQueryResultsCacheMiddleware makes a new instantQueryResultsCacheMiddleware.
+func newInstantQueryResultsCacheMiddleware(limits Limits, c cache.Cache, logger log.Logger, metrics *instantQueryResultsCacheMiddlewareMetrics) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &instantQueryResultsCacheMiddleware{
+			next:    next,
+			limits:  limits,
+			cache:   c,
+			logger:  logger,
+			metrics: metrics,
+		}
+	})
+}
+
+func (m *instantQueryResultsCacheMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	// The rule evaluations must run at the exact evaluation time and on the most recent data.
+	ttl := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, m.limits.ResultsCacheTTLForInstantQueries)
+	if req.GetOptions().CacheDisabled || ttl <= 0 || isRuleEvaluationContext(ctx) {
+		return m.next.Do(ctx, req)
+	}
+
+	// Align the evaluation time to the TTL, so that the queries evaluated in the same TTL window share the same result.
+	alignedTime := req.GetStart() - req.GetStart()%ttl.Milliseconds()
+	req = req.WithStartEnd(alignedTime, alignedTime)
+
+	key := instantQueryResultsCacheKey(resultsCacheTenantKey(tenantIDs, m.limits), req)
+
+	// The requests bypassing the results cache still fetch the cache generation, to store their results with it.
+	m.metrics.requests.Inc()
+	cached, generation, ok := m.fetch(ctx, key, tenantIDs)
+	if ok && !isResultsCacheBypassed(ctx) {
+		m.metrics.hits.Inc()
+		resultsCacheStatusFromContext(ctx).recordHit()
+		return cached, nil
+	}
+	resultsCacheStatusFromContext(ctx).recordMiss()
+
+	resp, err := m.next.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if promResp, ok := resp.(*PrometheusResponse); ok && promResp.Status == statusSuccess && isResponseCachable(resp, m.logger) {
+		m.store(ctx, key, generation, req, &PrometheusResponse{
+			Status: promResp.Status,
+			Data:   promResp.Data,
+		}, ttl)
+	}
+
+	return resp, nil
+}
+
+// fetch returns the cached response for the given key, if any, and the current cache generation of the tenants.
+func (m *instantQueryResultsCacheMiddleware) fetch(ctx context.Context, key string, tenantIDs []string) (*PrometheusResponse, string, bool) {
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, m.logger, "instantQueryResultsCache.fetch")
+	defer spanLog.Finish()
+
+	// Fetch the cached response along with the cache generation of each tenant in a single round trip.
+	hashedKey := cacheHashKey(key)
+	hashedGenerationKeys := make([]string, 0, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		hashedGenerationKeys = append(hashedGenerationKeys, cacheHashKey(instantQueryResultsCacheGenerationKey(tenantID)))
+	}
+	found := m.cache.Fetch(ctx, append([]string{hashedKey}, hashedGenerationKeys...))
+
+	generations := make([]string, 0, len(tenantIDs))
+	for _, hashedGenerationKey := range hashedGenerationKeys {
+		generations = append(generations, string(found[hashedGenerationKey]))
+	}
+	generation := strings.Join(generations, ",")
+
+	data, ok := found[hashedKey]
+	if !ok {
+		return nil, generation, false
+	}
+
+	var cached CachedResponse
+	if err := proto.Unmarshal(data, &cached); err != nil {
+		level.Error(spanLog).Log("msg", "error unmarshalling cached response", "err", err)
+		return nil, generation, false
+	}
+
+	// Ensure there's no hashed key collision, and that the response hasn't been invalidated in the meanwhile.
+	if cached.Key != versionedInstantQueryResultsCacheKey(key, generation) || len(cached.Extents) != 1 {
+		return nil, generation, false
+	}
+
+	resp, err := cached.Extents[0].toResponse()
+	if err != nil {
+		level.Error(spanLog).Log("msg", "error decoding cached response", "err", err)
+		return nil, generation, false
+	}
+
+	promResp, ok := resp.(*PrometheusResponse)
+	if !ok {
+		return nil, generation, false
+	}
+
+	// The empty result is decoded as nil, while it must be encoded as an empty list in the JSON response.
+	if promResp.Data != nil && promResp.Data.Result == nil {
+		promResp.Data.Result = []SampleStream{}
+	}
+	return promResp, generation, true
+}
+
+// store stores the response for the given key and cache generation in the cache.
+func (m *instantQueryResultsCacheMiddleware) store(ctx context.Context, key, generation string, req Request, resp *PrometheusResponse, ttl time.Duration) {
+	extent, err := toExtent(ctx, req, resp)
+	if err != nil {
+		level.Error(m.logger).Log("msg", "error encoding the response to cache", "err", err)
+		return
+	}
+
+	buf, err := proto.Marshal(&CachedResponse{
+		Key:     versionedInstantQueryResultsCacheKey(key, generation),
+		Extents: []Extent{extent},
+	})
+	if err != nil {
+		level.Error(m.logger).Log("msg", "error marshalling the response to cache", "err", err)
+		return
+	}
+
+	// The cache generation is only stored in the cached response: the hashed key stays the same across
+	// generations, so that the response cached with an old generation gets overwritten.
+	m.cache.Store(ctx, map[string][]byte{cacheHashKey(key): buf}, ttl)
+}
+
+// newInstantQueryResultsCacheInvalidationRoundTripper returns a http.RoundTripper invalidating the cached instant
+// query results of the tenants of the request, by bumping their cache generation.
+func newInstantQueryResultsCacheInvalidationRoundTripper(limits Limits, c cache.Cache, logger log.Logger, metrics *instantQueryResultsCacheMiddlewareMetrics) http.RoundTripper {
+	return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method != http.MethodDelete {
+			return nil, apierror.New(apierror.TypeBadData, fmt.Sprintf("unsupported method %s, the cached instant query results can only be invalidated with a DELETE request", r.Method))
+		}
+
+		tenantIDs, err := tenant.TenantIDs(r.Context())
+		if err != nil {
+			return nil, apierror.New(apierror.TypeBadData, err.Error())
+		}
+
+		generation := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+		for _, tenantID := range tenantIDs {
+			// The results cached before the invalidation expire within the TTL, so the generation doesn't need to
+			// outlive it: once it's expired, the results cached with it are not returned anymore either.
+			ttl := limits.ResultsCacheTTLForInstantQueries(tenantID)
+			if ttl <= 0 {
+				continue
+			}
+
+			c.Store(r.Context(), map[string][]byte{cacheHashKey(instantQueryResultsCacheGenerationKey(tenantID)): generation}, ttl)
+			metrics.invalidations.Inc()
+			level.Info(logger).Log("msg", "invalidated the cached instant query results", "user", tenantID)
+		}
+
+		return &http.Response{
+			Status:     http.StatusText(http.StatusNoContent),
+			StatusCode: http.StatusNoContent,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    r,
+		}, nil
+	})
+}
+
+// isInstantQueryResultsCacheInvalidation returns whether the request path is the one of the endpoint invalidating
+// the cached instant query results.
+func isInstantQueryResultsCacheInvalidation(path string) bool {
+	return strings.HasSuffix(path, instantQueryResultsCacheInvalidationPathSuffix)
+}
+
+// instantQueryResultsCacheKey returns the cache key of the instant query, whose evaluation time is expected to be aligned.
+func instantQueryResultsCacheKey(tenantID string, req Request) string {
+	return fmt.Sprintf("instant:%s:%s:%d", tenantID, req.GetQuery(), req.GetStart())
+}
+
+// versionedInstantQueryResultsCacheKey returns the cache key of the instant query, including the cache generation
+// of its tenants.
+func versionedInstantQueryResultsCacheKey(key, generation string) string {
+	return key + "@" + generation
+}
+
+// instantQueryResultsCacheGenerationKey returns the cache key of the cache generation of the tenant.
+func instantQueryResultsCacheGenerationKey(tenantID string) string {
+	return "instant-generation:" + tenantID
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestInstantQueryResultsCacheMiddleware(t *testing.T) {
+	resp := &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{ResultType: "vector", Result: []SampleStream{{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "up"}},
+			Samples: []mimirpb.Sample{{TimestampMs: 60 * 1000, Value: 1}},
+		}}},
+	}
+	noStoreResp := &PrometheusResponse{
+		Status:  statusSuccess,
+		Data:    resp.Data,
+		Headers: []*PrometheusResponseHeader{{Name: cacheControlHeader, Values: []string{noStoreValue}}},
+	}
+
+	tests := map[string]struct {
+		limits         mockLimits
+		cacheDisabled  bool
+		ruleEvaluation bool
+		downstreamResp Response
+		downstreamErr  error
+		expectedCached bool
+	}{
+		"should cache the result": {
+			limits:         mockLimits{instantQueriesCacheTTL: time.Minute},
+			downstreamResp: resp,
+			expectedCached: true,
+		},
+		"should not cache the result if the caching of instant queries is disabled": {
+			downstreamResp: resp,
+		},
+		"should not cache the result if the response is not cachable": {
+			limits:         mockLimits{instantQueriesCacheTTL: time.Minute},
+			downstreamResp: noStoreResp,
+		},
+		"should not cache the result if the cache is disabled for the request": {
+			limits:         mockLimits{instantQueriesCacheTTL: time.Minute},
+			cacheDisabled:  true,
+			downstreamResp: resp,
+		},
+		"should not cache the result of a rule evaluation": {
+			limits:         mockLimits{instantQueriesCacheTTL: time.Minute},
+			ruleEvaluation: true,
+			downstreamResp: resp,
+		},
+		"should not cache an error": {
+			limits:        mockLimits{instantQueriesCacheTTL: time.Minute},
+			downstreamErr: apierror.New(apierror.TypeBadData, "parse error"),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var downstreamReqs []Request
+			downstream := HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+				downstreamReqs = append(downstreamReqs, req)
+				return testData.downstreamResp, testData.downstreamErr
+			})
+
+			reg := prometheus.NewPedanticRegistry()
+			metrics := newInstantQueryResultsCacheMiddlewareMetrics(reg)
+			handler := newInstantQueryResultsCacheMiddleware(testData.limits, cache.NewMockCache(), log.NewNopLogger(), metrics).Wrap(downstream)
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			if testData.ruleEvaluation {
+				ctx = contextWithRuleEvaluation(ctx)
+			}
+
+			// The queries are evaluated at different times in the same TTL window.
+			for _, ts := range []int64{61 * 1000, 119 * 1000} {
+				req := &PrometheusInstantQueryRequest{Query: "up", Time: ts, Options: Options{CacheDisabled: testData.cacheDisabled}}
+
+				resp, err := handler.Do(ctx, req)
+				if testData.downstreamErr != nil {
+					require.Error(t, err)
+					assert.Equal(t, testData.downstreamErr.Error(), err.Error())
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, testData.downstreamResp.(*PrometheusResponse).Data, resp.(*PrometheusResponse).Data)
+			}
+
+			expectedDownstreamCalls := 2
+			if testData.expectedCached {
+				expectedDownstreamCalls = 1
+
+				// The evaluation time of the query is aligned to the TTL.
+				assert.Equal(t, int64(60*1000), downstreamReqs[0].GetStart())
+			}
+			assert.Len(t, downstreamReqs, expectedDownstreamCalls)
+			assert.Equal(t, float64(2-expectedDownstreamCalls), testutil.ToFloat64(metrics.hits))
+
+			// A query evaluated in a different TTL window doesn't hit the cache.
+			_, _ = handler.Do(ctx, &PrometheusInstantQueryRequest{Query: "up", Time: 120 * 1000})
+			assert.Len(t, downstreamReqs, expectedDownstreamCalls+1)
+		})
+	}
+}
+
+func TestInstantQueryResultsCacheMiddleware_ShouldInvalidateTheCachedResultsOfTheTenant(t *testing.T) {
+	downstreamCalls := map[string]int{}
+	downstream := HandlerFunc(func(ctx context.Context, _ Request) (Response, error) {
+		userID, err := user.ExtractOrgID(ctx)
+		require.NoError(t, err)
+		downstreamCalls[userID]++
+		return &PrometheusResponse{Status: statusSuccess, Data: &PrometheusData{ResultType: "vector", Result: []SampleStream{}}}, nil
+	})
+
+	c := cache.NewMockCache()
+	limits := mockLimits{instantQueriesCacheTTL: time.Minute}
+	metrics := newInstantQueryResultsCacheMiddlewareMetrics(nil)
+	handler := newInstantQueryResultsCacheMiddleware(limits, c, log.NewNopLogger(), metrics).Wrap(downstream)
+	invalidate := newInstantQueryResultsCacheInvalidationRoundTripper(limits, c, log.NewNopLogger(), metrics)
+
+	query := func(userID string) {
+		_, err := handler.Do(user.InjectOrgID(context.Background(), userID), &PrometheusInstantQueryRequest{Query: "up", Time: 1000})
+		require.NoError(t, err)
+	}
+
+	query("user-1")
+	query("user-2")
+	query("user-1")
+	query("user-2")
+	assert.Equal(t, map[string]int{"user-1": 1, "user-2": 1}, downstreamCalls)
+
+	req, err := http.NewRequest(http.MethodDelete, "/prometheus"+instantQueryResultsCacheInvalidationPathSuffix, nil)
+	require.NoError(t, err)
+	resp, err := invalidate.RoundTrip(req.WithContext(user.InjectOrgID(context.Background(), "user-1")))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.invalidations))
+
+	// Only the cached results of the invalidated tenant are not returned anymore.
+	query("user-1")
+	query("user-2")
+	assert.Equal(t, map[string]int{"user-1": 2, "user-2": 1}, downstreamCalls)
+
+	// The results stored after the invalidation are cached again.
+	query("user-1")
+	assert.Equal(t, map[string]int{"user-1": 2, "user-2": 1}, downstreamCalls)
+}
+
+func TestInstantQueryResultsCacheInvalidationRoundTripper_ShouldRejectNonDeleteRequests(t *testing.T) {
+	invalidate := newInstantQueryResultsCacheInvalidationRoundTripper(mockLimits{}, cache.NewMockCache(), log.NewNopLogger(), newInstantQueryResultsCacheMiddlewareMetrics(nil))
+
+	req, err := http.NewRequest(http.MethodGet, "/prometheus"+instantQueryResultsCacheInvalidationPathSuffix, nil)
+	require.NoError(t, err)
+	_, err = invalidate.RoundTrip(req.WithContext(user.InjectOrgID(context.Background(), "user-1")))
+	require.Error(t, err)
+	assert.True(t, apierror.IsType(err, apierror.TypeBadData))
+}
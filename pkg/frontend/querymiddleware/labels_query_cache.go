@@ -0,0 +1,323 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	labelNamesPathSuffix = "/api/v1/labels"
+	seriesPathSuffix     = "/api/v1/series"
+
+	// labelsQueryCacheTimeAlignment is the alignment of the start and end of the label names, label values and
+	// series requests in their cache key: the start is aligned down and the end is aligned up.
+	labelsQueryCacheTimeAlignment = time.Minute
+)
+
+var labelValuesPathPattern = regexp.MustCompile(`/api/v1/label/([^/]+)/values$`)
+
+type labelsQueryCacheMetrics struct {
+	requests          prometheus.Counter
+	hits              prometheus.Counter
+	tooLargeResponses prometheus.Counter
+}
+
+func newLabelsQueryCacheMetrics(reg prometheus.Registerer) *labelsQueryCacheMetrics {
+	return &labelsQueryCacheMetrics{
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_labels_query_cache_requests_total",
+			Help: "Total number of label names, label values and series requests looked up in the results cache.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_labels_query_cache_hits_total",
+			Help: "Total number of label names, label values and series requests whose response has been returned from the results cache.",
+		}),
+		tooLargeResponses: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_labels_query_cache_too_large_responses_total",
+			Help: "Total number of label names, label values and series responses not cached because they exceed the maximum number of items.",
+		}),
+	}
+}
+
+// labelsQueryCache caches, with a short per-tenant TTL, the whole responses of the label names, label values and
+// series API requests, like the ones Grafana sends to refresh the dashboard variables on every dashboard load.
+// The start and end of the requests are aligned to labelsQueryCacheTimeAlignment in the cache key, so that the
+// requests sent in the same minute share the same cached response. The responses with more items than the per-tenant
+// limit are not cached, so that the high cardinality responses don't evict the rest of the results cache.
+//
+// Both the successful responses, including the empty ones, and the bad request errors are cached: the latter are
+// deterministic, like an invalid matcher.
+type labelsQueryCache struct {
+	next    http.RoundTripper
+	limits  Limits
+	cache   cache.Cache
+	logger  log.Logger
+	metrics *labelsQueryCacheMetrics
+}
+
+// newLabelsQueryCacheRoundTripper returns a http.RoundTripper caching the responses of the label names,
+// label values and series API requests.
+func newLabelsQueryCacheRoundTripper(next http.RoundTripper, limits Limits, c cache.Cache, logger log.Logger, metrics *labelsQueryCacheMetrics) http.RoundTripper {
+	return &labelsQueryCache{
+		next:    next,
+		limits:  limits,
+		cache:   c,
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+func (l *labelsQueryCache) RoundTrip(r *http.Request) (*http.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	ttl := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, l.limits.ResultsCacheTTLForLabelsQuery)
+	if ttl <= 0 || (r.Method != http.MethodGet && r.Method != http.MethodPost) || isCacheDisabled(r) {
+		return l.next.RoundTrip(r)
+	}
+
+	key, err := labelsQueryCacheKey(tenant.JoinTenantIDs(tenantIDs), r)
+	if err != nil {
+		// The invalid requests are rejected downstream.
+		level.Debug(l.logger).Log("msg", "skipped the results cache of the labels query", "err", err)
+		return l.next.RoundTrip(r)
+	}
+
+	l.metrics.requests.Inc()
+	if !isResultsCacheBypassed(r.Context()) {
+		if cached, ok := l.fetch(r, key); ok {
+			l.metrics.hits.Inc()
+			resultsCacheStatusFromContext(r.Context()).recordHit()
+			return cached.toHTTPResponse(r), nil
+		}
+	}
+	resultsCacheStatusFromContext(r.Context()).recordMiss()
+
+	resp, err := l.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if (resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest) || resp.Header.Get("Content-Encoding") != "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if resp.StatusCode == http.StatusOK {
+		items, err := countLabelsQueryResponseItems(body)
+		if err != nil {
+			level.Warn(l.logger).Log("msg", "failed to decode the response of the labels query, not caching the response", "err", err)
+			return resp, nil
+		}
+
+		maxItems := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, l.limits.ResultsCacheMaxLabelsQueryItems)
+		if maxItems > 0 && items > maxItems {
+			l.metrics.tooLargeResponses.Inc()
+			return resp, nil
+		}
+	}
+
+	l.store(r, key, &cachedLabelsQueryResponse{
+		Key:         key,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, ttl)
+	return resp, nil
+}
+
+// fetch returns the cached response for the given key, if any.
+func (l *labelsQueryCache) fetch(r *http.Request, key string) (*cachedLabelsQueryResponse, bool) {
+	spanLog, ctx := spanlogger.NewWithLogger(r.Context(), l.logger, "labelsQueryCache.fetch")
+	defer spanLog.Finish()
+
+	hashedKey := cacheHashKey(key)
+	data, ok := l.cache.Fetch(ctx, []string{hashedKey})[hashedKey]
+	if !ok {
+		return nil, false
+	}
+
+	var cached cachedLabelsQueryResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		level.Error(spanLog).Log("msg", "error unmarshalling cached response", "err", err)
+		return nil, false
+	}
+
+	// Ensure there's no hashed key collision.
+	if cached.Key != key {
+		return nil, false
+	}
+	return &cached, true
+}
+
+// store stores the response for the given key in the cache.
+func (l *labelsQueryCache) store(r *http.Request, key string, resp *cachedLabelsQueryResponse, ttl time.Duration) {
+	buf, err := json.Marshal(resp)
+	if err != nil {
+		level.Error(l.logger).Log("msg", "error marshalling the response to cache", "err", err)
+		return
+	}
+
+	l.cache.Store(r.Context(), map[string][]byte{cacheHashKey(key): buf}, ttl)
+}
+
+// cachedLabelsQueryResponse is the cached HTTP response of a label names, label values or series request.
+type cachedLabelsQueryResponse struct {
+	Key         string `json:"key"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+func (c *cachedLabelsQueryResponse) toHTTPResponse(r *http.Request) *http.Response {
+	header := http.Header{}
+	if c.ContentType != "" {
+		header.Set("Content-Type", c.ContentType)
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(c.StatusCode),
+		StatusCode:    c.StatusCode,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       r,
+	}
+}
+
+// labelsQueryCacheKey returns the cache key of the label names, label values or series request. The start and end of
+// the request are aligned to labelsQueryCacheTimeAlignment, and the series matchers are sorted. The body of the request
+// is preserved, so that the request can still be sent downstream.
+func labelsQueryCacheKey(tenantID string, r *http.Request) (string, error) {
+	var op string
+	switch {
+	case strings.HasSuffix(r.URL.Path, labelNamesPathSuffix):
+		op = "names"
+	case strings.HasSuffix(r.URL.Path, seriesPathSuffix):
+		op = "series"
+	default:
+		matches := labelValuesPathPattern.FindStringSubmatch(r.URL.Path)
+		if matches == nil {
+			return "", fmt.Errorf("unsupported path %s", r.URL.Path)
+		}
+		op = "values:" + matches[1]
+	}
+
+	form, err := parseRequestFormPreservingBody(r)
+	if err != nil {
+		return "", err
+	}
+
+	alignment := labelsQueryCacheTimeAlignment.Milliseconds()
+	if start := form.Get("start"); start != "" {
+		ts, err := util.ParseTime(start)
+		if err != nil {
+			return "", err
+		}
+		form.Set("start", strconv.FormatInt(alignDown(ts, alignment), 10))
+	}
+	if end := form.Get("end"); end != "" {
+		ts, err := util.ParseTime(end)
+		if err != nil {
+			return "", err
+		}
+		form.Set("end", strconv.FormatInt(alignUp(ts, alignment), 10))
+	}
+
+	// The order of the series matchers doesn't change the response. The form is encoded sorted by key.
+	if matchers := form["match[]"]; len(matchers) > 1 {
+		sort.Strings(matchers)
+	}
+
+	return fmt.Sprintf("labels:%s:%s:%s", tenantID, op, form.Encode()), nil
+}
+
+// parseRequestFormPreservingBody returns the URL and body parameters of the request, without consuming its body.
+func parseRequestFormPreservingBody(r *http.Request) (url.Values, error) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	parsed := r.Clone(r.Context())
+	parsed.Body = io.NopCloser(bytes.NewReader(body))
+	if err := parsed.ParseForm(); err != nil {
+		return nil, err
+	}
+	return parsed.Form, nil
+}
+
+// countLabelsQueryResponseItems returns the number of label names, label values or series of the response body.
+func countLabelsQueryResponseItems(body []byte) (int, error) {
+	data := json.Get(body, "data")
+	if err := data.LastError(); err != nil {
+		return 0, err
+	}
+	if data.ValueType() != jsoniter.ArrayValue {
+		return 0, errors.New("the response data is not a list")
+	}
+	return data.Size(), nil
+}
+
+// isLabelsQuery returns whether the request path is the one of the label names, label values or series API.
+func isLabelsQuery(path string) bool {
+	return strings.HasSuffix(path, labelNamesPathSuffix) || strings.HasSuffix(path, seriesPathSuffix) || labelValuesPathPattern.MatchString(path)
+}
+
+// isCacheDisabled returns whether the request disables the results cache with the Cache-Control header.
+func isCacheDisabled(r *http.Request) bool {
+	for _, value := range r.Header.Values(cacheControlHeader) {
+		if strings.Contains(value, noStoreValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func alignDown(ts, alignment int64) int64 {
+	return ts - ts%alignment
+}
+
+func alignUp(ts, alignment int64) int64 {
+	if ts%alignment == 0 {
+		return ts
+	}
+	return alignDown(ts, alignment) + alignment
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/cache"
+)
+
+func TestLabelsQueryCache(t *testing.T) {
+	const (
+		labelNamesBody = `{"status":"success","data":["__name__","job"]}`
+		emptyBody      = `{"status":"success","data":[]}`
+		badRequestBody = `{"status":"error","errorType":"bad_data","error":"invalid matcher"}`
+	)
+
+	tests := map[string]struct {
+		limits           mockLimits
+		method           string
+		path             string
+		cacheControl     string
+		downstreamStatus int
+		downstreamBody   string
+		expectedCached   bool
+		expectedTooLarge bool
+	}{
+		"should cache the label names": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/labels",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   labelNamesBody,
+			expectedCached:   true,
+		},
+		"should cache the label values": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/label/job/values",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   `{"status":"success","data":["a","b"]}`,
+			expectedCached:   true,
+		},
+		"should cache the series": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/series",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   `{"status":"success","data":[{"__name__":"up"}]}`,
+			expectedCached:   true,
+		},
+		"should cache the series requested with POST": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			method:           http.MethodPost,
+			path:             "/prometheus/api/v1/series",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   `{"status":"success","data":[{"__name__":"up"}]}`,
+			expectedCached:   true,
+		},
+		"should cache an empty response": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/labels",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   emptyBody,
+			expectedCached:   true,
+		},
+		"should cache a bad request error": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/labels",
+			downstreamStatus: http.StatusBadRequest,
+			downstreamBody:   badRequestBody,
+			expectedCached:   true,
+		},
+		"should not cache if the caching of the labels queries is disabled": {
+			path:             "/prometheus/api/v1/labels",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   labelNamesBody,
+		},
+		"should not cache if the cache is disabled for the request": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/labels",
+			cacheControl:     noStoreValue,
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   labelNamesBody,
+		},
+		"should not cache a server error": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute},
+			path:             "/prometheus/api/v1/labels",
+			downstreamStatus: http.StatusInternalServerError,
+			downstreamBody:   `{"status":"error","errorType":"internal","error":"unavailable"}`,
+		},
+		"should not cache a response with more items than the limit": {
+			limits:           mockLimits{labelsQueryCacheTTL: time.Minute, labelsQueryCacheMaxItems: 1},
+			path:             "/prometheus/api/v1/labels",
+			downstreamStatus: http.StatusOK,
+			downstreamBody:   labelNamesBody,
+			expectedTooLarge: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var downstreamReqs []url.Values
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				require.NoError(t, r.ParseForm())
+				downstreamReqs = append(downstreamReqs, r.Form)
+
+				return &http.Response{
+					StatusCode: testData.downstreamStatus,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(testData.downstreamBody)),
+				}, nil
+			})
+
+			reg := prometheus.NewPedanticRegistry()
+			metrics := newLabelsQueryCacheMetrics(reg)
+			rt := newLabelsQueryCacheRoundTripper(downstream, testData.limits, cache.NewMockCache(), log.NewNopLogger(), metrics)
+
+			// The requests are sent in the same minute, with the series matchers in a different order.
+			for _, params := range []url.Values{
+				{"start": []string{"1000.5"}, "end": []string{"3610"}, "match[]": []string{"up", `{job="test"}`}},
+				{"start": []string{"1010"}, "end": []string{"3620.123"}, "match[]": []string{`{job="test"}`, "up"}},
+			} {
+				var req *http.Request
+				if testData.method == http.MethodPost {
+					req = httptest.NewRequest(http.MethodPost, testData.path, strings.NewReader(params.Encode()))
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				} else {
+					req = httptest.NewRequest(http.MethodGet, testData.path+"?"+params.Encode(), nil)
+				}
+				if testData.cacheControl != "" {
+					req.Header.Set(cacheControlHeader, testData.cacheControl)
+				}
+				req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+				resp, err := rt.RoundTrip(req)
+				require.NoError(t, err)
+				assert.Equal(t, testData.downstreamStatus, resp.StatusCode)
+				assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, testData.downstreamBody, string(body))
+			}
+
+			expectedDownstreamCalls := 2
+			if testData.expectedCached {
+				expectedDownstreamCalls = 1
+			}
+			require.Len(t, downstreamReqs, expectedDownstreamCalls)
+			assert.Equal(t, float64(2-expectedDownstreamCalls), testutil.ToFloat64(metrics.hits))
+
+			// The request parameters are sent downstream as is.
+			assert.Equal(t, []string{"1000.5"}, downstreamReqs[0]["start"])
+			assert.Equal(t, []string{"up", `{job="test"}`}, downstreamReqs[0]["match[]"])
+
+			expectedTooLarge := 0
+			if testData.expectedTooLarge {
+				expectedTooLarge = 2
+			}
+			assert.Equal(t, float64(expectedTooLarge), testutil.ToFloat64(metrics.tooLargeResponses))
+		})
+	}
+}
+
+func TestLabelsQueryCacheKey(t *testing.T) {
+	key := func(t *testing.T, path string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		key, err := labelsQueryCacheKey("user-1", req)
+		require.NoError(t, err)
+		return key
+	}
+
+	// The start and end are aligned to the minute.
+	assert.Equal(t, key(t, "/api/v1/labels?start=60&end=120"), key(t, "/api/v1/labels?start=60.5&end=119.999"))
+	assert.Equal(t, key(t, "/api/v1/labels?start=60&end=120"), key(t, "/api/v1/labels?start=1970-01-01T00:01:30Z&end=1970-01-01T00:01:30Z"))
+	assert.NotEqual(t, key(t, "/api/v1/labels?start=60&end=120"), key(t, "/api/v1/labels?start=59&end=120"))
+	assert.NotEqual(t, key(t, "/api/v1/labels?start=60&end=120"), key(t, "/api/v1/labels?start=60&end=121"))
+
+	// The label names, label values and series requests don't share the same key.
+	assert.NotEqual(t, key(t, "/api/v1/labels?start=60"), key(t, "/api/v1/series?start=60"))
+	assert.NotEqual(t, key(t, "/api/v1/label/job/values?start=60"), key(t, "/api/v1/label/instance/values?start=60"))
+
+	// The tenant is part of the key.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+	otherTenantKey, err := labelsQueryCacheKey("user-2", req)
+	require.NoError(t, err)
+	assert.NotEqual(t, key(t, "/api/v1/labels"), otherTenantKey)
+
+	// The invalid requests have no key.
+	_, err = labelsQueryCacheKey("user-1", httptest.NewRequest(http.MethodGet, "/api/v1/labels?start=invalid", nil))
+	assert.Error(t, err)
+	_, err = labelsQueryCacheKey("user-1", httptest.NewRequest(http.MethodGet, "/api/v1/metadata", nil))
+	assert.Error(t, err)
+}
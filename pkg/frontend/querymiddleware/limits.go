@@ -14,12 +14,15 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/dskit/tenant"
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier/downsampling"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -42,6 +45,33 @@ type Limits interface {
 	// to prevent caching of very recent results.
 	MaxCacheFreshness(userID string) time.Duration
 
+	// OutOfOrderTimeWindow returns the out-of-order time window of a given tenant.
+	OutOfOrderTimeWindow(userID string) model.Duration
+
+	// ResultsCacheTTLForEmptyResults returns the time to live of the cached responses of the queries
+	// returning an empty result. 0 to disable the caching of empty results.
+	ResultsCacheTTLForEmptyResults(userID string) time.Duration
+
+	// ResultsCacheTTLForErrors returns the time to live of the cached responses of the queries
+	// failing with a deterministic error. 0 to disable the caching of errors.
+	ResultsCacheTTLForErrors(userID string) time.Duration
+
+	// ResultsCacheTTLForInstantQueries returns the time to live of the cached responses of the instant
+	// queries. 0 to disable the caching of instant queries.
+	ResultsCacheTTLForInstantQueries(userID string) time.Duration
+
+	// ResultsCacheTTLForLabelsQuery returns the time to live of the cached responses of the label names,
+	// label values and series API requests. 0 to disable the caching of these requests.
+	ResultsCacheTTLForLabelsQuery(userID string) time.Duration
+
+	// ResultsCacheMaxLabelsQueryItems returns the maximum number of items of a cached response of the
+	// label names, label values and series API requests. 0 to disable the limit.
+	ResultsCacheMaxLabelsQueryItems(userID string) int
+
+	// ResultsCacheBypassEnabled returns whether the read requests of a given tenant can bypass the results
+	// cache with the Cache-Control: no-cache header.
+	ResultsCacheBypassEnabled(userID string) bool
+
 	// QueryShardingTotalShards returns the number of shards to use for a given tenant.
 	QueryShardingTotalShards(userID string) int
 
@@ -49,9 +79,54 @@ type Limits interface {
 	// be run for a given received query. 0 to disable limit.
 	QueryShardingMaxShardedQueries(userID string) int
 
+	// QueryShardingMaxRequestedShards returns the max number of shards a query can request, overriding
+	// the tenant's number of shards. 0 to default to the tenant's number of shards.
+	QueryShardingMaxRequestedShards(userID string) int
+
 	// SplitInstantQueriesByInterval returns the time interval to split instant queries for a given tenant.
 	SplitInstantQueriesByInterval(userID string) time.Duration
 
+	// SubquerySpinOffEnabled returns whether the expensive subqueries of the instant queries of a given tenant
+	// are spun off into range queries.
+	SubquerySpinOffEnabled(userID string) bool
+
+	// QueryResultLabelRules returns the rules applied to the labels of the series in the query results.
+	QueryResultLabelRules(userID string) []validation.ResultLabelRule
+
+	// QueryLoadSheddingEnabled returns whether the read requests of a given tenant, except the rule
+	// evaluations, are rejected.
+	QueryLoadSheddingEnabled(userID string) bool
+
+	// MaxFetchedChunkBytesPerMinute returns the maximum number of chunk bytes that the read requests of a given
+	// tenant can fetch in the last minute. 0 to disable the limit.
+	MaxFetchedChunkBytesPerMinute(userID string) int
+
+	// MaxEstimatedQueryCost returns the maximum estimated cost, in samples, of the instant and range
+	// queries of a given tenant. 0 to disable the limit.
+	MaxEstimatedQueryCost(userID string) int
+
+	// AutoDownsamplingEnabled returns whether the queries of a given tenant read the downsampled data with a
+	// resolution chosen from the query.
+	AutoDownsamplingEnabled(userID string) bool
+
+	// ExperimentalPromQLFunctions returns the experimental PromQL functions the queries of a given tenant can use.
+	ExperimentalPromQLFunctions(userID string) []string
+
+	// RemoteQueryFederationURLs returns the URLs of the remote clusters the instant and range queries
+	// of a given tenant are federated across.
+	RemoteQueryFederationURLs(userID string) []string
+
+	// MaxConcurrentUserQueries returns the maximum number of concurrent read requests of a given tenant, except
+	// the rule evaluations. 0 to disable the limit.
+	MaxConcurrentUserQueries(userID string) int
+
+	// RulerMaxConcurrentQueries returns the maximum number of concurrent rule evaluations of a given tenant.
+	// 0 to disable the limit.
+	RulerMaxConcurrentQueries(userID string) int
+
+	// RulerQueryTimeout returns the timeout of the rule evaluations of a given tenant. 0 to disable the timeout.
+	RulerQueryTimeout(userID string) time.Duration
+
 	// CompactorSplitAndMergeShards returns the number of shards to use when splitting blocks
 	// This method is copied from compactor.ConfigProvider.
 	CompactorSplitAndMergeShards(userID string) int
@@ -252,6 +327,21 @@ func (rth roundTripperHandler) Do(ctx context.Context, r Request) (Response, err
 		return nil, apierror.New(apierror.TypeBadData, err.Error())
 	}
 
+	if priority := queryPriorityFromContext(ctx); priority != "" {
+		request.Header.Set(queue.PriorityHeader, priority)
+	}
+
+	if isRuleEvaluationContext(ctx) {
+		request.Header.Set(queue.QuerySourceHeader, queue.QuerySourceRuler)
+	}
+
+	if resolution := maxSourceResolutionFromContext(ctx); resolution != "" {
+		query := request.URL.Query()
+		query.Set(downsampling.MaxSourceResolutionParam, resolution)
+		request.URL.RawQuery = query.Encode()
+		request.RequestURI = request.URL.String()
+	}
+
 	response, err := rth.next.RoundTrip(request)
 	if err != nil {
 		return nil, err
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -20,6 +21,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 func TestLimitsMiddleware_MaxQueryLookback(t *testing.T) {
@@ -199,17 +201,44 @@ type mockLimits struct {
 	maxQueryLookback            time.Duration
 	maxQueryLength              time.Duration
 	maxCacheFreshness           time.Duration
+	outOfOrderTimeWindow        time.Duration
+	emptyResultsCacheTTL        time.Duration
+	errorsCacheTTL              time.Duration
+	instantQueriesCacheTTL      time.Duration
+	labelsQueryCacheTTL         time.Duration
+	labelsQueryCacheMaxItems    int
+	resultsCacheBypass          bool
 	maxQueryParallelism         int
 	maxShardedQueries           int
+	maxRequestedShards          int
 	splitInstantQueriesInterval time.Duration
+	subquerySpinOff             bool
 	totalShards                 int
 	compactorShards             int
+	resultLabelRules            []validation.ResultLabelRule
+	queryLoadShedding           bool
+	maxFetchedChunkBytesPerMin  int
+	maxEstimatedQueryCost       int
+	remoteQueryFederationURLs   []string
+	experimentalFunctions       []string
+	autoDownsampling            bool
+	maxConcurrentUserQueries    int
+	rulerMaxConcurrentQueries   int
+	rulerQueryTimeout           time.Duration
 }
 
 func (m mockLimits) MaxQueryLookback(string) time.Duration {
 	return m.maxQueryLookback
 }
 
+func (m mockLimits) AutoDownsamplingEnabled(string) bool {
+	return m.autoDownsampling
+}
+
+func (m mockLimits) ExperimentalPromQLFunctions(string) []string {
+	return m.experimentalFunctions
+}
+
 func (m mockLimits) MaxQueryLength(string) time.Duration {
 	return m.maxQueryLength
 }
@@ -225,6 +254,34 @@ func (m mockLimits) MaxCacheFreshness(string) time.Duration {
 	return m.maxCacheFreshness
 }
 
+func (m mockLimits) OutOfOrderTimeWindow(string) model.Duration {
+	return model.Duration(m.outOfOrderTimeWindow)
+}
+
+func (m mockLimits) ResultsCacheTTLForEmptyResults(string) time.Duration {
+	return m.emptyResultsCacheTTL
+}
+
+func (m mockLimits) ResultsCacheTTLForErrors(string) time.Duration {
+	return m.errorsCacheTTL
+}
+
+func (m mockLimits) ResultsCacheTTLForInstantQueries(string) time.Duration {
+	return m.instantQueriesCacheTTL
+}
+
+func (m mockLimits) ResultsCacheTTLForLabelsQuery(string) time.Duration {
+	return m.labelsQueryCacheTTL
+}
+
+func (m mockLimits) ResultsCacheMaxLabelsQueryItems(string) int {
+	return m.labelsQueryCacheMaxItems
+}
+
+func (m mockLimits) ResultsCacheBypassEnabled(string) bool {
+	return m.resultsCacheBypass
+}
+
 func (m mockLimits) QueryShardingTotalShards(string) int {
 	return m.totalShards
 }
@@ -233,10 +290,50 @@ func (m mockLimits) QueryShardingMaxShardedQueries(string) int {
 	return m.maxShardedQueries
 }
 
+func (m mockLimits) QueryShardingMaxRequestedShards(string) int {
+	return m.maxRequestedShards
+}
+
 func (m mockLimits) SplitInstantQueriesByInterval(string) time.Duration {
 	return m.splitInstantQueriesInterval
 }
 
+func (m mockLimits) SubquerySpinOffEnabled(string) bool {
+	return m.subquerySpinOff
+}
+
+func (m mockLimits) QueryResultLabelRules(string) []validation.ResultLabelRule {
+	return m.resultLabelRules
+}
+
+func (m mockLimits) QueryLoadSheddingEnabled(string) bool {
+	return m.queryLoadShedding
+}
+
+func (m mockLimits) MaxFetchedChunkBytesPerMinute(string) int {
+	return m.maxFetchedChunkBytesPerMin
+}
+
+func (m mockLimits) MaxEstimatedQueryCost(string) int {
+	return m.maxEstimatedQueryCost
+}
+
+func (m mockLimits) RemoteQueryFederationURLs(string) []string {
+	return m.remoteQueryFederationURLs
+}
+
+func (m mockLimits) MaxConcurrentUserQueries(string) int {
+	return m.maxConcurrentUserQueries
+}
+
+func (m mockLimits) RulerMaxConcurrentQueries(string) int {
+	return m.rulerMaxConcurrentQueries
+}
+
+func (m mockLimits) RulerQueryTimeout(string) time.Duration {
+	return m.rulerQueryTimeout
+}
+
 func (m mockLimits) CompactorSplitAndMergeShards(userID string) int {
 	return m.compactorShards
 }
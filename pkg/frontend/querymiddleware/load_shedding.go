@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// newLoadSheddingTripperware creates a Tripperware rejecting the read requests of the tenants whose
+// query load is being shed, except the rule evaluations run by the ruler.
+func newLoadSheddingTripperware(limits Limits, logger log.Logger, registerer prometheus.Registerer) Tripperware {
+	rejectedRequests := promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_load_shedding_rejected_requests_total",
+		Help: "Total number of read requests rejected because the tenant's query load is being shed.",
+	})
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if isRuleEvaluation(r) {
+				return next.RoundTrip(r)
+			}
+
+			tenantIDs, err := tenant.TenantIDs(r.Context())
+			if err != nil {
+				return nil, apierror.New(apierror.TypeBadData, err.Error())
+			}
+
+			// The read requests of cross-tenant queries are rejected if the load of any tenant is being shed.
+			for _, tenantID := range tenantIDs {
+				if limits.QueryLoadSheddingEnabled(tenantID) {
+					level.Debug(logger).Log("msg", "rejecting read request because the tenant's query load is being shed", "user", tenantID, "path", r.URL.Path)
+					rejectedRequests.Inc()
+					return nil, apierror.New(apierror.TypeUnavailable, validation.NewQueryLoadSheddingError().Error())
+				}
+			}
+
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// isRuleEvaluation returns whether the request has been sent by the ruler to evaluate the rules, as tagged by the
+// query source header. The query-frontend handler strips the header from the requests which don't carry the query
+// source key shared with the ruler, see -query-frontend.ruler-query-source-key.
+func isRuleEvaluation(r *http.Request) bool {
+	return r.Header.Get(queue.QuerySourceHeader) == queue.QuerySourceRuler
+}
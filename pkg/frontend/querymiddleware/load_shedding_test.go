@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
+)
+
+func TestLoadSheddingTripperware(t *testing.T) {
+	tests := map[string]struct {
+		loadSheddingEnabled bool
+		userAgent           string
+		querySource         string
+		expectedRejected    bool
+	}{
+		"load shedding disabled": {
+			loadSheddingEnabled: false,
+			userAgent:           "Grafana/9.1.0",
+		},
+		"load shedding enabled, ad-hoc query": {
+			loadSheddingEnabled: true,
+			userAgent:           "Grafana/9.1.0",
+			expectedRejected:    true,
+		},
+		"load shedding enabled, query without User-Agent": {
+			loadSheddingEnabled: true,
+			expectedRejected:    true,
+		},
+		"load shedding enabled, rule evaluation": {
+			loadSheddingEnabled: true,
+			userAgent:           "mimir/2.3.0",
+			querySource:         queue.QuerySourceRuler,
+		},
+		"load shedding enabled, ruler User-Agent without query source": {
+			// The rule evaluations are identified by User-Agent in the query-frontend handler, which
+			// sets the query source header of the requests it trusts.
+			loadSheddingEnabled: true,
+			userAgent:           "mimir/2.3.0",
+			expectedRejected:    true,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			limits := mockLimits{queryLoadShedding: testData.loadSheddingEnabled}
+
+			downstreamCalls := 0
+			downstream := RoundTripFunc(func(*http.Request) (*http.Response, error) {
+				downstreamCalls++
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			if testData.userAgent != "" {
+				req.Header.Set("User-Agent", testData.userAgent)
+			}
+			if testData.querySource != "" {
+				req.Header.Set(queue.QuerySourceHeader, testData.querySource)
+			}
+
+			resp, err := newLoadSheddingTripperware(limits, log.NewNopLogger(), reg)(downstream).RoundTrip(req)
+
+			if testData.expectedRejected {
+				require.Error(t, err)
+				assert.True(t, apierror.IsAPIError(err))
+				assert.Contains(t, err.Error(), "the tenant's query load is being shed")
+				assert.Equal(t, 0, downstreamCalls)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, 1, downstreamCalls)
+			}
+
+			expectedRejected := 0
+			if testData.expectedRejected {
+				expectedRejected = 1
+			}
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+				# HELP cortex_query_frontend_load_shedding_rejected_requests_total Total number of read requests rejected because the tenant's query load is being shed.
+				# TYPE cortex_query_frontend_load_shedding_rejected_requests_total counter
+				cortex_query_frontend_load_shedding_rejected_requests_total %d
+			`, expectedRejected)), "cortex_query_frontend_load_shedding_rejected_requests_total"))
+		})
+	}
+}
@@ -287,6 +287,8 @@ type PrometheusResponse struct {
 	ErrorType string                      `protobuf:"bytes,3,opt,name=ErrorType,proto3" json:"errorType,omitempty"`
 	Error     string                      `protobuf:"bytes,4,opt,name=Error,proto3" json:"error,omitempty"`
 	Headers   []*PrometheusResponseHeader `protobuf:"bytes,5,rep,name=Headers,proto3" json:"-"`
+	// The warnings returned by the queriers, for example when the results may be incomplete.
+	Warnings []string `protobuf:"bytes,6,rep,name=Warnings,proto3" json:"warnings,omitempty"`
 }
 
 func (m *PrometheusResponse) Reset()      { *m = PrometheusResponse{} }
@@ -356,6 +358,13 @@ func (m *PrometheusResponse) GetHeaders() []*PrometheusResponseHeader {
 	return nil
 }
 
+func (m *PrometheusResponse) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
 type PrometheusData struct {
 	ResultType string         `protobuf:"bytes,1,opt,name=ResultType,proto3" json:"resultType"`
 	Result     []SampleStream `protobuf:"bytes,2,rep,name=Result,proto3" json:"result"`
@@ -706,70 +715,71 @@ func init() {
 func init() { proto.RegisterFile("model.proto", fileDescriptor_4c16552f9fdb66d8) }
 
 var fileDescriptor_4c16552f9fdb66d8 = []byte{
-	// 993 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcd, 0x6e, 0x1c, 0x45,
-	0x10, 0xde, 0xd9, 0x7f, 0xd7, 0x86, 0xb5, 0x69, 0x5b, 0x62, 0x6c, 0x94, 0x99, 0xd5, 0x28, 0x07,
-	0xf3, 0xe3, 0x35, 0x38, 0xe2, 0x82, 0x04, 0x22, 0x13, 0x5b, 0x8a, 0x11, 0x82, 0xd0, 0xb6, 0x38,
-	0x70, 0x41, 0xbd, 0x3b, 0x9d, 0xdd, 0x21, 0xf3, 0x97, 0x9e, 0x9e, 0x90, 0xbd, 0x21, 0x9e, 0x80,
-	0x23, 0x2f, 0x80, 0xc4, 0x81, 0x33, 0x27, 0x1e, 0x20, 0x47, 0x73, 0x8b, 0x38, 0x0c, 0x78, 0x2d,
-	0x24, 0xb4, 0xa7, 0x3c, 0x02, 0xea, 0xea, 0x99, 0xdd, 0x71, 0x6c, 0x44, 0xb8, 0xec, 0x56, 0x57,
-	0x7d, 0x55, 0xfd, 0xd5, 0x37, 0xd5, 0x05, 0xbd, 0x30, 0xf6, 0x78, 0x30, 0x4c, 0x44, 0x2c, 0x63,
-	0x02, 0x8f, 0x32, 0x2e, 0x66, 0x82, 0x45, 0x13, 0xbe, 0xb3, 0x37, 0xf1, 0xe5, 0x34, 0x1b, 0x0d,
-	0xc7, 0x71, 0xb8, 0x3f, 0x89, 0x27, 0xf1, 0x3e, 0x42, 0x46, 0xd9, 0x03, 0x3c, 0xe1, 0x01, 0x2d,
-	0x9d, 0xba, 0x63, 0x4d, 0xe2, 0x78, 0x12, 0xf0, 0x15, 0xca, 0xcb, 0x04, 0x93, 0x7e, 0x1c, 0x15,
-	0xf1, 0x77, 0xaa, 0xe5, 0x04, 0x7b, 0xc0, 0x22, 0xb6, 0x1f, 0xfa, 0xa1, 0x2f, 0xf6, 0x93, 0x87,
-	0x13, 0x6d, 0x25, 0x23, 0xfd, 0x5f, 0x64, 0x6c, 0xbf, 0x58, 0x91, 0x45, 0x33, 0x1d, 0x72, 0x7e,
-	0xa9, 0xc3, 0xeb, 0xf7, 0x45, 0x1c, 0x72, 0x39, 0xe5, 0x59, 0x4a, 0x15, 0xdf, 0xcf, 0x15, 0x73,
-	0xca, 0x1f, 0x65, 0x3c, 0x95, 0x84, 0x40, 0x33, 0x61, 0x72, 0x6a, 0x1a, 0x03, 0x63, 0x77, 0x8d,
-	0xa2, 0x4d, 0xb6, 0xa0, 0x95, 0x4a, 0x26, 0xa4, 0x59, 0x1f, 0x18, 0xbb, 0x0d, 0xaa, 0x0f, 0x64,
-	0x03, 0x1a, 0x3c, 0xf2, 0xcc, 0x06, 0xfa, 0x94, 0xa9, 0x72, 0x53, 0xc9, 0x13, 0xb3, 0x89, 0x2e,
-	0xb4, 0xc9, 0x07, 0xd0, 0x91, 0x7e, 0xc8, 0xe3, 0x4c, 0x9a, 0xad, 0x81, 0xb1, 0xdb, 0x3b, 0xd8,
-	0x1e, 0x6a, 0x72, 0xc3, 0x92, 0xdc, 0xf0, 0xb0, 0x68, 0xd7, 0xed, 0x3e, 0xcd, 0xed, 0xda, 0x0f,
-	0x7f, 0xd8, 0x06, 0x2d, 0x73, 0xd4, 0xd5, 0x28, 0xac, 0xd9, 0x46, 0x3e, 0xfa, 0x40, 0x6e, 0x43,
-	0x27, 0x4e, 0x54, 0x4a, 0x6a, 0x76, 0xb0, 0xe8, 0xe6, 0x70, 0x25, 0xff, 0xf0, 0x33, 0x1d, 0x72,
-	0x9b, 0xaa, 0x1c, 0x2d, 0x91, 0xa4, 0x0f, 0x75, 0xdf, 0x33, 0xbb, 0xc8, 0xad, 0xee, 0x7b, 0x64,
-	0x0f, 0x5a, 0x53, 0x3f, 0x92, 0xa9, 0xb9, 0x86, 0x25, 0x5e, 0xad, 0x96, 0xb8, 0xa7, 0x02, 0x58,
-	0xc0, 0xa0, 0x1a, 0xe5, 0xfc, 0x66, 0xc0, 0xcd, 0x95, 0x70, 0xc7, 0x51, 0x2a, 0x59, 0x24, 0xff,
-	0x53, 0x3a, 0x02, 0x4d, 0xd5, 0x4a, 0xa1, 0x1c, 0xda, 0xab, 0x9e, 0x1a, 0xff, 0xd2, 0x53, 0xf3,
-	0x7f, 0xf6, 0xd4, 0xba, 0xda, 0x53, 0xfb, 0xa5, 0x7a, 0x3a, 0x05, 0xb3, 0x32, 0x0b, 0x3c, 0x4d,
-	0xe2, 0x28, 0xe5, 0xf7, 0x38, 0xf3, 0xb8, 0x20, 0xdb, 0xd0, 0xfc, 0x94, 0x85, 0x5c, 0x77, 0xe3,
-	0xb6, 0x16, 0xb9, 0x6d, 0xec, 0x51, 0x74, 0x91, 0x9b, 0xd0, 0xfe, 0x82, 0x05, 0x19, 0x4f, 0xcd,
-	0xfa, 0xa0, 0xb1, 0x0a, 0x16, 0x4e, 0xe7, 0xc7, 0x3a, 0x90, 0xab, 0x65, 0x89, 0x03, 0xed, 0x13,
-	0xc9, 0x64, 0x96, 0x16, 0x25, 0x61, 0x91, 0xdb, 0xed, 0x14, 0x3d, 0xb4, 0x88, 0x10, 0x17, 0x9a,
-	0x87, 0x4c, 0x32, 0x94, 0xab, 0x77, 0xb0, 0x53, 0xa5, 0xbf, 0xaa, 0xa8, 0x10, 0x2e, 0x59, 0xe4,
-	0x76, 0xdf, 0x63, 0x92, 0xbd, 0x1d, 0x87, 0xbe, 0xe4, 0x61, 0x22, 0x67, 0x14, 0x73, 0xc9, 0x7b,
-	0xb0, 0x76, 0x24, 0x44, 0x2c, 0x4e, 0x67, 0x09, 0xd7, 0x12, 0xbb, 0xaf, 0x2d, 0x72, 0x7b, 0x93,
-	0x97, 0xce, 0x4a, 0xc6, 0x0a, 0x49, 0xde, 0x80, 0x16, 0x1e, 0x50, 0xfd, 0x35, 0x77, 0x73, 0x91,
-	0xdb, 0xeb, 0x98, 0x52, 0x81, 0x6b, 0x04, 0x39, 0x82, 0x8e, 0x16, 0x29, 0x35, 0x5b, 0x83, 0xc6,
-	0x6e, 0xef, 0xe0, 0xd6, 0xf5, 0x44, 0x2f, 0x2b, 0x5a, 0xca, 0x54, 0xe6, 0x3a, 0xdf, 0x19, 0xd0,
-	0xbf, 0xdc, 0x15, 0x19, 0x02, 0x50, 0x9e, 0x66, 0x81, 0x44, 0xf2, 0x5a, 0xa7, 0xfe, 0x22, 0xb7,
-	0x41, 0x2c, 0xbd, 0xb4, 0x82, 0x20, 0x1f, 0x41, 0x5b, 0x9f, 0xf0, 0x4b, 0xf4, 0x0e, 0xcc, 0x2a,
-	0x91, 0x13, 0x16, 0x26, 0x01, 0x3f, 0x91, 0x82, 0xb3, 0xd0, 0xed, 0xab, 0xc1, 0x51, 0x8a, 0xeb,
-	0x4a, 0xb4, 0xc8, 0x73, 0x7e, 0x35, 0xe0, 0x46, 0x15, 0x48, 0x12, 0x68, 0x07, 0x6c, 0xc4, 0x03,
-	0xf5, 0x99, 0x1a, 0x38, 0x86, 0xe3, 0x58, 0x48, 0xfe, 0x24, 0x19, 0x0d, 0x3f, 0x51, 0xfe, 0xfb,
-	0xcc, 0x17, 0xee, 0x5d, 0x55, 0xed, 0xf7, 0xdc, 0x7e, 0xf7, 0x65, 0x56, 0x93, 0xce, 0xbb, 0xe3,
-	0xb1, 0x44, 0x72, 0xa1, 0x28, 0x84, 0x5c, 0x0a, 0x7f, 0x4c, 0x8b, 0x7b, 0xc8, 0xfb, 0xd0, 0x49,
-	0x91, 0x41, 0x5a, 0x74, 0xb1, 0xb1, 0xba, 0x52, 0x53, 0x5b, 0xb1, 0x7f, 0x8c, 0x23, 0x46, 0xcb,
-	0x04, 0xe7, 0x6b, 0xe8, 0xdf, 0x65, 0xe3, 0x29, 0xf7, 0x96, 0x63, 0xb6, 0x0d, 0x8d, 0x87, 0x7c,
-	0x56, 0x68, 0xd7, 0x59, 0xe4, 0xb6, 0x3a, 0x52, 0xf5, 0xa3, 0x76, 0x11, 0x7f, 0x22, 0xb9, 0x7a,
-	0x1f, 0xfa, 0x22, 0x52, 0x95, 0xeb, 0x08, 0x43, 0xee, 0x7a, 0x71, 0x55, 0x09, 0xa5, 0xa5, 0xe1,
-	0xfc, 0x6c, 0x40, 0x5b, 0x83, 0x88, 0x5d, 0x6e, 0x44, 0x75, 0x4d, 0xc3, 0x5d, 0x5b, 0xe4, 0xb6,
-	0x76, 0x94, 0xcb, 0x71, 0x5b, 0x2f, 0x47, 0x7c, 0xf6, 0x9a, 0x05, 0x8f, 0x3c, 0xbd, 0x25, 0x07,
-	0xd0, 0x95, 0x82, 0x8d, 0xf9, 0x57, 0xbe, 0x57, 0xcc, 0x5a, 0x39, 0x18, 0xe8, 0x3e, 0xf6, 0xc8,
-	0x87, 0xd0, 0x15, 0x45, 0x3b, 0xc5, 0xd2, 0xdc, 0xba, 0xb2, 0x34, 0xef, 0x44, 0x33, 0xf7, 0xc6,
-	0x22, 0xb7, 0x97, 0x48, 0xba, 0xb4, 0x3e, 0x6e, 0x76, 0x1b, 0x1b, 0x4d, 0xe7, 0x2f, 0x03, 0x3a,
-	0xc5, 0xda, 0x20, 0xb7, 0xe0, 0x15, 0x94, 0xe9, 0xd0, 0x4f, 0xd9, 0x28, 0xe0, 0x1e, 0xf2, 0xee,
-	0xd2, 0xcb, 0x4e, 0xf2, 0x26, 0x6c, 0x9c, 0x4c, 0x99, 0xf0, 0xfc, 0x68, 0xb2, 0x04, 0xd6, 0x11,
-	0x78, 0xc5, 0x4f, 0x06, 0xd0, 0x3b, 0x8d, 0x25, 0x0b, 0x30, 0x90, 0xe2, 0x3b, 0x6b, 0xd1, 0xaa,
-	0x8b, 0x1c, 0xc0, 0x56, 0xb1, 0x25, 0x4f, 0x92, 0xc0, 0x97, 0xcb, 0x8a, 0x4d, 0xac, 0x78, 0x6d,
-	0xec, 0xc5, 0x9c, 0xe3, 0x48, 0x72, 0xf1, 0x98, 0x05, 0xc5, 0x86, 0xbb, 0x36, 0xe6, 0xbc, 0x05,
-	0x2d, 0x5c, 0x6d, 0xc4, 0x81, 0x1b, 0x78, 0xbf, 0x5a, 0xca, 0x3e, 0xd7, 0x6b, 0xa6, 0x45, 0x2f,
-	0xf9, 0xdc, 0xa3, 0xb3, 0x73, 0xab, 0xf6, 0xec, 0xdc, 0xaa, 0x3d, 0x3f, 0xb7, 0x8c, 0x6f, 0xe7,
-	0x96, 0xf1, 0xd3, 0xdc, 0x32, 0x9e, 0xce, 0x2d, 0xe3, 0x6c, 0x6e, 0x19, 0x7f, 0xce, 0x2d, 0xe3,
-	0xef, 0xb9, 0x55, 0x7b, 0x3e, 0xb7, 0x8c, 0xef, 0x2f, 0xac, 0xda, 0xd9, 0x85, 0x55, 0x7b, 0x76,
-	0x61, 0xd5, 0xbe, 0x5c, 0xc7, 0x31, 0x09, 0x7d, 0xcf, 0x0b, 0xf8, 0x37, 0x4c, 0xf0, 0x51, 0x1b,
-	0xbf, 0xc3, 0xed, 0x7f, 0x02, 0x00, 0x00, 0xff, 0xff, 0x35, 0xce, 0x26, 0x85, 0x03, 0x08, 0x00,
-	0x00,
+	// 1012 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x4d, 0x6f, 0x1b, 0x55,
+	0x17, 0xf6, 0xf8, 0x3b, 0xc7, 0x79, 0x9d, 0xbc, 0x37, 0x11, 0x4c, 0x82, 0x3a, 0x63, 0x8d, 0xba,
+	0x08, 0x1f, 0x71, 0xc0, 0x15, 0x1b, 0x24, 0x10, 0x9d, 0x26, 0x52, 0x83, 0x10, 0x94, 0x9b, 0x08,
+	0x24, 0x36, 0xe8, 0xda, 0x73, 0x6b, 0x0f, 0x9d, 0xaf, 0xde, 0xb9, 0x6e, 0xeb, 0x1d, 0xe2, 0x17,
+	0xb0, 0xe4, 0x27, 0xb0, 0x60, 0xcd, 0x8a, 0x1f, 0xd0, 0x65, 0xd8, 0x15, 0x16, 0x03, 0x71, 0x84,
+	0x84, 0xbc, 0xea, 0x4f, 0x40, 0xf7, 0xdc, 0x19, 0x7b, 0xd2, 0x04, 0x51, 0x36, 0xc9, 0xb9, 0xe7,
+	0x3c, 0xe7, 0xeb, 0x99, 0xe3, 0x07, 0x3a, 0x61, 0xec, 0xf1, 0xa0, 0x9f, 0x88, 0x58, 0xc6, 0x04,
+	0x1e, 0x4e, 0xb9, 0x98, 0x09, 0x16, 0x8d, 0xf9, 0xee, 0xfe, 0xd8, 0x97, 0x93, 0xe9, 0xb0, 0x3f,
+	0x8a, 0xc3, 0x83, 0x71, 0x3c, 0x8e, 0x0f, 0x10, 0x32, 0x9c, 0xde, 0xc7, 0x17, 0x3e, 0xd0, 0xd2,
+	0xa9, 0xbb, 0xd6, 0x38, 0x8e, 0xc7, 0x01, 0x5f, 0xa1, 0xbc, 0xa9, 0x60, 0xd2, 0x8f, 0xa3, 0x3c,
+	0xfe, 0x76, 0xb9, 0x9c, 0x60, 0xf7, 0x59, 0xc4, 0x0e, 0x42, 0x3f, 0xf4, 0xc5, 0x41, 0xf2, 0x60,
+	0xac, 0xad, 0x64, 0xa8, 0xff, 0xe7, 0x19, 0x3b, 0x2f, 0x56, 0x64, 0xd1, 0x4c, 0x87, 0x9c, 0x9f,
+	0xaa, 0xf0, 0xda, 0x3d, 0x11, 0x87, 0x5c, 0x4e, 0xf8, 0x34, 0xa5, 0x6a, 0xde, 0xcf, 0xd4, 0xe4,
+	0x94, 0x3f, 0x9c, 0xf2, 0x54, 0x12, 0x02, 0xf5, 0x84, 0xc9, 0x89, 0x69, 0xf4, 0x8c, 0xbd, 0x35,
+	0x8a, 0x36, 0xd9, 0x86, 0x46, 0x2a, 0x99, 0x90, 0x66, 0xb5, 0x67, 0xec, 0xd5, 0xa8, 0x7e, 0x90,
+	0x4d, 0xa8, 0xf1, 0xc8, 0x33, 0x6b, 0xe8, 0x53, 0xa6, 0xca, 0x4d, 0x25, 0x4f, 0xcc, 0x3a, 0xba,
+	0xd0, 0x26, 0xef, 0x43, 0x4b, 0xfa, 0x21, 0x8f, 0xa7, 0xd2, 0x6c, 0xf4, 0x8c, 0xbd, 0xce, 0x60,
+	0xa7, 0xaf, 0x87, 0xeb, 0x17, 0xc3, 0xf5, 0x0f, 0xf3, 0x75, 0xdd, 0xf6, 0xd3, 0xcc, 0xae, 0x7c,
+	0xff, 0xbb, 0x6d, 0xd0, 0x22, 0x47, 0xb5, 0x46, 0x62, 0xcd, 0x26, 0xce, 0xa3, 0x1f, 0xe4, 0x16,
+	0xb4, 0xe2, 0x44, 0xa5, 0xa4, 0x66, 0x0b, 0x8b, 0x6e, 0xf5, 0x57, 0xf4, 0xf7, 0x3f, 0xd5, 0x21,
+	0xb7, 0xae, 0xca, 0xd1, 0x02, 0x49, 0xba, 0x50, 0xf5, 0x3d, 0xb3, 0x8d, 0xb3, 0x55, 0x7d, 0x8f,
+	0xec, 0x43, 0x63, 0xe2, 0x47, 0x32, 0x35, 0xd7, 0xb0, 0xc4, 0xff, 0xcb, 0x25, 0xee, 0xaa, 0x00,
+	0x16, 0x30, 0xa8, 0x46, 0x39, 0xbf, 0x18, 0x70, 0x63, 0x45, 0xdc, 0x71, 0x94, 0x4a, 0x16, 0xc9,
+	0x7f, 0xa5, 0x8e, 0x40, 0x5d, 0xad, 0x92, 0x33, 0x87, 0xf6, 0x6a, 0xa7, 0xda, 0x3f, 0xec, 0x54,
+	0xff, 0x8f, 0x3b, 0x35, 0xae, 0xee, 0xd4, 0x7c, 0xa9, 0x9d, 0x4e, 0xc1, 0x2c, 0xdd, 0x02, 0x4f,
+	0x93, 0x38, 0x4a, 0xf9, 0x5d, 0xce, 0x3c, 0x2e, 0xc8, 0x0e, 0xd4, 0x3f, 0x61, 0x21, 0xd7, 0xdb,
+	0xb8, 0x8d, 0x45, 0x66, 0x1b, 0xfb, 0x14, 0x5d, 0xe4, 0x06, 0x34, 0x3f, 0x67, 0xc1, 0x94, 0xa7,
+	0x66, 0xb5, 0x57, 0x5b, 0x05, 0x73, 0xa7, 0xf3, 0x6b, 0x15, 0xc8, 0xd5, 0xb2, 0xc4, 0x81, 0xe6,
+	0x89, 0x64, 0x72, 0x9a, 0xe6, 0x25, 0x61, 0x91, 0xd9, 0xcd, 0x14, 0x3d, 0x34, 0x8f, 0x10, 0x17,
+	0xea, 0x87, 0x4c, 0x32, 0xa4, 0xab, 0x33, 0xd8, 0x2d, 0x8f, 0xbf, 0xaa, 0xa8, 0x10, 0x2e, 0x59,
+	0x64, 0x76, 0xd7, 0x63, 0x92, 0xbd, 0x15, 0x87, 0xbe, 0xe4, 0x61, 0x22, 0x67, 0x14, 0x73, 0xc9,
+	0xbb, 0xb0, 0x76, 0x24, 0x44, 0x2c, 0x4e, 0x67, 0x09, 0xd7, 0x14, 0xbb, 0xaf, 0x2e, 0x32, 0x7b,
+	0x8b, 0x17, 0xce, 0x52, 0xc6, 0x0a, 0x49, 0x5e, 0x87, 0x06, 0x3e, 0x90, 0xfd, 0x35, 0x77, 0x6b,
+	0x91, 0xd9, 0x1b, 0x98, 0x52, 0x82, 0x6b, 0x04, 0x39, 0x82, 0x96, 0x26, 0x29, 0x35, 0x1b, 0xbd,
+	0xda, 0x5e, 0x67, 0x70, 0xf3, 0xfa, 0x41, 0x2f, 0x33, 0x5a, 0xd0, 0x54, 0xe4, 0x92, 0x01, 0xb4,
+	0xbf, 0x60, 0x22, 0xf2, 0xa3, 0xb1, 0xfa, 0x5e, 0x8a, 0xc8, 0x57, 0x16, 0x99, 0x4d, 0x1e, 0xe7,
+	0xbe, 0x52, 0xdf, 0x25, 0xce, 0xf9, 0xd6, 0x80, 0xee, 0x65, 0x26, 0x48, 0x1f, 0x80, 0xf2, 0x74,
+	0x1a, 0x48, 0x5c, 0x58, 0x73, 0xdb, 0x5d, 0x64, 0x36, 0x88, 0xa5, 0x97, 0x96, 0x10, 0xe4, 0x43,
+	0x68, 0xea, 0x17, 0x7e, 0xbd, 0xce, 0xc0, 0x2c, 0x0f, 0x7f, 0xc2, 0xc2, 0x24, 0xe0, 0x27, 0x52,
+	0x70, 0x16, 0xba, 0x5d, 0x75, 0x6c, 0xea, 0x2b, 0xe9, 0x4a, 0x34, 0xcf, 0x73, 0x7e, 0x36, 0x60,
+	0xbd, 0x0c, 0x24, 0x09, 0x34, 0x03, 0x36, 0xe4, 0x81, 0xfa, 0xb4, 0x35, 0x3c, 0xdd, 0x51, 0x2c,
+	0x24, 0x7f, 0x92, 0x0c, 0xfb, 0x1f, 0x2b, 0xff, 0x3d, 0xe6, 0x0b, 0xf7, 0x8e, 0xaa, 0xf6, 0x5b,
+	0x66, 0xbf, 0xf3, 0x32, 0x72, 0xa6, 0xf3, 0x6e, 0x7b, 0x2c, 0x91, 0x5c, 0xa8, 0x11, 0x42, 0x2e,
+	0x85, 0x3f, 0xa2, 0x79, 0x1f, 0xf2, 0x1e, 0xb4, 0x52, 0x9c, 0x20, 0xcd, 0xb7, 0xd8, 0x5c, 0xb5,
+	0xd4, 0xa3, 0xad, 0xa6, 0x7f, 0x84, 0x67, 0x49, 0x8b, 0x04, 0xe7, 0x6b, 0xe8, 0xde, 0x61, 0xa3,
+	0x09, 0xf7, 0x96, 0xa7, 0xb9, 0x03, 0xb5, 0x07, 0x7c, 0x96, 0x73, 0xd7, 0x5a, 0x64, 0xb6, 0x7a,
+	0x52, 0xf5, 0x47, 0xe9, 0x17, 0x7f, 0x22, 0x79, 0x24, 0x8b, 0x46, 0xa4, 0x4c, 0xd7, 0x11, 0x86,
+	0xdc, 0x8d, 0xbc, 0x55, 0x01, 0xa5, 0x85, 0xe1, 0xfc, 0x68, 0x40, 0x53, 0x83, 0x88, 0x5d, 0xa8,
+	0xa8, 0x6a, 0x53, 0x73, 0xd7, 0x16, 0x99, 0xad, 0x1d, 0x85, 0xa0, 0xee, 0x68, 0x41, 0x45, 0xa9,
+	0xd0, 0x53, 0xf0, 0xc8, 0xd3, 0xca, 0xda, 0x83, 0xb6, 0x14, 0x6c, 0xc4, 0xbf, 0xf2, 0xbd, 0xfc,
+	0x3e, 0x8b, 0x63, 0x42, 0xf7, 0xb1, 0x47, 0x3e, 0x80, 0xb6, 0xc8, 0xd7, 0xc9, 0x85, 0x76, 0xfb,
+	0x8a, 0xd0, 0xde, 0x8e, 0x66, 0xee, 0xfa, 0x22, 0xb3, 0x97, 0x48, 0xba, 0xb4, 0x3e, 0xaa, 0xb7,
+	0x6b, 0x9b, 0x75, 0xe7, 0x4f, 0x03, 0x5a, 0xb9, 0xd4, 0x90, 0x9b, 0xf0, 0x3f, 0xa4, 0xe9, 0xd0,
+	0x4f, 0xd9, 0x30, 0xe0, 0x1e, 0xce, 0xdd, 0xa6, 0x97, 0x9d, 0xe4, 0x0d, 0xd8, 0x3c, 0x99, 0x30,
+	0xe1, 0xf9, 0xd1, 0x78, 0x09, 0xac, 0x22, 0xf0, 0x8a, 0x9f, 0xf4, 0xa0, 0x73, 0x1a, 0x4b, 0x16,
+	0x60, 0x20, 0xc5, 0xdf, 0x66, 0x83, 0x96, 0x5d, 0x64, 0x00, 0xdb, 0xb9, 0xb2, 0x9e, 0x24, 0x81,
+	0x2f, 0x97, 0x15, 0xeb, 0x58, 0xf1, 0xda, 0xd8, 0x8b, 0x39, 0xc7, 0x91, 0xe4, 0xe2, 0x11, 0x0b,
+	0x72, 0x55, 0xbc, 0x36, 0xe6, 0xbc, 0x09, 0x0d, 0x94, 0x43, 0xe2, 0xc0, 0x3a, 0xf6, 0x57, 0x42,
+	0xee, 0x73, 0x2d, 0x4d, 0x0d, 0x7a, 0xc9, 0xe7, 0x1e, 0x9d, 0x9d, 0x5b, 0x95, 0x67, 0xe7, 0x56,
+	0xe5, 0xf9, 0xb9, 0x65, 0x7c, 0x33, 0xb7, 0x8c, 0x1f, 0xe6, 0x96, 0xf1, 0x74, 0x6e, 0x19, 0x67,
+	0x73, 0xcb, 0xf8, 0x63, 0x6e, 0x19, 0x7f, 0xcd, 0xad, 0xca, 0xf3, 0xb9, 0x65, 0x7c, 0x77, 0x61,
+	0x55, 0xce, 0x2e, 0xac, 0xca, 0xb3, 0x0b, 0xab, 0xf2, 0xe5, 0x06, 0x9e, 0x49, 0xe8, 0x7b, 0x5e,
+	0xc0, 0x1f, 0x33, 0xc1, 0x87, 0x4d, 0xfc, 0x0e, 0xb7, 0xfe, 0x1e, 0x00, 0x3e, 0xf8, 0xba, 0x74,
+	0x37, 0x08, 0x00, 0x00,
 }
 
 func (this *PrometheusRangeQueryRequest) Equal(that interface{}) bool {
@@ -930,6 +940,14 @@ func (this *PrometheusResponse) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if len(this.Warnings) != len(that1.Warnings) {
+		return false
+	}
+	for i := range this.Warnings {
+		if this.Warnings[i] != that1.Warnings[i] {
+			return false
+		}
+	}
 	return true
 }
 func (this *PrometheusData) Equal(that interface{}) bool {
@@ -1178,7 +1196,7 @@ func (this *PrometheusResponse) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 10)
 	s = append(s, "&querymiddleware.PrometheusResponse{")
 	s = append(s, "Status: "+fmt.Sprintf("%#v", this.Status)+",\n")
 	if this.Data != nil {
@@ -1189,6 +1207,7 @@ func (this *PrometheusResponse) GoString() string {
 	if this.Headers != nil {
 		s = append(s, "Headers: "+fmt.Sprintf("%#v", this.Headers)+",\n")
 	}
+	s = append(s, "Warnings: "+fmt.Sprintf("%#v", this.Warnings)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1505,6 +1524,15 @@ func (m *PrometheusResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Warnings) > 0 {
+		for iNdEx := len(m.Warnings) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Warnings[iNdEx])
+			copy(dAtA[i:], m.Warnings[iNdEx])
+			i = encodeVarintModel(dAtA, i, uint64(len(m.Warnings[iNdEx])))
+			i--
+			dAtA[i] = 0x32
+		}
+	}
 	if len(m.Headers) > 0 {
 		for iNdEx := len(m.Headers) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1961,6 +1989,12 @@ func (m *PrometheusResponse) Size() (n int) {
 			n += 1 + l + sovModel(uint64(l))
 		}
 	}
+	if len(m.Warnings) > 0 {
+		for _, s := range m.Warnings {
+			l = len(s)
+			n += 1 + l + sovModel(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -2147,6 +2181,7 @@ func (this *PrometheusResponse) String() string {
 		`ErrorType:` + fmt.Sprintf("%v", this.ErrorType) + `,`,
 		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
 		`Headers:` + repeatedStringForHeaders + `,`,
+		`Warnings:` + fmt.Sprintf("%v", this.Warnings) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -3075,6 +3110,38 @@ func (m *PrometheusResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Warnings", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowModel
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthModel
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthModel
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Warnings = append(m.Warnings, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipModel(dAtA[iNdEx:])
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+type negativeResultsCacheMiddlewareMetrics struct {
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+func newNegativeResultsCacheMiddlewareMetrics(reg prometheus.Registerer) *negativeResultsCacheMiddlewareMetrics {
+	return &negativeResultsCacheMiddlewareMetrics{
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_negative_results_cache_requests_total",
+			Help: "Total number of queries looked up in the cache of empty results and errors.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_negative_results_cache_hits_total",
+			Help: "Total number of queries whose empty result or error has been returned from the cache.",
+		}),
+	}
+}
+
+// negativeResultsCacheMiddleware caches, with a short per-tenant TTL, the whole responses of the queries returning
+// an empty result or failing with a deterministic error, so that identical queries, like the ones of the broken
+// panels of a dashboard, are not run again and again. Unlike the results cache, the most recent data is cached too.
+type negativeResultsCacheMiddleware struct {
+	next          Handler
+	limits        Limits
+	cache         cache.Cache
+	splitInterval time.Duration
+	logger        log.Logger
+	metrics       *negativeResultsCacheMiddlewareMetrics
+}
+
+// newNegativeResultsCacheMiddleware makes a new negativeResultsCacheMiddleware. The cache keys of the queries are
+// aligned to the given split interval, like the results cache ones, 0 to match the queries time range exactly.
+func newNegativeResultsCacheMiddleware(limits Limits, c cache.Cache, splitInterval time.Duration, logger log.Logger, metrics *negativeResultsCacheMiddlewareMetrics) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &negativeResultsCacheMiddleware{
+			next:          next,
+			limits:        limits,
+			cache:         c,
+			splitInterval: splitInterval,
+			logger:        logger,
+			metrics:       metrics,
+		}
+	})
+}
+
+func (m *negativeResultsCacheMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	emptyResultsTTL := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, m.limits.ResultsCacheTTLForEmptyResults)
+	errorsTTL := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, m.limits.ResultsCacheTTLForErrors)
+	if req.GetOptions().CacheDisabled || (emptyResultsTTL <= 0 && errorsTTL <= 0) {
+		return m.next.Do(ctx, req)
+	}
+
+	key := negativeResultsCacheKey(resultsCacheTenantKey(tenantIDs, m.limits), req, m.splitInterval)
+
+	// A miss isn't recorded in the results cache status: the results which aren't empty are looked up in the
+	// other results caches downstream.
+	m.metrics.requests.Inc()
+	if !isResultsCacheBypassed(ctx) {
+		if cached, ok := m.fetch(ctx, key); ok {
+			m.metrics.hits.Inc()
+			resultsCacheStatusFromContext(ctx).recordHit()
+			if cached.Status == statusError {
+				return nil, apierror.New(apierror.Type(cached.ErrorType), cached.Error)
+			}
+			return cached, nil
+		}
+	}
+
+	resp, err := m.next.Do(ctx, req)
+	if err != nil {
+		// Only the bad data errors, like the query parse errors, are deterministic.
+		if errorsTTL > 0 && apierror.IsType(err, apierror.TypeBadData) {
+			m.store(ctx, key, req, &PrometheusResponse{
+				Status:    statusError,
+				ErrorType: string(apierror.TypeBadData),
+				Error:     err.Error(),
+			}, errorsTTL)
+		}
+		return nil, err
+	}
+
+	if emptyResultsTTL > 0 && isEmptyResponse(resp) && isResponseCachable(resp, m.logger) {
+		promResp := resp.(*PrometheusResponse)
+		m.store(ctx, key, req, &PrometheusResponse{
+			Status: promResp.Status,
+			Data:   promResp.Data,
+		}, emptyResultsTTL)
+	}
+
+	return resp, nil
+}
+
+// fetch returns the cached response for the given key, if any.
+func (m *negativeResultsCacheMiddleware) fetch(ctx context.Context, key string) (*PrometheusResponse, bool) {
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, m.logger, "negativeResultsCache.fetch")
+	defer spanLog.Finish()
+
+	hashedKey := cacheHashKey(key)
+	data, ok := m.cache.Fetch(ctx, []string{hashedKey})[hashedKey]
+	if !ok {
+		return nil, false
+	}
+
+	var cached CachedResponse
+	if err := proto.Unmarshal(data, &cached); err != nil {
+		level.Error(spanLog).Log("msg", "error unmarshalling cached response", "err", err)
+		return nil, false
+	}
+
+	// Ensure there's no hashed key collision.
+	if cached.Key != key || len(cached.Extents) != 1 {
+		return nil, false
+	}
+
+	resp, err := cached.Extents[0].toResponse()
+	if err != nil {
+		level.Error(spanLog).Log("msg", "error decoding cached response", "err", err)
+		return nil, false
+	}
+
+	promResp, ok := resp.(*PrometheusResponse)
+	if !ok {
+		return nil, false
+	}
+
+	// The empty result is decoded as nil, while it must be encoded as an empty list in the JSON response.
+	if promResp.Data != nil && promResp.Data.Result == nil {
+		promResp.Data.Result = []SampleStream{}
+	}
+	return promResp, true
+}
+
+// store stores the response for the given key in the cache.
+func (m *negativeResultsCacheMiddleware) store(ctx context.Context, key string, req Request, resp *PrometheusResponse, ttl time.Duration) {
+	extent, err := toExtent(ctx, req, resp)
+	if err != nil {
+		level.Error(m.logger).Log("msg", "error encoding the response to cache", "err", err)
+		return
+	}
+
+	buf, err := proto.Marshal(&CachedResponse{
+		Key:     key,
+		Extents: []Extent{extent},
+	})
+	if err != nil {
+		level.Error(m.logger).Log("msg", "error marshalling the response to cache", "err", err)
+		return
+	}
+
+	m.cache.Store(ctx, map[string][]byte{cacheHashKey(key): buf}, ttl)
+}
+
+// negativeResultsCacheKey returns the cache key of the request. Like the results cache keys, the start and the end
+// of the request are aligned to the split interval, so that the queries whose time range moves forward on each
+// refresh of a dashboard keep hitting the cache within the short TTL. The exact time range is used if the split
+// interval is 0.
+func negativeResultsCacheKey(tenantID string, req Request, splitInterval time.Duration) string {
+	start, end := req.GetStart(), req.GetEnd()
+	if interval := splitInterval.Milliseconds(); interval > 0 {
+		start, end = start/interval, end/interval
+	}
+	return fmt.Sprintf("negative:%s:%s:%d:%d:%d", tenantID, req.GetQuery(), req.GetStep(), start, end)
+}
+
+// isEmptyResponse returns whether the response is a successful query response with an empty result.
+func isEmptyResponse(resp Response) bool {
+	promResp, ok := resp.(*PrometheusResponse)
+	if !ok || promResp.Status != statusSuccess || promResp.Data == nil {
+		return false
+	}
+	return len(promResp.Data.Result) == 0
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestNegativeResultsCacheMiddleware(t *testing.T) {
+	emptyResp := &PrometheusResponse{
+		Status: statusSuccess,
+		Data:   &PrometheusData{ResultType: "matrix", Result: []SampleStream{}},
+	}
+	nonEmptyResp := &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{ResultType: "matrix", Result: []SampleStream{{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "up"}},
+			Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}},
+		}}},
+	}
+	noStoreEmptyResp := &PrometheusResponse{
+		Status:  statusSuccess,
+		Data:    &PrometheusData{ResultType: "matrix", Result: []SampleStream{}},
+		Headers: []*PrometheusResponseHeader{{Name: cacheControlHeader, Values: []string{noStoreValue}}},
+	}
+
+	tests := map[string]struct {
+		limits             mockLimits
+		cacheDisabled      bool
+		downstreamResp     Response
+		downstreamErr      error
+		expectedCached     bool
+		expectedErrorCache bool
+	}{
+		"should cache an empty result": {
+			limits:         mockLimits{emptyResultsCacheTTL: time.Minute},
+			downstreamResp: emptyResp,
+			expectedCached: true,
+		},
+		"should not cache an empty result if the caching of empty results is disabled": {
+			limits:         mockLimits{errorsCacheTTL: time.Minute},
+			downstreamResp: emptyResp,
+		},
+		"should not cache a non-empty result": {
+			limits:         mockLimits{emptyResultsCacheTTL: time.Minute},
+			downstreamResp: nonEmptyResp,
+		},
+		"should not cache an empty result if the response is not cachable": {
+			limits:         mockLimits{emptyResultsCacheTTL: time.Minute},
+			downstreamResp: noStoreEmptyResp,
+		},
+		"should not cache an empty result if the cache is disabled for the request": {
+			limits:         mockLimits{emptyResultsCacheTTL: time.Minute},
+			cacheDisabled:  true,
+			downstreamResp: emptyResp,
+		},
+		"should cache a bad data error": {
+			limits:             mockLimits{errorsCacheTTL: time.Minute},
+			downstreamErr:      apierror.New(apierror.TypeBadData, "parse error"),
+			expectedCached:     true,
+			expectedErrorCache: true,
+		},
+		"should not cache a bad data error if the caching of errors is disabled": {
+			limits:        mockLimits{emptyResultsCacheTTL: time.Minute},
+			downstreamErr: apierror.New(apierror.TypeBadData, "parse error"),
+		},
+		"should not cache a non deterministic error": {
+			limits:        mockLimits{errorsCacheTTL: time.Minute},
+			downstreamErr: apierror.New(apierror.TypeInternal, "internal error"),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			downstreamCalls := 0
+			downstream := HandlerFunc(func(context.Context, Request) (Response, error) {
+				downstreamCalls++
+				return testData.downstreamResp, testData.downstreamErr
+			})
+
+			reg := prometheus.NewPedanticRegistry()
+			metrics := newNegativeResultsCacheMiddlewareMetrics(reg)
+			handler := newNegativeResultsCacheMiddleware(testData.limits, cache.NewMockCache(), 24*time.Hour, log.NewNopLogger(), metrics).Wrap(downstream)
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			req := &PrometheusRangeQueryRequest{
+				Query:   "up",
+				Start:   0,
+				End:     3600 * 1000,
+				Step:    60 * 1000,
+				Options: Options{CacheDisabled: testData.cacheDisabled},
+			}
+
+			for i := 0; i < 2; i++ {
+				resp, err := handler.Do(ctx, req)
+				if testData.downstreamErr != nil {
+					require.Error(t, err)
+					assert.Equal(t, testData.downstreamErr.Error(), err.Error())
+					assert.Equal(t, apierror.IsType(testData.downstreamErr, apierror.TypeBadData), apierror.IsType(err, apierror.TypeBadData))
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, testData.downstreamResp.(*PrometheusResponse).Data, resp.(*PrometheusResponse).Data)
+			}
+
+			expectedDownstreamCalls := 2
+			if testData.expectedCached {
+				expectedDownstreamCalls = 1
+			}
+			assert.Equal(t, expectedDownstreamCalls, downstreamCalls)
+			assert.Equal(t, float64(2-expectedDownstreamCalls), testutil.ToFloat64(metrics.hits))
+
+			// A request whose time range moves forward within the split interval hits the cache.
+			if !testData.expectedCached {
+				expectedDownstreamCalls++
+			}
+			_, _ = handler.Do(ctx, req.WithStartEnd(req.Start+req.Step, req.End+req.Step))
+			assert.Equal(t, expectedDownstreamCalls, downstreamCalls)
+
+			// A request whose time range ends in another split interval doesn't hit the cache.
+			_, _ = handler.Do(ctx, req.WithStartEnd(req.Start, req.End+24*3600*1000))
+			assert.Equal(t, expectedDownstreamCalls+1, downstreamCalls)
+		})
+	}
+}
+
+func TestNegativeResultsCacheMiddleware_ShouldExpireCachedResultsAfterTheTTL(t *testing.T) {
+	downstreamCalls := 0
+	downstream := HandlerFunc(func(context.Context, Request) (Response, error) {
+		downstreamCalls++
+		return &PrometheusResponse{Status: statusSuccess, Data: &PrometheusData{ResultType: "vector"}}, nil
+	})
+
+	metrics := newNegativeResultsCacheMiddlewareMetrics(nil)
+	handler := newNegativeResultsCacheMiddleware(mockLimits{emptyResultsCacheTTL: 100 * time.Millisecond}, cache.NewMockCache(), 24*time.Hour, log.NewNopLogger(), metrics).Wrap(downstream)
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	req := &PrometheusInstantQueryRequest{Query: "up", Time: 1000}
+
+	_, err := handler.Do(ctx, req)
+	require.NoError(t, err)
+	_, err = handler.Do(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, downstreamCalls)
+
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = handler.Do(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, downstreamCalls)
+}
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	util_math "github.com/grafana/mimir/pkg/util/math"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// The scrape interval assumed to estimate the number of samples read by a range vector selector.
+	queryCostAssumedScrapeInterval = time.Minute
+
+	// The step assumed to estimate the number of evaluations of a subquery without an explicit step.
+	queryCostAssumedSubqueryStep = time.Minute
+
+	// The max number of query fingerprints whose fetched series are remembered.
+	queryCostHistorySize = 10000
+
+	// How long the fetched series of a query fingerprint are remembered. Once expired, the query is estimated as if it
+	// has never been run, so that the rejected queries are eventually run again and their fetched series re-measured.
+	queryCostHistoryTTL = time.Hour
+)
+
+type queryCostEstimationMiddlewareMetrics struct {
+	rejectedQueries prometheus.Counter
+}
+
+func newQueryCostEstimationMiddlewareMetrics(reg prometheus.Registerer) *queryCostEstimationMiddlewareMetrics {
+	return &queryCostEstimationMiddlewareMetrics{
+		rejectedQueries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_query_frontend_query_cost_estimation_rejected_queries_total",
+			Help: "Total number of queries rejected because their estimated cost exceeded the tenant's limit.",
+		}),
+	}
+}
+
+// queryCostEstimationMiddleware estimates the cost of the queries before running them, and rejects the queries
+// whose estimated cost exceeds the tenant's limit, instead of letting them run on the queriers until they hit
+// the per-query limits or time out.
+//
+// The cost of a query is estimated as the number of samples it reads: the number of points read by each selector
+// over all the query steps, multiplied by the number of series fetched by the selectors. The number of series isn't
+// known before running a query, so it's taken from the query statistics of the last run of a query with the same
+// fingerprint, being the same query run on the same time range length and step. If the query has never been run, or
+// its last run is older than queryCostHistoryTTL, each selector is assumed to fetch a single series.
+//
+// The history of the series fetched by the queries is shared with the query explain endpoint.
+type queryCostEstimationMiddleware struct {
+	next    Handler
+	limits  Limits
+	history *queryCostHistory
+	logger  log.Logger
+	metrics *queryCostEstimationMiddlewareMetrics
+}
+
+// newQueryCostEstimationMiddleware makes a new queryCostEstimationMiddleware.
+func newQueryCostEstimationMiddleware(limits Limits, history *queryCostHistory, logger log.Logger, metrics *queryCostEstimationMiddlewareMetrics) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &queryCostEstimationMiddleware{
+			next:    next,
+			limits:  limits,
+			history: history,
+			logger:  logger,
+			metrics: metrics,
+		}
+	})
+}
+
+func (m *queryCostEstimationMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	fingerprint := queryCostFingerprint(tenant.JoinTenantIDs(tenantIDs), req)
+
+	if limit := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, m.limits.MaxEstimatedQueryCost); limit > 0 {
+		if err := m.checkEstimatedCost(ctx, fingerprint, req, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	// The series fetched by the queries are tracked even if the limit is disabled, so that the query explain
+	// endpoint can estimate them. The query statistics aren't tracked if disabled.
+	stats := querier_stats.FromContext(ctx)
+	if stats == nil {
+		return m.next.Do(ctx, req)
+	}
+
+	fetchedSeriesBefore, splitQueriesBefore := stats.LoadFetchedSeries(), stats.LoadSplitQueries()
+	resp, err := m.next.Do(ctx, req)
+
+	// The cached results don't fetch any series, so they don't tell anything about the cost of the query.
+	if fetched := stats.LoadFetchedSeries() - fetchedSeriesBefore; err == nil && fetched > 0 {
+		// Each split query fetches the series of the whole query, on a part of its time range.
+		splitQueries := util_math.Max64(1, int64(stats.LoadSplitQueries()-splitQueriesBefore))
+		m.history.add(fingerprint, fetched/uint64(splitQueries))
+	}
+
+	return resp, err
+}
+
+// checkEstimatedCost returns an error if the estimated cost of the query exceeds the limit.
+func (m *queryCostEstimationMiddleware) checkEstimatedCost(ctx context.Context, fingerprint string, req Request, limit int) error {
+	// The queries which can't be parsed are left to the downstream, which returns the proper error.
+	expr, err := parser.ParseExpr(req.GetQuery())
+	if err != nil {
+		return nil
+	}
+
+	estimatedCost, _, _ := estimateQueryCost(m.history, fingerprint, expr, req)
+	if estimatedCost > int64(limit) {
+		spanLog := spanlogger.FromContext(ctx, m.logger)
+		level.Debug(spanLog).Log("msg", "rejecting query because its estimated cost exceeds the limit", "query", req.GetQuery(), "estimated_cost", estimatedCost, "limit", limit)
+		m.metrics.rejectedQueries.Inc()
+		return apierror.New(apierror.TypeBadData, validation.NewMaxEstimatedQueryCostError(estimatedCost, int64(limit)).Error())
+	}
+	return nil
+}
+
+// estimateQueryCost returns the estimated cost of the query, along with the number of series fetched by the last
+// run of a query with the same fingerprint, if it has already been run.
+func estimateQueryCost(history *queryCostHistory, fingerprint string, expr parser.Expr, req Request) (cost int64, fetchedSeries uint64, known bool) {
+	selectors := countVectorSelectors(expr)
+
+	// Assume each selector fetches a single series, unless the query has already been run.
+	seriesPerSelector := int64(1)
+	fetchedSeries, known = history.fetchedSeries(fingerprint)
+	if known && selectors > 0 {
+		seriesPerSelector = util_math.Max64(1, int64(fetchedSeries)/selectors)
+	}
+
+	return estimateQueryPoints(expr, req) * seriesPerSelector, fetchedSeries, known
+}
+
+// estimateQueryPoints returns the number of points read by the selectors of the query, over all the query steps,
+// assuming each selector fetches a single series.
+func estimateQueryPoints(expr parser.Expr, req Request) int64 {
+	evaluations := int64(1)
+	if step := req.GetStep(); step > 0 {
+		evaluations = (req.GetEnd()-req.GetStart())/step + 1
+	}
+	return estimateNodePoints(expr, evaluations)
+}
+
+// estimateNodePoints returns the number of points read by the selectors of the node when evaluated the given number
+// of times.
+func estimateNodePoints(node parser.Node, evaluations int64) int64 {
+	switch n := node.(type) {
+	case *parser.VectorSelector:
+		return evaluations
+	case *parser.MatrixSelector:
+		return evaluations * util_math.Max64(1, int64(n.Range/queryCostAssumedScrapeInterval))
+	case *parser.SubqueryExpr:
+		step := n.Step
+		if step <= 0 {
+			step = queryCostAssumedSubqueryStep
+		}
+		return estimateNodePoints(n.Expr, evaluations*util_math.Max64(1, int64(n.Range/step)))
+	}
+
+	points := int64(0)
+	for _, child := range parser.Children(node) {
+		points += estimateNodePoints(child, evaluations)
+	}
+	return points
+}
+
+// countVectorSelectors returns the number of vector selectors of the query, including the ones of the range
+// vector selectors.
+func countVectorSelectors(expr parser.Expr) int64 {
+	count := int64(0)
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if _, ok := node.(*parser.VectorSelector); ok {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// queryCostFingerprint returns the fingerprint of the query, which is the same for the queries expected to fetch
+// the same number of series.
+func queryCostFingerprint(tenantID string, req Request) string {
+	return cacheHashKey(fmt.Sprintf("%s:%s:%d:%d", tenantID, req.GetQuery(), req.GetEnd()-req.GetStart(), req.GetStep()))
+}
+
+// queryCostHistory remembers the number of series fetched by the last run of the most recently run query fingerprints,
+// for up to queryCostHistoryTTL.
+type queryCostHistory struct {
+	mtx sync.Mutex
+	lru *lru.LRU
+	now func() time.Time
+}
+
+type queryCostHistoryEntry struct {
+	fetchedSeries uint64
+	measuredAt    time.Time
+}
+
+func newQueryCostHistory(size int) *queryCostHistory {
+	// The LRU can't fail to be created with a positive size.
+	l, _ := lru.NewLRU(size, nil)
+	return &queryCostHistory{lru: l, now: time.Now}
+}
+
+// add remembers the number of series fetched by the last run of the query with the given fingerprint.
+func (h *queryCostHistory) add(fingerprint string, fetchedSeries uint64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.lru.Add(fingerprint, queryCostHistoryEntry{fetchedSeries: fetchedSeries, measuredAt: h.now()})
+}
+
+// fetchedSeries returns the number of series fetched by the last run of the query with the given fingerprint, if any
+// and not expired. Only the runs refresh the recency of the fingerprints, not the lookups, otherwise the fingerprints
+// of the rejected queries, which aren't run, would never be evicted.
+func (h *queryCostHistory) fetchedSeries(fingerprint string) (uint64, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	v, ok := h.lru.Peek(fingerprint)
+	if !ok {
+		return 0, false
+	}
+	entry := v.(queryCostHistoryEntry)
+	if h.now().Sub(entry.measuredAt) > queryCostHistoryTTL {
+		h.lru.Remove(fingerprint)
+		return 0, false
+	}
+	return entry.fetchedSeries, true
+}
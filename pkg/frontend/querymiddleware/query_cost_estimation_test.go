@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+func TestEstimateQueryPoints(t *testing.T) {
+	rangeReq := &PrometheusRangeQueryRequest{Start: 0, End: time.Hour.Milliseconds(), Step: time.Minute.Milliseconds()}
+	instantReq := &PrometheusInstantQueryRequest{Time: time.Hour.Milliseconds()}
+
+	tests := map[string]struct {
+		query    string
+		req      Request
+		expected int64
+	}{
+		"vector selector in an instant query": {
+			query:    `up`,
+			req:      instantReq,
+			expected: 1,
+		},
+		"vector selector in a range query": {
+			query:    `up`,
+			req:      rangeReq,
+			expected: 61,
+		},
+		"range vector selector in an instant query": {
+			query:    `rate(http_requests_total[5m])`,
+			req:      instantReq,
+			expected: 5,
+		},
+		"range vector selector in a range query": {
+			query:    `rate(http_requests_total[5m])`,
+			req:      rangeReq,
+			expected: 61 * 5,
+		},
+		"range vector selector shorter than the assumed scrape interval": {
+			query:    `rate(http_requests_total[30s])`,
+			req:      rangeReq,
+			expected: 61,
+		},
+		"binary operation between two selectors": {
+			query:    `sum(rate(http_requests_total[5m])) / sum(up)`,
+			req:      rangeReq,
+			expected: 61*5 + 61,
+		},
+		"subquery with step": {
+			query:    `max_over_time(rate(http_requests_total[5m])[1h:30s])`,
+			req:      instantReq,
+			expected: 120 * 5,
+		},
+		"subquery without step": {
+			query:    `max_over_time(up[1h:])`,
+			req:      rangeReq,
+			expected: 61 * 60,
+		},
+		"number literal": {
+			query:    `1`,
+			req:      rangeReq,
+			expected: 0,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			expr, err := parser.ParseExpr(testData.query)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, estimateQueryPoints(expr, testData.req))
+		})
+	}
+}
+
+func TestQueryCostEstimationMiddleware(t *testing.T) {
+	// The query reads 61 points per series, from 2 selectors.
+	req := &PrometheusRangeQueryRequest{Query: `up / up offset 1h`, Start: 0, End: time.Hour.Milliseconds(), Step: time.Minute.Milliseconds()}
+
+	tests := map[string]struct {
+		limit                 int
+		req                   Request
+		statsDisabled         bool
+		fetchedSeries         uint64
+		splitQueries          uint32
+		expectedFirstRejected bool
+		expectedNextRejected  bool
+	}{
+		"should not reject the queries if the limit is disabled": {
+			req:           req,
+			fetchedSeries: 1000,
+		},
+		"should reject the query if its estimated cost exceeds the limit, assuming each selector fetches a single series": {
+			limit:                 100,
+			req:                   req,
+			expectedFirstRejected: true,
+			expectedNextRejected:  true,
+		},
+		"should reject the query if its estimated cost exceeds the limit, given the series fetched by its last run": {
+			limit:                200,
+			req:                  req,
+			fetchedSeries:        4,
+			expectedNextRejected: true,
+		},
+		"should not reject the query if its estimated cost doesn't exceed the limit, given the series fetched by its last run": {
+			limit:         300,
+			req:           req,
+			fetchedSeries: 4,
+		},
+		"should not reject the query if the series fetched by its last run, divided by the split queries, don't exceed the limit": {
+			limit:         200,
+			req:           req,
+			fetchedSeries: 8,
+			splitQueries:  4,
+		},
+		"should not reject the query if the series fetched by its last run aren't tracked": {
+			limit:         200,
+			req:           req,
+			statsDisabled: true,
+			fetchedSeries: 4,
+		},
+		"should not reject the query if it can't be parsed": {
+			limit:         1,
+			req:           &PrometheusRangeQueryRequest{Query: `up{`, Start: 0, End: time.Hour.Milliseconds(), Step: time.Minute.Milliseconds()},
+			fetchedSeries: 4,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			downstreamCalls := 0
+			downstream := HandlerFunc(func(ctx context.Context, _ Request) (Response, error) {
+				downstreamCalls++
+				stats := querier_stats.FromContext(ctx)
+				stats.AddFetchedSeries(testData.fetchedSeries)
+				stats.AddSplitQueries(testData.splitQueries)
+				return &PrometheusResponse{Status: statusSuccess}, nil
+			})
+
+			metrics := newQueryCostEstimationMiddlewareMetrics(nil)
+			handler := newQueryCostEstimationMiddleware(mockLimits{maxEstimatedQueryCost: testData.limit}, newQueryCostHistory(queryCostHistorySize), log.NewNopLogger(), metrics).Wrap(downstream)
+
+			run := func() error {
+				ctx := user.InjectOrgID(context.Background(), "user-1")
+				if !testData.statsDisabled {
+					_, ctx = querier_stats.ContextWithEmptyStats(ctx)
+				}
+				_, err := handler.Do(ctx, testData.req)
+				return err
+			}
+
+			expectedRejected := 0
+			for _, expected := range []bool{testData.expectedFirstRejected, testData.expectedNextRejected} {
+				err := run()
+				if !expected {
+					require.NoError(t, err)
+					continue
+				}
+
+				expectedRejected++
+				require.Error(t, err)
+				assert.True(t, apierror.IsType(err, apierror.TypeBadData))
+				assert.Contains(t, err.Error(), "err-mimir-tenant-max-estimated-query-cost")
+			}
+
+			assert.Equal(t, 2-expectedRejected, downstreamCalls)
+			assert.Equal(t, float64(expectedRejected), testutil.ToFloat64(metrics.rejectedQueries))
+		})
+	}
+}
+
+func TestQueryCostHistory(t *testing.T) {
+	now := time.Now()
+	history := newQueryCostHistory(2)
+	history.now = func() time.Time { return now }
+
+	history.add("a", 10)
+	history.add("b", 20)
+
+	// The lookups don't refresh the recency of the fingerprints, so "a" is evicted first even if looked up.
+	fetched, ok := history.fetchedSeries("a")
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), fetched)
+
+	history.add("c", 30)
+	_, ok = history.fetchedSeries("a")
+	assert.False(t, ok)
+
+	// The fetched series expire, so that the query is estimated as if it has never been run.
+	now = now.Add(queryCostHistoryTTL + time.Second)
+	history.add("c", 40)
+	_, ok = history.fetchedSeries("b")
+	assert.False(t, ok)
+
+	fetched, ok = history.fetchedSeries("c")
+	require.True(t, ok)
+	assert.Equal(t, uint64(40), fetched)
+}
@@ -0,0 +1,554 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/tenant"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+	util_math "github.com/grafana/mimir/pkg/util/math"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// queryExplainPathSuffix is the path suffix of the endpoint explaining how the queries would be run.
+	queryExplainPathSuffix = "/api/v1/query_explain"
+
+	// The PromQL engine's default lookback delta, used if the configured one is 0.
+	defaultQueryExplainLookbackDelta = 5 * time.Minute
+
+	queryExplainTypeRange   = "range"
+	queryExplainTypeInstant = "instant"
+)
+
+type queryExplainResponse struct {
+	Status string            `json:"status"`
+	Data   *queryExplanation `json:"data"`
+}
+
+// queryExplanation describes how the query-frontend would run a query, without running it.
+type queryExplanation struct {
+	Query   string    `json:"query"`
+	Type    string    `json:"type"`
+	Tenants []string  `json:"tenants"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Step    string    `json:"step,omitempty"`
+
+	// The error the query would be rejected with, or the reason it wouldn't be run at all, if any.
+	Rejected string `json:"rejected,omitempty"`
+	Skipped  string `json:"skipped,omitempty"`
+
+	RemoteClusters int                      `json:"remote_clusters"`
+	Splitting      queryExplainSplitting    `json:"splitting"`
+	ResultsCache   queryExplainResultsCache `json:"results_cache"`
+	Sharding       queryExplainSharding     `json:"sharding"`
+
+	// The number of requests which would be sent to the queriers, given the results cached so far.
+	QuerierRequests int `json:"querier_requests"`
+
+	Storage queryExplainStorage `json:"storage"`
+	Cost    queryExplainCost    `json:"cost"`
+}
+
+type queryExplainSplitting struct {
+	// The interval the query is split by, empty if the query isn't split.
+	Interval     string `json:"interval,omitempty"`
+	SplitQueries int    `json:"split_queries"`
+}
+
+type queryExplainResultsCache struct {
+	Enabled bool `json:"enabled"`
+
+	// The number of split queries whose results are fully, partially and not cached, and which can't be cached,
+	// like the ones more recent than the max cache freshness.
+	Hits         int `json:"hits"`
+	PartialHits  int `json:"partial_hits"`
+	Misses       int `json:"misses"`
+	NotCacheable int `json:"not_cacheable"`
+}
+
+type queryExplainSharding struct {
+	Shards int `json:"shards"`
+	// The number of sharded queries each split query is rewritten to, and the rewritten query.
+	ShardedQueries int    `json:"sharded_queries"`
+	ShardedQuery   string `json:"sharded_query,omitempty"`
+}
+
+type queryExplainStorage struct {
+	// The time ranges of the data read from the ingesters and the store-gateways, nil if they're not queried.
+	Ingesters     *queryExplainTimeRange `json:"ingesters"`
+	StoreGateways *queryExplainTimeRange `json:"store_gateways"`
+
+	// The blocks the store-gateways are queried for, nil if the blocks aren't looked up, and the error they
+	// couldn't be looked up with, if any.
+	Blocks      []queryExplainBlock `json:"blocks"`
+	BlocksError string              `json:"blocks_error,omitempty"`
+}
+
+type queryExplainBlock struct {
+	Tenant          string    `json:"tenant"`
+	ID              string    `json:"id"`
+	MinTime         time.Time `json:"min_time"`
+	MaxTime         time.Time `json:"max_time"`
+	CompactionLevel int       `json:"compaction_level,omitempty"`
+	SizeBytes       int64     `json:"size_bytes,omitempty"`
+
+	// Whether the block is marked for deletion, but still queried until the deletion marks are ignored.
+	MarkedForDeletion bool `json:"marked_for_deletion,omitempty"`
+}
+
+type queryExplainTimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type queryExplainCost struct {
+	// The number of series fetched by the last run of the same query, nil if the query hasn't been run yet.
+	EstimatedSeries *uint64 `json:"estimated_series"`
+	EstimatedCost   int64   `json:"estimated_cost"`
+	Limit           int     `json:"limit,omitempty"`
+}
+
+// BlocksFinder finds the blocks of a tenant in the long-term storage containing samples within the range minT and
+// maxT (milliseconds, both included), like the querier.BlocksFinder.
+type BlocksFinder interface {
+	GetBlocks(ctx context.Context, userID string, minT, maxT int64) (bucketindex.Blocks, map[ulid.ULID]*bucketindex.BlockDeletionMark, error)
+}
+
+// queryExplainer explains how the instant and range queries would be run by the query-frontend: how they would be
+// split and sharded, how many of the split queries would be returned from the results cache, which of the ingesters
+// and the store-gateways the queriers would read the data from, which blocks the store-gateways would be queried
+// for, and the estimated cost of the queries.
+//
+// The decisions are taken with the same functions used by the middlewares running the queries, and the results
+// cache is only looked up. The number of sharded queries of the split instant queries is assumed to be the one of
+// the whole query.
+type queryExplainer struct {
+	cfg           Config
+	limits        Limits
+	codec         Codec
+	cache         cache.Cache
+	splitter      CacheSplitter
+	extractor     Extractor
+	costHistory   *queryCostHistory
+	lookbackDelta time.Duration
+	logger        log.Logger
+}
+
+// newQueryExplainRoundTripper returns a http.RoundTripper serving the query explain endpoint. The cache is nil if
+// the results cache is disabled.
+func newQueryExplainRoundTripper(cfg Config, limits Limits, codec Codec, c cache.Cache, splitter CacheSplitter, extractor Extractor, costHistory *queryCostHistory, lookbackDelta time.Duration, logger log.Logger) http.RoundTripper {
+	if lookbackDelta == 0 {
+		lookbackDelta = defaultQueryExplainLookbackDelta
+	}
+
+	e := &queryExplainer{
+		cfg:           cfg,
+		limits:        limits,
+		codec:         codec,
+		cache:         c,
+		splitter:      splitter,
+		extractor:     extractor,
+		costHistory:   costHistory,
+		lookbackDelta: lookbackDelta,
+		logger:        logger,
+	}
+	return RoundTripFunc(e.roundTrip)
+}
+
+func (e *queryExplainer) roundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	req, err := e.decodeRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation, err := e.explain(ctx, tenantIDs, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(queryExplainResponse{Status: statusSuccess, Data: explanation})
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(http.StatusOK),
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, nil
+}
+
+// decodeRequest decodes the query to explain, which is a range query if the step is set, or an instant query
+// otherwise. The parameters are the same of the query_range and query endpoints.
+func (e *queryExplainer) decodeRequest(r *http.Request) (Request, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	queryReq := r.Clone(r.Context())
+	prefix := strings.TrimSuffix(r.URL.Path, queryExplainPathSuffix)
+	if queryReq.Form.Get("step") != "" {
+		queryReq.URL.Path = prefix + "/api/v1" + queryRangePathSuffix
+	} else {
+		queryReq.URL.Path = prefix + "/api/v1" + instantQueryPathSuffix
+		if queryReq.Form.Get("time") == "" {
+			queryReq.Form.Set("time", strconv.FormatInt(time.Now().Unix(), 10))
+		}
+	}
+
+	return e.codec.DecodeRequest(r.Context(), queryReq)
+}
+
+func (e *queryExplainer) explain(ctx context.Context, tenantIDs []string, req Request) (*queryExplanation, error) {
+	expr, err := parser.ParseExpr(req.GetQuery())
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	isRange := req.GetStep() > 0
+	explanation := &queryExplanation{
+		Query:   req.GetQuery(),
+		Type:    queryExplainTypeInstant,
+		Tenants: tenantIDs,
+	}
+	if isRange {
+		explanation.Type = queryExplainTypeRange
+		explanation.Step = (time.Duration(req.GetStep()) * time.Millisecond).String()
+	}
+
+	// Apply the limits, like the limits middleware.
+	if maxQueryLookback := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, e.limits.MaxQueryLookback); maxQueryLookback > 0 {
+		minStartTime := util.TimeToMillis(time.Now().Add(-maxQueryLookback))
+		if req.GetEnd() < minStartTime {
+			explanation.Start, explanation.End = timestamp.Time(req.GetStart()), timestamp.Time(req.GetEnd())
+			explanation.Skipped = "the query time range is before the max query lookback, so the query returns an empty result"
+			return explanation, nil
+		}
+		if req.GetStart() < minStartTime {
+			req = req.WithStartEnd(minStartTime, req.GetEnd())
+		}
+	}
+	explanation.Start, explanation.End = timestamp.Time(req.GetStart()), timestamp.Time(req.GetEnd())
+
+	if maxQueryLength := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, e.limits.MaxQueryLength); maxQueryLength > 0 {
+		if queryLen := explanation.End.Sub(explanation.Start); queryLen > maxQueryLength {
+			explanation.Rejected = validation.NewMaxQueryLengthError(queryLen, maxQueryLength).Error()
+			return explanation, nil
+		}
+	}
+
+	if len(tenantIDs) == 1 {
+		explanation.RemoteClusters = len(e.limits.RemoteQueryFederationURLs(tenantIDs[0]))
+	}
+
+	explanation.Cost = e.explainCost(tenantIDs, expr, req)
+	if explanation.Cost.Limit > 0 && explanation.Cost.EstimatedCost > int64(explanation.Cost.Limit) {
+		explanation.Rejected = validation.NewMaxEstimatedQueryCostError(explanation.Cost.EstimatedCost, int64(explanation.Cost.Limit)).Error()
+		return explanation, nil
+	}
+
+	explanation.Storage = e.explainStorage(ctx, tenantIDs, expr, req)
+
+	var downstreamReqs int
+	if isRange {
+		downstreamReqs, err = e.explainRangeQuerySplitAndCache(ctx, tenantIDs, req, explanation)
+	} else {
+		downstreamReqs, err = e.explainInstantQuerySplitAndCache(ctx, tenantIDs, req, explanation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	explanation.Sharding = e.explainSharding(tenantIDs, req, downstreamReqs)
+	explanation.QuerierRequests = downstreamReqs * util_math.Max(1, explanation.Sharding.ShardedQueries)
+	return explanation, nil
+}
+
+// explainRangeQuerySplitAndCache explains how the range query is split and looked up in the results cache, like the
+// step align and split and cache middlewares. It returns the number of queries which would be run downstream.
+func (e *queryExplainer) explainRangeQuerySplitAndCache(ctx context.Context, tenantIDs []string, req Request, explanation *queryExplanation) (int, error) {
+	if e.cfg.AlignQueriesWithStep {
+		req = req.WithStartEnd((req.GetStart()/req.GetStep())*req.GetStep(), (req.GetEnd()/req.GetStep())*req.GetStep())
+	}
+
+	splitReqs := []Request{req}
+	if e.cfg.SplitQueriesByInterval > 0 {
+		var err error
+		if splitReqs, err = splitQueryByInterval(req, e.cfg.SplitQueriesByInterval); err != nil {
+			return 0, err
+		}
+		explanation.Splitting.Interval = e.cfg.SplitQueriesByInterval.String()
+	}
+	explanation.Splitting.SplitQueries = len(splitReqs)
+
+	explanation.ResultsCache.Enabled = e.cache != nil && !req.GetOptions().CacheDisabled
+	if !explanation.ResultsCache.Enabled {
+		return len(splitReqs), nil
+	}
+
+	maxCacheFreshness := maxCacheFreshnessPerTenant(tenantIDs, e.limits)
+	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
+
+	// The split queries are looked up in parts, when the results are cached with the fine-grained interval.
+	if e.cfg.ResultsCacheFineGrainedInterval > 0 {
+		parts := make([]Request, 0, len(splitReqs))
+		for _, splitReq := range splitReqs {
+			splitParts, err := splitQueryByInterval(splitReq, e.cfg.ResultsCacheFineGrainedInterval)
+			if err != nil {
+				return 0, err
+			}
+			parts = append(parts, splitParts...)
+		}
+		splitReqs = parts
+	}
+
+	var downstreamReqs []Request
+	lookupReqs := make([]Request, 0, len(splitReqs))
+	lookupKeys := make([]string, 0, len(splitReqs))
+	for _, splitReq := range splitReqs {
+		if !isRequestCachable(splitReq, maxCacheTime, e.cfg.CacheUnalignedRequests, e.logger) {
+			explanation.ResultsCache.NotCacheable++
+			downstreamReqs = append(downstreamReqs, splitReq)
+			continue
+		}
+
+		lookupReqs = append(lookupReqs, splitReq)
+		if e.cfg.ResultsCacheFineGrainedInterval > 0 {
+			lookupKeys = append(lookupKeys, fineGrainedCacheKey(ctx, resultsCacheTenantKey(tenantIDs, e.limits), splitReq, e.cfg.ResultsCacheFineGrainedInterval))
+		} else {
+			lookupKeys = append(lookupKeys, e.splitter.GenerateCacheKey(ctx, resultsCacheTenantKey(tenantIDs, e.limits), splitReq))
+		}
+	}
+
+	// The cache is only looked up, so there's no need of the other fields of the middleware.
+	lookup := &splitAndCacheMiddleware{cache: e.cache, logger: e.logger}
+	for idx, extents := range lookup.fetchCacheExtents(ctx, lookupKeys) {
+		if len(extents) == 0 {
+			explanation.ResultsCache.Misses++
+			downstreamReqs = append(downstreamReqs, lookupReqs[idx])
+			continue
+		}
+
+		requests, _, err := partitionCacheExtents(lookupReqs[idx], extents, defaultMinCacheExtent, e.extractor)
+		if err != nil {
+			return 0, err
+		}
+		if len(requests) == 0 {
+			explanation.ResultsCache.Hits++
+			continue
+		}
+		explanation.ResultsCache.PartialHits++
+		downstreamReqs = append(downstreamReqs, requests...)
+	}
+
+	// The contiguous parts which are not cached are run downstream as a single request.
+	if e.cfg.ResultsCacheFineGrainedInterval > 0 {
+		sort.SliceStable(downstreamReqs, func(i, j int) bool { return downstreamReqs[i].GetStart() < downstreamReqs[j].GetStart() })
+		downstreamReqs, _ = coalesceDownstreamRequests(downstreamReqs, e.cfg.SplitQueriesByInterval)
+	}
+	return len(downstreamReqs), nil
+}
+
+// explainInstantQuerySplitAndCache explains how the instant query is looked up in the instant query results cache
+// and split by interval, like the instant query results cache and the split instant query by interval middlewares.
+// It returns the number of queries which would be run downstream.
+func (e *queryExplainer) explainInstantQuerySplitAndCache(ctx context.Context, tenantIDs []string, req Request, explanation *queryExplanation) (int, error) {
+	explanation.Splitting.SplitQueries = 1
+
+	splitter := &splitInstantQueryByIntervalMiddleware{limits: e.limits, logger: e.logger}
+	if splitInterval := splitter.getSplitIntervalForQuery(tenantIDs, req, e.logger); splitInterval > 0 {
+		// The expression is parsed again because the mapper can modify it.
+		expr, err := parser.ParseExpr(req.GetQuery())
+		if err != nil {
+			return 0, apierror.New(apierror.TypeBadData, err.Error())
+		}
+
+		mapperStats := astmapper.NewInstantSplitterStats()
+		mapper := astmapper.NewInstantQuerySplitter(splitInterval, e.logger, mapperStats)
+		if _, err := mapper.Map(expr); err == nil && mapperStats.GetSplitQueries() > 0 {
+			explanation.Splitting.Interval = splitInterval.String()
+			explanation.Splitting.SplitQueries = mapperStats.GetSplitQueries()
+		}
+	}
+
+	ttl := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, e.limits.ResultsCacheTTLForInstantQueries)
+	explanation.ResultsCache.Enabled = e.cache != nil && ttl > 0 && !req.GetOptions().CacheDisabled
+	if !explanation.ResultsCache.Enabled {
+		return explanation.Splitting.SplitQueries, nil
+	}
+
+	alignedTime := req.GetStart() - req.GetStart()%ttl.Milliseconds()
+	key := instantQueryResultsCacheKey(resultsCacheTenantKey(tenantIDs, e.limits), req.WithStartEnd(alignedTime, alignedTime))
+
+	// The cache is only looked up, so there's no need of the other fields of the middleware.
+	lookup := &instantQueryResultsCacheMiddleware{cache: e.cache, logger: e.logger}
+	if _, _, ok := lookup.fetch(ctx, key, tenantIDs); ok {
+		explanation.ResultsCache.Hits = 1
+		return 0, nil
+	}
+	explanation.ResultsCache.Misses = 1
+	return explanation.Splitting.SplitQueries, nil
+}
+
+// explainSharding explains how each of the queries run downstream is sharded, like the query sharding middleware.
+func (e *queryExplainer) explainSharding(tenantIDs []string, req Request, downstreamReqs int) queryExplainSharding {
+	if !e.cfg.ShardedQueries {
+		return queryExplainSharding{}
+	}
+
+	// The sharding doesn't need the PromQL engine nor the metrics of the middleware.
+	sharder := &querySharding{limit: e.limits, logger: e.logger}
+	totalShards := sharder.getShardsForQuery(tenantIDs, req.WithHints(&Hints{TotalQueries: int32(util_math.Max(1, downstreamReqs))}), e.logger)
+	if totalShards <= 1 {
+		return queryExplainSharding{}
+	}
+
+	shardedQuery, stats, err := sharder.shardQuery(req.GetQuery(), totalShards)
+	if err != nil || stats.GetShardedQueries() == 0 {
+		return queryExplainSharding{}
+	}
+	return queryExplainSharding{
+		Shards:         totalShards,
+		ShardedQueries: stats.GetShardedQueries(),
+		ShardedQuery:   shardedQuery,
+	}
+}
+
+// explainStorage explains which of the ingesters and the store-gateways the queriers read the data of the query
+// from, given the time range of the data read by the selectors of the query, and which blocks the store-gateways
+// are queried for.
+func (e *queryExplainer) explainStorage(ctx context.Context, tenantIDs []string, expr parser.Expr, req Request) queryExplainStorage {
+	now := time.Now()
+	mint := timestamp.Time(req.GetStart()).Add(-queryLookback(expr, e.lookbackDelta))
+	maxt := timestamp.Time(req.GetEnd())
+
+	storage := queryExplainStorage{}
+	if within := e.cfg.QueryIngestersWithin; within == 0 || !maxt.Before(now.Add(-within)) {
+		start := mint
+		if within > 0 && start.Before(now.Add(-within)) {
+			start = now.Add(-within)
+		}
+		storage.Ingesters = &queryExplainTimeRange{Start: start, End: maxt}
+	}
+	if after := e.cfg.QueryStoreAfter; after == 0 || !mint.After(now.Add(-after)) {
+		end := maxt
+		if after > 0 && end.After(now.Add(-after)) {
+			end = now.Add(-after)
+		}
+		storage.StoreGateways = &queryExplainTimeRange{Start: mint, End: end}
+
+		if e.cfg.BlocksFinder != nil {
+			storage.Blocks, storage.BlocksError = e.explainBlocks(ctx, tenantIDs, mint, end)
+		}
+	}
+	return storage
+}
+
+// explainBlocks returns the blocks of the tenants the store-gateways are queried for, like the blocks store
+// queryable, sorted by tenant and time. It returns the error the blocks couldn't be looked up with otherwise.
+func (e *queryExplainer) explainBlocks(ctx context.Context, tenantIDs []string, mint, maxt time.Time) ([]queryExplainBlock, string) {
+	explained := []queryExplainBlock{}
+	for _, tenantID := range tenantIDs {
+		blocks, deletionMarks, err := e.cfg.BlocksFinder.GetBlocks(ctx, tenantID, util.TimeToMillis(mint), util.TimeToMillis(maxt))
+		if err != nil {
+			return nil, err.Error()
+		}
+
+		for _, b := range blocks {
+			_, marked := deletionMarks[b.ID]
+			explained = append(explained, queryExplainBlock{
+				Tenant:            tenantID,
+				ID:                b.ID.String(),
+				MinTime:           timestamp.Time(b.MinTime),
+				MaxTime:           timestamp.Time(b.MaxTime),
+				CompactionLevel:   b.CompactionLevel,
+				SizeBytes:         b.SizeBytes,
+				MarkedForDeletion: marked,
+			})
+		}
+	}
+
+	sort.Slice(explained, func(i, j int) bool {
+		if explained[i].Tenant != explained[j].Tenant {
+			return explained[i].Tenant < explained[j].Tenant
+		}
+		if !explained[i].MinTime.Equal(explained[j].MinTime) {
+			return explained[i].MinTime.Before(explained[j].MinTime)
+		}
+		return explained[i].ID < explained[j].ID
+	})
+	return explained, ""
+}
+
+// explainCost explains the estimated cost of the query, like the query cost estimation middleware.
+func (e *queryExplainer) explainCost(tenantIDs []string, expr parser.Expr, req Request) queryExplainCost {
+	fingerprint := queryCostFingerprint(tenant.JoinTenantIDs(tenantIDs), req)
+	estimatedCost, fetchedSeries, known := estimateQueryCost(e.costHistory, fingerprint, expr, req)
+
+	cost := queryExplainCost{
+		EstimatedCost: estimatedCost,
+		Limit:         validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, e.limits.MaxEstimatedQueryCost),
+	}
+	if known {
+		cost.EstimatedSeries = &fetchedSeries
+	}
+	return cost
+}
+
+// queryLookback returns how far before the evaluation time the selectors of the node read the data. The @ modifiers
+// and the negative offsets are not taken into account.
+func queryLookback(node parser.Node, lookbackDelta time.Duration) time.Duration {
+	switch n := node.(type) {
+	case *parser.VectorSelector:
+		return n.OriginalOffset + lookbackDelta
+	case *parser.MatrixSelector:
+		if vs, ok := n.VectorSelector.(*parser.VectorSelector); ok {
+			return vs.OriginalOffset + n.Range
+		}
+		return n.Range
+	case *parser.SubqueryExpr:
+		return n.OriginalOffset + n.Range + queryLookback(n.Expr, lookbackDelta)
+	}
+
+	lookback := time.Duration(0)
+	for _, child := range parser.Children(node) {
+		if childLookback := queryLookback(child, lookbackDelta); childLookback > lookback {
+			lookback = childLookback
+		}
+	}
+	return lookback
+}
+
+// isQueryExplain returns whether the request path is the one of the query explain endpoint.
+func isQueryExplain(path string) bool {
+	return strings.HasSuffix(path, queryExplainPathSuffix)
+}
@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestQueryExplainer(t *testing.T) {
+	const day = 24 * time.Hour
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(3*day - time.Hour)
+	step := time.Hour
+
+	rangeParams := url.Values{
+		"query": []string{"sum(rate(foo[5m]))"},
+		"start": []string{fmt.Sprint(start.Unix())},
+		"end":   []string{fmt.Sprint(end.Unix())},
+		"step":  []string{step.String()},
+	}
+
+	cfg := Config{
+		SplitQueriesByInterval: day,
+		CacheResults:           true,
+		ShardedQueries:         true,
+		QueryIngestersWithin:   13 * time.Hour,
+		QueryStoreAfter:        12 * time.Hour,
+	}
+
+	explainWithConfig := func(t *testing.T, cfg Config, limits Limits, c cache.Cache, params url.Values) *queryExplanation {
+		explainer := newQueryExplainRoundTripper(cfg, limits, PrometheusCodec, c, ConstSplitter(day), PrometheusResponseExtractor{}, newQueryCostHistory(queryCostHistorySize), 0, log.NewNopLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "/prometheus/api/v1/query_explain?"+params.Encode(), nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+		resp, err := explainer.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		explainResp := queryExplainResponse{}
+		require.NoError(t, json.Unmarshal(body, &explainResp))
+		require.Equal(t, statusSuccess, explainResp.Status)
+		return explainResp.Data
+	}
+	explain := func(t *testing.T, limits Limits, c cache.Cache, params url.Values) *queryExplanation {
+		return explainWithConfig(t, cfg, limits, c, params)
+	}
+
+	t.Run("range query", func(t *testing.T) {
+		c := cache.NewMockCache()
+
+		// The first split query is fully cached, the second one partially and the third one is not cached.
+		rangeReq := &PrometheusRangeQueryRequest{Query: "sum(rate(foo[5m]))", Start: start.UnixMilli(), End: end.UnixMilli(), Step: step.Milliseconds()}
+		splitReqs, err := splitQueryByInterval(rangeReq, day)
+		require.NoError(t, err)
+		require.Len(t, splitReqs, 3)
+
+		cacheMiddleware := &splitAndCacheMiddleware{cache: c, logger: log.NewNopLogger()}
+		for idx, extent := range []Extent{
+			mkExtentWithStep(splitReqs[0].GetStart(), splitReqs[0].GetEnd(), step.Milliseconds()),
+			mkExtentWithStep(splitReqs[1].GetStart(), splitReqs[1].GetStart()+12*step.Milliseconds(), step.Milliseconds()),
+		} {
+			key := ConstSplitter(day).GenerateCacheKey(context.Background(), "user-1", splitReqs[idx])
+			cacheMiddleware.storeCacheExtents(context.Background(), key, []Extent{extent})
+		}
+
+		explanation := explain(t, mockLimits{totalShards: 4}, c, rangeParams)
+
+		assert.Equal(t, queryExplainTypeRange, explanation.Type)
+		assert.Equal(t, []string{"user-1"}, explanation.Tenants)
+		assert.Equal(t, "1h0m0s", explanation.Step)
+		assert.Empty(t, explanation.Rejected)
+		assert.Empty(t, explanation.Skipped)
+
+		assert.Equal(t, queryExplainSplitting{Interval: "24h0m0s", SplitQueries: 3}, explanation.Splitting)
+		assert.Equal(t, queryExplainResultsCache{Enabled: true, Hits: 1, PartialHits: 1, Misses: 1}, explanation.ResultsCache)
+		assert.Equal(t, 4, explanation.Sharding.Shards)
+		assert.Equal(t, 4, explanation.Sharding.ShardedQueries)
+		assert.NotEmpty(t, explanation.Sharding.ShardedQuery)
+
+		// The partially cached split query and the not cached one are run, each one sharded into 4 queries.
+		assert.Equal(t, 8, explanation.QuerierRequests)
+
+		// The data is too old to be in the ingesters.
+		assert.Nil(t, explanation.Storage.Ingesters)
+		require.NotNil(t, explanation.Storage.StoreGateways)
+		assert.True(t, start.Add(-5*time.Minute).Equal(explanation.Storage.StoreGateways.Start))
+		assert.True(t, end.Equal(explanation.Storage.StoreGateways.End))
+
+		// The blocks aren't explained without a blocks finder.
+		assert.Nil(t, explanation.Storage.Blocks)
+
+		// The query has never been run: 72 evaluations, each one reading 5 points of a single series.
+		assert.Nil(t, explanation.Cost.EstimatedSeries)
+		assert.Equal(t, int64(360), explanation.Cost.EstimatedCost)
+	})
+
+	t.Run("instant query", func(t *testing.T) {
+		queryTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+		params := url.Values{
+			"query": []string{"up"},
+			"time":  []string{fmt.Sprint(queryTime.Unix())},
+		}
+
+		explanation := explain(t, mockLimits{instantQueriesCacheTTL: time.Minute}, cache.NewMockCache(), params)
+
+		assert.Equal(t, queryExplainTypeInstant, explanation.Type)
+		assert.Empty(t, explanation.Step)
+		assert.Equal(t, queryExplainSplitting{SplitQueries: 1}, explanation.Splitting)
+		assert.Equal(t, queryExplainResultsCache{Enabled: true, Misses: 1}, explanation.ResultsCache)
+		assert.Equal(t, queryExplainSharding{}, explanation.Sharding)
+		assert.Equal(t, 1, explanation.QuerierRequests)
+
+		// The data is too recent to be in the store-gateways.
+		require.NotNil(t, explanation.Storage.Ingesters)
+		assert.True(t, queryTime.Add(-5*time.Minute).Equal(explanation.Storage.Ingesters.Start))
+		assert.True(t, queryTime.Equal(explanation.Storage.Ingesters.End))
+		assert.Nil(t, explanation.Storage.StoreGateways)
+	})
+
+	t.Run("store-gateway blocks", func(t *testing.T) {
+		day1 := &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: start.UnixMilli(), MaxTime: start.Add(day).UnixMilli(), CompactionLevel: 3, SizeBytes: 1024}
+		day2 := &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: start.Add(day).UnixMilli(), MaxTime: start.Add(2 * day).UnixMilli()}
+		finder := &mockBlocksFinder{
+			blocks:        bucketindex.Blocks{day2, day1},
+			deletionMarks: map[ulid.ULID]*bucketindex.BlockDeletionMark{day2.ID: {ID: day2.ID}},
+		}
+
+		blocksCfg := cfg
+		blocksCfg.BlocksFinder = finder
+		explanation := explainWithConfig(t, blocksCfg, mockLimits{}, nil, rangeParams)
+
+		// The blocks are looked up in the time range the store-gateways are queried for.
+		assert.Equal(t, "user-1", finder.userID)
+		assert.Equal(t, start.Add(-5*time.Minute).UnixMilli(), finder.minT)
+		assert.Equal(t, end.UnixMilli(), finder.maxT)
+
+		assert.Equal(t, []queryExplainBlock{
+			{Tenant: "user-1", ID: day1.ID.String(), MinTime: start, MaxTime: start.Add(day), CompactionLevel: 3, SizeBytes: 1024},
+			{Tenant: "user-1", ID: day2.ID.String(), MinTime: start.Add(day), MaxTime: start.Add(2 * day), MarkedForDeletion: true},
+		}, explanation.Storage.Blocks)
+		assert.Empty(t, explanation.Storage.BlocksError)
+
+		// The error the blocks couldn't be looked up with is explained.
+		blocksCfg.BlocksFinder = &mockBlocksFinder{err: errors.New("bucket index is too old")}
+		explanation = explainWithConfig(t, blocksCfg, mockLimits{}, nil, rangeParams)
+		assert.Nil(t, explanation.Storage.Blocks)
+		assert.Equal(t, "bucket index is too old", explanation.Storage.BlocksError)
+	})
+
+	t.Run("query rejected because of the max query length", func(t *testing.T) {
+		explanation := explain(t, mockLimits{maxQueryLength: day}, nil, rangeParams)
+
+		assert.Contains(t, explanation.Rejected, "the query time range exceeds the limit")
+		assert.Equal(t, 0, explanation.QuerierRequests)
+	})
+
+	t.Run("query rejected because of the max estimated query cost", func(t *testing.T) {
+		explanation := explain(t, mockLimits{maxEstimatedQueryCost: 100}, nil, rangeParams)
+
+		assert.Contains(t, explanation.Rejected, "err-mimir-tenant-max-estimated-query-cost")
+		assert.Equal(t, 100, explanation.Cost.Limit)
+		assert.Equal(t, 0, explanation.QuerierRequests)
+	})
+
+	t.Run("query skipped because of the max query lookback", func(t *testing.T) {
+		explanation := explain(t, mockLimits{maxQueryLookback: day}, nil, rangeParams)
+
+		assert.NotEmpty(t, explanation.Skipped)
+		assert.Equal(t, 0, explanation.QuerierRequests)
+	})
+}
+
+func TestQueryLookback(t *testing.T) {
+	for query, expected := range map[string]time.Duration{
+		"up":                                 5 * time.Minute,
+		"up offset 1h":                       time.Hour + 5*time.Minute,
+		"rate(up[10m])":                      10 * time.Minute,
+		"rate(up[10m] offset 1h) / up":       time.Hour + 10*time.Minute,
+		"max_over_time(rate(up[5m])[1h:1m])": time.Hour + 5*time.Minute,
+		"vector(1)":                          0,
+	} {
+		t.Run(query, func(t *testing.T) {
+			expr, err := parser.ParseExpr(query)
+			require.NoError(t, err)
+			assert.Equal(t, expected, queryLookback(expr, 5*time.Minute))
+		})
+	}
+}
+
+type mockBlocksFinder struct {
+	blocks        bucketindex.Blocks
+	deletionMarks map[ulid.ULID]*bucketindex.BlockDeletionMark
+	err           error
+
+	userID     string
+	minT, maxT int64
+}
+
+func (f *mockBlocksFinder) GetBlocks(_ context.Context, userID string, minT, maxT int64) (bucketindex.Blocks, map[ulid.ULID]*bucketindex.BlockDeletionMark, error) {
+	f.userID, f.minT, f.maxT = userID, minT, maxT
+	return f.blocks, f.deletionMarks, f.err
+}
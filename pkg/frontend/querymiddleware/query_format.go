@@ -0,0 +1,321 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// formatQueryPathSuffix is the path suffix of the endpoint formatting and linting the queries.
+	formatQueryPathSuffix = "/api/v1/format_query"
+	metadataPathSuffix    = "/api/v1/metadata"
+
+	// The scrape interval the ranges of the range selectors are checked against, if not set in the request.
+	defaultLintScrapeInterval = time.Minute
+
+	lintRuleUnanchoredRegex = "unanchored-regex"
+	lintRuleRateOnGauge     = "rate-on-gauge"
+	lintRuleShortRateRange  = "short-rate-range"
+)
+
+// The functions computing a rate from the counters, which don't make sense for the gauges.
+var lintCounterFunctions = map[string]struct{}{
+	"rate":     {},
+	"increase": {},
+	"irate":    {},
+}
+
+// The functions computing a rate or a change from the samples in the range, by the min number of samples they need
+// in the range for a meaningful result despite missed scrapes. The ones using the last 2 samples only need 2.
+var lintRangeFunctions = map[string]int{
+	"rate":     4,
+	"increase": 4,
+	"delta":    4,
+	"deriv":    4,
+	"irate":    2,
+	"idelta":   2,
+}
+
+type formatQueryResponse struct {
+	Status string `json:"status"`
+	Data   string `json:"data"`
+}
+
+type lintQueryResponse struct {
+	formatQueryResponse
+	Lint []queryLintIssue `json:"lint"`
+}
+
+// queryLintIssue is an anti-pattern found in a query.
+type queryLintIssue struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// The position of the offending expression in the query sent in the request.
+	Position queryLintPosition `json:"position"`
+}
+
+type queryLintPosition struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// newFormatQueryRoundTripper returns a http.RoundTripper formatting the queries, like the Prometheus
+// /api/v1/format_query endpoint. With the lint parameter, the queries are also checked for common anti-patterns:
+// the regex matchers which can't use the index because they match a substring, the rate of gauges according to
+// the metric metadata, fetched from next, and the ranges too short for the scrape interval.
+func newFormatQueryRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if err := r.ParseForm(); err != nil {
+			return nil, apierror.New(apierror.TypeBadData, err.Error())
+		}
+
+		expr, err := parser.ParseExpr(r.Form.Get("query"))
+		if err != nil {
+			return nil, apierror.New(apierror.TypeBadData, err.Error())
+		}
+
+		formatted := formatQueryResponse{Status: statusSuccess, Data: parser.Prettify(expr)}
+		var resp interface{} = formatted
+
+		if lint, _ := strconv.ParseBool(r.Form.Get("lint")); lint {
+			scrapeInterval := defaultLintScrapeInterval
+			if value := r.Form.Get("scrape_interval"); value != "" {
+				interval, err := model.ParseDuration(value)
+				if err != nil || interval <= 0 {
+					return nil, apierror.New(apierror.TypeBadData, "invalid scrape_interval: the scrape interval must be a positive duration")
+				}
+				scrapeInterval = time.Duration(interval)
+			}
+
+			issues, err := lintQuery(expr, scrapeInterval, func(metrics []string) (map[string]string, error) {
+				return fetchMetricTypes(r, next, metrics)
+			})
+			if err != nil {
+				return nil, err
+			}
+			resp = lintQueryResponse{formatQueryResponse: formatted, Lint: issues}
+		}
+
+		body, err := json.Marshal(resp)
+		if err != nil {
+			return nil, apierror.New(apierror.TypeInternal, err.Error())
+		}
+
+		return &http.Response{
+			Status:        http.StatusText(http.StatusOK),
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       r,
+		}, nil
+	})
+}
+
+// lintQuery returns the anti-patterns found in the query, sorted by position. The types of the metrics passed to
+// the counter functions are looked up with the given function, called once with all the metrics, and are empty if
+// the metrics have no metadata.
+func lintQuery(expr parser.Expr, scrapeInterval time.Duration, metricTypes func(metrics []string) (map[string]string, error)) ([]queryLintIssue, error) {
+	type counterCall struct {
+		call   *parser.Call
+		metric string
+	}
+
+	issues := []queryLintIssue{}
+	var (
+		counterCalls []counterCall
+		metrics      []string
+	)
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			for _, m := range n.LabelMatchers {
+				if isUnanchoredRegexMatcher(m) {
+					issues = append(issues, newQueryLintIssue(lintRuleUnanchoredRegex, n.PositionRange(),
+						"the regex matcher %s matches a substring of the label value, so it's matched against all the values of the label: use an equality matcher, a prefix or an alternation of values when possible", m))
+				}
+			}
+
+		case *parser.Call:
+			minSamples, ok := lintRangeFunctions[n.Func.Name]
+			if !ok || len(n.Args) == 0 {
+				return nil
+			}
+			matrix, ok := unwrapParens(n.Args[0]).(*parser.MatrixSelector)
+			if !ok {
+				return nil
+			}
+
+			if minRange := time.Duration(minSamples) * scrapeInterval; matrix.Range < minRange {
+				issues = append(issues, newQueryLintIssue(lintRuleShortRateRange, n.PositionRange(),
+					"the range %s of %s() is shorter than %d times the scrape interval %s, so missed scrapes cause gaps in the result: use a range of at least %s",
+					model.Duration(matrix.Range), n.Func.Name, minSamples, model.Duration(scrapeInterval), model.Duration(minRange)))
+			}
+
+			if _, ok := lintCounterFunctions[n.Func.Name]; !ok {
+				return nil
+			}
+			metric := metricName(matrix.VectorSelector.(*parser.VectorSelector))
+			if metric == "" {
+				return nil
+			}
+			if !util.StringsContain(metrics, metric) {
+				metrics = append(metrics, metric)
+			}
+			counterCalls = append(counterCalls, counterCall{call: n, metric: metric})
+		}
+		return nil
+	})
+
+	if len(metrics) > 0 {
+		types, err := metricTypes(metrics)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range counterCalls {
+			if types[c.metric] == string(textparse.MetricTypeGauge) {
+				issues = append(issues, newQueryLintIssue(lintRuleRateOnGauge, c.call.PositionRange(),
+					"%s() is applied to %s, which is a gauge according to its metadata: use a function like deriv() or delta() instead", c.call.Func.Name, c.metric))
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Position.Start < issues[j].Position.Start
+	})
+	return issues, nil
+}
+
+func newQueryLintIssue(rule string, pos parser.PositionRange, format string, args ...interface{}) queryLintIssue {
+	return queryLintIssue{
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Position: queryLintPosition{Start: int(pos.Start), End: int(pos.End)},
+	}
+}
+
+// isUnanchoredRegexMatcher returns whether the matcher is a regex starting with a wildcard followed by something
+// else, like .*foo.*, which can't be matched using the prefix of the label values. The .* and .+ regexes alone
+// are cheap and common, and aren't reported.
+func isUnanchoredRegexMatcher(m *labels.Matcher) bool {
+	if m.Type != labels.MatchRegexp && m.Type != labels.MatchNotRegexp {
+		return false
+	}
+
+	re, err := syntax.Parse(m.Value, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 {
+		return false
+	}
+	first := re.Sub[0]
+	return (first.Op == syntax.OpStar || first.Op == syntax.OpPlus) && len(first.Sub) == 1 &&
+		(first.Sub[0].Op == syntax.OpAnyChar || first.Sub[0].Op == syntax.OpAnyCharNotNL)
+}
+
+// metricName returns the metric name the selector matches with an equality matcher, if any.
+func metricName(selector *parser.VectorSelector) string {
+	if selector.Name != "" {
+		return selector.Name
+	}
+	for _, m := range selector.LabelMatchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+func unwrapParens(expr parser.Expr) parser.Expr {
+	for {
+		paren, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.Expr
+	}
+}
+
+type metadataResponse struct {
+	Status string                            `json:"status"`
+	Data   map[string][]metadataResponseItem `json:"data"`
+}
+
+type metadataResponseItem struct {
+	Type string `json:"type"`
+}
+
+// fetchMetricTypes returns the types of the metrics according to the metadata returned by the metadata endpoint of
+// next, with a single request. The type of a metric is empty if the metric has no metadata or if the metadata
+// disagree about its type. The metadata endpoint only filters a single metric, so the metadata of all the metrics
+// is fetched if there are several.
+func fetchMetricTypes(r *http.Request, next http.RoundTripper, metrics []string) (map[string]string, error) {
+	u := &url.URL{Path: strings.TrimSuffix(r.URL.Path, formatQueryPathSuffix) + metadataPathSuffix}
+	if len(metrics) == 1 {
+		u.RawQuery = url.Values{"metric": metrics}.Encode()
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+	if err := user.InjectOrgIDIntoHTTPRequest(r.Context(), req); err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, apierror.Newf(apierror.TypeInternal, "fetching the metrics metadata: %s", string(body))
+	}
+
+	var metadata metadataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, apierror.Newf(apierror.TypeInternal, "decoding the metrics metadata: %s", err.Error())
+	}
+
+	types := make(map[string]string, len(metrics))
+	for _, metric := range metrics {
+		typ := ""
+		for _, item := range metadata.Data[metric] {
+			if typ != "" && item.Type != typ {
+				typ = ""
+				break
+			}
+			typ = item.Type
+		}
+		types[metric] = typ
+	}
+	return types, nil
+}
+
+// isFormatQuery returns whether the request path is the one of the endpoint formatting and linting the queries.
+func isFormatQuery(path string) bool {
+	return strings.HasSuffix(path, formatQueryPathSuffix)
+}
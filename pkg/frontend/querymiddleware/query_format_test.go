@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+)
+
+func TestFormatQueryRoundTripper(t *testing.T) {
+	var metadataReqs []*http.Request
+	metadata := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		metadataReqs = append(metadataReqs, r)
+		body := `{"status":"success","data":{}}`
+		if metric := r.URL.Query().Get("metric"); metric == "" || metric == "memory_bytes" {
+			body = `{"status":"success","data":{"memory_bytes":[{"type":"gauge","help":"","unit":""}],"requests_total":[{"type":"counter","help":"","unit":""}]}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+
+	tests := map[string]struct {
+		params                  url.Values
+		expectedBody            string
+		expectedError           bool
+		expectedMetadataQueries []string
+	}{
+		"should format the query": {
+			params:       url.Values{"query": []string{`sum(rate(foo{job=~".*api"}[1m]))`}},
+			expectedBody: `{"status":"success","data":"sum(rate(foo{job=~\".*api\"}[1m]))"}`,
+		},
+		"should lint the query without issues": {
+			params:                  url.Values{"query": []string{`sum(rate(foo{job=~"api.*"}[5m]))`}, "lint": []string{"true"}},
+			expectedBody:            `{"status":"success","data":"sum(rate(foo{job=~\"api.*\"}[5m]))","lint":[]}`,
+			expectedMetadataQueries: []string{"foo"},
+		},
+		"should report the unanchored regex matchers": {
+			params: url.Values{"query": []string{`foo{job=~".*api.*"}`}, "lint": []string{"true"}},
+			expectedBody: `{"status":"success","data":"foo{job=~\".*api.*\"}","lint":[` +
+				`{"rule":"unanchored-regex","message":"the regex matcher job=~\".*api.*\" matches a substring of the label value, so it's matched against all the values of the label: use an equality matcher, a prefix or an alternation of values when possible","position":{"start":0,"end":19}}]}`,
+		},
+		"should report the rate of the gauges": {
+			params: url.Values{"query": []string{`rate(memory_bytes[5m]) + rate(memory_bytes[10m])`}, "lint": []string{"true"}},
+			expectedBody: `{"status":"success","data":"rate(memory_bytes[5m]) + rate(memory_bytes[10m])","lint":[` +
+				`{"rule":"rate-on-gauge","message":"rate() is applied to memory_bytes, which is a gauge according to its metadata: use a function like deriv() or delta() instead","position":{"start":0,"end":22}},` +
+				`{"rule":"rate-on-gauge","message":"rate() is applied to memory_bytes, which is a gauge according to its metadata: use a function like deriv() or delta() instead","position":{"start":25,"end":48}}]}`,
+			expectedMetadataQueries: []string{"memory_bytes"},
+		},
+		"should fetch the metadata of all the metrics at once if several metrics are passed to the counter functions": {
+			params: url.Values{"query": []string{`rate(requests_total[5m]) / rate(memory_bytes[5m]) / rate(foo[5m])`}, "lint": []string{"true"}},
+			expectedBody: `{"status":"success","data":"rate(requests_total[5m]) / rate(memory_bytes[5m]) / rate(foo[5m])","lint":[` +
+				`{"rule":"rate-on-gauge","message":"rate() is applied to memory_bytes, which is a gauge according to its metadata: use a function like deriv() or delta() instead","position":{"start":27,"end":49}}]}`,
+			expectedMetadataQueries: []string{""},
+		},
+		"should not report the ranges long enough for the scrape interval": {
+			params:                  url.Values{"query": []string{`rate(foo[1m]) + irate(foo[30s])`}, "lint": []string{"true"}, "scrape_interval": []string{"15s"}},
+			expectedBody:            `{"status":"success","data":"rate(foo[1m]) + irate(foo[30s])","lint":[]}`,
+			expectedMetadataQueries: []string{"foo"},
+		},
+		"should report the ranges too short for the scrape interval": {
+			params: url.Values{"query": []string{`rate(foo[1m]) + deriv(bar[3m])`}, "lint": []string{"true"}, "scrape_interval": []string{"1m"}},
+			expectedBody: `{"status":"success","data":"rate(foo[1m]) + deriv(bar[3m])","lint":[` +
+				`{"rule":"short-rate-range","message":"the range 1m of rate() is shorter than 4 times the scrape interval 1m, so missed scrapes cause gaps in the result: use a range of at least 4m","position":{"start":0,"end":13}},` +
+				`{"rule":"short-rate-range","message":"the range 3m of deriv() is shorter than 4 times the scrape interval 1m, so missed scrapes cause gaps in the result: use a range of at least 4m","position":{"start":16,"end":30}}]}`,
+			expectedMetadataQueries: []string{"foo"},
+		},
+		"should report the ranges shorter than the default scrape interval": {
+			params: url.Values{"query": []string{`increase(foo[2m])`}, "lint": []string{"true"}},
+			expectedBody: `{"status":"success","data":"increase(foo[2m])","lint":[` +
+				`{"rule":"short-rate-range","message":"the range 2m of increase() is shorter than 4 times the scrape interval 1m, so missed scrapes cause gaps in the result: use a range of at least 4m","position":{"start":0,"end":17}}]}`,
+			expectedMetadataQueries: []string{"foo"},
+		},
+		"should fail on an invalid query": {
+			params:        url.Values{"query": []string{`sum(`}},
+			expectedError: true,
+		},
+		"should fail on an invalid scrape interval": {
+			params:        url.Values{"query": []string{`foo`}, "lint": []string{"true"}, "scrape_interval": []string{"0s"}},
+			expectedError: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			metadataReqs = nil
+
+			req := httptest.NewRequest(http.MethodGet, "/prometheus/api/v1/format_query?"+testData.params.Encode(), nil)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+			resp, err := newFormatQueryRoundTripper(metadata).RoundTrip(req)
+			if testData.expectedError {
+				require.Error(t, err)
+				assert.True(t, apierror.IsType(err, apierror.TypeBadData))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, testData.expectedBody, string(body))
+
+			// The metadata is fetched once per request, for the tenant of the request.
+			var metadataQueries []string
+			for _, r := range metadataReqs {
+				assert.Equal(t, "/prometheus/api/v1/metadata", r.URL.Path)
+				assert.Equal(t, "user-1", r.Header.Get(user.OrgIDHeaderName))
+				metadataQueries = append(metadataQueries, r.URL.Query().Get("metric"))
+			}
+			assert.Equal(t, testData.expectedMetadataQueries, metadataQueries)
+		})
+	}
+}
+
+func TestIsUnanchoredRegexMatcher(t *testing.T) {
+	for value, expected := range map[string]bool{
+		".*api.*": true,
+		".+api":   true,
+		".*":      false,
+		".+":      false,
+		"api.*":   false,
+		"api|web": false,
+	} {
+		t.Run(value, func(t *testing.T) {
+			matcher, err := labels.NewMatcher(labels.MatchRegexp, "job", value)
+			require.NoError(t, err)
+			assert.Equal(t, expected, isUnanchoredRegexMatcher(matcher))
+		})
+	}
+}
+
+func TestIsFormatQuery(t *testing.T) {
+	assert.True(t, isFormatQuery("/prometheus/api/v1/format_query"))
+	assert.False(t, isFormatQuery("/prometheus/api/v1/query"))
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	querySourceRuler = "ruler"
+	querySourceUser  = "user"
+)
+
+// newQuerySourceLimitsTripperware creates a Tripperware running the read requests of each tenant in two concurrency
+// pools, one for the rule evaluations run by the ruler and one for the other requests, so that a flood of requests
+// of a source doesn't delay the requests of the other source. The requests exceeding the concurrency of their pool
+// wait for a running request to complete. The rule evaluations also get their own timeout.
+func newQuerySourceLimitsTripperware(limits Limits, logger log.Logger, registerer prometheus.Registerer) Tripperware {
+	pools := newConcurrencyPools()
+	waitingRequests := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_concurrency_limited_requests_total",
+		Help: "Total number of read requests which waited because the tenant reached the max concurrent read requests of the request source.",
+	}, []string{"source"})
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			tenantIDs, err := tenant.TenantIDs(r.Context())
+			if err != nil {
+				return nil, apierror.New(apierror.TypeBadData, err.Error())
+			}
+
+			source, maxConcurrent := querySourceUser, limits.MaxConcurrentUserQueries
+			if isRuleEvaluation(r) {
+				source, maxConcurrent = querySourceRuler, limits.RulerMaxConcurrentQueries
+			}
+
+			// The limit of cross-tenant queries is the smallest limit of their tenants.
+			if limit := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, maxConcurrent); limit > 0 {
+				key := tenant.JoinTenantIDs(tenantIDs) + "/" + source
+				waited, err := pools.acquire(r.Context(), key, limit)
+				if waited {
+					level.Debug(logger).Log("msg", "read request waited because the tenant reached the max concurrent requests of its source", "user", tenant.JoinTenantIDs(tenantIDs), "source", source, "path", r.URL.Path, "limit", limit)
+					waitingRequests.WithLabelValues(source).Inc()
+				}
+				if err != nil {
+					return nil, err
+				}
+				defer pools.release(key)
+			}
+
+			if source != querySourceRuler {
+				return next.RoundTrip(r)
+			}
+
+			timeout := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, limits.RulerQueryTimeout)
+			if timeout <= 0 {
+				return next.RoundTrip(r)
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			resp, err := next.RoundTrip(r.WithContext(ctx))
+			if err != nil || resp == nil || resp.Body == nil {
+				cancel()
+				return resp, err
+			}
+
+			// The response body may be streamed from the downstream, so the context is only canceled once it's closed.
+			resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// cancelOnCloseBody is a response body canceling the context of its request once closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// concurrencyPools limits the number of concurrent requests by key. The slots released by the completed requests
+// are handed over to the waiting requests in FIFO order.
+type concurrencyPools struct {
+	mtx   sync.Mutex
+	pools map[string]*concurrencyPool
+}
+
+type concurrencyPool struct {
+	inflight int
+	limit    int
+	waiting  []chan struct{}
+}
+
+func newConcurrencyPools() *concurrencyPools {
+	return &concurrencyPools{
+		pools: map[string]*concurrencyPool{},
+	}
+}
+
+// acquire takes a slot of the pool of the key, waiting for one to be released if the pool is full. It returns
+// whether the request had to wait, and the context error if the context is done before a slot is released.
+func (p *concurrencyPools) acquire(ctx context.Context, key string, limit int) (bool, error) {
+	p.mtx.Lock()
+
+	pool, ok := p.pools[key]
+	if !ok {
+		pool = &concurrencyPool{}
+		p.pools[key] = pool
+	}
+	// The limit can change between calls: the waiting requests get the slots added by a higher limit first.
+	pool.limit = limit
+	for pool.inflight < limit && len(pool.waiting) > 0 {
+		pool.inflight++
+		close(pool.waiting[0])
+		pool.waiting[0] = nil
+		pool.waiting = pool.waiting[1:]
+	}
+
+	if pool.inflight < limit {
+		pool.inflight++
+		p.mtx.Unlock()
+		return false, nil
+	}
+
+	ready := make(chan struct{})
+	pool.waiting = append(pool.waiting, ready)
+	p.mtx.Unlock()
+
+	select {
+	case <-ready:
+		return true, nil
+	case <-ctx.Done():
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+
+		for i, ch := range pool.waiting {
+			if ch == ready {
+				pool.waiting = append(pool.waiting[:i], pool.waiting[i+1:]...)
+				return true, ctx.Err()
+			}
+		}
+
+		// The slot has been handed over while the context was done, so it's released to the next request.
+		p.releaseLocked(key, pool)
+		return true, ctx.Err()
+	}
+}
+
+// release releases a slot of the pool of the key, acquired by acquire.
+func (p *concurrencyPools) release(key string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if pool, ok := p.pools[key]; ok {
+		p.releaseLocked(key, pool)
+	}
+}
+
+func (p *concurrencyPools) releaseLocked(key string, pool *concurrencyPool) {
+	// The slot is handed over to the next waiting request, unless the limit has been lowered below the inflight requests.
+	if len(pool.waiting) > 0 && pool.inflight <= pool.limit {
+		close(pool.waiting[0])
+		pool.waiting[0] = nil
+		pool.waiting = pool.waiting[1:]
+		return
+	}
+
+	pool.inflight--
+	if pool.inflight <= 0 && len(pool.waiting) == 0 {
+		delete(p.pools, key)
+	}
+}
@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/scheduler/queue"
+)
+
+func TestQuerySourceLimitsTripperware_ShouldLimitTheConcurrencyOfEachSource(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	limits := mockLimits{maxConcurrentUserQueries: 1, rulerMaxConcurrentQueries: 1}
+
+	started := make(chan string, 10)
+	unblock := make(chan struct{})
+	downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		started <- r.URL.Query().Get("query")
+		<-unblock
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+	tripper := newQuerySourceLimitsTripperware(limits, log.NewNopLogger(), reg)(downstream)
+
+	roundTrip := func(query string, fromRuler bool) chan error {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query="+query, nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+		if fromRuler {
+			req.Header.Set(queue.QuerySourceHeader, queue.QuerySourceRuler)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := tripper.RoundTrip(req)
+			done <- err
+		}()
+		return done
+	}
+
+	// The user query fills the user pool, but doesn't delay the rule evaluation.
+	user1 := roundTrip("user1", false)
+	assert.Equal(t, "user1", <-started)
+	ruler1 := roundTrip("ruler1", true)
+	assert.Equal(t, "ruler1", <-started)
+
+	// The next requests of both sources wait for the running ones to complete.
+	user2 := roundTrip("user2", false)
+	ruler2 := roundTrip("ruler2", true)
+	assert.Never(t, func() bool { return len(started) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	close(unblock)
+	for _, done := range []chan error{user1, ruler1, user2, ruler2} {
+		require.NoError(t, <-done)
+	}
+	assert.ElementsMatch(t, []string{"user2", "ruler2"}, []string{<-started, <-started})
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_query_frontend_concurrency_limited_requests_total Total number of read requests which waited because the tenant reached the max concurrent read requests of the request source.
+		# TYPE cortex_query_frontend_concurrency_limited_requests_total counter
+		cortex_query_frontend_concurrency_limited_requests_total{source="ruler"} 1
+		cortex_query_frontend_concurrency_limited_requests_total{source="user"} 1
+	`), "cortex_query_frontend_concurrency_limited_requests_total"))
+}
+
+func TestQuerySourceLimitsTripperware_ShouldApplyTheRulerQueryTimeout(t *testing.T) {
+	limits := mockLimits{rulerQueryTimeout: time.Minute}
+
+	var deadline time.Time
+	downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		deadline, _ = r.Context().Deadline()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	})
+	tripper := newQuerySourceLimitsTripperware(limits, log.NewNopLogger(), nil)(downstream)
+
+	for name, fromRuler := range map[string]bool{"rule evaluation": true, "user query": false} {
+		t.Run(name, func(t *testing.T) {
+			deadline = time.Time{}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			if fromRuler {
+				req.Header.Set(queue.QuerySourceHeader, queue.QuerySourceRuler)
+			}
+
+			resp, err := tripper.RoundTrip(req)
+			require.NoError(t, err)
+
+			// The body is still readable once the request has returned.
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "ok", string(body))
+			require.NoError(t, resp.Body.Close())
+
+			if fromRuler {
+				assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 10*time.Second)
+			} else {
+				assert.True(t, deadline.IsZero())
+			}
+		})
+	}
+}
+
+func TestConcurrencyPools(t *testing.T) {
+	pools := newConcurrencyPools()
+	ctx := context.Background()
+
+	waited, err := pools.acquire(ctx, "user-1", 1)
+	require.NoError(t, err)
+	assert.False(t, waited)
+
+	// The pools of the other keys are independent.
+	waited, err = pools.acquire(ctx, "user-2", 1)
+	require.NoError(t, err)
+	assert.False(t, waited)
+	pools.release("user-2")
+
+	// The requests waiting for a slot stop waiting once their context is done.
+	canceledCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	waited, err = pools.acquire(canceledCtx, "user-1", 1)
+	assert.True(t, waited)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// The released slot is handed over to the waiting request.
+	acquired := make(chan struct{})
+	go func() {
+		waited, err := pools.acquire(ctx, "user-1", 1)
+		assert.NoError(t, err)
+		assert.True(t, waited)
+		close(acquired)
+	}()
+	require.Eventually(t, func() bool {
+		pools.mtx.Lock()
+		defer pools.mtx.Unlock()
+		return len(pools.pools["user-1"].waiting) == 1
+	}, time.Second, 10*time.Millisecond)
+	pools.release("user-1")
+	<-acquired
+
+	// A higher limit lets more requests run.
+	waited, err = pools.acquire(ctx, "user-1", 2)
+	require.NoError(t, err)
+	assert.False(t, waited)
+
+	pools.release("user-1")
+	pools.release("user-1")
+	assert.Empty(t, pools.pools)
+}
+
+func TestIsRuleEvaluation(t *testing.T) {
+	tests := map[string]struct {
+		headers  map[string]string
+		expected bool
+	}{
+		"no header": {},
+		"query source header set by the ruler": {
+			headers:  map[string]string{queue.QuerySourceHeader: queue.QuerySourceRuler},
+			expected: true,
+		},
+		"query source header set to another source": {
+			headers: map[string]string{queue.QuerySourceHeader: "user"},
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+			for k, v := range testData.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, testData.expected, isRuleEvaluation(req))
+		})
+	}
+}
@@ -151,7 +151,8 @@ func (s *querySharding) Do(ctx context.Context, r Request) (Response, error) {
 			ResultType: string(res.Value.Type()),
 			Result:     extracted,
 		},
-		Headers: shardedQueryable.getResponseHeaders(),
+		Headers:  shardedQueryable.getResponseHeaders(),
+		Warnings: promqlWarningsToStrings(res.Warnings),
 	}, nil
 }
 
@@ -255,9 +256,19 @@ func (s *querySharding) getShardsForQuery(tenantIDs []string, r Request, spanLog
 		return 1
 	}
 
-	// Honor the number of shards specified in the request (if any).
+	// Honor the number of shards specified in the request (if any), up to the max requested shards,
+	// which defaults to the tenant's number of shards.
 	if r.GetOptions().TotalShards > 0 {
+		maxRequestedShards := validation.SmallestPositiveIntPerTenant(tenantIDs, s.limit.QueryShardingMaxRequestedShards)
+		if maxRequestedShards <= 0 {
+			maxRequestedShards = totalShards
+		}
+
 		totalShards = int(r.GetOptions().TotalShards)
+		if totalShards > maxRequestedShards {
+			level.Debug(spanLog).Log("msg", "capped the number of shards requested by the query", "requested shards", totalShards, "max requested shards", maxRequestedShards)
+			totalShards = maxRequestedShards
+		}
 	}
 
 	maxShardedQueries := validation.SmallestPositiveIntPerTenant(tenantIDs, s.limit.QueryShardingMaxShardedQueries)
@@ -38,6 +38,7 @@ import (
 	apierror "github.com/grafana/mimir/pkg/api/error"
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/engine"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -395,9 +396,41 @@ func TestQueryShardingCorrectness(t *testing.T) {
 			query:                  `max by(unique) (max_over_time(metric_counter[5m])) > scalar(min(metric_counter))`,
 			expectedShardedQueries: 2,
 		},
+		`binary operation between two functions of the same metric`: {
+			query:                  `rate(metric_counter[1m]) - rate(metric_counter[5m])`,
+			expectedShardedQueries: 1,
+		},
+		`bool comparison between two vectors of the same metric`: {
+			query:                  `metric_counter > bool metric_counter offset 1m`,
+			expectedShardedQueries: 1,
+		},
+		`set operation or between two vectors of the same metric`: {
+			query:                  `metric_counter{group_1="0"} or metric_counter{group_2="1"}`,
+			expectedShardedQueries: 1,
+		},
+		`set operation unless between two vectors of the same metric`: {
+			query:                  `metric_counter unless metric_counter{group_1="0"}`,
+			expectedShardedQueries: 1,
+		},
+		`aggregation of a binary operation between two vectors of the same metric`: {
+			query:                  `sum by (group_1) (rate(metric_counter[1m]) - rate(metric_counter[5m]))`,
+			expectedShardedQueries: 1,
+		},
+		`subquery on a binary operation between two vectors of the same metric`: {
+			query:                  `max_over_time((rate(metric_counter[1m]) - rate(metric_counter[5m]))[10m:1m])`,
+			expectedShardedQueries: 1,
+		},
 		//
 		// The following queries are not expected to be shardable.
 		//
+		`set operation with vector matching on some labels`: {
+			query:                  `metric_counter unless on(group_1) metric_counter{group_1="0"}`,
+			expectedShardedQueries: 0,
+		},
+		`binary operation between two vectors of different metrics`: {
+			query:                  `metric_counter or metric_histogram_bucket`,
+			expectedShardedQueries: 0,
+		},
 		"subquery min_over_time with aggr": {
 			query: `min_over_time(
 						sum by(group_1) (
@@ -775,6 +808,8 @@ func labelsForShardsGenerator(base labels.Labels, shards uint64) func(shard uint
 // TestQuerySharding_FunctionCorrectness is the old test that probably at some point inspired the TestQuerySharding_Correctness,
 // we keep it here since it adds more test cases.
 func TestQuerySharding_FunctionCorrectness(t *testing.T) {
+	engine.RegisterExperimentalFunctions()
+
 	mkQueries, tests := func(tpl, fn string, testMatrix bool, fArgs []string) []string {
 		if tpl == "" {
 			tpl = `(<fn>(bar1{}<args>))`
@@ -865,6 +900,8 @@ func TestQuerySharding_FunctionCorrectness(t *testing.T) {
 		{fn: "predict_linear", args: []string{"1"}, rangeQuery: true},
 		{fn: "round", args: []string{"20"}},
 		{fn: "holt_winters", args: []string{"0.5", "0.7"}, rangeQuery: true},
+		{fn: "double_exponential_smoothing", args: []string{"0.5", "0.7"}, rangeQuery: true},
+		{fn: "mad_over_time", rangeQuery: true},
 		{fn: "label_replace", args: []string{`"fuzz"`, `"$1"`, `"foo"`, `"b(.*)"`}},
 		{fn: "label_join", args: []string{`"fuzz"`, `","`, `"foo"`, `"bar"`}},
 	}
@@ -1005,7 +1042,7 @@ func TestQuerySharding_ShouldOverrideShardingSizeViaOption(t *testing.T) {
 		},
 	}
 
-	shardingware := newQueryShardingMiddleware(log.NewNopLogger(), newEngine(), mockLimits{totalShards: 16}, nil)
+	shardingware := newQueryShardingMiddleware(log.NewNopLogger(), newEngine(), mockLimits{totalShards: 16, maxRequestedShards: 128}, nil)
 
 	downstream := &mockHandler{}
 	downstream.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{
@@ -1022,6 +1059,62 @@ func TestQuerySharding_ShouldOverrideShardingSizeViaOption(t *testing.T) {
 	downstream.AssertNumberOfCalls(t, "Do", 128)
 }
 
+func TestQuerySharding_ShouldCapTheShardingSizeOverriddenViaOption(t *testing.T) {
+	req := &PrometheusRangeQueryRequest{
+		Path:  "/query_range",
+		Start: util.TimeToMillis(start),
+		End:   util.TimeToMillis(end),
+		Step:  step.Milliseconds(),
+		Query: "sum by (foo) (rate(bar{}[1m]))", // shardable query.
+		Options: Options{
+			TotalShards: 128,
+		},
+	}
+
+	shardingware := newQueryShardingMiddleware(log.NewNopLogger(), newEngine(), mockLimits{totalShards: 16, maxRequestedShards: 32}, nil)
+
+	downstream := &mockHandler{}
+	downstream.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{
+		Status: statusSuccess, Data: &PrometheusData{
+			ResultType: string(parser.ValueTypeVector),
+		},
+	}, nil)
+
+	res, err := shardingware.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "test"), req)
+	require.NoError(t, err)
+	assert.Equal(t, statusSuccess, res.(*PrometheusResponse).GetStatus())
+	// we expect the requested shards to be capped to the max requested shards.
+	downstream.AssertNumberOfCalls(t, "Do", 32)
+}
+
+func TestQuerySharding_ShouldCapTheShardingSizeOverriddenViaOptionToTheTenantShardsByDefault(t *testing.T) {
+	req := &PrometheusRangeQueryRequest{
+		Path:  "/query_range",
+		Start: util.TimeToMillis(start),
+		End:   util.TimeToMillis(end),
+		Step:  step.Milliseconds(),
+		Query: "sum by (foo) (rate(bar{}[1m]))", // shardable query.
+		Options: Options{
+			TotalShards: 128,
+		},
+	}
+
+	shardingware := newQueryShardingMiddleware(log.NewNopLogger(), newEngine(), mockLimits{totalShards: 16}, nil)
+
+	downstream := &mockHandler{}
+	downstream.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{
+		Status: statusSuccess, Data: &PrometheusData{
+			ResultType: string(parser.ValueTypeVector),
+		},
+	}, nil)
+
+	res, err := shardingware.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "test"), req)
+	require.NoError(t, err)
+	assert.Equal(t, statusSuccess, res.(*PrometheusResponse).GetStatus())
+	// we expect the requested shards to be capped to the tenant shards, because the max requested shards is not set.
+	downstream.AssertNumberOfCalls(t, "Do", 16)
+}
+
 func TestQuerySharding_ShouldSupportMaxShardedQueries(t *testing.T) {
 	tests := map[string]struct {
 		query             string
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// The fetched bytes are tracked in a rolling window of a minute, made of buckets of a few seconds each.
+	readBandwidthQuotaWindow        = time.Minute
+	readBandwidthQuotaBucketsPerWin = 6
+	readBandwidthQuotaBucketSize    = readBandwidthQuotaWindow / readBandwidthQuotaBucketsPerWin
+)
+
+// newReadBandwidthQuotaTripperware creates a Tripperware rejecting the read requests of the tenants which fetched
+// more chunk bytes than their limit in the last minute. The fetched chunk bytes are tracked from the query statistics
+// of the requests, which are fed back by the queriers.
+func newReadBandwidthQuotaTripperware(limits Limits, logger log.Logger, registerer prometheus.Registerer) Tripperware {
+	quota := newReadBandwidthQuota()
+	rejectedRequests := promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_read_bandwidth_quota_rejected_requests_total",
+		Help: "Total number of read requests rejected because the tenant exceeded the limit of chunk bytes fetched in the last minute.",
+	})
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			tenantIDs, err := tenant.TenantIDs(r.Context())
+			if err != nil {
+				return nil, apierror.New(apierror.TypeBadData, err.Error())
+			}
+
+			// The read requests of cross-tenant queries are rejected if any tenant exceeded its limit.
+			now := time.Now()
+			for _, tenantID := range tenantIDs {
+				limit := limits.MaxFetchedChunkBytesPerMinute(tenantID)
+				if limit > 0 && quota.fetchedBytes(tenantID, now) >= uint64(limit) {
+					level.Debug(logger).Log("msg", "rejecting read request because the tenant exceeded the limit of chunk bytes fetched in the last minute", "user", tenantID, "path", r.URL.Path, "limit", limit)
+					rejectedRequests.Inc()
+					return nil, apierror.NewWithRetryAfter(apierror.TypeTooManyRequests, validation.NewMaxFetchedChunkBytesPerMinuteError(limit).Error(), quota.retryAfter(tenantID, uint64(limit), now))
+				}
+			}
+
+			// The query statistics aren't tracked if disabled.
+			stats := querier_stats.FromContext(r.Context())
+			if stats == nil {
+				return next.RoundTrip(r)
+			}
+
+			before := stats.LoadFetchedChunkBytes()
+			resp, err := next.RoundTrip(r)
+
+			// The bytes fetched by cross-tenant queries are accounted to each tenant, since they aren't tracked per tenant.
+			if fetched := stats.LoadFetchedChunkBytes() - before; fetched > 0 {
+				now = time.Now()
+				for _, tenantID := range tenantIDs {
+					if limits.MaxFetchedChunkBytesPerMinute(tenantID) > 0 {
+						quota.add(tenantID, fetched, now)
+					}
+				}
+			}
+
+			return resp, err
+		})
+	}
+}
+
+// readBandwidthQuota tracks the bytes fetched by each tenant in a rolling window.
+type readBandwidthQuota struct {
+	mtx       sync.Mutex
+	tenants   map[string]*fetchedBytesWindow
+	lastPurge time.Time
+}
+
+func newReadBandwidthQuota() *readBandwidthQuota {
+	return &readBandwidthQuota{
+		tenants: map[string]*fetchedBytesWindow{},
+	}
+}
+
+// add tracks the bytes fetched by the tenant at the given time.
+func (q *readBandwidthQuota) add(tenantID string, bytes uint64, now time.Time) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	w, ok := q.tenants[tenantID]
+	if !ok {
+		w = &fetchedBytesWindow{}
+		q.tenants[tenantID] = w
+	}
+	w.add(bytes, now)
+
+	// Remove the tenants which didn't fetch any byte in the window, at most once per window.
+	if now.Sub(q.lastPurge) >= readBandwidthQuotaWindow {
+		for id, w := range q.tenants {
+			if w.total(now) == 0 {
+				delete(q.tenants, id)
+			}
+		}
+		q.lastPurge = now
+	}
+}
+
+// fetchedBytes returns the bytes fetched by the tenant in the window ending at the given time.
+func (q *readBandwidthQuota) fetchedBytes(tenantID string, now time.Time) uint64 {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	w, ok := q.tenants[tenantID]
+	if !ok {
+		return 0
+	}
+	return w.total(now)
+}
+
+// retryAfter returns how long it takes for the bytes fetched by the tenant in the window to get below the limit, as
+// the oldest buckets of the window expire.
+func (q *readBandwidthQuota) retryAfter(tenantID string, limit uint64, now time.Time) time.Duration {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	w, ok := q.tenants[tenantID]
+	if !ok {
+		return 0
+	}
+	return w.untilBelow(limit, now)
+}
+
+// fetchedBytesWindow is a rolling window of the fetched bytes, split in buckets.
+type fetchedBytesWindow struct {
+	// The start of each bucket, as the number of bucket sizes since the epoch, and its fetched bytes.
+	starts [readBandwidthQuotaBucketsPerWin]int64
+	bytes  [readBandwidthQuotaBucketsPerWin]uint64
+}
+
+func (w *fetchedBytesWindow) add(bytes uint64, now time.Time) {
+	start := now.UnixNano() / int64(readBandwidthQuotaBucketSize)
+	idx := start % readBandwidthQuotaBucketsPerWin
+
+	// Reset the bucket if it was last used in a previous window.
+	if w.starts[idx] != start {
+		w.starts[idx] = start
+		w.bytes[idx] = 0
+	}
+	w.bytes[idx] += bytes
+}
+
+func (w *fetchedBytesWindow) total(now time.Time) uint64 {
+	start := now.UnixNano() / int64(readBandwidthQuotaBucketSize)
+
+	total := uint64(0)
+	for idx := range w.starts {
+		if start-w.starts[idx] < readBandwidthQuotaBucketsPerWin {
+			total += w.bytes[idx]
+		}
+	}
+	return total
+}
+
+// untilBelow returns how long it takes for the total of the window to get below the limit, as its buckets expire.
+func (w *fetchedBytesWindow) untilBelow(limit uint64, now time.Time) time.Duration {
+	total := w.total(now)
+	if total < limit {
+		return 0
+	}
+
+	// The buckets of the window are walked from the oldest one, which is the first one to expire.
+	current := now.UnixNano() / int64(readBandwidthQuotaBucketSize)
+	for start := current - readBandwidthQuotaBucketsPerWin + 1; start <= current; start++ {
+		idx := start % readBandwidthQuotaBucketsPerWin
+		if w.starts[idx] != start {
+			continue
+		}
+
+		total -= w.bytes[idx]
+		if total < limit {
+			expiresAt := time.Unix(0, (start+readBandwidthQuotaBucketsPerWin)*int64(readBandwidthQuotaBucketSize))
+			return expiresAt.Sub(now)
+		}
+	}
+	return readBandwidthQuotaWindow
+}
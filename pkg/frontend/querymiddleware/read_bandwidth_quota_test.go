@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+func TestReadBandwidthQuotaTripperware(t *testing.T) {
+	tests := map[string]struct {
+		limit            int
+		statsEnabled     bool
+		expectedRejected bool
+	}{
+		"limit disabled": {
+			limit:        0,
+			statsEnabled: true,
+		},
+		"limit not exceeded": {
+			limit:        1000,
+			statsEnabled: true,
+		},
+		"limit exceeded": {
+			limit:            100,
+			statsEnabled:     true,
+			expectedRejected: true,
+		},
+		"limit exceeded, query stats disabled": {
+			limit: 100,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			limits := mockLimits{maxFetchedChunkBytesPerMin: testData.limit}
+
+			// Each request to the downstream fetches 60 chunk bytes.
+			downstreamCalls := 0
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				downstreamCalls++
+				querier_stats.FromContext(r.Context()).AddFetchedChunkBytes(60)
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			tripper := newReadBandwidthQuotaTripperware(limits, log.NewNopLogger(), reg)(downstream)
+
+			roundTrip := func() (*http.Response, error) {
+				ctx := user.InjectOrgID(context.Background(), "user-1")
+				if testData.statsEnabled {
+					_, ctx = querier_stats.ContextWithEmptyStats(ctx)
+				}
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up", nil).WithContext(ctx)
+				return tripper.RoundTrip(req)
+			}
+
+			// The first two requests are below the limit, since it's checked before running the requests.
+			for i := 0; i < 2; i++ {
+				resp, err := roundTrip()
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+
+			resp, err := roundTrip()
+			if testData.expectedRejected {
+				require.Error(t, err)
+				assert.True(t, apierror.IsAPIError(err))
+				assert.Contains(t, err.Error(), "the tenant exceeded the limit of 100 chunk bytes fetched in the last minute")
+				httpResp, ok := apierror.HTTPResponseFromError(err)
+				require.True(t, ok)
+				assert.Equal(t, int32(http.StatusTooManyRequests), httpResp.Code)
+
+				// The bytes are fetched in the current bucket, so the request can be retried once the bucket expires.
+				var retryAfter []string
+				for _, h := range httpResp.Headers {
+					if h.Key == "Retry-After" {
+						retryAfter = h.Values
+					}
+				}
+				require.Len(t, retryAfter, 1)
+				seconds, err := strconv.Atoi(retryAfter[0])
+				require.NoError(t, err)
+				assert.Greater(t, seconds, int((readBandwidthQuotaWindow - readBandwidthQuotaBucketSize).Seconds()))
+				assert.LessOrEqual(t, seconds, int(readBandwidthQuotaWindow.Seconds()))
+				assert.Equal(t, 2, downstreamCalls)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, 3, downstreamCalls)
+			}
+
+			expectedRejected := 0
+			if testData.expectedRejected {
+				expectedRejected = 1
+			}
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+				# HELP cortex_query_frontend_read_bandwidth_quota_rejected_requests_total Total number of read requests rejected because the tenant exceeded the limit of chunk bytes fetched in the last minute.
+				# TYPE cortex_query_frontend_read_bandwidth_quota_rejected_requests_total counter
+				cortex_query_frontend_read_bandwidth_quota_rejected_requests_total %d
+			`, expectedRejected)), "cortex_query_frontend_read_bandwidth_quota_rejected_requests_total"))
+		})
+	}
+}
+
+func TestReadBandwidthQuota(t *testing.T) {
+	q := newReadBandwidthQuota()
+	now := time.Unix(1000, 0)
+
+	q.add("user-1", 10, now)
+	q.add("user-1", 20, now.Add(readBandwidthQuotaBucketSize))
+	q.add("user-2", 5, now)
+
+	assert.Equal(t, uint64(30), q.fetchedBytes("user-1", now.Add(readBandwidthQuotaBucketSize)))
+	assert.Equal(t, uint64(5), q.fetchedBytes("user-2", now.Add(readBandwidthQuotaBucketSize)))
+	assert.Equal(t, uint64(0), q.fetchedBytes("user-3", now))
+
+	// The bytes fetched in the buckets older than the window are not counted anymore.
+	assert.Equal(t, uint64(20), q.fetchedBytes("user-1", now.Add(readBandwidthQuotaWindow)))
+	assert.Equal(t, uint64(0), q.fetchedBytes("user-2", now.Add(readBandwidthQuotaWindow)))
+
+	// The bucket is reset when it's reused in a following window.
+	q.add("user-1", 1, now.Add(readBandwidthQuotaWindow))
+	assert.Equal(t, uint64(21), q.fetchedBytes("user-1", now.Add(readBandwidthQuotaWindow)))
+
+	// The retry after is the time until enough buckets expire to get below the limit.
+	assert.Equal(t, time.Duration(0), q.retryAfter("user-1", 100, now.Add(readBandwidthQuotaWindow)))
+	assert.Equal(t, readBandwidthQuotaBucketSize, q.retryAfter("user-1", 21, now.Add(readBandwidthQuotaWindow)))
+	assert.Equal(t, readBandwidthQuotaWindow, q.retryAfter("user-1", 1, now.Add(readBandwidthQuotaWindow)))
+	assert.Equal(t, time.Duration(0), q.retryAfter("user-3", 1, now))
+
+	// The tenants which didn't fetch any byte in the window are removed.
+	q.add("user-1", 1, now.Add(2*readBandwidthQuotaWindow))
+	assert.Contains(t, q.tenants, "user-1")
+	assert.NotContains(t, q.tenants, "user-2")
+}
@@ -0,0 +1,411 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	// federatedQueryHeader marks the queries sent to the remote clusters, which don't federate them again, to not
+	// loop across clusters federating the queries of the same tenant with each other. The results of the marked
+	// queries are not post-processed, so the header must be removed from the requests of the untrusted clients.
+	federatedQueryHeader = "X-Mimir-Federated-Query"
+
+	// remoteQueryFederationTimeout is the max time the remote clusters have to run the query and return the result,
+	// which is the default timeout of the queries.
+	remoteQueryFederationTimeout = 2 * time.Minute
+)
+
+type federatedQueryContextKey struct{}
+
+// contextWithFederatedQuery returns a context marking the request as federated by a remote cluster.
+func contextWithFederatedQuery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, federatedQueryContextKey{}, true)
+}
+
+// isFederatedQueryContext returns whether the request of the context has been federated by a remote cluster.
+func isFederatedQueryContext(ctx context.Context) bool {
+	v, ok := ctx.Value(federatedQueryContextKey{}).(bool)
+	return ok && v
+}
+
+type remoteQueryFederationMiddlewareMetrics struct {
+	requests       prometheus.Counter
+	failedRequests prometheus.Counter
+}
+
+func newRemoteQueryFederationMiddlewareMetrics(reg prometheus.Registerer) *remoteQueryFederationMiddlewareMetrics {
+	return &remoteQueryFederationMiddlewareMetrics{
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_remote_query_federation_requests_total",
+			Help: "Total number of queries sent to the remote clusters the queries are federated across.",
+		}),
+		failedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_remote_query_federation_failed_requests_total",
+			Help: "Total number of queries sent to the remote clusters the queries are federated across which failed.",
+		}),
+	}
+}
+
+// remoteQueryFederationMiddleware federates the instant and range queries of the tenant across the remote Mimir
+// clusters configured for the tenant: the query is run both by the next handler, on the local cluster, and by the
+// Prometheus HTTP API of each remote cluster, for the same tenant, and the results are merged.
+//
+// The results are merged series by series: a series returned by multiple clusters has the samples of the cluster
+// with the earliest data, followed by the more recent samples of the other clusters, so that the data of a tenant
+// being migrated between clusters is returned once. The aggregations are not combined across clusters. When the
+// clusters return different samples for the same series at the same timestamp, a warning is returned.
+//
+// The failures of the remote clusters are returned as warnings, so that the queries keep working on the local data
+// only when a remote cluster is unavailable.
+//
+// The queries sent to the remote clusters are marked by the federatedQueryHeader: the remote clusters don't federate
+// them again, and don't post-process their results, which are post-processed once merged.
+type remoteQueryFederationMiddleware struct {
+	next    Handler
+	limits  Limits
+	codec   Codec
+	client  *http.Client
+	logger  log.Logger
+	metrics *remoteQueryFederationMiddlewareMetrics
+}
+
+// newRemoteQueryFederationMiddleware makes a new remoteQueryFederationMiddleware.
+func newRemoteQueryFederationMiddleware(limits Limits, codec Codec, client *http.Client, logger log.Logger, metrics *remoteQueryFederationMiddlewareMetrics) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &remoteQueryFederationMiddleware{
+			next:    next,
+			limits:  limits,
+			codec:   codec,
+			client:  client,
+			logger:  logger,
+			metrics: metrics,
+		}
+	})
+}
+
+func (m *remoteQueryFederationMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	// The remote clusters are only configured per tenant, so the queries of multiple tenants are not federated.
+	if len(tenantIDs) != 1 || isFederatedQueryContext(ctx) {
+		return m.next.Do(ctx, req)
+	}
+
+	remoteURLs := m.limits.RemoteQueryFederationURLs(tenantIDs[0])
+	if len(remoteURLs) == 0 {
+		return m.next.Do(ctx, req)
+	}
+
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, m.logger, "remoteQueryFederationMiddleware.Do")
+	defer spanLog.Finish()
+
+	// The first response is the local one.
+	responses := make([]*PrometheusResponse, len(remoteURLs)+1)
+	remoteErrs := make([]error, len(remoteURLs)+1)
+
+	err = concurrency.ForEachJob(ctx, len(responses), len(responses), func(ctx context.Context, idx int) error {
+		if idx == 0 {
+			resp, err := m.next.Do(ctx, req)
+			if err != nil {
+				return err
+			}
+			promResp, ok := resp.(*PrometheusResponse)
+			if !ok {
+				return fmt.Errorf("unexpected response type %T", resp)
+			}
+			responses[idx] = promResp
+			return nil
+		}
+
+		remoteURL := remoteURLs[idx-1]
+		m.metrics.requests.Inc()
+
+		resp, err := m.doRemote(ctx, remoteURL, tenantIDs[0], req)
+		if err != nil {
+			m.metrics.failedRequests.Inc()
+			level.Warn(spanLog).Log("msg", "failed to run the federated query on the remote cluster", "remote_url", redactURL(remoteURL), "err", err)
+			remoteErrs[idx] = err
+			return nil
+		}
+		responses[idx] = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var successful []*PrometheusResponse
+	var warnings []string
+	for idx, resp := range responses {
+		if resp != nil {
+			successful = append(successful, resp)
+		}
+		if remoteErrs[idx] != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to query the remote cluster %s: %v", redactURL(remoteURLs[idx-1]), remoteErrs[idx]))
+		}
+	}
+
+	merged, err := mergeFederatedResponses(successful)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeExec, err.Error())
+	}
+	merged.Warnings = mergeWarnings(merged.Warnings, warnings)
+	return merged, nil
+}
+
+// doRemote runs the query for the tenant on the Prometheus HTTP API of the remote cluster.
+func (m *remoteQueryFederationMiddleware) doRemote(ctx context.Context, remoteURL, tenantID string, req Request) (*PrometheusResponse, error) {
+	httpReq, err := m.codec.EncodeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The remote URL is the prefix of the Prometheus HTTP API of the remote cluster, which can differ from the local one.
+	base, err := url.Parse(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	apiPath := httpReq.URL.Path
+	if idx := strings.LastIndex(apiPath, "/api/v1/"); idx >= 0 {
+		apiPath = apiPath[idx:]
+	}
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + apiPath
+	target.RawQuery = httpReq.URL.RawQuery
+
+	httpReq.URL = &target
+	httpReq.Host = target.Host
+	httpReq.RequestURI = ""
+	httpReq.Header.Set(federatedQueryHeader, "true")
+	if err := user.InjectOrgIDIntoHTTPRequest(user.InjectOrgID(ctx, tenantID), httpReq); err != nil {
+		return nil, err
+	}
+
+	httpResp, err := m.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	resp, err := m.codec.DecodeResponse(ctx, httpResp, req, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	promResp, ok := resp.(*PrometheusResponse)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type %T", resp)
+	}
+	// The headers of the remote clusters are not returned to the client.
+	promResp.Headers = nil
+	return promResp, nil
+}
+
+// mergeFederatedResponses merges the responses of the same query run on multiple clusters. The first response
+// takes precedence for the results which can't be merged.
+func mergeFederatedResponses(responses []*PrometheusResponse) (*PrometheusResponse, error) {
+	if len(responses) == 1 {
+		return responses[0], nil
+	}
+
+	resultType := ""
+	var warnings [][]string
+	for _, resp := range responses {
+		if resp.Status != statusSuccess || resp.Data == nil {
+			return nil, fmt.Errorf("can't merge an unsuccessful response")
+		}
+		if resultType == "" {
+			resultType = resp.Data.ResultType
+		} else if resp.Data.ResultType != resultType {
+			return nil, fmt.Errorf("can't merge the result type %q with the result type %q", resp.Data.ResultType, resultType)
+		}
+		warnings = append(warnings, resp.Warnings)
+	}
+
+	var result []SampleStream
+	switch resultType {
+	case model.ValMatrix.String():
+		var conflicts int
+		result, conflicts = mergeFederatedMatrices(responses)
+		if conflicts > 0 {
+			warnings = append(warnings, []string{fmt.Sprintf("%d series have different samples at the same timestamps in multiple clusters: the samples of the cluster with the earliest data have been returned", conflicts)})
+		}
+	case model.ValVector.String():
+		var conflicts int
+		result, conflicts = mergeFederatedVectors(responses)
+		if conflicts > 0 {
+			warnings = append(warnings, []string{fmt.Sprintf("%d series have a different sample in multiple clusters: the sample of the first cluster returning them has been returned", conflicts)})
+		}
+	default:
+		// The scalars and strings don't depend on the data of the cluster.
+		result = responses[0].Data.Result
+	}
+
+	return &PrometheusResponse{
+		Status:  statusSuccess,
+		Data:    &PrometheusData{ResultType: resultType, Result: result},
+		Headers: responses[0].Headers,
+		// The warnings are merged in the order of the responses.
+		Warnings: mergeWarnings(warnings...),
+	}, nil
+}
+
+// mergeFederatedMatrices merges the series of the range vectors returned by multiple clusters. The samples of
+// a series returned by multiple clusters are taken from the cluster with the earliest samples, followed by the
+// samples of the other clusters after its last sample. It also returns the number of series having different
+// samples at the same timestamp in multiple clusters.
+func mergeFederatedMatrices(responses []*PrometheusResponse) ([]SampleStream, int) {
+	streams := map[string][]SampleStream{}
+	for _, resp := range responses {
+		for _, stream := range resp.Data.Result {
+			if len(stream.Samples) == 0 {
+				continue
+			}
+			key := mimirpb.FromLabelAdaptersToLabels(stream.Labels).String()
+			streams[key] = append(streams[key], stream)
+		}
+	}
+
+	keys := make([]string, 0, len(streams))
+	for key := range streams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]SampleStream, 0, len(keys))
+	conflicts := 0
+	for _, key := range keys {
+		candidates := streams[key]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Samples[0].TimestampMs < candidates[j].Samples[0].TimestampMs
+		})
+
+		// Copy the samples of the first stream, to not append to the backing array of a response.
+		merged := SampleStream{Labels: candidates[0].Labels, Samples: append([]mimirpb.Sample(nil), candidates[0].Samples...)}
+		conflicting := false
+		for _, stream := range candidates[1:] {
+			lastTs := merged.Samples[len(merged.Samples)-1].TimestampMs
+			newer := sliceSamples(stream.Samples, lastTs)
+			if !conflicting {
+				conflicting = hasConflictingSamples(merged.Samples, stream.Samples[:len(stream.Samples)-len(newer)])
+			}
+			merged.Samples = append(merged.Samples, newer...)
+		}
+		if conflicting {
+			conflicts++
+		}
+		result = append(result, merged)
+	}
+	return result, conflicts
+}
+
+// hasConflictingSamples returns whether any of the samples has a different value than the sample of the merged
+// samples at the same timestamp. Both slices are sorted by timestamp.
+func hasConflictingSamples(merged, samples []mimirpb.Sample) bool {
+	for _, sample := range samples {
+		idx := sort.Search(len(merged), func(i int) bool {
+			return merged[i].TimestampMs >= sample.TimestampMs
+		})
+		if idx < len(merged) && merged[idx].TimestampMs == sample.TimestampMs && !sameSampleValue(merged[idx], sample) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSampleValue returns whether the samples have the same value, considering the NaN values equal.
+func sameSampleValue(a, b mimirpb.Sample) bool {
+	return a.Value == b.Value || (math.IsNaN(a.Value) && math.IsNaN(b.Value))
+}
+
+// mergeFederatedVectors merges the series of the instant vectors returned by multiple clusters. The sample of
+// a series returned by multiple clusters is taken from the first response returning it. It also returns the number
+// of series having a different sample in multiple clusters.
+func mergeFederatedVectors(responses []*PrometheusResponse) ([]SampleStream, int) {
+	seen := map[string]int{}
+	conflicting := map[string]struct{}{}
+	result := []SampleStream{}
+	for _, resp := range responses {
+		for _, stream := range resp.Data.Result {
+			key := mimirpb.FromLabelAdaptersToLabels(stream.Labels).String()
+			if idx, ok := seen[key]; ok {
+				if !sameSamples(result[idx].Samples, stream.Samples) {
+					conflicting[key] = struct{}{}
+				}
+				continue
+			}
+			seen[key] = len(result)
+			result = append(result, stream)
+		}
+	}
+	return result, len(conflicting)
+}
+
+// sameSamples returns whether the samples have the same timestamps and values.
+func sameSamples(a, b []mimirpb.Sample) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].TimestampMs != b[i].TimestampMs || !sameSampleValue(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// redactURL returns the URL without the password, if any, so that it can be logged.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// newRemoteQueryFederationClient returns the HTTP client used to query the remote clusters. The requests are
+// bounded by the context of the queries, and by the remoteQueryFederationTimeout.
+func newRemoteQueryFederationClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: remoteQueryFederationTimeout,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		Timeout: remoteQueryFederationTimeout,
+	}
+}
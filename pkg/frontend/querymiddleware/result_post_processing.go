@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/tenant"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	remoteReadPathSuffix = "/api/v1/read"
+
+	// maxRemoteReadRequestSize is the maximum size of the remote read requests decoded to apply the label rules.
+	maxRemoteReadRequestSize = 1024 * 1024
+)
+
+// resultPostProcessor modifies the successful query results of the tenants before they're returned to the client.
+type resultPostProcessor interface {
+	// Process returns the post-processed response. The input response must not be modified.
+	Process(tenantIDs []string, resp *PrometheusResponse) *PrometheusResponse
+}
+
+type resultPostProcessingMiddleware struct {
+	next       Handler
+	processors []resultPostProcessor
+}
+
+// newResultPostProcessingMiddleware creates a middleware that applies the post-processors, in order,
+// to the successful query results.
+func newResultPostProcessingMiddleware(processors ...resultPostProcessor) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return resultPostProcessingMiddleware{
+			next:       next,
+			processors: processors,
+		}
+	})
+}
+
+func (m resultPostProcessingMiddleware) Do(ctx context.Context, r Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	resp, err := m.next.Do(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The results of the queries federated by a remote cluster are post-processed by the remote cluster, once merged.
+	if isFederatedQueryContext(ctx) {
+		return resp, nil
+	}
+
+	promResp, ok := resp.(*PrometheusResponse)
+	if !ok || promResp.Status != statusSuccess || promResp.Data == nil {
+		return resp, nil
+	}
+
+	for _, p := range m.processors {
+		promResp = p.Process(tenantIDs, promResp)
+	}
+	return promResp, nil
+}
+
+// labelRulesPostProcessor applies the tenants' query result label rules to the series labels.
+type labelRulesPostProcessor struct {
+	limits  Limits
+	hashKey []byte
+}
+
+// newLabelRulesPostProcessor returns a post-processor applying the label rules, hashing the label values with the
+// given HMAC key.
+func newLabelRulesPostProcessor(limits Limits, hashKey []byte) resultPostProcessor {
+	return labelRulesPostProcessor{limits: limits, hashKey: hashKey}
+}
+
+// resultLabelRules returns the label rules of the tenants. The rules of all the tenants are applied to the results of
+// cross-tenant queries.
+func resultLabelRules(tenantIDs []string, limits Limits) []validation.ResultLabelRule {
+	var rules []validation.ResultLabelRule
+	for _, tenantID := range tenantIDs {
+		rules = append(rules, limits.QueryResultLabelRules(tenantID)...)
+	}
+	return rules
+}
+
+func (p labelRulesPostProcessor) Process(tenantIDs []string, resp *PrometheusResponse) *PrometheusResponse {
+	rules := resultLabelRules(tenantIDs, p.limits)
+	if len(rules) == 0 {
+		return resp
+	}
+
+	// Copy the response, because the input one may be shared, for example with the results cache.
+	data := *resp.Data
+	data.Result = make([]SampleStream, len(resp.Data.Result))
+	for i, stream := range resp.Data.Result {
+		stream.Labels = applyResultLabelRules(stream.Labels, rules, p.hashKey)
+		data.Result[i] = stream
+	}
+
+	processed := *resp
+	processed.Data = &data
+	return &processed
+}
+
+// applyResultLabelRules returns a copy of the input labels with the rules applied. The values of the labels with the
+// hash action are replaced with their HMAC-SHA256 with the given key, or dropped if the key is empty, because the
+// unsalted hashes of values with a low entropy, like the email addresses, could be reversed.
+func applyResultLabelRules(lbls []mimirpb.LabelAdapter, rules []validation.ResultLabelRule, hashKey []byte) []mimirpb.LabelAdapter {
+	out := make([]mimirpb.LabelAdapter, len(lbls))
+	copy(out, lbls)
+
+	for _, rule := range rules {
+		idx := -1
+		for i, l := range out {
+			if l.Name == rule.Label {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			continue
+		}
+
+		switch rule.Action {
+		case validation.ResultLabelRuleActionDrop:
+			out = append(out[:idx], out[idx+1:]...)
+		case validation.ResultLabelRuleActionHash:
+			if len(hashKey) == 0 {
+				out = append(out[:idx], out[idx+1:]...)
+				break
+			}
+			mac := hmac.New(sha256.New, hashKey)
+			_, _ = mac.Write([]byte(out[idx].Value))
+			out[idx].Value = hex.EncodeToString(mac.Sum(nil))
+		case validation.ResultLabelRuleActionRename:
+			// The renamed label overrides the target label, if already set.
+			value := out[idx].Value
+			out = append(out[:idx], out[idx+1:]...)
+			for i := 0; i < len(out); i++ {
+				if out[i].Name == rule.TargetLabel {
+					out = append(out[:i], out[i+1:]...)
+					break
+				}
+			}
+			out = append(out, mimirpb.LabelAdapter{Name: rule.TargetLabel, Value: value})
+			sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		}
+	}
+
+	return out
+}
+
+// labelRulesRoundTripper applies the tenants' query result label rules to the responses of the label names, label
+// values, series and remote read requests, so that the labels redacted from the query results can't be read with
+// the other read APIs.
+type labelRulesRoundTripper struct {
+	next    http.RoundTripper
+	limits  Limits
+	hashKey []byte
+}
+
+// newLabelRulesRoundTripper makes a new labelRulesRoundTripper, hashing the label values with the given HMAC key.
+func newLabelRulesRoundTripper(next http.RoundTripper, limits Limits, hashKey []byte) http.RoundTripper {
+	return labelRulesRoundTripper{
+		next:    next,
+		limits:  limits,
+		hashKey: hashKey,
+	}
+}
+
+func (l labelRulesRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	rules := resultLabelRules(tenantIDs, l.limits)
+	if len(rules) == 0 {
+		return l.next.RoundTrip(r)
+	}
+	if isRemoteRead(r.URL.Path) {
+		return l.roundTripRemoteRead(r, rules)
+	}
+
+	// The compressed responses can't be post-processed.
+	r = r.Clone(r.Context())
+	r.Header.Del("Accept-Encoding")
+
+	resp, err := l.next.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		_ = resp.Body.Close()
+		return nil, apierror.New(apierror.TypeInternal, "the compressed response of the labels query can't be post-processed")
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+
+	body, err = l.processLabelsQueryResponse(r.URL.Path, body, rules)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, errors.Wrap(err, "failed to apply the label rules to the response of the labels query").Error())
+	}
+	setResponseBody(resp, body)
+	return resp, nil
+}
+
+// processLabelsQueryResponse returns the response body of the label names, label values or series request with the
+// label rules applied to its data.
+func (l labelRulesRoundTripper) processLabelsQueryResponse(path string, body []byte, rules []validation.ResultLabelRule) ([]byte, error) {
+	var fields map[string]jsoniter.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	if len(fields["data"]) == 0 {
+		return body, nil
+	}
+
+	var data interface{}
+	switch {
+	case strings.HasSuffix(path, seriesPathSuffix):
+		var series []map[string]string
+		if err := json.Unmarshal(fields["data"], &series); err != nil {
+			return nil, err
+		}
+		for i, s := range series {
+			series[i] = mimirpb.FromLabelAdaptersToLabels(applyResultLabelRules(mimirpb.FromLabelsToLabelAdapters(labels.FromMap(s)), rules, l.hashKey)).Map()
+		}
+		data = series
+
+	case strings.HasSuffix(path, labelNamesPathSuffix):
+		var names []string
+		if err := json.Unmarshal(fields["data"], &names); err != nil {
+			return nil, err
+		}
+		// The label names are the ones of the series labels which the rules are applied to.
+		processed := map[string]struct{}{}
+		for _, name := range names {
+			for _, lbl := range applyResultLabelRules([]mimirpb.LabelAdapter{{Name: name}}, rules, l.hashKey) {
+				processed[lbl.Name] = struct{}{}
+			}
+		}
+		data = sortedKeys(processed)
+
+	default:
+		matches := labelValuesPathPattern.FindStringSubmatch(path)
+		if len(matches) != 2 {
+			return body, nil
+		}
+		name, err := url.PathUnescape(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		var values []string
+		if err := json.Unmarshal(fields["data"], &values); err != nil {
+			return nil, err
+		}
+		// The values of the labels dropped or renamed by the rules are not returned.
+		processed := map[string]struct{}{}
+		for _, value := range values {
+			for _, lbl := range applyResultLabelRules([]mimirpb.LabelAdapter{{Name: name, Value: value}}, rules, l.hashKey) {
+				if lbl.Name == name {
+					processed[lbl.Value] = struct{}{}
+				}
+			}
+		}
+		data = sortedKeys(processed)
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	fields["data"] = encoded
+	return json.Marshal(fields)
+}
+
+// roundTripRemoteRead applies the label rules to the series of the remote read response. Only the sampled responses
+// can be post-processed, so the streamed chunks are not accepted from downstream.
+func (l labelRulesRoundTripper) roundTripRemoteRead(r *http.Request, rules []validation.ResultLabelRule) (*http.Response, error) {
+	var req client.ReadRequest
+	if _, err := util.ParseProtoReader(r.Context(), r.Body, int(r.ContentLength), maxRemoteReadRequestSize, nil, &req, util.RawSnappy); err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+	req.AcceptedResponseTypes = []client.ReadRequest_ResponseType{client.SAMPLES}
+
+	reqBody, err := req.Marshal()
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+	reqBody = snappy.Encode(nil, reqBody)
+
+	r = r.Clone(r.Context())
+	r.Body = io.NopCloser(bytes.NewReader(reqBody))
+	r.ContentLength = int64(len(reqBody))
+	r.Header.Set("Content-Length", strconv.Itoa(len(reqBody)))
+
+	resp, err := l.next.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+
+	body, err = l.processRemoteReadResponse(body, rules)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, errors.Wrap(err, "failed to apply the label rules to the remote read response").Error())
+	}
+	setResponseBody(resp, body)
+	return resp, nil
+}
+
+// processRemoteReadResponse returns the snappy compressed remote read response with the label rules applied to its
+// series.
+func (l labelRulesRoundTripper) processRemoteReadResponse(body []byte, rules []validation.ResultLabelRule) ([]byte, error) {
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp client.ReadResponse
+	if err := resp.Unmarshal(decoded); err != nil {
+		return nil, err
+	}
+	for _, result := range resp.Results {
+		for i := range result.Timeseries {
+			result.Timeseries[i].Labels = applyResultLabelRules(result.Timeseries[i].Labels, rules, l.hashKey)
+		}
+	}
+
+	encoded, err := resp.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, encoded), nil
+}
+
+// setResponseBody replaces the body of the response with the given one.
+func setResponseBody(resp *http.Response, body []byte) {
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
+
+// sortedKeys returns the sorted keys of the set.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isRemoteRead returns whether the request path is the one of the remote read API.
+func isRemoteRead(path string) bool {
+	return strings.HasSuffix(path, remoteReadPathSuffix)
+}
@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestResultPostProcessingMiddleware_LabelRules(t *testing.T) {
+	// hmac-sha256("secret", "alice@example.com")
+	const hashedEmail = "a398d49ce1980b3642bc4dbd110121e3c953e1eadb497d50dea23e9611f83ee7"
+
+	tests := map[string]struct {
+		rules     []validation.ResultLabelRule
+		noHashKey bool
+		input     labels.Labels
+		expected  labels.Labels
+	}{
+		"no rules": {
+			input:    labels.FromStrings("__name__", "up", "user_email", "alice@example.com"),
+			expected: labels.FromStrings("__name__", "up", "user_email", "alice@example.com"),
+		},
+		"drop": {
+			rules:    []validation.ResultLabelRule{{Label: "user_email", Action: validation.ResultLabelRuleActionDrop}},
+			input:    labels.FromStrings("__name__", "up", "job", "api", "user_email", "alice@example.com"),
+			expected: labels.FromStrings("__name__", "up", "job", "api"),
+		},
+		"hash": {
+			rules:    []validation.ResultLabelRule{{Label: "user_email", Action: validation.ResultLabelRuleActionHash}},
+			input:    labels.FromStrings("__name__", "up", "user_email", "alice@example.com"),
+			expected: labels.FromStrings("__name__", "up", "user_email", hashedEmail),
+		},
+		"hash without a hash key": {
+			rules:     []validation.ResultLabelRule{{Label: "user_email", Action: validation.ResultLabelRuleActionHash}},
+			noHashKey: true,
+			input:     labels.FromStrings("__name__", "up", "user_email", "alice@example.com"),
+			expected:  labels.FromStrings("__name__", "up"),
+		},
+		"rename": {
+			rules:    []validation.ResultLabelRule{{Label: "customer", Action: validation.ResultLabelRuleActionRename, TargetLabel: "account"}},
+			input:    labels.FromStrings("__name__", "up", "customer", "acme", "job", "api"),
+			expected: labels.FromStrings("__name__", "up", "account", "acme", "job", "api"),
+		},
+		"rename overriding the target label": {
+			rules:    []validation.ResultLabelRule{{Label: "customer", Action: validation.ResultLabelRuleActionRename, TargetLabel: "job"}},
+			input:    labels.FromStrings("__name__", "up", "customer", "acme", "job", "api"),
+			expected: labels.FromStrings("__name__", "up", "job", "acme"),
+		},
+		"rules applied in order": {
+			rules: []validation.ResultLabelRule{
+				{Label: "user_email", Action: validation.ResultLabelRuleActionRename, TargetLabel: "user"},
+				{Label: "user", Action: validation.ResultLabelRuleActionHash},
+			},
+			input:    labels.FromStrings("__name__", "up", "user_email", "alice@example.com"),
+			expected: labels.FromStrings("__name__", "up", "user", hashedEmail),
+		},
+		"label not found": {
+			rules:    []validation.ResultLabelRule{{Label: "user_email", Action: validation.ResultLabelRuleActionDrop}},
+			input:    labels.FromStrings("__name__", "up"),
+			expected: labels.FromStrings("__name__", "up"),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			downstreamResp := &PrometheusResponse{
+				Status: statusSuccess,
+				Data: &PrometheusData{
+					ResultType: "vector",
+					Result: []SampleStream{{
+						Labels:  mimirpb.FromLabelsToLabelAdapters(testData.input),
+						Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}},
+					}},
+				},
+			}
+			downstream := HandlerFunc(func(context.Context, Request) (Response, error) {
+				return downstreamResp, nil
+			})
+
+			hashKey := []byte("secret")
+			if testData.noHashKey {
+				hashKey = nil
+			}
+			limits := mockLimits{resultLabelRules: testData.rules}
+			handler := newResultPostProcessingMiddleware(newLabelRulesPostProcessor(limits, hashKey)).Wrap(downstream)
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			resp, err := handler.Do(ctx, &PrometheusInstantQueryRequest{Query: "up"})
+			require.NoError(t, err)
+
+			result := resp.(*PrometheusResponse).Data.Result
+			require.Len(t, result, 1)
+			assert.Equal(t, testData.expected, mimirpb.FromLabelAdaptersToLabels(result[0].Labels))
+			assert.Equal(t, []mimirpb.Sample{{TimestampMs: 1000, Value: 1}}, result[0].Samples)
+
+			// The downstream response must not be modified.
+			assert.Equal(t, testData.input, mimirpb.FromLabelAdaptersToLabels(downstreamResp.Data.Result[0].Labels))
+		})
+	}
+}
+
+func TestResultPostProcessingMiddleware_ShouldNotPostProcessTheQueriesFederatedByARemoteCluster(t *testing.T) {
+	input := labels.FromStrings("__name__", "up", "user_email", "alice@example.com")
+	downstream := HandlerFunc(func(context.Context, Request) (Response, error) {
+		return &PrometheusResponse{
+			Status: statusSuccess,
+			Data: &PrometheusData{
+				ResultType: "vector",
+				Result:     []SampleStream{{Labels: mimirpb.FromLabelsToLabelAdapters(input), Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}}}},
+			},
+		}, nil
+	})
+
+	limits := mockLimits{resultLabelRules: []validation.ResultLabelRule{{Label: "user_email", Action: validation.ResultLabelRuleActionDrop}}}
+	handler := newResultPostProcessingMiddleware(newLabelRulesPostProcessor(limits, []byte("secret"))).Wrap(downstream)
+
+	// The label rules are applied by the cluster federating the query, once the results are merged.
+	ctx := contextWithFederatedQuery(user.InjectOrgID(context.Background(), "user-1"))
+	resp, err := handler.Do(ctx, &PrometheusInstantQueryRequest{Query: "up"})
+	require.NoError(t, err)
+
+	result := resp.(*PrometheusResponse).Data.Result
+	require.Len(t, result, 1)
+	assert.Equal(t, input, mimirpb.FromLabelAdaptersToLabels(result[0].Labels))
+}
+
+func TestLabelRulesRoundTripper_LabelsQueries(t *testing.T) {
+	// hmac-sha256("secret", "alice@example.com") and hmac-sha256("secret", "bob@example.com")
+	const (
+		hashedAlice = "a398d49ce1980b3642bc4dbd110121e3c953e1eadb497d50dea23e9611f83ee7"
+		hashedBob   = "19d2874a5656a44394f7a94c5fa00a19a04fd9114939a49ed875ff70385f0352"
+	)
+
+	rules := []validation.ResultLabelRule{
+		{Label: "user_email", Action: validation.ResultLabelRuleActionHash},
+		{Label: "secret", Action: validation.ResultLabelRuleActionDrop},
+		{Label: "customer", Action: validation.ResultLabelRuleActionRename, TargetLabel: "account"},
+	}
+
+	tests := map[string]struct {
+		path         string
+		responseBody string
+		expectedBody string
+	}{
+		"series": {
+			path:         "/prometheus/api/v1/series",
+			responseBody: `{"status":"success","data":[{"__name__":"up","customer":"acme","secret":"s3cr3t","user_email":"alice@example.com"}]}`,
+			expectedBody: `{"data":[{"__name__":"up","account":"acme","user_email":"` + hashedAlice + `"}],"status":"success"}`,
+		},
+		"label names": {
+			path:         "/prometheus/api/v1/labels",
+			responseBody: `{"status":"success","data":["__name__","customer","secret","user_email"]}`,
+			expectedBody: `{"data":["__name__","account","user_email"],"status":"success"}`,
+		},
+		"label values of a hashed label": {
+			path:         "/prometheus/api/v1/label/user_email/values",
+			responseBody: `{"status":"success","data":["alice@example.com","bob@example.com"]}`,
+			expectedBody: `{"data":["` + hashedBob + `","` + hashedAlice + `"],"status":"success"}`,
+		},
+		"label values of a dropped label": {
+			path:         "/prometheus/api/v1/label/secret/values",
+			responseBody: `{"status":"success","data":["s3cr3t"]}`,
+			expectedBody: `{"data":[],"status":"success"}`,
+		},
+		"label values of a renamed label": {
+			path:         "/prometheus/api/v1/label/customer/values",
+			responseBody: `{"status":"success","data":["acme"]}`,
+			expectedBody: `{"data":[],"status":"success"}`,
+		},
+		"label values of a label without rules": {
+			path:         "/prometheus/api/v1/label/job/values",
+			responseBody: `{"status":"success","data":["api"],"warnings":["warning"]}`,
+			expectedBody: `{"data":["api"],"status":"success","warnings":["warning"]}`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				assert.Empty(t, r.Header.Get("Accept-Encoding"))
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(testData.responseBody)),
+				}, nil
+			})
+			rt := newLabelRulesRoundTripper(downstream, mockLimits{resultLabelRules: rules}, []byte("secret"))
+
+			req := httptest.NewRequest(http.MethodGet, testData.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+			resp, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, testData.expectedBody, string(body))
+			assert.Equal(t, int64(len(body)), resp.ContentLength)
+		})
+	}
+}
+
+func TestLabelRulesRoundTripper_RemoteRead(t *testing.T) {
+	rules := []validation.ResultLabelRule{{Label: "secret", Action: validation.ResultLabelRuleActionDrop}}
+
+	downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		// The streamed chunks aren't accepted, because they can't be post-processed.
+		var req client.ReadRequest
+		_, err := util.ParseProtoReader(r.Context(), r.Body, int(r.ContentLength), maxRemoteReadRequestSize, nil, &req, util.RawSnappy)
+		require.NoError(t, err)
+		assert.Equal(t, []client.ReadRequest_ResponseType{client.SAMPLES}, req.AcceptedResponseTypes)
+
+		resp := client.ReadResponse{Results: []*client.QueryResponse{{
+			Timeseries: []mimirpb.TimeSeries{{
+				Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("__name__", "up", "secret", "s3cr3t")),
+				Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}},
+			}},
+		}}}
+		data, err := resp.Marshal()
+		require.NoError(t, err)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/x-protobuf"}, "Content-Encoding": []string{"snappy"}},
+			Body:       io.NopCloser(bytes.NewReader(snappy.Encode(nil, data))),
+		}, nil
+	})
+	rt := newLabelRulesRoundTripper(downstream, mockLimits{resultLabelRules: rules}, []byte("secret"))
+
+	reqData, err := (&client.ReadRequest{
+		Queries:               []*client.QueryRequest{{StartTimestampMs: 0, EndTimestampMs: 1000}},
+		AcceptedResponseTypes: []client.ReadRequest_ResponseType{client.STREAMED_XOR_CHUNKS, client.SAMPLES},
+	}).Marshal()
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/prometheus/api/v1/read", bytes.NewReader(snappy.Encode(nil, reqData)))
+	req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var readResp client.ReadResponse
+	_, err = util.ParseProtoReader(context.Background(), resp.Body, int(resp.ContentLength), maxRemoteReadRequestSize, nil, &readResp, util.RawSnappy)
+	require.NoError(t, err)
+	require.Len(t, readResp.Results, 1)
+	require.Len(t, readResp.Results[0].Timeseries, 1)
+	assert.Equal(t, labels.FromStrings("__name__", "up"), mimirpb.FromLabelAdaptersToLabels(readResp.Results[0].Timeseries[0].Labels))
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gogo/protobuf/types"
+	"github.com/grafana/dskit/tenant"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -29,6 +30,7 @@ import (
 	"github.com/grafana/mimir/pkg/cache"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -43,20 +45,23 @@ const (
 )
 
 var (
-	supportedResultsCacheBackends = []string{cache.BackendMemcached}
+	supportedResultsCacheBackends = []string{cache.BackendMemcached, cache.BackendRedis}
 )
 
 // ResultsCacheConfig is the config for the results cache.
 type ResultsCacheConfig struct {
-	cache.BackendConfig `yaml:",inline"`
-	Compression         cache.CompressionConfig `yaml:",inline"`
+	cache.BackendConfig  `yaml:",inline"`
+	Compression          cache.CompressionConfig          `yaml:",inline"`
+	CompressionMigration cache.CompressionMigrationConfig `yaml:"compression_migration"`
 }
 
 // RegisterFlags registers flags.
 func (cfg *ResultsCacheConfig) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.Backend, "query-frontend.results-cache.backend", "", fmt.Sprintf("Backend for query-frontend results cache, if not empty. Supported values: %s.", supportedResultsCacheBackends))
 	cfg.Memcached.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.redis.")
 	cfg.Compression.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.")
+	cfg.CompressionMigration.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.compression-migration.")
 }
 
 func (cfg *ResultsCacheConfig) Validate() error {
@@ -70,10 +75,20 @@ func (cfg *ResultsCacheConfig) Validate() error {
 		}
 	}
 
+	if cfg.Backend == cache.BackendRedis {
+		if err := cfg.Redis.Validate(); err != nil {
+			return errors.Wrap(err, "query-frontend results cache")
+		}
+	}
+
 	if err := cfg.Compression.Validate(); err != nil {
 		return errors.Wrap(err, "query-frontend results cache")
 	}
 
+	if err := cfg.CompressionMigration.Validate(); err != nil {
+		return errors.Wrap(err, "query-frontend results cache")
+	}
+
 	return nil
 }
 
@@ -94,10 +109,30 @@ func newResultsCache(cfg ResultsCacheConfig, logger log.Logger, reg prometheus.R
 		return nil, errUnsupportedResultsCacheBackend(cfg.Backend)
 	}
 
-	return cache.NewVersioned(
-		cache.NewSpanlessTracingCache(client, logger),
-		resultsCacheVersion,
-	), nil
+	tracingClient := cache.NewSpanlessTracingCache(client, logger)
+	migration := cfg.CompressionMigration
+	dualRead := migration.DualReadEnabled && migration.PreviousCompression != cfg.Compression.Compression
+
+	// The results cached before the keys included the compression keep being read, unless they're already read by
+	// the dual read of the uncompressed results, which have the same keys.
+	readUnformatted := !dualRead || migration.PreviousCompression != ""
+	c := newCompressedResultsCache(tracingClient, cfg.Compression, readUnformatted, logger)
+
+	// Read the results cached with the previous compression until they expire, instead of starting from an empty cache.
+	if dualRead {
+		previous := newCompressedResultsCache(tracingClient, cache.CompressionConfig{Compression: migration.PreviousCompression}, false, logger)
+		c = cache.NewDualRead(c, previous, reg)
+	}
+
+	return c, nil
+}
+
+// newCompressedResultsCache returns a cache which compresses the results with the given compression. The cache keys
+// include the compression, so that the results cached with different compressions don't collide. If readUnformatted
+// is true, the results missing in the cache are also read from the keys without the compression, where the results
+// of any compression were cached before the keys included it.
+func newCompressedResultsCache(client cache.Cache, cfg cache.CompressionConfig, readUnformatted bool, logger log.Logger) cache.Cache {
+	return cache.NewCompression(cfg, cache.NewVersionedWithFormat(client, resultsCacheVersion, cfg.Compression, readUnformatted), logger)
 }
 
 // Extractor is used by the cache to extract a subset of a response from a cache entry.
@@ -121,9 +156,10 @@ func (PrometheusResponseExtractor) Extract(start, end int64, from Response) Resp
 		}
 	}
 	return &PrometheusResponse{
-		Status:  promRes.Status,
-		Data:    data,
-		Headers: promRes.Headers,
+		Status:   promRes.Status,
+		Data:     data,
+		Headers:  promRes.Headers,
+		Warnings: promRes.Warnings,
 	}
 }
 
@@ -166,6 +202,25 @@ func (t ConstSplitter) GenerateCacheKey(_ context.Context, userID string, r Requ
 	return fmt.Sprintf("%s:%s:%d:%d:%d", userID, r.GetQuery(), r.GetStep(), startInterval, stepOffset)
 }
 
+// resultsCacheTenantKey returns the tenant part of the cache keys of the query results. The results of the queries
+// reading the downsampled data, because the automatic downsampling is enabled for any of the tenants, are cached apart
+// from the ones reading the raw data, so that changing the limit doesn't return the results cached with the other data.
+func resultsCacheTenantKey(tenantIDs []string, limits Limits) string {
+	key := tenant.JoinTenantIDs(tenantIDs)
+	for _, tenantID := range tenantIDs {
+		if limits.AutoDownsamplingEnabled(tenantID) {
+			return "downsampled:" + key
+		}
+	}
+	return key
+}
+
+// fineGrainedCacheKey generates the cache key of a part of a split query, when the results are cached with the
+// fine-grained interval. The key includes the interval, so that the parts cached with different intervals don't collide.
+func fineGrainedCacheKey(ctx context.Context, userID string, r Request, interval time.Duration) string {
+	return fmt.Sprintf("fine-grained:%d:%s", interval.Milliseconds(), ConstSplitter(interval).GenerateCacheKey(ctx, userID, r))
+}
+
 // shouldCacheFn checks whether the current request should go to cache
 // or not. If not, just send the request to next handler.
 type shouldCacheFn func(r Request) bool
@@ -173,6 +228,19 @@ type shouldCacheFn func(r Request) bool
 // resultsCacheAlwaysEnabled is a shouldCacheFn function always returning true.
 var resultsCacheAlwaysEnabled = func(_ Request) bool { return true }
 
+// maxCacheFreshnessPerTenant returns the period, before now, within which the results of the tenants are not cached: the
+// max cache freshness, extended to the out-of-order time window of the tenants, because the samples ingested
+// out-of-order may still change the results within that window.
+func maxCacheFreshnessPerTenant(tenantIDs []string, limits Limits) time.Duration {
+	freshness := validation.MaxDurationPerTenant(tenantIDs, limits.MaxCacheFreshness)
+	for _, tenantID := range tenantIDs {
+		if window := time.Duration(limits.OutOfOrderTimeWindow(tenantID)); window > freshness {
+			freshness = window
+		}
+	}
+	return freshness
+}
+
 // isRequestCachable says whether the request is eligible for caching.
 func isRequestCachable(req Request, maxCacheTime int64, cacheUnalignedRequests bool, logger log.Logger) bool {
 	// We can run with step alignment disabled because Grafana does it already. Mimir automatically aligning start and end is not
@@ -203,6 +271,12 @@ func isResponseCachable(r Response, logger log.Logger) bool {
 		}
 	}
 
+	// The responses with warnings may be incomplete, for example if the blocks storage was unavailable.
+	if promRes, ok := r.(*PrometheusResponse); ok && len(promRes.Warnings) > 0 {
+		level.Debug(logger).Log("msg", "response has warnings, not caching the response")
+		return false
+	}
+
 	return true
 }
 
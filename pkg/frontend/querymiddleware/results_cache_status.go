@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/grafana/dskit/tenant"
+)
+
+const (
+	// resultsCacheStatusHeader is the name of the response header telling whether the results of the read
+	// request have been served by the results cache.
+	resultsCacheStatusHeader = "Results-Cache-Status"
+
+	resultsCacheStatusHit     = "hit"
+	resultsCacheStatusMiss    = "miss"
+	resultsCacheStatusPartial = "partial"
+	resultsCacheStatusBypass  = "bypass"
+
+	// noCacheValue is the value of the cacheControlHeader of the requests bypassing the results cache.
+	noCacheValue = "no-cache"
+)
+
+// resultsCacheStatus tracks the results cache lookups of a read request. The results of a request can be looked
+// up in several parts, for example once split by interval, so it's safe for concurrent use.
+type resultsCacheStatus struct {
+	bypassed bool
+
+	mtx    sync.Mutex
+	hits   int
+	misses int
+}
+
+// recordHit records a results cache lookup which found the results. It's a noop on a nil resultsCacheStatus.
+func (s *resultsCacheStatus) recordHit() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	s.hits++
+	s.mtx.Unlock()
+}
+
+// recordMiss records a results cache lookup which didn't find the results, or found only part of them.
+// It's a noop on a nil resultsCacheStatus.
+func (s *resultsCacheStatus) recordMiss() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	s.misses++
+	s.mtx.Unlock()
+}
+
+// String returns the value of the resultsCacheStatusHeader, or an empty string if the results cache
+// hasn't been looked up. The lookups skipped because the request bypasses the results cache are
+// recorded as misses.
+func (s *resultsCacheStatus) String() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch {
+	case s.hits == 0 && s.misses == 0:
+		return ""
+	case s.bypassed:
+		return resultsCacheStatusBypass
+	case s.misses == 0:
+		return resultsCacheStatusHit
+	case s.hits == 0:
+		return resultsCacheStatusMiss
+	default:
+		return resultsCacheStatusPartial
+	}
+}
+
+type resultsCacheStatusContextKey struct{}
+
+// contextWithResultsCacheStatus returns a context carrying the given resultsCacheStatus.
+func contextWithResultsCacheStatus(ctx context.Context, status *resultsCacheStatus) context.Context {
+	return context.WithValue(ctx, resultsCacheStatusContextKey{}, status)
+}
+
+// resultsCacheStatusFromContext returns the resultsCacheStatus of the context, or nil if there's none.
+func resultsCacheStatusFromContext(ctx context.Context) *resultsCacheStatus {
+	status, _ := ctx.Value(resultsCacheStatusContextKey{}).(*resultsCacheStatus)
+	return status
+}
+
+// isResultsCacheBypassed returns whether the request of the context bypasses the results cache: the cached results
+// must not be looked up, but the results queried downstream are still cached.
+func isResultsCacheBypassed(ctx context.Context) bool {
+	status := resultsCacheStatusFromContext(ctx)
+	return status != nil && status.bypassed
+}
+
+// newResultsCacheStatusRoundTripper returns a http.RoundTripper tracking the results cache lookups of the read
+// requests, and returning their status in the resultsCacheStatusHeader of the response. The requests with the
+// Cache-Control: no-cache header bypass the results cache, if all their tenants are allowed to.
+func newResultsCacheStatusRoundTripper(next http.RoundTripper, limits Limits) http.RoundTripper {
+	return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		tenantIDs, err := tenant.TenantIDs(r.Context())
+		if err != nil {
+			return next.RoundTrip(r)
+		}
+
+		status := &resultsCacheStatus{bypassed: isResultsCacheBypassRequested(r)}
+		for _, tenantID := range tenantIDs {
+			if !limits.ResultsCacheBypassEnabled(tenantID) {
+				status.bypassed = false
+				break
+			}
+		}
+
+		resp, err := next.RoundTrip(r.WithContext(contextWithResultsCacheStatus(r.Context(), status)))
+		if err != nil || resp == nil {
+			return resp, err
+		}
+
+		if value := status.String(); value != "" {
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			resp.Header.Set(resultsCacheStatusHeader, value)
+		}
+		return resp, nil
+	})
+}
+
+// isResultsCacheBypassRequested returns whether the request asks to bypass the results cache with the
+// Cache-Control header.
+func isResultsCacheBypassRequested(r *http.Request) bool {
+	for _, value := range r.Header.Values(cacheControlHeader) {
+		if strings.Contains(value, noCacheValue) {
+			return true
+		}
+	}
+	return false
+}
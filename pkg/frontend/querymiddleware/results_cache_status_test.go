@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestResultsCacheStatus_String(t *testing.T) {
+	tests := map[string]struct {
+		bypassed bool
+		hits     int
+		misses   int
+		expected string
+	}{
+		"no lookups":          {expected: ""},
+		"hits":                {hits: 2, expected: resultsCacheStatusHit},
+		"misses":              {misses: 2, expected: resultsCacheStatusMiss},
+		"hits and misses":     {hits: 1, misses: 1, expected: resultsCacheStatusPartial},
+		"bypassed":            {bypassed: true, misses: 1, expected: resultsCacheStatusBypass},
+		"bypassed no lookups": {bypassed: true, expected: ""},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			status := &resultsCacheStatus{bypassed: testData.bypassed}
+			for i := 0; i < testData.hits; i++ {
+				status.recordHit()
+			}
+			for i := 0; i < testData.misses; i++ {
+				status.recordMiss()
+			}
+			assert.Equal(t, testData.expected, status.String())
+		})
+	}
+}
+
+func TestResultsCacheStatusRoundTripper(t *testing.T) {
+	tests := map[string]struct {
+		cacheControl     string
+		bypassEnabled    bool
+		hit              bool
+		expectedBypassed bool
+		expectedHeader   string
+	}{
+		"hit": {
+			hit:            true,
+			expectedHeader: resultsCacheStatusHit,
+		},
+		"miss": {
+			expectedHeader: resultsCacheStatusMiss,
+		},
+		"bypass requested but not allowed": {
+			cacheControl:   "no-cache",
+			hit:            true,
+			expectedHeader: resultsCacheStatusHit,
+		},
+		"bypass requested and allowed": {
+			cacheControl:     "max-age=0, no-cache",
+			bypassEnabled:    true,
+			expectedBypassed: true,
+			expectedHeader:   resultsCacheStatusBypass,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			var bypassed bool
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				bypassed = isResultsCacheBypassed(r.Context())
+				if testData.hit && !bypassed {
+					resultsCacheStatusFromContext(r.Context()).recordHit()
+				} else {
+					resultsCacheStatusFromContext(r.Context()).recordMiss()
+				}
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			rt := newResultsCacheStatusRoundTripper(downstream, mockLimits{resultsCacheBypass: testData.bypassEnabled})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			if testData.cacheControl != "" {
+				req.Header.Set(cacheControlHeader, testData.cacheControl)
+			}
+
+			resp, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedBypassed, bypassed)
+			assert.Equal(t, testData.expectedHeader, resp.Header.Get(resultsCacheStatusHeader))
+		})
+	}
+}
+
+func TestSplitAndCacheMiddleware_ResultsCache_ShouldBypassTheCache(t *testing.T) {
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		false,
+		0,
+		mockLimits{maxCacheFreshness: 10 * time.Minute},
+		PrometheusCodec,
+		cache.NewMockCache(),
+		ConstSplitter(day),
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		log.NewNopLogger(),
+		prometheus.NewPedanticRegistry(),
+	)
+
+	responseWithValue := func(value float64) *PrometheusResponse {
+		return &PrometheusResponse{
+			Status: statusSuccess,
+			Data: &PrometheusData{
+				ResultType: model.ValMatrix.String(),
+				Result: []SampleStream{{
+					Labels:  []mimirpb.LabelAdapter{{Name: "foo", Value: "bar"}},
+					Samples: []mimirpb.Sample{{Value: value, TimestampMs: 1634292000000}},
+				}},
+			},
+		}
+	}
+
+	downstreamReqs := 0
+	rc := mw.Wrap(HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+		downstreamReqs++
+		return responseWithValue(float64(downstreamReqs)), nil
+	}))
+
+	req := Request(&PrometheusRangeQueryRequest{
+		Path:  "/api/v1/query_range",
+		Start: parseTimeRFC3339(t, "2021-10-15T10:00:00Z").Unix() * 1000,
+		End:   parseTimeRFC3339(t, "2021-10-15T12:00:00Z").Unix() * 1000,
+		Step:  120 * 1000,
+		Query: `{__name__=~".+"}`,
+	})
+
+	do := func(bypassed bool) (Response, string) {
+		status := &resultsCacheStatus{bypassed: bypassed}
+		ctx := contextWithResultsCacheStatus(user.InjectOrgID(context.Background(), "1"), status)
+		resp, err := rc.Do(ctx, req)
+		require.NoError(t, err)
+		return resp, status.String()
+	}
+
+	resp, status := do(false)
+	assert.Equal(t, responseWithValue(1), resp)
+	assert.Equal(t, resultsCacheStatusMiss, status)
+
+	resp, status = do(false)
+	assert.Equal(t, responseWithValue(1), resp)
+	assert.Equal(t, resultsCacheStatusHit, status)
+
+	// The request bypassing the cache is run downstream, and refreshes the cached results.
+	resp, status = do(true)
+	assert.Equal(t, responseWithValue(2), resp)
+	assert.Equal(t, resultsCacheStatusBypass, status)
+	assert.Equal(t, 2, downstreamReqs)
+
+	resp, status = do(false)
+	assert.Equal(t, responseWithValue(2), resp)
+	assert.Equal(t, resultsCacheStatusHit, status)
+	assert.Equal(t, 2, downstreamReqs)
+}
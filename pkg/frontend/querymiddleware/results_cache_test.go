@@ -120,6 +120,23 @@ func mkExtentWithStep(start, end, step int64) Extent {
 	}
 }
 
+func TestNewCompressedResultsCache_ShouldReadTheResultsCachedBeforeTheKeysIncludedTheCompression(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewMockCache()
+
+	// The results were cached with the snappy compression before the keys included the compression.
+	legacy := cache.NewSnappy(cache.NewVersioned(backend, resultsCacheVersion), log.NewNopLogger())
+	legacy.Store(ctx, map[string][]byte{"legacy": []byte("legacy")}, time.Minute)
+
+	c := newCompressedResultsCache(backend, cache.CompressionConfig{Compression: cache.CompressionSnappy}, true, log.NewNopLogger())
+	c.Store(ctx, map[string][]byte{"current": []byte("current")}, time.Minute)
+	assert.Equal(t, map[string][]byte{"legacy": []byte("legacy"), "current": []byte("current")}, c.Fetch(ctx, []string{"legacy", "current", "miss"}))
+
+	// The legacy keys aren't read if disabled.
+	c = newCompressedResultsCache(backend, cache.CompressionConfig{Compression: cache.CompressionSnappy}, false, log.NewNopLogger())
+	assert.Equal(t, map[string][]byte{"current": []byte("current")}, c.Fetch(ctx, []string{"legacy", "current", "miss"}))
+}
+
 func TestIsRequestCachable(t *testing.T) {
 	maxCacheTime := int64(150 * 1000)
 
@@ -334,6 +351,13 @@ func TestIsResponseCachable(t *testing.T) {
 			}),
 			expected: true,
 		},
+		{
+			name: "response with warnings",
+			response: Response(&PrometheusResponse{
+				Warnings: []string{"the results may be incomplete"},
+			}),
+			expected: false,
+		},
 	} {
 		{
 			t.Run(tc.name, func(t *testing.T) {
@@ -557,6 +581,15 @@ func TestConstSplitter_generateCacheKey(t *testing.T) {
 	}
 }
 
+func TestResultsCacheTenantKey(t *testing.T) {
+	assert.Equal(t, "user-1", resultsCacheTenantKey([]string{"user-1"}, mockLimits{}))
+	assert.Equal(t, "user-1|user-2", resultsCacheTenantKey([]string{"user-1", "user-2"}, mockLimits{}))
+
+	// The results of the queries reading the downsampled data are cached apart.
+	assert.Equal(t, "downsampled:user-1", resultsCacheTenantKey([]string{"user-1"}, mockLimits{autoDownsampling: true}))
+	assert.Equal(t, "downsampled:user-1|user-2", resultsCacheTenantKey([]string{"user-1", "user-2"}, mockLimits{autoDownsampling: true}))
+}
+
 func toMs(t time.Duration) int64 {
 	return int64(t / time.Millisecond)
 }
@@ -14,13 +14,17 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/tenant"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/promql"
 
+	apierror "github.com/grafana/mimir/pkg/api/error"
 	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/querier/downsampling"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -40,9 +44,35 @@ type Config struct {
 	ShardedQueries         bool `yaml:"parallelize_shardable_queries"`
 	CacheUnalignedRequests bool `yaml:"cache_unaligned_requests" category:"advanced"`
 
+	ResultsCacheFineGrainedInterval time.Duration `yaml:"results_cache_fine_grained_interval" category:"experimental"`
+
+	HeavyQueriesMaxTrackedQueries int           `yaml:"heavy_queries_max_tracked_queries" category:"experimental"`
+	HeavyQueriesWindow            time.Duration `yaml:"heavy_queries_window" category:"experimental"`
+
+	ResultLabelRulesHashKey flagext.Secret `yaml:"result_label_rules_hash_key" category:"experimental"`
+
+	QueryExplainBlocksEnabled bool `yaml:"query_explain_blocks_enabled" category:"experimental"`
+
 	// CacheSplitter allows to inject a CacheSplitter to use for generating cache keys.
 	// If nil, the querymiddleware package uses a ConstSplitter with SplitQueriesByInterval.
 	CacheSplitter CacheSplitter `yaml:"-"`
+
+	// QueryIngestersWithin and QueryStoreAfter allow to inject the querier configuration, used by the query
+	// explain endpoint to explain which of the ingesters and the store-gateways the data is read from.
+	QueryIngestersWithin time.Duration `yaml:"-"`
+	QueryStoreAfter      time.Duration `yaml:"-"`
+
+	// BlocksFinder allows to inject the finder of the blocks in the long-term storage, used by the query explain
+	// endpoint to explain which blocks the store-gateways are queried for. If nil, the blocks aren't explained.
+	BlocksFinder BlocksFinder `yaml:"-"`
+
+	// PreSplitMiddlewares and PostCacheMiddlewares allow to inject custom middlewares in the range and instant
+	// query pipelines. The pre-split middlewares run once on each query, once the limits have been applied, and
+	// before its results are looked up in the caches, so also on the queries served by the caches. They don't
+	// run again on the subqueries spun off the instant queries. The post-cache middlewares run on each split
+	// query which isn't served by the results cache, right before it's sharded.
+	PreSplitMiddlewares  []Middleware `yaml:"-"`
+	PostCacheMiddlewares []Middleware `yaml:"-"`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet.
@@ -55,6 +85,11 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&cfg.CacheResults, "query-frontend.cache-results", false, "Cache query results.")
 	f.BoolVar(&cfg.ShardedQueries, "query-frontend.parallelize-shardable-queries", false, "True to enable query sharding.")
 	f.BoolVar(&cfg.CacheUnalignedRequests, "query-frontend.cache-unaligned-requests", false, "Cache requests that are not step-aligned.")
+	f.DurationVar(&cfg.ResultsCacheFineGrainedInterval, "query-frontend.results-cache-fine-grained-interval", 0, "Cache the results of each split query in parts of this interval, aligned to the query step, so that the queries with partially overlapping time ranges reuse the cached parts. The contiguous parts which are not cached are run downstream as a single query. It must evenly divide -query-frontend.split-queries-by-interval. 0 to disable it.")
+	f.IntVar(&cfg.HeavyQueriesMaxTrackedQueries, "query-frontend.heavy-queries-max-tracked-queries", 0, "Maximum number of distinct queries tracked per tenant to list the heaviest queries of the tenant, by querier wall time and samples fetched, with the <prometheus-http-prefix>/api/v1/heavy_queries endpoint. Once reached, the tracked query with the lowest querier wall time is evicted. It requires -query-frontend.query-stats-enabled. 0 to disable it.")
+	f.DurationVar(&cfg.HeavyQueriesWindow, "query-frontend.heavy-queries-window", time.Hour, "Time window the heaviest queries are tracked over. The listed statistics span the current and the previous window.")
+	f.BoolVar(&cfg.QueryExplainBlocksEnabled, "query-frontend.query-explain-blocks-enabled", false, "True to explain which blocks the store-gateways are queried for in the responses of the query explain endpoint. The blocks are read from the bucket index of the tenants, so it requires the blocks storage and the bucket index to be configured in the query-frontend.")
+	f.Var(&cfg.ResultLabelRulesHashKey, "query-frontend.result-label-rules-hash-key", "Key of the HMAC-SHA256 which replaces the values of the labels with the hash action of the query result label rules. If empty, these labels are dropped instead.")
 	cfg.ResultsCacheConfig.RegisterFlags(f)
 }
 
@@ -67,6 +102,12 @@ func (cfg *Config) Validate() error {
 		if err := cfg.ResultsCacheConfig.Validate(); err != nil {
 			return errors.Wrap(err, "invalid ResultsCache config")
 		}
+		if cfg.ResultsCacheFineGrainedInterval < 0 || (cfg.ResultsCacheFineGrainedInterval > 0 && cfg.SplitQueriesByInterval%cfg.ResultsCacheFineGrainedInterval != 0) {
+			return errors.New("-query-frontend.results-cache-fine-grained-interval must evenly divide -query-frontend.split-queries-by-interval")
+		}
+	}
+	if cfg.HeavyQueriesMaxTrackedQueries > 0 && cfg.HeavyQueriesWindow <= 0 {
+		return errors.New("-query-frontend.heavy-queries-window must be greater than 0 when the heaviest queries are tracked")
 	}
 	return nil
 }
@@ -146,6 +187,9 @@ func NewTripperware(
 	}
 	return MergeTripperwares(
 		newActiveUsersTripperware(log, registerer),
+		newLoadSheddingTripperware(limits, log, registerer),
+		newQuerySourceLimitsTripperware(limits, log, registerer),
+		newReadBandwidthQuotaTripperware(limits, log, registerer),
 		queryRangeTripperware,
 	), err
 }
@@ -159,6 +203,32 @@ func newQueryTripperware(
 	engineOpts promql.EngineOpts,
 	registerer prometheus.Registerer,
 ) (Tripperware, error) {
+	// Init the results cache client.
+	var c cache.Cache
+	if cfg.CacheResults {
+		var err error
+
+		c, err = newResultsCache(cfg.ResultsCacheConfig, log, registerer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newQueryTripperwareWithCache(cfg, log, limits, codec, cacheExtractor, engineOpts, c, registerer), nil
+}
+
+// newQueryTripperwareWithCache returns the query tripperware using the given results cache, which is nil
+// when the results aren't cached.
+func newQueryTripperwareWithCache(
+	cfg Config,
+	log log.Logger,
+	limits Limits,
+	codec Codec,
+	cacheExtractor Extractor,
+	engineOpts promql.EngineOpts,
+	c cache.Cache,
+	registerer prometheus.Registerer,
+) Tripperware {
 	// Disable concurrency limits for sharded queries.
 	engineOpts.ActiveQueryTracker = nil
 	engine := promql.NewEngine(engineOpts)
@@ -166,44 +236,88 @@ func newQueryTripperware(
 	// Metric used to keep track of each middleware execution duration.
 	metrics := newInstrumentMiddlewareMetrics(registerer)
 
+	// The range and instant queries share the history of the series fetched by the queries.
+	queryCostHistory := newQueryCostHistory(queryCostHistorySize)
+	queryCostEstimation := newQueryCostEstimationMiddleware(limits, queryCostHistory, log, newQueryCostEstimationMiddlewareMetrics(registerer))
+	remoteQueryFederation := newRemoteQueryFederationMiddleware(limits, codec, newRemoteQueryFederationClient(), log, newRemoteQueryFederationMiddlewareMetrics(registerer))
+
 	queryRangeMiddleware := []Middleware{
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
 		newQueryStatsMiddleware(registerer),
 		newLimitsMiddleware(limits, log),
+		// Reject the queries using experimental PromQL functions which aren't enabled for the tenants, before the
+		// queries are split, sharded or have their subqueries spun off.
+		newExperimentalFunctionsMiddleware(limits, log),
+		// Post-process the final results once, after they've been merged across the remote clusters and cached.
+		newResultPostProcessingMiddleware(newLabelRulesPostProcessor(limits, []byte(cfg.ResultLabelRulesHashKey.String()))),
+		// Federate the queries across the remote clusters, which run their own middlewares on their part of the query,
+		// except the federation and the post-processing.
+		remoteQueryFederation,
+		// Reject the queries whose estimated cost exceeds the limit, once their time range has been clamped.
+		queryCostEstimation,
+	}
+	queryInstantMiddleware := []Middleware{
+		newLimitsMiddleware(limits, log),
+		newExperimentalFunctionsMiddleware(limits, log),
+		newResultPostProcessingMiddleware(newLabelRulesPostProcessor(limits, []byte(cfg.ResultLabelRulesHashKey.String()))),
+		remoteQueryFederation,
+		queryCostEstimation,
 	}
+
+	// Track the heaviest queries, once the query statistics of the whole query are known.
+	var heavyQueries http.RoundTripper
+	if cfg.HeavyQueriesMaxTrackedQueries > 0 {
+		tracker := newHeavyQueriesTracker(cfg.HeavyQueriesMaxTrackedQueries, cfg.HeavyQueriesWindow, time.Now)
+		heavyQueries = newHeavyQueriesRoundTripper(tracker)
+		queryRangeMiddleware = append(queryRangeMiddleware, newHeavyQueriesMiddleware(tracker, queryExplainTypeRange))
+		queryInstantMiddleware = append(queryInstantMiddleware, newHeavyQueriesMiddleware(tracker, queryExplainTypeInstant))
+	}
+	// The custom pre-split middlewares run before the caches, so that they run on the queries served by the caches too.
+	if len(cfg.PreSplitMiddlewares) > 0 {
+		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("pre_split_custom", metrics, log), MergeMiddlewares(cfg.PreSplitMiddlewares...))
+		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("pre_split_custom", metrics, log), MergeMiddlewares(cfg.PreSplitMiddlewares...))
+	}
+	// The subqueries spun off the instant queries are range queries run through the range query middlewares
+	// from this index on: the limits, the post-processing, the federation and the custom pre-split middlewares
+	// have already been applied to the instant queries.
+	spunOffSubqueriesMiddlewareIdx := len(queryRangeMiddleware)
+
+	// Inject the middleware caching the results of the whole instant queries, and the empty results and errors of the whole queries.
+	var invalidateInstantQueryResultsCache http.RoundTripper
+	var labelsQueryCacheMetrics *labelsQueryCacheMetrics
+	if cfg.CacheResults {
+		labelsQueryCacheMetrics = newLabelsQueryCacheMetrics(registerer)
+
+		instantQueryResultsCacheMetrics := newInstantQueryResultsCacheMiddlewareMetrics(registerer)
+		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("instant_query_results_cache", metrics, log), newInstantQueryResultsCacheMiddleware(limits, c, log, instantQueryResultsCacheMetrics))
+		invalidateInstantQueryResultsCache = newInstantQueryResultsCacheInvalidationRoundTripper(limits, c, log, instantQueryResultsCacheMetrics)
+
+		negativeResultsCacheMetrics := newNegativeResultsCacheMiddlewareMetrics(registerer)
+		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("negative_results_cache", metrics, log), newNegativeResultsCacheMiddleware(limits, c, cfg.SplitQueriesByInterval, log, negativeResultsCacheMetrics))
+		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("negative_results_cache", metrics, log), newNegativeResultsCacheMiddleware(limits, c, cfg.SplitQueriesByInterval, log, negativeResultsCacheMetrics))
+	}
+
 	if cfg.AlignQueriesWithStep {
 		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("step_align", metrics, log), newStepAlignMiddleware())
 	}
 
+	splitter := cfg.CacheSplitter
+	if splitter == nil {
+		splitter = ConstSplitter(cfg.SplitQueriesByInterval)
+	}
+
 	// Inject the middleware to split requests by interval + results cache (if at least one of the two is enabled).
 	if cfg.SplitQueriesByInterval > 0 || cfg.CacheResults {
-		var c cache.Cache
-
-		// Init the cache client.
-		if cfg.CacheResults {
-			var err error
-
-			c, err = newResultsCache(cfg.ResultsCacheConfig, log, registerer)
-			if err != nil {
-				return nil, err
-			}
-			c = cache.NewCompression(cfg.ResultsCacheConfig.Compression, c, log)
-		}
-
 		shouldCache := func(r Request) bool {
 			return !r.GetOptions().CacheDisabled
 		}
 
-		splitter := cfg.CacheSplitter
-		if splitter == nil {
-			splitter = ConstSplitter(cfg.SplitQueriesByInterval)
-		}
-
 		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("split_by_interval_and_results_cache", metrics, log), newSplitAndCacheMiddleware(
 			cfg.SplitQueriesByInterval > 0,
 			cfg.CacheResults,
 			cfg.SplitQueriesByInterval,
 			cfg.CacheUnalignedRequests,
+			cfg.ResultsCacheFineGrainedInterval,
 			limits,
 			codec,
 			c,
@@ -215,12 +329,21 @@ func newQueryTripperware(
 		))
 	}
 
-	queryInstantMiddleware := []Middleware{newLimitsMiddleware(limits, log)}
+	if len(cfg.PostCacheMiddlewares) > 0 {
+		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("post_cache_custom", metrics, log), MergeMiddlewares(cfg.PostCacheMiddlewares...))
+	}
+
+	// The middleware spinning off the subqueries is injected here, once the range query middlewares are built.
+	spinOffSubqueriesMiddlewareIdx := len(queryInstantMiddleware)
+	spinOffSubqueriesMetrics := newSpinOffSubqueriesMetrics(registerer)
 
 	queryInstantMiddleware = append(
 		queryInstantMiddleware,
-		newSplitInstantQueryByIntervalMiddleware(limits, log, engine, registerer),
+		newSplitInstantQueryByIntervalMiddleware(limits, log, engine, c, registerer),
 	)
+	if len(cfg.PostCacheMiddlewares) > 0 {
+		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("post_cache_custom", metrics, log), MergeMiddlewares(cfg.PostCacheMiddlewares...))
+	}
 
 	if cfg.ShardedQueries {
 		queryshardingMiddleware := newQueryShardingMiddleware(
@@ -247,23 +370,118 @@ func newQueryTripperware(
 		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("retry", metrics, log), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
 	}
 
+	explain := newQueryExplainRoundTripper(cfg, limits, codec, c, splitter, cacheExtractor, queryCostHistory, engineOpts.LookbackDelta, log)
+
 	return func(next http.RoundTripper) http.RoundTripper {
 		queryrange := newLimitedParallelismRoundTripper(next, codec, limits, queryRangeMiddleware...)
+
+		spunOffSubqueries := roundTripperHandler{
+			next:   newLimitedParallelismRoundTripper(next, codec, limits, queryRangeMiddleware[spunOffSubqueriesMiddlewareIdx:]...),
+			codec:  codec,
+			logger: log,
+		}
+		instantMiddleware := make([]Middleware, 0, len(queryInstantMiddleware)+2)
+		instantMiddleware = append(instantMiddleware, queryInstantMiddleware[:spinOffSubqueriesMiddlewareIdx]...)
+		instantMiddleware = append(instantMiddleware, newInstrumentMiddleware("spin_off_subqueries", metrics, log), newSpinOffSubqueriesMiddleware(limits, log, engine, spunOffSubqueries, spinOffSubqueriesMetrics))
+		instantMiddleware = append(instantMiddleware, queryInstantMiddleware[spinOffSubqueriesMiddlewareIdx:]...)
+
 		instant := defaultInstantQueryParamsRoundTripper(
-			newLimitedParallelismRoundTripper(next, codec, limits, queryInstantMiddleware...),
+			newLimitedParallelismRoundTripper(next, codec, limits, instantMiddleware...),
 			time.Now,
 		)
+
+		format := newFormatQueryRoundTripper(next)
+
+		labels := next
+		if cfg.CacheResults {
+			labels = newLabelsQueryCacheRoundTripper(next, limits, c, log, labelsQueryCacheMetrics)
+
+			// Track the results cache lookups of the queries, and let them bypass the results cache.
+			queryrange = newResultsCacheStatusRoundTripper(queryrange, limits)
+			instant = newResultsCacheStatusRoundTripper(instant, limits)
+			labels = newResultsCacheStatusRoundTripper(labels, limits)
+		}
+
+		// The label rules are applied to the cached responses of the labels queries too, so that the changes of the
+		// rules apply immediately.
+		labels = newLabelRulesRoundTripper(labels, limits, []byte(cfg.ResultLabelRulesHashKey.String()))
+		remoteRead := newLabelRulesRoundTripper(next, limits, []byte(cfg.ResultLabelRulesHashKey.String()))
+
 		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			// The queries are split and sharded into new requests, which must keep the priority and the source
+			// of the original one.
+			if priority := r.Header.Get(queue.PriorityHeader); priority != "" {
+				r = r.WithContext(contextWithQueryPriority(r.Context(), priority))
+			}
+			if isRuleEvaluation(r) {
+				r = r.WithContext(contextWithRuleEvaluation(r.Context()))
+			}
+			if heavyQueries != nil {
+				r = r.WithContext(contextWithQueryDashboard(r.Context(), r))
+			}
+			if r.Header.Get(federatedQueryHeader) != "" {
+				r = r.WithContext(contextWithFederatedQuery(r.Context()))
+			}
+			// The queries are split and sharded into new requests, which must keep the max source resolution of the
+			// original one too.
+			if isRangeQuery(r.URL.Path) || isInstantQuery(r.URL.Path) {
+				if resolution := r.FormValue(downsampling.MaxSourceResolutionParam); resolution != "" {
+					if _, err := downsampling.ParseMaxSourceResolution(resolution); err != nil {
+						return nil, apierror.New(apierror.TypeBadData, err.Error())
+					}
+					r = r.WithContext(contextWithMaxSourceResolution(r.Context(), resolution))
+				}
+			}
+
 			switch {
 			case isRangeQuery(r.URL.Path):
 				return queryrange.RoundTrip(r)
 			case isInstantQuery(r.URL.Path):
 				return instant.RoundTrip(r)
+			case isQueryExplain(r.URL.Path):
+				return explain.RoundTrip(r)
+			case isFormatQuery(r.URL.Path):
+				return format.RoundTrip(r)
+			case isInstantQueryResultsCacheInvalidation(r.URL.Path) && invalidateInstantQueryResultsCache != nil:
+				return invalidateInstantQueryResultsCache.RoundTrip(r)
+			case isLabelsQuery(r.URL.Path):
+				return labels.RoundTrip(r)
+			case isRemoteRead(r.URL.Path):
+				return remoteRead.RoundTrip(r)
+			case isHeavyQueries(r.URL.Path) && heavyQueries != nil:
+				return heavyQueries.RoundTrip(r)
 			default:
 				return next.RoundTrip(r)
 			}
 		})
-	}, nil
+	}
+}
+
+type queryPriorityContextKey struct{}
+
+// contextWithQueryPriority returns a context carrying the value of the X-Query-Priority header of the query request.
+func contextWithQueryPriority(ctx context.Context, priority string) context.Context {
+	return context.WithValue(ctx, queryPriorityContextKey{}, priority)
+}
+
+// queryPriorityFromContext returns the value of the X-Query-Priority header of the query request, if any.
+func queryPriorityFromContext(ctx context.Context) string {
+	priority, _ := ctx.Value(queryPriorityContextKey{}).(string)
+	return priority
+}
+
+type maxSourceResolutionContextKey struct{}
+
+// contextWithMaxSourceResolution returns a context carrying the value of the max_source_resolution parameter of the
+// query request.
+func contextWithMaxSourceResolution(ctx context.Context, resolution string) context.Context {
+	return context.WithValue(ctx, maxSourceResolutionContextKey{}, resolution)
+}
+
+// maxSourceResolutionFromContext returns the value of the max_source_resolution parameter of the query request, if any.
+func maxSourceResolutionFromContext(ctx context.Context) string {
+	resolution, _ := ctx.Value(maxSourceResolutionContextKey{}).(string)
+	return resolution
 }
 
 func newActiveUsersTripperware(logger log.Logger, registerer prometheus.Registerer) Tripperware {
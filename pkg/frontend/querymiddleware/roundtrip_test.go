@@ -14,6 +14,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,7 +30,10 @@ import (
 	"github.com/weaveworks/common/middleware"
 	"github.com/weaveworks/common/user"
 
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 )
 
 func TestRangeTripperware(t *testing.T) {
@@ -176,6 +180,280 @@ func TestInstantTripperware(t *testing.T) {
 	})
 }
 
+func TestTripperware_ShouldForwardTheMaxSourceResolution(t *testing.T) {
+	tw, err := NewTripperware(
+		Config{},
+		log.NewNopLogger(),
+		mockLimits{},
+		PrometheusCodec,
+		nil,
+		promql.EngineOpts{
+			Logger:     log.NewNopLogger(),
+			Reg:        nil,
+			MaxSamples: 1000,
+			Timeout:    time.Minute,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	var downstreamReqs []*http.Request
+	rt := tw(RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		downstreamReqs = append(downstreamReqs, r)
+		return PrometheusCodec.EncodeResponse(r.Context(), &PrometheusResponse{
+			Status: "success",
+			Data:   &PrometheusData{ResultType: "vector", Result: []SampleStream{}},
+		})
+	}))
+
+	t.Run("should forward the max source resolution to the downstream requests", func(t *testing.T) {
+		downstreamReqs = nil
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up&time=1000&max_source_resolution=1h", nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, downstreamReqs, 1)
+		assert.Equal(t, "1h", downstreamReqs[0].URL.Query().Get("max_source_resolution"))
+		assert.Contains(t, downstreamReqs[0].RequestURI, "max_source_resolution=1h")
+	})
+
+	t.Run("should fail on an invalid max source resolution", func(t *testing.T) {
+		downstreamReqs = nil
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up&time=1000&max_source_resolution=foo", nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+		_, err := rt.RoundTrip(req)
+		require.Error(t, err)
+		assert.True(t, apierror.IsType(err, apierror.TypeBadData))
+		assert.Empty(t, downstreamReqs)
+	})
+}
+
+func TestTripperware_ShouldForwardTheQuerySource(t *testing.T) {
+	tw, err := NewTripperware(
+		Config{SplitQueriesByInterval: 24 * time.Hour},
+		log.NewNopLogger(),
+		mockLimits{},
+		PrometheusCodec,
+		nil,
+		promql.EngineOpts{
+			Logger:     log.NewNopLogger(),
+			Reg:        nil,
+			MaxSamples: 1000,
+			Timeout:    time.Minute,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	var (
+		downstreamMx      sync.Mutex
+		downstreamSources []string
+	)
+	rt := tw(RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		downstreamMx.Lock()
+		downstreamSources = append(downstreamSources, r.Header.Get(queue.QuerySourceHeader))
+		downstreamMx.Unlock()
+
+		resultType := "vector"
+		if isRangeQuery(r.URL.Path) {
+			resultType = "matrix"
+		}
+		return PrometheusCodec.EncodeResponse(r.Context(), &PrometheusResponse{
+			Status: "success",
+			Data:   &PrometheusData{ResultType: resultType, Result: []SampleStream{}},
+		})
+	}))
+
+	for name, testData := range map[string]struct {
+		path                  string
+		source                string
+		expectedDownstream    int
+		expectedDownstreamSrc string
+	}{
+		"range query split by interval run by the ruler": {
+			path:                  "/api/v1/query_range?query=up&start=0&end=200000&step=60",
+			source:                queue.QuerySourceRuler,
+			expectedDownstream:    3,
+			expectedDownstreamSrc: queue.QuerySourceRuler,
+		},
+		"range query split by interval not run by the ruler": {
+			path:               "/api/v1/query_range?query=up&start=0&end=200000&step=60",
+			expectedDownstream: 3,
+		},
+		"instant query run by the ruler": {
+			path:                  "/api/v1/query?query=up&time=1000",
+			source:                queue.QuerySourceRuler,
+			expectedDownstream:    1,
+			expectedDownstreamSrc: queue.QuerySourceRuler,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			downstreamSources = nil
+
+			req := httptest.NewRequest(http.MethodGet, testData.path, nil)
+			if testData.source != "" {
+				req.Header.Set(queue.QuerySourceHeader, testData.source)
+			}
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			resp, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			require.Len(t, downstreamSources, testData.expectedDownstream)
+			for _, source := range downstreamSources {
+				assert.Equal(t, testData.expectedDownstreamSrc, source)
+			}
+		})
+	}
+}
+
+func TestTripperware_ShouldRunTheCustomMiddlewares(t *testing.T) {
+	var (
+		mtx              sync.Mutex
+		preSplitQueries  []Request
+		postCacheQueries []Request
+	)
+	recordingMiddleware := func(recorded *[]Request) Middleware {
+		return MiddlewareFunc(func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, req Request) (Response, error) {
+				mtx.Lock()
+				*recorded = append(*recorded, req)
+				mtx.Unlock()
+				return next.Do(ctx, req)
+			})
+		})
+	}
+
+	tests := map[string]struct {
+		path                     string
+		limits                   Limits
+		expectedPreSplitQuery    string
+		expectedPostCacheQueries int
+	}{
+		"range query split in two days": {
+			path:                     "/api/v1/query_range?query=up&start=1536673680&end=1536760080&step=120",
+			limits:                   mockLimits{},
+			expectedPreSplitQuery:    "up",
+			expectedPostCacheQueries: 2,
+		},
+		"instant query": {
+			path:                     "/api/v1/query?query=up&time=1536673680",
+			limits:                   mockLimits{},
+			expectedPreSplitQuery:    "up",
+			expectedPostCacheQueries: 1,
+		},
+		"instant query with a spun off subquery": {
+			path:                     "/api/v1/query?query=" + url.QueryEscape("max_over_time(rate(up[5m])[1d:5m])") + "&time=1536673680",
+			limits:                   mockLimits{subquerySpinOff: true},
+			expectedPreSplitQuery:    "max_over_time(rate(up[5m])[1d:5m])",
+			expectedPostCacheQueries: 2,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			preSplitQueries, postCacheQueries = nil, nil
+
+			tw := newQueryTripperwareWithCache(
+				Config{
+					SplitQueriesByInterval: 24 * time.Hour,
+					PreSplitMiddlewares:    []Middleware{recordingMiddleware(&preSplitQueries)},
+					PostCacheMiddlewares:   []Middleware{recordingMiddleware(&postCacheQueries)},
+				},
+				log.NewNopLogger(),
+				testData.limits,
+				PrometheusCodec,
+				nil,
+				promql.EngineOpts{
+					Logger:     log.NewNopLogger(),
+					Reg:        nil,
+					MaxSamples: 1000,
+					Timeout:    time.Minute,
+				},
+				nil,
+				nil,
+			)
+			rt := tw(RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				return PrometheusCodec.EncodeResponse(r.Context(), &PrometheusResponse{
+					Status: "success",
+					Data:   &PrometheusData{ResultType: "matrix", Result: []SampleStream{}},
+				})
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, testData.path, nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			resp, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			// The pre-split middlewares don't run again on the spun off subqueries.
+			require.Len(t, preSplitQueries, 1)
+			assert.Equal(t, testData.expectedPreSplitQuery, preSplitQueries[0].GetQuery())
+			assert.Len(t, postCacheQueries, testData.expectedPostCacheQueries)
+		})
+	}
+}
+
+func TestTripperware_ShouldRunThePreSplitMiddlewaresOnTheQueriesServedByTheResultsCache(t *testing.T) {
+	var preSplitQueries, postCacheQueries int
+	countingMiddleware := func(count *int) Middleware {
+		return MiddlewareFunc(func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, req Request) (Response, error) {
+				*count++
+				return next.Do(ctx, req)
+			})
+		})
+	}
+
+	tw := newQueryTripperwareWithCache(
+		Config{
+			SplitQueriesByInterval: 24 * time.Hour,
+			CacheResults:           true,
+			AlignQueriesWithStep:   true,
+			PreSplitMiddlewares:    []Middleware{countingMiddleware(&preSplitQueries)},
+			PostCacheMiddlewares:   []Middleware{countingMiddleware(&postCacheQueries)},
+		},
+		log.NewNopLogger(),
+		mockLimits{},
+		PrometheusCodec,
+		PrometheusResponseExtractor{},
+		promql.EngineOpts{
+			Logger:     log.NewNopLogger(),
+			Reg:        nil,
+			MaxSamples: 1000,
+			Timeout:    time.Minute,
+		},
+		cache.NewMockCache(),
+		nil,
+	)
+
+	downstreamQueries := 0
+	rt := tw(RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		downstreamQueries++
+		return PrometheusCodec.EncodeResponse(r.Context(), &PrometheusResponse{
+			Status: "success",
+			Data:   &PrometheusData{ResultType: "matrix", Result: []SampleStream{}},
+		})
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up&start=1536673680&end=1536760080&step=120", nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// The second query is served by the results cache, but the pre-split middlewares still run on it.
+	assert.Equal(t, 2, downstreamQueries)
+	assert.Equal(t, 2, postCacheQueries)
+	assert.Equal(t, 2, preSplitQueries)
+}
+
 func TestTripperware_Metrics(t *testing.T) {
 	tests := map[string]struct {
 		path                    string
@@ -107,6 +107,7 @@ func (q *shardedQuerier) Select(_ bool, hints *storage.SelectHints, matchers ...
 // The returned storage.SeriesSet contains sorted series.
 func (q *shardedQuerier) handleEmbeddedQueries(queries []string, hints *storage.SelectHints) storage.SeriesSet {
 	streams := make([][]SampleStream, len(queries))
+	warnings := make([][]string, len(queries))
 
 	// Concurrently run each query. It breaks and cancels each worker context on first error.
 	err := concurrency.ForEachJob(q.ctx, len(queries), len(queries), func(ctx context.Context, idx int) error {
@@ -120,6 +121,7 @@ func (q *shardedQuerier) handleEmbeddedQueries(queries []string, hints *storage.
 			return err
 		}
 		streams[idx] = resStreams // No mutex is needed since each job writes its own index. This is like writing separate variables.
+		warnings[idx] = resp.(*PrometheusResponse).Warnings
 
 		q.responseHeaders.mergeHeaders(resp.(*PrometheusResponse).Headers)
 		return nil
@@ -129,7 +131,29 @@ func (q *shardedQuerier) handleEmbeddedQueries(queries []string, hints *storage.
 		return storage.ErrSeriesSet(err)
 	}
 
-	return newSeriesSetFromEmbeddedQueriesResults(streams, hints)
+	return newSeriesSetWithWarnings(newSeriesSetFromEmbeddedQueriesResults(streams, hints), mergeWarnings(warnings...))
+}
+
+// newSeriesSetWithWarnings returns the storage.SeriesSet with the input warnings, if any.
+func newSeriesSetWithWarnings(set storage.SeriesSet, warnings []string) storage.SeriesSet {
+	if len(warnings) == 0 {
+		return set
+	}
+
+	setWarnings := make(storage.Warnings, 0, len(warnings))
+	for _, w := range warnings {
+		setWarnings = append(setWarnings, errors.New(w))
+	}
+	return series.NewSeriesSetWithWarnings(set, setWarnings)
+}
+
+// promqlWarningsToStrings returns the deduplicated messages of the warnings of a PromQL query result.
+func promqlWarningsToStrings(warnings storage.Warnings) []string {
+	messages := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		messages = append(messages, w.Error())
+	}
+	return mergeWarnings(messages)
 }
 
 // LabelValues implements storage.LabelQuerier.
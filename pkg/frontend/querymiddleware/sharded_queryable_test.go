@@ -247,6 +247,45 @@ func TestShardedQuerier_Select_ShouldConcurrentlyRunEmbeddedQueries(t *testing.T
 	require.Equal(t, len(embeddedQueries), actualSeries)
 }
 
+func TestShardedQuerier_Select_ShouldReturnTheWarningsOfTheEmbeddedQueries(t *testing.T) {
+	embeddedQueries := []string{
+		`sum(rate(metric{__query_shard__="0_of_2"}[1m]))`,
+		`sum(rate(metric{__query_shard__="1_of_2"}[1m]))`,
+	}
+
+	querier := mkShardedQuerier(HandlerFunc(func(ctx context.Context, req Request) (Response, error) {
+		return &PrometheusResponse{
+			Data: &PrometheusData{
+				ResultType: string(parser.ValueTypeVector),
+				Result: []SampleStream{{
+					Labels:  []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+					Samples: []mimirpb.Sample{{Value: 1, TimestampMs: 1}},
+				}},
+			},
+			Warnings: []string{"the results may be incomplete"},
+		}, nil
+	}))
+
+	encodedQueries, err := astmapper.JSONCodec.Encode(embeddedQueries)
+	require.Nil(t, err)
+
+	seriesSet := querier.Select(
+		false,
+		nil,
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", astmapper.EmbeddedQueriesMetricName),
+		labels.MustNewMatcher(labels.MatchEqual, astmapper.EmbeddedQueriesLabelName, encodedQueries),
+	)
+	for seriesSet.Next() {
+	}
+	require.NoError(t, seriesSet.Err())
+
+	// The warnings of the embedded queries are deduplicated.
+	warnings := seriesSet.Warnings()
+	require.Len(t, warnings, 1)
+	assert.EqualError(t, warnings[0], "the results may be incomplete")
+	assert.Equal(t, []string{"the results may be incomplete"}, promqlWarningsToStrings(append(warnings, warnings...)))
+}
+
 func TestShardedQueryable_GetResponseHeaders(t *testing.T) {
 	queryable := newShardedQueryable(&PrometheusRangeQueryRequest{}, nil)
 	assert.Empty(t, queryable.getResponseHeaders())
@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/storage/lazyquery"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	skippedReasonNoSpinOffSubqueries = "no-spin-off-subqueries"
+)
+
+var (
+	errMissingSpunOffSubquery = errors.New("missing spun off subquery")
+	errMissingSelectHints     = errors.New("missing select hints")
+)
+
+type spinOffSubqueriesMetrics struct {
+	spinOffAttempts           prometheus.Counter
+	spinOffSuccesses          prometheus.Counter
+	spinOffSkipped            *prometheus.CounterVec
+	spunOffSubqueries         prometheus.Counter
+	spunOffSubqueriesPerQuery prometheus.Histogram
+}
+
+func newSpinOffSubqueriesMetrics(registerer prometheus.Registerer) spinOffSubqueriesMetrics {
+	m := spinOffSubqueriesMetrics{
+		spinOffAttempts: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_subquery_spin_off_attempts_total",
+			Help: "Total number of instant queries the query-frontend attempted to spin off the subqueries of.",
+		}),
+		spinOffSuccesses: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_subquery_spin_off_successes_total",
+			Help: "Total number of instant queries the query-frontend successfully spun off the subqueries of.",
+		}),
+		spinOffSkipped: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_frontend_subquery_spin_off_skipped_total",
+			Help: "Total number of instant queries the query-frontend skipped or failed to spin off the subqueries of.",
+		}, []string{"reason"}),
+		spunOffSubqueries: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_spun_off_subqueries_total",
+			Help: "Total number of subqueries spun off into range queries.",
+		}),
+		spunOffSubqueriesPerQuery: promauto.With(registerer).NewHistogram(prometheus.HistogramOpts{
+			Name:    "cortex_frontend_spun_off_subqueries_per_query",
+			Help:    "Number of subqueries a single instant query has been spun off into range queries.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 6),
+		}),
+	}
+
+	// Initialize known label values.
+	for _, reason := range []string{skippedReasonParsingFailed, skippedReasonMappingFailed, skippedReasonNoSpinOffSubqueries} {
+		m.spinOffSkipped.WithLabelValues(reason)
+	}
+
+	return m
+}
+
+// spinOffSubqueriesMiddleware is a Middleware spinning off the expensive subqueries of the instant queries into
+// range queries, run through the range queries middlewares, so that their results are split by interval, cached
+// and sharded. The rest of the instant query is run downstream and the outer expressions of the query are
+// evaluated in the query-frontend, on the results of the range and instant queries.
+//
+// This makes the subqueries of the instant queries run repeatedly over a sliding time range, like the ones of
+// the SLO dashboards, cacheable.
+type spinOffSubqueriesMiddleware struct {
+	next         Handler
+	rangeHandler Handler
+	limits       Limits
+	logger       log.Logger
+
+	engine *promql.Engine
+
+	metrics spinOffSubqueriesMetrics
+}
+
+// newSpinOffSubqueriesMiddleware makes a new spinOffSubqueriesMiddleware. The spun off subqueries are run
+// through the rangeHandler.
+func newSpinOffSubqueriesMiddleware(limits Limits, logger log.Logger, engine *promql.Engine, rangeHandler Handler, metrics spinOffSubqueriesMetrics) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &spinOffSubqueriesMiddleware{
+			next:         next,
+			rangeHandler: rangeHandler,
+			limits:       limits,
+			logger:       logger,
+			engine:       engine,
+			metrics:      metrics,
+		}
+	})
+}
+
+func (s *spinOffSubqueriesMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	if _, ok := req.(*PrometheusInstantQueryRequest); !ok {
+		return s.next.Do(ctx, req)
+	}
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	// The subqueries are only spun off if all the tenants of the query enable it.
+	for _, tenantID := range tenantIDs {
+		if !s.limits.SubquerySpinOffEnabled(tenantID) {
+			return s.next.Do(ctx, req)
+		}
+	}
+
+	logger := log.With(s.logger, "query", req.GetQuery(), "query_timestamp", req.GetStart())
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, logger, "spinOffSubqueriesMiddleware.Do")
+	defer spanLog.Span.Finish()
+
+	s.metrics.spinOffAttempts.Inc()
+
+	expr, err := parser.ParseExpr(req.GetQuery())
+	if err != nil {
+		level.Warn(spanLog).Log("msg", "failed to parse query", "err", err)
+		s.metrics.spinOffSkipped.WithLabelValues(skippedReasonParsingFailed).Inc()
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	mapperStats := astmapper.NewSubquerySpinOffMapperStats()
+	mapped, err := astmapper.NewSubquerySpinOffMapper(mapperStats).Map(expr)
+	if err != nil {
+		level.Debug(spanLog).Log("msg", "failed to map the input query, falling back to try executing without spinning off the subqueries", "err", err)
+		s.metrics.spinOffSkipped.WithLabelValues(skippedReasonMappingFailed).Inc()
+		return s.next.Do(ctx, req)
+	}
+
+	if mapperStats.GetSpunOffSubqueries() == 0 {
+		level.Debug(spanLog).Log("msg", "input query has no subqueries which can be spun off, falling back to try executing without spinning off the subqueries")
+		s.metrics.spinOffSkipped.WithLabelValues(skippedReasonNoSpinOffSubqueries).Inc()
+		return s.next.Do(ctx, req)
+	}
+
+	// Send hint with number of embedded queries to the sharding middleware.
+	mappedReq := req.WithQuery(mapped.String())
+	if downstreamQueries := mapperStats.GetDownstreamQueries(); downstreamQueries > 0 {
+		mappedReq = mappedReq.WithHints(&Hints{TotalQueries: int32(downstreamQueries)})
+	}
+
+	queryable := newSpinOffSubqueriesQueryable(mappedReq, s.next, s.rangeHandler)
+	qry, err := newQuery(mappedReq, s.engine, lazyquery.NewLazyQueryable(queryable))
+	if err != nil {
+		level.Warn(spanLog).Log("msg", "failed to create new query from the query with spun off subqueries, falling back to try executing without spinning off the subqueries", "err", err)
+		s.metrics.spinOffSkipped.WithLabelValues(skippedReasonMappingFailed).Inc()
+		return s.next.Do(ctx, req)
+	}
+
+	level.Debug(spanLog).Log("msg", "instant query subqueries have been spun off", "rewritten", mapped, "spun_off_subqueries", mapperStats.GetSpunOffSubqueries(), "downstream_queries", mapperStats.GetDownstreamQueries())
+
+	// Update query stats. The spun off subqueries are further split by the range queries middlewares.
+	queryStats := stats.FromContext(ctx)
+	queryStats.AddSplitQueries(uint32(mapperStats.GetSpunOffSubqueries() + mapperStats.GetDownstreamQueries()))
+
+	// Update metrics.
+	s.metrics.spinOffSuccesses.Inc()
+	s.metrics.spunOffSubqueries.Add(float64(mapperStats.GetSpunOffSubqueries()))
+	s.metrics.spunOffSubqueriesPerQuery.Observe(float64(mapperStats.GetSpunOffSubqueries()))
+
+	res := qry.Exec(ctx)
+	extracted, err := promqlResultToSamples(res)
+	if err != nil {
+		level.Warn(spanLog).Log("msg", "failed to execute the query with spun off subqueries", "err", err)
+		return nil, mapEngineError(err)
+	}
+	return &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{
+			ResultType: string(res.Value.Type()),
+			Result:     extracted,
+		},
+		Headers:  queryable.getResponseHeaders(),
+		Warnings: promqlWarningsToStrings(res.Warnings),
+	}, nil
+}
+
+// spinOffSubqueriesQueryable is an implementor of the Queryable interface, running the spun off subqueries
+// as range queries and the embedded queries as instant queries.
+type spinOffSubqueriesQueryable struct {
+	*shardedQueryable
+
+	rangeHandler Handler
+}
+
+// newSpinOffSubqueriesQueryable makes a new spinOffSubqueriesQueryable. As for the shardedQueryable,
+// a new queryable is expected to be created for each query.
+func newSpinOffSubqueriesQueryable(req Request, next, rangeHandler Handler) *spinOffSubqueriesQueryable {
+	return &spinOffSubqueriesQueryable{
+		shardedQueryable: newShardedQueryable(req, next),
+		rangeHandler:     rangeHandler,
+	}
+}
+
+// Querier implements storage.Queryable.
+func (q *spinOffSubqueriesQueryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &spinOffSubqueriesQuerier{
+		shardedQuerier: &shardedQuerier{ctx: ctx, req: q.req, handler: q.handler, responseHeaders: q.responseHeaders},
+		rangeHandler:   q.rangeHandler,
+	}, nil
+}
+
+// spinOffSubqueriesQuerier implements the storage.Querier interface, running the spun off subqueries selected
+// with the astmapper.SubquerySpinOffMetricName metric through the range handler, and the embedded queries
+// through the downstream handler.
+type spinOffSubqueriesQuerier struct {
+	*shardedQuerier
+
+	rangeHandler Handler
+}
+
+// Select implements storage.Querier.
+func (q *spinOffSubqueriesQuerier) Select(sorted bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var isSpunOff bool
+	var subquery, step string
+	for _, matcher := range matchers {
+		switch matcher.Name {
+		case labels.MetricName:
+			isSpunOff = matcher.Value == astmapper.SubquerySpinOffMetricName
+		case astmapper.SubqueryQueryLabelName:
+			subquery = matcher.Value
+		case astmapper.SubqueryStepLabelName:
+			step = matcher.Value
+		}
+	}
+
+	if !isSpunOff {
+		return q.shardedQuerier.Select(sorted, hints, matchers...)
+	}
+	if subquery == "" {
+		return storage.ErrSeriesSet(errMissingSpunOffSubquery)
+	}
+	if hints == nil {
+		return storage.ErrSeriesSet(errMissingSelectHints)
+	}
+
+	subqueryStep, err := astmapper.ParseSubqueryStep(step)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	rangeReq, ok := newSpunOffSubqueryRequest(q.req, subquery, subqueryStep, hints)
+	if !ok {
+		return storage.EmptySeriesSet()
+	}
+
+	resp, err := q.rangeHandler.Do(q.ctx, rangeReq)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	streams, err := responseToSamples(resp)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	q.responseHeaders.mergeHeaders(resp.(*PrometheusResponse).Headers)
+
+	// The subquery results are selected by a matrix selector, so no stale marker is needed.
+	return newSeriesSetWithWarnings(newSeriesSetFromEmbeddedQueriesResults([][]SampleStream{streams}, nil), resp.(*PrometheusResponse).Warnings)
+}
+
+// newSpunOffSubqueryRequest returns the range query request running the spun off subquery over the time range
+// selected by the hints. As for the subqueries evaluated by the PromQL engine, the range query steps are aligned
+// to the subquery step: the range query is evaluated at the timestamps multiple of the step within the time range.
+// It returns false if the time range has no step.
+func newSpunOffSubqueryRequest(req Request, subquery string, step time.Duration, hints *storage.SelectHints) (*PrometheusRangeQueryRequest, bool) {
+	stepMs := step.Milliseconds()
+
+	start := stepMs * (hints.Start / stepMs)
+	if start < hints.Start {
+		start += stepMs
+	}
+	end := stepMs * (hints.End / stepMs)
+	if end > hints.End {
+		end -= stepMs
+	}
+	if start > end {
+		return nil, false
+	}
+
+	// The range query is sent to the range query API with the same prefix of the instant query API.
+	path := queryRangePathSuffix
+	if instantReq, ok := req.(*PrometheusInstantQueryRequest); ok {
+		path = strings.TrimSuffix(instantReq.Path, instantQueryPathSuffix) + queryRangePathSuffix
+	}
+
+	return &PrometheusRangeQueryRequest{
+		Path:    path,
+		Start:   start,
+		End:     end,
+		Step:    stepMs,
+		Query:   subquery,
+		Options: req.GetOptions(),
+	}, true
+}
@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestSpinOffSubqueriesCorrectness(t *testing.T) {
+	queryTime := time.Date(2022, 1, 1, 12, 0, 30, 0, time.UTC)
+	seriesStart := queryTime.Add(-26 * time.Hour)
+
+	series := make([]*promql.StorageSeries, 0, 20)
+	for i := 0; i < 20; i++ {
+		gen := factor(float64(i) * 0.1)
+		if i%5 == 0 {
+			// Wrap the generator to inject the staleness marker in the middle of the subqueries time range.
+			gen = stale(queryTime.Add(-10*time.Hour), queryTime.Add(-8*time.Hour), gen)
+		}
+		series = append(series, newSeries(newTestCounterLabels(i), seriesStart, queryTime, time.Minute, gen))
+	}
+	queryable := storageSeriesQueryable(series)
+
+	tests := map[string]struct {
+		query                     string
+		expectedSpunOffSubqueries int
+	}{
+		"subquery over a range vector function": {
+			query:                     `max_over_time(rate(metric_counter[5m])[1d:5m])`,
+			expectedSpunOffSubqueries: 1,
+		},
+		"subquery over an aggregation": {
+			query:                     `avg_over_time(sum by (group_1) (rate(metric_counter[5m]))[6h:1m])`,
+			expectedSpunOffSubqueries: 1,
+		},
+		"subquery with offset": {
+			query:                     `min_over_time(sum(metric_counter)[2h:10m] offset 1h)`,
+			expectedSpunOffSubqueries: 1,
+		},
+		"subquery with a step not aligned to the query time": {
+			query:                     `count_over_time(metric_counter[2h:7m])`,
+			expectedSpunOffSubqueries: 1,
+		},
+		"subqueries and instant vectors": {
+			query:                     `1 - sum_over_time(sum(rate(metric_counter{group_2="0"}[5m]))[12h:5m]) / sum_over_time(sum(rate(metric_counter[5m]))[12h:5m]) * scalar(count(metric_counter))`,
+			expectedSpunOffSubqueries: 2,
+		},
+		"subquery with too few steps": {
+			query:                     `max_over_time(rate(metric_counter[5m])[30m:5m])`,
+			expectedSpunOffSubqueries: 0,
+		},
+		"subquery without step": {
+			query:                     `max_over_time(rate(metric_counter[5m])[1h:])`,
+			expectedSpunOffSubqueries: 0,
+		},
+		"subquery with @ modifier": {
+			query:                     `max_over_time(rate(metric_counter[5m] @ 1641038400)[1h:1m])`,
+			expectedSpunOffSubqueries: 0,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			req := &PrometheusInstantQueryRequest{
+				Path:  "/prometheus/api/v1/query",
+				Time:  util.TimeToMillis(queryTime),
+				Query: testData.query,
+			}
+
+			engine := newEngine()
+			downstream := &downstreamHandler{engine: engine, queryable: queryable}
+
+			// Run the query with the normal engine.
+			expectedRes, err := downstream.Do(context.Background(), req)
+			require.NoError(t, err)
+			expectedPrometheusRes := expectedRes.(*PrometheusResponse)
+			sort.Sort(byLabels(expectedPrometheusRes.Data.Result))
+			require.NotEmpty(t, expectedPrometheusRes.Data.Result)
+
+			// Run the query spinning off the subqueries.
+			rangeHandler := &recordingHandler{next: downstream}
+			reg := prometheus.NewPedanticRegistry()
+			spinOff := newSpinOffSubqueriesMiddleware(mockLimits{subquerySpinOff: true}, log.NewNopLogger(), engine, rangeHandler, newSpinOffSubqueriesMetrics(reg))
+
+			_, ctx := stats.ContextWithEmptyStats(context.Background())
+			spinOffRes, err := spinOff.Wrap(downstream).Do(user.InjectOrgID(ctx, "test"), req)
+			require.NoError(t, err)
+			spinOffPrometheusRes := spinOffRes.(*PrometheusResponse)
+			sort.Sort(byLabels(spinOffPrometheusRes.Data.Result))
+
+			approximatelyEquals(t, expectedPrometheusRes, spinOffPrometheusRes)
+
+			// The spun off subqueries are run as step-aligned range queries.
+			rangeReqs := rangeHandler.getRequests()
+			require.Len(t, rangeReqs, testData.expectedSpunOffSubqueries)
+			for _, rangeReq := range rangeReqs {
+				require.IsType(t, &PrometheusRangeQueryRequest{}, rangeReq)
+				assert.Equal(t, "/prometheus/api/v1/query_range", rangeReq.(*PrometheusRangeQueryRequest).Path)
+				assert.Zero(t, rangeReq.GetStart()%rangeReq.GetStep())
+				assert.Zero(t, rangeReq.GetEnd()%rangeReq.GetStep())
+			}
+
+			expectedSucceeded, expectedSkipped := 1, 0
+			if testData.expectedSpunOffSubqueries == 0 {
+				expectedSucceeded, expectedSkipped = 0, 1
+			}
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+				# HELP cortex_frontend_subquery_spin_off_attempts_total Total number of instant queries the query-frontend attempted to spin off the subqueries of.
+				# TYPE cortex_frontend_subquery_spin_off_attempts_total counter
+				cortex_frontend_subquery_spin_off_attempts_total 1
+
+				# HELP cortex_frontend_subquery_spin_off_successes_total Total number of instant queries the query-frontend successfully spun off the subqueries of.
+				# TYPE cortex_frontend_subquery_spin_off_successes_total counter
+				cortex_frontend_subquery_spin_off_successes_total %d
+
+				# HELP cortex_frontend_subquery_spin_off_skipped_total Total number of instant queries the query-frontend skipped or failed to spin off the subqueries of.
+				# TYPE cortex_frontend_subquery_spin_off_skipped_total counter
+				cortex_frontend_subquery_spin_off_skipped_total{reason="mapping-failed"} 0
+				cortex_frontend_subquery_spin_off_skipped_total{reason="no-spin-off-subqueries"} %d
+				cortex_frontend_subquery_spin_off_skipped_total{reason="parsing-failed"} 0
+
+				# HELP cortex_frontend_spun_off_subqueries_total Total number of subqueries spun off into range queries.
+				# TYPE cortex_frontend_spun_off_subqueries_total counter
+				cortex_frontend_spun_off_subqueries_total %d
+			`, expectedSucceeded, expectedSkipped, testData.expectedSpunOffSubqueries)),
+				"cortex_frontend_subquery_spin_off_attempts_total",
+				"cortex_frontend_subquery_spin_off_successes_total",
+				"cortex_frontend_subquery_spin_off_skipped_total",
+				"cortex_frontend_spun_off_subqueries_total"))
+		})
+	}
+}
+
+func TestSpinOffSubqueriesMiddleware_ShouldNotSpinOffIfDisabled(t *testing.T) {
+	req := &PrometheusInstantQueryRequest{Path: "/query", Time: 3600000, Query: `max_over_time(rate(metric_counter[5m])[1h:1m])`}
+
+	downstream := &recordingHandler{next: mockHandlerWith(&PrometheusResponse{Status: statusSuccess, Data: &PrometheusData{ResultType: "vector"}}, nil)}
+	rangeHandler := &recordingHandler{next: downstream}
+	spinOff := newSpinOffSubqueriesMiddleware(mockLimits{}, log.NewNopLogger(), newEngine(), rangeHandler, newSpinOffSubqueriesMetrics(nil))
+
+	_, err := spinOff.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "test"), req)
+	require.NoError(t, err)
+
+	// The query is run as is downstream.
+	require.Len(t, downstream.getRequests(), 1)
+	assert.Equal(t, req, downstream.getRequests()[0])
+	assert.Empty(t, rangeHandler.getRequests())
+}
+
+func TestNewSpunOffSubqueryRequest(t *testing.T) {
+	req := &PrometheusInstantQueryRequest{Path: "/prometheus/api/v1/query", Time: 3600000, Query: "unused", Options: Options{CacheDisabled: true}}
+
+	// The time range is aligned to the step, within the selected time range.
+	rangeReq, ok := newSpunOffSubqueryRequest(req, "up", time.Minute, &storage.SelectHints{Start: 10000, End: 310000})
+	require.True(t, ok)
+	assert.Equal(t, &PrometheusRangeQueryRequest{
+		Path:    "/prometheus/api/v1/query_range",
+		Start:   60000,
+		End:     300000,
+		Step:    60000,
+		Query:   "up",
+		Options: Options{CacheDisabled: true},
+	}, rangeReq)
+
+	// The time range has no step.
+	_, ok = newSpunOffSubqueryRequest(req, "up", time.Minute, &storage.SelectHints{Start: 10000, End: 50000})
+	assert.False(t, ok)
+}
+
+// recordingHandler is a Handler recording the requests passed to the next handler.
+type recordingHandler struct {
+	next Handler
+
+	mtx      sync.Mutex
+	requests []Request
+}
+
+func (h *recordingHandler) Do(ctx context.Context, req Request) (Response, error) {
+	h.mtx.Lock()
+	h.requests = append(h.requests, req)
+	h.mtx.Unlock()
+
+	return h.next.Do(ctx, req)
+}
+
+func (h *recordingHandler) getRequests() []Request {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.requests
+}
@@ -30,7 +30,6 @@ import (
 	"github.com/grafana/mimir/pkg/cache"
 	"github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
-	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -71,12 +70,13 @@ type splitAndCacheMiddleware struct {
 	splitInterval time.Duration
 
 	// Results caching.
-	cacheEnabled           bool
-	cacheUnalignedRequests bool
-	cache                  cache.Cache
-	splitter               CacheSplitter
-	extractor              Extractor
-	shouldCacheReq         shouldCacheFn
+	cacheEnabled             bool
+	cacheUnalignedRequests   bool
+	fineGrainedCacheInterval time.Duration
+	cache                    cache.Cache
+	splitter                 CacheSplitter
+	extractor                Extractor
+	shouldCacheReq           shouldCacheFn
 }
 
 // newSplitAndCacheMiddleware makes a new splitAndCacheMiddleware.
@@ -85,6 +85,7 @@ func newSplitAndCacheMiddleware(
 	cacheEnabled bool,
 	splitInterval time.Duration,
 	cacheUnalignedRequests bool,
+	fineGrainedCacheInterval time.Duration,
 	limits Limits,
 	merger Merger,
 	cache cache.Cache,
@@ -97,19 +98,20 @@ func newSplitAndCacheMiddleware(
 
 	return MiddlewareFunc(func(next Handler) Handler {
 		return &splitAndCacheMiddleware{
-			splitEnabled:           splitEnabled,
-			cacheEnabled:           cacheEnabled,
-			cacheUnalignedRequests: cacheUnalignedRequests,
-			next:                   next,
-			limits:                 limits,
-			merger:                 merger,
-			splitInterval:          splitInterval,
-			metrics:                metrics,
-			cache:                  cache,
-			splitter:               splitter,
-			extractor:              extractor,
-			shouldCacheReq:         shouldCacheReq,
-			logger:                 logger,
+			splitEnabled:             splitEnabled,
+			cacheEnabled:             cacheEnabled,
+			cacheUnalignedRequests:   cacheUnalignedRequests,
+			fineGrainedCacheInterval: fineGrainedCacheInterval,
+			next:                     next,
+			limits:                   limits,
+			merger:                   merger,
+			splitInterval:            splitInterval,
+			metrics:                  metrics,
+			cache:                    cache,
+			splitter:                 splitter,
+			extractor:                extractor,
+			shouldCacheReq:           shouldCacheReq,
+			logger:                   logger,
 		}
 	})
 }
@@ -128,11 +130,20 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 	}
 
 	isCacheEnabled := s.cacheEnabled && (s.shouldCacheReq == nil || s.shouldCacheReq(req))
-	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, s.limits.MaxCacheFreshness)
+	cacheStatus := resultsCacheStatusFromContext(ctx)
+	maxCacheFreshness := maxCacheFreshnessPerTenant(tenantIDs, s.limits)
 	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
 
 	// Lookup the results cache.
 	if isCacheEnabled {
+		// Split the requests further by the fine-grained interval, so that each part is cached on its own
+		// and reused by the queries whose time range partially overlaps.
+		if s.fineGrainedCacheInterval > 0 {
+			if splitReqs, err = s.splitRequestsByFineGrainedInterval(splitReqs); err != nil {
+				return nil, err
+			}
+		}
+
 		// Build the cache keys for all requests to try to fetch from cache.
 		lookupReqs := make([]*splitRequest, 0, len(splitReqs))
 		lookupKeys := make([]string, 0, len(splitReqs))
@@ -141,21 +152,28 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 			// Do not try to pick response from cache at all if the request is not cachable.
 			if !isRequestCachable(splitReq.orig, maxCacheTime, s.cacheUnalignedRequests, s.logger) {
 				splitReq.downstreamRequests = []Request{splitReq.orig}
+				cacheStatus.recordMiss()
 				continue
 			}
 
-			splitReq.cacheKey = s.splitter.GenerateCacheKey(ctx, tenant.JoinTenantIDs(tenantIDs), splitReq.orig)
+			splitReq.cacheKey = s.generateCacheKey(ctx, resultsCacheTenantKey(tenantIDs, s.limits), splitReq.orig)
 			lookupKeys = append(lookupKeys, splitReq.cacheKey)
 			lookupReqs = append(lookupReqs, splitReq)
 		}
 
-		// Lookup all keys from cache.
-		fetchedExtents := s.fetchCacheExtents(ctx, lookupKeys)
+		// Lookup all keys from cache, unless the request bypasses it.
+		var fetchedExtents [][]Extent
+		if isResultsCacheBypassed(ctx) {
+			fetchedExtents = make([][]Extent, len(lookupKeys))
+		} else {
+			fetchedExtents = s.fetchCacheExtents(ctx, lookupKeys)
+		}
 
 		for lookupIdx, extents := range fetchedExtents {
 			if len(extents) == 0 {
 				// We just need to run the request as is because no part of it has been cached yet.
 				lookupReqs[lookupIdx].downstreamRequests = []Request{lookupReqs[lookupIdx].orig}
+				cacheStatus.recordMiss()
 				continue
 			}
 
@@ -174,9 +192,14 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 				}
 
 				lookupReqs[lookupIdx].cachedResponses = []Response{response}
+				cacheStatus.recordHit()
 				continue
 			}
 
+			// Part of the response has been picked up from the cache.
+			cacheStatus.recordHit()
+			cacheStatus.recordMiss()
+
 			lookupReqs[lookupIdx].downstreamRequests = requests
 			lookupReqs[lookupIdx].cachedResponses = responses
 			lookupReqs[lookupIdx].cachedExtents = extents
@@ -191,6 +214,12 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 	// Prepare and execute the downstream requests.
 	execReqs := splitReqs.prepareDownstreamRequests()
 
+	// The contiguous parts of the fine-grained cache are run downstream as a single request.
+	var coalescedReqs map[int64][]Request
+	if isCacheEnabled && s.fineGrainedCacheInterval > 0 {
+		execReqs, coalescedReqs = coalesceDownstreamRequests(execReqs, s.coalescingInterval())
+	}
+
 	// Update query stats.
 	// Only consider the actual number of downstream requests, not the cache hits.
 	queryStats := stats.FromContext(ctx)
@@ -201,6 +230,9 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 		if err != nil {
 			return nil, err
 		}
+		if coalescedReqs != nil {
+			execResps = s.extractCoalescedResponses(execResps, coalescedReqs)
+		}
 
 		// Store the downstream responses in our internal data structure.
 		if err := splitReqs.storeDownstreamResponses(execResps); err != nil {
@@ -292,6 +324,60 @@ func (s *splitAndCacheMiddleware) splitRequestByInterval(req Request) (splitRequ
 	return out, nil
 }
 
+// splitRequestsByFineGrainedInterval splits the given requests by the fine-grained cache interval.
+func (s *splitAndCacheMiddleware) splitRequestsByFineGrainedInterval(reqs splitRequests) (splitRequests, error) {
+	out := make(splitRequests, 0, len(reqs))
+	for _, splitReq := range reqs {
+		parts, err := splitQueryByInterval(splitReq.orig, s.fineGrainedCacheInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, part := range parts {
+			out = append(out, &splitRequest{orig: part})
+		}
+	}
+	return out, nil
+}
+
+// generateCacheKey returns the cache key of the given split request.
+func (s *splitAndCacheMiddleware) generateCacheKey(ctx context.Context, userID string, req Request) string {
+	if s.fineGrainedCacheInterval > 0 {
+		return fineGrainedCacheKey(ctx, userID, req, s.fineGrainedCacheInterval)
+	}
+	return s.splitter.GenerateCacheKey(ctx, userID, req)
+}
+
+// coalescingInterval returns the interval the coalesced downstream requests must not cross, which is the
+// split interval. Returns 0 if splitting is disabled.
+func (s *splitAndCacheMiddleware) coalescingInterval() time.Duration {
+	if !s.splitEnabled {
+		return 0
+	}
+	return s.splitInterval
+}
+
+// extractCoalescedResponses extracts the response of each coalesced request from the response of the
+// request it has been coalesced into.
+func (s *splitAndCacheMiddleware) extractCoalescedResponses(execResps []requestResponse, coalescedReqs map[int64][]Request) []requestResponse {
+	out := make([]requestResponse, 0, len(execResps))
+	for _, execResp := range execResps {
+		parts, ok := coalescedReqs[execResp.Request.GetId()]
+		if !ok || len(parts) == 1 {
+			out = append(out, execResp)
+			continue
+		}
+
+		for _, part := range parts {
+			out = append(out, requestResponse{
+				Request:  part,
+				Response: s.extractor.Extract(part.GetStart(), part.GetEnd(), execResp.Response),
+			})
+		}
+	}
+	return out
+}
+
 // fetchCacheExtents fetches the extents for the given key from the cache. The returned slice
 // is guaranteed to have the same length of the input keys. For each input key, the fetched
 // extents are stored in the returned slice at the same position. In case of error or cache miss,
@@ -481,6 +567,47 @@ func (s *splitRequests) storeDownstreamResponses(responses []requestResponse) er
 	return nil
 }
 
+// coalesceDownstreamRequests merges the contiguous downstream requests into a single request, without crossing
+// the boundaries of the given interval (if not 0). The input requests are expected to be sorted by time range.
+// It returns the requests to execute and, by request ID, the input requests each one of them has been coalesced
+// from. The returned requests keep the ID of the first request they have been coalesced from.
+func coalesceDownstreamRequests(reqs []Request, interval time.Duration) ([]Request, map[int64][]Request) {
+	var groups [][]Request
+	for _, req := range reqs {
+		if len(groups) > 0 {
+			group := groups[len(groups)-1]
+			first, last := group[0], group[len(group)-1]
+			contiguous := req.GetQuery() == first.GetQuery() && req.GetStep() == first.GetStep() && req.GetStart() <= last.GetEnd()+last.GetStep()
+			sameInterval := interval <= 0 || req.GetStart()/interval.Milliseconds() == first.GetStart()/interval.Milliseconds()
+
+			if contiguous && sameInterval {
+				groups[len(groups)-1] = append(group, req)
+				continue
+			}
+		}
+		groups = append(groups, []Request{req})
+	}
+
+	hints := &Hints{TotalQueries: int32(len(groups))}
+	execReqs := make([]Request, 0, len(groups))
+	coalesced := make(map[int64][]Request, len(groups))
+
+	for _, group := range groups {
+		end := group[0].GetEnd()
+		for _, req := range group[1:] {
+			if req.GetEnd() > end {
+				end = req.GetEnd()
+			}
+		}
+
+		execReq := group[0].WithStartEnd(group[0].GetStart(), end).WithHints(hints)
+		execReqs = append(execReqs, execReq)
+		coalesced[execReq.GetId()] = group
+	}
+
+	return execReqs, coalesced
+}
+
 // requestResponse contains a request response and the respective request that was used.
 type requestResponse struct {
 	Request  Request
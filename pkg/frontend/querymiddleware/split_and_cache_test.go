@@ -88,6 +88,7 @@ func TestSplitAndCacheMiddleware_SplitByInterval(t *testing.T) {
 		false, // Cache disabled.
 		24*time.Hour,
 		false,
+		0,
 		mockLimits{},
 		PrometheusCodec,
 		nil,
@@ -145,6 +146,7 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 		true,
 		24*time.Hour,
 		false,
+		0,
 		mockLimits{maxCacheFreshness: 10 * time.Minute},
 		PrometheusCodec,
 		cacheBackend,
@@ -221,6 +223,74 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 	assert.Equal(t, uint32(2), queryStats.LoadSplitQueries())
 }
 
+func TestSplitAndCacheMiddleware_ResultsCache_FineGrainedInterval(t *testing.T) {
+	cacheBackend := cache.NewInstrumentedMockCache()
+
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		false,
+		time.Hour,
+		mockLimits{maxCacheFreshness: 10 * time.Minute},
+		PrometheusCodec,
+		cacheBackend,
+		ConstSplitter(day),
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		log.NewNopLogger(),
+		prometheus.NewPedanticRegistry(),
+	)
+
+	var downstreamReqs []Request
+	rc := mw.Wrap(HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+		downstreamReqs = append(downstreamReqs, req)
+		return mkAPIResponse(req.GetStart(), req.GetEnd(), req.GetStep()), nil
+	}))
+
+	step := int64(120 * 1000)
+	hour := time.Hour.Milliseconds()
+	start := parseTimeRFC3339(t, "2021-10-15T00:00:00Z").Unix() * 1000
+	req := Request(&PrometheusRangeQueryRequest{
+		Path:  "/api/v1/query_range",
+		Start: start + 2*hour,
+		End:   start + 6*hour - step,
+		Step:  step,
+		Query: `{__name__=~".+"}`,
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "1")
+	resp, err := rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, mkAPIResponse(req.GetStart(), req.GetEnd(), step), resp)
+
+	// The query is run downstream as a single request, but cached in parts of an hour.
+	require.Len(t, downstreamReqs, 1)
+	assert.Equal(t, req.GetStart(), downstreamReqs[0].GetStart())
+	assert.Equal(t, req.GetEnd(), downstreamReqs[0].GetEnd())
+	assert.Equal(t, 4, cacheBackend.CountStoreCalls())
+
+	// A query partially overlapping the previous one reuses the cached parts, even if it starts in the middle of a part.
+	downstreamReqs = nil
+	req = req.WithStartEnd(start+3*hour+30*time.Minute.Milliseconds(), start+8*hour-step)
+	resp, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, mkAPIResponse(req.GetStart(), req.GetEnd(), step), resp)
+
+	require.Len(t, downstreamReqs, 1)
+	assert.Equal(t, start+6*hour, downstreamReqs[0].GetStart())
+	assert.Equal(t, req.GetEnd(), downstreamReqs[0].GetEnd())
+	assert.Equal(t, 6, cacheBackend.CountStoreCalls())
+
+	// A query fully within the cached parts doesn't run any request downstream.
+	downstreamReqs = nil
+	req = req.WithStartEnd(start+2*hour, start+8*hour-step)
+	resp, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, mkAPIResponse(req.GetStart(), req.GetEnd(), step), resp)
+	require.Empty(t, downstreamReqs)
+}
+
 func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAligned(t *testing.T) {
 	cacheBackend := cache.NewInstrumentedMockCache()
 
@@ -229,6 +299,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAli
 		true,
 		24*time.Hour,
 		false,
+		0,
 		mockLimits{maxCacheFreshness: 10 * time.Minute},
 		PrometheusCodec,
 		cacheBackend,
@@ -295,6 +366,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_EnabledCachingOfStepUnalignedReque
 		true,
 		24*time.Hour,
 		true, // caching of step-unaligned requests is enabled in this test.
+		0,
 		mockLimits{maxCacheFreshness: 10 * time.Minute},
 		PrometheusCodec,
 		cacheBackend,
@@ -387,9 +459,11 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 		now              = time.Now()
 		fiveMinutesAgo   = now.Add(-5 * time.Minute)
 		twentyMinutesAgo = now.Add(-20 * time.Minute)
+		fortyMinutesAgo  = now.Add(-40 * time.Minute)
 	)
 
 	tests := map[string]struct {
+		outOfOrderTimeWindow        time.Duration
 		queryStartTime              time.Time
 		queryEndTime                time.Time
 		downstreamResponse          *PrometheusResponse
@@ -424,6 +498,36 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 					mimirpb.Sample{TimestampMs: twentyMinutesAgo.Unix() * 1000, Value: 10}),
 			},
 		},
+		"should not cache a response if query time range is within the out-of-order time window": {
+			outOfOrderTimeWindow: 30 * time.Minute,
+			queryStartTime:       twentyMinutesAgo,
+			queryEndTime:         now,
+			downstreamResponse: mockPrometheusResponseSingleSeries(
+				[]mimirpb.LabelAdapter{{Name: "__name__", Value: "test_metric"}},
+				mimirpb.Sample{TimestampMs: twentyMinutesAgo.Unix() * 1000, Value: 10},
+				mimirpb.Sample{TimestampMs: now.Unix() * 1000, Value: 20}),
+			expectedDownstreamStartTime: twentyMinutesAgo,
+			expectedDownstreamEndTime:   now,
+			expectedCachedResponses:     nil,
+		},
+		"should cache a response up until the out-of-order time window ago": {
+			outOfOrderTimeWindow: 30 * time.Minute,
+			queryStartTime:       fortyMinutesAgo,
+			queryEndTime:         now,
+			downstreamResponse: mockPrometheusResponseSingleSeries(
+				[]mimirpb.LabelAdapter{{Name: "__name__", Value: "test_metric"}},
+				mimirpb.Sample{TimestampMs: fortyMinutesAgo.Unix() * 1000, Value: 10},
+				mimirpb.Sample{TimestampMs: twentyMinutesAgo.Unix() * 1000, Value: 15},
+				mimirpb.Sample{TimestampMs: now.Unix() * 1000, Value: 20}),
+			expectedDownstreamStartTime: fortyMinutesAgo,
+			expectedDownstreamEndTime:   now,
+			expectedCachedResponses: []Response{
+				mockPrometheusResponseSingleSeries(
+					[]mimirpb.LabelAdapter{{Name: "__name__", Value: "test_metric"}},
+					// Any sample within the out-of-order time window shouldn't be cached.
+					mimirpb.Sample{TimestampMs: fortyMinutesAgo.Unix() * 1000, Value: 10}),
+			},
+		},
 	}
 
 	for testName, testData := range tests {
@@ -436,7 +540,8 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 				true,
 				24*time.Hour,
 				false,
-				mockLimits{maxCacheFreshness: maxCacheFreshness},
+				0,
+				mockLimits{maxCacheFreshness: maxCacheFreshness, outOfOrderTimeWindow: testData.outOfOrderTimeWindow},
 				PrometheusCodec,
 				cacheBackend,
 				cacheSplitter,
@@ -513,11 +618,12 @@ func TestSplitAndCacheMiddleware_ResultsCacheFuzzy(t *testing.T) {
 	)
 
 	tests := map[string]struct {
-		splitEnabled        bool
-		cacheEnabled        bool
-		cacheUnaligned      bool
-		maxCacheFreshness   time.Duration
-		maxQueryParallelism int
+		splitEnabled             bool
+		cacheEnabled             bool
+		cacheUnaligned           bool
+		fineGrainedCacheInterval time.Duration
+		maxCacheFreshness        time.Duration
+		maxQueryParallelism      int
 	}{
 		"default config": {
 			splitEnabled:        true,
@@ -547,6 +653,14 @@ func TestSplitAndCacheMiddleware_ResultsCacheFuzzy(t *testing.T) {
 			maxCacheFreshness:   time.Hour,
 			maxQueryParallelism: 14,
 		},
+		"fine-grained cache interval": {
+			splitEnabled:             true,
+			cacheEnabled:             true,
+			cacheUnaligned:           true,
+			fineGrainedCacheInterval: time.Hour,
+			maxCacheFreshness:        time.Minute,
+			maxQueryParallelism:      14,
+		},
 		"split by interval disabled": {
 			splitEnabled:        false,
 			cacheEnabled:        true,
@@ -641,6 +755,7 @@ func TestSplitAndCacheMiddleware_ResultsCacheFuzzy(t *testing.T) {
 					testData.cacheEnabled,
 					24*time.Hour,
 					testData.cacheUnaligned,
+					testData.fineGrainedCacheInterval,
 					mockLimits{
 						maxCacheFreshness:   testData.maxCacheFreshness,
 						maxQueryParallelism: testData.maxQueryParallelism,
@@ -880,6 +995,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ExtentsEdgeCases(t *testing.T) {
 				true,
 				24*time.Hour,
 				false,
+				0,
 				mockLimits{},
 				PrometheusCodec,
 				cacheBackend,
@@ -925,6 +1041,7 @@ func TestSplitAndCacheMiddleware_StoreAndFetchCacheExtents(t *testing.T) {
 		true,
 		24*time.Hour,
 		false,
+		0,
 		mockLimits{},
 		PrometheusCodec,
 		cacheBackend,
@@ -972,6 +1089,7 @@ func TestSplitAndCacheMiddleware_WrapMultipleTimes(t *testing.T) {
 		true,
 		24*time.Hour,
 		false,
+		0,
 		mockLimits{},
 		PrometheusCodec,
 		cache.NewMockCache(),
@@ -988,6 +1106,91 @@ func TestSplitAndCacheMiddleware_WrapMultipleTimes(t *testing.T) {
 	})
 }
 
+func TestCoalesceDownstreamRequests(t *testing.T) {
+	const step = 10
+	query := &PrometheusRangeQueryRequest{Query: "up", Step: step}
+	otherQuery := &PrometheusRangeQueryRequest{Query: "down", Step: step}
+
+	tests := map[string]struct {
+		input    []Request
+		interval time.Duration
+		expected [][]Request
+	}{
+		"no requests": {},
+		"contiguous requests are coalesced": {
+			input: []Request{
+				query.WithStartEnd(0, 90).WithID(1),
+				query.WithStartEnd(100, 190).WithID(2),
+				query.WithStartEnd(200, 290).WithID(3),
+			},
+			expected: [][]Request{{
+				query.WithStartEnd(0, 90).WithID(1),
+				query.WithStartEnd(100, 190).WithID(2),
+				query.WithStartEnd(200, 290).WithID(3),
+			}},
+		},
+		"overlapping requests are coalesced": {
+			input: []Request{
+				query.WithStartEnd(0, 100).WithID(1),
+				query.WithStartEnd(100, 190).WithID(2),
+			},
+			expected: [][]Request{{
+				query.WithStartEnd(0, 100).WithID(1),
+				query.WithStartEnd(100, 190).WithID(2),
+			}},
+		},
+		"requests with a gap are not coalesced": {
+			input: []Request{
+				query.WithStartEnd(0, 90).WithID(1),
+				query.WithStartEnd(200, 290).WithID(2),
+			},
+			expected: [][]Request{
+				{query.WithStartEnd(0, 90).WithID(1)},
+				{query.WithStartEnd(200, 290).WithID(2)},
+			},
+		},
+		"requests of different queries are not coalesced": {
+			input: []Request{
+				query.WithStartEnd(0, 90).WithID(1),
+				otherQuery.WithStartEnd(100, 190).WithID(2),
+			},
+			expected: [][]Request{
+				{query.WithStartEnd(0, 90).WithID(1)},
+				{otherQuery.WithStartEnd(100, 190).WithID(2)},
+			},
+		},
+		"requests are not coalesced across the interval boundaries": {
+			input: []Request{
+				query.WithStartEnd(0, 90).WithID(1),
+				query.WithStartEnd(100, 190).WithID(2),
+				query.WithStartEnd(200, 290).WithID(3),
+			},
+			interval: 200 * time.Millisecond,
+			expected: [][]Request{
+				{query.WithStartEnd(0, 90).WithID(1), query.WithStartEnd(100, 190).WithID(2)},
+				{query.WithStartEnd(200, 290).WithID(3)},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			execReqs, coalesced := coalesceDownstreamRequests(testData.input, testData.interval)
+			require.Len(t, execReqs, len(testData.expected))
+			require.Len(t, coalesced, len(testData.expected))
+
+			for idx, expected := range testData.expected {
+				execReq := execReqs[idx]
+				assert.Equal(t, expected[0].GetId(), execReq.GetId())
+				assert.Equal(t, expected[0].GetStart(), execReq.GetStart())
+				assert.Equal(t, expected[len(expected)-1].GetEnd(), execReq.GetEnd())
+				assert.Equal(t, int32(len(testData.expected)), execReq.GetHints().GetTotalQueries())
+				assert.Equal(t, expected, coalesced[execReq.GetId()])
+			}
+		})
+	}
+}
+
 func TestSplitRequests_prepareDownstreamRequests(t *testing.T) {
 	tests := map[string]struct {
 		input    splitRequests
@@ -15,6 +15,7 @@ import (
 	"github.com/prometheus/prometheus/promql/parser"
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
 	"github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/storage/lazyquery"
@@ -35,6 +36,9 @@ type splitInstantQueryByIntervalMiddleware struct {
 
 	engine *promql.Engine
 
+	// partialQueries runs the split partial queries, caching their results if the results cache is enabled.
+	partialQueries Handler
+
 	metrics instantQuerySplittingMetrics
 }
 
@@ -80,21 +84,34 @@ func newInstantQuerySplittingMetrics(registerer prometheus.Registerer) instantQu
 	return m
 }
 
-// newSplitInstantQueryByIntervalMiddleware makes a new splitInstantQueryByIntervalMiddleware.
+// newSplitInstantQueryByIntervalMiddleware makes a new splitInstantQueryByIntervalMiddleware. The results of the
+// split partial queries are cached in the given cache, if not nil.
 func newSplitInstantQueryByIntervalMiddleware(
 	limits Limits,
 	logger log.Logger,
 	engine *promql.Engine,
+	c cache.Cache,
 	registerer prometheus.Registerer) Middleware {
 	metrics := newInstantQuerySplittingMetrics(registerer)
 
+	var cacheMetrics *splitInstantQueryResultsCacheMetrics
+	if c != nil {
+		cacheMetrics = newSplitInstantQueryResultsCacheMetrics(registerer)
+	}
+
 	return MiddlewareFunc(func(next Handler) Handler {
+		partialQueries := next
+		if c != nil {
+			partialQueries = newSplitInstantQueryResultsCache(next, limits, c, logger, cacheMetrics)
+		}
+
 		return &splitInstantQueryByIntervalMiddleware{
-			next:    next,
-			limits:  limits,
-			logger:  logger,
-			engine:  engine,
-			metrics: metrics,
+			next:           next,
+			limits:         limits,
+			logger:         logger,
+			engine:         engine,
+			partialQueries: partialQueries,
+			metrics:        metrics,
 		}
 	})
 }
@@ -167,7 +184,7 @@ func (s *splitInstantQueryByIntervalMiddleware) Do(ctx context.Context, req Requ
 	s.metrics.splitQueriesPerQuery.Observe(float64(mapperStats.GetSplitQueries()))
 
 	req = req.WithQuery(instantSplitQuery.String()).WithHints(hints)
-	shardedQueryable := newShardedQueryable(req, s.next)
+	shardedQueryable := newShardedQueryable(req, s.partialQueries)
 
 	qry, err := newQuery(req, s.engine, lazyquery.NewLazyQueryable(shardedQueryable))
 	if err != nil {
@@ -187,7 +204,8 @@ func (s *splitInstantQueryByIntervalMiddleware) Do(ctx context.Context, req Requ
 			ResultType: string(res.Value.Type()),
 			Result:     extracted,
 		},
-		Headers: shardedQueryable.getResponseHeaders(),
+		Headers:  shardedQueryable.getResponseHeaders(),
+		Warnings: promqlWarningsToStrings(res.Warnings),
 	}, nil
 }
 
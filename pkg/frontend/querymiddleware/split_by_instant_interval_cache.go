@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+type splitInstantQueryResultsCacheMetrics struct {
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+func newSplitInstantQueryResultsCacheMetrics(reg prometheus.Registerer) *splitInstantQueryResultsCacheMetrics {
+	return &splitInstantQueryResultsCacheMetrics{
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_split_results_cache_requests_total",
+			Help: "Total number of split partial queries of the instant queries looked up in the results cache.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_split_results_cache_hits_total",
+			Help: "Total number of split partial queries of the instant queries whose result has been returned from the results cache.",
+		}),
+	}
+}
+
+// splitInstantQueryResultsCache is a Handler caching the results of the partial queries of the instant queries
+// split by interval. The partial queries of a query only differ by the offset of their selectors, so each one is
+// cached by the time range it covers: the offset is removed, and the evaluation time is moved back by the offset.
+// This way, a cached partial result is reused by the queries evaluated at the same time, and by the queries
+// evaluated at a time shifted by a multiple of the split interval, like the reports over the last month run
+// every day. Only the partial queries older than the max cache freshness are cached.
+type splitInstantQueryResultsCache struct {
+	next    Handler
+	limits  Limits
+	cache   cache.Cache
+	logger  log.Logger
+	metrics *splitInstantQueryResultsCacheMetrics
+}
+
+// newSplitInstantQueryResultsCache makes a new splitInstantQueryResultsCache.
+func newSplitInstantQueryResultsCache(next Handler, limits Limits, c cache.Cache, logger log.Logger, metrics *splitInstantQueryResultsCacheMetrics) Handler {
+	return &splitInstantQueryResultsCache{
+		next:    next,
+		limits:  limits,
+		cache:   c,
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+func (c *splitInstantQueryResultsCache) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	if req.GetOptions().CacheDisabled {
+		return c.next.Do(ctx, req)
+	}
+
+	normalized, ok := normalizeSplitInstantQuery(req)
+	if !ok {
+		return c.next.Do(ctx, req)
+	}
+
+	maxCacheFreshness := maxCacheFreshnessPerTenant(tenantIDs, c.limits)
+	if normalized.GetStart() > int64(model.Now().Add(-maxCacheFreshness)) {
+		return c.next.Do(ctx, req)
+	}
+
+	key := splitInstantQueryResultsCacheKey(resultsCacheTenantKey(tenantIDs, c.limits), normalized)
+
+	c.metrics.requests.Inc()
+	if !isResultsCacheBypassed(ctx) {
+		if cached, ok := c.fetch(ctx, key); ok {
+			c.metrics.hits.Inc()
+			resultsCacheStatusFromContext(ctx).recordHit()
+			return withEvaluationTime(cached, req.GetStart()), nil
+		}
+	}
+	resultsCacheStatusFromContext(ctx).recordMiss()
+
+	resp, err := c.next.Do(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	promResp, ok := resp.(*PrometheusResponse)
+	if !ok {
+		return resp, nil
+	}
+	if promResp.Status == statusSuccess && isResponseCachable(resp, c.logger) {
+		c.store(ctx, key, normalized, &PrometheusResponse{
+			Status: promResp.Status,
+			Data:   promResp.Data,
+		})
+	}
+
+	// The partial query has been run at the normalized evaluation time, while its result is expected at the
+	// evaluation time of the original query.
+	return withEvaluationTime(promResp, req.GetStart()), nil
+}
+
+// fetch returns the cached response for the given key, if any.
+func (c *splitInstantQueryResultsCache) fetch(ctx context.Context, key string) (*PrometheusResponse, bool) {
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, c.logger, "splitInstantQueryResultsCache.fetch")
+	defer spanLog.Finish()
+
+	hashedKey := cacheHashKey(key)
+	data, ok := c.cache.Fetch(ctx, []string{hashedKey})[hashedKey]
+	if !ok {
+		return nil, false
+	}
+
+	var cached CachedResponse
+	if err := proto.Unmarshal(data, &cached); err != nil {
+		level.Error(spanLog).Log("msg", "error unmarshalling cached response", "err", err)
+		return nil, false
+	}
+
+	// Ensure there's no hashed key collision.
+	if cached.Key != key || len(cached.Extents) != 1 {
+		return nil, false
+	}
+
+	resp, err := cached.Extents[0].toResponse()
+	if err != nil {
+		level.Error(spanLog).Log("msg", "error decoding cached response", "err", err)
+		return nil, false
+	}
+
+	promResp, ok := resp.(*PrometheusResponse)
+	return promResp, ok
+}
+
+// store stores the response for the given key in the cache.
+func (c *splitInstantQueryResultsCache) store(ctx context.Context, key string, req Request, resp *PrometheusResponse) {
+	extent, err := toExtent(ctx, req, resp)
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error encoding the response to cache", "err", err)
+		return
+	}
+
+	buf, err := proto.Marshal(&CachedResponse{
+		Key:     key,
+		Extents: []Extent{extent},
+	})
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error marshalling the response to cache", "err", err)
+		return
+	}
+
+	c.cache.Store(ctx, map[string][]byte{cacheHashKey(key): buf}, resultsCacheTTL)
+}
+
+// normalizeSplitInstantQuery returns the partial query without the offset of its selectors, evaluated at the
+// evaluation time of the input query moved back by the offset. Returns false if the partial query can't be
+// normalized, because its selectors don't share the same offset, or because its result depends on the
+// evaluation time.
+func normalizeSplitInstantQuery(req Request) (Request, bool) {
+	expr, err := parser.ParseExpr(req.GetQuery())
+	if err != nil {
+		return nil, false
+	}
+
+	var (
+		offset    time.Duration
+		selectors int
+		ok        = true
+	)
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			if n.Timestamp != nil || n.StartOrEnd != 0 || (selectors > 0 && n.OriginalOffset != offset) {
+				ok = false
+			}
+			offset = n.OriginalOffset
+			selectors++
+		case *parser.SubqueryExpr:
+			ok = false
+		case *parser.Call:
+			if n.Func.Name == "time" || n.Func.Name == "timestamp" {
+				ok = false
+			}
+		}
+		return nil
+	})
+	if !ok || selectors == 0 {
+		return nil, false
+	}
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if selector, isSelector := node.(*parser.VectorSelector); isSelector {
+			selector.OriginalOffset = 0
+		}
+		return nil
+	})
+
+	ts := req.GetStart() - offset.Milliseconds()
+	return req.WithQuery(expr.String()).WithStartEnd(ts, ts), true
+}
+
+// withEvaluationTime returns a copy of the response whose samples are at the given evaluation time.
+func withEvaluationTime(resp *PrometheusResponse, ts int64) *PrometheusResponse {
+	if resp.Data == nil {
+		return resp
+	}
+
+	result := make([]SampleStream, 0, len(resp.Data.Result))
+	for _, stream := range resp.Data.Result {
+		samples := make([]mimirpb.Sample, 0, len(stream.Samples))
+		for _, sample := range stream.Samples {
+			samples = append(samples, mimirpb.Sample{TimestampMs: ts, Value: sample.Value})
+		}
+		result = append(result, SampleStream{Labels: stream.Labels, Samples: samples})
+	}
+
+	return &PrometheusResponse{
+		Status:    resp.Status,
+		Data:      &PrometheusData{ResultType: resp.Data.ResultType, Result: result},
+		ErrorType: resp.ErrorType,
+		Error:     resp.Error,
+		Headers:   resp.Headers,
+		Warnings:  resp.Warnings,
+	}
+}
+
+// splitInstantQueryResultsCacheKey returns the cache key of the normalized partial query.
+func splitInstantQueryResultsCacheKey(tenantID string, req Request) string {
+	return fmt.Sprintf("instant-split:%s:%s:%d", tenantID, req.GetQuery(), req.GetStart())
+}
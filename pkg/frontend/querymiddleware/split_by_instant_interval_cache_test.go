@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/cache"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestSplitInstantQueryByIntervalMiddleware_ShouldCacheThePartialQueries(t *testing.T) {
+	start := time.Date(2020, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	var series []*promql.StorageSeries
+	for i := 0; i < 10; i++ {
+		series = append(series, newSeries(newTestCounterLabels(i), start.Add(-10*time.Minute), start.Add(10*time.Minute), 10*time.Second, factor(float64(i))))
+	}
+	downstream := &downstreamHandler{engine: newEngine(), queryable: storageSeriesQueryable(series)}
+
+	var (
+		downstreamMx   sync.Mutex
+		downstreamReqs []Request
+	)
+	countingDownstream := HandlerFunc(func(ctx context.Context, req Request) (Response, error) {
+		downstreamMx.Lock()
+		downstreamReqs = append(downstreamReqs, req)
+		downstreamMx.Unlock()
+		return downstream.Do(ctx, req)
+	})
+
+	reg := prometheus.NewPedanticRegistry()
+	limits := mockLimits{splitInstantQueriesInterval: time.Minute}
+	splitting := newSplitInstantQueryByIntervalMiddleware(limits, log.NewNopLogger(), newEngine(), cache.NewMockCache(), reg).Wrap(countingDownstream)
+
+	_, ctx := stats.ContextWithEmptyStats(context.Background())
+	ctx = user.InjectOrgID(ctx, "user-1")
+
+	// The second query is evaluated one split interval later, so it shares 2 of its 3 partial queries with the first one.
+	for i, ts := range []time.Time{start, start.Add(time.Minute)} {
+		req := &PrometheusInstantQueryRequest{Path: "/query", Time: util.TimeToMillis(ts), Query: `sum by (group_1) (increase(metric_counter[3m]))`}
+
+		expected, err := downstream.Do(ctx, req)
+		require.NoError(t, err)
+		require.NotEmpty(t, expected.(*PrometheusResponse).Data.Result)
+
+		actual, err := splitting.Do(ctx, req)
+		require.NoError(t, err)
+
+		sort.Sort(byLabels(expected.(*PrometheusResponse).Data.Result))
+		sort.Sort(byLabels(actual.(*PrometheusResponse).Data.Result))
+		approximatelyEquals(t, expected.(*PrometheusResponse), actual.(*PrometheusResponse))
+
+		if i == 0 {
+			assert.Len(t, downstreamReqs, 3)
+		}
+	}
+
+	assert.Len(t, downstreamReqs, 4)
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_frontend_instant_query_split_results_cache_requests_total Total number of split partial queries of the instant queries looked up in the results cache.
+		# TYPE cortex_frontend_instant_query_split_results_cache_requests_total counter
+		cortex_frontend_instant_query_split_results_cache_requests_total 6
+		# HELP cortex_frontend_instant_query_split_results_cache_hits_total Total number of split partial queries of the instant queries whose result has been returned from the results cache.
+		# TYPE cortex_frontend_instant_query_split_results_cache_hits_total counter
+		cortex_frontend_instant_query_split_results_cache_hits_total 2
+	`), "cortex_frontend_instant_query_split_results_cache_requests_total", "cortex_frontend_instant_query_split_results_cache_hits_total"))
+
+	// The partial queries are run downstream without offset, at the evaluation time of the time range they cover.
+	for _, req := range downstreamReqs {
+		assert.Equal(t, `sum by(group_1) (increase(metric_counter[1m]))`, req.GetQuery())
+	}
+}
+
+func TestSplitInstantQueryResultsCache(t *testing.T) {
+	now := time.Now()
+	resp := &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{ResultType: "vector", Result: []SampleStream{{
+			Labels:  []mimirpb.LabelAdapter{{Name: "group_1", Value: "0"}},
+			Samples: []mimirpb.Sample{{TimestampMs: 0, Value: 1}},
+		}}},
+	}
+
+	tests := map[string]struct {
+		query             string
+		ts                time.Time
+		cacheDisabled     bool
+		maxCacheFreshness time.Duration
+		expectedQuery     string
+		expectedTime      time.Time
+		expectedCached    bool
+	}{
+		"should cache the partial query without its offset": {
+			query:          `sum(increase(metric_counter[1h] offset 2h))`,
+			ts:             now.Add(-time.Hour),
+			expectedQuery:  `sum(increase(metric_counter[1h]))`,
+			expectedTime:   now.Add(-3 * time.Hour),
+			expectedCached: true,
+		},
+		"should cache the partial query without offset": {
+			query:          `sum(increase(metric_counter[1h]))`,
+			ts:             now.Add(-time.Hour),
+			expectedQuery:  `sum(increase(metric_counter[1h]))`,
+			expectedTime:   now.Add(-time.Hour),
+			expectedCached: true,
+		},
+		"should not cache the partial query more recent than the max cache freshness": {
+			query:             `sum(increase(metric_counter[1h] offset 2h))`,
+			ts:                now,
+			maxCacheFreshness: 3 * time.Hour,
+			expectedQuery:     `sum(increase(metric_counter[1h] offset 2h))`,
+			expectedTime:      now,
+		},
+		"should not cache the partial query whose selectors have different offsets": {
+			query:         `sum(increase(metric_counter[1h] offset 2h)) + sum(increase(metric_counter[1h]))`,
+			ts:            now.Add(-time.Hour),
+			expectedQuery: `sum(increase(metric_counter[1h] offset 2h)) + sum(increase(metric_counter[1h]))`,
+			expectedTime:  now.Add(-time.Hour),
+		},
+		"should not cache the partial query with the @ modifier": {
+			query:         `sum(increase(metric_counter[1h] @ 100))`,
+			ts:            now.Add(-time.Hour),
+			expectedQuery: `sum(increase(metric_counter[1h] @ 100))`,
+			expectedTime:  now.Add(-time.Hour),
+		},
+		"should not cache the partial query if the cache is disabled for the request": {
+			query:         `sum(increase(metric_counter[1h] offset 2h))`,
+			ts:            now.Add(-time.Hour),
+			cacheDisabled: true,
+			expectedQuery: `sum(increase(metric_counter[1h] offset 2h))`,
+			expectedTime:  now.Add(-time.Hour),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var downstreamReqs []Request
+			downstream := HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+				downstreamReqs = append(downstreamReqs, req)
+				return resp, nil
+			})
+
+			metrics := newSplitInstantQueryResultsCacheMetrics(nil)
+			handler := newSplitInstantQueryResultsCache(downstream, mockLimits{maxCacheFreshness: testData.maxCacheFreshness}, cache.NewMockCache(), log.NewNopLogger(), metrics)
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+
+			for i := 0; i < 2; i++ {
+				ts := util.TimeToMillis(testData.ts)
+				res, err := handler.Do(ctx, &PrometheusInstantQueryRequest{Query: testData.query, Time: ts, Options: Options{CacheDisabled: testData.cacheDisabled}})
+				require.NoError(t, err)
+
+				// The cached result is returned at the evaluation time of the request.
+				if testData.expectedCached {
+					require.Len(t, res.(*PrometheusResponse).Data.Result, 1)
+					assert.Equal(t, []mimirpb.Sample{{TimestampMs: ts, Value: 1}}, res.(*PrometheusResponse).Data.Result[0].Samples)
+				} else {
+					assert.Equal(t, resp, res)
+				}
+			}
+
+			expectedDownstreamCalls := 2
+			if testData.expectedCached {
+				expectedDownstreamCalls = 1
+			}
+			require.Len(t, downstreamReqs, expectedDownstreamCalls)
+			assert.Equal(t, testData.expectedQuery, downstreamReqs[0].GetQuery())
+			assert.Equal(t, util.TimeToMillis(testData.expectedTime), downstreamReqs[0].GetStart())
+			assert.Equal(t, float64(2-expectedDownstreamCalls), testutil.ToFloat64(metrics.hits))
+
+			// The downstream response is left untouched.
+			assert.Equal(t, int64(0), resp.Data.Result[0].Samples[0].TimestampMs)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queryrecorder
+
+import (
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+type Config struct {
+	Enabled                     bool          `yaml:"enabled" category:"experimental"`
+	SampleRate                  float64       `yaml:"sample_rate" category:"experimental"`
+	FlushPeriod                 time.Duration `yaml:"flush_period" category:"experimental"`
+	MaxBufferedQueriesPerTenant int           `yaml:"max_buffered_queries_per_tenant" category:"experimental"`
+	Storage                     bucket.Config `yaml:"storage"`
+}
+
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, "query-frontend.query-recorder.enabled", false, "Enables the recording of a sample of the queries received by the query-frontend to the query recorder storage. The recorded queries can be replayed against another cluster with the query-replay tool.")
+	f.Float64Var(&c.SampleRate, "query-frontend.query-recorder.sample-rate", 0.01, "Fraction of the queries recorded, between 0 and 1.")
+	f.DurationVar(&c.FlushPeriod, "query-frontend.query-recorder.flush-period", time.Minute, "How frequently the recorded queries buffered in memory are written to the query recorder storage.")
+	f.IntVar(&c.MaxBufferedQueriesPerTenant, "query-frontend.query-recorder.max-buffered-queries-per-tenant", 10000, "Maximum number of recorded queries buffered in memory for each tenant between two flushes. Queries exceeding the limit are not recorded.")
+	c.Storage.RegisterFlagsWithPrefixAndDefaultDirectory("query-frontend.query-recorder.storage.", "query-recorder", f)
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return errors.New("query-frontend.query-recorder.sample-rate must be greater than 0 and less than or equal to 1")
+	}
+	if c.FlushPeriod <= 0 {
+		return errors.New("query-frontend.query-recorder.flush-period must be greater than 0")
+	}
+	if c.MaxBufferedQueriesPerTenant < 1 {
+		return errors.New("query-frontend.query-recorder.max-buffered-queries-per-tenant must be greater than 0")
+	}
+	return c.Storage.Validate()
+}
@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queryrecorder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	mathrand "math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// ObjectExtension is the extension of the objects written to the query recorder storage. Each object
+// contains the recorded queries of a single tenant, one JSON-encoded Query per line.
+const ObjectExtension = ".ndjson"
+
+// timeParams are the query parameters excluded from the fingerprint, because they change between
+// the executions of the same query.
+var timeParams = map[string]struct{}{"start": {}, "end": {}, "time": {}}
+
+type Recorder interface {
+	services.Service
+	Record(userID, method, path string, params url.Values, responseTime time.Duration, statusCode int)
+}
+
+// Query is a recorded query, as written to the query recorder storage.
+type Query struct {
+	Fingerprint         string              `json:"fingerprint"`
+	Method              string              `json:"method"`
+	Path                string              `json:"path"`
+	Params              map[string][]string `json:"params,omitempty"`
+	RecordedAt          int64               `json:"recorded_at_ms"`
+	ResponseTimeSeconds float64             `json:"response_time_seconds"`
+	StatusCode          int                 `json:"status_code"`
+}
+
+// Fingerprint returns the fingerprint of the query to the path with the params, which is the same for
+// the executions of the query over different time ranges.
+func Fingerprint(path string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if _, ok := timeParams[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(path))
+	for _, k := range keys {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(k))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(strings.Join(params[k], "\x00")))
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+type recorder struct {
+	services.Service
+
+	cfg    Config
+	bucket objstore.Bucket
+	log    log.Logger
+
+	mtx     sync.Mutex
+	rnd     *mathrand.Rand
+	queries map[string][]Query
+
+	queriesRecordedTotal prometheus.Counter
+	queriesDroppedTotal  prometheus.Counter
+	flushFailuresTotal   prometheus.Counter
+}
+
+// NewRecorder returns a new query recorder, if the query recorder is disabled it returns nil.
+func NewRecorder(cfg Config, reg prometheus.Registerer, log log.Logger) (Recorder, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	bkt, err := bucket.NewClient(context.Background(), cfg.Storage, "query-frontend-query-recorder", log, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create query recorder storage bucket client")
+	}
+
+	return newRecorder(cfg, bkt, reg, log), nil
+}
+
+func newRecorder(cfg Config, bkt objstore.Bucket, reg prometheus.Registerer, log log.Logger) *recorder {
+	r := &recorder{
+		cfg:     cfg,
+		bucket:  bkt,
+		log:     log,
+		rnd:     mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+		queries: map[string][]Query{},
+
+		queriesRecordedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "query_frontend_recorded_queries_total",
+			Help:      "The total number of sampled queries the query-frontend wrote to the query recorder storage.",
+		}),
+		queriesDroppedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "query_frontend_recorded_queries_dropped_total",
+			Help:      "The total number of sampled queries the query-frontend didn't write to the query recorder storage because the tenant's buffer was full or the write failed.",
+		}),
+		flushFailuresTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "query_frontend_query_recorder_flush_failures_total",
+			Help:      "The total number of failures writing recorded queries to the query recorder storage.",
+		}),
+	}
+
+	r.Service = services.NewTimerService(cfg.FlushPeriod, nil, r.iteration, r.stop)
+	return r
+}
+
+// Record buffers the query, if sampled, to be written to the query recorder storage at the next flush.
+// The params are copied, so they can be reused once the function returns.
+func (r *recorder) Record(userID, method, path string, params url.Values, responseTime time.Duration, statusCode int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.rnd.Float64() >= r.cfg.SampleRate {
+		return
+	}
+	if len(r.queries[userID]) >= r.cfg.MaxBufferedQueriesPerTenant {
+		r.queriesDroppedTotal.Inc()
+		return
+	}
+
+	copied := make(map[string][]string, len(params))
+	for k, v := range params {
+		copied[k] = append([]string(nil), v...)
+	}
+	r.queries[userID] = append(r.queries[userID], Query{
+		Fingerprint:         Fingerprint(path, params),
+		Method:              method,
+		Path:                path,
+		Params:              copied,
+		RecordedAt:          time.Now().UnixMilli(),
+		ResponseTimeSeconds: responseTime.Seconds(),
+		StatusCode:          statusCode,
+	})
+}
+
+func (r *recorder) iteration(ctx context.Context) error {
+	r.flush(ctx)
+	return nil
+}
+
+func (r *recorder) stop(_ error) error {
+	// Write the queries still buffered before shutting down.
+	r.flush(context.Background())
+	return nil
+}
+
+func (r *recorder) flush(ctx context.Context) {
+	r.mtx.Lock()
+	toFlush := r.queries
+	r.queries = map[string][]Query{}
+	r.mtx.Unlock()
+
+	for userID, queries := range toFlush {
+		if err := r.upload(ctx, userID, queries); err != nil {
+			level.Warn(r.log).Log("msg", "failed to write recorded queries to the query recorder storage", "user", userID, "queries", len(queries), "err", err)
+			r.flushFailuresTotal.Inc()
+			r.queriesDroppedTotal.Add(float64(len(queries)))
+			continue
+		}
+		r.queriesRecordedTotal.Add(float64(len(queries)))
+	}
+}
+
+func (r *recorder) upload(ctx context.Context, userID string, queries []Query) error {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	for _, q := range queries {
+		if err := enc.Encode(q); err != nil {
+			return err
+		}
+	}
+
+	// The object name is a ULID, so that objects are sorted by time and never clash between query-frontends.
+	name := ulid.MustNew(ulid.Now(), rand.Reader).String() + ObjectExtension
+	return bucket.NewUserBucketClient(userID, r.bucket, nil).Upload(ctx, name, &buf)
+}
+
+// ReadQueries calls f, in order of recording, for each query of the tenant recorded between from and to,
+// included. A zero from or to doesn't limit the queries read.
+func ReadQueries(ctx context.Context, bkt objstore.Bucket, userID string, from, to time.Time, f func(Query) error) error {
+	fromMs, toMs := int64(math.MinInt64), int64(math.MaxInt64)
+	if !from.IsZero() {
+		fromMs = from.UnixMilli()
+	}
+	if !to.IsZero() {
+		toMs = to.UnixMilli()
+	}
+
+	userBkt := bucket.NewUserBucketClient(userID, bkt, nil)
+
+	var ids []ulid.ULID
+	err := userBkt.Iter(ctx, "", func(name string) error {
+		id, err := ulid.Parse(strings.TrimSuffix(name, ObjectExtension))
+		if err != nil || !strings.HasSuffix(name, ObjectExtension) {
+			return nil
+		}
+		// The objects are written after the queries they contain, so the older ones have no query in the range.
+		if int64(id.Time()) >= fromMs {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "list recorded queries")
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+
+	var queries []Query
+	for _, id := range ids {
+		if err := readObject(ctx, userBkt, id.String()+ObjectExtension, func(q Query) {
+			if q.RecordedAt >= fromMs && q.RecordedAt <= toMs {
+				queries = append(queries, q)
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The objects written by different query-frontends overlap in time.
+	sort.SliceStable(queries, func(i, j int) bool { return queries[i].RecordedAt < queries[j].RecordedAt })
+	for _, q := range queries {
+		if err := f(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readObject(ctx context.Context, bkt objstore.BucketReader, name string, f func(Query)) error {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "read recorded queries %s", name)
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var q Query
+		if err := json.Unmarshal(scanner.Bytes(), &q); err != nil {
+			return errors.Wrapf(err, "decode recorded query of %s", name)
+		}
+		f(q)
+	}
+	return errors.Wrapf(scanner.Err(), "read recorded queries %s", name)
+}
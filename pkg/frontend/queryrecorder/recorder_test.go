@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queryrecorder
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+func TestRecorder(t *testing.T) {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	cfg.Enabled = true
+	cfg.SampleRate = 1
+	cfg.FlushPeriod = time.Hour
+	cfg.MaxBufferedQueriesPerTenant = 2
+
+	bkt := objstore.NewInMemBucket()
+	reg := prometheus.NewPedanticRegistry()
+	r := newRecorder(cfg, bkt, reg, log.NewNopLogger())
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), r))
+
+	params := url.Values{"query": []string{"up"}, "start": []string{"1"}, "end": []string{"2"}, "step": []string{"1"}}
+	r.Record("user-1", "GET", "/prometheus/api/v1/query_range", params, time.Second, 200)
+	r.Record("user-1", "POST", "/prometheus/api/v1/query", url.Values{"query": []string{"sum(up)"}}, 2*time.Second, 422)
+	r.Record("user-1", "GET", "/prometheus/api/v1/query", url.Values{"query": []string{"dropped"}}, time.Second, 200)
+	r.Record("user-2", "GET", "/prometheus/api/v1/labels", nil, time.Second, 200)
+
+	// The params are copied.
+	params.Set("query", "changed")
+
+	// The buffered queries are written when stopping.
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), r))
+
+	readQueries := func(userID string, from, to time.Time) []Query {
+		var out []Query
+		require.NoError(t, ReadQueries(context.Background(), bkt, userID, from, to, func(q Query) error {
+			out = append(out, q)
+			return nil
+		}))
+		return out
+	}
+
+	user1 := readQueries("user-1", time.Time{}, time.Time{})
+	require.Len(t, user1, 2)
+	assert.Equal(t, "GET", user1[0].Method)
+	assert.Equal(t, "/prometheus/api/v1/query_range", user1[0].Path)
+	assert.Equal(t, map[string][]string{"query": {"up"}, "start": {"1"}, "end": {"2"}, "step": {"1"}}, user1[0].Params)
+	assert.Equal(t, 1.0, user1[0].ResponseTimeSeconds)
+	assert.Equal(t, 200, user1[0].StatusCode)
+	assert.Equal(t, Fingerprint("/prometheus/api/v1/query_range", url.Values{"query": []string{"up"}, "step": []string{"1"}}), user1[0].Fingerprint)
+	assert.Equal(t, "POST", user1[1].Method)
+	assert.Equal(t, 422, user1[1].StatusCode)
+
+	require.Len(t, readQueries("user-2", time.Time{}, time.Time{}), 1)
+	assert.Empty(t, readQueries("user-1", time.Now().Add(time.Hour), time.Time{}))
+	assert.Empty(t, readQueries("user-1", time.Time{}, time.Now().Add(-time.Hour)))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_query_frontend_recorded_queries_total The total number of sampled queries the query-frontend wrote to the query recorder storage.
+		# TYPE cortex_query_frontend_recorded_queries_total counter
+		cortex_query_frontend_recorded_queries_total 3
+		# HELP cortex_query_frontend_recorded_queries_dropped_total The total number of sampled queries the query-frontend didn't write to the query recorder storage because the tenant's buffer was full or the write failed.
+		# TYPE cortex_query_frontend_recorded_queries_dropped_total counter
+		cortex_query_frontend_recorded_queries_dropped_total 1
+		# HELP cortex_query_frontend_query_recorder_flush_failures_total The total number of failures writing recorded queries to the query recorder storage.
+		# TYPE cortex_query_frontend_query_recorder_flush_failures_total counter
+		cortex_query_frontend_query_recorder_flush_failures_total 0
+	`)))
+}
+
+func TestRecorder_ShouldSampleQueries(t *testing.T) {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	cfg.Enabled = true
+	cfg.SampleRate = 0.1
+	cfg.MaxBufferedQueriesPerTenant = 10000
+
+	r := newRecorder(cfg, objstore.NewInMemBucket(), nil, log.NewNopLogger())
+	for i := 0; i < 10000; i++ {
+		r.Record("user-1", "GET", "/api/v1/query", nil, time.Second, 200)
+	}
+	assert.InDelta(t, 1000, len(r.queries["user-1"]), 200)
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint("/api/v1/query_range", url.Values{"query": []string{"up"}, "step": []string{"15"}, "start": []string{"1"}, "end": []string{"2"}})
+
+	// The time range doesn't change the fingerprint.
+	assert.Equal(t, fp, Fingerprint("/api/v1/query_range", url.Values{"query": []string{"up"}, "step": []string{"15"}, "start": []string{"10"}, "end": []string{"20"}}))
+
+	assert.NotEqual(t, fp, Fingerprint("/api/v1/query_range", url.Values{"query": []string{"up"}, "step": []string{"30"}}))
+	assert.NotEqual(t, fp, Fingerprint("/api/v1/query_range", url.Values{"query": []string{"down"}, "step": []string{"15"}}))
+	assert.NotEqual(t, fp, Fingerprint("/api/v1/query", url.Values{"query": []string{"up"}, "step": []string{"15"}}))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup    func(cfg *Config)
+		expected string
+	}{
+		"should pass with the default config": {
+			setup: func(cfg *Config) {},
+		},
+		"should pass with the recorder enabled": {
+			setup: func(cfg *Config) { cfg.Enabled = true },
+		},
+		"should fail with a sample rate of 0": {
+			setup:    func(cfg *Config) { cfg.Enabled = true; cfg.SampleRate = 0 },
+			expected: "sample-rate must be greater than 0",
+		},
+		"should fail with a sample rate greater than 1": {
+			setup:    func(cfg *Config) { cfg.Enabled = true; cfg.SampleRate = 1.5 },
+			expected: "sample-rate must be greater than 0",
+		},
+		"should fail with no flush period": {
+			setup:    func(cfg *Config) { cfg.Enabled = true; cfg.FlushPeriod = 0 },
+			expected: "flush-period must be greater than 0",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{}
+			flagext.DefaultValues(&cfg)
+			tc.setup(&cfg)
+
+			err := cfg.Validate()
+			if tc.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expected)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ package transport
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -23,19 +25,30 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/httpgrpc/server"
+	"google.golang.org/grpc/peer"
 
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/tenant"
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/frontend/queryaudit"
+	"github.com/grafana/mimir/pkg/frontend/queryrecorder"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
 	// StatusClientClosedRequest is the status code for when a client request cancellation of an http request
 	StatusClientClosedRequest = 499
 	ServiceTimingHeaderName   = "Server-Timing"
+
+	// QueryStatsRequestHeaderName is the request header enabling the query statistics in the response, when set to true.
+	QueryStatsRequestHeaderName = "X-Mimir-Return-Query-Stats"
+	// QueryStatsHeaderName is the response header containing the JSON-encoded query statistics.
+	QueryStatsHeaderName = "X-Mimir-Query-Stats"
 )
 
 var (
@@ -46,15 +59,24 @@ var (
 
 // Config for a Handler.
 type HandlerConfig struct {
-	LogQueriesLongerThan time.Duration `yaml:"log_queries_longer_than"`
-	MaxBodySize          int64         `yaml:"max_body_size" category:"advanced"`
-	QueryStatsEnabled    bool          `yaml:"query_stats_enabled" category:"advanced"`
+	LogQueriesLongerThan time.Duration  `yaml:"log_queries_longer_than"`
+	MaxBodySize          int64          `yaml:"max_body_size" category:"advanced"`
+	QueryStatsEnabled    bool           `yaml:"query_stats_enabled" category:"advanced"`
+	RulerQuerySourceKey  flagext.Secret `yaml:"ruler_query_source_key" category:"experimental"`
 }
 
 func (cfg *HandlerConfig) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.LogQueriesLongerThan, "query-frontend.log-queries-longer-than", 0, "Log queries that are slower than the specified duration. Set to 0 to disable. Set to < 0 to enable on all queries.")
 	f.Int64Var(&cfg.MaxBodySize, "query-frontend.max-body-size", 10*1024*1024, "Max body size for downstream prometheus.")
-	f.BoolVar(&cfg.QueryStatsEnabled, "query-frontend.query-stats-enabled", true, "False to disable query statistics tracking. When enabled, a message with some statistics is logged for every query.")
+	f.BoolVar(&cfg.QueryStatsEnabled, "query-frontend.query-stats-enabled", true, "False to disable query statistics tracking. When enabled, a message with some statistics is logged for every query, and the statistics are returned in the "+QueryStatsHeaderName+" response header of the requests with the "+QueryStatsRequestHeaderName+": true header.")
+	f.Var(&cfg.RulerQuerySourceKey, "query-frontend.ruler-query-source-key", "Key the ruler must send along with the rule evaluations, configured with -ruler.query-frontend.query-source-key, for the query-frontend and the query-scheduler to apply the limits of the rule evaluations to them. When empty, the limits of the user queries are applied to all the queries.")
+}
+
+// Limits are the per-tenant limits used by the Handler.
+type Limits interface {
+	// SlowQueryLogThreshold returns the duration above which the tenant's queries are logged as slow.
+	// 0 to use the HandlerConfig.LogQueriesLongerThan.
+	SlowQueryLogThreshold(userID string) time.Duration
 }
 
 // Handler accepts queries and forwards them to RoundTripper. It can log slow queries,
@@ -63,6 +85,9 @@ type Handler struct {
 	cfg          HandlerConfig
 	log          log.Logger
 	roundTripper http.RoundTripper
+	limits       Limits
+	recorder     queryrecorder.Recorder
+	auditLog     queryaudit.AuditLog
 
 	// Metrics.
 	querySeconds *prometheus.CounterVec
@@ -72,12 +97,16 @@ type Handler struct {
 	activeUsers  *util.ActiveUsersCleanupService
 }
 
-// NewHandler creates a new frontend handler.
-func NewHandler(cfg HandlerConfig, roundTripper http.RoundTripper, log log.Logger, reg prometheus.Registerer) http.Handler {
+// NewHandler creates a new frontend handler. The recorder, if not nil, records a sample of the queries.
+// The audit log, if not nil, writes an audit log entry for every query.
+func NewHandler(cfg HandlerConfig, roundTripper http.RoundTripper, limits Limits, recorder queryrecorder.Recorder, auditLog queryaudit.AuditLog, log log.Logger, reg prometheus.Registerer) http.Handler {
 	h := &Handler{
 		cfg:          cfg,
 		log:          log,
 		roundTripper: roundTripper,
+		limits:       limits,
+		recorder:     recorder,
+		auditLog:     auditLog,
 	}
 
 	if cfg.QueryStatsEnabled {
@@ -115,15 +144,48 @@ func NewHandler(cfg HandlerConfig, roundTripper http.RoundTripper, log log.Logge
 	return h
 }
 
+// sanitizeQuerySource makes sure the query source header of the request can be trusted, since any client could
+// set it to bypass the limits of its queries, whether over the HTTP or the gRPC server. The header is only kept if
+// the request carries the query source key shared with the ruler. The key is always stripped from the request, so
+// that it isn't forwarded to the query-schedulers and the queriers.
+func sanitizeQuerySource(r *http.Request, key string) {
+	received := r.Header.Get(queue.QuerySourceKeyHeader)
+	r.Header.Del(queue.QuerySourceKeyHeader)
+
+	if key == "" || subtle.ConstantTimeCompare([]byte(received), []byte(key)) != 1 {
+		r.Header.Del(queue.QuerySourceHeader)
+	}
+}
+
+// sanitizeQueryPriority strips the query priority header of the requests received over the HTTP server, so that
+// any client can't set its queries to the high priority. Only the priorities set by the Mimir components, like the
+// ruler on the rule evaluations, are honored.
+func sanitizeQueryPriority(r *http.Request) {
+	if !isInternalRequest(r.Context()) {
+		r.Header.Del(queue.PriorityHeader)
+	}
+}
+
+// isInternalRequest returns whether the request has been received over the gRPC server, which is only
+// reachable by the Mimir components, rather than over the HTTP server.
+func isInternalRequest(ctx context.Context) bool {
+	_, ok := peer.FromContext(ctx)
+	return ok
+}
+
 func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
 		stats       *querier_stats.Stats
 		queryString url.Values
 	)
 
+	sanitizeQuerySource(r, f.cfg.RulerQuerySourceKey.String())
+	sanitizeQueryPriority(r)
+
 	// Initialise the stats in the context and make sure it's propagated
-	// down the request chain.
-	if f.cfg.QueryStatsEnabled {
+	// down the request chain. The audit log always needs the stats, to log
+	// the cost of the queries.
+	if f.cfg.QueryStatsEnabled || f.auditLog != nil {
 		var ctx context.Context
 		stats, ctx = querier_stats.ContextWithEmptyStats(r.Context())
 		r = r.WithContext(ctx)
@@ -144,6 +206,15 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		writeError(w, err)
+		if f.recorder != nil || f.auditLog != nil {
+			queryString = f.parseRequestQueryString(r, buf)
+		}
+		if f.recorder != nil {
+			f.recordQuery(r, queryString, queryResponseTime, errorStatusCode(err))
+		}
+		if f.auditLog != nil {
+			f.auditLog.Log(r, queryString, queryResponseTime, errorStatusCode(err), stats)
+		}
 		return
 	}
 
@@ -154,6 +225,10 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if f.cfg.QueryStatsEnabled {
 		writeServiceTimingHeader(queryResponseTime, hs, stats)
+
+		if strings.EqualFold(r.Header.Get(QueryStatsRequestHeaderName), "true") {
+			writeQueryStatsHeader(queryResponseTime, hs, stats)
+		}
 	}
 
 	w.WriteHeader(resp.StatusCode)
@@ -161,28 +236,70 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_, _ = io.Copy(w, resp.Body)
 
 	// Check whether we should parse the query string.
-	shouldReportSlowQuery := f.cfg.LogQueriesLongerThan > 0 && queryResponseTime > f.cfg.LogQueriesLongerThan
-	if shouldReportSlowQuery || f.cfg.QueryStatsEnabled {
+	slowQueryThreshold := f.slowQueryLogThreshold(r)
+	shouldReportSlowQuery := slowQueryThreshold > 0 && queryResponseTime > slowQueryThreshold
+	if shouldReportSlowQuery || f.cfg.QueryStatsEnabled || f.recorder != nil || f.auditLog != nil {
 		queryString = f.parseRequestQueryString(r, buf)
 	}
 
 	if shouldReportSlowQuery {
-		f.reportSlowQuery(r, queryString, queryResponseTime)
+		f.reportSlowQuery(r, queryString, queryResponseTime, slowQueryThreshold, stats)
 	}
 	if f.cfg.QueryStatsEnabled {
 		f.reportQueryStats(r, queryString, queryResponseTime, stats)
 	}
+	if f.recorder != nil {
+		f.recordQuery(r, queryString, queryResponseTime, resp.StatusCode)
+	}
+	if f.auditLog != nil {
+		f.auditLog.Log(r, queryString, queryResponseTime, resp.StatusCode, stats)
+	}
 }
 
-// reportSlowQuery reports slow queries.
-func (f *Handler) reportSlowQuery(r *http.Request, queryString url.Values, queryResponseTime time.Duration) {
-	logMessage := append([]interface{}{
+// recordQuery passes the query to the recorder, which records a sample of the queries.
+func (f *Handler) recordQuery(r *http.Request, queryString url.Values, queryResponseTime time.Duration, statusCode int) {
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return
+	}
+	f.recorder.Record(tenant.JoinTenantIDs(tenantIDs), r.Method, r.URL.Path, queryString, queryResponseTime, statusCode)
+}
+
+// slowQueryLogThreshold returns the duration above which the request is logged as a slow query: the smallest
+// per-tenant threshold of the request tenants, or the global one if the tenants have no threshold.
+func (f *Handler) slowQueryLogThreshold(r *http.Request) time.Duration {
+	if f.limits == nil {
+		return f.cfg.LogQueriesLongerThan
+	}
+
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return f.cfg.LogQueriesLongerThan
+	}
+
+	if threshold := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, f.limits.SlowQueryLogThreshold); threshold > 0 {
+		return threshold
+	}
+	return f.cfg.LogQueriesLongerThan
+}
+
+// reportSlowQuery reports slow queries, with their statistics when the query stats are enabled.
+func (f *Handler) reportSlowQuery(r *http.Request, queryString url.Values, queryResponseTime, threshold time.Duration, stats *querier_stats.Stats) {
+	logMessage := []interface{}{
 		"msg", "slow query detected",
 		"method", r.Method,
 		"host", r.Host,
 		"path", r.URL.Path,
 		"time_taken", queryResponseTime.String(),
-	}, formatQueryString(queryString)...)
+		"threshold", threshold.String(),
+	}
+	if tenantIDs, err := tenant.TenantIDs(r.Context()); err == nil {
+		logMessage = append(logMessage, "user", tenant.JoinTenantIDs(tenantIDs))
+	}
+	if stats != nil {
+		logMessage = append(logMessage, formatQueryStats(stats)...)
+	}
+	logMessage = append(logMessage, formatQueryString(queryString)...)
 
 	level.Info(util_log.WithContext(r.Context(), f.log)).Log(logMessage...)
 }
@@ -213,17 +330,28 @@ func (f *Handler) reportQueryStats(r *http.Request, queryString url.Values, quer
 		"method", r.Method,
 		"path", r.URL.Path,
 		"response_time", queryResponseTime,
-		"query_wall_time_seconds", wallTime.Seconds(),
-		"fetched_series_count", numSeries,
-		"fetched_chunk_bytes", numBytes,
-		"fetched_chunks_count", numChunks,
-		"sharded_queries", stats.LoadShardedQueries(),
-		"split_queries", stats.LoadSplitQueries(),
-	}, formatQueryString(queryString)...)
+	}, formatQueryStats(stats)...)
+	logMessage = append(logMessage, formatQueryString(queryString)...)
 
 	level.Info(util_log.WithContext(r.Context(), f.log)).Log(logMessage...)
 }
 
+// formatQueryStats returns the log fields of the query statistics.
+func formatQueryStats(stats *querier_stats.Stats) []interface{} {
+	return []interface{}{
+		"query_wall_time_seconds", stats.LoadWallTime().Seconds(),
+		"queue_time_seconds", stats.LoadQueueTime().Seconds(),
+		"fetched_series_count", stats.LoadFetchedSeries(),
+		"fetched_chunk_bytes", stats.LoadFetchedChunkBytes(),
+		"fetched_chunks_count", stats.LoadFetchedChunks(),
+		"fetched_ingester_chunks_count", stats.LoadFetchedIngesterChunks(),
+		"fetched_store_gateway_chunks_count", stats.LoadFetchedStoreGatewayChunks(),
+		"fetched_samples_count", stats.LoadFetchedSamples(),
+		"sharded_queries", stats.LoadShardedQueries(),
+		"split_queries", stats.LoadSplitQueries(),
+	}
+}
+
 func (f *Handler) parseRequestQueryString(r *http.Request, bodyBuf bytes.Buffer) url.Values {
 	// Use previously buffered body.
 	r.Body = io.NopCloser(&bodyBuf)
@@ -266,15 +394,74 @@ func writeError(w http.ResponseWriter, err error) {
 	server.WriteError(w, err)
 }
 
+// errorStatusCode returns the status code of the response written by writeError for the error.
+func errorStatusCode(err error) int {
+	switch {
+	case err == context.Canceled:
+		return StatusClientClosedRequest
+	case err == context.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case util.IsRequestBodyTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	}
+	if resp, ok := apierror.HTTPResponseFromError(err); ok {
+		return int(resp.Code)
+	}
+	if resp, ok := httpgrpc.HTTPResponseFromError(err); ok {
+		return int(resp.Code)
+	}
+	return http.StatusInternalServerError
+}
+
 func writeServiceTimingHeader(queryResponseTime time.Duration, headers http.Header, stats *querier_stats.Stats) {
 	if stats != nil {
 		parts := make([]string, 0)
 		parts = append(parts, statsValue("querier_wall_time", stats.LoadWallTime()))
+		parts = append(parts, statsValue("queue_time", stats.LoadQueueTime()))
 		parts = append(parts, statsValue("response_time", queryResponseTime))
 		headers.Set(ServiceTimingHeaderName, strings.Join(parts, ", "))
 	}
 }
 
+// queryStatsResponse is the JSON-encoded content of the QueryStatsHeaderName response header.
+type queryStatsResponse struct {
+	ResponseTimeSeconds            float64 `json:"response_time_seconds"`
+	WallTimeSeconds                float64 `json:"wall_time_seconds"`
+	QueueTimeSeconds               float64 `json:"queue_time_seconds"`
+	FetchedSeriesCount             uint64  `json:"fetched_series_count"`
+	FetchedChunkBytes              uint64  `json:"fetched_chunk_bytes"`
+	FetchedChunksCount             uint64  `json:"fetched_chunks_count"`
+	FetchedIngesterChunksCount     uint64  `json:"fetched_ingester_chunks_count"`
+	FetchedStoreGatewayChunksCount uint64  `json:"fetched_store_gateway_chunks_count"`
+	FetchedSamplesCount            uint64  `json:"fetched_samples_count"`
+	ShardedQueries                 uint32  `json:"sharded_queries"`
+	SplitQueries                   uint32  `json:"split_queries"`
+}
+
+func writeQueryStatsHeader(queryResponseTime time.Duration, headers http.Header, stats *querier_stats.Stats) {
+	if stats == nil {
+		return
+	}
+
+	encoded, err := json.Marshal(queryStatsResponse{
+		ResponseTimeSeconds:            queryResponseTime.Seconds(),
+		WallTimeSeconds:                stats.LoadWallTime().Seconds(),
+		QueueTimeSeconds:               stats.LoadQueueTime().Seconds(),
+		FetchedSeriesCount:             stats.LoadFetchedSeries(),
+		FetchedChunkBytes:              stats.LoadFetchedChunkBytes(),
+		FetchedChunksCount:             stats.LoadFetchedChunks(),
+		FetchedIngesterChunksCount:     stats.LoadFetchedIngesterChunks(),
+		FetchedStoreGatewayChunksCount: stats.LoadFetchedStoreGatewayChunks(),
+		FetchedSamplesCount:            stats.LoadFetchedSamples(),
+		ShardedQueries:                 stats.LoadShardedQueries(),
+		SplitQueries:                   stats.LoadSplitQueries(),
+	})
+	if err != nil {
+		return
+	}
+	headers.Set(QueryStatsHeaderName, string(encoded))
+}
+
 func statsValue(name string, d time.Duration) string {
 	durationInMs := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
 	return name + ";dur=" + durationInMs
@@ -6,14 +6,20 @@
 package transport
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
@@ -21,6 +27,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc/peer"
+
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 )
 
 type roundTripperFunc func(*http.Request) (*http.Response, error)
@@ -73,7 +83,7 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			})
 
 			reg := prometheus.NewPedanticRegistry()
-			handler := NewHandler(tt.cfg, roundTripper, log.NewNopLogger(), reg)
+			handler := NewHandler(tt.cfg, roundTripper, nil, nil, nil, log.NewNopLogger(), reg)
 
 			ctx := user.InjectOrgID(context.Background(), "12345")
 			req := httptest.NewRequest("GET", "/", nil)
@@ -97,3 +107,433 @@ func TestHandler_ServeHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_QueryStatsHeader(t *testing.T) {
+	roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		stats := querier_stats.FromContext(req.Context())
+		stats.AddQueueTime(time.Second)
+		stats.AddFetchedSeries(10)
+		stats.AddFetchedChunks(30)
+		stats.AddFetchedIngesterChunks(10)
+		stats.AddFetchedStoreGatewayChunks(20)
+		stats.AddFetchedSamples(3600)
+		stats.AddShardedQueries(16)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	})
+
+	for _, tt := range []struct {
+		name          string
+		cfg           HandlerConfig
+		requestHeader string
+		expectedStats bool
+	}{
+		{
+			name:          "should return the query stats if requested",
+			cfg:           HandlerConfig{QueryStatsEnabled: true},
+			requestHeader: "true",
+			expectedStats: true,
+		},
+		{
+			name: "should not return the query stats if not requested",
+			cfg:  HandlerConfig{QueryStatsEnabled: true},
+		},
+		{
+			name:          "should not return the query stats if the query stats are disabled",
+			cfg:           HandlerConfig{QueryStatsEnabled: false},
+			requestHeader: "true",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(tt.cfg, roundTripper, nil, nil, nil, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			req := httptest.NewRequest("GET", "/api/v1/query?query=up", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), "12345"))
+			if tt.requestHeader != "" {
+				req.Header.Set(QueryStatsRequestHeaderName, tt.requestHeader)
+			}
+			resp := httptest.NewRecorder()
+
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			if !tt.expectedStats {
+				assert.Empty(t, resp.Header().Get(QueryStatsHeaderName))
+				return
+			}
+
+			assert.Contains(t, resp.Header().Get(ServiceTimingHeaderName), "queue_time;dur=1000")
+
+			var stats queryStatsResponse
+			require.NoError(t, json.Unmarshal([]byte(resp.Header().Get(QueryStatsHeaderName)), &stats))
+			assert.Equal(t, 1.0, stats.QueueTimeSeconds)
+			assert.Equal(t, uint64(10), stats.FetchedSeriesCount)
+			assert.Equal(t, uint64(30), stats.FetchedChunksCount)
+			assert.Equal(t, uint64(10), stats.FetchedIngesterChunksCount)
+			assert.Equal(t, uint64(20), stats.FetchedStoreGatewayChunksCount)
+			assert.Equal(t, uint64(3600), stats.FetchedSamplesCount)
+			assert.Equal(t, uint32(16), stats.ShardedQueries)
+		})
+	}
+}
+
+type mockLimits map[string]time.Duration
+
+func (m mockLimits) SlowQueryLogThreshold(userID string) time.Duration {
+	return m[userID]
+}
+
+func TestHandler_SlowQueryLog(t *testing.T) {
+	roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		querier_stats.FromContext(req.Context()).AddFetchedSamples(3600)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	})
+
+	for _, tt := range []struct {
+		name        string
+		cfg         HandlerConfig
+		tenantID    string
+		expectedLog bool
+	}{
+		{
+			name:        "should log the query slower than the tenant threshold",
+			cfg:         HandlerConfig{QueryStatsEnabled: true},
+			tenantID:    "slow",
+			expectedLog: true,
+		},
+		{
+			name:     "should not log the query of a tenant without threshold",
+			cfg:      HandlerConfig{QueryStatsEnabled: true},
+			tenantID: "other",
+		},
+		{
+			name:     "should not log the query faster than the tenant threshold",
+			cfg:      HandlerConfig{QueryStatsEnabled: true, LogQueriesLongerThan: time.Nanosecond},
+			tenantID: "fast",
+		},
+		{
+			name:        "should log the query slower than the global threshold",
+			cfg:         HandlerConfig{QueryStatsEnabled: true, LogQueriesLongerThan: time.Nanosecond},
+			tenantID:    "other",
+			expectedLog: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			limits := mockLimits{"slow": time.Nanosecond, "fast": time.Hour}
+			handler := NewHandler(tt.cfg, roundTripper, limits, nil, nil, log.NewLogfmtLogger(&logs), prometheus.NewPedanticRegistry())
+
+			req := httptest.NewRequest("GET", "/api/v1/query?query=up", nil)
+			req = req.WithContext(user.InjectOrgID(context.Background(), tt.tenantID))
+			resp := httptest.NewRecorder()
+
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			if !tt.expectedLog {
+				assert.NotContains(t, logs.String(), "slow query detected")
+				return
+			}
+
+			var slowQueryLog string
+			for _, line := range strings.Split(logs.String(), "\n") {
+				if strings.Contains(line, "slow query detected") {
+					slowQueryLog = line
+				}
+			}
+			assert.Contains(t, slowQueryLog, "user="+tt.tenantID)
+			assert.Contains(t, slowQueryLog, "fetched_samples_count=3600")
+			assert.Contains(t, slowQueryLog, "param_query=up")
+		})
+	}
+}
+
+type recordedQuery struct {
+	userID, method, path string
+	params               url.Values
+	statusCode           int
+}
+
+type mockRecorder struct {
+	services.Service
+	queries []recordedQuery
+}
+
+func (m *mockRecorder) Record(userID, method, path string, params url.Values, _ time.Duration, statusCode int) {
+	m.queries = append(m.queries, recordedQuery{userID: userID, method: method, path: path, params: params, statusCode: statusCode})
+}
+
+func TestHandler_RecordQueries(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		cfg                HandlerConfig
+		roundTripperErr    error
+		expectedStatusCode int
+	}{
+		{
+			name:               "should record the successful query",
+			cfg:                HandlerConfig{MaxBodySize: 1024},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "should record the successful query with the query stats enabled",
+			cfg:                HandlerConfig{MaxBodySize: 1024, QueryStatsEnabled: true},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "should record the failed query",
+			cfg:                HandlerConfig{MaxBodySize: 1024},
+			roundTripperErr:    httpgrpc.Errorf(http.StatusTooManyRequests, "too many requests"),
+			expectedStatusCode: http.StatusTooManyRequests,
+		},
+		{
+			name:               "should record the canceled query",
+			cfg:                HandlerConfig{MaxBodySize: 1024},
+			roundTripperErr:    context.Canceled,
+			expectedStatusCode: StatusClientClosedRequest,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				// The request is encoded for the queriers, consuming the body.
+				if err := req.ParseForm(); err != nil {
+					return nil, err
+				}
+				if tt.roundTripperErr != nil {
+					return nil, tt.roundTripperErr
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("{}")),
+				}, nil
+			})
+
+			recorder := &mockRecorder{}
+			handler := NewHandler(tt.cfg, roundTripper, nil, recorder, nil, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			req := httptest.NewRequest("POST", "/api/v1/query?time=1", strings.NewReader("query=up"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			resp := httptest.NewRecorder()
+
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, tt.expectedStatusCode, resp.Code)
+
+			require.Len(t, recorder.queries, 1)
+			assert.Equal(t, recordedQuery{
+				userID:     "user-1",
+				method:     "POST",
+				path:       "/api/v1/query",
+				params:     url.Values{"query": []string{"up"}, "time": []string{"1"}},
+				statusCode: tt.expectedStatusCode,
+			}, recorder.queries[0])
+		})
+	}
+}
+
+func TestHandler_QuerySource(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		key            string
+		internal       bool
+		headers        map[string]string
+		expectedSource string
+	}{
+		{
+			name:    "should strip the query source if no key is configured",
+			headers: map[string]string{queue.QuerySourceHeader: queue.QuerySourceRuler, queue.QuerySourceKeyHeader: "secret"},
+		},
+		{
+			name:     "should strip the query source without a key of the requests received over the gRPC server",
+			key:      "secret",
+			internal: true,
+			headers:  map[string]string{queue.QuerySourceHeader: queue.QuerySourceRuler},
+		},
+		{
+			name:     "should strip the query source with a wrong key",
+			key:      "secret",
+			internal: true,
+			headers:  map[string]string{queue.QuerySourceHeader: queue.QuerySourceRuler, queue.QuerySourceKeyHeader: "wrong"},
+		},
+		{
+			name:     "should not identify the rule evaluations by User-Agent",
+			key:      "secret",
+			internal: true,
+			headers:  map[string]string{"User-Agent": "mimir/2.3.0"},
+		},
+		{
+			name:           "should keep the query source with the configured key",
+			key:            "secret",
+			internal:       true,
+			headers:        map[string]string{queue.QuerySourceHeader: queue.QuerySourceRuler, queue.QuerySourceKeyHeader: "secret"},
+			expectedSource: queue.QuerySourceRuler,
+		},
+		{
+			name:           "should keep the query source with the configured key of the requests received over the HTTP server",
+			key:            "secret",
+			headers:        map[string]string{queue.QuerySourceHeader: queue.QuerySourceRuler, queue.QuerySourceKeyHeader: "secret"},
+			expectedSource: queue.QuerySourceRuler,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var actualSource, actualKey string
+			roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				actualSource = req.Header.Get(queue.QuerySourceHeader)
+				actualKey = req.Header.Get(queue.QuerySourceKeyHeader)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("{}")),
+				}, nil
+			})
+
+			cfg := HandlerConfig{MaxBodySize: 1024}
+			require.NoError(t, cfg.RulerQuerySourceKey.Set(tt.key))
+			handler := NewHandler(cfg, roundTripper, nil, nil, nil, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			if tt.internal {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9095}})
+			}
+
+			req := httptest.NewRequest("GET", "/api/v1/query?query=up", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			req = req.WithContext(ctx)
+			resp := httptest.NewRecorder()
+
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tt.expectedSource, actualSource)
+			assert.Empty(t, actualKey, "the query source key must not be forwarded")
+		})
+	}
+}
+
+func TestHandler_QueryPriority(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		internal         bool
+		expectedPriority string
+	}{
+		{
+			name: "should strip the query priority of the requests received over the HTTP server",
+		},
+		{
+			name:             "should keep the query priority of the requests received over the gRPC server",
+			internal:         true,
+			expectedPriority: queue.PriorityHigh.String(),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var actualPriority string
+			roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				actualPriority = req.Header.Get(queue.PriorityHeader)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("{}")),
+				}, nil
+			})
+
+			handler := NewHandler(HandlerConfig{MaxBodySize: 1024}, roundTripper, nil, nil, nil, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			if tt.internal {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9095}})
+			}
+
+			req := httptest.NewRequest("GET", "/api/v1/query?query=up", nil)
+			req.Header.Set(queue.PriorityHeader, queue.PriorityHigh.String())
+			req = req.WithContext(ctx)
+			resp := httptest.NewRecorder()
+
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tt.expectedPriority, actualPriority)
+		})
+	}
+}
+
+type auditedQuery struct {
+	path       string
+	params     url.Values
+	statusCode int
+	withStats  bool
+}
+
+type mockAuditLog struct {
+	services.Service
+	queries []auditedQuery
+}
+
+func (m *mockAuditLog) Log(r *http.Request, params url.Values, _ time.Duration, statusCode int, stats *querier_stats.Stats) {
+	m.queries = append(m.queries, auditedQuery{path: r.URL.Path, params: params, statusCode: statusCode, withStats: stats != nil})
+}
+
+func TestHandler_AuditLog(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		cfg                HandlerConfig
+		roundTripperErr    error
+		expectedStatusCode int
+	}{
+		{
+			name:               "should audit the successful query with the query stats disabled",
+			cfg:                HandlerConfig{MaxBodySize: 1024},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "should audit the successful query with the query stats enabled",
+			cfg:                HandlerConfig{MaxBodySize: 1024, QueryStatsEnabled: true},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "should audit the failed query",
+			cfg:                HandlerConfig{MaxBodySize: 1024, QueryStatsEnabled: true},
+			roundTripperErr:    httpgrpc.Errorf(http.StatusTooManyRequests, "too many requests"),
+			expectedStatusCode: http.StatusTooManyRequests,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				// The request is encoded for the queriers, consuming the body.
+				if err := req.ParseForm(); err != nil {
+					return nil, err
+				}
+				if tt.roundTripperErr != nil {
+					return nil, tt.roundTripperErr
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("{}")),
+				}, nil
+			})
+
+			auditLog := &mockAuditLog{}
+			handler := NewHandler(tt.cfg, roundTripper, nil, nil, auditLog, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			req := httptest.NewRequest("POST", "/api/v1/query?time=1", strings.NewReader("query=up"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+			resp := httptest.NewRecorder()
+
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, tt.expectedStatusCode, resp.Code)
+
+			require.Len(t, auditLog.queries, 1)
+			assert.Equal(t, auditedQuery{
+				path:       "/api/v1/query",
+				params:     url.Values{"query": []string{"up"}, "time": []string{"1"}},
+				statusCode: tt.expectedStatusCode,
+				withStats:  true,
+			}, auditLog.queries[0])
+		})
+	}
+}
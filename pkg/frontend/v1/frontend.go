@@ -9,7 +9,6 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/go-kit/log"
@@ -31,10 +30,6 @@ import (
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
-var (
-	errTooManyRequest = httpgrpc.Errorf(http.StatusTooManyRequests, "too many outstanding requests")
-)
-
 // Config for a Frontend.
 type Config struct {
 	MaxOutstandingPerTenant int           `yaml:"max_outstanding_per_tenant" category:"advanced"`
@@ -50,6 +45,16 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 type Limits interface {
 	// Returns max queriers to use per tenant, or 0 if shuffle sharding is disabled.
 	MaxQueriersPerUser(user string) int
+
+	// Returns the weight of the tenant in the sharing of the querier capacity among the tenants.
+	QuerierCapacityWeight(user string) int
+
+	// Returns the max number of outstanding requests of the tenant in the queue, or 0 to use the limit of the queue.
+	MaxOutstandingRequestsPerTenant(user string) int
+
+	// Returns the max number of outstanding requests issued by the ruler of the tenant in the queue, or 0 to count
+	// them toward the max outstanding requests of the tenant.
+	RulerMaxOutstandingRequestsPerTenant(user string) int
 }
 
 // Frontend queues HTTP requests, dispatches them to backends, and handles retries
@@ -71,6 +76,7 @@ type Frontend struct {
 	// Metrics.
 	queueLength       *prometheus.GaugeVec
 	discardedRequests *prometheus.CounterVec
+	dequeuedRequests  *prometheus.CounterVec
 	numClients        prometheus.GaugeFunc
 	queueDuration     prometheus.Histogram
 }
@@ -85,6 +91,11 @@ type request struct {
 	response chan *httpgrpc.HTTPResponse
 }
 
+// FromRuler implements queue.RulerRequest.
+func (r *request) FromRuler() bool {
+	return queue.IsRulerHTTPRequest(r.request)
+}
+
 // New creates a new frontend. Frontend implements service, and must be started and stopped.
 func New(cfg Config, limits Limits, log log.Logger, registerer prometheus.Registerer) (*Frontend, error) {
 	f := &Frontend{
@@ -99,6 +110,10 @@ func New(cfg Config, limits Limits, log log.Logger, registerer prometheus.Regist
 			Name: "cortex_query_frontend_discarded_requests_total",
 			Help: "Total number of query requests discarded.",
 		}, []string{"user"}),
+		dequeuedRequests: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_frontend_dequeued_requests_total",
+			Help: "Total number of query requests dequeued by the queriers. The share of the querier capacity achieved by each tenant is the rate of its dequeued requests over the rate of all the dequeued requests.",
+		}, []string{"user"}),
 		queueDuration: promauto.With(registerer).NewHistogram(prometheus.HistogramOpts{
 			Name:    "cortex_query_frontend_queue_duration_seconds",
 			Help:    "Time spend by requests queued.",
@@ -106,7 +121,7 @@ func New(cfg Config, limits Limits, log log.Logger, registerer prometheus.Regist
 		}),
 	}
 
-	f.requestQueue = queue.NewRequestQueue(cfg.MaxOutstandingPerTenant, cfg.QuerierForgetDelay, f.queueLength, f.discardedRequests)
+	f.requestQueue = queue.NewRequestQueue(cfg.MaxOutstandingPerTenant, cfg.QuerierForgetDelay, queue.PrioritiesConfig{}, 0, f.queueLength, f.discardedRequests, f.dequeuedRequests)
 	f.activeUsers = util.NewActiveUsersCleanupWithDefaultValues(f.cleanupInactiveUserMetrics)
 
 	var err error
@@ -153,6 +168,7 @@ func (f *Frontend) stopping(_ error) error {
 func (f *Frontend) cleanupInactiveUserMetrics(user string) {
 	f.queueLength.DeleteLabelValues(user)
 	f.discardedRequests.DeleteLabelValues(user)
+	f.dequeuedRequests.DeleteLabelValues(user)
 }
 
 // RoundTripGRPC round trips a proto (instead of an HTTP request).
@@ -215,7 +231,8 @@ func (f *Frontend) Process(server frontendv1pb.Frontend_ProcessServer) error {
 
 		req := reqWrapper.(*request)
 
-		f.queueDuration.Observe(time.Since(req.enqueueTime).Seconds())
+		queueTime := time.Since(req.enqueueTime)
+		f.queueDuration.Observe(queueTime.Seconds())
 		req.queueSpan.Finish()
 
 		/*
@@ -276,6 +293,7 @@ func (f *Frontend) Process(server frontendv1pb.Frontend_ProcessServer) error {
 			if stats.ShouldTrackHTTPGRPCResponse(resp.HttpResponse) {
 				stats := stats.FromContext(req.originalCtx)
 				stats.Merge(resp.Stats) // Safe if stats is nil.
+				stats.AddQueueTime(queueTime)
 			}
 
 			req.response <- resp.HttpResponse
@@ -325,13 +343,16 @@ func (f *Frontend) queueRequest(ctx context.Context, req *request) error {
 
 	// aggregate the max queriers limit in the case of a multi tenant query
 	maxQueriers := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueriersPerUser)
+	weight := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.QuerierCapacityWeight)
+	maxOutstanding := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxOutstandingRequestsPerTenant)
+	maxRulerOutstanding := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.RulerMaxOutstandingRequestsPerTenant)
 
 	joinedTenantID := tenant.JoinTenantIDs(tenantIDs)
 	f.activeUsers.UpdateUserTimestamp(joinedTenantID, now)
 
-	err = f.requestQueue.EnqueueRequest(joinedTenantID, req, maxQueriers, nil)
+	err = f.requestQueue.EnqueueRequest(joinedTenantID, req, maxQueriers, weight, maxOutstanding, maxRulerOutstanding, nil)
 	if err == queue.ErrTooManyRequests {
-		return errTooManyRequest
+		return httpgrpcutil.NewTooManyRequestsError(err.Error(), f.requestQueue.RetryAfter(joinedTenantID))
 	}
 	return err
 }
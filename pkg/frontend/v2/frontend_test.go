@@ -195,12 +195,13 @@ func TestFrontendRetryEnqueue(t *testing.T) {
 
 func TestFrontendTooManyRequests(t *testing.T) {
 	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
-		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.TOO_MANY_REQUESTS_PER_TENANT}
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.TOO_MANY_REQUESTS_PER_TENANT, RetryAfterNanos: (2500 * time.Millisecond).Nanoseconds()}
 	})
 
 	resp, err := f.RoundTripGRPC(user.InjectOrgID(context.Background(), "test"), &httpgrpc.HTTPRequest{})
 	require.NoError(t, err)
 	require.Equal(t, int32(http.StatusTooManyRequests), resp.Code)
+	require.Equal(t, []*httpgrpc.Header{{Key: "Retry-After", Values: []string{"3"}}}, resp.Headers)
 }
 
 func TestFrontendEnqueueFailure(t *testing.T) {
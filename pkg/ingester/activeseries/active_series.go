@@ -124,6 +124,21 @@ func (c *ActiveSeries) Active(now time.Time) (int, []int, bool) {
 	return total, totalMatching, true
 }
 
+// MatchingSeries returns the labels of the active series matching all the matchers. The series updated before the
+// last reload of the custom trackers aren't tracked anymore, so they're missing until they're updated again.
+func (c *ActiveSeries) MatchingSeries(matchers []*labels.Matcher, now time.Time) []labels.Labels {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keepUntilNanos := now.Add(-c.timeout).UnixNano()
+
+	var series []labels.Labels
+	for s := 0; s < numStripes; s++ {
+		series = c.stripes[s].appendMatchingSeries(series, matchers, keepUntilNanos)
+	}
+
+	return series
+}
+
 // getTotalAndUpdateMatching will return the total active series in the stripe and also update the slice provided
 // with each matcher's total.
 func (s *seriesStripe) getTotalAndUpdateMatching(matching []int) int {
@@ -138,6 +153,22 @@ func (s *seriesStripe) getTotalAndUpdateMatching(matching []int) int {
 	return s.active
 }
 
+// appendMatchingSeries appends to series the labels of the entries of the stripe updated since keepUntilNanos which
+// match all the matchers.
+func (s *seriesStripe) appendMatchingSeries(series []labels.Labels, matchers labelsMatchers, keepUntilNanos int64) []labels.Labels {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, entries := range s.refs {
+		for _, entry := range entries {
+			if entry.nanos.Load() >= keepUntilNanos && matchers.Matches(entry.lbs) {
+				series = append(series, entry.lbs)
+			}
+		}
+	}
+	return series
+}
+
 func (s *seriesStripe) updateSeriesTimestamp(now time.Time, series labels.Labels, fingerprint uint64, labelsCopy func(labels.Labels) labels.Labels) {
 	nowNanos := now.UnixNano()
 
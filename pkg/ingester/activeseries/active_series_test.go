@@ -103,6 +103,32 @@ func TestActiveSeries_ShouldCorrectlyHandleFingerprintCollisions(t *testing.T) {
 	assert.True(t, valid)
 }
 
+func TestActiveSeries_MatchingSeries(t *testing.T) {
+	ls1 := []labels.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "a"}}
+	ls2 := []labels.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "b"}}
+	ls3 := []labels.Label{{Name: "__name__", Value: "down"}, {Name: "job", Value: "a"}}
+
+	c := NewActiveSeries(&Matchers{}, DefaultTimeout)
+	now := time.Now()
+	c.UpdateSeries(ls1, now.Add(-2*DefaultTimeout), copyFn)
+	c.UpdateSeries(ls2, now, copyFn)
+	c.UpdateSeries(ls3, now, copyFn)
+
+	// The series updated before the timeout aren't active anymore.
+	series := c.MatchingSeries([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", "up")}, now)
+	assert.Equal(t, []labels.Labels{ls2}, series)
+
+	series = c.MatchingSeries([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "job", "a")}, now)
+	assert.Equal(t, []labels.Labels{ls3}, series)
+
+	// The series aren't tracked anymore after a reload of the custom trackers, until they're updated again.
+	c.ReloadMatchers(&Matchers{}, now)
+	assert.Empty(t, c.MatchingSeries(nil, now))
+
+	c.UpdateSeries(ls2, now, copyFn)
+	assert.Equal(t, []labels.Labels{ls2}, c.MatchingSeries(nil, now))
+}
+
 func TestActiveSeries_Purge_NoMatchers(t *testing.T) {
 	series := [][]labels.Label{
 		{{Name: "a", Value: "1"}},
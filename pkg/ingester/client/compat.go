@@ -218,6 +218,18 @@ func FromLabelMatchers(matchers []*LabelMatcher) ([]*labels.Matcher, error) {
 	return result, nil
 }
 
+// ShardByAllLabels returns the token of the series of the given user in the ingesters ring, which the distributors
+// shard the series with. It generates different values for different order of same labels.
+func ShardByAllLabels(userID string, labels []mimirpb.LabelAdapter) uint32 {
+	h := HashNew32()
+	h = HashAdd32(h, userID)
+	for _, label := range labels {
+		h = HashAdd32(h, label.Name)
+		h = HashAdd32(h, label.Value)
+	}
+	return h
+}
+
 // FastFingerprint runs the same algorithm as Prometheus labelSetToFastFingerprint()
 func FastFingerprint(ls []mimirpb.LabelAdapter) model.Fingerprint {
 	if len(ls) == 0 {
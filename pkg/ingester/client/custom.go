@@ -5,6 +5,12 @@
 
 package client
 
+import (
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/grafana/mimir/pkg/storage/chunk"
+)
+
 // ChunksCount returns the number of chunks in response.
 func (m *QueryStreamResponse) ChunksCount() int {
 	if len(m.Chunkseries) == 0 {
@@ -32,3 +38,23 @@ func (m *QueryStreamResponse) ChunksSize() int {
 	}
 	return size
 }
+
+// SamplesCount returns the number of samples in the response: the samples of the
+// XOR-encoded chunks, read from their header, and the samples of the time series.
+func (m *QueryStreamResponse) SamplesCount() int {
+	count := 0
+	for _, entry := range m.Chunkseries {
+		for _, c := range entry.Chunks {
+			if chunk.Encoding(c.Encoding) != chunk.PrometheusXorChunk || len(c.Data) < 2 {
+				continue
+			}
+			if xor, err := chunkenc.FromData(chunkenc.EncXOR, c.Data); err == nil {
+				count += xor.NumSamples()
+			}
+		}
+	}
+	for _, series := range m.Timeseries {
+		count += len(series.Samples)
+	}
+	return count
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/chunk"
+)
+
+func TestQueryStreamResponse_SamplesCount(t *testing.T) {
+	xor := chunkenc.NewXORChunk()
+	app, err := xor.Appender()
+	require.NoError(t, err)
+	for ts := int64(0); ts < 10; ts++ {
+		app.Append(ts, float64(ts))
+	}
+
+	resp := &QueryStreamResponse{
+		Chunkseries: []TimeSeriesChunk{{
+			Chunks: []Chunk{
+				{Encoding: int32(chunk.PrometheusXorChunk), Data: xor.Bytes()},
+				{Encoding: int32(chunk.PrometheusXorChunk), Data: xor.Bytes()},
+				// The chunks with an unknown encoding are not accounted.
+				{Encoding: 1, Data: xor.Bytes()},
+			},
+		}},
+		Timeseries: []mimirpb.TimeSeries{{
+			Samples: []mimirpb.Sample{{TimestampMs: 1, Value: 1}, {TimestampMs: 2, Value: 2}},
+		}},
+	}
+
+	assert.Equal(t, 22, resp.SamplesCount())
+	assert.Equal(t, 3, resp.ChunksCount())
+}
@@ -76,3 +76,13 @@ func (m *IngesterServerMock) LabelValuesCardinality(req *LabelValuesCardinalityR
 	args := m.Called(req, srv)
 	return args.Error(0)
 }
+
+func (m *IngesterServerMock) HeadCardinalityStats(ctx context.Context, r *HeadCardinalityStatsRequest) (*HeadCardinalityStatsResponse, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*HeadCardinalityStatsResponse), args.Error(1)
+}
+
+func (m *IngesterServerMock) ActiveSeries(ctx context.Context, r *ActiveSeriesRequest) (*ActiveSeriesResponse, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*ActiveSeriesResponse), args.Error(1)
+}
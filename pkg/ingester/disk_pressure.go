@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	earlyHeadCompactionReasonRetention = "retention"
+)
+
+// diskUsageScanner scans the usage of the volume a directory is stored on.
+type diskUsageScanner interface {
+	// Scan returns the used bytes and the capacity, in bytes, of the volume the directory is stored on.
+	Scan(dir string) (usedBytes, capacityBytes uint64, err error)
+}
+
+// diskPressureMonitor tells whether the ingester is under disk pressure, being the utilization of the TSDB volume
+// above the configured threshold. While under disk pressure, the TSDB blocks already shipped to the storage are
+// deleted regardless of the retention.
+type diskPressureMonitor struct {
+	dir       string
+	threshold float64
+
+	scanner diskUsageScanner
+	logger  log.Logger
+
+	// Whether the ingester was under disk pressure at the last check.
+	underPressure bool
+
+	utilization prometheus.Gauge
+}
+
+func newDiskPressureMonitor(dir string, threshold float64, scanner diskUsageScanner, logger log.Logger, reg prometheus.Registerer) *diskPressureMonitor {
+	return &diskPressureMonitor{
+		dir:       dir,
+		threshold: threshold,
+		scanner:   scanner,
+		logger:    logger,
+		utilization: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_tsdb_disk_utilization",
+			Help: "Fraction of the capacity of the TSDB volume in use, as of the last head compaction.",
+		}),
+	}
+}
+
+// check scans the usage of the TSDB volume, and returns whether the ingester is under disk pressure. It's not safe
+// for concurrent use.
+func (m *diskPressureMonitor) check() bool {
+	usedBytes, capacityBytes, err := m.scanner.Scan(m.dir)
+	if err != nil || capacityBytes == 0 {
+		level.Warn(m.logger).Log("msg", "failed to scan the TSDB volume usage, not checking the disk pressure", "dir", m.dir, "err", err)
+		return false
+	}
+
+	utilization := float64(usedBytes) / float64(capacityBytes)
+	m.utilization.Set(utilization)
+
+	underPressure := utilization >= m.threshold
+	if underPressure != m.underPressure {
+		m.underPressure = underPressure
+
+		if underPressure {
+			level.Warn(m.logger).Log("msg", "the TSDB volume utilization exceeded the disk pressure threshold, deleting the TSDB blocks already shipped to the storage", "utilization", utilization, "threshold", m.threshold)
+		} else {
+			level.Info(m.logger).Log("msg", "the TSDB volume utilization is back below the disk pressure threshold", "utilization", utilization, "threshold", m.threshold)
+		}
+	}
+
+	return underPressure
+}
@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/shipper"
+	"github.com/weaveworks/common/user"
+)
+
+type diskUsageScannerMock struct {
+	usedBytes     uint64
+	capacityBytes uint64
+	err           error
+}
+
+func (s *diskUsageScannerMock) Scan(string) (uint64, uint64, error) {
+	return s.usedBytes, s.capacityBytes, s.err
+}
+
+func TestDiskPressureMonitor(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	scanner := &diskUsageScannerMock{usedBytes: 50, capacityBytes: 100}
+	m := newDiskPressureMonitor(t.TempDir(), 0.9, scanner, log.NewNopLogger(), reg)
+
+	assert.False(t, m.check())
+	assert.Equal(t, 0.5, testutil.ToFloat64(m.utilization))
+
+	scanner.usedBytes = 90
+	assert.True(t, m.check())
+	assert.Equal(t, 0.9, testutil.ToFloat64(m.utilization))
+
+	// The ingester isn't considered under disk pressure if the volume usage can't be scanned.
+	scanner.err = errors.New("failed")
+	assert.False(t, m.check())
+
+	scanner.err = nil
+	scanner.usedBytes = 10
+	assert.False(t, m.check())
+	assert.Equal(t, 0.1, testutil.ToFloat64(m.utilization))
+}
+
+func TestStatfsDiskUsageScanner(t *testing.T) {
+	usedBytes, capacityBytes, err := statfsDiskUsageScanner{}.Scan(t.TempDir())
+	require.NoError(t, err)
+	assert.Greater(t, capacityBytes, uint64(0))
+	assert.LessOrEqual(t, usedBytes, capacityBytes)
+}
+
+func TestIngester_EarlyHeadCompaction(t *testing.T) {
+	const (
+		minute = int64(time.Minute / time.Millisecond)
+	)
+
+	tests := map[string]struct {
+		headChunksRetention time.Duration
+		expectedCompaction  bool
+		expectedHeadMinTime int64
+	}{
+		"regular compaction": {
+			expectedHeadMinTime: 10 * minute,
+		},
+		"head spanning a longer time range than the head chunks retention": {
+			headChunksRetention: 15 * time.Minute,
+			expectedCompaction:  true,
+			expectedHeadMinTime: 25 * minute,
+		},
+		"head spanning a shorter time range than the head chunks retention": {
+			headChunksRetention: 30 * time.Minute,
+			expectedHeadMinTime: 10 * minute,
+		},
+		"no block aligned time range older than the head chunks retention": {
+			headChunksRetention: 20 * time.Minute,
+			expectedHeadMinTime: 10 * minute,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			cfg := defaultIngesterTestConfig(t)
+			cfg.BlocksStorageConfig.TSDB.BlockRanges = []time.Duration{20 * time.Minute}
+			cfg.BlocksStorageConfig.TSDB.HeadCompactionInterval = time.Hour // Long enough to not be reached during the test.
+			cfg.BlocksStorageConfig.TSDB.HeadCompactionIdleTimeout = 0
+			cfg.BlocksStorageConfig.TSDB.HeadChunksRetention = testData.headChunksRetention
+
+			reg := prometheus.NewPedanticRegistry()
+			i, err := prepareIngesterWithBlocksStorage(t, cfg, reg)
+			require.NoError(t, err)
+
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+			t.Cleanup(func() {
+				_ = services.StopAndAwaitTerminated(context.Background(), i)
+			})
+
+			// Wait until it's healthy
+			test.Poll(t, 1*time.Second, 1, func() interface{} {
+				return i.lifecycler.HealthyInstancesCount()
+			})
+
+			// Push samples spanning 26 minutes, less than the regular compaction threshold of 1.5 block ranges.
+			ctx := user.InjectOrgID(context.Background(), userID)
+			for _, ts := range []int64{10 * minute, 25 * minute, 36 * minute} {
+				req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 1, ts)
+				_, err = i.Push(ctx, req)
+				require.NoError(t, err)
+			}
+
+			i.compactBlocks(context.Background(), false, nil)
+
+			db := i.getTSDB(userID)
+			assert.Equal(t, testData.expectedHeadMinTime, db.Head().MinTime())
+
+			expectedMetrics := ""
+			if testData.expectedCompaction {
+				require.Len(t, db.Blocks(), 1)
+				assert.Equal(t, 10*minute, db.Blocks()[0].Meta().MinTime)
+				assert.Equal(t, 20*minute, db.Blocks()[0].Meta().MaxTime)
+
+				expectedMetrics = `
+					# HELP cortex_ingester_tsdb_early_head_compactions_total Total number of per-tenant TSDB head compactions of block aligned time ranges run before the head reached the regular compaction threshold, to truncate the head chunks and WAL earlier, by reason.
+					# TYPE cortex_ingester_tsdb_early_head_compactions_total counter
+					cortex_ingester_tsdb_early_head_compactions_total{reason="retention"} 1
+				`
+			} else {
+				assert.Empty(t, db.Blocks())
+			}
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expectedMetrics), "cortex_ingester_tsdb_early_head_compactions_total"))
+
+			// The pushes are never rejected because of the early compaction.
+			req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 1, 37*minute)
+			_, err = i.Push(ctx, req)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestIngester_DiskPressureShouldDeleteTheShippedBlocks(t *testing.T) {
+	chunkRangeMilliSec := (2 * time.Hour).Milliseconds()
+	cfg := defaultIngesterTestConfig(t)
+	cfg.BlocksStorageConfig.TSDB.BlockRanges = []time.Duration{2 * time.Hour}
+	cfg.BlocksStorageConfig.TSDB.ShipInterval = time.Hour // Required to enable shipping.
+	cfg.BlocksStorageConfig.TSDB.HeadCompactionInterval = time.Hour
+	cfg.BlocksStorageConfig.TSDB.HeadCompactionIdleTimeout = 0
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	scanner := &diskUsageScannerMock{usedBytes: 10, capacityBytes: 100}
+	i.diskPressureMonitor = newDiskPressureMonitor(cfg.BlocksStorageConfig.TSDB.Dir, 0.9, scanner, log.NewNopLogger(), nil)
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), i)
+	})
+
+	// Push some data to create 2 blocks.
+	ctx := user.InjectOrgID(context.Background(), userID)
+	for j := int64(0); j < 4; j++ {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 0, j*chunkRangeMilliSec)
+		_, err := i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	db := i.getTSDB(userID)
+	require.NotNil(t, db)
+	require.NoError(t, db.Compact())
+	require.Len(t, db.Blocks(), 2)
+	shippedID := db.Blocks()[0].Meta().ULID
+
+	// Only the first block has been shipped.
+	require.NoError(t, shipper.WriteMetaFile(nil, db.db.Dir(), &shipper.Meta{
+		Version:  shipper.MetaVersion1,
+		Uploaded: []ulid.ULID{shippedID},
+	}))
+	require.NoError(t, db.updateCachedShippedBlocks())
+
+	// The blocks are copied, because they're sorted in place.
+	blocksToDelete := func() map[ulid.ULID]struct{} {
+		return db.blocksToDelete(append([]*tsdb.Block(nil), db.Blocks()...))
+	}
+
+	// The shipped blocks are deleted according to the retention while not under disk pressure.
+	i.compactBlocks(context.Background(), false, nil)
+	assert.Empty(t, blocksToDelete())
+
+	// The shipped blocks are deleted regardless of the retention while under disk pressure.
+	scanner.usedBytes = 95
+	i.compactBlocks(context.Background(), false, nil)
+	assert.Equal(t, map[ulid.ULID]struct{}{shippedID: {}}, blocksToDelete())
+	assert.Equal(t, 2*chunkRangeMilliSec, db.Head().MinTime(), "the head shouldn't be compacted early")
+
+	scanner.usedBytes = 10
+	i.compactBlocks(context.Background(), false, nil)
+	assert.Empty(t, blocksToDelete())
+}
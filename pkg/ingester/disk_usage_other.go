@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package ingester
+
+import (
+	"github.com/pkg/errors"
+)
+
+// statfsDiskUsageScanner is not supported on this platform.
+type statfsDiskUsageScanner struct{}
+
+func (statfsDiskUsageScanner) Scan(string) (uint64, uint64, error) {
+	return 0, 0, errors.New("scanning the volume usage is not supported on this platform")
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build linux || darwin
+// +build linux darwin
+
+package ingester
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// statfsDiskUsageScanner scans the volume usage with the statfs system call.
+type statfsDiskUsageScanner struct{}
+
+func (statfsDiskUsageScanner) Scan(dir string) (uint64, uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, errors.Wrap(err, "failed to read the volume stats")
+	}
+
+	// The capacity excludes the blocks reserved to the root user, like df does.
+	blockSize := uint64(stat.Bsize)
+	usedBytes := (stat.Blocks - stat.Bfree) * blockSize
+	return usedBytes, usedBytes + stat.Bavail*blockSize, nil
+}
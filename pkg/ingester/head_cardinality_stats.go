@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+// headCardinalityStats computes the cardinality statistics of the series of the index, keeping the top limit items of
+// each statistic. It reads the postings of all the label pairs, so its cost is proportional to the size of the index.
+func headCardinalityStats(ctx context.Context, idx tsdb.IndexReader, limit int) (*client.HeadCardinalityStatsResponse, error) {
+	var (
+		labelValueCountByLabelName  = newTopCardinalityStats(limit)
+		seriesCountByMetricName     = newTopCardinalityStats(limit)
+		seriesCountByLabelValuePair = newTopCardinalityStats(limit)
+	)
+
+	labelNames, err := idx.LabelNames()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, labelName := range labelNames {
+		values, err := idx.LabelValues(labelName)
+		if err != nil {
+			return nil, err
+		}
+		labelValueCountByLabelName.push(labelName, uint64(len(values)))
+
+		for _, value := range values {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			postings, err := idx.Postings(labelName, value)
+			if err != nil {
+				return nil, err
+			}
+			count := uint64(0)
+			for postings.Next() {
+				count++
+			}
+			if err := postings.Err(); err != nil {
+				return nil, err
+			}
+
+			seriesCountByLabelValuePair.push(labelName+"="+value, count)
+			if labelName == labels.MetricName {
+				seriesCountByMetricName.push(value, count)
+			}
+		}
+	}
+
+	return &client.HeadCardinalityStatsResponse{
+		LabelValueCountByLabelName:  labelValueCountByLabelName.sorted(),
+		SeriesCountByMetricName:     seriesCountByMetricName.sorted(),
+		SeriesCountByLabelValuePair: seriesCountByLabelValuePair.sorted(),
+	}, nil
+}
+
+// topCardinalityStats keeps the limit items with the highest value, in a min-heap.
+type topCardinalityStats struct {
+	limit int
+	items []*client.CardinalityStatsItem
+}
+
+func newTopCardinalityStats(limit int) *topCardinalityStats {
+	return &topCardinalityStats{limit: limit}
+}
+
+func (t *topCardinalityStats) push(name string, value uint64) {
+	if t.limit <= 0 {
+		return
+	}
+
+	item := &client.CardinalityStatsItem{Name: name, Value: value}
+	if len(t.items) < t.limit {
+		heap.Push(t, item)
+		return
+	}
+
+	// Replace the lowest item, if the new one is higher.
+	if cardinalityStatsItemLess(t.items[0], item) {
+		t.items[0] = item
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the items sorted by value, in descending order, and then by name.
+func (t *topCardinalityStats) sorted() []*client.CardinalityStatsItem {
+	items := append([]*client.CardinalityStatsItem(nil), t.items...)
+	sortCardinalityStatsItems(items)
+	return items
+}
+
+func (t *topCardinalityStats) Len() int { return len(t.items) }
+
+func (t *topCardinalityStats) Less(i, j int) bool {
+	return cardinalityStatsItemLess(t.items[i], t.items[j])
+}
+
+func (t *topCardinalityStats) Swap(i, j int) { t.items[i], t.items[j] = t.items[j], t.items[i] }
+
+func (t *topCardinalityStats) Push(x interface{}) {
+	t.items = append(t.items, x.(*client.CardinalityStatsItem))
+}
+
+func (t *topCardinalityStats) Pop() interface{} {
+	last := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	return last
+}
+
+// cardinalityStatsItemLess returns whether a ranks lower than b: the items with the highest values rank first, and
+// the items with the same value are ranked by name.
+func cardinalityStatsItemLess(a, b *client.CardinalityStatsItem) bool {
+	if a.Value != b.Value {
+		return a.Value < b.Value
+	}
+	return a.Name > b.Name
+}
+
+func sortCardinalityStatsItems(items []*client.CardinalityStatsItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return cardinalityStatsItemLess(items[j], items[i])
+	})
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+func TestIngester_HeadCardinalityStats(t *testing.T) {
+	i, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	t.Run("should return an empty response if the tenant has no series", func(t *testing.T) {
+		res, err := i.HeadCardinalityStats(ctx, &client.HeadCardinalityStatsRequest{Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, &client.HeadCardinalityStatsResponse{}, res)
+	})
+
+	for _, series := range []labels.Labels{
+		labels.FromStrings(labels.MetricName, "test_1", "status", "200"),
+		labels.FromStrings(labels.MetricName, "test_1", "status", "500", "reason", "broken"),
+		labels.FromStrings(labels.MetricName, "test_2"),
+	} {
+		req, _, _, _ := mockWriteRequest(t, series, 1, 1)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	tests := map[string]struct {
+		limit    int32
+		expected *client.HeadCardinalityStatsResponse
+	}{
+		"should return all the items below the limit": {
+			limit: 10,
+			expected: &client.HeadCardinalityStatsResponse{
+				NumSeries: 3,
+				LabelValueCountByLabelName: []*client.CardinalityStatsItem{
+					{Name: labels.MetricName, Value: 2},
+					{Name: "status", Value: 2},
+					{Name: "reason", Value: 1},
+				},
+				SeriesCountByMetricName: []*client.CardinalityStatsItem{
+					{Name: "test_1", Value: 2},
+					{Name: "test_2", Value: 1},
+				},
+				SeriesCountByLabelValuePair: []*client.CardinalityStatsItem{
+					{Name: "__name__=test_1", Value: 2},
+					{Name: "__name__=test_2", Value: 1},
+					{Name: "reason=broken", Value: 1},
+					{Name: "status=200", Value: 1},
+					{Name: "status=500", Value: 1},
+				},
+			},
+		},
+		"should return the top limit items": {
+			limit: 2,
+			expected: &client.HeadCardinalityStatsResponse{
+				NumSeries: 3,
+				LabelValueCountByLabelName: []*client.CardinalityStatsItem{
+					{Name: labels.MetricName, Value: 2},
+					{Name: "status", Value: 2},
+				},
+				SeriesCountByMetricName: []*client.CardinalityStatsItem{
+					{Name: "test_1", Value: 2},
+					{Name: "test_2", Value: 1},
+				},
+				SeriesCountByLabelValuePair: []*client.CardinalityStatsItem{
+					{Name: "__name__=test_1", Value: 2},
+					{Name: "__name__=test_2", Value: 1},
+				},
+			},
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := i.HeadCardinalityStats(ctx, &client.HeadCardinalityStatsRequest{Limit: testData.limit})
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, res)
+		})
+	}
+}
@@ -14,16 +14,20 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/status"
+	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/concurrency"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
@@ -40,6 +44,7 @@ import (
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/hashcache"
+	"github.com/prometheus/prometheus/tsdb/wal"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/weaveworks/common/httpgrpc"
@@ -89,6 +94,9 @@ const (
 
 	instanceIngestionRateTickInterval = time.Second
 
+	// Min interval between the warnings logged for a tenant exceeding the per-user series limit in warn-only mode.
+	warnOnlySeriesLimitLogInterval = time.Minute
+
 	sampleOutOfOrder     = "sample-out-of-order"
 	sampleTooOld         = "sample-too-old"
 	newValueForTimestamp = "new-value-for-timestamp"
@@ -137,6 +145,7 @@ type Config struct {
 
 	BlocksStorageConfig         mimir_tsdb.BlocksStorageConfig `yaml:"-"`
 	StreamChunksWhenUsingBlocks bool                           `yaml:"-" category:"advanced"`
+	StreamChunksBatchSize       int                            `yaml:"stream_chunks_batch_size" category:"experimental"`
 	// Runtime-override for type of streaming query to use (chunks or samples).
 	StreamTypeFn func() QueryStreamType `yaml:"-"`
 
@@ -145,6 +154,13 @@ type Config struct {
 
 	IgnoreSeriesLimitForMetricNames string `yaml:"ignore_series_limit_for_metric_names" category:"advanced"`
 
+	ReadPathCPUUtilizationLimit       float64 `yaml:"read_path_cpu_utilization_limit" category:"experimental"`
+	ReadPathMemoryUtilizationLimit    uint64  `yaml:"read_path_memory_utilization_limit" category:"experimental"`
+	ReadPathExpensiveRequestMinSeries int     `yaml:"read_path_expensive_request_min_estimated_series" category:"experimental"`
+
+	OwnedSeriesUpdatePeriod time.Duration `yaml:"owned_series_update_period" category:"experimental"`
+	UseOwnedSeriesForLimits bool          `yaml:"use_owned_series_for_limits" category:"experimental"`
+
 	// For testing, you can override the address and ID of this ingester.
 	ingesterClientFactory func(addr string, cfg client.Config) (client.HealthAndIngesterClient, error)
 }
@@ -161,11 +177,19 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.ActiveSeriesMetricsIdleTimeout, "ingester.active-series-metrics-idle-timeout", 10*time.Minute, "After what time a series is considered to be inactive.")
 
 	f.BoolVar(&cfg.StreamChunksWhenUsingBlocks, "ingester.stream-chunks-when-using-blocks", true, "Stream chunks from ingesters to queriers.")
+	f.IntVar(&cfg.StreamChunksBatchSize, "ingester.stream-chunks-batch-size", 1000, "Maximum number of chunks sent to the queriers in each message when streaming chunks. The chunks of a series with more chunks are split across multiple messages. 0 to only limit the messages by size.")
 	f.DurationVar(&cfg.TSDBConfigUpdatePeriod, "ingester.tsdb-config-update-period", 15*time.Second, "Period with which to update the per-tenant TSDB configuration.")
 
 	cfg.DefaultLimits.RegisterFlags(f)
 
 	f.StringVar(&cfg.IgnoreSeriesLimitForMetricNames, "ingester.ignore-series-limit-for-metric-names", "", "Comma-separated list of metric names, for which the -ingester.max-global-series-per-metric limit will be ignored. Does not affect the -ingester.max-global-series-per-user limit.")
+
+	f.Float64Var(&cfg.ReadPathCPUUtilizationLimit, readPathCPUUtilizationLimitFlag, 0, "CPU utilization limit, as CPU cores, above which the expensive read requests are rejected, to protect the write path. The CPU utilization is a moving average over about a minute. 0 to disable.")
+	f.Uint64Var(&cfg.ReadPathMemoryUtilizationLimit, readPathMemoryUtilizationLimitFlag, 0, "Memory utilization limit, as bytes of in-use Go heap, above which the expensive read requests are rejected, to protect the write path. 0 to disable.")
+	f.IntVar(&cfg.ReadPathExpensiveRequestMinSeries, readPathExpensiveRequestMinSeriesFlag, 10000, "Minimum number of series, estimated from the in-memory series, selected by a read request for it to be rejected while the ingester exceeds the read path CPU or memory utilization limit. 0 to reject all the read requests selecting series.")
+
+	f.DurationVar(&cfg.OwnedSeriesUpdatePeriod, "ingester.owned-series-update-period", 0, "How often to recompute, per tenant, the number of in-memory series owned by the ingester, being the series the ingester is the primary replica of in the ring, and the number of replicated series. 0 to disable.")
+	f.BoolVar(&cfg.UseOwnedSeriesForLimits, "ingester.use-owned-series-for-limits", false, "Enforce the per-tenant series limit on the number of series owned by the ingester, scaled by the replication factor, instead of the number of in-memory series. Requires -ingester.owned-series-update-period to be set.")
 }
 
 func (cfg *Config) getIgnoreSeriesLimitForMetricNamesMap() map[string]struct{} {
@@ -200,6 +224,7 @@ type Ingester struct {
 	logger  log.Logger
 
 	lifecycler         *ring.Lifecycler
+	ownedSeriesRing    *ring.Ring // Used to compute the owned series. Nil if disabled.
 	limits             *validation.Overrides
 	limiter            *Limiter
 	subservicesWatcher *services.FailureWatcher
@@ -220,6 +245,15 @@ type Ingester struct {
 	forceCompactTrigger chan requestWithUsersAndCallback
 	shipTrigger         chan requestWithUsersAndCallback
 
+	// Set by the ShutdownHandler to only unregister from the ring once all TSDB blocks have been shipped
+	// to the storage, and the error preventing it, if any.
+	uploadBeforeUnregister    atomic.Bool
+	uploadBeforeUnregisterErr atomic.Error
+
+	// Unix timestamp since when the ingester is in read-only mode, or 0 if it's not. Set by the ReadOnlyHandler.
+	readOnlyMtx   sync.Mutex
+	readOnlySince atomic.Int64
+
 	// Maps the per-block series ID with its labels hash.
 	seriesHashCache *hashcache.SeriesHashCache
 
@@ -237,6 +271,12 @@ type Ingester struct {
 	ingestionRate        *util_math.EwmaRate
 	inflightPushRequests atomic.Int64
 
+	// Rejects the expensive read requests while the ingester exceeds the read path utilization limits. Nil if disabled.
+	utilizationBasedLimiter *utilizationBasedLimiter
+
+	// Forces the head compactions while the TSDB volume is nearly full. Nil if disabled.
+	diskPressureMonitor *diskPressureMonitor
+
 	// Anonymous usage statistics tracked by ingester.
 	memorySeriesStats      *expvar.Int
 	memoryTenantsStats     *expvar.Int
@@ -292,6 +332,14 @@ func New(cfg Config, limits *validation.Overrides, registerer prometheus.Registe
 	i.ingestionRate = util_math.NewEWMARate(0.2, instanceIngestionRateTickInterval)
 	i.metrics = newIngesterMetrics(registerer, cfg.ActiveSeriesMetricsEnabled, i.getInstanceLimits, i.ingestionRate, &i.inflightPushRequests)
 
+	if cfg.ReadPathCPUUtilizationLimit > 0 || cfg.ReadPathMemoryUtilizationLimit > 0 {
+		i.utilizationBasedLimiter = newUtilizationBasedLimiter(cfg.ReadPathCPUUtilizationLimit, cfg.ReadPathMemoryUtilizationLimit, procfsUtilizationScanner{}, logger, registerer)
+	}
+
+	if threshold := cfg.BlocksStorageConfig.TSDB.DiskPressureThreshold; threshold > 0 {
+		i.diskPressureMonitor = newDiskPressureMonitor(cfg.BlocksStorageConfig.TSDB.Dir, threshold, statfsDiskUsageScanner{}, logger, registerer)
+	}
+
 	// Replace specific metrics which we can't directly track but we need to read
 	// them from the underlying system (ie. TSDB).
 	if registerer != nil {
@@ -313,6 +361,15 @@ func New(cfg Config, limits *validation.Overrides, registerer prometheus.Registe
 	i.subservicesWatcher = services.NewFailureWatcher()
 	i.subservicesWatcher.WatchService(i.lifecycler)
 
+	if cfg.OwnedSeriesUpdatePeriod > 0 {
+		// The ring client metrics are not registered, because they would clash with the ones of the ingester ring
+		// client of the other components running in the same process.
+		i.ownedSeriesRing, err = ring.New(cfg.IngesterRing.ToRingConfig(), "ingester", IngesterRingKey, logger, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the ingester ring client")
+		}
+	}
+
 	// Init the limter and instantiate the user states which depend on it
 	i.limiter = NewLimiter(
 		limits,
@@ -368,6 +425,12 @@ func (i *Ingester) starting(ctx context.Context) error {
 		return errors.Wrap(err, "opening existing TSDBs")
 	}
 
+	if i.cfg.IngesterRing.TokenGenerationStrategy == tokenGenerationStrategySpreadMinimizing {
+		if err := i.cfg.IngesterRing.storeSpreadMinimizingTokens(i.logger); err != nil {
+			return errors.Wrap(err, "failed to generate the ingester tokens")
+		}
+	}
+
 	// Important: we want to keep lifecycler running until we ask it to stop, so we need to give it independent context
 	if err := i.lifecycler.StartAsync(context.Background()); err != nil {
 		return errors.Wrap(err, "failed to start lifecycler")
@@ -396,6 +459,11 @@ func (i *Ingester) starting(ctx context.Context) error {
 		servs = append(servs, closeIdleService)
 	}
 
+	if i.ownedSeriesRing != nil {
+		ownedSeriesService := services.NewTimerService(i.cfg.OwnedSeriesUpdatePeriod, nil, i.updateOwnedSeries, nil)
+		servs = append(servs, i.ownedSeriesRing, ownedSeriesService)
+	}
+
 	var err error
 	i.subservices, err = services.NewManager(servs...)
 	if err == nil {
@@ -455,6 +523,13 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 	usageStatsUpdateTicker := time.NewTicker(usageStatsUpdateInterval)
 	defer usageStatsUpdateTicker.Stop()
 
+	var utilizationScanTickerChan <-chan time.Time
+	if i.utilizationBasedLimiter != nil {
+		t := time.NewTicker(utilizationScanInterval)
+		utilizationScanTickerChan = t.C
+		defer t.Stop()
+	}
+
 	for {
 		select {
 		case <-metadataPurgeTicker.C:
@@ -470,6 +545,7 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 			i.tsdbsMtx.RUnlock()
 
 		case <-tsdbUpdateTicker.C:
+			i.updateSuggestedOutOfOrderTimeWindows(time.Now())
 			i.applyTSDBSettings()
 
 		case <-activeSeriesTickerChan:
@@ -478,6 +554,9 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 		case <-usageStatsUpdateTicker.C:
 			i.updateUsageStats()
 
+		case <-utilizationScanTickerChan:
+			i.utilizationBasedLimiter.update()
+
 		case <-ctx.Done():
 			return nil
 		case err := <-i.subservicesWatcher.Chan():
@@ -558,6 +637,11 @@ func (i *Ingester) applyTSDBSettings() {
 		globalValue := i.limits.MaxGlobalExemplarsPerUser(userID)
 		localValue := i.limiter.convertGlobalToLocalLimit(userID, globalValue)
 
+		db := i.getTSDB(userID)
+		if db == nil {
+			continue
+		}
+
 		oooTW := i.limits.OutOfOrderTimeWindow(userID)
 		if oooTW < 0 {
 			oooTW = 0
@@ -577,12 +661,27 @@ func (i *Ingester) applyTSDBSettings() {
 				},
 			},
 		}
+		if err := db.db.ApplyConfig(&cfg); err != nil {
+			level.Error(i.logger).Log("msg", "failed to apply config to TSDB", "user", userID, "err", err)
+		}
+	}
+}
+
+// updateSuggestedOutOfOrderTimeWindows goes through all tenants and updates the out-of-order time window suggested
+// from the lateness of the samples recently rejected for being too old. The suggestion is only exported: the window
+// applied is the tenant's -ingester.out-of-order-time-window, so that it's the same in all the ingesters.
+func (i *Ingester) updateSuggestedOutOfOrderTimeWindows(now time.Time) {
+	for _, userID := range i.getTSDBUsers() {
 		db := i.getTSDB(userID)
 		if db == nil {
 			continue
 		}
-		if err := db.db.ApplyConfig(&cfg); err != nil {
-			level.Error(i.logger).Log("msg", "failed to apply config to TSDB", "user", userID, "err", err)
+
+		suggested := db.rejectedSamplesLateness.suggestedWindow(now, oooWindowSuggestionQuantile)
+		if suggested > 0 {
+			i.metrics.suggestedOutOfOrderTimeWindow.WithLabelValues(userID).Set(suggested.Seconds())
+		} else {
+			i.metrics.suggestedOutOfOrderTimeWindow.DeleteLabelValues(userID)
 		}
 	}
 }
@@ -605,6 +704,10 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 		return nil, err
 	}
 
+	if i.readOnlySince.Load() > 0 {
+		return nil, errIngesterReadOnly
+	}
+
 	// We will report *this* request in the error too.
 	inflight := i.inflightPushRequests.Inc()
 	defer i.inflightPushRequests.Dec()
@@ -668,9 +771,19 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 		newValueForTimestampCount = 0
 		perUserSeriesLimitCount   = 0
 		perMetricSeriesLimitCount = 0
+		perScopeSeriesLimitCount  map[string]int // Lazily initialised, because the per-scope series limit is rarely hit.
 
 		minAppendTime, minAppendTimeAvailable = db.Head().AppendableMinValidTime()
 
+		// The lateness of the samples rejected for being too old is relative to the head max time,
+		// because it's where the out-of-order time window starts from.
+		headMaxTime         = db.Head().MaxTime()
+		observeTooOldSample = func(timestampMs int64) {
+			if headMaxTime != math.MinInt64 && timestampMs < headMaxTime {
+				db.rejectedSamplesLateness.observe(time.Duration(headMaxTime-timestampMs) * time.Millisecond)
+			}
+		}
+
 		updateFirstPartial = func(errFn func() error) {
 			if firstPartialErr == nil {
 				firstPartialErr = errFn()
@@ -687,6 +800,20 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 	}
 
 	oooTW := i.limits.OutOfOrderTimeWindow(userID)
+	ctZeroIngestionEnabled := i.limits.OTelCreatedTimestampZeroIngestionEnabled(userID)
+
+	// When the distributor passes the bounds of the samples timestamps, the per-series out of bounds
+	// check is skipped if all the samples in the request are either within or outside of the bounds.
+	checkOutOfBounds := oooTW <= 0 && minAppendTimeAvailable
+	allSeriesOutOfBounds := false
+	if checkOutOfBounds && req.TimestampHintsSet {
+		if req.MinTimestampMs >= minAppendTime {
+			checkOutOfBounds = false
+		} else if req.MaxTimestampMs < minAppendTime {
+			allSeriesOutOfBounds = true
+		}
+	}
+
 	for _, ts := range req.Timeseries {
 		// The labels must be sorted (in our case, it's guaranteed a write request
 		// has sorted labels once hit the ingester).
@@ -695,10 +822,13 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 		// and out-of-order support is not enabled.
 		// TODO(jesus.vazquez) If we had too many old samples we might want to
 		// extend the fast path to fail early.
-		if oooTW <= 0 && minAppendTimeAvailable &&
-			len(ts.Samples) > 0 && len(ts.Exemplars) == 0 && allOutOfBounds(ts.Samples, minAppendTime) {
+		if checkOutOfBounds && len(ts.Samples) > 0 && len(ts.Exemplars) == 0 &&
+			(allSeriesOutOfBounds || allOutOfBounds(ts.Samples, minAppendTime)) {
 			failedSamplesCount += len(ts.Samples)
 			sampleOutOfBoundsCount += len(ts.Samples)
+			for _, s := range ts.Samples {
+				observeTooOldSample(s.TimestampMs)
+			}
 
 			updateFirstPartial(func() error {
 				return newIngestErrSampleTimestampTooOld(model.Time(ts.Samples[0].TimestampMs), ts.Labels)
@@ -712,7 +842,7 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 		// To find out if any sample was added to this series, we keep old value.
 		oldSucceededSamplesCount := succeededSamplesCount
 
-		for _, s := range ts.Samples {
+		for sampleIdx, s := range ts.Samples {
 			var err error
 
 			// If the cached reference exists, we try to use it.
@@ -732,6 +862,13 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 				}
 			}
 
+			// The zero samples injected by the OTLP handler at the start timestamp of the OTel counters are
+			// followed by the actual sample, and expected to be rejected once the counter has already been
+			// ingested, so they're silently skipped. The samples pushed through the other APIs are never skipped.
+			if ctZeroIngestionEnabled && req.Source == mimirpb.OTLP && s.Value == 0 && sampleIdx < len(ts.Samples)-1 && isCreatedTimestampZeroSampleErr(err) {
+				continue
+			}
+
 			failedSamplesCount++
 
 			// Check if the error is a soft error we can proceed on. If so, we keep track
@@ -741,16 +878,19 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 			switch cause := errors.Cause(err); cause {
 			case storage.ErrOutOfBounds:
 				sampleOutOfBoundsCount++
+				observeTooOldSample(s.TimestampMs)
 				updateFirstPartial(func() error { return newIngestErrSampleTimestampTooOld(model.Time(s.TimestampMs), ts.Labels) })
 				continue
 
 			case storage.ErrOutOfOrderSample:
 				sampleOutOfOrderCount++
+				observeTooOldSample(s.TimestampMs)
 				updateFirstPartial(func() error { return newIngestErrSampleOutOfOrder(model.Time(s.TimestampMs), ts.Labels) })
 				continue
 
 			case storage.ErrTooOldSample:
 				sampleTooOldCount++
+				observeTooOldSample(s.TimestampMs)
 				updateFirstPartial(func() error {
 					return newIngestErrSampleTimestampTooOldOOOEnabled(model.Time(s.TimestampMs), ts.Labels, oooTW)
 				})
@@ -774,6 +914,16 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 					return makeMetricLimitError(perMetricSeriesLimit, copiedLabels, i.limiter.FormatError(userID, cause))
 				})
 				continue
+
+			case errMaxSeriesPerScopeLimitExceeded:
+				if perScopeSeriesLimitCount == nil {
+					perScopeSeriesLimitCount = map[string]int{}
+				}
+				perScopeSeriesLimitCount[db.seriesInScope.scopeOf(userID, copiedLabels)]++
+				updateFirstPartial(func() error {
+					return makeMetricLimitError(perScopeSeriesLimit, copiedLabels, i.limiter.FormatError(userID, cause))
+				})
+				continue
 			}
 
 			// The error looks an issue on our side, so we should rollback
@@ -873,6 +1023,24 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 	if perMetricSeriesLimitCount > 0 {
 		validation.DiscardedSamples.WithLabelValues(perMetricSeriesLimit, userID).Add(float64(perMetricSeriesLimitCount))
 	}
+	for scope, count := range perScopeSeriesLimitCount {
+		validation.DiscardedSamples.WithLabelValues(perScopeSeriesLimit, userID).Add(float64(count))
+		i.metrics.perScopeSeriesLimitDiscardedSamples.WithLabelValues(userID, scope).Add(float64(count))
+	}
+	if admitted := db.warnOnlySeriesAdmitted.Swap(0); admitted > 0 {
+		i.metrics.warnOnlySeriesAdmitted.WithLabelValues(userID).Add(float64(admitted))
+
+		if db.shouldLogWarnOnlySeriesLimit(time.Now(), warnOnlySeriesLimitLogInterval) {
+			level.Warn(i.logger).Log(
+				"msg", "admitted new series exceeding the per-user series limit, because the limit is in warn-only mode",
+				"user", userID,
+				"limit", i.limits.MaxGlobalSeriesPerUser(userID),
+				"local_limit", i.limiter.maxSeriesPerUser(userID),
+				"series", db.Head().NumSeries(),
+				"admitted_series", admitted,
+			)
+		}
+	}
 	if succeededSamplesCount > 0 {
 		i.ingestionRate.Add(int64(succeededSamplesCount))
 
@@ -898,6 +1066,16 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 	return &mimirpb.WriteResponse{}, nil
 }
 
+// applyRetention returns the input time range shrunk to the data within the tenant's retention, so that shrinking
+// the retention takes effect immediately on the queried in-memory data, instead of only once the compactor deletes the
+// blocks. The returned bool is false if the whole time range is outside the retention.
+func (i *Ingester) applyRetention(userID string, mint, maxt int64) (int64, int64, bool) {
+	if retention := i.limits.CompactorBlocksRetentionPeriod(userID); retention > 0 {
+		mint = util_math.Max64(mint, time.Now().Add(-retention).UnixMilli())
+	}
+	return mint, maxt, mint <= maxt
+}
+
 func (i *Ingester) QueryExemplars(ctx context.Context, req *client.ExemplarQueryRequest) (*client.ExemplarQueryResponse, error) {
 	if err := i.checkRunning(); err != nil {
 		return nil, err
@@ -923,6 +1101,15 @@ func (i *Ingester) QueryExemplars(ctx context.Context, req *client.ExemplarQuery
 		return &client.ExemplarQueryResponse{}, nil
 	}
 
+	if err := i.checkReadRequest(db, matchers...); err != nil {
+		return nil, err
+	}
+
+	from, through, ok := i.applyRetention(userID, from, through)
+	if !ok {
+		return &client.ExemplarQueryResponse{}, nil
+	}
+
 	q, err := db.ExemplarQuerier(ctx)
 	if err != nil {
 		return nil, err
@@ -972,6 +1159,11 @@ func (i *Ingester) LabelValues(ctx context.Context, req *client.LabelValuesReque
 		return &client.LabelValuesResponse{}, nil
 	}
 
+	startTimestampMs, endTimestampMs, ok := i.applyRetention(userID, startTimestampMs, endTimestampMs)
+	if !ok {
+		return &client.LabelValuesResponse{}, nil
+	}
+
 	q, err := db.Querier(ctx, startTimestampMs, endTimestampMs)
 	if err != nil {
 		return nil, err
@@ -1008,6 +1200,11 @@ func (i *Ingester) LabelNames(ctx context.Context, req *client.LabelNamesRequest
 		return nil, err
 	}
 
+	mint, maxt, ok := i.applyRetention(userID, mint, maxt)
+	if !ok {
+		return &client.LabelNamesResponse{}, nil
+	}
+
 	q, err := db.Querier(ctx, mint, maxt)
 	if err != nil {
 		return nil, err
@@ -1045,7 +1242,15 @@ func (i *Ingester) MetricsForLabelMatchers(ctx context.Context, req *client.Metr
 		return nil, err
 	}
 
-	mint, maxt := req.StartTimestampMs, req.EndTimestampMs
+	if err := i.checkReadRequest(db, matchersSet...); err != nil {
+		return nil, err
+	}
+
+	mint, maxt, ok := i.applyRetention(userID, req.StartTimestampMs, req.EndTimestampMs)
+	if !ok {
+		return &client.MetricsForLabelMatchersResponse{Metric: make([]*mimirpb.Metric, 0)}, nil
+	}
+
 	q, err := db.Querier(ctx, mint, maxt)
 	if err != nil {
 		return nil, err
@@ -1196,6 +1401,83 @@ func (i *Ingester) LabelValuesCardinality(req *client.LabelValuesCardinalityRequ
 	)
 }
 
+// HeadCardinalityStats returns the statistics about the cardinality of the in-memory series of the tenant.
+func (i *Ingester) HeadCardinalityStats(ctx context.Context, req *client.HeadCardinalityStatsRequest) (*client.HeadCardinalityStatsResponse, error) {
+	if err := i.checkRunning(); err != nil {
+		return nil, err
+	}
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db := i.getTSDB(userID)
+	if db == nil {
+		return &client.HeadCardinalityStatsResponse{}, nil
+	}
+
+	// The statistics are computed from all the in-memory series.
+	if err := i.checkReadRequest(db, nil); err != nil {
+		return nil, err
+	}
+
+	idx, err := db.Head().Index()
+	if err != nil {
+		return nil, err
+	}
+	defer idx.Close()
+
+	resp, err := headCardinalityStats(ctx, idx, int(req.GetLimit()))
+	if err != nil {
+		return nil, err
+	}
+	resp.NumSeries = db.Head().NumSeries()
+	return resp, nil
+}
+
+// ActiveSeries implements client.IngesterServer.
+func (i *Ingester) ActiveSeries(ctx context.Context, req *client.ActiveSeriesRequest) (*client.ActiveSeriesResponse, error) {
+	if err := i.checkRunning(); err != nil {
+		return nil, err
+	}
+	if !i.cfg.ActiveSeriesMetricsEnabled {
+		return nil, errors.New("the active series can't be listed because their tracking is disabled")
+	}
+
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db := i.getTSDB(userID)
+	if db == nil {
+		return &client.ActiveSeriesResponse{}, nil
+	}
+
+	matchers, err := client.FromLabelMatchers(req.GetMatchers())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.checkReadRequest(db, matchers); err != nil {
+		return nil, err
+	}
+
+	series := db.activeSeries.MatchingSeries(matchers, time.Now())
+	sort.Slice(series, func(i, j int) bool {
+		return labels.Compare(series[i], series[j]) < 0
+	})
+	if limit := int(req.GetLimit()); limit > 0 && len(series) > limit {
+		series = series[:limit]
+	}
+
+	resp := &client.ActiveSeriesResponse{Metric: make([]*mimirpb.Metric, 0, len(series))}
+	for _, s := range series {
+		resp.Metric = append(resp.Metric, &mimirpb.Metric{Labels: mimirpb.FromLabelsToLabelAdapters(s)})
+	}
+	return resp, nil
+}
+
 func createUserStats(db *userTSDB) *client.UserStatsResponse {
 	apiRate := db.ingestedAPISamples.Rate()
 	ruleRate := db.ingestedRuleSamples.Rate()
@@ -1242,8 +1524,18 @@ func (i *Ingester) QueryStream(req *client.QueryRequest, stream client.Ingester_
 		return nil
 	}
 
+	if err := i.checkReadRequest(db, matchers); err != nil {
+		return err
+	}
+
+	mint, maxt, ok := i.applyRetention(userID, int64(from), int64(through))
+	if !ok {
+		return nil
+	}
+
 	numSamples := 0
 	numSeries := 0
+	numChunks := 0
 
 	streamType := QueryStreamSamples
 	if i.cfg.StreamChunksWhenUsingBlocks {
@@ -1264,10 +1556,10 @@ func (i *Ingester) QueryStream(req *client.QueryRequest, stream client.Ingester_
 
 	if streamType == QueryStreamChunks {
 		level.Debug(spanlog).Log("msg", "using queryStreamChunks")
-		numSeries, numSamples, err = i.queryStreamChunks(ctx, db, int64(from), int64(through), matchers, shard, stream)
+		numSeries, numSamples, numChunks, err = i.queryStreamChunks(ctx, db, mint, maxt, matchers, shard, stream)
 	} else {
 		level.Debug(spanlog).Log("msg", "using queryStreamSamples")
-		numSeries, numSamples, err = i.queryStreamSamples(ctx, db, int64(from), int64(through), matchers, shard, stream)
+		numSeries, numSamples, err = i.queryStreamSamples(ctx, db, mint, maxt, matchers, shard, stream)
 	}
 	if err != nil {
 		return err
@@ -1275,7 +1567,10 @@ func (i *Ingester) QueryStream(req *client.QueryRequest, stream client.Ingester_
 
 	i.metrics.queriedSeries.Observe(float64(numSeries))
 	i.metrics.queriedSamples.Observe(float64(numSamples))
-	level.Debug(spanlog).Log("series", numSeries, "samples", numSamples)
+	if streamType == QueryStreamChunks {
+		i.metrics.queriedChunks.Observe(float64(numChunks))
+	}
+	level.Debug(spanlog).Log("series", numSeries, "samples", numSamples, "chunks", numChunks)
 	return nil
 }
 
@@ -1352,8 +1647,15 @@ func (i *Ingester) queryStreamSamples(ctx context.Context, db *userTSDB, from, t
 	return numSeries, numSamples, nil
 }
 
-// queryStreamChunks streams metrics from a TSDB. This implements the client.IngesterServer interface
-func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, through int64, matchers []*labels.Matcher, shard *sharding.ShardSelector, stream client.Ingester_QueryStreamServer) (numSeries, numSamples int, _ error) {
+// queryStreamChunks streams the chunks of the series from a TSDB in bounded messages. Each message holds at most
+// queryStreamBatchSize series, StreamChunksBatchSize chunks and about queryStreamBatchMessageSize bytes, so that the
+// ingester doesn't have to buffer the whole chunks of a high-cardinality series in a single message, and the size of
+// each gRPC message received by the querier is bounded: the chunks of a series exceeding these bounds are sent in
+// multiple consecutive messages. The querier still accumulates the chunks of all the series of all the ingesters before
+// returning them, so its memory usage is bounded by the query limits, not by the messages size. Sending a message
+// blocks while the querier doesn't keep up, because of the gRPC flow control, so the ingester never reads the chunks
+// much faster than the querier consumes them.
+func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, through int64, matchers []*labels.Matcher, shard *sharding.ShardSelector, stream client.Ingester_QueryStreamServer) (numSeries, numSamples, numChunks int, _ error) {
 	var q storage.ChunkQuerier
 	var err error
 	if i.limits.OutOfOrderTimeWindow(db.userID) > 0 {
@@ -1362,7 +1664,7 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 		q, err = db.ChunkQuerier(ctx, from, through)
 	}
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 	defer q.Close()
 
@@ -1375,18 +1677,44 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 	// It's not required to return sorted series because series are sorted by the Mimir querier.
 	ss := q.Select(false, hints, matchers...)
 	if ss.Err() != nil {
-		return 0, 0, ss.Err()
+		return 0, 0, 0, ss.Err()
+	}
+
+	var (
+		batch          = make([]client.TimeSeriesChunk, 0, queryStreamBatchSize)
+		batchSizeBytes = 0
+		batchChunks    = 0
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := client.SendQueryStream(stream, &client.QueryStreamResponse{Chunkseries: batch}); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		batchSizeBytes = 0
+		batchChunks = 0
+		return nil
 	}
 
-	chunkSeries := make([]client.TimeSeriesChunk, 0, queryStreamBatchSize)
-	batchSizeBytes := 0
 	for ss.Next() {
 		series := ss.At()
 
+		if len(batch) >= queryStreamBatchSize {
+			if err := flush(); err != nil {
+				return 0, 0, 0, err
+			}
+		}
+
 		// convert labels to LabelAdapter
 		ts := client.TimeSeriesChunk{
 			Labels: mimirpb.FromLabelsToLabelAdapters(series.Labels()),
 		}
+		labelsSize := ts.Size()
+		tsSize := labelsSize
+		partSent := false
 
 		it := series.Iterator()
 		for it.Next() {
@@ -1396,7 +1724,7 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 			// It is not guaranteed that chunk returned by iterator is populated.
 			// For now just return error. We could also try to figure out how to read the chunk.
 			if meta.Chunk == nil {
-				return 0, 0, errors.Errorf("unfilled chunk returned from TSDB chunk querier")
+				return 0, 0, 0, errors.Errorf("unfilled chunk returned from TSDB chunk querier")
 			}
 
 			ch := client.Chunk{
@@ -1409,49 +1737,58 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 			case chunkenc.EncXOR:
 				ch.Encoding = int32(chunk.PrometheusXorChunk)
 			default:
-				return 0, 0, errors.Errorf("unknown chunk encoding from TSDB chunk querier: %v", meta.Chunk.Encoding())
+				return 0, 0, 0, errors.Errorf("unknown chunk encoding from TSDB chunk querier: %v", meta.Chunk.Encoding())
+			}
+
+			chSize := encodedFieldSize(ch.Size())
+			msgChunks := batchChunks + len(ts.Chunks)
+			tooManyChunks := i.cfg.StreamChunksBatchSize > 0 && msgChunks >= i.cfg.StreamChunksBatchSize
+			tooBig := batchSizeBytes+encodedFieldSize(tsSize+chSize) > queryStreamBatchMessageSize
+			if msgChunks > 0 && (tooManyChunks || tooBig) {
+				// Adding this chunk to the message would make it too big, so send the chunks
+				// of the series collected so far and continue the series in the next message.
+				if len(ts.Chunks) > 0 {
+					batch = append(batch, ts)
+					partSent = true
+				}
+				if err := flush(); err != nil {
+					return 0, 0, 0, err
+				}
+
+				ts = client.TimeSeriesChunk{Labels: ts.Labels}
+				tsSize = labelsSize
 			}
 
 			ts.Chunks = append(ts.Chunks, ch)
+			tsSize += chSize
 			numSamples += meta.Chunk.NumSamples()
+			numChunks++
 		}
 		numSeries++
-		tsSize := ts.Size()
-
-		if (batchSizeBytes > 0 && batchSizeBytes+tsSize > queryStreamBatchMessageSize) || len(chunkSeries) >= queryStreamBatchSize {
-			// Adding this series to the batch would make it too big,
-			// flush the data and add it to new batch instead.
-			err = client.SendQueryStream(stream, &client.QueryStreamResponse{
-				Chunkseries: chunkSeries,
-			})
-			if err != nil {
-				return 0, 0, err
-			}
 
-			batchSizeBytes = 0
-			chunkSeries = chunkSeries[:0]
+		if len(ts.Chunks) > 0 || !partSent {
+			batch = append(batch, ts)
+			batchSizeBytes += encodedFieldSize(tsSize)
+			batchChunks += len(ts.Chunks)
 		}
-
-		chunkSeries = append(chunkSeries, ts)
-		batchSizeBytes += tsSize
 	}
 
 	// Ensure no error occurred while iterating the series set.
 	if err := ss.Err(); err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
 	// Final flush any existing metrics
-	if batchSizeBytes != 0 {
-		err = client.SendQueryStream(stream, &client.QueryStreamResponse{
-			Chunkseries: chunkSeries,
-		})
-		if err != nil {
-			return 0, 0, err
-		}
+	if err := flush(); err != nil {
+		return 0, 0, 0, err
 	}
 
-	return numSeries, numSamples, nil
+	return numSeries, numSamples, numChunks, nil
+}
+
+// encodedFieldSize returns the size of a protobuf message of the given size once encoded as a field of another message.
+func encodedFieldSize(size int) int {
+	return 1 + proto.SizeVarint(uint64(size)) + size
 }
 
 func (i *Ingester) getTSDB(userID string) *userTSDB {
@@ -1530,15 +1867,31 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 	blockRanges := i.cfg.BlocksStorageConfig.TSDB.BlockRanges.ToMilliseconds()
 	matchersConfig := i.limits.ActiveSeriesCustomTrackersConfig(userID)
 
+	// With a custom block duration, the TSDB only creates blocks of that duration.
+	blockDuration := i.blockDurationForUser(userID, userLogger)
+	if blockDuration != i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0] {
+		blockRanges = []int64{blockDuration.Milliseconds()}
+	}
+
 	userDB := &userTSDB{
-		userID:              userID,
-		activeSeries:        activeseries.NewActiveSeries(activeseries.NewMatchers(matchersConfig), i.cfg.ActiveSeriesMetricsIdleTimeout),
-		seriesInMetric:      newMetricCounter(i.limiter, i.cfg.getIgnoreSeriesLimitForMetricNamesMap()),
-		ingestedAPISamples:  util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
-		ingestedRuleSamples: util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
+		userID:                  userID,
+		blockDuration:           blockDuration,
+		activeSeries:            activeseries.NewActiveSeries(activeseries.NewMatchers(matchersConfig), i.cfg.ActiveSeriesMetricsIdleTimeout),
+		seriesInMetric:          newMetricCounter(i.limiter, i.cfg.getIgnoreSeriesLimitForMetricNamesMap()),
+		seriesInScope:           newScopeCounter(i.limiter),
+		rejectedSamplesLateness: newLatenessTracker(time.Now()),
+		ingestedAPISamples:      util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
+		ingestedRuleSamples:     util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
 
 		instanceLimitsFn:    i.getInstanceLimits,
 		instanceSeriesCount: &i.seriesCount,
+
+		useOwnedSeriesForLimits: i.cfg.UseOwnedSeriesForLimits,
+	}
+	if i.ownedSeriesRing != nil {
+		userDB.isOwnedSeries = func(series labels.Labels) bool {
+			return i.isOwnedSeries(userDB, series)
+		}
 	}
 
 	maxExemplars := i.limiter.convertGlobalToLocalLimit(userID, i.limits.MaxGlobalExemplarsPerUser(userID))
@@ -1560,7 +1913,7 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 		MaxExemplars:                   int64(maxExemplars),
 		SeriesHashCache:                i.seriesHashCache,
 		EnableMemorySnapshotOnShutdown: i.cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown,
-		IsolationDisabled:              true,
+		IsolationDisabled:              true, // Read isolation isn't needed, and its bookkeeping is CPU expensive with many concurrent appenders.
 		HeadChunksWriteQueueSize:       i.cfg.BlocksStorageConfig.TSDB.HeadChunksWriteQueueSize,
 		AllowOverlappingQueries:        true,                 // We can have overlapping blocks from past or out-of-order enabled during runtime.
 		AllowOverlappingCompaction:     false,                // always false since Mimir only uploads lvl 1 compacted blocks
@@ -1605,6 +1958,8 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 			bucket.NewUserBucketClient(userID, i.bucket, i.limits),
 			metadata.ReceiveSource,
 			metadata.NoneFunc,
+			func() []mimirpb.MetricMetadata { return i.metricMetadataForBlock(userID) },
+			func(minT, maxT int64) []mimirpb.TimeSeries { return i.exemplarsForBlock(userDB, minT, maxT) },
 		)
 
 		// Initialise the shipper blocks cache.
@@ -1617,6 +1972,22 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 	return userDB, nil
 }
 
+// blockDurationForUser returns the duration of the TSDB blocks of the user. A custom block duration which doesn't
+// evenly divide the configured block ranges period is ignored, because the blocks wouldn't be aligned.
+func (i *Ingester) blockDurationForUser(userID string, userLogger log.Logger) time.Duration {
+	defaultDuration := i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0]
+
+	duration := i.limits.TSDBBlockDuration(userID)
+	if duration <= 0 {
+		return defaultDuration
+	}
+	if duration > defaultDuration || defaultDuration%duration != 0 {
+		level.Warn(userLogger).Log("msg", "ignoring the TSDB block duration because it doesn't evenly divide the block ranges period", "block_duration", duration, "block_ranges_period", defaultDuration)
+		return defaultDuration
+	}
+	return duration
+}
+
 func (i *Ingester) closeAllTSDB() {
 	i.tsdbsMtx.Lock()
 
@@ -1658,13 +2029,34 @@ func (i *Ingester) closeAllTSDB() {
 func (i *Ingester) openExistingTSDB(ctx context.Context) error {
 	level.Info(i.logger).Log("msg", "opening existing TSDBs")
 
-	queue := make(chan string)
+	// Find all users with a TSDB on the filesystem first, so that the whole WAL replay progress is known
+	// before opening them.
+	toOpen, err := i.findExistingTSDBs()
+	if err != nil {
+		level.Error(i.logger).Log("msg", "error while opening existing TSDBs", "err", err)
+		return err
+	}
+
+	progress := newWALReplayProgress(i.metrics)
+	queue := make(chan tsdbToOpen, len(toOpen))
+	for _, t := range toOpen {
+		progress.enqueued(t.walSegments)
+		queue <- t
+	}
+	close(queue)
+
 	group, groupCtx := errgroup.WithContext(ctx)
 
 	// Create a pool of workers which will open existing TSDBs.
 	for n := 0; n < i.cfg.BlocksStorageConfig.TSDB.MaxTSDBOpeningConcurrencyOnStartup; n++ {
 		group.Go(func() error {
-			for userID := range queue {
+			for toOpen := range queue {
+				// Interrupt in case a failure occurred in another goroutine.
+				if groupCtx.Err() != nil {
+					return nil
+				}
+
+				userID := toOpen.userID
 				startTime := time.Now()
 
 				db, err := i.createTSDB(userID)
@@ -1680,80 +2072,138 @@ func (i *Ingester) openExistingTSDB(ctx context.Context) error {
 				i.metrics.memUsers.Inc()
 
 				i.metrics.walReplayTime.Observe(time.Since(startTime).Seconds())
+
+				remainingTenants, remainingSegments, eta := progress.replayed(toOpen.walSegments)
+				level.Info(i.logger).Log("msg", "opened TSDB", "user", userID, "wal_segments", toOpen.walSegments, "duration", time.Since(startTime),
+					"remaining_tenants", remainingTenants, "remaining_wal_segments", remainingSegments, "estimated_time_remaining", eta)
 			}
 
 			return nil
 		})
 	}
 
-	// Spawn a goroutine to find all users with a TSDB on the filesystem.
-	group.Go(func() error {
-		// Close the queue once filesystem walking is done.
-		defer close(queue)
+	// Wait for all workers to complete.
+	err = group.Wait()
+	if err != nil {
+		level.Error(i.logger).Log("msg", "error while opening existing TSDBs", "err", err)
+		return err
+	}
 
-		walkErr := filepath.Walk(i.cfg.BlocksStorageConfig.TSDB.Dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				// If the root directory doesn't exist, we're OK (not needed to be created upfront).
-				if os.IsNotExist(err) && path == i.cfg.BlocksStorageConfig.TSDB.Dir {
-					return filepath.SkipDir
-				}
+	// Update the usage statistics once all TSDBs have been opened.
+	i.updateUsageStats()
 
-				level.Error(i.logger).Log("msg", "an error occurred while iterating the filesystem storing TSDBs", "path", path, "err", err)
-				return errors.Wrapf(err, "an error occurred while iterating the filesystem storing TSDBs at %s", path)
-			}
+	level.Info(i.logger).Log("msg", "successfully opened existing TSDBs")
+	return nil
+}
 
-			// Skip root dir and all other files
-			if path == i.cfg.BlocksStorageConfig.TSDB.Dir || !info.IsDir() {
-				return nil
-			}
+// tsdbToOpen is a TSDB found on the filesystem at startup.
+type tsdbToOpen struct {
+	userID      string
+	walSegments int
+}
 
-			// Top level directories are assumed to be user TSDBs
-			userID := info.Name()
-			f, err := os.Open(path)
-			if err != nil {
-				level.Error(i.logger).Log("msg", "unable to open TSDB dir", "err", err, "user", userID, "path", path)
-				return errors.Wrapf(err, "unable to open TSDB dir %s for user %s", path, userID)
+// findExistingTSDBs walks the user tsdb dir, and returns the non-empty TSDB of each user, with the number of segments of its WAL.
+func (i *Ingester) findExistingTSDBs() ([]tsdbToOpen, error) {
+	var found []tsdbToOpen
+
+	walkErr := filepath.Walk(i.cfg.BlocksStorageConfig.TSDB.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// If the root directory doesn't exist, we're OK (not needed to be created upfront).
+			if os.IsNotExist(err) && path == i.cfg.BlocksStorageConfig.TSDB.Dir {
+				return filepath.SkipDir
 			}
-			defer f.Close()
 
-			// If the dir is empty skip it
-			if _, err := f.Readdirnames(1); err != nil {
-				if err == io.EOF {
-					return filepath.SkipDir
-				}
+			level.Error(i.logger).Log("msg", "an error occurred while iterating the filesystem storing TSDBs", "path", path, "err", err)
+			return errors.Wrapf(err, "an error occurred while iterating the filesystem storing TSDBs at %s", path)
+		}
 
-				level.Error(i.logger).Log("msg", "unable to read TSDB dir", "err", err, "user", userID, "path", path)
-				return errors.Wrapf(err, "unable to read TSDB dir %s for user %s", path, userID)
-			}
+		// Skip root dir and all other files
+		if path == i.cfg.BlocksStorageConfig.TSDB.Dir || !info.IsDir() {
+			return nil
+		}
 
-			// Enqueue the user to be processed.
-			select {
-			case queue <- userID:
-				// Nothing to do.
-			case <-groupCtx.Done():
-				// Interrupt in case a failure occurred in another goroutine.
-				return nil
+		// Top level directories are assumed to be user TSDBs
+		userID := info.Name()
+		f, err := os.Open(path)
+		if err != nil {
+			level.Error(i.logger).Log("msg", "unable to open TSDB dir", "err", err, "user", userID, "path", path)
+			return errors.Wrapf(err, "unable to open TSDB dir %s for user %s", path, userID)
+		}
+		defer f.Close()
+
+		// If the dir is empty skip it
+		if _, err := f.Readdirnames(1); err != nil {
+			if err == io.EOF {
+				return filepath.SkipDir
 			}
 
-			// Don't descend into subdirectories.
-			return filepath.SkipDir
-		})
+			level.Error(i.logger).Log("msg", "unable to read TSDB dir", "err", err, "user", userID, "path", path)
+			return errors.Wrapf(err, "unable to read TSDB dir %s for user %s", path, userID)
+		}
+
+		found = append(found, tsdbToOpen{userID: userID, walSegments: countWALSegments(filepath.Join(path, "wal"))})
 
-		return errors.Wrapf(walkErr, "unable to walk directory %s containing existing TSDBs", i.cfg.BlocksStorageConfig.TSDB.Dir)
+		// Don't descend into subdirectories.
+		return filepath.SkipDir
 	})
 
-	// Wait for all workers to complete.
-	err := group.Wait()
-	if err != nil {
-		level.Error(i.logger).Log("msg", "error while opening existing TSDBs", "err", err)
-		return err
+	return found, errors.Wrapf(walkErr, "unable to walk directory %s containing existing TSDBs", i.cfg.BlocksStorageConfig.TSDB.Dir)
+}
+
+// countWALSegments returns the number of segments in the input WAL directory, or 0 if they can't be listed.
+func countWALSegments(walDir string) int {
+	first, last, err := wal.Segments(walDir)
+	if err != nil || first < 0 {
+		return 0
 	}
+	return last - first + 1
+}
 
-	// Update the usage statistics once all TSDBs have been opened.
-	i.updateUsageStats()
+// walReplayProgress tracks the progress of the WAL replay of the TSDBs opened at startup.
+type walReplayProgress struct {
+	metrics   *ingesterMetrics
+	startTime time.Time
 
-	level.Info(i.logger).Log("msg", "successfully opened existing TSDBs")
-	return nil
+	mtx               sync.Mutex
+	remainingTenants  int
+	remainingSegments int
+	replayedSegments  int
+}
+
+func newWALReplayProgress(metrics *ingesterMetrics) *walReplayProgress {
+	return &walReplayProgress{metrics: metrics, startTime: time.Now()}
+}
+
+// enqueued records a TSDB, whose WAL has the input number of segments, to be opened.
+func (p *walReplayProgress) enqueued(segments int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.remainingTenants++
+	p.remainingSegments += segments
+	p.metrics.walReplayTenantsRemaining.Set(float64(p.remainingTenants))
+	p.metrics.walReplaySegmentsRemaining.Set(float64(p.remainingSegments))
+}
+
+// replayed records a TSDB, whose WAL has the input number of segments, as opened. It returns the
+// remaining number of tenants and WAL segments to replay, and the estimated time to replay them
+// based on the rate of segments replayed so far.
+func (p *walReplayProgress) replayed(segments int) (int, int, time.Duration) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.remainingTenants--
+	p.remainingSegments -= segments
+	p.replayedSegments += segments
+	p.metrics.walReplayTenantsRemaining.Set(float64(p.remainingTenants))
+	p.metrics.walReplaySegmentsRemaining.Set(float64(p.remainingSegments))
+	p.metrics.walReplaySegmentsReplayed.Add(float64(segments))
+
+	var eta time.Duration
+	if p.replayedSegments > 0 {
+		eta = time.Duration(float64(time.Since(p.startTime)) / float64(p.replayedSegments) * float64(p.remainingSegments)).Round(time.Second)
+	}
+	return p.remainingTenants, p.remainingSegments, eta
 }
 
 // getMemorySeriesMetric returns the total number of in-memory series across all open TSDBs.
@@ -1892,11 +2342,22 @@ func (i *Ingester) compactionLoop(ctx context.Context) error {
 	ticker := time.NewTicker(i.cfg.BlocksStorageConfig.TSDB.HeadCompactionInterval)
 	defer ticker.Stop()
 
+	// Memory snapshots run in the compaction loop, so that they never run concurrently with the head compaction.
+	var memorySnapshotTickerChan <-chan time.Time
+	if i.cfg.BlocksStorageConfig.TSDB.MemorySnapshotInterval > 0 {
+		memorySnapshotTicker := time.NewTicker(i.cfg.BlocksStorageConfig.TSDB.MemorySnapshotInterval)
+		defer memorySnapshotTicker.Stop()
+		memorySnapshotTickerChan = memorySnapshotTicker.C
+	}
+
 	for ctx.Err() == nil {
 		select {
 		case <-ticker.C:
 			i.compactBlocks(ctx, false, nil)
 
+		case <-memorySnapshotTickerChan:
+			i.snapshotHeads(ctx)
+
 		case req := <-i.forceCompactTrigger:
 			i.compactBlocks(ctx, true, req.users)
 			close(req.callback) // Notify back.
@@ -1919,6 +2380,10 @@ func (i *Ingester) compactBlocks(ctx context.Context, force bool, allowed *util.
 		}
 	}
 
+	// The disk pressure is checked once per compaction, for all the tenants.
+	underDiskPressure := !force && i.diskPressureMonitor != nil && i.diskPressureMonitor.check()
+	headChunksRetention := i.cfg.BlocksStorageConfig.TSDB.HeadChunksRetention.Milliseconds()
+
 	_ = concurrency.ForEachUser(ctx, i.getTSDBUsers(), i.cfg.BlocksStorageConfig.TSDB.HeadCompactionConcurrency, func(ctx context.Context, userID string) error {
 		if !allowed.IsAllowed(userID) {
 			return nil
@@ -1929,26 +2394,51 @@ func (i *Ingester) compactBlocks(ctx context.Context, force bool, allowed *util.
 			return nil
 		}
 
+		// While under disk pressure, the blocks already shipped to the storage are deleted at the next reload of
+		// the TSDB blocks, regardless of the retention, to free up disk space without compacting the head early.
+		if !force {
+			userDB.deleteShippedBlocks.Store(underDiskPressure)
+		}
+
 		// Don't do anything, if there is nothing to compact.
 		h := userDB.Head()
 		if h.NumSeries() == 0 {
 			return nil
 		}
 
-		var err error
+		var (
+			err       error
+			compacted bool
+		)
 
 		i.metrics.compactionsTriggered.Inc()
 
+		idleTimeout := i.compactionIdleTimeout
+		if userIdleTimeout := i.limits.TSDBHeadCompactionIdleTimeout(userID); userIdleTimeout > 0 {
+			idleTimeout = util.DurationWithPositiveJitter(userIdleTimeout, compactionIdleTimeoutJitter)
+		}
+
 		reason := ""
 		switch {
 		case force:
 			reason = "forced"
-			err = userDB.compactHead(i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0].Milliseconds())
+			err = userDB.compactHead(userDB.blockDuration.Milliseconds())
 
-		case i.compactionIdleTimeout > 0 && userDB.isIdle(time.Now(), i.compactionIdleTimeout):
+		case idleTimeout > 0 && userDB.isIdle(time.Now(), idleTimeout):
 			reason = "idle"
 			level.Info(i.logger).Log("msg", "TSDB is idle, forcing compaction", "user", userID)
-			err = userDB.compactHead(i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0].Milliseconds())
+			err = userDB.compactHead(userDB.blockDuration.Milliseconds())
+
+		case headChunksRetention > 0 && h.MaxTime()-h.MinTime() > headChunksRetention:
+			// The block aligned time ranges older than the retention are compacted early, then the regular
+			// compaction takes care of the rest.
+			reason = "retention"
+			if compacted, err = userDB.compactHeadBlocksUntil(userDB.blockDuration.Milliseconds(), h.MaxTime()-headChunksRetention); compacted {
+				i.metrics.earlyHeadCompactions.WithLabelValues(earlyHeadCompactionReasonRetention).Inc()
+			}
+			if err == nil {
+				err = userDB.Compact()
+			}
 
 		default:
 			reason = "regular"
@@ -1966,6 +2456,26 @@ func (i *Ingester) compactBlocks(ctx context.Context, force bool, allowed *util.
 	})
 }
 
+// snapshotHeads snapshots the in-memory data of all TSDBs on disk, so that at startup only the WAL
+// written after the snapshot needs to be replayed. The pushes of each tenant are blocked while its
+// TSDB is snapshotted.
+func (i *Ingester) snapshotHeads(ctx context.Context) {
+	_ = concurrency.ForEachUser(ctx, i.getTSDBUsers(), i.cfg.BlocksStorageConfig.TSDB.HeadCompactionConcurrency, func(ctx context.Context, userID string) error {
+		userDB := i.getTSDB(userID)
+		if userDB == nil || userDB.Head().NumSeries() == 0 {
+			return nil
+		}
+
+		i.metrics.memorySnapshotsTriggered.Inc()
+
+		if err := userDB.chunkSnapshot(); err != nil {
+			i.metrics.memorySnapshotsFailed.Inc()
+			level.Warn(i.logger).Log("msg", "TSDB memory snapshot for user has failed", "user", userID, "err", err)
+		}
+		return nil
+	})
+}
+
 func (i *Ingester) closeAndDeleteIdleUserTSDBs(ctx context.Context) error {
 	for _, userID := range i.getTSDBUsers() {
 		if ctx.Err() != nil {
@@ -2084,12 +2594,66 @@ func (i *Ingester) Flush() {
 		i.shipBlocks(ctx, nil)
 	}
 
+	if i.uploadBeforeUnregister.Load() {
+		if err := i.awaitBlocksShipped(ctx); err != nil {
+			// Keep the ingester registered in the ring, so that its tokens aren't taken over while
+			// the unshipped blocks are still on its local disk.
+			level.Error(i.logger).Log("msg", "not unregistering from the ring because not all TSDB blocks have been shipped", "err", err)
+			i.uploadBeforeUnregisterErr.Store(err)
+			i.lifecycler.SetUnregisterOnShutdown(false)
+			return
+		}
+	}
+
 	level.Info(i.logger).Log("msg", "finished flushing and shipping TSDB blocks")
 }
 
+// awaitBlocksShipped retries shipping the TSDB blocks until all of them have been shipped to the storage,
+// or the retries are exhausted.
+func (i *Ingester) awaitBlocksShipped(ctx context.Context) error {
+	boff := backoff.New(ctx, backoff.Config{
+		MinBackoff: time.Second,
+		MaxBackoff: 10 * time.Second,
+		MaxRetries: 5,
+	})
+
+	for {
+		tenants := i.getTenantsWithUnshippedBlocks()
+		if len(tenants) == 0 {
+			return nil
+		}
+
+		boff.Wait()
+		if !boff.Ongoing() {
+			return fmt.Errorf("TSDB blocks not shipped for tenants: %s", strings.Join(tenants, ", "))
+		}
+
+		level.Warn(i.logger).Log("msg", "retrying to ship TSDB blocks", "tenants", len(tenants))
+		i.shipBlocks(ctx, util.NewAllowedTenants(tenants, nil))
+	}
+}
+
+// getTenantsWithUnshippedBlocks returns the tenants having TSDB blocks not shipped to the storage yet.
+// Tenants marked for deletion are skipped, because their blocks are never shipped.
+func (i *Ingester) getTenantsWithUnshippedBlocks() []string {
+	var tenants []string
+	for _, userID := range i.getTSDBUsers() {
+		userDB := i.getTSDB(userID)
+		if userDB == nil || userDB.shipper == nil || userDB.deletionMarkFound.Load() {
+			continue
+		}
+		if userDB.getOldestUnshippedBlockTime() > 0 {
+			tenants = append(tenants, userID)
+		}
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
 const (
 	tenantParam = "tenant"
 	waitParam   = "wait"
+	uploadParam = "upload"
 )
 
 // Blocks version of Flush handler. It force-compacts blocks, and triggers shipping.
@@ -2228,7 +2792,22 @@ func (i *Ingester) getInstanceLimits() *InstanceLimits {
 // ShutdownHandler triggers the following set of operations in order:
 //   - Change the state of ring to stop accepting writes.
 //   - Flush all the chunks.
+//   - Unregister from the ring.
+//
+// When called with upload=true, the ingester only unregisters from the ring once all the TSDB blocks
+// have been shipped to the storage. If shipping fails, the ingester is shut down but keeps its tokens
+// in the ring, and the handler responds with an error.
 func (i *Ingester) ShutdownHandler(w http.ResponseWriter, r *http.Request) {
+	if r != nil && r.FormValue(uploadParam) == "true" {
+		if !i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
+			http.Error(w, "TSDB blocks shipping is disabled", http.StatusBadRequest)
+			return
+		}
+		i.uploadBeforeUnregisterErr.Store(nil)
+		i.uploadBeforeUnregister.Store(true)
+		defer i.uploadBeforeUnregister.Store(false)
+	}
+
 	originalFlush := i.lifecycler.FlushOnShutdown()
 	// We want to flush the chunks if transfer fails irrespective of original flag.
 	i.lifecycler.SetFlushOnShutdown(true)
@@ -2242,6 +2821,11 @@ func (i *Ingester) ShutdownHandler(w http.ResponseWriter, r *http.Request) {
 	i.lifecycler.SetFlushOnShutdown(originalFlush)
 	i.lifecycler.SetUnregisterOnShutdown(originalUnregister)
 
+	if err := i.uploadBeforeUnregisterErr.Load(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -2315,6 +2899,50 @@ func (i *Ingester) getOrCreateUserMetadata(userID string) *userMetricsMetadata {
 	return userMetadata
 }
 
+// metricMetadataForBlock returns the metric metadata of the tenant to persist in a block, up to the tenant limit.
+func (i *Ingester) metricMetadataForBlock(userID string) []mimirpb.MetricMetadata {
+	limit := i.limits.MaxMetadataPerBlock(userID)
+	if limit <= 0 {
+		return nil
+	}
+
+	userMetadata := i.getUserMetadata(userID)
+	if userMetadata == nil {
+		return nil
+	}
+	return mimir_tsdb.MergeMetricMetadata(limit, userMetadata.toMetadata())
+}
+
+// exemplarsForBlock returns the in-memory exemplars of the tenant within the time range of a block, to persist in the block,
+// up to the tenant limit. The block max time is exclusive.
+func (i *Ingester) exemplarsForBlock(db *userTSDB, minT, maxT int64) []mimirpb.TimeSeries {
+	limit := i.limits.MaxExemplarsPerBlock(db.userID)
+	if limit <= 0 {
+		return nil
+	}
+
+	q, err := db.ExemplarQuerier(context.Background())
+	if err != nil {
+		level.Warn(i.logger).Log("msg", "failed to query the exemplars to persist in the block", "user", db.userID, "err", err)
+		return nil
+	}
+
+	results, err := q.Select(minT, maxT-1, []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")})
+	if err != nil {
+		level.Warn(i.logger).Log("msg", "failed to query the exemplars to persist in the block", "user", db.userID, "err", err)
+		return nil
+	}
+
+	series := make([]mimirpb.TimeSeries, 0, len(results))
+	for _, r := range results {
+		series = append(series, mimirpb.TimeSeries{
+			Labels:    mimirpb.FromLabelsToLabelAdapters(r.SeriesLabels),
+			Exemplars: mimirpb.FromExemplarsToExemplarProtos(r.Exemplars),
+		})
+	}
+	return mimir_tsdb.MergeExemplars(limit, series)
+}
+
 func (i *Ingester) getUserMetadata(userID string) *userMetricsMetadata {
 	i.usersMetadataMtx.RLock()
 	defer i.usersMetadataMtx.RUnlock()
@@ -2413,6 +3041,16 @@ func configSelectHintsWithDisabledTrimming(hints *storage.SelectHints) *storage.
 	return hints
 }
 
+// isCreatedTimestampZeroSampleErr returns whether the error is expected when appending a zero sample
+// injected at the start timestamp of a counter which has already been ingested.
+func isCreatedTimestampZeroSampleErr(err error) bool {
+	switch errors.Cause(err) {
+	case storage.ErrOutOfBounds, storage.ErrOutOfOrderSample, storage.ErrTooOldSample, storage.ErrDuplicateSampleForTimestamp:
+		return true
+	}
+	return false
+}
+
 // allOutOfBounds returns whether all the provided samples are out of bounds.
 func allOutOfBounds(samples []mimirpb.Sample, minValidTime int64) bool {
 	for _, s := range samples {
@@ -129,6 +129,24 @@ func (i *ActivityTrackerWrapper) LabelValuesCardinality(request *client.LabelVal
 	return i.ing.LabelValuesCardinality(request, server)
 }
 
+func (i *ActivityTrackerWrapper) HeadCardinalityStats(ctx context.Context, request *client.HeadCardinalityStatsRequest) (*client.HeadCardinalityStatsResponse, error) {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(ctx, "Ingester/HeadCardinalityStats", request)
+	})
+	defer i.tracker.Delete(ix)
+
+	return i.ing.HeadCardinalityStats(ctx, request)
+}
+
+func (i *ActivityTrackerWrapper) ActiveSeries(ctx context.Context, request *client.ActiveSeriesRequest) (*client.ActiveSeriesResponse, error) {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(ctx, "Ingester/ActiveSeries", request)
+	})
+	defer i.tracker.Delete(ix)
+
+	return i.ing.ActiveSeries(ctx, request)
+}
+
 func (i *ActivityTrackerWrapper) FlushHandler(w http.ResponseWriter, r *http.Request) {
 	ix := i.tracker.Insert(func() string {
 		return requestActivity(r.Context(), "Ingester/FlushHandler", nil)
@@ -147,6 +165,15 @@ func (i *ActivityTrackerWrapper) ShutdownHandler(w http.ResponseWriter, r *http.
 	i.ing.ShutdownHandler(w, r)
 }
 
+func (i *ActivityTrackerWrapper) ReadOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(r.Context(), "Ingester/ReadOnlyHandler", nil)
+	})
+	defer i.tracker.Delete(ix)
+
+	i.ing.ReadOnlyHandler(w, r)
+}
+
 func requestActivity(ctx context.Context, name string, req interface{}) string {
 	userID, _ := tenant.TenantID(ctx)
 	traceID, _ := tracing.ExtractSampledTraceID(ctx)
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+var errIngesterReadOnly = status.Error(codes.Unavailable, "the write request has been rejected because the ingester is in read-only mode")
+
+type readOnlyResponse struct {
+	ReadOnly bool   `json:"read_only"`
+	Since    string `json:"since,omitempty"`
+}
+
+// ReadOnlyHandler puts the ingester in read-only mode on POST requests, and returns whether the ingester is in
+// read-only mode on GET requests.
+//
+// A read-only ingester is switched to the LEAVING state in the ring, so that the distributors stop sending
+// writes to it, and the series it owned are written to the next ingesters of the ring, while the queriers
+// keep querying it. The ingester rejects the writes sent with an outdated view of the ring. Once the
+// ingester data has aged out of -querier.query-ingesters-within, the ingester can be shut down without
+// missing recent samples from the query results. The read-only mode is reset when the ingester restarts.
+func (i *Ingester) ReadOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		i.readOnlyMtx.Lock()
+		defer i.readOnlyMtx.Unlock()
+
+		if err := i.checkRunning(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		if i.readOnlySince.Load() == 0 {
+			if err := i.lifecycler.ChangeState(r.Context(), ring.LEAVING); err != nil {
+				level.Error(i.logger).Log("msg", "failed to switch the ingester to read-only mode", "err", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			i.readOnlySince.Store(time.Now().Unix())
+			level.Info(i.logger).Log("msg", "switched the ingester to read-only mode")
+		}
+	}
+
+	resp := readOnlyResponse{}
+	if since := i.readOnlySince.Load(); since > 0 {
+		resp.ReadOnly = true
+		resp.Since = time.Unix(since, 0).UTC().Format(time.RFC3339)
+	}
+	util.WriteJSONResponse(w, resp)
+}
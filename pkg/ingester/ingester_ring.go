@@ -4,7 +4,9 @@ package ingester
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
@@ -13,6 +15,9 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/util"
 )
 
 const (
@@ -30,8 +35,10 @@ type RingConfig struct {
 	ExcludedZones        flagext.StringSliceCSV `yaml:"excluded_zones" category:"advanced"`
 
 	// Tokens
-	TokensFilePath string `yaml:"tokens_file_path"`
-	NumTokens      int    `yaml:"num_tokens" category:"advanced"`
+	TokensFilePath          string                 `yaml:"tokens_file_path"`
+	NumTokens               int                    `yaml:"num_tokens" category:"advanced"`
+	TokenGenerationStrategy string                 `yaml:"token_generation_strategy" category:"experimental"`
+	SpreadMinimizingZones   flagext.StringSliceCSV `yaml:"spread_minimizing_zones" category:"experimental"`
 
 	// Instance details
 	InstanceID             string   `yaml:"instance_id" category:"advanced" doc:"default=<hostname>"`
@@ -78,6 +85,8 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.StringVar(&cfg.TokensFilePath, prefix+"tokens-file-path", "", "File path where tokens are stored. If empty, tokens are not stored at shutdown and restored at startup.")
 	f.IntVar(&cfg.NumTokens, prefix+"num-tokens", 128, "Number of tokens for each ingester.")
+	f.StringVar(&cfg.TokenGenerationStrategy, prefix+"token-generation-strategy", tokenGenerationStrategyRandom, fmt.Sprintf("Strategy used to generate the tokens of the ingester joining the ring for the first time. Supported values are: %s. The %s strategy generates deterministic tokens, based on the ordinal at the end of the instance ID, minimizing the spread of the series ownership among the ingesters of each zone. It requires -%stokens-file-path and the same -%snum-tokens on all the ingesters.", strings.Join(tokenGenerationStrategies, ", "), tokenGenerationStrategySpreadMinimizing, prefix, prefix))
+	f.Var(&cfg.SpreadMinimizingZones, prefix+"spread-minimizing-zones", fmt.Sprintf("Comma-separated list of all the availability zones of the ingesters, in the same order on all the ingesters. Required by the %s token generation strategy when zone-awareness is enabled.", tokenGenerationStrategySpreadMinimizing))
 
 	// Instance flags
 	f.StringVar(&cfg.InstanceID, prefix+"instance-id", hostname, "Instance ID to register in the ring.")
@@ -97,6 +106,64 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.BoolVar(&cfg.ReadinessCheckRingHealth, prefix+"readiness-check-ring-health", true, "When enabled the readiness probe succeeds only after all instances are ACTIVE and healthy in the ring, otherwise only the instance itself is checked. This option should be disabled if in your cluster multiple instances can be rolled out simultaneously, otherwise rolling updates may be slowed down.")
 }
 
+// Validate the ring config.
+func (cfg *RingConfig) Validate() error {
+	if !util.StringsContain(tokenGenerationStrategies, cfg.TokenGenerationStrategy) {
+		return fmt.Errorf("unsupported token generation strategy: %s", cfg.TokenGenerationStrategy)
+	}
+	if cfg.TokenGenerationStrategy != tokenGenerationStrategySpreadMinimizing {
+		return nil
+	}
+
+	if cfg.TokensFilePath == "" {
+		return fmt.Errorf("the %s token generation strategy requires the tokens file path to be configured", tokenGenerationStrategySpreadMinimizing)
+	}
+	if _, err := parseInstanceOrdinal(cfg.InstanceID); err != nil {
+		return errors.Wrapf(err, "the %s token generation strategy requires the instance ID to end with an ordinal", tokenGenerationStrategySpreadMinimizing)
+	}
+	if cfg.ZoneAwarenessEnabled && len(cfg.SpreadMinimizingZones) == 0 {
+		return fmt.Errorf("the %s token generation strategy requires the list of zones when zone-awareness is enabled", tokenGenerationStrategySpreadMinimizing)
+	}
+	if len(cfg.SpreadMinimizingZones) > 0 && !util.StringsContain(cfg.SpreadMinimizingZones, cfg.InstanceZone) {
+		return fmt.Errorf("the instance zone %q is not in the list of zones of the %s token generation strategy", cfg.InstanceZone, tokenGenerationStrategySpreadMinimizing)
+	}
+	return nil
+}
+
+// storeSpreadMinimizingTokens stores the tokens generated with the spread-minimizing token generation strategy
+// to the tokens file, unless the file already exists. The lifecycler then loads the tokens from the file, when
+// the ingester joins the ring for the first time.
+func (cfg *RingConfig) storeSpreadMinimizingTokens(logger log.Logger) error {
+	if _, err := os.Stat(cfg.TokensFilePath); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	ordinal, err := parseInstanceOrdinal(cfg.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	zoneIndex, numZones := 0, 1
+	if len(cfg.SpreadMinimizingZones) > 0 {
+		numZones = len(cfg.SpreadMinimizingZones)
+		for i, zone := range cfg.SpreadMinimizingZones {
+			if zone == cfg.InstanceZone {
+				zoneIndex = i
+			}
+		}
+	}
+
+	tokens := spreadMinimizingTokens(ordinal, zoneIndex, numZones, cfg.NumTokens)
+	if err := tokens.StoreToFile(cfg.TokensFilePath); err != nil {
+		return errors.Wrap(err, "failed to store the spread-minimizing tokens")
+	}
+
+	level.Info(logger).Log("msg", "stored spread-minimizing tokens to the tokens file", "path", cfg.TokensFilePath, "num_tokens", len(tokens), "ordinal", ordinal, "zone", cfg.InstanceZone)
+	return nil
+}
+
 // ToRingConfig returns a ring.Config based on the ingester
 // ring config.
 func (cfg *RingConfig) ToRingConfig() ring.Config {
@@ -3,12 +3,15 @@
 package ingester
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/ring"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRingConfig_DefaultConfigToLifecyclerConfig(t *testing.T) {
@@ -86,3 +89,106 @@ func TestRingConfig_CustomConfigToLifecyclerConfig(t *testing.T) {
 
 	assert.Equal(t, expected, cfg.ToLifecyclerConfig())
 }
+
+func TestRingConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup       func(cfg *RingConfig)
+		expectedErr string
+	}{
+		"should pass with the default config": {
+			setup: func(cfg *RingConfig) {},
+		},
+		"should fail on unsupported token generation strategy": {
+			setup: func(cfg *RingConfig) {
+				cfg.TokenGenerationStrategy = "unknown"
+			},
+			expectedErr: "unsupported token generation strategy: unknown",
+		},
+		"should pass with the spread-minimizing strategy": {
+			setup: func(cfg *RingConfig) {
+				cfg.TokenGenerationStrategy = tokenGenerationStrategySpreadMinimizing
+				cfg.TokensFilePath = "/tokens"
+				cfg.InstanceID = "ingester-zone-a-3"
+				cfg.InstanceZone = "zone-a"
+				cfg.ZoneAwarenessEnabled = true
+				cfg.SpreadMinimizingZones = []string{"zone-a", "zone-b"}
+			},
+		},
+		"should fail with the spread-minimizing strategy and no tokens file path": {
+			setup: func(cfg *RingConfig) {
+				cfg.TokenGenerationStrategy = tokenGenerationStrategySpreadMinimizing
+				cfg.InstanceID = "ingester-3"
+			},
+			expectedErr: "the spread-minimizing token generation strategy requires the tokens file path to be configured",
+		},
+		"should fail with the spread-minimizing strategy and an instance ID without ordinal": {
+			setup: func(cfg *RingConfig) {
+				cfg.TokenGenerationStrategy = tokenGenerationStrategySpreadMinimizing
+				cfg.TokensFilePath = "/tokens"
+				cfg.InstanceID = "ingester"
+			},
+			expectedErr: `the spread-minimizing token generation strategy requires the instance ID to end with an ordinal: the instance ID "ingester" doesn't end with an ordinal, like in ingester-zone-a-3`,
+		},
+		"should fail with the spread-minimizing strategy, zone-awareness enabled and no zones": {
+			setup: func(cfg *RingConfig) {
+				cfg.TokenGenerationStrategy = tokenGenerationStrategySpreadMinimizing
+				cfg.TokensFilePath = "/tokens"
+				cfg.InstanceID = "ingester-zone-a-3"
+				cfg.InstanceZone = "zone-a"
+				cfg.ZoneAwarenessEnabled = true
+			},
+			expectedErr: "the spread-minimizing token generation strategy requires the list of zones when zone-awareness is enabled",
+		},
+		"should fail with the spread-minimizing strategy and the instance zone not in the zones": {
+			setup: func(cfg *RingConfig) {
+				cfg.TokenGenerationStrategy = tokenGenerationStrategySpreadMinimizing
+				cfg.TokensFilePath = "/tokens"
+				cfg.InstanceID = "ingester-zone-c-3"
+				cfg.InstanceZone = "zone-c"
+				cfg.ZoneAwarenessEnabled = true
+				cfg.SpreadMinimizingZones = []string{"zone-a", "zone-b"}
+			},
+			expectedErr: `the instance zone "zone-c" is not in the list of zones of the spread-minimizing token generation strategy`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := RingConfig{}
+			flagext.DefaultValues(&cfg)
+			testData.setup(&cfg)
+
+			if err := cfg.Validate(); testData.expectedErr != "" {
+				assert.EqualError(t, err, testData.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRingConfig_StoreSpreadMinimizingTokens(t *testing.T) {
+	cfg := RingConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.TokenGenerationStrategy = tokenGenerationStrategySpreadMinimizing
+	cfg.TokensFilePath = filepath.Join(t.TempDir(), "tokens")
+	cfg.InstanceID = "ingester-zone-b-2"
+	cfg.InstanceZone = "zone-b"
+	cfg.ZoneAwarenessEnabled = true
+	cfg.SpreadMinimizingZones = []string{"zone-a", "zone-b", "zone-c"}
+	require.NoError(t, cfg.Validate())
+
+	require.NoError(t, cfg.storeSpreadMinimizingTokens(log.NewNopLogger()))
+
+	tokens, err := ring.LoadTokensFromFile(cfg.TokensFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, spreadMinimizingTokens(2, 1, 3, cfg.NumTokens), tokens)
+
+	// The existing tokens file is not overwritten.
+	require.NoError(t, ring.Tokens{1, 2, 3}.StoreToFile(cfg.TokensFilePath))
+	require.NoError(t, cfg.storeSpreadMinimizingTokens(log.NewNopLogger()))
+
+	tokens, err = ring.LoadTokensFromFile(cfg.TokensFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, ring.Tokens{1, 2, 3}, tokens)
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/record"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -988,6 +989,50 @@ func TestIngester_Push_DecreaseInactiveSeries(t *testing.T) {
 	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), metricNames...))
 }
 
+func TestIngester_exemplarsForBlock(t *testing.T) {
+	metricLabelAdapters := []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "test"}}
+	metricLabels := mimirpb.FromLabelAdaptersToLabels(metricLabelAdapters)
+	exemplarAt := func(traceID string, ts int64) mimirpb.Exemplar {
+		return mimirpb.Exemplar{Labels: []mimirpb.LabelAdapter{{Name: "traceID", Value: traceID}}, TimestampMs: ts, Value: float64(ts)}
+	}
+
+	cfg := defaultIngesterTestConfig(t)
+	limits := defaultLimitsTestConfig()
+	limits.MaxGlobalExemplarsPerUser = 10
+	limits.MaxExemplarsPerBlock = 2
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	req := mimirpb.ToWriteRequest([]labels.Labels{metricLabels}, []mimirpb.Sample{{Value: 1, TimestampMs: 100}}, nil, nil, mimirpb.API)
+	_, err = i.Push(ctx, req)
+	require.NoError(t, err)
+
+	for _, e := range []mimirpb.Exemplar{exemplarAt("1", 100), exemplarAt("2", 200), exemplarAt("3", 300), exemplarAt("4", 400)} {
+		e := e
+		req := mimirpb.ToWriteRequest([]labels.Labels{metricLabels}, []mimirpb.Sample{{Value: 1, TimestampMs: e.TimestampMs}}, []*mimirpb.Exemplar{&e}, nil, mimirpb.API)
+		_, err := i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	db := i.getTSDB(userID)
+	require.NotNil(t, db)
+
+	// The block max time is exclusive, and only the most recent exemplars are kept up to the limit.
+	assert.Equal(t, []mimirpb.TimeSeries{
+		{Labels: metricLabelAdapters, Exemplars: []mimirpb.Exemplar{exemplarAt("2", 200), exemplarAt("3", 300)}},
+	}, i.exemplarsForBlock(db, 0, 400))
+
+	// No exemplars are persisted if the limit is disabled.
+	limits.MaxExemplarsPerBlock = 0
+	i.limits, err = validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+	assert.Empty(t, i.exemplarsForBlock(db, 0, 400))
+}
+
 func BenchmarkIngesterPush(b *testing.B) {
 	limits := defaultLimitsTestConfig()
 	benchmarkIngesterPush(b, limits, false)
@@ -1979,6 +2024,101 @@ func TestIngester_Push_ShouldNotCreateTSDBIfNotInActiveState(t *testing.T) {
 	assert.False(t, tsdbCreated)
 }
 
+func TestIngester_Push_CreatedTimestampZeroSamples(t *testing.T) {
+	pushRequest := func(source mimirpb.WriteRequest_SourceEnum, samples ...mimirpb.Sample) *mimirpb.WriteRequest {
+		return &mimirpb.WriteRequest{Source: source, Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  mimirpb.FromLabelsToLabelAdapters(labels.Labels{{Name: labels.MetricName, Value: "counter"}}),
+			Samples: samples,
+		}}}}
+	}
+
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			limits := defaultLimitsTestConfig()
+			limits.OTelCreatedTimestampZeroIngestionEnabled = enabled
+
+			i, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, "", nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+			defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+			test.Poll(t, 1*time.Second, 1, func() interface{} {
+				return i.lifecycler.HealthyInstancesCount()
+			})
+
+			ctx := user.InjectOrgID(context.Background(), "test")
+			_, err = i.Push(ctx, pushRequest(mimirpb.OTLP, mimirpb.Sample{TimestampMs: 1000, Value: 0}, mimirpb.Sample{TimestampMs: 2000, Value: 5}))
+			require.NoError(t, err)
+
+			// The zero sample at the same start timestamp is now out-of-order.
+			_, err = i.Push(ctx, pushRequest(mimirpb.OTLP, mimirpb.Sample{TimestampMs: 1000, Value: 0}, mimirpb.Sample{TimestampMs: 3000, Value: 7}))
+			if enabled {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "err-mimir-sample-out-of-order")
+			}
+
+			// Out-of-order samples which are not followed by another sample are always rejected.
+			_, err = i.Push(ctx, pushRequest(mimirpb.OTLP, mimirpb.Sample{TimestampMs: 1000, Value: 0}))
+			require.Error(t, err)
+
+			// Out-of-order zero samples pushed through the other APIs are always rejected.
+			_, err = i.Push(ctx, pushRequest(mimirpb.API, mimirpb.Sample{TimestampMs: 1000, Value: 0}, mimirpb.Sample{TimestampMs: 4000, Value: 9}))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "err-mimir-sample-out-of-order")
+		})
+	}
+}
+
+func TestIngester_Push_TimestampHints(t *testing.T) {
+	const now = int64(10 * time.Hour / time.Millisecond)
+
+	pushRequest := func(ts, minHint, maxHint int64) *mimirpb.WriteRequest {
+		return &mimirpb.WriteRequest{
+			Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+				Labels:  mimirpb.FromLabelsToLabelAdapters(labels.Labels{{Name: labels.MetricName, Value: "test"}}),
+				Samples: []mimirpb.Sample{{TimestampMs: ts, Value: 1}},
+			}}},
+			MinTimestampMs:    minHint,
+			MaxTimestampMs:    maxHint,
+			TimestampHintsSet: true,
+		}
+	}
+
+	i, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	_, err = i.Push(ctx, pushRequest(now, now, now))
+	require.NoError(t, err)
+
+	// The min valid time is now 1h before the latest sample (half of the 2h block range).
+	tooOld := now - 2*time.Hour.Milliseconds()
+
+	// The whole request is before the min valid time.
+	_, err = i.Push(ctx, pushRequest(tooOld, tooOld, tooOld))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "err-mimir-sample-timestamp-too-old")
+
+	// The whole request is after the min valid time, so the out of bounds check is left to the TSDB.
+	_, err = i.Push(ctx, pushRequest(now+1, now+1, now+1))
+	require.NoError(t, err)
+
+	// The hints are bounds, so a request partially before the min valid time is checked for each series.
+	_, err = i.Push(ctx, pushRequest(tooOld, tooOld, now+2))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "err-mimir-sample-timestamp-too-old")
+	_, err = i.Push(ctx, pushRequest(now+2, tooOld, now+2))
+	require.NoError(t, err)
+}
+
 func TestIngester_getOrCreateTSDB_ShouldNotAllowToCreateTSDBIfIngesterStateIsNotActive(t *testing.T) {
 	tests := map[string]struct {
 		state       ring.InstanceState
@@ -2216,6 +2356,63 @@ func Test_Ingester_MetricsForLabelMatchers_Deduplication(t *testing.T) {
 	require.Len(t, res.GetMetric(), numSeries)
 }
 
+func Test_Ingester_ActiveSeries(t *testing.T) {
+	series1 := labels.FromStrings(labels.MetricName, "test_1", "status", "200")
+	series2 := labels.FromStrings(labels.MetricName, "test_1", "status", "500")
+	series3 := labels.FromStrings(labels.MetricName, "test_2")
+
+	i, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+
+	res, err := i.ActiveSeries(ctx, &client.ActiveSeriesRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Metric)
+
+	for _, series := range []labels.Labels{series3, series2, series1} {
+		req, _, _, _ := mockWriteRequest(t, series, 1, util.TimeToMillis(time.Now()))
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	tests := map[string]struct {
+		matchers []*client.LabelMatcher
+		limit    int32
+		expected []labels.Labels
+	}{
+		"should return all the active series without matchers": {
+			expected: []labels.Labels{series1, series2, series3},
+		},
+		"should only return the first active series up to the limit": {
+			limit:    2,
+			expected: []labels.Labels{series1, series2},
+		},
+		"should only return the active series matching the matchers": {
+			matchers: []*client.LabelMatcher{{Type: client.EQUAL, Name: labels.MetricName, Value: "test_1"}},
+			expected: []labels.Labels{series1, series2},
+		},
+		"should return no series if none matches the matchers": {
+			matchers: []*client.LabelMatcher{{Type: client.EQUAL, Name: labels.MetricName, Value: "test_3"}},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			res, err := i.ActiveSeries(ctx, &client.ActiveSeriesRequest{Matchers: testData.matchers, Limit: testData.limit})
+			require.NoError(t, err)
+
+			var actual []labels.Labels
+			for _, m := range res.Metric {
+				actual = append(actual, mimirpb.FromLabelAdaptersToLabels(m.Labels))
+			}
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+}
+
 func Benchmark_Ingester_MetricsForLabelMatchers(b *testing.B) {
 	var (
 		userID              = "test"
@@ -2608,6 +2805,8 @@ func TestIngester_QueryStreamManySamplesChunks(t *testing.T) {
 	// Create ingester.
 	cfg := defaultIngesterTestConfig(t)
 	cfg.StreamChunksWhenUsingBlocks = true
+	// Only limit the messages by size.
+	cfg.StreamChunksBatchSize = 0
 
 	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
 	require.NoError(t, err)
@@ -2674,7 +2873,7 @@ func TestIngester_QueryStreamManySamplesChunks(t *testing.T) {
 	require.NoError(t, err)
 
 	recvMsgs := 0
-	series := 0
+	series := map[string]struct{}{}
 	totalSamples := 0
 
 	for {
@@ -2684,11 +2883,13 @@ func TestIngester_QueryStreamManySamplesChunks(t *testing.T) {
 		}
 		require.NoError(t, err)
 		require.True(t, len(resp.Chunkseries) > 0) // No empty messages.
+		require.LessOrEqual(t, resp.Size(), queryStreamBatchMessageSize)
 
 		recvMsgs++
-		series += len(resp.Chunkseries)
 
 		for _, ts := range resp.Chunkseries {
+			series[mimirpb.FromLabelAdaptersToLabels(ts.Labels).String()] = struct{}{}
+
 			for _, c := range ts.Chunks {
 				ch, err := chunk.NewForEncoding(chunk.Encoding(c.Encoding))
 				require.NoError(t, err)
@@ -2699,14 +2900,121 @@ func TestIngester_QueryStreamManySamplesChunks(t *testing.T) {
 		}
 	}
 
-	// As ingester doesn't guarantee sorting of series, we can get 2 (100k + 500k in first, 1M in second)
-	// or 3 messages (100k or 500k first, 1M second, and 500k or 100k last).
-
-	require.True(t, 2 <= recvMsgs && recvMsgs <= 3)
-	require.Equal(t, 3, series)
+	// The chunks of the 3 series need about 2.4 MiB, so they're split across at least 3 messages,
+	// with the chunks of the 1M samples series split across multiple messages.
+	require.GreaterOrEqual(t, recvMsgs, 3)
+	require.Len(t, series, 3)
 	require.Equal(t, 100000+500000+samplesCount, totalSamples)
 }
 
+func TestIngester_QueryStreamChunksBatchSize(t *testing.T) {
+	// Create ingester.
+	cfg := defaultIngesterTestConfig(t)
+	cfg.StreamChunksWhenUsingBlocks = true
+	cfg.StreamChunksBatchSize = 3
+
+	registry := prometheus.NewRegistry()
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy.
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	// Push series.
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	samples := make([]mimirpb.Sample, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, mimirpb.Sample{Value: float64(i), TimestampMs: int64(i)})
+	}
+
+	// A series with 9 chunks of up to 120 samples, and a series with a single chunk.
+	_, err = i.Push(ctx, writeRequestSingleSeries(labels.Labels{{Name: labels.MetricName, Value: "foo"}, {Name: "l", Value: "1"}}, samples))
+	require.NoError(t, err)
+	_, err = i.Push(ctx, writeRequestSingleSeries(labels.Labels{{Name: labels.MetricName, Value: "foo"}, {Name: "l", Value: "2"}}, samples[:10]))
+	require.NoError(t, err)
+
+	// Create a GRPC server used to query back the data.
+	serv := grpc.NewServer(grpc.StreamInterceptor(middleware.StreamServerUserHeaderInterceptor))
+	defer serv.GracefulStop()
+	client.RegisterIngesterServer(serv, i)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go func() {
+		require.NoError(t, serv.Serve(listener))
+	}()
+
+	// Query back the series using GRPC streaming.
+	c, err := client.MakeIngesterClient(listener.Addr().String(), defaultClientTestConfig())
+	require.NoError(t, err)
+	defer c.Close()
+
+	s, err := c.QueryStream(ctx, &client.QueryRequest{
+		StartTimestampMs: 0,
+		EndTimestampMs:   1000,
+		Matchers:         []*client.LabelMatcher{{Type: client.EQUAL, Name: model.MetricNameLabel, Value: "foo"}},
+	})
+	require.NoError(t, err)
+
+	recvMsgs := 0
+	samplesPerSeries := map[string]int{}
+	lastSampleTimestamp := map[string]int64{}
+
+	for {
+		resp, err := s.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, resp.ChunksCount(), 3)
+
+		recvMsgs++
+
+		for _, ts := range resp.Chunkseries {
+			require.NotEmpty(t, ts.Chunks)
+			series := mimirpb.FromLabelAdaptersToLabels(ts.Labels).String()
+
+			// The chunks of a series split across multiple messages are still sent in order.
+			for _, c := range ts.Chunks {
+				if last, ok := lastSampleTimestamp[series]; ok {
+					require.Greater(t, c.StartTimestampMs, last)
+				}
+				lastSampleTimestamp[series] = c.EndTimestampMs
+
+				ch, err := chunk.NewForEncoding(chunk.Encoding(c.Encoding))
+				require.NoError(t, err)
+				require.NoError(t, ch.UnmarshalFromBuf(c.Data))
+				samplesPerSeries[series] += ch.Len()
+			}
+		}
+	}
+
+	// 10 chunks in total, sent 3 by 3.
+	assert.Equal(t, 4, recvMsgs)
+	assert.Equal(t, map[string]int{`{__name__="foo", l="1"}`: 1000, `{__name__="foo", l="2"}`: 10}, samplesPerSeries)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_queried_chunks The total number of chunks streamed to the queriers by queries.
+		# TYPE cortex_ingester_queried_chunks histogram
+		cortex_ingester_queried_chunks_bucket{le="10"} 1
+		cortex_ingester_queried_chunks_bucket{le="80"} 1
+		cortex_ingester_queried_chunks_bucket{le="640"} 1
+		cortex_ingester_queried_chunks_bucket{le="5120"} 1
+		cortex_ingester_queried_chunks_bucket{le="40960"} 1
+		cortex_ingester_queried_chunks_bucket{le="327680"} 1
+		cortex_ingester_queried_chunks_bucket{le="2.62144e+06"} 1
+		cortex_ingester_queried_chunks_bucket{le="+Inf"} 1
+		cortex_ingester_queried_chunks_sum 10
+		cortex_ingester_queried_chunks_count 1
+	`), "cortex_ingester_queried_chunks"))
+}
+
 func writeRequestSingleSeries(lbls labels.Labels, samples []mimirpb.Sample) *mimirpb.WriteRequest {
 	req := &mimirpb.WriteRequest{
 		Source: mimirpb.API,
@@ -3145,6 +3453,74 @@ func TestIngester_OpenExistingTSDBOnStartup(t *testing.T) {
 	}
 }
 
+func TestIngester_OpenExistingTSDBOnStartup_ShouldTrackWALReplayProgress(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	dataDir := t.TempDir()
+
+	// Start an ingester and push a sample, to have a WAL to replay.
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, defaultLimitsTestConfig(), dataDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	pushSingleSampleWithMetadata(t, i)
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+	walSegments := countWALSegments(filepath.Join(dataDir, userID, "wal"))
+	require.Greater(t, walSegments, 0)
+
+	// Restart the ingester, replaying the WAL.
+	reg := prometheus.NewPedanticRegistry()
+	i, err = prepareIngesterWithBlocksStorageAndLimits(t, cfg, defaultLimitsTestConfig(), dataDir, reg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), i)
+	})
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+		# HELP cortex_ingester_tsdb_wal_replay_tenants_remaining Number of tenants whose TSDB is still to be opened, and its WAL replayed, at startup.
+		# TYPE cortex_ingester_tsdb_wal_replay_tenants_remaining gauge
+		cortex_ingester_tsdb_wal_replay_tenants_remaining 0
+
+		# HELP cortex_ingester_tsdb_wal_replay_segments_remaining Number of WAL segments still to be replayed at startup.
+		# TYPE cortex_ingester_tsdb_wal_replay_segments_remaining gauge
+		cortex_ingester_tsdb_wal_replay_segments_remaining 0
+
+		# HELP cortex_ingester_tsdb_wal_replay_segments_replayed_total Total number of WAL segments replayed at startup.
+		# TYPE cortex_ingester_tsdb_wal_replay_segments_replayed_total counter
+		cortex_ingester_tsdb_wal_replay_segments_replayed_total %d
+	`, walSegments)), "cortex_ingester_tsdb_wal_replay_tenants_remaining", "cortex_ingester_tsdb_wal_replay_segments_remaining", "cortex_ingester_tsdb_wal_replay_segments_replayed_total"))
+}
+
+func TestIngester_findExistingTSDBs(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	dataDir := t.TempDir()
+
+	// Start an ingester and push a sample for two tenants, to have a WAL to replay for each.
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, defaultLimitsTestConfig(), dataDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	for _, tenant := range []string{"user-1", "user-2"} {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 1, 1)
+		_, err := i.Push(user.InjectOrgID(context.Background(), tenant), req)
+		require.NoError(t, err)
+	}
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+	// The empty TSDB directories are skipped.
+	require.NoError(t, os.Mkdir(filepath.Join(dataDir, "user-3"), os.ModePerm))
+
+	found, err := i.findExistingTSDBs()
+	require.NoError(t, err)
+	sort.Slice(found, func(a, b int) bool { return found[a].userID < found[b].userID })
+
+	require.Len(t, found, 2)
+	for idx, tenant := range []string{"user-1", "user-2"} {
+		assert.Equal(t, tenant, found[idx].userID)
+		assert.Equal(t, countWALSegments(filepath.Join(dataDir, tenant, "wal")), found[idx].walSegments)
+		assert.Greater(t, found[idx].walSegments, 0)
+	}
+}
+
 func TestIngester_shipBlocks(t *testing.T) {
 	cfg := defaultIngesterTestConfig(t)
 	cfg.BlocksStorageConfig.TSDB.ShipConcurrency = 2
@@ -3522,6 +3898,29 @@ func TestIngester_flushing(t *testing.T) {
 			},
 		},
 
+		"shutdownHandler with upload": {
+			setupIngester: func(cfg *Config) {
+				cfg.BlocksStorageConfig.TSDB.FlushBlocksOnShutdown = false
+				cfg.BlocksStorageConfig.TSDB.KeepUserTSDBOpenOnShutdown = true
+			},
+
+			action: func(t *testing.T, i *Ingester, reg *prometheus.Registry) {
+				pushSingleSampleWithMetadata(t, i)
+
+				recorder := httptest.NewRecorder()
+				i.ShutdownHandler(recorder, httptest.NewRequest("POST", "/ingester/shutdown?upload=true", nil))
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+
+				verifyCompactedHead(t, i, true)
+				require.NoError(t, testutil.GatherAndCompare(reg, bytes.NewBufferString(`
+		# HELP cortex_ingester_shipper_uploads_total Total number of uploaded TSDB blocks
+		# TYPE cortex_ingester_shipper_uploads_total counter
+		cortex_ingester_shipper_uploads_total 1
+	`), "cortex_ingester_shipper_uploads_total"))
+				require.Empty(t, i.getTenantsWithUnshippedBlocks())
+			},
+		},
+
 		"flushHandler": {
 			setupIngester: func(cfg *Config) {
 				cfg.BlocksStorageConfig.TSDB.FlushBlocksOnShutdown = false
@@ -3928,6 +4327,240 @@ func TestIngesterCompactIdleBlock(t *testing.T) {
     `), "cortex_ingester_memory_series_created_total", "cortex_ingester_memory_series_removed_total", "cortex_ingester_memory_users"))
 }
 
+func TestIngesterCompactIdleBlockWithTenantIdleTimeout(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.BlocksStorageConfig.TSDB.HeadCompactionInterval = 1 * time.Hour    // Long enough to not be reached during the test.
+	cfg.BlocksStorageConfig.TSDB.HeadCompactionIdleTimeout = 1 * time.Hour // Long enough to not be reached during the test.
+
+	limits := defaultLimitsTestConfig()
+	limits.TSDBHeadCompactionIdleTimeout = model.Duration(1 * time.Second) // Testing this.
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), i)
+	})
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	pushSingleSampleWithMetadata(t, i)
+
+	i.compactBlocks(context.Background(), false, nil)
+	verifyCompactedHead(t, i, false)
+
+	// wait one second (plus maximum jitter) -- TSDB is now idle.
+	time.Sleep(time.Duration(float64(limits.TSDBHeadCompactionIdleTimeout) * (1 + compactionIdleTimeoutJitter)))
+
+	i.compactBlocks(context.Background(), false, nil)
+	verifyCompactedHead(t, i, true)
+}
+
+func TestIngesterCompactHeadWithTenantBlockDuration(t *testing.T) {
+	tests := map[string]struct {
+		blockDuration         time.Duration
+		expectedBlockDuration time.Duration
+	}{
+		"no custom block duration": {
+			blockDuration:         0,
+			expectedBlockDuration: 2 * time.Hour,
+		},
+		"custom block duration evenly dividing the block ranges period": {
+			blockDuration:         30 * time.Minute,
+			expectedBlockDuration: 30 * time.Minute,
+		},
+		"custom block duration not evenly dividing the block ranges period": {
+			blockDuration:         45 * time.Minute,
+			expectedBlockDuration: 2 * time.Hour,
+		},
+		"custom block duration longer than the block ranges period": {
+			blockDuration:         4 * time.Hour,
+			expectedBlockDuration: 2 * time.Hour,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := defaultIngesterTestConfig(t)
+			cfg.BlocksStorageConfig.TSDB.BlockRanges = []time.Duration{2 * time.Hour}
+
+			limits := defaultLimitsTestConfig()
+			limits.TSDBBlockDuration = model.Duration(testData.blockDuration)
+
+			i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+			require.NoError(t, err)
+
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+			t.Cleanup(func() {
+				_ = services.StopAndAwaitTerminated(context.Background(), i)
+			})
+
+			// Push samples spanning two hours, starting at the beginning of a 2h block range.
+			startTime := (time.Now().Add(-3*time.Hour).UnixMilli() / (2 * time.Hour).Milliseconds()) * (2 * time.Hour).Milliseconds()
+			for ts := startTime; ts < startTime+(2*time.Hour).Milliseconds(); ts += time.Minute.Milliseconds() {
+				pushSingleSampleAtTime(t, i, ts)
+			}
+
+			db := i.getTSDB(userID)
+			require.NotNil(t, db)
+			assert.Equal(t, testData.expectedBlockDuration, db.blockDuration)
+
+			i.compactBlocks(context.Background(), true, nil)
+			verifyCompactedHead(t, i, true)
+
+			blocks := db.Blocks()
+			require.Len(t, blocks, int(2*time.Hour/testData.expectedBlockDuration))
+			for _, b := range blocks {
+				assert.LessOrEqual(t, b.MaxTime()-b.MinTime(), testData.expectedBlockDuration.Milliseconds())
+			}
+		})
+	}
+}
+
+func TestIngesterSnapshotHeads(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown = true
+	cfg.BlocksStorageConfig.TSDB.MemorySnapshotInterval = 1 * time.Hour // Long enough to not be reached during the test.
+
+	r := prometheus.NewRegistry()
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, r)
+	require.NoError(t, err)
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), i)
+	})
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	pushSingleSampleWithMetadata(t, i)
+
+	db := i.getTSDB(userID)
+	require.NotNil(t, db)
+
+	_, _, _, err = tsdb.LastChunkSnapshot(db.db.Dir())
+	require.ErrorIs(t, err, record.ErrNotFound)
+
+	i.snapshotHeads(context.Background())
+
+	_, _, _, err = tsdb.LastChunkSnapshot(db.db.Dir())
+	require.NoError(t, err)
+
+	require.NoError(t, testutil.GatherAndCompare(r, strings.NewReader(`
+		# HELP cortex_ingester_tsdb_memory_snapshots_triggered_total Total number of triggered periodic TSDB memory snapshots.
+		# TYPE cortex_ingester_tsdb_memory_snapshots_triggered_total counter
+		cortex_ingester_tsdb_memory_snapshots_triggered_total 1
+
+		# HELP cortex_ingester_tsdb_memory_snapshots_failed_total Total number of periodic TSDB memory snapshots that failed.
+		# TYPE cortex_ingester_tsdb_memory_snapshots_failed_total counter
+		cortex_ingester_tsdb_memory_snapshots_failed_total 0
+	`), "cortex_ingester_tsdb_memory_snapshots_triggered_total", "cortex_ingester_tsdb_memory_snapshots_failed_total"))
+}
+
+func TestIngesterSnapshotHeads_ShouldNotLoseSamplesPushedDuringTheSnapshot(t *testing.T) {
+	const (
+		numSeries               = 10
+		numSamples              = 200
+		numSamplesAfterSnapshot = 10
+	)
+
+	// The memory snapshot on shutdown is disabled in the first ingester, so that at restart the WAL is replayed
+	// from the last periodic snapshot, like after a crash.
+	cfg := defaultIngesterTestConfig(t)
+	dataDir := t.TempDir()
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, defaultLimitsTestConfig(), dataDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	push := func(ts int64) {
+		for s := 0; s < numSeries; s++ {
+			req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test", "series", strconv.Itoa(s)), float64(ts), ts)
+			_, err := i.Push(ctx, req)
+			require.NoError(t, err)
+		}
+	}
+
+	// Push the first samples, so that the TSDB is created before the snapshots start.
+	push(1)
+
+	db := i.getTSDB(userID)
+	require.NotNil(t, db)
+
+	// Snapshot the TSDB during the pushes.
+	stopSnapshots := make(chan struct{})
+	snapshotErr := make(chan error, 1)
+	go func() {
+		defer close(snapshotErr)
+		for {
+			select {
+			case <-stopSnapshots:
+				return
+			default:
+				if err := db.chunkSnapshot(); err != nil {
+					snapshotErr <- err
+					return
+				}
+			}
+		}
+	}()
+
+	for ts := int64(2); ts <= numSamples; ts++ {
+		push(ts)
+	}
+	close(stopSnapshots)
+	require.NoError(t, <-snapshotErr)
+
+	// Take a last snapshot after the pushes, and push a few more samples which will be replayed from the WAL.
+	require.NoError(t, db.chunkSnapshot())
+	for ts := int64(numSamples + 1); ts <= numSamples+numSamplesAfterSnapshot; ts++ {
+		push(ts)
+	}
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+	_, _, _, err = tsdb.LastChunkSnapshot(filepath.Join(dataDir, userID))
+	require.NoError(t, err)
+
+	// Restart the ingester, loading the last snapshot and replaying the WAL written after it.
+	cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown = true
+	i, err = prepareIngesterWithBlocksStorageAndLimits(t, cfg, defaultLimitsTestConfig(), dataDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), i)
+	})
+
+	db = i.getTSDB(userID)
+	require.NotNil(t, db)
+	q, err := db.Querier(ctx, math.MinInt64, math.MaxInt64)
+	require.NoError(t, err)
+	defer q.Close()
+
+	set := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test"))
+	numSeriesFound := 0
+	for set.Next() {
+		numSeriesFound++
+		it := set.At().Iterator()
+		numSamplesFound := 0
+		for it.Next() {
+			numSamplesFound++
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, numSamples+numSamplesAfterSnapshot, numSamplesFound, set.At().Labels().String())
+	}
+	require.NoError(t, set.Err())
+	assert.Equal(t, numSeries, numSeriesFound)
+}
+
 func TestIngesterCompactAndCloseIdleTSDB(t *testing.T) {
 	cfg := defaultIngesterTestConfig(t)
 	cfg.BlocksStorageConfig.TSDB.ShipInterval = 1 * time.Second // Required to enable shipping.
@@ -5067,6 +5700,213 @@ func TestIngesterUserLimitExceeded(t *testing.T) {
 
 }
 
+func TestIngesterUserLimitExceededInWarnOnlyMode(t *testing.T) {
+	tests := map[string]struct {
+		admissionRatio     float64
+		maxSeriesPerMetric int
+		expectedAdmitted   bool
+	}{
+		"should admit all the new series exceeding the limit": {
+			admissionRatio:   1,
+			expectedAdmitted: true,
+		},
+		"should reject all the new series exceeding the limit if the admission ratio is 0": {
+			admissionRatio:   0,
+			expectedAdmitted: false,
+		},
+		"should not count the new series exceeding the limit which are rejected by the per-metric limit": {
+			admissionRatio:     1,
+			maxSeriesPerMetric: 2,
+			expectedAdmitted:   true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := defaultLimitsTestConfig()
+			limits.MaxGlobalSeriesPerUser = 1
+			limits.MaxGlobalSeriesPerUserWarnOnly = true
+			limits.MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio = testData.admissionRatio
+			limits.MaxGlobalSeriesPerMetric = testData.maxSeriesPerMetric
+
+			cfg := defaultIngesterTestConfig(t)
+			cfg.IngesterRing.ReplicationFactor = 1
+
+			registry := prometheus.NewRegistry()
+			ing, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", registry)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+			t.Cleanup(func() {
+				require.NoError(t, services.StopAndAwaitTerminated(context.Background(), ing))
+			})
+
+			// Wait until it's healthy
+			test.Poll(t, time.Second, 1, func() interface{} {
+				return ing.lifecycler.HealthyInstancesCount()
+			})
+
+			userID := "1"
+			labels1 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "bar"}}
+			labels2 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "biz"}}
+			labels3 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "baz"}}
+
+			ctx := user.InjectOrgID(context.Background(), userID)
+			_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{labels1}, []mimirpb.Sample{{TimestampMs: 0, Value: 1}}, nil, nil, mimirpb.API))
+			require.NoError(t, err)
+
+			// Append to three series, exceeding the limit by two series.
+			_, err = ing.Push(ctx, mimirpb.ToWriteRequest(
+				[]labels.Labels{labels1, labels2, labels3},
+				[]mimirpb.Sample{{TimestampMs: 1, Value: 2}, {TimestampMs: 1, Value: 3}, {TimestampMs: 1, Value: 4}},
+				nil, nil, mimirpb.API))
+
+			expectedSeries := 3
+			expectedAdmitted := 2
+			if !testData.expectedAdmitted {
+				expectedSeries = 1
+				expectedAdmitted = 0
+
+				httpResp, ok := httpgrpc.HTTPResponseFromError(err)
+				require.True(t, ok, "returned error is not an httpgrpc response")
+				assert.Equal(t, http.StatusBadRequest, int(httpResp.Code))
+				assert.Equal(t, wrapWithUser(makeLimitError(perUserSeriesLimit, ing.limiter.FormatError(userID, errMaxSeriesPerUserLimitExceeded)), userID).Error(), string(httpResp.Body))
+			} else if testData.maxSeriesPerMetric > 0 {
+				// The third series is admitted by the per-user limit, but rejected by the per-metric limit.
+				expectedSeries = testData.maxSeriesPerMetric
+				expectedAdmitted = testData.maxSeriesPerMetric - 1
+
+				httpResp, ok := httpgrpc.HTTPResponseFromError(err)
+				require.True(t, ok, "returned error is not an httpgrpc response")
+				assert.Equal(t, http.StatusBadRequest, int(httpResp.Code))
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, uint64(expectedSeries), ing.getTSDB(userID).Head().NumSeries())
+			assert.Equal(t, float64(expectedAdmitted), testutil.ToFloat64(ing.metrics.warnOnlySeriesAdmitted.WithLabelValues(userID)))
+		})
+	}
+}
+
+func TestIngesterScopeLimitExceeded(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.SeriesLimitScopeLabel = "namespace"
+	limits.MaxGlobalSeriesPerScope = 1
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ReplicationFactor = 1
+
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), ing))
+	})
+
+	// Wait until it's healthy
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	userID := "1"
+	labels1 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "bar"}, {Name: "namespace", Value: "team-a"}}
+	labels2 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "biz"}, {Name: "namespace", Value: "team-a"}}
+	labels3 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "biz"}, {Name: "namespace", Value: "team-b"}}
+	labels4 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "bar"}}
+	labels5 := labels.Labels{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: "biz"}}
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{labels1}, []mimirpb.Sample{{TimestampMs: 0, Value: 1}}, nil, nil, mimirpb.API))
+	require.NoError(t, err)
+
+	// The series of another scope, and the series without the scope label, are not affected by the full scope.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest(
+		[]labels.Labels{labels3, labels4, labels5},
+		[]mimirpb.Sample{{TimestampMs: 1, Value: 2}, {TimestampMs: 1, Value: 3}, {TimestampMs: 1, Value: 4}},
+		nil, nil, mimirpb.API))
+	require.NoError(t, err)
+
+	// Append to a new series of the full scope.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{labels2}, []mimirpb.Sample{{TimestampMs: 1, Value: 5}}, nil, nil, mimirpb.API))
+	httpResp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok, "returned error is not an httpgrpc response")
+	assert.Equal(t, http.StatusBadRequest, int(httpResp.Code))
+	assert.Equal(t, wrapWithUser(makeMetricLimitError(perScopeSeriesLimit, labels2, ing.limiter.FormatError(userID, errMaxSeriesPerScopeLimitExceeded)), userID).Error(), string(httpResp.Body))
+
+	assert.Equal(t, uint64(4), ing.getTSDB(userID).Head().NumSeries())
+	assert.Equal(t, 1, ing.getTSDB(userID).seriesInScope.seriesForScope("team-a"))
+	assert.Equal(t, 1, ing.getTSDB(userID).seriesInScope.seriesForScope("team-b"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ing.metrics.perScopeSeriesLimitDiscardedSamples.WithLabelValues(userID, "team-a")))
+}
+
+func TestIngester_QueriesShouldNotReturnSamplesOutsideTheRetention(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.CompactorBlocksRetentionPeriod = model.Duration(time.Hour)
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ReplicationFactor = 1
+
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), ing))
+	})
+
+	// Wait until it's healthy
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	now := time.Now()
+	oldTs := now.Add(-2 * time.Hour).UnixMilli()
+	newTs := now.UnixMilli()
+
+	oldSeries := labels.Labels{{Name: labels.MetricName, Value: "test"}, {Name: "series", Value: "old"}}
+	newSeries := labels.Labels{{Name: labels.MetricName, Value: "test"}, {Name: "series", Value: "new"}}
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{oldSeries, newSeries}, []mimirpb.Sample{{TimestampMs: oldTs, Value: 1}, {TimestampMs: oldTs, Value: 2}}, nil, nil, mimirpb.API))
+	require.NoError(t, err)
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{newSeries}, []mimirpb.Sample{{TimestampMs: newTs, Value: 3}}, nil, nil, mimirpb.API))
+	require.NoError(t, err)
+
+	t.Run("QueryStream", func(t *testing.T) {
+		res, _, err := runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "test")
+		require.NoError(t, err)
+		assert.Equal(t, model.Matrix{
+			{Metric: util.LabelsToMetric(newSeries), Values: []model.SamplePair{{Timestamp: model.Time(newTs), Value: 3}}},
+		}, res)
+
+		// A query fully outside the retention returns nothing.
+		res, _, err = runTestQueryTimes(ctx, t, ing, labels.MatchEqual, labels.MetricName, "test", model.Time(oldTs), model.Time(oldTs+1))
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	})
+
+	t.Run("MetricsForLabelMatchers", func(t *testing.T) {
+		res, err := ing.MetricsForLabelMatchers(ctx, &client.MetricsForLabelMatchersRequest{
+			StartTimestampMs: oldTs,
+			EndTimestampMs:   newTs,
+			MatchersSet:      []*client.LabelMatchers{{Matchers: []*client.LabelMatcher{{Type: client.EQUAL, Name: labels.MetricName, Value: "test"}}}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*mimirpb.Metric{{Labels: mimirpb.FromLabelsToLabelAdapters(newSeries)}}, res.Metric)
+	})
+
+	t.Run("LabelValues", func(t *testing.T) {
+		res, err := ing.LabelValues(ctx, &client.LabelValuesRequest{LabelName: "series", StartTimestampMs: oldTs, EndTimestampMs: oldTs + 1})
+		require.NoError(t, err)
+		assert.Empty(t, res.LabelValues)
+	})
+
+	t.Run("LabelNames", func(t *testing.T) {
+		res, err := ing.LabelNames(ctx, &client.LabelNamesRequest{StartTimestampMs: oldTs, EndTimestampMs: oldTs + 1})
+		require.NoError(t, err)
+		assert.Empty(t, res.LabelNames)
+	})
+}
+
 func TestIngesterMetricLimitExceeded(t *testing.T) {
 	limits := defaultLimitsTestConfig()
 	limits.MaxGlobalSeriesPerMetric = 1
@@ -5778,6 +6618,60 @@ func TestGetIgnoreSeriesLimitForMetricNamesMap(t *testing.T) {
 	require.Equal(t, map[string]struct{}{"foo": {}, "bar": {}}, cfg.getIgnoreSeriesLimitForMetricNamesMap())
 }
 
+func Test_Ingester_SuggestedOutOfOrderTimeWindow(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	limits := defaultLimitsTestConfig()
+
+	registry := prometheus.NewRegistry()
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+
+	pushSamples := func(start, end int64) error {
+		s := labels.FromStrings(labels.MetricName, "test_1", "status", "200")
+		var samples []mimirpb.Sample
+		var lbls []labels.Labels
+		for ts := start * time.Minute.Milliseconds(); ts <= end*time.Minute.Milliseconds(); ts += time.Minute.Milliseconds() {
+			samples = append(samples, mimirpb.Sample{TimestampMs: ts, Value: float64(ts)})
+			lbls = append(lbls, s)
+		}
+
+		_, err := i.Push(ctx, mimirpb.ToWriteRequest(lbls, samples, nil, nil, mimirpb.API))
+		return err
+	}
+
+	updateOutOfOrderTimeWindows := func() {
+		i.updateSuggestedOutOfOrderTimeWindows(time.Now())
+		i.applyTSDBSettings()
+	}
+
+	// Push the first in-order sample at minute 100.
+	require.NoError(t, pushSamples(100, 100))
+
+	// OOO is not enabled, so the samples up to 10 minutes late are rejected, and nothing is suggested yet.
+	require.Error(t, pushSamples(90, 99))
+	assert.Equal(t, 0, testutil.CollectAndCount(i.metrics.suggestedOutOfOrderTimeWindow))
+
+	// The window which would have accepted them is suggested, but the samples are still rejected, because the
+	// out-of-order time window is the configured one only.
+	updateOutOfOrderTimeWindows()
+	require.Error(t, pushSamples(90, 99))
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_suggested_out_of_order_time_window_seconds The out-of-order time window which would have accepted 99% of the samples recently rejected for being too old, per user.
+		# TYPE cortex_ingester_suggested_out_of_order_time_window_seconds gauge
+		cortex_ingester_suggested_out_of_order_time_window_seconds{user="test"} 600
+	`), "cortex_ingester_suggested_out_of_order_time_window_seconds"))
+}
+
 // Test_Ingester_OutOfOrder tests basic ingestion and query of out-of-order samples.
 // It also tests if the OutOfOrderTimeWindow gets changed during runtime.
 // The correctness of changed runtime is already tested in Prometheus, so we only check if the
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+const (
+	// The quantile of the lateness of the rejected samples covered by the suggested out-of-order time window.
+	oooWindowSuggestionQuantile = 0.99
+
+	// The suggested out-of-order time window is computed over the samples rejected in the last one to two periods.
+	latenessTrackerRotationPeriod = time.Hour
+)
+
+// latenessBuckets are the upper bounds of the lateness buckets, which are also the out-of-order time windows
+// which can be suggested. The samples later than the last bucket are counted in the last bucket.
+var latenessBuckets = []time.Duration{
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	3 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+	48 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// latenessTracker tracks the distribution of the lateness of the samples of a tenant rejected for being too old,
+// where the lateness is the distance of the sample from the TSDB head max time, to suggest the out-of-order time
+// window which would have accepted most of them. It's safe for concurrent use.
+type latenessTracker struct {
+	current []atomic.Uint64
+
+	mtx          sync.Mutex
+	previous     []uint64
+	lastRotation time.Time
+}
+
+func newLatenessTracker(now time.Time) *latenessTracker {
+	return &latenessTracker{
+		current:      make([]atomic.Uint64, len(latenessBuckets)),
+		previous:     make([]uint64, len(latenessBuckets)),
+		lastRotation: now,
+	}
+}
+
+// observe tracks a rejected sample with the given lateness.
+func (t *latenessTracker) observe(lateness time.Duration) {
+	for idx, upperBound := range latenessBuckets {
+		if lateness <= upperBound || idx == len(latenessBuckets)-1 {
+			t.current[idx].Inc()
+			return
+		}
+	}
+}
+
+// suggestedWindow returns the smallest out-of-order time window which would have accepted the given quantile
+// of the samples rejected in the last one to two rotation periods, or 0 if no sample has been rejected.
+// It first rotates the tracked samples if the rotation period has elapsed.
+func (t *latenessTracker) suggestedWindow(now time.Time, quantile float64) time.Duration {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if now.Sub(t.lastRotation) >= latenessTrackerRotationPeriod {
+		for idx := range t.previous {
+			t.previous[idx] = t.current[idx].Swap(0)
+		}
+		t.lastRotation = now
+	}
+
+	counts := make([]uint64, len(latenessBuckets))
+	total := uint64(0)
+	for idx := range counts {
+		counts[idx] = t.previous[idx] + t.current[idx].Load()
+		total += counts[idx]
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	target := quantile * float64(total)
+	cumulative := uint64(0)
+	for idx, count := range counts {
+		cumulative += count
+		if float64(cumulative) >= target {
+			return latenessBuckets[idx]
+		}
+	}
+	return latenessBuckets[len(latenessBuckets)-1]
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatenessTracker_SuggestedWindow(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		observed []time.Duration
+		expected time.Duration
+	}{
+		"no rejected samples": {
+			expected: 0,
+		},
+		"lateness matching a bucket upper bound": {
+			observed: []time.Duration{5 * time.Minute},
+			expected: 5 * time.Minute,
+		},
+		"lateness between two buckets": {
+			observed: []time.Duration{20 * time.Minute},
+			expected: 30 * time.Minute,
+		},
+		"lateness beyond the last bucket": {
+			observed: []time.Duration{30 * 24 * time.Hour},
+			expected: 7 * 24 * time.Hour,
+		},
+		"the outliers above the quantile are ignored": {
+			observed: append(repeatDuration(time.Minute, 99), 24*time.Hour),
+			expected: time.Minute,
+		},
+		"the quantile is covered": {
+			observed: append(repeatDuration(time.Minute, 98), 24*time.Hour, 24*time.Hour),
+			expected: 24 * time.Hour,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			tracker := newLatenessTracker(now)
+			for _, lateness := range testData.observed {
+				tracker.observe(lateness)
+			}
+			assert.Equal(t, testData.expected, tracker.suggestedWindow(now, oooWindowSuggestionQuantile))
+		})
+	}
+}
+
+func TestLatenessTracker_ShouldForgetTheSamplesRejectedBeforeThePreviousRotationPeriod(t *testing.T) {
+	now := time.Now()
+	tracker := newLatenessTracker(now)
+	tracker.observe(time.Hour)
+
+	// The samples are kept for the current and the next rotation periods.
+	now = now.Add(latenessTrackerRotationPeriod)
+	assert.Equal(t, time.Hour, tracker.suggestedWindow(now, oooWindowSuggestionQuantile))
+
+	tracker.observe(time.Minute)
+	assert.Equal(t, time.Hour, tracker.suggestedWindow(now, oooWindowSuggestionQuantile))
+
+	now = now.Add(latenessTrackerRotationPeriod)
+	assert.Equal(t, time.Minute, tracker.suggestedWindow(now, oooWindowSuggestionQuantile))
+
+	now = now.Add(latenessTrackerRotationPeriod)
+	assert.Equal(t, time.Duration(0), tracker.suggestedWindow(now, oooWindowSuggestionQuantile))
+}
+
+func repeatDuration(d time.Duration, n int) []time.Duration {
+	out := make([]time.Duration, n)
+	for i := range out {
+		out[i] = d
+	}
+	return out
+}
@@ -20,10 +20,14 @@ import (
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
 	"github.com/grafana/dskit/test"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -130,6 +134,77 @@ func TestIngester_ShutdownHandler(t *testing.T) {
 	}
 }
 
+func TestIngester_ShutdownHandler_ShouldRejectUploadIfShippingIsDisabled(t *testing.T) {
+	config := defaultIngesterTestConfig(t)
+	config.BlocksStorageConfig.TSDB.ShipInterval = 0
+	limits := defaultLimitsTestConfig()
+
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, config, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	recorder := httptest.NewRecorder()
+	ing.ShutdownHandler(recorder, httptest.NewRequest("POST", "/ingester/shutdown?upload=true", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// The ingester hasn't been shut down.
+	require.Equal(t, services.Running, ing.State())
+}
+
+func TestIngester_ReadOnlyHandler(t *testing.T) {
+	config := defaultIngesterTestConfig(t)
+	limits := defaultLimitsTestConfig()
+
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, config, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	// Wait until the ingester is ACTIVE in the ring.
+	test.Poll(t, time.Second, ring.ACTIVE, func() interface{} {
+		return ing.lifecycler.GetState()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	series := []labels.Labels{{{Name: labels.MetricName, Value: "test"}}}
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest(series, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	ing.ReadOnlyHandler(recorder, httptest.NewRequest(http.MethodGet, "/ingester/read-only", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"read_only":false}`, recorder.Body.String())
+
+	// Switch the ingester to read-only mode, twice to check the request is idempotent.
+	for n := 0; n < 2; n++ {
+		recorder = httptest.NewRecorder()
+		ing.ReadOnlyHandler(recorder, httptest.NewRequest(http.MethodPost, "/ingester/read-only", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Contains(t, recorder.Body.String(), `"read_only":true`)
+	}
+
+	// The ingester is LEAVING the ring, so that distributors stop sending it write requests.
+	assert.Equal(t, ring.LEAVING, ing.lifecycler.GetState())
+	test.Poll(t, time.Second, ring.LEAVING, func() interface{} {
+		ringDesc, err := config.IngesterRing.KVStore.Mock.Get(context.Background(), IngesterRingKey)
+		if ringDesc == nil || err != nil {
+			return nil
+		}
+		return ringDesc.(*ring.Desc).Ingesters["localhost"].State
+	})
+
+	// Write requests are rejected.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest(series, []mimirpb.Sample{{TimestampMs: 2, Value: 2}}, nil, nil, mimirpb.API))
+	require.Equal(t, errIngesterReadOnly, err)
+
+	// Queries still work.
+	res, _, err := runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "test")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []model.SamplePair{{Timestamp: 1, Value: 1}}, res[0].Values)
+}
+
 // numTokens determines the number of tokens owned by the specified
 // address
 func numTokens(c kv.Client, name, ringKey string) int {
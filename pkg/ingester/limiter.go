@@ -8,6 +8,7 @@ package ingester
 import (
 	"fmt"
 	"math"
+	"math/rand"
 
 	"github.com/pkg/errors"
 
@@ -22,6 +23,7 @@ var (
 	errMaxSeriesPerMetricLimitExceeded   = errors.New("per-metric series limit exceeded")
 	errMaxMetadataPerMetricLimitExceeded = errors.New("per-metric metadata limit exceeded")
 	errMaxSeriesPerUserLimitExceeded     = errors.New("per-user series limit exceeded")
+	errMaxSeriesPerScopeLimitExceeded    = errors.New("per-scope series limit exceeded")
 	errMaxMetadataPerUserLimitExceeded   = errors.New("per-user metric metadata limit exceeded")
 )
 
@@ -66,6 +68,27 @@ func (l *Limiter) AssertMaxSeriesPerMetric(userID string, series int) error {
 	return errMaxSeriesPerMetricLimitExceeded
 }
 
+// AssertMaxSeriesPerScope limit has not been reached compared to the current
+// number of series in the scope in input and returns an error if so.
+func (l *Limiter) AssertMaxSeriesPerScope(userID string, series int) error {
+	if actualLimit := l.maxSeriesPerScope(userID); series < actualLimit {
+		return nil
+	}
+
+	return errMaxSeriesPerScopeLimitExceeded
+}
+
+// AdmitSeriesExceedingMaxSeriesPerUser returns whether a new series exceeding the max series per user limit
+// should be admitted anyway, because the limit is in warn-only mode for the given user.
+func (l *Limiter) AdmitSeriesExceedingMaxSeriesPerUser(userID string) bool {
+	if !l.limits.MaxGlobalSeriesPerUserWarnOnly(userID) {
+		return false
+	}
+
+	ratio := l.limits.MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio(userID)
+	return ratio >= 1 || rand.Float64() < ratio
+}
+
 // AssertMaxMetadataPerMetric limit has not been reached compared to the current
 // number of metadata per metric in input and returns an error if so.
 func (l *Limiter) AssertMaxMetadataPerMetric(userID string, metadata int) error {
@@ -86,6 +109,14 @@ func (l *Limiter) AssertMaxSeriesPerUser(userID string, series int) error {
 	return errMaxSeriesPerUserLimitExceeded
 }
 
+// AssertMaxOwnedSeriesPerUser limit has not been reached compared to the current
+// number of series owned by the ingester in input and returns an error if so.
+// The owned series are scaled by the replication factor, because the local limit
+// accounts for the replicas too.
+func (l *Limiter) AssertMaxOwnedSeriesPerUser(userID string, ownedSeries int) error {
+	return l.AssertMaxSeriesPerUser(userID, ownedSeries*l.replicationFactor)
+}
+
 // AssertMaxMetricsWithMetadataPerUser limit has not been reached compared to the current
 // number of metrics with metadata in input and returns an error if so.
 func (l *Limiter) AssertMaxMetricsWithMetadataPerUser(userID string, metrics int) error {
@@ -104,6 +135,8 @@ func (l *Limiter) FormatError(userID string, err error) error {
 		return l.formatMaxSeriesPerUserError(userID)
 	case errMaxSeriesPerMetricLimitExceeded:
 		return l.formatMaxSeriesPerMetricError(userID)
+	case errMaxSeriesPerScopeLimitExceeded:
+		return l.formatMaxSeriesPerScopeError(userID)
 	case errMaxMetadataPerUserLimitExceeded:
 		return l.formatMaxMetadataPerUserError(userID)
 	case errMaxMetadataPerMetricLimitExceeded:
@@ -131,6 +164,15 @@ func (l *Limiter) formatMaxSeriesPerMetricError(userID string) error {
 	))
 }
 
+func (l *Limiter) formatMaxSeriesPerScopeError(userID string) error {
+	globalLimit := l.limits.MaxGlobalSeriesPerScope(userID)
+
+	return errors.New(globalerror.MaxSeriesPerScope.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("per-scope series limit of %d exceeded for the value of the label %s", globalLimit, l.limits.SeriesLimitScopeLabel(userID)),
+		validation.MaxSeriesPerScopeFlag,
+	))
+}
+
 func (l *Limiter) formatMaxMetadataPerUserError(userID string) error {
 	globalLimit := l.limits.MaxGlobalMetricsWithMetadataPerUser(userID)
 
@@ -153,6 +195,10 @@ func (l *Limiter) maxSeriesPerMetric(userID string) int {
 	return l.convertGlobalToLocalLimitOrUnlimited(userID, l.limits.MaxGlobalSeriesPerMetric)
 }
 
+func (l *Limiter) maxSeriesPerScope(userID string) int {
+	return l.convertGlobalToLocalLimitOrUnlimited(userID, l.limits.MaxGlobalSeriesPerScope)
+}
+
 func (l *Limiter) maxMetadataPerMetric(userID string) int {
 	return l.convertGlobalToLocalLimitOrUnlimited(userID, l.limits.MaxGlobalMetadataPerMetric)
 }
@@ -340,6 +340,60 @@ func TestLimiter_AssertMaxSeriesPerUser(t *testing.T) {
 	}
 }
 
+func TestLimiter_AssertMaxOwnedSeriesPerUser(t *testing.T) {
+	tests := map[string]struct {
+		maxGlobalSeriesPerUser int
+		ringReplicationFactor  int
+		ringIngesterCount      int
+		ownedSeries            int
+		expected               error
+	}{
+		"limit is disabled": {
+			maxGlobalSeriesPerUser: 0,
+			ringReplicationFactor:  1,
+			ringIngesterCount:      1,
+			ownedSeries:            100,
+			expected:               nil,
+		},
+		"current number of owned series is below the limit": {
+			maxGlobalSeriesPerUser: 1000,
+			ringReplicationFactor:  3,
+			ringIngesterCount:      10,
+			ownedSeries:            99,
+			expected:               nil,
+		},
+		"current number of owned series is above the limit": {
+			maxGlobalSeriesPerUser: 1000,
+			ringReplicationFactor:  3,
+			ringIngesterCount:      10,
+			ownedSeries:            100,
+			expected:               errMaxSeriesPerUserLimitExceeded,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			// Mock the ring
+			ring := &ringCountMock{}
+			ring.On("HealthyInstancesCount").Return(testData.ringIngesterCount)
+			ring.On("ZonesCount").Return(1)
+
+			// Mock limits
+			limits, err := validation.NewOverrides(validation.Limits{
+				MaxGlobalSeriesPerUser: testData.maxGlobalSeriesPerUser,
+			}, nil)
+			require.NoError(t, err)
+
+			limiter := NewLimiter(limits, ring, testData.ringReplicationFactor, false)
+			actual := limiter.AssertMaxOwnedSeriesPerUser("test", testData.ownedSeries)
+
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+}
+
 func TestLimiter_AssertMaxMetricsWithMetadataPerUser(t *testing.T) {
 	tests := map[string]struct {
 		maxGlobalMetadataPerUser int
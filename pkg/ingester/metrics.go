@@ -25,11 +25,21 @@ type ingesterMetrics struct {
 	queriedSamples          prometheus.Histogram
 	queriedExemplars        prometheus.Histogram
 	queriedSeries           prometheus.Histogram
+	queriedChunks           prometheus.Histogram
 	memMetadata             prometheus.Gauge
 	memUsers                prometheus.Gauge
 	memMetadataCreatedTotal *prometheus.CounterVec
 	memMetadataRemovedTotal *prometheus.CounterVec
 
+	warnOnlySeriesAdmitted *prometheus.CounterVec
+
+	perScopeSeriesLimitDiscardedSamples *prometheus.CounterVec
+
+	suggestedOutOfOrderTimeWindow *prometheus.GaugeVec
+
+	ownedSeriesPerUser      *prometheus.GaugeVec
+	replicatedSeriesPerUser *prometheus.GaugeVec
+
 	activeSeriesLoading               *prometheus.GaugeVec
 	activeSeriesPerUser               *prometheus.GaugeVec
 	activeSeriesCustomTrackersPerUser *prometheus.GaugeVec
@@ -45,10 +55,20 @@ type ingesterMetrics struct {
 	// Head compactions metrics.
 	compactionsTriggered   prometheus.Counter
 	compactionsFailed      prometheus.Counter
+	earlyHeadCompactions   *prometheus.CounterVec
 	walReplayTime          prometheus.Histogram
 	appenderAddDuration    prometheus.Histogram
 	appenderCommitDuration prometheus.Histogram
 	idleTsdbChecks         *prometheus.CounterVec
+
+	// Periodic memory snapshots metrics.
+	memorySnapshotsTriggered prometheus.Counter
+	memorySnapshotsFailed    prometheus.Counter
+
+	// WAL replay at startup metrics.
+	walReplayTenantsRemaining  prometheus.Gauge
+	walReplaySegmentsRemaining prometheus.Gauge
+	walReplaySegmentsReplayed  prometheus.Counter
 }
 
 func newIngesterMetrics(
@@ -103,6 +123,26 @@ func newIngesterMetrics(
 			Name: "cortex_ingester_ingested_samples_failures_total",
 			Help: "The total number of samples that errored on ingestion per user.",
 		}, []string{"user"}),
+		warnOnlySeriesAdmitted: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_series_limit_warn_only_admitted_series_total",
+			Help: "The total number of new series admitted in spite of the per-user series limit, because the limit is in warn-only mode.",
+		}, []string{"user"}),
+		perScopeSeriesLimitDiscardedSamples: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_series_per_scope_limit_discarded_samples_total",
+			Help: "The total number of samples discarded because the per-scope series limit was exceeded, per user and scope.",
+		}, []string{"user", "scope"}),
+		suggestedOutOfOrderTimeWindow: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_ingester_suggested_out_of_order_time_window_seconds",
+			Help: "The out-of-order time window which would have accepted 99% of the samples recently rejected for being too old, per user.",
+		}, []string{"user"}),
+		ownedSeriesPerUser: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_ingester_owned_series",
+			Help: "The number of in-memory series owned by the ingester, being the ingester the primary replica of the series in the ring, per user.",
+		}, []string{"user"}),
+		replicatedSeriesPerUser: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_ingester_replicated_series",
+			Help: "The number of in-memory series held by the ingester as replicas of the series owned by other ingesters, per user.",
+		}, []string{"user"}),
 		ingestedExemplarsFail: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_ingester_ingested_exemplars_failures_total",
 			Help: "The total number of exemplars that errored on ingestion.",
@@ -133,6 +173,12 @@ func newIngesterMetrics(
 			// A reasonable upper bound is around 100k - 10*(8^(6-1)) = 327k.
 			Buckets: prometheus.ExponentialBuckets(10, 8, 6),
 		}),
+		queriedChunks: promauto.With(r).NewHistogram(prometheus.HistogramOpts{
+			Name: "cortex_ingester_queried_chunks",
+			Help: "The total number of chunks streamed to the queriers by queries.",
+			// A reasonable upper bound is around 1m - 10*(8^(7-1)) = 2.6m.
+			Buckets: prometheus.ExponentialBuckets(10, 8, 7),
+		}),
 		memMetadata: promauto.With(r).NewGauge(prometheus.GaugeOpts{
 			Name: "cortex_ingester_memory_metadata",
 			Help: "The current number of metadata in memory.",
@@ -241,6 +287,36 @@ func newIngesterMetrics(
 			Name: "cortex_ingester_tsdb_compactions_failed_total",
 			Help: "Total number of compactions that failed.",
 		}),
+
+		earlyHeadCompactions: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_tsdb_early_head_compactions_total",
+			Help: "Total number of per-tenant TSDB head compactions of block aligned time ranges run before the head reached the regular compaction threshold, to truncate the head chunks and WAL earlier, by reason.",
+		}, []string{"reason"}),
+
+		memorySnapshotsTriggered: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_tsdb_memory_snapshots_triggered_total",
+			Help: "Total number of triggered periodic TSDB memory snapshots.",
+		}),
+
+		memorySnapshotsFailed: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_tsdb_memory_snapshots_failed_total",
+			Help: "Total number of periodic TSDB memory snapshots that failed.",
+		}),
+
+		walReplayTenantsRemaining: promauto.With(r).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_tsdb_wal_replay_tenants_remaining",
+			Help: "Number of tenants whose TSDB is still to be opened, and its WAL replayed, at startup.",
+		}),
+
+		walReplaySegmentsRemaining: promauto.With(r).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_tsdb_wal_replay_segments_remaining",
+			Help: "Number of WAL segments still to be replayed at startup.",
+		}),
+
+		walReplaySegmentsReplayed: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_tsdb_wal_replay_segments_replayed_total",
+			Help: "Total number of WAL segments replayed at startup.",
+		}),
 		walReplayTime: promauto.With(r).NewHistogram(prometheus.HistogramOpts{
 			Name:    "cortex_ingester_tsdb_wal_replay_duration_seconds",
 			Help:    "The total time it takes to open and replay a TSDB WAL.",
@@ -268,6 +344,11 @@ func (m *ingesterMetrics) deletePerUserMetrics(userID string) {
 	m.ingestedSamplesFail.DeleteLabelValues(userID)
 	m.memMetadataCreatedTotal.DeleteLabelValues(userID)
 	m.memMetadataRemovedTotal.DeleteLabelValues(userID)
+	m.warnOnlySeriesAdmitted.DeleteLabelValues(userID)
+	m.perScopeSeriesLimitDiscardedSamples.DeletePartialMatch(prometheus.Labels{"user": userID})
+	m.suggestedOutOfOrderTimeWindow.DeleteLabelValues(userID)
+	m.ownedSeriesPerUser.DeleteLabelValues(userID)
+	m.replicatedSeriesPerUser.DeleteLabelValues(userID)
 }
 
 func (m *ingesterMetrics) deletePerUserCustomTrackerMetrics(userID string, customTrackerMetrics []string) {
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// ownedSeriesChecker tells whether a series is owned by the ingester, being the ingester the primary replica of the
+// series in the ring. The series which are not owned are replicas of the series owned by another ingester. It's not
+// safe for concurrent use, since the ring lookup buffers are reused across calls.
+type ownedSeriesChecker struct {
+	ring         ring.ReadRing
+	instanceAddr string
+	userID       string
+
+	bufDescs [ring.GetBufferSize]ring.InstanceDesc
+	bufHosts [ring.GetBufferSize]string
+	bufZones [ring.GetBufferSize]string
+}
+
+// newOwnedSeriesChecker returns a checker looking up the owner of the series of the tenant in the given subring,
+// which is the shuffle shard of the tenant.
+func newOwnedSeriesChecker(subring ring.ReadRing, instanceAddr, userID string) *ownedSeriesChecker {
+	return &ownedSeriesChecker{
+		ring:         subring,
+		instanceAddr: instanceAddr,
+		userID:       userID,
+	}
+}
+
+func (c *ownedSeriesChecker) isOwned(series labels.Labels) (bool, error) {
+	set, err := c.ring.Get(client.ShardByAllLabels(c.userID, mimirpb.FromLabelsToLabelAdapters(series)), ring.WriteNoExtend, c.bufDescs[:0], c.bufHosts[:0], c.bufZones[:0])
+	if err != nil {
+		return false, err
+	}
+
+	// The first instance of the replication set is the one owning the token range the series belongs to.
+	return len(set.Instances) > 0 && set.Instances[0].Addr == c.instanceAddr, nil
+}
+
+// isOwnedSeries returns whether the series of the tenant is owned by the ingester. It returns false if the owned
+// series tracking is disabled, or if the ingester ring can't tell the owner of the series.
+func (i *Ingester) isOwnedSeries(db *userTSDB, series labels.Labels) bool {
+	if i.ownedSeriesRing == nil {
+		return false
+	}
+
+	owned, err := newOwnedSeriesChecker(i.getOwnedSeriesSubring(db), i.lifecycler.Addr, db.userID).isOwned(series)
+	return err == nil && owned
+}
+
+// getOwnedSeriesSubring returns the subring of the tenant the owned series are looked up in. It's computed on the
+// first lookup, and then once per recount of the owned series, instead of for each series.
+func (i *Ingester) getOwnedSeriesSubring(db *userTSDB) ring.ReadRing {
+	db.ownedSeriesSubringMtx.RLock()
+	subring := db.ownedSeriesSubring
+	db.ownedSeriesSubringMtx.RUnlock()
+
+	if subring != nil {
+		return subring
+	}
+	return i.updateOwnedSeriesSubring(db)
+}
+
+// updateOwnedSeriesSubring recomputes the subring of the tenant the owned series are looked up in, and returns it.
+func (i *Ingester) updateOwnedSeriesSubring(db *userTSDB) ring.ReadRing {
+	subring := i.ownedSeriesRing.ShuffleShard(db.userID, i.limits.IngestionTenantShardSize(db.userID))
+
+	db.ownedSeriesSubringMtx.Lock()
+	db.ownedSeriesSubring = subring
+	db.ownedSeriesSubringMtx.Unlock()
+
+	return subring
+}
+
+// updateOwnedSeries recomputes the number of in-memory series owned by the ingester for each tenant, and updates the
+// owned and replicated series metrics. It's expected to be called periodically.
+func (i *Ingester) updateOwnedSeries(ctx context.Context) error {
+	for _, userID := range i.getTSDBUsers() {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		db := i.getTSDB(userID)
+		if db == nil {
+			continue
+		}
+
+		owned, total, err := i.countOwnedSeries(db)
+		if err != nil {
+			level.Warn(i.logger).Log("msg", "failed to compute the owned series", "user", userID, "err", err)
+			continue
+		}
+
+		db.ownedSeries.Store(int64(owned))
+		db.ownedSeriesComputed.Store(true)
+		i.metrics.ownedSeriesPerUser.WithLabelValues(userID).Set(float64(owned))
+		i.metrics.replicatedSeriesPerUser.WithLabelValues(userID).Set(float64(total - owned))
+	}
+
+	return nil
+}
+
+// countOwnedSeries returns the number of the in-memory series of the tenant owned by the ingester, and the total
+// number of its in-memory series.
+func (i *Ingester) countOwnedSeries(db *userTSDB) (owned, total int, _ error) {
+	idx, err := db.Head().Index()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer idx.Close()
+
+	postings, err := idx.Postings(index.AllPostingsKey())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The subring is recomputed once per recount, picking up the changes of the ring since the previous one.
+	checker := newOwnedSeriesChecker(i.updateOwnedSeriesSubring(db), i.lifecycler.Addr, db.userID)
+	var series labels.Labels
+	for postings.Next() {
+		if err := idx.Series(postings.At(), &series, nil); err != nil {
+			// The series may have been garbage collected in the meanwhile.
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			return 0, 0, err
+		}
+
+		isOwned, err := checker.isOwned(series)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to look up the series owner in the ring")
+		}
+
+		total++
+		if isOwned {
+			owned++
+		}
+	}
+
+	return owned, total, postings.Err()
+}
@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestIngester_OwnedSeries(t *testing.T) {
+	const numSeries = 50
+
+	// Register the ingester, and another one, in the ring with tokens such that the ingester owns the series whose
+	// token is between the other ingester's token, exclusive, and its own token, inclusive: half of the series.
+	series := make([]labels.Labels, 0, numSeries)
+	tokens := make([]uint32, 0, numSeries)
+	for seriesID := 0; seriesID < numSeries; seriesID++ {
+		s := labels.FromStrings(labels.MetricName, "test", "series_id", strconv.Itoa(seriesID))
+		series = append(series, s)
+		tokens = append(tokens, client.ShardByAllLabels(userID, mimirpb.FromLabelsToLabelAdapters(s)))
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i] < tokens[j] })
+	ownToken, otherToken := tokens[numSeries-1], tokens[numSeries/2-1]
+	expectedOwned := numSeries / 2
+
+	tokensFile := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, ring.Tokens{ownToken}.StoreToFile(tokensFile))
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ReplicationFactor = 2
+	cfg.IngesterRing.TokensFilePath = tokensFile
+	// The owned series are recomputed explicitly by the test.
+	cfg.OwnedSeriesUpdatePeriod = time.Hour
+
+	reg := prometheus.NewPedanticRegistry()
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, reg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	test.Poll(t, time.Second, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	require.NoError(t, cfg.IngesterRing.KVStore.Mock.CAS(context.Background(), IngesterRingKey, func(in interface{}) (interface{}, bool, error) {
+		desc := in.(*ring.Desc)
+		desc.AddIngester("other", "other-addr", "", []uint32{otherToken}, ring.ACTIVE, time.Now())
+		return desc, true, nil
+	}))
+	test.Poll(t, time.Second, 2, func() interface{} {
+		return i.ownedSeriesRing.InstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	for _, s := range series {
+		req, _, _, _ := mockWriteRequest(t, s, 1, 1)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	// The owned series are tracked on series creation.
+	db := i.getTSDB(userID)
+	assert.Equal(t, int64(expectedOwned), db.ownedSeries.Load())
+	assert.False(t, db.ownedSeriesComputed.Load())
+
+	// The metrics are exported once the owned series are computed.
+	db.ownedSeries.Store(0)
+	require.NoError(t, i.updateOwnedSeries(context.Background()))
+	assert.Equal(t, int64(expectedOwned), db.ownedSeries.Load())
+	assert.True(t, db.ownedSeriesComputed.Load())
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+		# HELP cortex_ingester_owned_series The number of in-memory series owned by the ingester, being the ingester the primary replica of the series in the ring, per user.
+		# TYPE cortex_ingester_owned_series gauge
+		cortex_ingester_owned_series{user="%s"} %d
+		# HELP cortex_ingester_replicated_series The number of in-memory series held by the ingester as replicas of the series owned by other ingesters, per user.
+		# TYPE cortex_ingester_replicated_series gauge
+		cortex_ingester_replicated_series{user="%s"} %d
+	`, userID, expectedOwned, userID, numSeries-expectedOwned)), "cortex_ingester_owned_series", "cortex_ingester_replicated_series"))
+}
+
+func TestIngester_UseOwnedSeriesForLimits(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ReplicationFactor = 1
+	cfg.OwnedSeriesUpdatePeriod = time.Hour
+	cfg.UseOwnedSeriesForLimits = true
+
+	limits := defaultLimitsTestConfig()
+	limits.MaxGlobalSeriesPerUser = 10
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	test.Poll(t, time.Second, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return i.ownedSeriesRing.InstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	push := func(seriesID int) error {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test", "series_id", strconv.Itoa(seriesID)), 1, 1)
+		_, err := i.Push(ctx, req)
+		return err
+	}
+
+	for seriesID := 0; seriesID < 5; seriesID++ {
+		require.NoError(t, push(seriesID))
+	}
+	require.NoError(t, i.updateOwnedSeries(context.Background()))
+
+	// Pretend that some of the series are not owned anymore, like after the ring topology changed.
+	db := i.getTSDB(userID)
+	db.ownedSeries.Store(2)
+
+	// The series limit is enforced on the owned series, admitting more in-memory series than the limit.
+	for seriesID := 5; seriesID < 13; seriesID++ {
+		require.NoError(t, push(seriesID))
+	}
+	assert.Equal(t, uint64(13), db.Head().NumSeries())
+	assert.Equal(t, int64(10), db.ownedSeries.Load())
+
+	require.Error(t, push(13))
+}
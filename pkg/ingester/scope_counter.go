@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"sync"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// DiscardedSamples metric label for the samples rejected because of the per-scope series limit.
+const perScopeSeriesLimit = "per_scope_series_limit"
+
+// scopeCounter counts the in-memory series of a tenant per scope, where the scope of a series is the value of
+// the tenant's scope label. The number of scopes of a tenant is expected to be small (e.g. namespaces), so a
+// single lock is enough.
+type scopeCounter struct {
+	limiter *Limiter
+
+	mtx sync.Mutex
+	m   map[string]int
+}
+
+func newScopeCounter(limiter *Limiter) *scopeCounter {
+	return &scopeCounter{
+		limiter: limiter,
+		m:       map[string]int{},
+	}
+}
+
+// scopeOf returns the scope of the series, which is the value of the tenant's scope label, or an empty string
+// if the scope label is not configured for the tenant or the series doesn't have it.
+func (c *scopeCounter) scopeOf(userID string, metric labels.Labels) string {
+	// The limiter is not set when the ingester only runs to flush the TSDBs (flusher), which doesn't enforce limits.
+	if c.limiter == nil {
+		return ""
+	}
+
+	scopeLabel := c.limiter.limits.SeriesLimitScopeLabel(userID)
+	if scopeLabel == "" {
+		return ""
+	}
+	return metric.Get(scopeLabel)
+}
+
+func (c *scopeCounter) canAddSeriesFor(userID, scope string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.limiter.AssertMaxSeriesPerScope(userID, c.m[scope])
+}
+
+func (c *scopeCounter) increaseSeriesForScope(scope string) {
+	c.mtx.Lock()
+	c.m[scope]++
+	c.mtx.Unlock()
+}
+
+func (c *scopeCounter) decreaseSeriesForScope(scope string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// The count may be missing if the tenant's scope label has changed since the series creation.
+	if c.m[scope] <= 1 {
+		delete(c.m, scope)
+		return
+	}
+	c.m[scope]--
+}
+
+func (c *scopeCounter) seriesForScope(scope string) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.m[scope]
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/shipper"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/tsdb"
 )
 
@@ -66,6 +67,12 @@ type Shipper struct {
 	source  metadata.SourceType
 
 	hashFunc metadata.HashFunc
+
+	// Optional function returning the metric metadata to persist in each uploaded block.
+	metricMetadata func() []mimirpb.MetricMetadata
+
+	// Optional function returning the exemplars, within the time range of the block, to persist in each uploaded block.
+	exemplars func(minT, maxT int64) []mimirpb.TimeSeries
 }
 
 // NewShipper creates a new uploader that detects new TSDB blocks in dir and uploads them to
@@ -78,18 +85,22 @@ func NewShipper(
 	bucket objstore.Bucket,
 	source metadata.SourceType,
 	hashFunc metadata.HashFunc,
+	metricMetadata func() []mimirpb.MetricMetadata,
+	exemplars func(minT, maxT int64) []mimirpb.TimeSeries,
 ) *Shipper {
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
 
 	return &Shipper{
-		logger:   logger,
-		dir:      dir,
-		bucket:   bucket,
-		metrics:  newMetrics(r),
-		source:   source,
-		hashFunc: hashFunc,
+		logger:         logger,
+		dir:            dir,
+		bucket:         bucket,
+		metrics:        newMetrics(r),
+		source:         source,
+		hashFunc:       hashFunc,
+		metricMetadata: metricMetadata,
+		exemplars:      exemplars,
 	}
 }
 
@@ -189,6 +200,22 @@ func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
 	meta.Thanos.Source = s.source
 	meta.Thanos.SegmentFiles = block.GetSegmentFiles(blockDir)
 
+	if s.metricMetadata != nil {
+		if md := s.metricMetadata(); len(md) > 0 {
+			if err := tsdb.WriteMetricMetadataFile(blockDir, md); err != nil {
+				return errors.Wrap(err, "write metric metadata file")
+			}
+		}
+	}
+
+	if s.exemplars != nil {
+		if series := s.exemplars(meta.MinTime, meta.MaxTime); len(series) > 0 {
+			if err := tsdb.WriteExemplarsFile(blockDir, series); err != nil {
+				return errors.Wrap(err, "write exemplars file")
+			}
+		}
+	}
+
 	// Upload block with custom metadata.
 	return tsdb.UploadBlock(ctx, s.logger, s.bucket, blockDir, meta)
 }
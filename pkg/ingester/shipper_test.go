@@ -16,13 +16,16 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/dskit/concurrency"
 	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 )
 
 func createBlock(t *testing.T, blocksDir string, id ulid.ULID, m metadata.Meta) {
@@ -45,7 +48,7 @@ func TestShipper(t *testing.T) {
 	logs := &concurrency.SyncBuffer{}
 	logger := log.NewLogfmtLogger(logs)
 
-	s := NewShipper(logger, nil, blocksDir, bkt, metadata.TestSource, metadata.NoneFunc)
+	s := NewShipper(logger, nil, blocksDir, bkt, metadata.TestSource, metadata.NoneFunc, nil, nil)
 
 	t.Run("no shipper file yet", func(t *testing.T) {
 		// No shipper file = nothing is reported as shipped.
@@ -179,7 +182,7 @@ func TestShipper_DeceivingUploadErrors(t *testing.T) {
 	bkt = deceivingUploadBucket{Bucket: bkt, objectBaseName: block.MetaFilename}
 
 	logger := log.NewLogfmtLogger(os.Stderr)
-	s := NewShipper(logger, nil, blocksDir, bkt, metadata.TestSource, metadata.NoneFunc)
+	s := NewShipper(logger, nil, blocksDir, bkt, metadata.TestSource, metadata.NoneFunc, nil, nil)
 
 	// Create and upload a block
 	id1 := ulid.MustNew(1, nil)
@@ -207,3 +210,91 @@ func TestShipper_DeceivingUploadErrors(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, uploaded)
 }
+
+func TestShipper_ShouldUploadTheMetricMetadata(t *testing.T) {
+	blocksDir := t.TempDir()
+	bucketDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: bucketDir})
+	require.NoError(t, err)
+
+	md := []mimirpb.MetricMetadata{{Type: mimirpb.COUNTER, MetricFamilyName: "test_total", Help: "A test counter."}}
+	s := NewShipper(log.NewNopLogger(), nil, blocksDir, bkt, metadata.TestSource, metadata.NoneFunc, func() []mimirpb.MetricMetadata { return md }, nil)
+
+	id1 := ulid.MustNew(1, nil)
+	createBlock(t, blocksDir, id1, metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id1,
+			MaxTime: 2000,
+			MinTime: 1000,
+			Version: 1,
+			Stats: tsdb.BlockStats{
+				NumSamples: 100, // Shipper checks if number of samples is greater than 0.
+			},
+		},
+	})
+
+	uploaded, err := s.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, uploaded)
+
+	r, err := bkt.Get(context.Background(), path.Join(id1.String(), mimir_tsdb.MetricMetadataFilename))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	actual, err := mimir_tsdb.DecodeMetricMetadataFile(data)
+	require.NoError(t, err)
+	require.Equal(t, md, actual)
+}
+
+func TestShipper_ShouldUploadTheExemplars(t *testing.T) {
+	blocksDir := t.TempDir()
+	bucketDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: bucketDir})
+	require.NoError(t, err)
+
+	series := []mimirpb.TimeSeries{{
+		Labels:    []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "test_total"}},
+		Exemplars: []mimirpb.Exemplar{{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "123"}}, Value: 1, TimestampMs: 1500}},
+	}}
+
+	var actualMinT, actualMaxT int64
+	s := NewShipper(log.NewNopLogger(), nil, blocksDir, bkt, metadata.TestSource, metadata.NoneFunc, nil, func(minT, maxT int64) []mimirpb.TimeSeries {
+		actualMinT, actualMaxT = minT, maxT
+		return series
+	})
+
+	id1 := ulid.MustNew(1, nil)
+	createBlock(t, blocksDir, id1, metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id1,
+			MaxTime: 2000,
+			MinTime: 1000,
+			Version: 1,
+			Stats: tsdb.BlockStats{
+				NumSamples: 100, // Shipper checks if number of samples is greater than 0.
+			},
+		},
+	})
+
+	uploaded, err := s.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, uploaded)
+
+	// The exemplars are requested for the time range of the block.
+	require.Equal(t, int64(1000), actualMinT)
+	require.Equal(t, int64(2000), actualMaxT)
+
+	r, err := bkt.Get(context.Background(), path.Join(id1.String(), mimir_tsdb.ExemplarsFilename))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	actual, err := mimir_tsdb.DecodeExemplarsFile(data)
+	require.NoError(t, err)
+	require.Equal(t, series, actual)
+}
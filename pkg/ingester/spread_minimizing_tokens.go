@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/dskit/ring"
+)
+
+const (
+	tokenGenerationStrategyRandom           = "random"
+	tokenGenerationStrategySpreadMinimizing = "spread-minimizing"
+)
+
+var (
+	tokenGenerationStrategies = []string{tokenGenerationStrategyRandom, tokenGenerationStrategySpreadMinimizing}
+
+	instanceOrdinalRegexp = regexp.MustCompile(`-(\d+)$`)
+)
+
+// parseInstanceOrdinal returns the ordinal of the instance, which is the number at the end of its ID,
+// for example 3 for "ingester-zone-a-3".
+func parseInstanceOrdinal(instanceID string) (int, error) {
+	matches := instanceOrdinalRegexp.FindStringSubmatch(instanceID)
+	if len(matches) != 2 {
+		return 0, fmt.Errorf("the instance ID %q doesn't end with an ordinal, like in ingester-zone-a-3", instanceID)
+	}
+	return strconv.Atoi(matches[1])
+}
+
+// spreadMinimizingTokens returns the tokens of the instance with the given ordinal in its zone. The tokens
+// are deterministic: the instances with ordinals 0 to N-1 of a zone always get the same tokens, and these
+// tokens are chosen to minimize the spread of the ring ownership among the instances. Adding the instance
+// with ordinal N only takes the ownership it needs from the instances 0 to N-1, which keep the same tokens.
+//
+// The tokens of the different zones never collide, because the tokens of the zone with index z are all
+// congruent to z modulo the number of zones.
+func spreadMinimizingTokens(ordinal, zoneIndex, numZones, numTokens int) ring.Tokens {
+	size := int64(math.MaxUint32+1) / int64(numZones)
+
+	// Tokens of the first instance are evenly spaced.
+	positions := make([]tokenPosition, 0, (ordinal+1)*numTokens)
+	for t := 0; t < numTokens; t++ {
+		positions = append(positions, tokenPosition{pos: int64(t) * size / int64(numTokens), owner: 0})
+	}
+
+	for instance := 1; instance <= ordinal; instance++ {
+		positions = addSpreadMinimizingInstance(positions, instance, numTokens, size)
+	}
+
+	tokens := make(ring.Tokens, 0, numTokens)
+	for _, p := range positions {
+		if p.owner == ordinal {
+			tokens = append(tokens, uint32(p.pos*int64(numZones)+int64(zoneIndex)))
+		}
+	}
+	sort.Sort(tokens)
+	return tokens
+}
+
+type tokenPosition struct {
+	pos   int64
+	owner int
+}
+
+// addSpreadMinimizingInstance returns the sorted positions of the ring with the tokens of the input instance
+// added. The input positions are the sorted positions of the tokens owned by the instances 0 to instance-1.
+// Each token of the new instance takes a slice of the ownership of the instance which owns the most above
+// the target ownership, at the beginning of its largest range.
+func addSpreadMinimizingInstance(positions []tokenPosition, instance, numTokens int, size int64) []tokenPosition {
+	// The range owned by each token is (prev, pos]. The first range wraps around the ring.
+	prev := make([]int64, len(positions))
+	ownership := make([]int64, instance)
+	tokensByOwner := make([][]int, instance)
+	for i, p := range positions {
+		if i == 0 {
+			prev[i] = positions[len(positions)-1].pos - size
+		} else {
+			prev[i] = positions[i-1].pos
+		}
+		ownership[p.owner] += p.pos - prev[i]
+		tokensByOwner[p.owner] = append(tokensByOwner[p.owner], i)
+	}
+
+	target := size / int64(instance+1)
+	toGive := make([]int64, instance)
+	for owner := range toGive {
+		toGive[owner] = ownership[owner] - target
+	}
+
+	added := make([]tokenPosition, 0, numTokens)
+	remaining := target
+	for t := 0; t < numTokens; t++ {
+		// Pick the owner which owns the most above the target.
+		owner := 0
+		for o := 1; o < instance; o++ {
+			if toGive[o] > toGive[owner] {
+				owner = o
+			}
+		}
+
+		// Pick the largest range of the owner.
+		idx := tokensByOwner[owner][0]
+		for _, i := range tokensByOwner[owner][1:] {
+			if positions[i].pos-prev[i] > positions[idx].pos-prev[idx] {
+				idx = i
+			}
+		}
+		rangeLen := positions[idx].pos - prev[idx]
+
+		amount := remaining / int64(numTokens-t)
+		if toGive[owner] > 0 && toGive[owner] < amount {
+			amount = toGive[owner]
+		}
+		if amount >= rangeLen {
+			amount = rangeLen / 2
+		}
+		if amount < 1 {
+			amount = 1
+		}
+
+		// The new token takes the beginning of the range.
+		pos := prev[idx] + amount
+		prev[idx] = pos
+		if pos < 0 {
+			pos += size
+		}
+		added = append(added, tokenPosition{pos: pos, owner: instance})
+
+		toGive[owner] -= amount
+		remaining -= amount
+	}
+
+	sort.Slice(added, func(i, j int) bool { return added[i].pos < added[j].pos })
+
+	// Merge the sorted positions.
+	merged := make([]tokenPosition, 0, len(positions)+len(added))
+	i, j := 0, 0
+	for i < len(positions) || j < len(added) {
+		if j == len(added) || (i < len(positions) && positions[i].pos < added[j].pos) {
+			merged = append(merged, positions[i])
+			i++
+		} else {
+			merged = append(merged, added[j])
+			j++
+		}
+	}
+	return merged
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInstanceOrdinal(t *testing.T) {
+	tests := map[string]struct {
+		instanceID      string
+		expectedOrdinal int
+		expectedErr     bool
+	}{
+		"instance ID with zone": {
+			instanceID:      "ingester-zone-a-12",
+			expectedOrdinal: 12,
+		},
+		"instance ID without zone": {
+			instanceID:      "ingester-0",
+			expectedOrdinal: 0,
+		},
+		"instance ID without ordinal": {
+			instanceID:  "ingester",
+			expectedErr: true,
+		},
+		"instance ID with a number not preceded by a dash": {
+			instanceID:  "ingester1",
+			expectedErr: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ordinal, err := parseInstanceOrdinal(testData.instanceID)
+			if testData.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedOrdinal, ordinal)
+		})
+	}
+}
+
+func TestSpreadMinimizingTokens(t *testing.T) {
+	const (
+		numTokens    = 128
+		numInstances = 30
+		numZones     = 3
+	)
+
+	tokensByZone := make([][]ring.Tokens, numZones)
+	for zone := 0; zone < numZones; zone++ {
+		for ordinal := 0; ordinal < numInstances; ordinal++ {
+			tokens := spreadMinimizingTokens(ordinal, zone, numZones, numTokens)
+			require.Len(t, tokens, numTokens)
+			require.True(t, sort.IsSorted(tokens))
+
+			// The tokens are deterministic.
+			require.Equal(t, tokens, spreadMinimizingTokens(ordinal, zone, numZones, numTokens))
+
+			tokensByZone[zone] = append(tokensByZone[zone], tokens)
+		}
+	}
+
+	// The tokens are unique across all the instances of all the zones.
+	unique := map[uint32]struct{}{}
+	for _, zoneTokens := range tokensByZone {
+		for _, tokens := range zoneTokens {
+			for _, token := range tokens {
+				unique[token] = struct{}{}
+			}
+		}
+	}
+	assert.Len(t, unique, numZones*numInstances*numTokens)
+
+	// The ownership spread within each zone is small, whatever the number of instances.
+	for zone := 0; zone < numZones; zone++ {
+		for n := 1; n <= numInstances; n++ {
+			ownership := tokensOwnership(tokensByZone[zone][:n])
+
+			minOwnership, maxOwnership := ownership[0], ownership[0]
+			for _, o := range ownership {
+				minOwnership = math.Min(minOwnership, o)
+				maxOwnership = math.Max(maxOwnership, o)
+			}
+			assert.Less(t, (maxOwnership-minOwnership)/maxOwnership, 0.1, "zone: %d instances: %d", zone, n)
+		}
+	}
+}
+
+// tokensOwnership returns the fraction of the ring owned by each instance.
+func tokensOwnership(instancesTokens []ring.Tokens) []float64 {
+	type instanceToken struct {
+		token    uint32
+		instance int
+	}
+
+	var all []instanceToken
+	for instance, tokens := range instancesTokens {
+		for _, token := range tokens {
+			all = append(all, instanceToken{token: token, instance: instance})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].token < all[j].token })
+
+	ownership := make([]float64, len(instancesTokens))
+	for i, t := range all {
+		var prev int64
+		if i == 0 {
+			prev = int64(all[len(all)-1].token) - math.MaxUint32 - 1
+		} else {
+			prev = int64(all[i-1].token)
+		}
+		ownership[t.instance] += float64(int64(t.token)-prev) / (math.MaxUint32 + 1)
+	}
+	return ownership
+}
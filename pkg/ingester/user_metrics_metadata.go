@@ -100,6 +100,19 @@ func (mm *userMetricsMetadata) toClientMetadata() []*mimirpb.MetricMetadata {
 	return r
 }
 
+// toMetadata returns the metadata of the tenant.
+func (mm *userMetricsMetadata) toMetadata() []mimirpb.MetricMetadata {
+	mm.mtx.RLock()
+	defer mm.mtx.RUnlock()
+	r := make([]mimirpb.MetricMetadata, 0, len(mm.metricToMetadata))
+	for _, set := range mm.metricToMetadata {
+		for m := range set {
+			r = append(r, m)
+		}
+	}
+	return r
+}
+
 type metricMetadataSet map[mimirpb.MetricMetadata]time.Time
 
 // If deadline is zero time, all metrics are purged.
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/grafana/dskit/ring"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
@@ -58,8 +59,12 @@ type userTSDB struct {
 	userID         string
 	activeSeries   *activeseries.ActiveSeries
 	seriesInMetric *metricCounter
+	seriesInScope  *scopeCounter
 	limiter        *Limiter
 
+	// Duration of the blocks compacted from the head, set when the TSDB is opened.
+	blockDuration time.Duration
+
 	instanceSeriesCount *atomic.Int64 // Shared across all userTSDB instances created by ingester.
 	instanceLimitsFn    func() *InstanceLimits
 
@@ -67,9 +72,41 @@ type userTSDB struct {
 	state          tsdbState
 	pushesInFlight sync.WaitGroup // Increased with stateMtx read lock held, only if state == active or activeShipping.
 
+	// Read locked by the pushes for the whole time they append, and write locked by the memory snapshots, because the
+	// samples appended while a snapshot walks the series would be lost when replaying the WAL from the snapshot.
+	appendMtx sync.RWMutex
+
 	// Used to detect idle TSDBs.
 	lastUpdate atomic.Int64
 
+	// Whether all the blocks shipped to the storage are deleted at the next reload of the TSDB blocks, regardless of
+	// the retention, because the ingester is under disk pressure.
+	deleteShippedBlocks atomic.Bool
+
+	// Number of new series admitted in spite of the per-user series limit, because the limit is in warn-only mode,
+	// and not yet accounted in the metrics.
+	warnOnlySeriesAdmitted atomic.Int64
+
+	// Unix timestamp of the last warning logged because the per-user series limit is exceeded in warn-only mode.
+	lastWarnOnlySeriesLimitLog atomic.Int64
+
+	// Number of in-memory series owned by the ingester, being the ingester the primary replica of the series in the
+	// ring. It's periodically recomputed, and updated in the meanwhile on series creation and deletion. It's tracked
+	// only if isOwnedSeries is set.
+	ownedSeries         atomic.Int64
+	ownedSeriesComputed atomic.Bool
+	isOwnedSeries       func(series labels.Labels) bool
+
+	// Subring of the tenant the owned series are looked up in, recomputed on each recount of the owned series.
+	ownedSeriesSubringMtx sync.RWMutex
+	ownedSeriesSubring    ring.ReadRing
+
+	// Whether the per-user series limit is enforced on the owned series, once computed.
+	useOwnedSeriesForLimits bool
+
+	// Lateness of the samples rejected for being too old, used to suggest the out-of-order time window.
+	rejectedSamplesLateness *latenessTracker
+
 	// Thanos shipper used to upload blocks to the storage.
 	shipper BlocksUploader
 
@@ -174,6 +211,29 @@ func (u *userTSDB) compactHead(blockDuration int64) error {
 	return u.db.CompactHead(tsdb.NewRangeHead(h, minTime, maxTime))
 }
 
+// compactHeadBlocksUntil compacts the block aligned time ranges of the Head block ending before the until
+// timestamp, inclusive, and before the oldest timestamp the in-order samples can still be appended at. Unlike
+// compactHead, the pushes aren't rejected during the compaction, because like in the regular compaction the
+// compacted samples can't be appended anymore. It returns whether the Head has been compacted.
+func (u *userTSDB) compactHeadBlocksUntil(blockDuration, until int64) (bool, error) {
+	h := u.Head()
+	until = util_math.Min64(until, h.MaxTime()-blockDuration/2)
+
+	compacted := false
+	for minTime := h.MinTime(); minTime <= until; minTime = h.MinTime() {
+		// Block max time is exclusive, so we do a -1 here.
+		blockMaxTime := ((minTime/blockDuration)+1)*blockDuration - 1
+		if blockMaxTime > until {
+			break
+		}
+		if err := u.db.CompactHead(tsdb.NewRangeHead(h, minTime, blockMaxTime)); err != nil {
+			return compacted, err
+		}
+		compacted = true
+	}
+	return compacted, nil
+}
+
 // PreCreation implements SeriesLifecycleCallback interface.
 func (u *userTSDB) PreCreation(metric labels.Labels) error {
 	if u.limiter == nil {
@@ -188,9 +248,13 @@ func (u *userTSDB) PreCreation(metric labels.Labels) error {
 		}
 	}
 
-	// Total series limit.
-	if err := u.limiter.AssertMaxSeriesPerUser(u.userID, int(u.Head().NumSeries())); err != nil {
-		return err
+	// Total series limit. The series admitted in warn-only mode are only counted once they pass the other limits too.
+	warnOnlyAdmitted := false
+	if err := u.assertMaxSeriesPerUser(); err != nil {
+		if !u.limiter.AdmitSeriesExceedingMaxSeriesPerUser(u.userID) {
+			return err
+		}
+		warnOnlyAdmitted = true
 	}
 
 	// Series per metric name limit.
@@ -202,19 +266,47 @@ func (u *userTSDB) PreCreation(metric labels.Labels) error {
 		return err
 	}
 
+	// Series per scope limit.
+	if scope := u.seriesInScope.scopeOf(u.userID, metric); scope != "" {
+		if err := u.seriesInScope.canAddSeriesFor(u.userID, scope); err != nil {
+			return err
+		}
+	}
+
+	if warnOnlyAdmitted {
+		u.warnOnlySeriesAdmitted.Inc()
+	}
 	return nil
 }
 
+// assertMaxSeriesPerUser checks the per-user series limit against the owned series, if enabled and already computed,
+// or against the in-memory series otherwise.
+func (u *userTSDB) assertMaxSeriesPerUser() error {
+	if u.useOwnedSeriesForLimits && u.isOwnedSeries != nil && u.ownedSeriesComputed.Load() {
+		return u.limiter.AssertMaxOwnedSeriesPerUser(u.userID, int(u.ownedSeries.Load()))
+	}
+
+	return u.limiter.AssertMaxSeriesPerUser(u.userID, int(u.Head().NumSeries()))
+}
+
 // PostCreation implements SeriesLifecycleCallback interface.
 func (u *userTSDB) PostCreation(metric labels.Labels) {
 	u.instanceSeriesCount.Inc()
 
+	if u.isOwnedSeries != nil && u.isOwnedSeries(metric) {
+		u.ownedSeries.Inc()
+	}
+
 	metricName, err := extract.MetricNameFromLabels(metric)
 	if err != nil {
 		// This should never happen because it has already been checked in PreCreation().
 		return
 	}
 	u.seriesInMetric.increaseSeriesForMetric(metricName)
+
+	if scope := u.seriesInScope.scopeOf(u.userID, metric); scope != "" {
+		u.seriesInScope.increaseSeriesForScope(scope)
+	}
 }
 
 // PostDeletion implements SeriesLifecycleCallback interface.
@@ -222,13 +314,31 @@ func (u *userTSDB) PostDeletion(metrics ...labels.Labels) {
 	u.instanceSeriesCount.Sub(int64(len(metrics)))
 
 	for _, metric := range metrics {
+		if u.isOwnedSeries != nil && u.isOwnedSeries(metric) {
+			u.ownedSeries.Dec()
+		}
+
 		metricName, err := extract.MetricNameFromLabels(metric)
 		if err != nil {
 			// This should never happen because it has already been checked in PreCreation().
 			continue
 		}
 		u.seriesInMetric.decreaseSeriesForMetric(metricName)
+
+		if scope := u.seriesInScope.scopeOf(u.userID, metric); scope != "" {
+			u.seriesInScope.decreaseSeriesForScope(scope)
+		}
+	}
+}
+
+// shouldLogWarnOnlySeriesLimit returns whether the warning about the per-user series limit exceeded in warn-only
+// mode should be logged, allowing at most one warning per interval.
+func (u *userTSDB) shouldLogWarnOnlySeriesLimit(now time.Time, interval time.Duration) bool {
+	last := u.lastWarnOnlySeriesLimitLog.Load()
+	if now.Sub(time.Unix(last, 0)) < interval {
+		return false
 	}
+	return u.lastWarnOnlySeriesLimitLog.CAS(last, now.Unix())
 }
 
 // blocksToDelete filters the input blocks and returns the blocks which are safe to be deleted from the ingester.
@@ -242,6 +352,14 @@ func (u *userTSDB) blocksToDelete(blocks []*tsdb.Block) map[ulid.ULID]struct{} {
 	}
 
 	shippedBlocks := u.getCachedShippedBlocks()
+	if u.deleteShippedBlocks.Load() {
+		if deletable == nil {
+			deletable = make(map[ulid.ULID]struct{}, len(blocks))
+		}
+		for _, b := range blocks {
+			deletable[b.Meta().ULID] = struct{}{}
+		}
+	}
 
 	result := map[ulid.ULID]struct{}{}
 	for shippedID := range shippedBlocks {
@@ -329,19 +447,28 @@ func (u *userTSDB) shouldCloseTSDB(idleTimeout time.Duration) tsdbCloseCheckResu
 }
 
 func (u *userTSDB) acquireAppendLock() error {
+	// Wait for the memory snapshot in progress, if any, before checking the state, so that the state
+	// transitions aren't blocked by the pushes waiting for the snapshot.
+	u.appendMtx.RLock()
+
 	u.stateMtx.RLock()
 	defer u.stateMtx.RUnlock()
 
+	var err error
 	switch u.state {
 	case active:
 	case activeShipping:
 		// Pushes are allowed.
 	case forceCompacting:
-		return errors.New("forced compaction in progress")
+		err = errors.New("forced compaction in progress")
 	case closing:
-		return errors.New("TSDB is closing")
+		err = errors.New("TSDB is closing")
 	default:
-		return errors.New("TSDB is not active")
+		err = errors.New("TSDB is not active")
+	}
+	if err != nil {
+		u.appendMtx.RUnlock()
+		return err
 	}
 
 	u.pushesInFlight.Add(1)
@@ -350,4 +477,15 @@ func (u *userTSDB) acquireAppendLock() error {
 
 func (u *userTSDB) releaseAppendLock() {
 	u.pushesInFlight.Done()
+	u.appendMtx.RUnlock()
+}
+
+// chunkSnapshot snapshots the in-memory data of the Head on disk. The pushes are blocked until the snapshot
+// completes.
+func (u *userTSDB) chunkSnapshot() error {
+	u.appendMtx.Lock()
+	defer u.appendMtx.Unlock()
+
+	_, err := u.Head().ChunkSnapshot()
+	return err
 }
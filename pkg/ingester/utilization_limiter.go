@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	runtimemetrics "runtime/metrics"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/procfs"
+	"github.com/prometheus/prometheus/model/labels"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	util_math "github.com/grafana/mimir/pkg/util/math"
+)
+
+const (
+	readPathCPUUtilizationLimitFlag       = "ingester.read-path-cpu-utilization-limit"
+	readPathMemoryUtilizationLimitFlag    = "ingester.read-path-memory-utilization-limit"
+	readPathExpensiveRequestMinSeriesFlag = "ingester.read-path-expensive-request-min-estimated-series"
+
+	// The utilization is scanned every second, and the CPU utilization is a moving average over about a minute.
+	utilizationScanInterval = time.Second
+	cpuUtilizationEWMAAlpha = 2. / (60. + 1.)
+
+	heapInUseMetric = "/memory/classes/heap/objects:bytes"
+
+	utilizationReasonCPU    = "cpu"
+	utilizationReasonMemory = "memory"
+)
+
+var (
+	// We don't include values in the message to avoid leaking Mimir cluster configuration to users. The request is
+	// rejected with the ResourceExhausted gRPC status, so that the clients can tell it apart from the failures.
+	errReadPathUtilizationLimitReached = status.Error(codes.ResourceExhausted, globalerror.IngesterReadPathUtilizationLimit.MessageWithPerInstanceLimitConfig("the read request has been rejected because the ingester exceeded the CPU or memory utilization limit of the read path, and the request is estimated expensive", readPathCPUUtilizationLimitFlag, readPathMemoryUtilizationLimitFlag, readPathExpensiveRequestMinSeriesFlag))
+)
+
+// utilizationScanner scans the resources utilization of the process.
+type utilizationScanner interface {
+	// Scan returns the CPU seconds spent by the process and the bytes of its in-use heap.
+	Scan() (cpuSeconds float64, heapBytes uint64, err error)
+}
+
+// procfsUtilizationScanner scans the CPU utilization from procfs, and the heap utilization from the Go runtime.
+type procfsUtilizationScanner struct{}
+
+func (procfsUtilizationScanner) Scan() (float64, uint64, error) {
+	proc, err := procfs.Self()
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to read the process")
+	}
+	stat, err := proc.Stat()
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to read the process stats")
+	}
+
+	samples := []runtimemetrics.Sample{{Name: heapInUseMetric}}
+	runtimemetrics.Read(samples)
+	if samples[0].Value.Kind() != runtimemetrics.KindUint64 {
+		return 0, 0, errors.Errorf("unsupported runtime metric %s", heapInUseMetric)
+	}
+
+	return stat.CPUTime(), samples[0].Value.Uint64(), nil
+}
+
+// utilizationBasedLimiter rejects the expensive read requests while the CPU or memory utilization of the
+// ingester is above the configured limits, to protect the write path during query storms.
+type utilizationBasedLimiter struct {
+	cpuLimit    float64
+	memoryLimit uint64
+
+	scanner utilizationScanner
+	logger  log.Logger
+
+	// CPU seconds spent by the process at the last scan, and its moving average rate in CPU milliseconds per second.
+	lastCPUSeconds float64
+	cpuRate        *util_math.EwmaRate
+	scanned        bool
+
+	// The reason why the expensive read requests are rejected, or empty if they're not.
+	limitingReason atomic.String
+
+	rejectedRequests *prometheus.CounterVec
+}
+
+func newUtilizationBasedLimiter(cpuLimit float64, memoryLimit uint64, scanner utilizationScanner, logger log.Logger, reg prometheus.Registerer) *utilizationBasedLimiter {
+	return &utilizationBasedLimiter{
+		cpuLimit:    cpuLimit,
+		memoryLimit: memoryLimit,
+		scanner:     scanner,
+		logger:      logger,
+		cpuRate:     util_math.NewEWMARate(cpuUtilizationEWMAAlpha, utilizationScanInterval),
+		rejectedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_utilization_limiter_rejected_requests_total",
+			Help: "Total number of read requests rejected because the ingester exceeded the CPU or memory utilization limit of the read path.",
+		}, []string{"reason"}),
+	}
+}
+
+// update scans the current utilization, and updates the limiting reason. It must be called every utilizationScanInterval.
+func (l *utilizationBasedLimiter) update() {
+	cpuSeconds, heapBytes, err := l.scanner.Scan()
+	if err != nil {
+		level.Warn(l.logger).Log("msg", "failed to scan the resources utilization, not limiting the read requests", "err", err)
+		l.limitingReason.Store("")
+		return
+	}
+
+	// The CPU utilization is computed since the previous scan.
+	if l.scanned {
+		l.cpuRate.Add(int64((cpuSeconds - l.lastCPUSeconds) * 1000))
+		l.cpuRate.Tick()
+	}
+	l.lastCPUSeconds = cpuSeconds
+	l.scanned = true
+
+	reason := ""
+	if l.memoryLimit > 0 && heapBytes >= l.memoryLimit {
+		reason = utilizationReasonMemory
+	} else if cpuUtilization := l.cpuRate.Rate() / 1000; l.cpuLimit > 0 && cpuUtilization >= l.cpuLimit {
+		reason = utilizationReasonCPU
+	}
+
+	// The limiting reason is only updated by this function, which isn't called concurrently.
+	if previous := l.limitingReason.Load(); previous != reason {
+		l.limitingReason.Store(reason)
+
+		if reason == "" {
+			level.Info(l.logger).Log("msg", "the resources utilization is back below the read path limits, not rejecting the expensive read requests anymore")
+		} else {
+			level.Warn(l.logger).Log("msg", "the resources utilization exceeded the read path limit, rejecting the expensive read requests", "reason", reason, "cpu_utilization", l.cpuRate.Rate()/1000, "heap_bytes", heapBytes)
+		}
+	}
+}
+
+// checkReadRequest returns errReadPathUtilizationLimitReached if the ingester is above the utilization limits and
+// the request is estimated expensive.
+func (l *utilizationBasedLimiter) checkReadRequest(isExpensive func() bool) error {
+	reason := l.limitingReason.Load()
+	if reason == "" || !isExpensive() {
+		return nil
+	}
+
+	l.rejectedRequests.WithLabelValues(reason).Inc()
+	return errReadPathUtilizationLimitReached
+}
+
+// checkReadRequest returns an error if the ingester is above the read path utilization limits, and the series selected
+// by any of the matchers sets are estimated to be at least the configured minimum series of an expensive request.
+func (i *Ingester) checkReadRequest(db *userTSDB, matchersSets ...[]*labels.Matcher) error {
+	if i.utilizationBasedLimiter == nil {
+		return nil
+	}
+
+	return i.utilizationBasedLimiter.checkReadRequest(func() bool {
+		estimated := 0
+		for _, matchers := range matchersSets {
+			estimated += estimateHeadSeries(db, matchers, i.cfg.ReadPathExpensiveRequestMinSeries-estimated)
+			if estimated >= i.cfg.ReadPathExpensiveRequestMinSeries {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// estimateHeadSeries estimates the number of the head series selected by the matchers, as the lowest number of series
+// matching any of the equal matchers, up to max series. The estimate is cheap because it doesn't intersect the postings.
+func estimateHeadSeries(db *userTSDB, matchers []*labels.Matcher, max int) int {
+	if max <= 0 {
+		return 0
+	}
+
+	head := db.Head()
+	estimated := int(head.NumSeries())
+	if estimated > max {
+		estimated = max
+	}
+
+	idx, err := head.Index()
+	if err != nil {
+		return estimated
+	}
+	defer idx.Close()
+
+	for _, m := range matchers {
+		if m.Type != labels.MatchEqual || m.Value == "" {
+			continue
+		}
+
+		postings, err := idx.Postings(m.Name, m.Value)
+		if err != nil {
+			continue
+		}
+
+		// Count up to the current estimate only, since a higher count can't lower it.
+		count := 0
+		for count < estimated && postings.Next() {
+			count++
+		}
+		estimated = count
+	}
+
+	return estimated
+}
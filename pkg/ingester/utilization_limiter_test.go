@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+type utilizationScannerMock struct {
+	cpuSeconds float64
+	heapBytes  uint64
+	err        error
+}
+
+func (s *utilizationScannerMock) Scan() (float64, uint64, error) {
+	return s.cpuSeconds, s.heapBytes, s.err
+}
+
+func TestUtilizationBasedLimiter(t *testing.T) {
+	newLimiter := func(scanner utilizationScanner) (*utilizationBasedLimiter, *prometheus.Registry) {
+		reg := prometheus.NewPedanticRegistry()
+		return newUtilizationBasedLimiter(2, 1000, scanner, log.NewNopLogger(), reg), reg
+	}
+	isExpensive := func() bool { return true }
+	isCheap := func() bool { return false }
+
+	t.Run("should reject the expensive requests above the memory utilization limit", func(t *testing.T) {
+		scanner := &utilizationScannerMock{heapBytes: 1000}
+		l, reg := newLimiter(scanner)
+		l.update()
+
+		assert.Equal(t, errReadPathUtilizationLimitReached, l.checkReadRequest(isExpensive))
+		assert.NoError(t, l.checkReadRequest(isCheap))
+
+		scanner.heapBytes = 999
+		l.update()
+		assert.NoError(t, l.checkReadRequest(isExpensive))
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_ingester_utilization_limiter_rejected_requests_total Total number of read requests rejected because the ingester exceeded the CPU or memory utilization limit of the read path.
+			# TYPE cortex_ingester_utilization_limiter_rejected_requests_total counter
+			cortex_ingester_utilization_limiter_rejected_requests_total{reason="memory"} 1
+		`)))
+	})
+
+	t.Run("should reject the expensive requests above the CPU utilization limit", func(t *testing.T) {
+		scanner := &utilizationScannerMock{cpuSeconds: 100}
+		l, _ := newLimiter(scanner)
+
+		// The CPU utilization isn't known until the second scan.
+		l.update()
+		assert.NoError(t, l.checkReadRequest(isExpensive))
+
+		// 3 CPU seconds spent in the last second.
+		scanner.cpuSeconds += 3
+		l.update()
+		assert.Equal(t, errReadPathUtilizationLimitReached, l.checkReadRequest(isExpensive))
+
+		// The moving average goes below the limit once the CPU utilization is lower for a while.
+		for i := 0; i < 60; i++ {
+			scanner.cpuSeconds += 0.5
+			l.update()
+		}
+		assert.NoError(t, l.checkReadRequest(isExpensive))
+	})
+
+	t.Run("should not reject the requests if the utilization can't be scanned", func(t *testing.T) {
+		scanner := &utilizationScannerMock{heapBytes: 1000}
+		l, _ := newLimiter(scanner)
+		l.update()
+		require.Equal(t, errReadPathUtilizationLimitReached, l.checkReadRequest(isExpensive))
+
+		scanner.err = assert.AnError
+		l.update()
+		assert.NoError(t, l.checkReadRequest(isExpensive))
+	})
+}
+
+func TestIngester_ReadPathUtilizationLimit(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.ReadPathExpensiveRequestMinSeries = 5
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	// Push 10 series of the metric "expensive", and 1 series of the metric "cheap".
+	for seriesID := 0; seriesID < 10; seriesID++ {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "expensive", "series_id", strconv.Itoa(seriesID)), 1, 1)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+	req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "cheap"), 1, 1)
+	_, err = i.Push(ctx, req)
+	require.NoError(t, err)
+
+	// Exceed the memory utilization limit.
+	i.utilizationBasedLimiter = newUtilizationBasedLimiter(0, 1, &utilizationScannerMock{heapBytes: 1}, log.NewNopLogger(), nil)
+	i.utilizationBasedLimiter.update()
+
+	metricsForLabelMatchers := func(metricName string) error {
+		_, err := i.MetricsForLabelMatchers(ctx, &client.MetricsForLabelMatchersRequest{
+			StartTimestampMs: 0,
+			EndTimestampMs:   10,
+			MatchersSet: []*client.LabelMatchers{{Matchers: []*client.LabelMatcher{
+				{Type: client.EQUAL, Name: labels.MetricName, Value: metricName},
+			}}},
+		})
+		return err
+	}
+
+	err = metricsForLabelMatchers("expensive")
+	assert.Equal(t, errReadPathUtilizationLimitReached, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NoError(t, metricsForLabelMatchers("cheap"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(i.utilizationBasedLimiter.rejectedRequests.WithLabelValues(utilizationReasonMemory)))
+
+	// The expensive requests are accepted once the utilization is back below the limit.
+	i.utilizationBasedLimiter.scanner = &utilizationScannerMock{}
+	i.utilizationBasedLimiter.update()
+	assert.NoError(t, metricsForLabelMatchers("expensive"))
+}
+
+func TestEstimateHeadSeries(t *testing.T) {
+	i, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	for seriesID := 0; seriesID < 10; seriesID++ {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "a", "series_id", strconv.Itoa(seriesID), "odd", strconv.FormatBool(seriesID%2 == 1)), 1, 1)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+	for seriesID := 0; seriesID < 5; seriesID++ {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "b", "series_id", strconv.Itoa(seriesID)), 1, 1)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+	db := i.getTSDB(userID)
+
+	tests := map[string]struct {
+		matchers []*labels.Matcher
+		max      int
+		expected int
+	}{
+		"no equal matchers": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "a|b")},
+			max:      100,
+			expected: 15,
+		},
+		"single equal matcher": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")},
+			max:      100,
+			expected: 10,
+		},
+		"the lowest of the equal matchers": {
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a"),
+				labels.MustNewMatcher(labels.MatchEqual, "odd", "true"),
+			},
+			max:      100,
+			expected: 5,
+		},
+		"up to max series": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")},
+			max:      3,
+			expected: 3,
+		},
+		"no matching series": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "c")},
+			max:      100,
+			expected: 0,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testData.expected, estimateHeadSeries(db, testData.matchers, testData.max))
+		})
+	}
+}
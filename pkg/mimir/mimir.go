@@ -28,8 +28,8 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/prometheus/promql"
 	prom_storage "github.com/prometheus/prometheus/storage"
+	v1 "github.com/prometheus/prometheus/web/api/v1"
 	"github.com/weaveworks/common/server"
 	"github.com/weaveworks/common/signals"
 	"go.opentelemetry.io/otel"
@@ -47,7 +47,9 @@ import (
 	"github.com/grafana/mimir/pkg/distributor"
 	"github.com/grafana/mimir/pkg/flusher"
 	"github.com/grafana/mimir/pkg/frontend"
+	"github.com/grafana/mimir/pkg/frontend/queryaudit"
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware"
+	"github.com/grafana/mimir/pkg/frontend/queryrecorder"
 	frontendv1 "github.com/grafana/mimir/pkg/frontend/v1"
 	"github.com/grafana/mimir/pkg/ingester"
 	"github.com/grafana/mimir/pkg/ingester/client"
@@ -206,6 +208,9 @@ func (c *Config) Validate(log log.Logger) error {
 	if err := c.validateFilesystemPaths(log); err != nil {
 		return err
 	}
+	if err := c.API.Validate(); err != nil {
+		return errors.Wrap(err, "invalid API config")
+	}
 	if err := c.RulerStorage.Validate(); err != nil {
 		return errors.Wrap(err, "invalid rulestore config")
 	}
@@ -221,6 +226,11 @@ func (c *Config) Validate(log log.Logger) error {
 	if err := c.Querier.Validate(); err != nil {
 		return errors.Wrap(err, "invalid querier config")
 	}
+	if c.isAnyModuleEnabled(All, Ingester, Write) {
+		if err := c.Ingester.IngesterRing.Validate(); err != nil {
+			return errors.Wrap(err, "invalid ingester ring config")
+		}
+	}
 	if err := c.IngesterClient.Validate(log); err != nil {
 		return errors.Wrap(err, "invalid ingester_client config")
 	}
@@ -230,6 +240,12 @@ func (c *Config) Validate(log log.Logger) error {
 	if err := c.Frontend.QueryMiddleware.Validate(); err != nil {
 		return errors.Wrap(err, "invalid query-frontend middleware config")
 	}
+	if err := c.Frontend.QueryRecorder.Validate(); err != nil {
+		return errors.Wrap(err, "invalid query-frontend query recorder config")
+	}
+	if err := c.QueryScheduler.Validate(); err != nil {
+		return errors.Wrap(err, "invalid query-scheduler config")
+	}
 	if err := c.StoreGateway.Validate(c.LimitsConfig); err != nil {
 		return errors.Wrap(err, "invalid store-gateway config")
 	}
@@ -631,8 +647,10 @@ type Mimir struct {
 	QuerierQueryable         prom_storage.SampleAndChunkQueryable
 	ExemplarQueryable        prom_storage.ExemplarQueryable
 	MetadataSupplier         querier.MetadataSupplier
-	QuerierEngine            *promql.Engine
+	QuerierEngine            v1.QueryEngine
 	QueryFrontendTripperware querymiddleware.Tripperware
+	QueryRecorder            queryrecorder.Recorder
+	QueryAuditLog            queryaudit.AuditLog
 	Ruler                    *ruler.Ruler
 	RulerStorage             rulestore.RuleStore
 	Alertmanager             *alertmanager.MultitenantAlertmanager
@@ -645,6 +663,15 @@ type Mimir struct {
 
 	// Queryables that the querier should use to query the long term storage.
 	StoreQueryables []querier.QueryableWithFilter
+
+	// Supplier of the metric metadata persisted in the long term storage.
+	StoreMetadataSupplier querier.MetadataSupplier
+
+	// Supplier of the statistics of the blocks in the long term storage.
+	StoreBlocksStatsSupplier querier.BlocksStatsSupplier
+
+	// Queryable of the exemplars persisted in the long term storage.
+	StoreExemplarQueryable prom_storage.ExemplarQueryable
 }
 
 // New makes a new Mimir.
@@ -817,7 +844,16 @@ func (t *Mimir) readyHandler(sm *services.Manager) http.HandlerFunc {
 			var serviceNamesStates []string
 			for name, s := range t.ServiceMap {
 				if s.State() != services.Running {
-					serviceNamesStates = append(serviceNamesStates, fmt.Sprintf("%s: %s", name, s.State()))
+					state := fmt.Sprintf("%s: %s", name, s.State())
+
+					// Store-gateway reports the progress of the blocks loading while starting.
+					if name == StoreGateway && t.StoreGateway != nil {
+						if progress := t.StoreGateway.StartupProgress(); progress != "" {
+							state += " (" + progress + ")"
+						}
+					}
+
+					serviceNamesStates = append(serviceNamesStates, state)
 				}
 			}
 
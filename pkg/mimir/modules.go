@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/rules"
 	prom_storage "github.com/prometheus/prometheus/storage"
 	prom_remote "github.com/prometheus/prometheus/storage/remote"
@@ -39,7 +40,9 @@ import (
 	"github.com/grafana/mimir/pkg/distributor"
 	"github.com/grafana/mimir/pkg/flusher"
 	"github.com/grafana/mimir/pkg/frontend"
+	"github.com/grafana/mimir/pkg/frontend/queryaudit"
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware"
+	"github.com/grafana/mimir/pkg/frontend/queryrecorder"
 	"github.com/grafana/mimir/pkg/frontend/transport"
 	"github.com/grafana/mimir/pkg/ingester"
 	"github.com/grafana/mimir/pkg/querier"
@@ -50,6 +53,7 @@ import (
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storegateway"
+	"github.com/grafana/mimir/pkg/streamingpromql"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
@@ -78,6 +82,8 @@ const (
 	StoreQueryable           string = "store-queryable"
 	QueryFrontend            string = "query-frontend"
 	QueryFrontendTripperware string = "query-frontend-tripperware"
+	QueryRecorder            string = "query-recorder"
+	QueryAuditLog            string = "query-audit-log"
 	RulerStorage             string = "ruler-storage"
 	Ruler                    string = "ruler"
 	AlertManager             string = "alertmanager"
@@ -105,7 +111,7 @@ func newDefaultConfig() *Config {
 func (t *Mimir) initAPI() (services.Service, error) {
 	t.Cfg.API.ServerPrefix = t.Cfg.Server.PathPrefix
 
-	a, err := api.New(t.Cfg.API, t.Cfg.Server, t.Server, util_log.Logger)
+	a, err := api.New(t.Cfg.API, t.Cfg.Server, t.Server, util_log.Logger, t.Registerer)
 	if err != nil {
 		return nil, err
 	}
@@ -293,7 +299,7 @@ func (t *Mimir) initDistributorService() (serv services.Service, err error) {
 }
 
 func (t *Mimir) initDistributor() (serv services.Service, err error) {
-	t.API.RegisterDistributor(t.Distributor, t.Cfg.Distributor)
+	t.API.RegisterDistributor(t.Distributor, t.Cfg.Distributor, t.Overrides)
 
 	return nil, nil
 }
@@ -302,12 +308,31 @@ func (t *Mimir) initDistributor() (serv services.Service, err error) {
 // Mimir. It also registers the API endpoints associated with those two services.
 func (t *Mimir) initQueryable() (serv services.Service, err error) {
 	querierRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "querier"}, t.Registerer)
+	engine.RegisterExperimentalFunctions()
 
 	// Create a querier queryable and PromQL engine
-	t.QuerierQueryable, t.ExemplarQueryable, t.QuerierEngine = querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, querierRegisterer, util_log.Logger, t.ActivityTracker)
+	var standardEngine *promql.Engine
+	t.QuerierQueryable, t.ExemplarQueryable, standardEngine = querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, querierRegisterer, util_log.Logger, t.ActivityTracker)
+
+	// The queries of the tenants which enabled the streaming PromQL engine are run by it, when supported.
+	streamingEngine := streamingpromql.NewEngine(engine.NewPromQLEngineOptions(t.Cfg.Querier.EngineConfig, t.ActivityTracker, util_log.Logger, nil))
+	// The queries using experimental PromQL functions only run for the tenants which enabled them.
+	t.QuerierEngine = querier.NewExperimentalFunctionsEngine(
+		streamingpromql.NewEngineWithFallback(standardEngine, streamingEngine, t.Overrides, querierRegisterer, util_log.Logger),
+		t.Overrides,
+	)
 
-	// Use the distributor to return metric metadata by default
+	// Use the distributor to return metric metadata by default, merged with
+	// the metadata persisted in the long term storage, if any.
 	t.MetadataSupplier = t.Distributor
+	if t.StoreMetadataSupplier != nil {
+		t.MetadataSupplier = querier.NewMergedMetadataSupplier(t.Distributor, t.StoreMetadataSupplier)
+	}
+
+	// Merge the exemplars in the ingesters with the exemplars persisted in the long term storage, if any.
+	if t.StoreExemplarQueryable != nil {
+		t.ExemplarQueryable = querier.NewMergedExemplarQueryable(t.ExemplarQueryable, t.StoreExemplarQueryable)
+	}
 
 	// Register the default endpoints that are always enabled for the querier module
 	t.API.RegisterQueryable(t.QuerierQueryable, t.Distributor)
@@ -322,9 +347,10 @@ func (t *Mimir) initTenantFederation() (serv services.Service, err error) {
 		// single tenant. This allows for a less impactful enabling of tenant
 		// federation.
 		const bypassForSingleQuerier = true
-		t.QuerierQueryable = querier.NewSampleAndChunkQueryable(tenantfederation.NewQueryable(t.QuerierQueryable, bypassForSingleQuerier, util_log.Logger))
-		t.ExemplarQueryable = tenantfederation.NewExemplarQueryable(t.ExemplarQueryable, bypassForSingleQuerier, util_log.Logger)
-		t.MetadataSupplier = tenantfederation.NewMetadataSupplier(t.MetadataSupplier, util_log.Logger)
+		maxTenants := t.Cfg.TenantFederation.MaxTenants
+		t.QuerierQueryable = querier.NewSampleAndChunkQueryable(tenantfederation.NewQueryable(t.QuerierQueryable, bypassForSingleQuerier, maxTenants, util_log.Logger))
+		t.ExemplarQueryable = tenantfederation.NewExemplarQueryable(t.ExemplarQueryable, bypassForSingleQuerier, maxTenants, util_log.Logger)
+		t.MetadataSupplier = tenantfederation.NewMetadataSupplier(t.MetadataSupplier, maxTenants, util_log.Logger)
 	}
 	return nil, nil
 }
@@ -389,6 +415,7 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 		t.MetadataSupplier,
 		t.QuerierEngine,
 		t.Distributor,
+		t.StoreBlocksStatsSupplier,
 		t.Registerer,
 		util_log.Logger,
 		t.Overrides,
@@ -431,6 +458,7 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 	}
 
 	t.Cfg.Worker.MaxConcurrentRequests = t.Cfg.Querier.EngineConfig.MaxConcurrent
+	t.Cfg.Worker.MaxConcurrentRequestsFn = querierMaxConcurrentRequests(t.RuntimeConfig)
 	return querier_worker.NewQuerierWorker(t.Cfg.Worker, httpgrpc_server.NewServer(internalQuerierRouter), util_log.Logger, t.Registerer)
 }
 
@@ -442,6 +470,9 @@ func (t *Mimir) initStoreQueryables() (services.Service, error) {
 		return nil, fmt.Errorf("failed to initialize querier: %v", err)
 	} else {
 		t.StoreQueryables = append(t.StoreQueryables, querier.UseAlwaysQueryable(q))
+		t.StoreMetadataSupplier = q
+		t.StoreBlocksStatsSupplier = q
+		t.StoreExemplarQueryable = q
 		servs = append(servs, q)
 	}
 
@@ -509,9 +540,25 @@ func (t *Mimir) initFlusher() (serv services.Service, err error) {
 // to optimize Prometheus query requests.
 func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error) {
 	promqlEngineRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "query-frontend"}, t.Registerer)
+	engine.RegisterExperimentalFunctions()
+
+	// The query explain endpoint explains which of the ingesters and the store-gateways the queriers read from.
+	queryMiddlewareCfg := t.Cfg.Frontend.QueryMiddleware
+	queryMiddlewareCfg.QueryIngestersWithin = t.Cfg.Querier.QueryIngestersWithin
+	queryMiddlewareCfg.QueryStoreAfter = t.Cfg.Querier.QueryStoreAfter
+
+	// The query explain endpoint also explains which blocks the store-gateways are queried for, if enabled.
+	var blocksFinder *querier.BucketIndexBlocksFinder
+	if queryMiddlewareCfg.QueryExplainBlocksEnabled {
+		blocksFinder, err = querier.NewBucketIndexBlocksFinderFromConfig(t.Cfg.BlocksStorage, t.Overrides, "query-frontend", util_log.Logger, t.Registerer)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the blocks finder of the query explain endpoint")
+		}
+		queryMiddlewareCfg.BlocksFinder = blocksFinder
+	}
 
 	tripperware, err := querymiddleware.NewTripperware(
-		t.Cfg.Frontend.QueryMiddleware,
+		queryMiddlewareCfg,
 		util_log.Logger,
 		t.Overrides,
 		querymiddleware.PrometheusCodec,
@@ -524,9 +571,28 @@ func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error
 	}
 
 	t.QueryFrontendTripperware = tripperware
+	if blocksFinder != nil {
+		return blocksFinder, nil
+	}
 	return nil, nil
 }
 
+func (t *Mimir) initQueryRecorder() (serv services.Service, err error) {
+	t.QueryRecorder, err = queryrecorder.NewRecorder(t.Cfg.Frontend.QueryRecorder, t.Registerer, util_log.Logger)
+	if err != nil || t.QueryRecorder == nil {
+		return nil, err
+	}
+	return t.QueryRecorder, nil
+}
+
+func (t *Mimir) initQueryAuditLog() (serv services.Service, err error) {
+	t.QueryAuditLog = queryaudit.NewAuditLog(t.Cfg.Frontend.QueryAuditLog, t.Registerer, util_log.Logger)
+	if t.QueryAuditLog == nil {
+		return nil, nil
+	}
+	return t.QueryAuditLog, nil
+}
+
 func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 	roundTripper, frontendV1, frontendV2, err := frontend.InitFrontend(t.Cfg.Frontend, t.Overrides, t.Cfg.Server.GRPCListenPort, util_log.Logger, t.Registerer)
 	if err != nil {
@@ -536,7 +602,7 @@ func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 	// Wrap roundtripper into Tripperware.
 	roundTripper = t.QueryFrontendTripperware(roundTripper)
 
-	handler := transport.NewHandler(t.Cfg.Frontend.Handler, roundTripper, util_log.Logger, t.Registerer)
+	handler := transport.NewHandler(t.Cfg.Frontend.Handler, roundTripper, t.Overrides, t.QueryRecorder, t.QueryAuditLog, util_log.Logger, t.Registerer)
 	t.API.RegisterQueryFrontendHandler(handler, t.BuildInfoHandler)
 
 	if frontendV1 != nil {
@@ -571,6 +637,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 	}
 
 	t.Cfg.Ruler.Ring.ListenPort = t.Cfg.Server.GRPCListenPort
+	engine.RegisterExperimentalFunctions()
 
 	var embeddedQueryable prom_storage.Queryable
 	var queryFunc rules.QueryFunc
@@ -580,7 +647,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 		if err != nil {
 			return nil, err
 		}
-		remoteQuerier := ruler.NewRemoteQuerier(queryFrontendClient, t.Cfg.Querier.EngineConfig.Timeout, t.Cfg.API.PrometheusHTTPPrefix, util_log.Logger, ruler.WithOrgIDMiddleware)
+		remoteQuerier := ruler.NewRemoteQuerier(queryFrontendClient, t.Cfg.Querier.EngineConfig.Timeout, t.Cfg.API.PrometheusHTTPPrefix, util_log.Logger, ruler.WithOrgIDMiddleware, ruler.WithQuerySourceKeyMiddleware(t.Cfg.Ruler.QueryFrontend.QuerySourceKey.String()))
 
 		embeddedQueryable = prom_remote.NewSampleAndChunkQueryableClient(
 			remoteQuerier,
@@ -609,25 +676,29 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 			// This makes this label more consistent and hopefully less confusing to users.
 			const bypassForSingleQuerier = false
 
-			federatedQueryable = tenantfederation.NewQueryable(queryable, bypassForSingleQuerier, util_log.Logger)
+			// The source tenants of the federated rule groups are not limited by -tenant-federation.max-tenants.
+			federatedQueryable = tenantfederation.NewQueryable(queryable, bypassForSingleQuerier, 0, util_log.Logger)
 
-			regularQueryFunc := rules.EngineQueryFunc(eng, queryable)
-			federatedQueryFunc := rules.EngineQueryFunc(eng, federatedQueryable)
+			// The queries using experimental PromQL functions only run for the tenants which enabled them.
+			regularQueryFunc := ruler.ExperimentalFunctionsQueryFunc(rules.EngineQueryFunc(eng, queryable), t.Overrides)
+			federatedQueryFunc := ruler.ExperimentalFunctionsQueryFunc(rules.EngineQueryFunc(eng, federatedQueryable), t.Overrides)
 
 			embeddedQueryable = federatedQueryable
 			queryFunc = ruler.TenantFederationQueryFunc(regularQueryFunc, federatedQueryFunc)
 
 		} else {
 			embeddedQueryable = queryable
-			queryFunc = rules.EngineQueryFunc(eng, queryable)
+			queryFunc = ruler.ExperimentalFunctionsQueryFunc(rules.EngineQueryFunc(eng, queryable), t.Overrides)
 		}
 	}
+	evaluationSpreads := ruler.NewGroupEvaluationSpreads()
 	managerFactory := ruler.DefaultTenantManagerFactory(
 		t.Cfg.Ruler,
 		t.Distributor,
 		embeddedQueryable,
 		queryFunc,
 		t.Overrides,
+		evaluationSpreads,
 		t.Registerer,
 	)
 
@@ -642,7 +713,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 	)
 
 	dnsResolver := dns.NewProvider(util_log.Logger, dnsProviderReg, dns.GolangResolverType)
-	manager, err := ruler.NewDefaultMultiTenantManager(t.Cfg.Ruler, managerFactory, t.Registerer, util_log.Logger, dnsResolver)
+	manager, err := ruler.NewDefaultMultiTenantManager(t.Cfg.Ruler, managerFactory, t.Overrides, t.Registerer, util_log.Logger, dnsResolver)
 	if err != nil {
 		return nil, err
 	}
@@ -654,6 +725,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 		util_log.Logger,
 		t.RulerStorage,
 		t.Overrides,
+		evaluationSpreads,
 	)
 	if err != nil {
 		return
@@ -701,6 +773,7 @@ func (t *Mimir) initCompactor() (serv services.Service, err error) {
 
 func (t *Mimir) initStoreGateway() (serv services.Service, err error) {
 	t.Cfg.StoreGateway.ShardingRing.ListenPort = t.Cfg.Server.GRPCListenPort
+	t.Cfg.StoreGateway.HTTPListenPort = t.Cfg.Server.HTTPListenPort
 
 	t.StoreGateway, err = storegateway.NewStoreGateway(t.Cfg.StoreGateway, t.Cfg.BlocksStorage, t.Overrides, t.Cfg.Server.LogLevel, util_log.Logger, t.Registerer, t.ActivityTracker)
 	if err != nil {
@@ -797,6 +870,8 @@ func (t *Mimir) setupModuleManager() error {
 	mm.RegisterModule(Querier, t.initQuerier)
 	mm.RegisterModule(StoreQueryable, t.initStoreQueryables, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontendTripperware, t.initQueryFrontendTripperware, modules.UserInvisibleModule)
+	mm.RegisterModule(QueryRecorder, t.initQueryRecorder, modules.UserInvisibleModule)
+	mm.RegisterModule(QueryAuditLog, t.initQueryAuditLog, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontend, t.initQueryFrontend)
 	mm.RegisterModule(RulerStorage, t.initRulerStorage, modules.UserInvisibleModule)
 	mm.RegisterModule(Ruler, t.initRuler)
@@ -829,7 +904,9 @@ func (t *Mimir) setupModuleManager() error {
 		Querier:                  {TenantFederation},
 		StoreQueryable:           {Overrides, MemberlistKV},
 		QueryFrontendTripperware: {API, Overrides},
-		QueryFrontend:            {QueryFrontendTripperware},
+		QueryRecorder:            {API},
+		QueryAuditLog:            {API},
+		QueryFrontend:            {QueryFrontendTripperware, QueryRecorder, QueryAuditLog},
 		QueryScheduler:           {API, Overrides},
 		Ruler:                    {DistributorService, StoreQueryable, RulerStorage},
 		RulerStorage:             {Overrides},
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	querier_worker "github.com/grafana/mimir/pkg/querier/worker"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -74,6 +75,17 @@ func TestLoadRuntimeConfig_MissingPointerFieldsAreNil(t *testing.T) {
 
 	// Ensure that when settings are omitted, the pointers are nil. See #4228
 	assert.Nil(t, actualCfg.IngesterLimits)
+	assert.Nil(t, actualCfg.QuerierLimits)
+}
+
+func TestLoadRuntimeConfig_ShouldLoadTheQuerierLimits(t *testing.T) {
+	yamlFile := strings.NewReader(`
+querier_limits:
+  max_concurrent: 4
+`)
+	actual, err := loadRuntimeConfig(yamlFile)
+	require.NoError(t, err)
+	assert.Equal(t, &runtimeConfigValues{QuerierLimits: &querier_worker.InstanceLimits{MaxConcurrent: 4}}, actual)
 }
 
 func TestLoadRuntimeConfig_ShouldReturnErrorOnMultipleDocumentsInTheConfig(t *testing.T) {
@@ -33,16 +33,19 @@ type WriteRequest_SourceEnum int32
 const (
 	API  WriteRequest_SourceEnum = 0
 	RULE WriteRequest_SourceEnum = 1
+	OTLP WriteRequest_SourceEnum = 2
 )
 
 var WriteRequest_SourceEnum_name = map[int32]string{
 	0: "API",
 	1: "RULE",
+	2: "OTLP",
 }
 
 var WriteRequest_SourceEnum_value = map[string]int32{
 	"API":  0,
 	"RULE": 1,
+	"OTLP": 2,
 }
 
 func (WriteRequest_SourceEnum) EnumDescriptor() ([]byte, []int) {
@@ -93,6 +96,11 @@ type WriteRequest struct {
 	Source                  WriteRequest_SourceEnum `protobuf:"varint,2,opt,name=Source,proto3,enum=cortexpb.WriteRequest_SourceEnum" json:"Source,omitempty"`
 	Metadata                []*MetricMetadata       `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty"`
 	SkipLabelNameValidation bool                    `protobuf:"varint,1000,opt,name=skip_label_name_validation,json=skipLabelNameValidation,proto3" json:"skip_label_name_validation,omitempty"`
+	// Minimum and maximum timestamp of the samples in the request, set by the distributor once the samples
+	// have been validated, to let the ingester skip per-sample bound checks. Only valid if timestamp_hints_set is true.
+	MinTimestampMs    int64 `protobuf:"varint,1001,opt,name=min_timestamp_ms,json=minTimestampMs,proto3" json:"min_timestamp_ms,omitempty"`
+	MaxTimestampMs    int64 `protobuf:"varint,1002,opt,name=max_timestamp_ms,json=maxTimestampMs,proto3" json:"max_timestamp_ms,omitempty"`
+	TimestampHintsSet bool  `protobuf:"varint,1003,opt,name=timestamp_hints_set,json=timestampHintsSet,proto3" json:"timestamp_hints_set,omitempty"`
 }
 
 func (m *WriteRequest) Reset()      { *m = WriteRequest{} }
@@ -148,6 +156,27 @@ func (m *WriteRequest) GetSkipLabelNameValidation() bool {
 	return false
 }
 
+func (m *WriteRequest) GetMinTimestampMs() int64 {
+	if m != nil {
+		return m.MinTimestampMs
+	}
+	return 0
+}
+
+func (m *WriteRequest) GetMaxTimestampMs() int64 {
+	if m != nil {
+		return m.MaxTimestampMs
+	}
+	return 0
+}
+
+func (m *WriteRequest) GetTimestampHintsSet() bool {
+	if m != nil {
+		return m.TimestampHintsSet
+	}
+	return false
+}
+
 type WriteResponse struct {
 }
 
@@ -511,51 +540,55 @@ func init() {
 func init() { proto.RegisterFile("mimir.proto", fileDescriptor_86d4d7485f544059) }
 
 var fileDescriptor_86d4d7485f544059 = []byte{
-	// 701 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xbd, 0x6e, 0xd3, 0x5e,
-	0x14, 0xf7, 0xcd, 0x77, 0x4e, 0xd2, 0xfc, 0xad, 0xfb, 0xaf, 0x84, 0xd5, 0xc1, 0x49, 0xcd, 0x92,
-	0x01, 0x52, 0x54, 0x04, 0x08, 0x04, 0x83, 0x83, 0xd2, 0x52, 0xb5, 0xf9, 0xd0, 0x8d, 0x43, 0x05,
-	0x4b, 0x74, 0x93, 0xde, 0xb6, 0x16, 0xbe, 0xb1, 0xb1, 0x9d, 0xaa, 0xd9, 0x98, 0x98, 0x99, 0x79,
-	0x02, 0x9e, 0x00, 0x89, 0x37, 0xe8, 0xd8, 0xb1, 0x62, 0xa8, 0xa8, 0xbb, 0x74, 0xec, 0x23, 0x20,
-	0x5f, 0x3b, 0x71, 0xab, 0x8a, 0xad, 0xdb, 0x3d, 0xe7, 0xf7, 0x71, 0x8e, 0xcf, 0x39, 0x32, 0x94,
-	0xb8, 0xc9, 0x4d, 0xb7, 0xe1, 0xb8, 0xb6, 0x6f, 0xe3, 0xc2, 0xd8, 0x76, 0x7d, 0x76, 0xec, 0x8c,
-	0x56, 0x1e, 0x1f, 0x98, 0xfe, 0xe1, 0x74, 0xd4, 0x18, 0xdb, 0x7c, 0xed, 0xc0, 0x3e, 0xb0, 0xd7,
-	0x04, 0x61, 0x34, 0xdd, 0x17, 0x91, 0x08, 0xc4, 0x2b, 0x12, 0x6a, 0x3f, 0x53, 0x50, 0xde, 0x75,
-	0x4d, 0x9f, 0x11, 0xf6, 0x79, 0xca, 0x3c, 0x1f, 0xf7, 0x00, 0x7c, 0x93, 0x33, 0x8f, 0xb9, 0x26,
-	0xf3, 0x14, 0x54, 0x4b, 0xd7, 0x4b, 0xeb, 0xcb, 0x8d, 0xb9, 0x7d, 0xc3, 0x30, 0x39, 0xeb, 0x0b,
-	0xac, 0xb9, 0x72, 0x72, 0x5e, 0x95, 0x7e, 0x9f, 0x57, 0x71, 0xcf, 0x65, 0xd4, 0xb2, 0xec, 0xb1,
-	0xb1, 0xd0, 0x91, 0x1b, 0x1e, 0xf8, 0x25, 0xe4, 0xfa, 0xf6, 0xd4, 0x1d, 0x33, 0x25, 0x55, 0x43,
-	0xf5, 0xca, 0xfa, 0x6a, 0xe2, 0x76, 0xb3, 0x72, 0x23, 0x22, 0xb5, 0x26, 0x53, 0x4e, 0x62, 0x01,
-	0x7e, 0x05, 0x05, 0xce, 0x7c, 0xba, 0x47, 0x7d, 0xaa, 0xa4, 0x45, 0x2b, 0x4a, 0x22, 0x6e, 0x33,
-	0xdf, 0x35, 0xc7, 0xed, 0x18, 0x6f, 0x66, 0x4e, 0xce, 0xab, 0x88, 0x2c, 0xf8, 0xf8, 0x35, 0xac,
-	0x78, 0x9f, 0x4c, 0x67, 0x68, 0xd1, 0x11, 0xb3, 0x86, 0x13, 0xca, 0xd9, 0xf0, 0x88, 0x5a, 0xe6,
-	0x1e, 0xf5, 0x4d, 0x7b, 0xa2, 0x5c, 0xe5, 0x6b, 0xa8, 0x5e, 0x20, 0x0f, 0x42, 0xca, 0x4e, 0xc8,
-	0xe8, 0x50, 0xce, 0xde, 0x2f, 0x70, 0xad, 0x0a, 0x90, 0xf4, 0x83, 0xf3, 0x90, 0xd6, 0x7b, 0x5b,
-	0xb2, 0x84, 0x0b, 0x90, 0x21, 0x83, 0x9d, 0x96, 0x8c, 0xb4, 0xff, 0x60, 0x29, 0xee, 0xde, 0x73,
-	0xec, 0x89, 0xc7, 0xb4, 0x5f, 0x08, 0x20, 0x99, 0x0e, 0xd6, 0x21, 0x27, 0x2a, 0xcf, 0x67, 0xf8,
-	0x7f, 0xd2, 0xb8, 0xa8, 0xd7, 0xa3, 0xa6, 0xdb, 0x5c, 0x8e, 0x47, 0x58, 0x16, 0x29, 0x7d, 0x8f,
-	0x3a, 0x3e, 0x73, 0x49, 0x2c, 0xc4, 0x4f, 0x20, 0xef, 0x51, 0xee, 0x58, 0xcc, 0x53, 0x52, 0xc2,
-	0x43, 0x4e, 0x3c, 0xfa, 0x02, 0x10, 0x1f, 0x2d, 0x91, 0x39, 0x0d, 0x3f, 0x87, 0x22, 0x3b, 0x66,
-	0xdc, 0xb1, 0xa8, 0xeb, 0xc5, 0x03, 0xc3, 0x89, 0xa6, 0x15, 0x43, 0xb1, 0x2a, 0xa1, 0x6a, 0xcf,
-	0xa0, 0xb8, 0x68, 0x0a, 0x63, 0xc8, 0x84, 0xd3, 0x52, 0x50, 0x0d, 0xd5, 0xcb, 0x44, 0xbc, 0xf1,
-	0x32, 0x64, 0x8f, 0xa8, 0x35, 0x8d, 0x56, 0x58, 0x26, 0x51, 0xa0, 0xe9, 0x90, 0x8b, 0xfa, 0xc0,
-	0xab, 0x50, 0x16, 0x1b, 0xf7, 0x29, 0x77, 0x86, 0xdc, 0x13, 0xb4, 0x34, 0x29, 0x2d, 0x72, 0x6d,
-	0x2f, 0xb1, 0x08, 0x7d, 0xd1, 0xdc, 0xe2, 0x7b, 0x0a, 0x2a, 0xb7, 0x17, 0x89, 0x5f, 0x40, 0xc6,
-	0x9f, 0x39, 0x11, 0xaf, 0xb2, 0xfe, 0xf0, 0x5f, 0x0b, 0x8f, 0x43, 0x63, 0xe6, 0x30, 0x22, 0x04,
-	0xf8, 0x11, 0x60, 0x2e, 0x72, 0xc3, 0x7d, 0xca, 0x4d, 0x6b, 0x26, 0x96, 0x2e, 0x5a, 0x29, 0x12,
-	0x39, 0x42, 0x36, 0x04, 0x10, 0xee, 0x3a, 0xfc, 0xcc, 0x43, 0x66, 0x39, 0x4a, 0x46, 0xe0, 0xe2,
-	0x1d, 0xe6, 0xa6, 0x13, 0xd3, 0x57, 0xb2, 0x51, 0x2e, 0x7c, 0x6b, 0x33, 0x80, 0xa4, 0x12, 0x2e,
-	0x41, 0x7e, 0xd0, 0xd9, 0xee, 0x74, 0x77, 0x3b, 0xb2, 0x14, 0x06, 0x6f, 0xbb, 0x83, 0x8e, 0xd1,
-	0x22, 0x32, 0xc2, 0x45, 0xc8, 0x6e, 0xea, 0x83, 0xcd, 0x96, 0x9c, 0xc2, 0x4b, 0x50, 0x7c, 0xb7,
-	0xd5, 0x37, 0xba, 0x9b, 0x44, 0x6f, 0xcb, 0x69, 0x8c, 0xa1, 0x22, 0x90, 0x24, 0x97, 0x09, 0xa5,
-	0xfd, 0x41, 0xbb, 0xad, 0x93, 0x0f, 0x72, 0x36, 0xbc, 0xaa, 0xad, 0xce, 0x46, 0x57, 0xce, 0xe1,
-	0x32, 0x14, 0xfa, 0x86, 0x6e, 0xb4, 0xfa, 0x2d, 0x43, 0xce, 0x6b, 0xdb, 0x90, 0x8b, 0x4a, 0xdf,
-	0xc3, 0x35, 0x69, 0x5f, 0x11, 0x14, 0xe6, 0x17, 0x70, 0x1f, 0xd7, 0x79, 0xeb, 0x24, 0xe6, 0xfb,
-	0xbc, 0x73, 0x08, 0xe9, 0x3b, 0x87, 0xd0, 0x7c, 0x73, 0x7a, 0xa1, 0x4a, 0x67, 0x17, 0xaa, 0x74,
-	0x7d, 0xa1, 0xa2, 0x2f, 0x81, 0x8a, 0x7e, 0x04, 0x2a, 0x3a, 0x09, 0x54, 0x74, 0x1a, 0xa8, 0xe8,
-	0x4f, 0xa0, 0xa2, 0xab, 0x40, 0x95, 0xae, 0x03, 0x15, 0x7d, 0xbb, 0x54, 0xa5, 0xd3, 0x4b, 0x55,
-	0x3a, 0xbb, 0x54, 0xa5, 0x8f, 0x79, 0xf1, 0xbb, 0x73, 0x46, 0xa3, 0x9c, 0xf8, 0x71, 0x3d, 0xfd,
-	0x1b, 0x00, 0x00, 0xff, 0xff, 0x40, 0x35, 0x74, 0x55, 0x00, 0x05, 0x00, 0x00,
+	// 767 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0x4d, 0x6f, 0xe3, 0x44,
+	0x18, 0xf6, 0x24, 0x69, 0x3e, 0xde, 0x66, 0x83, 0x99, 0xad, 0x84, 0xd5, 0x83, 0xdb, 0x35, 0x97,
+	0xac, 0x04, 0x29, 0x2a, 0x02, 0x04, 0x82, 0x83, 0x83, 0xb2, 0xdd, 0x6a, 0x9b, 0x0f, 0x8d, 0x1d,
+	0x56, 0x70, 0x89, 0x26, 0xe9, 0x6c, 0x3b, 0xc2, 0x63, 0x1b, 0x7b, 0xb2, 0x4a, 0x6e, 0x9c, 0x38,
+	0x73, 0xe6, 0x17, 0xf0, 0x17, 0xf8, 0x05, 0xf4, 0xd8, 0xe3, 0x8a, 0x43, 0x45, 0xd3, 0xcb, 0x02,
+	0x97, 0xfd, 0x09, 0xc8, 0x63, 0x3b, 0x4e, 0xb4, 0xda, 0x5b, 0x6f, 0x33, 0xef, 0xf3, 0x3c, 0xef,
+	0xbc, 0x7e, 0x9f, 0x47, 0x86, 0x5d, 0xc1, 0x05, 0x8f, 0x3a, 0x61, 0x14, 0xc8, 0x00, 0xd7, 0x67,
+	0x41, 0x24, 0xd9, 0x22, 0x9c, 0xee, 0x7f, 0x7c, 0xc1, 0xe5, 0xe5, 0x7c, 0xda, 0x99, 0x05, 0xe2,
+	0xe8, 0x22, 0xb8, 0x08, 0x8e, 0x14, 0x61, 0x3a, 0x7f, 0xa1, 0x6e, 0xea, 0xa2, 0x4e, 0xa9, 0xd0,
+	0xfa, 0xb3, 0x0c, 0xcd, 0xe7, 0x11, 0x97, 0x8c, 0xb0, 0x9f, 0xe6, 0x2c, 0x96, 0x78, 0x04, 0x20,
+	0xb9, 0x60, 0x31, 0x8b, 0x38, 0x8b, 0x0d, 0x74, 0x58, 0x6e, 0xef, 0x1e, 0xef, 0x75, 0xf2, 0xf6,
+	0x1d, 0x97, 0x0b, 0xe6, 0x28, 0xac, 0xbb, 0x7f, 0x75, 0x73, 0xa0, 0xfd, 0x75, 0x73, 0x80, 0x47,
+	0x11, 0xa3, 0x9e, 0x17, 0xcc, 0xdc, 0xb5, 0x8e, 0x6c, 0xf4, 0xc0, 0x5f, 0x42, 0xd5, 0x09, 0xe6,
+	0xd1, 0x8c, 0x19, 0xa5, 0x43, 0xd4, 0x6e, 0x1d, 0x3f, 0x2a, 0xba, 0x6d, 0xbe, 0xdc, 0x49, 0x49,
+	0x3d, 0x7f, 0x2e, 0x48, 0x26, 0xc0, 0x5f, 0x41, 0x5d, 0x30, 0x49, 0xcf, 0xa9, 0xa4, 0x46, 0x59,
+	0x8d, 0x62, 0x14, 0xe2, 0x3e, 0x93, 0x11, 0x9f, 0xf5, 0x33, 0xbc, 0x5b, 0xb9, 0xba, 0x39, 0x40,
+	0x64, 0xcd, 0xc7, 0x5f, 0xc3, 0x7e, 0xfc, 0x23, 0x0f, 0x27, 0x1e, 0x9d, 0x32, 0x6f, 0xe2, 0x53,
+	0xc1, 0x26, 0x2f, 0xa9, 0xc7, 0xcf, 0xa9, 0xe4, 0x81, 0x6f, 0xbc, 0xae, 0x1d, 0xa2, 0x76, 0x9d,
+	0x7c, 0x90, 0x50, 0xce, 0x12, 0xc6, 0x80, 0x0a, 0xf6, 0xdd, 0x1a, 0xc7, 0x8f, 0x41, 0x17, 0xdc,
+	0x9f, 0xa8, 0xcf, 0x90, 0x54, 0x84, 0x13, 0x11, 0x1b, 0xff, 0x24, 0x9a, 0x32, 0x69, 0x09, 0xee,
+	0xbb, 0x79, 0xbd, 0x1f, 0x2b, 0x2a, 0x5d, 0x6c, 0x53, 0xff, 0xcd, 0xa9, 0x74, 0xb1, 0x49, 0x3d,
+	0x82, 0x87, 0x05, 0xed, 0x92, 0xfb, 0x32, 0x9e, 0xc4, 0x4c, 0x1a, 0xff, 0xa5, 0xc3, 0xbc, 0xbf,
+	0xc6, 0x9e, 0x26, 0x90, 0xc3, 0xa4, 0xf5, 0x18, 0xa0, 0x58, 0x0b, 0xae, 0x41, 0xd9, 0x1e, 0x9d,
+	0xea, 0x1a, 0xae, 0x43, 0x85, 0x8c, 0xcf, 0x7a, 0x3a, 0x4a, 0x4e, 0x43, 0xf7, 0x6c, 0xa4, 0x97,
+	0xac, 0xf7, 0xe0, 0x41, 0xb6, 0xce, 0x38, 0x0c, 0xfc, 0x98, 0x59, 0x7f, 0x20, 0x80, 0xc2, 0x2e,
+	0x6c, 0x43, 0x55, 0xad, 0x22, 0x37, 0xf5, 0x61, 0xb1, 0x49, 0xb5, 0x80, 0x11, 0xe5, 0x51, 0x77,
+	0x2f, 0xf3, 0xb4, 0xa9, 0x4a, 0xf6, 0x39, 0x0d, 0x25, 0x8b, 0x48, 0x26, 0xc4, 0x9f, 0x40, 0x2d,
+	0xa6, 0x22, 0xf4, 0x58, 0x6c, 0x94, 0x54, 0x0f, 0xbd, 0xe8, 0xe1, 0x28, 0x40, 0xb9, 0xa0, 0x91,
+	0x9c, 0x86, 0x3f, 0x87, 0x06, 0x5b, 0x30, 0x11, 0x7a, 0x34, 0x8a, 0x33, 0x07, 0x71, 0xa1, 0xe9,
+	0x65, 0x50, 0xa6, 0x2a, 0xa8, 0xd6, 0x67, 0xd0, 0x58, 0x0f, 0x85, 0x31, 0x54, 0x12, 0xfb, 0x0c,
+	0x74, 0x88, 0xda, 0x4d, 0xa2, 0xce, 0x78, 0x0f, 0x76, 0x5e, 0x52, 0x6f, 0x9e, 0x66, 0xaa, 0x49,
+	0xd2, 0x8b, 0x65, 0x43, 0x35, 0x9d, 0x03, 0x3f, 0x82, 0xe6, 0x96, 0x21, 0x25, 0xe5, 0xc7, 0xae,
+	0xdc, 0x30, 0x63, 0xdd, 0x22, 0xe9, 0x8b, 0xf2, 0x16, 0xbf, 0x95, 0xa0, 0xb5, 0x9d, 0x2c, 0xfc,
+	0x05, 0x54, 0xe4, 0x32, 0x4c, 0x79, 0xad, 0xe3, 0x0f, 0xdf, 0x95, 0xc0, 0xec, 0xea, 0x2e, 0x43,
+	0x46, 0x94, 0x00, 0x7f, 0x04, 0x58, 0xa8, 0xda, 0xe4, 0x05, 0x15, 0xdc, 0x5b, 0xaa, 0x14, 0xaa,
+	0x51, 0x1a, 0x44, 0x4f, 0x91, 0x27, 0x0a, 0x48, 0xc2, 0x97, 0x7c, 0xe6, 0x25, 0xf3, 0x42, 0xa3,
+	0xa2, 0x70, 0x75, 0x4e, 0x6a, 0x73, 0x9f, 0x4b, 0x63, 0x27, 0xad, 0x25, 0x67, 0x6b, 0x09, 0x50,
+	0xbc, 0x84, 0x77, 0xa1, 0x36, 0x1e, 0x3c, 0x1b, 0x0c, 0x9f, 0x0f, 0x74, 0x2d, 0xb9, 0x7c, 0x3b,
+	0x1c, 0x0f, 0xdc, 0x1e, 0xd1, 0x11, 0x6e, 0xc0, 0xce, 0x89, 0x3d, 0x3e, 0xe9, 0xe9, 0x25, 0xfc,
+	0x00, 0x1a, 0x4f, 0x4f, 0x1d, 0x77, 0x78, 0x42, 0xec, 0xbe, 0x5e, 0xc6, 0x18, 0x5a, 0x0a, 0x29,
+	0x6a, 0x95, 0x44, 0xea, 0x8c, 0xfb, 0x7d, 0x9b, 0x7c, 0xaf, 0xef, 0x24, 0xa9, 0x3a, 0x1d, 0x3c,
+	0x19, 0xea, 0x55, 0xdc, 0x84, 0xba, 0xe3, 0xda, 0x6e, 0xcf, 0xe9, 0xb9, 0x7a, 0xcd, 0x7a, 0x06,
+	0xd5, 0xf4, 0xe9, 0x7b, 0x48, 0x93, 0xf5, 0x0b, 0x82, 0x7a, 0x9e, 0x80, 0xfb, 0x48, 0xe7, 0x56,
+	0x24, 0x72, 0x3f, 0xdf, 0x0a, 0x42, 0xf9, 0xad, 0x20, 0x74, 0xbf, 0xb9, 0xbe, 0x35, 0xb5, 0x57,
+	0xb7, 0xa6, 0xf6, 0xe6, 0xd6, 0x44, 0x3f, 0xaf, 0x4c, 0xf4, 0xfb, 0xca, 0x44, 0x57, 0x2b, 0x13,
+	0x5d, 0xaf, 0x4c, 0xf4, 0xf7, 0xca, 0x44, 0xaf, 0x57, 0xa6, 0xf6, 0x66, 0x65, 0xa2, 0x5f, 0xef,
+	0x4c, 0xed, 0xfa, 0xce, 0xd4, 0x5e, 0xdd, 0x99, 0xda, 0x0f, 0x35, 0xf5, 0xff, 0x0d, 0xa7, 0xd3,
+	0xaa, 0xfa, 0x93, 0x7e, 0xfa, 0xff, 0x00, 0xa5, 0x53, 0x52, 0x8f, 0x91, 0x05, 0x00, 0x00,
 }
 
 func (x WriteRequest_SourceEnum) String() string {
@@ -613,6 +646,15 @@ func (this *WriteRequest) Equal(that interface{}) bool {
 	if this.SkipLabelNameValidation != that1.SkipLabelNameValidation {
 		return false
 	}
+	if this.MinTimestampMs != that1.MinTimestampMs {
+		return false
+	}
+	if this.MaxTimestampMs != that1.MaxTimestampMs {
+		return false
+	}
+	if this.TimestampHintsSet != that1.TimestampHintsSet {
+		return false
+	}
 	return true
 }
 func (this *WriteResponse) Equal(that interface{}) bool {
@@ -836,7 +878,7 @@ func (this *WriteRequest) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 11)
 	s = append(s, "&mimirpb.WriteRequest{")
 	s = append(s, "Timeseries: "+fmt.Sprintf("%#v", this.Timeseries)+",\n")
 	s = append(s, "Source: "+fmt.Sprintf("%#v", this.Source)+",\n")
@@ -844,6 +886,9 @@ func (this *WriteRequest) GoString() string {
 		s = append(s, "Metadata: "+fmt.Sprintf("%#v", this.Metadata)+",\n")
 	}
 	s = append(s, "SkipLabelNameValidation: "+fmt.Sprintf("%#v", this.SkipLabelNameValidation)+",\n")
+	s = append(s, "MinTimestampMs: "+fmt.Sprintf("%#v", this.MinTimestampMs)+",\n")
+	s = append(s, "MaxTimestampMs: "+fmt.Sprintf("%#v", this.MaxTimestampMs)+",\n")
+	s = append(s, "TimestampHintsSet: "+fmt.Sprintf("%#v", this.TimestampHintsSet)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -965,6 +1010,32 @@ func (m *WriteRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.TimestampHintsSet {
+		i--
+		if m.TimestampHintsSet {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x3e
+		i--
+		dAtA[i] = 0xd8
+	}
+	if m.MaxTimestampMs != 0 {
+		i = encodeVarintMimir(dAtA, i, uint64(m.MaxTimestampMs))
+		i--
+		dAtA[i] = 0x3e
+		i--
+		dAtA[i] = 0xd0
+	}
+	if m.MinTimestampMs != 0 {
+		i = encodeVarintMimir(dAtA, i, uint64(m.MinTimestampMs))
+		i--
+		dAtA[i] = 0x3e
+		i--
+		dAtA[i] = 0xc8
+	}
 	if m.SkipLabelNameValidation {
 		i--
 		if m.SkipLabelNameValidation {
@@ -1341,6 +1412,15 @@ func (m *WriteRequest) Size() (n int) {
 	if m.SkipLabelNameValidation {
 		n += 3
 	}
+	if m.MinTimestampMs != 0 {
+		n += 2 + sovMimir(uint64(m.MinTimestampMs))
+	}
+	if m.MaxTimestampMs != 0 {
+		n += 2 + sovMimir(uint64(m.MaxTimestampMs))
+	}
+	if m.TimestampHintsSet {
+		n += 3
+	}
 	return n
 }
 
@@ -1492,6 +1572,9 @@ func (this *WriteRequest) String() string {
 		`Source:` + fmt.Sprintf("%v", this.Source) + `,`,
 		`Metadata:` + repeatedStringForMetadata + `,`,
 		`SkipLabelNameValidation:` + fmt.Sprintf("%v", this.SkipLabelNameValidation) + `,`,
+		`MinTimestampMs:` + fmt.Sprintf("%v", this.MinTimestampMs) + `,`,
+		`MaxTimestampMs:` + fmt.Sprintf("%v", this.MaxTimestampMs) + `,`,
+		`TimestampHintsSet:` + fmt.Sprintf("%v", this.TimestampHintsSet) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1728,6 +1811,64 @@ func (m *WriteRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.SkipLabelNameValidation = bool(v != 0)
+		case 1001:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinTimestampMs", wireType)
+			}
+			m.MinTimestampMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MinTimestampMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 1002:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTimestampMs", wireType)
+			}
+			m.MaxTimestampMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxTimestampMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 1003:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TimestampHintsSet", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.TimestampHintsSet = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMimir(dAtA[iNdEx:])
@@ -32,7 +32,7 @@ func (r *MimirClient) CreateAlertmanagerConfig(ctx context.Context, cfg string,
 		return err
 	}
 
-	res, err := r.doRequest(alertmanagerAPIPath, "POST", bytes.NewBuffer(payload), int64(len(payload)))
+	res, err := r.doRequest(ctx, alertmanagerAPIPath, "POST", bytes.NewReader(payload), int64(len(payload)))
 	if err != nil {
 		return err
 	}
@@ -44,7 +44,7 @@ func (r *MimirClient) CreateAlertmanagerConfig(ctx context.Context, cfg string,
 
 // DeleteAlermanagerConfig deletes the users alertmanagerconfig
 func (r *MimirClient) DeleteAlermanagerConfig(ctx context.Context) error {
-	res, err := r.doRequest(alertmanagerAPIPath, "DELETE", nil, -1)
+	res, err := r.doRequest(ctx, alertmanagerAPIPath, "DELETE", nil, -1)
 	if err != nil {
 		return err
 	}
@@ -56,7 +56,7 @@ func (r *MimirClient) DeleteAlermanagerConfig(ctx context.Context) error {
 
 // GetAlertmanagerConfig retrieves a Mimir cluster's Alertmanager config.
 func (r *MimirClient) GetAlertmanagerConfig(ctx context.Context) (string, map[string]string, error) {
-	res, err := r.doRequest(alertmanagerAPIPath, "GET", nil, -1)
+	res, err := r.doRequest(ctx, alertmanagerAPIPath, "GET", nil, -1)
 	if err != nil {
 		log.Debugln("no alert config present in response")
 		return "", nil, err
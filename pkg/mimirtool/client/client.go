@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/crypto/tls"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -40,6 +41,10 @@ type Config struct {
 	TLS             tls.ClientConfig
 	UseLegacyRoutes bool   `yaml:"use_legacy_routes"`
 	AuthToken       string `yaml:"auth_token"`
+
+	// MaxRetries is the maximum number of times a request failed with a network error, a 429 or a
+	// 5xx status code is retried, with an exponential backoff. 0 to disable retries.
+	MaxRetries int `yaml:"max_retries"`
 }
 
 // MimirClient is a client to the Mimir API.
@@ -51,6 +56,9 @@ type MimirClient struct {
 	Client    http.Client
 	apiPath   string
 	authToken string
+
+	maxRetries int
+	backoff    backoff.Config
 }
 
 // New returns a new MimirClient.
@@ -92,13 +100,18 @@ func New(cfg Config) (*MimirClient, error) {
 	}
 
 	return &MimirClient{
-		user:      cfg.User,
-		key:       cfg.Key,
-		id:        cfg.ID,
-		endpoint:  endpoint,
-		Client:    client,
-		apiPath:   path,
-		authToken: cfg.AuthToken,
+		user:       cfg.User,
+		key:        cfg.Key,
+		id:         cfg.ID,
+		endpoint:   endpoint,
+		Client:     client,
+		apiPath:    path,
+		authToken:  cfg.AuthToken,
+		maxRetries: cfg.MaxRetries,
+		backoff: backoff.Config{
+			MinBackoff: 100 * time.Millisecond,
+			MaxBackoff: 5 * time.Second,
+		},
 	}, nil
 }
 
@@ -106,7 +119,7 @@ func New(cfg Config) (*MimirClient, error) {
 func (r *MimirClient) Query(ctx context.Context, query string) (*http.Response, error) {
 	req := fmt.Sprintf("/prometheus/api/v1/query?query=%s&time=%d", url.QueryEscape(query), time.Now().Unix())
 
-	res, err := r.doRequest(req, "GET", nil, -1)
+	res, err := r.doRequest(ctx, req, "GET", nil, -1)
 	if err != nil {
 		return nil, err
 	}
@@ -114,10 +127,55 @@ func (r *MimirClient) Query(ctx context.Context, query string) (*http.Response,
 	return res, nil
 }
 
-func (r *MimirClient) doRequest(path, method string, payload io.Reader, contentLength int64) (*http.Response, error) {
+func (r *MimirClient) doRequest(ctx context.Context, path, method string, payload io.Reader, contentLength int64) (*http.Response, error) {
+	return r.doRequestWithHeaders(ctx, path, method, payload, contentLength, nil)
+}
+
+// doRequestWithHeaders sends the request, retrying it on failure if retries are enabled. Requests with a
+// payload are only retried if the payload can be rewound, that is it implements io.Seeker. The retries stop once
+// the context is canceled.
+func (r *MimirClient) doRequestWithHeaders(ctx context.Context, path, method string, payload io.Reader, contentLength int64, headers http.Header) (*http.Response, error) {
+	seeker, seekable := payload.(io.Seeker)
+	canRetry := payload == nil || seekable
+
+	retries := backoff.New(ctx, r.backoff)
+	for {
+		resp, retriable, err := r.doRequestOnce(ctx, path, method, payload, contentLength, headers)
+		if err == nil || !retriable || !canRetry || retries.NumRetries() >= r.maxRetries {
+			return resp, err
+		}
+
+		retries.Wait()
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"path":    path,
+			"method":  method,
+			"error":   err.Error(),
+			"retries": retries.NumRetries(),
+		}).Warnln("retrying request to Grafana Mimir API")
+
+		if seekable {
+			if _, seekErr := seeker.Seek(0, io.SeekStart); seekErr != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// doRequestOnce sends the request, returning whether the request can be retried on failure.
+func (r *MimirClient) doRequestOnce(ctx context.Context, path, method string, payload io.Reader, contentLength int64, headers http.Header) (*http.Response, bool, error) {
 	req, err := buildRequest(path, method, *r.endpoint, payload, contentLength)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	req = req.WithContext(ctx)
+
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
 	}
 
 	switch {
@@ -128,7 +186,7 @@ func (r *MimirClient) doRequest(path, method string, payload io.Reader, contentL
 			"method": req.Method,
 			"error":  err,
 		}).Errorln("error during setting up request to mimir api")
-		return nil, err
+		return nil, false, err
 
 	case r.user != "":
 		req.SetBasicAuth(r.user, r.key)
@@ -154,15 +212,16 @@ func (r *MimirClient) doRequest(path, method string, payload io.Reader, contentL
 			"method": req.Method,
 			"error":  err.Error(),
 		}).Errorln("error during request to Grafana Mimir API")
-		return nil, err
+		return nil, true, err
 	}
 
 	if err := checkResponse(resp); err != nil {
 		_ = resp.Body.Close()
-		return nil, errors.Wrapf(err, "%s request to %s failed", req.Method, req.URL.String())
+		retriable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5
+		return nil, retriable, errors.Wrapf(err, "%s request to %s failed", req.Method, req.URL.String())
 	}
 
-	return resp, nil
+	return resp, false, nil
 }
 
 // checkResponse checks an API response for errors.
@@ -7,7 +7,10 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -99,3 +102,98 @@ func TestBuildURL(t *testing.T) {
 	}
 
 }
+
+func TestMimirClient_Retries(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		maxRetries       int
+		statusCodes      []int
+		expectedRequests int
+		expectedErr      bool
+	}{
+		{
+			name:             "should not retry if retries are disabled",
+			maxRetries:       0,
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedRequests: 1,
+			expectedErr:      true,
+		},
+		{
+			name:             "should retry on 5xx and 429 status codes",
+			maxRetries:       2,
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			expectedRequests: 3,
+		},
+		{
+			name:             "should give up once the max retries are reached",
+			maxRetries:       1,
+			statusCodes:      []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			expectedRequests: 2,
+			expectedErr:      true,
+		},
+		{
+			name:             "should not retry on 4xx status codes",
+			maxRetries:       2,
+			statusCodes:      []int{http.StatusBadRequest, http.StatusOK},
+			expectedRequests: 1,
+			expectedErr:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []string
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(tc.statusCodes[len(bodies)-1])
+			}))
+			defer ts.Close()
+
+			client, err := New(Config{
+				Address:    ts.URL,
+				ID:         "my-id",
+				MaxRetries: tc.maxRetries,
+			})
+			require.NoError(t, err)
+
+			res, err := client.doRequest(context.Background(), "/test", http.MethodPost, bytes.NewReader([]byte("payload")), int64(len("payload")))
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, res.Body.Close())
+			}
+
+			// The payload is sent again on each retry.
+			require.Len(t, bodies, tc.expectedRequests)
+			for _, body := range bodies {
+				require.Equal(t, "payload", body)
+			}
+		})
+	}
+}
+
+func TestMimirClient_ShouldStopRetryingOnceTheContextIsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client, err := New(Config{
+		Address:    ts.URL,
+		ID:         "my-id",
+		MaxRetries: 10,
+	})
+	require.NoError(t, err)
+
+	err = client.Push(ctx, nil)
+	require.Error(t, err)
+	require.Equal(t, 1, requests)
+}
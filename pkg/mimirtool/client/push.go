@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const pushAPIPath = "/api/v1/push"
+
+// Push writes the series to Mimir using the Prometheus remote write protocol.
+func (r *MimirClient) Push(ctx context.Context, series []prompb.TimeSeries) error {
+	req := prompb.WriteRequest{Timeseries: series}
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	payload := snappy.Encode(nil, data)
+
+	headers := http.Header{}
+	headers.Set("Content-Encoding", "snappy")
+	headers.Set("Content-Type", "application/x-protobuf")
+	headers.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	res, err := r.doRequestWithHeaders(ctx, pushAPIPath, http.MethodPost, bytes.NewReader(payload), int64(len(payload)), headers)
+	if err != nil {
+		return err
+	}
+
+	res.Body.Close()
+
+	return nil
+}
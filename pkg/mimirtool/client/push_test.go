@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimirClient_Push(t *testing.T) {
+	requestCh := make(chan *http.Request, 1)
+	bodyCh := make(chan []byte, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requestCh <- r
+		bodyCh <- body
+	}))
+	defer ts.Close()
+
+	client, err := New(Config{
+		Address: ts.URL,
+		ID:      "my-id",
+	})
+	require.NoError(t, err)
+
+	series := []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+		Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}},
+	}}
+	require.NoError(t, client.Push(context.Background(), series))
+
+	req := <-requestCh
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/api/v1/push", req.URL.Path)
+	assert.Equal(t, "my-id", req.Header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "snappy", req.Header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+
+	data, err := snappy.Decode(nil, <-bodyCh)
+	require.NoError(t, err)
+
+	var received prompb.WriteRequest
+	require.NoError(t, received.Unmarshal(data))
+	assert.Equal(t, series, received.Timeseries)
+}
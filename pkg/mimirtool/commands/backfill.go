@@ -83,6 +83,10 @@ func (c *BackfillCommand) Register(app *kingpin.Application, envVars EnvVarNames
 		Envar(envVars.TLSKeyPath).
 		StringVar(&c.clientConfig.TLS.KeyPath)
 
+	cmd.Flag("max-retries", "Maximum number of times a request failed with a network error, a 429 or a 5xx status code is retried, with an exponential backoff. 0 to disable retries.").
+		Default("0").
+		IntVar(&c.clientConfig.MaxRetries)
+
 	cmd.Flag("sleep-time", "How long to sleep between checking state of block upload after uploading all files for the block.").
 		Default("20s").
 		DurationVar(&c.sleepTime)
@@ -92,6 +92,7 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames, re
 	rulesCmd.Flag("key", "API key to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").Default("").Envar(envVars.APIKey).StringVar(&r.ClientConfig.Key)
 	rulesCmd.Flag("backend", "Backend type to interact with (deprecated)").Default(rules.MimirBackend).EnumVar(&r.Backend, backends...)
 	rulesCmd.Flag("auth-token", "Authentication token for bearer token or JWT auth, alternatively set "+envVars.AuthToken+".").Default("").Envar(envVars.AuthToken).StringVar(&r.ClientConfig.AuthToken)
+	rulesCmd.Flag("max-retries", "Maximum number of times a request failed with a network error, a 429 or a 5xx status code is retried, with an exponential backoff. 0 to disable retries.").Default("0").IntVar(&r.ClientConfig.MaxRetries)
 
 	// Register rule commands
 	listCmd := rulesCmd.
@@ -13,6 +13,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 
@@ -48,6 +49,9 @@ type blockQuerierSeriesSet struct {
 	series   []*storepb.Series
 	warnings storage.Warnings
 
+	// The aggregates read from the chunks of the downsampled blocks, if any.
+	aggrs []storepb.Aggr
+
 	// next response to process
 	next int
 
@@ -74,7 +78,7 @@ func (bqss *blockQuerierSeriesSet) Next() bool {
 		bqss.next++
 	}
 
-	bqss.currSeries = newBlockQuerierSeries(currLabels, currChunks)
+	bqss.currSeries = newBlockQuerierSeries(currLabels, currChunks, bqss.aggrs)
 	return true
 }
 
@@ -91,17 +95,19 @@ func (bqss *blockQuerierSeriesSet) Warnings() storage.Warnings {
 }
 
 // newBlockQuerierSeries makes a new blockQuerierSeries. Input labels must be already sorted by name.
-func newBlockQuerierSeries(lbls []labels.Label, chunks []storepb.AggrChunk) *blockQuerierSeries {
+// The aggregates are the ones read from the chunks of the downsampled blocks, if any.
+func newBlockQuerierSeries(lbls []labels.Label, chunks []storepb.AggrChunk, aggrs []storepb.Aggr) *blockQuerierSeries {
 	sort.Slice(chunks, func(i, j int) bool {
 		return chunks[i].MinTime < chunks[j].MinTime
 	})
 
-	return &blockQuerierSeries{labels: lbls, chunks: chunks}
+	return &blockQuerierSeries{labels: lbls, chunks: chunks, aggrs: aggrs}
 }
 
 type blockQuerierSeries struct {
 	labels labels.Labels
 	chunks []storepb.AggrChunk
+	aggrs  []storepb.Aggr
 }
 
 func (bqs *blockQuerierSeries) Labels() labels.Labels {
@@ -114,6 +120,17 @@ func (bqs *blockQuerierSeries) Iterator() chunkenc.Iterator {
 		return series.NewErrIterator(errors.New("no chunks"))
 	}
 
+	downsampled := false
+	for _, c := range bqs.chunks {
+		if c.Raw == nil {
+			downsampled = true
+			break
+		}
+	}
+	if downsampled {
+		return bqs.downsampledIterator()
+	}
+
 	its := make([]iteratorWithMaxTime, 0, len(bqs.chunks))
 
 	for _, c := range bqs.chunks {
@@ -129,6 +146,113 @@ func (bqs *blockQuerierSeries) Iterator() chunkenc.Iterator {
 	return newBlockQuerierSeriesIterator(bqs.Labels(), its)
 }
 
+// downsampledIterator returns the iterator of a series whose chunks come, at least in part, from downsampled blocks.
+// The raw chunks are iterated as they are, while the aggregates of the downsampled chunks are read according to the
+// aggregates requested to the store-gateways.
+func (bqs *blockQuerierSeries) downsampledIterator() chunkenc.Iterator {
+	isCounter := len(bqs.aggrs) == 1 && bqs.aggrs[0] == storepb.Aggr_COUNTER
+
+	its := make([]iteratorWithMaxTime, 0, len(bqs.chunks))
+	for _, c := range bqs.chunks {
+		it, err := aggrChunkIterator(c, bqs.aggrs)
+		if err != nil {
+			return series.NewErrIterator(errors.Wrapf(err, "failed to initialize downsampled chunk (series: %v min time: %d max time: %d)", bqs.Labels(), c.MinTime, c.MaxTime))
+		}
+		its = append(its, iteratorWithMaxTime{it, c.MaxTime})
+	}
+
+	if isCounter {
+		// The counter resets are applied across the chunks, so that the counter is monotonic at the boundaries between
+		// the raw and the downsampled chunks too.
+		chunkIts := make([]chunkenc.Iterator, 0, len(its))
+		for _, it := range its {
+			chunkIts = append(chunkIts, it.Iterator)
+		}
+		return newBlockQuerierSeriesIterator(bqs.Labels(), []iteratorWithMaxTime{{downsample.NewApplyCounterResetsIterator(chunkIts...), math.MaxInt64}})
+	}
+
+	return newBlockQuerierSeriesIterator(bqs.Labels(), its)
+}
+
+// aggrChunkIterator returns the iterator of the raw chunk, or of the given aggregates of the downsampled chunk. The
+// count and sum aggregates together are iterated as their average.
+func aggrChunkIterator(c storepb.AggrChunk, aggrs []storepb.Aggr) (chunkenc.Iterator, error) {
+	if c.Raw != nil {
+		ch, err := chunkenc.FromData(chunkenc.EncXOR, c.Raw.Data)
+		if err != nil {
+			return nil, err
+		}
+		return ch.Iterator(nil), nil
+	}
+
+	if len(aggrs) == 2 && aggrs[0] == storepb.Aggr_COUNT && aggrs[1] == storepb.Aggr_SUM {
+		cnt, err := aggrChunkOf(c, storepb.Aggr_COUNT)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := aggrChunkOf(c, storepb.Aggr_SUM)
+		if err != nil {
+			return nil, err
+		}
+		return &seekByNextIterator{Iterator: downsample.NewAverageChunkIterator(cnt.Iterator(nil), sum.Iterator(nil))}, nil
+	}
+
+	if len(aggrs) != 1 {
+		return nil, errors.Errorf("unsupported aggregates %v", aggrs)
+	}
+	ch, err := aggrChunkOf(c, aggrs[0])
+	if err != nil {
+		return nil, err
+	}
+	return ch.Iterator(nil), nil
+}
+
+// aggrChunkOf returns the chunk of the given aggregate of the downsampled chunk.
+func aggrChunkOf(c storepb.AggrChunk, aggr storepb.Aggr) (chunkenc.Chunk, error) {
+	var data *storepb.Chunk
+	switch aggr {
+	case storepb.Aggr_COUNT:
+		data = c.Count
+	case storepb.Aggr_SUM:
+		data = c.Sum
+	case storepb.Aggr_MIN:
+		data = c.Min
+	case storepb.Aggr_MAX:
+		data = c.Max
+	case storepb.Aggr_COUNTER:
+		data = c.Counter
+	}
+	if data == nil {
+		return nil, errors.Errorf("no %s aggregate in the chunk", aggr)
+	}
+	return chunkenc.FromData(chunkenc.EncXOR, data.Data)
+}
+
+// seekByNextIterator implements Seek() by calling Next(), for the iterators which don't implement it.
+type seekByNextIterator struct {
+	chunkenc.Iterator
+	started bool
+}
+
+func (it *seekByNextIterator) Next() bool {
+	it.started = true
+	return it.Iterator.Next()
+}
+
+func (it *seekByNextIterator) Seek(t int64) bool {
+	if it.started {
+		if ts, _ := it.At(); ts >= t {
+			return true
+		}
+	}
+	for it.Next() {
+		if ts, _ := it.At(); ts >= t {
+			return true
+		}
+	}
+	return false
+}
+
 func newBlockQuerierSeriesIterator(labels labels.Labels, its []iteratorWithMaxTime) *blockQuerierSeriesIterator {
 	return &blockQuerierSeriesIterator{labels: labels, iterators: its, lastT: math.MinInt64}
 }
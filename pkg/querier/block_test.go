@@ -6,6 +6,7 @@
 package querier
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
@@ -18,11 +19,14 @@ import (
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 
+	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -77,7 +81,7 @@ func TestBlockQuerierSeries(t *testing.T) {
 		testData := testData
 
 		t.Run(testName, func(t *testing.T) {
-			series := newBlockQuerierSeries(labelpb.ZLabelsToPromLabels(testData.series.Labels), testData.series.Chunks)
+			series := newBlockQuerierSeries(labelpb.ZLabelsToPromLabels(testData.series.Labels), testData.series.Chunks, nil)
 
 			assert.Equal(t, testData.expectedMetric, series.Labels())
 
@@ -103,6 +107,213 @@ func TestBlockQuerierSeries(t *testing.T) {
 	}
 }
 
+func TestBlockQuerierSeries_Downsampled(t *testing.T) {
+	t.Parallel()
+
+	rawChunk := storepb.AggrChunk{MinTime: 0, MaxTime: 20, Raw: &storepb.Chunk{Type: storepb.Chunk_XOR, Data: mockChunkData(sample{0, 10}, sample{10, 20}, sample{20, 30})}}
+	downsampledChunk := storepb.AggrChunk{
+		MinTime: 100,
+		MaxTime: 200,
+		Count:   &storepb.Chunk{Type: storepb.Chunk_XOR, Data: mockChunkData(sample{100, 2}, sample{200, 4})},
+		Sum:     &storepb.Chunk{Type: storepb.Chunk_XOR, Data: mockChunkData(sample{100, 10}, sample{200, 40})},
+		Max:     &storepb.Chunk{Type: storepb.Chunk_XOR, Data: mockChunkData(sample{100, 6}, sample{200, 12})},
+		// The last sample of the counter chunk is the last raw value of the counter, after a reset.
+		Counter: &storepb.Chunk{Type: storepb.Chunk_XOR, Data: mockChunkData(sample{100, 5}, sample{200, 15}, sample{200, 15})},
+	}
+
+	tests := map[string]struct {
+		aggrs           []storepb.Aggr
+		seek            int64
+		expectedSamples []sample
+		expectedErr     string
+	}{
+		"should return the average of the downsampled samples": {
+			aggrs:           []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM},
+			expectedSamples: []sample{{0, 10}, {10, 20}, {20, 30}, {100, 5}, {200, 10}},
+		},
+		"should seek in the average of the downsampled samples": {
+			aggrs:           []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM},
+			seek:            150,
+			expectedSamples: []sample{{200, 10}},
+		},
+		"should return the requested aggregate of the downsampled samples": {
+			aggrs:           []storepb.Aggr{storepb.Aggr_MAX},
+			expectedSamples: []sample{{0, 10}, {10, 20}, {20, 30}, {100, 6}, {200, 12}},
+		},
+		"should apply the counter resets across the raw and the downsampled chunks": {
+			aggrs:           []storepb.Aggr{storepb.Aggr_COUNTER},
+			expectedSamples: []sample{{0, 10}, {10, 20}, {20, 30}, {100, 35}, {200, 45}},
+		},
+		"should fail if the aggregate is missing from the downsampled chunk": {
+			aggrs:       []storepb.Aggr{storepb.Aggr_MIN},
+			expectedErr: `failed to initialize downsampled chunk (series: {foo="bar"} min time: 100 max time: 200): no MIN aggregate in the chunk`,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			series := newBlockQuerierSeries(labels.FromStrings("foo", "bar"), []storepb.AggrChunk{downsampledChunk, rawChunk}, testData.aggrs)
+
+			var actual []sample
+			it := series.Iterator()
+			if testData.seek > 0 {
+				if it.Seek(testData.seek) {
+					ts, val := it.At()
+					actual = append(actual, sample{ts, val})
+				}
+			}
+			for it.Next() {
+				ts, val := it.At()
+				actual = append(actual, sample{ts, val})
+			}
+
+			if testData.expectedErr != "" {
+				require.EqualError(t, it.Err(), testData.expectedErr)
+				return
+			}
+			require.NoError(t, it.Err())
+			assert.Equal(t, testData.expectedSamples, actual)
+		})
+	}
+}
+
+func TestBlockQuerierSeries_DownsampledShouldMatchTheRawData(t *testing.T) {
+	t.Parallel()
+
+	// Two hours of raw samples scraped every 15s, and the same samples downsampled with a 5m resolution, so that
+	// the 1h ranges evaluated at the end of the data cover the same 5m windows.
+	const scrapeInterval = 15 * time.Second
+	var rawSamples []tsdbutil.Sample
+	for ts := int64(0); ts < (2 * time.Hour).Milliseconds(); ts += scrapeInterval.Milliseconds() {
+		rawSamples = append(rawSamples, sample{t: ts, v: float64((ts / scrapeInterval.Milliseconds() * 37) % 11)})
+	}
+
+	rawChunk := chunkenc.NewXORChunk()
+	appender, err := rawChunk.Appender()
+	require.NoError(t, err)
+	for _, s := range rawSamples {
+		appender.Append(s.T(), s.V())
+	}
+	rawChunks := []storepb.AggrChunk{{
+		MinTime: rawSamples[0].T(),
+		MaxTime: rawSamples[len(rawSamples)-1].T(),
+		Raw:     &storepb.Chunk{Type: storepb.Chunk_XOR, Data: rawChunk.Bytes()},
+	}}
+
+	var downsampledChunks []storepb.AggrChunk
+	for _, meta := range downsample.DownsampleRaw(downsample.SamplesFromTSDBSamples(rawSamples), (5 * time.Minute).Milliseconds()) {
+		aggrChunk := downsample.AggrChunk(meta.Chunk.Bytes())
+		getAggr := func(aggr downsample.AggrType) *storepb.Chunk {
+			c, err := aggrChunk.Get(aggr)
+			require.NoError(t, err)
+			return &storepb.Chunk{Type: storepb.Chunk_XOR, Data: c.Bytes()}
+		}
+
+		downsampledChunks = append(downsampledChunks, storepb.AggrChunk{
+			MinTime: meta.MinTime,
+			MaxTime: meta.MaxTime,
+			Count:   getAggr(downsample.AggrCount),
+			Sum:     getAggr(downsample.AggrSum),
+			Min:     getAggr(downsample.AggrMin),
+			Max:     getAggr(downsample.AggrMax),
+			Counter: getAggr(downsample.AggrCounter),
+		})
+	}
+
+	// The queryables read the aggregates of the downsampled chunks chosen from the select hints, like the querier.
+	newQueryable := func(chunks []storepb.AggrChunk) storage.Queryable {
+		return storage.QueryableFunc(func(context.Context, int64, int64) (storage.Querier, error) {
+			return &hintsSeriesQuerier{seriesFunc: func(hints *storage.SelectHints) storage.Series {
+				return newBlockQuerierSeries(labels.FromStrings(labels.MetricName, "series"), chunks, aggrsFromFunc(hints.Func))
+			}}, nil
+		})
+	}
+	rawQueryable := newQueryable(rawChunks)
+	downsampledQueryable := newQueryable(downsampledChunks)
+
+	engine := promql.NewEngine(promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute})
+	evalTime := util.TimeFromMillis((2 * time.Hour).Milliseconds())
+
+	for _, query := range []string{
+		"min_over_time(series[1h])",
+		"max_over_time(series[1h])",
+		"sum_over_time(series[1h])",
+		"avg_over_time(series[1h])",
+		"sum(avg_over_time(series[1h]))",
+		"count(max_over_time(series[1h]))",
+	} {
+		query := query
+
+		t.Run(query, func(t *testing.T) {
+			eval := func(queryable storage.Queryable) float64 {
+				q, err := engine.NewInstantQuery(queryable, nil, query, evalTime)
+				require.NoError(t, err)
+				t.Cleanup(q.Close)
+
+				res := q.Exec(context.Background())
+				require.NoError(t, res.Err)
+				vector, err := res.Vector()
+				require.NoError(t, err)
+				require.Len(t, vector, 1)
+				return vector[0].V
+			}
+
+			expected := eval(rawQueryable)
+			assert.InDelta(t, expected, eval(downsampledQueryable), 1e-9)
+		})
+	}
+}
+
+// hintsSeriesQuerier is a storage.Querier returning the series built from the hints of the select.
+type hintsSeriesQuerier struct {
+	seriesFunc func(hints *storage.SelectHints) storage.Series
+}
+
+func (m *hintsSeriesQuerier) Select(_ bool, hints *storage.SelectHints, _ ...*labels.Matcher) storage.SeriesSet {
+	return series.NewConcreteSeriesSet([]storage.Series{m.seriesFunc(hints)})
+}
+
+func (m *hintsSeriesQuerier) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (m *hintsSeriesQuerier) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (m *hintsSeriesQuerier) Close() error {
+	return nil
+}
+
+type sample struct {
+	t int64
+	v float64
+}
+
+func (s sample) T() int64 {
+	return s.t
+}
+
+func (s sample) V() float64 {
+	return s.v
+}
+
+func mockChunkData(samples ...sample) []byte {
+	chunk := chunkenc.NewXORChunk()
+	appender, err := chunk.Appender()
+	if err != nil {
+		panic(err)
+	}
+
+	for _, s := range samples {
+		appender.Append(s.t, s.v)
+	}
+
+	return chunk.Bytes()
+}
+
 func mockTSDBChunkData() []byte {
 	chunk := chunkenc.NewXORChunk()
 	appender, err := chunk.Appender()
@@ -450,7 +661,7 @@ func Benchmark_newBlockQuerierSeries(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		newBlockQuerierSeries(lbls, chunks)
+		newBlockQuerierSeries(lbls, chunks, nil)
 	}
 }
 
@@ -18,6 +18,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/util/globalerror"
 )
@@ -41,6 +42,35 @@ type BucketIndexBlocksFinder struct {
 	loader *bucketindex.Loader
 }
 
+func newBucketIndexBlocksFinderConfig(storageCfg mimir_tsdb.BlocksStorageConfig) BucketIndexBlocksFinderConfig {
+	return BucketIndexBlocksFinderConfig{
+		IndexLoader: bucketindex.LoaderConfig{
+			CheckInterval:         time.Minute,
+			UpdateOnStaleInterval: storageCfg.BucketStore.SyncInterval,
+			UpdateOnErrorInterval: storageCfg.BucketStore.BucketIndex.UpdateOnErrorInterval,
+			IdleTimeout:           storageCfg.BucketStore.BucketIndex.IdleTimeout,
+		},
+		MaxStalePeriod:           storageCfg.BucketStore.BucketIndex.MaxStalePeriod,
+		IgnoreDeletionMarksDelay: storageCfg.BucketStore.IgnoreDeletionMarksDelay,
+	}
+}
+
+// NewBucketIndexBlocksFinderFromConfig creates a BucketIndexBlocksFinder finding the blocks like the queriers do,
+// for the components which need to know the blocks queried by the queriers. The bucket index must be enabled. The
+// metrics of the bucket index loader are not registered, since they would conflict with the ones of the queriers
+// running in the same process.
+func NewBucketIndexBlocksFinderFromConfig(storageCfg mimir_tsdb.BlocksStorageConfig, cfgProvider bucket.TenantConfigProvider, component string, logger log.Logger, reg prometheus.Registerer) (*BucketIndexBlocksFinder, error) {
+	if !storageCfg.BucketStore.BucketIndex.Enabled {
+		return nil, errors.New("the bucket index must be enabled to find the blocks")
+	}
+
+	bucketClient, err := mimir_tsdb.NewBucketClient(context.Background(), storageCfg, component, logger, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bucket client")
+	}
+	return NewBucketIndexBlocksFinder(newBucketIndexBlocksFinderConfig(storageCfg), bucketClient, cfgProvider, logger, nil), nil
+}
+
 func NewBucketIndexBlocksFinder(cfg BucketIndexBlocksFinderConfig, bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider, logger log.Logger, reg prometheus.Registerer) *BucketIndexBlocksFinder {
 	loader := bucketindex.NewLoader(cfg.IndexLoader, bkt, cfgProvider, logger, reg)
 
@@ -0,0 +1,389 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// The hedging delay is the 99th percentile of the latency of the last hedgingLatencyWindowSize series requests
+	// to the same store-gateway.
+	hedgingLatencyQuantile   = 0.99
+	hedgingLatencyWindowSize = 1000
+
+	// The series requests to a store-gateway aren't hedged until the latency of enough requests has been observed.
+	hedgingMinObservations = 100
+
+	// The latency quantile is computed again every hedgingQuantileUpdateInterval observations.
+	hedgingQuantileUpdateInterval = 10
+
+	// The latencies of the store-gateways which haven't been requested for this long are forgotten, so that the
+	// latencies of the store-gateways which left, for example after a rollout, don't pile up.
+	hedgingLatencyWindowIdleTimeout = time.Hour
+
+	// A hedge is allowed every hedgingRequestsPerHedge series requests, with a burst of up to hedgingMaxHedgesBurst
+	// hedges, so that at most about 10% of the requests are hedged, and the hedging doesn't add much load to the
+	// store-gateways when they are all slow.
+	hedgingRequestsPerHedge = 10
+	hedgingMaxHedgesBurst   = 10
+)
+
+// storeGatewayHedging hedges the series requests to the store-gateways: when a store-gateway doesn't return the
+// series within the 99th percentile of the latency of its recent requests, the same blocks are requested to the
+// other store-gateways holding them, and the first complete response is used. It smooths over the slowness of a
+// single store-gateway, for example during a GC pause.
+type storeGatewayHedging struct {
+	minDelay time.Duration
+
+	mtx       sync.Mutex
+	latencies map[string]*latencyWindow // Keyed by store-gateway address.
+	budget    int                       // The number of requests earned for the hedges.
+
+	hedgedRequests          prometheus.Counter
+	hedgedRequestsWon       prometheus.Counter
+	hedgedRequestsThrottled prometheus.Counter
+}
+
+func newStoreGatewayHedging(minDelay time.Duration, reg prometheus.Registerer) *storeGatewayHedging {
+	return &storeGatewayHedging{
+		minDelay:  minDelay,
+		latencies: map[string]*latencyWindow{},
+		budget:    hedgingMaxHedgesBurst * hedgingRequestsPerHedge,
+		hedgedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_storegateway_hedged_requests_total",
+			Help: "Number of series requests hedged to other store-gateways because the store-gateway was slow to respond.",
+		}),
+		hedgedRequestsWon: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_storegateway_hedged_requests_won_total",
+			Help: "Number of hedged series requests whose response was received before the response of the original request.",
+		}),
+		hedgedRequestsThrottled: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_storegateway_hedged_requests_throttled_total",
+			Help: "Number of series requests not hedged, even if the store-gateway was slow to respond, because too many requests have been hedged recently.",
+		}),
+	}
+}
+
+// delay returns the delay after which a series request to the store-gateway with the given address is hedged, and
+// false if its requests can't be hedged yet.
+func (h *storeGatewayHedging) delay(addr string) (time.Duration, bool) {
+	h.mtx.Lock()
+	w := h.latencies[addr]
+	h.mtx.Unlock()
+	if w == nil {
+		return 0, false
+	}
+
+	latency, ok := w.quantile()
+	if !ok {
+		return 0, false
+	}
+	if latency < h.minDelay {
+		return h.minDelay, true
+	}
+	return latency, true
+}
+
+// observe records the latency of a series request to the store-gateway with the given address.
+func (h *storeGatewayHedging) observe(addr string, latency time.Duration) {
+	now := time.Now()
+
+	h.mtx.Lock()
+	w := h.latencies[addr]
+	if w == nil {
+		// The latencies of the idle store-gateways are only pruned when a new store-gateway is requested, which
+		// is rare.
+		for idleAddr, idle := range h.latencies {
+			if now.Sub(idle.lastObservedAt()) > hedgingLatencyWindowIdleTimeout {
+				delete(h.latencies, idleAddr)
+			}
+		}
+		w = newLatencyWindow(hedgingLatencyWindowSize)
+		h.latencies[addr] = w
+	}
+	h.mtx.Unlock()
+
+	w.observeAt(latency, now)
+}
+
+// requested earns the hedging budget of a series request.
+func (h *storeGatewayHedging) requested() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.budget < hedgingMaxHedgesBurst*hedgingRequestsPerHedge {
+		h.budget++
+	}
+}
+
+// allowHedge returns whether a slow series request can be hedged, consuming the hedging budget if so.
+func (h *storeGatewayHedging) allowHedge() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.budget < hedgingRequestsPerHedge {
+		h.hedgedRequestsThrottled.Inc()
+		return false
+	}
+	h.budget -= hedgingRequestsPerHedge
+	return true
+}
+
+// fetchSeriesFromStoreWithHedging fetches the series of the blocks from the store-gateway, hedging the request to
+// the other store-gateways holding the blocks if it's slow to respond. Once hedged, the series received by each
+// attempt are checked against the query limits while streaming with the checks returned by newAttemptCheckLimits,
+// and only the series of the first complete response are accounted to the query with checkLimits. It returns no
+// results if the store-gateways failed to return the series.
+func (q *blocksStoreQuerier) fetchSeriesFromStoreWithHedging(
+	ctx context.Context,
+	logger log.Logger,
+	c BlocksStoreClient,
+	blockIDs []ulid.ULID,
+	newRequest func([]ulid.ULID) (*storepb.SeriesRequest, error),
+	checkLimits func(*storepb.Series) error,
+	newAttemptCheckLimits func() func(*storepb.Series) error,
+) ([]seriesFetchResult, error) {
+	req, err := newRequest(blockIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := c.RemoteAddress()
+	q.hedging.requested()
+
+	delay, ok := q.hedging.delay(addr)
+	if !ok {
+		start := time.Now()
+		result, ok, err := fetchSeriesFromStore(ctx, logger, c, blockIDs, req, checkLimits)
+		if err != nil || !ok {
+			return nil, err
+		}
+		q.hedging.observe(addr, time.Since(start))
+		return []seriesFetchResult{result}, nil
+	}
+
+	// The request that didn't complete first is canceled.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The latency of the original request is observed once, either when it completes, or when a hedged request
+	// completes first, as the lower bound of its latency. Otherwise only the latency of the requests faster than
+	// the hedging delay would be observed, and the hedging delay would keep shrinking.
+	start := time.Now()
+	observed := atomic.NewBool(false)
+	observeOriginal := func() {
+		if observed.CAS(false, true) {
+			q.hedging.observe(addr, time.Since(start))
+		}
+	}
+
+	type attempt struct {
+		results []seriesFetchResult
+		ok      bool
+		err     error
+		hedged  bool
+	}
+	done := make(chan attempt, 2)
+
+	go func() {
+		result, ok, err := fetchSeriesFromStore(ctx, logger, c, blockIDs, req, newAttemptCheckLimits())
+		if ok {
+			observeOriginal()
+		}
+		done <- attempt{results: []seriesFetchResult{result}, ok: ok, err: err}
+	}()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var (
+		inflight = 1
+		firstErr error
+	)
+	for {
+		select {
+		case <-timer.C:
+			// The request can't be hedged if some blocks are only held by the slow store-gateway.
+			hedgeClients, err := q.stores.GetClientsFor(q.userID, blockIDs, excludeStoreGateway(blockIDs, addr))
+			if err != nil {
+				continue
+			}
+			if !q.hedging.allowHedge() {
+				continue
+			}
+
+			q.hedging.hedgedRequests.Inc()
+			inflight++
+			go func() {
+				results, ok, err := fetchSeriesFromHedgeStores(ctx, logger, hedgeClients, newRequest, newAttemptCheckLimits())
+				done <- attempt{results: results, ok: ok, err: err, hedged: true}
+			}()
+
+		case a := <-done:
+			inflight--
+			if a.err != nil {
+				// A query limit exceeded by a single attempt is exceeded by the query too, while any other
+				// error doesn't fail the query as long as another attempt can still succeed.
+				var limitErr validation.LimitError
+				if errors.As(a.err, &limitErr) || inflight == 0 {
+					return nil, a.err
+				}
+				if firstErr == nil {
+					firstErr = a.err
+				}
+				continue
+			}
+			if a.ok {
+				if a.hedged {
+					q.hedging.hedgedRequestsWon.Inc()
+					observeOriginal()
+				}
+				for _, result := range a.results {
+					for _, s := range result.series {
+						if err := checkLimits(s); err != nil {
+							return nil, err
+						}
+					}
+				}
+				return a.results, nil
+			}
+
+			// All the requests sent so far failed.
+			if inflight == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// fetchSeriesFromHedgeStores fetches the series of the blocks from each store-gateway, checking the series received
+// from all of them with onSeries. It returns false if any store-gateway failed to return the series.
+func fetchSeriesFromHedgeStores(ctx context.Context, logger log.Logger, clients map[BlocksStoreClient][]ulid.ULID, newRequest func([]ulid.ULID) (*storepb.SeriesRequest, error), onSeries func(*storepb.Series) error) ([]seriesFetchResult, bool, error) {
+	var (
+		g, gCtx = errgroup.WithContext(ctx)
+		mtx     sync.Mutex
+		results = make([]seriesFetchResult, 0, len(clients))
+		failed  bool
+	)
+
+	for c, blockIDs := range clients {
+		// Change variables scope since it will be used in a goroutine.
+		c := c
+		blockIDs := blockIDs
+
+		g.Go(func() error {
+			req, err := newRequest(blockIDs)
+			if err != nil {
+				return err
+			}
+
+			result, ok, err := fetchSeriesFromStore(gCtx, logger, c, blockIDs, req, onSeries)
+			if err != nil {
+				return err
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if ok {
+				results = append(results, result)
+			} else {
+				failed = true
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, false, err
+	}
+	return results, !failed, nil
+}
+
+// excludeStoreGateway returns the map of the blocks excluded from the store-gateway with the given address.
+func excludeStoreGateway(blockIDs []ulid.ULID, addr string) map[ulid.ULID][]string {
+	exclude := make(map[ulid.ULID][]string, len(blockIDs))
+	for _, blockID := range blockIDs {
+		exclude[blockID] = []string{addr}
+	}
+	return exclude
+}
+
+// latencyWindow tracks a quantile of the latency of the last observed requests.
+type latencyWindow struct {
+	mtx          sync.Mutex
+	latencies    []time.Duration
+	next         int
+	sinceUpdate  int
+	value        time.Duration
+	sorted       []time.Duration
+	lastObserved time.Time
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{
+		latencies: make([]time.Duration, 0, size),
+		sorted:    make([]time.Duration, 0, size),
+	}
+}
+
+// observe records the latency of a request, evicting the oldest one once the window is full.
+func (w *latencyWindow) observe(latency time.Duration) {
+	w.observeAt(latency, time.Now())
+}
+
+// observeAt records the latency of a request completed at the given time.
+func (w *latencyWindow) observeAt(latency time.Duration, now time.Time) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.lastObserved = now
+
+	if len(w.latencies) < cap(w.latencies) {
+		w.latencies = append(w.latencies, latency)
+	} else {
+		w.latencies[w.next] = latency
+		w.next = (w.next + 1) % len(w.latencies)
+	}
+
+	w.sinceUpdate++
+	if len(w.latencies) >= hedgingMinObservations && (w.value == 0 || w.sinceUpdate >= hedgingQuantileUpdateInterval) {
+		w.sorted = append(w.sorted[:0], w.latencies...)
+		sort.Slice(w.sorted, func(i, j int) bool { return w.sorted[i] < w.sorted[j] })
+		w.value = w.sorted[int(float64(len(w.sorted)-1)*hedgingLatencyQuantile)]
+		w.sinceUpdate = 0
+	}
+}
+
+// lastObservedAt returns when the last latency has been observed.
+func (w *latencyWindow) lastObservedAt() time.Time {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.lastObserved
+}
+
+// quantile returns the latency quantile, and false if not enough latencies have been observed yet.
+func (w *latencyWindow) quantile() (time.Duration, bool) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if len(w.latencies) < hedgingMinObservations {
+		return 0, false
+	}
+	return w.value, true
+}
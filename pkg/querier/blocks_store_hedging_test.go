@@ -0,0 +1,373 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/types"
+	"github.com/grafana/dskit/test"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	w := newLatencyWindow(200)
+
+	for i := 1; i < hedgingMinObservations; i++ {
+		w.observe(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := w.quantile()
+	assert.False(t, ok)
+
+	w.observe(hedgingMinObservations * time.Millisecond)
+	latency, ok := w.quantile()
+	require.True(t, ok)
+	assert.Equal(t, 99*time.Millisecond, latency)
+
+	// Once the window is full, the oldest latencies are evicted.
+	for i := 0; i < 200; i++ {
+		w.observe(time.Second)
+	}
+	latency, ok = w.quantile()
+	require.True(t, ok)
+	assert.Equal(t, time.Second, latency)
+}
+
+func TestStoreGatewayHedging_Delay(t *testing.T) {
+	h := newStoreGatewayHedging(50*time.Millisecond, nil)
+
+	_, ok := h.delay("1.1.1.1")
+	assert.False(t, ok)
+
+	for i := 0; i < hedgingMinObservations; i++ {
+		h.observe("1.1.1.1", time.Millisecond)
+	}
+	delay, ok := h.delay("1.1.1.1")
+	require.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, delay)
+
+	for i := 0; i < hedgingLatencyWindowSize; i++ {
+		h.observe("1.1.1.1", time.Second)
+	}
+	delay, ok = h.delay("1.1.1.1")
+	require.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	// The latencies are tracked per store-gateway.
+	_, ok = h.delay("2.2.2.2")
+	assert.False(t, ok)
+
+	// The latencies of the idle store-gateways are forgotten once a new store-gateway is requested.
+	h.latencies["1.1.1.1"].lastObserved = time.Now().Add(-hedgingLatencyWindowIdleTimeout - time.Minute)
+	h.observe("2.2.2.2", time.Millisecond)
+	_, ok = h.delay("1.1.1.1")
+	assert.False(t, ok)
+}
+
+func TestStoreGatewayHedging_Budget(t *testing.T) {
+	h := newStoreGatewayHedging(50*time.Millisecond, nil)
+
+	// The budget allows a burst of hedges.
+	for i := 0; i < hedgingMaxHedgesBurst; i++ {
+		assert.True(t, h.allowHedge())
+	}
+	assert.False(t, h.allowHedge())
+
+	// Then a hedge every hedgingRequestsPerHedge requests.
+	for i := 0; i < hedgingRequestsPerHedge-1; i++ {
+		h.requested()
+	}
+	assert.False(t, h.allowHedge())
+	h.requested()
+	assert.True(t, h.allowHedge())
+	assert.False(t, h.allowHedge())
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(h.hedgedRequestsThrottled))
+}
+
+func TestBlocksStoreQuerier_SelectWithHedging(t *testing.T) {
+	const (
+		metricName = "test_metric"
+		minT       = int64(10)
+		maxT       = int64(20)
+	)
+
+	var (
+		block1          = ulid.MustNew(1, nil)
+		metricNameLabel = labels.Label{Name: labels.MetricName, Value: metricName}
+		seriesLabels    = labels.Labels{metricNameLabel, {Name: "series", Value: "1"}}
+		series2Labels   = labels.Labels{metricNameLabel, {Name: "series", Value: "2"}}
+	)
+
+	tests := map[string]struct {
+		storeSetResponses        []interface{}
+		maxSeriesPerQuery        int
+		budgetExhausted          bool
+		expectedValue            float64
+		expectedErr              string
+		expectedMetrics          string
+		expectedSlowObservations int
+	}{
+		"the store-gateway responds before the hedging delay": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 1),
+						mockHintsResponse(block1),
+					}}: {block1},
+				},
+			},
+			expectedValue: 1,
+			expectedMetrics: `
+				# HELP cortex_querier_storegateway_hedged_requests_total Number of series requests hedged to other store-gateways because the store-gateway was slow to respond.
+				# TYPE cortex_querier_storegateway_hedged_requests_total counter
+				cortex_querier_storegateway_hedged_requests_total 0
+				# HELP cortex_querier_storegateway_hedged_requests_won_total Number of hedged series requests whose response was received before the response of the original request.
+				# TYPE cortex_querier_storegateway_hedged_requests_won_total counter
+				cortex_querier_storegateway_hedged_requests_won_total 0
+				# HELP cortex_querier_storegateway_hedged_requests_throttled_total Number of series requests not hedged, even if the store-gateway was slow to respond, because too many requests have been hedged recently.
+				# TYPE cortex_querier_storegateway_hedged_requests_throttled_total counter
+				cortex_querier_storegateway_hedged_requests_throttled_total 0
+			`,
+		},
+		"the store-gateway is slow and the hedged request responds first": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&slowStoreGatewayClientMock{delay: 10 * time.Second, storeGatewayClientMock: &storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 1),
+						mockHintsResponse(block1),
+					}}}: {block1},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 2),
+						mockHintsResponse(block1),
+					}}: {block1},
+				},
+			},
+			expectedValue: 2,
+			// The latency of the canceled request to the slow store-gateway is observed too.
+			expectedSlowObservations: hedgingMinObservations + 1,
+			expectedMetrics: `
+				# HELP cortex_querier_storegateway_hedged_requests_total Number of series requests hedged to other store-gateways because the store-gateway was slow to respond.
+				# TYPE cortex_querier_storegateway_hedged_requests_total counter
+				cortex_querier_storegateway_hedged_requests_total 1
+				# HELP cortex_querier_storegateway_hedged_requests_won_total Number of hedged series requests whose response was received before the response of the original request.
+				# TYPE cortex_querier_storegateway_hedged_requests_won_total counter
+				cortex_querier_storegateway_hedged_requests_won_total 1
+				# HELP cortex_querier_storegateway_hedged_requests_throttled_total Number of series requests not hedged, even if the store-gateway was slow to respond, because too many requests have been hedged recently.
+				# TYPE cortex_querier_storegateway_hedged_requests_throttled_total counter
+				cortex_querier_storegateway_hedged_requests_throttled_total 0
+			`,
+		},
+		"the store-gateway is slow but the hedging budget is exhausted": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&slowStoreGatewayClientMock{delay: time.Second, storeGatewayClientMock: &storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 1),
+						mockHintsResponse(block1),
+					}}}: {block1},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 2),
+						mockHintsResponse(block1),
+					}}: {block1},
+				},
+			},
+			budgetExhausted: true,
+			expectedValue:   1,
+			expectedMetrics: `
+				# HELP cortex_querier_storegateway_hedged_requests_total Number of series requests hedged to other store-gateways because the store-gateway was slow to respond.
+				# TYPE cortex_querier_storegateway_hedged_requests_total counter
+				cortex_querier_storegateway_hedged_requests_total 0
+				# HELP cortex_querier_storegateway_hedged_requests_won_total Number of hedged series requests whose response was received before the response of the original request.
+				# TYPE cortex_querier_storegateway_hedged_requests_won_total counter
+				cortex_querier_storegateway_hedged_requests_won_total 0
+				# HELP cortex_querier_storegateway_hedged_requests_throttled_total Number of series requests not hedged, even if the store-gateway was slow to respond, because too many requests have been hedged recently.
+				# TYPE cortex_querier_storegateway_hedged_requests_throttled_total counter
+				cortex_querier_storegateway_hedged_requests_throttled_total 1
+			`,
+		},
+		"the hedged request fails while the slow store-gateway is still responding": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&slowStoreGatewayClientMock{delay: time.Second, storeGatewayClientMock: &storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 1),
+						mockHintsResponse(block1),
+					}}}: {block1},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 2),
+						{Result: &storepb.SeriesResponse_Hints{Hints: &types.Any{TypeUrl: "invalid"}}},
+					}}: {block1},
+				},
+			},
+			expectedValue: 1,
+			expectedMetrics: `
+				# HELP cortex_querier_storegateway_hedged_requests_total Number of series requests hedged to other store-gateways because the store-gateway was slow to respond.
+				# TYPE cortex_querier_storegateway_hedged_requests_total counter
+				cortex_querier_storegateway_hedged_requests_total 1
+				# HELP cortex_querier_storegateway_hedged_requests_won_total Number of hedged series requests whose response was received before the response of the original request.
+				# TYPE cortex_querier_storegateway_hedged_requests_won_total counter
+				cortex_querier_storegateway_hedged_requests_won_total 0
+				# HELP cortex_querier_storegateway_hedged_requests_throttled_total Number of series requests not hedged, even if the store-gateway was slow to respond, because too many requests have been hedged recently.
+				# TYPE cortex_querier_storegateway_hedged_requests_throttled_total counter
+				cortex_querier_storegateway_hedged_requests_throttled_total 0
+			`,
+		},
+		"the hedged request exceeds the limits while streaming the series": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&slowStoreGatewayClientMock{delay: 10 * time.Second, storeGatewayClientMock: &storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 1),
+						mockSeriesResponse(series2Labels, minT, 1),
+						mockHintsResponse(block1),
+					}}}: {block1},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					// The stream never completes, so the limit can only be reached while streaming.
+					&hangingStoreGatewayClientMock{storeGatewayClientMock: &storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(seriesLabels, minT, 2),
+						mockSeriesResponse(series2Labels, minT, 2),
+					}}}: {block1},
+				},
+			},
+			maxSeriesPerQuery: 1,
+			expectedErr:       fmt.Sprintf(limiter.MaxSeriesHitMsgFormat, 1),
+			expectedMetrics: `
+				# HELP cortex_querier_storegateway_hedged_requests_total Number of series requests hedged to other store-gateways because the store-gateway was slow to respond.
+				# TYPE cortex_querier_storegateway_hedged_requests_total counter
+				cortex_querier_storegateway_hedged_requests_total 1
+				# HELP cortex_querier_storegateway_hedged_requests_won_total Number of hedged series requests whose response was received before the response of the original request.
+				# TYPE cortex_querier_storegateway_hedged_requests_won_total counter
+				cortex_querier_storegateway_hedged_requests_won_total 0
+				# HELP cortex_querier_storegateway_hedged_requests_throttled_total Number of series requests not hedged, even if the store-gateway was slow to respond, because too many requests have been hedged recently.
+				# TYPE cortex_querier_storegateway_hedged_requests_throttled_total counter
+				cortex_querier_storegateway_hedged_requests_throttled_total 0
+			`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := limiter.AddQueryLimiterToContext(context.Background(), limiter.NewQueryLimiter(testData.maxSeriesPerQuery, 0, 0))
+			reg := prometheus.NewPedanticRegistry()
+
+			stores := &blocksStoreSetMock{mockedResponses: testData.storeSetResponses}
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+			hedging := newStoreGatewayHedging(100*time.Millisecond, reg)
+			for i := 0; i < hedgingMinObservations; i++ {
+				hedging.observe("1.1.1.1", time.Millisecond)
+			}
+			if testData.budgetExhausted {
+				hedging.budget = 0
+			}
+
+			q := &blocksStoreQuerier{
+				ctx:         ctx,
+				minT:        minT,
+				maxT:        maxT,
+				userID:      "user-1",
+				finder:      finder,
+				stores:      stores,
+				consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(reg),
+				limits:      &blocksStoreLimitsMock{},
+				hedging:     hedging,
+			}
+
+			start := time.Now()
+			set := q.Select(true, &storage.SelectHints{Start: minT, End: maxT}, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, metricName))
+			if testData.expectedErr != "" {
+				require.False(t, set.Next())
+				assert.EqualError(t, set.Err(), testData.expectedErr)
+			} else {
+				require.True(t, set.Next())
+				assert.Equal(t, seriesLabels, set.At().Labels())
+
+				it := set.At().Iterator()
+				require.True(t, it.Next())
+				ts, v := it.At()
+				assert.Equal(t, minT, ts)
+				assert.Equal(t, testData.expectedValue, v)
+
+				assert.False(t, set.Next())
+				require.NoError(t, set.Err())
+			}
+			assert.Less(t, time.Since(start), 5*time.Second)
+
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(testData.expectedMetrics),
+				"cortex_querier_storegateway_hedged_requests_total", "cortex_querier_storegateway_hedged_requests_won_total", "cortex_querier_storegateway_hedged_requests_throttled_total"))
+
+			if testData.expectedSlowObservations > 0 {
+				test.Poll(t, time.Second, testData.expectedSlowObservations, func() interface{} {
+					w := hedging.latencies["1.1.1.1"]
+					w.mtx.Lock()
+					defer w.mtx.Unlock()
+					return len(w.latencies)
+				})
+			}
+		})
+	}
+}
+
+// slowStoreGatewayClientMock delays the series responses of the store-gateway, until the request is canceled.
+type slowStoreGatewayClientMock struct {
+	*storeGatewayClientMock
+	delay time.Duration
+}
+
+func (m *slowStoreGatewayClientMock) Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
+	select {
+	case <-time.After(m.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return m.storeGatewayClientMock.Series(ctx, in, opts...)
+}
+
+// hangingStoreGatewayClientMock streams the series responses of the store-gateway, but never completes the stream
+// until the request is canceled.
+type hangingStoreGatewayClientMock struct {
+	*storeGatewayClientMock
+}
+
+func (m *hangingStoreGatewayClientMock) Series(ctx context.Context, _ *storepb.SeriesRequest, _ ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
+	return &hangingSeriesClientMock{ctx: ctx, storeGatewaySeriesClientMock: &storeGatewaySeriesClientMock{mockedResponses: m.mockedSeriesResponses}}, nil
+}
+
+type hangingSeriesClientMock struct {
+	*storeGatewaySeriesClientMock
+	ctx context.Context
+}
+
+func (m *hangingSeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
+	if len(m.mockedResponses) == 0 {
+		<-m.ctx.Done()
+		return nil, m.ctx.Err()
+	}
+	return m.storeGatewaySeriesClientMock.Recv()
+}
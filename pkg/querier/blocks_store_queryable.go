@@ -9,6 +9,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	stdmath "math"
+	"net/http"
 	"sort"
 	"strings"
 	"sync"
@@ -25,9 +27,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/store/hintspb"
@@ -35,11 +41,15 @@ import (
 	"github.com/thanos-io/thanos/pkg/strutil"
 	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
 	grpc_metadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/grafana/dskit/tenant"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/downsampling"
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
 	"github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/series"
@@ -79,6 +89,10 @@ type BlocksStoreSet interface {
 	// query the set of blocks in input. The exclude parameter is the map of
 	// blocks -> store-gateway addresses that should be excluded.
 	GetClientsFor(userID string, blockIDs []ulid.ULID, exclude map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error)
+
+	// InstanceZone returns the zone of the store-gateway instance with the given address, or an empty
+	// string if the zone is unknown or zone-awareness is disabled.
+	InstanceZone(addr string) string
 }
 
 // BlocksFinder is the interface used to find blocks for a given user and time range.
@@ -107,6 +121,11 @@ type BlocksStoreLimits interface {
 	MaxLabelsQueryLength(userID string) time.Duration
 	MaxChunksPerQuery(userID string) int
 	StoreGatewayTenantShardSize(userID string) int
+	StoreGatewayTenantReplicationFactor(userID string) int
+	MaxMetadataPerBlock(userID string) int
+	MaxExemplarsPerBlock(userID string) int
+	AutoDownsamplingEnabled(userID string) bool
+	ZoneOutagePartialResultsEnabled(userID string) bool
 }
 
 type blocksStoreQueryableMetrics struct {
@@ -116,6 +135,8 @@ type blocksStoreQueryableMetrics struct {
 	blocksFound                                       prometheus.Counter
 	blocksQueried                                     prometheus.Counter
 	blocksWithCompactorShardButIncompatibleQueryShard prometheus.Counter
+	degradedReads                                     prometheus.Counter
+	partialResults                                    prometheus.Counter
 }
 
 func newBlocksStoreQueryableMetrics(reg prometheus.Registerer) *blocksStoreQueryableMetrics {
@@ -145,6 +166,14 @@ func newBlocksStoreQueryableMetrics(reg prometheus.Registerer) *blocksStoreQuery
 			Name: "cortex_querier_blocks_with_compactor_shard_but_incompatible_query_shard_total",
 			Help: "Blocks that couldn't be checked for query and compactor sharding optimization due to incompatible shard counts.",
 		}),
+		degradedReads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_storage_degraded_reads_total",
+			Help: "Number of read requests served without the blocks storage data, because the blocks storage or the store-gateways were unavailable.",
+		}),
+		partialResults: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_storegateway_partial_results_total",
+			Help: "Number of read requests served with partial results, because some blocks couldn't be queried from the store-gateways of a single zone or from a single store-gateway.",
+		}),
 	}
 }
 
@@ -158,9 +187,21 @@ type BlocksStoreQueryable struct {
 	consistency     *BlocksConsistencyChecker
 	logger          log.Logger
 	queryStoreAfter time.Duration
+	lookbackDelta   time.Duration
 	metrics         *blocksStoreQueryableMetrics
 	limits          BlocksStoreLimits
 
+	// If enabled, the read requests are served without the blocks storage data, with a warning, when the blocks
+	// storage or the store-gateways are unavailable.
+	degradedReadModeEnabled bool
+
+	// If enabled, the read requests are served with partial results, with a warning, when some blocks couldn't be
+	// queried from the store-gateways of a single zone or from a single store-gateway.
+	partialResultsEnabled bool
+
+	// If set, the series requests to the slow store-gateways are hedged to the other store-gateways holding the blocks.
+	hedging *storeGatewayHedging
+
 	// Subservices manager.
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -172,6 +213,10 @@ func NewBlocksStoreQueryable(
 	consistency *BlocksConsistencyChecker,
 	limits BlocksStoreLimits,
 	queryStoreAfter time.Duration,
+	lookbackDelta time.Duration,
+	degradedReadModeEnabled bool,
+	partialResultsEnabled bool,
+	hedgingMinDelay time.Duration,
 	logger log.Logger,
 	reg prometheus.Registerer,
 ) (*BlocksStoreQueryable, error) {
@@ -181,15 +226,23 @@ func NewBlocksStoreQueryable(
 	}
 
 	q := &BlocksStoreQueryable{
-		stores:             stores,
-		finder:             finder,
-		consistency:        consistency,
-		queryStoreAfter:    queryStoreAfter,
-		logger:             logger,
-		subservices:        manager,
-		subservicesWatcher: services.NewFailureWatcher(),
-		metrics:            newBlocksStoreQueryableMetrics(reg),
-		limits:             limits,
+		stores:                  stores,
+		finder:                  finder,
+		consistency:             consistency,
+		queryStoreAfter:         queryStoreAfter,
+		lookbackDelta:           lookbackDelta,
+		degradedReadModeEnabled: degradedReadModeEnabled,
+		partialResultsEnabled:   partialResultsEnabled,
+		logger:                  logger,
+		subservices:             manager,
+		subservicesWatcher:      services.NewFailureWatcher(),
+		metrics:                 newBlocksStoreQueryableMetrics(reg),
+		limits:                  limits,
+	}
+
+	// The series requests are hedged if the min delay is set.
+	if hedgingMinDelay > 0 {
+		q.hedging = newStoreGatewayHedging(hedgingMinDelay, reg)
 	}
 
 	q.Service = services.NewBasicService(q.starting, q.running, q.stopping)
@@ -203,7 +256,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		bucketClient objstore.Bucket
 	)
 
-	bucketClient, err := bucket.NewClient(context.Background(), storageCfg.Bucket, "querier", logger, reg)
+	bucketClient, err := mimir_tsdb.NewBucketClient(context.Background(), storageCfg, "querier", logger, reg)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create bucket client")
 	}
@@ -218,16 +271,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 	// Create the blocks finder.
 	var finder BlocksFinder
 	if storageCfg.BucketStore.BucketIndex.Enabled {
-		finder = NewBucketIndexBlocksFinder(BucketIndexBlocksFinderConfig{
-			IndexLoader: bucketindex.LoaderConfig{
-				CheckInterval:         time.Minute,
-				UpdateOnStaleInterval: storageCfg.BucketStore.SyncInterval,
-				UpdateOnErrorInterval: storageCfg.BucketStore.BucketIndex.UpdateOnErrorInterval,
-				IdleTimeout:           storageCfg.BucketStore.BucketIndex.IdleTimeout,
-			},
-			MaxStalePeriod:           storageCfg.BucketStore.BucketIndex.MaxStalePeriod,
-			IgnoreDeletionMarksDelay: storageCfg.BucketStore.IgnoreDeletionMarksDelay,
-		}, bucketClient, limits, logger, reg)
+		finder = NewBucketIndexBlocksFinder(newBucketIndexBlocksFinderConfig(storageCfg), bucketClient, limits, logger, reg)
 	} else {
 		finder = NewBucketScanBlocksFinder(BucketScanBlocksFinderConfig{
 			ScanInterval:             storageCfg.BucketStore.SyncInterval,
@@ -254,7 +298,14 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		return nil, errors.Wrap(err, "failed to create store-gateway ring client")
 	}
 
-	stores, err = newBlocksStoreReplicationSet(storesRing, randomLoadBalancing, limits, querierCfg.StoreGatewayClient, logger, reg)
+	// The store-gateways shutting down are queried during the handover period, while the ones which will own their
+	// blocks once they're gone preload them.
+	readOp := storegateway.BlocksRead
+	if gatewayCfg.ShardingRing.ShutdownHandoverPeriod > 0 {
+		readOp = storegateway.BlocksReadHandover
+	}
+
+	stores, err = newBlocksStoreReplicationSet(storesRing, randomLoadBalancing, readOp, limits, querierCfg.StoreGatewayClient, logger, reg)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create store set")
 	}
@@ -271,7 +322,12 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		reg,
 	)
 
-	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, logger, reg)
+	var hedgingMinDelay time.Duration
+	if querierCfg.StoreGatewayHedgingEnabled {
+		hedgingMinDelay = querierCfg.StoreGatewayHedgingMinDelay
+	}
+
+	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, querierCfg.EngineConfig.LookbackDelta, querierCfg.DegradedReadModeEnabled, querierCfg.StoreGatewayPartialResultsEnabled, hedgingMinDelay, logger, reg)
 }
 
 func (q *BlocksStoreQueryable) starting(ctx context.Context) error {
@@ -310,6 +366,85 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		return nil, err
 	}
 
+	return q.newBlocksStoreQuerier(ctx, userID, mint, maxt), nil
+}
+
+// MetricsMetadata returns the metric metadata persisted in the tenant's blocks, fetched from the store-gateways.
+// The store-gateways aren't queried if the persistence of the metadata is disabled for the tenant.
+func (q *BlocksStoreQueryable) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error) {
+	if s := q.State(); s != services.Running {
+		return nil, errors.Errorf("BlocksStoreQueryable is not running: %v", s)
+	}
+
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.limits.MaxMetadataPerBlock(userID) <= 0 {
+		return nil, nil
+	}
+
+	return q.newBlocksStoreQuerier(ctx, userID, 0, util.TimeToMillis(time.Now())).metricsMetadata()
+}
+
+// BlocksStats returns the statistics of the tenant's blocks in the long-term storage, excluding the blocks which
+// are not queried anymore because marked for deletion.
+func (q *BlocksStoreQueryable) BlocksStats(ctx context.Context, userID string) (BlocksStats, error) {
+	if s := q.State(); s != services.Running {
+		return BlocksStats{}, errors.Errorf("BlocksStoreQueryable is not running: %v", s)
+	}
+
+	blocks, _, err := q.finder.GetBlocks(ctx, userID, 0, stdmath.MaxInt64)
+	if err != nil {
+		return BlocksStats{}, err
+	}
+
+	stats := BlocksStats{NumBlocks: len(blocks)}
+	for i, b := range blocks {
+		stats.SizeBytes += b.SizeBytes
+		if i == 0 || b.MinTime < stats.MinTime {
+			stats.MinTime = b.MinTime
+		}
+		if i == 0 || b.MaxTime > stats.MaxTime {
+			stats.MaxTime = b.MaxTime
+		}
+	}
+	return stats, nil
+}
+
+// ExemplarQuerier returns a new ExemplarQuerier querying the exemplars persisted in the tenant's blocks, fetched from
+// the store-gateways.
+func (q *BlocksStoreQueryable) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	if s := q.State(); s != services.Running {
+		return nil, errors.Errorf("BlocksStoreQueryable is not running: %v", s)
+	}
+
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blocksStoreExemplarQuerier{ctx: ctx, userID: userID, queryable: q}, nil
+}
+
+type blocksStoreExemplarQuerier struct {
+	ctx       context.Context
+	userID    string
+	queryable *BlocksStoreQueryable
+}
+
+// Select implements storage.ExemplarQuerier. The store-gateways aren't queried if the persistence of the exemplars
+// is disabled for the tenant.
+func (q *blocksStoreExemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	if q.queryable.limits.MaxExemplarsPerBlock(q.userID) <= 0 {
+		return nil, nil
+	}
+
+	return q.queryable.newBlocksStoreQuerier(q.ctx, q.userID, start, end).exemplars(matchers)
+}
+
+func (q *BlocksStoreQueryable) newBlocksStoreQuerier(ctx context.Context, userID string, mint, maxt int64) *blocksStoreQuerier {
 	return &blocksStoreQuerier{
 		ctx:             ctx,
 		minT:            mint,
@@ -322,7 +457,12 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		consistency:     q.consistency,
 		logger:          q.logger,
 		queryStoreAfter: q.queryStoreAfter,
-	}, nil
+		lookbackDelta:   q.lookbackDelta,
+
+		degradedReadModeEnabled: q.degradedReadModeEnabled,
+		partialResultsEnabled:   q.partialResultsEnabled,
+		hedging:                 q.hedging,
+	}
 }
 
 type blocksStoreQuerier struct {
@@ -339,6 +479,21 @@ type blocksStoreQuerier struct {
 	// If set, the querier manipulates the max time to not be greater than
 	// "now - queryStoreAfter" so that most recent blocks are not queried.
 	queryStoreAfter time.Duration
+
+	// The lookback delta of the PromQL engine, which bounds the resolution of the downsampled data read by the
+	// instant selectors.
+	lookbackDelta time.Duration
+
+	// If enabled, the Select(), LabelNames() and LabelValues() return a warning instead of an error when the blocks
+	// storage or the store-gateways are unavailable.
+	degradedReadModeEnabled bool
+
+	// If enabled, the queries return the partial results, with a warning, instead of failing the consistency check
+	// when the non-queried blocks were only attempted on the store-gateways of a single zone or on a single store-gateway.
+	partialResultsEnabled bool
+
+	// If set, the series requests to the slow store-gateways are hedged to the other store-gateways holding the blocks.
+	hedging *storeGatewayHedging
 }
 
 // Select implements storage.Querier interface.
@@ -381,12 +536,105 @@ func (q *blocksStoreQuerier) LabelNames(matchers ...*labels.Matcher) ([]string,
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
+	partialWarnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, stdmath.MaxInt64, queryFunc)
+	if warnings, ok := q.degradedReadWarnings(spanLog, err, minT, maxT); ok {
+		return nil, warnings, nil
+	}
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return strutil.MergeSlices(resNameSets...), resWarnings, nil
+	return strutil.MergeSlices(resNameSets...), append(resWarnings, partialWarnings...), nil
+}
+
+// metricsMetadata returns the metric metadata persisted in the blocks queried by the querier.
+func (q *blocksStoreQuerier) metricsMetadata() ([]scrape.MetricMetadata, error) {
+	spanLog, spanCtx := spanlogger.NewWithLogger(q.ctx, q.logger, "blocksStoreQuerier.metricsMetadata")
+	defer spanLog.Span.Finish()
+
+	resMetadataSets := [][]mimirpb.MetricMetadata{}
+
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+		metadataSets, queriedBlocks, err := q.fetchMetricsMetadataFromStore(spanCtx, clients)
+		if err != nil {
+			return nil, err
+		}
+
+		resMetadataSets = append(resMetadataSets, metadataSets...)
+
+		return queriedBlocks, nil
+	}
+
+	// The metadata is best-effort, so the metadata fetched from the store-gateways which didn't fail is returned,
+	// unless the request has been canceled. The metadata API reports the warnings collected in the context.
+	warnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, q.minT, q.maxT, nil, stdmath.MaxInt64, queryFunc)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+
+		level.Warn(util_log.WithContext(q.ctx, spanLog)).Log("msg", "serving partial metric metadata because it couldn't be fetched from some store-gateways", "err", err)
+		warnings = append(warnings, errors.Wrap(err, "the metric metadata may be incomplete"))
+	}
+	collector := querywarnings.FromContext(q.ctx)
+	for _, w := range warnings {
+		collector.Add(w)
+	}
+
+	merged := mimir_tsdb.MergeMetricMetadata(0, resMetadataSets...)
+	result := make([]scrape.MetricMetadata, 0, len(merged))
+	for _, m := range merged {
+		result = append(result, scrape.MetricMetadata{
+			Metric: m.MetricFamilyName,
+			Help:   m.Help,
+			Unit:   m.Unit,
+			Type:   mimirpb.MetricMetadataMetricTypeToMetricType(m.Type),
+		})
+	}
+	return result, nil
+}
+
+// exemplars returns the exemplars persisted in the blocks queried by the querier, matching any of the input matchers sets.
+func (q *blocksStoreQuerier) exemplars(matchersSets [][]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	spanLog, spanCtx := spanlogger.NewWithLogger(q.ctx, q.logger, "blocksStoreQuerier.exemplars")
+	defer spanLog.Span.Finish()
+
+	level.Debug(spanLog).Log("start", util.TimeFromMillis(q.minT).UTC().String(), "end",
+		util.TimeFromMillis(q.maxT).UTC().String(), "matchers", util.MultiMatchersStringer(matchersSets))
+
+	selectors := make([]string, 0, len(matchersSets))
+	for _, matchers := range matchersSets {
+		selectors = append(selectors, util.LabelMatchersToString(matchers))
+	}
+
+	resExemplarSets := [][]mimirpb.TimeSeries{}
+
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+		exemplarSets, queriedBlocks, err := q.fetchExemplarsFromStore(spanCtx, clients, minT, maxT, selectors)
+		if err != nil {
+			return nil, err
+		}
+
+		resExemplarSets = append(resExemplarSets, exemplarSets...)
+
+		return queriedBlocks, nil
+	}
+
+	// The exemplars API doesn't support warnings, so the partial results are returned without them.
+	_, err := q.queryWithConsistencyCheck(spanCtx, spanLog, q.minT, q.maxT, nil, stdmath.MaxInt64, queryFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := mimir_tsdb.MergeExemplars(0, resExemplarSets...)
+	result := make([]exemplar.QueryResult, 0, len(merged))
+	for _, s := range merged {
+		result = append(result, exemplar.QueryResult{
+			SeriesLabels: mimirpb.FromLabelAdaptersToLabels(s.Labels),
+			Exemplars:    mimirpb.FromExemplarProtosToExemplars(s.Exemplars),
+		})
+	}
+	return result, nil
 }
 
 func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
@@ -422,12 +670,15 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
+	partialWarnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, stdmath.MaxInt64, queryFunc)
+	if warnings, ok := q.degradedReadWarnings(spanLog, err, minT, maxT); ok {
+		return nil, warnings, nil
+	}
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return strutil.MergeSlices(resValueSets...), resWarnings, nil
+	return strutil.MergeSlices(resValueSets...), append(resWarnings, partialWarnings...), nil
 }
 
 func (q *blocksStoreQuerier) Close() error {
@@ -454,8 +705,11 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return storage.ErrSeriesSet(err)
 	}
 
+	maxResolution := q.maxResolution(sp)
+	level.Debug(spanLog).Log("msg", "max resolution of the blocks to query", "resolution", maxResolution)
+
 	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
-		seriesSets, queriedBlocks, warnings, numChunks, err := q.fetchSeriesFromStores(spanCtx, sp, clients, minT, maxT, matchers, convertedMatchers, maxChunksLimit, leftChunksLimit)
+		seriesSets, queriedBlocks, warnings, numChunks, err := q.fetchSeriesFromStores(spanCtx, sp, clients, minT, maxT, maxResolution, matchers, convertedMatchers, maxChunksLimit, leftChunksLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -472,10 +726,14 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return queriedBlocks, nil
 	}
 
-	err = q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, queryFunc)
+	partialWarnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, maxResolution, queryFunc)
+	if warnings, ok := q.degradedReadWarnings(spanLog, err, minT, maxT); ok {
+		return series.NewSeriesSetWithWarnings(storage.EmptySeriesSet(), warnings)
+	}
 	if err != nil {
 		return storage.ErrSeriesSet(err)
 	}
+	resWarnings = append(resWarnings, partialWarnings...)
 
 	if len(resSeriesSets) == 0 {
 		storage.EmptySeriesSet()
@@ -486,8 +744,47 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		resWarnings)
 }
 
-func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector,
-	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) error {
+// maxResolution returns the max resolution, in milliseconds, of the downsampled blocks read by the select, or 0 to
+// only read the raw blocks. The resolution is set by the max_source_resolution parameter of the query if any, or
+// chosen from the select hints if automatic downsampling is enabled for the tenant. The selects counting the samples
+// only read the raw data, because the samples of the downsampled data don't match the raw ones.
+func (q *blocksStoreQuerier) maxResolution(sp *storage.SelectHints) int64 {
+	if sp != nil && sp.Func == "count_over_time" {
+		return 0
+	}
+
+	resolution, ok := downsampling.MaxSourceResolutionFromContext(q.ctx)
+	if ok && !resolution.Auto {
+		return resolution.Resolution
+	}
+	if !ok && !q.limits.AutoDownsamplingEnabled(q.userID) {
+		return 0
+	}
+	return autoMaxResolution(sp, q.lookbackDelta)
+}
+
+// autoMaxResolution returns the max resolution, in milliseconds, of the downsampled data which doesn't change the
+// result of the select noticeably: a fifth of the query step and of the range of a range selector, and not bigger
+// than the lookback delta for an instant selector, so that each evaluation still finds a sample.
+func autoMaxResolution(sp *storage.SelectHints, lookbackDelta time.Duration) int64 {
+	if sp == nil {
+		return 0
+	}
+
+	resolution := int64(stdmath.MaxInt64)
+	if sp.Step > 0 {
+		resolution = sp.Step / 5
+	}
+	if sp.Range > 0 {
+		resolution = math.Min64(resolution, sp.Range/5)
+	} else {
+		resolution = math.Min64(resolution, lookbackDelta.Milliseconds())
+	}
+	return math.Max64(resolution, 0)
+}
+
+func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector, maxResolution int64,
+	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) (storage.Warnings, error) {
 	// If queryStoreAfter is enabled, we do manipulate the query maxt to query samples up until
 	// now - queryStoreAfter, because the most recent time range is covered by ingesters. This
 	// optimization is particularly important for the blocks storage because can be used to skip
@@ -504,20 +801,20 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		if maxT < minT {
 			q.metrics.storesHit.Observe(0)
 			level.Debug(logger).Log("msg", "empty query time range after max time manipulation")
-			return nil
+			return nil, nil
 		}
 	}
 
 	// Find the list of blocks we need to query given the time range.
 	knownBlocks, knownDeletionMarks, err := q.finder.GetBlocks(ctx, q.userID, minT, maxT)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(knownBlocks) == 0 {
 		q.metrics.storesHit.Observe(0)
 		level.Debug(logger).Log("msg", "no blocks found")
-		return nil
+		return nil, nil
 	}
 
 	q.metrics.blocksFound.Add(float64(len(knownBlocks)))
@@ -533,6 +830,11 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		knownBlocks = result
 	}
 
+	if result := filterBlocksByResolution(knownBlocks, minT, maxT, maxResolution); len(result) != len(knownBlocks) {
+		level.Debug(logger).Log("msg", "filtered blocks by resolution", "before", len(knownBlocks), "after", len(result), "maxResolution", maxResolution)
+		knownBlocks = result
+	}
+
 	q.metrics.blocksQueried.Add(float64(len(knownBlocks)))
 
 	level.Debug(logger).Log("msg", "found blocks to query", "expected", knownBlocks.String())
@@ -558,7 +860,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 				break
 			}
 
-			return err
+			return nil, err
 		}
 		level.Debug(logger).Log("msg", "found store-gateway instances to query", "num instances", len(clients), "attempt", attempt)
 
@@ -566,7 +868,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		// are only meant to cover missing blocks.
 		queriedBlocks, err := queryFunc(clients, minT, maxT)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		level.Debug(logger).Log("msg", "received series from all store-gateways", "queried blocks", strings.Join(convertULIDsToString(queriedBlocks), " "))
 
@@ -587,7 +889,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 			q.metrics.storesHit.Observe(float64(len(touchedStores)))
 			q.metrics.refetches.Observe(float64(attempt - 1))
 
-			return nil
+			return nil, nil
 		}
 
 		level.Debug(logger).Log("msg", "consistency check failed", "attempt", attempt, "missing blocks", strings.Join(convertULIDsToString(missingBlocks), " "))
@@ -597,14 +899,146 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 	}
 
 	// We've not been able to query all expected blocks after all retries.
+	if warnings, ok := q.partialResultsWarnings(ctx, logger, remainingBlocks, attemptedBlocks, minT, maxT); ok {
+		return warnings, nil
+	}
+
 	level.Warn(util_log.WithContext(ctx, logger)).Log("msg", "failed consistency check", "err", err)
-	return newStoreConsistencyCheckFailedError(remainingBlocks)
+	return nil, newStoreConsistencyCheckFailedError(remainingBlocks)
+}
+
+// partialResultsWarnings returns the warnings to return instead of failing the consistency check, and true, if the
+// partial results are enabled and the missing blocks were only attempted on the store-gateways of a single zone, or
+// on a single store-gateway if the zones are unknown. The partial results enabled for the tenant on zone outages only
+// cover the former.
+func (q *blocksStoreQuerier) partialResultsWarnings(ctx context.Context, logger log.Logger, missingBlocks []ulid.ULID, attemptedBlocks map[ulid.ULID][]string, minT, maxT int64) (storage.Warnings, bool) {
+	if !q.partialResultsEnabled && !q.limits.ZoneOutagePartialResultsEnabled(q.userID) {
+		return nil, false
+	}
+
+	addrs := map[string]struct{}{}
+	zones := map[string]struct{}{}
+	for _, blockID := range missingBlocks {
+		for _, addr := range attemptedBlocks[blockID] {
+			addrs[addr] = struct{}{}
+			zones[q.stores.InstanceZone(addr)] = struct{}{}
+		}
+	}
+
+	var unavailable string
+	if _, unknownZone := zones[""]; !unknownZone && len(zones) == 1 {
+		for zone := range zones {
+			unavailable = fmt.Sprintf("the store-gateways of the zone %s", zone)
+		}
+	} else if len(addrs) == 1 && q.partialResultsEnabled {
+		for addr := range addrs {
+			unavailable = fmt.Sprintf("the store-gateway %s", addr)
+		}
+	} else {
+		return nil, false
+	}
+
+	level.Warn(util_log.WithContext(ctx, logger)).Log("msg", "serving the read request with partial results because some blocks couldn't be queried", "unavailable", unavailable, "missing blocks", strings.Join(convertULIDsToString(missingBlocks), " "))
+	q.metrics.partialResults.Inc()
+
+	return storage.Warnings{fmt.Errorf("some blocks couldn't be queried because %s failed to return them, the results between %s and %s may be incomplete. The non-queried blocks are: %s",
+		unavailable, util.TimeFromMillis(minT).UTC().Format(time.RFC3339), util.TimeFromMillis(maxT).UTC().Format(time.RFC3339), strings.Join(convertULIDsToString(missingBlocks), " "))}, true
+}
+
+// degradedReadWarnings returns the warnings to return instead of the input error, and true, if the degraded read mode is
+// enabled and the error was caused by the blocks storage or the store-gateways being unavailable. The errors caused by
+// the query limits or by the canceled requests are returned as is.
+func (q *blocksStoreQuerier) degradedReadWarnings(logger log.Logger, err error, minT, maxT int64) (storage.Warnings, bool) {
+	if err == nil || !q.degradedReadModeEnabled {
+		return nil, false
+	}
+
+	var limitErr validation.LimitError
+	if errors.As(err, &limitErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, false
+	}
+
+	// The most recent time range isn't queried from the blocks storage, so it's not affected.
+	if q.queryStoreAfter > 0 {
+		maxT = math.Min64(maxT, util.TimeToMillis(time.Now().Add(-q.queryStoreAfter)))
+	}
+
+	level.Warn(util_log.WithContext(q.ctx, logger)).Log("msg", "serving the read request without the blocks storage data because it's unavailable", "err", err)
+	q.metrics.degradedReads.Inc()
+
+	return storage.Warnings{fmt.Errorf("the blocks storage is unavailable, the results between %s and %s may be incomplete",
+		util.TimeFromMillis(minT).UTC().Format(time.RFC3339), util.TimeFromMillis(maxT).UTC().Format(time.RFC3339))}, true
 }
 
 func newStoreConsistencyCheckFailedError(remainingBlocks []ulid.ULID) error {
 	return fmt.Errorf("%v. The non-queried blocks are: %s", globalerror.StoreConsistencyCheckFailed.Message("the consistency check failed because some blocks were not queried"), strings.Join(convertULIDsToString(remainingBlocks), " "))
 }
 
+// filterBlocksByResolution returns the blocks covering the time range with the biggest resolution not bigger than the
+// max resolution, like the store-gateway does. The time ranges not covered by the blocks of that resolution are
+// covered by the blocks of the lower resolutions, down to the raw blocks. The blocks keep their input order.
+func filterBlocksByResolution(blocks bucketindex.Blocks, minT, maxT, maxResolution int64) bucketindex.Blocks {
+	downsampled := false
+	for _, b := range blocks {
+		if b.Resolution != 0 {
+			downsampled = true
+			break
+		}
+	}
+	if !downsampled {
+		return blocks
+	}
+
+	selected := map[ulid.ULID]struct{}{}
+	selectBlocksByResolution(blocks, minT, maxT, maxResolution, selected)
+
+	result := make(bucketindex.Blocks, 0, len(selected))
+	for _, b := range blocks {
+		if _, ok := selected[b.ID]; ok {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// The resolutions of the blocks, from the lowest to the highest.
+var blockResolutions = []int64{downsample.ResLevel2, downsample.ResLevel1, downsample.ResLevel0}
+
+func selectBlocksByResolution(blocks bucketindex.Blocks, minT, maxT, maxResolution int64, selected map[ulid.ULID]struct{}) {
+	if minT > maxT {
+		return
+	}
+
+	// Find the biggest resolution not bigger than the max resolution.
+	i := 0
+	for i < len(blockResolutions)-1 && blockResolutions[i] > maxResolution {
+		i++
+	}
+
+	var candidates bucketindex.Blocks
+	for _, b := range blocks {
+		if b.Resolution == blockResolutions[i] && b.MinTime <= maxT && b.MaxTime > minT {
+			candidates = append(candidates, b)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].MinTime < candidates[j].MinTime
+	})
+
+	// Fill the gaps between the blocks of the resolution with the blocks of the higher resolutions.
+	start := minT
+	for _, b := range candidates {
+		if i+1 < len(blockResolutions) && b.MinTime > start {
+			selectBlocksByResolution(blocks, start, b.MinTime-1, blockResolutions[i+1], selected)
+		}
+		selected[b.ID] = struct{}{}
+		start = math.Max64(start, b.MaxTime)
+	}
+	if i+1 < len(blockResolutions) {
+		selectBlocksByResolution(blocks, start, maxT, blockResolutions[i+1], selected)
+	}
+}
+
 // filterBlocksByShard removes blocks that can be safely ignored when using query sharding. We know that block can be safely
 // ignored, if it was compacted using split-and-merge compactor, and it has a valid compactor shard ID. We exploit the
 // fact that split-and-merge compactor and query-sharding use the same series-sharding algorithm.
@@ -684,6 +1118,7 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 	clients map[BlocksStoreClient][]ulid.ULID,
 	minT int64,
 	maxT int64,
+	maxResolution int64,
 	matchers []*labels.Matcher,
 	convertedMatchers []storepb.LabelMatcher,
 	maxChunksLimit int,
@@ -699,9 +1134,16 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 		numChunks     = atomic.NewInt32(0)
 		spanLog       = spanlogger.FromContext(ctx, q.logger)
 		queryLimiter  = limiter.QueryLimiterFromContextWithFallback(ctx)
+		memoryTracker = limiter.MemoryConsumptionTrackerFromContextWithFallback(ctx)
 		reqStats      = stats.FromContext(ctx)
 	)
 
+	// The aggregates to read from the downsampled blocks, if any.
+	var aggrs []storepb.Aggr
+	if maxResolution > 0 && sp != nil {
+		aggrs = aggrsFromFunc(sp.Func)
+	}
+
 	// Concurrently fetch series from all clients.
 	for c, blockIDs := range clients {
 		// Change variables scope since it will be used in a goroutine.
@@ -715,41 +1157,16 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 			// But this is an acceptable workaround for now.
 			skipChunks := sp != nil && sp.Func == "series"
 
-			req, err := createSeriesRequest(minT, maxT, convertedMatchers, skipChunks, blockIDs)
-			if err != nil {
-				return errors.Wrapf(err, "failed to create series request")
-			}
-
-			stream, err := c.Series(gCtx, req)
-			if err != nil {
-				level.Warn(spanLog).Log("msg", "failed to fetch series", "remote", c.RemoteAddress(), "err", err)
-				return nil
-			}
-
-			mySeries := []*storepb.Series(nil)
-			myWarnings := storage.Warnings(nil)
-			myQueriedBlocks := []ulid.ULID(nil)
-
-			for {
-				// Ensure the context hasn't been canceled in the meanwhile (eg. an error occurred
-				// in another goroutine).
-				if gCtx.Err() != nil {
-					return gCtx.Err()
-				}
-
-				resp, err := stream.Recv()
-				if err == io.EOF {
-					break
-				}
+			newRequest := func(blockIDs []ulid.ULID) (*storepb.SeriesRequest, error) {
+				req, err := createSeriesRequest(minT, maxT, convertedMatchers, skipChunks, blockIDs, maxResolution, aggrs)
 				if err != nil {
-					level.Warn(spanLog).Log("msg", "failed to receive series", "remote", c.RemoteAddress(), "err", err)
-					return nil
+					return nil, errors.Wrapf(err, "failed to create series request")
 				}
+				return req, nil
+			}
 
-				// Response may either contain series, warning or hints.
-				if s := resp.GetSeries(); s != nil {
-					mySeries = append(mySeries, s)
-
+			newCheckLimits := func(queryLimiter *limiter.QueryLimiter, memoryTracker *limiter.MemoryConsumptionTracker, numChunks *atomic.Int32) func(*storepb.Series) error {
+				return func(s *storepb.Series) error {
 					// Add series fingerprint to query limiter; will return error if we are over the limit
 					limitErr := queryLimiter.AddSeries(mimirpb.FromLabelsToLabelAdapters(s.PromLabels()))
 					if limitErr != nil {
@@ -768,51 +1185,70 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 					if chunkBytesLimitErr := queryLimiter.AddChunkBytes(chunksSize); chunkBytesLimitErr != nil {
 						return validation.LimitError(chunkBytesLimitErr.Error())
 					}
+					if memoryLimitErr := memoryTracker.IncreaseMemoryConsumption(uint64(chunksSize)); memoryLimitErr != nil {
+						return validation.LimitError(memoryLimitErr.Error())
+					}
 					if chunkLimitErr := queryLimiter.AddChunks(len(s.Chunks)); chunkLimitErr != nil {
 						return validation.LimitError(chunkLimitErr.Error())
 					}
+					return nil
 				}
+			}
+			checkLimits := newCheckLimits(queryLimiter, memoryTracker, numChunks)
 
-				if w := resp.GetWarning(); w != "" {
-					myWarnings = append(myWarnings, errors.New(w))
-				}
-
-				if h := resp.GetHints(); h != nil {
-					hints := hintspb.SeriesResponseHints{}
-					if err := types.UnmarshalAny(h, &hints); err != nil {
-						return errors.Wrapf(err, "failed to unmarshal series hints from %s", c.RemoteAddress())
-					}
-
-					ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
-					if err != nil {
-						return errors.Wrapf(err, "failed to parse queried block IDs from received hints")
-					}
+			// The series received by each attempt of a hedged request are checked against the limits while
+			// streaming, without being accounted to the query: only the series of the winning attempt are.
+			newAttemptCheckLimits := func() func(*storepb.Series) error {
+				return newCheckLimits(queryLimiter.NewAttemptLimiter(), memoryTracker.NewAttemptTracker(), atomic.NewInt32(0))
+			}
 
-					myQueriedBlocks = append(myQueriedBlocks, ids...)
+			// The results are nil if the store-gateway failed to return the series: the blocks are retried on
+			// other store-gateways by the consistency check.
+			var results []seriesFetchResult
+			if q.hedging != nil {
+				var err error
+				if results, err = q.fetchSeriesFromStoreWithHedging(gCtx, spanLog, c, blockIDs, newRequest, checkLimits, newAttemptCheckLimits); err != nil {
+					return err
+				}
+			} else {
+				req, err := newRequest(blockIDs)
+				if err != nil {
+					return err
+				}
+				result, ok, err := fetchSeriesFromStore(gCtx, spanLog, c, blockIDs, req, checkLimits)
+				if err != nil {
+					return err
+				}
+				if ok {
+					results = []seriesFetchResult{result}
 				}
 			}
 
-			numSeries := len(mySeries)
-			chunksFetched, chunkBytes := countChunksAndBytes(mySeries...)
-
-			reqStats.AddFetchedSeries(uint64(numSeries))
-			reqStats.AddFetchedChunkBytes(uint64(chunkBytes))
-			reqStats.AddFetchedChunks(uint64(chunksFetched))
-
-			level.Debug(spanLog).Log("msg", "received series from store-gateway",
-				"instance", c.RemoteAddress(),
-				"fetched series", numSeries,
-				"fetched chunk bytes", chunkBytes,
-				"fetched chunks", chunksFetched,
-				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
-				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
-
-			// Store the result.
-			mtx.Lock()
-			seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: mySeries})
-			warnings = append(warnings, myWarnings...)
-			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
-			mtx.Unlock()
+			for _, result := range results {
+				numSeries := len(result.series)
+				chunksFetched, chunkBytes := countChunksAndBytes(result.series...)
+
+				reqStats.AddFetchedSeries(uint64(numSeries))
+				reqStats.AddFetchedChunkBytes(uint64(chunkBytes))
+				reqStats.AddFetchedChunks(uint64(chunksFetched))
+				reqStats.AddFetchedStoreGatewayChunks(uint64(chunksFetched))
+				reqStats.AddFetchedSamples(uint64(countSamples(result.series...)))
+
+				level.Debug(spanLog).Log("msg", "received series from store-gateway",
+					"instance", result.remoteAddress,
+					"fetched series", numSeries,
+					"fetched chunk bytes", chunkBytes,
+					"fetched chunks", chunksFetched,
+					"requested blocks", strings.Join(convertULIDsToString(result.requestedBlocks), " "),
+					"queried blocks", strings.Join(convertULIDsToString(result.queriedBlocks), " "))
+
+				// Store the result.
+				mtx.Lock()
+				seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: result.series, aggrs: aggrs})
+				warnings = append(warnings, result.warnings...)
+				queriedBlocks = append(queriedBlocks, result.queriedBlocks...)
+				mtx.Unlock()
+			}
 
 			return nil
 		})
@@ -826,6 +1262,94 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 	return seriesSets, queriedBlocks, warnings, int(numChunks.Load()), nil
 }
 
+// seriesFetchResult is the response of a store-gateway to a series request.
+type seriesFetchResult struct {
+	remoteAddress   string
+	requestedBlocks []ulid.ULID
+	series          []*storepb.Series
+	warnings        storage.Warnings
+	queriedBlocks   []ulid.ULID
+}
+
+// storeGatewayLimitError returns the limit error if the store-gateway failed the request because it exceeded one of
+// its limits, which are returned with the 422 status code, or nil otherwise.
+func storeGatewayLimitError(err error) error {
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Code(http.StatusUnprocessableEntity) {
+		return validation.LimitError(s.Message())
+	}
+	return nil
+}
+
+// fetchSeriesFromStore fetches the series from the store-gateway, calling onSeries, if not nil, for each received
+// series. It returns false if the store-gateway failed to return the series, or an error if the request must fail.
+func fetchSeriesFromStore(ctx context.Context, logger log.Logger, c BlocksStoreClient, blockIDs []ulid.ULID, req *storepb.SeriesRequest, onSeries func(*storepb.Series) error) (seriesFetchResult, bool, error) {
+	result := seriesFetchResult{remoteAddress: c.RemoteAddress(), requestedBlocks: blockIDs}
+
+	stream, err := c.Series(ctx, req)
+	if err != nil {
+		if limitErr := storeGatewayLimitError(err); limitErr != nil {
+			return result, false, limitErr
+		}
+
+		level.Warn(logger).Log("msg", "failed to fetch series", "remote", c.RemoteAddress(), "err", err)
+		return result, false, nil
+	}
+
+	for {
+		// Ensure the context hasn't been canceled in the meanwhile (eg. an error occurred
+		// in another goroutine).
+		if ctx.Err() != nil {
+			return result, false, ctx.Err()
+		}
+
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// The store-gateway limits are enforced on the tenant's request, so they would be exceeded by retrying
+			// the request on another store-gateway too.
+			if limitErr := storeGatewayLimitError(err); limitErr != nil {
+				return result, false, limitErr
+			}
+
+			level.Warn(logger).Log("msg", "failed to receive series", "remote", c.RemoteAddress(), "err", err)
+			return result, false, nil
+		}
+
+		// Response may either contain series, warning or hints.
+		if s := resp.GetSeries(); s != nil {
+			result.series = append(result.series, s)
+
+			if onSeries != nil {
+				if err := onSeries(s); err != nil {
+					return result, false, err
+				}
+			}
+		}
+
+		if w := resp.GetWarning(); w != "" {
+			result.warnings = append(result.warnings, errors.New(w))
+		}
+
+		if h := resp.GetHints(); h != nil {
+			hints := hintspb.SeriesResponseHints{}
+			if err := types.UnmarshalAny(h, &hints); err != nil {
+				return result, false, errors.Wrapf(err, "failed to unmarshal series hints from %s", c.RemoteAddress())
+			}
+
+			ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
+			if err != nil {
+				return result, false, errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+			}
+
+			result.queriedBlocks = append(result.queriedBlocks, ids...)
+		}
+	}
+
+	return result, true, nil
+}
+
 func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 	ctx context.Context,
 	clients map[BlocksStoreClient][]ulid.ULID,
@@ -903,6 +1427,144 @@ func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 	return nameSets, warnings, queriedBlocks, nil
 }
 
+func (q *blocksStoreQuerier) fetchMetricsMetadataFromStore(
+	ctx context.Context,
+	clients map[BlocksStoreClient][]ulid.ULID,
+) ([][]mimirpb.MetricMetadata, []ulid.ULID, error) {
+	var (
+		reqCtx        = grpc_metadata.AppendToOutgoingContext(ctx, storegateway.GrpcContextMetadataTenantID, q.userID)
+		g, gCtx       = errgroup.WithContext(reqCtx)
+		mtx           = sync.Mutex{}
+		metadataSets  = [][]mimirpb.MetricMetadata{}
+		queriedBlocks = []ulid.ULID(nil)
+		spanLog       = spanlogger.FromContext(ctx, q.logger)
+	)
+
+	// Concurrently fetch metadata from all clients.
+	for c, blockIDs := range clients {
+		// Change variables scope since it will be used in a goroutine.
+		c := c
+		blockIDs := blockIDs
+
+		g.Go(func() error {
+			req := &storegatewaypb.MetricsMetadataRequest{BlockIds: convertULIDsToString(blockIDs)}
+
+			metadataResp, err := c.MetricsMetadata(gCtx, req)
+			if err != nil {
+				level.Warn(spanLog).Log("msg", "failed to fetch metric metadata", "remote", c.RemoteAddress(), "err", err)
+				return nil
+			}
+
+			// The metadata of a store-gateway returning invalid block IDs is skipped, and its blocks are retried on
+			// the other store-gateways, like for the store-gateways failing to respond.
+			myQueriedBlocks := make([]ulid.ULID, 0, len(metadataResp.QueriedBlocks))
+			for _, id := range metadataResp.QueriedBlocks {
+				blockID, err := ulid.Parse(id)
+				if err != nil {
+					level.Warn(spanLog).Log("msg", "failed to parse queried block IDs of the metric metadata", "remote", c.RemoteAddress(), "err", err)
+					return nil
+				}
+				myQueriedBlocks = append(myQueriedBlocks, blockID)
+			}
+
+			metadata := make([]mimirpb.MetricMetadata, 0, len(metadataResp.Metadata))
+			for _, m := range metadataResp.Metadata {
+				metadata = append(metadata, *m)
+			}
+
+			level.Debug(spanLog).Log("msg", "received metric metadata from store-gateway",
+				"instance", c,
+				"num metadata", len(metadata),
+				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
+				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
+
+			// Store the result.
+			mtx.Lock()
+			metadataSets = append(metadataSets, metadata)
+			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
+			mtx.Unlock()
+
+			return nil
+		})
+	}
+
+	// Wait until all client requests complete.
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return metadataSets, queriedBlocks, nil
+}
+
+func (q *blocksStoreQuerier) fetchExemplarsFromStore(
+	ctx context.Context,
+	clients map[BlocksStoreClient][]ulid.ULID,
+	minT int64,
+	maxT int64,
+	selectors []string,
+) ([][]mimirpb.TimeSeries, []ulid.ULID, error) {
+	var (
+		reqCtx        = grpc_metadata.AppendToOutgoingContext(ctx, storegateway.GrpcContextMetadataTenantID, q.userID)
+		g, gCtx       = errgroup.WithContext(reqCtx)
+		mtx           = sync.Mutex{}
+		exemplarSets  = [][]mimirpb.TimeSeries{}
+		queriedBlocks = []ulid.ULID(nil)
+		spanLog       = spanlogger.FromContext(ctx, q.logger)
+	)
+
+	// Concurrently fetch exemplars from all clients.
+	for c, blockIDs := range clients {
+		// Change variables scope since it will be used in a goroutine.
+		c := c
+		blockIDs := blockIDs
+
+		g.Go(func() error {
+			req := &storegatewaypb.ExemplarsRequest{
+				BlockIds: convertULIDsToString(blockIDs),
+				Start:    minT,
+				End:      maxT,
+				Matchers: selectors,
+			}
+
+			exemplarsResp, err := c.Exemplars(gCtx, req)
+			if err != nil {
+				level.Warn(spanLog).Log("msg", "failed to fetch exemplars", "remote", c.RemoteAddress(), "err", err)
+				return nil
+			}
+
+			myQueriedBlocks := make([]ulid.ULID, 0, len(exemplarsResp.QueriedBlocks))
+			for _, id := range exemplarsResp.QueriedBlocks {
+				blockID, err := ulid.Parse(id)
+				if err != nil {
+					return errors.Wrapf(err, "failed to parse queried block IDs from %s", c.RemoteAddress())
+				}
+				myQueriedBlocks = append(myQueriedBlocks, blockID)
+			}
+
+			level.Debug(spanLog).Log("msg", "received exemplars from store-gateway",
+				"instance", c,
+				"num series", len(exemplarsResp.Timeseries),
+				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
+				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
+
+			// Store the result.
+			mtx.Lock()
+			exemplarSets = append(exemplarSets, exemplarsResp.Timeseries)
+			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
+			mtx.Unlock()
+
+			return nil
+		})
+	}
+
+	// Wait until all client requests complete.
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return exemplarSets, queriedBlocks, nil
+}
+
 func (q *blocksStoreQuerier) fetchLabelValuesFromStore(
 	ctx context.Context,
 	name string,
@@ -984,7 +1646,25 @@ func (q *blocksStoreQuerier) fetchLabelValuesFromStore(
 	return valueSets, warnings, queriedBlocks, nil
 }
 
-func createSeriesRequest(minT, maxT int64, matchers []storepb.LabelMatcher, skipChunks bool, blockIDs []ulid.ULID) (*storepb.SeriesRequest, error) {
+// aggrsFromFunc returns the aggregates of the downsampled chunks to read for the function applied to the selector.
+func aggrsFromFunc(f string) []storepb.Aggr {
+	switch {
+	case f == "min" || strings.HasPrefix(f, "min_"):
+		return []storepb.Aggr{storepb.Aggr_MIN}
+	case f == "max" || strings.HasPrefix(f, "max_"):
+		return []storepb.Aggr{storepb.Aggr_MAX}
+	case strings.HasPrefix(f, "sum_"):
+		return []storepb.Aggr{storepb.Aggr_SUM}
+	case f == "rate" || f == "increase" || f == "irate" || f == "resets":
+		return []storepb.Aggr{storepb.Aggr_COUNTER}
+	default:
+		// The other functions, including the sum and count aggregations, are applied to the average of the samples.
+		// The count aggregate isn't read as samples: count_over_time() only reads the raw data, see maxResolution().
+		return []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM}
+	}
+}
+
+func createSeriesRequest(minT, maxT int64, matchers []storepb.LabelMatcher, skipChunks bool, blockIDs []ulid.ULID, maxResolution int64, aggrs []storepb.Aggr) (*storepb.SeriesRequest, error) {
 	// Selectively query only specific blocks.
 	hints := &hintspb.SeriesRequestHints{
 		BlockMatchers: []storepb.LabelMatcher{
@@ -1008,6 +1688,8 @@ func createSeriesRequest(minT, maxT int64, matchers []storepb.LabelMatcher, skip
 		PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
 		Hints:                   anyHints,
 		SkipChunks:              skipChunks,
+		MaxResolutionWindow:     maxResolution,
+		Aggregates:              aggrs,
 	}, nil
 }
 
@@ -1102,3 +1784,20 @@ func countChunksAndBytes(series ...*storepb.Series) (chunks, bytes int) {
 
 	return chunks, bytes
 }
+
+// countSamples returns the number of samples of the XOR-encoded chunks making up the provided series, read from
+// the chunks header.
+func countSamples(series ...*storepb.Series) (samples int) {
+	for _, s := range series {
+		for _, c := range s.Chunks {
+			if c.Raw == nil || c.Raw.Type != storepb.Chunk_XOR || len(c.Raw.Data) < 2 {
+				continue
+			}
+			if xor, err := chunkenc.FromData(chunkenc.EncXOR, c.Raw.Data); err == nil {
+				samples += xor.NumSamples()
+			}
+		}
+	}
+
+	return samples
+}
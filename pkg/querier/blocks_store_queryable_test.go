@@ -11,6 +11,9 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
+	"net/http"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -18,26 +21,44 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/gogo/protobuf/types"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/grpcclient"
 	"github.com/grafana/dskit/services"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
 	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/weaveworks/common/logging"
 	"github.com/weaveworks/common/user"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/downsampling"
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
 	"github.com/grafana/mimir/pkg/storage/sharding"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bloom"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/storegateway/testhelper"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/limiter"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -746,6 +767,29 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 					cortex_querier_storegateway_refetches_per_query_count 1
 			`,
 		},
+		"a store-gateway fails the request because it exceeded one of the store-gateway limits": {
+			finderResult: bucketindex.Blocks{
+				{ID: block1},
+			},
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{
+						remoteAddr:            "1.1.1.1",
+						mockedSeriesStreamErr: status.Error(http.StatusUnprocessableEntity, "exceeded chunks bytes limit: limit 10 violated (got 20)"),
+					}: {block1},
+				},
+				// The request isn't retried on another store-gateway.
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(labels.Labels{metricNameLabel, series1Label}, minT, 2),
+						mockHintsResponse(block1),
+					}}: {block1},
+				},
+			},
+			limits:       &blocksStoreLimitsMock{},
+			queryLimiter: noOpQueryLimiter,
+			expectedErr:  validation.LimitError("exceeded chunks bytes limit: limit 10 violated (got 20)"),
+		},
 		"multiple store-gateways have the block, but one of them fails to return": {
 			finderResult: bucketindex.Blocks{
 				{ID: block1},
@@ -890,6 +934,100 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 	}
 }
 
+func TestBlocksStoreQuerier_SelectShouldNotFailConsistencyCheckOnBlocksSkippedByBloomFilter(t *testing.T) {
+	const (
+		userID = "user-1"
+		minT   = int64(0)
+		maxT   = int64(100)
+	)
+
+	ctx := context.Background()
+	storageDir := t.TempDir()
+
+	// Create two blocks, with a bloom filter over the "job" label values, each one storing a different job.
+	var blocks bucketindex.Blocks
+	for _, job := range []string{"a", "b"} {
+		series := []labels.Labels{labels.FromStrings(labels.MetricName, "test_metric", "job", job)}
+		blockID, err := testhelper.CreateBlock(ctx, filepath.Join(storageDir, userID), series, 10, minT, maxT, nil, 0, metadata.NoneFunc)
+		require.NoError(t, err)
+
+		blockDir := filepath.Join(storageDir, userID, blockID.String())
+		filter, err := bloom.BuildFromIndex(filepath.Join(blockDir, block.IndexFilename), []string{"job"}, bloom.DefaultFalsePositiveRate)
+		require.NoError(t, err)
+		require.NoError(t, bloom.WriteFile(blockDir, filter))
+
+		blocks = append(blocks, &bucketindex.Block{ID: blockID, MinTime: minT, MaxTime: maxT})
+	}
+
+	// Run a store-gateway loading the blocks with their bloom filter.
+	storageCfg := mimir_tsdb.BlocksStorageConfig{}
+	flagext.DefaultValues(&storageCfg)
+	storageCfg.BucketStore.ConsistencyDelay = 0
+	storageCfg.BucketStore.BucketIndex.Enabled = false
+	storageCfg.BucketStore.IgnoreBlocksWithin = 0
+	storageCfg.BucketStore.SyncDir = t.TempDir()
+	storageCfg.BucketStore.BloomFilterEnabled = true
+
+	bucketClient, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), nil)
+	require.NoError(t, err)
+
+	logLevel := logging.Level{}
+	require.NoError(t, logLevel.Set("info"))
+
+	stores, err := storegateway.NewBucketStores(storageCfg, &noShardingStrategyMock{}, bucketClient, overrides, logLevel, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, stores.InitialSync(ctx))
+
+	grpcServer := grpc.NewServer()
+	t.Cleanup(grpcServer.GracefulStop)
+	storegatewaypb.RegisterStoreGatewayServer(grpcServer, &bucketStoresServer{stores: stores})
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+
+	clientCfg := grpcclient.Config{}
+	flagext.DefaultValues(&clientCfg)
+	client, err := newStoreGatewayClientFactory(clientCfg, nil)(listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, client.Close()) })
+
+	// The store-gateway is mocked to be the only one holding the blocks, so the query fails
+	// if any of them is retried because it's not reported as queried.
+	finder := &blocksFinderMock{}
+	finder.On("GetBlocks", mock.Anything, userID, minT, maxT).Return(blocks, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+	q := &blocksStoreQuerier{
+		ctx:    limiter.AddQueryLimiterToContext(user.InjectOrgID(ctx, userID), limiter.NewQueryLimiter(0, 0, 0)),
+		minT:   minT,
+		maxT:   maxT,
+		userID: userID,
+		finder: finder,
+		stores: &blocksStoreSetMock{mockedResponses: []interface{}{
+			map[BlocksStoreClient][]ulid.ULID{client.(BlocksStoreClient): {blocks[0].ID, blocks[1].ID}},
+			errors.New("the blocks have been retried"),
+		}},
+		consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+		logger:      log.NewNopLogger(),
+		metrics:     newBlocksStoreQueryableMetrics(nil),
+		limits:      &blocksStoreLimitsMock{},
+	}
+
+	set := q.Select(true, &storage.SelectHints{Start: minT, End: maxT}, labels.MustNewMatcher(labels.MatchEqual, "job", "b"))
+
+	var actualSeries []labels.Labels
+	for set.Next() {
+		actualSeries = append(actualSeries, set.At().Labels())
+	}
+	require.NoError(t, set.Err())
+	assert.Equal(t, []labels.Labels{labels.FromStrings(labels.MetricName, "test_metric", "job", "b")}, actualSeries)
+}
+
 func TestBlocksStoreQuerier_Labels(t *testing.T) {
 	const (
 		metricName = "test_metric"
@@ -1496,6 +1634,104 @@ func TestBlocksStoreQuerier_SelectSortedShouldHonorQueryStoreAfter(t *testing.T)
 	}
 }
 
+func TestBlocksStoreQuerier_DegradedReadMode(t *testing.T) {
+	var (
+		block1 = ulid.MustNew(1, nil)
+		minT   = util.TimeToMillis(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+		maxT   = util.TimeToMillis(time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC))
+		blocks = bucketindex.Blocks{{ID: block1, MinTime: minT, MaxTime: maxT}}
+	)
+
+	const expectedWarning = "the blocks storage is unavailable, the results between 2022-01-01T00:00:00Z and 2022-01-01T01:00:00Z may be incomplete"
+
+	tests := map[string]struct {
+		degradedReadModeEnabled bool
+		finderBlocks            bucketindex.Blocks
+		finderErr               error
+		storeSetErr             error
+		expectedErr             string
+		expectedWarning         bool
+	}{
+		"should fail if the degraded read mode is disabled and the bucket index can't be loaded": {
+			finderErr:   errors.New("failed to load bucket index"),
+			expectedErr: "failed to load bucket index",
+		},
+		"should return a warning if the degraded read mode is enabled and the bucket index can't be loaded": {
+			degradedReadModeEnabled: true,
+			finderErr:               errors.New("failed to load bucket index"),
+			expectedWarning:         true,
+		},
+		"should return a warning if the degraded read mode is enabled and no store-gateway is available": {
+			degradedReadModeEnabled: true,
+			finderBlocks:            blocks,
+			storeSetErr:             errors.New("no store-gateway instance left"),
+			expectedWarning:         true,
+		},
+		"should fail if the degraded read mode is enabled but a limit is exceeded": {
+			degradedReadModeEnabled: true,
+			finderBlocks:            blocks,
+			storeSetErr:             validation.LimitError("limit exceeded"),
+			expectedErr:             "limit exceeded",
+		},
+		"should fail if the degraded read mode is enabled but the request is canceled": {
+			degradedReadModeEnabled: true,
+			finderErr:               context.Canceled,
+			expectedErr:             context.Canceled.Error(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", mock.Anything, mock.Anything).Return(testData.finderBlocks, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), testData.finderErr)
+
+			stores := &blocksStoreSetMock{mockedResponses: []interface{}{testData.storeSetErr, testData.storeSetErr, testData.storeSetErr}}
+
+			q := &blocksStoreQuerier{
+				ctx:                     context.Background(),
+				minT:                    minT,
+				maxT:                    maxT,
+				userID:                  "user-1",
+				finder:                  finder,
+				stores:                  stores,
+				consistency:             NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:                  log.NewNopLogger(),
+				metrics:                 newBlocksStoreQueryableMetrics(nil),
+				limits:                  &blocksStoreLimitsMock{},
+				degradedReadModeEnabled: testData.degradedReadModeEnabled,
+			}
+
+			set := q.Select(true, &storage.SelectHints{Start: minT, End: maxT})
+			assert.False(t, set.Next())
+			names, namesWarnings, namesErr := q.LabelNames()
+			values, valuesWarnings, valuesErr := q.LabelValues(labels.MetricName)
+
+			if testData.expectedErr != "" {
+				require.Error(t, set.Err())
+				assert.Contains(t, set.Err().Error(), testData.expectedErr)
+				require.Error(t, namesErr)
+				assert.Contains(t, namesErr.Error(), testData.expectedErr)
+				require.Error(t, valuesErr)
+				assert.Contains(t, valuesErr.Error(), testData.expectedErr)
+				assert.Equal(t, float64(0), testutil.ToFloat64(q.metrics.degradedReads))
+				return
+			}
+
+			require.NoError(t, set.Err())
+			require.NoError(t, namesErr)
+			require.NoError(t, valuesErr)
+			assert.Empty(t, names)
+			assert.Empty(t, values)
+
+			for _, warnings := range []storage.Warnings{set.Warnings(), namesWarnings, valuesWarnings} {
+				require.Len(t, warnings, 1)
+				assert.EqualError(t, warnings[0], expectedWarning)
+			}
+			assert.Equal(t, float64(3), testutil.ToFloat64(q.metrics.degradedReads))
+		})
+	}
+}
+
 func TestBlocksStoreQuerier_MaxLabelsQueryRange(t *testing.T) {
 	const (
 		engineLookbackDelta = 5 * time.Minute
@@ -1569,6 +1805,332 @@ func TestBlocksStoreQuerier_MaxLabelsQueryRange(t *testing.T) {
 	}
 }
 
+func TestBlocksStoreQuerier_PartialResults(t *testing.T) {
+	var (
+		block1 = ulid.MustNew(1, nil)
+		block2 = ulid.MustNew(2, nil)
+		minT   = util.TimeToMillis(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+		maxT   = util.TimeToMillis(time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC))
+	)
+
+	// The first store-gateway only returns the first block, and the second one fails.
+	storeSetResponses := func(secondAttempt bool) []interface{} {
+		responses := []interface{}{
+			map[BlocksStoreClient][]ulid.ULID{
+				&storeGatewayClientMock{
+					remoteAddr: "1.1.1.1",
+					mockedLabelNamesResponse: &storepb.LabelNamesResponse{
+						Names: []string{"foo"},
+						Hints: mockNamesHints(block1),
+					},
+				}: {block1, block2},
+			},
+		}
+		if secondAttempt {
+			responses = append(responses, map[BlocksStoreClient][]ulid.ULID{
+				&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedLabelNamesErr: errors.New("failed to receive from store-gateway")}: {block2},
+			})
+		}
+		return append(responses, errors.New("no store-gateway instance left after checking exclude"))
+	}
+
+	tests := map[string]struct {
+		partialResultsEnabled    bool
+		zoneOutagePartialResults bool
+		secondAttempt            bool
+		instanceZones            map[string]string
+		expectedWarning          string
+	}{
+		"should fail if the partial results are disabled": {
+			secondAttempt: true,
+			instanceZones: map[string]string{"1.1.1.1": "zone-a", "2.2.2.2": "zone-a"},
+		},
+		"should return a warning if the missing blocks were only attempted on the store-gateways of a single zone": {
+			partialResultsEnabled: true,
+			secondAttempt:         true,
+			instanceZones:         map[string]string{"1.1.1.1": "zone-a", "2.2.2.2": "zone-a"},
+			expectedWarning:       "some blocks couldn't be queried because the store-gateways of the zone zone-a failed to return them, the results between 2022-01-01T00:00:00Z and 2022-01-01T01:00:00Z may be incomplete. The non-queried blocks are: " + block2.String(),
+		},
+		"should fail if the missing blocks were attempted on the store-gateways of multiple zones": {
+			partialResultsEnabled: true,
+			secondAttempt:         true,
+			instanceZones:         map[string]string{"1.1.1.1": "zone-a", "2.2.2.2": "zone-b"},
+		},
+		"should return a warning if the missing blocks were only attempted on a single store-gateway": {
+			partialResultsEnabled: true,
+			expectedWarning:       "some blocks couldn't be queried because the store-gateway 1.1.1.1 failed to return them, the results between 2022-01-01T00:00:00Z and 2022-01-01T01:00:00Z may be incomplete. The non-queried blocks are: " + block2.String(),
+		},
+		"should fail if the missing blocks were attempted on multiple store-gateways without a zone": {
+			partialResultsEnabled: true,
+			secondAttempt:         true,
+		},
+		"should return a warning if the tenant enabled the partial results on zone outages and the missing blocks were only attempted on the store-gateways of a single zone": {
+			zoneOutagePartialResults: true,
+			secondAttempt:            true,
+			instanceZones:            map[string]string{"1.1.1.1": "zone-a", "2.2.2.2": "zone-a"},
+			expectedWarning:          "some blocks couldn't be queried because the store-gateways of the zone zone-a failed to return them, the results between 2022-01-01T00:00:00Z and 2022-01-01T01:00:00Z may be incomplete. The non-queried blocks are: " + block2.String(),
+		},
+		"should fail if the tenant enabled the partial results on zone outages and the missing blocks were only attempted on a single store-gateway": {
+			zoneOutagePartialResults: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", mock.Anything, mock.Anything).Return(bucketindex.Blocks{
+				{ID: block1, MinTime: minT, MaxTime: maxT},
+				{ID: block2, MinTime: minT, MaxTime: maxT},
+			}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), error(nil))
+
+			q := &blocksStoreQuerier{
+				ctx:                   context.Background(),
+				minT:                  minT,
+				maxT:                  maxT,
+				userID:                "user-1",
+				finder:                finder,
+				stores:                &blocksStoreSetMock{mockedResponses: storeSetResponses(testData.secondAttempt), instanceZones: testData.instanceZones},
+				consistency:           NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:                log.NewNopLogger(),
+				metrics:               newBlocksStoreQueryableMetrics(nil),
+				limits:                &blocksStoreLimitsMock{zoneOutagePartialResults: testData.zoneOutagePartialResults},
+				partialResultsEnabled: testData.partialResultsEnabled,
+			}
+
+			names, warnings, err := q.LabelNames()
+			if testData.expectedWarning == "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "the consistency check failed because some blocks were not queried")
+				assert.Equal(t, float64(0), testutil.ToFloat64(q.metrics.partialResults))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"foo"}, names)
+			require.Len(t, warnings, 1)
+			assert.EqualError(t, warnings[0], testData.expectedWarning)
+			assert.Equal(t, float64(1), testutil.ToFloat64(q.metrics.partialResults))
+		})
+	}
+}
+
+func TestBlocksStoreQuerier_MetricsMetadata(t *testing.T) {
+	var (
+		block1  = ulid.MustNew(1, nil)
+		block2  = ulid.MustNew(2, nil)
+		counter = mimirpb.MetricMetadata{Type: mimirpb.COUNTER, MetricFamilyName: "a_total", Help: "A counter."}
+		gauge   = mimirpb.MetricMetadata{Type: mimirpb.GAUGE, MetricFamilyName: "b", Help: "B gauge.", Unit: "bytes"}
+	)
+
+	tests := map[string]struct {
+		storeSetResponses []interface{}
+		expectedMetadata  []scrape.MetricMetadata
+		expectedWarnings  []string
+	}{
+		"a single store-gateway instance holds the required blocks": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&gauge, &counter},
+						QueriedBlocks: []string{block1.String(), block2.String()},
+					}}: {block1, block2},
+				},
+			},
+			expectedMetadata: []scrape.MetricMetadata{
+				{Metric: "a_total", Type: textparse.MetricTypeCounter, Help: "A counter."},
+				{Metric: "b", Type: textparse.MetricTypeGauge, Help: "B gauge.", Unit: "bytes"},
+			},
+		},
+		"multiple store-gateway instances hold the required blocks with the same metadata": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&counter},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&counter},
+						QueriedBlocks: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			expectedMetadata: []scrape.MetricMetadata{
+				{Metric: "a_total", Type: textparse.MetricTypeCounter, Help: "A counter."},
+			},
+		},
+		"a store-gateway instance fails and the blocks are queried from another one": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&counter},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedMetadataErr: errors.New("unavailable")}: {block2},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "3.3.3.3", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&gauge},
+						QueriedBlocks: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			expectedMetadata: []scrape.MetricMetadata{
+				{Metric: "a_total", Type: textparse.MetricTypeCounter, Help: "A counter."},
+				{Metric: "b", Type: textparse.MetricTypeGauge, Help: "B gauge.", Unit: "bytes"},
+			},
+		},
+		"a store-gateway instance returns invalid block IDs and the blocks are queried from another one": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&counter},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&counter},
+						QueriedBlocks: []string{"invalid"},
+					}}: {block2},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "3.3.3.3", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&gauge},
+						QueriedBlocks: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			expectedMetadata: []scrape.MetricMetadata{
+				{Metric: "a_total", Type: textparse.MetricTypeCounter, Help: "A counter."},
+				{Metric: "b", Type: textparse.MetricTypeGauge, Help: "B gauge.", Unit: "bytes"},
+			},
+		},
+		"a block is not queried from any store-gateway": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedMetadataResponse: &storegatewaypb.MetricsMetadataResponse{
+						Metadata:      []*mimirpb.MetricMetadata{&counter},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1, block2},
+				},
+				errors.New("no store-gateway remaining after exclude"),
+			},
+			expectedMetadata: []scrape.MetricMetadata{
+				{Metric: "a_total", Type: textparse.MetricTypeCounter, Help: "A counter."},
+			},
+			expectedWarnings: []string{"the metric metadata may be incomplete: " + newStoreConsistencyCheckFailedError([]ulid.ULID{block2}).Error()},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", mock.Anything, mock.Anything).Return(bucketindex.Blocks{{ID: block1}, {ID: block2}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), error(nil))
+
+			collector, ctx := querywarnings.ContextWithCollector(user.InjectOrgID(context.Background(), "user-1"))
+			q := &blocksStoreQuerier{
+				ctx:         ctx,
+				minT:        0,
+				maxT:        util.TimeToMillis(time.Now()),
+				userID:      "user-1",
+				finder:      finder,
+				stores:      &blocksStoreSetMock{mockedResponses: testData.storeSetResponses},
+				consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(nil),
+				limits:      &blocksStoreLimitsMock{},
+			}
+
+			actual, err := q.metricsMetadata()
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedMetadata, actual)
+
+			var warnings []string
+			for _, w := range collector.Warnings() {
+				warnings = append(warnings, w.Error())
+			}
+			assert.Equal(t, testData.expectedWarnings, warnings)
+		})
+	}
+}
+
+func TestBlocksStoreQuerier_Exemplars(t *testing.T) {
+	var (
+		block1      = ulid.MustNew(1, nil)
+		block2      = ulid.MustNew(2, nil)
+		seriesA     = []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "a"}}
+		seriesB     = []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "b"}}
+		exemplarAt1 = mimirpb.Exemplar{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "1"}}, Value: 1, TimestampMs: 1000}
+		exemplarAt2 = mimirpb.Exemplar{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "2"}}, Value: 2, TimestampMs: 2000}
+	)
+
+	tests := map[string]struct {
+		storeSetResponses []interface{}
+		expectedExemplars []exemplar.QueryResult
+		expectedErr       string
+	}{
+		"multiple store-gateway instances hold the required blocks": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{{Labels: seriesB, Exemplars: []mimirpb.Exemplar{exemplarAt2}}},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries: []mimirpb.TimeSeries{
+							{Labels: seriesA, Exemplars: []mimirpb.Exemplar{exemplarAt1}},
+							{Labels: seriesB, Exemplars: []mimirpb.Exemplar{exemplarAt1, exemplarAt2}},
+						},
+						QueriedBlocks: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			expectedExemplars: []exemplar.QueryResult{
+				{SeriesLabels: mimirpb.FromLabelAdaptersToLabels(seriesA), Exemplars: mimirpb.FromExemplarProtosToExemplars([]mimirpb.Exemplar{exemplarAt1})},
+				{SeriesLabels: mimirpb.FromLabelAdaptersToLabels(seriesB), Exemplars: mimirpb.FromExemplarProtosToExemplars([]mimirpb.Exemplar{exemplarAt1, exemplarAt2})},
+			},
+		},
+		"a block is not queried from any store-gateway": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1, block2},
+				},
+				errors.New("no store-gateway remaining after exclude"),
+			},
+			expectedErr: newStoreConsistencyCheckFailedError([]ulid.ULID{block2}).Error(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", mock.Anything, mock.Anything).Return(bucketindex.Blocks{{ID: block1}, {ID: block2}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), error(nil))
+
+			q := &blocksStoreQuerier{
+				ctx:         user.InjectOrgID(context.Background(), "user-1"),
+				minT:        0,
+				maxT:        util.TimeToMillis(time.Now()),
+				userID:      "user-1",
+				finder:      finder,
+				stores:      &blocksStoreSetMock{mockedResponses: testData.storeSetResponses},
+				consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(nil),
+				limits:      &blocksStoreLimitsMock{},
+			}
+
+			actual, err := q.exemplars([][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}})
+			if testData.expectedErr != "" {
+				require.EqualError(t, err, testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedExemplars, actual)
+		})
+	}
+}
+
 func TestBlocksStoreQuerier_PromQLExecution(t *testing.T) {
 	// Prepare series fixtures.
 	series1 := labels.Labels{{Name: "__name__", Value: "metric_1"}}
@@ -1668,7 +2230,7 @@ func TestBlocksStoreQuerier_PromQLExecution(t *testing.T) {
 
 			// Instantiate the querier that will be executed to run the query.
 			logger := log.NewNopLogger()
-			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, logger, nil)
+			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, 0, false, false, 0, logger, nil)
 			require.NoError(t, err)
 			require.NoError(t, services.StartAndAwaitRunning(context.Background(), queryable))
 			defer services.StopAndAwaitTerminated(context.Background(), queryable) // nolint:errcheck
@@ -1795,11 +2357,183 @@ func TestFilterBlocksByShard(t *testing.T) {
 	}
 }
 
+func TestFilterBlocksByResolution(t *testing.T) {
+	const (
+		res5m = int64(5 * time.Minute / time.Millisecond)
+		res1h = int64(time.Hour / time.Millisecond)
+	)
+
+	raw1 := &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 100}
+	raw2 := &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 100, MaxTime: 200}
+	raw3 := &bucketindex.Block{ID: ulid.MustNew(3, nil), MinTime: 200, MaxTime: 300}
+	res5m1 := &bucketindex.Block{ID: ulid.MustNew(4, nil), MinTime: 0, MaxTime: 100, Resolution: res5m}
+	res5m2 := &bucketindex.Block{ID: ulid.MustNew(5, nil), MinTime: 100, MaxTime: 200, Resolution: res5m}
+	res1h1 := &bucketindex.Block{ID: ulid.MustNew(6, nil), MinTime: 0, MaxTime: 100, Resolution: res1h}
+
+	allBlocks := bucketindex.Blocks{raw1, raw2, raw3, res5m1, res5m2, res1h1}
+
+	for name, testData := range map[string]struct {
+		blocks         bucketindex.Blocks
+		minT, maxT     int64
+		maxResolution  int64
+		expectedBlocks bucketindex.Blocks
+	}{
+		"should return the raw blocks as they are if there are no downsampled blocks": {
+			blocks:         bucketindex.Blocks{raw3, raw1, raw2},
+			minT:           0,
+			maxT:           300,
+			maxResolution:  res1h,
+			expectedBlocks: bucketindex.Blocks{raw3, raw1, raw2},
+		},
+		"should only return the raw blocks with a max resolution of 0": {
+			blocks:         allBlocks,
+			minT:           0,
+			maxT:           300,
+			maxResolution:  0,
+			expectedBlocks: bucketindex.Blocks{raw1, raw2, raw3},
+		},
+		"should return the blocks of the highest resolution up to the max resolution, and the raw blocks for the rest of the time range": {
+			blocks:         allBlocks,
+			minT:           0,
+			maxT:           300,
+			maxResolution:  res5m,
+			expectedBlocks: bucketindex.Blocks{raw3, res5m1, res5m2},
+		},
+		"should fill the gaps of the lowest resolution with the higher resolutions": {
+			blocks:         allBlocks,
+			minT:           0,
+			maxT:           300,
+			maxResolution:  res1h,
+			expectedBlocks: bucketindex.Blocks{raw3, res5m2, res1h1},
+		},
+		"should pick the resolution below the max resolution": {
+			blocks:         allBlocks,
+			minT:           0,
+			maxT:           300,
+			maxResolution:  res1h - 1,
+			expectedBlocks: bucketindex.Blocks{raw3, res5m1, res5m2},
+		},
+		"should only return the blocks within the time range": {
+			blocks:         allBlocks,
+			minT:           150,
+			maxT:           300,
+			maxResolution:  res5m,
+			expectedBlocks: bucketindex.Blocks{raw3, res5m2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testData.expectedBlocks, filterBlocksByResolution(testData.blocks, testData.minT, testData.maxT, testData.maxResolution))
+		})
+	}
+}
+
+func TestAutoMaxResolution(t *testing.T) {
+	const lookbackDelta = 5 * time.Minute
+
+	for name, testData := range map[string]struct {
+		hints              *storage.SelectHints
+		expectedResolution time.Duration
+	}{
+		"no hints": {
+			hints:              nil,
+			expectedResolution: 0,
+		},
+		"range query with an instant selector": {
+			hints:              &storage.SelectHints{Step: time.Hour.Milliseconds()},
+			expectedResolution: 5 * time.Minute,
+		},
+		"range query with an instant selector and a step bigger than 5 times the lookback delta": {
+			hints:              &storage.SelectHints{Step: 24 * time.Hour.Milliseconds()},
+			expectedResolution: lookbackDelta,
+		},
+		"range query with a range selector": {
+			hints:              &storage.SelectHints{Step: 24 * time.Hour.Milliseconds(), Range: time.Hour.Milliseconds(), Func: "rate"},
+			expectedResolution: 12 * time.Minute,
+		},
+		"instant query with an instant selector": {
+			hints:              &storage.SelectHints{},
+			expectedResolution: lookbackDelta,
+		},
+		"instant query with a range selector": {
+			hints:              &storage.SelectHints{Range: 30 * 24 * time.Hour.Milliseconds(), Func: "rate"},
+			expectedResolution: 6 * 24 * time.Hour,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testData.expectedResolution.Milliseconds(), autoMaxResolution(testData.hints, lookbackDelta))
+		})
+	}
+}
+
+func TestBlocksStoreQuerier_MaxResolution(t *testing.T) {
+	hints := &storage.SelectHints{Step: time.Hour.Milliseconds()}
+	ctx := context.Background()
+
+	for name, testData := range map[string]struct {
+		ctx                     context.Context
+		hints                   *storage.SelectHints
+		autoDownsamplingEnabled bool
+		expectedResolution      time.Duration
+	}{
+		"should only read the raw data by default": {
+			ctx:                ctx,
+			expectedResolution: 0,
+		},
+		"should choose the resolution if automatic downsampling is enabled for the tenant": {
+			ctx:                     ctx,
+			autoDownsamplingEnabled: true,
+			expectedResolution:      12 * time.Minute,
+		},
+		"should choose the resolution if the query asks for it": {
+			ctx:                downsampling.ContextWithMaxSourceResolution(ctx, downsampling.MaxSourceResolution{Auto: true}),
+			expectedResolution: 12 * time.Minute,
+		},
+		"should use the resolution of the query": {
+			ctx:                downsampling.ContextWithMaxSourceResolution(ctx, downsampling.MaxSourceResolution{Resolution: time.Hour.Milliseconds()}),
+			expectedResolution: time.Hour,
+		},
+		"should only read the raw data if the query asks for it": {
+			ctx:                     downsampling.ContextWithMaxSourceResolution(ctx, downsampling.MaxSourceResolution{}),
+			autoDownsamplingEnabled: true,
+			expectedResolution:      0,
+		},
+		"should only read the raw data to count the samples": {
+			ctx:                     ctx,
+			hints:                   &storage.SelectHints{Step: time.Hour.Milliseconds(), Range: time.Hour.Milliseconds(), Func: "count_over_time"},
+			autoDownsamplingEnabled: true,
+			expectedResolution:      0,
+		},
+		"should only read the raw data to count the samples even if the query sets the resolution": {
+			ctx:                downsampling.ContextWithMaxSourceResolution(ctx, downsampling.MaxSourceResolution{Resolution: time.Hour.Milliseconds()}),
+			hints:              &storage.SelectHints{Step: time.Hour.Milliseconds(), Range: time.Hour.Milliseconds(), Func: "count_over_time"},
+			expectedResolution: 0,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q := &blocksStoreQuerier{
+				ctx:           testData.ctx,
+				userID:        "user-1",
+				limits:        &blocksStoreLimitsMock{autoDownsamplingEnabled: testData.autoDownsamplingEnabled},
+				lookbackDelta: time.Hour,
+			}
+			if testData.hints == nil {
+				testData.hints = hints
+			}
+			assert.Equal(t, testData.expectedResolution.Milliseconds(), q.maxResolution(testData.hints))
+		})
+	}
+}
+
 type blocksStoreSetMock struct {
 	services.Service
 
 	mockedResponses []interface{}
 	nextResult      int
+	instanceZones   map[string]string
+}
+
+func (m *blocksStoreSetMock) InstanceZone(addr string) string {
+	return m.instanceZones[addr]
 }
 
 func (m *blocksStoreSetMock) GetClientsFor(_ string, _ []ulid.ULID, _ map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error) {
@@ -1820,6 +2554,27 @@ func (m *blocksStoreSetMock) GetClientsFor(_ string, _ []ulid.ULID, _ map[ulid.U
 	return nil, errors.New("unknown data type in the mocked result")
 }
 
+// bucketStoresServer exposes the bucket stores through the store-gateway gRPC service.
+type bucketStoresServer struct {
+	storegatewaypb.UnimplementedStoreGatewayServer
+
+	stores *storegateway.BucketStores
+}
+
+func (s *bucketStoresServer) Series(req *storepb.SeriesRequest, srv storegatewaypb.StoreGateway_SeriesServer) error {
+	return s.stores.Series(req, srv)
+}
+
+type noShardingStrategyMock struct{}
+
+func (s *noShardingStrategyMock) FilterUsers(_ context.Context, userIDs []string) ([]string, error) {
+	return userIDs, nil
+}
+
+func (s *noShardingStrategyMock) FilterBlocks(_ context.Context, _ string, _ map[ulid.ULID]*metadata.Meta, _ map[ulid.ULID]struct{}, _ *extprom.TxGaugeVec) error {
+	return nil
+}
+
 type blocksFinderMock struct {
 	services.Service
 	mock.Mock
@@ -1834,15 +2589,21 @@ type storeGatewayClientMock struct {
 	remoteAddr                string
 	mockedSeriesResponses     []*storepb.SeriesResponse
 	mockedSeriesErr           error
+	mockedSeriesStreamErr     error
 	mockedLabelNamesResponse  *storepb.LabelNamesResponse
 	mockedLabelNamesErr       error
 	mockedLabelValuesResponse *storepb.LabelValuesResponse
 	mockedLabelValuesErr      error
+	mockedMetadataResponse    *storegatewaypb.MetricsMetadataResponse
+	mockedMetadataErr         error
+	mockedExemplarsResponse   *storegatewaypb.ExemplarsResponse
+	mockedExemplarsErr        error
 }
 
 func (m *storeGatewayClientMock) Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
 	seriesClient := &storeGatewaySeriesClientMock{
 		mockedResponses: m.mockedSeriesResponses,
+		mockedErr:       m.mockedSeriesStreamErr,
 	}
 
 	return seriesClient, m.mockedSeriesErr
@@ -1856,6 +2617,14 @@ func (m *storeGatewayClientMock) LabelValues(context.Context, *storepb.LabelValu
 	return m.mockedLabelValuesResponse, m.mockedLabelValuesErr
 }
 
+func (m *storeGatewayClientMock) MetricsMetadata(context.Context, *storegatewaypb.MetricsMetadataRequest, ...grpc.CallOption) (*storegatewaypb.MetricsMetadataResponse, error) {
+	return m.mockedMetadataResponse, m.mockedMetadataErr
+}
+
+func (m *storeGatewayClientMock) Exemplars(context.Context, *storegatewaypb.ExemplarsRequest, ...grpc.CallOption) (*storegatewaypb.ExemplarsResponse, error) {
+	return m.mockedExemplarsResponse, m.mockedExemplarsErr
+}
+
 func (m *storeGatewayClientMock) RemoteAddress() string {
 	return m.remoteAddr
 }
@@ -1864,6 +2633,7 @@ type storeGatewaySeriesClientMock struct {
 	grpc.ClientStream
 
 	mockedResponses []*storepb.SeriesResponse
+	mockedErr       error
 }
 
 func (m *storeGatewaySeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
@@ -1871,6 +2641,9 @@ func (m *storeGatewaySeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
 	time.Sleep(10 * time.Millisecond)
 
 	if len(m.mockedResponses) == 0 {
+		if m.mockedErr != nil {
+			return nil, m.mockedErr
+		}
 		return nil, io.EOF
 	}
 
@@ -1880,9 +2653,14 @@ func (m *storeGatewaySeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
 }
 
 type blocksStoreLimitsMock struct {
-	maxLabelsQueryLength        time.Duration
-	maxChunksPerQuery           int
-	storeGatewayTenantShardSize int
+	maxLabelsQueryLength                time.Duration
+	maxChunksPerQuery                   int
+	storeGatewayTenantShardSize         int
+	storeGatewayTenantReplicationFactor int
+	maxMetadataPerBlock                 int
+	maxExemplarsPerBlock                int
+	autoDownsamplingEnabled             bool
+	zoneOutagePartialResults            bool
 }
 
 func (m *blocksStoreLimitsMock) MaxLabelsQueryLength(_ string) time.Duration {
@@ -1897,6 +2675,26 @@ func (m *blocksStoreLimitsMock) StoreGatewayTenantShardSize(_ string) int {
 	return m.storeGatewayTenantShardSize
 }
 
+func (m *blocksStoreLimitsMock) StoreGatewayTenantReplicationFactor(_ string) int {
+	return m.storeGatewayTenantReplicationFactor
+}
+
+func (m *blocksStoreLimitsMock) MaxMetadataPerBlock(_ string) int {
+	return m.maxMetadataPerBlock
+}
+
+func (m *blocksStoreLimitsMock) MaxExemplarsPerBlock(_ string) int {
+	return m.maxExemplarsPerBlock
+}
+
+func (m *blocksStoreLimitsMock) AutoDownsamplingEnabled(_ string) bool {
+	return m.autoDownsamplingEnabled
+}
+
+func (m *blocksStoreLimitsMock) ZoneOutagePartialResultsEnabled(_ string) bool {
+	return m.zoneOutagePartialResults
+}
+
 func (m *blocksStoreLimitsMock) S3SSEType(_ string) string {
 	return ""
 }
@@ -2027,3 +2825,20 @@ func TestBlocksStoreQueryableErrMsgs(t *testing.T) {
 		})
 	}
 }
+
+func TestCountSamples(t *testing.T) {
+	series := []*storepb.Series{
+		{Chunks: []storepb.AggrChunk{
+			createAggrChunkWithSamples(promql.Point{T: 1, V: 1}, promql.Point{T: 2, V: 2}),
+			createAggrChunkWithSamples(promql.Point{T: 3, V: 3}),
+		}},
+		{Chunks: []storepb.AggrChunk{
+			createAggrChunkWithSamples(promql.Point{T: 1, V: 1}, promql.Point{T: 2, V: 2}, promql.Point{T: 3, V: 3}),
+			// The chunks with no raw data are not accounted.
+			{MinTime: 1, MaxTime: 2},
+		}},
+	}
+
+	assert.Equal(t, 6, countSamples(series...))
+	assert.Equal(t, 0, countSamples())
+}
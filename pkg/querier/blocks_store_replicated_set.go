@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/dskit/ring"
@@ -18,7 +19,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 
-	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/util"
 )
@@ -38,8 +38,12 @@ type blocksStoreReplicationSet struct {
 	storesRing        *ring.Ring
 	clientsPool       *client.Pool
 	balancingStrategy loadBalancingStrategy
+	readOp            ring.Operation
 	limits            BlocksStoreLimits
 
+	// The zone of each store-gateway address returned by GetClientsFor.
+	instanceZones sync.Map
+
 	// Subservices manager.
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -48,6 +52,7 @@ type blocksStoreReplicationSet struct {
 func newBlocksStoreReplicationSet(
 	storesRing *ring.Ring,
 	balancingStrategy loadBalancingStrategy,
+	readOp ring.Operation,
 	limits BlocksStoreLimits,
 	clientConfig ClientConfig,
 	logger log.Logger,
@@ -57,6 +62,7 @@ func newBlocksStoreReplicationSet(
 		storesRing:        storesRing,
 		clientsPool:       newStoreGatewayClientPool(client.NewRingServiceDiscovery(storesRing), clientConfig, logger, reg),
 		balancingStrategy: balancingStrategy,
+		readOp:            readOp,
 		limits:            limits,
 	}
 
@@ -107,7 +113,7 @@ func (s *blocksStoreReplicationSet) GetClientsFor(userID string, blockIDs []ulid
 		// returned replication set.
 		bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
 
-		set, err := userRing.Get(mimir_tsdb.HashBlockID(blockID), storegateway.BlocksRead, bufDescs, bufHosts, bufZones)
+		set, err := storegateway.GetBlockReplicationSet(userRing, userID, blockID, s.readOp, s.limits, bufDescs, bufHosts, bufZones)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get store-gateway replication set owning the block %s", blockID.String())
 		}
@@ -117,6 +123,7 @@ func (s *blocksStoreReplicationSet) GetClientsFor(userID string, blockIDs []ulid
 		if addr == "" {
 			return nil, fmt.Errorf("no store-gateway instance left after checking exclude for block %s", blockID.String())
 		}
+		s.trackInstanceZone(set, addr)
 
 		shards[addr] = append(shards[addr], blockID)
 	}
@@ -136,6 +143,28 @@ func (s *blocksStoreReplicationSet) GetClientsFor(userID string, blockIDs []ulid
 	return clients, nil
 }
 
+// InstanceZone implements BlocksStoreSet.
+func (s *blocksStoreReplicationSet) InstanceZone(addr string) string {
+	zone, ok := s.instanceZones.Load(addr)
+	if !ok {
+		return ""
+	}
+	return zone.(string)
+}
+
+// trackInstanceZone keeps track of the zone of the instance of the replication set with the given address.
+func (s *blocksStoreReplicationSet) trackInstanceZone(set ring.ReplicationSet, addr string) {
+	for _, instance := range set.Instances {
+		if instance.Addr != addr {
+			continue
+		}
+		if zone, ok := s.instanceZones.Load(addr); !ok || zone != instance.Zone {
+			s.instanceZones.Store(addr, instance.Zone)
+		}
+		return
+	}
+}
+
 func getNonExcludedInstanceAddr(set ring.ReplicationSet, exclude []string, balancingStrategy loadBalancingStrategy) string {
 	if balancingStrategy == randomLoadBalancing {
 		// Randomize the list of instances to not always query the same one.
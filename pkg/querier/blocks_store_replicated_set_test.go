@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storegateway"
 )
 
 func TestBlocksStoreReplicationSet_GetClientsFor(t *testing.T) {
@@ -44,13 +45,15 @@ func TestBlocksStoreReplicationSet_GetClientsFor(t *testing.T) {
 	registeredAt := time.Now()
 
 	tests := map[string]struct {
-		tenantShardSize   int
-		replicationFactor int
-		setup             func(*ring.Desc)
-		queryBlocks       []ulid.ULID
-		exclude           map[ulid.ULID][]string
-		expectedClients   map[string][]ulid.ULID
-		expectedErr       error
+		tenantShardSize         int
+		tenantReplicationFactor int
+		replicationFactor       int
+		shutdownHandover        bool
+		setup                   func(*ring.Desc)
+		queryBlocks             []ulid.ULID
+		exclude                 map[ulid.ULID][]string
+		expectedClients         map[string][]ulid.ULID
+		expectedErr             error
 	}{
 		"shard size 0, single instance in the ring with RF = 1": {
 			tenantShardSize:   0,
@@ -193,6 +196,47 @@ func TestBlocksStoreReplicationSet_GetClientsFor(t *testing.T) {
 				"127.0.0.4": {block1},
 			},
 		},
+		"shard size 0, multiple instances in the ring with RF = 1, the requested block belongs to a LEAVING instance": {
+			tenantShardSize:   0,
+			replicationFactor: 1,
+			setup: func(d *ring.Desc) {
+				d.AddIngester("instance-1", "127.0.0.1", "", []uint32{block1Hash + 1}, ring.LEAVING, registeredAt)
+				d.AddIngester("instance-2", "127.0.0.2", "", []uint32{block2Hash + 1}, ring.ACTIVE, registeredAt)
+			},
+			queryBlocks: []ulid.ULID{block1},
+			expectedClients: map[string][]ulid.ULID{
+				"127.0.0.2": {block1},
+			},
+		},
+		"shard size 0, multiple instances in the ring with RF = 1 and shutdown handover, the requested block belongs to a LEAVING instance": {
+			tenantShardSize:   0,
+			replicationFactor: 1,
+			shutdownHandover:  true,
+			setup: func(d *ring.Desc) {
+				d.AddIngester("instance-1", "127.0.0.1", "", []uint32{block1Hash + 1}, ring.LEAVING, registeredAt)
+				d.AddIngester("instance-2", "127.0.0.2", "", []uint32{block2Hash + 1}, ring.ACTIVE, registeredAt)
+			},
+			queryBlocks: []ulid.ULID{block1},
+			expectedClients: map[string][]ulid.ULID{
+				"127.0.0.1": {block1},
+			},
+		},
+		"shard size 0, multiple instances in the ring with RF = 1 and shutdown handover, the requested block belongs to a LEAVING instance but excluded": {
+			tenantShardSize:   0,
+			replicationFactor: 1,
+			shutdownHandover:  true,
+			setup: func(d *ring.Desc) {
+				d.AddIngester("instance-1", "127.0.0.1", "", []uint32{block1Hash + 1}, ring.LEAVING, registeredAt)
+				d.AddIngester("instance-2", "127.0.0.2", "", []uint32{block2Hash + 1}, ring.ACTIVE, registeredAt)
+			},
+			queryBlocks: []ulid.ULID{block1},
+			exclude: map[ulid.ULID][]string{
+				block1: {"127.0.0.1"},
+			},
+			expectedClients: map[string][]ulid.ULID{
+				"127.0.0.2": {block1},
+			},
+		},
 		"shard size 1, single instance in the ring with RF = 1": {
 			tenantShardSize:   1,
 			replicationFactor: 1,
@@ -306,6 +350,35 @@ func TestBlocksStoreReplicationSet_GetClientsFor(t *testing.T) {
 			},
 			expectedErr: fmt.Errorf("no store-gateway instance left after checking exclude for block %s", block1.String()),
 		},
+		"shard size 0, multiple instances in the ring with RF = 1, excluded block and no replacement available": {
+			tenantShardSize:   0,
+			replicationFactor: 1,
+			setup: func(d *ring.Desc) {
+				d.AddIngester("instance-1", "127.0.0.1", "", []uint32{block1Hash + 1}, ring.ACTIVE, registeredAt)
+				d.AddIngester("instance-2", "127.0.0.2", "", []uint32{block3Hash + 1}, ring.ACTIVE, registeredAt)
+			},
+			queryBlocks: []ulid.ULID{block1},
+			exclude: map[ulid.ULID][]string{
+				block1: {"127.0.0.1"},
+			},
+			expectedErr: fmt.Errorf("no store-gateway instance left after checking exclude for block %s", block1.String()),
+		},
+		"shard size 0, multiple instances in the ring with RF = 1 and tenant RF = 2, excluded block and replacement available": {
+			tenantShardSize:         0,
+			tenantReplicationFactor: 2,
+			replicationFactor:       1,
+			setup: func(d *ring.Desc) {
+				d.AddIngester("instance-1", "127.0.0.1", "", []uint32{block1Hash + 1}, ring.ACTIVE, registeredAt)
+				d.AddIngester("instance-2", "127.0.0.2", "", []uint32{block3Hash + 1}, ring.ACTIVE, registeredAt)
+			},
+			queryBlocks: []ulid.ULID{block1},
+			exclude: map[ulid.ULID][]string{
+				block1: {"127.0.0.1"},
+			},
+			expectedClients: map[string][]ulid.ULID{
+				"127.0.0.2": {block1},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
@@ -334,11 +407,17 @@ func TestBlocksStoreReplicationSet_GetClientsFor(t *testing.T) {
 			require.NoError(t, err)
 
 			limits := &blocksStoreLimitsMock{
-				storeGatewayTenantShardSize: testData.tenantShardSize,
+				storeGatewayTenantShardSize:         testData.tenantShardSize,
+				storeGatewayTenantReplicationFactor: testData.tenantReplicationFactor,
+			}
+
+			readOp := storegateway.BlocksRead
+			if testData.shutdownHandover {
+				readOp = storegateway.BlocksReadHandover
 			}
 
 			reg := prometheus.NewPedanticRegistry()
-			s, err := newBlocksStoreReplicationSet(r, noLoadBalancing, limits, ClientConfig{}, log.NewNopLogger(), reg)
+			s, err := newBlocksStoreReplicationSet(r, noLoadBalancing, readOp, limits, ClientConfig{}, log.NewNopLogger(), reg)
 			require.NoError(t, err)
 			require.NoError(t, services.StartAndAwaitRunning(ctx, s))
 			defer services.StopAndAwaitTerminated(ctx, s) //nolint:errcheck
@@ -383,7 +462,7 @@ func TestBlocksStoreReplicationSet_GetClientsFor_ShouldSupportRandomLoadBalancin
 	require.NoError(t, ringStore.CAS(ctx, "test", func(in interface{}) (interface{}, bool, error) {
 		d := ring.NewDesc()
 		for n := 1; n <= numInstances; n++ {
-			d.AddIngester(fmt.Sprintf("instance-%d", n), fmt.Sprintf("127.0.0.%d", n), "", []uint32{uint32(n)}, ring.ACTIVE, registeredAt)
+			d.AddIngester(fmt.Sprintf("instance-%d", n), fmt.Sprintf("127.0.0.%d", n), fmt.Sprintf("zone-%d", n), []uint32{uint32(n)}, ring.ACTIVE, registeredAt)
 		}
 		return d, true, nil
 	}))
@@ -398,7 +477,7 @@ func TestBlocksStoreReplicationSet_GetClientsFor_ShouldSupportRandomLoadBalancin
 
 	limits := &blocksStoreLimitsMock{storeGatewayTenantShardSize: 0}
 	reg := prometheus.NewPedanticRegistry()
-	s, err := newBlocksStoreReplicationSet(r, randomLoadBalancing, limits, ClientConfig{}, log.NewNopLogger(), reg)
+	s, err := newBlocksStoreReplicationSet(r, randomLoadBalancing, storegateway.BlocksRead, limits, ClientConfig{}, log.NewNopLogger(), reg)
 	require.NoError(t, err)
 	require.NoError(t, services.StartAndAwaitRunning(ctx, s))
 	defer services.StopAndAwaitTerminated(ctx, s) //nolint:errcheck
@@ -429,6 +508,12 @@ func TestBlocksStoreReplicationSet_GetClientsFor_ShouldSupportRandomLoadBalancin
 		// the 80% of the perfect even distribution.
 		assert.Greaterf(t, float64(count), (float64(numRuns)/float64(numInstances))*0.8, "store-gateway address: %s", addr)
 	}
+
+	// The zone of each returned store-gateway is tracked.
+	for n := 1; n <= numInstances; n++ {
+		assert.Equal(t, fmt.Sprintf("zone-%d", n), s.InstanceZone(fmt.Sprintf("127.0.0.%d", n)))
+	}
+	assert.Equal(t, "", s.InstanceZone("127.0.0.100"))
 }
 
 func getStoreGatewayClientAddrs(clients map[BlocksStoreClient][]ulid.ULID) map[string][]ulid.ULID {
@@ -3,15 +3,18 @@
 package querier
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/weaveworks/common/httpgrpc"
 
 	"github.com/grafana/dskit/tenant"
@@ -26,10 +29,35 @@ const (
 	minLimit     = 0
 	maxLimit     = 500
 	defaultLimit = 20
+
+	minActiveSeriesLimit     = 1
+	maxActiveSeriesLimit     = 10000
+	defaultActiveSeriesLimit = 1000
+
+	sortByLabelName        = "label_name"
+	sortByLabelValuesCount = "label_values_count"
+	sortByLabelValue       = "label_value"
+	sortBySeriesCount      = "series_count"
 )
 
-// LabelNamesCardinalityHandler creates handler for label names cardinality endpoint.
-func LabelNamesCardinalityHandler(d Distributor, limits *validation.Overrides) http.Handler {
+// cardinalityRequestParams holds the params of the label names and label values cardinality requests.
+type cardinalityRequestParams struct {
+	labelNames []model.LabelName
+	matchers   []*labels.Matcher
+	limit      int
+	offset     int
+	sortBy     string
+
+	// The time range is only set when the cardinality of the series in the time range, read from both the ingesters
+	// and the long-term storage, is requested rather than the cardinality of the in-memory series of the ingesters.
+	hasTimeRange bool
+	start, end   int64
+}
+
+// LabelNamesCardinalityHandler creates handler for label names cardinality endpoint. The cardinality of the series
+// in the requested time range, if any, is read from the queryable, with the time range clamped to the tenant's max
+// labels query length.
+func LabelNamesCardinalityHandler(d Distributor, queryable storage.Queryable, limits *validation.Overrides) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		tenantID, err := tenant.TenantID(ctx)
@@ -41,23 +69,32 @@ func LabelNamesCardinalityHandler(d Distributor, limits *validation.Overrides) h
 			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
 			return
 		}
-		matchers, limit, err := extractLabelNamesRequestParams(r)
+		params, err := extractLabelNamesRequestParams(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		response, err := d.LabelNamesAndValues(ctx, matchers)
+
+		var response *ingester_client.LabelNamesAndValuesResponse
+		if params.hasTimeRange {
+			clampToMaxLabelsQueryLength(params, limits, tenantID)
+			response, err = labelNamesAndValuesFromQueryable(ctx, queryable, params)
+		} else {
+			response, err = d.LabelNamesAndValues(ctx, params.matchers)
+		}
 		if err != nil {
 			respondFromError(err, w)
 			return
 		}
-		cardinalityResponse := toLabelNamesCardinalityResponse(response, limit)
+		cardinalityResponse := toLabelNamesCardinalityResponse(response, params)
 		util.WriteJSONResponse(w, cardinalityResponse)
 	})
 }
 
-// LabelValuesCardinalityHandler creates handler for label values cardinality endpoint.
-func LabelValuesCardinalityHandler(distributor Distributor, limits *validation.Overrides) http.Handler {
+// LabelValuesCardinalityHandler creates handler for label values cardinality endpoint. The cardinality of the series
+// in the requested time range, if any, is read from the queryable, with the time range clamped to the tenant's max
+// labels query length.
+func LabelValuesCardinalityHandler(distributor Distributor, queryable storage.Queryable, limits *validation.Overrides) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		// Guarantee request's context is for a single tenant id
@@ -71,60 +108,206 @@ func LabelValuesCardinalityHandler(distributor Distributor, limits *validation.O
 			return
 		}
 
-		labelNames, matchers, limit, err := extractLabelValuesRequestParams(r)
+		params, err := extractLabelValuesRequestParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var (
+			seriesCountTotal    uint64
+			cardinalityResponse *ingester_client.LabelValuesCardinalityResponse
+		)
+		if params.hasTimeRange {
+			clampToMaxLabelsQueryLength(params, limits, tenantID)
+			seriesCountTotal, cardinalityResponse, err = labelValuesCardinalityFromQueryable(ctx, queryable, params)
+		} else {
+			seriesCountTotal, cardinalityResponse, err = distributor.LabelValuesCardinality(ctx, params.labelNames, params.matchers)
+		}
+		if err != nil {
+			respondFromError(err, w)
+			return
+		}
+
+		util.WriteJSONResponse(w, toLabelValuesCardinalityResponse(seriesCountTotal, cardinalityResponse, params))
+	})
+}
+
+// HeadCardinalityStatsHandler creates handler for the cardinality statistics of the in-memory series endpoint.
+func HeadCardinalityStatsHandler(distributor Distributor, limits *validation.Overrides) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !limits.CardinalityAnalysisEnabled(tenantID) {
+			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, err := extractLimit(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := distributor.HeadCardinalityStats(ctx, limit)
+		if err != nil {
+			respondFromError(err, w)
+			return
+		}
+
+		util.WriteJSONResponse(w, toHeadCardinalityStatsResponse(response))
+	})
+}
+
+// ActiveSeriesCardinalityHandler creates handler for the active series endpoint, returning the series tracked as
+// active by the ingesters. The series written in the requested time range, if any, are read from the queryable too,
+// so that the series which are no longer in the ingesters memory are included. The time range is clamped to the
+// tenant's max labels query length, and only the first limit series sorted by labels are returned.
+func ActiveSeriesCardinalityHandler(distributor Distributor, queryable storage.Queryable, limits *validation.Overrides) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !limits.CardinalityAnalysisEnabled(tenantID) {
+			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
+			return
+		}
+
+		params, err := extractActiveSeriesRequestParams(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		seriesCountTotal, cardinalityResponse, err := distributor.LabelValuesCardinality(ctx, labelNames, matchers)
+		series, err := distributor.ActiveSeries(ctx, params.matchers, params.limit)
 		if err != nil {
 			respondFromError(err, w)
 			return
 		}
+		if params.hasTimeRange {
+			clampToMaxLabelsQueryLength(params, limits, tenantID)
+			written, err := seriesFromQueryable(ctx, queryable, params)
+			if err != nil {
+				respondFromError(err, w)
+				return
+			}
+			series = mergeSeries(series, written, params.limit)
+		}
 
-		util.WriteJSONResponse(w, toLabelValuesCardinalityResponse(seriesCountTotal, cardinalityResponse, limit))
+		util.WriteJSONResponse(w, activeSeriesResponse{Data: series})
 	})
 }
 
-func extractLabelNamesRequestParams(r *http.Request) ([]*labels.Matcher, int, error) {
+// clampToMaxLabelsQueryLength moves the start of the request time range forward, so that the time range is not longer
+// than the tenant's max labels query length.
+func clampToMaxLabelsQueryLength(params *cardinalityRequestParams, limits *validation.Overrides, tenantID string) {
+	if maxLength := limits.MaxLabelsQueryLength(tenantID); maxLength > 0 && params.end-params.start > maxLength.Milliseconds() {
+		params.start = params.end - maxLength.Milliseconds()
+	}
+}
+
+func extractLabelNamesRequestParams(r *http.Request) (*cardinalityRequestParams, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	matchers, err := extractSelector(r)
+	return extractCardinalityRequestParams(r, sortByLabelValuesCount, sortByLabelName)
+}
+
+// extractLabelValuesRequestParams parses query params from GET requests and parses request body from POST requests
+func extractLabelValuesRequestParams(r *http.Request) (*cardinalityRequestParams, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	labelNames, err := extractLabelNames(r)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	limit, err := extractLimit(r)
+
+	params, err := extractCardinalityRequestParams(r, sortBySeriesCount, sortByLabelValue)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	return matchers, limit, nil
+	params.labelNames = labelNames
+	return params, nil
 }
 
-// extractLabelValuesRequestParams parses query params from GET requests and parses request body from POST requests
-func extractLabelValuesRequestParams(r *http.Request) (labelNames []model.LabelName, matchers []*labels.Matcher, limit int, err error) {
+// extractActiveSeriesRequestParams parses the selector, the limit and the time range of the active series requests.
+// The selector is required, and must have at least one matcher not matching the empty string, so that the requests
+// can't list all the series of the tenant.
+func extractActiveSeriesRequestParams(r *http.Request) (*cardinalityRequestParams, error) {
 	if err := r.ParseForm(); err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 
-	labelNames, err = extractLabelNames(r)
+	matchers, err := extractSelector(r)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
+	}
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("the 'selector' param is required")
+	}
+	if !hasNonEmptyMatcher(matchers) {
+		return nil, fmt.Errorf("the 'selector' param must contain at least one non-empty matcher")
 	}
 
-	matchers, err = extractSelector(r)
+	limit, err := extractActiveSeriesLimit(r)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 
-	limit, err = extractLimit(r)
+	params := &cardinalityRequestParams{matchers: matchers, limit: limit}
+	if err := extractTimeRange(r, params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// extractCardinalityRequestParams parses the params shared by the label names and label values cardinality requests.
+// The first of the allowed sort orders is the default one.
+func extractCardinalityRequestParams(r *http.Request, sortOrders ...string) (*cardinalityRequestParams, error) {
+	matchers, err := extractSelector(r)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 
-	return labelNames, matchers, limit, nil
+	limit, err := extractLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := extractOffset(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sortBy, err := extractSortBy(r, sortOrders)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &cardinalityRequestParams{
+		matchers: matchers,
+		limit:    limit,
+		offset:   offset,
+		sortBy:   sortBy,
+	}
+	if err := extractTimeRange(r, params); err != nil {
+		return nil, err
+	}
+	return params, nil
 }
 
 // extractSelector parses and gets selector query parameter containing a single matcher
@@ -161,6 +344,95 @@ func extractLimit(r *http.Request) (limit int, err error) {
 	return limit, nil
 }
 
+// extractActiveSeriesLimit parses and validates request param `limit` of the active series requests if it's defined,
+// otherwise returns the default value.
+func extractActiveSeriesLimit(r *http.Request) (limit int, err error) {
+	limitParams := r.Form["limit"]
+	if len(limitParams) == 0 {
+		return defaultActiveSeriesLimit, nil
+	}
+	if len(limitParams) > 1 {
+		return 0, fmt.Errorf("multiple 'limit' params are not allowed")
+	}
+	limit, err = strconv.Atoi(limitParams[0])
+	if err != nil {
+		return 0, err
+	}
+	if limit < minActiveSeriesLimit {
+		return 0, fmt.Errorf("'limit' param cannot be less than '%v'", minActiveSeriesLimit)
+	}
+	if limit > maxActiveSeriesLimit {
+		return 0, fmt.Errorf("'limit' param cannot be greater than '%v'", maxActiveSeriesLimit)
+	}
+	return limit, nil
+}
+
+// hasNonEmptyMatcher returns whether at least one of the matchers doesn't match the empty string.
+func hasNonEmptyMatcher(matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches("") {
+			return true
+		}
+	}
+	return false
+}
+
+// extractOffset parses and validates request param `offset` if it's defined, otherwise returns 0.
+func extractOffset(r *http.Request) (offset int, err error) {
+	offsetParams := r.Form["offset"]
+	if len(offsetParams) == 0 {
+		return 0, nil
+	}
+	if len(offsetParams) > 1 {
+		return 0, fmt.Errorf("multiple 'offset' params are not allowed")
+	}
+	offset, err = strconv.Atoi(offsetParams[0])
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("'offset' param cannot be less than '0'")
+	}
+	return offset, nil
+}
+
+// extractSortBy parses and validates request param `sort_by` if it's defined, otherwise returns the first allowed value.
+func extractSortBy(r *http.Request, allowed []string) (string, error) {
+	sortByParams := r.Form["sort_by"]
+	if len(sortByParams) == 0 {
+		return allowed[0], nil
+	}
+	if len(sortByParams) > 1 {
+		return "", fmt.Errorf("multiple 'sort_by' params are not allowed")
+	}
+	if !util.StringsContain(allowed, sortByParams[0]) {
+		return "", fmt.Errorf("invalid 'sort_by' param '%v', allowed values are: %s", sortByParams[0], strings.Join(allowed, ", "))
+	}
+	return sortByParams[0], nil
+}
+
+// extractTimeRange parses and validates request params `start` and `end`, which must be either both defined or not.
+func extractTimeRange(r *http.Request, params *cardinalityRequestParams) (err error) {
+	startParams, endParams := r.Form["start"], r.Form["end"]
+	if len(startParams) == 0 && len(endParams) == 0 {
+		return nil
+	}
+	if len(startParams) != 1 || len(endParams) != 1 {
+		return fmt.Errorf("a single 'start' and a single 'end' params are required to analyze the cardinality in a time range")
+	}
+	if params.start, err = util.ParseTime(startParams[0]); err != nil {
+		return fmt.Errorf("invalid 'start' param: %w", err)
+	}
+	if params.end, err = util.ParseTime(endParams[0]); err != nil {
+		return fmt.Errorf("invalid 'end' param: %w", err)
+	}
+	if params.end < params.start {
+		return fmt.Errorf("'end' param cannot be before the 'start' param")
+	}
+	params.hasTimeRange = true
+	return nil
+}
+
 // extractLabelNames parses and gets label_names query parameter containing an array of label values
 func extractLabelNames(r *http.Request) ([]model.LabelName, error) {
 	labelNamesParams := r.Form["label_names[]"]
@@ -190,14 +462,155 @@ func respondFromError(err error, w http.ResponseWriter) {
 	w.Write(httpResp.Body) //nolint
 }
 
+// labelNamesAndValuesFromQueryable returns the label names and values of the series matching the request
+// selector in the request time range, read from the queryable. The label names and values are collected from the
+// labels of the selected series, so that the queryable is only queried once instead of once per label name.
+func labelNamesAndValuesFromQueryable(ctx context.Context, queryable storage.Queryable, params *cardinalityRequestParams) (*ingester_client.LabelNamesAndValuesResponse, error) {
+	q, err := queryable.Querier(ctx, params.start, params.end)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	// Select all series if there's no selector.
+	matchers := params.matchers
+	if len(matchers) == 0 {
+		matchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}
+	}
+
+	// Only the series labels are needed, so the chunks are not fetched.
+	set := q.Select(false, &storage.SelectHints{Start: params.start, End: params.end, Func: "series"}, matchers...)
+	valuesByName := map[string]map[string]struct{}{}
+	for set.Next() {
+		for _, l := range set.At().Labels() {
+			values, ok := valuesByName[l.Name]
+			if !ok {
+				values = map[string]struct{}{}
+				valuesByName[l.Name] = values
+			}
+			values[l.Value] = struct{}{}
+		}
+	}
+	if err := set.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make([]*ingester_client.LabelValues, 0, len(valuesByName))
+	for name, values := range valuesByName {
+		item := &ingester_client.LabelValues{LabelName: name, Values: make([]string, 0, len(values))}
+		for value := range values {
+			item.Values = append(item.Values, value)
+		}
+		sort.Strings(item.Values)
+		items = append(items, item)
+	}
+	return &ingester_client.LabelNamesAndValuesResponse{Items: items}, nil
+}
+
+// labelValuesCardinalityFromQueryable returns the number of series matching the request selector in the request
+// time range, and the number of series of each value of the requested label names, read from the queryable.
+func labelValuesCardinalityFromQueryable(ctx context.Context, queryable storage.Queryable, params *cardinalityRequestParams) (uint64, *ingester_client.LabelValuesCardinalityResponse, error) {
+	q, err := queryable.Querier(ctx, params.start, params.end)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer q.Close()
+
+	// Select all series if there's no selector.
+	matchers := params.matchers
+	if len(matchers) == 0 {
+		matchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}
+	}
+
+	seriesCountByLabelValue := make(map[model.LabelName]map[string]uint64, len(params.labelNames))
+	for _, labelName := range params.labelNames {
+		seriesCountByLabelValue[labelName] = map[string]uint64{}
+	}
+
+	// Only the series labels are needed, so the chunks are not fetched.
+	set := q.Select(false, &storage.SelectHints{Start: params.start, End: params.end, Func: "series"}, matchers...)
+	seriesCountTotal := uint64(0)
+	for set.Next() {
+		series := set.At().Labels()
+		seriesCountTotal++
+		for _, labelName := range params.labelNames {
+			if value := series.Get(string(labelName)); value != "" {
+				seriesCountByLabelValue[labelName][value]++
+			}
+		}
+	}
+	if err := set.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	items := make([]*ingester_client.LabelValueSeriesCount, 0, len(params.labelNames))
+	for _, labelName := range params.labelNames {
+		items = append(items, &ingester_client.LabelValueSeriesCount{
+			LabelName:        string(labelName),
+			LabelValueSeries: seriesCountByLabelValue[labelName],
+		})
+	}
+	return seriesCountTotal, &ingester_client.LabelValuesCardinalityResponse{Items: items}, nil
+}
+
+// seriesFromQueryable returns the first limit series, sorted by labels, matching the request selector in the request
+// time range, read from the queryable.
+func seriesFromQueryable(ctx context.Context, queryable storage.Queryable, params *cardinalityRequestParams) ([]labels.Labels, error) {
+	q, err := queryable.Querier(ctx, params.start, params.end)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	// Only the series labels are needed, so the chunks are not fetched. The series are sorted, so that the iteration
+	// can stop once the limit is reached.
+	set := q.Select(true, &storage.SelectHints{Start: params.start, End: params.end, Func: "series"}, params.matchers...)
+	var result []labels.Labels
+	for len(result) < params.limit && set.Next() {
+		result = append(result, set.At().Labels())
+	}
+	if err := set.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeSeries returns the first limit deduplicated series of both a and b, sorted by labels.
+func mergeSeries(a, b []labels.Labels, limit int) []labels.Labels {
+	unique := make(map[uint64]labels.Labels, len(a)+len(b))
+	for _, series := range a {
+		unique[series.Hash()] = series
+	}
+	for _, series := range b {
+		unique[series.Hash()] = series
+	}
+
+	result := make([]labels.Labels, 0, len(unique))
+	for _, series := range unique {
+		result = append(result, series)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return labels.Compare(result[i], result[j]) < 0
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
 // toLabelNamesCardinalityResponse converts ingester's response to LabelNamesCardinalityResponse
-func toLabelNamesCardinalityResponse(response *ingester_client.LabelNamesAndValuesResponse, limit int) *LabelNamesCardinalityResponse {
+func toLabelNamesCardinalityResponse(response *ingester_client.LabelNamesAndValuesResponse, params *cardinalityRequestParams) *LabelNamesCardinalityResponse {
 	labelsWithValues := response.Items
-	sortByValuesCountAndName(labelsWithValues)
+	if params.sortBy == sortByLabelName {
+		sortByName(labelsWithValues)
+	} else {
+		sortByValuesCountAndName(labelsWithValues)
+	}
 	valuesCountTotal := getValuesCountTotal(labelsWithValues)
-	items := make([]*LabelNamesCardinalityItem, util_math.Min(len(labelsWithValues), limit))
-	for i := 0; i < len(items); i++ {
-		items[i] = &LabelNamesCardinalityItem{LabelName: labelsWithValues[i].LabelName, LabelValuesCount: len(labelsWithValues[i].Values)}
+	page := paginate(len(labelsWithValues), params.offset, params.limit)
+	items := make([]*LabelNamesCardinalityItem, 0, page.end-page.start)
+	for i := page.start; i < page.end; i++ {
+		items = append(items, &LabelNamesCardinalityItem{LabelName: labelsWithValues[i].LabelName, LabelValuesCount: len(labelsWithValues[i].Values)})
 	}
 	return &LabelNamesCardinalityResponse{
 		LabelValuesCountTotal: valuesCountTotal,
@@ -214,6 +627,23 @@ func sortByValuesCountAndName(labelsWithValues []*ingester_client.LabelValues) {
 	})
 }
 
+func sortByName(labelsWithValues []*ingester_client.LabelValues) {
+	sort.Slice(labelsWithValues, func(i, j int) bool {
+		return labelsWithValues[i].LabelName < labelsWithValues[j].LabelName
+	})
+}
+
+// pageBounds holds the bounds of a page of items, start inclusive and end exclusive.
+type pageBounds struct {
+	start, end int
+}
+
+// paginate returns the bounds of the page of items starting at offset, with at most limit items.
+func paginate(count, offset, limit int) pageBounds {
+	start := util_math.Min(offset, count)
+	return pageBounds{start: start, end: util_math.Min(start+limit, count)}
+}
+
 func getValuesCountTotal(labelsWithValues []*ingester_client.LabelValues) int {
 	var valuesCountTotal int
 	for _, item := range labelsWithValues {
@@ -233,7 +663,7 @@ type LabelNamesCardinalityItem struct {
 	LabelValuesCount int    `json:"label_values_count"`
 }
 
-func toLabelValuesCardinalityResponse(seriesCountTotal uint64, cardinalityResponse *ingester_client.LabelValuesCardinalityResponse, limit int) *labelValuesCardinalityResponse {
+func toLabelValuesCardinalityResponse(seriesCountTotal uint64, cardinalityResponse *ingester_client.LabelValuesCardinalityResponse, params *cardinalityRequestParams) *labelValuesCardinalityResponse {
 	labels := make([]labelNamesCardinality, 0, len(cardinalityResponse.Items))
 
 	for _, cardinalityItem := range cardinalityResponse.Items {
@@ -248,11 +678,17 @@ func toLabelValuesCardinalityResponse(seriesCountTotal uint64, cardinalityRespon
 			})
 		}
 
+		if params.sortBy == sortByLabelValue {
+			sortByLabelValueOnly(cardinality)
+		} else {
+			sortBySeriesCountAndLabelValue(cardinality)
+		}
+
 		labels = append(labels, labelNamesCardinality{
 			LabelName:        cardinalityItem.LabelName,
 			LabelValuesCount: uint64(len(cardinalityItem.LabelValueSeries)),
 			SeriesCount:      labelValuesSeriesCountTotal,
-			Cardinality:      limitLabelValuesCardinality(sortBySeriesCountAndLabelValue(cardinality), limit),
+			Cardinality:      paginateLabelValuesCardinality(cardinality, params.offset, params.limit),
 		})
 	}
 
@@ -284,11 +720,17 @@ func sortBySeriesCountAndLabelValue(labelValuesCardinality []labelValuesCardinal
 	return labelValuesCardinality
 }
 
-func limitLabelValuesCardinality(labelValuesCardinality []labelValuesCardinality, limit int) []labelValuesCardinality {
-	if len(labelValuesCardinality) <= limit {
-		return labelValuesCardinality
-	}
-	return labelValuesCardinality[:limit]
+// sortByLabelValueOnly sorts labelValuesCardinality array in ASC order by LabelValue
+func sortByLabelValueOnly(labelValuesCardinality []labelValuesCardinality) []labelValuesCardinality {
+	sort.Slice(labelValuesCardinality, func(l, r int) bool {
+		return labelValuesCardinality[l].LabelValue < labelValuesCardinality[r].LabelValue
+	})
+	return labelValuesCardinality
+}
+
+func paginateLabelValuesCardinality(labelValuesCardinality []labelValuesCardinality, offset, limit int) []labelValuesCardinality {
+	page := paginate(len(labelValuesCardinality), offset, limit)
+	return labelValuesCardinality[page.start:page.end]
 }
 
 type labelValuesCardinality struct {
@@ -307,3 +749,36 @@ type labelValuesCardinalityResponse struct {
 	SeriesCountTotal uint64                  `json:"series_count_total"`
 	Labels           []labelNamesCardinality `json:"labels"`
 }
+
+type headCardinalityStatsResponse struct {
+	NumSeries                   uint64                 `json:"num_series"`
+	LabelValueCountByLabelName  []cardinalityStatsItem `json:"label_value_count_by_label_name"`
+	SeriesCountByMetricName     []cardinalityStatsItem `json:"series_count_by_metric_name"`
+	SeriesCountByLabelValuePair []cardinalityStatsItem `json:"series_count_by_label_value_pair"`
+}
+
+type activeSeriesResponse struct {
+	Data []labels.Labels `json:"data"`
+}
+
+type cardinalityStatsItem struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+func toHeadCardinalityStatsResponse(response *ingester_client.HeadCardinalityStatsResponse) *headCardinalityStatsResponse {
+	return &headCardinalityStatsResponse{
+		NumSeries:                   response.NumSeries,
+		LabelValueCountByLabelName:  toCardinalityStatsItems(response.LabelValueCountByLabelName),
+		SeriesCountByMetricName:     toCardinalityStatsItems(response.SeriesCountByMetricName),
+		SeriesCountByLabelValuePair: toCardinalityStatsItems(response.SeriesCountByLabelValuePair),
+	}
+}
+
+func toCardinalityStatsItems(items []*ingester_client.CardinalityStatsItem) []cardinalityStatsItem {
+	result := make([]cardinalityStatsItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, cardinalityStatsItem{Name: item.Name, Value: item.Value})
+	}
+	return result
+}
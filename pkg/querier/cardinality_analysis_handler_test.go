@@ -12,10 +12,13 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/teststorage"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/httpgrpc"
@@ -33,7 +36,7 @@ func TestLabelNamesCardinalityHandler(t *testing.T) {
 		{LabelName: "label-z", Values: []string{"0z", "1z", "2z"}},
 	}
 	distributor := mockDistributorLabelNamesAndValues(items, nil)
-	handler := createEnabledHandler(t, LabelNamesCardinalityHandler, distributor)
+	handler := createEnabledHandler(t, withQueryable(LabelNamesCardinalityHandler, nil), distributor)
 	ctx := user.InjectOrgID(context.Background(), "team-a")
 	request, err := http.NewRequestWithContext(ctx, "GET", "/ignored-url?limit=4", http.NoBody)
 	require.NoError(t, err)
@@ -98,7 +101,7 @@ func TestLabelNamesCardinalityHandler_MatchersTest(t *testing.T) {
 	for _, data := range td {
 		t.Run(data.name, func(t *testing.T) {
 			distributor := mockDistributorLabelNamesAndValues([]*client.LabelValues{}, nil)
-			handler := createEnabledHandler(t, LabelNamesCardinalityHandler, distributor)
+			handler := createEnabledHandler(t, withQueryable(LabelNamesCardinalityHandler, nil), distributor)
 			ctx := user.InjectOrgID(context.Background(), "team-a")
 			recorder := httptest.NewRecorder()
 			path := "/ignored-url"
@@ -150,7 +153,7 @@ func TestLabelNamesCardinalityHandler_LimitTest(t *testing.T) {
 			labelCountTotal := 30
 			items, valuesCountTotal := generateLabelValues(labelCountTotal)
 			distributor := mockDistributorLabelNamesAndValues(items, nil)
-			handler := createEnabledHandler(t, LabelNamesCardinalityHandler, distributor)
+			handler := createEnabledHandler(t, withQueryable(LabelNamesCardinalityHandler, nil), distributor)
 
 			ctx := user.InjectOrgID(context.Background(), "team-a")
 			path := "/ignored-url"
@@ -178,6 +181,120 @@ func TestLabelNamesCardinalityHandler_LimitTest(t *testing.T) {
 	}
 }
 
+func TestLabelNamesCardinalityHandler_OffsetAndSortByTest(t *testing.T) {
+	items := []*client.LabelValues{
+		{LabelName: "label-c", Values: []string{"0c"}},
+		{LabelName: "label-b", Values: []string{"0b", "1b"}},
+		{LabelName: "label-a", Values: []string{"0a", "1a"}},
+		{LabelName: "label-z", Values: []string{"0z", "1z", "2z"}},
+	}
+
+	td := []struct {
+		name          string
+		params        string
+		expectedItems []*LabelNamesCardinalityItem
+	}{
+		{
+			name:   "expected items sorted by label values count to be skipped by offset",
+			params: "offset=1&limit=2",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-a", LabelValuesCount: 2},
+				{LabelName: "label-b", LabelValuesCount: 2},
+			},
+		},
+		{
+			name:   "expected items sorted by label name",
+			params: "sort_by=label_name&limit=3",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-a", LabelValuesCount: 2},
+				{LabelName: "label-b", LabelValuesCount: 2},
+				{LabelName: "label-c", LabelValuesCount: 1},
+			},
+		},
+		{
+			name:   "expected items sorted by label name to be skipped by offset",
+			params: "sort_by=label_name&offset=3",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-z", LabelValuesCount: 3},
+			},
+		},
+		{
+			name:          "expected empty items list in response if offset param is greater than count of items",
+			params:        "offset=10",
+			expectedItems: []*LabelNamesCardinalityItem{},
+		},
+	}
+	for _, data := range td {
+		t.Run(data.name, func(t *testing.T) {
+			distributor := mockDistributorLabelNamesAndValues(items, nil)
+			handler := createEnabledHandler(t, withQueryable(LabelNamesCardinalityHandler, nil), distributor)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest("/ignored-url?"+data.params, "team-a"))
+
+			require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+			body := recorder.Result().Body
+			defer body.Close()
+			responseBody := LabelNamesCardinalityResponse{}
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(bodyContent, &responseBody))
+			require.Equal(t, 4, responseBody.LabelNamesCount)
+			require.Equal(t, 8, responseBody.LabelValuesCountTotal)
+			require.Equal(t, data.expectedItems, responseBody.Cardinality)
+		})
+	}
+}
+
+func TestLabelNamesCardinalityHandler_TimeRange(t *testing.T) {
+	queryable := newCardinalityTestQueryable(t)
+	distributor := &mockDistributor{}
+	handler := createEnabledHandler(t, withQueryable(LabelNamesCardinalityHandler, queryable), distributor)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest(`/ignored-url?start=0&end=100&selector={job="api"}`, "team-a"))
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	body := recorder.Result().Body
+	defer body.Close()
+	responseBody := LabelNamesCardinalityResponse{}
+	bodyContent, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bodyContent, &responseBody))
+	require.Equal(t, 3, responseBody.LabelNamesCount)
+	require.Equal(t, 6, responseBody.LabelValuesCountTotal)
+	require.Equal(t, []*LabelNamesCardinalityItem{
+		{LabelName: "instance", LabelValuesCount: 3},
+		{LabelName: "__name__", LabelValuesCount: 2},
+		{LabelName: "job", LabelValuesCount: 1},
+	}, responseBody.Cardinality)
+	distributor.AssertNotCalled(t, "LabelNamesAndValues", mock.Anything, mock.Anything)
+}
+
+func TestLabelNamesCardinalityHandler_TimeRangeClampedToMaxLabelsQueryLength(t *testing.T) {
+	limits := validation.Limits{CardinalityAnalysisEnabled: true, MaxLabelsQueryLength: model.Duration(10 * time.Second)}
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+	distributor := &mockDistributor{}
+	handler := LabelNamesCardinalityHandler(distributor, newCardinalityTestQueryable(t), overrides)
+
+	// The series are written at the start of the time range, which is out of the clamped time range.
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest(`/ignored-url?start=0&end=100`, "team-a"))
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	body := recorder.Result().Body
+	defer body.Close()
+	responseBody := LabelNamesCardinalityResponse{}
+	bodyContent, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bodyContent, &responseBody))
+	require.Equal(t, 0, responseBody.LabelNamesCount)
+	require.Equal(t, 0, responseBody.LabelValuesCountTotal)
+	require.Empty(t, responseBody.Cardinality)
+	distributor.AssertNotCalled(t, "LabelNamesAndValues", mock.Anything, mock.Anything)
+}
+
 func TestLabelNamesCardinalityHandler_DistributorError(t *testing.T) {
 	const labelNamesURL = "/label_names"
 
@@ -209,7 +326,7 @@ func TestLabelNamesCardinalityHandler_DistributorError(t *testing.T) {
 			limits.CardinalityAnalysisEnabled = true
 			overrides, err := validation.NewOverrides(limits, nil)
 			require.NoError(t, err)
-			handler := LabelNamesCardinalityHandler(distributor, overrides)
+			handler := LabelNamesCardinalityHandler(distributor, nil, overrides)
 			ctx := user.InjectOrgID(context.Background(), "test")
 
 			request, err := http.NewRequestWithContext(ctx, "GET", labelNamesURL, http.NoBody)
@@ -258,6 +375,26 @@ func TestLabelNamesCardinalityHandler_NegativeTests(t *testing.T) {
 			request:              createRequest("/ignored-url?limit=10&limit=20", "team-a"),
 			expectedErrorMessage: "multiple 'limit' params are not allowed",
 		},
+		{
+			name:                 "expected error if `offset` param is negative",
+			request:              createRequest("/ignored-url?offset=-1", "team-a"),
+			expectedErrorMessage: "'offset' param cannot be less than '0'",
+		},
+		{
+			name:                 "expected error if `sort_by` param is invalid",
+			request:              createRequest("/ignored-url?sort_by=series_count", "team-a"),
+			expectedErrorMessage: "invalid 'sort_by' param 'series_count', allowed values are: label_values_count, label_name",
+		},
+		{
+			name:                 "expected error if `end` param is provided without the `start` param",
+			request:              createRequest("/ignored-url?end=10", "team-a"),
+			expectedErrorMessage: "a single 'start' and a single 'end' params are required to analyze the cardinality in a time range",
+		},
+		{
+			name:                 "expected error if `start` param is invalid",
+			request:              createRequest("/ignored-url?start=foo&end=10", "team-a"),
+			expectedErrorMessage: "invalid 'start' param",
+		},
 		{
 			name:                        "expected error that cardinality analysis feature is disabled",
 			request:                     createRequest("/ignored-url", "team-a"),
@@ -274,7 +411,7 @@ func TestLabelNamesCardinalityHandler_NegativeTests(t *testing.T) {
 			}
 			overrides, err := validation.NewOverrides(limits, nil)
 			require.NoError(t, err)
-			handler := LabelNamesCardinalityHandler(mockDistributorLabelNamesAndValues([]*client.LabelValues{}, nil), overrides)
+			handler := LabelNamesCardinalityHandler(mockDistributorLabelNamesAndValues([]*client.LabelValues{}, nil), nil, overrides)
 
 			recorder := httptest.NewRecorder()
 
@@ -552,7 +689,7 @@ func TestLabelValuesCardinalityHandler_Success(t *testing.T) {
 			seriesCountTotal,
 			testData.labelValuesCardinality,
 			nil)
-		handler := createEnabledHandler(t, LabelValuesCardinalityHandler, distributor)
+		handler := createEnabledHandler(t, withQueryable(LabelValuesCardinalityHandler, nil), distributor)
 		ctx := user.InjectOrgID(context.Background(), "test")
 
 		t.Run("GET request "+testName, func(t *testing.T) {
@@ -597,6 +734,167 @@ func TestLabelValuesCardinalityHandler_Success(t *testing.T) {
 	}
 }
 
+func TestLabelValuesCardinalityHandler_OffsetAndSortBy(t *testing.T) {
+	cardinalityResponse := &client.LabelValuesCardinalityResponse{
+		Items: []*client.LabelValueSeriesCount{{
+			LabelName:        "env",
+			LabelValueSeries: map[string]uint64{"prod": 30, "dev": 5, "staging": 10, "qa": 5},
+		}},
+	}
+
+	tests := map[string]struct {
+		params              string
+		expectedCardinality []labelValuesCardinality
+	}{
+		"should skip the label values sorted by series count": {
+			params: "offset=1&limit=2",
+			expectedCardinality: []labelValuesCardinality{
+				{LabelValue: "staging", SeriesCount: 10},
+				{LabelValue: "dev", SeriesCount: 5},
+			},
+		},
+		"should sort the label values by label value": {
+			params: "sort_by=label_value&limit=3",
+			expectedCardinality: []labelValuesCardinality{
+				{LabelValue: "dev", SeriesCount: 5},
+				{LabelValue: "prod", SeriesCount: 30},
+				{LabelValue: "qa", SeriesCount: 5},
+			},
+		},
+		"should skip the label values sorted by label value": {
+			params: "sort_by=label_value&offset=3",
+			expectedCardinality: []labelValuesCardinality{
+				{LabelValue: "staging", SeriesCount: 10},
+			},
+		},
+		"should return no label values if the offset is greater than the number of label values": {
+			params:              "offset=10",
+			expectedCardinality: []labelValuesCardinality{},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			distributor := mockDistributorLabelValuesCardinality([]model.LabelName{"env"}, []*labels.Matcher(nil), 50, cardinalityResponse, nil)
+			handler := createEnabledHandler(t, withQueryable(LabelValuesCardinalityHandler, nil), distributor)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest("/label_values?label_names[]=env&"+testData.params, "team-a"))
+
+			require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+			body := recorder.Result().Body
+			defer func() { _ = body.Close() }()
+			responseBody := labelValuesCardinalityResponse{}
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(bodyContent, &responseBody))
+			require.Equal(t, labelValuesCardinalityResponse{
+				SeriesCountTotal: 50,
+				Labels: []labelNamesCardinality{{
+					LabelName:        "env",
+					LabelValuesCount: 4,
+					SeriesCount:      50,
+					Cardinality:      testData.expectedCardinality,
+				}},
+			}, responseBody)
+		})
+	}
+}
+
+func TestLabelValuesCardinalityHandler_TimeRange(t *testing.T) {
+	queryable := newCardinalityTestQueryable(t)
+	distributor := &mockDistributor{}
+	handler := createEnabledHandler(t, withQueryable(LabelValuesCardinalityHandler, queryable), distributor)
+
+	tests := map[string]struct {
+		params           string
+		expectedResponse labelValuesCardinalityResponse
+	}{
+		"should count the series of all the metrics if no selector is provided": {
+			params: "label_names[]=job&label_names[]=__name__",
+			expectedResponse: labelValuesCardinalityResponse{
+				SeriesCountTotal: 4,
+				Labels: []labelNamesCardinality{
+					{
+						LabelName:        "__name__",
+						LabelValuesCount: 2,
+						SeriesCount:      4,
+						Cardinality: []labelValuesCardinality{
+							{LabelValue: "up", SeriesCount: 3},
+							{LabelValue: "http_requests", SeriesCount: 1},
+						},
+					},
+					{
+						LabelName:        "job",
+						LabelValuesCount: 2,
+						SeriesCount:      4,
+						Cardinality: []labelValuesCardinality{
+							{LabelValue: "api", SeriesCount: 3},
+							{LabelValue: "db", SeriesCount: 1},
+						},
+					},
+				},
+			},
+		},
+		"should only count the series matching the selector": {
+			params: `label_names[]=instance&selector={job="api"}`,
+			expectedResponse: labelValuesCardinalityResponse{
+				SeriesCountTotal: 3,
+				Labels: []labelNamesCardinality{{
+					LabelName:        "instance",
+					LabelValuesCount: 3,
+					SeriesCount:      3,
+					Cardinality: []labelValuesCardinality{
+						{LabelValue: "a", SeriesCount: 1},
+						{LabelValue: "b", SeriesCount: 1},
+						{LabelValue: "c", SeriesCount: 1},
+					},
+				}},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest("/label_values?start=0&end=100&"+testData.params, "team-a"))
+
+			require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+			body := recorder.Result().Body
+			defer func() { _ = body.Close() }()
+			responseBody := labelValuesCardinalityResponse{}
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(bodyContent, &responseBody))
+			require.Equal(t, testData.expectedResponse, responseBody)
+			distributor.AssertNotCalled(t, "LabelValuesCardinality", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestLabelValuesCardinalityHandler_TimeRangeClampedToMaxLabelsQueryLength(t *testing.T) {
+	limits := validation.Limits{CardinalityAnalysisEnabled: true, MaxLabelsQueryLength: model.Duration(10 * time.Second)}
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+	distributor := &mockDistributor{}
+	handler := LabelValuesCardinalityHandler(distributor, newCardinalityTestQueryable(t), overrides)
+
+	// The series are written at the start of the time range, which is out of the clamped time range.
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/label_values?start=0&end=100&label_names[]=job", "team-a"))
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	body := recorder.Result().Body
+	defer func() { _ = body.Close() }()
+	responseBody := labelValuesCardinalityResponse{}
+	bodyContent, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bodyContent, &responseBody))
+	require.Equal(t, uint64(0), responseBody.SeriesCountTotal)
+	require.Equal(t, []labelNamesCardinality{{LabelName: "job", Cardinality: []labelValuesCardinality{}}}, responseBody.Labels)
+	distributor.AssertNotCalled(t, "LabelValuesCardinality", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestLabelValuesCardinalityHandler_FeatureFlag(t *testing.T) {
 	const labelValuesURL = "/label_values?label_names[]=foo"
 
@@ -631,7 +929,7 @@ func TestLabelValuesCardinalityHandler_FeatureFlag(t *testing.T) {
 			limits := validation.Limits{CardinalityAnalysisEnabled: testData.cardinalityAnalysisEnabled}
 			overrides, err := validation.NewOverrides(limits, nil)
 			require.NoError(t, err)
-			handler := LabelValuesCardinalityHandler(distributor, overrides)
+			handler := LabelValuesCardinalityHandler(distributor, nil, overrides)
 
 			recorder := httptest.NewRecorder()
 			handler.ServeHTTP(recorder, testData.request)
@@ -657,7 +955,7 @@ func TestLabelValuesCardinalityHandler_ParseError(t *testing.T) {
 		uint64(0),
 		&client.LabelValuesCardinalityResponse{Items: []*client.LabelValueSeriesCount{}},
 		nil)
-	handler := createEnabledHandler(t, LabelValuesCardinalityHandler, distributor)
+	handler := createEnabledHandler(t, withQueryable(LabelValuesCardinalityHandler, nil), distributor)
 	ctx := user.InjectOrgID(context.Background(), "test")
 
 	t.Run("should return bad request if no tenant id is provided", func(t *testing.T) {
@@ -697,6 +995,22 @@ func TestLabelValuesCardinalityHandler_ParseError(t *testing.T) {
 				url:                  "/label_values?label_names[]=hello&limit=501",
 				expectedErrorMessage: "'limit' param cannot be greater than '500'",
 			},
+			"offset param is a negative number": {
+				url:                  "/label_values?label_names[]=hello&offset=-1",
+				expectedErrorMessage: "'offset' param cannot be less than '0'",
+			},
+			"sort_by param is invalid": {
+				url:                  "/label_values?label_names[]=hello&sort_by=label_values_count",
+				expectedErrorMessage: "invalid 'sort_by' param 'label_values_count', allowed values are: series_count, label_value",
+			},
+			"start param is provided without the end param": {
+				url:                  "/label_values?label_names[]=hello&start=10",
+				expectedErrorMessage: "a single 'start' and a single 'end' params are required to analyze the cardinality in a time range",
+			},
+			"end param is before the start param": {
+				url:                  "/label_values?label_names[]=hello&start=20&end=10",
+				expectedErrorMessage: "'end' param cannot be before the 'start' param",
+			},
 		}
 		for testName, testData := range tests {
 			t.Run(testName, func(t *testing.T) {
@@ -750,7 +1064,7 @@ func TestLabelValuesCardinalityHandler_DistributorError(t *testing.T) {
 				uint64(0),
 				&client.LabelValuesCardinalityResponse{Items: []*client.LabelValueSeriesCount{}},
 				testData.distributorError)
-			handler := createEnabledHandler(t, LabelValuesCardinalityHandler, distributor)
+			handler := createEnabledHandler(t, withQueryable(LabelValuesCardinalityHandler, nil), distributor)
 			ctx := user.InjectOrgID(context.Background(), "test")
 
 			request, err := http.NewRequestWithContext(ctx, "GET", labelValuesURL, http.NoBody)
@@ -772,7 +1086,225 @@ func TestLabelValuesCardinalityHandler_DistributorError(t *testing.T) {
 	}
 }
 
-// createEnabledHandler creates a cardinalityHandler that can be either a LabelNamesCardinalityHandler or a LabelValuesCardinalityHandler
+func TestHeadCardinalityStatsHandler(t *testing.T) {
+	response := &client.HeadCardinalityStatsResponse{
+		NumSeries:                   3,
+		LabelValueCountByLabelName:  []*client.CardinalityStatsItem{{Name: "__name__", Value: 2}, {Name: "status", Value: 1}},
+		SeriesCountByMetricName:     []*client.CardinalityStatsItem{{Name: "test_1", Value: 2}, {Name: "test_2", Value: 1}},
+		SeriesCountByLabelValuePair: []*client.CardinalityStatsItem{{Name: "__name__=test_1", Value: 2}},
+	}
+
+	tests := map[string]struct {
+		url                string
+		distributorError   error
+		expectedLimit      int
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		"should return the statistics with the default limit": {
+			url:                "/head_stats",
+			expectedLimit:      defaultLimit,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{
+				"num_series": 3,
+				"label_value_count_by_label_name": [{"name": "__name__", "value": 2}, {"name": "status", "value": 1}],
+				"series_count_by_metric_name": [{"name": "test_1", "value": 2}, {"name": "test_2", "value": 1}],
+				"series_count_by_label_value_pair": [{"name": "__name__=test_1", "value": 2}]
+			}`,
+		},
+		"should return the statistics with the requested limit": {
+			url:                "/head_stats?limit=5",
+			expectedLimit:      5,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{
+				"num_series": 3,
+				"label_value_count_by_label_name": [{"name": "__name__", "value": 2}, {"name": "status", "value": 1}],
+				"series_count_by_metric_name": [{"name": "test_1", "value": 2}, {"name": "test_2", "value": 1}],
+				"series_count_by_label_value_pair": [{"name": "__name__=test_1", "value": 2}]
+			}`,
+		},
+		"should return an error if the limit is invalid": {
+			url:                "/head_stats?limit=501",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "'limit' param cannot be greater than '500'\n",
+		},
+		"should return internal server error if the distributor returns a non httpgrpc error": {
+			url:                "/head_stats",
+			distributorError:   fmt.Errorf("non httpgrpc error"),
+			expectedLimit:      defaultLimit,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       "non httpgrpc error\n",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			distributor := &mockDistributor{}
+			distributor.On("HeadCardinalityStats", mock.Anything, testData.expectedLimit).Return(response, testData.distributorError)
+			handler := createEnabledHandler(t, HeadCardinalityStatsHandler, distributor)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest(testData.url, "team-a"))
+
+			require.Equal(t, testData.expectedStatusCode, recorder.Result().StatusCode)
+
+			body := recorder.Result().Body
+			defer func() { _ = body.Close() }()
+
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			if testData.expectedStatusCode == http.StatusOK {
+				require.JSONEq(t, testData.expectedBody, string(bodyContent))
+			} else {
+				require.Equal(t, testData.expectedBody, string(bodyContent))
+			}
+		})
+	}
+}
+
+func TestHeadCardinalityStatsHandler_FeatureFlag(t *testing.T) {
+	distributor := &mockDistributor{}
+	limits := validation.Limits{CardinalityAnalysisEnabled: false}
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+	handler := HeadCardinalityStatsHandler(distributor, overrides)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/head_stats", "team-a"))
+
+	require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+	body := recorder.Result().Body
+	defer func() { _ = body.Close() }()
+
+	bodyContent, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "cardinality analysis is disabled for the tenant: team-a\n", string(bodyContent))
+	distributor.AssertNotCalled(t, "HeadCardinalityStats", mock.Anything, mock.Anything)
+}
+
+func TestActiveSeriesCardinalityHandler(t *testing.T) {
+	active := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "up", "job", "api", "instance", "a"),
+		labels.FromStrings(labels.MetricName, "up", "job", "api", "instance", "z"),
+	}
+
+	tests := map[string]struct {
+		url                  string
+		maxLabelsQueryLength time.Duration
+		distributorError     error
+		expectedMatchers     []*labels.Matcher
+		expectedLimit        int
+		expectedStatusCode   int
+		expectedBody         string
+	}{
+		"should return the active series": {
+			url:                `/active_series?selector={job="api"}`,
+			expectedMatchers:   []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "job", "api")},
+			expectedLimit:      defaultActiveSeriesLimit,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"data": [
+				{"__name__": "up", "instance": "a", "job": "api"},
+				{"__name__": "up", "instance": "z", "job": "api"}
+			]}`,
+		},
+		"should return an error if the selector is missing": {
+			url:                `/active_series`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "the 'selector' param is required\n",
+		},
+		"should return an error if the selector only has matchers matching the empty string": {
+			url:                `/active_series?selector={job=~".*"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "the 'selector' param must contain at least one non-empty matcher\n",
+		},
+		"should return an error if the limit is greater than the max": {
+			url:                `/active_series?selector={job="api"}&limit=10001`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "'limit' param cannot be greater than '10000'\n",
+		},
+		"should return an error if the limit is less than 1": {
+			url:                `/active_series?selector={job="api"}&limit=0`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "'limit' param cannot be less than '1'\n",
+		},
+		"should only return the first series up to the limit, including the series written in the time range": {
+			url:                `/active_series?selector={job="api"}&start=0&end=100&limit=2`,
+			expectedMatchers:   []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "job", "api")},
+			expectedLimit:      2,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"data": [
+				{"__name__": "http_requests", "instance": "c", "job": "api"},
+				{"__name__": "up", "instance": "a", "job": "api"}
+			]}`,
+		},
+		"should clamp the time range of the written series to the max labels query length": {
+			url:                  `/active_series?selector={job="api"}&start=0&end=100`,
+			maxLabelsQueryLength: 10 * time.Second,
+			expectedMatchers:     []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "job", "api")},
+			expectedLimit:        defaultActiveSeriesLimit,
+			expectedStatusCode:   http.StatusOK,
+			expectedBody: `{"data": [
+				{"__name__": "up", "instance": "a", "job": "api"},
+				{"__name__": "up", "instance": "z", "job": "api"}
+			]}`,
+		},
+		"should also return the series written in the time range": {
+			url:                `/active_series?selector={job="api"}&start=0&end=100`,
+			expectedMatchers:   []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "job", "api")},
+			expectedLimit:      defaultActiveSeriesLimit,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"data": [
+				{"__name__": "http_requests", "instance": "c", "job": "api"},
+				{"__name__": "up", "instance": "a", "job": "api"},
+				{"__name__": "up", "instance": "b", "job": "api"},
+				{"__name__": "up", "instance": "z", "job": "api"}
+			]}`,
+		},
+		"should return an error if the time range is invalid": {
+			url:                `/active_series?selector={job="api"}&start=100`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "a single 'start' and a single 'end' params are required to analyze the cardinality in a time range\n",
+		},
+		"should return internal server error if the distributor returns a non httpgrpc error": {
+			url:                `/active_series?selector={job="api"}`,
+			distributorError:   fmt.Errorf("non httpgrpc error"),
+			expectedMatchers:   []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "job", "api")},
+			expectedLimit:      defaultActiveSeriesLimit,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       "non httpgrpc error\n",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			distributor := &mockDistributor{}
+			distributor.On("ActiveSeries", mock.Anything, testData.expectedMatchers, testData.expectedLimit).Return(active, testData.distributorError)
+
+			limits := validation.Limits{CardinalityAnalysisEnabled: true, MaxLabelsQueryLength: model.Duration(testData.maxLabelsQueryLength)}
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+			handler := ActiveSeriesCardinalityHandler(distributor, newCardinalityTestQueryable(t), overrides)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest(testData.url, "team-a"))
+
+			require.Equal(t, testData.expectedStatusCode, recorder.Result().StatusCode)
+
+			body := recorder.Result().Body
+			defer func() { _ = body.Close() }()
+
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			if testData.expectedStatusCode == http.StatusOK {
+				require.JSONEq(t, testData.expectedBody, string(bodyContent))
+			} else {
+				require.Equal(t, testData.expectedBody, string(bodyContent))
+			}
+		})
+	}
+}
+
+// createEnabledHandler creates a cardinalityHandler that can be either a LabelNamesCardinalityHandler, a LabelValuesCardinalityHandler or a HeadCardinalityStatsHandler
 func createEnabledHandler(t *testing.T, cardinalityHandler func(Distributor, *validation.Overrides) http.Handler, distributor *mockDistributor) http.Handler {
 	limits := validation.Limits{CardinalityAnalysisEnabled: true}
 	overrides, err := validation.NewOverrides(limits, nil)
@@ -782,6 +1314,32 @@ func createEnabledHandler(t *testing.T, cardinalityHandler func(Distributor, *va
 	return handler
 }
 
+// withQueryable returns a function creating the cardinalityHandler with the given queryable, so that it can be passed to createEnabledHandler
+func withQueryable(cardinalityHandler func(Distributor, storage.Queryable, *validation.Overrides) http.Handler, queryable storage.Queryable) func(Distributor, *validation.Overrides) http.Handler {
+	return func(distributor Distributor, overrides *validation.Overrides) http.Handler {
+		return cardinalityHandler(distributor, queryable, overrides)
+	}
+}
+
+// newCardinalityTestQueryable returns a queryable with a few series of the "api" and "db" jobs.
+func newCardinalityTestQueryable(t *testing.T) storage.Queryable {
+	db := teststorage.New(t)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	app := db.Appender(context.Background())
+	for _, series := range []labels.Labels{
+		labels.FromStrings(labels.MetricName, "up", "job", "api", "instance", "a"),
+		labels.FromStrings(labels.MetricName, "up", "job", "api", "instance", "b"),
+		labels.FromStrings(labels.MetricName, "http_requests", "job", "api", "instance", "c"),
+		labels.FromStrings(labels.MetricName, "up", "job", "db", "instance", "d"),
+	} {
+		_, err := app.Append(0, series, 50, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+	return db
+}
+
 func createRequest(path string, tenantID string) *http.Request {
 	ctx := context.Background()
 	if len(tenantID) > 0 {
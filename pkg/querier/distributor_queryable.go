@@ -19,6 +19,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
 	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/chunkcompat"
@@ -36,6 +37,8 @@ type Distributor interface {
 	MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error)
 	LabelNamesAndValues(ctx context.Context, matchers []*labels.Matcher) (*client.LabelNamesAndValuesResponse, error)
 	LabelValuesCardinality(ctx context.Context, labelNames []model.LabelName, matchers []*labels.Matcher) (uint64, *client.LabelValuesCardinalityResponse, error)
+	HeadCardinalityStats(ctx context.Context, limit int) (*client.HeadCardinalityStatsResponse, error)
+	ActiveSeries(ctx context.Context, matchers []*labels.Matcher, limit int) ([]labels.Labels, error)
 }
 
 func newDistributorQueryable(distributor Distributor, iteratorFn chunkIteratorFunc, queryIngestersWithin time.Duration, logger log.Logger) QueryableWithFilter {
@@ -102,15 +105,26 @@ func (q *distributorQuerier) Select(_ bool, sp *storage.SelectHints, matchers ..
 		return storage.EmptySeriesSet()
 	}
 
+	// The distributor reports the partial results served on zone outages as warnings in the context.
+	collector, ctx := querywarnings.ContextWithCollector(ctx)
+
 	if sp != nil && sp.Func == "series" {
 		ms, err := q.distributor.MetricsForLabelMatchers(ctx, model.Time(minT), model.Time(maxT), matchers...)
 		if err != nil {
 			return storage.ErrSeriesSet(err)
 		}
-		return series.LabelsToSeriesSet(ms)
+		return withWarnings(series.LabelsToSeriesSet(ms), collector.Warnings())
 	}
 
-	return q.streamingSelect(ctx, minT, maxT, matchers)
+	return withWarnings(q.streamingSelect(ctx, minT, maxT, matchers), collector.Warnings())
+}
+
+// withWarnings returns the series set with the additional warnings, if any.
+func withWarnings(set storage.SeriesSet, warnings storage.Warnings) storage.SeriesSet {
+	if len(warnings) == 0 {
+		return set
+	}
+	return series.NewSeriesSetWithWarnings(set, warnings)
 }
 
 func (q *distributorQuerier) streamingSelect(ctx context.Context, minT, maxT int64, matchers []*labels.Matcher) storage.SeriesSet {
@@ -169,9 +183,10 @@ func (q *distributorQuerier) LabelValues(name string, matchers ...*labels.Matche
 		return nil, nil, nil
 	}
 
-	lvs, err := q.distributor.LabelValuesForLabelName(q.ctx, minT, model.Time(q.maxt), model.LabelName(name), matchers...)
+	collector, ctx := querywarnings.ContextWithCollector(q.ctx)
+	lvs, err := q.distributor.LabelValuesForLabelName(ctx, minT, model.Time(q.maxt), model.LabelName(name), matchers...)
 
-	return lvs, nil, err
+	return lvs, collector.Warnings(), err
 }
 
 func (q *distributorQuerier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
@@ -185,8 +200,9 @@ func (q *distributorQuerier) LabelNames(matchers ...*labels.Matcher) ([]string,
 		return nil, nil, nil
 	}
 
+	collector, ctx := querywarnings.ContextWithCollector(ctx)
 	ln, err := q.distributor.LabelNames(ctx, minT, model.Time(q.maxt), matchers...)
-	return ln, nil, err
+	return ln, collector.Warnings(), err
 }
 
 func (q *distributorQuerier) Close() error {
@@ -7,6 +7,7 @@ package querier
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
 	"github.com/grafana/mimir/pkg/storage/chunk"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/chunkcompat"
@@ -110,6 +112,24 @@ func TestDistributorQuerier_SelectShouldHonorQueryIngestersWithin(t *testing.T)
 	}
 }
 
+func TestDistributorQuerier_SelectSeriesShouldReturnTheWarnings(t *testing.T) {
+	distributor := &mockDistributor{}
+	distributor.On("MetricsForLabelMatchers", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		querywarnings.FromContext(args.Get(0).(context.Context)).Add(errors.New("partial results"))
+	}).Return([]labels.Labels{labels.FromStrings(labels.MetricName, "series_1")}, nil)
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	queryable := newDistributorQueryable(distributor, nil, 0, log.NewNopLogger())
+	querier, err := queryable.Querier(ctx, 0, 10)
+	require.NoError(t, err)
+
+	seriesSet := querier.Select(true, &storage.SelectHints{Start: 0, End: 10, Func: "series"})
+	require.True(t, seriesSet.Next())
+	assert.False(t, seriesSet.Next())
+	require.NoError(t, seriesSet.Err())
+	assert.Equal(t, storage.Warnings{errors.New("partial results")}, seriesSet.Warnings())
+}
+
 func TestDistributorQueryableFilter(t *testing.T) {
 	d := &mockDistributor{}
 	dq := newDistributorQueryable(d, nil, 1*time.Hour, log.NewNopLogger())
@@ -410,3 +430,13 @@ func (m *mockDistributor) LabelValuesCardinality(ctx context.Context, labelNames
 	args := m.Called(ctx, labelNames, matchers)
 	return args.Get(0).(uint64), args.Get(1).(*client.LabelValuesCardinalityResponse), args.Error(2)
 }
+
+func (m *mockDistributor) HeadCardinalityStats(ctx context.Context, limit int) (*client.HeadCardinalityStatsResponse, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).(*client.HeadCardinalityStatsResponse), args.Error(1)
+}
+
+func (m *mockDistributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher, limit int) ([]labels.Labels, error) {
+	args := m.Called(ctx, matchers, limit)
+	return args.Get(0).([]labels.Labels), args.Error(1)
+}
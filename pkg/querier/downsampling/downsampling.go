@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package downsampling
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// MaxSourceResolutionParam is the query parameter overriding the max resolution of the data read by the query.
+	MaxSourceResolutionParam = "max_source_resolution"
+
+	maxSourceResolutionAuto = "auto"
+	maxSourceResolutionRaw  = "raw"
+)
+
+type contextKey int
+
+var maxSourceResolutionCtxKey = contextKey(0)
+
+// MaxSourceResolution is the max resolution of the data read by a query, among the raw data and the downsampled
+// data of the downsampled blocks.
+type MaxSourceResolution struct {
+	// Auto is true if the resolution is chosen by the querier from the query step and the ranges of the
+	// range selectors.
+	Auto bool
+
+	// Resolution is the max resolution in milliseconds, 0 to only read the raw data. Ignored if Auto is true.
+	Resolution int64
+}
+
+// ParseMaxSourceResolution parses the value of the max_source_resolution query parameter: auto, raw, or a
+// duration like 5m or 1h.
+func ParseMaxSourceResolution(value string) (MaxSourceResolution, error) {
+	switch value {
+	case maxSourceResolutionAuto:
+		return MaxSourceResolution{Auto: true}, nil
+	case maxSourceResolutionRaw:
+		return MaxSourceResolution{}, nil
+	}
+
+	resolution, err := model.ParseDuration(value)
+	if err != nil {
+		return MaxSourceResolution{}, fmt.Errorf("invalid %s: it must be %s, %s or a duration: %w", MaxSourceResolutionParam, maxSourceResolutionAuto, maxSourceResolutionRaw, err)
+	}
+	return MaxSourceResolution{Resolution: time.Duration(resolution).Milliseconds()}, nil
+}
+
+// ContextWithMaxSourceResolution returns a context carrying the max source resolution of the query.
+func ContextWithMaxSourceResolution(ctx context.Context, resolution MaxSourceResolution) context.Context {
+	return context.WithValue(ctx, maxSourceResolutionCtxKey, resolution)
+}
+
+// MaxSourceResolutionFromContext returns the max source resolution of the query, and false if the query
+// doesn't override it.
+func MaxSourceResolutionFromContext(ctx context.Context) (MaxSourceResolution, bool) {
+	resolution, ok := ctx.Value(maxSourceResolutionCtxKey).(MaxSourceResolution)
+	return resolution, ok
+}
+
+// NewMaxSourceResolutionHandler returns a http.Handler injecting the max source resolution of the query, set by
+// the max_source_resolution parameter of the request, in the request context.
+func NewMaxSourceResolutionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.FormValue(MaxSourceResolutionParam)
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resolution, err := ParseMaxSourceResolution(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithMaxSourceResolution(r.Context(), resolution)))
+	})
+}
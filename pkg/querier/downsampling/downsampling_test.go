@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package downsampling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaxSourceResolution(t *testing.T) {
+	for value, expected := range map[string]MaxSourceResolution{
+		"auto": {Auto: true},
+		"raw":  {},
+		"0s":   {},
+		"5m":   {Resolution: (5 * time.Minute).Milliseconds()},
+		"1h":   {Resolution: time.Hour.Milliseconds()},
+	} {
+		t.Run(value, func(t *testing.T) {
+			actual, err := ParseMaxSourceResolution(value)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	for _, value := range []string{"", "5", "-1h", "foo"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := ParseMaxSourceResolution(value)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewMaxSourceResolutionHandler(t *testing.T) {
+	tests := map[string]struct {
+		url                string
+		expectedStatusCode int
+		expectedResolution MaxSourceResolution
+		expectedOverride   bool
+	}{
+		"should not override the resolution without the parameter": {
+			url:                "/api/v1/query?query=up",
+			expectedStatusCode: http.StatusOK,
+		},
+		"should override the resolution with the parameter": {
+			url:                "/api/v1/query?query=up&max_source_resolution=1h",
+			expectedStatusCode: http.StatusOK,
+			expectedResolution: MaxSourceResolution{Resolution: time.Hour.Milliseconds()},
+			expectedOverride:   true,
+		},
+		"should fail on an invalid parameter": {
+			url:                "/api/v1/query?query=up&max_source_resolution=foo",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				actualResolution MaxSourceResolution
+				actualOverride   bool
+			)
+			handler := NewMaxSourceResolutionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualResolution, actualOverride = MaxSourceResolutionFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, testData.url, nil).WithContext(context.Background())
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testData.expectedStatusCode, resp.Code)
+			assert.Equal(t, testData.expectedResolution, actualResolution)
+			assert.Equal(t, testData.expectedOverride, actualOverride)
+		})
+	}
+}
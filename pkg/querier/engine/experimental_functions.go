@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package engine
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// The experimental PromQL functions. They're registered in the PromQL parser and engine by
+// RegisterExperimentalFunctions, but the queriers and the rulers only run the queries using them for the tenants
+// which enabled them.
+const (
+	// MadOverTimeFunction is the median absolute deviation of the samples of each series in the range.
+	MadOverTimeFunction = "mad_over_time"
+	// DoubleExponentialSmoothingFunction is the holt_winters function, with the name it has in the recent PromQL versions.
+	DoubleExponentialSmoothingFunction = "double_exponential_smoothing"
+)
+
+// ExperimentalFunctions is the list of the experimental PromQL functions.
+var ExperimentalFunctions = []string{MadOverTimeFunction, DoubleExponentialSmoothingFunction}
+
+var registerExperimentalFunctionsOnce sync.Once
+
+// RegisterExperimentalFunctions registers the experimental PromQL functions in the PromQL parser and engine. Their
+// functions are global, so it must be called at startup, before any query is parsed. It's safe to call it multiple
+// times.
+func RegisterExperimentalFunctions() {
+	registerExperimentalFunctionsOnce.Do(func() {
+		registerFunction(&parser.Function{
+			Name:       MadOverTimeFunction,
+			ArgTypes:   []parser.ValueType{parser.ValueTypeMatrix},
+			ReturnType: parser.ValueTypeVector,
+		}, funcMadOverTime)
+
+		holtWinters := *parser.Functions["holt_winters"]
+		holtWinters.Name = DoubleExponentialSmoothingFunction
+		registerFunction(&holtWinters, promql.FunctionCalls["holt_winters"])
+	})
+}
+
+// registerFunction registers the function in the PromQL parser and engine, unless a function with the same name
+// is already registered, so that the upstream implementation takes precedence once it's available.
+func registerFunction(fn *parser.Function, call promql.FunctionCall) {
+	if _, ok := parser.Functions[fn.Name]; ok {
+		return
+	}
+	parser.Functions[fn.Name] = fn
+	promql.FunctionCalls[fn.Name] = call
+}
+
+// === mad_over_time(Matrix parser.ValueTypeMatrix) Vector ===
+func funcMadOverTime(vals []parser.Value, _ parser.Expressions, enh *promql.EvalNodeHelper) promql.Vector {
+	points := vals[0].(promql.Matrix)[0].Points
+
+	values := make([]float64, 0, len(points))
+	for _, p := range points {
+		values = append(values, p.V)
+	}
+	med := median(values)
+
+	for i, v := range values {
+		values[i] = math.Abs(v - med)
+	}
+	return append(enh.Out, promql.Sample{
+		Point: promql.Point{V: median(values)},
+	})
+}
+
+// median returns the median of the values, which are sorted in place.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid]
+	}
+	return (values[mid-1] + values[mid]) / 2
+}
+
+// UsesExperimentalFunctions returns whether the query may use experimental PromQL functions, without parsing it.
+func UsesExperimentalFunctions(qs string) bool {
+	for _, name := range ExperimentalFunctions {
+		if strings.Contains(qs, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsedExperimentalFunctions returns the experimental PromQL functions used by the node, each one once.
+func UsedExperimentalFunctions(node parser.Node) []string {
+	var used []string
+	parser.Inspect(node, func(node parser.Node, _ []parser.Node) error {
+		call, ok := node.(*parser.Call)
+		if !ok {
+			return nil
+		}
+		for _, name := range ExperimentalFunctions {
+			if call.Func.Name == name && !contains(used, name) {
+				used = append(used, name)
+			}
+		}
+		return nil
+	})
+	return used
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperimentalFunctions(t *testing.T) {
+	RegisterExperimentalFunctions()
+
+	test, err := promql.NewTest(t, `
+load 1m
+	metric{series="1"} 1 2 3 4 10
+	metric{series="2"} 5 5 5 5 5
+	metric{series="3"} 1 2 3 4
+`)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	engine := promql.NewEngine(promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute})
+	eval := func(qs string) promql.Vector {
+		q, err := engine.NewInstantQuery(test.Queryable(), nil, qs, time.Unix(4*60, 0))
+		require.NoError(t, err)
+		defer q.Close()
+
+		res := q.Exec(context.Background())
+		require.NoError(t, res.Err)
+		vector, err := res.Vector()
+		require.NoError(t, err)
+		return append(promql.Vector{}, vector...)
+	}
+
+	t.Run("mad_over_time", func(t *testing.T) {
+		values := map[string]float64{}
+		for _, s := range eval(`mad_over_time(metric[5m])`) {
+			assert.Empty(t, s.Metric.Get("__name__"))
+			values[s.Metric.Get("series")] = s.V
+		}
+
+		// The median of 1, 2, 3, 4, 10 is 3, and the median of the deviations 2, 1, 0, 1, 7 is 1.
+		// The median of 1, 2, 3, 4 is 2.5, and the median of the deviations 1.5, 0.5, 0.5, 1.5 is 1.
+		assert.Equal(t, map[string]float64{"1": 1, "2": 0, "3": 1}, values)
+	})
+
+	t.Run("double_exponential_smoothing", func(t *testing.T) {
+		assert.Equal(t, eval(`holt_winters(metric[5m], 0.5, 0.5)`), eval(`double_exponential_smoothing(metric[5m], 0.5, 0.5)`))
+	})
+}
+
+func TestUsedExperimentalFunctions(t *testing.T) {
+	RegisterExperimentalFunctions()
+
+	for qs, expected := range map[string][]string{
+		`rate(metric[5m])`:          nil,
+		`mad_over_time(metric[5m])`: {MadOverTimeFunction},
+		`sum(mad_over_time(metric[5m])) / sum(mad_over_time(other[5m]))`:                  {MadOverTimeFunction},
+		`double_exponential_smoothing(metric[5m], 0.5, 0.5) > mad_over_time(metric[1h:])`: {DoubleExponentialSmoothingFunction, MadOverTimeFunction},
+	} {
+		t.Run(qs, func(t *testing.T) {
+			expr, err := parser.ParseExpr(qs)
+			require.NoError(t, err)
+			assert.Equal(t, expected, UsedExperimentalFunctions(expr))
+		})
+	}
+}
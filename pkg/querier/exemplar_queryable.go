@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+type mergedExemplarQueryable struct {
+	queryables []storage.ExemplarQueryable
+}
+
+// NewMergedExemplarQueryable returns an ExemplarQueryable which concurrently queries the exemplars from all the input
+// queryables, and returns the exemplars merged by series and deduplicated by timestamp.
+func NewMergedExemplarQueryable(queryables ...storage.ExemplarQueryable) storage.ExemplarQueryable {
+	return &mergedExemplarQueryable{queryables: queryables}
+}
+
+func (m *mergedExemplarQueryable) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	queriers := make([]storage.ExemplarQuerier, 0, len(m.queryables))
+	for _, queryable := range m.queryables {
+		q, err := queryable.ExemplarQuerier(ctx)
+		if err != nil {
+			return nil, err
+		}
+		queriers = append(queriers, q)
+	}
+	return &mergedExemplarQuerier{queriers: queriers}, nil
+}
+
+type mergedExemplarQuerier struct {
+	queriers []storage.ExemplarQuerier
+}
+
+func (m *mergedExemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	sets := make([][]mimirpb.TimeSeries, len(m.queriers))
+
+	g := errgroup.Group{}
+	for idx, q := range m.queriers {
+		// Change variables scope since it will be used in a goroutine.
+		idx, q := idx, q
+
+		g.Go(func() error {
+			results, err := q.Select(start, end, matchers...)
+			if err != nil {
+				return err
+			}
+
+			series := make([]mimirpb.TimeSeries, 0, len(results))
+			for _, r := range results {
+				series = append(series, mimirpb.TimeSeries{
+					Labels:    mimirpb.FromLabelsToLabelAdapters(r.SeriesLabels),
+					Exemplars: mimirpb.FromExemplarsToExemplarProtos(r.Exemplars),
+				})
+			}
+			sets[idx] = series
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	merged := mimir_tsdb.MergeExemplars(0, sets...)
+	result := make([]exemplar.QueryResult, 0, len(merged))
+	for _, s := range merged {
+		result = append(result, exemplar.QueryResult{
+			SeriesLabels: mimirpb.FromLabelAdaptersToLabels(s.Labels),
+			Exemplars:    mimirpb.FromExemplarProtosToExemplars(s.Exemplars),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergedExemplarQueryable(t *testing.T) {
+	var (
+		seriesA   = labels.FromStrings(labels.MetricName, "a")
+		seriesB   = labels.FromStrings(labels.MetricName, "b")
+		exemplar1 = exemplar.Exemplar{Labels: labels.FromStrings("trace_id", "1"), Value: 1, Ts: 1000}
+		exemplar2 = exemplar.Exemplar{Labels: labels.FromStrings("trace_id", "2"), Value: 2, Ts: 2000}
+		matchers  = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}
+	)
+
+	t.Run("should merge the exemplars of all the queryables", func(t *testing.T) {
+		ingesters := staticExemplarQueryable{results: []exemplar.QueryResult{
+			{SeriesLabels: seriesB, Exemplars: []exemplar.Exemplar{exemplar2}},
+		}}
+		stores := staticExemplarQueryable{results: []exemplar.QueryResult{
+			{SeriesLabels: seriesB, Exemplars: []exemplar.Exemplar{exemplar1, exemplar2}},
+			{SeriesLabels: seriesA, Exemplars: []exemplar.Exemplar{exemplar1}},
+		}}
+
+		q, err := NewMergedExemplarQueryable(ingesters, stores).ExemplarQuerier(context.Background())
+		require.NoError(t, err)
+
+		actual, err := q.Select(0, 3000, matchers)
+		require.NoError(t, err)
+		assert.Equal(t, []exemplar.QueryResult{
+			{SeriesLabels: seriesA, Exemplars: []exemplar.Exemplar{exemplar1}},
+			{SeriesLabels: seriesB, Exemplars: []exemplar.Exemplar{exemplar1, exemplar2}},
+		}, actual)
+	})
+
+	t.Run("should fail if any queryable fails", func(t *testing.T) {
+		ingesters := staticExemplarQueryable{}
+		stores := staticExemplarQueryable{err: errors.New("store-gateways unavailable")}
+
+		q, err := NewMergedExemplarQueryable(ingesters, stores).ExemplarQuerier(context.Background())
+		require.NoError(t, err)
+
+		_, err = q.Select(0, 3000, matchers)
+		require.EqualError(t, err, "store-gateways unavailable")
+	})
+}
+
+type staticExemplarQueryable struct {
+	results []exemplar.QueryResult
+	err     error
+}
+
+func (s staticExemplarQueryable) ExemplarQuerier(context.Context) (storage.ExemplarQuerier, error) {
+	return s, nil
+}
+
+func (s staticExemplarQueryable) Select(int64, int64, ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	return s.results, s.err
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	v1 "github.com/prometheus/prometheus/web/api/v1"
+
+	"github.com/grafana/mimir/pkg/querier/engine"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// ExperimentalFunctionsLimits is the per-tenant configuration of the experimental PromQL functions.
+type ExperimentalFunctionsLimits interface {
+	// ExperimentalPromQLFunctions returns the experimental PromQL functions the queries of the tenant can use.
+	ExperimentalPromQLFunctions(userID string) []string
+}
+
+// ExperimentalFunctionsEngine is a PromQL engine whose queries fail if they use experimental PromQL functions
+// which aren't enabled for all the tenants of the query.
+type ExperimentalFunctionsEngine struct {
+	v1.QueryEngine
+
+	limits ExperimentalFunctionsLimits
+}
+
+// NewExperimentalFunctionsEngine wraps the engine to check the experimental PromQL functions used by its queries.
+func NewExperimentalFunctionsEngine(engine v1.QueryEngine, limits ExperimentalFunctionsLimits) *ExperimentalFunctionsEngine {
+	return &ExperimentalFunctionsEngine{QueryEngine: engine, limits: limits}
+}
+
+// NewInstantQuery implements v1.QueryEngine.
+func (e *ExperimentalFunctionsEngine) NewInstantQuery(q storage.Queryable, opts *promql.QueryOpts, qs string, ts time.Time) (promql.Query, error) {
+	query, err := e.QueryEngine.NewInstantQuery(q, opts, qs, ts)
+	if err != nil {
+		return nil, err
+	}
+	return e.wrap(query), nil
+}
+
+// NewRangeQuery implements v1.QueryEngine.
+func (e *ExperimentalFunctionsEngine) NewRangeQuery(q storage.Queryable, opts *promql.QueryOpts, qs string, start, end time.Time, interval time.Duration) (promql.Query, error) {
+	query, err := e.QueryEngine.NewRangeQuery(q, opts, qs, start, end, interval)
+	if err != nil {
+		return nil, err
+	}
+	return e.wrap(query), nil
+}
+
+func (e *ExperimentalFunctionsEngine) wrap(query promql.Query) promql.Query {
+	functions := engine.UsedExperimentalFunctions(query.Statement())
+	if len(functions) == 0 {
+		return query
+	}
+	return &experimentalFunctionsQuery{Query: query, functions: functions, limits: e.limits}
+}
+
+// experimentalFunctionsQuery is a query using experimental PromQL functions. The tenants of the query are only known
+// once it's executed, so the functions are checked then.
+type experimentalFunctionsQuery struct {
+	promql.Query
+
+	functions []string
+	limits    ExperimentalFunctionsLimits
+}
+
+// Exec implements promql.Query.
+func (q *experimentalFunctionsQuery) Exec(ctx context.Context) *promql.Result {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return &promql.Result{Err: err}
+	}
+
+	if err := CheckExperimentalFunctions(q.limits, tenantIDs, q.functions); err != nil {
+		return &promql.Result{Err: err}
+	}
+
+	return q.Query.Exec(ctx)
+}
+
+// CheckExperimentalFunctions returns an error if any of the experimental PromQL functions isn't enabled for all
+// the tenants.
+func CheckExperimentalFunctions(limits ExperimentalFunctionsLimits, tenantIDs []string, functions []string) error {
+	for _, tenantID := range tenantIDs {
+		enabled := limits.ExperimentalPromQLFunctions(tenantID)
+		for _, function := range functions {
+			if !util.StringsContain(enabled, function) {
+				return validation.NewExperimentalPromQLFunctionError(function)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	querier_engine "github.com/grafana/mimir/pkg/querier/engine"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+type experimentalFunctionsLimitsMock map[string][]string
+
+func (m experimentalFunctionsLimitsMock) ExperimentalPromQLFunctions(userID string) []string {
+	return m[userID]
+}
+
+func TestExperimentalFunctionsEngine(t *testing.T) {
+	querier_engine.RegisterExperimentalFunctions()
+
+	// Set a multi tenant resolver.
+	tenant.WithDefaultResolver(tenant.NewMultiResolver())
+
+	test, err := promql.NewTest(t, `
+load 1m
+	metric{series="1"} 1 2 3 4 10
+`)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	limits := experimentalFunctionsLimitsMock{
+		"enabled":       {"mad_over_time"},
+		"all-enabled":   {"mad_over_time", "double_exponential_smoothing"},
+		"not-enabled":   nil,
+		"other-enabled": {"double_exponential_smoothing"},
+	}
+	engine := NewExperimentalFunctionsEngine(promql.NewEngine(promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute}), limits)
+
+	tests := map[string]struct {
+		tenantID    string
+		query       string
+		expectedErr error
+	}{
+		"should run the queries without experimental functions": {
+			tenantID: "not-enabled",
+			query:    `rate(metric[5m])`,
+		},
+		"should run the queries with the experimental functions enabled for the tenant": {
+			tenantID: "enabled",
+			query:    `mad_over_time(metric[5m])`,
+		},
+		"should fail the queries with experimental functions not enabled for the tenant": {
+			tenantID:    "other-enabled",
+			query:       `mad_over_time(metric[5m])`,
+			expectedErr: validation.NewExperimentalPromQLFunctionError("mad_over_time"),
+		},
+		"should fail the queries with any experimental function not enabled for the tenant": {
+			tenantID:    "enabled",
+			query:       `mad_over_time(metric[5m]) + double_exponential_smoothing(metric[5m], 0.5, 0.5)`,
+			expectedErr: validation.NewExperimentalPromQLFunctionError("double_exponential_smoothing"),
+		},
+		"should fail the queries with experimental functions not enabled for all the tenants of the query": {
+			tenantID:    "all-enabled|not-enabled",
+			query:       `mad_over_time(metric[5m])`,
+			expectedErr: validation.NewExperimentalPromQLFunctionError("mad_over_time"),
+		},
+		"should run the queries with experimental functions enabled for all the tenants of the query": {
+			tenantID: "all-enabled|enabled",
+			query:    `mad_over_time(metric[5m])`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), testData.tenantID)
+
+			instant, err := engine.NewInstantQuery(test.Queryable(), nil, testData.query, time.Unix(4*60, 0))
+			require.NoError(t, err)
+			rng, err := engine.NewRangeQuery(test.Queryable(), nil, testData.query, time.Unix(0, 0), time.Unix(4*60, 0), time.Minute)
+			require.NoError(t, err)
+
+			for _, q := range []promql.Query{instant, rng} {
+				res := q.Exec(ctx)
+				q.Close()
+				if testData.expectedErr != nil {
+					assert.Equal(t, testData.expectedErr, res.Err)
+				} else {
+					assert.NoError(t, res.Err)
+					assert.NotNil(t, res.Value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"unsafe"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/util/limiter"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// pointSize is the size of a point evaluated by the PromQL engine.
+var pointSize = uint64(unsafe.Sizeof(promql.Point{}))
+
+// memoryTrackingSeriesSet accounts the estimated memory the PromQL engine needs to load each series of the
+// wrapped SeriesSet to the memory consumption of the query: the size of the series labels and of the points
+// evaluated for the series over the time range of the selector. Once the limit is reached, the iteration stops
+// and the SeriesSet returns the limit error.
+type memoryTrackingSeriesSet struct {
+	storage.SeriesSet
+
+	tracker         *limiter.MemoryConsumptionTracker
+	pointsPerSeries uint64
+	err             error
+}
+
+func newMemoryTrackingSeriesSet(set storage.SeriesSet, tracker *limiter.MemoryConsumptionTracker, sp *storage.SelectHints) storage.SeriesSet {
+	return &memoryTrackingSeriesSet{
+		SeriesSet:       set,
+		tracker:         tracker,
+		pointsPerSeries: estimatedPointsPerSeries(sp),
+	}
+}
+
+func (s *memoryTrackingSeriesSet) Next() bool {
+	if s.err != nil || !s.SeriesSet.Next() {
+		return false
+	}
+
+	if err := s.tracker.IncreaseMemoryConsumption(estimatedSeriesBytes(s.SeriesSet.At().Labels(), s.pointsPerSeries)); err != nil {
+		s.err = validation.LimitError(err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *memoryTrackingSeriesSet) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.SeriesSet.Err()
+}
+
+// estimatedPointsPerSeries returns the number of points the PromQL engine evaluates for each series selected
+// with the input hints: a point per step for the range queries, and a single point otherwise. The series
+// selected for the metadata APIs have no points.
+func estimatedPointsPerSeries(sp *storage.SelectHints) uint64 {
+	if sp.Func == "series" {
+		return 0
+	}
+	if sp.Step <= 0 || sp.End < sp.Start {
+		return 1
+	}
+	return uint64((sp.End-sp.Start)/sp.Step) + 1
+}
+
+// estimatedSeriesBytes returns the estimated memory of a series with the input labels and number of points.
+func estimatedSeriesBytes(lbls labels.Labels, points uint64) uint64 {
+	size := uint64(0)
+	for _, l := range lbls {
+		size += uint64(len(l.Name) + len(l.Value))
+	}
+	return size + points*pointSize
+}
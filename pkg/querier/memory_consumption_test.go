@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestQuerier_MaxEstimatedMemory(t *testing.T) {
+	var (
+		queryStart = mustParseTime("2021-11-01T06:00:00Z")
+		queryEnd   = mustParseTime("2021-11-01T07:00:00Z")
+		queryStep  = time.Minute
+	)
+
+	var cfg Config
+	flagext.DefaultValues(&cfg)
+	cfg.QueryIngestersWithin = 0 // Always query ingesters in this test.
+
+	distributor := &mockDistributor{}
+	distributor.On("QueryStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		&client.QueryStreamResponse{
+			Chunkseries: []client.TimeSeriesChunk{
+				{
+					Labels: []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "one"}, {Name: "series", Value: "1"}},
+					Chunks: convertToChunks(t, []mimirpb.Sample{{TimestampMs: queryStart.UnixMilli(), Value: 1}}),
+				},
+				{
+					Labels: []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "one"}, {Name: "series", Value: "2"}},
+					Chunks: convertToChunks(t, []mimirpb.Sample{{TimestampMs: queryStart.UnixMilli(), Value: 2}}),
+				},
+			},
+		},
+		nil)
+
+	engine := promql.NewEngine(promql.EngineOpts{
+		Logger:     log.NewNopLogger(),
+		MaxSamples: 1e6,
+		Timeout:    1 * time.Minute,
+	})
+
+	tests := map[string]struct {
+		maxEstimatedMemoryPerQuery  int
+		maxEstimatedMemoryPerTenant int
+		expectedErr                 string
+	}{
+		"should succeed if the limits are disabled": {},
+		"should succeed if the query doesn't exceed the limits": {
+			// The tenant limit fits a single query.
+			maxEstimatedMemoryPerQuery:  3000,
+			maxEstimatedMemoryPerTenant: 3000,
+		},
+		"should fail if the query exceeds the per-query limit": {
+			// The points of a single series, on 61 steps, exceed the limit.
+			maxEstimatedMemoryPerQuery: 500,
+			expectedErr:                "err-mimir-max-estimated-memory-per-query",
+		},
+		"should fail if the query exceeds the per-tenant limit": {
+			maxEstimatedMemoryPerTenant: 500,
+			expectedErr:                 "err-mimir-tenant-max-estimated-memory",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := defaultLimitsConfig()
+			limits.MaxEstimatedMemoryPerQuery = testData.maxEstimatedMemoryPerQuery
+			limits.MaxEstimatedMemoryPerTenant = testData.maxEstimatedMemoryPerTenant
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			queryable, _, _ := New(cfg, overrides, distributor, nil, nil, log.NewNopLogger(), nil)
+
+			// The queries are run multiple times, to check the memory of the completed queries is given back to the tenant.
+			for i := 0; i < 3; i++ {
+				query, err := engine.NewRangeQuery(queryable, nil, `sum(one)`, queryStart, queryEnd, queryStep)
+				require.NoError(t, err)
+
+				r := query.Exec(user.InjectOrgID(context.Background(), "user-1"))
+				if testData.expectedErr == "" {
+					require.NoError(t, r.Err)
+					m, err := r.Matrix()
+					require.NoError(t, err)
+					require.Len(t, m, 1)
+				} else {
+					require.Error(t, r.Err)
+					assert.Contains(t, r.Err.Error(), testData.expectedErr)
+				}
+				query.Close()
+			}
+		})
+	}
+}
+
+func TestEstimatedPointsPerSeries(t *testing.T) {
+	assert.Equal(t, uint64(61), estimatedPointsPerSeries(&storage.SelectHints{Start: 0, End: 3600000, Step: 60000}))
+	assert.Equal(t, uint64(1), estimatedPointsPerSeries(&storage.SelectHints{Start: 0, End: 3600000}))
+	assert.Equal(t, uint64(0), estimatedPointsPerSeries(&storage.SelectHints{Start: 0, End: 3600000, Step: 60000, Func: "series"}))
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/prometheus/prometheus/scrape"
 
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -33,25 +34,34 @@ type metricMetadata struct {
 }
 
 type metadataResult struct {
-	Status string                      `json:"status"`
-	Data   map[string][]metricMetadata `json:"data,omitempty"`
-	Error  string                      `json:"error,omitempty"`
+	Status   string                      `json:"status"`
+	Data     map[string][]metricMetadata `json:"data,omitempty"`
+	Error    string                      `json:"error,omitempty"`
+	Warnings []string                    `json:"warnings,omitempty"`
 }
 
 // NewMetadataHandler creates a http.Handler for serving metric metadata held by
 // Mimir for a given tenant. It is kept and returned as a set.
 func NewMetadataHandler(m MetadataSupplier) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp, err := m.MetricsMetadata(r.Context())
+		// The distributor reports the partial results served on zone outages as warnings in the context.
+		collector, ctx := querywarnings.ContextWithCollector(r.Context())
+
+		resp, err := m.MetricsMetadata(ctx)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			util.WriteJSONResponse(w, metadataResult{Status: statusError, Error: err.Error()})
 			return
 		}
 
-		// Put all the elements of the pseudo-set into a map of slices for marshalling.
+		// Put all the elements of the pseudo-set into a map of slices for marshalling, only keeping the requested
+		// metric, if any.
+		metric := r.FormValue("metric")
 		metrics := map[string][]metricMetadata{}
 		for _, m := range resp {
+			if metric != "" && m.Metric != metric {
+				continue
+			}
 			ms, ok := metrics[m.Metric]
 			if !ok {
 				// Most metrics will only hold 1 copy of the same metadata.
@@ -61,6 +71,11 @@ func NewMetadataHandler(m MetadataSupplier) http.Handler {
 			metrics[m.Metric] = append(ms, metricMetadata{Type: string(m.Type), Help: m.Help, Unit: m.Unit})
 		}
 
-		util.WriteJSONResponse(w, metadataResult{Status: statusSuccess, Data: metrics})
+		var warnings []string
+		for _, w := range collector.Warnings() {
+			warnings = append(warnings, w.Error())
+		}
+
+		util.WriteJSONResponse(w, metadataResult{Status: statusSuccess, Data: metrics, Warnings: warnings})
 	})
 }
@@ -6,6 +6,8 @@
 package querier
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +17,8 @@ import (
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/querier/querywarnings"
 )
 
 func TestMetadataHandler_Success(t *testing.T) {
@@ -55,6 +59,45 @@ func TestMetadataHandler_Success(t *testing.T) {
 	require.JSONEq(t, expectedJSON, string(responseBody))
 }
 
+func TestMetadataHandler_ShouldOnlyReturnTheRequestedMetric(t *testing.T) {
+	d := &mockDistributor{}
+	d.On("MetricsMetadata", mock.Anything).Return(
+		[]scrape.MetricMetadata{
+			{Metric: "alertmanager_dispatcher_aggregation_groups", Help: "Number of active aggregation groups", Type: "gauge", Unit: ""},
+			{Metric: "alertmanager_alerts_received_total", Help: "The total number of received alerts.", Type: "counter", Unit: ""},
+		},
+		nil)
+
+	handler := NewMetadataHandler(d)
+
+	request, err := http.NewRequest("GET", "/metadata?metric=alertmanager_alerts_received_total", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	responseBody, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+
+	expectedJSON := `
+	{
+		"status": "success",
+		"data": {
+			"alertmanager_alerts_received_total": [
+				{
+					"help": "The total number of received alerts.",
+					"type": "counter",
+					"unit": ""
+				}
+			]
+		}
+	}
+	`
+
+	require.JSONEq(t, expectedJSON, string(responseBody))
+}
+
 func TestMetadataHandler_Error(t *testing.T) {
 	d := &mockDistributor{}
 	d.On("MetricsMetadata", mock.Anything).Return([]scrape.MetricMetadata{}, fmt.Errorf("no user id"))
@@ -80,3 +123,31 @@ func TestMetadataHandler_Error(t *testing.T) {
 
 	require.JSONEq(t, expectedJSON, string(responseBody))
 }
+
+func TestMetadataHandler_Warnings(t *testing.T) {
+	d := &mockDistributor{}
+	d.On("MetricsMetadata", mock.Anything).Run(func(args mock.Arguments) {
+		querywarnings.FromContext(args.Get(0).(context.Context)).Add(errors.New("partial results"))
+	}).Return([]scrape.MetricMetadata{}, nil)
+
+	handler := NewMetadataHandler(d)
+
+	request, err := http.NewRequest("GET", "/metadata", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	responseBody, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+
+	expectedJSON := `
+	{
+		"status": "success",
+		"warnings": ["partial results"]
+	}
+	`
+
+	require.JSONEq(t, expectedJSON, string(responseBody))
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/scrape"
+	"golang.org/x/sync/errgroup"
+)
+
+type mergedMetadataSupplier struct {
+	suppliers []MetadataSupplier
+}
+
+// NewMergedMetadataSupplier returns a MetadataSupplier which concurrently fetches the metric metadata from all
+// the input suppliers, and returns the deduplicated metadata in the order of the suppliers.
+func NewMergedMetadataSupplier(suppliers ...MetadataSupplier) MetadataSupplier {
+	return &mergedMetadataSupplier{suppliers: suppliers}
+}
+
+func (m *mergedMetadataSupplier) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error) {
+	sets := make([][]scrape.MetricMetadata, len(m.suppliers))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	for idx, supplier := range m.suppliers {
+		// Change variables scope since it will be used in a goroutine.
+		idx, supplier := idx, supplier
+
+		g.Go(func() error {
+			metadata, err := supplier.MetricsMetadata(gCtx)
+			if err != nil {
+				return err
+			}
+			sets[idx] = metadata
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	unique := map[scrape.MetricMetadata]struct{}{}
+	result := []scrape.MetricMetadata{}
+	for _, set := range sets {
+		for _, metadata := range set {
+			if _, ok := unique[metadata]; ok {
+				continue
+			}
+			unique[metadata] = struct{}{}
+			result = append(result, metadata)
+		}
+	}
+	return result, nil
+}
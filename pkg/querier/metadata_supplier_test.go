@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergedMetadataSupplier(t *testing.T) {
+	var (
+		counter      = scrape.MetricMetadata{Metric: "a_total", Type: "counter", Help: "A counter."}
+		counterStale = scrape.MetricMetadata{Metric: "a_total", Type: "counter", Help: "A counter, with an old help."}
+		gauge        = scrape.MetricMetadata{Metric: "b", Type: "gauge", Help: "B gauge.", Unit: "bytes"}
+	)
+
+	t.Run("should deduplicate the metadata in the order of the suppliers", func(t *testing.T) {
+		ingesters := &mockDistributor{}
+		ingesters.On("MetricsMetadata", mock.Anything).Return([]scrape.MetricMetadata{counter}, nil)
+		stores := &mockDistributor{}
+		stores.On("MetricsMetadata", mock.Anything).Return([]scrape.MetricMetadata{counterStale, gauge, counter}, nil)
+
+		actual, err := NewMergedMetadataSupplier(ingesters, stores).MetricsMetadata(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []scrape.MetricMetadata{counter, counterStale, gauge}, actual)
+	})
+
+	t.Run("should fail if any supplier fails", func(t *testing.T) {
+		ingesters := &mockDistributor{}
+		ingesters.On("MetricsMetadata", mock.Anything).Return([]scrape.MetricMetadata{counter}, nil)
+		stores := &mockDistributor{}
+		stores.On("MetricsMetadata", mock.Anything).Return([]scrape.MetricMetadata(nil), errors.New("store-gateways unavailable"))
+
+		_, err := NewMergedMetadataSupplier(ingesters, stores).MetricsMetadata(context.Background())
+		require.EqualError(t, err, "store-gateways unavailable")
+	})
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/grafana/mimir/pkg/querier/iterators"
 	"github.com/grafana/mimir/pkg/storage/chunk"
 	"github.com/grafana/mimir/pkg/storage/lazyquery"
+	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
 	"github.com/grafana/mimir/pkg/util/limiter"
@@ -48,6 +49,12 @@ type Config struct {
 	QueryStoreAfter    time.Duration `yaml:"query_store_after" category:"advanced"`
 	MaxQueryIntoFuture time.Duration `yaml:"max_query_into_future" category:"advanced"`
 
+	DegradedReadModeEnabled           bool `yaml:"degraded_read_mode_enabled" category:"experimental"`
+	StoreGatewayPartialResultsEnabled bool `yaml:"store_gateway_partial_results_enabled" category:"experimental"`
+
+	StoreGatewayHedgingEnabled  bool          `yaml:"store_gateway_hedging_enabled" category:"experimental"`
+	StoreGatewayHedgingMinDelay time.Duration `yaml:"store_gateway_hedging_min_delay" category:"experimental"`
+
 	StoreGatewayClient ClientConfig `yaml:"store_gateway_client"`
 
 	ShuffleShardingIngestersEnabled bool `yaml:"shuffle_sharding_ingesters_enabled" category:"advanced"`
@@ -65,6 +72,8 @@ const (
 var (
 	errBadLookbackConfigs = fmt.Errorf("the -%s setting must be greater than -%s otherwise queries might return partial results", queryIngestersWithinFlag, queryStoreAfterFlag)
 	errEmptyTimeRange     = errors.New("empty time range")
+
+	errStoreGatewayHedgingMinDelay = errors.New("the -querier.store-gateway-hedging-min-delay setting must be greater than 0 when the store-gateway hedging is enabled")
 )
 
 // RegisterFlags adds the flags required to config this to the given FlagSet.
@@ -75,6 +84,10 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.QueryIngestersWithin, queryIngestersWithinFlag, 13*time.Hour, "Maximum lookback beyond which queries are not sent to ingester. 0 means all queries are sent to ingester.")
 	f.DurationVar(&cfg.MaxQueryIntoFuture, "querier.max-query-into-future", 10*time.Minute, "Maximum duration into the future you can query. 0 to disable.")
 	f.DurationVar(&cfg.QueryStoreAfter, queryStoreAfterFlag, 12*time.Hour, "The time after which a metric should be queried from storage and not just ingesters. 0 means all queries are sent to store. If this option is enabled, the time range of the query sent to the store-gateway will be manipulated to ensure the query end is not more recent than 'now - query-store-after'.")
+	f.BoolVar(&cfg.DegradedReadModeEnabled, "querier.degraded-read-mode-enabled", false, "If enabled, when the blocks storage or the store-gateways are unavailable, the queries are served from the ingesters only, with a warning about the time range whose results may be incomplete, instead of failing.")
+	f.BoolVar(&cfg.StoreGatewayPartialResultsEnabled, "querier.store-gateway-partial-results-enabled", false, "If enabled, when the consistency check fails and all the store-gateways attempted for the non-queried blocks belong to a single zone (or are a single store-gateway, if zone-awareness is disabled), the queries return the partial results with a warning about the blocks not queried, instead of failing. The health of the zones in the ring isn't checked: the blocks not returned by the store-gateways of other zones too always fail the queries.")
+	f.BoolVar(&cfg.StoreGatewayHedgingEnabled, "querier.store-gateway-hedging-enabled", false, "If enabled, when a store-gateway doesn't return the series within the 99th percentile of the latency of its recent series requests, the querier sends the same request to the other store-gateways holding the blocks, and uses the first complete response. At most about 10% of the series requests are hedged.")
+	f.DurationVar(&cfg.StoreGatewayHedgingMinDelay, "querier.store-gateway-hedging-min-delay", 100*time.Millisecond, "Minimum delay after which the series requests to the store-gateways are hedged, when the hedging is enabled.")
 	// TODO(56quarters): Deprecated in Mimir 2.2, remove in Mimir 2.4
 	flagext.DeprecatedFlag(f, shuffleShardingIngestersLookbackPeriodFlag, fmt.Sprintf("Deprecated: this setting should always be the same as -%s and will now behave as if it is", queryIngestersWithinFlag), logger)
 	f.BoolVar(&cfg.ShuffleShardingIngestersEnabled, "querier.shuffle-sharding-ingesters-enabled", true, fmt.Sprintf("Fetch in-memory series from the minimum set of required ingesters, selecting only ingesters which may have received series since -%s. If this setting is false or -%s is '0', queriers always query all ingesters (ingesters shuffle sharding on read path is disabled).", queryIngestersWithinFlag, queryIngestersWithinFlag))
@@ -84,6 +97,10 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 // Validate the config
 func (cfg *Config) Validate() error {
+	if cfg.StoreGatewayHedgingEnabled && cfg.StoreGatewayHedgingMinDelay <= 0 {
+		return errStoreGatewayHedgingMinDelay
+	}
+
 	// Ensure the config wont create a situation where no queriers are returned.
 	if cfg.QueryIngestersWithin != 0 && cfg.QueryStoreAfter != 0 {
 		if cfg.QueryStoreAfter >= cfg.QueryIngestersWithin {
@@ -116,7 +133,11 @@ func New(cfg Config, limits *validation.Overrides, distributor Distributor, stor
 			QueryStoreAfter:     cfg.QueryStoreAfter,
 		}
 	}
-	queryable := NewQueryable(distributorQueryable, ns, iteratorFunc, cfg, limits, logger)
+	// The memory consumption of the inflight queries of each tenant is tracked across all the queries run by the querier.
+	tenantMemoryTracker := limiter.NewTenantMemoryConsumptionTracker()
+
+	var queryable storage.Queryable = NewQueryable(distributorQueryable, ns, iteratorFunc, cfg, limits, tenantMemoryTracker, logger)
+	queryable = newSecondaryQuerySourceQueryable(queryable, limits, cfg.EngineConfig.Timeout, logger)
 	exemplarQueryable := newDistributorExemplarQueryable(distributor, logger)
 
 	lazyQueryable := storage.QueryableFunc(func(ctx context.Context, mint int64, maxt int64) (storage.Querier, error) {
@@ -166,7 +187,7 @@ type QueryableWithFilter interface {
 }
 
 // NewQueryable creates a new Queryable for mimir.
-func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter, chunkIterFn chunkIteratorFunc, cfg Config, limits *validation.Overrides, logger log.Logger) storage.Queryable {
+func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter, chunkIterFn chunkIteratorFunc, cfg Config, limits *validation.Overrides, tenantMemoryTracker *limiter.TenantMemoryConsumptionTracker, logger log.Logger) storage.Queryable {
 	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
 		now := time.Now()
 
@@ -177,6 +198,9 @@ func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter,
 
 		ctx = limiter.AddQueryLimiterToContext(ctx, limiter.NewQueryLimiter(limits.MaxFetchedSeriesPerQuery(userID), limits.MaxFetchedChunkBytesPerQuery(userID), limits.MaxChunksPerQuery(userID)))
 
+		memoryTracker := limiter.NewMemoryConsumptionTracker(limits.MaxEstimatedMemoryPerQuery(userID), limits.MaxEstimatedMemoryPerTenant(userID), userID, tenantMemoryTracker)
+		ctx = limiter.AddMemoryConsumptionTrackerToContext(ctx, memoryTracker)
+
 		mint, maxt, err = validateQueryTimeRange(ctx, userID, mint, maxt, limits, cfg.MaxQueryIntoFuture, logger)
 		if err == errEmptyTimeRange {
 			return storage.NoopQuerier(), nil
@@ -189,6 +213,7 @@ func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter,
 			mint:               mint,
 			maxt:               maxt,
 			chunkIterFn:        chunkIterFn,
+			memoryTracker:      memoryTracker,
 			limits:             limits,
 			maxQueryIntoFuture: cfg.MaxQueryIntoFuture,
 			logger:             logger,
@@ -227,6 +252,8 @@ type querier struct {
 	ctx         context.Context
 	mint, maxt  int64
 
+	memoryTracker *limiter.MemoryConsumptionTracker
+
 	limits             *validation.Overrides
 	maxQueryIntoFuture time.Duration
 	logger             log.Logger
@@ -281,7 +308,7 @@ func (q querier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels.Mat
 	}
 
 	if len(q.queriers) == 1 {
-		return q.queriers[0].Select(true, sp, matchers...)
+		return newMemoryTrackingSeriesSet(q.queriers[0].Select(true, sp, matchers...), q.memoryTracker, sp)
 	}
 
 	sets := make(chan storage.SeriesSet, len(q.queriers))
@@ -304,7 +331,7 @@ func (q querier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels.Mat
 	// we have all the sets from different sources (chunk from store, chunks from ingesters,
 	// time series from store and time series from ingesters).
 	// mergeSeriesSets will return sorted set.
-	return q.mergeSeriesSets(result)
+	return newMemoryTrackingSeriesSet(q.mergeSeriesSets(result), q.memoryTracker, sp)
 }
 
 // LabelValues implements storage.Querier.
@@ -388,7 +415,9 @@ func (q querier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warn
 	return strutil.MergeSlices(sets...), warnings, nil
 }
 
-func (querier) Close() error {
+// Close implements storage.Querier, releasing the memory consumption of the query.
+func (q querier) Close() error {
+	q.memoryTracker.Release()
 	return nil
 }
 
@@ -398,6 +427,7 @@ func (q querier) mergeSeriesSets(sets []storage.SeriesSet) storage.SeriesSet {
 
 	otherSets := []storage.SeriesSet(nil)
 	chunks := []chunk.Chunk(nil)
+	warnings := storage.Warnings(nil)
 
 	for _, set := range sets {
 		nonChunkSeries := []storage.Series(nil)
@@ -413,6 +443,9 @@ func (q querier) mergeSeriesSets(sets []storage.SeriesSet) storage.SeriesSet {
 			}
 		}
 
+		// The warnings are only available once the set has been fully iterated.
+		warnings = append(warnings, set.Warnings()...)
+
 		if err := set.Err(); err != nil {
 			otherSets = append(otherSets, storage.ErrSeriesSet(err))
 		} else if len(nonChunkSeries) > 0 {
@@ -420,19 +453,25 @@ func (q querier) mergeSeriesSets(sets []storage.SeriesSet) storage.SeriesSet {
 		}
 	}
 
+	var merged storage.SeriesSet
 	if len(chunks) == 0 {
-		return storage.NewMergeSeriesSet(otherSets, storage.ChainedSeriesMerge)
+		merged = storage.NewMergeSeriesSet(otherSets, storage.ChainedSeriesMerge)
+	} else {
+		// partitionChunks returns set with sorted series, so it can be used by NewMergeSeriesSet
+		chunksSet := partitionChunks(chunks, q.mint, q.maxt, q.chunkIterFn)
+
+		if len(otherSets) == 0 {
+			merged = chunksSet
+		} else {
+			otherSets = append(otherSets, chunksSet)
+			merged = storage.NewMergeSeriesSet(otherSets, storage.ChainedSeriesMerge)
+		}
 	}
 
-	// partitionChunks returns set with sorted series, so it can be used by NewMergeSeriesSet
-	chunksSet := partitionChunks(chunks, q.mint, q.maxt, q.chunkIterFn)
-
-	if len(otherSets) == 0 {
-		return chunksSet
+	if len(warnings) == 0 {
+		return merged
 	}
-
-	otherSets = append(otherSets, chunksSet)
-	return storage.NewMergeSeriesSet(otherSets, storage.ChainedSeriesMerge)
+	return series.NewSeriesSetWithWarnings(merged, warnings)
 }
 
 type sliceSeriesSet struct {
@@ -31,6 +31,7 @@ import (
 	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -868,6 +869,14 @@ func (m *errDistributor) LabelValuesCardinality(ctx context.Context, labelNames
 	return 0, nil, errDistributorError
 }
 
+func (m *errDistributor) HeadCardinalityStats(ctx context.Context, limit int) (*client.HeadCardinalityStatsResponse, error) {
+	return nil, errDistributorError
+}
+
+func (m *errDistributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher, limit int) ([]labels.Labels, error) {
+	return nil, errDistributorError
+}
+
 type emptyDistributor struct{}
 
 func (d *emptyDistributor) LabelNamesAndValues(_ context.Context, _ []*labels.Matcher) (*client.LabelNamesAndValuesResponse, error) {
@@ -906,6 +915,14 @@ func (d *emptyDistributor) LabelValuesCardinality(ctx context.Context, labelName
 	return 0, nil, nil
 }
 
+func (d *emptyDistributor) HeadCardinalityStats(ctx context.Context, limit int) (*client.HeadCardinalityStatsResponse, error) {
+	return &client.HeadCardinalityStatsResponse{}, nil
+}
+
+func (d *emptyDistributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher, limit int) ([]labels.Labels, error) {
+	return nil, nil
+}
+
 func TestQuerier_QueryStoreAfterConfig(t *testing.T) {
 	testCases := []struct {
 		name                 string
@@ -1002,6 +1019,37 @@ func TestQuerier_QueryStoreAfterConfig(t *testing.T) {
 	}
 }
 
+func TestQuerier_ShouldReturnTheWarningsOfTheMergedQueriers(t *testing.T) {
+	engine := promql.NewEngine(promql.EngineOpts{
+		Logger:     log.NewNopLogger(),
+		MaxSamples: 1e6,
+		Timeout:    1 * time.Minute,
+	})
+
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	cfg.QueryStoreAfter = 0
+
+	distributor := &mockDistributor{}
+	distributor.On("QueryStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&client.QueryStreamResponse{}, nil)
+
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), nil)
+	require.NoError(t, err)
+
+	// Mock the blocks storage to return an empty SeriesSet with a warning.
+	querier := &mockBlocksStorageQuerier{}
+	querier.On("Select", true, mock.Anything, mock.Anything).Return(series.NewSeriesSetWithWarnings(storage.EmptySeriesSet(), storage.Warnings{errors.New("the blocks storage is unavailable")}))
+
+	queryable, _, _ := New(cfg, overrides, distributor, []QueryableWithFilter{UseAlwaysQueryable(newMockBlocksStorageQueryable(querier))}, nil, log.NewNopLogger(), nil)
+	query, err := engine.NewRangeQuery(queryable, nil, "metric", time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	require.NoError(t, err)
+
+	r := query.Exec(user.InjectOrgID(context.Background(), "0"))
+	require.NoError(t, r.Err)
+	require.Len(t, r.Warnings, 1)
+	assert.EqualError(t, r.Warnings[0], "the blocks storage is unavailable")
+}
+
 func TestUseAlwaysQueryable(t *testing.T) {
 	m := &mockQueryableWithFilter{}
 	qwf := UseAlwaysQueryable(m)
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querywarnings
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// NewHandler returns a handler collecting the warnings of the requests served by next, which must be a handler of
+// the Prometheus HTTP API, and adding them to the warnings of its successful JSON responses. It's used for the APIs,
+// like the exemplars one, whose Prometheus implementation never returns warnings.
+func NewHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		collector, ctx := ContextWithCollector(r.Context())
+
+		rec := &bufferedResponseWriter{header: w.Header(), statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		body := rec.body.Bytes()
+		if warnings := collector.Warnings(); len(warnings) > 0 && rec.statusCode == http.StatusOK {
+			if withWarnings, err := addWarnings(body, warnings); err == nil {
+				body = withWarnings
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+
+		w.WriteHeader(rec.statusCode)
+		_, _ = w.Write(body)
+	})
+}
+
+// addWarnings returns the JSON response body with the warnings appended to its own ones.
+func addWarnings(body []byte, warnings []error) ([]byte, error) {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	var all []string
+	if existing, ok := resp["warnings"]; ok {
+		if err := json.Unmarshal(existing, &all); err != nil {
+			return nil, err
+		}
+	}
+	for _, w := range warnings {
+		all = append(all, w.Error())
+	}
+
+	encoded, err := json.Marshal(all)
+	if err != nil {
+		return nil, err
+	}
+	resp["warnings"] = encoded
+
+	return json.Marshal(resp)
+}
+
+// bufferedResponseWriter is a http.ResponseWriter buffering the response body.
+type bufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querywarnings
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	tests := map[string]struct {
+		warnings       []error
+		statusCode     int
+		body           string
+		expectedBody   string
+		expectedLength string
+	}{
+		"no warnings": {
+			statusCode:   http.StatusOK,
+			body:         `{"status":"success","data":[]}`,
+			expectedBody: `{"status":"success","data":[]}`,
+		},
+		"warnings added to the response": {
+			warnings:       []error{errors.New("warning 1")},
+			statusCode:     http.StatusOK,
+			body:           `{"status":"success","data":[]}`,
+			expectedBody:   `{"data":[],"status":"success","warnings":["warning 1"]}`,
+			expectedLength: "55",
+		},
+		"warnings appended to the ones of the response": {
+			warnings:       []error{errors.New("warning 2")},
+			statusCode:     http.StatusOK,
+			body:           `{"status":"success","data":[],"warnings":["warning 1"]}`,
+			expectedBody:   `{"data":[],"status":"success","warnings":["warning 1","warning 2"]}`,
+			expectedLength: "67",
+		},
+		"warnings not added to the failed response": {
+			warnings:     []error{errors.New("warning 1")},
+			statusCode:   http.StatusUnprocessableEntity,
+			body:         `{"status":"error","error":"failed"}`,
+			expectedBody: `{"status":"error","error":"failed"}`,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, warning := range testData.warnings {
+					FromContext(r.Context()).Add(warning)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(testData.statusCode)
+				_, _ = w.Write([]byte(testData.body))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/query_exemplars", nil))
+
+			assert.Equal(t, testData.statusCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, testData.expectedLength, rec.Header().Get("Content-Length"))
+			assert.Equal(t, testData.expectedBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querywarnings
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/prometheus/storage"
+)
+
+type contextKey int
+
+var ctxKey = contextKey(0)
+
+// Collector collects the warnings of a read request raised by the components, like the distributor, whose APIs
+// can't return them.
+type Collector struct {
+	mtx      sync.Mutex
+	warnings storage.Warnings
+}
+
+// ContextWithCollector returns a context with an empty warnings collector.
+func ContextWithCollector(ctx context.Context) (*Collector, context.Context) {
+	c := &Collector{}
+	ctx = context.WithValue(ctx, ctxKey, c)
+	return c, ctx
+}
+
+// FromContext gets the Collector out of the Context. Returns nil if the collector has not
+// been initialised in the context.
+func FromContext(ctx context.Context) *Collector {
+	o := ctx.Value(ctxKey)
+	if o == nil {
+		return nil
+	}
+	return o.(*Collector)
+}
+
+// Add adds a warning, unless the same warning has already been added.
+func (c *Collector) Add(warning error) {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, w := range c.warnings {
+		if w.Error() == warning.Error() {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, warning)
+}
+
+// Warnings returns the collected warnings.
+func (c *Collector) Warnings() storage.Warnings {
+	if c == nil {
+		return nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return append(storage.Warnings(nil), c.warnings...)
+}
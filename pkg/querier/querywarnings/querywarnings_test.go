@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querywarnings
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	collector, ctx := ContextWithCollector(context.Background())
+	assert.Same(t, collector, FromContext(ctx))
+
+	FromContext(ctx).Add(errors.New("warning 1"))
+	FromContext(ctx).Add(errors.New("warning 2"))
+	FromContext(ctx).Add(errors.New("warning 1"))
+	assert.Equal(t, storage.Warnings{errors.New("warning 1"), errors.New("warning 2")}, collector.Warnings())
+}
+
+func TestCollector_ShouldBeNoopWithoutCollectorInTheContext(t *testing.T) {
+	collector := FromContext(context.Background())
+	assert.Nil(t, collector)
+
+	collector.Add(errors.New("warning"))
+	assert.Nil(t, collector.Warnings())
+}
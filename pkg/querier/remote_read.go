@@ -90,6 +90,7 @@ func remoteReadSamples(
 				errCh <- err
 				return
 			}
+			defer querier.Close()
 
 			params := &storage.SelectHints{
 				Start: int64(from),
@@ -136,6 +137,11 @@ func remoteReadStreamedXORChunks(
 
 	w.Header().Set("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
 
+	// The queries are run one after the other, and the series of each query are streamed as they're read, so that
+	// the querier doesn't buffer the whole response in memory when the request is received by its HTTP server. The
+	// requests received from the query-frontend or the query-scheduler are still buffered, by the querier worker which
+	// sends the whole response back at once. The status code is 200 once the first frame has been sent: the clients
+	// detect the failures after that through the truncated stream.
 	for i, qr := range req.Queries {
 		if err := processReadStreamedQueryRequest(ctx, i, qr, q, w, f, maxBytesInFrame); err != nil {
 			level.Error(logger).Log("msg", "error streaming remote read response", "err", err)
@@ -143,7 +149,6 @@ func remoteReadStreamedXORChunks(
 			return
 		}
 	}
-	w.WriteHeader(http.StatusOK)
 }
 
 func processReadStreamedQueryRequest(
@@ -164,6 +169,8 @@ func processReadStreamedQueryRequest(
 	if err != nil {
 		return err
 	}
+	// Release the resources of the query as soon as its series have been streamed.
+	defer querier.Close()
 
 	params := &storage.SelectHints{
 		Start: int64(from),
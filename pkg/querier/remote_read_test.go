@@ -22,6 +22,7 @@ import (
 	prom_remote "github.com/prometheus/prometheus/storage/remote"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
@@ -44,6 +45,14 @@ func (m mockSampleAndChunkQueryable) ChunkQuerier(ctx context.Context, mint, max
 type mockQuerier struct {
 	storage.Querier
 	matrix model.Matrix
+	closed *atomic.Int32
+}
+
+func (m mockQuerier) Close() error {
+	if m.closed != nil {
+		m.closed.Inc()
+	}
+	return nil
 }
 
 func (m mockQuerier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
@@ -56,6 +65,14 @@ func (m mockQuerier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels
 type mockChunkQuerier struct {
 	storage.ChunkQuerier
 	matrix model.Matrix
+	closed *atomic.Int32
+}
+
+func (m mockChunkQuerier) Close() error {
+	if m.closed != nil {
+		m.closed.Inc()
+	}
+	return nil
 }
 
 func (m mockChunkQuerier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels.Matcher) storage.ChunkSeriesSet {
@@ -286,6 +303,65 @@ func TestStreamedRemoteRead(t *testing.T) {
 	}
 }
 
+func TestStreamedRemoteRead_MultipleQueries(t *testing.T) {
+	closed := atomic.NewInt32(0)
+	q := &mockSampleAndChunkQueryable{
+		chunkQueryableFn: func(ctx context.Context, mint, maxt int64) (storage.ChunkQuerier, error) {
+			return mockChunkQuerier{
+				matrix: model.Matrix{
+					{
+						Metric: model.Metric{"foo": "bar"},
+						Values: []model.SamplePair{{Timestamp: model.Time(mint), Value: 1}},
+					},
+				},
+				closed: closed,
+			}, nil
+		},
+	}
+
+	handler := RemoteReadHandler(q, log.NewNopLogger())
+
+	requestBody, err := proto.Marshal(&client.ReadRequest{
+		Queries: []*client.QueryRequest{
+			{StartTimestampMs: 0, EndTimestampMs: 10},
+			{StartTimestampMs: 20, EndTimestampMs: 30},
+		},
+		AcceptedResponseTypes: []client.ReadRequest_ResponseType{client.STREAMED_XOR_CHUNKS, client.SAMPLES},
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/api/v1/read", bytes.NewReader(snappy.Encode(nil, requestBody)))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	require.Equal(t, []string{"application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse"}, recorder.Result().Header["Content-Type"])
+
+	// The series of each query are streamed in the order of the queries.
+	stream := prom_remote.NewChunkedReader(recorder.Result().Body, prom_remote.DefaultChunkedReadLimit, nil)
+	var queryIndexes []int64
+	var minTimes []int64
+	for {
+		var res client.StreamReadResponse
+		err := stream.NextProto(&res)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Len(t, res.ChunkedSeries, 1)
+		require.Len(t, res.ChunkedSeries[0].Chunks, 1)
+
+		queryIndexes = append(queryIndexes, res.QueryIndex)
+		minTimes = append(minTimes, res.ChunkedSeries[0].Chunks[0].MinTimeMs)
+	}
+	require.Equal(t, []int64{0, 1}, queryIndexes)
+	require.Equal(t, []int64{0, 20}, minTimes)
+
+	// The querier of each query has been closed once its series have been streamed.
+	require.Equal(t, int32(2), closed.Load())
+}
+
 func getNSamples(n int) []model.SamplePair {
 	var retVal []model.SamplePair
 	for i := 0; i < n; i++ {
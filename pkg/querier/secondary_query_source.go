@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+
+	"github.com/grafana/mimir/pkg/util"
+	util_math "github.com/grafana/mimir/pkg/util/math"
+)
+
+// secondaryQuerySourceLimits is the subset of the limits used to configure the tenants' secondary query source.
+type secondaryQuerySourceLimits interface {
+	SecondaryQuerySourceURL(userID string) string
+	SecondaryQuerySourceTimeWindow(userID string) time.Duration
+}
+
+// secondaryQuerySourceQueryable merges the series queried from the next queryable with the series read, via the
+// Prometheus remote read API, from the tenant's secondary query source, if configured. The secondary query source
+// is typically the system the tenant's historical data is being migrated from.
+type secondaryQuerySourceQueryable struct {
+	next    storage.Queryable
+	limits  secondaryQuerySourceLimits
+	timeout time.Duration
+	logger  log.Logger
+
+	// Read clients, cached by URL.
+	clientsMtx sync.Mutex
+	clients    map[string]remote.ReadClient
+}
+
+func newSecondaryQuerySourceQueryable(next storage.Queryable, limits secondaryQuerySourceLimits, timeout time.Duration, logger log.Logger) *secondaryQuerySourceQueryable {
+	return &secondaryQuerySourceQueryable{
+		next:    next,
+		limits:  limits,
+		timeout: timeout,
+		logger:  logger,
+		clients: map[string]remote.ReadClient{},
+	}
+}
+
+// Querier implements storage.Queryable.
+func (q *secondaryQuerySourceQueryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	primary, err := q.next.Querier(ctx, mint, maxt)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceURL := q.limits.SecondaryQuerySourceURL(userID)
+	if sourceURL == "" {
+		return primary, nil
+	}
+
+	// The secondary query source is only queried within the configured time window.
+	if window := q.limits.SecondaryQuerySourceTimeWindow(userID); window > 0 {
+		mint = util_math.Max64(mint, util.TimeToMillis(time.Now().Add(-window)))
+		if mint > maxt {
+			return primary, nil
+		}
+	}
+
+	client, err := q.getClient(sourceURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the client of the secondary query source for tenant %s", userID)
+	}
+
+	secondary, err := remote.NewSampleAndChunkQueryableClient(client, nil, nil, true, nil).Querier(ctx, mint, maxt)
+	if err != nil {
+		return nil, err
+	}
+
+	level.Debug(q.logger).Log("msg", "querying the secondary query source", "user", userID, "mint", mint, "maxt", maxt)
+
+	// The errors of the secondary query source are returned as warnings, so that the queries keep working
+	// on the local data only when the secondary source is unavailable.
+	return storage.NewMergeQuerier([]storage.Querier{primary}, []storage.Querier{secondaryQuerySourceQuerier{secondary}}, storage.ChainedSeriesMerge), nil
+}
+
+func (q *secondaryQuerySourceQueryable) getClient(sourceURL string) (remote.ReadClient, error) {
+	q.clientsMtx.Lock()
+	defer q.clientsMtx.Unlock()
+
+	if client, ok := q.clients[sourceURL]; ok {
+		return client, nil
+	}
+
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := remote.NewReadClient("secondary-query-source", &remote.ClientConfig{
+		URL:              &config_util.URL{URL: parsed},
+		Timeout:          model.Duration(q.timeout),
+		HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	q.clients[sourceURL] = client
+	return client, nil
+}
+
+// secondaryQuerySourceQuerier wraps the remote read querier of the secondary query source, which doesn't
+// support the label names and values queries. These queries are answered from the local data only.
+type secondaryQuerySourceQuerier struct {
+	storage.Querier
+}
+
+// LabelValues implements storage.Querier.
+func (q secondaryQuerySourceQuerier) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+// LabelNames implements storage.Querier.
+func (q secondaryQuerySourceQuerier) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
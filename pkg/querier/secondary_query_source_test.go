@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/series"
+)
+
+type secondaryQuerySourceLimitsMock struct {
+	url        string
+	timeWindow time.Duration
+}
+
+func (m secondaryQuerySourceLimitsMock) SecondaryQuerySourceURL(string) string {
+	return m.url
+}
+
+func (m secondaryQuerySourceLimitsMock) SecondaryQuerySourceTimeWindow(string) time.Duration {
+	return m.timeWindow
+}
+
+func TestSecondaryQuerySourceQueryable(t *testing.T) {
+	now := time.Now()
+	mint, maxt := now.Add(-2*time.Hour), now
+
+	primarySeries := []storage.Series{
+		series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "series_1"), []model.SamplePair{
+			{Timestamp: model.TimeFromUnixNano(now.Add(-10 * time.Minute).UnixNano()), Value: 3},
+		}),
+	}
+
+	// The secondary query source returns older samples of the same series and another series.
+	secondaryResult := &prompb.QueryResult{
+		Timeseries: []*prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: labels.MetricName, Value: "series_1"}},
+				Samples: []prompb.Sample{{Timestamp: now.Add(-90 * time.Minute).UnixMilli(), Value: 1}, {Timestamp: now.Add(-80 * time.Minute).UnixMilli(), Value: 2}},
+			},
+			{
+				Labels:  []prompb.Label{{Name: labels.MetricName, Value: "series_2"}},
+				Samples: []prompb.Sample{{Timestamp: now.Add(-90 * time.Minute).UnixMilli(), Value: 4}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		limits               func(serverURL string) secondaryQuerySourceLimitsMock
+		serverStatusCode     int
+		expectedSeries       map[string][]float64
+		expectedWarnings     bool
+		expectedSourceCalled bool
+		expectedQueryMint    int64
+	}{
+		"should only query the primary queryable if the secondary query source is disabled": {
+			limits: func(string) secondaryQuerySourceLimitsMock {
+				return secondaryQuerySourceLimitsMock{}
+			},
+			expectedSeries: map[string][]float64{"series_1": {3}},
+		},
+		"should merge the series of the primary queryable and the secondary query source": {
+			limits: func(serverURL string) secondaryQuerySourceLimitsMock {
+				return secondaryQuerySourceLimitsMock{url: serverURL}
+			},
+			expectedSeries:       map[string][]float64{"series_1": {1, 2, 3}, "series_2": {4}},
+			expectedSourceCalled: true,
+			expectedQueryMint:    mint.UnixMilli(),
+		},
+		"should only query the secondary query source within the time window": {
+			limits: func(serverURL string) secondaryQuerySourceLimitsMock {
+				return secondaryQuerySourceLimitsMock{url: serverURL, timeWindow: time.Hour}
+			},
+			expectedSeries:       map[string][]float64{"series_1": {1, 2, 3}, "series_2": {4}},
+			expectedSourceCalled: true,
+			expectedQueryMint:    now.Add(-time.Hour).UnixMilli(),
+		},
+		"should return the failures of the secondary query source as warnings": {
+			limits: func(serverURL string) secondaryQuerySourceLimitsMock {
+				return secondaryQuerySourceLimitsMock{url: serverURL}
+			},
+			serverStatusCode:     http.StatusInternalServerError,
+			expectedSeries:       map[string][]float64{"series_1": {3}},
+			expectedWarnings:     true,
+			expectedSourceCalled: true,
+			expectedQueryMint:    mint.UnixMilli(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				sourceCalled bool
+				queryMint    int64
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sourceCalled = true
+
+				req, err := remote.DecodeReadRequest(r)
+				require.NoError(t, err)
+				require.Len(t, req.Queries, 1)
+				queryMint = req.Queries[0].StartTimestampMs
+
+				if testData.serverStatusCode != 0 {
+					w.WriteHeader(testData.serverStatusCode)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/x-protobuf")
+				w.Header().Set("Content-Encoding", "snappy")
+				require.NoError(t, remote.EncodeReadResponse(&prompb.ReadResponse{Results: []*prompb.QueryResult{secondaryResult}}, w))
+			}))
+			t.Cleanup(server.Close)
+
+			primary := storage.QueryableFunc(func(context.Context, int64, int64) (storage.Querier, error) {
+				return &mockSeriesQuerier{series: primarySeries}, nil
+			})
+
+			queryable := newSecondaryQuerySourceQueryable(primary, testData.limits(server.URL+"/api/v1/read"), time.Minute, log.NewNopLogger())
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			q, err := queryable.Querier(ctx, mint.UnixMilli(), maxt.UnixMilli())
+			require.NoError(t, err)
+
+			set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "series_.*"))
+
+			actual := map[string][]float64{}
+			for set.Next() {
+				s := set.At()
+				it := s.Iterator()
+				for it.Next() {
+					_, v := it.At()
+					actual[s.Labels().Get(labels.MetricName)] = append(actual[s.Labels().Get(labels.MetricName)], v)
+				}
+				require.NoError(t, it.Err())
+			}
+			require.NoError(t, set.Err())
+
+			assert.Equal(t, testData.expectedSeries, actual)
+			assert.Equal(t, testData.expectedWarnings, len(set.Warnings()) > 0)
+			assert.Equal(t, testData.expectedSourceCalled, sourceCalled)
+			if testData.expectedSourceCalled {
+				assert.InDelta(t, testData.expectedQueryMint, queryMint, float64(time.Minute.Milliseconds()))
+			}
+
+			// Label names and values queries are not sent to the secondary query source.
+			sourceCalled = false
+			names, _, err := q.LabelNames()
+			require.NoError(t, err)
+			assert.Equal(t, []string{labels.MetricName}, names)
+			assert.False(t, sourceCalled)
+		})
+	}
+}
+
+type mockSeriesQuerier struct {
+	series []storage.Series
+}
+
+func (m *mockSeriesQuerier) Select(bool, *storage.SelectHints, ...*labels.Matcher) storage.SeriesSet {
+	return series.NewConcreteSeriesSet(m.series)
+}
+
+func (m *mockSeriesQuerier) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (m *mockSeriesQuerier) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return []string{labels.MetricName}, nil, nil
+}
+
+func (m *mockSeriesQuerier) Close() error {
+	return nil
+}
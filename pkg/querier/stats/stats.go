@@ -139,6 +139,72 @@ func (s *Stats) LoadSplitQueries() uint32 {
 	return atomic.LoadUint32(&s.SplitQueries)
 }
 
+func (s *Stats) AddFetchedSamples(samples uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.FetchedSamplesCount, samples)
+}
+
+func (s *Stats) LoadFetchedSamples() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedSamplesCount)
+}
+
+func (s *Stats) AddFetchedIngesterChunks(chunks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.FetchedIngesterChunksCount, chunks)
+}
+
+func (s *Stats) LoadFetchedIngesterChunks() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedIngesterChunksCount)
+}
+
+func (s *Stats) AddFetchedStoreGatewayChunks(chunks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.FetchedStoreGatewayChunksCount, chunks)
+}
+
+func (s *Stats) LoadFetchedStoreGatewayChunks() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedStoreGatewayChunksCount)
+}
+
+// AddQueueTime adds some time to the time spent by the query in the queue.
+func (s *Stats) AddQueueTime(t time.Duration) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt64((*int64)(&s.QueueTime), int64(t))
+}
+
+// LoadQueueTime returns current queue time.
+func (s *Stats) LoadQueueTime() time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64((*int64)(&s.QueueTime)))
+}
+
 // Merge the provided Stats into this one.
 func (s *Stats) Merge(other *Stats) {
 	if s == nil || other == nil {
@@ -151,6 +217,10 @@ func (s *Stats) Merge(other *Stats) {
 	s.AddFetchedChunks(other.LoadFetchedChunks())
 	s.AddShardedQueries(other.LoadShardedQueries())
 	s.AddSplitQueries(other.LoadSplitQueries())
+	s.AddFetchedSamples(other.LoadFetchedSamples())
+	s.AddFetchedIngesterChunks(other.LoadFetchedIngesterChunks())
+	s.AddFetchedStoreGatewayChunks(other.LoadFetchedStoreGatewayChunks())
+	s.AddQueueTime(other.LoadQueueTime())
 }
 
 func ShouldTrackHTTPGRPCResponse(r *httpgrpc.HTTPResponse) bool {
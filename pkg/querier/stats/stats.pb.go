@@ -42,6 +42,14 @@ type Stats struct {
 	ShardedQueries uint32 `protobuf:"varint,5,opt,name=sharded_queries,json=shardedQueries,proto3" json:"sharded_queries,omitempty"`
 	// The number of split partial queries executed. 0 if splitting is disabled or the query can't be split.
 	SplitQueries uint32 `protobuf:"varint,6,opt,name=split_queries,json=splitQueries,proto3" json:"split_queries,omitempty"`
+	// The number of samples of the chunks fetched for the query
+	FetchedSamplesCount uint64 `protobuf:"varint,7,opt,name=fetched_samples_count,json=fetchedSamplesCount,proto3" json:"fetched_samples_count,omitempty"`
+	// The number of chunks fetched from the ingesters for the query
+	FetchedIngesterChunksCount uint64 `protobuf:"varint,8,opt,name=fetched_ingester_chunks_count,json=fetchedIngesterChunksCount,proto3" json:"fetched_ingester_chunks_count,omitempty"`
+	// The number of chunks fetched from the store-gateways for the query
+	FetchedStoreGatewayChunksCount uint64 `protobuf:"varint,9,opt,name=fetched_store_gateway_chunks_count,json=fetchedStoreGatewayChunksCount,proto3" json:"fetched_store_gateway_chunks_count,omitempty"`
+	// The sum of all time spent by the query in the queue of the query-frontend or query-scheduler.
+	QueueTime time.Duration `protobuf:"bytes,10,opt,name=queue_time,json=queueTime,proto3,stdduration" json:"queue_time"`
 }
 
 func (m *Stats) Reset()      { *m = Stats{} }
@@ -118,6 +126,34 @@ func (m *Stats) GetSplitQueries() uint32 {
 	return 0
 }
 
+func (m *Stats) GetFetchedSamplesCount() uint64 {
+	if m != nil {
+		return m.FetchedSamplesCount
+	}
+	return 0
+}
+
+func (m *Stats) GetFetchedIngesterChunksCount() uint64 {
+	if m != nil {
+		return m.FetchedIngesterChunksCount
+	}
+	return 0
+}
+
+func (m *Stats) GetFetchedStoreGatewayChunksCount() uint64 {
+	if m != nil {
+		return m.FetchedStoreGatewayChunksCount
+	}
+	return 0
+}
+
+func (m *Stats) GetQueueTime() time.Duration {
+	if m != nil {
+		return m.QueueTime
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Stats)(nil), "stats.Stats")
 }
@@ -125,29 +161,34 @@ func init() {
 func init() { proto.RegisterFile("stats.proto", fileDescriptor_b4756a0aec8b9d44) }
 
 var fileDescriptor_b4756a0aec8b9d44 = []byte{
-	// 340 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x91, 0xbd, 0x52, 0xea, 0x40,
-	0x14, 0xc7, 0x77, 0xb9, 0xc0, 0x70, 0x97, 0xcb, 0x75, 0x8c, 0x16, 0x91, 0xe2, 0xc0, 0x68, 0x21,
-	0x8d, 0xc1, 0xd1, 0xd2, 0xc6, 0x01, 0x5f, 0x40, 0xb0, 0xb2, 0xc9, 0xe4, 0x63, 0x49, 0x32, 0x06,
-	0x16, 0xb3, 0x9b, 0x71, 0xec, 0x7c, 0x04, 0x4b, 0x1f, 0xc1, 0x19, 0x5f, 0x84, 0x92, 0x92, 0x4a,
-	0x65, 0x69, 0x2c, 0x79, 0x04, 0x27, 0x27, 0xc1, 0x8f, 0x6e, 0xcf, 0xf9, 0xfd, 0x7f, 0xfb, 0x9f,
-	0xd9, 0x65, 0x75, 0xa9, 0x1c, 0x25, 0xad, 0x69, 0x22, 0x94, 0x30, 0x2a, 0x38, 0x34, 0x8f, 0x82,
-	0x48, 0x85, 0xa9, 0x6b, 0x79, 0x62, 0xdc, 0x0d, 0x44, 0x20, 0xba, 0x48, 0xdd, 0x74, 0x84, 0x13,
-	0x0e, 0x78, 0xca, 0xad, 0x26, 0x04, 0x42, 0x04, 0x31, 0xff, 0x4e, 0xf9, 0x69, 0xe2, 0xa8, 0x48,
-	0x4c, 0x72, 0xbe, 0xff, 0x52, 0x62, 0x95, 0x61, 0x76, 0xb1, 0x71, 0xce, 0xfe, 0xde, 0x39, 0x71,
-	0x6c, 0xab, 0x68, 0xcc, 0x4d, 0xda, 0xa6, 0x9d, 0xfa, 0xc9, 0x9e, 0x95, 0xdb, 0xd6, 0xc6, 0xb6,
-	0x2e, 0x0a, 0xbb, 0x57, 0x9b, 0xbd, 0xb6, 0xc8, 0xd3, 0x5b, 0x8b, 0x0e, 0x6a, 0x99, 0x75, 0x15,
-	0x8d, 0xb9, 0x71, 0xcc, 0x76, 0x47, 0x5c, 0x79, 0x21, 0xf7, 0x6d, 0xc9, 0x93, 0x88, 0x4b, 0xdb,
-	0x13, 0xe9, 0x44, 0x99, 0xa5, 0x36, 0xed, 0x94, 0x07, 0x46, 0xc1, 0x86, 0x88, 0xfa, 0x19, 0x31,
-	0x2c, 0xb6, 0xb3, 0x31, 0xbc, 0x30, 0x9d, 0xdc, 0xd8, 0xee, 0xbd, 0xe2, 0xd2, 0xfc, 0x83, 0xc2,
-	0x76, 0x81, 0xfa, 0x19, 0xe9, 0x65, 0xe0, 0x67, 0x03, 0xe6, 0x37, 0x0d, 0xe5, 0x5f, 0x0d, 0x28,
-	0x14, 0x0d, 0x87, 0x6c, 0x4b, 0x86, 0x4e, 0xe2, 0x73, 0xdf, 0xbe, 0x4d, 0xb1, 0xd9, 0xac, 0xb4,
-	0x69, 0xa7, 0x31, 0xf8, 0x5f, 0xac, 0x2f, 0xf3, 0xad, 0x71, 0xc0, 0x1a, 0x72, 0x1a, 0x47, 0xea,
-	0x2b, 0x56, 0xc5, 0xd8, 0x3f, 0x5c, 0x16, 0xa1, 0xde, 0xd9, 0x7c, 0x09, 0x64, 0xb1, 0x04, 0xb2,
-	0x5e, 0x02, 0x7d, 0xd0, 0x40, 0x9f, 0x35, 0xd0, 0x99, 0x06, 0x3a, 0xd7, 0x40, 0xdf, 0x35, 0xd0,
-	0x0f, 0x0d, 0x64, 0xad, 0x81, 0x3e, 0xae, 0x80, 0xcc, 0x57, 0x40, 0x16, 0x2b, 0x20, 0xd7, 0xf9,
-	0xcf, 0xb9, 0x55, 0x7c, 0xc5, 0xd3, 0xcf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x9d, 0x30, 0xbc, 0x87,
-	0xd6, 0x01, 0x00, 0x00,
+	// 424 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x3f, 0x0f, 0xd2, 0x40,
+	0x18, 0xc6, 0x7b, 0x0a, 0x08, 0x87, 0x68, 0xac, 0x9a, 0x54, 0x12, 0x0f, 0x82, 0x83, 0x2c, 0x16,
+	0x83, 0xa3, 0x8b, 0x16, 0x13, 0xa3, 0x9b, 0xe0, 0xe4, 0xd2, 0xf4, 0xcf, 0xd1, 0x36, 0xb6, 0x3d,
+	0xe8, 0xdd, 0x85, 0xb0, 0xf9, 0x11, 0x1c, 0xfd, 0x08, 0xce, 0x7e, 0x0a, 0x46, 0x46, 0x26, 0x95,
+	0xb2, 0x38, 0xf2, 0x11, 0x4c, 0xdf, 0x6b, 0x2b, 0x6c, 0x6e, 0xbd, 0xf7, 0x79, 0x7e, 0xef, 0xf3,
+	0xbe, 0x79, 0x8b, 0xbb, 0x5c, 0x38, 0x82, 0x9b, 0xab, 0x8c, 0x09, 0xa6, 0x37, 0xe1, 0xd1, 0x7f,
+	0x16, 0x44, 0x22, 0x94, 0xae, 0xe9, 0xb1, 0x64, 0x12, 0xb0, 0x80, 0x4d, 0x40, 0x75, 0xe5, 0x12,
+	0x5e, 0xf0, 0x80, 0x2f, 0x45, 0xf5, 0x49, 0xc0, 0x58, 0x10, 0xd3, 0x7f, 0x2e, 0x5f, 0x66, 0x8e,
+	0x88, 0x58, 0xaa, 0xf4, 0xd1, 0x8f, 0x06, 0x6e, 0x2e, 0x8a, 0xc6, 0xfa, 0x2b, 0xdc, 0xd9, 0x38,
+	0x71, 0x6c, 0x8b, 0x28, 0xa1, 0x06, 0x1a, 0xa2, 0x71, 0x77, 0xfa, 0xc8, 0x54, 0xb4, 0x59, 0xd1,
+	0xe6, 0x9b, 0x92, 0xb6, 0xda, 0xbb, 0x9f, 0x03, 0xed, 0xdb, 0xaf, 0x01, 0x9a, 0xb7, 0x0b, 0xea,
+	0x63, 0x94, 0x50, 0xfd, 0x39, 0x7e, 0xb0, 0xa4, 0xc2, 0x0b, 0xa9, 0x6f, 0x73, 0x9a, 0x45, 0x94,
+	0xdb, 0x1e, 0x93, 0xa9, 0x30, 0x6e, 0x0c, 0xd1, 0xb8, 0x31, 0xd7, 0x4b, 0x6d, 0x01, 0xd2, 0xac,
+	0x50, 0x74, 0x13, 0xdf, 0xaf, 0x08, 0x2f, 0x94, 0xe9, 0x67, 0xdb, 0xdd, 0x0a, 0xca, 0x8d, 0x9b,
+	0x00, 0xdc, 0x2b, 0xa5, 0x59, 0xa1, 0x58, 0x85, 0x70, 0x99, 0x00, 0xfe, 0x2a, 0xa1, 0x71, 0x95,
+	0x00, 0x40, 0x99, 0xf0, 0x14, 0xdf, 0xe5, 0xa1, 0x93, 0xf9, 0xd4, 0xb7, 0xd7, 0x12, 0x92, 0x8d,
+	0xe6, 0x10, 0x8d, 0x7b, 0xf3, 0x3b, 0x65, 0xf9, 0x83, 0xaa, 0xea, 0x4f, 0x70, 0x8f, 0xaf, 0xe2,
+	0x48, 0xd4, 0xb6, 0x16, 0xd8, 0x6e, 0x43, 0xb1, 0x32, 0x4d, 0xf1, 0xc3, 0x7a, 0x43, 0x27, 0x59,
+	0xc5, 0xf5, 0x8a, 0xb7, 0x60, 0x80, 0x6a, 0x99, 0x85, 0xd2, 0xd4, 0x04, 0xaf, 0xf1, 0xe3, 0x8a,
+	0x89, 0xd2, 0x80, 0x72, 0x41, 0xb3, 0xeb, 0xe1, 0xdb, 0xc0, 0xf6, 0x4b, 0xd3, 0xbb, 0xd2, 0x73,
+	0xb9, 0xc4, 0x7b, 0x3c, 0xaa, 0x63, 0x05, 0xcb, 0xa8, 0x1d, 0x38, 0x82, 0x6e, 0x9c, 0xed, 0x75,
+	0x9f, 0x0e, 0xf4, 0x21, 0xd5, 0x0c, 0x85, 0xf1, 0xad, 0xf2, 0x5d, 0xf6, 0xb2, 0x30, 0x5e, 0x4b,
+	0x2a, 0xa9, 0xba, 0x33, 0xfe, 0xff, 0x3b, 0x77, 0x00, 0x2b, 0x0e, 0x6d, 0xbd, 0xdc, 0x1f, 0x89,
+	0x76, 0x38, 0x12, 0xed, 0x7c, 0x24, 0xe8, 0x4b, 0x4e, 0xd0, 0xf7, 0x9c, 0xa0, 0x5d, 0x4e, 0xd0,
+	0x3e, 0x27, 0xe8, 0x77, 0x4e, 0xd0, 0x9f, 0x9c, 0x68, 0xe7, 0x9c, 0xa0, 0xaf, 0x27, 0xa2, 0xed,
+	0x4f, 0x44, 0x3b, 0x9c, 0x88, 0xf6, 0x49, 0xfd, 0xc0, 0x6e, 0x0b, 0x42, 0x5e, 0xfc, 0x1d, 0x00,
+	0xbf, 0xf1, 0x3b, 0x9c, 0xdd, 0x02, 0x00, 0x00,
 }
 
 func (this *Stats) Equal(that interface{}) bool {
@@ -187,13 +228,25 @@ func (this *Stats) Equal(that interface{}) bool {
 	if this.SplitQueries != that1.SplitQueries {
 		return false
 	}
+	if this.FetchedSamplesCount != that1.FetchedSamplesCount {
+		return false
+	}
+	if this.FetchedIngesterChunksCount != that1.FetchedIngesterChunksCount {
+		return false
+	}
+	if this.FetchedStoreGatewayChunksCount != that1.FetchedStoreGatewayChunksCount {
+		return false
+	}
+	if this.QueueTime != that1.QueueTime {
+		return false
+	}
 	return true
 }
 func (this *Stats) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 10)
+	s := make([]string, 0, 14)
 	s = append(s, "&stats.Stats{")
 	s = append(s, "WallTime: "+fmt.Sprintf("%#v", this.WallTime)+",\n")
 	s = append(s, "FetchedSeriesCount: "+fmt.Sprintf("%#v", this.FetchedSeriesCount)+",\n")
@@ -201,6 +254,10 @@ func (this *Stats) GoString() string {
 	s = append(s, "FetchedChunksCount: "+fmt.Sprintf("%#v", this.FetchedChunksCount)+",\n")
 	s = append(s, "ShardedQueries: "+fmt.Sprintf("%#v", this.ShardedQueries)+",\n")
 	s = append(s, "SplitQueries: "+fmt.Sprintf("%#v", this.SplitQueries)+",\n")
+	s = append(s, "FetchedSamplesCount: "+fmt.Sprintf("%#v", this.FetchedSamplesCount)+",\n")
+	s = append(s, "FetchedIngesterChunksCount: "+fmt.Sprintf("%#v", this.FetchedIngesterChunksCount)+",\n")
+	s = append(s, "FetchedStoreGatewayChunksCount: "+fmt.Sprintf("%#v", this.FetchedStoreGatewayChunksCount)+",\n")
+	s = append(s, "QueueTime: "+fmt.Sprintf("%#v", this.QueueTime)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -232,6 +289,29 @@ func (m *Stats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	n1, err1 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.QueueTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.QueueTime):])
+	if err1 != nil {
+		return 0, err1
+	}
+	i -= n1
+	i = encodeVarintStats(dAtA, i, uint64(n1))
+	i--
+	dAtA[i] = 0x52
+	if m.FetchedStoreGatewayChunksCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedStoreGatewayChunksCount))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.FetchedIngesterChunksCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedIngesterChunksCount))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.FetchedSamplesCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedSamplesCount))
+		i--
+		dAtA[i] = 0x38
+	}
 	if m.SplitQueries != 0 {
 		i = encodeVarintStats(dAtA, i, uint64(m.SplitQueries))
 		i--
@@ -257,12 +337,12 @@ func (m *Stats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x10
 	}
-	n1, err1 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.WallTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.WallTime):])
-	if err1 != nil {
-		return 0, err1
+	n2, err2 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.WallTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.WallTime):])
+	if err2 != nil {
+		return 0, err2
 	}
-	i -= n1
-	i = encodeVarintStats(dAtA, i, uint64(n1))
+	i -= n2
+	i = encodeVarintStats(dAtA, i, uint64(n2))
 	i--
 	dAtA[i] = 0xa
 	return len(dAtA) - i, nil
@@ -302,6 +382,17 @@ func (m *Stats) Size() (n int) {
 	if m.SplitQueries != 0 {
 		n += 1 + sovStats(uint64(m.SplitQueries))
 	}
+	if m.FetchedSamplesCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedSamplesCount))
+	}
+	if m.FetchedIngesterChunksCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedIngesterChunksCount))
+	}
+	if m.FetchedStoreGatewayChunksCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedStoreGatewayChunksCount))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.QueueTime)
+	n += 1 + l + sovStats(uint64(l))
 	return n
 }
 
@@ -322,6 +413,10 @@ func (this *Stats) String() string {
 		`FetchedChunksCount:` + fmt.Sprintf("%v", this.FetchedChunksCount) + `,`,
 		`ShardedQueries:` + fmt.Sprintf("%v", this.ShardedQueries) + `,`,
 		`SplitQueries:` + fmt.Sprintf("%v", this.SplitQueries) + `,`,
+		`FetchedSamplesCount:` + fmt.Sprintf("%v", this.FetchedSamplesCount) + `,`,
+		`FetchedIngesterChunksCount:` + fmt.Sprintf("%v", this.FetchedIngesterChunksCount) + `,`,
+		`FetchedStoreGatewayChunksCount:` + fmt.Sprintf("%v", this.FetchedStoreGatewayChunksCount) + `,`,
+		`QueueTime:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.QueueTime), "Duration", "duration.Duration", 1), `&`, ``, 1) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -491,6 +586,96 @@ func (m *Stats) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedSamplesCount", wireType)
+			}
+			m.FetchedSamplesCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedSamplesCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedIngesterChunksCount", wireType)
+			}
+			m.FetchedIngesterChunksCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedIngesterChunksCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedStoreGatewayChunksCount", wireType)
+			}
+			m.FetchedStoreGatewayChunksCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedStoreGatewayChunksCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueueTime", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthStats
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthStats
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdDurationUnmarshal(&m.QueueTime, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipStats(dAtA[iNdEx:])
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStats_WallTime(t *testing.T) {
@@ -115,6 +116,61 @@ func TestStats_AddSplitQueries(t *testing.T) {
 	})
 }
 
+func TestStats_AddFetchedSamples(t *testing.T) {
+	t.Run("add and load samples", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddFetchedSamples(120)
+		stats.AddFetchedSamples(240)
+
+		assert.Equal(t, uint64(360), stats.LoadFetchedSamples())
+	})
+
+	t.Run("add and load samples nil receiver", func(t *testing.T) {
+		var stats *Stats
+		stats.AddFetchedSamples(120)
+
+		assert.Equal(t, uint64(0), stats.LoadFetchedSamples())
+	})
+}
+
+func TestStats_AddFetchedIngesterAndStoreGatewayChunks(t *testing.T) {
+	t.Run("add and load chunks", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddFetchedIngesterChunks(20)
+		stats.AddFetchedIngesterChunks(22)
+		stats.AddFetchedStoreGatewayChunks(5)
+
+		assert.Equal(t, uint64(42), stats.LoadFetchedIngesterChunks())
+		assert.Equal(t, uint64(5), stats.LoadFetchedStoreGatewayChunks())
+	})
+
+	t.Run("add and load chunks nil receiver", func(t *testing.T) {
+		var stats *Stats
+		stats.AddFetchedIngesterChunks(3)
+		stats.AddFetchedStoreGatewayChunks(3)
+
+		assert.Equal(t, uint64(0), stats.LoadFetchedIngesterChunks())
+		assert.Equal(t, uint64(0), stats.LoadFetchedStoreGatewayChunks())
+	})
+}
+
+func TestStats_QueueTime(t *testing.T) {
+	t.Run("add and load queue time", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddQueueTime(time.Second)
+		stats.AddQueueTime(time.Second)
+
+		assert.Equal(t, 2*time.Second, stats.LoadQueueTime())
+	})
+
+	t.Run("add and load queue time nil receiver", func(t *testing.T) {
+		var stats *Stats
+		stats.AddQueueTime(time.Second)
+
+		assert.Equal(t, time.Duration(0), stats.LoadQueueTime())
+	})
+}
+
 func TestStats_Merge(t *testing.T) {
 	t.Run("merge two stats objects", func(t *testing.T) {
 		stats1 := &Stats{}
@@ -124,6 +180,10 @@ func TestStats_Merge(t *testing.T) {
 		stats1.AddFetchedChunks(10)
 		stats1.AddShardedQueries(20)
 		stats1.AddSplitQueries(10)
+		stats1.AddFetchedSamples(100)
+		stats1.AddFetchedIngesterChunks(4)
+		stats1.AddFetchedStoreGatewayChunks(6)
+		stats1.AddQueueTime(time.Millisecond)
 
 		stats2 := &Stats{}
 		stats2.AddWallTime(time.Second)
@@ -132,6 +192,10 @@ func TestStats_Merge(t *testing.T) {
 		stats2.AddFetchedChunks(11)
 		stats2.AddShardedQueries(21)
 		stats2.AddSplitQueries(11)
+		stats2.AddFetchedSamples(200)
+		stats2.AddFetchedIngesterChunks(5)
+		stats2.AddFetchedStoreGatewayChunks(6)
+		stats2.AddQueueTime(time.Second)
 
 		stats1.Merge(stats2)
 
@@ -141,6 +205,10 @@ func TestStats_Merge(t *testing.T) {
 		assert.Equal(t, uint64(21), stats1.LoadFetchedChunks())
 		assert.Equal(t, uint32(41), stats1.LoadShardedQueries())
 		assert.Equal(t, uint32(21), stats1.LoadSplitQueries())
+		assert.Equal(t, uint64(300), stats1.LoadFetchedSamples())
+		assert.Equal(t, uint64(9), stats1.LoadFetchedIngesterChunks())
+		assert.Equal(t, uint64(12), stats1.LoadFetchedStoreGatewayChunks())
+		assert.Equal(t, 1001*time.Millisecond, stats1.LoadQueueTime())
 	})
 
 	t.Run("merge two nil stats objects", func(t *testing.T) {
@@ -155,5 +223,28 @@ func TestStats_Merge(t *testing.T) {
 		assert.Equal(t, uint64(0), stats1.LoadFetchedChunks())
 		assert.Equal(t, uint32(0), stats1.LoadShardedQueries())
 		assert.Equal(t, uint32(0), stats1.LoadSplitQueries())
+		assert.Equal(t, uint64(0), stats1.LoadFetchedSamples())
+		assert.Equal(t, time.Duration(0), stats1.LoadQueueTime())
 	})
 }
+
+func TestStats_MarshalUnmarshal(t *testing.T) {
+	stats := &Stats{}
+	stats.AddWallTime(time.Second)
+	stats.AddFetchedSeries(10)
+	stats.AddFetchedChunkBytes(1024)
+	stats.AddFetchedChunks(20)
+	stats.AddShardedQueries(16)
+	stats.AddSplitQueries(2)
+	stats.AddFetchedSamples(2400)
+	stats.AddFetchedIngesterChunks(8)
+	stats.AddFetchedStoreGatewayChunks(12)
+	stats.AddQueueTime(time.Millisecond)
+
+	data, err := stats.Marshal()
+	require.NoError(t, err)
+
+	actual := &Stats{}
+	require.NoError(t, actual.Unmarshal(data))
+	assert.Equal(t, stats, actual)
+}
@@ -28,8 +28,10 @@ import (
 // By setting bypassWithSingleQuerier to true, tenant federation logic gets
 // bypassed if the request is only for a single tenant. The requests will also
 // not contain the pseudo series label __tenant_id__ in this case.
-func NewExemplarQueryable(upstream storage.ExemplarQueryable, bypassWithSingleQuerier bool, logger log.Logger) storage.ExemplarQueryable {
-	return NewMergeExemplarQueryable(defaultTenantLabel, upstream, bypassWithSingleQuerier, logger)
+//
+// The requests for more than maxTenants tenants are rejected, unless maxTenants is 0.
+func NewExemplarQueryable(upstream storage.ExemplarQueryable, bypassWithSingleQuerier bool, maxTenants int, logger log.Logger) storage.ExemplarQueryable {
+	return NewMergeExemplarQueryable(defaultTenantLabel, upstream, bypassWithSingleQuerier, maxTenants, logger)
 }
 
 // NewMergeExemplarQueryable returns an exemplar queryable that makes requests for
@@ -42,11 +44,14 @@ func NewExemplarQueryable(upstream storage.ExemplarQueryable, bypassWithSingleQu
 // By setting bypassWithSingleQuerier to true, tenant federation logic gets
 // bypassed if the request is only for a single tenant. The requests will also
 // not contain the pseudo series label `idLabelName` in this case.
-func NewMergeExemplarQueryable(idLabelName string, upstream storage.ExemplarQueryable, bypassWithSingleQuerier bool, logger log.Logger) storage.ExemplarQueryable {
+//
+// The requests for more than maxTenants tenants are rejected, unless maxTenants is 0.
+func NewMergeExemplarQueryable(idLabelName string, upstream storage.ExemplarQueryable, bypassWithSingleQuerier bool, maxTenants int, logger log.Logger) storage.ExemplarQueryable {
 	return &mergeExemplarQueryable{
 		logger:                  logger,
 		idLabelName:             idLabelName,
 		bypassWithSingleQuerier: bypassWithSingleQuerier,
+		maxTenants:              maxTenants,
 		upstream:                upstream,
 		resolver:                tenant.NewMultiResolver(),
 	}
@@ -56,6 +61,7 @@ type mergeExemplarQueryable struct {
 	logger                  log.Logger
 	idLabelName             string
 	bypassWithSingleQuerier bool
+	maxTenants              int
 	upstream                storage.ExemplarQueryable
 	resolver                tenant.Resolver
 }
@@ -66,6 +72,9 @@ func (m *mergeExemplarQueryable) tenantsAndQueriers(ctx context.Context) ([]stri
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := checkMaxTenants(tenantIDs, m.maxTenants); err != nil {
+		return nil, nil, err
+	}
 
 	queriers := make([]storage.ExemplarQuerier, len(tenantIDs))
 	for i, tenantID := range tenantIDs {
@@ -95,7 +95,7 @@ func (m *mockExemplarQuerier) matches(res exemplar.QueryResult, matchers []*labe
 func TestMergeExemplarQueryable_ExemplarQuerier(t *testing.T) {
 	t.Run("error getting tenant IDs", func(t *testing.T) {
 		upstream := &mockExemplarQueryable{}
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 
 		q, err := federated.ExemplarQuerier(context.Background())
 		assert.ErrorIs(t, err, user.ErrNoOrgID)
@@ -105,18 +105,28 @@ func TestMergeExemplarQueryable_ExemplarQuerier(t *testing.T) {
 	t.Run("error getting upstream querier", func(t *testing.T) {
 		ctx := user.InjectOrgID(context.Background(), "123")
 		upstream := &mockExemplarQueryable{err: errors.New("unable to get querier")}
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 
 		q, err := federated.ExemplarQuerier(ctx)
 		assert.Error(t, err)
 		assert.Nil(t, q)
 	})
 
+	t.Run("more tenants than the max tenants", func(t *testing.T) {
+		ctx := user.InjectOrgID(context.Background(), "123|456")
+		upstream := &mockExemplarQueryable{queriers: map[string]storage.ExemplarQuerier{"123": &mockExemplarQuerier{}, "456": &mockExemplarQuerier{}}}
+		federated := NewExemplarQueryable(upstream, false, 1, test.NewTestingLogger(t))
+
+		q, err := federated.ExemplarQuerier(ctx)
+		assert.ErrorContains(t, err, "err-mimir-tenant-federation-max-tenants")
+		assert.Nil(t, q)
+	})
+
 	t.Run("single tenant bypass single querier happy path", func(t *testing.T) {
 		ctx := user.InjectOrgID(context.Background(), "123")
 		querier := &mockExemplarQuerier{}
 		upstream := &mockExemplarQueryable{queriers: map[string]storage.ExemplarQuerier{"123": querier}}
-		federated := NewExemplarQueryable(upstream, true, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, true, 0, test.NewTestingLogger(t))
 
 		q, err := federated.ExemplarQuerier(ctx)
 		assert.NoError(t, err)
@@ -127,7 +137,7 @@ func TestMergeExemplarQueryable_ExemplarQuerier(t *testing.T) {
 		ctx := user.InjectOrgID(context.Background(), "123")
 		querier := &mockExemplarQuerier{}
 		upstream := &mockExemplarQueryable{queriers: map[string]storage.ExemplarQuerier{"123": querier}}
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 
 		q, err := federated.ExemplarQuerier(ctx)
 		require.NoError(t, err)
@@ -146,7 +156,7 @@ func TestMergeExemplarQueryable_ExemplarQuerier(t *testing.T) {
 			"123": querier1,
 			"456": querier2,
 		}}
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 
 		q, err := federated.ExemplarQuerier(ctx)
 		require.NoError(t, err)
@@ -218,7 +228,7 @@ func TestMergeExemplarQuerier_Select(t *testing.T) {
 			"456": &mockExemplarQuerier{res: res2},
 		}}
 
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 		q, err := federated.ExemplarQuerier(user.InjectOrgID(context.Background(), "123|456"))
 		require.NoError(t, err)
 
@@ -243,7 +253,7 @@ func TestMergeExemplarQuerier_Select(t *testing.T) {
 			"456": &mockExemplarQuerier{res: res2},
 		}}
 
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 		q, err := federated.ExemplarQuerier(user.InjectOrgID(context.Background(), "123|456"))
 		require.NoError(t, err)
 
@@ -273,7 +283,7 @@ func TestMergeExemplarQuerier_Select(t *testing.T) {
 			"456": &mockExemplarQuerier{res: res2},
 		}}
 
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 		q, err := federated.ExemplarQuerier(user.InjectOrgID(context.Background(), "123|456"))
 		require.NoError(t, err)
 
@@ -294,7 +304,7 @@ func TestMergeExemplarQuerier_Select(t *testing.T) {
 			"456": &mockExemplarQuerier{res: res2},
 		}}
 
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 		q, err := federated.ExemplarQuerier(user.InjectOrgID(context.Background(), "123|456"))
 		require.NoError(t, err)
 
@@ -316,7 +326,7 @@ func TestMergeExemplarQuerier_Select(t *testing.T) {
 			"456": &mockExemplarQuerier{err: errors.New("timeout running exemplar query")},
 		}}
 
-		federated := NewExemplarQueryable(upstream, false, test.NewTestingLogger(t))
+		federated := NewExemplarQueryable(upstream, false, 0, test.NewTestingLogger(t))
 		q, err := federated.ExemplarQuerier(user.InjectOrgID(context.Background(), "123|456"))
 		require.NoError(t, err)
 
@@ -20,19 +20,22 @@ import (
 // NewMetadataSupplier returns a querier.MetadataSupplier that returns metric
 // metadata for all tenant IDs that are part of the request and merges the results.
 //
-// No deduplication of metadata is done before being returned.
-func NewMetadataSupplier(next querier.MetadataSupplier, logger log.Logger) querier.MetadataSupplier {
+// No deduplication of metadata is done before being returned. The requests for
+// more than maxTenants tenants are rejected, unless maxTenants is 0.
+func NewMetadataSupplier(next querier.MetadataSupplier, maxTenants int, logger log.Logger) querier.MetadataSupplier {
 	return &mergeMetadataSupplier{
-		next:     next,
-		logger:   logger,
-		resolver: tenant.NewMultiResolver(),
+		next:       next,
+		maxTenants: maxTenants,
+		logger:     logger,
+		resolver:   tenant.NewMultiResolver(),
 	}
 }
 
 type mergeMetadataSupplier struct {
-	next     querier.MetadataSupplier
-	resolver tenant.Resolver
-	logger   log.Logger
+	next       querier.MetadataSupplier
+	maxTenants int
+	resolver   tenant.Resolver
+	logger     log.Logger
 }
 
 func (m *mergeMetadataSupplier) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error) {
@@ -43,6 +46,9 @@ func (m *mergeMetadataSupplier) MetricsMetadata(ctx context.Context) ([]scrape.M
 	if err != nil {
 		return nil, err
 	}
+	if err := checkMaxTenants(tenantIDs, m.maxTenants); err != nil {
+		return nil, err
+	}
 
 	if len(tenantIDs) == 1 {
 		level.Debug(spanlog).Log("msg", "only a single tenant, bypassing federated metadata supplier")
@@ -48,12 +48,20 @@ func TestMergeMetadataSupplier_MetricsMetadata(t *testing.T) {
 
 	t.Run("invalid tenant IDs", func(t *testing.T) {
 		upstream := &mockMetadataSupplier{}
-		supplier := NewMetadataSupplier(upstream, test.NewTestingLogger(t))
+		supplier := NewMetadataSupplier(upstream, 0, test.NewTestingLogger(t))
 		_, err := supplier.MetricsMetadata(context.Background())
 
 		assert.ErrorIs(t, err, user.ErrNoOrgID)
 	})
 
+	t.Run("more tenants than the max tenants", func(t *testing.T) {
+		upstream := &mockMetadataSupplier{}
+		supplier := NewMetadataSupplier(upstream, 1, test.NewTestingLogger(t))
+		_, err := supplier.MetricsMetadata(user.InjectOrgID(context.Background(), "team-a|team-b"))
+
+		assert.ErrorContains(t, err, "err-mimir-tenant-federation-max-tenants")
+	})
+
 	t.Run("single tenant bypass", func(t *testing.T) {
 		upstream := &mockMetadataSupplier{
 			results: map[string][]scrape.MetricMetadata{
@@ -61,7 +69,7 @@ func TestMergeMetadataSupplier_MetricsMetadata(t *testing.T) {
 			},
 		}
 
-		supplier := NewMetadataSupplier(upstream, test.NewTestingLogger(t))
+		supplier := NewMetadataSupplier(upstream, 0, test.NewTestingLogger(t))
 		res, err := supplier.MetricsMetadata(user.InjectOrgID(context.Background(), "team-a"))
 
 		require.NoError(t, err)
@@ -77,7 +85,7 @@ func TestMergeMetadataSupplier_MetricsMetadata(t *testing.T) {
 			},
 		}
 
-		supplier := NewMetadataSupplier(upstream, test.NewTestingLogger(t))
+		supplier := NewMetadataSupplier(upstream, 0, test.NewTestingLogger(t))
 		res, err := supplier.MetricsMetadata(user.InjectOrgID(context.Background(), "team-a|team-b"))
 
 		require.NoError(t, err)
@@ -94,7 +102,7 @@ func TestMergeMetadataSupplier_MetricsMetadata(t *testing.T) {
 			},
 		}
 
-		supplier := NewMetadataSupplier(upstream, test.NewTestingLogger(t))
+		supplier := NewMetadataSupplier(upstream, 0, test.NewTestingLogger(t))
 		res, err := supplier.MetricsMetadata(user.InjectOrgID(context.Background(), "team-a|team-b"))
 
 		require.NoError(t, err)
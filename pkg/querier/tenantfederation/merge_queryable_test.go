@@ -29,6 +29,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -267,7 +268,7 @@ type mergeQueryableScenario struct {
 
 func (s *mergeQueryableScenario) init() (storage.Querier, error) {
 	// initialize with default tenant label
-	q := NewQueryable(&s.queryable, !s.doNotByPassSingleQuerier, log.NewNopLogger())
+	q := NewQueryable(&s.queryable, !s.doNotByPassSingleQuerier, 0, log.NewNopLogger())
 
 	// inject tenants into context
 	ctx := context.Background()
@@ -346,13 +347,29 @@ type labelValuesScenario struct {
 func TestMergeQueryable_Querier(t *testing.T) {
 	t.Run("querying without a tenant specified should error", func(t *testing.T) {
 		queryable := &mockTenantQueryableWithFilter{logger: log.NewNopLogger()}
-		q := NewQueryable(queryable, false /* bypassWithSingleQuerier */, log.NewNopLogger())
+		q := NewQueryable(queryable, false /* bypassWithSingleQuerier */, 0, log.NewNopLogger())
 		// Create a context with no tenant specified.
 		ctx := context.Background()
 
 		_, err := q.Querier(ctx, mint, maxt)
 		require.EqualError(t, err, user.ErrNoOrgID.Error())
 	})
+
+	t.Run("querying more tenants than the max tenants should error", func(t *testing.T) {
+		// Set a multi tenant resolver.
+		tenant.WithDefaultResolver(tenant.NewMultiResolver())
+
+		queryable := &mockTenantQueryableWithFilter{logger: log.NewNopLogger()}
+		q := NewQueryable(queryable, false /* bypassWithSingleQuerier */, 2, log.NewNopLogger())
+
+		_, err := q.Querier(user.InjectOrgID(context.Background(), "team-a|team-b"), mint, maxt)
+		require.NoError(t, err)
+
+		_, err = q.Querier(user.InjectOrgID(context.Background(), "team-a|team-b|team-c"), mint, maxt)
+		require.Error(t, err)
+		require.IsType(t, validation.LimitError(""), err)
+		require.Contains(t, err.Error(), "err-mimir-tenant-federation-max-tenants")
+	})
 }
 
 var (
@@ -927,7 +944,7 @@ func TestTracingMergeQueryable(t *testing.T) {
 	// set a multi tenant resolver
 	tenant.WithDefaultResolver(tenant.NewMultiResolver())
 	filter := mockTenantQueryableWithFilter{}
-	q := NewQueryable(&filter, false, log.NewNopLogger())
+	q := NewQueryable(&filter, false, 0, log.NewNopLogger())
 	// retrieve querier if set
 	querier, err := q.Querier(ctx, mint, maxt)
 	require.NoError(t, err)
@@ -7,23 +7,47 @@ package tenantfederation
 
 import (
 	"flag"
+	"fmt"
 
 	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
 	defaultTenantLabel   = "__tenant_id__"
 	retainExistingPrefix = "original_"
 	maxConcurrency       = 16
+
+	maxTenantsFlag = "tenant-federation.max-tenants"
 )
 
 type Config struct {
 	// Enabled switches on support for multi tenant query federation
 	Enabled bool `yaml:"enabled"`
+	// MaxTenants is the max number of tenants a query can be federated across
+	MaxTenants int `yaml:"max_tenants"`
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&cfg.Enabled, "tenant-federation.enabled", false, "If enabled on all services, queries can be federated across multiple tenants. The tenant IDs involved need to be specified separated by a '|' character in the 'X-Scope-OrgID' header.")
+	f.IntVar(&cfg.MaxTenants, maxTenantsFlag, 0, "The max number of tenants a query can be federated across. The queries federated across more tenants are rejected. 0 to disable the limit.")
+}
+
+// checkMaxTenants returns an error if the number of tenants exceeds the max number of tenants a query
+// can be federated across. A maxTenants of 0 disables the limit.
+func checkMaxTenants(tenantIDs []string, maxTenants int) error {
+	if maxTenants > 0 && len(tenantIDs) > maxTenants {
+		return newMaxTenantsError(len(tenantIDs), maxTenants)
+	}
+	return nil
+}
+
+func newMaxTenantsError(tenants, maxTenants int) validation.LimitError {
+	return validation.LimitError(globalerror.TenantFederationMaxTenants.MessageWithPerInstanceLimitConfig(
+		fmt.Sprintf("the query has been rejected because it's federated across %d tenants, which exceeds the limit of %d tenants", tenants, maxTenants),
+		maxTenantsFlag))
 }
 
 // filterValuesByMatchers applies matchers to inputed `idLabelName` and
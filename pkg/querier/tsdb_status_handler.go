@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// defaultTSDBStatusLimit is the default number of items of each statistic, the same as Prometheus.
+const defaultTSDBStatusLimit = 10
+
+// BlocksStats holds the statistics of the blocks of a tenant in the long-term storage.
+type BlocksStats struct {
+	NumBlocks int
+	SizeBytes int64
+
+	// MinTime of the oldest block and MaxTime of the newest block (millis precision). Both are 0 if there are no blocks.
+	MinTime int64
+	MaxTime int64
+}
+
+// BlocksStatsSupplier supplies the statistics of the blocks of the tenants in the long-term storage.
+type BlocksStatsSupplier interface {
+	BlocksStats(ctx context.Context, userID string) (BlocksStats, error)
+}
+
+// tsdbStatusResult is the response of the Prometheus /api/v1/status/tsdb API.
+type tsdbStatusResult struct {
+	Status    string      `json:"status"`
+	Data      *tsdbStatus `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type tsdbStatus struct {
+	HeadStats                   tsdbHeadStats          `json:"headStats"`
+	SeriesCountByMetricName     []cardinalityStatsItem `json:"seriesCountByMetricName"`
+	LabelValueCountByLabelName  []cardinalityStatsItem `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []cardinalityStatsItem `json:"memoryInBytesByLabelName"`
+	SeriesCountByLabelValuePair []cardinalityStatsItem `json:"seriesCountByLabelValuePair"`
+
+	// BlocksStats is not part of the Prometheus API.
+	BlocksStats *tsdbBlocksStats `json:"blocksStats,omitempty"`
+}
+
+// tsdbHeadStats are the statistics of the in-memory series of the tenant. The number of label pairs and chunks are
+// not tracked in Mimir, and always 0. The ingesters don't report the time range of their in-memory series, so the
+// time range is the one of the queryable series instead: from the oldest block of the tenant in the long-term storage,
+// if any, up to now. The time range is omitted if there are no in-memory series, like Prometheus returns an undefined
+// time range for an empty head.
+type tsdbHeadStats struct {
+	NumSeries     uint64 `json:"numSeries"`
+	NumLabelPairs int    `json:"numLabelPairs"`
+	ChunkCount    int64  `json:"chunkCount"`
+	MinTime       *int64 `json:"minTime,omitempty"`
+	MaxTime       *int64 `json:"maxTime,omitempty"`
+}
+
+// tsdbBlocksStats are the statistics of the blocks of the tenant. The time range is omitted if there are no blocks.
+type tsdbBlocksStats struct {
+	NumBlocks int    `json:"numBlocks"`
+	SizeBytes int64  `json:"sizeBytes"`
+	MinTime   *int64 `json:"minTime,omitempty"`
+	MaxTime   *int64 `json:"maxTime,omitempty"`
+}
+
+// TSDBStatusHandler creates a handler for the Prometheus-compatible TSDB status endpoint, serving the cardinality
+// statistics of the in-memory series of the tenant, read from the ingesters, and the statistics of the tenant's blocks,
+// read from the bucket index.
+func TSDBStatusHandler(d Distributor, blocks BlocksStatsSupplier, limits *validation.Overrides) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			writeTSDBStatusError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !limits.CardinalityAnalysisEnabled(tenantID) {
+			writeTSDBStatusError(w, http.StatusBadRequest, fmt.Errorf("cardinality analysis is disabled for the tenant: %v", tenantID))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			writeTSDBStatusError(w, http.StatusBadRequest, err)
+			return
+		}
+		limit := defaultTSDBStatusLimit
+		if len(r.Form["limit"]) > 0 {
+			if limit, err = extractLimit(r); err != nil {
+				writeTSDBStatusError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		head, err := d.HeadCardinalityStats(ctx, limit)
+		if err != nil {
+			writeTSDBStatusError(w, httpStatusCodeFromError(err), err)
+			return
+		}
+
+		status := &tsdbStatus{
+			HeadStats:                   tsdbHeadStats{NumSeries: head.NumSeries},
+			SeriesCountByMetricName:     toCardinalityStatsItems(head.SeriesCountByMetricName),
+			LabelValueCountByLabelName:  toCardinalityStatsItems(head.LabelValueCountByLabelName),
+			MemoryInBytesByLabelName:    []cardinalityStatsItem{},
+			SeriesCountByLabelValuePair: toCardinalityStatsItems(head.SeriesCountByLabelValuePair),
+		}
+
+		now := time.Now().UnixMilli()
+		headMinTime := now
+		if blocks != nil {
+			stats, err := blocks.BlocksStats(ctx, tenantID)
+			if err != nil {
+				writeTSDBStatusError(w, httpStatusCodeFromError(err), err)
+				return
+			}
+			status.BlocksStats = &tsdbBlocksStats{
+				NumBlocks: stats.NumBlocks,
+				SizeBytes: stats.SizeBytes,
+			}
+			if stats.NumBlocks > 0 {
+				headMinTime = stats.MinTime
+				status.BlocksStats.MinTime = &stats.MinTime
+				status.BlocksStats.MaxTime = &stats.MaxTime
+			}
+		}
+		if head.NumSeries > 0 {
+			status.HeadStats.MinTime = &headMinTime
+			status.HeadStats.MaxTime = &now
+		}
+
+		util.WriteJSONResponse(w, tsdbStatusResult{Status: statusSuccess, Data: status})
+	})
+}
+
+// httpStatusCodeFromError returns the status code of the httpgrpc error, or 500 for the other errors.
+func httpStatusCodeFromError(err error) int {
+	if resp, ok := httpgrpc.HTTPResponseFromError(errors.Cause(err)); ok {
+		return int(resp.Code)
+	}
+	return http.StatusInternalServerError
+}
+
+func writeTSDBStatusError(w http.ResponseWriter, statusCode int, err error) {
+	errorType := "internal"
+	if statusCode/100 == 4 {
+		errorType = "bad_data"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	util.WriteJSONResponse(w, tsdbStatusResult{Status: statusError, ErrorType: errorType, Error: err.Error()})
+}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+type blocksStatsSupplierFunc func(ctx context.Context, userID string) (BlocksStats, error)
+
+func (f blocksStatsSupplierFunc) BlocksStats(ctx context.Context, userID string) (BlocksStats, error) {
+	return f(ctx, userID)
+}
+
+func TestTSDBStatusHandler(t *testing.T) {
+	head := &client.HeadCardinalityStatsResponse{
+		NumSeries:                   3,
+		LabelValueCountByLabelName:  []*client.CardinalityStatsItem{{Name: "__name__", Value: 2}, {Name: "status", Value: 1}},
+		SeriesCountByMetricName:     []*client.CardinalityStatsItem{{Name: "test_1", Value: 2}, {Name: "test_2", Value: 1}},
+		SeriesCountByLabelValuePair: []*client.CardinalityStatsItem{{Name: "__name__=test_1", Value: 2}},
+	}
+	blocks := BlocksStats{NumBlocks: 2, SizeBytes: 1024, MinTime: 1000, MaxTime: 5000}
+
+	tests := map[string]struct {
+		url                string
+		head               *client.HeadCardinalityStatsResponse
+		distributorError   error
+		blocks             BlocksStats
+		blocksError        error
+		expectedLimit      int
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		"should return the status with the default limit": {
+			url:                "/api/v1/status/tsdb",
+			blocks:             blocks,
+			expectedLimit:      defaultTSDBStatusLimit,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{
+				"status": "success",
+				"data": {
+					"headStats": {"numSeries": 3, "numLabelPairs": 0, "chunkCount": 0, "minTime": 1000, "maxTime": <now>},
+					"seriesCountByMetricName": [{"name": "test_1", "value": 2}, {"name": "test_2", "value": 1}],
+					"labelValueCountByLabelName": [{"name": "__name__", "value": 2}, {"name": "status", "value": 1}],
+					"memoryInBytesByLabelName": [],
+					"seriesCountByLabelValuePair": [{"name": "__name__=test_1", "value": 2}],
+					"blocksStats": {"numBlocks": 2, "sizeBytes": 1024, "minTime": 1000, "maxTime": 5000}
+				}
+			}`,
+		},
+		"should return the status with the requested limit": {
+			url:                "/api/v1/status/tsdb?limit=5",
+			blocks:             blocks,
+			expectedLimit:      5,
+			expectedStatusCode: http.StatusOK,
+		},
+		"should return the status of a tenant without blocks": {
+			url:                "/api/v1/status/tsdb",
+			expectedLimit:      defaultTSDBStatusLimit,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{
+				"status": "success",
+				"data": {
+					"headStats": {"numSeries": 3, "numLabelPairs": 0, "chunkCount": 0, "minTime": <now>, "maxTime": <now>},
+					"seriesCountByMetricName": [{"name": "test_1", "value": 2}, {"name": "test_2", "value": 1}],
+					"labelValueCountByLabelName": [{"name": "__name__", "value": 2}, {"name": "status", "value": 1}],
+					"memoryInBytesByLabelName": [],
+					"seriesCountByLabelValuePair": [{"name": "__name__=test_1", "value": 2}],
+					"blocksStats": {"numBlocks": 0, "sizeBytes": 0}
+				}
+			}`,
+		},
+		"should omit the head time range of a tenant without in-memory series": {
+			url:                "/api/v1/status/tsdb",
+			head:               &client.HeadCardinalityStatsResponse{},
+			blocks:             blocks,
+			expectedLimit:      defaultTSDBStatusLimit,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{
+				"status": "success",
+				"data": {
+					"headStats": {"numSeries": 0, "numLabelPairs": 0, "chunkCount": 0},
+					"seriesCountByMetricName": [],
+					"labelValueCountByLabelName": [],
+					"memoryInBytesByLabelName": [],
+					"seriesCountByLabelValuePair": [],
+					"blocksStats": {"numBlocks": 2, "sizeBytes": 1024, "minTime": 1000, "maxTime": 5000}
+				}
+			}`,
+		},
+		"should return an error if the limit is invalid": {
+			url:                "/api/v1/status/tsdb?limit=501",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status": "error", "errorType": "bad_data", "error": "'limit' param cannot be greater than '500'"}`,
+		},
+		"should return the status code of the httpgrpc error of the distributor": {
+			url:                "/api/v1/status/tsdb",
+			distributorError:   httpgrpc.Errorf(http.StatusServiceUnavailable, "ingesters unavailable"),
+			expectedLimit:      defaultTSDBStatusLimit,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       `{"status": "error", "errorType": "internal", "error": "rpc error: code = Code(503) desc = ingesters unavailable"}`,
+		},
+		"should return internal server error if reading the blocks stats fails": {
+			url:                "/api/v1/status/tsdb",
+			blocksError:        fmt.Errorf("bucket index unavailable"),
+			expectedLimit:      defaultTSDBStatusLimit,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status": "error", "errorType": "internal", "error": "bucket index unavailable"}`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			testHead := head
+			if testData.head != nil {
+				testHead = testData.head
+			}
+			distributor := &mockDistributor{}
+			distributor.On("HeadCardinalityStats", mock.Anything, testData.expectedLimit).Return(testHead, testData.distributorError)
+			supplier := blocksStatsSupplierFunc(func(_ context.Context, userID string) (BlocksStats, error) {
+				assert.Equal(t, "team-a", userID)
+				return testData.blocks, testData.blocksError
+			})
+
+			overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: true}, nil)
+			require.NoError(t, err)
+			handler := TSDBStatusHandler(distributor, supplier, overrides)
+
+			recorder := httptest.NewRecorder()
+			before := time.Now().UnixMilli()
+			handler.ServeHTTP(recorder, createRequest(testData.url, "team-a"))
+			after := time.Now().UnixMilli()
+
+			require.Equal(t, testData.expectedStatusCode, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			if testData.expectedStatusCode == http.StatusOK {
+				distributor.AssertCalled(t, "HeadCardinalityStats", mock.Anything, testData.expectedLimit)
+			}
+			if testData.expectedBody == "" {
+				return
+			}
+
+			// The current time is replaced in the response, to compare it with the expected one.
+			var actual tsdbStatusResult
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &actual))
+			if actual.Data != nil {
+				for _, ts := range []*int64{actual.Data.HeadStats.MinTime, actual.Data.HeadStats.MaxTime} {
+					if ts != nil && *ts >= before && *ts <= after {
+						*ts = -1
+					}
+				}
+			}
+			actualBody, err := json.Marshal(actual)
+			require.NoError(t, err)
+			assert.JSONEq(t, strings.ReplaceAll(testData.expectedBody, "<now>", "-1"), string(actualBody))
+		})
+	}
+}
+
+func TestTSDBStatusHandler_FeatureFlag(t *testing.T) {
+	distributor := &mockDistributor{}
+	overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: false}, nil)
+	require.NoError(t, err)
+	handler := TSDBStatusHandler(distributor, nil, overrides)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/api/v1/status/tsdb", "team-a"))
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"status": "error", "errorType": "bad_data", "error": "cardinality analysis is disabled for the tenant: team-a"}`, recorder.Body.String())
+	distributor.AssertNotCalled(t, "HeadCardinalityStats", mock.Anything, mock.Anything)
+}
+
+func TestBlocksStoreQueryable_BlocksStats(t *testing.T) {
+	finder := &blocksFinderMock{Service: services.NewIdleService(nil, nil)}
+	finder.On("GetBlocks", mock.Anything, "user-1", int64(0), int64(math.MaxInt64)).Return(bucketindex.Blocks{
+		{ID: ulid.MustNew(1, nil), MinTime: 2000, MaxTime: 4000, SizeBytes: 100},
+		{ID: ulid.MustNew(2, nil), MinTime: 1000, MaxTime: 3000, SizeBytes: 200},
+	}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+	finder.On("GetBlocks", mock.Anything, "user-2", int64(0), int64(math.MaxInt64)).Return(bucketindex.Blocks(nil), map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+	stores := &blocksStoreSetMock{Service: services.NewIdleService(nil, nil)}
+	logger := log.NewNopLogger()
+	queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, 0, false, false, 0, logger, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), queryable))
+	defer services.StopAndAwaitTerminated(context.Background(), queryable) // nolint:errcheck
+
+	stats, err := queryable.BlocksStats(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, BlocksStats{NumBlocks: 2, SizeBytes: 300, MinTime: 1000, MaxTime: 4000}, stats)
+
+	stats, err = queryable.BlocksStats(context.Background(), "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, BlocksStats{}, stats)
+}
@@ -24,6 +24,17 @@ import (
 	"github.com/grafana/mimir/pkg/util"
 )
 
+// How frequently the max concurrent requests overridden by the runtime config are reloaded.
+const maxConcurrentRequestsReloadPeriod = 10 * time.Second
+
+// InstanceLimits are the limits of the querier which can be overridden by the runtime config, so that they
+// can be changed without restarting the queriers.
+type InstanceLimits struct {
+	// MaxConcurrent overrides -querier.max-concurrent for the number of queries fetched concurrently from the
+	// query-frontends or query-schedulers, up to -querier.max-concurrent. 0 to not override it.
+	MaxConcurrent int `yaml:"max_concurrent"`
+}
+
 type Config struct {
 	FrontendAddress       string        `yaml:"frontend_address"`
 	SchedulerAddress      string        `yaml:"scheduler_address"`
@@ -32,6 +43,10 @@ type Config struct {
 	QuerierID             string        `yaml:"id" category:"advanced"`
 
 	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config"`
+
+	// MaxConcurrentRequestsFn returns the max concurrent requests overriding MaxConcurrentRequests, or 0 if not
+	// overridden. It's periodically reloaded, so that the concurrency can be changed without restarting the querier.
+	MaxConcurrentRequestsFn func() int `yaml:"-"`
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
@@ -84,6 +99,8 @@ type querierWorker struct {
 	mu sync.Mutex
 	// Set to nil when stop is called... no more managers are created afterwards.
 	managers map[string]*processorManager
+	// The max concurrent requests the concurrency of the managers has been last reset to.
+	maxConcurrentRequests int
 }
 
 func NewQuerierWorker(cfg Config, handler RequestHandler, log log.Logger, reg prometheus.Registerer) (services.Service, error) {
@@ -127,6 +144,10 @@ func newQuerierWorkerWithProcessor(cfg Config, log log.Logger, processor process
 		processor: processor,
 	}
 
+	if cfg.MaxConcurrentRequestsFn != nil {
+		servs = append(servs, services.NewTimerService(maxConcurrentRequestsReloadPeriod, nil, f.reloadConcurrency, nil).WithName("querier worker concurrency reloader"))
+	}
+
 	// Empty address is only used in tests, where individual targets are added manually.
 	if address != "" {
 		w, err := util.NewDNSWatcher(address, cfg.DNSLookupPeriod, f)
@@ -212,23 +233,54 @@ func (w *querierWorker) AddressRemoved(address string) {
 	}
 }
 
+// reloadConcurrency resets the concurrency of the managers if the max concurrent requests overridden by the
+// runtime config has changed.
+func (w *querierWorker) reloadConcurrency(_ context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The managers must not get processors again once they've been stopped.
+	if w.State() != services.Running {
+		return nil
+	}
+
+	if maxConcurrent := w.getMaxConcurrentRequests(); maxConcurrent != w.maxConcurrentRequests {
+		level.Info(w.log).Log("msg", "max concurrent requests changed, resetting the concurrency", "old", w.maxConcurrentRequests, "new", maxConcurrent)
+		w.resetConcurrency()
+	}
+	return nil
+}
+
+// getMaxConcurrentRequests returns the max concurrent requests, as overridden by the runtime config if set.
+func (w *querierWorker) getMaxConcurrentRequests() int {
+	if w.cfg.MaxConcurrentRequestsFn != nil {
+		// The queries beyond -querier.max-concurrent would wait for the PromQL engine anyway.
+		if maxConcurrent := w.cfg.MaxConcurrentRequestsFn(); maxConcurrent > 0 && maxConcurrent < w.cfg.MaxConcurrentRequests {
+			return maxConcurrent
+		}
+	}
+	return w.cfg.MaxConcurrentRequests
+}
+
 // Must be called with lock.
 func (w *querierWorker) resetConcurrency() {
 	totalConcurrency := 0
 	index := 0
+	maxConcurrent := w.getMaxConcurrentRequests()
+	w.maxConcurrentRequests = maxConcurrent
 
 	for _, m := range w.managers {
-		concurrency := w.cfg.MaxConcurrentRequests / len(w.managers)
+		concurrency := maxConcurrent / len(w.managers)
 
 		// If max concurrency does not evenly divide into our frontends a subset will be chosen
 		// to receive an extra connection.  Frontend addresses were shuffled above so this will be a
 		// random selection of frontends.
-		if index < w.cfg.MaxConcurrentRequests%len(w.managers) {
+		if index < maxConcurrent%len(w.managers) {
 			level.Warn(w.log).Log("msg", "max concurrency is not evenly divisible across targets, adding an extra connection", "addr", m.address)
 			concurrency++
 		}
 
-		// If concurrency is 0 then MaxConcurrentRequests is less than the total number of
+		// If concurrency is 0 then the max concurrent requests is less than the total number of
 		// frontends/schedulers. In order to prevent accidentally starving a frontend or scheduler we are just going to
 		// always connect once to every target.  This is dangerous b/c we may start exceeding PromQL
 		// max concurrency.
@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/dskit/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 )
 
@@ -72,6 +73,42 @@ func TestResetConcurrency(t *testing.T) {
 	}
 }
 
+func TestReloadConcurrency(t *testing.T) {
+	maxConcurrent := atomic.NewInt64(0)
+	cfg := Config{
+		MaxConcurrentRequests:   8,
+		MaxConcurrentRequestsFn: func() int { return int(maxConcurrent.Load()) },
+	}
+
+	w, err := newQuerierWorkerWithProcessor(cfg, log.NewNopLogger(), &mockProcessor{}, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), w))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), w))
+	})
+
+	for i := 0; i < 2; i++ {
+		w.AddressAdded(fmt.Sprintf("127.0.0.1:%d", i))
+	}
+	test.Poll(t, 250*time.Millisecond, 8, func() interface{} {
+		return getConcurrentProcessors(w)
+	})
+
+	// The max concurrent requests overridden by the runtime config is applied once reloaded.
+	maxConcurrent.Store(2)
+	require.NoError(t, w.reloadConcurrency(context.Background()))
+	test.Poll(t, 250*time.Millisecond, 2, func() interface{} {
+		return getConcurrentProcessors(w)
+	})
+
+	// The configured max concurrent requests is applied again once the override is removed.
+	maxConcurrent.Store(0)
+	require.NoError(t, w.reloadConcurrency(context.Background()))
+	test.Poll(t, 250*time.Millisecond, 8, func() interface{} {
+		return getConcurrentProcessors(w)
+	})
+}
+
 func getConcurrentProcessors(w *querierWorker) int {
 	result := 0
 	w.mu.Lock()
@@ -21,7 +21,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/user"
 
+	"github.com/grafana/mimir/pkg/querier/engine"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 func TestRuler(t *testing.T) {
@@ -513,6 +515,57 @@ rules:
 	}
 }
 
+func TestRuler_CreateRuleGroupWithExperimentalFunctions(t *testing.T) {
+	engine.RegisterExperimentalFunctions()
+
+	cfg := defaultRulerConfig(t)
+
+	r := newTestRuler(t, cfg, newMockRuleStore(make(map[string]rulespb.RuleGroupList)))
+	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
+
+	r.limits = &ruleLimits{experimentalFuncs: map[string][]string{"enabled": {engine.MadOverTimeFunction}}}
+
+	a := NewAPI(r, r.store, log.NewNopLogger())
+
+	const input = `
+name: test
+interval: 15s
+rules:
+- record: mad_rule
+  expr: sum(mad_over_time(up{}[5m]))
+`
+
+	tc := map[string]struct {
+		userID string
+		status int
+		output string
+	}{
+		"should accept the rules using the experimental functions enabled for the tenant": {
+			userID: "enabled",
+			status: 202,
+			output: "{\"status\":\"success\",\"data\":null,\"errorType\":\"\",\"error\":\"\"}",
+		},
+		"should reject the rules using the experimental functions not enabled for the tenant": {
+			userID: "not-enabled",
+			status: 400,
+			output: validation.NewExperimentalPromQLFunctionError(engine.MadOverTimeFunction).Error() + "\n",
+		},
+	}
+
+	for name, tt := range tc {
+		t.Run(name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Path("/prometheus/config/v1/rules/{namespace}").Methods("POST").HandlerFunc(a.CreateRuleGroup)
+			req := requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/namespace", strings.NewReader(input), tt.userID)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			require.Equal(t, tt.output, w.Body.String())
+		})
+	}
+}
+
 func requestFor(t *testing.T, method string, url string, body io.Reader, userID string) *http.Request {
 	t.Helper()
 
@@ -13,12 +13,15 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gogo/status"
+	"github.com/grafana/dskit/tenant"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/prometheus/prometheus/notifier"
 	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/rules"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/weaveworks/common/httpgrpc"
@@ -26,7 +29,9 @@ import (
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/querier"
+	"github.com/grafana/mimir/pkg/querier/engine"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	rulernotifier "github.com/grafana/mimir/pkg/ruler/notifier"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
@@ -120,6 +125,55 @@ type RulesLimits interface {
 	RulerTenantShardSize(userID string) int
 	RulerMaxRuleGroupsPerTenant(userID string) int
 	RulerMaxRulesPerRuleGroup(userID string) int
+	RulerAlertmanagerClientConfig(userID string) rulernotifier.AlertmanagerClientConfig
+	ExperimentalPromQLFunctions(userID string) []string
+}
+
+// ExperimentalFunctionsQueryFunc fails the queries using experimental PromQL functions which aren't enabled for all
+// the tenants of the query.
+func ExperimentalFunctionsQueryFunc(qf rules.QueryFunc, limits querier.ExperimentalFunctionsLimits) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		// Most of the queries don't use experimental functions, so they're not parsed twice.
+		if !engine.UsesExperimentalFunctions(qs) {
+			return qf(ctx, qs, t)
+		}
+
+		expr, err := parser.ParseExpr(qs)
+		if err != nil {
+			return nil, err
+		}
+		if functions := engine.UsedExperimentalFunctions(expr); len(functions) > 0 {
+			tenantIDs, err := tenant.TenantIDs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if err := querier.CheckExperimentalFunctions(limits, tenantIDs, functions); err != nil {
+				return nil, err
+			}
+		}
+		return qf(ctx, qs, t)
+	}
+}
+
+// ValidateExperimentalFunctions returns an error if the rules of the group use experimental PromQL functions which
+// aren't enabled for the tenant or the source tenants of the group.
+func ValidateExperimentalFunctions(limits querier.ExperimentalFunctionsLimits, userID string, rg rulefmt.RuleGroup) error {
+	tenantIDs := append([]string{userID}, rg.SourceTenants...)
+	for _, r := range rg.Rules {
+		if !engine.UsesExperimentalFunctions(r.Expr.Value) {
+			continue
+		}
+
+		// The rules with an invalid expression are rejected by the rule group validation.
+		expr, err := parser.ParseExpr(r.Expr.Value)
+		if err != nil {
+			continue
+		}
+		if err := querier.CheckExperimentalFunctions(limits, tenantIDs, engine.UsedExperimentalFunctions(expr)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func MetricsQueryFunc(qf rules.QueryFunc, queries, failedQueries prometheus.Counter) rules.QueryFunc {
@@ -227,6 +281,7 @@ func DefaultTenantManagerFactory(
 	embeddedQueryable storage.Queryable,
 	queryFunc rules.QueryFunc,
 	overrides RulesLimits,
+	evaluationSpreads *GroupEvaluationSpreads,
 	reg prometheus.Registerer,
 ) ManagerFactory {
 	totalWrites := promauto.With(reg).NewCounter(prometheus.CounterOpts{
@@ -263,12 +318,20 @@ func DefaultTenantManagerFactory(
 		wrappedQueryFunc = MetricsQueryFunc(queryFunc, totalQueries, failedQueries)
 		wrappedQueryFunc = RecordAndReportRuleQueryMetrics(wrappedQueryFunc, queryTime, logger)
 
+		groupEvaluationContextFunc := FederatedGroupContextFunc
+		if evaluationSpreads != nil {
+			wrappedQueryFunc = EvaluationSpreadQueryFunc(wrappedQueryFunc, evaluationSpreads)
+			groupEvaluationContextFunc = func(ctx context.Context, g *rules.Group) context.Context {
+				return EvaluationSpreadGroupContextFunc(FederatedGroupContextFunc(ctx, g), g)
+			}
+		}
+
 		return rules.NewManager(&rules.ManagerOptions{
 			Appendable:                 NewPusherAppendable(p, userID, overrides, totalWrites, failedWrites),
 			Queryable:                  embeddedQueryable,
 			QueryFunc:                  wrappedQueryFunc,
 			Context:                    user.InjectOrgID(ctx, userID),
-			GroupEvaluationContextFunc: FederatedGroupContextFunc,
+			GroupEvaluationContextFunc: groupEvaluationContextFunc,
 			ExternalURL:                cfg.ExternalURL.URL,
 			NotifyFunc:                 SendAlerts(notifier, cfg.ExternalURL.URL.String()),
 			Logger:                     log.With(logger, "user", userID),
@@ -27,9 +27,12 @@ import (
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/engine"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 type fakePusher struct {
@@ -237,6 +240,44 @@ func TestMetricsQueryFuncErrors(t *testing.T) {
 	}
 }
 
+func TestExperimentalFunctionsQueryFunc(t *testing.T) {
+	engine.RegisterExperimentalFunctions()
+
+	limits := ruleLimits{experimentalFuncs: map[string][]string{"enabled": {engine.MadOverTimeFunction}}}
+	queryFunc := ExperimentalFunctionsQueryFunc(func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		return promql.Vector{}, nil
+	}, limits)
+
+	for name, tc := range map[string]struct {
+		userID      string
+		query       string
+		expectedErr error
+	}{
+		"should run the queries without experimental functions": {
+			userID: "not-enabled",
+			query:  "sum(rate(up[5m]))",
+		},
+		"should run the queries with the experimental functions enabled for the tenant": {
+			userID: "enabled",
+			query:  "sum(mad_over_time(up[5m]))",
+		},
+		"should fail the queries with the experimental functions not enabled for the tenant": {
+			userID:      "not-enabled",
+			query:       "sum(mad_over_time(up[5m]))",
+			expectedErr: validation.NewExperimentalPromQLFunctionError(engine.MadOverTimeFunction),
+		},
+		"should run the queries only mentioning an experimental function in a label": {
+			userID: "not-enabled",
+			query:  `up{job="mad_over_time"}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := queryFunc(user.InjectOrgID(context.Background(), tc.userID), tc.query, time.Now())
+			require.Equal(t, tc.expectedErr, err)
+		})
+	}
+}
+
 func TestRecordAndReportRuleQueryMetrics(t *testing.T) {
 	queryTime := promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"})
 
@@ -322,7 +363,7 @@ func TestManagerFactory_CorrectQueryableUsed(t *testing.T) {
 			queryFunc := TenantFederationQueryFunc(regularQueryFunc, federatedQueryFunc)
 
 			// create and use manager factory
-			managerFactory := DefaultTenantManagerFactory(cfg, pusher, federatedQueryable, queryFunc, overrides, nil)
+			managerFactory := DefaultTenantManagerFactory(cfg, pusher, federatedQueryable, queryFunc, overrides, nil, nil)
 
 			manager := managerFactory(context.Background(), userID, notifierManager, logger, nil)
 
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+)
+
+const evaluationSpreadGroupState contextKey = 2
+
+// GroupEvaluationSpread is the adjustment of the evaluations of a rule group, used to spread over time the
+// evaluations of the rule groups which would otherwise run at the same time.
+type GroupEvaluationSpread struct {
+	// Phase delays each evaluation of the group by a fixed duration.
+	Phase time.Duration `json:"phase"`
+	// Jitter delays each evaluation of the group by a random duration, up to the jitter.
+	Jitter time.Duration `json:"jitter"`
+}
+
+// GroupEvaluationSpreads holds the evaluation spread of the rule groups evaluated by a ruler, keyed by
+// rules.GroupKey. It's safe for concurrent use.
+type GroupEvaluationSpreads struct {
+	mtx     sync.RWMutex
+	spreads map[string]GroupEvaluationSpread
+}
+
+func NewGroupEvaluationSpreads() *GroupEvaluationSpreads {
+	return &GroupEvaluationSpreads{spreads: map[string]GroupEvaluationSpread{}}
+}
+
+// Get returns the evaluation spread of the rule group, which is zero if not set.
+func (s *GroupEvaluationSpreads) Get(groupKey string) GroupEvaluationSpread {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.spreads[groupKey]
+}
+
+// Set sets the evaluation spread of the rule group. A zero spread restores the default evaluation time.
+func (s *GroupEvaluationSpreads) Set(groupKey string, spread GroupEvaluationSpread) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if spread == (GroupEvaluationSpread{}) {
+		delete(s.spreads, groupKey)
+		return
+	}
+	s.spreads[groupKey] = spread
+}
+
+// groupEvaluationState tracks the last evaluation of a rule group delayed by its evaluation spread.
+type groupEvaluationState struct {
+	groupKey string
+
+	mtx           sync.Mutex
+	lastDelayedTs time.Time
+}
+
+// EvaluationSpreadGroupContextFunc injects in the context the state used by EvaluationSpreadQueryFunc to delay
+// the evaluations of the rule group.
+func EvaluationSpreadGroupContextFunc(ctx context.Context, g *rules.Group) context.Context {
+	return context.WithValue(ctx, evaluationSpreadGroupState, &groupEvaluationState{groupKey: rules.GroupKey(g.File(), g.Name())})
+}
+
+// EvaluationSpreadQueryFunc delays the first query of each evaluation of a rule group by the evaluation spread of the
+// group. The rules of a group are evaluated sequentially, at the same timestamp, so the first query with a new
+// timestamp starts a new evaluation.
+func EvaluationSpreadQueryFunc(qf rules.QueryFunc, spreads *GroupEvaluationSpreads) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		if state, ok := ctx.Value(evaluationSpreadGroupState).(*groupEvaluationState); ok {
+			if err := state.wait(ctx, spreads, t); err != nil {
+				return nil, err
+			}
+		}
+		return qf(ctx, qs, t)
+	}
+}
+
+func (s *groupEvaluationState) wait(ctx context.Context, spreads *GroupEvaluationSpreads, t time.Time) error {
+	s.mtx.Lock()
+	newEvaluation := !t.Equal(s.lastDelayedTs)
+	s.lastDelayedTs = t
+	s.mtx.Unlock()
+
+	if !newEvaluation {
+		return nil
+	}
+
+	spread := spreads.Get(s.groupKey)
+	delay := spread.Phase
+	if spread.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(spread.Jitter)))
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupEvaluationSpreads(t *testing.T) {
+	spreads := NewGroupEvaluationSpreads()
+	assert.Equal(t, GroupEvaluationSpread{}, spreads.Get("file;group"))
+
+	spreads.Set("file;group", GroupEvaluationSpread{Phase: time.Second, Jitter: 2 * time.Second})
+	assert.Equal(t, GroupEvaluationSpread{Phase: time.Second, Jitter: 2 * time.Second}, spreads.Get("file;group"))
+
+	spreads.Set("file;group", GroupEvaluationSpread{})
+	assert.Equal(t, GroupEvaluationSpread{}, spreads.Get("file;group"))
+	assert.Empty(t, spreads.spreads)
+}
+
+func TestEvaluationSpreadQueryFunc(t *testing.T) {
+	const phase = 100 * time.Millisecond
+
+	group := rules.NewGroup(rules.GroupOptions{Name: "group", File: "file", Interval: time.Minute, Opts: &rules.ManagerOptions{}})
+	spreads := NewGroupEvaluationSpreads()
+	spreads.Set(rules.GroupKey("file", "group"), GroupEvaluationSpread{Phase: phase})
+
+	queries := 0
+	queryFunc := EvaluationSpreadQueryFunc(func(context.Context, string, time.Time) (promql.Vector, error) {
+		queries++
+		return nil, nil
+	}, spreads)
+
+	ctx := EvaluationSpreadGroupContextFunc(context.Background(), group)
+	evalTime := time.Now()
+
+	timeQuery := func(ctx context.Context, t time.Time) (time.Duration, error) {
+		start := time.Now()
+		_, err := queryFunc(ctx, "up", t)
+		return time.Since(start), err
+	}
+
+	t.Run("should delay the first query of an evaluation", func(t *testing.T) {
+		elapsed, err := timeQuery(ctx, evalTime)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, elapsed, phase)
+	})
+
+	t.Run("should not delay the next queries of the same evaluation", func(t *testing.T) {
+		elapsed, err := timeQuery(ctx, evalTime)
+		require.NoError(t, err)
+		assert.Less(t, elapsed, phase)
+	})
+
+	t.Run("should delay the first query of the next evaluation", func(t *testing.T) {
+		elapsed, err := timeQuery(ctx, evalTime.Add(time.Minute))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, elapsed, phase)
+	})
+
+	t.Run("should not delay the queries of the groups without evaluation spread", func(t *testing.T) {
+		otherCtx := EvaluationSpreadGroupContextFunc(context.Background(), rules.NewGroup(rules.GroupOptions{Name: "other", File: "file", Interval: time.Minute, Opts: &rules.ManagerOptions{}}))
+
+		elapsed, err := timeQuery(otherCtx, evalTime)
+		require.NoError(t, err)
+		assert.Less(t, elapsed, phase)
+	})
+
+	t.Run("should stop waiting when the context is canceled", func(t *testing.T) {
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := timeQuery(canceledCtx, evalTime.Add(2*time.Minute))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	assert.Equal(t, 4, queries)
+}
@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	_ "embed" // Used to embed html template
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	promRules "github.com/prometheus/prometheus/rules"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+//go:embed evaluation_timeline.gohtml
+var evaluationTimelinePageHTML string
+var evaluationTimelineTemplate = template.Must(template.New("webpage").Parse(evaluationTimelinePageHTML))
+
+type evaluationTimelinePageContents struct {
+	Now      time.Time                 `json:"now"`
+	Instance string                    `json:"instance,omitempty"`
+	Message  string                    `json:"message,omitempty"`
+	Groups   []evaluationTimelineGroup `json:"groups"`
+	Slots    []evaluationTimelineSlot  `json:"slots"`
+}
+
+type evaluationTimelineGroup struct {
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	Interval model.Duration `json:"interval"`
+	// Offset is the default offset of the evaluations of the group within the interval, computed from the group key.
+	Offset model.Duration `json:"offset"`
+	Phase  model.Duration `json:"phase"`
+	Jitter model.Duration `json:"jitter"`
+
+	NextEvaluation         time.Time      `json:"next_evaluation"`
+	LastEvaluation         time.Time      `json:"last_evaluation"`
+	LastEvaluationDuration model.Duration `json:"last_evaluation_duration"`
+
+	groupKey string
+}
+
+// evaluationTimelineSlot is the number of rule groups whose next evaluation starts in the same second.
+type evaluationTimelineSlot struct {
+	Time   time.Time `json:"time"`
+	Groups int       `json:"groups"`
+}
+
+// EvaluationTimelineHandler shows the planned evaluation timeline of the rule groups evaluated by this ruler, and
+// sets the evaluation spread of a rule group on POST requests. The evaluation spread of a rule group is kept in
+// memory by this ruler, until it restarts or the rule group is moved to another ruler.
+func (r *Ruler) EvaluationTimelineHandler(w http.ResponseWriter, req *http.Request) {
+	now := time.Now()
+
+	groups, err := r.evaluationTimelineGroups(now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	message := ""
+	if req.Method == http.MethodPost {
+		var status int
+		message, status = r.setGroupEvaluationSpread(req, groups)
+		if status != http.StatusOK {
+			http.Error(w, message, status)
+			return
+		}
+
+		// Refresh the timeline with the new evaluation spread.
+		if groups, err = r.evaluationTimelineGroups(now); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	instance := ""
+	if r.lifecycler != nil {
+		instance = r.lifecycler.GetInstanceID()
+	}
+
+	util.RenderHTTPResponse(w, evaluationTimelinePageContents{
+		Now:      now,
+		Instance: instance,
+		Message:  message,
+		Groups:   groups,
+		Slots:    evaluationTimelineSlots(groups),
+	}, evaluationTimelineTemplate, req)
+}
+
+// setGroupEvaluationSpread sets the evaluation spread of the rule group of the request, and returns the message and
+// status code to respond with.
+func (r *Ruler) setGroupEvaluationSpread(req *http.Request, groups []evaluationTimelineGroup) (string, int) {
+	if r.evaluationSpreads == nil {
+		return "the evaluation spread of the rule groups can't be set on this ruler", http.StatusNotImplemented
+	}
+
+	userID, namespace, name := req.FormValue("user"), req.FormValue("namespace"), req.FormValue("group")
+
+	var spread GroupEvaluationSpread
+	var err error
+	if spread.Phase, err = parseEvaluationSpreadParam(req, "phase"); err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	if spread.Jitter, err = parseEvaluationSpreadParam(req, "jitter"); err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+
+	for _, g := range groups {
+		if g.User != userID || g.Namespace != namespace || g.Name != name {
+			continue
+		}
+
+		// An evaluation spread greater than the interval would skip evaluations.
+		if spread.Phase+spread.Jitter >= time.Duration(g.Interval) {
+			return fmt.Sprintf("the sum of phase and jitter must be lower than the rule group interval (%s)", g.Interval), http.StatusBadRequest
+		}
+
+		r.evaluationSpreads.Set(g.groupKey, spread)
+		return fmt.Sprintf("Set the evaluation spread of the rule group %s/%s of tenant %s: phase %s, jitter %s.", namespace, name, userID, spread.Phase, spread.Jitter), http.StatusOK
+	}
+
+	return fmt.Sprintf("rule group %s/%s of tenant %s is not evaluated by this ruler", namespace, name, userID), http.StatusNotFound
+}
+
+// parseEvaluationSpreadParam parses the duration of the request form parameter, which is 0 if not set.
+func parseEvaluationSpreadParam(req *http.Request, param string) (time.Duration, error) {
+	value := req.FormValue(param)
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", param, value)
+	}
+	return d, nil
+}
+
+// evaluationTimelineGroups returns the rule groups evaluated by this ruler, sorted by next evaluation time.
+func (r *Ruler) evaluationTimelineGroups(now time.Time) ([]evaluationTimelineGroup, error) {
+	var result []evaluationTimelineGroup
+
+	for _, userID := range r.manager.GetUsers() {
+		prefix := filepath.Join(r.cfg.RulePath, userID) + "/"
+
+		for _, group := range r.manager.GetRules(userID) {
+			// The mapped filename is url path escaped encoded to make handling `/` characters easier
+			decodedNamespace, err := url.PathUnescape(strings.TrimPrefix(group.File(), prefix))
+			if err != nil {
+				return nil, errors.Wrap(err, "unable to decode rule filename")
+			}
+
+			result = append(result, newEvaluationTimelineGroup(userID, decodedNamespace, group, r.evaluationSpreads, now))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextEvaluation.Equal(result[j].NextEvaluation) {
+			return result[i].NextEvaluation.Before(result[j].NextEvaluation)
+		}
+		return result[i].groupKey < result[j].groupKey
+	})
+	return result, nil
+}
+
+func newEvaluationTimelineGroup(userID, namespace string, group *promRules.Group, spreads *GroupEvaluationSpreads, now time.Time) evaluationTimelineGroup {
+	var (
+		groupKey = promRules.GroupKey(group.File(), group.Name())
+		interval = group.Interval()
+		spread   GroupEvaluationSpread
+	)
+	if spreads != nil {
+		spread = spreads.Get(groupKey)
+	}
+
+	// The evaluations of a group start at the same offset of each interval.
+	lastSlot := group.EvalTimestamp(now.UnixNano())
+	offset := time.Duration(0)
+	if interval > 0 {
+		offset = time.Duration(lastSlot.UnixNano() % int64(interval))
+	}
+
+	return evaluationTimelineGroup{
+		User:                   userID,
+		Namespace:              namespace,
+		Name:                   group.Name(),
+		Interval:               model.Duration(interval),
+		Offset:                 model.Duration(offset),
+		Phase:                  model.Duration(spread.Phase),
+		Jitter:                 model.Duration(spread.Jitter),
+		NextEvaluation:         lastSlot.Add(interval).Add(spread.Phase),
+		LastEvaluation:         group.GetLastEvaluation(),
+		LastEvaluationDuration: model.Duration(group.GetEvaluationTime()),
+		groupKey:               groupKey,
+	}
+}
+
+// evaluationTimelineSlots returns the number of rule groups per second of their next evaluation, to spot the
+// evaluations which run at the same time.
+func evaluationTimelineSlots(groups []evaluationTimelineGroup) []evaluationTimelineSlot {
+	var slots []evaluationTimelineSlot
+
+	// The groups are sorted by next evaluation time.
+	for _, g := range groups {
+		slotTime := g.NextEvaluation.Truncate(time.Second)
+		if len(slots) > 0 && slots[len(slots)-1].Time.Equal(slotTime) {
+			slots[len(slots)-1].Groups++
+			continue
+		}
+		slots = append(slots, evaluationTimelineSlot{Time: slotTime, Groups: 1})
+	}
+	return slots
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuler_EvaluationTimelineHandler(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+
+	r := newTestRuler(t, cfg, newMockRuleStore(mockRules))
+	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
+
+	request := func(t *testing.T, method string, form url.Values) (int, evaluationTimelinePageContents) {
+		req := httptest.NewRequest(method, "/ruler/evaluation_timeline", strings.NewReader(form.Encode()))
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		r.EvaluationTimelineHandler(w, req)
+
+		var contents evaluationTimelinePageContents
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&contents))
+		}
+		return w.Code, contents
+	}
+
+	t.Run("should list the rule groups evaluated by the ruler", func(t *testing.T) {
+		status, contents := request(t, http.MethodGet, nil)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "localhost", contents.Instance)
+		require.Len(t, contents.Groups, 2)
+
+		users := []string{}
+		totalSlotGroups := 0
+		for _, g := range contents.Groups {
+			users = append(users, g.User)
+			assert.Equal(t, "namespace1", g.Namespace)
+			assert.Equal(t, "group1", g.Name)
+			assert.Equal(t, model.Duration(interval), g.Interval)
+			assert.Less(t, time.Duration(g.Offset), interval)
+			assert.True(t, g.NextEvaluation.After(contents.Now))
+			assert.False(t, g.NextEvaluation.After(contents.Now.Add(interval)))
+		}
+		for _, slot := range contents.Slots {
+			totalSlotGroups += slot.Groups
+		}
+		assert.ElementsMatch(t, []string{"user1", "user2"}, users)
+		assert.Equal(t, 2, totalSlotGroups)
+	})
+
+	t.Run("should set the evaluation spread of a rule group", func(t *testing.T) {
+		status, contents := request(t, http.MethodPost, url.Values{"user": {"user1"}, "namespace": {"namespace1"}, "group": {"group1"}, "phase": {"10s"}, "jitter": {"5s"}})
+		require.Equal(t, http.StatusOK, status)
+		assert.NotEmpty(t, contents.Message)
+
+		for _, g := range contents.Groups {
+			if g.User != "user1" {
+				assert.Zero(t, g.Phase)
+				continue
+			}
+
+			assert.Equal(t, model.Duration(10*time.Second), g.Phase)
+			assert.Equal(t, model.Duration(5*time.Second), g.Jitter)
+
+			// The next evaluation is delayed by the phase from the default offset within the interval,
+			// which is encoded with a millisecond precision.
+			offset := time.Duration(g.NextEvaluation.Add(-10*time.Second).UnixNano() % int64(interval))
+			assert.Equal(t, offset.Truncate(time.Millisecond), time.Duration(g.Offset))
+		}
+	})
+
+	t.Run("should restore the default evaluation time of a rule group", func(t *testing.T) {
+		status, contents := request(t, http.MethodPost, url.Values{"user": {"user1"}, "namespace": {"namespace1"}, "group": {"group1"}})
+		require.Equal(t, http.StatusOK, status)
+
+		for _, g := range contents.Groups {
+			assert.Zero(t, g.Phase)
+			assert.Zero(t, g.Jitter)
+		}
+	})
+
+	t.Run("should reject an evaluation spread not lower than the interval", func(t *testing.T) {
+		status, _ := request(t, http.MethodPost, url.Values{"user": {"user1"}, "namespace": {"namespace1"}, "group": {"group1"}, "phase": {"50s"}, "jitter": {"10s"}})
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("should reject an invalid evaluation spread", func(t *testing.T) {
+		status, _ := request(t, http.MethodPost, url.Values{"user": {"user1"}, "namespace": {"namespace1"}, "group": {"group1"}, "phase": {"-1s"}})
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("should reject a rule group not evaluated by the ruler", func(t *testing.T) {
+		status, _ := request(t, http.MethodPost, url.Values{"user": {"user3"}, "namespace": {"namespace1"}, "group": {"group1"}, "phase": {"10s"}})
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
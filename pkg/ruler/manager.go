@@ -9,6 +9,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
 	"sync"
 
 	"github.com/go-kit/log"
@@ -25,6 +27,7 @@ import (
 	"github.com/weaveworks/common/user"
 	"golang.org/x/net/context/ctxhttp"
 
+	rulernotifier "github.com/grafana/mimir/pkg/ruler/notifier"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 )
 
@@ -32,6 +35,8 @@ type DefaultMultiTenantManager struct {
 	cfg            Config
 	notifierCfg    *config.Config
 	managerFactory ManagerFactory
+	limits         RulesLimits
+	dnsResolver    cacheutil.AddressProvider
 
 	mapper *mapper
 
@@ -54,7 +59,7 @@ type DefaultMultiTenantManager struct {
 	logger                        log.Logger
 }
 
-func NewDefaultMultiTenantManager(cfg Config, managerFactory ManagerFactory, reg prometheus.Registerer, logger log.Logger, dnsResolver cacheutil.AddressProvider) (*DefaultMultiTenantManager, error) {
+func NewDefaultMultiTenantManager(cfg Config, managerFactory ManagerFactory, limits RulesLimits, reg prometheus.Registerer, logger log.Logger, dnsResolver cacheutil.AddressProvider) (*DefaultMultiTenantManager, error) {
 	ncfg, err := buildNotifierConfig(&cfg, dnsResolver)
 	if err != nil {
 		return nil, err
@@ -69,6 +74,8 @@ func NewDefaultMultiTenantManager(cfg Config, managerFactory ManagerFactory, reg
 		cfg:                cfg,
 		notifierCfg:        ncfg,
 		managerFactory:     managerFactory,
+		limits:             limits,
+		dnsResolver:        dnsResolver,
 		notifiers:          map[string]*rulerNotifier{},
 		mapper:             newMapper(cfg.RulePath, logger),
 		userManagers:       map[string]RulesManager{},
@@ -148,6 +155,11 @@ func (r *DefaultMultiTenantManager) syncRulesToManager(ctx context.Context, user
 		return
 	}
 
+	// The tenant's Alertmanager client config can be changed at runtime.
+	if err := r.syncNotifierConfig(user); err != nil {
+		level.Error(r.logger).Log("msg", "unable to update the notifier config", "user", user, "err", err)
+	}
+
 	// We need to update the manager only if it was just created or rules on disk have changed.
 	if !(created || update) {
 		level.Debug(r.logger).Log("msg", "rules have not changed, skipping rule manager update", "user", user)
@@ -228,6 +240,9 @@ func (r *DefaultMultiTenantManager) getOrCreateNotifier(userID string) (*notifie
 		return n.notifier, nil
 	}
 
+	amClientCfg := r.limits.RulerAlertmanagerClientConfig(userID)
+	ncfg := r.buildNotifierConfigForTenant(userID, amClientCfg)
+
 	reg := prometheus.WrapRegistererWith(prometheus.Labels{"user": userID}, r.registry)
 	reg = prometheus.WrapRegistererWithPrefix("cortex_", reg)
 	n = newRulerNotifier(&notifier.Options{
@@ -253,14 +268,55 @@ func (r *DefaultMultiTenantManager) getOrCreateNotifier(userID string) (*notifie
 	n.run()
 
 	// This should never fail, unless there's a programming mistake.
-	if err := n.applyConfig(r.notifierCfg); err != nil {
+	if err := n.applyConfig(ncfg); err != nil {
 		return nil, err
 	}
+	n.amClientCfg = amClientCfg
 
 	r.notifiers[userID] = n
 	return n.notifier, nil
 }
 
+// syncNotifierConfig re-applies the config of the tenant's notifier if the tenant's Alertmanager client config has changed.
+func (r *DefaultMultiTenantManager) syncNotifierConfig(userID string) error {
+	r.notifiersMtx.Lock()
+	defer r.notifiersMtx.Unlock()
+
+	n, ok := r.notifiers[userID]
+	if !ok {
+		return nil
+	}
+
+	amClientCfg := r.limits.RulerAlertmanagerClientConfig(userID)
+	if reflect.DeepEqual(amClientCfg, n.amClientCfg) {
+		return nil
+	}
+
+	ncfg := r.buildNotifierConfigForTenant(userID, amClientCfg)
+	if err := n.applyConfig(ncfg); err != nil {
+		return err
+	}
+
+	level.Info(r.logger).Log("msg", "updated the notifier config", "user", userID, "alertmanager_url", amClientCfg.AlertmanagerURL)
+	n.amClientCfg = amClientCfg
+	return nil
+}
+
+// buildNotifierConfigForTenant returns the notifier config sending the alerts to the tenant's Alertmanager, if configured,
+// or to the Alertmanager configured for all the tenants otherwise. An invalid tenant's Alertmanager client config
+// falls back to the Alertmanager configured for all the tenants, so that a misconfigured tenant doesn't stop its rules.
+func (r *DefaultMultiTenantManager) buildNotifierConfigForTenant(userID string, amClientCfg rulernotifier.AlertmanagerClientConfig) *config.Config {
+	if !amClientCfg.IsEnabled() {
+		return r.notifierCfg
+	}
+	ncfg, err := buildTenantNotifierConfig(&r.cfg, amClientCfg, r.dnsResolver)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "invalid Alertmanager client config of the tenant, sending the alerts to the Alertmanager configured for all the tenants", "user", userID, "alertmanager_url", amClientCfg.AlertmanagerURL, "err", err)
+		return r.notifierCfg
+	}
+	return ncfg
+}
+
 func (r *DefaultMultiTenantManager) GetRules(userID string) []*promRules.Group {
 	r.userManagerMtx.RLock()
 	mngr, exists := r.userManagers[userID]
@@ -272,6 +328,18 @@ func (r *DefaultMultiTenantManager) GetRules(userID string) []*promRules.Group {
 	return nil
 }
 
+func (r *DefaultMultiTenantManager) GetUsers() []string {
+	r.userManagerMtx.RLock()
+	defer r.userManagerMtx.RUnlock()
+
+	users := make([]string, 0, len(r.userManagers))
+	for userID := range r.userManagers {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+	return users
+}
+
 func (r *DefaultMultiTenantManager) Stop() {
 	r.notifiersMtx.Lock()
 	for _, n := range r.notifiers {
@@ -17,16 +17,18 @@ import (
 	"github.com/prometheus/prometheus/notifier"
 	"github.com/prometheus/prometheus/rules"
 	promRules "github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 
+	rulernotifier "github.com/grafana/mimir/pkg/ruler/notifier"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 )
 
 func TestSyncRuleGroups(t *testing.T) {
 	dir := t.TempDir()
 
-	m, err := NewDefaultMultiTenantManager(Config{RulePath: dir}, factory, nil, log.NewNopLogger(), nil)
+	m, err := NewDefaultMultiTenantManager(Config{RulePath: dir}, factory, ruleLimits{}, nil, log.NewNopLogger(), nil)
 	require.NoError(t, err)
 
 	const user = "testUser"
@@ -98,6 +100,83 @@ func TestSyncRuleGroups(t *testing.T) {
 	})
 }
 
+func TestSyncRuleGroups_ShouldApplyTenantAlertmanagerClientConfig(t *testing.T) {
+	const user = "testUser"
+
+	limits := ruleLimits{alertmanagerClient: map[string]rulernotifier.AlertmanagerClientConfig{}}
+	m, err := NewDefaultMultiTenantManager(Config{RulePath: t.TempDir()}, factory, limits, nil, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	t.Cleanup(m.Stop)
+
+	userRules := map[string]rulespb.RuleGroupList{
+		user: {
+			&rulespb.RuleGroupDesc{
+				Name:      "group1",
+				Namespace: "ns",
+				Interval:  1 * time.Minute,
+				User:      user,
+			},
+		},
+	}
+
+	getAlertmanagerClientConfig := func() rulernotifier.AlertmanagerClientConfig {
+		m.notifiersMtx.Lock()
+		defer m.notifiersMtx.Unlock()
+
+		require.Contains(t, m.notifiers, user)
+		return m.notifiers[user].amClientCfg
+	}
+
+	// The notifier is created with the global config.
+	m.SyncRuleGroups(context.Background(), userRules)
+	assert.Equal(t, rulernotifier.AlertmanagerClientConfig{}, getAlertmanagerClientConfig())
+
+	// The tenant's Alertmanager client config is applied once changed at runtime.
+	tenantCfg := rulernotifier.AlertmanagerClientConfig{
+		AlertmanagerURL: "https://alertmanager.tenant.example.com/alertmanager",
+		NotifierConfig: rulernotifier.Config{
+			OAuth2: rulernotifier.OAuth2Config{
+				ClientID: "ruler",
+				TokenURL: "https://auth.tenant.example.com/token",
+			},
+		},
+	}
+	limits.alertmanagerClient[user] = tenantCfg
+	m.SyncRuleGroups(context.Background(), userRules)
+	assert.Equal(t, tenantCfg, getAlertmanagerClientConfig())
+
+	// An invalid config falls back to the global config.
+	invalidCfg := rulernotifier.AlertmanagerClientConfig{AlertmanagerURL: "dnsserv+https://alertmanager.tenant.example.com"}
+	limits.alertmanagerClient[user] = invalidCfg
+	m.SyncRuleGroups(context.Background(), userRules)
+	assert.Equal(t, invalidCfg, getAlertmanagerClientConfig())
+	assert.Equal(t, m.notifierCfg, m.buildNotifierConfigForTenant(user, invalidCfg))
+}
+
+func TestSyncRuleGroups_ShouldStartTheRulesOfTenantsWithAnInvalidAlertmanagerClientConfig(t *testing.T) {
+	const user = "testUser"
+
+	limits := ruleLimits{alertmanagerClient: map[string]rulernotifier.AlertmanagerClientConfig{
+		user: {AlertmanagerURL: "alertmanager.tenant.example.com"},
+	}}
+	m, err := NewDefaultMultiTenantManager(Config{RulePath: t.TempDir()}, factory, limits, nil, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	t.Cleanup(m.Stop)
+
+	m.SyncRuleGroups(context.Background(), map[string]rulespb.RuleGroupList{
+		user: {
+			&rulespb.RuleGroupDesc{
+				Name:      "group1",
+				Namespace: "ns",
+				Interval:  1 * time.Minute,
+				User:      user,
+			},
+		},
+	})
+
+	assert.NotNil(t, getManager(m, user))
+}
+
 func getManager(m *DefaultMultiTenantManager, user string) RulesManager {
 	m.userManagerMtx.RLock()
 	defer m.userManagerMtx.RUnlock()
@@ -7,14 +7,12 @@ package ruler
 
 import (
 	"context"
-	"flag"
 	"net/url"
 	"strings"
 	"sync"
 
 	gklog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
-	"github.com/grafana/dskit/crypto/tls"
 	config_util "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
@@ -22,19 +20,9 @@ import (
 	"github.com/prometheus/prometheus/notifier"
 	"github.com/thanos-io/thanos/pkg/cacheutil"
 
-	"github.com/grafana/mimir/pkg/util"
+	rulernotifier "github.com/grafana/mimir/pkg/ruler/notifier"
 )
 
-type NotifierConfig struct {
-	TLS       tls.ClientConfig `yaml:",inline"`
-	BasicAuth util.BasicAuth   `yaml:",inline"`
-}
-
-func (cfg *NotifierConfig) RegisterFlags(f *flag.FlagSet) {
-	cfg.TLS.RegisterFlagsWithPrefix("ruler.alertmanager-client", f)
-	cfg.BasicAuth.RegisterFlagsWithPrefix("ruler.alertmanager-client.", f)
-}
-
 // rulerNotifier bundles a notifier.Manager together with an associated
 // Alertmanager service discovery manager and handles the lifecycle
 // of both actors.
@@ -44,6 +32,9 @@ type rulerNotifier struct {
 	sdManager *discovery.Manager
 	wg        sync.WaitGroup
 	logger    gklog.Logger
+
+	// The tenant's Alertmanager client config the notifier has been configured with.
+	amClientCfg rulernotifier.AlertmanagerClientConfig
 }
 
 func newRulerNotifier(o *notifier.Options, l gklog.Logger) *rulerNotifier {
@@ -101,7 +92,7 @@ func buildNotifierConfig(rulerConfig *Config, resolver cacheutil.AddressProvider
 	amConfigs := make([]*config.AlertmanagerConfig, 0, len(amURLs))
 
 	for _, rawURL := range amURLs {
-		isSD, qType, url, err := sanitizedAlertmanagerURL(rawURL)
+		isSD, qType, url, err := rulernotifier.SanitizedAlertmanagerURL(rawURL)
 		if err != nil {
 			return nil, err
 		}
@@ -125,6 +116,15 @@ func buildNotifierConfig(rulerConfig *Config, resolver cacheutil.AddressProvider
 	return promConfig, nil
 }
 
+// Builds the Prometheus config.Config to send the alerts of a tenant to its own Alertmanager, with the Alertmanager
+// client options of the tenant and the other notification options of the ruler.Config.
+func buildTenantNotifierConfig(rulerConfig *Config, amClientCfg rulernotifier.AlertmanagerClientConfig, resolver cacheutil.AddressProvider) (*config.Config, error) {
+	tenantCfg := *rulerConfig
+	tenantCfg.AlertmanagerURL = amClientCfg.AlertmanagerURL
+	tenantCfg.Notifier = amClientCfg.NotifierConfig
+	return buildNotifierConfig(&tenantCfg, resolver)
+}
+
 func amConfigWithSD(rulerConfig *Config, url *url.URL, sdConfig discovery.Config) *config.AlertmanagerConfig {
 	amConfig := &config.AlertmanagerConfig{
 		APIVersion:              config.AlertmanagerAPIVersionV2,
@@ -162,5 +162,14 @@ func amConfigWithSD(rulerConfig *Config, url *url.URL, sdConfig discovery.Config
 		}
 	}
 
+	if rulerConfig.Notifier.OAuth2.IsEnabled() {
+		amConfig.HTTPClientConfig.OAuth2 = &config_util.OAuth2{
+			ClientID:     rulerConfig.Notifier.OAuth2.ClientID,
+			ClientSecret: config_util.Secret(rulerConfig.Notifier.OAuth2.ClientSecret.String()),
+			TokenURL:     rulerConfig.Notifier.OAuth2.TokenURL,
+			Scopes:       rulerConfig.Notifier.OAuth2.Scopes,
+		}
+	}
+
 	return amConfig
 }
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package notifier
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/dskit/crypto/tls"
+	"github.com/grafana/dskit/flagext"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/discovery/dns"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+var (
+	errOAuth2ClientIDRequired    = errors.New("OAuth2 client ID is required when OAuth2 is enabled")
+	errOAuth2TokenURLRequired    = errors.New("OAuth2 token URL is required when OAuth2 is enabled")
+	errBasicAuthAndOAuth2Enabled = errors.New("at most one of basic authentication and OAuth2 can be enabled")
+)
+
+// Config configures the client used by the ruler to send alerts to the Alertmanager.
+type Config struct {
+	TLS       tls.ClientConfig `yaml:",inline"`
+	BasicAuth util.BasicAuth   `yaml:",inline"`
+	OAuth2    OAuth2Config     `yaml:"oauth2"`
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	cfg.TLS.RegisterFlagsWithPrefix("ruler.alertmanager-client", f)
+	cfg.BasicAuth.RegisterFlagsWithPrefix("ruler.alertmanager-client.", f)
+	cfg.OAuth2.RegisterFlagsWithPrefix("ruler.alertmanager-client.oauth2.", f)
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.BasicAuth.IsEnabled() && cfg.OAuth2.IsEnabled() {
+		return errBasicAuthAndOAuth2Enabled
+	}
+	return cfg.OAuth2.Validate()
+}
+
+// OAuth2Config configures the OAuth2 client credentials used to authenticate with the Alertmanager.
+type OAuth2Config struct {
+	ClientID     string                 `yaml:"client_id" category:"experimental"`
+	ClientSecret flagext.Secret         `yaml:"client_secret" category:"experimental"`
+	TokenURL     string                 `yaml:"token_url" category:"experimental"`
+	Scopes       flagext.StringSliceCSV `yaml:"scopes,omitempty" category:"experimental"`
+}
+
+func (cfg *OAuth2Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ClientID, prefix+"client-id", "", "OAuth2 client ID. Enables the use of OAuth2 client credentials to authenticate with the Alertmanager.")
+	f.Var(&cfg.ClientSecret, prefix+"client-secret", "OAuth2 client secret.")
+	f.StringVar(&cfg.TokenURL, prefix+"token-url", "", "Endpoint used to fetch the OAuth2 access token.")
+	f.Var(&cfg.Scopes, prefix+"scopes", "Optional comma-separated list of the scopes requested for the OAuth2 access token.")
+}
+
+// IsEnabled returns false if OAuth2 isn't enabled.
+func (cfg OAuth2Config) IsEnabled() bool {
+	return cfg.ClientID != "" || cfg.TokenURL != ""
+}
+
+func (cfg OAuth2Config) Validate() error {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+	if cfg.ClientID == "" {
+		return errOAuth2ClientIDRequired
+	}
+	if cfg.TokenURL == "" {
+		return errOAuth2TokenURLRequired
+	}
+	return nil
+}
+
+// AlertmanagerClientConfig is the per-tenant configuration of the Alertmanager client, used
+// to send the alerts of a tenant to its own Alertmanager.
+type AlertmanagerClientConfig struct {
+	AlertmanagerURL string `yaml:"alertmanager_url"`
+	NotifierConfig  Config `yaml:",inline"`
+}
+
+// IsEnabled returns false if the tenant's alerts are sent to the globally configured Alertmanager.
+func (cfg AlertmanagerClientConfig) IsEnabled() bool {
+	return cfg.AlertmanagerURL != ""
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (cfg *AlertmanagerClientConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AlertmanagerClientConfig
+	if err := unmarshal((*plain)(cfg)); err != nil {
+		return err
+	}
+	if cfg.IsEnabled() {
+		for _, rawURL := range strings.Split(cfg.AlertmanagerURL, ",") {
+			if _, _, _, err := SanitizedAlertmanagerURL(rawURL); err != nil {
+				return err
+			}
+		}
+	}
+	return cfg.NotifierConfig.Validate()
+}
+
+// SanitizedAlertmanagerURL parses an Alertmanager URL, optionally prefixed with a DNS service discovery
+// query type, and returns whether the service discovery is used to resolve it.
+func SanitizedAlertmanagerURL(amURL string) (isServiceDiscovery bool, qType dns.QType, parsedURL *url.URL, err error) {
+	rawQType, rawURL := dns.GetQTypeName(amURL)
+	qType = dns.QType(rawQType)
+
+	switch qType {
+	case "", dns.A, dns.SRV, dns.SRVNoA:
+	default:
+		err = errors.Errorf("invalid DNS service discovery prefix %q", qType)
+		return
+	}
+
+	parsedURL, err = url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	if parsedURL.String() == "" || parsedURL.Host == "" {
+		err = fmt.Errorf("improperly formatted alertmanager URL %q (maybe the scheme is missing?); see DNS Service Discovery docs", rawURL)
+		return
+	}
+
+	isServiceDiscovery = qType != ""
+
+	return
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package notifier
+
+import (
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg         Config
+		expectedErr error
+	}{
+		"no authentication": {
+			cfg: Config{},
+		},
+		"basic authentication": {
+			cfg: Config{BasicAuth: util.BasicAuth{Username: "user", Password: flagext.SecretWithValue("pass")}},
+		},
+		"OAuth2": {
+			cfg: Config{OAuth2: OAuth2Config{ClientID: "client", TokenURL: "https://auth.example.com/token"}},
+		},
+		"OAuth2 without client ID": {
+			cfg:         Config{OAuth2: OAuth2Config{TokenURL: "https://auth.example.com/token"}},
+			expectedErr: errOAuth2ClientIDRequired,
+		},
+		"OAuth2 without token URL": {
+			cfg:         Config{OAuth2: OAuth2Config{ClientID: "client"}},
+			expectedErr: errOAuth2TokenURLRequired,
+		},
+		"both basic authentication and OAuth2": {
+			cfg: Config{
+				BasicAuth: util.BasicAuth{Username: "user"},
+				OAuth2:    OAuth2Config{ClientID: "client", TokenURL: "https://auth.example.com/token"},
+			},
+			expectedErr: errBasicAuthAndOAuth2Enabled,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, tc.cfg.Validate())
+		})
+	}
+}
+
+func TestAlertmanagerClientConfig_UnmarshalYAML(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		var cfg AlertmanagerClientConfig
+		require.NoError(t, yaml.Unmarshal([]byte(`
+alertmanager_url: https://alertmanager.example.com/alertmanager
+tls_cert_path: /certs/client.crt
+tls_key_path: /certs/client.key
+oauth2:
+  client_id: client
+  client_secret: secret
+  token_url: https://auth.example.com/token
+  scopes: alerts:read,alerts:write
+`), &cfg))
+
+		assert.True(t, cfg.IsEnabled())
+		assert.Equal(t, "https://alertmanager.example.com/alertmanager", cfg.AlertmanagerURL)
+		assert.Equal(t, "/certs/client.crt", cfg.NotifierConfig.TLS.CertPath)
+		assert.Equal(t, "/certs/client.key", cfg.NotifierConfig.TLS.KeyPath)
+		assert.Equal(t, OAuth2Config{
+			ClientID:     "client",
+			ClientSecret: flagext.SecretWithValue("secret"),
+			TokenURL:     "https://auth.example.com/token",
+			Scopes:       []string{"alerts:read", "alerts:write"},
+		}, cfg.NotifierConfig.OAuth2)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		var cfg AlertmanagerClientConfig
+		assert.Equal(t, errOAuth2TokenURLRequired, yaml.Unmarshal([]byte(`
+alertmanager_url: https://alertmanager.example.com/alertmanager
+oauth2:
+  client_id: client
+`), &cfg))
+	})
+
+	t.Run("invalid Alertmanager URL", func(t *testing.T) {
+		for _, amURL := range []string{
+			"alertmanager.example.com",
+			"dnsserv+https://alertmanager.example.com",
+			"https://alertmanager-1.example.com,alertmanager-2.example.com",
+		} {
+			var cfg AlertmanagerClientConfig
+			assert.Error(t, yaml.Unmarshal([]byte("alertmanager_url: "+amURL), &cfg), amURL)
+		}
+	})
+}
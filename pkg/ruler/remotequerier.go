@@ -18,6 +18,7 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/grpcclient"
 	otgrpc "github.com/opentracing-contrib/go-grpc"
 	"github.com/opentracing/opentracing-go"
@@ -32,6 +33,7 @@ import (
 	"github.com/weaveworks/common/user"
 	"google.golang.org/grpc"
 
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 	"github.com/grafana/mimir/pkg/util/version"
 )
@@ -56,6 +58,10 @@ type QueryFrontendConfig struct {
 
 	// GRPCClientConfig contains gRPC specific config options.
 	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config"`
+
+	// QuerySourceKey is the key sent along with the rule evaluations, so that the query-frontend trusts they're
+	// issued by the ruler.
+	QuerySourceKey flagext.Secret `yaml:"query_source_key" category:"experimental"`
 }
 
 func (c *QueryFrontendConfig) RegisterFlags(f *flag.FlagSet) {
@@ -66,6 +72,8 @@ func (c *QueryFrontendConfig) RegisterFlags(f *flag.FlagSet) {
 			"to enable client side load balancing.")
 
 	c.GRPCClientConfig.RegisterFlagsWithPrefix("ruler.query-frontend.grpc-client-config", f)
+
+	f.Var(&c.QuerySourceKey, "ruler.query-frontend.query-source-key", "Key sent to the query-frontend along with the rule evaluations. It must match the -query-frontend.ruler-query-source-key of the query-frontend, which otherwise applies the limits of the user queries to the rule evaluations.")
 }
 
 // DialQueryFrontend creates and initializes a new httpgrpc.HTTPClient taking a QueryFrontendConfig configuration.
@@ -141,6 +149,7 @@ func (q *RemoteQuerier) Read(ctx context.Context, query *prompb.Query) (*prompb.
 			{Key: textproto.CanonicalMIMEHeaderKey("Content-Type"), Values: []string{"application/x-protobuf"}},
 			{Key: textproto.CanonicalMIMEHeaderKey("User-Agent"), Values: []string{userAgent}},
 			{Key: textproto.CanonicalMIMEHeaderKey("X-Prometheus-Remote-Read-Version"), Values: []string{"0.1.0"}},
+			{Key: textproto.CanonicalMIMEHeaderKey(queue.QuerySourceHeader), Values: []string{queue.QuerySourceRuler}},
 		},
 	}
 
@@ -208,6 +217,9 @@ func (q *RemoteQuerier) query(ctx context.Context, query string, ts time.Time, l
 			{Key: textproto.CanonicalMIMEHeaderKey("User-Agent"), Values: []string{userAgent}},
 			{Key: textproto.CanonicalMIMEHeaderKey("Content-Type"), Values: []string{mimeTypeFormPost}},
 			{Key: textproto.CanonicalMIMEHeaderKey("Content-Length"), Values: []string{strconv.Itoa(len(body))}},
+			// The rule evaluations must not be delayed by the other queries in the query-scheduler.
+			{Key: textproto.CanonicalMIMEHeaderKey(queue.PriorityHeader), Values: []string{queue.PriorityHigh.String()}},
+			{Key: textproto.CanonicalMIMEHeaderKey(queue.QuerySourceHeader), Values: []string{queue.QuerySourceRuler}},
 		},
 	}
 
@@ -307,6 +319,21 @@ func scalarToPromQLVector(sc *prommodel.Scalar) promql.Vector {
 	}}
 }
 
+// WithQuerySourceKeyMiddleware returns a Middleware attaching the query source key to the outgoing request, if the
+// key isn't empty.
+func WithQuerySourceKeyMiddleware(key string) Middleware {
+	return func(_ context.Context, req *httpgrpc.HTTPRequest) error {
+		if key == "" {
+			return nil
+		}
+		req.Headers = append(req.Headers, &httpgrpc.Header{
+			Key:    textproto.CanonicalMIMEHeaderKey(queue.QuerySourceKeyHeader),
+			Values: []string{key},
+		})
+		return nil
+	}
+}
+
 // WithOrgIDMiddleware attaches 'X-Scope-OrgID' header value to the outgoing request by inspecting the passed context.
 // In case the expression to evaluate corresponds to a federated rule, the ExtractTenantIDs function will take care
 // of normalizing and concatenating source tenants by separating them with a '|' character.
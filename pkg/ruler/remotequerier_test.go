@@ -18,6 +18,8 @@ import (
 	"github.com/weaveworks/common/httpgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 )
 
 type mockHTTPGRPCClient func(ctx context.Context, req *httpgrpc.HTTPRequest, _ ...grpc.CallOption) (*httpgrpc.HTTPResponse, error)
@@ -119,3 +121,12 @@ func TestRemoteQuerier_StatusErrorResponses(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, codes.Code(http.StatusUnprocessableEntity), st.Code())
 }
+
+func TestWithQuerySourceKeyMiddleware(t *testing.T) {
+	req := &httpgrpc.HTTPRequest{}
+	require.NoError(t, WithQuerySourceKeyMiddleware("")(context.Background(), req))
+	require.Empty(t, req.Headers)
+
+	require.NoError(t, WithQuerySourceKeyMiddleware("secret")(context.Background(), req))
+	require.Equal(t, []*httpgrpc.Header{{Key: queue.QuerySourceKeyHeader, Values: []string{"secret"}}}, req.Headers)
+}
@@ -16,6 +16,10 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/mimir/pkg/util/ringobserver"
 )
 
 const (
@@ -109,3 +113,19 @@ func (cfg *RingConfig) ToRingConfig() ring.Config {
 
 	return rc
 }
+
+// NewRingWatcher returns a service watching the rulers ring, to observe its membership changes
+// without running the component itself.
+func NewRingWatcher(cfg RingConfig, logger log.Logger, reg prometheus.Registerer) (*ringobserver.Watcher, error) {
+	kvClient, err := kv.NewClient(
+		cfg.KVStore,
+		ring.GetCodec(),
+		kv.RegistererWithKVName(prometheus.WrapRegistererWithPrefix("cortex_", reg), "ruler-ring-watcher"),
+		logger,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "create KV store client")
+	}
+
+	return ringobserver.NewWatcher("ruler", RulerRingKey, kvClient, logger), nil
+}
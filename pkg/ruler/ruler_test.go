@@ -45,6 +45,7 @@ import (
 	"github.com/grafana/dskit/tenant"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	rulernotifier "github.com/grafana/mimir/pkg/ruler/notifier"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 	"github.com/grafana/mimir/pkg/ruler/rulestore"
 	"github.com/grafana/mimir/pkg/ruler/rulestore/bucketclient"
@@ -80,6 +81,8 @@ type ruleLimits struct {
 	tenantShard          int
 	maxRulesPerRuleGroup int
 	maxRuleGroups        int
+	alertmanagerClient   map[string]rulernotifier.AlertmanagerClientConfig
+	experimentalFuncs    map[string][]string
 }
 
 func (r ruleLimits) EvaluationDelay(_ string) time.Duration {
@@ -98,6 +101,14 @@ func (r ruleLimits) RulerMaxRulesPerRuleGroup(_ string) int {
 	return r.maxRulesPerRuleGroup
 }
 
+func (r ruleLimits) RulerAlertmanagerClientConfig(userID string) rulernotifier.AlertmanagerClientConfig {
+	return r.alertmanagerClient[userID]
+}
+
+func (r ruleLimits) ExperimentalPromQLFunctions(userID string) []string {
+	return r.experimentalFuncs[userID]
+}
+
 func testSetup() (storage.QueryableFunc, promRules.QueryFunc, Pusher, log.Logger, RulesLimits) {
 	noopQueryable := storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
 		return storage.NoopQuerier(), nil
@@ -119,8 +130,8 @@ func testSetup() (storage.QueryableFunc, promRules.QueryFunc, Pusher, log.Logger
 func newManager(t *testing.T, cfg Config) *DefaultMultiTenantManager {
 	noopQueryable, noopQueryFunc, pusher, logger, overrides := testSetup()
 
-	mngFactory := DefaultTenantManagerFactory(cfg, pusher, noopQueryable, noopQueryFunc, overrides, nil)
-	manager, err := NewDefaultMultiTenantManager(cfg, mngFactory, prometheus.NewRegistry(), logger, nil)
+	mngFactory := DefaultTenantManagerFactory(cfg, pusher, noopQueryable, noopQueryFunc, overrides, nil, nil)
+	manager, err := NewDefaultMultiTenantManager(cfg, mngFactory, overrides, prometheus.NewRegistry(), logger, nil)
 	require.NoError(t, err)
 
 	return manager
@@ -168,11 +179,12 @@ func buildRuler(t *testing.T, cfg Config, storage rulestore.RuleStore, rulerAddr
 	noopQueryable, noopQueryFunc, pusher, logger, overrides := testSetup()
 
 	reg := prometheus.NewRegistry()
-	managerFactory := DefaultTenantManagerFactory(cfg, pusher, noopQueryable, noopQueryFunc, overrides, reg)
-	manager, err := NewDefaultMultiTenantManager(cfg, managerFactory, reg, log.NewNopLogger(), nil)
+	evaluationSpreads := NewGroupEvaluationSpreads()
+	managerFactory := DefaultTenantManagerFactory(cfg, pusher, noopQueryable, noopQueryFunc, overrides, evaluationSpreads, reg)
+	manager, err := NewDefaultMultiTenantManager(cfg, managerFactory, overrides, reg, log.NewNopLogger(), nil)
 	require.NoError(t, err)
 
-	ruler, err := newRuler(cfg, manager, reg, logger, storage, overrides, newMockClientsPool(cfg, logger, reg, rulerAddrMap))
+	ruler, err := newRuler(cfg, manager, reg, logger, storage, overrides, evaluationSpreads, newMockClientsPool(cfg, logger, reg, rulerAddrMap))
 	require.NoError(t, err)
 	return ruler
 }
@@ -916,7 +928,7 @@ func TestDeleteTenantRuleGroups(t *testing.T) {
 	require.Equal(t, 3, len(obj.Objects()))
 
 	cfg := defaultRulerConfig(t)
-	api, err := NewRuler(cfg, nil, nil, log.NewNopLogger(), rs, nil)
+	api, err := NewRuler(cfg, nil, nil, log.NewNopLogger(), rs, nil, nil)
 	require.NoError(t, err)
 
 	{
@@ -4,11 +4,9 @@ package ruler
 
 import (
 	"context"
-	"fmt"
 	"net/url"
 	"time"
 
-	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/discovery/refresh"
@@ -74,29 +72,3 @@ func staticTarget(url *url.URL) discovery.Config {
 		},
 	}
 }
-
-func sanitizedAlertmanagerURL(amURL string) (isServiceDiscovery bool, qType dns.QType, parsedURL *url.URL, err error) {
-	rawQType, rawURL := dns.GetQTypeName(amURL)
-	qType = dns.QType(rawQType)
-
-	switch qType {
-	case "", dns.A, dns.SRV, dns.SRVNoA:
-	default:
-		err = errors.Errorf("invalid DNS service discovery prefix %q", qType)
-		return
-	}
-
-	parsedURL, err = url.Parse(rawURL)
-	if err != nil {
-		return
-	}
-
-	if parsedURL.String() == "" || parsedURL.Host == "" {
-		err = fmt.Errorf("improperly formatted alertmanager URL %q (maybe the scheme is missing?); see DNS Service Discovery docs", rawURL)
-		return
-	}
-
-	isServiceDiscovery = qType != ""
-
-	return
-}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/weaveworks/common/httpgrpc"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	inflightQueryStateQueued  = "queued"
+	inflightQueryStateRunning = "running"
+
+	// The timeout to notify the frontend about a query canceled through the admin API.
+	canceledQueryNotificationTimeout = 10 * time.Second
+)
+
+type inflightQuery struct {
+	ID       uint64 `json:"id"`
+	Tenant   string `json:"tenant"`
+	Query    string `json:"query"`
+	Path     string `json:"path"`
+	Priority string `json:"priority"`
+	State    string `json:"state"`
+	Frontend string `json:"frontend"`
+	Querier  string `json:"querier,omitempty"`
+
+	// The time spent in the queue and running on the querier so far.
+	QueuedFor  string `json:"queued_for"`
+	RunningFor string `json:"running_for,omitempty"`
+}
+
+type inflightQueriesResponse struct {
+	Queries []inflightQuery `json:"queries"`
+}
+
+// InflightQueriesHandler lists the queries enqueued to the scheduler which haven't completed yet, either waiting in
+// the queue or running on a querier. The queries which have been running for the longest time are listed first.
+func (s *Scheduler) InflightQueriesHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.FormValue("tenant")
+	now := time.Now()
+
+	s.pendingRequestsMu.Lock()
+	queries := make([]inflightQuery, 0, len(s.pendingRequests))
+	enqueueTimes := make(map[uint64]time.Time, len(s.pendingRequests))
+	for _, req := range s.pendingRequests {
+		if tenantID != "" && req.userID != tenantID {
+			continue
+		}
+
+		path, query := requestPathAndQuery(req.request)
+		q := inflightQuery{
+			ID:        req.id,
+			Tenant:    req.userID,
+			Query:     query,
+			Path:      path,
+			Priority:  req.priority.String(),
+			State:     inflightQueryStateQueued,
+			Frontend:  req.frontendAddress,
+			QueuedFor: now.Sub(req.enqueueTime).String(),
+		}
+		if req.querierID != "" {
+			q.State = inflightQueryStateRunning
+			q.Querier = req.querierID
+			q.QueuedFor = req.dispatchTime.Sub(req.enqueueTime).String()
+			q.RunningFor = now.Sub(req.dispatchTime).String()
+		}
+
+		queries = append(queries, q)
+		enqueueTimes[req.id] = req.enqueueTime
+	}
+	s.pendingRequestsMu.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		return enqueueTimes[queries[i].ID].Before(enqueueTimes[queries[j].ID])
+	})
+
+	util.WriteJSONResponse(w, inflightQueriesResponse{Queries: queries})
+}
+
+// CancelQueryHandler cancels the inflight query with the given ID. A query waiting in the queue is never dispatched
+// to a querier, while the stream to the querier running the query is closed, which cancels the query on the querier
+// and the requests it's running to the ingesters and store-gateways. The frontend receives an error as response.
+func (s *Scheduler) CancelQueryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query ID: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	req := s.cancelRequestByID(id)
+	if req == nil {
+		http.Error(w, fmt.Sprintf("query %d not found, it may have already completed", id), http.StatusNotFound)
+		return
+	}
+
+	level.Info(s.log).Log("msg", "canceled inflight query", "id", id, "user", req.userID, "frontend", req.frontendAddress, "querier", req.querierID)
+
+	// The frontend would otherwise wait for the query response until it times out. The error is a query execution
+	// error, so that the frontend doesn't retry the query.
+	requestErr := apierror.New(apierror.TypeExec, "the query has been canceled by an operator")
+	resp, _ := apierror.HTTPResponseFromError(requestErr)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), canceledQueryNotificationTimeout)
+		defer cancel()
+		s.forwardResponseToFrontend(ctx, req, resp, requestErr)
+	}()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cancelRequestByID cancels the pending request with the given ID and removes it from the pending requests.
+// It returns the canceled request, or nil if there's no pending request with the given ID.
+func (s *Scheduler) cancelRequestByID(id uint64) *schedulerRequest {
+	s.pendingRequestsMu.Lock()
+	defer s.pendingRequestsMu.Unlock()
+
+	for key, req := range s.pendingRequests {
+		if req.id != id {
+			continue
+		}
+
+		req.ctxCancel()
+		delete(s.pendingRequests, key)
+		return req
+	}
+	return nil
+}
+
+// markRequestDispatched records that the request has been dispatched to the querier.
+func (s *Scheduler) markRequestDispatched(req *schedulerRequest, querierID string) {
+	s.pendingRequestsMu.Lock()
+	defer s.pendingRequestsMu.Unlock()
+
+	req.querierID = querierID
+	req.dispatchTime = time.Now()
+}
+
+// requestPathAndQuery returns the path of the request and its PromQL query, if any, either in the URL
+// or in the form-encoded body.
+func requestPathAndQuery(req *httpgrpc.HTTPRequest) (string, string) {
+	u, err := url.Parse(req.GetUrl())
+	if err != nil {
+		return req.GetUrl(), ""
+	}
+
+	query := u.Query().Get("query")
+	if query == "" && len(req.GetBody()) > 0 {
+		if values, err := url.ParseQuery(string(req.GetBody())); err == nil {
+			query = values.Get("query")
+		}
+	}
+	return u.Path, query
+}
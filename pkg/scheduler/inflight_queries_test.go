@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/mimir/pkg/frontend/v2/frontendv2pb"
+	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
+)
+
+func TestScheduler_InflightQueriesAndCancelQuery(t *testing.T) {
+	scheduler, frontendClient, querierClient := setupScheduler(t, nil)
+
+	fm := &frontendMock{resp: map[uint64]*httpgrpc.HTTPResponse{}}
+	frontendGrpcServer := grpc.NewServer()
+	frontendv2pb.RegisterFrontendForQuerierServer(frontendGrpcServer, fm)
+
+	l, err := net.Listen("tcp", "")
+	require.NoError(t, err)
+	go func() {
+		_ = frontendGrpcServer.Serve(l)
+	}()
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	frontendLoop := initFrontendLoop(t, frontendClient, l.Addr().String())
+	frontendToScheduler(t, frontendLoop, &schedulerpb.FrontendToScheduler{
+		Type:        schedulerpb.ENQUEUE,
+		QueryID:     1,
+		UserID:      "user-1",
+		HttpRequest: &httpgrpc.HTTPRequest{Method: "GET", Url: "/prometheus/api/v1/query?query=up&time=10"},
+	})
+	frontendToScheduler(t, frontendLoop, &schedulerpb.FrontendToScheduler{
+		Type:        schedulerpb.ENQUEUE,
+		QueryID:     2,
+		UserID:      "user-1",
+		HttpRequest: &httpgrpc.HTTPRequest{Method: "POST", Url: "/prometheus/api/v1/query_range", Body: []byte("query=sum%28up%29&start=0&end=10&step=1")},
+	})
+
+	// Dispatch the first query to a querier.
+	querierLoop, err := querierClient.QuerierLoop(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, querierLoop.Send(&schedulerpb.QuerierToScheduler{QuerierID: "querier-1"}))
+	_, err = querierLoop.Recv()
+	require.NoError(t, err)
+
+	listInflightQueries := func(tenantID string) []inflightQuery {
+		rec := httptest.NewRecorder()
+		scheduler.InflightQueriesHandler(rec, httptest.NewRequest(http.MethodGet, "/query-scheduler/inflight_queries?tenant="+tenantID, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		resp := inflightQueriesResponse{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Queries
+	}
+
+	cancelQuery := func(id string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/query-scheduler/cancel_query", strings.NewReader("id="+id))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		scheduler.CancelQueryHandler(rec, req)
+		return rec.Code
+	}
+
+	queries := listInflightQueries("")
+	require.Len(t, queries, 2)
+
+	assert.Equal(t, "user-1", queries[0].Tenant)
+	assert.Equal(t, "up", queries[0].Query)
+	assert.Equal(t, "/prometheus/api/v1/query", queries[0].Path)
+	assert.Equal(t, inflightQueryStateRunning, queries[0].State)
+	assert.Equal(t, "querier-1", queries[0].Querier)
+	assert.NotEmpty(t, queries[0].RunningFor)
+
+	assert.Equal(t, "sum(up)", queries[1].Query)
+	assert.Equal(t, "/prometheus/api/v1/query_range", queries[1].Path)
+	assert.Equal(t, inflightQueryStateQueued, queries[1].State)
+	assert.Empty(t, queries[1].Querier)
+	assert.Empty(t, queries[1].RunningFor)
+
+	assert.Empty(t, listInflightQueries("user-2"))
+
+	// Cancel the running query.
+	require.Equal(t, http.StatusNoContent, cancelQuery(strconv.FormatUint(queries[0].ID, 10)))
+
+	// The frontend receives an error which is not retried.
+	test.Poll(t, 2*time.Second, true, func() interface{} {
+		resp := fm.getRequest(1)
+		if resp == nil {
+			return false
+		}
+
+		require.Equal(t, int32(http.StatusUnprocessableEntity), resp.Code)
+		return true
+	})
+
+	// The stream to the querier is closed, which cancels the query on the querier.
+	test.Poll(t, 2*time.Second, true, func() interface{} {
+		return querierLoop.Send(&schedulerpb.QuerierToScheduler{}) != nil
+	})
+
+	queries = listInflightQueries("")
+	require.Len(t, queries, 1)
+	assert.Equal(t, "sum(up)", queries[0].Query)
+
+	// Cancel the queued query. A new querier doesn't receive it.
+	require.Equal(t, http.StatusNoContent, cancelQuery(strconv.FormatUint(queries[0].ID, 10)))
+	assert.Empty(t, listInflightQueries(""))
+
+	otherQuerierLoop, err := querierClient.QuerierLoop(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, otherQuerierLoop.Send(&schedulerpb.QuerierToScheduler{QuerierID: "querier-2"}))
+	verifyQuerierDoesntReceiveRequest(t, otherQuerierLoop, 500*time.Millisecond)
+
+	// The queries which don't exist anymore can't be canceled.
+	assert.Equal(t, http.StatusNotFound, cancelQuery(strconv.FormatUint(queries[0].ID, 10)))
+	assert.Equal(t, http.StatusBadRequest, cancelQuery("invalid"))
+}
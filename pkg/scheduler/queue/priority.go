@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queue
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// PriorityHeader is the HTTP header carrying the priority of a query request.
+const PriorityHeader = "X-Query-Priority"
+
+// Priority is the priority class of a request. The requests of the different priorities of a tenant are dequeued
+// in a weighted fashion, so that the requests of a priority are not starved by a flood of requests of another one.
+type Priority int
+
+const (
+	// PriorityHigh is the priority of the requests which must not be delayed, like the rule evaluations.
+	PriorityHigh Priority = iota
+	// PriorityNormal is the priority of the interactive requests. It's the default priority.
+	PriorityNormal
+	// PriorityLow is the priority of the batch requests, like the ones run through the API by scripts.
+	PriorityLow
+
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// ParsePriority returns the priority with the given name. It returns PriorityNormal if the name is unknown.
+func ParsePriority(name string) Priority {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "high":
+		return PriorityHigh
+	case "low":
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// PrioritizedRequest is a Request with a priority. The requests not implementing it have the normal priority.
+type PrioritizedRequest interface {
+	QueryPriority() Priority
+}
+
+func priorityOf(req Request) Priority {
+	if r, ok := req.(PrioritizedRequest); ok && r.QueryPriority() >= 0 && r.QueryPriority() < numPriorities {
+		return r.QueryPriority()
+	}
+	return PriorityNormal
+}
+
+// PriorityConfig configures how the requests of a priority are dequeued.
+type PriorityConfig struct {
+	Weight              int `yaml:"weight" category:"experimental"`
+	MaxInflightRequests int `yaml:"max_inflight_requests" category:"experimental"`
+}
+
+func (cfg *PriorityConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet, priority Priority, defaultWeight int) {
+	f.IntVar(&cfg.Weight, prefix+"weight", defaultWeight, fmt.Sprintf("The weight of the %s priority requests when dequeueing the requests of a tenant. Each priority with pending requests gets a share of the dequeued requests proportional to its weight.", priority))
+	f.IntVar(&cfg.MaxInflightRequests, prefix+"max-inflight-requests", 0, fmt.Sprintf("The maximum number of %s priority requests which can be dispatched to the queriers at the same time. Once reached, the pending %s priority requests wait in the queue until a dispatched one completes. 0 to disable the limit.", priority, priority))
+}
+
+// PrioritiesConfig configures how the requests of each priority are dequeued.
+type PrioritiesConfig struct {
+	High   PriorityConfig `yaml:"high"`
+	Normal PriorityConfig `yaml:"normal"`
+	Low    PriorityConfig `yaml:"low"`
+}
+
+func (cfg *PrioritiesConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	cfg.High.RegisterFlagsWithPrefix(prefix+"high.", f, PriorityHigh, 10)
+	cfg.Normal.RegisterFlagsWithPrefix(prefix+"normal.", f, PriorityNormal, 5)
+	cfg.Low.RegisterFlagsWithPrefix(prefix+"low.", f, PriorityLow, 1)
+}
+
+func (cfg *PrioritiesConfig) Validate() error {
+	for p, c := range cfg.byPriority() {
+		if c.Weight < 1 {
+			return fmt.Errorf("the weight of the %s priority requests must be greater than 0", Priority(p))
+		}
+		if c.MaxInflightRequests < 0 {
+			return fmt.Errorf("the max inflight %s priority requests can't be negative", Priority(p))
+		}
+	}
+	return nil
+}
+
+func (cfg *PrioritiesConfig) byPriority() [numPriorities]PriorityConfig {
+	return [numPriorities]PriorityConfig{
+		PriorityHigh:   cfg.High,
+		PriorityNormal: cfg.Normal,
+		PriorityLow:    cfg.Low,
+	}
+}
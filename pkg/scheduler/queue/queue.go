@@ -14,11 +14,20 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/atomic"
+
+	util_math "github.com/grafana/mimir/pkg/util/math"
 )
 
 const (
 	// How frequently to check for disconnected queriers that should be forgotten.
 	forgetCheckPeriod = 5 * time.Second
+
+	// Bounds of the time after which a rejected request is suggested to be retried.
+	minRetryAfter = time.Second
+	maxRetryAfter = time.Minute
+
+	// Weight of the last interval in the moving average of the interval between the dequeued requests.
+	dequeueIntervalSmoothing = 0.2
 )
 
 var (
@@ -30,6 +39,10 @@ var (
 // of RequestQueue.GetNextRequestForQuerier method.
 type UserIndex struct {
 	last int
+
+	// Number of requests the querier can still dequeue from the last user, according to the weight of the user,
+	// before moving on to the next user.
+	remaining int
 }
 
 // Modify index to start iteration on the same user, for which last queue was returned.
@@ -50,7 +63,7 @@ type Request interface{}
 
 // RequestQueue holds incoming requests in per-user queues. It also assigns each user specified number of queriers,
 // and when querier asks for next request to handle (using GetNextRequestForQuerier), it returns requests
-// in a fair fashion.
+// in a fair fashion. The requests of each user are dequeued according to their priority, see PrioritiesConfig.
 type RequestQueue struct {
 	services.Service
 
@@ -63,14 +76,20 @@ type RequestQueue struct {
 
 	queueLength       *prometheus.GaugeVec   // Per user and reason.
 	discardedRequests *prometheus.CounterVec // Per user.
+	dequeuedRequests  *prometheus.CounterVec // Per user.
 }
 
-func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, queueLength *prometheus.GaugeVec, discardedRequests *prometheus.CounterVec) *RequestQueue {
+// NewRequestQueue creates a new RequestQueue. RulerReservedQuerierWorkers is the number of connected querier workers
+// reserved to the requests issued by the ruler: the other requests are only dispatched as long as they leave this number
+// of workers available, see queues.canDispatchRequest. It requires ReleaseRequest to be called once each dequeued
+// request has been handled.
+func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, priorities PrioritiesConfig, rulerReservedQuerierWorkers int, queueLength *prometheus.GaugeVec, discardedRequests, dequeuedRequests *prometheus.CounterVec) *RequestQueue {
 	q := &RequestQueue{
-		queues:                  newUserQueues(maxOutstandingPerTenant, forgetDelay),
+		queues:                  newUserQueues(maxOutstandingPerTenant, forgetDelay, priorities, rulerReservedQuerierWorkers),
 		connectedQuerierWorkers: atomic.NewInt32(0),
 		queueLength:             queueLength,
 		discardedRequests:       discardedRequests,
+		dequeuedRequests:        dequeuedRequests,
 	}
 
 	q.cond = contextCond{Cond: sync.NewCond(&q.mtx)}
@@ -81,10 +100,16 @@ func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, que
 
 // EnqueueRequest puts the request into the queue. MaxQueries is user-specific value that specifies how many queriers can
 // this user use (zero or negative = all queriers). It is passed to each EnqueueRequest, because it can change
-// between calls.
+// between calls. Weight is the user-specific share of the querier capacity, relative to the weight of the other users
+// (zero or negative = 1): when the queriers are saturated, the requests of the users are dequeued proportionally to
+// their weight. It's passed to each EnqueueRequest too. MaxOutstanding is the user-specific max number of outstanding
+// requests in the user queue (zero or negative = the max outstanding requests per tenant of the queue), passed to each
+// EnqueueRequest for the same reason. MaxRulerOutstanding is the user-specific max number of outstanding requests issued
+// by the ruler in the user queue: when greater than 0, these requests get their own budget and don't count toward
+// MaxOutstanding.
 //
 // If request is successfully enqueued, successFn is called with the lock held, before any querier can receive the request.
-func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers int, successFn func()) error {
+func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers, weight, maxOutstanding, maxRulerOutstanding int, successFn func()) error {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
 
@@ -97,20 +122,25 @@ func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers in
 		// This can only happen if userID is "".
 		return errors.New("no queue found")
 	}
+	queue.weight = util_math.Max(1, weight)
+	queue.maxOutstanding = maxOutstanding
+	queue.maxRulerOutstanding = maxRulerOutstanding
+	if queue.lastDequeuedAt.IsZero() {
+		queue.lastDequeuedAt = time.Now()
+	}
 
-	select {
-	case queue <- req:
-		q.queueLength.WithLabelValues(userID).Inc()
-		q.cond.Broadcast()
-		// Call this function while holding a lock. This guarantees that no querier can fetch the request before function returns.
-		if successFn != nil {
-			successFn()
-		}
-		return nil
-	default:
+	if !q.queues.enqueueRequest(queue, req) {
 		q.discardedRequests.WithLabelValues(userID).Inc()
 		return ErrTooManyRequests
 	}
+
+	q.queueLength.WithLabelValues(userID).Inc()
+	q.cond.Broadcast()
+	// Call this function while holding a lock. This guarantees that no querier can fetch the request before function returns.
+	if successFn != nil {
+		successFn()
+	}
+	return nil
 }
 
 // GetNextRequestForQuerier find next user queue and takes the next request off of it. Will block if there are no requests.
@@ -137,35 +167,83 @@ FindQueue:
 		return nil, last, err
 	}
 
-	for {
+	// The querier dequeues up to as many requests in a row from a user as the weight of the user, before moving on
+	// to the next user, so it starts from the last user again if it has requests left in its turn. Each user is
+	// visited at most once otherwise, since the requests of a user may not be dispatchable because their priorities
+	// reached the max inflight requests.
+	prev, remaining := last.last, last.remaining
+	visits := q.queues.len()
+	if remaining > 0 {
+		last.last--
+		visits++
+	}
+	for ; visits > 0; visits-- {
 		queue, userID, idx := q.queues.getNextQueueForQuerier(last.last, querierID)
 		last.last = idx
 		if queue == nil {
 			break
 		}
 
+		// A new turn of the user starts, unless the querier keeps dequeuing the requests of the last user.
+		if idx != prev || remaining <= 0 {
+			remaining = queue.weight
+		}
+		prev = idx
+
 		// Pick next request from the queue.
-		for {
-			request := <-queue
-			if len(queue) == 0 {
-				q.queues.deleteQueue(userID)
-			}
+		request, ok := q.queues.dequeueRequest(queue)
+		if !ok {
+			remaining = 0
+			continue
+		}
+		last.remaining = remaining - 1
+		queue.trackDequeue(time.Now())
+		if queue.length == 0 {
+			q.queues.deleteQueue(userID)
+		}
 
-			q.queueLength.WithLabelValues(userID).Dec()
+		q.queueLength.WithLabelValues(userID).Dec()
+		q.dequeuedRequests.WithLabelValues(userID).Inc()
 
-			// Tell close() we've processed a request.
-			q.cond.Broadcast()
+		// Tell close() we've processed a request.
+		q.cond.Broadcast()
 
-			return request, last, nil
-		}
+		return request, last, nil
 	}
 
 	// There are no unexpired requests, so we can get back
 	// and wait for more requests.
+	last.remaining = 0
 	querierWait = true
 	goto FindQueue
 }
 
+// RetryAfter returns the suggested time after which a request of the user, rejected with ErrTooManyRequests, can be
+// retried. It's estimated from the depth of the user queue and the rate its requests are dequeued.
+func (q *RequestQueue) RetryAfter(userID string) time.Duration {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	uq := q.queues.userQueues[userID]
+	if uq == nil {
+		return minRetryAfter
+	}
+	return uq.retryAfter(time.Now())
+}
+
+// ReleaseRequest must be called once a request returned by GetNextRequestForQuerier has been handled, so that
+// another request of the same priority can be dispatched if the priority has a max number of inflight requests,
+// and another request not issued by the ruler if querier workers are reserved to the ruler.
+func (q *RequestQueue) ReleaseRequest(req Request) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.queues.releaseRequest(req)
+
+	// Notify the queriers waiting for a request that can be dispatched.
+	q.cond.Broadcast()
+}
+
 func (q *RequestQueue) forgetDisconnectedQueriers(_ context.Context) error {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/dskit/services"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,9 +29,10 @@ func BenchmarkGetNextRequest(b *testing.B) {
 	queues := make([]*RequestQueue, 0, b.N)
 
 	for n := 0; n < b.N; n++ {
-		queue := NewRequestQueue(maxOutstandingPerTenant, 0,
+		queue := NewRequestQueue(maxOutstandingPerTenant, 0, PrioritiesConfig{}, 0,
 			promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
 			promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+			promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
 		)
 		queues = append(queues, queue)
 
@@ -42,7 +44,7 @@ func BenchmarkGetNextRequest(b *testing.B) {
 			for j := 0; j < numTenants; j++ {
 				userID := strconv.Itoa(j)
 
-				err := queue.EnqueueRequest(userID, "request", 0, nil)
+				err := queue.EnqueueRequest(userID, "request", 0, 0, 0, 0, nil)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -85,9 +87,10 @@ func BenchmarkQueueRequest(b *testing.B) {
 	requests := make([]string, 0, numTenants)
 
 	for n := 0; n < b.N; n++ {
-		q := NewRequestQueue(maxOutstandingPerTenant, 0,
+		q := NewRequestQueue(maxOutstandingPerTenant, 0, PrioritiesConfig{}, 0,
 			promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
 			promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+			promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
 		)
 
 		for ix := 0; ix < queriers; ix++ {
@@ -106,7 +109,7 @@ func BenchmarkQueueRequest(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		for i := 0; i < maxOutstandingPerTenant; i++ {
 			for j := 0; j < numTenants; j++ {
-				err := queues[n].EnqueueRequest(users[j], requests[j], 0, nil)
+				err := queues[n].EnqueueRequest(users[j], requests[j], 0, 0, 0, 0, nil)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -118,8 +121,9 @@ func BenchmarkQueueRequest(b *testing.B) {
 func TestRequestQueue_GetNextRequestForQuerier_ShouldGetRequestAfterReshardingBecauseQuerierHasBeenForgotten(t *testing.T) {
 	const forgetDelay = 3 * time.Second
 
-	queue := NewRequestQueue(1, forgetDelay,
+	queue := NewRequestQueue(1, forgetDelay, PrioritiesConfig{}, 0,
 		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
 		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
 
 	// Start the queue service.
@@ -147,7 +151,7 @@ func TestRequestQueue_GetNextRequestForQuerier_ShouldGetRequestAfterReshardingBe
 
 	// Enqueue a request from an user which would be assigned to querier-1.
 	// NOTE: "user-1" hash falls in the querier-1 shard.
-	require.NoError(t, queue.EnqueueRequest("user-1", "request", 1, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request", 1, 0, 0, 0, nil))
 
 	startTime := time.Now()
 	querier2wg.Wait()
@@ -157,6 +161,152 @@ func TestRequestQueue_GetNextRequestForQuerier_ShouldGetRequestAfterReshardingBe
 	assert.GreaterOrEqual(t, waitTime.Milliseconds(), forgetDelay.Milliseconds())
 }
 
+type prioritizedRequest struct {
+	id       string
+	priority Priority
+}
+
+func (r prioritizedRequest) QueryPriority() Priority {
+	return r.priority
+}
+
+func TestRequestQueue_GetNextRequestForQuerier_ShouldDequeueTheRequestsByWeightedPriority(t *testing.T) {
+	cfg := PrioritiesConfig{
+		High:   PriorityConfig{Weight: 3},
+		Normal: PriorityConfig{Weight: 2},
+		Low:    PriorityConfig{Weight: 1},
+	}
+
+	queue := NewRequestQueue(100, 0, cfg, 0,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+	queue.RegisterQuerierConnection("querier-1")
+
+	// A flood of low priority requests is enqueued before the other ones.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, queue.EnqueueRequest("user-1", prioritizedRequest{id: fmt.Sprint("low-", i), priority: PriorityLow}, 0, 0, 0, 0, nil))
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.EnqueueRequest("user-1", prioritizedRequest{id: fmt.Sprint("normal-", i), priority: PriorityNormal}, 0, 0, 0, 0, nil))
+		require.NoError(t, queue.EnqueueRequest("user-1", prioritizedRequest{id: fmt.Sprint("high-", i), priority: PriorityHigh}, 0, 0, 0, 0, nil))
+	}
+	// The requests without a priority have the normal one.
+	require.NoError(t, queue.EnqueueRequest("user-1", "normal-3", 0, 0, 0, 0, nil))
+
+	var dequeued []string
+	for i := 0; i < 10; i++ {
+		req, _, err := queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+		require.NoError(t, err)
+		if r, ok := req.(prioritizedRequest); ok {
+			dequeued = append(dequeued, r.id)
+		} else {
+			dequeued = append(dequeued, req.(string))
+		}
+	}
+
+	// The requests of each priority are dequeued in FIFO order, in a share proportional to the priority weight.
+	assert.Equal(t, []string{"high-0", "normal-0", "high-1", "low-0", "normal-1", "high-2", "normal-2", "low-1", "normal-3", "low-2"}, dequeued)
+}
+
+func TestRequestQueue_GetNextRequestForQuerier_ShouldShareTheQuerierCapacityByTenantWeight(t *testing.T) {
+	dequeuedRequests := promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"})
+	queue := NewRequestQueue(100, 0, PrioritiesConfig{}, 0,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		dequeuedRequests)
+	queue.RegisterQuerierConnection("querier-1")
+
+	// The premium tenant has 3 times the weight of the other ones, while the tenant without weight has the minimum one.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, queue.EnqueueRequest("premium", fmt.Sprint("premium-", i), 0, 3, 0, 0, nil))
+		require.NoError(t, queue.EnqueueRequest("standard", fmt.Sprint("standard-", i), 0, 1, 0, 0, nil))
+		require.NoError(t, queue.EnqueueRequest("unset", fmt.Sprint("unset-", i), 0, 0, 0, 0, nil))
+	}
+
+	var dequeued []string
+	last := FirstUser()
+	for i := 0; i < 10; i++ {
+		req, idx, err := queue.GetNextRequestForQuerier(context.Background(), last, "querier-1")
+		require.NoError(t, err)
+		dequeued = append(dequeued, req.(string))
+		last = idx
+	}
+
+	// The requests of the premium tenant are dequeued 3 in a row, while the other tenants still get their turn.
+	assert.Equal(t, []string{
+		"premium-0", "premium-1", "premium-2", "standard-0", "unset-0",
+		"premium-3", "premium-4", "premium-5", "standard-1", "unset-1",
+	}, dequeued)
+
+	assert.Equal(t, float64(6), testutil.ToFloat64(dequeuedRequests.WithLabelValues("premium")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(dequeuedRequests.WithLabelValues("standard")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(dequeuedRequests.WithLabelValues("unset")))
+
+	// Reusing the last user starts a new turn of the user.
+	req, _, err := queue.GetNextRequestForQuerier(context.Background(), last.ReuseLastUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, "unset-2", req)
+}
+
+func TestRequestQueue_GetNextRequestForQuerier_ShouldNotDispatchMoreThanTheMaxInflightRequestsOfAPriority(t *testing.T) {
+	cfg := PrioritiesConfig{
+		High:   PriorityConfig{Weight: 10},
+		Normal: PriorityConfig{Weight: 5},
+		Low:    PriorityConfig{Weight: 1, MaxInflightRequests: 1},
+	}
+
+	queue := NewRequestQueue(100, 0, cfg, 0,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+	queue.RegisterQuerierConnection("querier-1")
+
+	lowReq1 := prioritizedRequest{id: "low-1", priority: PriorityLow}
+	lowReq2 := prioritizedRequest{id: "low-2", priority: PriorityLow}
+	highReq := prioritizedRequest{id: "high", priority: PriorityHigh}
+	require.NoError(t, queue.EnqueueRequest("user-1", lowReq1, 0, 0, 0, 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", lowReq2, 0, 0, 0, 0, nil))
+
+	req, _, err := queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, lowReq1, req)
+
+	// The second low priority request isn't dispatched while the first one is inflight.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err = queue.GetNextRequestForQuerier(ctx, FirstUser(), "querier-1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The requests of the other priorities are still dispatched.
+	require.NoError(t, queue.EnqueueRequest("user-1", highReq, 0, 0, 0, 0, nil))
+	req, _, err = queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, highReq, req)
+
+	// The querier waiting for a request gets the second low priority request once the first one completes.
+	dequeued := make(chan Request, 1)
+	go func() {
+		req, _, err := queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+		require.NoError(t, err)
+		dequeued <- req
+	}()
+
+	select {
+	case <-dequeued:
+		t.Fatal("the second low priority request has been dispatched while the first one is inflight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	queue.ReleaseRequest(lowReq1)
+	select {
+	case req := <-dequeued:
+		assert.Equal(t, lowReq2, req)
+	case <-time.After(time.Second):
+		t.Fatal("the second low priority request has not been dispatched after the first one completed")
+	}
+}
+
 func TestContextCond(t *testing.T) {
 	t.Run("wait until broadcast", func(t *testing.T) {
 		t.Parallel()
@@ -307,3 +457,147 @@ func assertChanNotReceived(t *testing.T, c chan struct{}, wait time.Duration, ms
 		// OK!
 	}
 }
+
+func TestRequestQueue_RetryAfter(t *testing.T) {
+	queue := NewRequestQueue(2, 0, PrioritiesConfig{}, 0,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+
+	// The retry after is the lowest one for the users without a queue.
+	assert.Equal(t, minRetryAfter, queue.RetryAfter("user-1"))
+
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-1", 0, 0, 0, 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-2", 0, 0, 0, 0, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", "request-3", 0, 0, 0, 0, nil))
+
+	retryAfter := queue.RetryAfter("user-1")
+	assert.GreaterOrEqual(t, retryAfter, minRetryAfter)
+	assert.LessOrEqual(t, retryAfter, maxRetryAfter)
+}
+
+func TestRequestQueue_EnqueueRequest_ShouldApplyTheMaxOutstandingRequestsOfTheUser(t *testing.T) {
+	queue := NewRequestQueue(3, 0, PrioritiesConfig{}, 0,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+
+	// The user-specific limit overrides the limit of the queue.
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-1", 0, 0, 1, 0, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", "request-2", 0, 0, 1, 0, nil))
+
+	// The limit can change between calls.
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-2", 0, 0, 2, 0, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", "request-3", 0, 0, 2, 0, nil))
+
+	// The limit of the queue applies when the user-specific one isn't set.
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-3", 0, 0, 0, 0, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", "request-4", 0, 0, 0, 0, nil))
+}
+
+type rulerRequest string
+
+func (r rulerRequest) FromRuler() bool {
+	return true
+}
+
+func TestRequestQueue_EnqueueRequest_ShouldApplyTheRulerMaxOutstandingRequestsOfTheUser(t *testing.T) {
+	queue := NewRequestQueue(2, 0, PrioritiesConfig{}, 0,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+
+	// The requests issued by the ruler count toward the limit of the queue when the ruler limit isn't set.
+	require.NoError(t, queue.EnqueueRequest("user-1", rulerRequest("ruler-1"), 0, 0, 0, 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-1", 0, 0, 0, 0, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", "request-2", 0, 0, 0, 0, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", rulerRequest("ruler-2"), 0, 0, 0, 0, nil))
+
+	// When set, the requests issued by the ruler get their own budget.
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-2", 0, 0, 0, 2, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", rulerRequest("ruler-2"), 0, 0, 0, 2, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", rulerRequest("ruler-3"), 0, 0, 0, 2, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", "request-3", 0, 0, 0, 2, nil))
+
+	// The dequeued requests issued by the ruler free their budget.
+	queue.RegisterQuerierConnection("querier-1")
+	ctx := context.Background()
+	lastUserIndex := FirstUser()
+	for i := 0; i < 4; i++ {
+		var err error
+		_, lastUserIndex, err = queue.GetNextRequestForQuerier(ctx, lastUserIndex, "querier-1")
+		require.NoError(t, err)
+	}
+	require.NoError(t, queue.EnqueueRequest("user-1", rulerRequest("ruler-3"), 0, 0, 0, 2, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", rulerRequest("ruler-4"), 0, 0, 0, 2, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-3", 0, 0, 0, 2, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-4", 0, 0, 0, 2, nil))
+	require.Equal(t, ErrTooManyRequests, queue.EnqueueRequest("user-1", rulerRequest("ruler-5"), 0, 0, 0, 2, nil))
+}
+
+func TestRequestQueue_GetNextRequestForQuerier_ShouldReserveQuerierWorkersToTheRuler(t *testing.T) {
+	queue := NewRequestQueue(100, 0, PrioritiesConfig{}, 1,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+
+	// 2 querier workers are connected, 1 of which is reserved to the ruler.
+	queue.RegisterQuerierConnection("querier-1")
+	queue.RegisterQuerierConnection("querier-1")
+
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-1", 0, 0, 0, 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-2", 0, 0, 0, 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", rulerRequest("ruler-1"), 0, 0, 0, 0, nil))
+
+	req, _, err := queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, "request-1", req)
+
+	// The request issued by the ruler skips the queued request, which would use the reserved querier worker.
+	req, _, err = queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, rulerRequest("ruler-1"), req)
+
+	// The other request isn't dispatched while the first one is inflight, even when the ruler's is done.
+	queue.ReleaseRequest(rulerRequest("ruler-1"))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err = queue.GetNextRequestForQuerier(ctx, FirstUser(), "querier-1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The querier waiting for a request gets the other request once the first one completes.
+	dequeued := make(chan Request, 1)
+	go func() {
+		req, _, err := queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+		require.NoError(t, err)
+		dequeued <- req
+	}()
+
+	queue.ReleaseRequest("request-1")
+	select {
+	case req := <-dequeued:
+		assert.Equal(t, "request-2", req)
+	case <-time.After(time.Second):
+		t.Fatal("the other request has not been dispatched after the first one completed")
+	}
+}
+
+func TestRequestQueue_GetNextRequestForQuerier_ShouldDispatchOneRequestWhenAllTheQuerierWorkersAreReservedToTheRuler(t *testing.T) {
+	queue := NewRequestQueue(100, 0, PrioritiesConfig{}, 2,
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+	queue.RegisterQuerierConnection("querier-1")
+
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-1", 0, 0, 0, 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "request-2", 0, 0, 0, 0, nil))
+
+	req, _, err := queue.GetNextRequestForQuerier(context.Background(), FirstUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, "request-1", req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err = queue.GetNextRequestForQuerier(ctx, FirstUser(), "querier-1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
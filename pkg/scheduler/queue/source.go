@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package queue
+
+import (
+	"net/textproto"
+
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+const (
+	// QuerySourceHeader is the HTTP header carrying the component which issued a query request.
+	QuerySourceHeader = "X-Mimir-Query-Source"
+
+	// QuerySourceRuler is the value of the QuerySourceHeader of the queries run by the ruler to evaluate the rules.
+	QuerySourceRuler = "ruler"
+
+	// QuerySourceKeyHeader is the HTTP header carrying the key shared by the ruler and the query-frontend, which
+	// the query-frontend requires to trust the QuerySourceHeader.
+	QuerySourceKeyHeader = "X-Mimir-Query-Source-Key"
+)
+
+// RulerRequest is a Request which may have been issued by the ruler. The requests not implementing it are not
+// considered to be issued by the ruler.
+type RulerRequest interface {
+	FromRuler() bool
+}
+
+func isFromRuler(req Request) bool {
+	r, ok := req.(RulerRequest)
+	return ok && r.FromRuler()
+}
+
+// IsRulerHTTPRequest returns whether the QuerySourceHeader of the request is set by the ruler.
+func IsRulerHTTPRequest(req *httpgrpc.HTTPRequest) bool {
+	for _, h := range req.GetHeaders() {
+		if textproto.CanonicalMIMEHeaderKey(h.Key) == QuerySourceHeader && len(h.Values) > 0 {
+			return h.Values[0] == QuerySourceRuler
+		}
+	}
+	return false
+}
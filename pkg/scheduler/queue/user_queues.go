@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/grafana/mimir/pkg/util"
+	util_math "github.com/grafana/mimir/pkg/util/math"
 )
 
 // querier holds information about a querier registered in the queue.
@@ -37,6 +38,16 @@ type queues struct {
 
 	maxUserQueueSize int
 
+	// Dequeueing config of each priority, and number of dispatched requests of the priorities with a limit.
+	priorities [numPriorities]PriorityConfig
+	inflight   [numPriorities]int
+
+	// Number of querier workers reserved to the requests issued by the ruler, the connected querier workers, and
+	// the dispatched requests not issued by the ruler, only tracked if some querier workers are reserved.
+	rulerReservedWorkers int
+	connectedWorkers     int
+	inflightNonRuler     int
+
 	// How long to wait before removing a querier which has got disconnected
 	// but hasn't notified about a graceful shutdown.
 	forgetDelay time.Duration
@@ -49,7 +60,25 @@ type queues struct {
 }
 
 type userQueue struct {
-	ch chan Request
+	// Pending requests of each priority, in FIFO order, and their total number.
+	requests [numPriorities][]Request
+	length   int
+
+	// Number of the pending requests issued by the ruler.
+	rulerLength int
+
+	// Current weights of the smooth weighted round-robin choosing the priority of the next dequeued request.
+	currentWeights [numPriorities]int
+
+	// Max number of requests dequeued in a row by a querier before moving on to the next user.
+	weight int
+
+	// Max number of pending requests overriding the max user queue size, if greater than 0.
+	maxOutstanding int
+
+	// Max number of pending requests issued by the ruler, if greater than 0. When set, the requests issued by
+	// the ruler are limited by it instead of the max outstanding requests, and don't count toward the latter.
+	maxRulerOutstanding int
 
 	// If not nil, only these queriers can handle user requests. If nil, all queriers can.
 	// We set this to nil if number of available queriers <= maxQueriers.
@@ -62,16 +91,24 @@ type userQueue struct {
 
 	// Points back to 'users' field in queues. Enables quick cleanup.
 	index int
+
+	// When the last request has been dequeued (or when the queue has been created, if no request has been dequeued
+	// yet), and the moving average of the interval between the dequeued requests. They're used to estimate how long
+	// the pending requests take to be dequeued.
+	lastDequeuedAt  time.Time
+	dequeueInterval time.Duration
 }
 
-func newUserQueues(maxUserQueueSize int, forgetDelay time.Duration) *queues {
+func newUserQueues(maxUserQueueSize int, forgetDelay time.Duration, priorities PrioritiesConfig, rulerReservedWorkers int) *queues {
 	return &queues{
-		userQueues:       map[string]*userQueue{},
-		users:            nil,
-		maxUserQueueSize: maxUserQueueSize,
-		forgetDelay:      forgetDelay,
-		queriers:         map[string]*querier{},
-		sortedQueriers:   nil,
+		userQueues:           map[string]*userQueue{},
+		users:                nil,
+		maxUserQueueSize:     maxUserQueueSize,
+		priorities:           priorities.byPriority(),
+		rulerReservedWorkers: rulerReservedWorkers,
+		forgetDelay:          forgetDelay,
+		queriers:             map[string]*querier{},
+		sortedQueriers:       nil,
 	}
 }
 
@@ -98,7 +135,7 @@ func (q *queues) deleteQueue(userID string) {
 // MaxQueriers is used to compute which queriers should handle requests for this user.
 // If maxQueriers is <= 0, all queriers can handle this user's requests.
 // If maxQueriers has changed since the last call, queriers for this are recomputed.
-func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
+func (q *queues) getOrAddQueue(userID string, maxQueriers int) *userQueue {
 	// Empty user is not allowed, as that would break our users list ("" is used for free spot).
 	if userID == "" {
 		return nil
@@ -112,7 +149,6 @@ func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
 
 	if uq == nil {
 		uq = &userQueue{
-			ch:    make(chan Request, q.maxUserQueueSize),
 			seed:  util.ShuffleShardSeed(userID, ""),
 			index: -1,
 		}
@@ -139,13 +175,13 @@ func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
 		uq.queriers = shuffleQueriersForUser(uq.seed, maxQueriers, q.sortedQueriers, nil)
 	}
 
-	return uq.ch
+	return uq
 }
 
 // Finds next queue for the querier. To support fair scheduling between users, client is expected
 // to pass last user index returned by this function as argument. Is there was no previous
 // last user index, use -1.
-func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (chan Request, string, int) {
+func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (*userQueue, string, int) {
 	uid := lastUserIndex
 
 	// Ensure the querier is not shutting down. If the querier is shutting down, we shouldn't forward
@@ -177,12 +213,167 @@ func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (ch
 			}
 		}
 
-		return q.ch, u, uid
+		return q, u, uid
 	}
 	return nil, "", uid
 }
 
+// enqueueRequest adds the request to the user queue. It returns false if the user queue is full.
+func (q *queues) enqueueRequest(uq *userQueue, req Request) bool {
+	fromRuler := isFromRuler(req)
+
+	if fromRuler && uq.maxRulerOutstanding > 0 {
+		if uq.rulerLength >= uq.maxRulerOutstanding {
+			return false
+		}
+	} else {
+		maxOutstanding := q.maxUserQueueSize
+		if uq.maxOutstanding > 0 {
+			maxOutstanding = uq.maxOutstanding
+		}
+
+		length := uq.length
+		if uq.maxRulerOutstanding > 0 {
+			length -= uq.rulerLength
+		}
+		if length >= maxOutstanding {
+			return false
+		}
+	}
+
+	p := priorityOf(req)
+	uq.requests[p] = append(uq.requests[p], req)
+	uq.length++
+	if fromRuler {
+		uq.rulerLength++
+	}
+	return true
+}
+
+// dequeueRequest takes the next request off the user queue. The priority of the request is chosen with a smooth
+// weighted round-robin among the priorities with pending requests which haven't reached their max inflight requests,
+// and the request is the first one of the priority which can be dispatched. It returns false if there's no such
+// priority.
+func (q *queues) dequeueRequest(uq *userQueue) (Request, bool) {
+	next, nextIdx, totalWeight := Priority(-1), -1, 0
+	for p := Priority(0); p < numPriorities; p++ {
+		if len(uq.requests[p]) == 0 || !q.canDispatch(p) {
+			continue
+		}
+		idx := q.nextDispatchableRequest(uq.requests[p])
+		if idx < 0 {
+			continue
+		}
+
+		weight := util_math.Max(1, q.priorities[p].Weight)
+		uq.currentWeights[p] += weight
+		totalWeight += weight
+		if next < 0 || uq.currentWeights[p] > uq.currentWeights[next] {
+			next, nextIdx = p, idx
+		}
+	}
+	if next < 0 {
+		return nil, false
+	}
+	uq.currentWeights[next] -= totalWeight
+
+	requests := uq.requests[next]
+	req := requests[nextIdx]
+	if nextIdx == 0 {
+		requests[0] = nil
+		uq.requests[next] = requests[1:]
+	} else {
+		copy(requests[nextIdx:], requests[nextIdx+1:])
+		requests[len(requests)-1] = nil
+		uq.requests[next] = requests[:len(requests)-1]
+	}
+	uq.length--
+	fromRuler := isFromRuler(req)
+	if fromRuler {
+		uq.rulerLength--
+	}
+	if q.rulerReservedWorkers > 0 && !fromRuler {
+		q.inflightNonRuler++
+	}
+
+	// The dispatched requests are only tracked for the priorities with a limit.
+	if q.priorities[next].MaxInflightRequests > 0 {
+		q.inflight[next]++
+	}
+	return req, true
+}
+
+// trackDequeue updates the moving average of the interval between the dequeued requests of the queue.
+func (uq *userQueue) trackDequeue(now time.Time) {
+	interval := now.Sub(uq.lastDequeuedAt)
+	if uq.dequeueInterval == 0 {
+		uq.dequeueInterval = interval
+	} else {
+		uq.dequeueInterval += time.Duration(dequeueIntervalSmoothing * float64(interval-uq.dequeueInterval))
+	}
+	uq.lastDequeuedAt = now
+}
+
+// retryAfter estimates how long it takes to dequeue the pending requests of the queue, at the rate the requests have
+// been dequeued so far. The time since the last dequeued request is taken into account, so that the estimate grows
+// when the requests aren't dequeued anymore.
+func (uq *userQueue) retryAfter(now time.Time) time.Duration {
+	interval := uq.dequeueInterval
+	if sinceLast := now.Sub(uq.lastDequeuedAt); sinceLast > interval {
+		interval = sinceLast
+	}
+
+	retryAfter := time.Duration(uq.length) * interval
+	if retryAfter < minRetryAfter {
+		return minRetryAfter
+	}
+	if retryAfter > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return retryAfter
+}
+
+// canDispatch returns whether a request of the priority can be dispatched, without exceeding its max inflight requests.
+func (q *queues) canDispatch(p Priority) bool {
+	limit := q.priorities[p].MaxInflightRequests
+	return limit <= 0 || q.inflight[p] < limit
+}
+
+// nextDispatchableRequest returns the index of the first of the pending requests which can be dispatched without
+// using the querier workers reserved to the ruler, or -1 if there's none. When the workers not reserved are all
+// busy, only the requests issued by the ruler can be dispatched.
+func (q *queues) nextDispatchableRequest(requests []Request) int {
+	if q.rulerReservedWorkers <= 0 || q.inflightNonRuler < q.maxInflightNonRuler() {
+		return 0
+	}
+	for i, req := range requests {
+		if isFromRuler(req) {
+			return i
+		}
+	}
+	return -1
+}
+
+// maxInflightNonRuler returns the max number of dispatched requests not issued by the ruler, which leaves the
+// reserved querier workers available to the ruler. At least one request can be dispatched, so that the other
+// requests aren't stuck when there are no more connected querier workers than the reserved ones.
+func (q *queues) maxInflightNonRuler() int {
+	return util_math.Max(1, q.connectedWorkers-q.rulerReservedWorkers)
+}
+
+// releaseRequest tracks the completion of a dispatched request.
+func (q *queues) releaseRequest(req Request) {
+	if p := priorityOf(req); q.priorities[p].MaxInflightRequests > 0 && q.inflight[p] > 0 {
+		q.inflight[p]--
+	}
+	if q.rulerReservedWorkers > 0 && !isFromRuler(req) && q.inflightNonRuler > 0 {
+		q.inflightNonRuler--
+	}
+}
+
 func (q *queues) addQuerierConnection(querierID string) {
+	q.connectedWorkers++
+
 	info := q.queriers[querierID]
 	if info != nil {
 		info.connections++
@@ -210,6 +401,7 @@ func (q *queues) removeQuerierConnection(querierID string, now time.Time) {
 
 	// Decrease the number of active connections.
 	info.connections--
+	q.connectedWorkers--
 	if info.connections > 0 {
 		return
 	}
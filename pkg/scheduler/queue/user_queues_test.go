@@ -18,7 +18,7 @@ import (
 )
 
 func TestQueues(t *testing.T) {
-	uq := newUserQueues(0, 0)
+	uq := newUserQueues(0, 0, PrioritiesConfig{}, 0)
 	assert.NotNil(t, uq)
 	assert.NoError(t, isConsistent(uq))
 
@@ -76,7 +76,7 @@ func TestQueues(t *testing.T) {
 }
 
 func TestQueuesOnTerminatingQuerier(t *testing.T) {
-	uq := newUserQueues(0, 0)
+	uq := newUserQueues(0, 0, PrioritiesConfig{}, 0)
 	assert.NotNil(t, uq)
 	assert.NoError(t, isConsistent(uq))
 
@@ -106,7 +106,7 @@ func TestQueuesOnTerminatingQuerier(t *testing.T) {
 }
 
 func TestQueuesWithQueriers(t *testing.T) {
-	uq := newUserQueues(0, 0)
+	uq := newUserQueues(0, 0, PrioritiesConfig{}, 0)
 	assert.NotNil(t, uq)
 	assert.NoError(t, isConsistent(uq))
 
@@ -183,7 +183,7 @@ func TestQueuesConsistency(t *testing.T) {
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			uq := newUserQueues(0, testData.forgetDelay)
+			uq := newUserQueues(0, testData.forgetDelay, PrioritiesConfig{}, 0)
 			assert.NotNil(t, uq)
 			assert.NoError(t, isConsistent(uq))
 
@@ -232,7 +232,7 @@ func TestQueues_ForgetDelay(t *testing.T) {
 	)
 
 	now := time.Now()
-	uq := newUserQueues(0, forgetDelay)
+	uq := newUserQueues(0, forgetDelay, PrioritiesConfig{}, 0)
 	assert.NotNil(t, uq)
 	assert.NoError(t, isConsistent(uq))
 
@@ -324,7 +324,7 @@ func TestQueues_ForgetDelay_ShouldCorrectlyHandleQuerierReconnectingBeforeForget
 	)
 
 	now := time.Now()
-	uq := newUserQueues(0, forgetDelay)
+	uq := newUserQueues(0, forgetDelay, PrioritiesConfig{}, 0)
 	assert.NotNil(t, uq)
 	assert.NoError(t, isConsistent(uq))
 
@@ -397,7 +397,7 @@ func generateQuerier(r *rand.Rand) string {
 	return fmt.Sprint("querier-", r.Int()%5)
 }
 
-func getOrAdd(t *testing.T, uq *queues, tenant string, maxQueriers int) chan Request {
+func getOrAdd(t *testing.T, uq *queues, tenant string, maxQueriers int) *userQueue {
 	q := uq.getOrAddQueue(tenant, maxQueriers)
 	assert.NotNil(t, q)
 	assert.NoError(t, isConsistent(uq))
@@ -405,8 +405,8 @@ func getOrAdd(t *testing.T, uq *queues, tenant string, maxQueriers int) chan Req
 	return q
 }
 
-func confirmOrderForQuerier(t *testing.T, uq *queues, querier string, lastUserIndex int, qs ...chan Request) int {
-	var n chan Request
+func confirmOrderForQuerier(t *testing.T, uq *queues, querier string, lastUserIndex int, qs ...*userQueue) int {
+	var n *userQueue
 	for _, q := range qs {
 		n, _, lastUserIndex = uq.getNextQueueForQuerier(lastUserIndex, querier)
 		assert.Equal(t, q, n)
@@ -522,3 +522,32 @@ func TestShuffleQueriersCorrectness(t *testing.T) {
 		}
 	}
 }
+
+func TestUserQueue_RetryAfter(t *testing.T) {
+	now := time.Now()
+	uq := &userQueue{lastDequeuedAt: now, length: 10}
+
+	// No request has been dequeued since the queue has been created.
+	assert.Equal(t, minRetryAfter, uq.retryAfter(now))
+	assert.Equal(t, 10*5*time.Second, uq.retryAfter(now.Add(5*time.Second)))
+	assert.Equal(t, maxRetryAfter, uq.retryAfter(now.Add(10*time.Second)))
+
+	// The requests are dequeued every 500ms.
+	for i := 1; i <= 5; i++ {
+		uq.trackDequeue(now.Add(time.Duration(i) * 500 * time.Millisecond))
+	}
+	now = now.Add(5 * 500 * time.Millisecond)
+	assert.Equal(t, 500*time.Millisecond, uq.dequeueInterval)
+	assert.Equal(t, 10*500*time.Millisecond, uq.retryAfter(now))
+
+	// The estimate grows if no request is dequeued anymore.
+	assert.Equal(t, 10*2*time.Second, uq.retryAfter(now.Add(2*time.Second)))
+
+	// The moving average of the interval follows the slower dequeues.
+	uq.trackDequeue(now.Add(1500 * time.Millisecond))
+	assert.Equal(t, 700*time.Millisecond, uq.dequeueInterval)
+
+	// The estimate is bounded.
+	uq.length = 1
+	assert.Equal(t, minRetryAfter, uq.retryAfter(now.Add(1500*time.Millisecond)))
+}
@@ -10,6 +10,7 @@ import (
 	"flag"
 	"io"
 	"net/http"
+	"net/textproto"
 	"sync"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
 	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 
 	"github.com/grafana/dskit/tenant"
@@ -59,6 +61,9 @@ type Scheduler struct {
 	pendingRequestsMu sync.Mutex
 	pendingRequests   map[requestKey]*schedulerRequest // Request is kept in this map even after being dispatched to querier. It can still be canceled at that time.
 
+	// The ID assigned to the last enqueued request, used to reference the inflight queries in the admin API.
+	lastRequestID atomic.Uint64
+
 	// Subservices manager.
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -66,10 +71,12 @@ type Scheduler struct {
 	// Metrics.
 	queueLength              *prometheus.GaugeVec
 	discardedRequests        *prometheus.CounterVec
+	dequeuedRequests         *prometheus.CounterVec
 	connectedQuerierClients  prometheus.GaugeFunc
 	connectedFrontendClients prometheus.GaugeFunc
 	queueDuration            prometheus.Histogram
 	inflightRequests         prometheus.Summary
+	enqueuedRequests         *prometheus.CounterVec
 }
 
 type requestKey struct {
@@ -87,17 +94,29 @@ type connectedFrontend struct {
 }
 
 type Config struct {
-	MaxOutstandingPerTenant int               `yaml:"max_outstanding_requests_per_tenant"`
-	QuerierForgetDelay      time.Duration     `yaml:"querier_forget_delay" category:"experimental"`
-	GRPCClientConfig        grpcclient.Config `yaml:"grpc_client_config" doc:"description=This configures the gRPC client used to report errors back to the query-frontend."`
+	MaxOutstandingPerTenant int                    `yaml:"max_outstanding_requests_per_tenant"`
+	QuerierForgetDelay      time.Duration          `yaml:"querier_forget_delay" category:"experimental"`
+	Priorities              queue.PrioritiesConfig `yaml:"priorities" category:"experimental" doc:"description=Configures how the requests of each priority are dequeued. The priority of a request is set by the X-Query-Priority header, being high, normal or low, which the query-frontend only honors on the requests received from the other Mimir components: the ruler sets the high priority on the rule evaluations, while the requests without a valid priority have the normal one."`
+	GRPCClientConfig        grpcclient.Config      `yaml:"grpc_client_config" doc:"description=This configures the gRPC client used to report errors back to the query-frontend."`
+
+	RulerReservedQuerierWorkers int `yaml:"ruler_reserved_querier_workers" category:"experimental"`
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&cfg.MaxOutstandingPerTenant, "query-scheduler.max-outstanding-requests-per-tenant", 100, "Maximum number of outstanding requests per tenant per query-scheduler. In-flight requests above this limit will fail with HTTP response status code 429.")
 	f.DurationVar(&cfg.QuerierForgetDelay, "query-scheduler.querier-forget-delay", 0, "If a querier disconnects without sending notification about graceful shutdown, the query-scheduler will keep the querier in the tenant's shard until the forget delay has passed. This feature is useful to reduce the blast radius when shuffle-sharding is enabled.")
+	cfg.Priorities.RegisterFlagsWithPrefix("query-scheduler.priority.", f)
+	f.IntVar(&cfg.RulerReservedQuerierWorkers, "query-scheduler.ruler-reserved-querier-workers", 0, "Number of the querier workers connected to each query-scheduler which are reserved to the rule evaluations. The other queries are only dispatched to the queriers as long as this number of querier workers is left available, unless all the connected querier workers are reserved, in which case a single other query can be dispatched at a time. The rule evaluations are identified by the query source key set with -query-frontend.ruler-query-source-key. 0 to disable.")
 	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("query-scheduler.grpc-client-config", f)
 }
 
+func (cfg *Config) Validate() error {
+	if cfg.RulerReservedQuerierWorkers < 0 {
+		return errors.New("the ruler reserved querier workers can't be negative")
+	}
+	return cfg.Priorities.Validate()
+}
+
 // NewScheduler creates a new Scheduler.
 func NewScheduler(cfg Config, limits Limits, log log.Logger, registerer prometheus.Registerer) (*Scheduler, error) {
 	s := &Scheduler{
@@ -118,7 +137,16 @@ func NewScheduler(cfg Config, limits Limits, log log.Logger, registerer promethe
 		Name: "cortex_query_scheduler_discarded_requests_total",
 		Help: "Total number of query requests discarded.",
 	}, []string{"user"})
-	s.requestQueue = queue.NewRequestQueue(cfg.MaxOutstandingPerTenant, cfg.QuerierForgetDelay, s.queueLength, s.discardedRequests)
+	s.dequeuedRequests = promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_scheduler_dequeued_requests_total",
+		Help: "Total number of query requests dequeued by the queriers. The share of the querier capacity achieved by each tenant is the rate of its dequeued requests over the rate of all the dequeued requests.",
+	}, []string{"user"})
+	s.requestQueue = queue.NewRequestQueue(cfg.MaxOutstandingPerTenant, cfg.QuerierForgetDelay, cfg.Priorities, cfg.RulerReservedQuerierWorkers, s.queueLength, s.discardedRequests, s.dequeuedRequests)
+
+	s.enqueuedRequests = promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_scheduler_enqueued_requests_total",
+		Help: "Total number of query requests enqueued, per priority.",
+	}, []string{"priority"})
 
 	s.queueDuration = promauto.With(registerer).NewHistogram(prometheus.HistogramOpts{
 		Name:    "cortex_query_scheduler_queue_duration_seconds",
@@ -158,6 +186,17 @@ func NewScheduler(cfg Config, limits Limits, log log.Logger, registerer promethe
 type Limits interface {
 	// MaxQueriersPerUser returns max queriers to use per tenant, or 0 if shuffle sharding is disabled.
 	MaxQueriersPerUser(user string) int
+
+	// QuerierCapacityWeight returns the weight of the tenant in the sharing of the querier capacity among the tenants.
+	QuerierCapacityWeight(user string) int
+
+	// MaxOutstandingRequestsPerTenant returns the max number of outstanding requests of the tenant in the queue,
+	// or 0 to use the limit of the queue.
+	MaxOutstandingRequestsPerTenant(user string) int
+
+	// RulerMaxOutstandingRequestsPerTenant returns the max number of outstanding requests issued by the ruler of the
+	// tenant in the queue, or 0 to count them toward the max outstanding requests of the tenant.
+	RulerMaxOutstandingRequestsPerTenant(user string) int
 }
 
 type schedulerRequest struct {
@@ -166,9 +205,18 @@ type schedulerRequest struct {
 	queryID         uint64
 	request         *httpgrpc.HTTPRequest
 	statsEnabled    bool
+	priority        queue.Priority
+	fromRuler       bool
+
+	// The ID assigned by the scheduler to the request, unique across frontends.
+	id uint64
 
 	enqueueTime time.Time
 
+	// The querier the request has been dispatched to, and when. Guarded by Scheduler.pendingRequestsMu.
+	querierID    string
+	dispatchTime time.Time
+
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 	queueSpan opentracing.Span
@@ -177,6 +225,27 @@ type schedulerRequest struct {
 	parentSpanContext opentracing.SpanContext
 }
 
+// QueryPriority implements queue.PrioritizedRequest.
+func (r *schedulerRequest) QueryPriority() queue.Priority {
+	return r.priority
+}
+
+// FromRuler implements queue.RulerRequest.
+func (r *schedulerRequest) FromRuler() bool {
+	return r.fromRuler
+}
+
+// requestPriority returns the priority set by the X-Query-Priority header of the request, if any,
+// otherwise the normal priority.
+func requestPriority(req *httpgrpc.HTTPRequest) queue.Priority {
+	for _, h := range req.GetHeaders() {
+		if textproto.CanonicalMIMEHeaderKey(h.Key) == queue.PriorityHeader && len(h.Values) > 0 {
+			return queue.ParsePriority(h.Values[0])
+		}
+	}
+	return queue.PriorityNormal
+}
+
 // FrontendLoop handles connection from frontend.
 func (s *Scheduler) FrontendLoop(frontend schedulerpb.SchedulerForFrontend_FrontendLoopServer) error {
 	frontendAddress, frontendCtx, err := s.frontendConnected(frontend)
@@ -217,7 +286,10 @@ func (s *Scheduler) FrontendLoop(frontend schedulerpb.SchedulerForFrontend_Front
 			case err == nil:
 				resp = &schedulerpb.SchedulerToFrontend{Status: schedulerpb.OK}
 			case err == queue.ErrTooManyRequests:
-				resp = &schedulerpb.SchedulerToFrontend{Status: schedulerpb.TOO_MANY_REQUESTS_PER_TENANT}
+				resp = &schedulerpb.SchedulerToFrontend{
+					Status:          schedulerpb.TOO_MANY_REQUESTS_PER_TENANT,
+					RetryAfterNanos: s.requestQueue.RetryAfter(msg.UserID).Nanoseconds(),
+				}
 			default:
 				resp = &schedulerpb.SchedulerToFrontend{Status: schedulerpb.ERROR, Error: err.Error()}
 			}
@@ -305,6 +377,9 @@ func (s *Scheduler) enqueueRequest(frontendContext context.Context, frontendAddr
 		queryID:         msg.QueryID,
 		request:         msg.HttpRequest,
 		statsEnabled:    msg.StatsEnabled,
+		priority:        requestPriority(msg.HttpRequest),
+		fromRuler:       queue.IsRulerHTTPRequest(msg.HttpRequest),
+		id:              s.lastRequestID.Inc(),
 	}
 
 	now := time.Now()
@@ -320,10 +395,14 @@ func (s *Scheduler) enqueueRequest(frontendContext context.Context, frontendAddr
 		return err
 	}
 	maxQueriers := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.MaxQueriersPerUser)
+	weight := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.QuerierCapacityWeight)
+	maxOutstanding := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.MaxOutstandingRequestsPerTenant)
+	maxRulerOutstanding := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.RulerMaxOutstandingRequestsPerTenant)
 
 	s.activeUsers.UpdateUserTimestamp(userID, now)
-	return s.requestQueue.EnqueueRequest(userID, req, maxQueriers, func() {
+	return s.requestQueue.EnqueueRequest(userID, req, maxQueriers, weight, maxOutstanding, maxRulerOutstanding, func() {
 		shouldCancel = false
+		s.enqueuedRequests.WithLabelValues(req.priority.String()).Inc()
 
 		s.pendingRequestsMu.Lock()
 		s.pendingRequests[requestKey{frontendAddr: frontendAddr, queryID: msg.QueryID}] = req
@@ -369,7 +448,8 @@ func (s *Scheduler) QuerierLoop(querier schedulerpb.SchedulerForQuerier_QuerierL
 
 		r := req.(*schedulerRequest)
 
-		s.queueDuration.Observe(time.Since(r.enqueueTime).Seconds())
+		queueTime := time.Since(r.enqueueTime)
+		s.queueDuration.Observe(queueTime.Seconds())
 		r.queueSpan.Finish()
 
 		/*
@@ -387,12 +467,16 @@ func (s *Scheduler) QuerierLoop(querier schedulerpb.SchedulerForQuerier_QuerierL
 		if r.ctx.Err() != nil {
 			// Remove from pending requests.
 			s.cancelRequestAndRemoveFromPending(r.frontendAddress, r.queryID)
+			s.requestQueue.ReleaseRequest(r)
 
 			lastUserIndex = lastUserIndex.ReuseLastUser()
 			continue
 		}
 
-		if err := s.forwardRequestToQuerier(querier, r); err != nil {
+		s.markRequestDispatched(r, querierID)
+		err = s.forwardRequestToQuerier(querier, r, queueTime)
+		s.requestQueue.ReleaseRequest(r)
+		if err != nil {
 			return err
 		}
 	}
@@ -407,7 +491,7 @@ func (s *Scheduler) NotifyQuerierShutdown(_ context.Context, req *schedulerpb.No
 	return &schedulerpb.NotifyQuerierShutdownResponse{}, nil
 }
 
-func (s *Scheduler) forwardRequestToQuerier(querier schedulerpb.SchedulerForQuerier_QuerierLoopServer, req *schedulerRequest) error {
+func (s *Scheduler) forwardRequestToQuerier(querier schedulerpb.SchedulerForQuerier_QuerierLoopServer, req *schedulerRequest, queueTime time.Duration) error {
 	// Make sure to cancel request at the end to cleanup resources.
 	defer s.cancelRequestAndRemoveFromPending(req.frontendAddress, req.queryID)
 
@@ -421,6 +505,7 @@ func (s *Scheduler) forwardRequestToQuerier(querier schedulerpb.SchedulerForQuer
 			FrontendAddress: req.frontendAddress,
 			HttpRequest:     req.request,
 			StatsEnabled:    req.statsEnabled,
+			QueueTimeNanos:  queueTime.Nanoseconds(),
 		})
 		if err != nil {
 			errCh <- err
@@ -450,6 +535,14 @@ func (s *Scheduler) forwardRequestToQuerier(querier schedulerpb.SchedulerForQuer
 }
 
 func (s *Scheduler) forwardErrorToFrontend(ctx context.Context, req *schedulerRequest, requestErr error) {
+	s.forwardResponseToFrontend(ctx, req, &httpgrpc.HTTPResponse{
+		Code: http.StatusInternalServerError,
+		Body: []byte(requestErr.Error()),
+	}, requestErr)
+}
+
+// forwardResponseToFrontend sends the response of the request to the frontend, in place of the querier.
+func (s *Scheduler) forwardResponseToFrontend(ctx context.Context, req *schedulerRequest, resp *httpgrpc.HTTPResponse, requestErr error) {
 	opts, err := s.cfg.GRPCClientConfig.DialOption([]grpc.UnaryClientInterceptor{
 		otgrpc.OpenTracingClientInterceptor(opentracing.GlobalTracer()),
 		middleware.ClientUserHeaderInterceptor},
@@ -473,11 +566,8 @@ func (s *Scheduler) forwardErrorToFrontend(ctx context.Context, req *schedulerRe
 
 	userCtx := user.InjectOrgID(ctx, req.userID)
 	_, err = client.QueryResult(userCtx, &frontendv2pb.QueryResultRequest{
-		QueryID: req.queryID,
-		HttpResponse: &httpgrpc.HTTPResponse{
-			Code: http.StatusInternalServerError,
-			Body: []byte(requestErr.Error()),
-		},
+		QueryID:      req.queryID,
+		HttpResponse: resp,
 	})
 
 	if err != nil {
@@ -534,6 +624,7 @@ func (s *Scheduler) stopping(_ error) error {
 func (s *Scheduler) cleanupMetricsForInactiveUser(user string) {
 	s.queueLength.DeleteLabelValues(user)
 	s.discardedRequests.DeleteLabelValues(user)
+	s.dequeuedRequests.DeleteLabelValues(user)
 }
 
 func (s *Scheduler) getConnectedFrontendClientsMetric() float64 {
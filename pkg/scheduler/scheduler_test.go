@@ -29,6 +29,7 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/grafana/mimir/pkg/frontend/v2/frontendv2pb"
+	"github.com/grafana/mimir/pkg/scheduler/queue"
 	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
 	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
 )
@@ -95,6 +96,7 @@ func TestSchedulerBasicEnqueue(t *testing.T) {
 		require.Equal(t, "frontend-12345", msg2.FrontendAddress)
 		require.Equal(t, "GET", msg2.HttpRequest.Method)
 		require.Equal(t, "/hello", msg2.HttpRequest.Url)
+		require.Greater(t, msg2.QueueTimeNanos, int64(0))
 		require.NoError(t, querierLoop.Send(&schedulerpb.QuerierToScheduler{}))
 	}
 
@@ -349,6 +351,7 @@ func TestSchedulerMaxOutstandingRequests(t *testing.T) {
 	msg, err := fl.Recv()
 	require.NoError(t, err)
 	require.Equal(t, schedulerpb.TOO_MANY_REQUESTS_PER_TENANT, msg.Status)
+	require.GreaterOrEqual(t, msg.RetryAfterNanos, time.Second.Nanoseconds())
 }
 
 func TestSchedulerForwardsErrorToFrontend(t *testing.T) {
@@ -507,6 +510,18 @@ func (l limits) MaxQueriersPerUser(_ string) int {
 	return l.queriers
 }
 
+func (l limits) QuerierCapacityWeight(_ string) int {
+	return 1
+}
+
+func (l limits) MaxOutstandingRequestsPerTenant(_ string) int {
+	return 0
+}
+
+func (l limits) RulerMaxOutstandingRequestsPerTenant(_ string) int {
+	return 0
+}
+
 type frontendMock struct {
 	mu   sync.Mutex
 	resp map[uint64]*httpgrpc.HTTPResponse
@@ -526,3 +541,32 @@ func (f *frontendMock) getRequest(queryID uint64) *httpgrpc.HTTPResponse {
 
 	return f.resp[queryID]
 }
+
+func TestRequestPriority(t *testing.T) {
+	tests := map[string]struct {
+		headers  []*httpgrpc.Header
+		expected queue.Priority
+	}{
+		"no priority header": {
+			expected: queue.PriorityNormal,
+		},
+		"high priority": {
+			headers:  []*httpgrpc.Header{{Key: queue.PriorityHeader, Values: []string{"high"}}},
+			expected: queue.PriorityHigh,
+		},
+		"low priority with a non-canonical header key": {
+			headers:  []*httpgrpc.Header{{Key: "x-query-priority", Values: []string{"LOW"}}},
+			expected: queue.PriorityLow,
+		},
+		"unknown priority": {
+			headers:  []*httpgrpc.Header{{Key: queue.PriorityHeader, Values: []string{"urgent"}}},
+			expected: queue.PriorityNormal,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			require.Equal(t, testData.expected, requestPriority(&httpgrpc.HTTPRequest{Headers: testData.headers}))
+		})
+	}
+}
@@ -139,6 +139,9 @@ type SchedulerToQuerier struct {
 	// Whether query statistics tracking should be enabled. The response will include
 	// statistics only when this option is enabled.
 	StatsEnabled bool `protobuf:"varint,5,opt,name=statsEnabled,proto3" json:"statsEnabled,omitempty"`
+	// How long the query spent in the scheduler queue, in nanoseconds. It's tracked in the query
+	// statistics when the statistics are enabled.
+	QueueTimeNanos int64 `protobuf:"varint,6,opt,name=queueTimeNanos,proto3" json:"queueTimeNanos,omitempty"`
 }
 
 func (m *SchedulerToQuerier) Reset()      { *m = SchedulerToQuerier{} }
@@ -208,6 +211,13 @@ func (m *SchedulerToQuerier) GetStatsEnabled() bool {
 	return false
 }
 
+func (m *SchedulerToQuerier) GetQueueTimeNanos() int64 {
+	if m != nil {
+		return m.QueueTimeNanos
+	}
+	return 0
+}
+
 type FrontendToScheduler struct {
 	Type FrontendToSchedulerType `protobuf:"varint,1,opt,name=type,proto3,enum=schedulerpb.FrontendToSchedulerType" json:"type,omitempty"`
 	// Used by INIT message. Will be put into all requests passed to querier.
@@ -298,6 +308,8 @@ func (m *FrontendToScheduler) GetStatsEnabled() bool {
 type SchedulerToFrontend struct {
 	Status SchedulerToFrontendStatus `protobuf:"varint,1,opt,name=status,proto3,enum=schedulerpb.SchedulerToFrontendStatus" json:"status,omitempty"`
 	Error  string                    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	// Suggested time after which the request can be retried, when status is TOO_MANY_REQUESTS_PER_TENANT.
+	RetryAfterNanos int64 `protobuf:"varint,3,opt,name=retryAfterNanos,proto3" json:"retryAfterNanos,omitempty"`
 }
 
 func (m *SchedulerToFrontend) Reset()      { *m = SchedulerToFrontend{} }
@@ -346,6 +358,13 @@ func (m *SchedulerToFrontend) GetError() string {
 	return ""
 }
 
+func (m *SchedulerToFrontend) GetRetryAfterNanos() int64 {
+	if m != nil {
+		return m.RetryAfterNanos
+	}
+	return 0
+}
+
 type NotifyQuerierShutdownRequest struct {
 	QuerierID string `protobuf:"bytes,1,opt,name=querierID,proto3" json:"querierID,omitempty"`
 }
@@ -438,48 +457,50 @@ func init() {
 func init() { proto.RegisterFile("scheduler.proto", fileDescriptor_2b3fc28395a6d9c5) }
 
 var fileDescriptor_2b3fc28395a6d9c5 = []byte{
-	// 650 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xcf, 0x4f, 0x13, 0x41,
-	0x14, 0xde, 0x29, 0x6d, 0x81, 0x57, 0x94, 0x75, 0x00, 0xad, 0x0d, 0x0e, 0x4d, 0x63, 0x4c, 0x25,
-	0xb1, 0x35, 0xd5, 0x44, 0x0f, 0xc4, 0xa4, 0xc2, 0x22, 0x8d, 0xb8, 0x85, 0xe9, 0x34, 0xfe, 0xb8,
-	0x34, 0xb4, 0x1d, 0x5a, 0x02, 0xec, 0x2c, 0xb3, 0xbb, 0x92, 0xde, 0x3c, 0x7a, 0xf4, 0xcf, 0xf0,
-	0x4f, 0xf1, 0x62, 0xc2, 0x91, 0x83, 0x07, 0x59, 0x2e, 0x1e, 0xf9, 0x13, 0x0c, 0xd3, 0x6d, 0xdd,
-	0xd6, 0x16, 0xb8, 0xbd, 0xf7, 0xf6, 0xfb, 0x76, 0xde, 0xf7, 0xbd, 0x37, 0x03, 0xb3, 0x4e, 0xa3,
-	0xcd, 0x9b, 0xde, 0x01, 0x97, 0x39, 0x5b, 0x0a, 0x57, 0xe0, 0x44, 0xbf, 0x60, 0xd7, 0x53, 0x4f,
-	0x5a, 0x7b, 0x6e, 0xdb, 0xab, 0xe7, 0x1a, 0xe2, 0x30, 0xdf, 0x12, 0x2d, 0x91, 0x57, 0x98, 0xba,
-	0xb7, 0xab, 0x32, 0x95, 0xa8, 0xa8, 0xcb, 0x4d, 0x3d, 0x0f, 0xc1, 0x8f, 0xf9, 0xce, 0x67, 0x7e,
-	0x2c, 0xe4, 0xbe, 0x93, 0x6f, 0x88, 0xc3, 0x43, 0x61, 0xe5, 0xdb, 0xae, 0x6b, 0xb7, 0xa4, 0xdd,
-	0xe8, 0x07, 0x5d, 0x56, 0xa6, 0x00, 0x78, 0xdb, 0xe3, 0x72, 0x8f, 0x4b, 0x26, 0x2a, 0xbd, 0xc3,
-	0xf1, 0x22, 0x4c, 0x1f, 0x75, 0xab, 0xa5, 0xb5, 0x24, 0x4a, 0xa3, 0xec, 0x34, 0xfd, 0x57, 0xc8,
-	0xfc, 0x44, 0x80, 0xfb, 0x58, 0x26, 0x02, 0x3e, 0x4e, 0xc2, 0xe4, 0x25, 0xa6, 0x13, 0x50, 0xa2,
-	0xb4, 0x97, 0xe2, 0x17, 0x90, 0xb8, 0x3c, 0x96, 0xf2, 0x23, 0x8f, 0x3b, 0x6e, 0x32, 0x92, 0x46,
-	0xd9, 0x44, 0x61, 0x21, 0xd7, 0x6f, 0x65, 0x83, 0xb1, 0xad, 0xe0, 0x23, 0x0d, 0x23, 0x71, 0x16,
-	0x66, 0x77, 0xa5, 0xb0, 0x5c, 0x6e, 0x35, 0x8b, 0xcd, 0xa6, 0xe4, 0x8e, 0x93, 0x9c, 0x50, 0xdd,
-	0x0c, 0x97, 0xf1, 0x5d, 0x88, 0x7b, 0x8e, 0x6a, 0x37, 0xaa, 0x00, 0x41, 0x86, 0x33, 0x30, 0xe3,
-	0xb8, 0x3b, 0xae, 0x63, 0x58, 0x3b, 0xf5, 0x03, 0xde, 0x4c, 0xc6, 0xd2, 0x28, 0x3b, 0x45, 0x07,
-	0x6a, 0x99, 0xaf, 0x11, 0x98, 0x5b, 0x0f, 0xfe, 0x17, 0x76, 0xe1, 0x25, 0x44, 0xdd, 0x8e, 0xcd,
-	0x95, 0x9a, 0xdb, 0x85, 0x87, 0xb9, 0xd0, 0x70, 0x72, 0x23, 0xf0, 0xac, 0x63, 0x73, 0xaa, 0x18,
-	0xa3, 0xfa, 0x8e, 0x8c, 0xee, 0x3b, 0x64, 0xda, 0xc4, 0xa0, 0x69, 0xe3, 0x14, 0x0d, 0x99, 0x19,
-	0xbb, 0xb1, 0x99, 0xc3, 0x56, 0xc4, 0x47, 0x58, 0xb1, 0x0f, 0x73, 0xa1, 0xc9, 0xf6, 0x44, 0xe2,
-	0x57, 0x10, 0xbf, 0x84, 0x79, 0x4e, 0xe0, 0xc5, 0xa3, 0x01, 0x2f, 0x46, 0x30, 0x2a, 0x0a, 0x4d,
-	0x03, 0x16, 0x9e, 0x87, 0x18, 0x97, 0x52, 0xc8, 0xc0, 0x85, 0x6e, 0x92, 0x59, 0x81, 0x45, 0x53,
-	0xb8, 0x7b, 0xbb, 0x9d, 0x60, 0x83, 0x2a, 0x6d, 0xcf, 0x6d, 0x8a, 0x63, 0xab, 0xd7, 0xf0, 0xd5,
-	0x5b, 0xb8, 0x04, 0x0f, 0xc6, 0xb0, 0x1d, 0x5b, 0x58, 0x0e, 0x5f, 0x5e, 0x81, 0x7b, 0x63, 0xa6,
-	0x84, 0xa7, 0x20, 0x5a, 0x32, 0x4b, 0x4c, 0xd7, 0x70, 0x02, 0x26, 0x0d, 0x73, 0xbb, 0x6a, 0x54,
-	0x0d, 0x1d, 0x61, 0x80, 0xf8, 0x6a, 0xd1, 0x5c, 0x35, 0x36, 0xf5, 0xc8, 0x72, 0x03, 0xee, 0x8f,
-	0xd5, 0x85, 0xe3, 0x10, 0x29, 0xbf, 0xd5, 0x35, 0x9c, 0x86, 0x45, 0x56, 0x2e, 0xd7, 0xde, 0x15,
-	0xcd, 0x8f, 0x35, 0x6a, 0x6c, 0x57, 0x8d, 0x0a, 0xab, 0xd4, 0xb6, 0x0c, 0x5a, 0x63, 0x86, 0x59,
-	0x34, 0x99, 0x8e, 0xf0, 0x34, 0xc4, 0x0c, 0x4a, 0xcb, 0x54, 0x8f, 0xe0, 0x3b, 0x70, 0xab, 0xb2,
-	0x51, 0x65, 0xac, 0x64, 0xbe, 0xa9, 0xad, 0x95, 0xdf, 0x9b, 0xfa, 0x44, 0xe1, 0x17, 0x0a, 0xf9,
-	0xbd, 0x2e, 0x64, 0xef, 0x2a, 0x55, 0x21, 0x11, 0x84, 0x9b, 0x42, 0xd8, 0x78, 0x69, 0xc0, 0xee,
-	0xff, 0xef, 0x6b, 0x6a, 0x69, 0xdc, 0x3c, 0x02, 0x6c, 0x46, 0xcb, 0xa2, 0xa7, 0x08, 0x5b, 0xb0,
-	0x30, 0xd2, 0x32, 0xfc, 0x78, 0x80, 0x7f, 0xd5, 0x50, 0x52, 0xcb, 0x37, 0x81, 0x76, 0x27, 0x50,
-	0xb0, 0x61, 0x3e, 0xac, 0xae, 0xbf, 0x4e, 0x1f, 0x60, 0xa6, 0x17, 0x2b, 0x7d, 0xe9, 0xeb, 0xae,
-	0x56, 0x2a, 0x7d, 0xdd, 0xc2, 0x75, 0x15, 0xbe, 0x2e, 0x9e, 0x9c, 0x11, 0xed, 0xf4, 0x8c, 0x68,
-	0x17, 0x67, 0x04, 0x7d, 0xf1, 0x09, 0xfa, 0xee, 0x13, 0xf4, 0xc3, 0x27, 0xe8, 0xc4, 0x27, 0xe8,
-	0xb7, 0x4f, 0xd0, 0x1f, 0x9f, 0x68, 0x17, 0x3e, 0x41, 0xdf, 0xce, 0x89, 0x76, 0x72, 0x4e, 0xb4,
-	0xd3, 0x73, 0xa2, 0x7d, 0x0a, 0x3f, 0xbb, 0xf5, 0xb8, 0x7a, 0x18, 0x9f, 0xfd, 0x0d, 0x00, 0x00,
-	0xff, 0xff, 0x49, 0x11, 0xf9, 0x34, 0x9d, 0x05, 0x00, 0x00,
+	// 688 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x4b, 0x6f, 0xd3, 0x4c,
+	0x14, 0xf5, 0xe4, 0xe1, 0xb6, 0x37, 0xfd, 0x5a, 0x7f, 0xd3, 0x16, 0x42, 0x54, 0x5c, 0xcb, 0x42,
+	0x55, 0xa8, 0x44, 0x82, 0x02, 0x12, 0x2c, 0x2a, 0xa4, 0xd0, 0xba, 0x34, 0xa2, 0x38, 0xad, 0xe3,
+	0x88, 0xc7, 0x26, 0xca, 0x63, 0xf2, 0x10, 0x8d, 0xc7, 0x1d, 0xdb, 0x54, 0xd9, 0xb1, 0x64, 0xc9,
+	0x86, 0xff, 0xc0, 0x4f, 0x61, 0xd9, 0x65, 0x17, 0x2c, 0xa8, 0xb3, 0x61, 0xd9, 0x0d, 0x7b, 0x14,
+	0xdb, 0x09, 0x4e, 0x9a, 0xb4, 0xdd, 0xdd, 0x7b, 0x7d, 0x8e, 0x66, 0xce, 0x39, 0xd7, 0x03, 0xcb,
+	0x56, 0xbd, 0x4d, 0x1a, 0xce, 0x31, 0x61, 0x19, 0x93, 0x51, 0x9b, 0xe2, 0xc4, 0x68, 0x60, 0xd6,
+	0x52, 0x8f, 0x5a, 0x1d, 0xbb, 0xed, 0xd4, 0x32, 0x75, 0xda, 0xcd, 0xb6, 0x68, 0x8b, 0x66, 0x3d,
+	0x4c, 0xcd, 0x69, 0x7a, 0x9d, 0xd7, 0x78, 0x95, 0xcf, 0x4d, 0x3d, 0x0d, 0xc1, 0x4f, 0x49, 0xf5,
+	0x13, 0x39, 0xa5, 0xec, 0xa3, 0x95, 0xad, 0xd3, 0x6e, 0x97, 0x1a, 0xd9, 0xb6, 0x6d, 0x9b, 0x2d,
+	0x66, 0xd6, 0x47, 0x85, 0xcf, 0x92, 0x73, 0x80, 0x8f, 0x1c, 0xc2, 0x3a, 0x84, 0xe9, 0xb4, 0x34,
+	0x3c, 0x1c, 0xaf, 0xc3, 0xc2, 0x89, 0x3f, 0x2d, 0xec, 0x26, 0x91, 0x84, 0xd2, 0x0b, 0xda, 0xbf,
+	0x81, 0xfc, 0x07, 0x01, 0x1e, 0x61, 0x75, 0x1a, 0xf0, 0x71, 0x12, 0xe6, 0x06, 0x98, 0x5e, 0x40,
+	0x89, 0x69, 0xc3, 0x16, 0x3f, 0x83, 0xc4, 0xe0, 0x58, 0x8d, 0x9c, 0x38, 0xc4, 0xb2, 0x93, 0x11,
+	0x09, 0xa5, 0x13, 0xb9, 0xb5, 0xcc, 0xe8, 0x2a, 0xfb, 0xba, 0x7e, 0x18, 0x7c, 0xd4, 0xc2, 0x48,
+	0x9c, 0x86, 0xe5, 0x26, 0xa3, 0x86, 0x4d, 0x8c, 0x46, 0xbe, 0xd1, 0x60, 0xc4, 0xb2, 0x92, 0x51,
+	0xef, 0x36, 0x93, 0x63, 0x7c, 0x07, 0x78, 0xc7, 0xf2, 0xae, 0x1b, 0xf3, 0x00, 0x41, 0x87, 0x65,
+	0x58, 0xb4, 0xec, 0xaa, 0x6d, 0x29, 0x46, 0xb5, 0x76, 0x4c, 0x1a, 0xc9, 0xb8, 0x84, 0xd2, 0xf3,
+	0xda, 0xd8, 0x0c, 0x6f, 0xc2, 0xd2, 0x89, 0x43, 0x1c, 0xa2, 0x77, 0xba, 0x44, 0xad, 0x1a, 0xd4,
+	0x4a, 0xf2, 0x12, 0x4a, 0x47, 0xb5, 0x89, 0xa9, 0xfc, 0x25, 0x02, 0x2b, 0x7b, 0xc1, 0xb9, 0x61,
+	0xb7, 0x9e, 0x43, 0xcc, 0xee, 0x99, 0xc4, 0x53, 0xbd, 0x94, 0x7b, 0x90, 0x09, 0x85, 0x98, 0x99,
+	0x82, 0xd7, 0x7b, 0x26, 0xd1, 0x3c, 0xc6, 0x34, 0x7d, 0x91, 0xe9, 0xfa, 0x42, 0xe6, 0x46, 0xc7,
+	0xcd, 0x9d, 0xa5, 0x7c, 0xc2, 0xf4, 0xf8, 0xad, 0x4d, 0x9f, 0xb4, 0x8c, 0xbf, 0x6a, 0x99, 0xfc,
+	0x0d, 0xc1, 0x4a, 0x68, 0x05, 0x86, 0x2a, 0xf1, 0x0b, 0xe0, 0x07, 0x38, 0xc7, 0x0a, 0xcc, 0xd8,
+	0x1c, 0x33, 0x63, 0x0a, 0xa3, 0xe4, 0xa1, 0xb5, 0x80, 0x85, 0x57, 0x21, 0x4e, 0x18, 0xa3, 0x2c,
+	0xb0, 0xc1, 0x6f, 0x06, 0x36, 0x31, 0x62, 0xb3, 0x5e, 0xbe, 0x69, 0x13, 0xe6, 0x27, 0x14, 0xf5,
+	0x12, 0x9a, 0x1c, 0xcb, 0xdb, 0xb0, 0xae, 0x52, 0xbb, 0xd3, 0xec, 0x05, 0x4b, 0x59, 0x6a, 0x3b,
+	0x76, 0x83, 0x9e, 0x1a, 0x43, 0x6d, 0xd7, 0x2f, 0xf6, 0x06, 0xdc, 0x9f, 0xc1, 0xb6, 0x4c, 0x6a,
+	0x58, 0x64, 0x6b, 0x1b, 0xee, 0xce, 0x08, 0x14, 0xcf, 0x43, 0xac, 0xa0, 0x16, 0x74, 0x81, 0xc3,
+	0x09, 0x98, 0x53, 0xd4, 0xa3, 0xb2, 0x52, 0x56, 0x04, 0x84, 0x01, 0xf8, 0x9d, 0xbc, 0xba, 0xa3,
+	0x1c, 0x08, 0x91, 0xad, 0x3a, 0xdc, 0x9b, 0xe9, 0x00, 0xe6, 0x21, 0x52, 0x7c, 0x2d, 0x70, 0x58,
+	0x82, 0x75, 0xbd, 0x58, 0xac, 0xbc, 0xc9, 0xab, 0xef, 0x2b, 0x9a, 0x72, 0x54, 0x56, 0x4a, 0x7a,
+	0xa9, 0x72, 0xa8, 0x68, 0x15, 0x5d, 0x51, 0xf3, 0xaa, 0x2e, 0x20, 0xbc, 0x00, 0x71, 0x45, 0xd3,
+	0x8a, 0x9a, 0x10, 0xc1, 0xff, 0xc3, 0x7f, 0xa5, 0xfd, 0xb2, 0xae, 0x17, 0xd4, 0x57, 0x95, 0xdd,
+	0xe2, 0x5b, 0x55, 0x88, 0xe6, 0x7e, 0x86, 0x93, 0xd9, 0xa3, 0x6c, 0xf8, 0x77, 0x96, 0x21, 0x11,
+	0x94, 0x07, 0x94, 0x9a, 0x78, 0x63, 0x2c, 0x98, 0xab, 0x4f, 0x40, 0x6a, 0x63, 0x56, 0x72, 0x01,
+	0x56, 0xe6, 0xd2, 0xe8, 0x31, 0xc2, 0x06, 0xac, 0x4d, 0xb5, 0x0c, 0x3f, 0x1c, 0xe3, 0x5f, 0x17,
+	0x4a, 0x6a, 0xeb, 0x36, 0x50, 0x3f, 0x81, 0x9c, 0x09, 0xab, 0x61, 0x75, 0xa3, 0xc5, 0x7b, 0x07,
+	0x8b, 0xc3, 0xda, 0xd3, 0x27, 0xdd, 0xf4, 0x17, 0xa6, 0xa4, 0x9b, 0x56, 0xd3, 0x57, 0xf8, 0x32,
+	0x7f, 0x76, 0x21, 0x72, 0xe7, 0x17, 0x22, 0x77, 0x79, 0x21, 0xa2, 0xcf, 0xae, 0x88, 0xbe, 0xbb,
+	0x22, 0xfa, 0xe1, 0x8a, 0xe8, 0xcc, 0x15, 0xd1, 0x2f, 0x57, 0x44, 0xbf, 0x5d, 0x91, 0xbb, 0x74,
+	0x45, 0xf4, 0xb5, 0x2f, 0x72, 0x67, 0x7d, 0x91, 0x3b, 0xef, 0x8b, 0xdc, 0x87, 0xf0, 0x4b, 0x5e,
+	0xe3, 0xbd, 0xb7, 0xf6, 0xc9, 0xdf, 0x01, 0x00, 0x97, 0xd2, 0xaa, 0x3d, 0xf0, 0x05, 0x00, 0x00,
 }
 
 func (x FrontendToSchedulerType) String() string {
@@ -554,6 +575,9 @@ func (this *SchedulerToQuerier) Equal(that interface{}) bool {
 	if this.StatsEnabled != that1.StatsEnabled {
 		return false
 	}
+	if this.QueueTimeNanos != that1.QueueTimeNanos {
+		return false
+	}
 	return true
 }
 func (this *FrontendToScheduler) Equal(that interface{}) bool {
@@ -620,6 +644,9 @@ func (this *SchedulerToFrontend) Equal(that interface{}) bool {
 	if this.Error != that1.Error {
 		return false
 	}
+	if this.RetryAfterNanos != that1.RetryAfterNanos {
+		return false
+	}
 	return true
 }
 func (this *NotifyQuerierShutdownRequest) Equal(that interface{}) bool {
@@ -681,7 +708,7 @@ func (this *SchedulerToQuerier) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 10)
 	s = append(s, "&schedulerpb.SchedulerToQuerier{")
 	s = append(s, "QueryID: "+fmt.Sprintf("%#v", this.QueryID)+",\n")
 	if this.HttpRequest != nil {
@@ -690,6 +717,7 @@ func (this *SchedulerToQuerier) GoString() string {
 	s = append(s, "FrontendAddress: "+fmt.Sprintf("%#v", this.FrontendAddress)+",\n")
 	s = append(s, "UserID: "+fmt.Sprintf("%#v", this.UserID)+",\n")
 	s = append(s, "StatsEnabled: "+fmt.Sprintf("%#v", this.StatsEnabled)+",\n")
+	s = append(s, "QueueTimeNanos: "+fmt.Sprintf("%#v", this.QueueTimeNanos)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -714,10 +742,11 @@ func (this *SchedulerToFrontend) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 6)
+	s := make([]string, 0, 7)
 	s = append(s, "&schedulerpb.SchedulerToFrontend{")
 	s = append(s, "Status: "+fmt.Sprintf("%#v", this.Status)+",\n")
 	s = append(s, "Error: "+fmt.Sprintf("%#v", this.Error)+",\n")
+	s = append(s, "RetryAfterNanos: "+fmt.Sprintf("%#v", this.RetryAfterNanos)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1078,6 +1107,11 @@ func (m *SchedulerToQuerier) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.QueueTimeNanos != 0 {
+		i = encodeVarintScheduler(dAtA, i, uint64(m.QueueTimeNanos))
+		i--
+		dAtA[i] = 0x30
+	}
 	if m.StatsEnabled {
 		i--
 		if m.StatsEnabled {
@@ -1211,6 +1245,11 @@ func (m *SchedulerToFrontend) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.RetryAfterNanos != 0 {
+		i = encodeVarintScheduler(dAtA, i, uint64(m.RetryAfterNanos))
+		i--
+		dAtA[i] = 0x18
+	}
 	if len(m.Error) > 0 {
 		i -= len(m.Error)
 		copy(dAtA[i:], m.Error)
@@ -1327,6 +1366,9 @@ func (m *SchedulerToQuerier) Size() (n int) {
 	if m.StatsEnabled {
 		n += 2
 	}
+	if m.QueueTimeNanos != 0 {
+		n += 1 + sovScheduler(uint64(m.QueueTimeNanos))
+	}
 	return n
 }
 
@@ -1373,6 +1415,9 @@ func (m *SchedulerToFrontend) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovScheduler(uint64(l))
 	}
+	if m.RetryAfterNanos != 0 {
+		n += 1 + sovScheduler(uint64(m.RetryAfterNanos))
+	}
 	return n
 }
 
@@ -1424,6 +1469,7 @@ func (this *SchedulerToQuerier) String() string {
 		`FrontendAddress:` + fmt.Sprintf("%v", this.FrontendAddress) + `,`,
 		`UserID:` + fmt.Sprintf("%v", this.UserID) + `,`,
 		`StatsEnabled:` + fmt.Sprintf("%v", this.StatsEnabled) + `,`,
+		`QueueTimeNanos:` + fmt.Sprintf("%v", this.QueueTimeNanos) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1450,6 +1496,7 @@ func (this *SchedulerToFrontend) String() string {
 	s := strings.Join([]string{`&SchedulerToFrontend{`,
 		`Status:` + fmt.Sprintf("%v", this.Status) + `,`,
 		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
+		`RetryAfterNanos:` + fmt.Sprintf("%v", this.RetryAfterNanos) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1734,6 +1781,25 @@ func (m *SchedulerToQuerier) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.StatsEnabled = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueueTimeNanos", wireType)
+			}
+			m.QueueTimeNanos = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowScheduler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.QueueTimeNanos |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipScheduler(dAtA[iNdEx:])
@@ -2049,6 +2115,25 @@ func (m *SchedulerToFrontend) Unmarshal(dAtA []byte) error {
 			}
 			m.Error = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RetryAfterNanos", wireType)
+			}
+			m.RetryAfterNanos = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowScheduler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RetryAfterNanos |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipScheduler(dAtA[iNdEx:])
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucket
+
+import (
+	"context"
+	"io"
+	"path"
+	"sync"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+// TieredBucketClient is a bucket client reading the objects from a hot bucket and, if not found there, from a cold
+// bucket, which the old objects are archived to. The objects are always written to the hot bucket, while they're
+// deleted from both buckets, and the objects of both buckets are listed. This way, the objects moved from the hot
+// bucket to the cold one can be read transparently.
+type TieredBucketClient struct {
+	hot  objstore.Bucket
+	cold objstore.Bucket
+
+	// Limits the concurrent reads from the cold bucket, nil if unlimited. A read holds its slot until the returned
+	// reader is closed.
+	coldReads chan struct{}
+
+	// The directories of the objects found in the cold bucket, whose objects are looked up in the cold bucket
+	// first, to not look them up in the hot bucket each time.
+	coldDirs *coldDirs
+}
+
+// NewTieredBucketClient returns a new TieredBucketClient. The max concurrent reads from the cold bucket are
+// unlimited if 0.
+func NewTieredBucketClient(hot, cold objstore.Bucket, maxConcurrentColdReads int) *TieredBucketClient {
+	b := &TieredBucketClient{
+		hot:      hot,
+		cold:     cold,
+		coldDirs: &coldDirs{dirs: map[string]struct{}{}},
+	}
+	if maxConcurrentColdReads > 0 {
+		b.coldReads = make(chan struct{}, maxConcurrentColdReads)
+	}
+	return b
+}
+
+// HotBucket returns the hot bucket.
+func (b *TieredBucketClient) HotBucket() objstore.Bucket { return b.hot }
+
+// ColdBucket returns the cold bucket.
+func (b *TieredBucketClient) ColdBucket() objstore.Bucket { return b.cold }
+
+// Close implements io.Closer.
+func (b *TieredBucketClient) Close() error {
+	hotErr := b.hot.Close()
+	if err := b.cold.Close(); err != nil {
+		return err
+	}
+	return hotErr
+}
+
+// Upload the contents of the reader as an object into the hot bucket.
+func (b *TieredBucketClient) Upload(ctx context.Context, name string, r io.Reader) error {
+	return b.hot.Upload(ctx, name, r)
+}
+
+// Delete removes the object with the given name from both buckets. It fails if the object doesn't exist in any.
+func (b *TieredBucketClient) Delete(ctx context.Context, name string) error {
+	hotErr := b.hot.Delete(ctx, name)
+	if hotErr != nil && !b.hot.IsObjNotFoundErr(hotErr) {
+		return hotErr
+	}
+
+	coldErr := b.cold.Delete(ctx, name)
+	if coldErr != nil && !b.cold.IsObjNotFoundErr(coldErr) {
+		return coldErr
+	}
+
+	if hotErr != nil && coldErr != nil {
+		return hotErr
+	}
+	return nil
+}
+
+// Name returns the name of the hot bucket.
+func (b *TieredBucketClient) Name() string { return b.hot.Name() }
+
+// Iter calls f for each entry of the given directory in both buckets, once for the entries existing in both.
+func (b *TieredBucketClient) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	seen := map[string]struct{}{}
+	if err := b.hot.Iter(ctx, dir, func(name string) error {
+		seen[name] = struct{}{}
+		return f(name)
+	}, options...); err != nil {
+		return err
+	}
+
+	return b.cold.Iter(ctx, dir, func(name string) error {
+		if _, ok := seen[name]; ok {
+			return nil
+		}
+		return f(name)
+	}, options...)
+}
+
+// Get returns a reader for the given object name.
+func (b *TieredBucketClient) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.get(ctx, name, func(bkt objstore.BucketReader) (io.ReadCloser, error) {
+		return bkt.Get(ctx, name)
+	})
+}
+
+// GetRange returns a new range reader for the given object name and range.
+func (b *TieredBucketClient) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	return b.get(ctx, name, func(bkt objstore.BucketReader) (io.ReadCloser, error) {
+		return bkt.GetRange(ctx, name, off, length)
+	})
+}
+
+func (b *TieredBucketClient) get(ctx context.Context, name string, get func(objstore.BucketReader) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if b.coldDirs.contains(name) {
+		r, err := b.getCold(ctx, get)
+		if err == nil || !b.cold.IsObjNotFoundErr(err) {
+			return r, err
+		}
+		return get(b.hot)
+	}
+
+	r, err := get(expectNotFoundErrs(b.hot))
+	if err == nil || !b.hot.IsObjNotFoundErr(err) {
+		return r, err
+	}
+
+	r, coldErr := b.getCold(ctx, get)
+	if coldErr != nil {
+		if b.cold.IsObjNotFoundErr(coldErr) {
+			return nil, err
+		}
+		return nil, coldErr
+	}
+	b.coldDirs.add(name)
+	return r, nil
+}
+
+// getCold reads from the cold bucket, once a read slot is available.
+func (b *TieredBucketClient) getCold(ctx context.Context, get func(objstore.BucketReader) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if b.coldReads == nil {
+		return get(b.cold)
+	}
+
+	select {
+	case b.coldReads <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	r, err := get(b.cold)
+	if err != nil {
+		<-b.coldReads
+		return nil, err
+	}
+	return &coldReader{ReadCloser: r, release: func() { <-b.coldReads }}, nil
+}
+
+// Exists checks if the given object exists in any of the buckets.
+func (b *TieredBucketClient) Exists(ctx context.Context, name string) (bool, error) {
+	ok, err := b.hot.Exists(ctx, name)
+	if err != nil || ok {
+		return ok, err
+	}
+	return b.cold.Exists(ctx, name)
+}
+
+// IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
+func (b *TieredBucketClient) IsObjNotFoundErr(err error) bool {
+	return b.hot.IsObjNotFoundErr(err) || b.cold.IsObjNotFoundErr(err)
+}
+
+// Attributes returns attributes of the specified object.
+func (b *TieredBucketClient) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	attrs, err := expectNotFoundErrs(b.hot).Attributes(ctx, name)
+	if err == nil || !b.hot.IsObjNotFoundErr(err) {
+		return attrs, err
+	}
+
+	attrs, coldErr := b.cold.Attributes(ctx, name)
+	if coldErr != nil && b.cold.IsObjNotFoundErr(coldErr) {
+		return attrs, err
+	}
+	return attrs, coldErr
+}
+
+// ReaderWithExpectedErrs allows to specify a filter that marks certain errors as expected, so it will not increment
+// thanos_objstore_bucket_operation_failures_total metric.
+func (b *TieredBucketClient) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+// WithExpectedErrs allows to specify a filter that marks certain errors as expected, so it will not increment
+// thanos_objstore_bucket_operation_failures_total metric.
+func (b *TieredBucketClient) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	hot, cold := b.hot, b.cold
+	if ib, ok := hot.(objstore.InstrumentedBucket); ok {
+		hot = ib.WithExpectedErrs(fn)
+	}
+	if ib, ok := cold.(objstore.InstrumentedBucket); ok {
+		cold = ib.WithExpectedErrs(fn)
+	}
+
+	// The copy shares the read slots and the cold directories of the original client.
+	return &TieredBucketClient{
+		hot:       hot,
+		cold:      cold,
+		coldReads: b.coldReads,
+		coldDirs:  b.coldDirs,
+	}
+}
+
+type coldDirs struct {
+	mx   sync.RWMutex
+	dirs map[string]struct{}
+}
+
+func (d *coldDirs) contains(name string) bool {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+
+	_, ok := d.dirs[path.Dir(name)]
+	return ok
+}
+
+func (d *coldDirs) add(name string) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.dirs[path.Dir(name)] = struct{}{}
+}
+
+// expectNotFoundErrs returns the bucket reader not tracking the object not found errors as failures, because the
+// objects not found in the hot bucket are looked up in the cold bucket.
+func expectNotFoundErrs(bkt objstore.Bucket) objstore.BucketReader {
+	if ib, ok := bkt.(objstore.InstrumentedBucket); ok {
+		return ib.ReaderWithExpectedErrs(ib.IsObjNotFoundErr)
+	}
+	return bkt
+}
+
+// coldReader is a reader of an object of the cold bucket, releasing its read slot once closed.
+type coldReader struct {
+	io.ReadCloser
+	release     func()
+	releaseOnce sync.Once
+}
+
+func (r *coldReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.releaseOnce.Do(r.release)
+	return err
+}
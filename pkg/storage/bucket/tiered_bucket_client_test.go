@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucket
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+func TestTieredBucketClient(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*objstore.InMemBucket, *objstore.InMemBucket, *TieredBucketClient) {
+		hot, cold := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+		require.NoError(t, hot.Upload(ctx, "block-1/meta.json", strings.NewReader("hot")))
+		require.NoError(t, cold.Upload(ctx, "block-2/meta.json", strings.NewReader("cold")))
+		require.NoError(t, cold.Upload(ctx, "block-2/index", strings.NewReader("cold index")))
+		return hot, cold, NewTieredBucketClient(hot, cold, 0)
+	}
+
+	t.Run("Upload", func(t *testing.T) {
+		hot, cold, client := setup(t)
+		require.NoError(t, client.Upload(ctx, "block-3/meta.json", strings.NewReader("new")))
+
+		ok, err := hot.Exists(ctx, "block-3/meta.json")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = cold.Exists(ctx, "block-3/meta.json")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		hot, cold, client := setup(t)
+		require.NoError(t, client.Delete(ctx, "block-1/meta.json"))
+		require.NoError(t, client.Delete(ctx, "block-2/meta.json"))
+		assert.Empty(t, hot.Objects())
+		assert.Len(t, cold.Objects(), 1)
+
+		err := client.Delete(ctx, "block-3/meta.json")
+		require.Error(t, err)
+		assert.True(t, client.IsObjNotFoundErr(err))
+	})
+
+	t.Run("Iter", func(t *testing.T) {
+		hot, _, client := setup(t)
+		require.NoError(t, hot.Upload(ctx, "block-2/meta.json", strings.NewReader("hot")))
+
+		var names []string
+		require.NoError(t, client.Iter(ctx, "", func(name string) error {
+			names = append(names, name)
+			return nil
+		}))
+		sort.Strings(names)
+		assert.Equal(t, []string{"block-1/", "block-2/"}, names)
+
+		names = nil
+		require.NoError(t, client.Iter(ctx, "", func(name string) error {
+			names = append(names, name)
+			return nil
+		}, objstore.WithRecursiveIter))
+		sort.Strings(names)
+		assert.Equal(t, []string{"block-1/meta.json", "block-2/index", "block-2/meta.json"}, names)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		_, _, client := setup(t)
+		assertContent(t, client, "block-1/meta.json", "hot")
+		assertContent(t, client, "block-2/meta.json", "cold")
+
+		// The other objects of the directory are then read from the cold bucket first.
+		assert.True(t, client.coldDirs.contains("block-2/index"))
+		assertContent(t, client, "block-2/index", "cold index")
+
+		_, err := client.Get(ctx, "block-3/meta.json")
+		require.Error(t, err)
+		assert.True(t, client.IsObjNotFoundErr(err))
+	})
+
+	t.Run("GetRange", func(t *testing.T) {
+		_, _, client := setup(t)
+		reader, err := client.GetRange(ctx, "block-2/index", 5, 5)
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		assert.Equal(t, "index", string(content))
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		_, _, client := setup(t)
+		for name, expected := range map[string]bool{
+			"block-1/meta.json": true,
+			"block-2/meta.json": true,
+			"block-3/meta.json": false,
+		} {
+			ok, err := client.Exists(ctx, name)
+			require.NoError(t, err)
+			assert.Equal(t, expected, ok, name)
+		}
+	})
+
+	t.Run("Attributes", func(t *testing.T) {
+		_, _, client := setup(t)
+		attrs, err := client.Attributes(ctx, "block-2/meta.json")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("cold")), attrs.Size)
+
+		_, err = client.Attributes(ctx, "block-3/meta.json")
+		require.Error(t, err)
+		assert.True(t, client.IsObjNotFoundErr(err))
+	})
+}
+
+func TestTieredBucketClient_MaxConcurrentColdReads(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+	require.NoError(t, hot.Upload(ctx, "block-1/meta.json", strings.NewReader("hot")))
+	require.NoError(t, cold.Upload(ctx, "block-2/meta.json", strings.NewReader("cold")))
+	client := NewTieredBucketClient(hot, cold, 1)
+
+	reader, err := client.Get(ctx, "block-2/meta.json")
+	require.NoError(t, err)
+
+	// The hot bucket is still read, while the cold reads wait for the open reader to be closed.
+	assertContent(t, client, "block-1/meta.json", "hot")
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = client.Get(timeoutCtx, "block-2/meta.json")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, reader.Close())
+	require.NoError(t, reader.Close())
+	assertContent(t, client, "block-2/meta.json", "cold")
+}
+
+func assertContent(t *testing.T, bkt objstore.BucketReader, name, expected string) {
+	reader, err := bkt.Get(context.Background(), name)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, expected, string(content))
+}
@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bloom
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/index"
+)
+
+const (
+	// FilterFilename is the name of the file, stored in the block directory alongside the index,
+	// containing the label values bloom filter.
+	FilterFilename = "label-values-bloom"
+
+	filterMagic   = 0xB100F117
+	filterVersion = 1
+
+	// DefaultFalsePositiveRate is the false positive rate used when building filters from a block index.
+	DefaultFalsePositiveRate = 0.01
+)
+
+var (
+	errInvalidFilter = errors.New("invalid bloom filter")
+	castagnoliTable  = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Filter is a bloom filter over label name and value pairs. The filter keeps track of the label names
+// it has been built for, so that it can only be used to rule out matchers on those label names.
+type Filter struct {
+	labelNames map[string]struct{}
+	hashes     uint32
+	bits       []uint64
+}
+
+// New returns an empty Filter for the given label names, sized to hold the expected number of
+// label name and value pairs with the given false positive rate.
+func New(labelNames []string, expectedItems int, falsePositiveRate float64) *Filter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+
+	// Optimal number of bits and hash functions, see https://en.wikipedia.org/wiki/Bloom_filter.
+	numBits := math.Ceil(-float64(expectedItems) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	numHashes := math.Max(1, math.Round(numBits/float64(expectedItems)*math.Ln2))
+
+	f := &Filter{
+		labelNames: make(map[string]struct{}, len(labelNames)),
+		hashes:     uint32(numHashes),
+		bits:       make([]uint64, int(math.Ceil(numBits/64))),
+	}
+	for _, name := range labelNames {
+		f.labelNames[name] = struct{}{}
+	}
+	return f
+}
+
+// Add adds the label name and value pair to the filter.
+func (f *Filter) Add(name, value string) {
+	h1, h2 := hashLabel(name, value)
+	numBits := uint64(len(f.bits)) * 64
+
+	for i := uint32(0); i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % numBits
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// MayContain returns false if the label name and value pair has definitely not been added to the filter.
+func (f *Filter) MayContain(name, value string) bool {
+	h1, h2 := hashLabel(name, value)
+	numBits := uint64(len(f.bits)) * 64
+
+	for i := uint32(0); i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % numBits
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CannotMatch returns true if the filter guarantees that no series in the block can match all the
+// input matchers. Only equality matchers on label names covered by the filter are taken into account.
+func (f *Filter) CannotMatch(matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		// An equality matcher on an empty value matches series without the label too.
+		if m.Type != labels.MatchEqual || m.Value == "" {
+			continue
+		}
+		if _, ok := f.labelNames[m.Name]; !ok {
+			continue
+		}
+		if !f.MayContain(m.Name, m.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// LabelNames returns the sorted list of label names covered by the filter.
+func (f *Filter) LabelNames() []string {
+	names := make([]string, 0, len(f.labelNames))
+	for name := range f.labelNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Marshal encodes the filter.
+func (f *Filter) Marshal() []byte {
+	names := f.LabelNames()
+
+	buf := make([]byte, 0, 16+len(f.bits)*8)
+	buf = appendUint32(buf, filterMagic)
+	buf = append(buf, filterVersion)
+	buf = appendUvarint(buf, uint64(f.hashes))
+	buf = appendUvarint(buf, uint64(len(names)))
+	for _, name := range names {
+		buf = appendUvarint(buf, uint64(len(name)))
+		buf = append(buf, name...)
+	}
+	buf = appendUvarint(buf, uint64(len(f.bits)))
+	for _, w := range f.bits {
+		buf = appendUint64(buf, w)
+	}
+	return appendUint32(buf, crc32.Checksum(buf, castagnoliTable))
+}
+
+// Unmarshal decodes a filter previously encoded with Marshal.
+func Unmarshal(buf []byte) (*Filter, error) {
+	if len(buf) < 9 {
+		return nil, errInvalidFilter
+	}
+
+	content, checksum := buf[:len(buf)-4], binary.BigEndian.Uint32(buf[len(buf)-4:])
+	if crc32.Checksum(content, castagnoliTable) != checksum {
+		return nil, errors.Wrap(errInvalidFilter, "checksum mismatch")
+	}
+	if binary.BigEndian.Uint32(content) != filterMagic {
+		return nil, errors.Wrap(errInvalidFilter, "invalid magic number")
+	}
+	if v := content[4]; v != filterVersion {
+		return nil, errors.Wrapf(errInvalidFilter, "unsupported version %d", v)
+	}
+
+	d := decoder{buf: content[5:]}
+	f := &Filter{hashes: uint32(d.uvarint())}
+
+	numNames := d.uvarint()
+	f.labelNames = make(map[string]struct{}, numNames)
+	for i := uint64(0); i < numNames && d.err == nil; i++ {
+		f.labelNames[d.string()] = struct{}{}
+	}
+
+	numWords := d.uvarint()
+	if d.err == nil && uint64(len(d.buf)) != numWords*8 {
+		return nil, errors.Wrap(errInvalidFilter, "unexpected bits length")
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if numWords == 0 || f.hashes == 0 {
+		return nil, errors.Wrap(errInvalidFilter, "empty filter")
+	}
+
+	f.bits = make([]uint64, numWords)
+	for i := range f.bits {
+		f.bits[i] = binary.BigEndian.Uint64(d.buf[i*8:])
+	}
+	return f, nil
+}
+
+// BuildFromIndex builds a filter over all the values of the input label names found in the TSDB index at indexPath.
+func BuildFromIndex(indexPath string, labelNames []string, falsePositiveRate float64) (*Filter, error) {
+	r, err := index.NewFileReader(indexPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "open index")
+	}
+	defer r.Close()
+
+	valuesByName := make(map[string][]string, len(labelNames))
+	numValues := 0
+	for _, name := range labelNames {
+		values, err := r.LabelValues(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read values for label %s", name)
+		}
+		valuesByName[name] = values
+		numValues += len(values)
+	}
+
+	f := New(labelNames, numValues, falsePositiveRate)
+	for name, values := range valuesByName {
+		for _, value := range values {
+			f.Add(name, value)
+		}
+	}
+	return f, nil
+}
+
+// WriteFile writes the filter to the block directory.
+func WriteFile(blockDir string, f *Filter) error {
+	return os.WriteFile(filepath.Join(blockDir, FilterFilename), f.Marshal(), 0644)
+}
+
+func hashLabel(name, value string) (uint64, uint64) {
+	d := xxhash.New()
+	_, _ = d.WriteString(name)
+	_, _ = d.Write([]byte{0xff})
+	_, _ = d.WriteString(value)
+	h := d.Sum64()
+
+	// Derive the second hash from the first one, as in "Less Hashing, Same Performance" (Kirsch and Mitzenmacher).
+	return h, (h >> 33) | (h << 31) | 1
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(buf, tmp[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	return append(buf, tmp[:]...)
+}
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errors.Wrap(errInvalidFilter, "invalid varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	l := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.buf)) < l {
+		d.err = errors.Wrap(errInvalidFilter, "string out of bounds")
+		return ""
+	}
+	s := string(d.buf[:l])
+	d.buf = d.buf[l:]
+	return s
+}
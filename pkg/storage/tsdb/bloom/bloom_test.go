@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+
+	"github.com/grafana/mimir/pkg/storegateway/testhelper"
+)
+
+func TestFilter_MayContain(t *testing.T) {
+	f := New([]string{"pod"}, 1000, DefaultFalsePositiveRate)
+	for i := 0; i < 1000; i++ {
+		f.Add("pod", fmt.Sprintf("pod-%d", i))
+	}
+
+	// No false negatives.
+	for i := 0; i < 1000; i++ {
+		assert.True(t, f.MayContain("pod", fmt.Sprintf("pod-%d", i)))
+	}
+
+	// False positives should be around the configured rate.
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.MayContain("pod", fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestFilter_CannotMatch(t *testing.T) {
+	f := New([]string{"trace_id"}, 10, DefaultFalsePositiveRate)
+	f.Add("trace_id", "abc")
+
+	tests := map[string]struct {
+		matchers []*labels.Matcher
+		expected bool
+	}{
+		"equality matcher on a value in the filter": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "trace_id", "abc")},
+			expected: false,
+		},
+		"equality matcher on a value not in the filter": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "trace_id", "xyz")},
+			expected: true,
+		},
+		"equality matcher on an empty value": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "trace_id", "")},
+			expected: false,
+		},
+		"equality matcher on a label name not covered by the filter": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "pod", "xyz")},
+			expected: false,
+		},
+		"regexp matcher": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "trace_id", "xyz")},
+			expected: false,
+		},
+		"multiple matchers, one of them not matching": {
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "__name__", "foo"),
+				labels.MustNewMatcher(labels.MatchEqual, "trace_id", "xyz"),
+			},
+			expected: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, f.CannotMatch(testData.matchers))
+		})
+	}
+}
+
+func TestFilter_MarshalUnmarshal(t *testing.T) {
+	f := New([]string{"pod", "trace_id"}, 100, DefaultFalsePositiveRate)
+	for i := 0; i < 100; i++ {
+		f.Add("pod", fmt.Sprintf("pod-%d", i))
+	}
+
+	decoded, err := Unmarshal(f.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, f, decoded)
+
+	// Corrupted data should be detected.
+	data := f.Marshal()
+	data[10]++
+	_, err = Unmarshal(data)
+	assert.ErrorIs(t, err, errInvalidFilter)
+
+	_, err = Unmarshal([]byte{1, 2, 3})
+	assert.ErrorIs(t, err, errInvalidFilter)
+}
+
+func TestBuildFromIndex(t *testing.T) {
+	dir := t.TempDir()
+	series := []labels.Labels{
+		labels.FromStrings("__name__", "foo", "pod", "pod-1"),
+		labels.FromStrings("__name__", "foo", "pod", "pod-2"),
+	}
+
+	id, err := testhelper.CreateBlock(context.Background(), dir, series, 10, 0, 100, labels.Labels{{Name: "ext1", Value: "1"}}, 0, metadata.NoneFunc)
+	require.NoError(t, err)
+	blockDir := filepath.Join(dir, id.String())
+
+	f, err := BuildFromIndex(filepath.Join(blockDir, block.IndexFilename), []string{"pod"}, DefaultFalsePositiveRate)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod"}, f.LabelNames())
+	assert.True(t, f.MayContain("pod", "pod-1"))
+	assert.True(t, f.MayContain("pod", "pod-2"))
+
+	require.NoError(t, WriteFile(blockDir, f))
+	data, err := os.ReadFile(filepath.Join(blockDir, FilterFilename))
+	require.NoError(t, err)
+
+	decoded, err := Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, f, decoded)
+}
@@ -44,6 +44,8 @@ type CachingBucket struct {
 	requestedGetRangeBytes *prometheus.CounterVec
 	fetchedGetRangeBytes   *prometheus.CounterVec
 	refetchedGetRangeBytes *prometheus.CounterVec
+	servedGetRangeBytes    *prometheus.CounterVec
+	bucketGetRangeRequests *prometheus.CounterVec
 
 	operationConfigs  map[string][]*operationConfig
 	operationRequests *prometheus.CounterVec
@@ -76,6 +78,14 @@ func NewCachingBucket(b objstore.Bucket, cfg *CachingBucketConfig, logger log.Lo
 			Name: "thanos_store_bucket_cache_getrange_refetched_bytes_total",
 			Help: "Total number of bytes re-fetched from storage because of GetRange operation, despite being in cache already.",
 		}, []string{"origin", "config"}),
+		servedGetRangeBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_served_bytes_total",
+			Help: "Total number of bytes returned by GetRange operation, by origin of the subranges the bytes are read from.",
+		}, []string{"origin", "config"}),
+		bucketGetRangeRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_bucket_requests_total",
+			Help: "Total number of GetRange requests issued to the storage, to fetch the subranges missing from the cache.",
+		}, []string{"config"}),
 
 		operationRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "thanos_store_bucket_cache_operation_requests_total",
@@ -97,6 +107,9 @@ func NewCachingBucket(b objstore.Bucket, cfg *CachingBucketConfig, logger log.Lo
 				cb.fetchedGetRangeBytes.WithLabelValues(originCache, n)
 				cb.fetchedGetRangeBytes.WithLabelValues(originBucket, n)
 				cb.refetchedGetRangeBytes.WithLabelValues(originCache, n)
+				cb.servedGetRangeBytes.WithLabelValues(originCache, n)
+				cb.servedGetRangeBytes.WithLabelValues(originBucket, n)
+				cb.bucketGetRangeRequests.WithLabelValues(n)
 			}
 		}
 	}
@@ -373,6 +386,15 @@ func (cb *CachingBucket) cachedGetRange(ctx context.Context, name string, offset
 	cb.fetchedGetRangeBytes.WithLabelValues(originCache, cfgName).Add(float64(totalCachedBytes))
 	cb.operationHits.WithLabelValues(objstore.OpGetRange, cfgName).Add(float64(len(hits)) / float64(len(keys)))
 
+	// Only the part of the subranges overlapping the requested range is served.
+	servedCachedBytes := int64(0)
+	for off := startRange; off < endRange; off += cfg.subrangeSize {
+		if _, ok := hits[offsetKeys[off]]; ok {
+			servedCachedBytes += overlapLength(off, off+cfg.subrangeSize, offset, offset+length)
+		}
+	}
+	cb.servedGetRangeBytes.WithLabelValues(originCache, cfgName).Add(float64(servedCachedBytes))
+
 	if len(hits) < len(keys) {
 		if hits == nil {
 			hits = map[string][]byte{}
@@ -382,6 +404,7 @@ func (cb *CachingBucket) cachedGetRange(ctx context.Context, name string, offset
 		if err != nil {
 			return nil, err
 		}
+		cb.servedGetRangeBytes.WithLabelValues(originBucket, cfgName).Add(float64(length - servedCachedBytes))
 	}
 
 	return io.NopCloser(newSubrangesReader(cfg.subrangeSize, offsetKeys, hits, offset, length)), nil
@@ -409,6 +432,8 @@ func (cb *CachingBucket) fetchMissingSubranges(ctx context.Context, name string,
 		missing = mergeRanges(missing, limit)
 	}
 
+	cb.bucketGetRangeRequests.WithLabelValues(cfgName).Add(float64(len(missing)))
+
 	var hitsMutex sync.Mutex
 
 	// Run parallel queries for each missing range. Fetched data is stored into 'hits' map, protected by hitsMutex.
@@ -465,6 +490,20 @@ func (cb *CachingBucket) fetchMissingSubranges(ctx context.Context, name string,
 	return g.Wait()
 }
 
+// overlapLength returns the length of the intersection of the ranges [start, end) and [otherStart, otherEnd).
+func overlapLength(start, end, otherStart, otherEnd int64) int64 {
+	if otherStart > start {
+		start = otherStart
+	}
+	if otherEnd < end {
+		end = otherEnd
+	}
+	if end < start {
+		return 0
+	}
+	return end - start
+}
+
 // Merges ranges that are close to each other. Modifies input.
 func mergeRanges(input []rng, limit int64) []rng {
 	if len(input) == 0 {
@@ -240,6 +240,52 @@ func TestChunksCaching(t *testing.T) {
 	}
 }
 
+func TestChunksCaching_ServedBytes(t *testing.T) {
+	length := int64(1024 * 1024)
+	subrangeSize := int64(16000)
+
+	data := make([]byte, length)
+	for ix := 0; ix < len(data); ix++ {
+		data[ix] = byte(ix)
+	}
+
+	name := "/test/chunks/000001"
+
+	inmem := objstore.NewInMemBucket()
+	assert.NoError(t, inmem.Upload(context.Background(), name, bytes.NewReader(data)))
+
+	cache := cache.NewMockCache()
+	cfg := NewCachingBucketConfig()
+	cfg.CacheGetRange("chunks", cache, isTSDBChunkFile, subrangeSize, cache, time.Hour, time.Hour, 0)
+
+	cachingBucket, err := NewCachingBucket(inmem, cfg, nil, nil)
+	assert.NoError(t, err)
+
+	verifyServedBytes := func(expectedCached, expectedBucket, expectedBucketRequests int64) {
+		t.Helper()
+		assert.Equal(t, expectedCached, int64(promtest.ToFloat64(cachingBucket.servedGetRangeBytes.WithLabelValues(originCache, "chunks"))))
+		assert.Equal(t, expectedBucket, int64(promtest.ToFloat64(cachingBucket.servedGetRangeBytes.WithLabelValues(originBucket, "chunks"))))
+		assert.Equal(t, expectedBucketRequests, int64(promtest.ToFloat64(cachingBucket.bucketGetRangeRequests.WithLabelValues("chunks"))))
+	}
+
+	// The subranges [544000, 624000) are fetched from the bucket, in a single request.
+	verifyGetRange(t, cachingBucket, name, 555555, 55555, 55555)
+	verifyServedBytes(0, 55555, 1)
+
+	// The same request is entirely served from the cache.
+	verifyGetRange(t, cachingBucket, name, 555555, 55555, 55555)
+	verifyServedBytes(55555, 55555, 1)
+
+	// Only the subrange missing from the cache is fetched from the bucket.
+	cache.Delete(cachingKeyObjectSubrange(name, 576000, 592000))
+	verifyGetRange(t, cachingBucket, name, 555555, 55555, 55555)
+	verifyServedBytes(55555+55555-subrangeSize, 55555+subrangeSize, 2)
+
+	// An overlapping request only fetches the subranges not cached yet.
+	verifyGetRange(t, cachingBucket, name, 600000, 30000, 30000)
+	verifyServedBytes(55555+55555-subrangeSize+24000, 55555+subrangeSize+6000, 3)
+}
+
 func verifyGetRange(t *testing.T, cachingBucket *CachingBucket, name string, offset, length, expectedLength int64) {
 	r, err := cachingBucket.GetRange(context.Background(), name, offset, length)
 	assert.NoError(t, err)
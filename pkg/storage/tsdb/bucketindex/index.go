@@ -25,6 +25,7 @@ const (
 	IndexCompressedFilename = IndexFilename + ".gz"
 	IndexVersion1           = 1
 	IndexVersion2           = 2 // Added CompactorShardID field.
+	IndexVersion3           = 3 // Added SizeBytes and CompactionLevel fields.
 	SegmentsFormatUnknown   = ""
 
 	// SegmentsFormat1Based6Digits defined segments numbered with 6 digits numbers in a sequence starting from number 1
@@ -52,6 +53,48 @@ func (idx *Index) GetUpdatedAt() time.Time {
 	return time.Unix(idx.UpdatedAt, 0)
 }
 
+// Stats holds the statistics of the blocks in the index.
+type Stats struct {
+	// Number of blocks, including the blocks marked for deletion.
+	Blocks int
+
+	// Number of blocks marked for deletion.
+	BlocksMarkedForDeletion int
+
+	// Total size of the blocks, including the blocks marked for deletion.
+	BlocksBytes int64
+
+	// Number of blocks by compaction level.
+	BlocksByCompactionLevel map[int]int
+
+	// MinTime of the oldest block and MaxTime of the newest block (millis precision). Both are 0 if there are no blocks.
+	MinTime int64
+	MaxTime int64
+}
+
+// Stats returns the statistics of the blocks in the index.
+func (idx *Index) Stats() Stats {
+	stats := Stats{
+		Blocks:                  len(idx.Blocks),
+		BlocksMarkedForDeletion: len(idx.BlockDeletionMarks),
+		BlocksByCompactionLevel: map[int]int{},
+	}
+
+	for ix, b := range idx.Blocks {
+		stats.BlocksBytes += b.SizeBytes
+		stats.BlocksByCompactionLevel[b.CompactionLevel]++
+
+		if ix == 0 || b.MinTime < stats.MinTime {
+			stats.MinTime = b.MinTime
+		}
+		if ix == 0 || b.MaxTime > stats.MaxTime {
+			stats.MaxTime = b.MaxTime
+		}
+	}
+
+	return stats
+}
+
 // RemoveBlock removes block and its deletion mark (if any) from index.
 func (idx *Index) RemoveBlock(id ulid.ULID) {
 	for i := 0; i < len(idx.Blocks); i++ {
@@ -91,6 +134,20 @@ type Block struct {
 
 	// Block's compactor shard ID, copied from tsdb.CompactorShardIDExternalLabel label.
 	CompactorShardID string `json:"compactor_shard_id,omitempty"`
+
+	// SizeBytes is the total size of the block files listed in the meta.json. It's 0 if the meta.json
+	// doesn't list the files with their size.
+	SizeBytes int64 `json:"size_bytes,omitempty"`
+
+	// CompactionLevel is the compaction level of the block, copied from the meta.json.
+	CompactionLevel int `json:"compaction_level,omitempty"`
+
+	// Resolution is the downsampling resolution of the block in milliseconds, copied from the meta.json.
+	// It's 0 for the raw blocks.
+	Resolution int64 `json:"resolution,omitempty"`
+
+	// ColdStorage is true if the block has been moved by the compactor to the cold storage.
+	ColdStorage bool `json:"cold_storage,omitempty"`
 }
 
 // Within returns whether the block contains samples within the provided range.
@@ -118,6 +175,7 @@ func (m *Block) ThanosMeta() *metadata.Meta {
 		Thanos: metadata.Thanos{
 			Version:      metadata.ThanosVersion1,
 			SegmentFiles: m.thanosMetaSegmentFiles(),
+			Downsample:   metadata.ThanosDownsample{Resolution: m.Resolution},
 		},
 	}
 }
@@ -147,6 +205,11 @@ func (m *Block) String() string {
 func BlockFromThanosMeta(meta metadata.Meta) *Block {
 	segmentsFormat, segmentsNum := detectBlockSegmentsFormat(meta)
 
+	sizeBytes := int64(0)
+	for _, f := range meta.Thanos.Files {
+		sizeBytes += f.SizeBytes
+	}
+
 	return &Block{
 		ID:               meta.ULID,
 		MinTime:          meta.MinTime,
@@ -154,6 +217,9 @@ func BlockFromThanosMeta(meta metadata.Meta) *Block {
 		SegmentsFormat:   segmentsFormat,
 		SegmentsNum:      segmentsNum,
 		CompactorShardID: meta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+		SizeBytes:        sizeBytes,
+		CompactionLevel:  meta.Compaction.Level,
+		Resolution:       meta.Thanos.Downsample.Resolution,
 	}
 }
 
@@ -208,6 +208,50 @@ func TestBlockFromThanosMeta(t *testing.T) {
 				SegmentsNum:    3,
 			},
 		},
+		"meta.json with Files sizes and compaction level": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:       blockID,
+					MinTime:    10,
+					MaxTime:    20,
+					Compaction: tsdb.BlockMetaCompaction{Level: 3},
+				},
+				Thanos: metadata.Thanos{
+					Files: []metadata.File{
+						{RelPath: "index", SizeBytes: 100},
+						{RelPath: "chunks/000001", SizeBytes: 1000},
+						{RelPath: "meta.json"},
+					},
+				},
+			},
+			expected: Block{
+				ID:              blockID,
+				MinTime:         10,
+				MaxTime:         20,
+				SegmentsFormat:  SegmentsFormat1Based6Digits,
+				SegmentsNum:     1,
+				SizeBytes:       1100,
+				CompactionLevel: 3,
+			},
+		},
+		"meta.json of a downsampled block": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+				},
+				Thanos: metadata.Thanos{
+					Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				},
+			},
+			expected: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 300000,
+			},
+		},
 		"meta.json with external labels, no compactor shard ID": {
 			meta: metadata.Meta{
 				BlockMeta: tsdb.BlockMeta{
@@ -378,6 +422,26 @@ func TestBlock_ThanosMeta(t *testing.T) {
 				},
 			},
 		},
+		"downsampled block": {
+			block: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 300000,
+			},
+			expected: &metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+					Version: metadata.TSDBVersion1,
+				},
+				Thanos: metadata.Thanos{
+					Version:    metadata.ThanosVersion1,
+					Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
@@ -411,3 +475,32 @@ func TestBlockDeletionMarks_Clone(t *testing.T) {
 	orig[0].DeletionTime = -1
 	assert.Equal(t, int64(1), clone[0].DeletionTime)
 }
+
+func TestIndex_Stats(t *testing.T) {
+	t.Run("empty index", func(t *testing.T) {
+		idx := &Index{}
+		assert.Equal(t, Stats{BlocksByCompactionLevel: map[int]int{}}, idx.Stats())
+	})
+
+	t.Run("index with blocks", func(t *testing.T) {
+		idx := &Index{
+			Blocks: Blocks{
+				{ID: ulid.MustNew(1, nil), MinTime: 20, MaxTime: 30, SizeBytes: 100, CompactionLevel: 1},
+				{ID: ulid.MustNew(2, nil), MinTime: 10, MaxTime: 20, SizeBytes: 200, CompactionLevel: 2},
+				{ID: ulid.MustNew(3, nil), MinTime: 30, MaxTime: 40, SizeBytes: 300, CompactionLevel: 1},
+			},
+			BlockDeletionMarks: BlockDeletionMarks{
+				{ID: ulid.MustNew(2, nil)},
+			},
+		}
+
+		assert.Equal(t, Stats{
+			Blocks:                  3,
+			BlocksMarkedForDeletion: 1,
+			BlocksBytes:             600,
+			BlocksByCompactionLevel: map[int]int{1: 2, 2: 1},
+			MinTime:                 10,
+			MaxTime:                 40,
+		}, idx.Stats())
+	})
+}
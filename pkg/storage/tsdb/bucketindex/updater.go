@@ -52,7 +52,7 @@ func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid
 	var oldBlockDeletionMarks []*BlockDeletionMark
 
 	// Use the old index if provided, and it is using the latest version format.
-	if old != nil && old.Version == IndexVersion2 {
+	if old != nil && old.Version == IndexVersion3 {
 		oldBlocks = old.Blocks
 		oldBlockDeletionMarks = old.BlockDeletionMarks
 	}
@@ -68,7 +68,7 @@ func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid
 	}
 
 	return &Index{
-		Version:            IndexVersion2,
+		Version:            IndexVersion3,
 		Blocks:             blocks,
 		BlockDeletionMarks: blockDeletionMarks,
 		UpdatedAt:          time.Now().Unix(),
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"flag"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+var errInvalidColdStorageMaxConcurrentReads = errors.New("invalid cold storage max concurrent reads, must be greater than or equal to 0")
+
+// ColdStorageConfig holds the config of the cold storage, which the compactor archives the old blocks to.
+type ColdStorageConfig struct {
+	Enabled            bool          `yaml:"enabled" category:"experimental"`
+	MaxConcurrentReads int           `yaml:"max_concurrent_reads" category:"experimental"`
+	Storage            bucket.Config `yaml:"storage"`
+}
+
+// RegisterFlags registers the ColdStorageConfig flags.
+func (cfg *ColdStorageConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "blocks-storage.cold-storage.enabled", false, "Enables the cold storage, which the compactor archives the blocks older than -compactor.cold-storage-archive-after to. The queriers and store-gateways read the blocks from both the blocks storage and the cold storage.")
+	f.IntVar(&cfg.MaxConcurrentReads, "blocks-storage.cold-storage.max-concurrent-reads", 0, "Maximum number of concurrent reads from the cold storage for each querier, store-gateway and compactor. 0 to disable the limit.")
+	cfg.Storage.RegisterFlagsWithPrefixAndDefaultDirectory("blocks-storage.cold-storage.storage.", "cold-blocks", f)
+}
+
+// Validate the config.
+func (cfg *ColdStorageConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MaxConcurrentReads < 0 {
+		return errInvalidColdStorageMaxConcurrentReads
+	}
+	return cfg.Storage.Validate()
+}
+
+// NewBucketClient creates a new client of the blocks storage bucket. If the cold storage is enabled, the client
+// also reads the blocks archived to the cold storage, whose operations are tracked with the name suffixed by
+// "-cold-storage".
+func NewBucketClient(ctx context.Context, cfg BlocksStorageConfig, name string, logger log.Logger, reg prometheus.Registerer) (objstore.InstrumentedBucket, error) {
+	hot, err := bucket.NewClient(ctx, cfg.Bucket, name, logger, reg)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.ColdStorage.Enabled {
+		return hot, nil
+	}
+
+	cold, err := bucket.NewClient(ctx, cfg.ColdStorage.Storage, name+"-cold-storage", logger, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create cold storage bucket client")
+	}
+	return bucket.NewTieredBucketClient(hot, cold, cfg.ColdStorage.MaxConcurrentReads), nil
+}
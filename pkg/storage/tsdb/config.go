@@ -75,6 +75,11 @@ var (
 	errInvalidWALSegmentSizeBytes   = errors.New("invalid TSDB WAL segment size bytes")
 	errInvalidStripeSize            = errors.New("invalid TSDB stripe size")
 	errEmptyBlockranges             = errors.New("empty block ranges for TSDB")
+
+	errInvalidMemorySnapshotInterval                   = errors.New("invalid TSDB memory snapshot interval")
+	errMemorySnapshotIntervalWithoutSnapshotOnShutdown = errors.New("TSDB periodic memory snapshots require the memory snapshot on shutdown to be enabled")
+	errInvalidHeadChunksRetention                      = errors.New("invalid TSDB head chunks retention")
+	errInvalidDiskPressureThreshold                    = errors.New("invalid TSDB disk pressure threshold, must be between 0 and 1")
 )
 
 // BlocksStorageConfig holds the config information for the blocks storage.
@@ -84,6 +89,7 @@ type BlocksStorageConfig struct {
 	Bucket      bucket.Config     `yaml:",inline"`
 	BucketStore BucketStoreConfig `yaml:"bucket_store" doc:"description=This configures how the querier and store-gateway discover and synchronize blocks stored in the bucket."`
 	TSDB        TSDBConfig        `yaml:"tsdb"`
+	ColdStorage ColdStorageConfig `yaml:"cold_storage"`
 }
 
 // DurationList is the block ranges for a tsdb
@@ -128,6 +134,7 @@ func (cfg *BlocksStorageConfig) RegisterFlags(f *flag.FlagSet) {
 	cfg.Bucket.RegisterFlagsWithPrefixAndDefaultDirectory("blocks-storage.", "blocks", f)
 	cfg.BucketStore.RegisterFlags(f)
 	cfg.TSDB.RegisterFlags(f)
+	cfg.ColdStorage.RegisterFlags(f)
 }
 
 // Validate the config.
@@ -140,6 +147,10 @@ func (cfg *BlocksStorageConfig) Validate() error {
 		return err
 	}
 
+	if err := cfg.ColdStorage.Validate(); err != nil {
+		return err
+	}
+
 	return cfg.BucketStore.Validate()
 }
 
@@ -163,7 +174,10 @@ type TSDBConfig struct {
 	FlushBlocksOnShutdown     bool          `yaml:"flush_blocks_on_shutdown" category:"advanced"`
 	CloseIdleTSDBTimeout      time.Duration `yaml:"close_idle_tsdb_timeout" category:"advanced"`
 	MemorySnapshotOnShutdown  bool          `yaml:"memory_snapshot_on_shutdown" category:"experimental"`
+	MemorySnapshotInterval    time.Duration `yaml:"memory_snapshot_interval" category:"experimental"`
 	HeadChunksWriteQueueSize  int           `yaml:"head_chunks_write_queue_size" category:"advanced"`
+	HeadChunksRetention       time.Duration `yaml:"head_chunks_retention" category:"experimental"`
+	DiskPressureThreshold     float64       `yaml:"disk_pressure_threshold" category:"experimental"`
 
 	// Series hash cache.
 	SeriesHashCacheMaxBytes uint64 `yaml:"series_hash_cache_max_size_bytes" category:"advanced"`
@@ -207,7 +221,10 @@ func (cfg *TSDBConfig) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&cfg.FlushBlocksOnShutdown, "blocks-storage.tsdb.flush-blocks-on-shutdown", false, "True to flush blocks to storage on shutdown. If false, incomplete blocks will be reused after restart.")
 	f.DurationVar(&cfg.CloseIdleTSDBTimeout, "blocks-storage.tsdb.close-idle-tsdb-timeout", 13*time.Hour, "If TSDB has not received any data for this duration, and all blocks from TSDB have been shipped, TSDB is closed and deleted from local disk. If set to positive value, this value should be equal or higher than -querier.query-ingesters-within flag to make sure that TSDB is not closed prematurely, which could cause partial query results. 0 or negative value disables closing of idle TSDB.")
 	f.BoolVar(&cfg.MemorySnapshotOnShutdown, "blocks-storage.tsdb.memory-snapshot-on-shutdown", false, "True to enable snapshotting of in-memory TSDB data on disk when shutting down.")
+	f.DurationVar(&cfg.MemorySnapshotInterval, "blocks-storage.tsdb.memory-snapshot-interval", 0, "How frequently the in-memory TSDB data is snapshotted on disk while running, so that at startup, even after a crash, only the WAL written after the last snapshot is replayed. Requires -blocks-storage.tsdb.memory-snapshot-on-shutdown to be enabled. 0 to disable.")
 	f.IntVar(&cfg.HeadChunksWriteQueueSize, "blocks-storage.tsdb.head-chunks-write-queue-size", 1000000, "The size of the write queue used by the head chunks mapper. Lower values reduce memory utilisation at the cost of potentially higher ingest latency. Value of 0 switches chunks mapper to implementation without a queue.")
+	f.DurationVar(&cfg.HeadChunksRetention, "blocks-storage.tsdb.head-chunks-retention", 0, "Maximum time range of the samples kept in the TSDB head, and so of their memory-mapped chunks on disk. When the head spans a longer time range, the block aligned time ranges older than the retention are compacted to blocks at the next head compaction, truncating their head chunks and WAL. The samples are kept in the head for at least half of the block range, like in the regular head compaction. 0 to keep the samples in the head until the regular head compaction.")
+	f.Float64Var(&cfg.DiskPressureThreshold, "blocks-storage.tsdb.disk-pressure-threshold", 0, "Fraction of the capacity of the TSDB volume, between 0 and 1, above which the ingester is under disk pressure. While under disk pressure, the TSDB blocks already shipped to the storage are deleted, regardless of the retention, to free up disk space before the volume is full. 0 to disable.")
 	f.IntVar(&cfg.OutOfOrderCapacityMin, "blocks-storage.tsdb.out-of-order-capacity-min", 4, "Minimum capacity for out-of-order chunks, in samples between 0 and 255.")
 	f.IntVar(&cfg.OutOfOrderCapacityMax, "blocks-storage.tsdb.out-of-order-capacity-max", 32, "Maximum capacity for out of order chunks, in samples between 1 and 255.")
 }
@@ -246,6 +263,22 @@ func (cfg *TSDBConfig) Validate() error {
 		return errInvalidWALSegmentSizeBytes
 	}
 
+	if cfg.MemorySnapshotInterval < 0 {
+		return errInvalidMemorySnapshotInterval
+	}
+
+	if cfg.MemorySnapshotInterval > 0 && !cfg.MemorySnapshotOnShutdown {
+		return errMemorySnapshotIntervalWithoutSnapshotOnShutdown
+	}
+
+	if cfg.HeadChunksRetention < 0 {
+		return errInvalidHeadChunksRetention
+	}
+
+	if cfg.DiskPressureThreshold < 0 || cfg.DiskPressureThreshold > 1 {
+		return errInvalidDiskPressureThreshold
+	}
+
 	return nil
 }
 
@@ -267,6 +300,7 @@ type BucketStoreConfig struct {
 	MaxConcurrent            int                 `yaml:"max_concurrent" category:"advanced"`
 	TenantSyncConcurrency    int                 `yaml:"tenant_sync_concurrency" category:"advanced"`
 	BlockSyncConcurrency     int                 `yaml:"block_sync_concurrency" category:"advanced"`
+	BlockLoadingConcurrency  int                 `yaml:"block_loading_concurrency" category:"experimental"`
 	MetaSyncConcurrency      int                 `yaml:"meta_sync_concurrency" category:"advanced"`
 	ConsistencyDelay         time.Duration       `yaml:"consistency_delay" category:"advanced"`
 	IndexCache               IndexCacheConfig    `yaml:"index_cache"`
@@ -288,6 +322,10 @@ type BucketStoreConfig struct {
 	IndexHeaderLazyLoadingEnabled     bool          `yaml:"index_header_lazy_loading_enabled" category:"advanced"`
 	IndexHeaderLazyLoadingIdleTimeout time.Duration `yaml:"index_header_lazy_loading_idle_timeout" category:"advanced"`
 
+	// Controls the eviction of the lazy loaded index-headers, on top of the idle timeout.
+	IndexHeaderLazyLoadingMaxLoaded      int    `yaml:"index_header_lazy_loading_max_loaded" category:"experimental"`
+	IndexHeaderLazyLoadingMaxLoadedBytes uint64 `yaml:"index_header_lazy_loading_max_loaded_bytes" category:"experimental"`
+
 	// Controls the partitioner, used to aggregate multiple GET object API requests.
 	PartitionerMaxGapBytes uint64 `yaml:"partitioner_max_gap_bytes" category:"advanced"`
 
@@ -300,6 +338,9 @@ type BucketStoreConfig struct {
 
 	// Controls experimental options for index-header file reading.
 	IndexHeader indexheader.BinaryReaderConfig `yaml:"index_header" category:"experimental"`
+
+	// Controls whether per-block label values bloom filters, built by the compactor, are used to skip blocks.
+	BloomFilterEnabled bool `yaml:"bloom_filter_enabled" category:"experimental"`
 }
 
 // RegisterFlags registers the BucketStore flags
@@ -319,6 +360,7 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&cfg.MaxConcurrent, "blocks-storage.bucket-store.max-concurrent", 100, "Max number of concurrent queries to execute against the long-term storage. The limit is shared across all tenants.")
 	f.IntVar(&cfg.TenantSyncConcurrency, "blocks-storage.bucket-store.tenant-sync-concurrency", 10, "Maximum number of concurrent tenants synching blocks.")
 	f.IntVar(&cfg.BlockSyncConcurrency, "blocks-storage.bucket-store.block-sync-concurrency", 20, "Maximum number of concurrent blocks synching per tenant.")
+	f.IntVar(&cfg.BlockLoadingConcurrency, "blocks-storage.bucket-store.block-loading-concurrency", 0, "Maximum number of concurrent blocks loading across all tenants. When the limit is reached, the blocks with the most recent samples are loaded first. 0 to disable the limit, in which case the blocks are loaded with up to -blocks-storage.bucket-store.block-sync-concurrency blocks per tenant.")
 	f.IntVar(&cfg.MetaSyncConcurrency, "blocks-storage.bucket-store.meta-sync-concurrency", 20, "Number of Go routines to use when syncing block meta files from object storage per tenant.")
 	f.DurationVar(&cfg.ConsistencyDelay, "blocks-storage.bucket-store.consistency-delay", 0, "Minimum age of a block before it's being read. Set it to safe value (e.g 30m) if your object storage is eventually consistent. GCS and S3 are (roughly) strongly consistent.")
 	f.DurationVar(&cfg.IgnoreDeletionMarksDelay, "blocks-storage.bucket-store.ignore-deletion-marks-delay", time.Hour*1, "Duration after which the blocks marked for deletion will be filtered out while fetching blocks. "+
@@ -327,7 +369,10 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&cfg.PostingOffsetsInMemSampling, "blocks-storage.bucket-store.posting-offsets-in-mem-sampling", DefaultPostingOffsetInMemorySampling, "Controls what is the ratio of postings offsets that the store will hold in memory.")
 	f.BoolVar(&cfg.IndexHeaderLazyLoadingEnabled, "blocks-storage.bucket-store.index-header-lazy-loading-enabled", true, "If enabled, store-gateway will lazy load an index-header only once required by a query.")
 	f.DurationVar(&cfg.IndexHeaderLazyLoadingIdleTimeout, "blocks-storage.bucket-store.index-header-lazy-loading-idle-timeout", 60*time.Minute, "If index-header lazy loading is enabled and this setting is > 0, the store-gateway will offload unused index-headers after 'idle timeout' inactivity.")
+	f.IntVar(&cfg.IndexHeaderLazyLoadingMaxLoaded, "blocks-storage.bucket-store.index-header-lazy-loading-max-loaded", 0, "If index-header lazy loading is enabled and this setting is > 0, the store-gateway will offload the least recently used index-headers when more index-headers than this are loaded across all tenants. The index-headers are offloaded from the tenant with the most loaded index-headers first.")
+	f.Uint64Var(&cfg.IndexHeaderLazyLoadingMaxLoadedBytes, "blocks-storage.bucket-store.index-header-lazy-loading-max-loaded-bytes", 0, "If index-header lazy loading is enabled and this setting is > 0, the store-gateway will offload the least recently used index-headers when the size in bytes of the loaded index-headers across all tenants exceeds it. The index-headers are offloaded from the tenant with the largest loaded index-headers first.")
 	f.Uint64Var(&cfg.PartitionerMaxGapBytes, "blocks-storage.bucket-store.partitioner-max-gap-bytes", DefaultPartitionerMaxGapSize, "Max size - in bytes - of a gap for which the partitioner aggregates together two bucket GET object requests.")
+	f.BoolVar(&cfg.BloomFilterEnabled, "blocks-storage.bucket-store.bloom-filter-enabled", false, "If enabled, store-gateway loads the label values bloom filter of each block, if built by the compactor, and skips blocks that cannot match the equality matchers of a query.")
 }
 
 // Validate the config.
@@ -120,6 +120,65 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expectedErr: errInvalidWALSegmentSizeBytes,
 		},
+		"should fail on negative TSDB memory snapshot interval": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.MemorySnapshotOnShutdown = true
+				cfg.TSDB.MemorySnapshotInterval = -time.Minute
+			},
+			expectedErr: errInvalidMemorySnapshotInterval,
+		},
+		"should fail on TSDB memory snapshot interval without memory snapshot on shutdown": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.MemorySnapshotInterval = time.Minute
+			},
+			expectedErr: errMemorySnapshotIntervalWithoutSnapshotOnShutdown,
+		},
+		"should pass on TSDB memory snapshot interval with memory snapshot on shutdown": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.MemorySnapshotOnShutdown = true
+				cfg.TSDB.MemorySnapshotInterval = time.Minute
+			},
+			expectedErr: nil,
+		},
+		"should fail on negative TSDB head chunks retention": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.HeadChunksRetention = -time.Minute
+			},
+			expectedErr: errInvalidHeadChunksRetention,
+		},
+		"should fail on TSDB disk pressure threshold greater than 1": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.DiskPressureThreshold = 1.1
+			},
+			expectedErr: errInvalidDiskPressureThreshold,
+		},
+		"should pass on valid TSDB head chunks retention and disk pressure threshold": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.HeadChunksRetention = time.Hour
+				cfg.TSDB.DiskPressureThreshold = 0.9
+			},
+			expectedErr: nil,
+		},
+		"should pass on invalid cold storage config but cold storage is disabled": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.ColdStorage.Storage.Backend = "unknown"
+			},
+			expectedErr: nil,
+		},
+		"should fail on unknown cold storage backend": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.ColdStorage.Enabled = true
+				cfg.ColdStorage.Storage.Backend = "unknown"
+			},
+			expectedErr: bucket.ErrUnsupportedStorageBackend,
+		},
+		"should fail on negative cold storage max concurrent reads": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.ColdStorage.Enabled = true
+				cfg.ColdStorage.MaxConcurrentReads = -1
+			},
+			expectedErr: errInvalidColdStorageMaxConcurrentReads,
+		},
 	}
 
 	for testName, testData := range tests {
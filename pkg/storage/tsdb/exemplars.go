@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// ExemplarsFilename is the name of the file, stored in the block directory alongside the index,
+	// containing the exemplars of the series of the block.
+	ExemplarsFilename = "exemplars.json"
+
+	// ExemplarsVersion1 is the first version of the exemplars file format.
+	ExemplarsVersion1 = 1
+)
+
+// ExemplarsFile is the content of the exemplars file of a block.
+type ExemplarsFile struct {
+	Version int                   `json:"version"`
+	Series  []ExemplarsFileSeries `json:"series"`
+}
+
+// ExemplarsFileSeries are the exemplars of a series in the exemplars file.
+type ExemplarsFileSeries struct {
+	Labels    labels.Labels  `json:"labels"`
+	Exemplars []ExemplarFile `json:"exemplars"`
+}
+
+// ExemplarFile is an exemplar in the exemplars file. The value is encoded as a string, like in the
+// Prometheus HTTP API, since JSON doesn't support the special float values.
+type ExemplarFile struct {
+	Labels      labels.Labels `json:"labels"`
+	Value       string        `json:"value"`
+	TimestampMs int64         `json:"timestamp"`
+}
+
+// WriteExemplarsFile writes the exemplars file, with the exemplars of the input series, to the block directory.
+func WriteExemplarsFile(blockDir string, series []mimirpb.TimeSeries) error {
+	file := ExemplarsFile{Version: ExemplarsVersion1}
+	for _, s := range MergeExemplars(0, series) {
+		fs := ExemplarsFileSeries{
+			Labels:    mimirpb.FromLabelAdaptersToLabels(s.Labels),
+			Exemplars: make([]ExemplarFile, 0, len(s.Exemplars)),
+		}
+		for _, e := range s.Exemplars {
+			fs.Exemplars = append(fs.Exemplars, ExemplarFile{
+				Labels:      mimirpb.FromLabelAdaptersToLabels(e.Labels),
+				Value:       strconv.FormatFloat(e.Value, 'f', -1, 64),
+				TimestampMs: e.TimestampMs,
+			})
+		}
+		file.Series = append(file.Series, fs)
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return errors.Wrap(err, "encode exemplars file")
+	}
+	return os.WriteFile(filepath.Join(blockDir, ExemplarsFilename), data, 0644)
+}
+
+// ReadExemplarsFile reads the exemplars file from the block directory. It returns no exemplars
+// if the block has no exemplars file.
+func ReadExemplarsFile(blockDir string) ([]mimirpb.TimeSeries, error) {
+	data, err := os.ReadFile(filepath.Join(blockDir, ExemplarsFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read exemplars file")
+	}
+	return DecodeExemplarsFile(data)
+}
+
+// DecodeExemplarsFile decodes the content of an exemplars file.
+func DecodeExemplarsFile(data []byte) ([]mimirpb.TimeSeries, error) {
+	var file ExemplarsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "decode exemplars file")
+	}
+	if file.Version != ExemplarsVersion1 {
+		return nil, errors.Errorf("unsupported exemplars file version %d", file.Version)
+	}
+
+	series := make([]mimirpb.TimeSeries, 0, len(file.Series))
+	for _, fs := range file.Series {
+		s := mimirpb.TimeSeries{
+			Labels:    mimirpb.FromLabelsToLabelAdapters(fs.Labels),
+			Exemplars: make([]mimirpb.Exemplar, 0, len(fs.Exemplars)),
+		}
+		for _, e := range fs.Exemplars {
+			value, err := strconv.ParseFloat(e.Value, 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "decode exemplar value")
+			}
+			s.Exemplars = append(s.Exemplars, mimirpb.Exemplar{
+				Labels:      mimirpb.FromLabelsToLabelAdapters(e.Labels),
+				Value:       value,
+				TimestampMs: e.TimestampMs,
+			})
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+// MergeExemplars returns the exemplars of the input sets merged by series, sorted by series labels, with the exemplars
+// of each series deduplicated by timestamp and sorted by timestamp. If limit is greater than 0, at most limit
+// exemplars are returned, keeping the most recent ones.
+func MergeExemplars(limit int, sets ...[]mimirpb.TimeSeries) []mimirpb.TimeSeries {
+	type seriesExemplar struct {
+		key      string
+		exemplar mimirpb.Exemplar
+	}
+
+	seriesLabels := map[string][]mimirpb.LabelAdapter{}
+	seen := map[string]map[int64]struct{}{}
+	var all []seriesExemplar
+	for _, set := range sets {
+		for _, s := range set {
+			key := mimirpb.FromLabelAdaptersToLabels(s.Labels).String()
+			if _, ok := seriesLabels[key]; !ok {
+				seriesLabels[key] = s.Labels
+				seen[key] = map[int64]struct{}{}
+			}
+			for _, e := range s.Exemplars {
+				if _, ok := seen[key][e.TimestampMs]; ok {
+					continue
+				}
+				seen[key][e.TimestampMs] = struct{}{}
+				all = append(all, seriesExemplar{key: key, exemplar: e})
+			}
+		}
+	}
+
+	// Keep the most recent exemplars, and the first series by labels among the exemplars with the same timestamp.
+	if limit > 0 && len(all) > limit {
+		sort.Slice(all, func(i, j int) bool {
+			if all[i].exemplar.TimestampMs != all[j].exemplar.TimestampMs {
+				return all[i].exemplar.TimestampMs > all[j].exemplar.TimestampMs
+			}
+			return all[i].key < all[j].key
+		})
+		all = all[:limit]
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].key != all[j].key {
+			return all[i].key < all[j].key
+		}
+		return all[i].exemplar.TimestampMs < all[j].exemplar.TimestampMs
+	})
+
+	var merged []mimirpb.TimeSeries
+	for ix, se := range all {
+		if ix == 0 || all[ix-1].key != se.key {
+			merged = append(merged, mimirpb.TimeSeries{Labels: seriesLabels[se.key]})
+		}
+		last := &merged[len(merged)-1]
+		last.Exemplars = append(last.Exemplars, se.exemplar)
+	}
+	return merged
+}
+
+// FilterExemplars returns the exemplars of the series matching any of the matchers sets, within the start and end
+// timestamps, both inclusive.
+func FilterExemplars(series []mimirpb.TimeSeries, start, end int64, matchersSets ...[]*labels.Matcher) []mimirpb.TimeSeries {
+	var filtered []mimirpb.TimeSeries
+	for _, s := range series {
+		lbls := mimirpb.FromLabelAdaptersToLabels(s.Labels)
+		if !matchesAnySet(lbls, matchersSets) {
+			continue
+		}
+
+		var exemplars []mimirpb.Exemplar
+		for _, e := range s.Exemplars {
+			if e.TimestampMs >= start && e.TimestampMs <= end {
+				exemplars = append(exemplars, e)
+			}
+		}
+		if len(exemplars) > 0 {
+			filtered = append(filtered, mimirpb.TimeSeries{Labels: s.Labels, Exemplars: exemplars})
+		}
+	}
+	return filtered
+}
+
+func matchesAnySet(lbls labels.Labels, matchersSets [][]*labels.Matcher) bool {
+	for _, matchers := range matchersSets {
+		matches := true
+		for _, m := range matchers {
+			if !m.Matches(lbls.Get(m.Name)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
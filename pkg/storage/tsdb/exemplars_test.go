@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestExemplarsFile(t *testing.T) {
+	t.Run("should write and read back the exemplars", func(t *testing.T) {
+		dir := t.TempDir()
+		input := []mimirpb.TimeSeries{
+			exemplarsSeries("b", exemplarAt("2", 2, 20)),
+			exemplarsSeries("a", exemplarAt("3", math.NaN(), 30), exemplarAt("1", 1, 10)),
+		}
+
+		require.NoError(t, WriteExemplarsFile(dir, input))
+
+		actual, err := ReadExemplarsFile(dir)
+		require.NoError(t, err)
+		require.Len(t, actual, 2)
+		assert.Equal(t, exemplarsSeries("b", exemplarAt("2", 2, 20)), actual[1])
+
+		// NaN isn't equal to itself, so the values are compared one by one.
+		assert.Equal(t, []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "a"}}, actual[0].Labels)
+		require.Len(t, actual[0].Exemplars, 2)
+		assert.Equal(t, exemplarAt("1", 1, 10), actual[0].Exemplars[0])
+		assert.Equal(t, int64(30), actual[0].Exemplars[1].TimestampMs)
+		assert.True(t, math.IsNaN(actual[0].Exemplars[1].Value))
+	})
+
+	t.Run("should return no exemplars if the file doesn't exist", func(t *testing.T) {
+		actual, err := ReadExemplarsFile(t.TempDir())
+		require.NoError(t, err)
+		assert.Empty(t, actual)
+	})
+
+	t.Run("should fail on unsupported version", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ExemplarsFilename), []byte(`{"version":2,"series":[]}`), 0644))
+
+		_, err := ReadExemplarsFile(dir)
+		require.Error(t, err)
+	})
+}
+
+func TestMergeExemplars(t *testing.T) {
+	tests := map[string]struct {
+		limit    int
+		sets     [][]mimirpb.TimeSeries
+		expected []mimirpb.TimeSeries
+	}{
+		"no sets": {
+			expected: nil,
+		},
+		"should merge the series, and deduplicate and sort the exemplars": {
+			sets: [][]mimirpb.TimeSeries{
+				{exemplarsSeries("b", exemplarAt("2", 2, 20)), exemplarsSeries("a", exemplarAt("3", 3, 30))},
+				{exemplarsSeries("a", exemplarAt("1", 1, 10), exemplarAt("3", 3, 30))},
+			},
+			expected: []mimirpb.TimeSeries{
+				exemplarsSeries("a", exemplarAt("1", 1, 10), exemplarAt("3", 3, 30)),
+				exemplarsSeries("b", exemplarAt("2", 2, 20)),
+			},
+		},
+		"should keep the most recent exemplars up to the limit": {
+			limit: 2,
+			sets: [][]mimirpb.TimeSeries{
+				{exemplarsSeries("b", exemplarAt("2", 2, 20)), exemplarsSeries("a", exemplarAt("3", 3, 30))},
+				{exemplarsSeries("a", exemplarAt("1", 1, 10))},
+			},
+			expected: []mimirpb.TimeSeries{
+				exemplarsSeries("a", exemplarAt("3", 3, 30)),
+				exemplarsSeries("b", exemplarAt("2", 2, 20)),
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, MergeExemplars(testData.limit, testData.sets...))
+		})
+	}
+}
+
+func TestFilterExemplars(t *testing.T) {
+	series := []mimirpb.TimeSeries{
+		exemplarsSeries("a", exemplarAt("1", 1, 10), exemplarAt("2", 2, 20), exemplarAt("3", 3, 30)),
+		exemplarsSeries("b", exemplarAt("4", 4, 40)),
+		exemplarsSeries("c", exemplarAt("5", 5, 20)),
+	}
+
+	actual := FilterExemplars(series, 20, 40,
+		[]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")},
+		[]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "b")},
+	)
+	assert.Equal(t, []mimirpb.TimeSeries{
+		exemplarsSeries("a", exemplarAt("2", 2, 20), exemplarAt("3", 3, 30)),
+		exemplarsSeries("b", exemplarAt("4", 4, 40)),
+	}, actual)
+
+	assert.Empty(t, FilterExemplars(series, 50, 60, []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")}))
+}
+
+func exemplarsSeries(metricName string, exemplars ...mimirpb.Exemplar) mimirpb.TimeSeries {
+	return mimirpb.TimeSeries{
+		Labels:    []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: metricName}},
+		Exemplars: exemplars,
+	}
+}
+
+func exemplarAt(traceID string, value float64, ts int64) mimirpb.Exemplar {
+	return mimirpb.Exemplar{
+		Labels:      []mimirpb.LabelAdapter{{Name: "trace_id", Value: traceID}},
+		Value:       value,
+		TimestampMs: ts,
+	}
+}
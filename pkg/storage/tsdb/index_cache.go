@@ -29,6 +29,12 @@ const (
 	// IndexCacheBackendMemcached is the value for the memcached index cache backend.
 	IndexCacheBackendMemcached = cache.BackendMemcached
 
+	// IndexCacheBackendRedis is the value for the redis index cache backend.
+	IndexCacheBackendRedis = cache.BackendRedis
+
+	// IndexCacheBackendDisk is the value for the disk index cache backend.
+	IndexCacheBackendDisk = "disk"
+
 	// IndexCacheBackendDefault is the value for the default index cache backend.
 	IndexCacheBackendDefault = IndexCacheBackendInMemory
 
@@ -36,14 +42,16 @@ const (
 )
 
 var (
-	supportedIndexCacheBackends = []string{IndexCacheBackendInMemory, IndexCacheBackendMemcached}
+	supportedIndexCacheBackends = []string{IndexCacheBackendInMemory, IndexCacheBackendMemcached, IndexCacheBackendRedis, IndexCacheBackendDisk}
 
 	errUnsupportedIndexCacheBackend = errors.New("unsupported index cache backend")
+	errEmptyDiskIndexCacheDir       = errors.New("the disk index cache directory must be set")
 )
 
 type IndexCacheConfig struct {
 	cache.BackendConfig `yaml:",inline"`
 	InMemory            InMemoryIndexCacheConfig `yaml:"inmemory"`
+	Disk                DiskIndexCacheConfig     `yaml:"disk"`
 }
 
 func (cfg *IndexCacheConfig) RegisterFlags(f *flag.FlagSet) {
@@ -54,7 +62,9 @@ func (cfg *IndexCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix str
 	f.StringVar(&cfg.Backend, prefix+"backend", IndexCacheBackendDefault, fmt.Sprintf("The index cache backend type. Supported values: %s.", strings.Join(supportedIndexCacheBackends, ", ")))
 
 	cfg.InMemory.RegisterFlagsWithPrefix(f, prefix+"inmemory.")
+	cfg.Disk.RegisterFlagsWithPrefix(f, prefix+"disk.")
 	cfg.Memcached.RegisterFlagsWithPrefix(f, prefix+"memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(f, prefix+"redis.")
 }
 
 // Validate the config.
@@ -69,6 +79,16 @@ func (cfg *IndexCacheConfig) Validate() error {
 		}
 	}
 
+	if cfg.Backend == IndexCacheBackendRedis {
+		if err := cfg.Redis.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Backend == IndexCacheBackendDisk && cfg.Disk.Dir == "" {
+		return errEmptyDiskIndexCacheDir
+	}
+
 	return nil
 }
 
@@ -80,6 +100,16 @@ func (cfg *InMemoryIndexCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, pr
 	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", uint64(1*units.Gibibyte), "Maximum size in bytes of in-memory index cache used to speed up blocks index lookups (shared between all tenants).")
 }
 
+type DiskIndexCacheConfig struct {
+	Dir          string `yaml:"dir" category:"experimental"`
+	MaxSizeBytes uint64 `yaml:"max_size_bytes" category:"experimental"`
+}
+
+func (cfg *DiskIndexCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.StringVar(&cfg.Dir, prefix+"dir", "./index-cache/", "Directory of the disk index cache. The cached items are kept across restarts, so the directory should be on a persistent volume, preferably on a local SSD.")
+	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", uint64(10*units.Gibibyte), "Maximum size in bytes of the disk index cache used to speed up blocks index lookups (shared between all tenants).")
+}
+
 // NewIndexCache creates a new index cache based on the input configuration.
 func NewIndexCache(cfg IndexCacheConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
 	switch cfg.Backend {
@@ -87,6 +117,10 @@ func NewIndexCache(cfg IndexCacheConfig, logger log.Logger, registerer prometheu
 		return newInMemoryIndexCache(cfg.InMemory, logger, registerer)
 	case IndexCacheBackendMemcached:
 		return newMemcachedIndexCache(cfg.Memcached, logger, registerer)
+	case IndexCacheBackendRedis:
+		return newRedisIndexCache(cfg.Redis, logger, registerer)
+	case IndexCacheBackendDisk:
+		return newDiskIndexCache(cfg.Disk, logger, registerer)
 	default:
 		return nil, errUnsupportedIndexCacheBackend
 	}
@@ -107,6 +141,27 @@ func newInMemoryIndexCache(cfg InMemoryIndexCacheConfig, logger log.Logger, regi
 	})
 }
 
+func newDiskIndexCache(cfg DiskIndexCacheConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
+	maxCacheSize := model.Bytes(cfg.MaxSizeBytes)
+
+	// Calculate the max item size.
+	maxItemSize := defaultMaxItemSize
+	if maxItemSize > maxCacheSize {
+		maxItemSize = maxCacheSize
+	}
+
+	cache, err := indexcache.NewDiskIndexCache(logger, registerer, indexcache.DiskIndexCacheConfig{
+		Dir:         cfg.Dir,
+		MaxSize:     maxCacheSize,
+		MaxItemSize: maxItemSize,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create disk-based index cache")
+	}
+
+	return indexcache.NewTracingIndexCache(cache, logger), nil
+}
+
 func newMemcachedIndexCache(cfg cache.MemcachedConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
 	client, err := cacheutil.NewMemcachedClientWithConfig(logger, "index-cache", cfg.ToMemcachedClientConfig(), registerer)
 	if err != nil {
@@ -120,3 +175,18 @@ func newMemcachedIndexCache(cfg cache.MemcachedConfig, logger log.Logger, regist
 
 	return indexcache.NewTracingIndexCache(cache, logger), nil
 }
+
+func newRedisIndexCache(cfg cache.RedisConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
+	client, err := cache.NewRedisClient(logger, "index-cache", cfg, registerer)
+	if err != nil {
+		return nil, errors.Wrap(err, "create index cache redis client")
+	}
+
+	// The memcached index cache works on top of any remote cache client.
+	cache, err := indexcache.NewMemcachedIndexCache(logger, client, registerer)
+	if err != nil {
+		return nil, errors.Wrap(err, "create redis-based index cache")
+	}
+
+	return indexcache.NewTracingIndexCache(cache, logger), nil
+}
@@ -51,6 +51,42 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 				},
 			},
 		},
+		"no redis addresses should fail": {
+			cfg: IndexCacheConfig{
+				BackendConfig: cache.BackendConfig{
+					Backend: IndexCacheBackendRedis,
+				},
+			},
+			expected: cache.ErrNoRedisAddresses,
+		},
+		"one redis address should pass": {
+			cfg: IndexCacheConfig{
+				BackendConfig: cache.BackendConfig{
+					Backend: IndexCacheBackendRedis,
+					Redis: cache.RedisConfig{
+						Addresses: "localhost:6379",
+					},
+				},
+			},
+		},
+		"no disk directory should fail": {
+			cfg: IndexCacheConfig{
+				BackendConfig: cache.BackendConfig{
+					Backend: IndexCacheBackendDisk,
+				},
+			},
+			expected: errEmptyDiskIndexCacheDir,
+		},
+		"disk directory should pass": {
+			cfg: IndexCacheConfig{
+				BackendConfig: cache.BackendConfig{
+					Backend: IndexCacheBackendDisk,
+				},
+				Disk: DiskIndexCacheConfig{
+					Dir: "./index-cache/",
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
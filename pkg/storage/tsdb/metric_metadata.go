@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// MetricMetadataFilename is the name of the file, stored in the block directory alongside the index,
+	// containing the metadata of the metrics of the block.
+	MetricMetadataFilename = "metric-metadata.json"
+
+	// MetricMetadataVersion1 is the first version of the metric metadata file format.
+	MetricMetadataVersion1 = 1
+)
+
+// MetricMetadataFile is the content of the metric metadata file of a block.
+type MetricMetadataFile struct {
+	Version  int                      `json:"version"`
+	Metadata []mimirpb.MetricMetadata `json:"metadata"`
+}
+
+// WriteMetricMetadataFile writes the metric metadata file, with the input metadata, to the block directory.
+func WriteMetricMetadataFile(blockDir string, metadata []mimirpb.MetricMetadata) error {
+	data, err := json.Marshal(MetricMetadataFile{
+		Version:  MetricMetadataVersion1,
+		Metadata: MergeMetricMetadata(0, metadata),
+	})
+	if err != nil {
+		return errors.Wrap(err, "encode metric metadata file")
+	}
+	return os.WriteFile(filepath.Join(blockDir, MetricMetadataFilename), data, 0644)
+}
+
+// ReadMetricMetadataFile reads the metric metadata file from the block directory. It returns no metadata
+// if the block has no metric metadata file.
+func ReadMetricMetadataFile(blockDir string) ([]mimirpb.MetricMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(blockDir, MetricMetadataFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read metric metadata file")
+	}
+	return DecodeMetricMetadataFile(data)
+}
+
+// DecodeMetricMetadataFile decodes the content of a metric metadata file.
+func DecodeMetricMetadataFile(data []byte) ([]mimirpb.MetricMetadata, error) {
+	var file MetricMetadataFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "decode metric metadata file")
+	}
+	if file.Version != MetricMetadataVersion1 {
+		return nil, errors.Errorf("unsupported metric metadata file version %d", file.Version)
+	}
+	return file.Metadata, nil
+}
+
+// MergeMetricMetadata returns the deduplicated metadata of the input sets, sorted by metric name. If limit is
+// greater than 0, at most limit metadata are returned, keeping the first ones by metric name.
+func MergeMetricMetadata(limit int, sets ...[]mimirpb.MetricMetadata) []mimirpb.MetricMetadata {
+	unique := map[mimirpb.MetricMetadata]struct{}{}
+	for _, set := range sets {
+		for _, m := range set {
+			unique[m] = struct{}{}
+		}
+	}
+
+	merged := make([]mimirpb.MetricMetadata, 0, len(unique))
+	for m := range unique {
+		merged = append(merged, m)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		switch {
+		case a.MetricFamilyName != b.MetricFamilyName:
+			return a.MetricFamilyName < b.MetricFamilyName
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.Help != b.Help:
+			return a.Help < b.Help
+		default:
+			return a.Unit < b.Unit
+		}
+	})
+
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestMetricMetadataFile(t *testing.T) {
+	t.Run("should write and read back the metadata", func(t *testing.T) {
+		dir := t.TempDir()
+		input := []mimirpb.MetricMetadata{
+			{Type: mimirpb.GAUGE, MetricFamilyName: "b", Help: "B gauge.", Unit: "bytes"},
+			{Type: mimirpb.COUNTER, MetricFamilyName: "a_total", Help: "A counter."},
+			{Type: mimirpb.GAUGE, MetricFamilyName: "b", Help: "B gauge.", Unit: "bytes"},
+		}
+
+		require.NoError(t, WriteMetricMetadataFile(dir, input))
+
+		actual, err := ReadMetricMetadataFile(dir)
+		require.NoError(t, err)
+		assert.Equal(t, []mimirpb.MetricMetadata{
+			{Type: mimirpb.COUNTER, MetricFamilyName: "a_total", Help: "A counter."},
+			{Type: mimirpb.GAUGE, MetricFamilyName: "b", Help: "B gauge.", Unit: "bytes"},
+		}, actual)
+	})
+
+	t.Run("should return no metadata if the file doesn't exist", func(t *testing.T) {
+		actual, err := ReadMetricMetadataFile(t.TempDir())
+		require.NoError(t, err)
+		assert.Empty(t, actual)
+	})
+
+	t.Run("should fail on unsupported version", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, MetricMetadataFilename), []byte(`{"version":2,"metadata":[]}`), 0644))
+
+		_, err := ReadMetricMetadataFile(dir)
+		require.Error(t, err)
+	})
+}
+
+func TestMergeMetricMetadata(t *testing.T) {
+	a := mimirpb.MetricMetadata{Type: mimirpb.COUNTER, MetricFamilyName: "a_total", Help: "A counter."}
+	aOtherHelp := mimirpb.MetricMetadata{Type: mimirpb.COUNTER, MetricFamilyName: "a_total", Help: "A counter, with a different help."}
+	b := mimirpb.MetricMetadata{Type: mimirpb.GAUGE, MetricFamilyName: "b", Help: "B gauge."}
+	c := mimirpb.MetricMetadata{Type: mimirpb.HISTOGRAM, MetricFamilyName: "c", Help: "C histogram."}
+
+	tests := map[string]struct {
+		limit    int
+		sets     [][]mimirpb.MetricMetadata
+		expected []mimirpb.MetricMetadata
+	}{
+		"no sets": {
+			expected: []mimirpb.MetricMetadata{},
+		},
+		"should deduplicate and sort the metadata": {
+			sets:     [][]mimirpb.MetricMetadata{{c, a}, {b, a, aOtherHelp}},
+			expected: []mimirpb.MetricMetadata{aOtherHelp, a, b, c},
+		},
+		"should keep the first metadata up to the limit": {
+			limit:    2,
+			sets:     [][]mimirpb.MetricMetadata{{c, a}, {b, a}},
+			expected: []mimirpb.MetricMetadata{a, b},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, MergeMetricMetadata(testData.limit, testData.sets...))
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/go-kit/log"
@@ -18,6 +19,8 @@ import (
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bloom"
 )
 
 // UploadBlock is copy of block.Upload with following modifications:
@@ -28,6 +31,8 @@ import (
 // - Meta struct is updated with gatherFileStats
 //
 // - external labels are not checked for
+//
+// - the optional label values bloom filter, metric metadata and exemplars files are uploaded too, if they exist
 func UploadBlock(ctx context.Context, logger log.Logger, bkt objstore.Bucket, blockDir string, meta *metadata.Meta) error {
 	df, err := os.Stat(blockDir)
 	if err != nil {
@@ -58,6 +63,24 @@ func UploadBlock(ctx context.Context, logger log.Logger, bkt objstore.Bucket, bl
 		return errors.Wrap(err, "gather meta file stats")
 	}
 
+	var optionalFiles []string
+	for _, name := range []string{bloom.FilterFilename, MetricMetadataFilename, ExemplarsFilename} {
+		fi, err := os.Stat(filepath.Join(blockDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "stat %s file", name)
+		}
+		optionalFiles = append(optionalFiles, name)
+		meta.Thanos.Files = append(meta.Thanos.Files, metadata.File{RelPath: name, SizeBytes: fi.Size()})
+	}
+	if len(optionalFiles) > 0 {
+		sort.Slice(meta.Thanos.Files, func(i, j int) bool {
+			return meta.Thanos.Files[i].RelPath < meta.Thanos.Files[j].RelPath
+		})
+	}
+
 	metaEncoded := strings.Builder{}
 	if err := meta.Write(&metaEncoded); err != nil {
 		return errors.Wrap(err, "encode meta file")
@@ -71,6 +94,12 @@ func UploadBlock(ctx context.Context, logger log.Logger, bkt objstore.Bucket, bl
 		return cleanUp(logger, bkt, id, errors.Wrap(err, "upload index"))
 	}
 
+	for _, name := range optionalFiles {
+		if err := objstore.UploadFile(ctx, logger, bkt, filepath.Join(blockDir, name), path.Join(id.String(), name)); err != nil {
+			return cleanUp(logger, bkt, id, errors.Wrapf(err, "upload %s file", name))
+		}
+	}
+
 	// Meta.json always need to be uploaded as a last item. This will allow to assume block directories without meta file to be pending uploads.
 	if err := bkt.Upload(ctx, path.Join(id.String(), block.MetaFilename), strings.NewReader(metaEncoded.String())); err != nil {
 		// Don't call cleanUp here. Despite getting error, meta.json may have been uploaded in certain cases,
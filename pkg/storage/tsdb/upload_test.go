@@ -19,6 +19,8 @@ import (
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bloom"
 	"github.com/grafana/mimir/pkg/storegateway/testhelper"
 	"github.com/grafana/mimir/pkg/util/test"
 )
@@ -203,6 +205,79 @@ func TestUploadBlock(t *testing.T) {
 		require.Equal(t, updatedMeta.Thanos.Labels, bucketMeta.Thanos.Labels)
 		require.Equal(t, updatedMeta.Thanos.Source, bucketMeta.Thanos.Source)
 	})
+
+	t.Run("upload with bloom filter", func(t *testing.T) {
+		b4, err := testhelper.CreateBlock(ctx, tmpDir, []labels.Labels{
+			{{Name: "a", Value: "1"}},
+			{{Name: "a", Value: "2"}},
+		}, 100, 0, 1000, nil, 124, metadata.NoneFunc)
+		require.NoError(t, err)
+
+		filter, err := bloom.BuildFromIndex(filepath.Join(tmpDir, b4.String(), block.IndexFilename), []string{"a"}, bloom.DefaultFalsePositiveRate)
+		require.NoError(t, err)
+		require.NoError(t, bloom.WriteFile(filepath.Join(tmpDir, b4.String()), filter))
+		filterFileSize := getFileSize(t, filepath.Join(tmpDir, b4.String(), bloom.FilterFilename))
+
+		require.NoError(t, UploadBlock(ctx, log.NewNopLogger(), bkt, path.Join(tmpDir, b4.String()), nil))
+		require.Equal(t, filterFileSize, int64(len(bkt.Objects()[path.Join(b4.String(), bloom.FilterFilename)])))
+
+		bucketMeta, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), bkt, b4)
+		require.NoError(t, err)
+
+		files := bucketMeta.Thanos.Files
+		require.Len(t, files, 4)
+		require.Equal(t, "index", files[1].RelPath)
+		require.Equal(t, metadata.File{RelPath: bloom.FilterFilename, SizeBytes: filterFileSize}, files[2])
+		require.Equal(t, metadata.File{RelPath: "meta.json", SizeBytes: 0}, files[3])
+	})
+
+	t.Run("upload with metric metadata", func(t *testing.T) {
+		b5, err := testhelper.CreateBlock(ctx, tmpDir, []labels.Labels{
+			{{Name: "a", Value: "1"}},
+		}, 100, 0, 1000, nil, 124, metadata.NoneFunc)
+		require.NoError(t, err)
+
+		require.NoError(t, WriteMetricMetadataFile(filepath.Join(tmpDir, b5.String()), []mimirpb.MetricMetadata{
+			{Type: mimirpb.COUNTER, MetricFamilyName: "a_total", Help: "A counter."},
+		}))
+		metadataFileSize := getFileSize(t, filepath.Join(tmpDir, b5.String(), MetricMetadataFilename))
+
+		require.NoError(t, UploadBlock(ctx, log.NewNopLogger(), bkt, path.Join(tmpDir, b5.String()), nil))
+		require.Equal(t, metadataFileSize, int64(len(bkt.Objects()[path.Join(b5.String(), MetricMetadataFilename)])))
+
+		bucketMeta, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), bkt, b5)
+		require.NoError(t, err)
+
+		files := bucketMeta.Thanos.Files
+		require.Len(t, files, 4)
+		require.Equal(t, metadata.File{RelPath: "meta.json", SizeBytes: 0}, files[2])
+		require.Equal(t, metadata.File{RelPath: MetricMetadataFilename, SizeBytes: metadataFileSize}, files[3])
+	})
+
+	t.Run("upload with exemplars", func(t *testing.T) {
+		b6, err := testhelper.CreateBlock(ctx, tmpDir, []labels.Labels{
+			{{Name: "a", Value: "1"}},
+		}, 100, 0, 1000, nil, 124, metadata.NoneFunc)
+		require.NoError(t, err)
+
+		require.NoError(t, WriteExemplarsFile(filepath.Join(tmpDir, b6.String()), []mimirpb.TimeSeries{{
+			Labels:    []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+			Exemplars: []mimirpb.Exemplar{{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "123"}}, Value: 1, TimestampMs: 100}},
+		}}))
+		exemplarsFileSize := getFileSize(t, filepath.Join(tmpDir, b6.String(), ExemplarsFilename))
+
+		require.NoError(t, UploadBlock(ctx, log.NewNopLogger(), bkt, path.Join(tmpDir, b6.String()), nil))
+		require.Equal(t, exemplarsFileSize, int64(len(bkt.Objects()[path.Join(b6.String(), ExemplarsFilename)])))
+
+		bucketMeta, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), bkt, b6)
+		require.NoError(t, err)
+
+		files := bucketMeta.Thanos.Files
+		require.Len(t, files, 4)
+		require.Equal(t, metadata.File{RelPath: ExemplarsFilename, SizeBytes: exemplarsFileSize}, files[1])
+		require.Equal(t, "index", files[2].RelPath)
+		require.Equal(t, metadata.File{RelPath: "meta.json", SizeBytes: 0}, files[3])
+	})
 }
 
 func getFileSize(t *testing.T, filepath string) int64 {
@@ -6,6 +6,8 @@
 package tsdb
 
 import (
+	"strconv"
+
 	"github.com/oklog/ulid"
 
 	"github.com/grafana/mimir/pkg/ingester/client"
@@ -20,3 +22,18 @@ func HashBlockID(id ulid.ULID) uint32 {
 	}
 	return h
 }
+
+// HashBlockReplicaKey returns a 32-bit hash of the block ID and the replica number, used
+// to look up in the ring the owners of the block replicas beyond the ring replication factor.
+func HashBlockReplicaKey(id ulid.ULID, replica int) uint32 {
+	h := client.HashAdd32(HashBlockID(id), strconv.Itoa(replica))
+
+	// The keys of the replicas differ by few bits only, so they're mixed with the murmur3
+	// finalizer to spread them across the ring.
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
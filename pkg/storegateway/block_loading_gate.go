@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// blockLoadingGate limits the number of blocks concurrently loaded across all tenants. Unlike
+// a FIFO gate, the waiting blocks are admitted by priority, so that the blocks covering the most
+// recent time ranges (which are the ones queried the most) are loaded first.
+type blockLoadingGate struct {
+	maxConcurrent int
+
+	mtx      sync.Mutex
+	inflight int
+	waiting  blockLoadingWaiters
+	seq      uint64
+}
+
+func newBlockLoadingGate(maxConcurrent int) *blockLoadingGate {
+	return &blockLoadingGate{maxConcurrent: maxConcurrent}
+}
+
+// start waits until a block with the given priority can be loaded, or the context is canceled.
+// The higher the priority, the sooner the block is loaded. done() must be called once the block
+// has been loaded, only if start() returned no error.
+func (g *blockLoadingGate) start(ctx context.Context, priority int64) error {
+	g.mtx.Lock()
+	if g.inflight < g.maxConcurrent && len(g.waiting) == 0 {
+		g.inflight++
+		g.mtx.Unlock()
+		return nil
+	}
+
+	w := &blockLoadingWaiter{priority: priority, seq: g.seq, admitted: make(chan struct{})}
+	g.seq++
+	heap.Push(&g.waiting, w)
+	g.mtx.Unlock()
+
+	select {
+	case <-w.admitted:
+		return nil
+	case <-ctx.Done():
+		g.mtx.Lock()
+		if w.index >= 0 {
+			heap.Remove(&g.waiting, w.index)
+			g.mtx.Unlock()
+			return ctx.Err()
+		}
+		g.mtx.Unlock()
+
+		// The block has been admitted in the meanwhile, so its slot is released.
+		g.done()
+		return ctx.Err()
+	}
+}
+
+// done releases the slot of a loaded block, admitting the waiting block with the highest priority.
+func (g *blockLoadingGate) done() {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if len(g.waiting) == 0 {
+		g.inflight--
+		return
+	}
+
+	// The slot is handed over to the next block.
+	w := heap.Pop(&g.waiting).(*blockLoadingWaiter)
+	close(w.admitted)
+}
+
+type blockLoadingWaiter struct {
+	priority int64
+	seq      uint64
+	admitted chan struct{}
+
+	// The index in the heap, or -1 once removed from it.
+	index int
+}
+
+// blockLoadingWaiters implements heap.Interface, sorting the waiters by priority (highest first)
+// and by arrival (oldest first) for the same priority.
+type blockLoadingWaiters []*blockLoadingWaiter
+
+func (w blockLoadingWaiters) Len() int { return len(w) }
+
+func (w blockLoadingWaiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w blockLoadingWaiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *blockLoadingWaiters) Push(x interface{}) {
+	waiter := x.(*blockLoadingWaiter)
+	waiter.index = len(*w)
+	*w = append(*w, waiter)
+}
+
+func (w *blockLoadingWaiters) Pop() interface{} {
+	old := *w
+	n := len(old)
+	waiter := old[n-1]
+	old[n-1] = nil
+	waiter.index = -1
+	*w = old[:n-1]
+	return waiter
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockLoadingGate_ShouldAdmitWaitingBlocksByPriority(t *testing.T) {
+	ctx := context.Background()
+	g := newBlockLoadingGate(1)
+
+	// Fill the gate.
+	require.NoError(t, g.start(ctx, 0))
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		admitted []int64
+	)
+
+	for i, priority := range []int64{10, 30, 20, 30} {
+		priority := priority
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, g.start(ctx, priority))
+
+			mtx.Lock()
+			admitted = append(admitted, priority)
+			mtx.Unlock()
+
+			g.done()
+		}()
+
+		// Wait until the block is waiting, to have a deterministic arrival order.
+		waitForWaiting(t, g, i+1)
+	}
+
+	g.done()
+	wg.Wait()
+
+	assert.Equal(t, []int64{30, 30, 20, 10}, admitted)
+	assert.Equal(t, 0, g.inflight)
+}
+
+func TestBlockLoadingGate_ShouldAdmitBlocksUpToTheMaxConcurrency(t *testing.T) {
+	ctx := context.Background()
+	g := newBlockLoadingGate(2)
+
+	require.NoError(t, g.start(ctx, 1))
+	require.NoError(t, g.start(ctx, 2))
+
+	// The third block waits until a slot is released.
+	admitted := make(chan struct{})
+	go func() {
+		require.NoError(t, g.start(ctx, 3))
+		close(admitted)
+	}()
+
+	waitForWaiting(t, g, 1)
+	select {
+	case <-admitted:
+		t.Fatal("the block has been admitted while the gate is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.done()
+	<-admitted
+
+	g.done()
+	g.done()
+	assert.Equal(t, 0, g.inflight)
+}
+
+func TestBlockLoadingGate_ShouldReturnOnContextCanceled(t *testing.T) {
+	g := newBlockLoadingGate(1)
+	require.NoError(t, g.start(context.Background(), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- g.start(ctx, 2)
+	}()
+
+	waitForWaiting(t, g, 1)
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+
+	// The canceled block is no longer waiting, so the released slot is free.
+	g.done()
+	assert.Equal(t, 0, g.inflight)
+	assert.Empty(t, g.waiting)
+}
+
+func waitForWaiting(t *testing.T, g *blockLoadingGate, expected int) {
+	require.Eventually(t, func() bool {
+		g.mtx.Lock()
+		defer g.mtx.Unlock()
+		return len(g.waiting) == expected
+	}, time.Second, time.Millisecond)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
@@ -54,10 +55,13 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bloom"
 	"github.com/grafana/mimir/pkg/storegateway/indexcache"
 	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
 	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 )
@@ -103,6 +107,9 @@ type BucketStore struct {
 	chunkPool       pool.Bytes
 	seriesHashCache *hashcache.SeriesHashCache
 
+	// Evictor of the lazy loaded index-headers, shared across all tenants. Optional.
+	lazyIndexReaderEvictor *indexheader.LazyReaderEvictor
+
 	// Sets of blocks that have the same labels. They are indexed by a hash over their label set.
 	mtx      sync.RWMutex
 	blocks   map[ulid.ULID]*bucketBlock
@@ -110,14 +117,20 @@ type BucketStore struct {
 
 	// Verbose enabled additional logging.
 	debugLogging bool
+	// Whether to load the blocks label values bloom filter, if any, and use it to skip blocks at query time.
+	bloomFilterEnabled bool
 	// Number of goroutines to use when syncing blocks from object storage.
 	blockSyncConcurrency int
+	// Gate limiting the blocks concurrently loaded across all tenants, by priority. Optional.
+	blockLoadingGate *blockLoadingGate
 
 	// Query gate which limits the maximum amount of concurrent queries.
 	queryGate gate.Gate
 
 	// chunksLimiterFactory creates a new limiter used to limit the number of chunks fetched by each Series() call.
 	chunksLimiterFactory ChunksLimiterFactory
+	// chunksBytesLimiterFactory creates a new limiter used to limit the size of the chunks fetched by each Series() call.
+	chunksBytesLimiterFactory BytesLimiterFactory
 	// seriesLimiterFactory creates a new limiter used to limit the number of touched series by each Series() call,
 	// or LabelName and LabelValues calls when used with matchers.
 	seriesLimiterFactory SeriesLimiterFactory
@@ -208,6 +221,38 @@ func WithDebugLogging() BucketStoreOption {
 	}
 }
 
+// WithBloomFilters enables loading the label values bloom filter of each block, built by the compactor,
+// and using it to skip blocks that cannot match the query.
+func WithBloomFilters() BucketStoreOption {
+	return func(s *BucketStore) {
+		s.bloomFilterEnabled = true
+	}
+}
+
+// WithLazyIndexReaderEvictor sets the evictor of the lazy loaded index-headers, limiting the number and the size
+// of the index-headers loaded across all the BucketStore sharing it.
+func WithLazyIndexReaderEvictor(evictor *indexheader.LazyReaderEvictor) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.lazyIndexReaderEvictor = evictor
+	}
+}
+
+// WithChunksBytesLimiterFactory sets the factory of the limiter used to limit the size in bytes of the chunks
+// fetched by each Series() call.
+func WithChunksBytesLimiterFactory(factory BytesLimiterFactory) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.chunksBytesLimiterFactory = factory
+	}
+}
+
+// WithBlockLoadingGate sets the gate limiting the number of blocks concurrently loaded across all the BucketStore
+// sharing it. The blocks with the most recent samples are loaded first.
+func WithBlockLoadingGate(g *blockLoadingGate) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.blockLoadingGate = g
+	}
+}
+
 // NewBucketStore creates a new bucket backed store that implements the store API against
 // an object store bucket. It is optimized to work against high latency backends.
 func NewBucketStore(
@@ -240,6 +285,7 @@ func NewBucketStore(
 		blockSyncConcurrency:        blockSyncConcurrency,
 		queryGate:                   gate.NewNoop(),
 		chunksLimiterFactory:        chunksLimiterFactory,
+		chunksBytesLimiterFactory:   NewBytesLimiterFactory(0),
 		seriesLimiterFactory:        seriesLimiterFactory,
 		partitioner:                 partitioner,
 		postingOffsetsInMemSampling: postingOffsetsInMemSampling,
@@ -255,7 +301,7 @@ func NewBucketStore(
 	}
 
 	// Depend on the options
-	s.indexReaderPool = indexheader.NewReaderPool(s.logger, lazyIndexReaderEnabled, lazyIndexReaderIdleTimeout, metrics.indexHeaderReaderMetrics)
+	s.indexReaderPool = indexheader.NewReaderPool(s.logger, lazyIndexReaderEnabled, lazyIndexReaderIdleTimeout, s.lazyIndexReaderEvictor, metrics.indexHeaderReaderMetrics)
 
 	if err := os.MkdirAll(dir, 0750); err != nil {
 		return nil, errors.Wrap(err, "create dir")
@@ -301,7 +347,7 @@ func (s *BucketStore) SyncBlocks(ctx context.Context) error {
 		wg.Add(1)
 		go func() {
 			for meta := range blockc {
-				if err := s.addBlock(ctx, meta); err != nil {
+				if err := s.loadBlock(ctx, meta); err != nil {
 					continue
 				}
 			}
@@ -309,10 +355,22 @@ func (s *BucketStore) SyncBlocks(ctx context.Context) error {
 		}()
 	}
 
+	// The blocks covering the most recent time ranges are loaded first, because they're the most
+	// queried ones. It doesn't make the store-gateway serve queries sooner at startup, because it
+	// only becomes ACTIVE in the ring once the initial sync has loaded all the blocks, but it does
+	// on the periodic syncs, while the store-gateway is serving the blocks it already loaded.
+	toLoad := make([]*metadata.Meta, 0, len(metas))
 	for id, meta := range metas {
 		if b := s.getBlock(id); b != nil {
 			continue
 		}
+		toLoad = append(toLoad, meta)
+	}
+	sort.Slice(toLoad, func(i, j int) bool {
+		return toLoad[i].MaxTime > toLoad[j].MaxTime
+	})
+
+	for _, meta := range toLoad {
 		select {
 		case <-ctx.Done():
 		case blockc <- meta:
@@ -379,6 +437,18 @@ func (s *BucketStore) getBlock(id ulid.ULID) *bucketBlock {
 	return s.blocks[id]
 }
 
+// loadBlock adds the block once the block loading gate, if any, admits it.
+func (s *BucketStore) loadBlock(ctx context.Context, meta *metadata.Meta) error {
+	if s.blockLoadingGate != nil {
+		if err := s.blockLoadingGate.start(ctx, meta.MaxTime); err != nil {
+			return err
+		}
+		defer s.blockLoadingGate.done()
+	}
+
+	return s.addBlock(ctx, meta)
+}
+
 func (s *BucketStore) addBlock(ctx context.Context, meta *metadata.Meta) (err error) {
 	dir := filepath.Join(s.dir, meta.ULID.String())
 	start := time.Now()
@@ -438,6 +508,13 @@ func (s *BucketStore) addBlock(ctx context.Context, meta *metadata.Meta) (err er
 		}
 	}()
 
+	if s.bloomFilterEnabled {
+		// The bloom filter is an optimization, so a block is loaded even if its filter can't be.
+		if err := b.loadBloomFilter(ctx); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to load block bloom filter", "id", meta.ULID, "err", err)
+		}
+	}
+
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
@@ -933,6 +1010,7 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 		resHints         = &hintspb.SeriesResponseHints{}
 		reqBlockMatchers []*labels.Matcher
 		chunksLimiter    = s.chunksLimiterFactory(s.metrics.queriesDropped.WithLabelValues("chunks"))
+		bytesLimiter     = s.chunksBytesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("chunks_bytes"))
 		seriesLimiter    = s.seriesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("series"))
 	)
 
@@ -962,15 +1040,21 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 		b := b
 
 		if s.enableSeriesResponseHints {
-			// Keep track of queried blocks.
+			// Keep track of queried blocks. A block skipped by its bloom filter is queried too,
+			// it just has no matching series.
 			resHints.AddQueriedBlock(b.meta.ULID)
 		}
 
+		if b.bloomFilter != nil && b.bloomFilter.CannotMatch(matchers) {
+			s.metrics.seriesBlocksSkipped.Inc()
+			continue
+		}
+
 		var chunkr *bucketChunkReader
 		// We must keep the readers open until all their data has been sent.
 		indexr := b.indexReader()
 		if !req.SkipChunks {
-			chunkr = b.chunkReader(gctx)
+			chunkr = b.chunkReader(gctx, bytesLimiter)
 			defer runutil.CloseWithLogOnErr(s.logger, chunkr, "series block")
 		}
 
@@ -1361,12 +1445,124 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 	}, nil
 }
 
+// MetricsMetadata returns the metric metadata persisted in the requested blocks. The blocks not loaded by the store
+// are skipped, and the queried ones are returned in the response.
+func (s *BucketStore) MetricsMetadata(ctx context.Context, req *storegatewaypb.MetricsMetadataRequest) (*storegatewaypb.MetricsMetadataResponse, error) {
+	blockIDs := make([]ulid.ULID, 0, len(req.BlockIds))
+	for _, id := range req.BlockIds {
+		blockID, err := ulid.Parse(id)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, errors.Wrapf(err, "parse block ID %q", id).Error())
+		}
+		blockIDs = append(blockIDs, blockID)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	resp := &storegatewaypb.MetricsMetadataResponse{}
+
+	var mtx sync.Mutex
+	var sets [][]mimirpb.MetricMetadata
+
+	s.mtx.RLock()
+	for _, blockID := range blockIDs {
+		b, ok := s.blocks[blockID]
+		if !ok {
+			continue
+		}
+		resp.QueriedBlocks = append(resp.QueriedBlocks, blockID.String())
+
+		g.Go(func() error {
+			md, err := b.loadMetricMetadata(gctx)
+			if err != nil {
+				return errors.Wrapf(err, "block %s", b.meta.ULID)
+			}
+
+			mtx.Lock()
+			sets = append(sets, md)
+			mtx.Unlock()
+			return nil
+		})
+	}
+	s.mtx.RUnlock()
+
+	if err := g.Wait(); err != nil {
+		return nil, status.Error(codes.Aborted, err.Error())
+	}
+
+	merged := mimir_tsdb.MergeMetricMetadata(0, sets...)
+	resp.Metadata = make([]*mimirpb.MetricMetadata, 0, len(merged))
+	for ix := range merged {
+		resp.Metadata = append(resp.Metadata, &merged[ix])
+	}
+	return resp, nil
+}
+
+// Exemplars returns the exemplars persisted in the requested blocks, matching any of the requested series selectors
+// within the requested time range. The blocks not loaded by the store are skipped, and the queried ones are returned
+// in the response.
+func (s *BucketStore) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	blockIDs := make([]ulid.ULID, 0, len(req.BlockIds))
+	for _, id := range req.BlockIds {
+		blockID, err := ulid.Parse(id)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, errors.Wrapf(err, "parse block ID %q", id).Error())
+		}
+		blockIDs = append(blockIDs, blockID)
+	}
+
+	matchersSets := make([][]*labels.Matcher, 0, len(req.Matchers))
+	for _, selector := range req.Matchers {
+		matchers, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, errors.Wrapf(err, "parse series selector %q", selector).Error())
+		}
+		matchersSets = append(matchersSets, matchers)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	resp := &storegatewaypb.ExemplarsResponse{}
+
+	var mtx sync.Mutex
+	var sets [][]mimirpb.TimeSeries
+
+	s.mtx.RLock()
+	for _, blockID := range blockIDs {
+		b, ok := s.blocks[blockID]
+		if !ok {
+			continue
+		}
+		resp.QueriedBlocks = append(resp.QueriedBlocks, blockID.String())
+
+		g.Go(func() error {
+			series, err := b.loadExemplars(gctx)
+			if err != nil {
+				return errors.Wrapf(err, "block %s", b.meta.ULID)
+			}
+			series = mimir_tsdb.FilterExemplars(series, req.Start, req.End, matchersSets...)
+
+			mtx.Lock()
+			sets = append(sets, series)
+			mtx.Unlock()
+			return nil
+		})
+	}
+	s.mtx.RUnlock()
+
+	if err := g.Wait(); err != nil {
+		return nil, status.Error(codes.Aborted, err.Error())
+	}
+
+	resp.Timeseries = mimir_tsdb.MergeExemplars(0, sets...)
+	return resp, nil
+}
+
 // blockLabelValues provides the values of the label with requested name,
 // optionally restricting the search to the series that match the matchers provided.
 // - First we fetch all possible values for this label from the index.
 //   - If no matchers were provided, we just return those values.
 // - Next we load the postings (references to series) for supplied matchers.
-// - Then we load the postings for each label-value fetched in the first step.
+//   - If the matchers select fewer series than the label values, we load those series and collect their values.
+// - Otherwise, we load the postings for each label-value fetched in the first step.
 // - Finally, we check if postings from each label-value intersect postings from matchers.
 //   - A non empty intersection means that a matched series has that value, so we add it to the result.
 //
@@ -1393,6 +1589,17 @@ func blockLabelValues(ctx context.Context, indexr *bucketIndexReader, labelName
 		return nil, errors.Wrap(err, "expanded postings")
 	}
 
+	// Fetching the postings of every label value is expensive for labels with many values, like on the
+	// huge tenants, so when the matchers are selective we read the values from the matched series instead.
+	if len(p) < len(allValues) {
+		matched, err := labelValuesFromSeries(ctx, indexr, labelName, p)
+		if err != nil {
+			return nil, err
+		}
+		storeCachedLabelValues(ctx, indexr.block.indexCache, indexr.block.userID, indexr.block.meta.ULID, labelName, matchers, matched, logger)
+		return matched, nil
+	}
+
 	keys := make([]labels.Label, len(allValues))
 	for i, value := range allValues {
 		keys[i] = labels.Label{Name: labelName, Value: value}
@@ -1418,6 +1625,45 @@ func blockLabelValues(ctx context.Context, indexr *bucketIndexReader, labelName
 	return matched, nil
 }
 
+// labelValuesFromSeries returns the sorted values of the label with the requested name of the series with the given postings.
+func labelValuesFromSeries(ctx context.Context, indexr *bucketIndexReader, labelName string, postings []storage.SeriesRef) ([]string, error) {
+	if err := indexr.PreloadSeries(ctx, postings); err != nil {
+		return nil, errors.Wrap(err, "preload series")
+	}
+
+	var (
+		// We ignore request's min/max time and query the entire block to make the result cacheable.
+		minTime, maxTime = indexr.block.meta.MinTime, indexr.block.meta.MaxTime
+		symbolizedLset   []symbolizedLabel
+		chks             []chunks.Meta
+		values           = map[string]struct{}{}
+	)
+	for _, id := range postings {
+		ok, err := indexr.LoadSeriesForTime(ctx, id, &symbolizedLset, &chks, true, minTime, maxTime)
+		if err != nil {
+			return nil, errors.Wrap(err, "read series")
+		}
+		if !ok {
+			continue
+		}
+
+		lset, err := indexr.LookupLabelsSymbols(ctx, symbolizedLset)
+		if err != nil {
+			return nil, errors.Wrap(err, "lookup labels symbols")
+		}
+		if value := lset.Get(labelName); value != "" {
+			values[value] = struct{}{}
+		}
+	}
+
+	matched := make([]string, 0, len(values))
+	for value := range values {
+		matched = append(matched, value)
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
 type labelValuesCacheEntry struct {
 	Values      []string
 	LabelName   string
@@ -1597,6 +1843,19 @@ type bucketBlock struct {
 	// request hints' BlockMatchers.
 	blockLabels labels.Labels
 
+	// Optional label values bloom filter, used to skip the block when querying. Nil if the block has no filter.
+	bloomFilter *bloom.Filter
+
+	// Metric metadata persisted in the block, lazily loaded on the first metadata request.
+	metricMetadataMtx    sync.Mutex
+	metricMetadataLoaded bool
+	metricMetadata       []mimirpb.MetricMetadata
+
+	// Exemplars persisted in the block, lazily loaded on the first exemplars request.
+	exemplarsMtx    sync.Mutex
+	exemplarsLoaded bool
+	exemplars       []mimirpb.TimeSeries
+
 	expandedPostingsPromises sync.Map
 }
 
@@ -1651,6 +1910,91 @@ func newBucketBlock(
 	return b, nil
 }
 
+// loadBloomFilter fetches the block's label values bloom filter from the bucket, if the block has one.
+func (b *bucketBlock) loadBloomFilter(ctx context.Context) error {
+	r, err := b.bkt.Get(ctx, path.Join(b.meta.ULID.String(), bloom.FilterFilename))
+	if b.bkt.IsObjNotFoundErr(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "get bloom filter")
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "close bloom filter reader")
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "read bloom filter")
+	}
+	b.bloomFilter, err = bloom.Unmarshal(data)
+	return errors.Wrap(err, "decode bloom filter")
+}
+
+// loadMetricMetadata returns the metric metadata persisted in the block, fetching it from the bucket on the first call.
+// It returns no metadata if the block has no metric metadata file.
+func (b *bucketBlock) loadMetricMetadata(ctx context.Context) ([]mimirpb.MetricMetadata, error) {
+	b.metricMetadataMtx.Lock()
+	defer b.metricMetadataMtx.Unlock()
+
+	if b.metricMetadataLoaded {
+		return b.metricMetadata, nil
+	}
+
+	r, err := b.bkt.Get(ctx, path.Join(b.meta.ULID.String(), mimir_tsdb.MetricMetadataFilename))
+	if b.bkt.IsObjNotFoundErr(err) {
+		b.metricMetadataLoaded = true
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get metric metadata")
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "close metric metadata reader")
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "read metric metadata")
+	}
+	md, err := mimir_tsdb.DecodeMetricMetadataFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b.metricMetadata, b.metricMetadataLoaded = md, true
+	return md, nil
+}
+
+// loadExemplars returns the exemplars persisted in the block, fetching them from the bucket on the first call.
+// It returns no exemplars if the block has no exemplars file.
+func (b *bucketBlock) loadExemplars(ctx context.Context) ([]mimirpb.TimeSeries, error) {
+	b.exemplarsMtx.Lock()
+	defer b.exemplarsMtx.Unlock()
+
+	if b.exemplarsLoaded {
+		return b.exemplars, nil
+	}
+
+	r, err := b.bkt.Get(ctx, path.Join(b.meta.ULID.String(), mimir_tsdb.ExemplarsFilename))
+	if b.bkt.IsObjNotFoundErr(err) {
+		b.exemplarsLoaded = true
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get exemplars")
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "close exemplars reader")
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "read exemplars")
+	}
+	series, err := mimir_tsdb.DecodeExemplarsFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b.exemplars, b.exemplarsLoaded = series, true
+	return series, nil
+}
+
 func (b *bucketBlock) indexFilename() string {
 	return path.Join(b.meta.ULID.String(), block.IndexFilename)
 }
@@ -1712,9 +2056,9 @@ func (b *bucketBlock) indexReader() *bucketIndexReader {
 	return newBucketIndexReader(b)
 }
 
-func (b *bucketBlock) chunkReader(ctx context.Context) *bucketChunkReader {
+func (b *bucketBlock) chunkReader(ctx context.Context, bytesLimiter BytesLimiter) *bucketChunkReader {
 	b.pendingReaders.Add(1)
-	return newBucketChunkReader(ctx, b)
+	return newBucketChunkReader(ctx, b, bytesLimiter)
 }
 
 // matchLabels verifies whether the block matches the given matchers.
@@ -2538,6 +2882,9 @@ type bucketChunkReader struct {
 
 	toLoad [][]loadIdx
 
+	// Limits the size of the chunks fetched, shared by the chunk readers of all the blocks queried by a request.
+	bytesLimiter BytesLimiter
+
 	// Mutex protects access to following fields, when updated from chunks-loading goroutines.
 	// After chunks are loaded, mutex is no longer used.
 	mtx        sync.Mutex
@@ -2545,12 +2892,13 @@ type bucketChunkReader struct {
 	chunkBytes []*[]byte // Byte slice to return to the chunk pool on close.
 }
 
-func newBucketChunkReader(ctx context.Context, block *bucketBlock) *bucketChunkReader {
+func newBucketChunkReader(ctx context.Context, block *bucketBlock, bytesLimiter BytesLimiter) *bucketChunkReader {
 	return &bucketChunkReader{
-		ctx:    ctx,
-		block:  block,
-		stats:  &queryStats{},
-		toLoad: make([][]loadIdx, len(block.chunkObjs)),
+		ctx:          ctx,
+		block:        block,
+		bytesLimiter: bytesLimiter,
+		stats:        &queryStats{},
+		toLoad:       make([][]loadIdx, len(block.chunkObjs)),
 	}
 }
 
@@ -2673,6 +3021,13 @@ func (r *bucketChunkReader) loadChunks(ctx context.Context, res []seriesEntry, a
 		// Chunk length is n (number of bytes used to encode chunk data), 1 for chunk encoding and chunkDataLen for actual chunk data.
 		// There is also crc32 after the chunk, but we ignore that.
 		chunkLen = n + 1 + int(chunkDataLen)
+
+		// The actual size of the chunk is reserved, instead of the size of the fetched range, which also
+		// includes the gaps between the chunks. It's reserved before fetching the rest of the large chunks.
+		if err = r.bytesLimiter.Reserve(uint64(chunkLen)); err != nil {
+			return errors.Wrap(err, "exceeded chunks bytes limit")
+		}
+
 		if chunkLen <= len(cb) {
 			err = populateChunk(&(res[pIdx.seriesEntry].chks[pIdx.chunk]), rawChunk(cb[n:chunkLen]), aggrs, r.save)
 			if err != nil {
@@ -28,6 +28,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/pool"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/tracing"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/logging"
 	"google.golang.org/grpc/metadata"
@@ -35,6 +36,8 @@ import (
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storegateway/indexcache"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -71,6 +74,21 @@ type BucketStores struct {
 	// Gate used to limit query concurrency across all tenants.
 	queryGate gate.Gate
 
+	// Limiter used to limit the concurrent series requests of each tenant.
+	tenantSeriesLimiter *tenantConcurrencyLimiter
+
+	// Evictor of the lazy loaded index-headers shared across all tenants, if the limits are enabled.
+	lazyIndexReaderEvictor *indexheader.LazyReaderEvictor
+
+	// Gate used to limit the blocks concurrently loaded across all tenants, if the limit is enabled.
+	blockLoadingGate *blockLoadingGate
+
+	// Progress of the initial sync, reported while it's running.
+	initialSyncMx            sync.Mutex
+	initialSyncRunning       bool
+	initialSyncTenants       int
+	initialSyncTenantsSynced map[string]struct{}
+
 	// Keeps a bucket store for each tenant.
 	storesMu sync.RWMutex
 	stores   map[string]*BucketStore
@@ -99,18 +117,19 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 	}).Set(float64(cfg.BucketStore.MaxConcurrent))
 
 	u := &BucketStores{
-		logger:             logger,
-		cfg:                cfg,
-		limits:             limits,
-		bucket:             cachingBucket,
-		shardingStrategy:   shardingStrategy,
-		stores:             map[string]*BucketStore{},
-		logLevel:           logLevel,
-		bucketStoreMetrics: NewBucketStoreMetrics(reg),
-		metaFetcherMetrics: NewMetadataFetcherMetrics(),
-		queryGate:          queryGate,
-		partitioner:        newGapBasedPartitioner(cfg.BucketStore.PartitionerMaxGapBytes, reg),
-		seriesHashCache:    hashcache.NewSeriesHashCache(cfg.BucketStore.SeriesHashCacheMaxBytes),
+		logger:              logger,
+		cfg:                 cfg,
+		limits:              limits,
+		bucket:              cachingBucket,
+		shardingStrategy:    shardingStrategy,
+		stores:              map[string]*BucketStore{},
+		logLevel:            logLevel,
+		bucketStoreMetrics:  NewBucketStoreMetrics(reg),
+		metaFetcherMetrics:  NewMetadataFetcherMetrics(),
+		queryGate:           queryGate,
+		tenantSeriesLimiter: newTenantConcurrencyLimiter(),
+		partitioner:         newGapBasedPartitioner(cfg.BucketStore.PartitionerMaxGapBytes, reg),
+		seriesHashCache:     hashcache.NewSeriesHashCache(cfg.BucketStore.SeriesHashCacheMaxBytes),
 		syncBackoffConfig: backoff.Config{
 			MinBackoff: 1 * time.Second,
 			MaxBackoff: 10 * time.Second,
@@ -146,6 +165,15 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 		return nil, errors.Wrap(err, "create index cache")
 	}
 
+	// Init the evictor of the lazy loaded index-headers.
+	if cfg.BucketStore.IndexHeaderLazyLoadingEnabled && (cfg.BucketStore.IndexHeaderLazyLoadingMaxLoaded > 0 || cfg.BucketStore.IndexHeaderLazyLoadingMaxLoadedBytes > 0) {
+		u.lazyIndexReaderEvictor = indexheader.NewLazyReaderEvictor(logger, cfg.BucketStore.IndexHeaderLazyLoadingMaxLoaded, int64(cfg.BucketStore.IndexHeaderLazyLoadingMaxLoadedBytes), extprom.WrapRegistererWithPrefix("cortex_bucket_store_", reg))
+	}
+
+	if cfg.BucketStore.BlockLoadingConcurrency > 0 {
+		u.blockLoadingGate = newBlockLoadingGate(cfg.BucketStore.BlockLoadingConcurrency)
+	}
+
 	// Init the chunks bytes pool.
 	if u.chunksPool, err = newChunkBytesPool(cfg.BucketStore.ChunkPoolMinBucketSizeBytes, cfg.BucketStore.ChunkPoolMaxBucketSizeBytes, cfg.BucketStore.MaxChunkPoolBytes, reg); err != nil {
 		return nil, errors.Wrap(err, "create chunks bytes pool")
@@ -162,8 +190,26 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 func (u *BucketStores) InitialSync(ctx context.Context) error {
 	level.Info(u.logger).Log("msg", "synchronizing TSDB blocks for all users")
 
+	u.initialSyncMx.Lock()
+	u.initialSyncRunning = true
+	u.initialSyncTenantsSynced = map[string]struct{}{}
+	u.initialSyncMx.Unlock()
+
+	defer func() {
+		u.initialSyncMx.Lock()
+		u.initialSyncRunning = false
+		u.initialSyncMx.Unlock()
+	}()
+
 	if err := u.syncUsersBlocksWithRetries(ctx, func(ctx context.Context, s *BucketStore) error {
-		return s.InitialSync(ctx)
+		if err := s.InitialSync(ctx); err != nil {
+			return err
+		}
+
+		u.initialSyncMx.Lock()
+		u.initialSyncTenantsSynced[s.userID] = struct{}{}
+		u.initialSyncMx.Unlock()
+		return nil
 	}); err != nil {
 		level.Warn(u.logger).Log("msg", "failed to synchronize TSDB blocks", "err", err)
 		return err
@@ -173,6 +219,19 @@ func (u *BucketStores) InitialSync(ctx context.Context) error {
 	return nil
 }
 
+// InitialSyncProgress returns the progress of the initial sync, or an empty string if it's not running.
+func (u *BucketStores) InitialSyncProgress() string {
+	u.initialSyncMx.Lock()
+	running, tenants, synced := u.initialSyncRunning, u.initialSyncTenants, len(u.initialSyncTenantsSynced)
+	u.initialSyncMx.Unlock()
+
+	if !running {
+		return ""
+	}
+
+	return fmt.Sprintf("initial blocks synchronization in progress: %d of %d tenants synchronized, %d blocks loaded", synced, tenants, int(u.getBlocksLoadedMetric()))
+}
+
 // SyncBlocks synchronizes the stores state with the Bucket store for every user.
 func (u *BucketStores) SyncBlocks(ctx context.Context) error {
 	return u.syncUsersBlocksWithRetries(ctx, func(ctx context.Context, s *BucketStore) error {
@@ -239,6 +298,10 @@ func (u *BucketStores) syncUsersBlocks(ctx context.Context, f func(context.Conte
 	u.tenantsDiscovered.Set(float64(len(userIDs)))
 	u.tenantsSynced.Set(float64(len(includeUserIDs)))
 
+	u.initialSyncMx.Lock()
+	u.initialSyncTenants = len(includeUserIDs)
+	u.initialSyncMx.Unlock()
+
 	// Create a pool of workers which will synchronize blocks. The pool size
 	// is limited in order to avoid to concurrently sync a lot of tenants in
 	// a large cluster.
@@ -307,6 +370,20 @@ func (u *BucketStores) Series(req *storepb.SeriesRequest, srv storepb.Store_Seri
 		return nil
 	}
 
+	// The tenant's requests exceeding the limit wait before the query gate, so that they don't
+	// take the turn of the other tenants' requests.
+	var (
+		done func()
+		err  error
+	)
+	tracing.DoInSpan(spanCtx, "store_tenant_query_gate_ismyturn", func(ctx context.Context) {
+		done, err = u.tenantSeriesLimiter.start(ctx, userID, u.limits.StoreGatewayTenantMaxConcurrentSeriesRequests(userID))
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for turn")
+	}
+	defer done()
+
 	return store.Series(req, spanSeriesServer{
 		Store_SeriesServer: srv,
 		ctx:                spanCtx,
@@ -331,6 +408,42 @@ func (u *BucketStores) LabelNames(ctx context.Context, req *storepb.LabelNamesRe
 	return store.LabelNames(ctx, req)
 }
 
+// MetricsMetadata implements the Storegateway proto service.
+func (u *BucketStores) MetricsMetadata(ctx context.Context, req *storegatewaypb.MetricsMetadataRequest) (*storegatewaypb.MetricsMetadataResponse, error) {
+	spanLog, spanCtx := spanlogger.NewWithLogger(ctx, u.logger, "BucketStores.MetricsMetadata")
+	defer spanLog.Span.Finish()
+
+	userID := getUserIDFromGRPCContext(spanCtx)
+	if userID == "" {
+		return nil, fmt.Errorf("no userID")
+	}
+
+	store := u.getStore(userID)
+	if store == nil {
+		return &storegatewaypb.MetricsMetadataResponse{}, nil
+	}
+
+	return store.MetricsMetadata(ctx, req)
+}
+
+// Exemplars implements the Storegateway proto service.
+func (u *BucketStores) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	spanLog, spanCtx := spanlogger.NewWithLogger(ctx, u.logger, "BucketStores.Exemplars")
+	defer spanLog.Span.Finish()
+
+	userID := getUserIDFromGRPCContext(spanCtx)
+	if userID == "" {
+		return nil, fmt.Errorf("no userID")
+	}
+
+	store := u.getStore(userID)
+	if store == nil {
+		return &storegatewaypb.ExemplarsResponse{}, nil
+	}
+
+	return store.Exemplars(ctx, req)
+}
+
 // LabelValues implements the Storegateway proto service.
 func (u *BucketStores) LabelValues(ctx context.Context, req *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
 	spanLog, spanCtx := spanlogger.NewWithLogger(ctx, u.logger, "BucketStores.LabelValues")
@@ -466,6 +579,16 @@ func (u *BucketStores) getOrCreateStore(userID string) (*BucketStore, error) {
 	if u.logLevel.String() == "debug" {
 		bucketStoreOpts = append(bucketStoreOpts, WithDebugLogging())
 	}
+	if u.cfg.BucketStore.BloomFilterEnabled {
+		bucketStoreOpts = append(bucketStoreOpts, WithBloomFilters())
+	}
+	if u.lazyIndexReaderEvictor != nil {
+		bucketStoreOpts = append(bucketStoreOpts, WithLazyIndexReaderEvictor(u.lazyIndexReaderEvictor))
+	}
+	if u.blockLoadingGate != nil {
+		bucketStoreOpts = append(bucketStoreOpts, WithBlockLoadingGate(u.blockLoadingGate))
+	}
+	bucketStoreOpts = append(bucketStoreOpts, WithChunksBytesLimiterFactory(newChunksBytesLimiterFactory(u.limits, userID)))
 
 	bs, err := NewBucketStore(
 		userID,
@@ -593,3 +716,13 @@ func newChunksLimiterFactory(limits *validation.Overrides, userID string) Chunks
 		}
 	}
 }
+
+func newChunksBytesLimiterFactory(limits *validation.Overrides, userID string) BytesLimiterFactory {
+	return func(failedCounter prometheus.Counter) BytesLimiter {
+		// Since limit overrides could be live reloaded, we have to get the current user's limit
+		// each time a new limiter is instantiated.
+		return &chunkLimiter{
+			limiter: NewLimiter(uint64(limits.StoreGatewayTenantMaxFetchedChunkBytesPerRequest(userID)), failedCounter),
+		}
+	}
+}
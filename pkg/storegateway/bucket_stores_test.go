@@ -12,10 +12,12 @@ import (
 	"io"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +35,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
 	thanos_metadata "github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/objstore"
@@ -43,11 +46,13 @@ import (
 	"go.uber.org/atomic"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
 	"github.com/grafana/mimir/pkg/storegateway/indexcache"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/test"
 )
@@ -194,6 +199,54 @@ func TestBucketStores_InitialSyncShouldRetryOnFailure(t *testing.T) {
 	assert.Greater(t, testutil.ToFloat64(stores.syncLastSuccess), float64(0))
 }
 
+func TestBucketStores_InitialSyncShouldLoadMostRecentBlocksFirst(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+	cfg.BucketStore.BlockSyncConcurrency = 1
+	cfg.BucketStore.BlockLoadingConcurrency = 1
+
+	storageDir := t.TempDir()
+
+	// Generate blocks for the user, not sorted by time.
+	generateStorageBlock(t, storageDir, "user-1", "series_1", 100, 200, 15)
+	generateStorageBlock(t, storageDir, "user-1", "series_1", 300, 400, 15)
+	generateStorageBlock(t, storageDir, "user-1", "series_1", 0, 100, 15)
+	generateStorageBlock(t, storageDir, "user-1", "series_1", 200, 300, 15)
+
+	bucketClient, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	recordingBucket := &recordIndexReadsBucket{Bucket: bucketClient}
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), recordingBucket, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	// The progress is reported while the initial sync is running.
+	recordingBucket.onFirstRead = func() {
+		assert.Equal(t, "initial blocks synchronization in progress: 0 of 1 tenants synchronized, 0 blocks loaded", stores.InitialSyncProgress())
+	}
+
+	assert.Empty(t, stores.InitialSyncProgress())
+	require.NoError(t, stores.InitialSync(ctx))
+	assert.Empty(t, stores.InitialSyncProgress())
+
+	// The index-headers have been built starting from the most recent block.
+	blockIDs := recordingBucket.readBlocks()
+	require.Len(t, blockIDs, 4)
+
+	var maxTimes []int64
+	for _, blockID := range blockIDs {
+		meta, err := thanos_metadata.ReadFromDir(filepath.Join(storageDir, "user-1", blockID))
+		require.NoError(t, err)
+		maxTimes = append(maxTimes, meta.MaxTime)
+	}
+	assert.True(t, sort.SliceIsSorted(maxTimes, func(i, j int) bool { return maxTimes[i] > maxTimes[j] }), "max times: %v", maxTimes)
+
+	assert.Equal(t, float64(4), testutil.ToFloat64(stores.blocksLoaded))
+}
+
 func TestBucketStores_SyncBlocks(t *testing.T) {
 	test.VerifyNoLeak(t)
 
@@ -499,6 +552,129 @@ func TestBucketStore_Series_ShouldQueryBlockWithOutOfOrderChunks(t *testing.T) {
 	}
 }
 
+func TestBucketStores_MetricsMetadata(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+
+	storageDir := t.TempDir()
+	generateStorageBlock(t, storageDir, userID, "series_1", 10, 100, 15)
+	generateStorageBlock(t, storageDir, userID, "series_2", 100, 200, 15)
+
+	entries, err := os.ReadDir(filepath.Join(storageDir, userID))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	blockWithMetadata, blockWithoutMetadata := entries[0].Name(), entries[1].Name()
+
+	counter := mimirpb.MetricMetadata{Type: mimirpb.COUNTER, MetricFamilyName: "series_1", Help: "Series 1."}
+	require.NoError(t, mimir_tsdb.WriteMetricMetadataFile(filepath.Join(storageDir, userID, blockWithMetadata), []mimirpb.MetricMetadata{counter}))
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bucket, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, stores.InitialSync(ctx))
+
+	t.Run("should return the metadata of the requested blocks loaded by the store", func(t *testing.T) {
+		unknownBlock := ulid.MustNew(1, nil).String()
+
+		resp, err := stores.MetricsMetadata(setUserIDToGRPCContext(ctx, userID), &storegatewaypb.MetricsMetadataRequest{
+			BlockIds: []string{blockWithMetadata, blockWithoutMetadata, unknownBlock},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*mimirpb.MetricMetadata{&counter}, resp.Metadata)
+		assert.Equal(t, []string{blockWithMetadata, blockWithoutMetadata}, resp.QueriedBlocks)
+	})
+
+	t.Run("should return no metadata for a tenant without blocks", func(t *testing.T) {
+		resp, err := stores.MetricsMetadata(setUserIDToGRPCContext(ctx, "user-2"), &storegatewaypb.MetricsMetadataRequest{
+			BlockIds: []string{blockWithMetadata},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Metadata)
+		assert.Empty(t, resp.QueriedBlocks)
+	})
+
+	t.Run("should fail on invalid block ID", func(t *testing.T) {
+		_, err := stores.MetricsMetadata(setUserIDToGRPCContext(ctx, userID), &storegatewaypb.MetricsMetadataRequest{
+			BlockIds: []string{"invalid"},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestBucketStores_Exemplars(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+
+	storageDir := t.TempDir()
+	generateStorageBlock(t, storageDir, userID, "series_1", 10, 100, 15)
+	generateStorageBlock(t, storageDir, userID, "series_2", 100, 200, 15)
+
+	entries, err := os.ReadDir(filepath.Join(storageDir, userID))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	blockWithExemplars, blockWithoutExemplars := entries[0].Name(), entries[1].Name()
+
+	exemplarAt := func(traceID string, ts int64) mimirpb.Exemplar {
+		return mimirpb.Exemplar{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: traceID}}, Value: 1, TimestampMs: ts}
+	}
+	series1 := []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "series_1"}}
+	series2 := []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "series_2"}}
+	require.NoError(t, mimir_tsdb.WriteExemplarsFile(filepath.Join(storageDir, userID, blockWithExemplars), []mimirpb.TimeSeries{
+		{Labels: series1, Exemplars: []mimirpb.Exemplar{exemplarAt("1", 20), exemplarAt("2", 50)}},
+		{Labels: series2, Exemplars: []mimirpb.Exemplar{exemplarAt("3", 30)}},
+	}))
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bucket, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, stores.InitialSync(ctx))
+
+	t.Run("should return the matching exemplars of the requested blocks loaded by the store", func(t *testing.T) {
+		unknownBlock := ulid.MustNew(1, nil).String()
+
+		resp, err := stores.Exemplars(setUserIDToGRPCContext(ctx, userID), &storegatewaypb.ExemplarsRequest{
+			BlockIds: []string{blockWithExemplars, blockWithoutExemplars, unknownBlock},
+			Start:    10,
+			End:      40,
+			Matchers: []string{`{__name__="series_1"}`, `{__name__="series_2"}`},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []mimirpb.TimeSeries{
+			{Labels: series1, Exemplars: []mimirpb.Exemplar{exemplarAt("1", 20)}},
+			{Labels: series2, Exemplars: []mimirpb.Exemplar{exemplarAt("3", 30)}},
+		}, resp.Timeseries)
+		assert.Equal(t, []string{blockWithExemplars, blockWithoutExemplars}, resp.QueriedBlocks)
+	})
+
+	t.Run("should return no exemplars for a tenant without blocks", func(t *testing.T) {
+		resp, err := stores.Exemplars(setUserIDToGRPCContext(ctx, "user-2"), &storegatewaypb.ExemplarsRequest{
+			BlockIds: []string{blockWithExemplars},
+			Start:    10,
+			End:      40,
+			Matchers: []string{`{__name__="series_1"}`},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Timeseries)
+		assert.Empty(t, resp.QueriedBlocks)
+	})
+
+	t.Run("should fail on invalid series selector", func(t *testing.T) {
+		_, err := stores.Exemplars(setUserIDToGRPCContext(ctx, userID), &storegatewaypb.ExemplarsRequest{
+			BlockIds: []string{blockWithExemplars},
+			Matchers: []string{`{__name__=}`},
+		})
+		require.Error(t, err)
+	})
+}
+
 func prepareStorageConfig(t *testing.T) mimir_tsdb.BlocksStorageConfig {
 	tmpDir := t.TempDir()
 
@@ -710,6 +886,47 @@ func (u *userShardingStrategy) FilterBlocks(ctx context.Context, userID string,
 	return nil
 }
 
+// recordIndexReadsBucket is an objstore.Bucket wrapper which records the IDs of the blocks whose index
+// is read, in the order of the first read.
+type recordIndexReadsBucket struct {
+	objstore.Bucket
+
+	onFirstRead func()
+
+	mtx    sync.Mutex
+	blocks []string
+}
+
+func (r *recordIndexReadsBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if path.Base(name) == block.IndexFilename {
+		r.recordRead(path.Base(path.Dir(name)))
+	}
+
+	return r.Bucket.GetRange(ctx, name, off, length)
+}
+
+func (r *recordIndexReadsBucket) recordRead(blockID string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if len(r.blocks) == 0 && r.onFirstRead != nil {
+		r.onFirstRead()
+	}
+	for _, id := range r.blocks {
+		if id == blockID {
+			return
+		}
+	}
+	r.blocks = append(r.blocks, blockID)
+}
+
+func (r *recordIndexReadsBucket) readBlocks() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return append([]string(nil), r.blocks...)
+}
+
 // failFirstGetBucket is an objstore.Bucket wrapper which fails the first Get() request with a mocked error.
 type failFirstGetBucket struct {
 	objstore.Bucket
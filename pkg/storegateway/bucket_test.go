@@ -61,6 +61,7 @@ import (
 	"github.com/grafana/mimir/pkg/compactor"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bloom"
 	"github.com/grafana/mimir/pkg/storegateway/indexcache"
 	"github.com/grafana/mimir/pkg/storegateway/indexheader"
 	"github.com/grafana/mimir/pkg/util/test"
@@ -704,6 +705,30 @@ func TestBlockLabelValues(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, []string{"bar"}, values)
 	})
+
+	t.Run("happy case with matchers selecting fewer series than the label values", func(t *testing.T) {
+		b := newTestBucketBlock()
+
+		// The matchers select 20 series, while the label has 40 values.
+		matchers := []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, "i", "0"+labelLongSuffix),
+			labels.MustNewMatcher(labels.MatchEqual, "j", "foo"),
+		}
+
+		var expected []string
+		for n := 0; n < 10; n++ {
+			expected = append(expected, strconv.Itoa(n)+labelLongSuffix, "2_"+strconv.Itoa(n)+labelLongSuffix)
+		}
+		sort.Strings(expected)
+
+		indexr := b.indexReader()
+		values, err := blockLabelValues(context.Background(), indexr, "n", matchers, log.NewNopLogger())
+		require.NoError(t, err)
+		require.Equal(t, expected, values)
+
+		// The values have been read from the matched series.
+		require.Equal(t, 20, indexr.stats.seriesTouched)
+	})
 }
 
 type cacheNotExpectingToStoreLabelValues struct {
@@ -1497,16 +1522,17 @@ func TestBucketSeries_OneBlock_InMemIndexCacheSegfault(t *testing.T) {
 		bkt:             objstore.WithNoopInstr(bkt),
 		logger:          logger,
 		indexCache:      indexCache,
-		indexReaderPool: indexheader.NewReaderPool(log.NewNopLogger(), false, 0, indexheader.NewReaderPoolMetrics(nil)),
+		indexReaderPool: indexheader.NewReaderPool(log.NewNopLogger(), false, 0, nil, indexheader.NewReaderPoolMetrics(nil)),
 		metrics:         NewBucketStoreMetrics(nil),
 		blockSet:        &bucketBlockSet{blocks: [][]*bucketBlock{{b1, b2}}},
 		blocks: map[ulid.ULID]*bucketBlock{
 			b1.meta.ULID: b1,
 			b2.meta.ULID: b2,
 		},
-		queryGate:            gate.NewNoop(),
-		chunksLimiterFactory: NewChunksLimiterFactory(0),
-		seriesLimiterFactory: NewSeriesLimiterFactory(0),
+		queryGate:                 gate.NewNoop(),
+		chunksLimiterFactory:      NewChunksLimiterFactory(0),
+		chunksBytesLimiterFactory: NewBytesLimiterFactory(0),
+		seriesLimiterFactory:      NewSeriesLimiterFactory(0),
 	}
 
 	t.Run("invoke series for one block. Fill the cache on the way.", func(t *testing.T) {
@@ -1620,6 +1646,115 @@ func TestSeries_RequestAndResponseHints(t *testing.T) {
 	runTestServerSeries(tb, store, testCases...)
 }
 
+func TestSeries_BloomFilter(t *testing.T) {
+	tb := test.NewTB(t)
+	tmpDir := t.TempDir()
+
+	bktDir := filepath.Join(tmpDir, "bkt")
+	bkt, err := filesystem.NewBucket(bktDir)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, bkt.Close()) }()
+
+	var (
+		logger   = log.NewNopLogger()
+		instrBkt = objstore.WithNoopInstr(bkt)
+		random   = rand.New(rand.NewSource(120))
+	)
+
+	// Create two blocks, with a bloom filter over the "i" label values.
+	var blockIDs []ulid.ULID
+	var seriesSets [][]*storepb.Series
+	for j := 0; j < 2; j++ {
+		head, seriesSet := createHeadWithSeries(t, j, headGenOptions{
+			TSDBDir:          filepath.Join(tmpDir, strconv.Itoa(j)),
+			SamplesPerSeries: 1,
+			Series:           2,
+			Random:           random,
+		})
+		blockID := createBlockFromHead(t, bktDir, head)
+		require.NoError(t, head.Close())
+
+		blockDir := filepath.Join(bktDir, blockID.String())
+		_, err := metadata.InjectThanos(logger, blockDir, metadata.Thanos{Source: metadata.TestSource}, nil)
+		require.NoError(t, err)
+
+		filter, err := bloom.BuildFromIndex(filepath.Join(blockDir, block.IndexFilename), []string{"i"}, bloom.DefaultFalsePositiveRate)
+		require.NoError(t, err)
+		require.NoError(t, bloom.WriteFile(blockDir, filter))
+
+		blockIDs = append(blockIDs, blockID)
+		seriesSets = append(seriesSets, seriesSet)
+	}
+
+	fetcher, err := block.NewMetaFetcher(logger, 10, instrBkt, tmpDir, nil, nil)
+	require.NoError(t, err)
+
+	metrics := NewBucketStoreMetrics(nil)
+	store, err := NewBucketStore(
+		"tenant",
+		instrBkt,
+		fetcher,
+		tmpDir,
+		NewChunksLimiterFactory(10000/MaxSamplesPerChunk),
+		NewSeriesLimiterFactory(0),
+		newGapBasedPartitioner(mimir_tsdb.DefaultPartitionerMaxGapSize, nil),
+		10,
+		mimir_tsdb.DefaultPostingOffsetInMemorySampling,
+		indexheader.BinaryReaderConfig{},
+		true,
+		false,
+		0,
+		hashcache.NewSeriesHashCache(1024*1024),
+		metrics,
+		WithLogger(logger),
+		WithBloomFilters(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, store.SyncBlocks(context.Background()))
+	defer func() { assert.NoError(t, store.RemoveBlocksAndClose()) }()
+
+	runTestServerSeries(tb, store,
+		&seriesCase{
+			Name: "equality matcher on a label value in a single block should skip the other block",
+			Req: &storepb.SeriesRequest{
+				MinTime: 0,
+				MaxTime: 3,
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "i", Value: fmt.Sprintf("%07d%s", 2, labelLongSuffix)},
+				},
+			},
+			ExpectedSeries: seriesSets[1][:1],
+			// The skipped block is reported as queried, otherwise the querier's consistency check
+			// would retry it on the other store-gateways.
+			ExpectedHints: hintspb.SeriesResponseHints{
+				QueriedBlocks: []hintspb.Block{
+					{Id: blockIDs[0].String()},
+					{Id: blockIDs[1].String()},
+				},
+			},
+		},
+		&seriesCase{
+			Name: "matchers not covered by the bloom filter should query all blocks",
+			Req: &storepb.SeriesRequest{
+				MinTime: 0,
+				MaxTime: 3,
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
+				},
+			},
+			ExpectedSeries: append(append([]*storepb.Series{}, seriesSets[0]...), seriesSets[1]...),
+			ExpectedHints: hintspb.SeriesResponseHints{
+				QueriedBlocks: []hintspb.Block{
+					{Id: blockIDs[0].String()},
+					{Id: blockIDs[1].String()},
+				},
+			},
+		},
+	)
+
+	assert.Equal(t, float64(1), promtest.ToFloat64(metrics.seriesBlocksSkipped))
+}
+
 func TestSeries_ErrorUnmarshallingRequestHints(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -2245,6 +2380,7 @@ func benchmarkBlockSeriesWithConcurrency(b *testing.B, concurrency int, blockMet
 
 	// No limits.
 	chunksLimiter := NewChunksLimiterFactory(0)(nil)
+	bytesLimiter := NewBytesLimiterFactory(0)(nil)
 	seriesLimiter := NewSeriesLimiterFactory(0)(nil)
 
 	// Create the series hash cached used when query sharding is enabled.
@@ -2295,7 +2431,7 @@ func benchmarkBlockSeriesWithConcurrency(b *testing.B, concurrency int, blockMet
 				require.NoError(b, err)
 
 				indexReader := blk.indexReader()
-				chunkReader := blk.chunkReader(ctx)
+				chunkReader := blk.chunkReader(ctx, bytesLimiter)
 
 				seriesSet, _, err := blockSeries(context.Background(), indexReader, chunkReader, matchers, shardSelector, seriesHashCache, chunksLimiter, seriesLimiter, req.SkipChunks, req.MinTime, req.MaxTime, req.Aggregates, log.NewNopLogger())
 				require.NoError(b, err)
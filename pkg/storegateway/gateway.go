@@ -9,6 +9,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/go-kit/log"
@@ -25,7 +26,6 @@ import (
 	"github.com/weaveworks/common/logging"
 	"github.com/weaveworks/common/tracing"
 
-	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
 	"github.com/grafana/mimir/pkg/util"
@@ -45,17 +45,23 @@ const (
 
 var (
 	// Validation errors.
-	errInvalidTenantShardSize = errors.New("invalid tenant shard size, the value must be greater or equal to 0")
+	errInvalidTenantShardSize         = errors.New("invalid tenant shard size, the value must be greater or equal to 0")
+	errInvalidTenantReplicationFactor = errors.New("invalid tenant replication factor, the value must be greater or equal to 0")
 )
 
 // Config holds the store gateway config.
 type Config struct {
-	ShardingRing RingConfig `yaml:"sharding_ring" doc:"description=The hash ring configuration."`
+	ShardingRing       RingConfig               `yaml:"sharding_ring" doc:"description=The hash ring configuration."`
+	LoadedBlocksClient LoadedBlocksClientConfig `yaml:"loaded_blocks_client" doc:"description=Configures the client used to request the blocks loaded by the other store-gateways to their HTTP server."`
+
+	// Injected internally, used to request the loaded blocks to the other store-gateways.
+	HTTPListenPort int `yaml:"-"`
 }
 
 // RegisterFlags registers the Config flags.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.ShardingRing.RegisterFlags(f, logger)
+	cfg.LoadedBlocksClient.RegisterFlagsWithPrefix(f, "store-gateway.loaded-blocks-client.")
 }
 
 // Validate the Config.
@@ -63,6 +69,9 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 	if limits.StoreGatewayTenantShardSize < 0 {
 		return errInvalidTenantShardSize
 	}
+	if limits.StoreGatewayTenantReplicationFactor < 0 {
+		return errInvalidTenantReplicationFactor
+	}
 
 	return nil
 }
@@ -79,10 +88,16 @@ type StoreGateway struct {
 	stores     *BucketStores
 	tracker    *activitytracker.ActivityTracker
 
+	// Client used to request the blocks loaded by the other store-gateways.
+	loadedBlocksClient *http.Client
+
 	// Ring used for sharding blocks.
 	ringLifecycler *ring.BasicLifecycler
 	ring           *ring.Ring
 
+	// Interrupts the shutdown handover period, if enabled.
+	cancelShutdownHandover context.CancelFunc
+
 	// Subservices manager (ring, lifecycler)
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -125,6 +140,11 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 		}, []string{"reason"}),
 	}
 
+	g.loadedBlocksClient, err = newLoadedBlocksClient(gatewayCfg.LoadedBlocksClient)
+	if err != nil {
+		return nil, err
+	}
+
 	// Init metrics.
 	g.bucketSync.WithLabelValues(syncReasonInitial)
 	g.bucketSync.WithLabelValues(syncReasonPeriodic)
@@ -141,6 +161,11 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 	// Define lifecycler delegates in reverse order (last to be called defined first because they're
 	// chained via "next delegate").
 	delegate := ring.BasicLifecyclerDelegate(ring.NewInstanceRegisterDelegate(ring.JOINING, RingNumTokens))
+	if gatewayCfg.ShardingRing.ShutdownHandoverPeriod > 0 {
+		var handoverCtx context.Context
+		handoverCtx, g.cancelShutdownHandover = context.WithCancel(context.Background())
+		delegate = newShutdownHandoverDelegate(handoverCtx, gatewayCfg.ShardingRing.ShutdownHandoverPeriod, delegate, logger)
+	}
 	delegate = ring.NewLeaveOnStoppingDelegate(delegate, logger)
 	delegate = ring.NewTokensPersistencyDelegate(gatewayCfg.ShardingRing.TokensFilePath, ring.JOINING, delegate, logger)
 	delegate = ring.NewAutoForgetDelegate(ringAutoForgetUnhealthyPeriods*gatewayCfg.ShardingRing.HeartbeatTimeout, delegate, logger)
@@ -156,7 +181,7 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 		return nil, errors.Wrap(err, "create ring client")
 	}
 
-	shardingStrategy = NewShuffleShardingStrategy(g.ring, lifecyclerCfg.ID, lifecyclerCfg.Addr, limits, logger)
+	shardingStrategy = NewShuffleShardingStrategy(g.ring, lifecyclerCfg.ID, lifecyclerCfg.Addr, limits, gatewayCfg.ShardingRing.ShutdownHandoverPeriod > 0, logger)
 
 	g.stores, err = NewBucketStores(storageCfg, shardingStrategy, bucketClient, limits, logLevel, logger, extprom.WrapRegistererWith(prometheus.Labels{"component": "store-gateway"}, reg))
 	if err != nil {
@@ -277,13 +302,27 @@ func (g *StoreGateway) running(ctx context.Context) error {
 	}
 }
 
-func (g *StoreGateway) stopping(_ error) error {
+func (g *StoreGateway) stopping(failureCase error) error {
+	if g.cancelShutdownHandover != nil {
+		// A failed store-gateway can't be relied upon to serve its blocks during the handover period.
+		if failureCase != nil {
+			g.cancelShutdownHandover()
+		}
+		defer g.cancelShutdownHandover()
+	}
+
 	if g.subservices != nil {
 		return services.StopManagerAndAwaitStopped(context.Background(), g.subservices)
 	}
 	return nil
 }
 
+// StartupProgress returns the progress of the initial blocks synchronization run at startup,
+// or an empty string if it's not running.
+func (g *StoreGateway) StartupProgress() string {
+	return g.stores.InitialSyncProgress()
+}
+
 func (g *StoreGateway) syncStores(ctx context.Context, reason string) {
 	level.Info(g.logger).Log("msg", "synchronizing TSDB blocks for all users", "reason", reason)
 	g.bucketSync.WithLabelValues(reason).Inc()
@@ -324,6 +363,26 @@ func (g *StoreGateway) LabelValues(ctx context.Context, req *storepb.LabelValues
 	return g.stores.LabelValues(ctx, req)
 }
 
+// MetricsMetadata implements the Storegateway proto service.
+func (g *StoreGateway) MetricsMetadata(ctx context.Context, req *storegatewaypb.MetricsMetadataRequest) (*storegatewaypb.MetricsMetadataResponse, error) {
+	ix := g.tracker.Insert(func() string {
+		return requestActivity(ctx, "StoreGateway/MetricsMetadata", req)
+	})
+	defer g.tracker.Delete(ix)
+
+	return g.stores.MetricsMetadata(ctx, req)
+}
+
+// Exemplars implements the Storegateway proto service.
+func (g *StoreGateway) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	ix := g.tracker.Insert(func() string {
+		return requestActivity(ctx, "StoreGateway/Exemplars", req)
+	})
+	defer g.tracker.Delete(ix)
+
+	return g.stores.Exemplars(ctx, req)
+}
+
 func requestActivity(ctx context.Context, name string, req interface{}) string {
 	user := getUserIDFromGRPCContext(ctx)
 	traceID, _ := tracing.ExtractSampledTraceID(ctx)
@@ -331,7 +390,7 @@ func requestActivity(ctx context.Context, name string, req interface{}) string {
 }
 
 func createBucketClient(cfg mimir_tsdb.BlocksStorageConfig, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
-	bucketClient, err := bucket.NewClient(context.Background(), cfg.Bucket, "store-gateway", logger, reg)
+	bucketClient, err := mimir_tsdb.NewBucketClient(context.Background(), cfg, "store-gateway", logger, reg)
 	if err != nil {
 		return nil, errors.Wrap(err, "create bucket client")
 	}
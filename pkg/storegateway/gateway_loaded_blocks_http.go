@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	_ "embed" // Used to embed html template
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/crypto/tls"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/block"
+
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// loadedBlocksPath is the path of the page listing the blocks loaded by the store-gateway.
+	loadedBlocksPath = "/store-gateway/loaded-blocks"
+
+	// loadedBlocksRingTimeout is the timeout of the requests to the other store-gateways, when
+	// assembling the global view of the loaded blocks.
+	loadedBlocksRingTimeout = 10 * time.Second
+)
+
+// LoadedBlocksClientConfig configures the client used to request the blocks loaded by the other store-gateways.
+type LoadedBlocksClientConfig struct {
+	HTTPPort   int              `yaml:"http_port" category:"experimental"`
+	TLSEnabled bool             `yaml:"tls_enabled" category:"experimental"`
+	TLS        tls.ClientConfig `yaml:",inline"`
+}
+
+func (cfg *LoadedBlocksClientConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.IntVar(&cfg.HTTPPort, prefix+"http-port", 0, "The port of the HTTP server of the other store-gateways. 0 to use the HTTP port of this store-gateway.")
+	f.BoolVar(&cfg.TLSEnabled, prefix+"tls-enabled", false, "Enable requesting the loaded blocks to the other store-gateways over HTTPS.")
+	cfg.TLS.RegisterFlagsWithPrefix(strings.TrimSuffix(prefix, "."), f)
+}
+
+// newLoadedBlocksClient returns the HTTP client used to request the blocks loaded by the other store-gateways.
+func newLoadedBlocksClient(cfg LoadedBlocksClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLSEnabled {
+		tlsConfig, err := cfg.TLS.GetTLSConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the loaded blocks client TLS config")
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+//go:embed loaded_blocks.gohtml
+var loadedBlocksPageHTML string
+var loadedBlocksPageTemplate = template.Must(template.New("webpage").Parse(loadedBlocksPageHTML))
+
+type loadedBlocksPageContents struct {
+	Now       time.Time              `json:"now"`
+	Tenant    string                 `json:"tenant,omitempty"`
+	Global    bool                   `json:"global"`
+	Instances []instanceLoadedBlocks `json:"instances"`
+}
+
+type instanceLoadedBlocks struct {
+	InstanceID string               `json:"instanceId"`
+	Address    string               `json:"address"`
+	Error      string               `json:"error,omitempty"`
+	Tenants    []tenantLoadedBlocks `json:"tenants"`
+}
+
+type tenantLoadedBlocks struct {
+	Tenant string        `json:"tenant"`
+	Blocks []loadedBlock `json:"blocks"`
+}
+
+type loadedBlock struct {
+	ULID    ulid.ULID `json:"ulid"`
+	MinTime time.Time `json:"minTime"`
+	MaxTime time.Time `json:"maxTime"`
+
+	// The index-header state. When the lazy loading is disabled, the index-header is always loaded
+	// and the last used time isn't tracked.
+	IndexHeaderLazyLoading  bool       `json:"indexHeaderLazyLoading"`
+	IndexHeaderLoaded       bool       `json:"indexHeaderLoaded"`
+	IndexHeaderSizeBytes    int64      `json:"indexHeaderSizeBytes"`
+	IndexHeaderLastUsedTime *time.Time `json:"indexHeaderLastUsedTime,omitempty"`
+}
+
+// LoadedBlocksHandler lists the blocks loaded by this store-gateway for each tenant, or by all
+// the healthy store-gateways in the ring if the "global" parameter is set. The view can be
+// restricted to a single tenant with the "tenant" parameter.
+func (g *StoreGateway) LoadedBlocksHandler(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		util.WriteTextResponse(w, fmt.Sprintf("Can't parse form: %s", err))
+		return
+	}
+
+	tenantID := req.Form.Get("tenant")
+	global, _ := strconv.ParseBool(req.Form.Get("global"))
+
+	local := instanceLoadedBlocks{
+		InstanceID: g.ringLifecycler.GetInstanceID(),
+		Address:    g.ringLifecycler.GetInstanceAddr(),
+		Tenants:    g.stores.loadedBlocks(tenantID),
+	}
+
+	instances := []instanceLoadedBlocks{local}
+	if global {
+		var err error
+		if instances, err = g.ringLoadedBlocks(req.Context(), local, tenantID); err != nil {
+			util.WriteTextResponse(w, fmt.Sprintf("Can't read the store-gateways from the ring: %s", err))
+			return
+		}
+	}
+
+	util.RenderHTTPResponse(w, loadedBlocksPageContents{
+		Now:       time.Now(),
+		Tenant:    tenantID,
+		Global:    global,
+		Instances: instances,
+	}, loadedBlocksPageTemplate, req)
+}
+
+// ringLoadedBlocks returns the blocks loaded by this store-gateway and all the healthy store-gateways in
+// the ring. The blocks loaded by the other store-gateways are requested to their HTTP server, which listens
+// on the port configured by the loaded blocks client, or on the same port of this store-gateway by default.
+// This store-gateway is always included, even if it isn't healthy in the ring, e.g. while it's leaving.
+func (g *StoreGateway) ringLoadedBlocks(ctx context.Context, local instanceLoadedBlocks, tenantID string) ([]instanceLoadedBlocks, error) {
+	rs, err := g.ring.GetAllHealthy(BlocksOwnerSync)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]instanceLoadedBlocks, len(rs.Instances))
+	if !rs.Includes(local.Address) {
+		instances = append(instances, local)
+	}
+
+	err = concurrency.ForEachJob(ctx, len(rs.Instances), len(rs.Instances), func(ctx context.Context, idx int) error {
+		addr := rs.Instances[idx].Addr
+		if addr == local.Address {
+			instances[idx] = local
+			return nil
+		}
+
+		instance, err := g.fetchInstanceLoadedBlocks(ctx, addr, tenantID)
+		if err != nil {
+			level.Warn(g.logger).Log("msg", "failed to read the loaded blocks of a store-gateway", "addr", addr, "err", err)
+			instances[idx] = instanceLoadedBlocks{Address: addr, Error: err.Error()}
+			return nil
+		}
+
+		// The address is the one registered in the ring, used to request the instance.
+		instance.Address = addr
+		instances[idx] = instance
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Address < instances[j].Address
+	})
+
+	return instances, nil
+}
+
+func (g *StoreGateway) fetchInstanceLoadedBlocks(ctx context.Context, addr, tenantID string) (instanceLoadedBlocks, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return instanceLoadedBlocks{}, err
+	}
+
+	query := url.Values{}
+	if tenantID != "" {
+		query.Set("tenant", tenantID)
+	}
+	port := g.gatewayCfg.LoadedBlocksClient.HTTPPort
+	if port == 0 {
+		port = g.gatewayCfg.HTTPListenPort
+	}
+	scheme := "http"
+	if g.gatewayCfg.LoadedBlocksClient.TLSEnabled {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     loadedBlocksPath,
+		RawQuery: query.Encode(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, loadedBlocksRingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return instanceLoadedBlocks{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.loadedBlocksClient.Do(req)
+	if err != nil {
+		return instanceLoadedBlocks{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return instanceLoadedBlocks{}, errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var contents loadedBlocksPageContents
+	if err := json.NewDecoder(resp.Body).Decode(&contents); err != nil {
+		return instanceLoadedBlocks{}, errors.Wrap(err, "decode response")
+	}
+	if len(contents.Instances) != 1 {
+		return instanceLoadedBlocks{}, errors.Errorf("unexpected number of store-gateways in the response: %d", len(contents.Instances))
+	}
+
+	return contents.Instances[0], nil
+}
+
+// loadedBlocks returns the blocks loaded for each tenant, sorted by tenant, or for the given tenant only if not empty.
+func (u *BucketStores) loadedBlocks(tenantID string) []tenantLoadedBlocks {
+	u.storesMu.RLock()
+	stores := make(map[string]*BucketStore, len(u.stores))
+	for userID, store := range u.stores {
+		if tenantID == "" || userID == tenantID {
+			stores[userID] = store
+		}
+	}
+	u.storesMu.RUnlock()
+
+	tenants := make([]tenantLoadedBlocks, 0, len(stores))
+	for userID, store := range stores {
+		tenants = append(tenants, tenantLoadedBlocks{
+			Tenant: userID,
+			Blocks: store.loadedBlocks(),
+		})
+	}
+
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].Tenant < tenants[j].Tenant
+	})
+
+	return tenants
+}
+
+// loadedBlocks returns the loaded blocks, sorted by time.
+func (s *BucketStore) loadedBlocks() []loadedBlock {
+	s.mtx.RLock()
+	blocks := make([]*bucketBlock, 0, len(s.blocks))
+	for _, b := range s.blocks {
+		blocks = append(blocks, b)
+	}
+	s.mtx.RUnlock()
+
+	res := make([]loadedBlock, 0, len(blocks))
+	for _, b := range blocks {
+		lb := loadedBlock{
+			ULID:              b.meta.ULID,
+			MinTime:           util.TimeFromMillis(b.meta.MinTime).UTC(),
+			MaxTime:           util.TimeFromMillis(b.meta.MaxTime).UTC(),
+			IndexHeaderLoaded: true,
+		}
+
+		if info, err := os.Stat(filepath.Join(b.dir, block.IndexHeaderFilename)); err == nil {
+			lb.IndexHeaderSizeBytes = info.Size()
+		}
+
+		if r, ok := b.indexHeaderReader.(*indexheader.LazyBinaryReader); ok {
+			lastUsed := r.LastUsedTime().UTC()
+			lb.IndexHeaderLazyLoading = true
+			lb.IndexHeaderLoaded = r.IsLoaded()
+			lb.IndexHeaderLastUsedTime = &lastUsed
+		}
+
+		res = append(res, lb)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].MinTime.Equal(res[j].MinTime) {
+			return res[i].ULID.Compare(res[j].ULID) < 0
+		}
+		return res[i].MinTime.Before(res[j].MinTime)
+	})
+
+	return res
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestStoreGateway_LoadedBlocksHandler(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	ctx := context.Background()
+	storageDir := t.TempDir()
+
+	now := time.Now()
+	minT := now.Add(-1*time.Hour).Unix() * 1000
+	maxT := now.Unix() * 1000
+	mockTSDB(t, path.Join(storageDir, "user-1"), 1, 0, minT-(4*time.Hour).Milliseconds(), maxT-(4*time.Hour).Milliseconds())
+	mockTSDB(t, path.Join(storageDir, "user-1"), 1, 0, minT, maxT)
+	mockTSDB(t, path.Join(storageDir, "user-2"), 1, 0, minT, maxT)
+
+	bucketClient, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	g, err := newStoreGateway(mockGatewayConfig(), mockStorageConfig(t), bucketClient, ringStore, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, g)) })
+
+	server := httptest.NewServer(http.HandlerFunc(g.LoadedBlocksHandler))
+	t.Cleanup(server.Close)
+
+	t.Run("should list the blocks loaded by the store-gateway", func(t *testing.T) {
+		contents := getLoadedBlocks(t, server.URL, url.Values{})
+		require.Len(t, contents.Instances, 1)
+
+		instance := contents.Instances[0]
+		assert.Equal(t, g.ringLifecycler.GetInstanceID(), instance.InstanceID)
+		assert.Empty(t, instance.Error)
+		require.Len(t, instance.Tenants, 2)
+		assert.Equal(t, "user-1", instance.Tenants[0].Tenant)
+		assert.Len(t, instance.Tenants[0].Blocks, 2)
+		assert.Equal(t, "user-2", instance.Tenants[1].Tenant)
+		assert.Len(t, instance.Tenants[1].Blocks, 1)
+
+		// The index-headers are lazy loaded, so they're not loaded until queried.
+		for _, tenant := range instance.Tenants {
+			for _, b := range tenant.Blocks {
+				assert.True(t, b.IndexHeaderLazyLoading)
+				assert.False(t, b.IndexHeaderLoaded)
+				assert.Greater(t, b.IndexHeaderSizeBytes, int64(0))
+				assert.NotNil(t, b.IndexHeaderLastUsedTime)
+			}
+		}
+	})
+
+	t.Run("should report the index-headers loaded once queried", func(t *testing.T) {
+		srv := newBucketStoreSeriesServer(setUserIDToGRPCContext(ctx, "user-2"))
+		require.NoError(t, g.Series(&storepb.SeriesRequest{
+			MinTime: minT,
+			MaxTime: maxT,
+			Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: ".*"},
+			},
+		}, srv))
+
+		contents := getLoadedBlocks(t, server.URL, url.Values{"tenant": {"user-2"}})
+		require.Len(t, contents.Instances, 1)
+		require.Len(t, contents.Instances[0].Tenants, 1)
+
+		tenant := contents.Instances[0].Tenants[0]
+		assert.Equal(t, "user-2", tenant.Tenant)
+		require.Len(t, tenant.Blocks, 1)
+		assert.True(t, tenant.Blocks[0].IndexHeaderLoaded)
+	})
+
+	t.Run("should list the blocks loaded by all the store-gateways in the ring", func(t *testing.T) {
+		contents := getLoadedBlocks(t, server.URL, url.Values{"global": {"true"}, "tenant": {"user-1"}})
+		assert.True(t, contents.Global)
+		require.Len(t, contents.Instances, 1)
+		assert.Equal(t, g.ringLifecycler.GetInstanceID(), contents.Instances[0].InstanceID)
+		require.Len(t, contents.Instances[0].Tenants, 1)
+		assert.Equal(t, "user-1", contents.Instances[0].Tenants[0].Tenant)
+	})
+
+	t.Run("should fetch the blocks loaded by another store-gateway from its HTTP server", func(t *testing.T) {
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(serverURL.Port())
+		require.NoError(t, err)
+
+		g.gatewayCfg.HTTPListenPort = port
+		instance, err := g.fetchInstanceLoadedBlocks(ctx, serverURL.Hostname()+":9095", "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, g.ringLifecycler.GetInstanceID(), instance.InstanceID)
+		require.Len(t, instance.Tenants, 1)
+		assert.Len(t, instance.Tenants[0].Blocks, 2)
+
+		// The port of the HTTP server of the other store-gateways can be configured.
+		g.gatewayCfg.HTTPListenPort = 1
+		g.gatewayCfg.LoadedBlocksClient.HTTPPort = port
+		_, err = g.fetchInstanceLoadedBlocks(ctx, serverURL.Hostname()+":9095", "user-1")
+		require.NoError(t, err)
+
+		// An unreachable store-gateway is reported as an error.
+		g.gatewayCfg.LoadedBlocksClient.HTTPPort = 1
+		_, err = g.fetchInstanceLoadedBlocks(ctx, serverURL.Hostname()+":9095", "user-1")
+		assert.Error(t, err)
+		g.gatewayCfg.LoadedBlocksClient.HTTPPort = 0
+	})
+
+	t.Run("should fetch the blocks loaded by another store-gateway from its HTTPS server", func(t *testing.T) {
+		tlsServer := httptest.NewTLSServer(http.HandlerFunc(g.LoadedBlocksHandler))
+		t.Cleanup(tlsServer.Close)
+
+		serverURL, err := url.Parse(tlsServer.URL)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(serverURL.Port())
+		require.NoError(t, err)
+
+		origCfg, origClient := g.gatewayCfg, g.loadedBlocksClient
+		t.Cleanup(func() { g.gatewayCfg, g.loadedBlocksClient = origCfg, origClient })
+
+		g.gatewayCfg.LoadedBlocksClient = LoadedBlocksClientConfig{HTTPPort: port, TLSEnabled: true}
+		g.gatewayCfg.LoadedBlocksClient.TLS.InsecureSkipVerify = true
+		g.loadedBlocksClient, err = newLoadedBlocksClient(g.gatewayCfg.LoadedBlocksClient)
+		require.NoError(t, err)
+
+		instance, err := g.fetchInstanceLoadedBlocks(ctx, serverURL.Hostname()+":9095", "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, g.ringLifecycler.GetInstanceID(), instance.InstanceID)
+	})
+
+	t.Run("should include this store-gateway even if it's not healthy in the ring", func(t *testing.T) {
+		// This store-gateway isn't in the ring with this address, like when it's leaving the ring.
+		local := instanceLoadedBlocks{InstanceID: "leaving", Address: "127.0.0.2:9095"}
+
+		g.gatewayCfg.LoadedBlocksClient.HTTPPort = 1
+		t.Cleanup(func() { g.gatewayCfg.LoadedBlocksClient.HTTPPort = 0 })
+
+		instances, err := g.ringLoadedBlocks(ctx, local, "user-1")
+		require.NoError(t, err)
+		require.Len(t, instances, 2)
+		assert.Equal(t, g.ringLifecycler.GetInstanceAddr(), instances[0].Address)
+		assert.NotEmpty(t, instances[0].Error)
+		assert.Equal(t, local, instances[1])
+	})
+}
+
+func getLoadedBlocks(t *testing.T, serverURL string, query url.Values) loadedBlocksPageContents {
+	req, err := http.NewRequest(http.MethodGet, serverURL+loadedBlocksPath+"?"+query.Encode(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var contents loadedBlocksPageContents
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&contents))
+	return contents
+}
@@ -17,8 +17,11 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/ringobserver"
 )
 
 const (
@@ -46,11 +49,26 @@ var (
 	// (replicas included).
 	BlocksOwnerSync = ring.NewOp([]ring.InstanceState{ring.JOINING, ring.ACTIVE, ring.LEAVING}, nil)
 
+	// BlocksOwnerHandover is the operation used to check the authoritative owners of a block (replicas included)
+	// when the shutdown handover is enabled. The replication set is extended when a LEAVING instance owns the
+	// block, so that the instance which will own it once the LEAVING one is gone can preload it.
+	BlocksOwnerHandover = ring.NewOp([]ring.InstanceState{ring.JOINING, ring.ACTIVE, ring.LEAVING}, func(s ring.InstanceState) bool {
+		return s == ring.LEAVING
+	})
+
 	// BlocksOwnerRead is the operation used to check the authoritative owners of a block
 	// (replicas included) that are available for queries (a store-gateway is available for
 	// queries only when ACTIVE).
 	BlocksOwnerRead = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 
+	// BlocksReadHandover is the operation run by the querier to query blocks via the store-gateway when the
+	// shutdown handover is enabled. A LEAVING store-gateway keeps its blocks loaded during the handover period,
+	// so it can be queried, while the replication set is extended to the instance which will own the block once
+	// the LEAVING one is gone, in case it has already left.
+	BlocksReadHandover = ring.NewOp([]ring.InstanceState{ring.ACTIVE, ring.LEAVING}, func(s ring.InstanceState) bool {
+		return s != ring.ACTIVE
+	})
+
 	// BlocksRead is the operation run by the querier to query blocks via the store-gateway.
 	BlocksRead = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, func(s ring.InstanceState) bool {
 		// Blocks can only be queried from ACTIVE instances. However, if the block belongs to
@@ -84,7 +102,8 @@ type RingConfig struct {
 	InstanceAddr           string   `yaml:"instance_addr" category:"advanced"`
 	InstanceZone           string   `yaml:"instance_availability_zone"`
 
-	UnregisterOnShutdown bool `yaml:"unregister_on_shutdown"`
+	UnregisterOnShutdown   bool          `yaml:"unregister_on_shutdown"`
+	ShutdownHandoverPeriod time.Duration `yaml:"shutdown_handover_period" category:"experimental"`
 
 	// Injected internally
 	ListenPort      int           `yaml:"-"`
@@ -123,6 +142,7 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.StringVar(&cfg.InstanceZone, ringFlagsPrefix+"instance-availability-zone", "", "The availability zone where this instance is running. Required if zone-awareness is enabled.")
 
 	f.BoolVar(&cfg.UnregisterOnShutdown, ringFlagsPrefix+"unregister-on-shutdown", true, "Unregister from the ring upon clean shutdown.")
+	f.DurationVar(&cfg.ShutdownHandoverPeriod, ringFlagsPrefix+"shutdown-handover-period", 0, "How long a store-gateway stays LEAVING in the ring when shutting down, before leaving the ring. While a store-gateway is LEAVING, it keeps being queried and the store-gateways which will own its blocks once it's gone preload them, so that the blocks are available for queries as soon as it leaves the ring. 0 to disable."+sharedOptionWithRingClient)
 
 	// Defaults for internal settings.
 	cfg.RingCheckPeriod = 5 * time.Second
@@ -160,3 +180,19 @@ func (cfg *RingConfig) ToLifecyclerConfig(logger log.Logger) (ring.BasicLifecycl
 		KeepInstanceInTheRingOnShutdown: !cfg.UnregisterOnShutdown,
 	}, nil
 }
+
+// NewRingWatcher returns a service watching the store-gateways ring, to observe its membership changes
+// without running the component itself.
+func NewRingWatcher(cfg RingConfig, logger log.Logger, reg prometheus.Registerer) (*ringobserver.Watcher, error) {
+	kvClient, err := kv.NewClient(
+		cfg.KVStore,
+		ring.GetCodec(),
+		kv.RegistererWithKVName(prometheus.WrapRegistererWithPrefix("cortex_", reg), "store-gateway-ring-watcher"),
+		logger,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "create KV store client")
+	}
+
+	return ringobserver.NewWatcher(RingNameForServer, RingKey, kvClient, logger), nil
+}
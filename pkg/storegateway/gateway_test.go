@@ -44,6 +44,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
@@ -78,6 +79,12 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expected: nil,
 		},
+		"should fail if tenant replication factor is negative": {
+			setup: func(cfg *Config, limits *validation.Limits) {
+				limits.StoreGatewayTenantReplicationFactor = -1
+			},
+			expected: errInvalidTenantReplicationFactor,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -595,6 +602,85 @@ func TestStoreGateway_ShouldSupportLoadRingTokensFromFile(t *testing.T) {
 	}
 }
 
+func TestStoreGateway_ShouldStayLeavingInTheRingDuringTheShutdownHandoverPeriod(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	ctx := context.Background()
+	gatewayCfg := mockGatewayConfig()
+	gatewayCfg.ShardingRing.ShutdownHandoverPeriod = time.Second
+
+	storageCfg := mockStorageConfig(t)
+	ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	bucketClient := &bucket.ClientMock{}
+	bucketClient.MockIter("", []string{}, nil)
+
+	g, err := newStoreGateway(gatewayCfg, storageCfg, bucketClient, ringStore, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+
+	getInstanceState := func() interface{} {
+		d, err := ringStore.Get(ctx, RingKey)
+		if err != nil || d == nil {
+			return nil
+		}
+		instance, ok := d.(*ring.Desc).Ingesters[gatewayCfg.ShardingRing.InstanceID]
+		if !ok {
+			return nil
+		}
+		return instance.State
+	}
+	assert.Equal(t, ring.ACTIVE, getInstanceState())
+
+	// The store-gateway is LEAVING in the ring during the handover period, then it leaves the ring.
+	stoppingStart := time.Now()
+	g.StopAsync()
+	dstest.Poll(t, 500*time.Millisecond, ring.LEAVING, getInstanceState)
+
+	require.NoError(t, g.AwaitTerminated(ctx))
+	assert.GreaterOrEqual(t, time.Since(stoppingStart), gatewayCfg.ShardingRing.ShutdownHandoverPeriod)
+	assert.Nil(t, getInstanceState())
+}
+
+func TestStoreGateway_ShouldLeaveTheRingOnceTheShutdownHandoverIsInterrupted(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	ctx := context.Background()
+	gatewayCfg := mockGatewayConfig()
+	gatewayCfg.ShardingRing.ShutdownHandoverPeriod = time.Hour
+
+	storageCfg := mockStorageConfig(t)
+	ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	bucketClient := &bucket.ClientMock{}
+	bucketClient.MockIter("", []string{}, nil)
+
+	g, err := newStoreGateway(gatewayCfg, storageCfg, bucketClient, ringStore, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+
+	getInstanceState := func() interface{} {
+		d, err := ringStore.Get(ctx, RingKey)
+		if err != nil || d == nil {
+			return nil
+		}
+		instance, ok := d.(*ring.Desc).Ingesters[gatewayCfg.ShardingRing.InstanceID]
+		if !ok {
+			return nil
+		}
+		return instance.State
+	}
+
+	g.StopAsync()
+	dstest.Poll(t, 500*time.Millisecond, ring.LEAVING, getInstanceState)
+
+	g.cancelShutdownHandover()
+	require.NoError(t, g.AwaitTerminated(ctx))
+	assert.Nil(t, getInstanceState())
+}
+
 func TestStoreGateway_SyncOnRingTopologyChanged(t *testing.T) {
 	test.VerifyNoLeak(t)
 
@@ -1368,6 +1454,152 @@ func TestStoreGateway_SeriesQueryingShouldEnforceMaxChunksPerQueryLimit(t *testi
 	}
 }
 
+func TestStoreGateway_SeriesQueryingShouldEnforceMaxFetchedChunkBytesPerRequestLimit(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	const numSeries = 10
+
+	tests := map[string]struct {
+		limit         int
+		expectedError bool
+	}{
+		"no limit enforced if zero": {
+			limit: 0,
+		},
+		"should return NO error if the size of the fetched chunks is <= limit": {
+			limit: 1024 * 1024,
+		},
+		"should return error if the size of the fetched chunks is > limit": {
+			limit:         1,
+			expectedError: true,
+		},
+	}
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	userID := "user-1"
+
+	storageDir := t.TempDir()
+
+	now := time.Now()
+	minT := now.Add(-1*time.Hour).Unix() * 1000
+	maxT := now.Unix() * 1000
+	mockTSDB(t, path.Join(storageDir, userID), numSeries, 0, minT, maxT)
+
+	bucketClient, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	req := &storepb.SeriesRequest{
+		MinTime: minT,
+		MaxTime: maxT,
+		Matchers: []storepb.LabelMatcher{
+			{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: ".*"},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			// Customise the limits.
+			limits := defaultLimitsConfig()
+			limits.StoreGatewayTenantMaxFetchedChunkBytesPerRequest = testData.limit
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			// Create a store-gateway used to query back the series from the blocks.
+			gatewayCfg := mockGatewayConfig()
+			storageCfg := mockStorageConfig(t)
+
+			ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+			t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+			g, err := newStoreGateway(gatewayCfg, storageCfg, bucketClient, ringStore, overrides, mockLoggingLevel(), logger, nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+			t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, g)) })
+
+			srv := newBucketStoreSeriesServer(setUserIDToGRPCContext(ctx, userID))
+			err = g.Series(req, srv)
+
+			if testData.expectedError {
+				require.Error(t, err)
+				s, ok := status.FromError(errors.Cause(err))
+				require.True(t, ok)
+				assert.Equal(t, codes.Code(http.StatusUnprocessableEntity), s.Code())
+				assert.Contains(t, s.Message(), "exceeded chunks bytes limit")
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, srv.Warnings)
+				assert.Len(t, srv.SeriesSet, numSeries)
+			}
+		})
+	}
+}
+
+func TestStoreGateway_SeriesQueryingShouldEnforceMaxConcurrentSeriesRequestsLimit(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	userID := "user-1"
+
+	storageDir := t.TempDir()
+
+	now := time.Now()
+	minT := now.Add(-1*time.Hour).Unix() * 1000
+	maxT := now.Unix() * 1000
+	mockTSDB(t, path.Join(storageDir, userID), 1, 0, minT, maxT)
+
+	bucketClient, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	limits := defaultLimitsConfig()
+	limits.StoreGatewayTenantMaxConcurrentSeriesRequests = 1
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	g, err := newStoreGateway(mockGatewayConfig(), mockStorageConfig(t), bucketClient, ringStore, overrides, mockLoggingLevel(), logger, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, g)) })
+
+	req := &storepb.SeriesRequest{
+		MinTime: minT,
+		MaxTime: maxT,
+		Matchers: []storepb.LabelMatcher{
+			{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: ".*"},
+		},
+	}
+
+	// The requests run one after the other are not limited.
+	for i := 0; i < 3; i++ {
+		srv := newBucketStoreSeriesServer(setUserIDToGRPCContext(ctx, userID))
+		require.NoError(t, g.Series(req, srv))
+		assert.Len(t, srv.SeriesSet, 1)
+	}
+
+	// Simulate a running request of the tenant.
+	done, err := g.stores.tenantSeriesLimiter.start(ctx, userID, 1)
+	require.NoError(t, err)
+
+	// The request of another tenant is not limited.
+	srv := newBucketStoreSeriesServer(setUserIDToGRPCContext(ctx, "user-2"))
+	require.NoError(t, g.Series(req, srv))
+
+	// The request of the tenant waits until the running request completes.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	srv = newBucketStoreSeriesServer(setUserIDToGRPCContext(timeoutCtx, userID))
+	assert.ErrorIs(t, g.Series(req, srv), context.DeadlineExceeded)
+
+	done()
+	srv = newBucketStoreSeriesServer(setUserIDToGRPCContext(ctx, userID))
+	require.NoError(t, g.Series(req, srv))
+	assert.Len(t, srv.SeriesSet, 1)
+}
+
 func mockGatewayConfig() Config {
 	cfg := Config{}
 	flagext.DefaultValues(&cfg)
@@ -0,0 +1,595 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexcache
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/thanos-io/thanos/pkg/model"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/grafana/mimir/pkg/storage/sharding"
+)
+
+const (
+	// diskTempFilePrefix is the prefix of the files being written, which are removed at startup.
+	diskTempFilePrefix = "tmp-"
+
+	// diskBlockSize is the size of the filesystem blocks: each item takes at least one block on disk, so the
+	// size of the items is accounted in whole blocks.
+	diskBlockSize = 4096
+
+	// diskItemHeaderSize is the size of the CRC32 checksum written before each item. The files aren't synced to
+	// the disk, so the items partially written before a crash are detected by their checksum when read.
+	diskItemHeaderSize = 4
+
+	// diskWriteQueueMaxItems and diskWriteQueueMaxSizeBytes bound the items waiting to be written to the disk.
+	// The items cached while the queue is full are not written.
+	diskWriteQueueMaxItems     = 10000
+	diskWriteQueueMaxSizeBytes = 256 * 1024 * 1024
+)
+
+var diskItemCastagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// DiskIndexCacheConfig holds the disk-based index cache config.
+type DiskIndexCacheConfig struct {
+	// Dir is the directory where the cached items are stored.
+	Dir string
+	// MaxSize represents overall maximum number of bytes cache can contain.
+	MaxSize model.Bytes
+	// MaxItemSize represents maximum size of single item.
+	MaxItemSize model.Bytes
+}
+
+// DiskIndexCache is an index cache storing each item in a file on the local disk, so that the cache can be
+// bigger than the memory and the cached items are still available after a restart. The items are written to
+// the disk in the background, and are found by the lookups once written. When the cache is full, the least
+// recently used items are removed. The items found on disk at startup are loaded in no particular order,
+// without reading the files, and are older than the items cached afterwards.
+type DiskIndexCache struct {
+	logger           log.Logger
+	dir              string
+	maxSizeBytes     uint64
+	maxItemSizeBytes uint64
+
+	// The LRU is keyed by the item key, see itemKey.
+	mtx     sync.Mutex
+	lru     *lru.LRU
+	curSize uint64
+	// The files of the items evicted from the LRU, removed once the mutex is released.
+	evictedPaths []string
+	// The items queued to be written to the disk, keyed like the LRU, and their total size.
+	pending     map[string]struct{}
+	pendingSize uint64
+
+	writes        chan diskCacheWrite
+	pendingWrites sync.WaitGroup
+	stopped       chan struct{}
+
+	evicted       *prometheus.CounterVec
+	requests      *prometheus.CounterVec
+	hits          *prometheus.CounterVec
+	added         *prometheus.CounterVec
+	overflow      *prometheus.CounterVec
+	failures      *prometheus.CounterVec
+	droppedWrites *prometheus.CounterVec
+	current       *prometheus.GaugeVec
+	currentSize   *prometheus.GaugeVec
+}
+
+// diskCacheEntry is the value of the LRU of the DiskIndexCache.
+type diskCacheEntry struct {
+	typ string
+	// size is the size of the item, without the header.
+	size uint64
+}
+
+// diskCacheWrite is an item queued to be written to the disk.
+type diskCacheWrite struct {
+	typ string
+	key string
+	val []byte
+}
+
+// NewDiskIndexCache creates a new thread-safe disk-based index cache, loading the items previously cached in
+// the directory, and ensures the total size of the cached items approximately does not exceed the max size.
+func NewDiskIndexCache(logger log.Logger, reg prometheus.Registerer, config DiskIndexCacheConfig) (*DiskIndexCache, error) {
+	if config.MaxItemSize > config.MaxSize {
+		return nil, errors.Errorf("max item size (%v) cannot be bigger than overall cache size (%v)", config.MaxItemSize, config.MaxSize)
+	}
+
+	c := &DiskIndexCache{
+		logger:           logger,
+		dir:              config.Dir,
+		maxSizeBytes:     uint64(config.MaxSize),
+		maxItemSizeBytes: uint64(config.MaxItemSize),
+		pending:          map[string]struct{}{},
+		writes:           make(chan diskCacheWrite, diskWriteQueueMaxItems),
+		stopped:          make(chan struct{}),
+	}
+
+	c.evicted = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_items_evicted_total",
+		Help: "Total number of items that were evicted from the index cache.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.evicted.MetricVec)
+
+	c.added = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_items_added_total",
+		Help: "Total number of items that were added to the index cache.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.added.MetricVec)
+
+	c.requests = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_requests_total",
+		Help: "Total number of requests to the cache.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.requests.MetricVec)
+
+	c.overflow = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_items_overflowed_total",
+		Help: "Total number of items that could not be added to the cache due to being too big.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.overflow.MetricVec)
+
+	c.hits = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_hits_total",
+		Help: "Total number of requests to the cache that were a hit.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.hits.MetricVec)
+
+	c.failures = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_disk_failures_total",
+		Help: "Total number of items that could not be read from or written to the disk.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.failures.MetricVec)
+
+	c.droppedWrites = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_disk_dropped_writes_total",
+		Help: "Total number of items that were not written to the disk because too many items were waiting to be written.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.droppedWrites.MetricVec)
+
+	c.current = promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_items",
+		Help: "Current number of items in the index cache.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.current.MetricVec)
+
+	c.currentSize = promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_items_size_bytes",
+		Help: "Current byte size of items in the index cache. The disk index cache accounts the items in whole filesystem blocks.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.currentSize.MetricVec)
+
+	_ = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_max_size_bytes",
+		Help: "Maximum number of bytes to be held in the index cache.",
+	}, func() float64 {
+		return float64(c.maxSizeBytes)
+	})
+	_ = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_max_item_size_bytes",
+		Help: "Maximum number of bytes for single entry to be held in the index cache.",
+	}, func() float64 {
+		return float64(c.maxItemSizeBytes)
+	})
+
+	// Initialize LRU cache with a high size limit since we will manage evictions ourselves
+	// based on stored size using `RemoveOldest` method.
+	l, err := lru.NewLRU(maxInt, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+
+	if err := c.load(); err != nil {
+		return nil, errors.Wrap(err, "load the disk index cache")
+	}
+	go c.writeLoop()
+
+	level.Info(logger).Log(
+		"msg", "created disk index cache",
+		"dir", c.dir,
+		"maxItemSizeBytes", c.maxItemSizeBytes,
+		"maxSizeBytes", c.maxSizeBytes,
+		"loadedItems", c.lru.Len(),
+		"loadedSizeBytes", c.curSize,
+	)
+	return c, nil
+}
+
+// load adds to the LRU the items found on disk, and removes the files left over by the writes interrupted by
+// a shutdown. The files are neither read nor stat'ed, because the size of the items is part of their file name.
+func (c *DiskIndexCache) load() error {
+	c.mtx.Lock()
+	defer func() {
+		c.ensureFits(0)
+		evicted := c.takeEvictedPaths()
+		c.mtx.Unlock()
+
+		c.removeFiles(evicted)
+	}()
+
+	for _, typ := range allCacheTypes {
+		typDir := filepath.Join(c.dir, typ)
+		if err := os.MkdirAll(typDir, os.ModePerm); err != nil {
+			return err
+		}
+
+		names, err := readDirNames(typDir)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			key, size, ok := parseItemFileName(typ, name)
+			if !ok {
+				// The temporary files, and the files of the items cached in a previous format, are removed.
+				if err := os.RemoveAll(filepath.Join(typDir, name)); err != nil {
+					level.Warn(c.logger).Log("msg", "failed to remove unexpected disk index cache file", "file", name, "err", err)
+				}
+				continue
+			}
+			c.add(key, diskCacheEntry{typ: typ, size: size})
+		}
+	}
+	return nil
+}
+
+// readDirNames returns the names of the directory entries, without sorting them.
+func readDirNames(dir string) ([]string, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = d.Close() }()
+
+	return d.Readdirnames(-1)
+}
+
+// onEvict is called by the LRU, while holding the mutex, so the files are removed later by the caller,
+// once the mutex is released, via removeFiles.
+func (c *DiskIndexCache) onEvict(key, val interface{}) {
+	entry := val.(diskCacheEntry)
+
+	c.evictedPaths = append(c.evictedPaths, itemFilePath(key.(string), entry.size))
+	c.evicted.WithLabelValues(entry.typ).Inc()
+	c.current.WithLabelValues(entry.typ).Dec()
+	c.currentSize.WithLabelValues(entry.typ).Sub(float64(diskSize(entry.size)))
+	c.curSize -= diskSize(entry.size)
+}
+
+// takeEvictedPaths returns the paths of the items evicted since the last call. The caller must hold the mutex.
+func (c *DiskIndexCache) takeEvictedPaths() []string {
+	paths := c.evictedPaths
+	c.evictedPaths = nil
+	return paths
+}
+
+// removeFiles removes the files of the evicted items. The caller must not hold the mutex.
+func (c *DiskIndexCache) removeFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(filepath.Join(c.dir, path)); err != nil && !os.IsNotExist(err) {
+			level.Warn(c.logger).Log("msg", "failed to remove evicted disk index cache item", "file", path, "err", err)
+		}
+	}
+}
+
+// add adds the item to the LRU. The caller must hold the mutex.
+func (c *DiskIndexCache) add(key string, entry diskCacheEntry) {
+	c.lru.Add(key, entry)
+	c.current.WithLabelValues(entry.typ).Inc()
+	c.currentSize.WithLabelValues(entry.typ).Add(float64(diskSize(entry.size)))
+	c.curSize += diskSize(entry.size)
+}
+
+// ensureFits evicts the least recently used items until an item of the given size on disk fits in the cache.
+// The caller must hold the mutex.
+func (c *DiskIndexCache) ensureFits(size uint64) {
+	for c.curSize+size > c.maxSizeBytes {
+		if _, _, ok := c.lru.RemoveOldest(); !ok {
+			return
+		}
+	}
+}
+
+// itemKey returns the key of the item in the LRU, which is the path of the item file relative to the cache
+// directory, without the size. The key is versioned like the memcached keys, so that the items cached with a
+// previous format are not read.
+func itemKey(typ, key string) string {
+	hash := blake2b.Sum256([]byte(versionedCacheKey(memcachedKeyVersion, key)))
+	return filepath.Join(typ, hex.EncodeToString(hash[:]))
+}
+
+// itemFilePath returns the path of the file of the item, relative to the cache directory.
+func itemFilePath(key string, size uint64) string {
+	return key + "-" + strconv.FormatUint(size, 10)
+}
+
+// parseItemFileName returns the key and the size of the item of the file, and whether the file is an item file.
+func parseItemFileName(typ, name string) (string, uint64, bool) {
+	idx := strings.LastIndexByte(name, '-')
+	if idx < 0 || strings.HasPrefix(name, diskTempFilePrefix) {
+		return "", 0, false
+	}
+	if _, err := hex.DecodeString(name[:idx]); err != nil {
+		return "", 0, false
+	}
+	size, err := strconv.ParseUint(name[idx+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return filepath.Join(typ, name[:idx]), size, true
+}
+
+// diskSize returns the size on disk of an item, in whole filesystem blocks.
+func diskSize(size uint64) uint64 {
+	return (size + diskItemHeaderSize + diskBlockSize - 1) / diskBlockSize * diskBlockSize
+}
+
+func (c *DiskIndexCache) get(typ, key string) ([]byte, bool) {
+	c.requests.WithLabelValues(typ).Inc()
+
+	k := itemKey(typ, key)
+
+	c.mtx.Lock()
+	v, ok := c.lru.Get(k)
+	c.mtx.Unlock()
+	if !ok {
+		return nil, false
+	}
+	entry := v.(diskCacheEntry)
+
+	data, err := os.ReadFile(filepath.Join(c.dir, itemFilePath(k, entry.size)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The item could have been evicted in the meanwhile, or its file removed after being cached again
+			// concurrently with the eviction, in which case it's removed from the LRU so that it can be cached again.
+			c.remove(k)
+		} else {
+			c.failures.WithLabelValues(typ).Inc()
+			level.Warn(c.logger).Log("msg", "failed to read disk index cache item", "type", typ, "err", err)
+		}
+		return nil, false
+	}
+
+	val, ok := decodeDiskItem(data, entry.size)
+	if !ok {
+		// The item has been partially written before a crash, so it's removed and can be cached again.
+		c.failures.WithLabelValues(typ).Inc()
+		level.Warn(c.logger).Log("msg", "removing corrupted disk index cache item", "type", typ, "file", itemFilePath(k, entry.size))
+		c.remove(k)
+		return nil, false
+	}
+
+	c.hits.WithLabelValues(typ).Inc()
+	return val, true
+}
+
+// remove removes the item from the LRU, and its file.
+func (c *DiskIndexCache) remove(key string) {
+	c.mtx.Lock()
+	c.lru.Remove(key)
+	evicted := c.takeEvictedPaths()
+	c.mtx.Unlock()
+
+	c.removeFiles(evicted)
+}
+
+// decodeDiskItem returns the item of the file data, and whether the data is the complete item of the given size.
+func decodeDiskItem(data []byte, size uint64) ([]byte, bool) {
+	if uint64(len(data)) != size+diskItemHeaderSize {
+		return nil, false
+	}
+	val := data[diskItemHeaderSize:]
+	if binary.BigEndian.Uint32(data) != crc32.Checksum(val, diskItemCastagnoliTable) {
+		return nil, false
+	}
+	return val, true
+}
+
+func (c *DiskIndexCache) set(typ, key string, val []byte) {
+	size := uint64(len(val))
+	if size > c.maxItemSizeBytes {
+		level.Debug(c.logger).Log(
+			"msg", "item bigger than maxItemSizeBytes. Ignoring..",
+			"maxItemSizeBytes", c.maxItemSizeBytes,
+			"maxSizeBytes", c.maxSizeBytes,
+			"itemSize", size,
+			"cacheType", typ,
+		)
+		c.overflow.WithLabelValues(typ).Inc()
+		return
+	}
+
+	k := itemKey(typ, key)
+
+	c.mtx.Lock()
+	if _, queued := c.pending[k]; queued || c.lru.Contains(k) {
+		c.mtx.Unlock()
+		return
+	}
+	if len(c.pending) >= diskWriteQueueMaxItems || c.pendingSize+size > diskWriteQueueMaxSizeBytes {
+		c.mtx.Unlock()
+		c.droppedWrites.WithLabelValues(typ).Inc()
+		return
+	}
+	c.pending[k] = struct{}{}
+	c.pendingSize += size
+	c.pendingWrites.Add(1)
+	c.mtx.Unlock()
+
+	// The queue has room for all the pending items, so this never blocks.
+	c.writes <- diskCacheWrite{typ: typ, key: k, val: val}
+}
+
+// writeLoop writes the queued items to the disk, and adds them to the LRU once written, until the cache is stopped.
+func (c *DiskIndexCache) writeLoop() {
+	defer close(c.stopped)
+
+	for w := range c.writes {
+		c.write(w)
+		c.pendingWrites.Done()
+	}
+}
+
+// Stop writes the items already queued to the disk and stops the background writes. The cache must not be
+// used once stopped.
+func (c *DiskIndexCache) Stop() {
+	close(c.writes)
+	<-c.stopped
+}
+
+func (c *DiskIndexCache) write(w diskCacheWrite) {
+	size := uint64(len(w.val))
+	err := c.writeFile(w.typ, itemFilePath(w.key, size), w.val)
+
+	c.mtx.Lock()
+	delete(c.pending, w.key)
+	c.pendingSize -= size
+
+	if err != nil {
+		c.mtx.Unlock()
+		c.failures.WithLabelValues(w.typ).Inc()
+		level.Warn(c.logger).Log("msg", "failed to write disk index cache item", "type", w.typ, "err", err)
+		return
+	}
+
+	c.ensureFits(diskSize(size))
+	c.add(w.key, diskCacheEntry{typ: w.typ, size: size})
+	c.added.WithLabelValues(w.typ).Inc()
+	evicted := c.takeEvictedPaths()
+	c.mtx.Unlock()
+
+	c.removeFiles(evicted)
+}
+
+// writeFile writes the item, after its checksum, to a temporary file renamed once complete, so that partially
+// written items are never read. The file isn't synced to the disk: an item partially written before a crash
+// doesn't match its checksum.
+func (c *DiskIndexCache) writeFile(typ, path string, val []byte) error {
+	f, err := os.CreateTemp(filepath.Join(c.dir, typ), diskTempFilePrefix)
+	if err != nil {
+		return err
+	}
+
+	var header [diskItemHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], crc32.Checksum(val, diskItemCastagnoliTable))
+
+	_, err = f.Write(header[:])
+	if err == nil {
+		_, err = f.Write(val)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.dir, path))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// StorePostings sets the postings identified by the ulid and label to the value v.
+func (c *DiskIndexCache) StorePostings(_ context.Context, userID string, blockID ulid.ULID, l labels.Label, v []byte) {
+	c.set(cacheTypePostings, postingsCacheKey(userID, blockID, l), v)
+}
+
+// FetchMultiPostings fetches multiple postings - each identified by a label -
+// and returns a map containing cache hits, along with a list of missing keys.
+func (c *DiskIndexCache) FetchMultiPostings(_ context.Context, userID string, blockID ulid.ULID, keys []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label) {
+	hits = map[labels.Label][]byte{}
+
+	for _, key := range keys {
+		if b, ok := c.get(cacheTypePostings, postingsCacheKey(userID, blockID, key)); ok {
+			hits[key] = b
+			continue
+		}
+
+		misses = append(misses, key)
+	}
+
+	return hits, misses
+}
+
+// StoreSeriesForRef sets the series identified by the ulid and id to the value v.
+func (c *DiskIndexCache) StoreSeriesForRef(_ context.Context, userID string, blockID ulid.ULID, id storage.SeriesRef, v []byte) {
+	c.set(cacheTypeSeriesForRef, seriesForRefCacheKey(userID, blockID, id), v)
+}
+
+// FetchMultiSeriesForRefs fetches multiple series - each identified by ID - from the cache
+// and returns a map containing cache hits, along with a list of missing IDs.
+func (c *DiskIndexCache) FetchMultiSeriesForRefs(_ context.Context, userID string, blockID ulid.ULID, ids []storage.SeriesRef) (hits map[storage.SeriesRef][]byte, misses []storage.SeriesRef) {
+	hits = map[storage.SeriesRef][]byte{}
+
+	for _, id := range ids {
+		if b, ok := c.get(cacheTypeSeriesForRef, seriesForRefCacheKey(userID, blockID, id)); ok {
+			hits[id] = b
+			continue
+		}
+
+		misses = append(misses, id)
+	}
+
+	return hits, misses
+}
+
+// StoreExpandedPostings stores the encoded result of ExpandedPostings for specified matchers identified by the provided LabelMatchersKey.
+func (c *DiskIndexCache) StoreExpandedPostings(_ context.Context, userID string, blockID ulid.ULID, key LabelMatchersKey, v []byte) {
+	c.set(cacheTypeExpandedPostings, expandedPostingsCacheKey(userID, blockID, key), v)
+}
+
+// FetchExpandedPostings fetches the encoded result of ExpandedPostings for specified matchers identified by the provided LabelMatchersKey.
+func (c *DiskIndexCache) FetchExpandedPostings(_ context.Context, userID string, blockID ulid.ULID, key LabelMatchersKey) ([]byte, bool) {
+	return c.get(cacheTypeExpandedPostings, expandedPostingsCacheKey(userID, blockID, key))
+}
+
+// StoreSeries stores the result of a Series() call.
+func (c *DiskIndexCache) StoreSeries(_ context.Context, userID string, blockID ulid.ULID, matchersKey LabelMatchersKey, shard *sharding.ShardSelector, v []byte) {
+	c.set(cacheTypeSeries, seriesCacheKey(userID, blockID, matchersKey, shard), v)
+}
+
+// FetchSeries fetches the result of a Series() call.
+func (c *DiskIndexCache) FetchSeries(_ context.Context, userID string, blockID ulid.ULID, matchersKey LabelMatchersKey, shard *sharding.ShardSelector) ([]byte, bool) {
+	return c.get(cacheTypeSeries, seriesCacheKey(userID, blockID, matchersKey, shard))
+}
+
+// StoreLabelNames stores the result of a LabelNames() call.
+func (c *DiskIndexCache) StoreLabelNames(_ context.Context, userID string, blockID ulid.ULID, matchersKey LabelMatchersKey, v []byte) {
+	c.set(cacheTypeLabelNames, labelNamesCacheKey(userID, blockID, matchersKey), v)
+}
+
+// FetchLabelNames fetches the result of a LabelNames() call.
+func (c *DiskIndexCache) FetchLabelNames(_ context.Context, userID string, blockID ulid.ULID, matchersKey LabelMatchersKey) ([]byte, bool) {
+	return c.get(cacheTypeLabelNames, labelNamesCacheKey(userID, blockID, matchersKey))
+}
+
+// StoreLabelValues stores the result of a LabelValues() call.
+func (c *DiskIndexCache) StoreLabelValues(_ context.Context, userID string, blockID ulid.ULID, labelName string, matchersKey LabelMatchersKey, v []byte) {
+	c.set(cacheTypeLabelValues, labelValuesCacheKey(userID, blockID, labelName, matchersKey), v)
+}
+
+// FetchLabelValues fetches the result of a LabelValues() call.
+func (c *DiskIndexCache) FetchLabelValues(_ context.Context, userID string, blockID ulid.ULID, labelName string, matchersKey LabelMatchersKey) ([]byte, bool) {
+	return c.get(cacheTypeLabelValues, labelValuesCacheKey(userID, blockID, labelName, matchersKey))
+}
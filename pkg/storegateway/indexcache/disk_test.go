@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/sharding"
+)
+
+func TestNewDiskIndexCache(t *testing.T) {
+	_, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: t.TempDir(), MaxSize: 1024, MaxItemSize: 2048})
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: 2048, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+	assert.Equal(t, uint64(2048), cache.maxSizeBytes)
+	assert.Equal(t, uint64(1024), cache.maxItemSizeBytes)
+
+	for _, typ := range allCacheTypes {
+		assert.DirExists(t, filepath.Join(dir, typ))
+	}
+}
+
+func TestDiskIndexCache_StoreAndFetch(t *testing.T) {
+	const user = "tenant"
+
+	ctx := context.Background()
+	blockID := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "foo", Value: "bar"}
+	matchersKey := CanonicalLabelMatchersKey([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")})
+	shard := &sharding.ShardSelector{ShardIndex: 1, ShardCount: 16}
+
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: t.TempDir(), MaxSize: 1024 * 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+
+	cache.StorePostings(ctx, user, blockID, lbl, []byte("postings"))
+	cache.StoreSeriesForRef(ctx, user, blockID, 1, []byte("series for ref"))
+	cache.StoreExpandedPostings(ctx, user, blockID, matchersKey, []byte("expanded postings"))
+	cache.StoreSeries(ctx, user, blockID, matchersKey, shard, []byte("series"))
+	cache.StoreLabelNames(ctx, user, blockID, matchersKey, []byte("label names"))
+	cache.StoreLabelValues(ctx, user, blockID, "foo", matchersKey, []byte("label values"))
+	cache.pendingWrites.Wait()
+
+	postings, misses := cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl, {Name: "foo", Value: "baz"}})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("postings")}, postings)
+	assert.Equal(t, []labels.Label{{Name: "foo", Value: "baz"}}, misses)
+
+	series, missingRefs := cache.FetchMultiSeriesForRefs(ctx, user, blockID, []storage.SeriesRef{1, 2})
+	assert.Equal(t, map[storage.SeriesRef][]byte{1: []byte("series for ref")}, series)
+	assert.Equal(t, []storage.SeriesRef{2}, missingRefs)
+
+	data, ok := cache.FetchExpandedPostings(ctx, user, blockID, matchersKey)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("expanded postings"), data)
+
+	data, ok = cache.FetchSeries(ctx, user, blockID, matchersKey, shard)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("series"), data)
+
+	_, ok = cache.FetchSeries(ctx, user, blockID, matchersKey, nil)
+	assert.False(t, ok)
+
+	data, ok = cache.FetchLabelNames(ctx, user, blockID, matchersKey)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("label names"), data)
+
+	data, ok = cache.FetchLabelValues(ctx, user, blockID, "foo", matchersKey)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("label values"), data)
+
+	_, ok = cache.FetchLabelValues(ctx, "another-tenant", blockID, "foo", matchersKey)
+	assert.False(t, ok)
+}
+
+func TestDiskIndexCache_ShouldKeepTheItemsAcrossRestarts(t *testing.T) {
+	const user = "tenant"
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	blockID := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "foo", Value: "bar"}
+
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: 1024 * 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+	cache.StorePostings(ctx, user, blockID, lbl, []byte("postings"))
+	cache.pendingWrites.Wait()
+
+	// Simulate a write interrupted by a shutdown.
+	tmpFile := filepath.Join(dir, cacheTypePostings, diskTempFilePrefix+"123")
+	require.NoError(t, os.WriteFile(tmpFile, []byte("partial"), os.ModePerm))
+
+	reg := prometheus.NewPedanticRegistry()
+	cache, err = NewDiskIndexCache(log.NewNopLogger(), reg, DiskIndexCacheConfig{Dir: dir, MaxSize: 1024 * 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+	assert.NoFileExists(t, tmpFile)
+
+	hits, misses := cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("postings")}, hits)
+	assert.Empty(t, misses)
+
+	assert.Equal(t, float64(1), promtest.ToFloat64(cache.current.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, float64(diskBlockSize), promtest.ToFloat64(cache.currentSize.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, float64(0), promtest.ToFloat64(cache.added.WithLabelValues(cacheTypePostings)))
+}
+
+func TestDiskIndexCache_Eviction(t *testing.T) {
+	const user = "tenant"
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	blockID := ulid.MustNew(1, nil)
+	lbl1 := labels.Label{Name: "series", Value: "1"}
+	lbl2 := labels.Label{Name: "series", Value: "2"}
+	lbl3 := labels.Label{Name: "series", Value: "3"}
+
+	// The items are accounted in whole blocks, including their header.
+	const itemSize = diskBlockSize - diskItemHeaderSize
+
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: 2 * diskBlockSize, MaxItemSize: itemSize})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+
+	// Items bigger than the max item size are not cached.
+	cache.StorePostings(ctx, user, blockID, lbl1, make([]byte, itemSize+1))
+	cache.pendingWrites.Wait()
+	assert.Equal(t, float64(1), promtest.ToFloat64(cache.overflow.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, float64(0), promtest.ToFloat64(cache.added.WithLabelValues(cacheTypePostings)))
+
+	// A small item still takes a whole block.
+	cache.StorePostings(ctx, user, blockID, lbl1, make([]byte, 10))
+	cache.StorePostings(ctx, user, blockID, lbl2, make([]byte, itemSize))
+	cache.pendingWrites.Wait()
+	assert.Equal(t, uint64(2*diskBlockSize), cache.curSize)
+
+	// The least recently used item is evicted, and its file removed.
+	_, misses := cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl1})
+	assert.Empty(t, misses)
+	cache.StorePostings(ctx, user, blockID, lbl3, make([]byte, itemSize))
+	cache.pendingWrites.Wait()
+
+	_, misses = cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl1, lbl2, lbl3})
+	assert.Equal(t, []labels.Label{lbl2}, misses)
+	assert.NoFileExists(t, filepath.Join(dir, itemFilePath(itemKey(cacheTypePostings, postingsCacheKey(user, blockID, lbl2)), itemSize)))
+
+	assert.Equal(t, float64(1), promtest.ToFloat64(cache.evicted.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, float64(2), promtest.ToFloat64(cache.current.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, float64(2*diskBlockSize), promtest.ToFloat64(cache.currentSize.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, uint64(2*diskBlockSize), cache.curSize)
+
+	// The cache is shrunk at startup when the max size is lowered.
+	cache, err = NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: diskBlockSize, MaxItemSize: itemSize})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+	assert.Equal(t, 1, cache.lru.Len())
+	assert.Equal(t, uint64(diskBlockSize), cache.curSize)
+
+	entries, err := os.ReadDir(filepath.Join(dir, cacheTypePostings))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestDiskIndexCache_ShouldCacheAgainTheItemsWhoseFileHasBeenRemoved(t *testing.T) {
+	const user = "tenant"
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	blockID := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "foo", Value: "bar"}
+
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+
+	cache.StorePostings(ctx, user, blockID, lbl, []byte("postings"))
+	cache.pendingWrites.Wait()
+	require.NoError(t, os.Remove(filepath.Join(dir, itemFilePath(itemKey(cacheTypePostings, postingsCacheKey(user, blockID, lbl)), uint64(len("postings"))))))
+
+	// The item is missed and removed from the cache, without being reported as a failure.
+	_, misses := cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl})
+	assert.Equal(t, []labels.Label{lbl}, misses)
+	assert.Equal(t, 0, cache.lru.Len())
+	assert.Equal(t, uint64(0), cache.curSize)
+	assert.Equal(t, float64(0), promtest.ToFloat64(cache.failures.WithLabelValues(cacheTypePostings)))
+
+	cache.StorePostings(ctx, user, blockID, lbl, []byte("postings"))
+	cache.pendingWrites.Wait()
+	hits, misses := cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("postings")}, hits)
+	assert.Empty(t, misses)
+}
+
+func TestDiskIndexCache_ShouldRemoveTheCorruptedItems(t *testing.T) {
+	const user = "tenant"
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	blockID := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "foo", Value: "bar"}
+
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: 1024 * 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+
+	cache.StorePostings(ctx, user, blockID, lbl, []byte("postings"))
+	cache.pendingWrites.Wait()
+
+	// Simulate an item partially written before a crash, since the files aren't synced.
+	path := filepath.Join(dir, itemFilePath(itemKey(cacheTypePostings, postingsCacheKey(user, blockID, lbl)), uint64(len("postings"))))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] = 0
+	require.NoError(t, os.WriteFile(path, data, os.ModePerm))
+
+	cache, err = NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: dir, MaxSize: 1024 * 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+	require.Equal(t, 1, cache.lru.Len())
+
+	_, misses := cache.FetchMultiPostings(ctx, user, blockID, []labels.Label{lbl})
+	assert.Equal(t, []labels.Label{lbl}, misses)
+	assert.Equal(t, 0, cache.lru.Len())
+	assert.NoFileExists(t, path)
+	assert.Equal(t, float64(1), promtest.ToFloat64(cache.failures.WithLabelValues(cacheTypePostings)))
+}
+
+func TestDiskIndexCache_ShouldDropTheWritesWhenTheQueueIsFull(t *testing.T) {
+	const user = "tenant"
+
+	ctx := context.Background()
+	blockID := ulid.MustNew(1, nil)
+
+	cache, err := NewDiskIndexCache(log.NewNopLogger(), nil, DiskIndexCacheConfig{Dir: t.TempDir(), MaxSize: 1024 * 1024, MaxItemSize: 1024})
+	require.NoError(t, err)
+	t.Cleanup(cache.Stop)
+
+	// Fill the queue, as if the items were waiting to be written.
+	cache.mtx.Lock()
+	cache.pendingSize = diskWriteQueueMaxSizeBytes
+	cache.mtx.Unlock()
+
+	cache.StorePostings(ctx, user, blockID, labels.Label{Name: "foo", Value: "bar"}, []byte("postings"))
+	cache.pendingWrites.Wait()
+	assert.Equal(t, 0, cache.lru.Len())
+	assert.Equal(t, float64(1), promtest.ToFloat64(cache.droppedWrites.WithLabelValues(cacheTypePostings)))
+}
@@ -26,6 +26,18 @@ import (
 
 const (
 	memcachedDefaultTTL = 7 * 24 * time.Hour
+
+	// memcachedKeyVersion is the version of the cache keys, which should be increased every time the format of the
+	// cached items changes. The keys of the version 1 have no version prefix.
+	memcachedKeyVersion = 1
+
+	// memcachedPreviousKeyVersion is the version of the cache keys read for the items missing with the current
+	// version, or 0 to not read them. Set it to the previous version when increasing memcachedKeyVersion only if
+	// the items cached with the previous version can still be decoded, so that the cache doesn't start empty
+	// after a rollout: the items cached with the previous version keep being read until they expire.
+	// It's fixed at build time, not configurable, because only the code changing the format of the cached items
+	// knows whether the previous version can be decoded.
+	memcachedPreviousKeyVersion = 0
 )
 
 // MemcachedIndexCache is a memcached-based index cache.
@@ -33,16 +45,22 @@ type MemcachedIndexCache struct {
 	logger    log.Logger
 	memcached cacheutil.MemcachedClient
 
+	keyVersion         int
+	previousKeyVersion int
+
 	// Metrics.
-	requests *prometheus.CounterVec
-	hits     *prometheus.CounterVec
+	requests     *prometheus.CounterVec
+	hits         *prometheus.CounterVec
+	previousHits *prometheus.CounterVec
 }
 
 // NewMemcachedIndexCache makes a new MemcachedIndexCache.
 func NewMemcachedIndexCache(logger log.Logger, memcached cacheutil.MemcachedClient, reg prometheus.Registerer) (*MemcachedIndexCache, error) {
 	c := &MemcachedIndexCache{
-		logger:    logger,
-		memcached: memcached,
+		logger:             logger,
+		memcached:          memcached,
+		keyVersion:         memcachedKeyVersion,
+		previousKeyVersion: memcachedPreviousKeyVersion,
 	}
 
 	c.requests = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
@@ -57,6 +75,12 @@ func NewMemcachedIndexCache(logger log.Logger, memcached cacheutil.MemcachedClie
 	}, []string{"item_type"})
 	initLabelValuesForAllCacheTypes(c.hits.MetricVec)
 
+	c.previousHits = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_previous_key_version_hits_total",
+		Help: "Total number of items requests to the cache that were a hit on an item cached with the previous key version.",
+	}, []string{"item_type"})
+	initLabelValuesForAllCacheTypes(c.previousHits.MetricVec)
+
 	level.Info(logger).Log("msg", "created memcached index cache")
 
 	return c, nil
@@ -64,7 +88,7 @@ func NewMemcachedIndexCache(logger log.Logger, memcached cacheutil.MemcachedClie
 
 // set stores a value for the given key in memcached.
 func (c *MemcachedIndexCache) set(ctx context.Context, typ string, key string, val []byte) {
-	if err := c.memcached.SetAsync(ctx, key, val, memcachedDefaultTTL); err != nil {
+	if err := c.memcached.SetAsync(ctx, versionedCacheKey(c.keyVersion, key), val, memcachedDefaultTTL); err != nil {
 		level.Error(c.logger).Log("msg", "failed to cache in memcached", "type", typ, "err", err)
 	}
 }
@@ -72,7 +96,7 @@ func (c *MemcachedIndexCache) set(ctx context.Context, typ string, key string, v
 // get retrieves a single value from memcached, returned bool value indicates whether the value was found or not.
 func (c *MemcachedIndexCache) get(ctx context.Context, typ string, key string) ([]byte, bool) {
 	c.requests.WithLabelValues(typ).Inc()
-	results := c.memcached.GetMulti(ctx, []string{key})
+	results := c.getMulti(ctx, typ, []string{key})
 	data, ok := results[key]
 	if ok {
 		c.hits.WithLabelValues(typ).Inc()
@@ -80,6 +104,69 @@ func (c *MemcachedIndexCache) get(ctx context.Context, typ string, key string) (
 	return data, ok
 }
 
+// getMulti retrieves multiple values from memcached, looking up with the previous key version the keys missing
+// with the current one. The returned map is keyed by the input keys, without version.
+func (c *MemcachedIndexCache) getMulti(ctx context.Context, typ string, keys []string) map[string][]byte {
+	results := c.getMultiWithVersion(ctx, c.keyVersion, keys)
+	if c.previousKeyVersion <= 0 || len(results) == len(keys) {
+		return results
+	}
+
+	misses := make([]string, 0, len(keys)-len(results))
+	for _, key := range keys {
+		if _, ok := results[key]; !ok {
+			misses = append(misses, key)
+		}
+	}
+
+	previousResults := c.getMultiWithVersion(ctx, c.previousKeyVersion, misses)
+	if len(previousResults) == 0 {
+		return results
+	}
+
+	c.previousHits.WithLabelValues(typ).Add(float64(len(previousResults)))
+	if results == nil {
+		return previousResults
+	}
+	for key, value := range previousResults {
+		results[key] = value
+	}
+	return results
+}
+
+func (c *MemcachedIndexCache) getMultiWithVersion(ctx context.Context, version int, keys []string) map[string][]byte {
+	if version == 1 {
+		return c.memcached.GetMulti(ctx, keys)
+	}
+
+	versionedKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		versionedKeys = append(versionedKeys, versionedCacheKey(version, key))
+	}
+
+	versionedResults := c.memcached.GetMulti(ctx, versionedKeys)
+	if len(versionedResults) == 0 {
+		return nil
+	}
+
+	results := make(map[string][]byte, len(versionedResults))
+	for i, versionedKey := range versionedKeys {
+		if value, ok := versionedResults[versionedKey]; ok {
+			results[keys[i]] = value
+		}
+	}
+	return results
+}
+
+// versionedCacheKey returns the key with the version prefix. The keys of the version 1 have no version prefix, to
+// keep the keys of the items cached before the keys were versioned.
+func versionedCacheKey(version int, key string) string {
+	if version == 1 {
+		return key
+	}
+	return "V" + strconv.Itoa(version) + ":" + key
+}
+
 // StorePostings sets the postings identified by the ulid and label to the value v.
 // The function enqueues the request and returns immediately: the entry will be
 // asynchronously stored in the cache.
@@ -105,7 +192,7 @@ func (c *MemcachedIndexCache) FetchMultiPostings(ctx context.Context, userID str
 
 	// Fetch the keys from memcached in a single request.
 	c.requests.WithLabelValues(cacheTypePostings).Add(float64(len(keys)))
-	results := c.memcached.GetMulti(ctx, keys)
+	results := c.getMulti(ctx, cacheTypePostings, keys)
 	if len(results) == 0 {
 		return nil, lbls
 	}
@@ -168,7 +255,7 @@ func (c *MemcachedIndexCache) FetchMultiSeriesForRefs(ctx context.Context, userI
 
 	// Fetch the keys from memcached in a single request.
 	c.requests.WithLabelValues(cacheTypeSeriesForRef).Add(float64(len(ids)))
-	results := c.memcached.GetMulti(ctx, keys)
+	results := c.getMulti(ctx, cacheTypeSeriesForRef, keys)
 	if len(results) == 0 {
 		return nil, ids
 	}
@@ -606,6 +606,50 @@ func TestMemcachedIndexCache_FetchLabelValues(t *testing.T) {
 	}
 }
 
+func TestMemcachedIndexCache_PreviousKeyVersion(t *testing.T) {
+	t.Parallel()
+
+	user := "tenant1"
+	block := ulid.MustNew(1, nil)
+	label1 := labels.Label{Name: "instance", Value: "a"}
+	label2 := labels.Label{Name: "instance", Value: "b"}
+	label3 := labels.Label{Name: "instance", Value: "c"}
+	ctx := context.Background()
+
+	memcached := newMockedMemcachedClient(nil)
+
+	// Cache some postings with the previous key version, which has no version prefix.
+	previous, err := NewMemcachedIndexCache(log.NewNopLogger(), memcached, nil)
+	assert.NoError(t, err)
+	previous.StorePostings(ctx, user, block, label1, []byte{1})
+	previous.StorePostings(ctx, user, block, label2, []byte{2})
+	previous.StoreSeries(ctx, user, block, "matchers", nil, []byte{3})
+
+	c, err := NewMemcachedIndexCache(log.NewNopLogger(), memcached, nil)
+	assert.NoError(t, err)
+	c.keyVersion, c.previousKeyVersion = 2, 1
+	c.StorePostings(ctx, user, block, label2, []byte{20})
+	assert.Contains(t, memcached.cache, "V2:"+postingsCacheKey(user, block, label2))
+
+	hits, misses := c.FetchMultiPostings(ctx, user, block, []labels.Label{label1, label2, label3})
+	assert.Equal(t, map[labels.Label][]byte{label1: {1}, label2: {20}}, hits)
+	assert.Equal(t, []labels.Label{label3}, misses)
+
+	series, ok := c.FetchSeries(ctx, user, block, "matchers", nil)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{3}, series)
+
+	assert.Equal(t, 2.0, prom_testutil.ToFloat64(c.hits.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.previousHits.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.previousHits.WithLabelValues(cacheTypeSeries)))
+
+	// Items cached with the previous key version are not read when not configured.
+	c.previousKeyVersion = 0
+	hits, misses = c.FetchMultiPostings(ctx, user, block, []labels.Label{label1, label2})
+	assert.Equal(t, map[labels.Label][]byte{label2: {20}}, hits)
+	assert.Equal(t, []labels.Label{label1}, misses)
+}
+
 func TestStringCacheKeys_Values(t *testing.T) {
 	t.Parallel()
 
@@ -80,6 +80,10 @@ type LazyBinaryReader struct {
 	metrics                     *LazyBinaryReaderMetrics
 	onClosed                    func(*LazyBinaryReader)
 
+	// Optional functions called once the index-header has been loaded, with its size, and unloaded.
+	onLoaded   func(*LazyBinaryReader, int64)
+	onUnloaded func(*LazyBinaryReader)
+
 	readerMx  sync.RWMutex
 	reader    *BinaryReader
 	readerErr error
@@ -261,6 +265,17 @@ func (r *LazyBinaryReader) load() (returnErr error) {
 	level.Debug(r.logger).Log("msg", "lazy loaded index-header file", "path", r.filepath, "elapsed", time.Since(startTime))
 	r.metrics.loadDuration.Observe(time.Since(startTime).Seconds())
 
+	if r.onLoaded != nil {
+		// The reader is about to be used: it's marked as used before being tracked, so that it's not evicted first.
+		r.usedAt.Store(time.Now().UnixNano())
+
+		var size int64
+		if info, err := os.Stat(r.filepath); err == nil {
+			size = info.Size()
+		}
+		r.onLoaded(r, size)
+	}
+
 	return nil
 }
 
@@ -287,6 +302,9 @@ func (r *LazyBinaryReader) unloadIfIdleSince(ts int64) error {
 	}
 
 	r.reader = nil
+	if r.onUnloaded != nil {
+		r.onUnloaded(r)
+	}
 	return nil
 }
 
@@ -303,3 +321,16 @@ func (r *LazyBinaryReader) isIdleSince(ts int64) bool {
 
 	return loaded
 }
+
+// IsLoaded returns true if the index-header is currently loaded.
+func (r *LazyBinaryReader) IsLoaded() bool {
+	r.readerMx.RLock()
+	defer r.readerMx.RUnlock()
+
+	return r.reader != nil
+}
+
+// LastUsedTime returns the last time the reader has been used, or the time it has been created if never used.
+func (r *LazyBinaryReader) LastUsedTime() time.Time {
+	return time.Unix(0, r.usedAt.Load())
+}
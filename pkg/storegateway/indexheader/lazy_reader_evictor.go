@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexheader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+)
+
+const (
+	evictionReasonMaxLoaded      = "max-loaded"
+	evictionReasonMaxLoadedBytes = "max-loaded-bytes"
+
+	// defaultEvictionMinIdleTime is the min time an index-header must have been idle for to be evicted, so that
+	// the index-headers in use, or just loaded to be used, are not evicted.
+	defaultEvictionMinIdleTime = 10 * time.Second
+)
+
+// LazyReaderEvictor limits the number and the total size of the index-headers loaded by the lazy readers
+// of all the pools sharing it, on top of the idle timeout of each pool. When a limit is exceeded, the least
+// recently used index-header of the pool with the most loaded index-headers (or bytes) is unloaded, so that
+// the tenants with few blocks keep their index-headers loaded when a tenant queries lots of blocks. There's
+// a pool for each tenant. Only the index-headers idle for at least a min idle time are evicted, so the limits
+// can be exceeded while more index-headers are in use.
+type LazyReaderEvictor struct {
+	logger         log.Logger
+	maxLoaded      int
+	maxLoadedBytes int64
+	minIdleTime    time.Duration
+
+	mtx         sync.Mutex
+	pools       map[*ReaderPool]*poolLoadedReaders
+	loaded      int
+	loadedBytes int64
+
+	// Whether the index-headers are being evicted.
+	evicting *atomic.Bool
+
+	// Metrics.
+	evictions          *prometheus.CounterVec
+	loadedHeaders      prometheus.Gauge
+	loadedHeadersBytes prometheus.Gauge
+}
+
+// poolLoadedReaders holds the loaded readers of a pool, with the size of their index-header.
+type poolLoadedReaders struct {
+	readers map[*LazyBinaryReader]int64
+	bytes   int64
+}
+
+// NewLazyReaderEvictor makes a new LazyReaderEvictor. A limit set to 0 is disabled.
+func NewLazyReaderEvictor(logger log.Logger, maxLoaded int, maxLoadedBytes int64, reg prometheus.Registerer) *LazyReaderEvictor {
+	e := &LazyReaderEvictor{
+		logger:         logger,
+		maxLoaded:      maxLoaded,
+		maxLoadedBytes: maxLoadedBytes,
+		minIdleTime:    defaultEvictionMinIdleTime,
+		pools:          map[*ReaderPool]*poolLoadedReaders{},
+		evicting:       atomic.NewBool(false),
+		evictions: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "indexheader_lazy_evictions_total",
+			Help: "Total number of index-headers unloaded because the max number of loaded index-headers or the max loaded bytes were exceeded.",
+		}, []string{"reason"}),
+		loadedHeaders: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "indexheader_lazy_loaded",
+			Help: "Number of index-headers currently loaded by the lazy readers.",
+		}),
+		loadedHeadersBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "indexheader_lazy_loaded_bytes",
+			Help: "Size in bytes of the index-headers currently loaded by the lazy readers.",
+		}),
+	}
+	e.evictions.WithLabelValues(evictionReasonMaxLoaded)
+	e.evictions.WithLabelValues(evictionReasonMaxLoadedBytes)
+
+	return e
+}
+
+// onLoaded tracks the reader of the pool which has just loaded its index-header. The index-headers are
+// evicted asynchronously when a limit is exceeded, because the reader's lock is held by the caller.
+func (e *LazyReaderEvictor) onLoaded(p *ReaderPool, r *LazyBinaryReader, size int64) {
+	e.mtx.Lock()
+	loaded, ok := e.pools[p]
+	if !ok {
+		loaded = &poolLoadedReaders{readers: map[*LazyBinaryReader]int64{}}
+		e.pools[p] = loaded
+	}
+	if _, ok := loaded.readers[r]; !ok {
+		loaded.readers[r] = size
+		loaded.bytes += size
+		e.loaded++
+		e.loadedBytes += size
+		e.updateMetrics()
+	}
+	overLimits := e.overLimits()
+	e.mtx.Unlock()
+
+	if overLimits && e.evicting.CAS(false, true) {
+		go e.evictLoop()
+	}
+}
+
+// onUnloaded stops tracking the reader of the pool which has just unloaded its index-header.
+func (e *LazyReaderEvictor) onUnloaded(p *ReaderPool, r *LazyBinaryReader) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.untrack(p, r)
+}
+
+func (e *LazyReaderEvictor) evictLoop() {
+	for {
+		// The readers which failed to be unloaded are still tracked, and are skipped until the next run.
+		skip := map[*LazyBinaryReader]struct{}{}
+		for {
+			idleSince := time.Now().Add(-e.minIdleTime).UnixNano()
+			r, reason := e.nextVictim(idleSince, skip)
+			if r == nil {
+				break
+			}
+
+			// The reader is unloaded only if it's still idle, and stops being tracked once unloaded.
+			if err := r.unloadIfIdleSince(idleSince); err != nil {
+				skip[r] = struct{}{}
+				if !errors.Is(err, errNotIdle) {
+					level.Warn(e.logger).Log("msg", "failed to evict index-header", "path", r.filepath, "reason", reason, "err", err)
+				}
+				continue
+			}
+			e.evictions.WithLabelValues(reason).Inc()
+		}
+
+		// Another index-header may have been loaded after the last check, but before resetting the flag.
+		e.evicting.Store(false)
+
+		e.mtx.Lock()
+		overLimits := e.overLimits()
+		e.mtx.Unlock()
+
+		if !overLimits || !e.evicting.CAS(false, true) {
+			return
+		}
+	}
+}
+
+// nextVictim returns the next reader to evict, and nil if the limits aren't exceeded or no reader can be evicted.
+// The reader is the least recently used one, idle since the given time (as unix nano), of the pool with the most
+// loaded index-headers, or bytes if the max loaded bytes are exceeded. The pools are ranked by all their loaded
+// index-headers, but only their idle readers not skipped are evicted.
+func (e *LazyReaderEvictor) nextVictim(idleSince int64, skip map[*LazyBinaryReader]struct{}) (*LazyBinaryReader, string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	var reason string
+	switch {
+	case e.maxLoaded > 0 && e.loaded > e.maxLoaded:
+		reason = evictionReasonMaxLoaded
+	case e.maxLoadedBytes > 0 && e.loadedBytes > e.maxLoadedBytes:
+		reason = evictionReasonMaxLoadedBytes
+	default:
+		return nil, ""
+	}
+
+	var (
+		victim     *LazyBinaryReader
+		victimLoad int64
+	)
+	for _, loaded := range e.pools {
+		load := int64(len(loaded.readers))
+		if reason == evictionReasonMaxLoadedBytes {
+			load = loaded.bytes
+		}
+		if victim != nil && load <= victimLoad {
+			continue
+		}
+
+		var candidate *LazyBinaryReader
+		for r := range loaded.readers {
+			if _, skipped := skip[r]; skipped || r.usedAt.Load() > idleSince {
+				continue
+			}
+			if candidate == nil || r.usedAt.Load() < candidate.usedAt.Load() {
+				candidate = r
+			}
+		}
+		if candidate != nil {
+			victim, victimLoad = candidate, load
+		}
+	}
+
+	return victim, reason
+}
+
+// untrack stops tracking the reader of the pool. This function MUST be called with the lock already acquired.
+func (e *LazyReaderEvictor) untrack(p *ReaderPool, r *LazyBinaryReader) {
+	loaded, ok := e.pools[p]
+	if !ok {
+		return
+	}
+	size, ok := loaded.readers[r]
+	if !ok {
+		return
+	}
+
+	delete(loaded.readers, r)
+	loaded.bytes -= size
+	if len(loaded.readers) == 0 {
+		delete(e.pools, p)
+	}
+
+	e.loaded--
+	e.loadedBytes -= size
+	e.updateMetrics()
+}
+
+// overLimits returns whether a limit is exceeded. This function MUST be called with the lock already acquired.
+func (e *LazyReaderEvictor) overLimits() bool {
+	return (e.maxLoaded > 0 && e.loaded > e.maxLoaded) || (e.maxLoadedBytes > 0 && e.loadedBytes > e.maxLoadedBytes)
+}
+
+// updateMetrics updates the gauges. This function MUST be called with the lock already acquired.
+func (e *LazyReaderEvictor) updateMetrics() {
+	e.loadedHeaders.Set(float64(e.loaded))
+	e.loadedHeadersBytes.Set(float64(e.loadedBytes))
+}
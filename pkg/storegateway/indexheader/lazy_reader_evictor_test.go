@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexheader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/filesystem"
+
+	"github.com/grafana/mimir/pkg/storegateway/testhelper"
+)
+
+// testMinIdleTime is the min idle time of the evicted readers in the tests.
+const testMinIdleTime = 10 * time.Millisecond
+
+func TestLazyReaderEvictor(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, bkt.Close()) })
+
+	blockID, err := testhelper.CreateBlock(ctx, tmpDir, []labels.Labels{
+		{{Name: "a", Value: "1"}},
+		{{Name: "a", Value: "2"}},
+	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124, metadata.NoneFunc)
+	require.NoError(t, err)
+	require.NoError(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), metadata.NoneFunc))
+
+	// Build the index-header once, to know its size.
+	headersDir := filepath.Join(tmpDir, "headers")
+	require.NoError(t, WriteBinary(ctx, bkt, blockID, filepath.Join(headersDir, blockID.String(), block.IndexHeaderFilename)))
+	info, err := os.Stat(filepath.Join(headersDir, blockID.String(), block.IndexHeaderFilename))
+	require.NoError(t, err)
+	headerSize := info.Size()
+
+	tests := map[string]struct {
+		maxLoaded      int
+		maxLoadedBytes int64
+		expectedReason string
+	}{
+		"max loaded index-headers": {
+			maxLoaded:      2,
+			expectedReason: evictionReasonMaxLoaded,
+		},
+		"max loaded bytes": {
+			maxLoadedBytes: 2 * headerSize,
+			expectedReason: evictionReasonMaxLoadedBytes,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			evictor := NewLazyReaderEvictor(log.NewNopLogger(), testData.maxLoaded, testData.maxLoadedBytes, nil)
+			evictor.minIdleTime = testMinIdleTime
+
+			// Each tenant has its own pool.
+			poolA := NewReaderPool(log.NewNopLogger(), true, 0, evictor, NewReaderPoolMetrics(nil))
+			poolB := NewReaderPool(log.NewNopLogger(), true, 0, evictor, NewReaderPoolMetrics(nil))
+			t.Cleanup(poolA.Close)
+			t.Cleanup(poolB.Close)
+
+			readerA1 := newTestLazyReader(t, poolA, bkt, headersDir, blockID)
+			readerA2 := newTestLazyReader(t, poolA, bkt, headersDir, blockID)
+			readerB1 := newTestLazyReader(t, poolB, bkt, headersDir, blockID)
+
+			// Both readers of the tenant A are loaded, within the limits.
+			readLabelNames(t, readerA1)
+			time.Sleep(testMinIdleTime)
+			readLabelNames(t, readerA2)
+			assert.Equal(t, float64(2), promtestutil.ToFloat64(evictor.loadedHeaders))
+			assert.Equal(t, float64(2*headerSize), promtestutil.ToFloat64(evictor.loadedHeadersBytes))
+
+			// Loading the reader of the tenant B evicts the least recently used reader of the tenant A,
+			// which has the most loaded index-headers.
+			readLabelNames(t, readerB1)
+			require.Eventually(t, func() bool {
+				return promtestutil.ToFloat64(evictor.loadedHeaders) == 2
+			}, 5*time.Second, 10*time.Millisecond)
+
+			assert.False(t, readerA1.IsLoaded())
+			assert.True(t, readerA2.IsLoaded())
+			assert.True(t, readerB1.IsLoaded())
+			assert.Equal(t, float64(1), promtestutil.ToFloat64(evictor.evictions.WithLabelValues(testData.expectedReason)))
+			assert.Equal(t, float64(2*headerSize), promtestutil.ToFloat64(evictor.loadedHeadersBytes))
+
+			// The evicted reader is loaded again once used.
+			time.Sleep(testMinIdleTime)
+			readLabelNames(t, readerA1)
+			require.Eventually(t, func() bool {
+				return promtestutil.ToFloat64(evictor.evictions.WithLabelValues(testData.expectedReason)) == 2
+			}, 5*time.Second, 10*time.Millisecond)
+			require.Eventually(t, func() bool {
+				return promtestutil.ToFloat64(evictor.loadedHeaders) == 2
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.True(t, readerA1.IsLoaded())
+			assert.False(t, readerA2.IsLoaded())
+
+			// The readers closed by the consumer are no longer tracked.
+			require.NoError(t, readerA1.Close())
+			require.NoError(t, readerA2.Close())
+			require.NoError(t, readerB1.Close())
+			assert.Equal(t, float64(0), promtestutil.ToFloat64(evictor.loadedHeaders))
+			assert.Equal(t, float64(0), promtestutil.ToFloat64(evictor.loadedHeadersBytes))
+		})
+	}
+}
+
+func TestLazyReaderEvictor_ShouldNotEvictTheRecentlyUsedReaders(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, bkt.Close()) })
+
+	blockID, err := testhelper.CreateBlock(ctx, tmpDir, []labels.Labels{
+		{{Name: "a", Value: "1"}},
+	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124, metadata.NoneFunc)
+	require.NoError(t, err)
+	require.NoError(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), metadata.NoneFunc))
+	headersDir := filepath.Join(tmpDir, "headers")
+
+	evictor := NewLazyReaderEvictor(log.NewNopLogger(), 1, 0, nil)
+	evictor.minIdleTime = 500 * time.Millisecond
+
+	pool := NewReaderPool(log.NewNopLogger(), true, 0, evictor, NewReaderPoolMetrics(nil))
+	t.Cleanup(pool.Close)
+
+	reader1 := newTestLazyReader(t, pool, bkt, headersDir, blockID)
+	reader2 := newTestLazyReader(t, pool, bkt, headersDir, blockID)
+
+	// Both readers have been used within the min idle time, so the limit is exceeded but none is evicted.
+	readLabelNames(t, reader1)
+	readLabelNames(t, reader2)
+
+	assert.True(t, reader1.IsLoaded())
+	assert.True(t, reader2.IsLoaded())
+	assert.Equal(t, float64(2), promtestutil.ToFloat64(evictor.loadedHeaders))
+	assert.Equal(t, float64(0), promtestutil.ToFloat64(evictor.evictions.WithLabelValues(evictionReasonMaxLoaded)))
+
+	// The least recently used reader is evicted once idle for long enough, on the next load.
+	time.Sleep(evictor.minIdleTime)
+	require.NoError(t, reader2.Close())
+	readLabelNames(t, reader2)
+	require.Eventually(t, func() bool {
+		return promtestutil.ToFloat64(evictor.evictions.WithLabelValues(evictionReasonMaxLoaded)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.False(t, reader1.IsLoaded())
+	assert.True(t, reader2.IsLoaded())
+	assert.Equal(t, float64(1), promtestutil.ToFloat64(evictor.loadedHeaders))
+}
+
+func newTestLazyReader(t *testing.T, pool *ReaderPool, bkt objstore.BucketReader, dir string, blockID ulid.ULID) *LazyBinaryReader {
+	r, err := pool.NewBinaryReader(context.Background(), log.NewNopLogger(), bkt, dir, blockID, 3, BinaryReaderConfig{})
+	require.NoError(t, err)
+	return r.(*LazyBinaryReader)
+}
+
+func readLabelNames(t *testing.T, r *LazyBinaryReader) {
+	labelNames, err := r.LabelNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, labelNames)
+}
@@ -34,10 +34,12 @@ func NewReaderPoolMetrics(reg prometheus.Registerer) *ReaderPoolMetrics {
 // ReaderPool is used to istantiate new index-header readers and keep track of them.
 // When the lazy reader is enabled, the pool keeps track of all instantiated readers
 // and automatically close them once the idle timeout is reached. A closed lazy reader
-// will be automatically re-opened upon next usage.
+// will be automatically re-opened upon next usage. If an evictor is set, the lazy readers
+// are also closed when the limits of the evictor are exceeded.
 type ReaderPool struct {
 	lazyReaderEnabled     bool
 	lazyReaderIdleTimeout time.Duration
+	lazyReaderEvictor     *LazyReaderEvictor
 	logger                log.Logger
 	metrics               *ReaderPoolMetrics
 
@@ -49,13 +51,14 @@ type ReaderPool struct {
 	lazyReaders   map[*LazyBinaryReader]struct{}
 }
 
-// NewReaderPool makes a new ReaderPool.
-func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, metrics *ReaderPoolMetrics) *ReaderPool {
+// NewReaderPool makes a new ReaderPool. The evictor is optional, and can be shared by multiple pools.
+func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, lazyReaderEvictor *LazyReaderEvictor, metrics *ReaderPoolMetrics) *ReaderPool {
 	p := &ReaderPool{
 		logger:                logger,
 		metrics:               metrics,
 		lazyReaderEnabled:     lazyReaderEnabled,
 		lazyReaderIdleTimeout: lazyReaderIdleTimeout,
+		lazyReaderEvictor:     lazyReaderEvictor,
 		lazyReaders:           make(map[*LazyBinaryReader]struct{}),
 		close:                 make(chan struct{}),
 	}
@@ -87,7 +90,13 @@ func (p *ReaderPool) NewBinaryReader(ctx context.Context, logger log.Logger, bkt
 	var err error
 
 	if p.lazyReaderEnabled {
-		reader, err = NewLazyBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling, cfg, p.metrics.lazyReader, p.onLazyReaderClosed)
+		var lazyReader *LazyBinaryReader
+		lazyReader, err = NewLazyBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling, cfg, p.metrics.lazyReader, p.onLazyReaderClosed)
+		if err == nil && p.lazyReaderEvictor != nil {
+			lazyReader.onLoaded = func(r *LazyBinaryReader, size int64) { p.lazyReaderEvictor.onLoaded(p, r, size) }
+			lazyReader.onUnloaded = func(r *LazyBinaryReader) { p.lazyReaderEvictor.onUnloaded(p, r) }
+		}
+		reader = lazyReader
 	} else {
 		reader, err = NewBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling, cfg)
 	}
@@ -22,6 +22,9 @@ import (
 
 const (
 	shardExcludedMeta = "shard-excluded"
+
+	// The max number of replica keys looked up in the ring for each block replica beyond the ring replication factor.
+	maxReplicaKeysPerReplica = 4
 )
 
 var (
@@ -43,6 +46,7 @@ type ShardingStrategy interface {
 // limiting the scope of the limits to the ones required by sharding strategies.
 type ShardingLimits interface {
 	StoreGatewayTenantShardSize(userID string) int
+	StoreGatewayTenantReplicationFactor(userID string) int
 }
 
 // ShuffleShardingStrategy is a shuffle sharding strategy, based on the hash ring formed by store-gateways,
@@ -53,16 +57,20 @@ type ShuffleShardingStrategy struct {
 	instanceAddr string
 	limits       ShardingLimits
 	logger       log.Logger
+
+	// Whether to preload the blocks owned by LEAVING instances, which will be owned by this instance once they're gone.
+	handoverEnabled bool
 }
 
 // NewShuffleShardingStrategy makes a new ShuffleShardingStrategy.
-func NewShuffleShardingStrategy(r *ring.Ring, instanceID, instanceAddr string, limits ShardingLimits, logger log.Logger) *ShuffleShardingStrategy {
+func NewShuffleShardingStrategy(r *ring.Ring, instanceID, instanceAddr string, limits ShardingLimits, handoverEnabled bool, logger log.Logger) *ShuffleShardingStrategy {
 	return &ShuffleShardingStrategy{
-		r:            r,
-		instanceID:   instanceID,
-		instanceAddr: instanceAddr,
-		limits:       limits,
-		logger:       logger,
+		r:               r,
+		instanceID:      instanceID,
+		instanceAddr:    instanceAddr,
+		limits:          limits,
+		logger:          logger,
+		handoverEnabled: handoverEnabled,
 	}
 }
 
@@ -115,11 +123,14 @@ func (s *ShuffleShardingStrategy) FilterBlocks(_ context.Context, userID string,
 	r := GetShuffleShardingSubring(s.r, userID, s.limits)
 	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
 
-	for blockID := range metas {
-		key := mimir_tsdb.HashBlockID(blockID)
+	ownerOp := BlocksOwnerSync
+	if s.handoverEnabled {
+		ownerOp = BlocksOwnerHandover
+	}
 
+	for blockID := range metas {
 		// Check if the block is owned by the store-gateway
-		set, err := r.Get(key, BlocksOwnerSync, bufDescs, bufHosts, bufZones)
+		set, err := GetBlockReplicationSet(r, userID, blockID, ownerOp, s.limits, bufDescs, bufHosts, bufZones)
 
 		// If an error occurs while checking the ring, we keep the previously loaded blocks.
 		if err != nil {
@@ -146,7 +157,7 @@ func (s *ShuffleShardingStrategy) FilterBlocks(_ context.Context, userID string,
 		// for queries.
 		if _, ok := loaded[blockID]; ok {
 			// The ring Get() returns an error if there's no available instance.
-			if _, err := r.Get(key, BlocksOwnerRead, bufDescs, bufHosts, bufZones); err != nil {
+			if _, err := GetBlockReplicationSet(r, userID, blockID, BlocksOwnerRead, s.limits, bufDescs, bufHosts, bufZones); err != nil {
 				// Keep the block.
 				continue
 			}
@@ -176,6 +187,54 @@ func GetShuffleShardingSubring(ring *ring.Ring, userID string, limits ShardingLi
 	return ring.ShuffleShard(userID, shardSize)
 }
 
+// GetBlockReplicationSet returns the replication set of the store-gateways owning the block for the given
+// operation. When the tenant's replication factor is higher than the ring replication factor, the block is
+// also owned by the store-gateways owning its replica keys, until the tenant's replication factor is reached.
+// This function should be used both by store-gateway and querier in order to guarantee the same logic is used.
+func GetBlockReplicationSet(r ring.ReadRing, userID string, blockID ulid.ULID, op ring.Operation, limits ShardingLimits, bufDescs []ring.InstanceDesc, bufHosts, bufZones []string) (ring.ReplicationSet, error) {
+	set, err := r.Get(mimir_tsdb.HashBlockID(blockID), op, bufDescs, bufHosts, bufZones)
+	if err != nil {
+		return set, err
+	}
+
+	replicationFactor := limits.StoreGatewayTenantReplicationFactor(userID)
+	if replicationFactor <= r.ReplicationFactor() || len(set.Instances) >= replicationFactor {
+		return set, nil
+	}
+
+	// The replica keys are looked up with a dedicated buffer, because the instances of the set are retained.
+	instances := make([]ring.InstanceDesc, 0, replicationFactor)
+	instances = append(instances, set.Instances...)
+	replicaDescs, replicaHosts, replicaZones := ring.MakeBuffersForGet()
+
+	// The replica keys can be owned by the same store-gateways, so the number of looked up replica keys is
+	// bounded: the block can have fewer owners than the tenant's replication factor in small rings.
+	for replica := 1; replica <= replicationFactor*maxReplicaKeysPerReplica && len(instances) < replicationFactor && len(instances) < r.InstancesCount(); replica++ {
+		replicaSet, err := r.Get(mimir_tsdb.HashBlockReplicaKey(blockID, replica), op, replicaDescs, replicaHosts, replicaZones)
+		if err != nil {
+			return ring.ReplicationSet{}, err
+		}
+
+		for _, instance := range replicaSet.Instances {
+			if len(instances) < replicationFactor && !containsInstance(instances, instance.Addr) {
+				instances = append(instances, instance)
+			}
+		}
+	}
+
+	set.Instances = instances
+	return set, nil
+}
+
+func containsInstance(instances []ring.InstanceDesc, addr string) bool {
+	for _, instance := range instances {
+		if instance.Addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
 type shardingMetadataFilterAdapter struct {
 	userID   string
 	strategy ShardingStrategy
@@ -7,6 +7,7 @@ package storegateway
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -60,6 +61,7 @@ func TestShuffleShardingStrategy(t *testing.T) {
 		limits            ShardingLimits
 		setupRing         func(*ring.Desc)
 		prevLoadedBlocks  map[string]map[ulid.ULID]struct{}
+		handoverEnabled   bool
 		expectedUsers     []usersExpectation
 		expectedBlocks    []blocksExpectation
 	}{
@@ -314,6 +316,26 @@ func TestShuffleShardingStrategy(t *testing.T) {
 				{instanceID: "instance-3", instanceAddr: "127.0.0.3", blocks: []ulid.ULID{block4}},
 			},
 		},
+		"LEAVING instance in the ring should continue to keep its shard blocks and they should be preloaded by another instance if the shutdown handover is enabled": {
+			replicationFactor: 1,
+			limits:            &shardingLimitsMock{storeGatewayTenantShardSize: 2},
+			setupRing: func(r *ring.Desc) {
+				r.AddIngester("instance-1", "127.0.0.1", "", []uint32{block1Hash + 1, block3Hash + 1}, ring.ACTIVE, registeredAt)
+				r.AddIngester("instance-2", "127.0.0.2", "", []uint32{block2Hash + 1}, ring.ACTIVE, registeredAt)
+				r.AddIngester("instance-3", "127.0.0.3", "", []uint32{block4Hash + 1}, ring.LEAVING, registeredAt)
+			},
+			handoverEnabled: true,
+			expectedUsers: []usersExpectation{
+				{instanceID: "instance-1", instanceAddr: "127.0.0.1", users: []string{userID}},
+				{instanceID: "instance-2", instanceAddr: "127.0.0.2", users: nil},
+				{instanceID: "instance-3", instanceAddr: "127.0.0.3", users: []string{userID}},
+			},
+			expectedBlocks: []blocksExpectation{
+				{instanceID: "instance-1", instanceAddr: "127.0.0.1", blocks: []ulid.ULID{block1, block2, block3 /* preloaded: */, block4}},
+				{instanceID: "instance-2", instanceAddr: "127.0.0.2", blocks: []ulid.ULID{ /* no blocks because not belonging to the shard */ }},
+				{instanceID: "instance-3", instanceAddr: "127.0.0.3", blocks: []ulid.ULID{block4}},
+			},
+		},
 		"JOINING instance in the ring should get its shard blocks and they should not be replicated to another instance": {
 			replicationFactor: 1,
 			limits:            &shardingLimitsMock{storeGatewayTenantShardSize: 2},
@@ -385,7 +407,7 @@ func TestShuffleShardingStrategy(t *testing.T) {
 
 			// Assert on filter users.
 			for _, expected := range testData.expectedUsers {
-				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, testData.limits, log.NewNopLogger())
+				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, testData.limits, testData.handoverEnabled, log.NewNopLogger())
 				actualUsers, err := filter.FilterUsers(ctx, []string{userID})
 				assert.Equal(t, expected.err, err)
 				assert.Equal(t, expected.users, actualUsers)
@@ -393,7 +415,7 @@ func TestShuffleShardingStrategy(t *testing.T) {
 
 			// Assert on filter blocks.
 			for _, expected := range testData.expectedBlocks {
-				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, testData.limits, log.NewNopLogger())
+				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, testData.limits, testData.handoverEnabled, log.NewNopLogger())
 				synced := extprom.NewTxGaugeVec(nil, prometheus.GaugeOpts{}, []string{"state"})
 				synced.WithLabelValues(shardExcludedMeta).Set(0)
 
@@ -422,10 +444,91 @@ func TestShuffleShardingStrategy(t *testing.T) {
 	}
 }
 
+func TestGetBlockReplicationSet(t *testing.T) {
+	const numInstances = 5
+
+	ctx := context.Background()
+	store, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	require.NoError(t, store.CAS(ctx, "test", func(in interface{}) (interface{}, bool, error) {
+		d := ring.NewDesc()
+		for i := 0; i < numInstances; i++ {
+			tokens := []uint32{uint32(i) * 800000000, uint32(i)*800000000 + 400000000}
+			d.AddIngester(fmt.Sprintf("instance-%d", i), fmt.Sprintf("127.0.0.%d", i), "", tokens, ring.ACTIVE, time.Now())
+		}
+		return d, true, nil
+	}))
+
+	cfg := ring.Config{
+		ReplicationFactor:    2,
+		HeartbeatTimeout:     time.Minute,
+		SubringCacheDisabled: true,
+	}
+
+	r, err := ring.NewWithStoreClientAndStrategy(cfg, "test", "test", store, ring.NewIgnoreUnhealthyInstancesReplicationStrategy(), nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, r))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, r)) })
+	require.NoError(t, ring.WaitInstanceState(ctx, r, "instance-0", ring.ACTIVE))
+
+	tests := map[string]struct {
+		tenantReplicationFactor int
+		expectedInstances       int
+	}{
+		"no tenant replication factor": {
+			tenantReplicationFactor: 0,
+			expectedInstances:       2,
+		},
+		"tenant replication factor lower than the ring replication factor": {
+			tenantReplicationFactor: 1,
+			expectedInstances:       2,
+		},
+		"tenant replication factor higher than the ring replication factor": {
+			tenantReplicationFactor: 4,
+			expectedInstances:       4,
+		},
+		"tenant replication factor higher than the number of instances": {
+			tenantReplicationFactor: 10,
+			expectedInstances:       numInstances,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := &shardingLimitsMock{storeGatewayTenantReplicationFactor: testData.tenantReplicationFactor}
+
+			for i := 0; i < 10; i++ {
+				blockID := ulid.MustNew(uint64(i), nil)
+				bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+
+				set, err := GetBlockReplicationSet(r, "user-1", blockID, BlocksRead, limits, bufDescs, bufHosts, bufZones)
+				require.NoError(t, err)
+				assert.Len(t, set.GetAddresses(), testData.expectedInstances)
+
+				// The ring replication set is always included.
+				ringSet, err := r.Get(mimir_tsdb.HashBlockID(blockID), BlocksRead, bufDescs, bufHosts, bufZones)
+				require.NoError(t, err)
+				assert.Subset(t, set.GetAddresses(), ringSet.GetAddresses())
+
+				// The store-gateways syncing the block are the ones that the queriers query.
+				ownerSet, err := GetBlockReplicationSet(r, "user-1", blockID, BlocksOwnerSync, limits, bufDescs, bufHosts, bufZones)
+				require.NoError(t, err)
+				assert.ElementsMatch(t, set.GetAddresses(), ownerSet.GetAddresses())
+			}
+		})
+	}
+}
+
 type shardingLimitsMock struct {
-	storeGatewayTenantShardSize int
+	storeGatewayTenantShardSize         int
+	storeGatewayTenantReplicationFactor int
 }
 
 func (m *shardingLimitsMock) StoreGatewayTenantShardSize(_ string) int {
 	return m.storeGatewayTenantShardSize
 }
+
+func (m *shardingLimitsMock) StoreGatewayTenantReplicationFactor(_ string) int {
+	return m.storeGatewayTenantReplicationFactor
+}
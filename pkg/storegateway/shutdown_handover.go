@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+)
+
+// shutdownHandoverDelegate keeps the store-gateway LEAVING in the ring for the handover period when shutting down,
+// before it leaves the ring. During this period, the store-gateways which will own its blocks once it's gone see
+// it LEAVING and preload these blocks, while the instance keeps its blocks loaded. It must be chained after the
+// ring.LeaveOnStoppingDelegate. The handover period is interrupted once the ctx is done.
+type shutdownHandoverDelegate struct {
+	ctx    context.Context
+	next   ring.BasicLifecyclerDelegate
+	period time.Duration
+	logger log.Logger
+}
+
+func newShutdownHandoverDelegate(ctx context.Context, period time.Duration, next ring.BasicLifecyclerDelegate, logger log.Logger) *shutdownHandoverDelegate {
+	return &shutdownHandoverDelegate{
+		ctx:    ctx,
+		next:   next,
+		period: period,
+		logger: logger,
+	}
+}
+
+func (d *shutdownHandoverDelegate) OnRingInstanceRegister(lifecycler *ring.BasicLifecycler, ringDesc ring.Desc, instanceExists bool, instanceID string, instanceDesc ring.InstanceDesc) (ring.InstanceState, ring.Tokens) {
+	return d.next.OnRingInstanceRegister(lifecycler, ringDesc, instanceExists, instanceID, instanceDesc)
+}
+
+func (d *shutdownHandoverDelegate) OnRingInstanceTokens(lifecycler *ring.BasicLifecycler, tokens ring.Tokens) {
+	d.next.OnRingInstanceTokens(lifecycler, tokens)
+}
+
+func (d *shutdownHandoverDelegate) OnRingInstanceStopping(lifecycler *ring.BasicLifecycler) {
+	// The lifecycler keeps heartbeating the ring while waiting.
+	level.Info(d.logger).Log("msg", "waiting for the other store-gateways to preload the blocks before leaving the ring", "period", d.period)
+	timer := time.NewTimer(d.period)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		level.Info(d.logger).Log("msg", "shutdown handover period elapsed, leaving the ring")
+	case <-d.ctx.Done():
+		level.Warn(d.logger).Log("msg", "shutdown handover interrupted, leaving the ring", "err", d.ctx.Err())
+	}
+
+	d.next.OnRingInstanceStopping(lifecycler)
+}
+
+func (d *shutdownHandoverDelegate) OnRingInstanceHeartbeat(lifecycler *ring.BasicLifecycler, ringDesc *ring.Desc, instanceDesc *ring.InstanceDesc) {
+	d.next.OnRingInstanceHeartbeat(lifecycler, ringDesc, instanceDesc)
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"sync"
+)
+
+// tenantConcurrencyLimiter limits the number of concurrent requests of each tenant. The requests
+// exceeding the limit wait until a running request of the same tenant completes. The limit is
+// passed each time a request is started, so that a live reloaded limit applies to the following
+// requests.
+type tenantConcurrencyLimiter struct {
+	mtx      sync.Mutex
+	inflight map[string]int
+
+	// Closed and removed once a request of the tenant completes, to wake up the waiting requests.
+	released map[string]chan struct{}
+}
+
+func newTenantConcurrencyLimiter() *tenantConcurrencyLimiter {
+	return &tenantConcurrencyLimiter{
+		inflight: map[string]int{},
+		released: map[string]chan struct{}{},
+	}
+}
+
+// start waits until the request of the tenant can run, or the context is canceled. If the request
+// can run, the returned function must be called once it completes. A limit of 0 disables the limit.
+func (l *tenantConcurrencyLimiter) start(ctx context.Context, userID string, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	for {
+		l.mtx.Lock()
+		if l.inflight[userID] < limit {
+			l.inflight[userID]++
+			l.mtx.Unlock()
+
+			return func() { l.done(userID) }, nil
+		}
+
+		released, ok := l.released[userID]
+		if !ok {
+			released = make(chan struct{})
+			l.released[userID] = released
+		}
+		l.mtx.Unlock()
+
+		select {
+		case <-released:
+			// Check again, because another waiting request may have taken the slot.
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *tenantConcurrencyLimiter) done(userID string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.inflight[userID]--; l.inflight[userID] <= 0 {
+		delete(l.inflight, userID)
+	}
+
+	if released, ok := l.released[userID]; ok {
+		close(released)
+		delete(l.released, userID)
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantConcurrencyLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := newTenantConcurrencyLimiter()
+
+	// Fill the limit of user-1.
+	done1, err := l.start(ctx, "user-1", 2)
+	require.NoError(t, err)
+	done2, err := l.start(ctx, "user-1", 2)
+	require.NoError(t, err)
+
+	// The requests of other tenants are not affected.
+	done3, err := l.start(ctx, "user-2", 2)
+	require.NoError(t, err)
+	done3()
+
+	// The request exceeding the limit waits until a running request completes.
+	started := make(chan func())
+	go func() {
+		done, err := l.start(ctx, "user-1", 2)
+		require.NoError(t, err)
+		started <- done
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("the request has been started while the limit is reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	done1()
+	done4 := <-started
+
+	done2()
+	done4()
+	assert.Empty(t, l.inflight)
+	assert.Empty(t, l.released)
+}
+
+func TestTenantConcurrencyLimiter_ShouldNotLimitWhenDisabled(t *testing.T) {
+	l := newTenantConcurrencyLimiter()
+
+	for i := 0; i < 10; i++ {
+		_, err := l.start(context.Background(), "user-1", 0)
+		require.NoError(t, err)
+	}
+	assert.Empty(t, l.inflight)
+}
+
+func TestTenantConcurrencyLimiter_ShouldReturnOnContextCanceled(t *testing.T) {
+	l := newTenantConcurrencyLimiter()
+
+	done, err := l.start(context.Background(), "user-1", 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = l.start(ctx, "user-1", 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The limit is applied at the time the request starts.
+	done2, err := l.start(context.Background(), "user-1", 2)
+	require.NoError(t, err)
+
+	done()
+	done2()
+	assert.Empty(t, l.inflight)
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// aggregation evaluates a sum, count, min, max or avg aggregation. The groups are returned in the order their last
+// series is evaluated, so that each group is returned, and its partial result released, as soon as all its series
+// have been aggregated.
+type aggregation struct {
+	inner     instantVectorOperator
+	op        parser.ItemType
+	grouping  []string
+	without   bool
+	timeRange queryTimeRange
+	samples   *querySamples
+
+	groups       []*aggregationGroup // In the order they're returned.
+	seriesGroups []*aggregationGroup // The group of each series of the inner operator.
+	nextGroup    int
+	nextSeries   int
+}
+
+type aggregationGroup struct {
+	labels          labels.Labels
+	lastSeriesIndex int
+	remainingSeries int
+
+	// The partial results, by step, allocated when the first series of the group is aggregated.
+	values []float64
+	counts []float64
+}
+
+func (a *aggregation) SeriesMetadata(ctx context.Context) ([]labels.Labels, storage.Warnings, error) {
+	metadata, warnings, err := a.inner.SeriesMetadata(ctx)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	groups := map[uint64]*aggregationGroup{}
+	a.seriesGroups = make([]*aggregationGroup, len(metadata))
+	lb := labels.NewBuilder(nil)
+	var buf []byte
+	for i, l := range metadata {
+		var key uint64
+		if a.without {
+			key, buf = l.HashWithoutLabels(buf, a.grouping...)
+		} else if len(a.grouping) > 0 {
+			key, buf = l.HashForLabels(buf, a.grouping...)
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			lb.Reset(l)
+			if a.without {
+				lb.Del(a.grouping...)
+				lb.Del(labels.MetricName)
+			} else {
+				lb.Keep(a.grouping...)
+			}
+			group = &aggregationGroup{labels: lb.Labels()}
+			groups[key] = group
+			a.groups = append(a.groups, group)
+		}
+		group.lastSeriesIndex = i
+		group.remainingSeries++
+		a.seriesGroups[i] = group
+	}
+
+	sort.Slice(a.groups, func(i, j int) bool {
+		return a.groups[i].lastSeriesIndex < a.groups[j].lastSeriesIndex
+	})
+
+	result := make([]labels.Labels, len(a.groups))
+	for i, group := range a.groups {
+		result[i] = group.labels
+	}
+	return result, warnings, nil
+}
+
+func (a *aggregation) Next(ctx context.Context) ([]promql.Point, error) {
+	group := a.groups[a.nextGroup]
+	a.groups[a.nextGroup] = nil
+	a.nextGroup++
+
+	// Aggregate the series of the inner operator until all the series of the group have been aggregated.
+	for group.remainingSeries > 0 {
+		points, err := a.inner.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		seriesGroup := a.seriesGroups[a.nextSeries]
+		a.seriesGroups[a.nextSeries] = nil
+		a.nextSeries++
+
+		if err := a.accumulate(seriesGroup, points); err != nil {
+			return nil, err
+		}
+		seriesGroup.remainingSeries--
+	}
+
+	if group.counts == nil {
+		return nil, nil
+	}
+
+	var points []promql.Point
+	for step, count := range group.counts {
+		if count == 0 {
+			continue
+		}
+		v := group.values[step]
+		if a.op == parser.COUNT {
+			v = count
+		}
+		points = append(points, promql.Point{T: a.timeRange.stepTime(step), V: v})
+	}
+
+	// The partial results of the group are released.
+	a.samples.release(len(points))
+	return points, nil
+}
+
+// accumulate aggregates the points of a series to the partial results of its group. The partial result of each step
+// is accounted as a sample held in memory, until the group is returned.
+func (a *aggregation) accumulate(group *aggregationGroup, points []promql.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if group.counts == nil {
+		group.values = make([]float64, a.timeRange.steps)
+		group.counts = make([]float64, a.timeRange.steps)
+	}
+
+	for _, p := range points {
+		step := a.timeRange.stepIndex(p.T)
+		group.counts[step]++
+		count := group.counts[step]
+
+		if count == 1 {
+			group.values[step] = p.V
+			if err := a.samples.add(1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch a.op {
+		case parser.SUM:
+			group.values[step] += p.V
+
+		case parser.AVG:
+			// The mean is computed incrementally, the same way of the standard engine.
+			mean := group.values[step]
+			if math.IsInf(mean, 0) {
+				if math.IsInf(p.V, 0) && (mean > 0) == (p.V > 0) {
+					// The mean and the value are Inf of the same sign, so the mean is correct already.
+					break
+				}
+				if !math.IsInf(p.V, 0) && !math.IsNaN(p.V) {
+					// The mean is kept infinite, instead of becoming NaN.
+					break
+				}
+			}
+			// Divide each side of the `-` by the count to avoid float64 overflows.
+			group.values[step] += p.V/count - mean/count
+
+		case parser.MAX:
+			if group.values[step] < p.V || math.IsNaN(group.values[step]) {
+				group.values[step] = p.V
+			}
+
+		case parser.MIN:
+			if group.values[step] > p.V || math.IsNaN(group.values[step]) {
+				group.values[step] = p.V
+			}
+		}
+	}
+	return nil
+}
+
+func (a *aggregation) Close() {
+	a.inner.Close()
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/stats"
+)
+
+// defaultLookbackDelta is the lookback delta of the standard engine when it's not configured.
+const defaultLookbackDelta = 5 * time.Minute
+
+// NotSupportedError is returned when creating a query the streaming engine doesn't support.
+type NotSupportedError struct {
+	reason string
+}
+
+func newNotSupportedError(format string, args ...interface{}) NotSupportedError {
+	return NotSupportedError{reason: fmt.Sprintf(format, args...)}
+}
+
+func (e NotSupportedError) Error() string {
+	return "not supported by the streaming PromQL engine: " + e.reason
+}
+
+// Engine is a PromQL engine evaluating the queries series by series: the series read from the storage are streamed
+// through the operators of the query, so that only the points of the series being evaluated and the partial results
+// of the aggregations are held in memory, instead of the points of all the series selected by the query.
+//
+// The engine supports a subset of PromQL: the instant vector selectors, the rate() and increase() functions of
+// range vector selectors, and the sum, count, min, max and avg aggregations of these. The queries using any other
+// expression, or the @ modifier, are rejected with a NotSupportedError when they're created.
+type Engine struct {
+	lookbackDelta      time.Duration
+	timeout            time.Duration
+	maxSamples         int
+	activeQueryTracker promql.QueryTracker
+}
+
+// NewEngine makes a new Engine. The max samples are the samples a query can hold in memory, like in the standard
+// engine: since the series are streamed, they're the samples of the query result and of the partial results of the
+// aggregations. The max samples limit is disabled if 0.
+func NewEngine(opts promql.EngineOpts) *Engine {
+	lookbackDelta := opts.LookbackDelta
+	if lookbackDelta == 0 {
+		lookbackDelta = defaultLookbackDelta
+	}
+
+	return &Engine{
+		lookbackDelta:      lookbackDelta,
+		timeout:            opts.Timeout,
+		maxSamples:         opts.MaxSamples,
+		activeQueryTracker: opts.ActiveQueryTracker,
+	}
+}
+
+// SetQueryLogger implements the QueryEngine interface of the Prometheus API. The queries run by the streaming
+// engine aren't logged.
+func (e *Engine) SetQueryLogger(promql.QueryLogger) {}
+
+// NewInstantQuery returns an instant query evaluated at ts.
+func (e *Engine) NewInstantQuery(q storage.Queryable, _ *promql.QueryOpts, qs string, ts time.Time) (promql.Query, error) {
+	return e.newQuery(q, qs, ts, ts, 0)
+}
+
+// NewRangeQuery returns a range query evaluated from start to end, at every interval.
+func (e *Engine) NewRangeQuery(q storage.Queryable, _ *promql.QueryOpts, qs string, start, end time.Time, interval time.Duration) (promql.Query, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%v is not a valid interval for a range query, must be greater than 0", interval)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("range query end time %v is before its start time %v", end, start)
+	}
+	return e.newQuery(q, qs, start, end, interval)
+}
+
+func (e *Engine) newQuery(q storage.Queryable, qs string, start, end time.Time, interval time.Duration) (*query, error) {
+	expr, err := parser.ParseExpr(qs)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Type() != parser.ValueTypeVector {
+		return nil, newNotSupportedError("%s expression", expr.Type())
+	}
+
+	timeRange := newQueryTimeRange(start, end, interval)
+	samples := &querySamples{max: e.maxSamples, stats: stats.NewQuerySamples(false)}
+	root, err := e.newOperator(q, expr, timeRange, samples)
+	if err != nil {
+		return nil, err
+	}
+
+	return &query{
+		engine: e,
+		qs:     qs,
+		stmt: &parser.EvalStmt{
+			Expr:     expr,
+			Start:    start,
+			End:      end,
+			Interval: interval,
+		},
+		root:      root,
+		timeRange: timeRange,
+		instant:   interval == 0,
+		samples:   samples,
+		timers:    stats.NewQueryTimers(),
+	}, nil
+}
+
+// newOperator returns the operator evaluating the instant vector expression.
+func (e *Engine) newOperator(q storage.Queryable, expr parser.Expr, timeRange queryTimeRange, samples *querySamples) (instantVectorOperator, error) {
+	switch expr := expr.(type) {
+	case *parser.ParenExpr:
+		return e.newOperator(q, expr.Expr, timeRange, samples)
+
+	case *parser.VectorSelector:
+		s, err := newSelector(q, expr, timeRange, e.lookbackDelta.Milliseconds(), "", samples)
+		if err != nil {
+			return nil, err
+		}
+		return &instantVectorSelector{selector: s}, nil
+
+	case *parser.Call:
+		isRate := expr.Func.Name == "rate"
+		if !isRate && expr.Func.Name != "increase" {
+			return nil, newNotSupportedError("%s function", expr.Func.Name)
+		}
+		matrix, ok := expr.Args[0].(*parser.MatrixSelector)
+		if !ok {
+			return nil, newNotSupportedError("%s function of a %T", expr.Func.Name, expr.Args[0])
+		}
+		vs, ok := matrix.VectorSelector.(*parser.VectorSelector)
+		if !ok {
+			return nil, newNotSupportedError("%s function of a %T", expr.Func.Name, matrix.VectorSelector)
+		}
+		s, err := newSelector(q, vs, timeRange, matrix.Range.Milliseconds(), expr.Func.Name, samples)
+		if err != nil {
+			return nil, err
+		}
+		return &rangeVectorFunction{selector: s, rangeMs: matrix.Range.Milliseconds(), isRate: isRate}, nil
+
+	case *parser.AggregateExpr:
+		switch expr.Op {
+		case parser.SUM, parser.COUNT, parser.MIN, parser.MAX, parser.AVG:
+		default:
+			return nil, newNotSupportedError("%s aggregation", expr.Op)
+		}
+		inner, err := e.newOperator(q, expr.Expr, timeRange, samples)
+		if err != nil {
+			return nil, err
+		}
+		// The grouping labels must be sorted to compute the grouping keys.
+		grouping := append([]string(nil), expr.Grouping...)
+		sort.Strings(grouping)
+		return &aggregation{inner: inner, op: expr.Op, grouping: grouping, without: expr.Without, timeRange: timeRange, samples: samples}, nil
+
+	default:
+		return nil, newNotSupportedError("%T expression", expr)
+	}
+}
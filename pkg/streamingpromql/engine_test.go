@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testData = `
+load 1m
+	http_requests_total{job="api", instance="1", status="200"} 0+10x30
+	http_requests_total{job="api", instance="2", status="200"} 0+20x10 0+20x19
+	http_requests_total{job="api", instance="1", status="500"} 0+1x15 stale 15+1x13
+	http_requests_total{job="db", instance="1", status="200"} 5+5x10 _x10 55+5x9
+	memory_bytes{job="api", instance="1"} 100+10x30
+	memory_bytes{job="db", instance="1"} 1000-10x30
+`
+
+func TestEngine_ShouldReturnTheSameResultsOfTheStandardEngine(t *testing.T) {
+	test, err := promql.NewTest(t, testData)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	opts := promql.EngineOpts{
+		MaxSamples:    1e6,
+		Timeout:       time.Minute,
+		LookbackDelta: 5 * time.Minute,
+	}
+	standard := promql.NewEngine(opts)
+	streaming := NewEngine(opts)
+
+	queries := []string{
+		`http_requests_total`,
+		`http_requests_total{status="200"}`,
+		`http_requests_total offset 5m`,
+		`(memory_bytes)`,
+		`rate(http_requests_total[5m])`,
+		`increase(http_requests_total{job="api"}[10m])`,
+		`rate(http_requests_total[5m] offset 3m)`,
+		`sum(http_requests_total)`,
+		`sum by (job) (rate(http_requests_total[5m]))`,
+		`sum without (instance) (rate(http_requests_total[5m]))`,
+		`count by (status) (http_requests_total)`,
+		`max by (job) (memory_bytes)`,
+		`min(memory_bytes)`,
+		`avg by (job) (http_requests_total)`,
+		`sum by (job) (count by (job, instance) (http_requests_total))`,
+		`sum(non_existent)`,
+	}
+
+	for _, qs := range queries {
+		t.Run(qs, func(t *testing.T) {
+			for _, ts := range []time.Time{time.Unix(0, 0), time.Unix(7*60, 0), time.Unix(15*60+30, 0), time.Unix(25*60, 0), time.Unix(40*60, 0)} {
+				expected := runInstantQuery(t, standard, test.Queryable(), qs, ts)
+				actual := runInstantQuery(t, streaming, test.Queryable(), qs, ts)
+				assert.Equal(t, expected, actual, "instant query at %v", ts)
+			}
+
+			for _, step := range []time.Duration{time.Minute, 90 * time.Second, 7 * time.Minute} {
+				start, end := time.Unix(0, 0), time.Unix(40*60, 0)
+				expected := runRangeQuery(t, standard, test.Queryable(), qs, start, end, step)
+				actual := runRangeQuery(t, streaming, test.Queryable(), qs, start, end, step)
+				assert.Equal(t, expected, actual, "range query with step %v", step)
+			}
+		})
+	}
+}
+
+func TestEngine_ShouldRejectTheUnsupportedQueries(t *testing.T) {
+	engine := NewEngine(promql.EngineOpts{})
+
+	for _, qs := range []string{
+		`http_requests_total[5m]`,
+		`1`,
+		`http_requests_total @ 100`,
+		`rate(http_requests_total[5m] @ end())`,
+		`irate(http_requests_total[5m])`,
+		`rate(http_requests_total[5m:1m])`,
+		`topk(5, http_requests_total)`,
+		`sum(http_requests_total) / 2`,
+		`abs(http_requests_total)`,
+	} {
+		t.Run(qs, func(t *testing.T) {
+			_, err := engine.NewInstantQuery(nil, nil, qs, time.Unix(0, 0))
+			require.Error(t, err)
+			assert.True(t, errors.As(err, &NotSupportedError{}))
+		})
+	}
+
+	_, err := engine.NewInstantQuery(nil, nil, `sum(`, time.Unix(0, 0))
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &NotSupportedError{}))
+}
+
+func TestEngine_ShouldFailOnDuplicateSeriesAfterDroppingTheMetricName(t *testing.T) {
+	test, err := promql.NewTest(t, `
+load 1m
+	a{job="test"} 0+1x10
+	b{job="test"} 0+1x10
+`)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	q, err := NewEngine(promql.EngineOpts{}).NewInstantQuery(test.Queryable(), nil, `rate({job="test"}[5m])`, time.Unix(5*60, 0))
+	require.NoError(t, err)
+	res := q.Exec(context.Background())
+	require.EqualError(t, res.Err, "vector cannot contain metrics with the same labelset")
+}
+
+func TestEngine_ShouldNotFailOnDuplicateSeriesWithoutPoints(t *testing.T) {
+	test, err := promql.NewTest(t, `
+load 1m
+	a{job="test"} 0+1x10
+	b{job="test"} _x20 0+1x10
+`)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	// Only the first series has points in the time range of the query.
+	const qs = `rate({job="test"}[5m])`
+	start := time.Unix(0, 0)
+	expected := runRangeQuery(t, promql.NewEngine(promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute}), test.Queryable(), qs, start, time.Unix(15*60, 0), time.Minute)
+	actual := runRangeQuery(t, NewEngine(promql.EngineOpts{}), test.Queryable(), qs, start, time.Unix(15*60, 0), time.Minute)
+	assert.Len(t, actual, 1)
+	assert.Equal(t, expected, actual)
+
+	// Both series have points once the time range includes the samples of both.
+	q, err := NewEngine(promql.EngineOpts{}).NewRangeQuery(test.Queryable(), nil, qs, start, time.Unix(30*60, 0), time.Minute)
+	require.NoError(t, err)
+	res := q.Exec(context.Background())
+	require.EqualError(t, res.Err, "vector cannot contain metrics with the same labelset")
+}
+
+func TestEngine_ShouldEnforceTheMaxSamples(t *testing.T) {
+	test, err := promql.NewTest(t, testData)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	// The range queries have 31 steps, and the series have points at all of them.
+	start, end := time.Unix(0, 0), time.Unix(30*60, 0)
+	for qs, minSamples := range map[string]int{
+		// The points of the 2 series of the result.
+		`memory_bytes`: 62,
+		// The points of the result of the first group, and the partial result of the second one.
+		`sum by (job) (memory_bytes)`: 62,
+		// The partial result of the single group, then the points of the result.
+		`sum(memory_bytes)`: 31,
+	} {
+		t.Run(qs, func(t *testing.T) {
+			q, err := NewEngine(promql.EngineOpts{MaxSamples: minSamples - 1}).NewRangeQuery(test.Queryable(), nil, qs, start, end, time.Minute)
+			require.NoError(t, err)
+			res := q.Exec(context.Background())
+			require.Error(t, res.Err)
+			assert.True(t, errors.As(res.Err, new(promql.ErrTooManySamples)))
+
+			q, err = NewEngine(promql.EngineOpts{MaxSamples: minSamples}).NewRangeQuery(test.Queryable(), nil, qs, start, end, time.Minute)
+			require.NoError(t, err)
+			res = q.Exec(context.Background())
+			require.NoError(t, res.Err)
+			assert.Equal(t, minSamples, q.Stats().Samples.PeakSamples)
+		})
+	}
+}
+
+func TestEngine_ShouldReturnTheSameTotalSamplesOfTheStandardEngine(t *testing.T) {
+	test, err := promql.NewTest(t, testData)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	opts := promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute}
+	standard := promql.NewEngine(opts)
+	streaming := NewEngine(opts)
+
+	for _, qs := range []string{
+		`http_requests_total`,
+		`rate(http_requests_total[5m])`,
+		`sum by (job) (increase(http_requests_total[10m] offset 3m))`,
+	} {
+		t.Run(qs, func(t *testing.T) {
+			totalSamples := func(engine queryEngine) int64 {
+				q, err := engine.NewRangeQuery(test.Queryable(), nil, qs, time.Unix(0, 0), time.Unix(40*60, 0), time.Minute)
+				require.NoError(t, err)
+				defer q.Close()
+				require.NoError(t, q.Exec(context.Background()).Err)
+				return q.Stats().Samples.TotalSamples
+			}
+
+			expected := totalSamples(standard)
+			assert.Greater(t, expected, int64(0))
+			assert.Equal(t, expected, totalSamples(streaming))
+		})
+	}
+}
+
+func TestEngine_ShouldHonorTheContextCancellation(t *testing.T) {
+	test, err := promql.NewTest(t, testData)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	q, err := NewEngine(promql.EngineOpts{}).NewInstantQuery(test.Queryable(), nil, `sum(http_requests_total)`, time.Unix(0, 0))
+	require.NoError(t, err)
+	res := q.Exec(ctx)
+	require.Error(t, res.Err)
+	var canceledErr promql.ErrQueryCanceled
+	assert.True(t, errors.As(res.Err, &canceledErr))
+}
+
+type queryEngine interface {
+	NewInstantQuery(q storage.Queryable, opts *promql.QueryOpts, qs string, ts time.Time) (promql.Query, error)
+	NewRangeQuery(q storage.Queryable, opts *promql.QueryOpts, qs string, start, end time.Time, interval time.Duration) (promql.Query, error)
+}
+
+func runInstantQuery(t *testing.T, engine queryEngine, queryable storage.Queryable, qs string, ts time.Time) promql.Vector {
+	q, err := engine.NewInstantQuery(queryable, nil, qs, ts)
+	require.NoError(t, err)
+	defer q.Close()
+
+	res := q.Exec(context.Background())
+	require.NoError(t, res.Err)
+	vector, err := res.Vector()
+	require.NoError(t, err)
+
+	// The standard engine doesn't sort the instant query results.
+	vector = append(promql.Vector{}, vector...)
+	sort.Slice(vector, func(i, j int) bool {
+		return vector[i].Metric.String() < vector[j].Metric.String()
+	})
+	return vector
+}
+
+func runRangeQuery(t *testing.T, engine queryEngine, queryable storage.Queryable, qs string, start, end time.Time, step time.Duration) promql.Matrix {
+	q, err := engine.NewRangeQuery(queryable, nil, qs, start, end, step)
+	require.NoError(t, err)
+
+	res := q.Exec(context.Background())
+	require.NoError(t, res.Err)
+	matrix, err := res.Matrix()
+	require.NoError(t, err)
+
+	// The points of the standard engine are pooled, so they're copied before the query is closed.
+	result := make(promql.Matrix, 0, len(matrix))
+	for _, series := range matrix {
+		result = append(result, promql.Series{Metric: series.Metric, Points: append([]promql.Point(nil), series.Points...)})
+	}
+	q.Close()
+	return result
+}
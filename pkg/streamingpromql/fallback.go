@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/stats"
+)
+
+// Limits is the per-tenant configuration of the streaming engine.
+type Limits interface {
+	// StreamingPromQLEngineEnabled returns whether the queries of the tenant are run by the streaming engine.
+	StreamingPromQLEngineEnabled(userID string) bool
+}
+
+// EngineWithFallback runs the queries with the streaming engine, for the tenants which enabled it, and falls back to
+// the standard engine for the other tenants and for the queries the streaming engine doesn't support.
+type EngineWithFallback struct {
+	standard  *promql.Engine
+	streaming *Engine
+	limits    Limits
+	logger    log.Logger
+
+	streamedQueries prometheus.Counter
+	fallbackQueries prometheus.Counter
+}
+
+// NewEngineWithFallback makes a new EngineWithFallback.
+func NewEngineWithFallback(standard *promql.Engine, streaming *Engine, limits Limits, reg prometheus.Registerer, logger log.Logger) *EngineWithFallback {
+	queries := promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_querier_streaming_promql_engine_queries_total",
+		Help: "Total number of queries of the tenants which enabled the streaming PromQL engine, by outcome: streamed by the streaming engine, or fallback to the standard engine because the query isn't supported.",
+	}, []string{"outcome"})
+
+	return &EngineWithFallback{
+		standard:        standard,
+		streaming:       streaming,
+		limits:          limits,
+		logger:          logger,
+		streamedQueries: queries.WithLabelValues("streamed"),
+		fallbackQueries: queries.WithLabelValues("fallback"),
+	}
+}
+
+// SetQueryLogger sets the query logger of the standard engine.
+func (e *EngineWithFallback) SetQueryLogger(l promql.QueryLogger) {
+	e.standard.SetQueryLogger(l)
+}
+
+// NewInstantQuery returns an instant query evaluated at ts.
+func (e *EngineWithFallback) NewInstantQuery(q storage.Queryable, opts *promql.QueryOpts, qs string, ts time.Time) (promql.Query, error) {
+	standard, err := e.standard.NewInstantQuery(q, opts, qs, ts)
+	if err != nil {
+		return nil, err
+	}
+	return e.newQuery(standard, func() (promql.Query, error) {
+		return e.streaming.NewInstantQuery(q, opts, qs, ts)
+	}), nil
+}
+
+// NewRangeQuery returns a range query evaluated from start to end, at every interval.
+func (e *EngineWithFallback) NewRangeQuery(q storage.Queryable, opts *promql.QueryOpts, qs string, start, end time.Time, interval time.Duration) (promql.Query, error) {
+	standard, err := e.standard.NewRangeQuery(q, opts, qs, start, end, interval)
+	if err != nil {
+		return nil, err
+	}
+	return e.newQuery(standard, func() (promql.Query, error) {
+		return e.streaming.NewRangeQuery(q, opts, qs, start, end, interval)
+	}), nil
+}
+
+func (e *EngineWithFallback) newQuery(standard promql.Query, newStreaming func() (promql.Query, error)) *queryWithFallback {
+	return &queryWithFallback{engine: e, standard: standard, newStreaming: newStreaming}
+}
+
+// queryWithFallback is a query run by the streaming engine, if enabled for the tenant of the query and supported,
+// or by the standard engine otherwise. The tenant is only known once the query is executed, so the streaming query
+// is only created then.
+type queryWithFallback struct {
+	engine       *EngineWithFallback
+	standard     promql.Query
+	newStreaming func() (promql.Query, error)
+
+	mtx       sync.Mutex
+	streaming promql.Query
+}
+
+// Exec implements promql.Query.
+func (q *queryWithFallback) Exec(ctx context.Context) *promql.Result {
+	if streaming := q.streamingQuery(ctx); streaming != nil {
+		return streaming.Exec(ctx)
+	}
+	return q.standard.Exec(ctx)
+}
+
+// streamingQuery returns the streaming query if the tenants of the query enabled the streaming engine, and the
+// streaming engine supports the query.
+func (q *queryWithFallback) streamingQuery(ctx context.Context) promql.Query {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, tenantID := range tenantIDs {
+		if !q.engine.limits.StreamingPromQLEngineEnabled(tenantID) {
+			return nil
+		}
+	}
+
+	streaming, err := q.newStreaming()
+	if err != nil {
+		q.engine.fallbackQueries.Inc()
+		level.Debug(q.engine.logger).Log("msg", "falling back to the standard PromQL engine", "query", q.standard.String(), "err", err)
+		return nil
+	}
+
+	q.engine.streamedQueries.Inc()
+	q.mtx.Lock()
+	q.streaming = streaming
+	q.mtx.Unlock()
+	return streaming
+}
+
+func (q *queryWithFallback) current() promql.Query {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.streaming != nil {
+		return q.streaming
+	}
+	return q.standard
+}
+
+// Close implements promql.Query.
+func (q *queryWithFallback) Close() {
+	q.standard.Close()
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.streaming != nil {
+		q.streaming.Close()
+	}
+}
+
+// Statement implements promql.Query.
+func (q *queryWithFallback) Statement() parser.Statement {
+	return q.current().Statement()
+}
+
+// Stats implements promql.Query.
+func (q *queryWithFallback) Stats() *stats.Statistics {
+	return q.current().Stats()
+}
+
+// Cancel implements promql.Query.
+func (q *queryWithFallback) Cancel() {
+	q.current().Cancel()
+}
+
+// String implements promql.Query.
+func (q *queryWithFallback) String() string {
+	return q.standard.String()
+}
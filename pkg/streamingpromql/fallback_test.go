@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+)
+
+type mockLimits map[string]bool
+
+func (m mockLimits) StreamingPromQLEngineEnabled(userID string) bool {
+	return m[userID]
+}
+
+func TestEngineWithFallback(t *testing.T) {
+	test, err := promql.NewTest(t, testData)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	tests := map[string]struct {
+		tenantID         string
+		query            string
+		expectedStreamed int
+		expectedFallback int
+	}{
+		"should run the query with the streaming engine if enabled for the tenant": {
+			tenantID:         "enabled",
+			query:            `sum by (job) (rate(http_requests_total[5m]))`,
+			expectedStreamed: 2,
+		},
+		"should fall back to the standard engine if the query is not supported": {
+			tenantID:         "enabled",
+			query:            `topk(1, http_requests_total)`,
+			expectedFallback: 2,
+		},
+		"should run the query with the standard engine if not enabled for the tenant": {
+			tenantID: "disabled",
+			query:    `sum by (job) (rate(http_requests_total[5m]))`,
+		},
+		"should run the query with the standard engine if not enabled for all the tenants of the query": {
+			tenantID: "enabled|disabled",
+			query:    `sum by (job) (rate(http_requests_total[5m]))`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			opts := promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute}
+			engine := NewEngineWithFallback(promql.NewEngine(opts), NewEngine(opts), mockLimits{"enabled": true}, reg, log.NewNopLogger())
+			ctx := user.InjectOrgID(context.Background(), testData.tenantID)
+
+			expected := runInstantQuery(t, promql.NewEngine(opts), test.Queryable(), testData.query, time.Unix(10*60, 0))
+			assert.NotEmpty(t, expected)
+
+			q, err := engine.NewInstantQuery(test.Queryable(), nil, testData.query, time.Unix(10*60, 0))
+			require.NoError(t, err)
+			res := q.Exec(ctx)
+			require.NoError(t, res.Err)
+			q.Close()
+			assert.Len(t, res.Value, len(expected))
+
+			q, err = engine.NewRangeQuery(test.Queryable(), nil, testData.query, time.Unix(0, 0), time.Unix(20*60, 0), time.Minute)
+			require.NoError(t, err)
+			res = q.Exec(ctx)
+			require.NoError(t, res.Err)
+			q.Close()
+
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+				# HELP cortex_querier_streaming_promql_engine_queries_total Total number of queries of the tenants which enabled the streaming PromQL engine, by outcome: streamed by the streaming engine, or fallback to the standard engine because the query isn't supported.
+				# TYPE cortex_querier_streaming_promql_engine_queries_total counter
+				cortex_querier_streaming_promql_engine_queries_total{outcome="fallback"} `+strconv.Itoa(testData.expectedFallback)+`
+				cortex_querier_streaming_promql_engine_queries_total{outcome="streamed"} `+strconv.Itoa(testData.expectedStreamed)+`
+			`)))
+		})
+	}
+}
+
+func TestEngineWithFallback_ShouldReturnTheStandardEngineErrors(t *testing.T) {
+	engine := NewEngineWithFallback(promql.NewEngine(promql.EngineOpts{}), NewEngine(promql.EngineOpts{}), mockLimits{}, nil, log.NewNopLogger())
+
+	_, err := engine.NewInstantQuery(nil, nil, `sum(`, time.Unix(0, 0))
+	require.Error(t, err)
+
+	_, err = engine.NewRangeQuery(nil, nil, `foo[5m]`, time.Unix(0, 0), time.Unix(60, 0), time.Second)
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/stats"
+)
+
+// instantVectorOperator evaluates an instant vector expression series by series.
+type instantVectorOperator interface {
+	// SeriesMetadata returns the labels of the series the operator evaluates, in the order they're returned by Next.
+	SeriesMetadata(ctx context.Context) ([]labels.Labels, storage.Warnings, error)
+
+	// Next returns the points of the next series, at the steps of the query. It must be called once for each series
+	// returned by SeriesMetadata, and the returned points aren't used by the operator anymore.
+	Next(ctx context.Context) ([]promql.Point, error)
+
+	// Close releases the resources of the operator.
+	Close()
+}
+
+// querySamples tracks the samples of a query: the ones held in memory, which can't exceed the max samples, and the
+// statistics of the samples.
+type querySamples struct {
+	max     int
+	current int
+	stats   *stats.QuerySamples
+}
+
+// add accounts the samples held in memory, and returns an error if they exceed the max samples.
+func (s *querySamples) add(n int) error {
+	s.current += n
+	if s.max > 0 && s.current > s.max {
+		return promql.ErrTooManySamples("query execution")
+	}
+	s.stats.UpdatePeak(s.current)
+	return nil
+}
+
+// release accounts the samples which aren't held in memory anymore.
+func (s *querySamples) release(n int) {
+	s.current -= n
+}
+
+// queryTimeRange is the time range of the steps of a query, in milliseconds.
+type queryTimeRange struct {
+	start    int64
+	end      int64
+	interval int64
+	steps    int
+}
+
+func newQueryTimeRange(start, end time.Time, interval time.Duration) queryTimeRange {
+	r := queryTimeRange{
+		start:    timeMilliseconds(start),
+		end:      timeMilliseconds(end),
+		interval: interval.Milliseconds(),
+		steps:    1,
+	}
+	if r.interval > 0 {
+		r.steps = int((r.end-r.start)/r.interval) + 1
+	}
+	return r
+}
+
+// stepTime returns the timestamp of the step.
+func (r queryTimeRange) stepTime(step int) int64 {
+	return r.start + int64(step)*r.interval
+}
+
+// stepIndex returns the step of the timestamp.
+func (r queryTimeRange) stepIndex(t int64) int {
+	if r.interval == 0 {
+		return 0
+	}
+	return int((t - r.start) / r.interval)
+}
+
+func timeMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// contextErr returns the PromQL error of the context error, like the standard engine does.
+func contextErr(err error, env string) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return promql.ErrQueryCanceled(env)
+	case errors.Is(err, context.DeadlineExceeded):
+		return promql.ErrQueryTimeout(env)
+	default:
+		return err
+	}
+}
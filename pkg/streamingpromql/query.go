@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/util/stats"
+)
+
+// query is a query run by the streaming engine.
+type query struct {
+	engine    *Engine
+	qs        string
+	stmt      *parser.EvalStmt
+	root      instantVectorOperator
+	timeRange queryTimeRange
+	instant   bool
+	samples   *querySamples
+	timers    *stats.QueryTimers
+
+	cancelMtx sync.Mutex
+	cancel    context.CancelFunc
+}
+
+// Exec implements promql.Query.
+func (q *query) Exec(ctx context.Context) *promql.Result {
+	execTimer := q.timers.GetTimer(stats.ExecTotalTime).Start()
+	defer execTimer.Stop()
+
+	if q.engine.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, q.engine.timeout)
+		defer cancelTimeout()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	q.cancelMtx.Lock()
+	q.cancel = cancel
+	q.cancelMtx.Unlock()
+
+	if tracker := q.engine.activeQueryTracker; tracker != nil {
+		queueTimer := q.timers.GetTimer(stats.ExecQueueTime).Start()
+		queryIndex, err := tracker.Insert(ctx, q.qs)
+		queueTimer.Stop()
+		if err != nil {
+			return &promql.Result{Err: contextErr(err, "query queue")}
+		}
+		defer tracker.Delete(queryIndex)
+	}
+
+	evalTimer := q.timers.GetTimer(stats.EvalTotalTime).Start()
+	defer evalTimer.Stop()
+
+	defer q.root.Close()
+
+	metadata, warnings, err := q.root.SeriesMetadata(ctx)
+	if err != nil {
+		return &promql.Result{Err: err, Warnings: warnings}
+	}
+
+	if q.instant {
+		vector := make(promql.Vector, 0, len(metadata))
+		for _, l := range metadata {
+			points, err := q.root.Next(ctx)
+			if err == nil {
+				err = q.samples.add(len(points))
+			}
+			if err != nil {
+				return &promql.Result{Err: err, Warnings: warnings}
+			}
+			if len(points) > 0 {
+				vector = append(vector, promql.Sample{Metric: l, Point: points[0]})
+			}
+		}
+		return &promql.Result{Value: vector, Warnings: warnings}
+	}
+
+	matrix := make(promql.Matrix, 0, len(metadata))
+	for _, l := range metadata {
+		points, err := q.root.Next(ctx)
+		if err == nil {
+			err = q.samples.add(len(points))
+		}
+		if err != nil {
+			return &promql.Result{Err: err, Warnings: warnings}
+		}
+		if len(points) > 0 {
+			matrix = append(matrix, promql.Series{Metric: l, Points: points})
+		}
+	}
+	sortTimer := q.timers.GetTimer(stats.ResultSortTime).Start()
+	sort.Sort(matrix)
+	sortTimer.Stop()
+	return &promql.Result{Value: matrix, Warnings: warnings}
+}
+
+// Close implements promql.Query.
+func (q *query) Close() {}
+
+// Statement implements promql.Query.
+func (q *query) Statement() parser.Statement {
+	return q.stmt
+}
+
+// Stats implements promql.Query. The samples statistics are the total samples and the peak samples, the per-step
+// statistics aren't tracked.
+func (q *query) Stats() *stats.Statistics {
+	return &stats.Statistics{
+		Timers:  q.timers,
+		Samples: q.samples.stats,
+	}
+}
+
+// Cancel implements promql.Query.
+func (q *query) Cancel() {
+	q.cancelMtx.Lock()
+	defer q.cancelMtx.Unlock()
+
+	if q.cancel != nil {
+		q.cancel()
+	}
+}
+
+// String implements promql.Query.
+func (q *query) String() string {
+	return q.qs
+}
@@ -0,0 +1,324 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package streamingpromql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// selector selects the series of a vector selector from the storage, and returns them one at a time.
+type selector struct {
+	queryable storage.Queryable
+	timeRange queryTimeRange
+	matchers  []*labels.Matcher
+	offset    int64
+	// lookback is how far back before each step the samples are read: the lookback delta for an instant vector
+	// selector, or the range of a range vector selector.
+	lookback int64
+	function string
+	samples  *querySamples
+
+	querier storage.Querier
+	series  []storage.Series
+	next    int
+}
+
+func newSelector(q storage.Queryable, vs *parser.VectorSelector, timeRange queryTimeRange, lookback int64, function string, samples *querySamples) (*selector, error) {
+	if vs.Timestamp != nil || vs.StartOrEnd != 0 {
+		return nil, newNotSupportedError("@ modifier")
+	}
+
+	return &selector{
+		queryable: q,
+		timeRange: timeRange,
+		matchers:  vs.LabelMatchers,
+		offset:    vs.OriginalOffset.Milliseconds(),
+		lookback:  lookback,
+		function:  function,
+		samples:   samples,
+	}, nil
+}
+
+func (s *selector) seriesMetadata(ctx context.Context) ([]labels.Labels, storage.Warnings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, contextErr(err, "query preparation")
+	}
+
+	start := s.timeRange.start - s.offset - s.lookback
+	end := s.timeRange.end - s.offset
+
+	var err error
+	s.querier, err = s.queryable.Querier(ctx, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hints := &storage.SelectHints{
+		Start: start,
+		End:   end,
+		Step:  s.timeRange.interval,
+		Func:  s.function,
+	}
+	if s.function != "" {
+		hints.Range = s.lookback
+	}
+
+	set := s.querier.Select(false, hints, s.matchers...)
+	var metadata []labels.Labels
+	for set.Next() {
+		series := set.At()
+		s.series = append(s.series, series)
+		metadata = append(metadata, series.Labels())
+	}
+	if err := set.Err(); err != nil {
+		return nil, set.Warnings(), err
+	}
+	return metadata, set.Warnings(), nil
+}
+
+// nextSeries returns the iterator of the next series, and releases the series.
+func (s *selector) nextSeries() chunkenc.Iterator {
+	series := s.series[s.next]
+	s.series[s.next] = nil
+	s.next++
+	return series.Iterator()
+}
+
+func (s *selector) close() {
+	s.series = nil
+	if s.querier != nil {
+		_ = s.querier.Close()
+		s.querier = nil
+	}
+}
+
+// samplesIterator iterates the samples of a series, keeping the next sample.
+type samplesIterator struct {
+	it      chunkenc.Iterator
+	hasNext bool
+	t       int64
+	v       float64
+}
+
+func newSamplesIterator(it chunkenc.Iterator) *samplesIterator {
+	s := &samplesIterator{it: it}
+	s.advance()
+	return s
+}
+
+func (s *samplesIterator) advance() {
+	s.hasNext = s.it.Next()
+	if s.hasNext {
+		s.t, s.v = s.it.At()
+	}
+}
+
+// instantVectorSelector evaluates an instant vector selector: at each step, the value of a series is its most recent
+// sample within the lookback delta, unless it's a staleness marker.
+type instantVectorSelector struct {
+	selector *selector
+}
+
+func (v *instantVectorSelector) SeriesMetadata(ctx context.Context) ([]labels.Labels, storage.Warnings, error) {
+	return v.selector.seriesMetadata(ctx)
+}
+
+func (v *instantVectorSelector) Next(ctx context.Context) ([]promql.Point, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err, "expression evaluation")
+	}
+
+	var (
+		s         = v.selector
+		it        = newSamplesIterator(s.nextSeries())
+		points    []promql.Point
+		prevT     int64
+		prevV     float64
+		prevFound bool
+	)
+	for step := 0; step < s.timeRange.steps; step++ {
+		ts := s.timeRange.stepTime(step)
+		refT := ts - s.offset
+
+		for it.hasNext && it.t <= refT {
+			prevT, prevV, prevFound = it.t, it.v, true
+			it.advance()
+		}
+		if !prevFound || prevT < refT-s.lookback || value.IsStaleNaN(prevV) {
+			continue
+		}
+		if points == nil {
+			points = make([]promql.Point, 0, s.timeRange.steps-step)
+		}
+		points = append(points, promql.Point{T: ts, V: prevV})
+	}
+	if err := it.it.Err(); err != nil {
+		return nil, err
+	}
+	s.samples.stats.TotalSamples += int64(len(points))
+	return points, nil
+}
+
+func (v *instantVectorSelector) Close() {
+	v.selector.close()
+}
+
+// rangeVectorFunction evaluates the rate() or increase() function of a range vector selector. Only the samples in the
+// range of the current step are held in memory.
+type rangeVectorFunction struct {
+	selector *selector
+	rangeMs  int64
+	isRate   bool
+
+	// The series with the same labels once the metric name is dropped can't have points, like in the standard
+	// engine, unless only one of them has: the labels hashes of these series are tracked with whether one of them
+	// has points.
+	hashes        []uint64
+	duplicatesHit map[uint64]bool
+}
+
+func (f *rangeVectorFunction) SeriesMetadata(ctx context.Context) ([]labels.Labels, storage.Warnings, error) {
+	metadata, warnings, err := f.selector.seriesMetadata(ctx)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	// The functions drop the metric name, so the series may not be unique anymore.
+	f.hashes = make([]uint64, len(metadata))
+	seen := make(map[uint64]struct{}, len(metadata))
+	lb := labels.NewBuilder(nil)
+	for i, l := range metadata {
+		lb.Reset(l)
+		metadata[i] = lb.Del(labels.MetricName).Labels()
+
+		hash := metadata[i].Hash()
+		f.hashes[i] = hash
+		if _, ok := seen[hash]; !ok {
+			seen[hash] = struct{}{}
+			continue
+		}
+		if f.duplicatesHit == nil {
+			f.duplicatesHit = map[uint64]bool{}
+		}
+		f.duplicatesHit[hash] = false
+	}
+	return metadata, warnings, nil
+}
+
+func (f *rangeVectorFunction) Next(ctx context.Context) ([]promql.Point, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err, "expression evaluation")
+	}
+
+	var (
+		s      = f.selector
+		hash   = f.hashes[s.next]
+		it     = newSamplesIterator(s.nextSeries())
+		points []promql.Point
+		window []promql.Point
+	)
+	for step := 0; step < s.timeRange.steps; step++ {
+		ts := s.timeRange.stepTime(step)
+		rangeEnd := ts - s.offset
+		rangeStart := rangeEnd - f.rangeMs
+
+		// Drop the samples before the range of the step, and add the ones up to its end.
+		drop := 0
+		for drop < len(window) && window[drop].T < rangeStart {
+			drop++
+		}
+		window = append(window[:0], window[drop:]...)
+		for it.hasNext && it.t <= rangeEnd {
+			if it.t >= rangeStart && !value.IsStaleNaN(it.v) {
+				window = append(window, promql.Point{T: it.t, V: it.v})
+			}
+			it.advance()
+		}
+		s.samples.stats.TotalSamples += int64(len(window))
+
+		// No sense in trying to compute a rate without at least two points.
+		if len(window) < 2 {
+			continue
+		}
+		if points == nil {
+			points = make([]promql.Point, 0, s.timeRange.steps-step)
+		}
+		points = append(points, promql.Point{T: ts, V: extrapolatedRate(window, rangeStart, rangeEnd, f.rangeMs, f.isRate)})
+	}
+	if err := it.it.Err(); err != nil {
+		return nil, err
+	}
+
+	if hit, ok := f.duplicatesHit[hash]; ok && len(points) > 0 {
+		if hit {
+			return nil, errors.New("vector cannot contain metrics with the same labelset")
+		}
+		f.duplicatesHit[hash] = true
+	}
+	return points, nil
+}
+
+func (f *rangeVectorFunction) Close() {
+	f.selector.close()
+}
+
+// extrapolatedRate returns the rate or increase of the counter samples of the range, extrapolated to the boundaries of
+// the range, like the standard engine does.
+func extrapolatedRate(samples []promql.Point, rangeStart, rangeEnd, rangeMs int64, isRate bool) float64 {
+	first, last := samples[0], samples[len(samples)-1]
+
+	resultValue := last.V - first.V
+	var lastValue float64
+	for _, sample := range samples {
+		// Account for the counter resets.
+		if sample.V < lastValue {
+			resultValue += lastValue
+		}
+		lastValue = sample.V
+	}
+
+	// Duration between first/last samples and boundary of range.
+	durationToStart := float64(first.T-rangeStart) / 1000
+	durationToEnd := float64(rangeEnd-last.T) / 1000
+
+	sampledInterval := float64(last.T-first.T) / 1000
+	averageDurationBetweenSamples := sampledInterval / float64(len(samples)-1)
+
+	if resultValue > 0 && first.V >= 0 {
+		// Counters cannot be negative: if the duration to the zero point of the counter is shorter than the
+		// duration to the start of the range, the zero point is taken as the start of the series.
+		durationToZero := sampledInterval * (first.V / resultValue)
+		if durationToZero < durationToStart {
+			durationToStart = durationToZero
+		}
+	}
+
+	// If the first/last samples are close to the boundaries of the range, the result is extrapolated to them, as
+	// another sample is expected to exist given the spacing between the samples.
+	extrapolationThreshold := averageDurationBetweenSamples * 1.1
+	extrapolateToInterval := sampledInterval
+
+	if durationToStart < extrapolationThreshold {
+		extrapolateToInterval += durationToStart
+	} else {
+		extrapolateToInterval += averageDurationBetweenSamples / 2
+	}
+	if durationToEnd < extrapolationThreshold {
+		extrapolateToInterval += durationToEnd
+	} else {
+		extrapolateToInterval += averageDurationBetweenSamples / 2
+	}
+	resultValue = resultValue * (extrapolateToInterval / sampledInterval)
+	if isRate {
+		resultValue = resultValue / (float64(rangeMs) / 1000)
+	}
+	return resultValue
+}
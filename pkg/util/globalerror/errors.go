@@ -26,19 +26,23 @@ const (
 	MaxSeriesPerMetric            ID = "max-series-per-metric"
 	MaxMetadataPerMetric          ID = "max-metadata-per-metric"
 	MaxSeriesPerUser              ID = "max-series-per-user"
+	MaxSeriesPerScope             ID = "max-series-per-scope"
 	MaxMetadataPerUser            ID = "max-metadata-per-user"
 	MaxChunksPerQuery             ID = "max-chunks-per-query"
 	MaxSeriesPerQuery             ID = "max-series-per-query"
 	MaxChunkBytesPerQuery         ID = "max-chunks-bytes-per-query"
+	MaxEstimatedMemoryPerQuery    ID = "max-estimated-memory-per-query"
+	MaxEstimatedMemoryPerTenant   ID = "tenant-max-estimated-memory"
 
 	DistributorMaxIngestionRate             ID = "distributor-max-ingestion-rate"
 	DistributorMaxInflightPushRequests      ID = "distributor-max-inflight-push-requests"
 	DistributorMaxInflightPushRequestsBytes ID = "distributor-max-inflight-push-requests-bytes"
 
-	IngesterMaxIngestionRate        ID = "ingester-max-ingestion-rate"
-	IngesterMaxTenants              ID = "ingester-max-tenants"
-	IngesterMaxInMemorySeries       ID = "ingester-max-series"
-	IngesterMaxInflightPushRequests ID = "ingester-max-inflight-push-requests"
+	IngesterMaxIngestionRate         ID = "ingester-max-ingestion-rate"
+	IngesterMaxTenants               ID = "ingester-max-tenants"
+	IngesterMaxInMemorySeries        ID = "ingester-max-series"
+	IngesterMaxInflightPushRequests  ID = "ingester-max-inflight-push-requests"
+	IngesterReadPathUtilizationLimit ID = "ingester-read-path-utilization-limit"
 
 	ExemplarLabelsMissing    ID = "exemplar-labels-missing"
 	ExemplarLabelsTooLong    ID = "exemplar-labels-too-long"
@@ -49,16 +53,29 @@ const (
 	MetricMetadataHelpTooLong       ID = "help-too-long"
 	MetricMetadataUnitTooLong       ID = "unit-too-long"
 
-	MaxQueryLength       ID = "max-query-length"
-	RequestRateLimited   ID = "tenant-max-request-rate"
-	IngestionRateLimited ID = "tenant-max-ingestion-rate"
-	TooManyHAClusters    ID = "tenant-too-many-ha-clusters"
+	MaxQueryLength                ID = "max-query-length"
+	QueryLoadShedding             ID = "tenant-query-load-shedding"
+	MaxFetchedChunkBytesPerMinute ID = "tenant-max-fetched-chunk-bytes-per-minute"
+	MaxEstimatedQueryCost         ID = "tenant-max-estimated-query-cost"
+	ExperimentalPromQLFunction    ID = "tenant-experimental-promql-function"
+	RequestRateLimited            ID = "tenant-max-request-rate"
+	IngestionRateLimited          ID = "tenant-max-ingestion-rate"
+	TooManyHAClusters             ID = "tenant-too-many-ha-clusters"
+	MaxPushRequestBytes           ID = "tenant-max-push-request-bytes"
+	MaxSeriesPerRequest           ID = "tenant-max-series-per-request"
+	MaxLabelsBytesPerRequest      ID = "tenant-max-labels-bytes-per-request"
+
+	IngestionMaintenanceMode   ID = "tenant-ingestion-maintenance-mode"
+	IngestionClientDenied      ID = "tenant-ingestion-client-denied"
+	IngestionClientRateLimited ID = "tenant-ingestion-client-rate-limited"
 
 	SampleTimestampTooOld    ID = "sample-timestamp-too-old"
 	SampleOutOfOrder         ID = "sample-out-of-order"
 	SampleDuplicateTimestamp ID = "sample-duplicate-timestamp"
 	ExemplarSeriesMissing    ID = "exemplar-series-missing"
 
+	TenantFederationMaxTenants ID = "tenant-federation-max-tenants"
+
 	StoreConsistencyCheckFailed ID = "store-consistency-check-failed"
 	BucketIndexTooOld           ID = "bucket-index-too-old"
 
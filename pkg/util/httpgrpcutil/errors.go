@@ -3,7 +3,10 @@
 package httpgrpcutil
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/weaveworks/common/httpgrpc"
 )
@@ -34,3 +37,22 @@ func PrioritizeRecoverableErr(errs ...error) error {
 
 	return firstErr
 }
+
+// RetryAfterHeader returns the Retry-After header suggesting to retry a request after the given duration, rounded up
+// to seconds.
+func RetryAfterHeader(retryAfter time.Duration) *httpgrpc.Header {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &httpgrpc.Header{Key: "Retry-After", Values: []string{strconv.Itoa(seconds)}}
+}
+
+// NewTooManyRequestsError returns a 429 HTTP error suggesting to retry the request after the given duration.
+func NewTooManyRequestsError(msg string, retryAfter time.Duration) error {
+	return httpgrpc.ErrorFromHTTPResponse(&httpgrpc.HTTPResponse{
+		Code:    http.StatusTooManyRequests,
+		Body:    []byte(msg),
+		Headers: []*httpgrpc.Header{RetryAfterHeader(retryAfter)},
+	})
+}
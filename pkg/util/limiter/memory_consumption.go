@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+type memoryConsumptionTrackerCtxKey struct{}
+
+var (
+	memoryConsumptionTrackerKey = &memoryConsumptionTrackerCtxKey{}
+
+	MaxEstimatedMemoryPerQueryMsgFormat = globalerror.MaxEstimatedMemoryPerQuery.MessageWithPerTenantLimitConfig(
+		"the query exceeded the maximum estimated memory (limit: %d bytes)",
+		validation.MaxEstimatedMemoryPerQueryFlag,
+	)
+	MaxEstimatedMemoryPerTenantMsgFormat = globalerror.MaxEstimatedMemoryPerTenant.MessageWithPerTenantLimitConfig(
+		"the inflight queries of the tenant exceeded the maximum estimated memory in the querier (limit: %d bytes)",
+		validation.MaxEstimatedMemoryPerTenantFlag,
+	)
+)
+
+// TenantMemoryConsumptionTracker tracks the estimated memory consumption of the inflight queries of each tenant.
+// It's shared by all the queries run by a querier.
+type TenantMemoryConsumptionTracker struct {
+	mtx      sync.Mutex
+	consumed map[string]uint64
+}
+
+// NewTenantMemoryConsumptionTracker makes a new TenantMemoryConsumptionTracker.
+func NewTenantMemoryConsumptionTracker() *TenantMemoryConsumptionTracker {
+	return &TenantMemoryConsumptionTracker{
+		consumed: map[string]uint64{},
+	}
+}
+
+// increase adds the input bytes to the memory consumption of the tenant and returns an error if the limit is
+// reached, in which case the memory consumption is not increased. The limit is disabled if 0.
+func (t *TenantMemoryConsumptionTracker) increase(tenantID string, b uint64, limit uint64) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if limit > 0 && t.consumed[tenantID]+b > limit {
+		return errors.New(fmt.Sprintf(MaxEstimatedMemoryPerTenantMsgFormat, limit))
+	}
+	t.consumed[tenantID] += b
+	return nil
+}
+
+// decrease removes the input bytes from the memory consumption of the tenant.
+func (t *TenantMemoryConsumptionTracker) decrease(tenantID string, b uint64) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.consumed[tenantID] <= b {
+		delete(t.consumed, tenantID)
+		return
+	}
+	t.consumed[tenantID] -= b
+}
+
+// consumedBytes returns the memory consumption of the inflight queries of the tenant.
+func (t *TenantMemoryConsumptionTracker) consumedBytes(tenantID string) uint64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.consumed[tenantID]
+}
+
+// MemoryConsumptionTracker tracks the estimated memory consumption of a single query: the size of the chunks
+// fetched from the ingesters and the storage, and of the series and points loaded by the PromQL engine. The
+// memory consumption is also accounted to the tenant, if a TenantMemoryConsumptionTracker is configured, until
+// the tracker is released at the end of the query.
+type MemoryConsumptionTracker struct {
+	mtx      sync.Mutex
+	consumed uint64
+	released bool
+
+	maxEstimatedMemoryPerQuery  uint64
+	maxEstimatedMemoryPerTenant uint64
+
+	tenantID string
+	tenant   *TenantMemoryConsumptionTracker
+}
+
+// NewMemoryConsumptionTracker makes a new per-query memory consumption tracker. The tenant tracker can be nil,
+// in which case the per-tenant limit is not enforced. The limits are disabled if 0.
+func NewMemoryConsumptionTracker(maxEstimatedMemoryPerQuery, maxEstimatedMemoryPerTenant int, tenantID string, tenant *TenantMemoryConsumptionTracker) *MemoryConsumptionTracker {
+	return &MemoryConsumptionTracker{
+		maxEstimatedMemoryPerQuery:  uint64(maxEstimatedMemoryPerQuery),
+		maxEstimatedMemoryPerTenant: uint64(maxEstimatedMemoryPerTenant),
+		tenantID:                    tenantID,
+		tenant:                      tenant,
+	}
+}
+
+// NewAttemptTracker makes a new tracker with the same per-query limit and nothing consumed yet, not accounting
+// the memory consumption to the tenant. It checks the limit on the results of a single attempt of a request, which
+// may be discarded, before they're accounted to this tracker.
+func (t *MemoryConsumptionTracker) NewAttemptTracker() *MemoryConsumptionTracker {
+	return NewMemoryConsumptionTracker(int(t.maxEstimatedMemoryPerQuery), 0, "", nil)
+}
+
+func AddMemoryConsumptionTrackerToContext(ctx context.Context, tracker *MemoryConsumptionTracker) context.Context {
+	return context.WithValue(ctx, memoryConsumptionTrackerKey, tracker)
+}
+
+// MemoryConsumptionTrackerFromContextWithFallback returns a MemoryConsumptionTracker from the current context.
+// If there is not a MemoryConsumptionTracker on the context it will return a new unlimited tracker.
+func MemoryConsumptionTrackerFromContextWithFallback(ctx context.Context) *MemoryConsumptionTracker {
+	tracker, ok := ctx.Value(memoryConsumptionTrackerKey).(*MemoryConsumptionTracker)
+	if !ok {
+		tracker = NewMemoryConsumptionTracker(0, 0, "", nil)
+	}
+	return tracker
+}
+
+// IncreaseMemoryConsumption adds the input bytes to the memory consumption of the query and returns an error
+// if the per-query or the per-tenant limit is reached.
+func (t *MemoryConsumptionTracker) IncreaseMemoryConsumption(b uint64) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	// The memory consumed after the end of the query isn't accounted.
+	if t.released {
+		return nil
+	}
+
+	if t.maxEstimatedMemoryPerQuery > 0 && t.consumed+b > t.maxEstimatedMemoryPerQuery {
+		return errors.New(fmt.Sprintf(MaxEstimatedMemoryPerQueryMsgFormat, t.maxEstimatedMemoryPerQuery))
+	}
+	if t.tenant != nil {
+		if err := t.tenant.increase(t.tenantID, b, t.maxEstimatedMemoryPerTenant); err != nil {
+			return err
+		}
+	}
+
+	t.consumed += b
+	return nil
+}
+
+// CurrentEstimatedMemoryConsumptionBytes returns the estimated memory consumption of the query.
+func (t *MemoryConsumptionTracker) CurrentEstimatedMemoryConsumptionBytes() uint64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.consumed
+}
+
+// Release gives back the memory consumption of the query to the tenant, once the query has completed.
+// It's safe to call Release multiple times.
+func (t *MemoryConsumptionTracker) Release() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.released {
+		return
+	}
+	t.released = true
+
+	if t.tenant != nil {
+		t.tenant.decrease(t.tenantID, t.consumed)
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryConsumptionTracker_PerQueryLimit(t *testing.T) {
+	tracker := NewMemoryConsumptionTracker(100, 0, "user-1", nil)
+
+	require.NoError(t, tracker.IncreaseMemoryConsumption(60))
+	require.NoError(t, tracker.IncreaseMemoryConsumption(40))
+	assert.Equal(t, uint64(100), tracker.CurrentEstimatedMemoryConsumptionBytes())
+
+	err := tracker.IncreaseMemoryConsumption(1)
+	require.EqualError(t, err, fmt.Sprintf(MaxEstimatedMemoryPerQueryMsgFormat, 100))
+	assert.Equal(t, uint64(100), tracker.CurrentEstimatedMemoryConsumptionBytes())
+}
+
+func TestMemoryConsumptionTracker_PerTenantLimit(t *testing.T) {
+	tenant := NewTenantMemoryConsumptionTracker()
+
+	first := NewMemoryConsumptionTracker(0, 100, "user-1", tenant)
+	second := NewMemoryConsumptionTracker(0, 100, "user-1", tenant)
+	other := NewMemoryConsumptionTracker(0, 100, "user-2", tenant)
+
+	require.NoError(t, first.IncreaseMemoryConsumption(70))
+	require.NoError(t, other.IncreaseMemoryConsumption(70))
+
+	// The inflight queries of the same tenant share the limit.
+	err := second.IncreaseMemoryConsumption(40)
+	require.EqualError(t, err, fmt.Sprintf(MaxEstimatedMemoryPerTenantMsgFormat, 100))
+	assert.Equal(t, uint64(0), second.CurrentEstimatedMemoryConsumptionBytes())
+	assert.Equal(t, uint64(70), tenant.consumedBytes("user-1"))
+
+	// Once the first query has completed, its memory is given back to the tenant.
+	first.Release()
+	first.Release()
+	assert.Equal(t, uint64(0), tenant.consumedBytes("user-1"))
+	require.NoError(t, second.IncreaseMemoryConsumption(40))
+	assert.Equal(t, uint64(40), tenant.consumedBytes("user-1"))
+	assert.Equal(t, uint64(70), tenant.consumedBytes("user-2"))
+
+	// The memory consumed after the release isn't accounted.
+	require.NoError(t, first.IncreaseMemoryConsumption(1000))
+	assert.Equal(t, uint64(40), tenant.consumedBytes("user-1"))
+}
+
+func TestMemoryConsumptionTracker_NewAttemptTracker(t *testing.T) {
+	tenant := NewTenantMemoryConsumptionTracker()
+	tracker := NewMemoryConsumptionTracker(100, 1000, "user-1", tenant)
+	require.NoError(t, tracker.IncreaseMemoryConsumption(60))
+
+	// The attempt tracker has the same per-query limit, but doesn't account the memory to the query or the tenant.
+	attempt := tracker.NewAttemptTracker()
+	require.NoError(t, attempt.IncreaseMemoryConsumption(100))
+	require.Error(t, attempt.IncreaseMemoryConsumption(1))
+	assert.Equal(t, uint64(60), tracker.CurrentEstimatedMemoryConsumptionBytes())
+	assert.Equal(t, uint64(60), tenant.consumedBytes("user-1"))
+}
+
+func TestMemoryConsumptionTrackerFromContextWithFallback(t *testing.T) {
+	tracker := NewMemoryConsumptionTracker(100, 0, "user-1", nil)
+	assert.Same(t, tracker, MemoryConsumptionTrackerFromContextWithFallback(AddMemoryConsumptionTrackerToContext(context.Background(), tracker)))
+
+	// The fallback tracker is unlimited.
+	fallback := MemoryConsumptionTrackerFromContextWithFallback(context.Background())
+	assert.NoError(t, fallback.IncreaseMemoryConsumption(1<<40))
+}
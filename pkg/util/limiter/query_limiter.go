@@ -63,6 +63,12 @@ func NewQueryLimiter(maxSeriesPerQuery, maxChunkBytesPerQuery int, maxChunksPerQ
 	}
 }
 
+// NewAttemptLimiter makes a new limiter with the same limits and nothing added yet. It checks the limits on the
+// results of a single attempt of a request, which may be discarded, before they're added to this limiter.
+func (ql *QueryLimiter) NewAttemptLimiter() *QueryLimiter {
+	return NewQueryLimiter(ql.maxSeriesPerQuery, ql.maxChunkBytesPerQuery, ql.maxChunksPerQuery)
+}
+
 func AddQueryLimiterToContext(ctx context.Context, limiter *QueryLimiter) context.Context {
 	return context.WithValue(ctx, ctxKey, limiter)
 }
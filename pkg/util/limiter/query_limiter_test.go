@@ -75,6 +75,19 @@ func TestQueryLimiter_AddChunkBytes(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestQueryLimiter_NewAttemptLimiter(t *testing.T) {
+	var limiter = NewQueryLimiter(0, 100, 0)
+	require.NoError(t, limiter.AddChunkBytes(60))
+
+	// The attempt limiter has the same limits, but doesn't count what's been added to the query limiter.
+	attempt := limiter.NewAttemptLimiter()
+	require.NoError(t, attempt.AddChunkBytes(100))
+	require.Error(t, attempt.AddChunkBytes(1))
+
+	// Nothing added to the attempt limiter is added to the query limiter.
+	require.NoError(t, limiter.AddChunkBytes(40))
+}
+
 func BenchmarkQueryLimiter_AddSeries(b *testing.B) {
 	const (
 		metricName = "test_metric"
@@ -35,10 +35,16 @@ const (
 	maxErrMsgLen   = 1024
 )
 
+// OTLPHandlerLimits are the per-tenant limits applied by the OTLP handler.
+type OTLPHandlerLimits interface {
+	OTelCreatedTimestampZeroIngestionEnabled(userID string) bool
+}
+
 func OTLPHandler(
 	maxRecvMsgSize int,
 	sourceIPs *middleware.SourceIPExtractor,
 	allowSkipLabelNameValidation bool,
+	limits OTLPHandlerLimits,
 	push Func,
 ) http.Handler {
 	return handler(maxRecvMsgSize, sourceIPs, allowSkipLabelNameValidation, push, func(ctx context.Context, r *http.Request, maxRecvMsgSize int, dst []byte, req *mimirpb.PreallocWriteRequest) ([]byte, error) {
@@ -108,12 +114,23 @@ func OTLPHandler(
 			return body, err
 		}
 
+		if limits != nil {
+			userID, err := tenant.TenantID(ctx)
+			if err != nil {
+				return body, err
+			}
+			if limits.OTelCreatedTimestampZeroIngestionEnabled(userID) {
+				injectCreatedTimestampZeroPoints(otlpReq.Metrics())
+			}
+		}
+
 		metrics, err := otelMetricsToTimeseries(ctx, logger, otlpReq.Metrics())
 		if err != nil {
 			return body, err
 		}
 
 		req.Timeseries = metrics
+		req.Source = mimirpb.OTLP
 		return body, nil
 	})
 }
@@ -150,6 +167,92 @@ func otelMetricsToTimeseries(ctx context.Context, logger kitlog.Logger, md pmetr
 	return mimirTs, nil
 }
 
+// injectCreatedTimestampZeroPoints adds a zero-valued data point at the start timestamp of each data point
+// of the cumulative monotonic sums and cumulative histograms. Data points are sorted by timestamp, so that the
+// zero samples precede the actual samples in the translated series.
+func injectCreatedTimestampZeroPoints(md pmetric.Metrics) {
+	resourceMetrics := md.ResourceMetrics()
+	for i := 0; i < resourceMetrics.Len(); i++ {
+		scopeMetrics := resourceMetrics.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metrics := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+
+				switch metric.DataType() {
+				case pmetric.MetricDataTypeSum:
+					sum := metric.Sum()
+					if !sum.IsMonotonic() || sum.AggregationTemporality() != pmetric.MetricAggregationTemporalityCumulative {
+						continue
+					}
+					injectSumZeroPoints(sum.DataPoints())
+
+				case pmetric.MetricDataTypeHistogram:
+					histogram := metric.Histogram()
+					if histogram.AggregationTemporality() != pmetric.MetricAggregationTemporalityCumulative {
+						continue
+					}
+					injectHistogramZeroPoints(histogram.DataPoints())
+				}
+			}
+		}
+	}
+}
+
+func injectSumZeroPoints(points pmetric.NumberDataPointSlice) {
+	count := points.Len()
+	for i := 0; i < count; i++ {
+		point := points.At(i)
+		if !hasValidStartTimestamp(point.StartTimestamp(), point.Timestamp()) {
+			continue
+		}
+
+		zero := points.AppendEmpty()
+		point.CopyTo(zero)
+		zero.SetTimestamp(point.StartTimestamp())
+		zero.Exemplars().RemoveIf(func(pmetric.Exemplar) bool { return true })
+		if zero.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			zero.SetIntVal(0)
+		} else {
+			zero.SetDoubleVal(0)
+		}
+	}
+
+	if points.Len() > count {
+		points.Sort(func(a, b pmetric.NumberDataPoint) bool { return a.Timestamp() < b.Timestamp() })
+	}
+}
+
+func injectHistogramZeroPoints(points pmetric.HistogramDataPointSlice) {
+	count := points.Len()
+	for i := 0; i < count; i++ {
+		point := points.At(i)
+		if !hasValidStartTimestamp(point.StartTimestamp(), point.Timestamp()) {
+			continue
+		}
+
+		zero := points.AppendEmpty()
+		point.CopyTo(zero)
+		zero.SetTimestamp(point.StartTimestamp())
+		zero.Exemplars().RemoveIf(func(pmetric.Exemplar) bool { return true })
+		zero.SetCount(0)
+		if zero.HasSum() {
+			zero.SetSum(0)
+		}
+		zero.SetBucketCounts(pcommon.NewImmutableUInt64Slice(make([]uint64, point.BucketCounts().Len())))
+	}
+
+	if points.Len() > count {
+		points.Sort(func(a, b pmetric.HistogramDataPoint) bool { return a.Timestamp() < b.Timestamp() })
+	}
+}
+
+// hasValidStartTimestamp returns whether a zero sample can be injected at the start timestamp,
+// which is optional and must precede the data point timestamp at the millisecond precision.
+func hasValidStartTimestamp(start, ts pcommon.Timestamp) bool {
+	return start > 0 && start.AsTime().UnixMilli() < ts.AsTime().UnixMilli()
+}
+
 func promToMimirTimeseries(promTs *prompb.TimeSeries) mimirpb.PreallocTimeseries {
 	labels := make([]mimirpb.LabelAdapter, 0, len(promTs.Labels))
 	for _, label := range promTs.Labels {
@@ -40,6 +40,17 @@ var bufferPool = sync.Pool{
 const SkipLabelNameValidationHeader = "X-Mimir-SkipLabelNameValidation"
 const statusClientClosedRequest = 499
 
+type contextKey int
+
+const requestHeadersContextKey contextKey = 0
+
+// RequestHeadersFromContext returns the HTTP headers of the push request being handled,
+// or nil if the request has not been received through the HTTP push handler.
+func RequestHeadersFromContext(ctx context.Context) http.Header {
+	headers, _ := ctx.Value(requestHeadersContextKey).(http.Header)
+	return headers
+}
+
 // Handler is a http.Handler which accepts WriteRequests.
 func Handler(
 	maxRecvMsgSize int,
@@ -76,7 +87,7 @@ func handler(maxRecvMsgSize int,
 	parser ParserFunc,
 ) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		ctx := context.WithValue(r.Context(), requestHeadersContextKey, r.Header)
 		logger := log.WithContext(ctx, log.Logger)
 		if sourceIPs != nil {
 			source := sourceIPs.Get(r)
@@ -135,6 +146,11 @@ func handler(maxRecvMsgSize int,
 			if resp.GetCode() != 202 {
 				level.Error(logger).Log("msg", "push error", "err", err)
 			}
+			for _, h := range resp.Headers {
+				for _, v := range h.Values {
+					w.Header().Add(h.Key, v)
+				}
+			}
 			http.Error(w, string(resp.Body), int(resp.Code))
 		}
 	})
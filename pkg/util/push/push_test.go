@@ -21,7 +21,11 @@ import (
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
+	"github.com/weaveworks/common/user"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
@@ -38,7 +42,7 @@ func TestHandler_remoteWrite(t *testing.T) {
 func TestHandler_otlpWriteNoCompression(t *testing.T) {
 	req := createOTLPRequest(t, createOTLPMetricRequest(t), false)
 	resp := httptest.NewRecorder()
-	handler := OTLPHandler(100000, nil, false, verifyWriteRequestHandler(t, mimirpb.API))
+	handler := OTLPHandler(100000, nil, false, nil, verifyWriteRequestHandler(t, mimirpb.OTLP))
 	handler.ServeHTTP(resp, req)
 	assert.Equal(t, 200, resp.Code)
 }
@@ -46,7 +50,7 @@ func TestHandler_otlpWriteNoCompression(t *testing.T) {
 func TestHandler_otlpWriteWithCompression(t *testing.T) {
 	req := createOTLPRequest(t, createOTLPMetricRequest(t), true)
 	resp := httptest.NewRecorder()
-	handler := OTLPHandler(100000, nil, false, verifyWriteRequestHandler(t, mimirpb.API))
+	handler := OTLPHandler(100000, nil, false, nil, verifyWriteRequestHandler(t, mimirpb.OTLP))
 	handler.ServeHTTP(resp, req)
 	assert.Equal(t, 200, resp.Code)
 }
@@ -56,7 +60,7 @@ func TestHandler_otlpWriteRequestTooBigNoCompression(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	// This one is caught in the r.ContentLength check.
-	handler := OTLPHandler(30, nil, false, verifyWriteRequestHandler(t, mimirpb.API))
+	handler := OTLPHandler(30, nil, false, nil, verifyWriteRequestHandler(t, mimirpb.OTLP))
 	handler.ServeHTTP(resp, req)
 	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
 	assert.Contains(t, resp.Body.String(), "the incoming push request has been rejected because its message size of 37 bytes is larger than the allowed limit of 30 bytes (err-mimir-distributor-max-write-message-size). To adjust the related limit, configure -distributor.max-recv-msg-size, or contact your service administrator.")
@@ -79,7 +83,7 @@ func TestHandler_otlpWriteRequestTooBigWithCompression(t *testing.T) {
 
 	resp := httptest.NewRecorder()
 
-	handler := OTLPHandler(140, nil, false, verifyWriteRequestHandler(t, mimirpb.API))
+	handler := OTLPHandler(140, nil, false, nil, verifyWriteRequestHandler(t, mimirpb.OTLP))
 	handler.ServeHTTP(resp, req)
 	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
 	body, err := io.ReadAll(resp.Body)
@@ -92,11 +96,100 @@ func TestHandler_otlpWriteRequestWithUnSupportedCompression(t *testing.T) {
 	req.Header.Set("Content-Encoding", "snappy")
 
 	resp := httptest.NewRecorder()
-	handler := OTLPHandler(100000, nil, false, verifyWriteRequestHandler(t, mimirpb.API))
+	handler := OTLPHandler(100000, nil, false, nil, verifyWriteRequestHandler(t, mimirpb.OTLP))
 	handler.ServeHTTP(resp, req)
 	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
 }
 
+type mockOTLPHandlerLimits struct {
+	ctZeroIngestionEnabled bool
+}
+
+func (l mockOTLPHandlerLimits) OTelCreatedTimestampZeroIngestionEnabled(string) bool {
+	return l.ctZeroIngestionEnabled
+}
+
+func TestHandler_otlpCreatedTimestampZeroIngestion(t *testing.T) {
+	start := time.UnixMilli(1000)
+	ts := time.UnixMilli(5000)
+
+	createRequest := func() pmetricotlp.Request {
+		md := pmetric.NewMetrics()
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+		counter := metrics.AppendEmpty()
+		counter.SetName("counter")
+		counter.SetDataType(pmetric.MetricDataTypeSum)
+		counter.Sum().SetIsMonotonic(true)
+		counter.Sum().SetAggregationTemporality(pmetric.MetricAggregationTemporalityCumulative)
+		point := counter.Sum().DataPoints().AppendEmpty()
+		point.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		point.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		point.SetDoubleVal(10)
+
+		// Zero samples are not injected for non-monotonic sums.
+		gauge := metrics.AppendEmpty()
+		gauge.SetName("updown")
+		gauge.SetDataType(pmetric.MetricDataTypeSum)
+		gauge.Sum().SetAggregationTemporality(pmetric.MetricAggregationTemporalityCumulative)
+		point = gauge.Sum().DataPoints().AppendEmpty()
+		point.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		point.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		point.SetDoubleVal(3)
+
+		histogram := metrics.AppendEmpty()
+		histogram.SetName("histogram")
+		histogram.SetDataType(pmetric.MetricDataTypeHistogram)
+		histogram.Histogram().SetAggregationTemporality(pmetric.MetricAggregationTemporalityCumulative)
+		hPoint := histogram.Histogram().DataPoints().AppendEmpty()
+		hPoint.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		hPoint.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		hPoint.SetCount(4)
+		hPoint.SetSum(20)
+		hPoint.SetExplicitBounds(pcommon.NewImmutableFloat64Slice([]float64{1}))
+		hPoint.SetBucketCounts(pcommon.NewImmutableUInt64Slice([]uint64{1, 3}))
+
+		return pmetricotlp.NewRequestFromMetrics(md)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			var received map[string][]mimirpb.Sample
+			handler := OTLPHandler(100000, nil, false, mockOTLPHandlerLimits{ctZeroIngestionEnabled: enabled}, func(_ context.Context, req *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+				defer cleanup()
+
+				received = map[string][]mimirpb.Sample{}
+				for _, series := range req.Timeseries {
+					received[mimirpb.FromLabelAdaptersToLabels(series.Labels).String()] = append([]mimirpb.Sample(nil), series.Samples...)
+				}
+				return &mimirpb.WriteResponse{}, nil
+			})
+
+			req := createOTLPRequest(t, createRequest(), false)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "test"))
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			samples := func(values ...float64) []mimirpb.Sample {
+				if !enabled {
+					return []mimirpb.Sample{{TimestampMs: 5000, Value: values[0]}}
+				}
+				return []mimirpb.Sample{{TimestampMs: 1000, Value: 0}, {TimestampMs: 5000, Value: values[0]}}
+			}
+
+			assert.Equal(t, map[string][]mimirpb.Sample{
+				`{__name__="counter"}`:                     samples(10),
+				`{__name__="updown"}`:                      {{TimestampMs: 5000, Value: 3}},
+				`{__name__="histogram_count"}`:             samples(4),
+				`{__name__="histogram_sum"}`:               samples(20),
+				`{__name__="histogram_bucket", le="1"}`:    samples(1),
+				`{__name__="histogram_bucket", le="+Inf"}`: samples(4),
+			}, received)
+		})
+	}
+}
+
 func TestHandler_mimirWriteRequest(t *testing.T) {
 	req := createRequest(t, createMimirWriteRequestProtobuf(t, false))
 	resp := httptest.NewRecorder()
@@ -118,6 +211,38 @@ func TestHandler_contextCanceledRequest(t *testing.T) {
 	assert.Equal(t, 499, resp.Code)
 }
 
+func TestHandler_errorWithHeaders(t *testing.T) {
+	req := createRequest(t, createMimirWriteRequestProtobuf(t, false))
+	resp := httptest.NewRecorder()
+	handler := Handler(100000, nil, false, func(_ context.Context, _ *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+		defer cleanup()
+		return nil, httpgrpc.ErrorFromHTTPResponse(&httpgrpc.HTTPResponse{
+			Code:    http.StatusServiceUnavailable,
+			Body:    []byte("ingestion paused"),
+			Headers: []*httpgrpc.Header{{Key: "Retry-After", Values: []string{"60"}}},
+		})
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+	assert.Contains(t, resp.Body.String(), "ingestion paused")
+}
+
+func TestHandler_requestHeadersInContext(t *testing.T) {
+	req := createRequest(t, createMimirWriteRequestProtobuf(t, false))
+	req.Header.Set("User-Agent", "test-agent/1.0")
+	resp := httptest.NewRecorder()
+	handler := Handler(100000, nil, false, func(ctx context.Context, _ *mimirpb.WriteRequest, cleanup func()) (*mimirpb.WriteResponse, error) {
+		defer cleanup()
+		assert.Equal(t, "test-agent/1.0", RequestHeadersFromContext(ctx).Get("User-Agent"))
+		return &mimirpb.WriteResponse{}, nil
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.Nil(t, RequestHeadersFromContext(context.Background()))
+}
+
 func TestHandler_EnsureSkipLabelNameValidationBehaviour(t *testing.T) {
 	tests := []struct {
 		name                                      string
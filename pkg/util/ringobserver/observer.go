@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package ringobserver provides a stable API to observe the membership changes of the hash rings
+// used by Mimir components, for the applications embedding Mimir modules.
+package ringobserver
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+)
+
+// Instance is a snapshot of a ring member.
+type Instance struct {
+	ID    string
+	Addr  string
+	Zone  string
+	State ring.InstanceState
+
+	// NumTokens is the number of tokens owned by the instance in the ring.
+	NumTokens int
+
+	// RegisteredAt is the time the instance has been registered to the ring, zero if unknown.
+	RegisteredAt time.Time
+
+	// LastHeartbeat is the time of the last heartbeat received from the instance when the snapshot has been taken.
+	LastHeartbeat time.Time
+}
+
+// Observer is notified about the ring membership changes. All notifications are sent on the same goroutine.
+type Observer interface {
+	// InstanceAdded is called when an instance joins the ring.
+	InstanceAdded(instance Instance)
+
+	// InstanceRemoved is called when an instance leaves the ring.
+	InstanceRemoved(instance Instance)
+
+	// InstanceChanged is called when the address, zone, state or number of tokens of an instance change.
+	// Heartbeats alone don't trigger a notification.
+	InstanceChanged(previous, current Instance)
+}
+
+// Watcher watches a ring in the KV store and notifies the registered observers about its membership changes.
+type Watcher struct {
+	services.Service
+
+	name     string
+	key      string
+	kvClient kv.Client
+	logger   log.Logger
+
+	mtx       sync.RWMutex
+	instances map[string]Instance
+	observers []Observer
+}
+
+// NewWatcher returns a service watching the ring stored under key in the KV store. The name is only used for logging.
+func NewWatcher(name, key string, kvClient kv.Client, logger log.Logger) *Watcher {
+	w := &Watcher{
+		name:      name,
+		key:       key,
+		kvClient:  kvClient,
+		logger:    log.With(logger, "ring", name),
+		instances: map[string]Instance{},
+	}
+
+	w.Service = services.NewBasicService(w.starting, w.running, nil)
+	return w
+}
+
+// AddObserver registers an observer. Observers added after the watcher has started are not notified about the
+// instances already in the ring: use Instances to get them.
+func (w *Watcher) AddObserver(o Observer) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.observers = append(w.observers, o)
+}
+
+// Instances returns the instances currently in the ring, sorted by ID.
+func (w *Watcher) Instances() []Instance {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	instances := make([]Instance, 0, len(w.instances))
+	for _, inst := range w.instances {
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances
+}
+
+func (w *Watcher) starting(ctx context.Context) error {
+	value, err := w.kvClient.Get(ctx, w.key)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read the %s ring", w.name)
+	}
+
+	w.update(value)
+	return nil
+}
+
+func (w *Watcher) running(ctx context.Context) error {
+	w.kvClient.WatchKey(ctx, w.key, func(value interface{}) bool {
+		w.update(value)
+		return true
+	})
+	return nil
+}
+
+func (w *Watcher) update(value interface{}) {
+	var desc *ring.Desc
+	if value != nil {
+		var ok bool
+		if desc, ok = value.(*ring.Desc); !ok {
+			level.Warn(w.logger).Log("msg", "unexpected value in the ring KV store", "key", w.key)
+			return
+		}
+	}
+
+	current := make(map[string]Instance)
+	if desc != nil {
+		for id, inst := range desc.Ingesters {
+			// When using memberlist, instances which left the ring are kept as tombstones for a while.
+			if inst.State == ring.LEFT {
+				continue
+			}
+			current[id] = instanceFromDesc(id, inst)
+		}
+	}
+
+	w.mtx.Lock()
+	previous := w.instances
+	w.instances = current
+	observers := w.observers
+	w.mtx.Unlock()
+
+	// Notify in a deterministic order.
+	ids := make([]string, 0, len(previous)+len(current))
+	for id := range previous {
+		ids = append(ids, id)
+	}
+	for id := range current {
+		if _, ok := previous[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		prev, hadPrev := previous[id]
+		curr, hasCurr := current[id]
+
+		for _, o := range observers {
+			switch {
+			case !hadPrev:
+				o.InstanceAdded(curr)
+			case !hasCurr:
+				o.InstanceRemoved(prev)
+			case changed(prev, curr):
+				o.InstanceChanged(prev, curr)
+			}
+		}
+	}
+}
+
+func instanceFromDesc(id string, desc ring.InstanceDesc) Instance {
+	inst := Instance{
+		ID:            id,
+		Addr:          desc.Addr,
+		Zone:          desc.Zone,
+		State:         desc.State,
+		NumTokens:     len(desc.Tokens),
+		LastHeartbeat: time.Unix(desc.Timestamp, 0),
+	}
+	if desc.RegisteredTimestamp > 0 {
+		inst.RegisteredAt = time.Unix(desc.RegisteredTimestamp, 0)
+	}
+	return inst
+}
+
+func changed(prev, curr Instance) bool {
+	return prev.Addr != curr.Addr || prev.Zone != curr.Zone || prev.State != curr.State || prev.NumTokens != curr.NumTokens
+}
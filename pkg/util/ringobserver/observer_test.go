@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ringobserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mtx    sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) InstanceAdded(instance Instance) {
+	o.record(fmt.Sprintf("added %s %s", instance.ID, instance.State))
+}
+
+func (o *recordingObserver) InstanceRemoved(instance Instance) {
+	o.record(fmt.Sprintf("removed %s", instance.ID))
+}
+
+func (o *recordingObserver) InstanceChanged(previous, current Instance) {
+	o.record(fmt.Sprintf("changed %s %s -> %s", current.ID, previous.State, current.State))
+}
+
+func (o *recordingObserver) record(event string) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) getEvents() []string {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return append([]string(nil), o.events...)
+}
+
+func TestWatcher(t *testing.T) {
+	ctx := context.Background()
+	kvClient, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	updateRing := func(f func(desc *ring.Desc)) {
+		require.NoError(t, kvClient.CAS(ctx, "ring", func(in interface{}) (interface{}, bool, error) {
+			desc, _ := in.(*ring.Desc)
+			if desc == nil {
+				desc = ring.NewDesc()
+			}
+			f(desc)
+			return desc, true, nil
+		}))
+	}
+
+	updateRing(func(desc *ring.Desc) {
+		desc.AddIngester("instance-1", "1.1.1.1", "zone-a", []uint32{1, 2}, ring.ACTIVE, time.Now())
+	})
+
+	observer := &recordingObserver{}
+	w := NewWatcher("test", "ring", kvClient, log.NewNopLogger())
+	w.AddObserver(observer)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, w))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, w)) })
+
+	// The instances in the ring at startup are notified as added.
+	assert.Equal(t, []string{"added instance-1 ACTIVE"}, observer.getEvents())
+	require.Len(t, w.Instances(), 1)
+	assert.Equal(t, Instance{
+		ID:            "instance-1",
+		Addr:          "1.1.1.1",
+		Zone:          "zone-a",
+		State:         ring.ACTIVE,
+		NumTokens:     2,
+		RegisteredAt:  w.Instances()[0].RegisteredAt,
+		LastHeartbeat: w.Instances()[0].LastHeartbeat,
+	}, w.Instances()[0])
+
+	// Watch key notifications may be batched, so wait for each change to be observed before the next one.
+	waitEvents := func(expected int) {
+		require.Eventually(t, func() bool {
+			return len(observer.getEvents()) == expected
+		}, 5*time.Second, 10*time.Millisecond, "events: %v", observer.getEvents())
+	}
+
+	updateRing(func(desc *ring.Desc) {
+		desc.AddIngester("instance-2", "2.2.2.2", "zone-b", nil, ring.JOINING, time.Now())
+	})
+	waitEvents(2)
+
+	updateRing(func(desc *ring.Desc) {
+		// Heartbeats don't trigger any notification.
+		inst := desc.Ingesters["instance-1"]
+		inst.Timestamp++
+		desc.Ingesters["instance-1"] = inst
+
+		inst = desc.Ingesters["instance-2"]
+		inst.State = ring.ACTIVE
+		desc.Ingesters["instance-2"] = inst
+	})
+	waitEvents(3)
+
+	updateRing(func(desc *ring.Desc) {
+		desc.RemoveIngester("instance-1")
+	})
+	waitEvents(4)
+
+	assert.Equal(t, []string{
+		"added instance-1 ACTIVE",
+		"added instance-2 JOINING",
+		"changed instance-2 JOINING -> ACTIVE",
+		"removed instance-1",
+	}, observer.getEvents())
+
+	instances := w.Instances()
+	require.Len(t, instances, 1)
+	assert.Equal(t, "instance-2", instances[0].ID)
+}
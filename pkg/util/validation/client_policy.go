@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/regexp"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ClientPolicyActionAllow accepts the push request, skipping the remaining policies.
+	ClientPolicyActionAllow = "allow"
+	// ClientPolicyActionDeny rejects the push request.
+	ClientPolicyActionDeny = "deny"
+	// ClientPolicyActionRateLimit rejects the push request if the matching clients exceed the configured request rate.
+	ClientPolicyActionRateLimit = "ratelimit"
+
+	defaultClientPolicyHeader = "User-Agent"
+)
+
+// ClientPolicy is an ingestion policy applied by distributors to the push requests whose client
+// identity, read from a HTTP header, matches a regular expression.
+type ClientPolicy struct {
+	Header string  `yaml:"header" json:"header"`
+	Regex  string  `yaml:"regex" json:"regex"`
+	Action string  `yaml:"action" json:"action"`
+	Rate   float64 `yaml:"rate,omitempty" json:"rate,omitempty"`
+	Burst  int     `yaml:"burst,omitempty" json:"burst,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (p *ClientPolicy) UnmarshalYAML(value *yaml.Node) error {
+	type plain ClientPolicy
+	if err := value.DecodeWithOptions((*plain)(p), yaml.DecodeOptions{KnownFields: true}); err != nil {
+		return err
+	}
+	return p.init()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *ClientPolicy) UnmarshalJSON(data []byte) error {
+	type plain ClientPolicy
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	return p.init()
+}
+
+// init validates the policy, applies the defaults and compiles the regular expression.
+func (p *ClientPolicy) init() error {
+	if p.Header == "" {
+		p.Header = defaultClientPolicyHeader
+	}
+
+	switch p.Action {
+	case ClientPolicyActionAllow, ClientPolicyActionDeny:
+	case ClientPolicyActionRateLimit:
+		if p.Rate <= 0 {
+			return fmt.Errorf("ingestion client policy with action %q requires a rate greater than 0", p.Action)
+		}
+		if p.Burst <= 0 {
+			return fmt.Errorf("ingestion client policy with action %q requires a burst greater than 0", p.Action)
+		}
+	default:
+		return fmt.Errorf("unsupported ingestion client policy action %q, supported values are: %s, %s, %s", p.Action, ClientPolicyActionAllow, ClientPolicyActionDeny, ClientPolicyActionRateLimit)
+	}
+
+	// The regular expression is fully anchored, like in relabel configs.
+	var err error
+	p.regex, err = regexp.Compile("^(?:" + p.Regex + ")$")
+	return errors.Wrapf(err, "invalid ingestion client policy regex %q", p.Regex)
+}
+
+// Matches returns whether the client identity value matches the policy.
+func (p ClientPolicy) Matches(value string) bool {
+	return p.regex != nil && p.regex.MatchString(value)
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestClientPolicy_Unmarshal(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		expectedErr   string
+		expectedMatch map[string]bool
+	}{
+		"default header": {
+			input:         `{"regex": "bad-agent/.*", "action": "deny"}`,
+			expectedMatch: map[string]bool{"bad-agent/1.0": true, "good-agent/1.0": false, "my-bad-agent/1.0": false},
+		},
+		"ratelimit": {
+			input:         `{"header": "X-Client-ID", "regex": "noisy", "action": "ratelimit", "rate": 10, "burst": 20}`,
+			expectedMatch: map[string]bool{"noisy": true, "noisy-2": false},
+		},
+		"ratelimit without rate": {
+			input:       `{"regex": "noisy", "action": "ratelimit", "burst": 20}`,
+			expectedErr: `ingestion client policy with action "ratelimit" requires a rate greater than 0`,
+		},
+		"ratelimit without burst": {
+			input:       `{"regex": "noisy", "action": "ratelimit", "rate": 10}`,
+			expectedErr: `ingestion client policy with action "ratelimit" requires a burst greater than 0`,
+		},
+		"unsupported action": {
+			input:       `{"regex": "noisy", "action": "drop"}`,
+			expectedErr: `unsupported ingestion client policy action "drop", supported values are: allow, deny, ratelimit`,
+		},
+		"invalid regex": {
+			input:       `{"regex": "(", "action": "deny"}`,
+			expectedErr: `invalid ingestion client policy regex "("`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			// JSON is a subset of YAML, so the same input can be used with both decoders.
+			var fromYAML, fromJSON ClientPolicy
+			errYAML := yaml.Unmarshal([]byte(testData.input), &fromYAML)
+			errJSON := json.Unmarshal([]byte(testData.input), &fromJSON)
+
+			if testData.expectedErr != "" {
+				require.Error(t, errYAML)
+				require.Error(t, errJSON)
+				assert.Contains(t, errYAML.Error(), testData.expectedErr)
+				assert.Contains(t, errJSON.Error(), testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, errYAML)
+			require.NoError(t, errJSON)
+			for _, policy := range []ClientPolicy{fromYAML, fromJSON} {
+				if policy.Action != ClientPolicyActionRateLimit {
+					assert.Equal(t, "User-Agent", policy.Header)
+				}
+				for value, expected := range testData.expectedMatch {
+					assert.Equal(t, expected, policy.Matches(value), value)
+				}
+			}
+		})
+	}
+}
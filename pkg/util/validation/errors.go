@@ -287,6 +287,64 @@ func NewIngestionRateLimitedError(limit float64, burst int) LimitError {
 		ingestionRateFlag, ingestionBurstSizeFlag))
 }
 
+func NewMaxPushRequestBytesError(actual, limit int) LimitError {
+	return LimitError(globalerror.MaxPushRequestBytes.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the push request has been rejected because its uncompressed size of %d bytes exceeds the limit of %d bytes", actual, limit),
+		maxPushRequestBytesFlag))
+}
+
+func NewMaxSeriesPerRequestError(actual, limit int) LimitError {
+	return LimitError(globalerror.MaxSeriesPerRequest.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the push request has been rejected because it contains %d series, exceeding the limit of %d series per request", actual, limit),
+		maxSeriesPerRequestFlag))
+}
+
+func NewMaxLabelsBytesPerRequestError(actual, limit int) LimitError {
+	return LimitError(globalerror.MaxLabelsBytesPerRequest.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the push request has been rejected because the labels of its series have a total size of %d bytes, exceeding the limit of %d bytes per request", actual, limit),
+		maxLabelsBytesPerRequestFlag))
+}
+
+func NewIngestionMaintenanceModeError(retryAfter time.Duration) LimitError {
+	return LimitError(globalerror.IngestionMaintenanceMode.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the push request has been rejected because the tenant's ingestion is paused for maintenance, retry after %s", retryAfter),
+		ingestionMaintenanceFlag))
+}
+
+func NewQueryLoadSheddingError() LimitError {
+	return LimitError(globalerror.QueryLoadShedding.MessageWithPerTenantLimitConfig(
+		"the read request has been rejected because the tenant's query load is being shed, only the rule evaluations are allowed",
+		queryLoadSheddingFlag))
+}
+
+func NewMaxFetchedChunkBytesPerMinuteError(limit int) LimitError {
+	return LimitError(globalerror.MaxFetchedChunkBytesPerMinute.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the read request has been rejected because the tenant exceeded the limit of %d chunk bytes fetched in the last minute", limit),
+		maxFetchedChunkBytesPerMinuteFlag))
+}
+
+func NewMaxEstimatedQueryCostError(estimatedCost, limit int64) LimitError {
+	return LimitError(globalerror.MaxEstimatedQueryCost.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the query has been rejected because its estimated cost of %d samples exceeds the limit of %d samples", estimatedCost, limit),
+		maxEstimatedQueryCostFlag))
+}
+
+func NewExperimentalPromQLFunctionError(function string) LimitError {
+	return LimitError(globalerror.ExperimentalPromQLFunction.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the query uses the experimental PromQL function %s, which isn't enabled for the tenant", function),
+		experimentalPromQLFunctionsFlag))
+}
+
+func NewIngestionClientDeniedError(header, value string) LimitError {
+	return LimitError(globalerror.IngestionClientDenied.Message(
+		fmt.Sprintf("the push request has been rejected because the client %s %q is denied by the tenant's ingestion client policies", header, value)))
+}
+
+func NewIngestionClientRateLimitedError(header, regex string, rate float64, burst int) LimitError {
+	return LimitError(globalerror.IngestionClientRateLimited.Message(
+		fmt.Sprintf("the push request has been rejected because the clients whose %s matches %q exceeded the rate limit of the tenant's ingestion client policies, set to %v requests/s per distributor with a maximum allowed burst of %d", header, regex, rate, burst)))
+}
+
 // formatLabelSet formats label adapters as a metric name with labels, while preserving
 // label order, and keeping duplicates. If there are multiple "__name__" labels, only
 // first one is used as metric name, other ones will be included as regular labels.
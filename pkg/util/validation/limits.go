@@ -22,27 +22,39 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
+	"github.com/grafana/mimir/pkg/ruler/notifier"
 )
 
 const (
-	MaxSeriesPerMetricFlag     = "ingester.max-global-series-per-metric"
-	MaxMetadataPerMetricFlag   = "ingester.max-global-metadata-per-metric"
-	MaxSeriesPerUserFlag       = "ingester.max-global-series-per-user"
-	MaxMetadataPerUserFlag     = "ingester.max-global-metadata-per-user"
-	MaxChunksPerQueryFlag      = "querier.max-fetched-chunks-per-query"
-	MaxChunkBytesPerQueryFlag  = "querier.max-fetched-chunk-bytes-per-query"
-	MaxSeriesPerQueryFlag      = "querier.max-fetched-series-per-query"
-	maxLabelNamesPerSeriesFlag = "validation.max-label-names-per-series"
-	maxLabelNameLengthFlag     = "validation.max-length-label-name"
-	maxLabelValueLengthFlag    = "validation.max-length-label-value"
-	maxMetadataLengthFlag      = "validation.max-metadata-length"
-	creationGracePeriodFlag    = "validation.create-grace-period"
-	maxQueryLengthFlag         = "store.max-query-length"
-	requestRateFlag            = "distributor.request-rate-limit"
-	requestBurstSizeFlag       = "distributor.request-burst-size"
-	ingestionRateFlag          = "distributor.ingestion-rate-limit"
-	ingestionBurstSizeFlag     = "distributor.ingestion-burst-size"
-	HATrackerMaxClustersFlag   = "distributor.ha-tracker.max-clusters"
+	MaxSeriesPerMetricFlag            = "ingester.max-global-series-per-metric"
+	MaxSeriesPerScopeFlag             = "ingester.max-global-series-per-scope"
+	MaxMetadataPerMetricFlag          = "ingester.max-global-metadata-per-metric"
+	MaxSeriesPerUserFlag              = "ingester.max-global-series-per-user"
+	MaxMetadataPerUserFlag            = "ingester.max-global-metadata-per-user"
+	MaxChunksPerQueryFlag             = "querier.max-fetched-chunks-per-query"
+	MaxChunkBytesPerQueryFlag         = "querier.max-fetched-chunk-bytes-per-query"
+	MaxSeriesPerQueryFlag             = "querier.max-fetched-series-per-query"
+	MaxEstimatedMemoryPerQueryFlag    = "querier.max-estimated-memory-per-query"
+	MaxEstimatedMemoryPerTenantFlag   = "querier.max-estimated-memory-per-tenant"
+	maxLabelNamesPerSeriesFlag        = "validation.max-label-names-per-series"
+	maxLabelNameLengthFlag            = "validation.max-length-label-name"
+	maxLabelValueLengthFlag           = "validation.max-length-label-value"
+	maxMetadataLengthFlag             = "validation.max-metadata-length"
+	creationGracePeriodFlag           = "validation.create-grace-period"
+	maxQueryLengthFlag                = "store.max-query-length"
+	requestRateFlag                   = "distributor.request-rate-limit"
+	requestBurstSizeFlag              = "distributor.request-burst-size"
+	ingestionRateFlag                 = "distributor.ingestion-rate-limit"
+	ingestionBurstSizeFlag            = "distributor.ingestion-burst-size"
+	HATrackerMaxClustersFlag          = "distributor.ha-tracker.max-clusters"
+	maxPushRequestBytesFlag           = "distributor.max-push-request-bytes"
+	maxSeriesPerRequestFlag           = "distributor.max-series-per-request"
+	maxLabelsBytesPerRequestFlag      = "distributor.max-labels-bytes-per-request"
+	ingestionMaintenanceFlag          = "distributor.ingestion-maintenance-mode"
+	queryLoadSheddingFlag             = "query-frontend.load-shedding-enabled"
+	maxFetchedChunkBytesPerMinuteFlag = "query-frontend.max-fetched-chunk-bytes-per-minute"
+	maxEstimatedQueryCostFlag         = "query-frontend.max-estimated-query-cost"
+	experimentalPromQLFunctionsFlag   = "querier.experimental-promql-functions"
 
 	// MinCompactorPartialBlockDeletionDelay is the minimum partial blocks deletion delay that can be configured in Mimir.
 	MinCompactorPartialBlockDeletionDelay = 4 * time.Hour
@@ -88,57 +100,115 @@ type Limits struct {
 	EnforceMetadataMetricName bool                `yaml:"enforce_metadata_metric_name" json:"enforce_metadata_metric_name" category:"advanced"`
 	IngestionTenantShardSize  int                 `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
 	MetricRelabelConfigs      []*relabel.Config   `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs." category:"experimental"`
+	MaxPushRequestBytes       int                 `yaml:"max_push_request_bytes" json:"max_push_request_bytes" category:"experimental"`
+	MaxSeriesPerRequest       int                 `yaml:"max_series_per_request" json:"max_series_per_request" category:"experimental"`
+	MaxLabelsBytesPerRequest  int                 `yaml:"max_labels_bytes_per_request" json:"max_labels_bytes_per_request" category:"experimental"`
+	// Maintenance mode.
+	IngestionMaintenanceMode       bool           `yaml:"ingestion_maintenance_mode" json:"ingestion_maintenance_mode" category:"experimental"`
+	IngestionMaintenanceRetryAfter model.Duration `yaml:"ingestion_maintenance_retry_after" json:"ingestion_maintenance_retry_after" category:"experimental"`
+	// Client identity based ingestion policies.
+	IngestionClientPolicies []ClientPolicy `yaml:"ingestion_client_policies,omitempty" json:"ingestion_client_policies,omitempty" doc:"nocli|description=List of ingestion policies matched against the client identity of HTTP push requests. Each policy has a header (default User-Agent), a fully anchored regex matched against the header value, and an action: allow, deny or ratelimit. Policies are evaluated in order and the first matching one applies. Denied requests are rejected with status code 403. The ratelimit action requires rate (requests/s) and burst, applied by each distributor to all the matching requests, which are rejected with status code 429 when exceeding the limit." category:"experimental"`
+	// Dead-letter of the series rejected by the distributor validation.
+	RejectedSeriesDeadLetterEnabled bool `yaml:"rejected_series_dead_letter_enabled" json:"rejected_series_dead_letter_enabled" category:"experimental"`
+	// Created timestamp zero ingestion.
+	OTelCreatedTimestampZeroIngestionEnabled bool `yaml:"otel_created_timestamp_zero_ingestion_enabled" json:"otel_created_timestamp_zero_ingestion_enabled" category:"experimental"`
 
 	// Ingester enforced limits.
 	// Series
 	MaxGlobalSeriesPerUser   int `yaml:"max_global_series_per_user" json:"max_global_series_per_user"`
 	MaxGlobalSeriesPerMetric int `yaml:"max_global_series_per_metric" json:"max_global_series_per_metric"`
+	// Series limit warn-only mode
+	MaxGlobalSeriesPerUserWarnOnly               bool    `yaml:"max_global_series_per_user_warn_only" json:"max_global_series_per_user_warn_only" category:"experimental"`
+	MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio float64 `yaml:"max_global_series_per_user_warn_only_admission_ratio" json:"max_global_series_per_user_warn_only_admission_ratio" category:"experimental"`
+	// Series limit per scope
+	SeriesLimitScopeLabel   string `yaml:"series_limit_scope_label" json:"series_limit_scope_label" category:"experimental"`
+	MaxGlobalSeriesPerScope int    `yaml:"max_global_series_per_scope" json:"max_global_series_per_scope" category:"experimental"`
 	// Metadata
 	MaxGlobalMetricsWithMetadataPerUser int `yaml:"max_global_metadata_per_user" json:"max_global_metadata_per_user"`
 	MaxGlobalMetadataPerMetric          int `yaml:"max_global_metadata_per_metric" json:"max_global_metadata_per_metric"`
+	MaxMetadataPerBlock                 int `yaml:"max_metadata_per_block" json:"max_metadata_per_block" category:"experimental"`
 	// Exemplars
 	MaxGlobalExemplarsPerUser int `yaml:"max_global_exemplars_per_user" json:"max_global_exemplars_per_user" category:"experimental"`
+	MaxExemplarsPerBlock      int `yaml:"max_exemplars_per_block" json:"max_exemplars_per_block" category:"experimental"`
 	// Active series custom trackers
 	// TODO remove this with Mimir version 2.4
 	ActiveSeriesCustomTrackersConfigOld activeseries.CustomTrackersConfig `yaml:"active_series_custom_trackers_config" json:"active_series_custom_trackers_config" doc:"hidden"`
 	ActiveSeriesCustomTrackersConfig    activeseries.CustomTrackersConfig `yaml:"active_series_custom_trackers" json:"active_series_custom_trackers" doc:"description=Additional custom trackers for active metrics. If there are active series matching a provided matcher (map value), the count will be exposed in the custom trackers metric labeled using the tracker name (map key). Zero valued counts are not exposed (and removed when they go back to zero)." category:"advanced"`
 	// Max allowed time window for out-of-order samples.
 	OutOfOrderTimeWindow model.Duration `yaml:"out_of_order_time_window" json:"out_of_order_time_window" category:"experimental"`
+	// TSDB overrides.
+	TSDBBlockDuration             model.Duration `yaml:"tsdb_block_duration" json:"tsdb_block_duration" category:"experimental"`
+	TSDBHeadCompactionIdleTimeout model.Duration `yaml:"tsdb_head_compaction_idle_timeout" json:"tsdb_head_compaction_idle_timeout" category:"experimental"`
 
 	// Querier enforced limits.
-	MaxChunksPerQuery              int            `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
-	MaxFetchedSeriesPerQuery       int            `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
-	MaxFetchedChunkBytesPerQuery   int            `yaml:"max_fetched_chunk_bytes_per_query" json:"max_fetched_chunk_bytes_per_query"`
-	MaxQueryLookback               model.Duration `yaml:"max_query_lookback" json:"max_query_lookback"`
-	MaxQueryLength                 model.Duration `yaml:"max_query_length" json:"max_query_length"`
-	MaxQueryParallelism            int            `yaml:"max_query_parallelism" json:"max_query_parallelism"`
-	MaxLabelsQueryLength           model.Duration `yaml:"max_labels_query_length" json:"max_labels_query_length"`
-	MaxCacheFreshness              model.Duration `yaml:"max_cache_freshness" json:"max_cache_freshness" category:"advanced"`
-	MaxQueriersPerTenant           int            `yaml:"max_queriers_per_tenant" json:"max_queriers_per_tenant"`
-	QueryShardingTotalShards       int            `yaml:"query_sharding_total_shards" json:"query_sharding_total_shards"`
-	QueryShardingMaxShardedQueries int            `yaml:"query_sharding_max_sharded_queries" json:"query_sharding_max_sharded_queries"`
-	SplitInstantQueriesByInterval  model.Duration `yaml:"split_instant_queries_by_interval" json:"split_instant_queries_by_interval" category:"experimental"`
+	MaxChunksPerQuery                int                    `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
+	MaxFetchedSeriesPerQuery         int                    `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
+	MaxFetchedChunkBytesPerQuery     int                    `yaml:"max_fetched_chunk_bytes_per_query" json:"max_fetched_chunk_bytes_per_query"`
+	MaxEstimatedMemoryPerQuery       int                    `yaml:"max_estimated_memory_per_query" json:"max_estimated_memory_per_query" category:"experimental"`
+	MaxEstimatedMemoryPerTenant      int                    `yaml:"max_estimated_memory_per_tenant" json:"max_estimated_memory_per_tenant" category:"experimental"`
+	MaxQueryLookback                 model.Duration         `yaml:"max_query_lookback" json:"max_query_lookback"`
+	MaxQueryLength                   model.Duration         `yaml:"max_query_length" json:"max_query_length"`
+	MaxQueryParallelism              int                    `yaml:"max_query_parallelism" json:"max_query_parallelism"`
+	MaxLabelsQueryLength             model.Duration         `yaml:"max_labels_query_length" json:"max_labels_query_length"`
+	MaxCacheFreshness                model.Duration         `yaml:"max_cache_freshness" json:"max_cache_freshness" category:"advanced"`
+	ResultsCacheTTLForEmptyResults   model.Duration         `yaml:"results_cache_ttl_for_empty_results" json:"results_cache_ttl_for_empty_results" category:"experimental"`
+	ResultsCacheTTLForErrors         model.Duration         `yaml:"results_cache_ttl_for_errors" json:"results_cache_ttl_for_errors" category:"experimental"`
+	ResultsCacheTTLForInstantQueries model.Duration         `yaml:"results_cache_ttl_for_instant_queries" json:"results_cache_ttl_for_instant_queries" category:"experimental"`
+	ResultsCacheTTLForLabelsQuery    model.Duration         `yaml:"results_cache_ttl_for_labels_query" json:"results_cache_ttl_for_labels_query" category:"experimental"`
+	ResultsCacheMaxLabelsQueryItems  int                    `yaml:"results_cache_max_labels_query_items" json:"results_cache_max_labels_query_items" category:"experimental"`
+	ResultsCacheBypassEnabled        bool                   `yaml:"results_cache_bypass_enabled" json:"results_cache_bypass_enabled" category:"experimental"`
+	MaxQueriersPerTenant             int                    `yaml:"max_queriers_per_tenant" json:"max_queriers_per_tenant"`
+	QuerierCapacityWeight            int                    `yaml:"querier_capacity_weight" json:"querier_capacity_weight" category:"experimental"`
+	MaxOutstandingRequestsPerTenant  int                    `yaml:"max_outstanding_requests_per_tenant" json:"max_outstanding_requests_per_tenant" category:"experimental"`
+	RulerMaxOutstandingPerTenant     int                    `yaml:"ruler_max_outstanding_requests_per_tenant" json:"ruler_max_outstanding_requests_per_tenant" category:"experimental"`
+	MaxConcurrentUserQueries         int                    `yaml:"max_concurrent_user_queries" json:"max_concurrent_user_queries" category:"experimental"`
+	RulerMaxConcurrentQueries        int                    `yaml:"ruler_max_concurrent_queries" json:"ruler_max_concurrent_queries" category:"experimental"`
+	RulerQueryTimeout                model.Duration         `yaml:"ruler_query_timeout" json:"ruler_query_timeout" category:"experimental"`
+	QueryShardingTotalShards         int                    `yaml:"query_sharding_total_shards" json:"query_sharding_total_shards"`
+	QueryShardingMaxShardedQueries   int                    `yaml:"query_sharding_max_sharded_queries" json:"query_sharding_max_sharded_queries"`
+	QueryShardingMaxRequestedShards  int                    `yaml:"query_sharding_max_requested_shards" json:"query_sharding_max_requested_shards" category:"experimental"`
+	SplitInstantQueriesByInterval    model.Duration         `yaml:"split_instant_queries_by_interval" json:"split_instant_queries_by_interval" category:"experimental"`
+	SubquerySpinOffEnabled           bool                   `yaml:"subquery_spin_off_enabled" json:"subquery_spin_off_enabled" category:"experimental"`
+	QueryResultLabelRules            []ResultLabelRule      `yaml:"query_result_label_rules,omitempty" json:"query_result_label_rules,omitempty" doc:"nocli|description=List of rules applied by the query-frontend to the labels of the series in the results of instant and range queries, and in the responses of the series, label names, label values and remote read requests, before they're returned to the client. Each rule has a label and an action: drop removes the label, hash replaces the label value with its hex-encoded HMAC-SHA256 keyed with -query-frontend.result-label-rules-hash-key, or removes the label if the key isn't set, and rename renames the label to target_label, overriding the target label if already set. Rules are applied in order. Series whose labels become identical are not merged." category:"experimental"`
+	QueryLoadSheddingEnabled         bool                   `yaml:"query_load_shedding_enabled" json:"query_load_shedding_enabled" category:"experimental"`
+	SlowQueryLogThreshold            model.Duration         `yaml:"slow_query_log_threshold" json:"slow_query_log_threshold" category:"experimental"`
+	MaxFetchedChunkBytesPerMinute    int                    `yaml:"max_fetched_chunk_bytes_per_minute" json:"max_fetched_chunk_bytes_per_minute" category:"experimental"`
+	MaxEstimatedQueryCost            int                    `yaml:"max_estimated_query_cost" json:"max_estimated_query_cost" category:"experimental"`
+	RemoteQueryFederationURLs        flagext.StringSlice    `yaml:"remote_query_federation_urls" json:"remote_query_federation_urls" category:"experimental"`
+	SecondaryQuerySourceURL          string                 `yaml:"secondary_query_source_url" json:"secondary_query_source_url" category:"experimental"`
+	SecondaryQuerySourceTimeWindow   model.Duration         `yaml:"secondary_query_source_time_window" json:"secondary_query_source_time_window" category:"experimental"`
+	StreamingPromQLEngineEnabled     bool                   `yaml:"streaming_promql_engine_enabled" json:"streaming_promql_engine_enabled" category:"experimental"`
+	ExperimentalPromQLFunctions      flagext.StringSliceCSV `yaml:"experimental_promql_functions" json:"experimental_promql_functions" category:"experimental"`
+	AutoDownsamplingEnabled          bool                   `yaml:"auto_downsampling_enabled" json:"auto_downsampling_enabled" category:"experimental"`
+	ZoneOutagePartialResultsEnabled  bool                   `yaml:"zone_outage_partial_results_enabled" json:"zone_outage_partial_results_enabled" category:"experimental"`
 	// Cardinality
 	CardinalityAnalysisEnabled                    bool `yaml:"cardinality_analysis_enabled" json:"cardinality_analysis_enabled"`
 	LabelNamesAndValuesResultsMaxSizeBytes        int  `yaml:"label_names_and_values_results_max_size_bytes" json:"label_names_and_values_results_max_size_bytes"`
 	LabelValuesMaxCardinalityLabelNamesPerRequest int  `yaml:"label_values_max_cardinality_label_names_per_request" json:"label_values_max_cardinality_label_names_per_request"`
 
 	// Ruler defaults and limits.
-	RulerEvaluationDelay        model.Duration `yaml:"ruler_evaluation_delay_duration" json:"ruler_evaluation_delay_duration"`
-	RulerTenantShardSize        int            `yaml:"ruler_tenant_shard_size" json:"ruler_tenant_shard_size"`
-	RulerMaxRulesPerRuleGroup   int            `yaml:"ruler_max_rules_per_rule_group" json:"ruler_max_rules_per_rule_group"`
-	RulerMaxRuleGroupsPerTenant int            `yaml:"ruler_max_rule_groups_per_tenant" json:"ruler_max_rule_groups_per_tenant"`
+	RulerEvaluationDelay          model.Duration                    `yaml:"ruler_evaluation_delay_duration" json:"ruler_evaluation_delay_duration"`
+	RulerTenantShardSize          int                               `yaml:"ruler_tenant_shard_size" json:"ruler_tenant_shard_size"`
+	RulerMaxRulesPerRuleGroup     int                               `yaml:"ruler_max_rules_per_rule_group" json:"ruler_max_rules_per_rule_group"`
+	RulerMaxRuleGroupsPerTenant   int                               `yaml:"ruler_max_rule_groups_per_tenant" json:"ruler_max_rule_groups_per_tenant"`
+	RulerAlertmanagerClientConfig notifier.AlertmanagerClientConfig `yaml:"ruler_alertmanager_client_config,omitempty" json:"ruler_alertmanager_client_config,omitempty" doc:"nocli|description=Per-tenant Alertmanager client configuration. When the Alertmanager URL is set, the ruler sends the alerts of the tenant to the configured Alertmanager, using the configured TLS, basic authentication and OAuth2 client options, instead of the Alertmanager configured with -ruler.alertmanager-url. The options have the same format as the alertmanager_url and alertmanager_client options of the ruler block." category:"experimental"`
 
 	// Store-gateway.
-	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayTenantShardSize                      int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayTenantReplicationFactor              int `yaml:"store_gateway_tenant_replication_factor" json:"store_gateway_tenant_replication_factor" category:"experimental"`
+	StoreGatewayTenantMaxConcurrentSeriesRequests    int `yaml:"store_gateway_tenant_max_concurrent_series_requests" json:"store_gateway_tenant_max_concurrent_series_requests" category:"experimental"`
+	StoreGatewayTenantMaxFetchedChunkBytesPerRequest int `yaml:"store_gateway_tenant_max_fetched_chunk_bytes_per_request" json:"store_gateway_tenant_max_fetched_chunk_bytes_per_request" category:"experimental"`
 
 	// Compactor.
-	CompactorBlocksRetentionPeriod     model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
-	CompactorSplitAndMergeShards       int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
-	CompactorSplitGroups               int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
-	CompactorTenantShardSize           int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
-	CompactorPartialBlockDeletionDelay model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
-	CompactorBlockUploadEnabled        bool           `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorBlocksRetentionPeriod          model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
+	CompactorSplitAndMergeShards            int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
+	CompactorSplitGroups                    int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
+	CompactorTenantShardSize                int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
+	CompactorPartialBlockDeletionDelay      model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
+	CompactorBlockUploadEnabled             bool           `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorAllowedTimeWindows             TimeWindows    `yaml:"compactor_allowed_time_windows" json:"compactor_allowed_time_windows" category:"experimental"`
+	CompactorFirstLevelCompactionWaitPeriod model.Duration `yaml:"compactor_first_level_compaction_wait_period" json:"compactor_first_level_compaction_wait_period" category:"experimental"`
+	CompactorColdStorageArchiveAfter        model.Duration `yaml:"compactor_cold_storage_archive_after" json:"compactor_cold_storage_archive_after" category:"experimental"`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -183,19 +253,37 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	_ = l.CreationGracePeriod.Set("10m")
 	f.Var(&l.CreationGracePeriod, creationGracePeriodFlag, "Controls how far into the future incoming samples are accepted compared to the wall clock. Any sample with timestamp `t` will be rejected if `t > (now + validation.create-grace-period)`.")
 	f.BoolVar(&l.EnforceMetadataMetricName, "validation.enforce-metadata-metric-name", true, "Enforce every metadata has a metric name.")
+	f.IntVar(&l.MaxPushRequestBytes, maxPushRequestBytesFlag, 0, "Per-tenant maximum size in bytes of a single uncompressed push request. This limit is applied in addition to -distributor.max-recv-msg-size. 0 to disable.")
+	f.IntVar(&l.MaxSeriesPerRequest, maxSeriesPerRequestFlag, 0, "Per-tenant maximum number of series in a single push request. 0 to disable.")
+	f.IntVar(&l.MaxLabelsBytesPerRequest, maxLabelsBytesPerRequestFlag, 0, "Per-tenant maximum total size in bytes of the label names and values of all the series in a single push request. 0 to disable.")
+	f.BoolVar(&l.IngestionMaintenanceMode, ingestionMaintenanceFlag, false, "When enabled, distributors reject all push requests for the tenant with a 503 status code and a Retry-After header, so that clients like Prometheus keep buffering data and retry later. Queries are unaffected.")
+	_ = l.IngestionMaintenanceRetryAfter.Set("1m")
+	f.Var(&l.IngestionMaintenanceRetryAfter, "distributor.ingestion-maintenance-retry-after", "The Retry-After duration returned to clients while the tenant's ingestion is in maintenance mode.")
+	f.BoolVar(&l.RejectedSeriesDeadLetterEnabled, "distributor.rejected-series-dead-letter-enabled", false, "When enabled, the series rejected by the distributor validation or by the request and ingestion rate limits are written, together with the rejection reason, to the dead-letter storage. The samples rejected by the ingesters are not written. Requires -distributor.dead-letter.enabled.")
+	f.BoolVar(&l.OTelCreatedTimestampZeroIngestionEnabled, "distributor.otel-created-timestamp-zero-ingestion-enabled", false, "When enabled, the OTLP endpoint injects a zero sample at the start timestamp of the cumulative monotonic sums and cumulative histograms, so that functions like rate() are accurate over the first samples of newly created or restarted counters. Out-of-order zero samples injected this way are silently ignored by the ingesters.")
 
 	f.IntVar(&l.MaxGlobalSeriesPerUser, MaxSeriesPerUserFlag, 150000, "The maximum number of in-memory series per tenant, across the cluster before replication. 0 to disable.")
 	f.IntVar(&l.MaxGlobalSeriesPerMetric, MaxSeriesPerMetricFlag, 0, "The maximum number of in-memory series per metric name, across the cluster before replication. 0 to disable.")
+	f.BoolVar(&l.MaxGlobalSeriesPerUserWarnOnly, "ingester.max-global-series-per-user-warn-only", false, "When enabled, the series exceeding -"+MaxSeriesPerUserFlag+" are not rejected. The ingesters track them in metrics and log a warning instead.")
+	f.Float64Var(&l.MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio, "ingester.max-global-series-per-user-warn-only-admission-ratio", 1, "The ratio, between 0 and 1, of the new series exceeding -"+MaxSeriesPerUserFlag+" which are admitted when -ingester.max-global-series-per-user-warn-only is enabled. The other new series are rejected. 1 to admit all the new series.")
+	f.StringVar(&l.SeriesLimitScopeLabel, "ingester.series-limit-scope-label", "", "The name of the label defining the scopes of the tenant's series, for example namespace. Each value of this label is a scope, whose in-memory series are limited by -"+MaxSeriesPerScopeFlag+". The series without this label are not limited per scope.")
+	f.IntVar(&l.MaxGlobalSeriesPerScope, MaxSeriesPerScopeFlag, 0, "The maximum number of in-memory series per value of -ingester.series-limit-scope-label, across the cluster before replication. 0 to disable.")
 
 	f.IntVar(&l.MaxGlobalMetricsWithMetadataPerUser, MaxMetadataPerUserFlag, 0, "The maximum number of in-memory metrics with metadata per tenant, across the cluster. 0 to disable.")
 	f.IntVar(&l.MaxGlobalMetadataPerMetric, MaxMetadataPerMetricFlag, 0, "The maximum number of metadata per metric, across the cluster. 0 to disable.")
+	f.IntVar(&l.MaxMetadataPerBlock, "ingester.max-metadata-per-block", 0, "The maximum number of metric metadata persisted in each block, so that the metadata of the metrics no longer in the ingesters can be queried from the store-gateways. The ingesters persist the metadata in memory when shipping a block, and the compactor merges the metadata of the compacted blocks. 0 to not persist the metadata.")
 	f.IntVar(&l.MaxGlobalExemplarsPerUser, "ingester.max-global-exemplars-per-user", 0, "The maximum number of exemplars in memory, across the cluster. 0 to disable exemplars ingestion.")
+	f.IntVar(&l.MaxExemplarsPerBlock, "ingester.max-exemplars-per-block", 0, "The maximum number of exemplars persisted in each block, so that the exemplars no longer in the ingester memory can be queried from the store-gateways. The ingesters persist the most recent exemplars of the block time range when shipping a block, and the compactor merges the exemplars of the compacted blocks. 0 to not persist the exemplars.")
 	f.Var(&l.ActiveSeriesCustomTrackersConfig, "ingester.active-series-custom-trackers", "Additional active series metrics, matching the provided matchers. Matchers should be in form <name>:<matcher>, like 'foobar:{foo=\"bar\"}'. Multiple matchers can be provided either providing the flag multiple times or providing multiple semicolon-separated values to a single flag.")
 	f.Var(&l.OutOfOrderTimeWindow, "ingester.out-of-order-time-window", "Non-zero value enables out-of-order support for most recent samples that are within the time window in relation to the following two conditions: (1) The newest sample for that time series, if it exists. For example, within [series.maxTime-timeWindow, series.maxTime]). (2) The TSDB's maximum time, if the series does not exist. For example, within [db.maxTime-timeWindow, db.maxTime]). The ingester will need more memory as a factor of rate of out-of-order samples being ingested and the number of series that are getting out-of-order samples.")
+	f.Var(&l.TSDBBlockDuration, "ingester.tsdb-block-duration", "The duration of the TSDB blocks created by the ingester for the tenant. It must evenly divide the first -blocks-storage.tsdb.block-ranges-period, otherwise the block ranges period is used. A shorter duration makes the ingester ship the tenant's blocks to the storage more frequently. Changes apply to the TSDBs opened after the change. 0 to use -blocks-storage.tsdb.block-ranges-period.")
+	f.Var(&l.TSDBHeadCompactionIdleTimeout, "ingester.tsdb-head-compaction-idle-timeout", "If the tenant's TSDB head receives no samples within this period, it is compacted. 0 to use -blocks-storage.tsdb.head-compaction-idle-timeout.")
 
 	f.IntVar(&l.MaxChunksPerQuery, MaxChunksPerQueryFlag, 2e6, "Maximum number of chunks that can be fetched in a single query from ingesters and long-term storage. This limit is enforced in the querier, ruler and store-gateway. 0 to disable.")
 	f.IntVar(&l.MaxFetchedSeriesPerQuery, MaxSeriesPerQueryFlag, 0, "The maximum number of unique series for which a query can fetch samples from each ingesters and storage. This limit is enforced in the querier and ruler. 0 to disable")
 	f.IntVar(&l.MaxFetchedChunkBytesPerQuery, MaxChunkBytesPerQueryFlag, 0, "The maximum size of all chunks in bytes that a query can fetch from each ingester and storage. This limit is enforced in the querier and ruler. 0 to disable.")
+	f.IntVar(&l.MaxEstimatedMemoryPerQuery, MaxEstimatedMemoryPerQueryFlag, 0, "The maximum estimated memory, in bytes, a single query can use in the querier: the size of the chunks fetched from the ingesters and the storage, of the labels of the fetched series, and of the points evaluated by the PromQL engine for the selectors of the query. This limit is enforced in the querier and ruler. 0 to disable.")
+	f.IntVar(&l.MaxEstimatedMemoryPerTenant, MaxEstimatedMemoryPerTenantFlag, 0, "The maximum estimated memory, in bytes, the inflight queries of a tenant can use in each querier, estimated the same way of -"+MaxEstimatedMemoryPerQueryFlag+". This limit is enforced in the querier and ruler. 0 to disable.")
 	f.Var(&l.MaxQueryLength, maxQueryLengthFlag, "Limit the query time range (end - start time). This limit is enforced in the query-frontend (on the received query), in the querier (on the query possibly split by the query-frontend) and ruler. 0 to disable.")
 	f.Var(&l.MaxQueryLookback, "querier.max-query-lookback", "Limit how long back data (series and metadata) can be queried, up until <lookback> duration ago. This limit is enforced in the query-frontend, querier and ruler. If the requested time range is outside the allowed range, the request will not fail but will be manipulated to only query data within the allowed time range. 0 to disable.")
 	f.IntVar(&l.MaxQueryParallelism, "querier.max-query-parallelism", 14, "Maximum number of split (by time) or partial (by shard) queries that will be scheduled in parallel by the query-frontend for a single input query. This limit is introduced to have a fairer query scheduling and avoid a single query over a large time range saturating all available queriers.")
@@ -204,26 +292,57 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&l.CardinalityAnalysisEnabled, "querier.cardinality-analysis-enabled", false, "Enables endpoints used for cardinality analysis.")
 	f.IntVar(&l.LabelValuesMaxCardinalityLabelNamesPerRequest, "querier.label-values-max-cardinality-label-names-per-request", 100, "Maximum number of label names allowed to be queried in a single /api/v1/cardinality/label_values API call.")
 	_ = l.MaxCacheFreshness.Set("1m")
-	f.Var(&l.MaxCacheFreshness, "query-frontend.max-cache-freshness", "Most recent allowed cacheable result per-tenant, to prevent caching very recent results that might still be in flux.")
+	f.Var(&l.MaxCacheFreshness, "query-frontend.max-cache-freshness", "Most recent allowed cacheable result per-tenant, to prevent caching very recent results that might still be in flux. The results within the tenant's out-of-order time window are not cached either.")
+	f.Var(&l.ResultsCacheTTLForEmptyResults, "query-frontend.results-cache-ttl-for-empty-results", "Time to live of the cached responses of the queries returning an empty result. The whole response is cached, including the most recent data, so it should be short. It requires -query-frontend.cache-results. 0 to disable the caching of empty results.")
+	f.Var(&l.ResultsCacheTTLForErrors, "query-frontend.results-cache-ttl-for-errors", "Time to live of the cached responses of the queries failing with a deterministic error, like a query parse error. It requires -query-frontend.cache-results. 0 to disable the caching of errors.")
+	f.Var(&l.ResultsCacheTTLForInstantQueries, "query-frontend.results-cache-ttl-for-instant-queries", "Time to live of the cached responses of the instant queries. The evaluation time of the instant queries is aligned to the TTL, so that the queries evaluated in the same TTL window share the same cached result. The whole response is cached, including the most recent data, so it should be short. The rule evaluations are never cached. It requires -query-frontend.cache-results. 0 to disable the caching of instant queries.")
+	f.Var(&l.ResultsCacheTTLForLabelsQuery, "query-frontend.results-cache-ttl-for-labels-query", "Time to live of the cached responses of the label names, label values and series API requests. The start and end of the requests are aligned to the minute, so that the requests sent in the same minute share the same cached response. The whole response is cached, including the empty ones and the most recent data, so it should be short. It requires -query-frontend.cache-results. 0 to disable the caching of these requests.")
+	f.IntVar(&l.ResultsCacheMaxLabelsQueryItems, "query-frontend.results-cache-max-labels-query-items", 10000, "Maximum number of label names, label values or series of a cached response of the label names, label values and series API requests. The bigger responses are not cached. 0 to cache the responses regardless of their number of items.")
+	f.BoolVar(&l.ResultsCacheBypassEnabled, "query-frontend.results-cache-bypass-enabled", false, "When enabled, the read requests of the tenant with the Cache-Control: no-cache header bypass the results cache: the results are queried again, ignoring the cached ones, and are stored in the results cache to refresh it. When disabled, the header is ignored.")
 	f.IntVar(&l.MaxQueriersPerTenant, "query-frontend.max-queriers-per-tenant", 0, "Maximum number of queriers that can handle requests for a single tenant. If set to 0 or value higher than number of available queriers, *all* queriers will handle requests for the tenant. Each frontend (or query-scheduler, if used) will select the same set of queriers for the same tenant (given that all queriers are connected to all frontends / query-schedulers). This option only works with queriers connecting to the query-frontend / query-scheduler, not when using downstream URL.")
+	f.IntVar(&l.QuerierCapacityWeight, "query-frontend.querier-capacity-weight", 1, "Weight of the tenant in the sharing of the querier capacity among the tenants with queued requests. When the queriers are saturated, each tenant gets a share of the dequeued requests proportional to its weight, while every tenant with queued requests still gets at least one request dequeued on each round over the tenants. The weight of a query spanning multiple tenants is the smallest weight of its tenants. This option only works with queriers connecting to the query-frontend / query-scheduler, not when using downstream URL.")
+	f.IntVar(&l.MaxOutstandingRequestsPerTenant, "query-frontend.max-outstanding-requests-per-tenant", 0, "Maximum number of outstanding requests of the tenant in the queue of each query-frontend / query-scheduler, overriding -querier.max-outstanding-requests-per-tenant and -query-scheduler.max-outstanding-requests-per-tenant. Further requests are rejected with the status code 429. The limit of a query spanning multiple tenants is the smallest limit of its tenants. 0 to use the limit of the query-frontend / query-scheduler.")
+	f.IntVar(&l.RulerMaxOutstandingPerTenant, "query-frontend.ruler-max-outstanding-requests-per-tenant", 0, "Maximum number of outstanding requests issued by the ruler for the tenant in the queue of each query-frontend / query-scheduler. When set, the rule evaluations get their own budget in the queue and don't count toward -query-frontend.max-outstanding-requests-per-tenant, so that a flood of other queries can't get them rejected. The rule evaluations are identified by the X-Mimir-Query-Source header set by the ruler. 0 to count the rule evaluations toward the max outstanding requests of the tenant.")
+	f.IntVar(&l.MaxConcurrentUserQueries, "query-frontend.max-concurrent-user-queries", 0, "Maximum number of concurrent read requests of the tenant in each query-frontend, except the rule evaluations run by the ruler. The requests beyond the limit wait for a running request to complete. 0 to disable the limit.")
+	f.IntVar(&l.RulerMaxConcurrentQueries, "query-frontend.ruler-max-concurrent-queries", 0, "Maximum number of concurrent rule evaluations run by the ruler for the tenant in each query-frontend, separate from -query-frontend.max-concurrent-user-queries. The rule evaluations beyond the limit wait for a running one to complete. 0 to disable the limit.")
+	f.Var(&l.RulerQueryTimeout, "query-frontend.ruler-query-timeout", "Timeout of the rule evaluations run by the ruler for the tenant in the query-frontend. The query-frontend fails the rule evaluations running longer with a 504 status code. 0 to disable the timeout.")
 	f.IntVar(&l.QueryShardingTotalShards, "query-frontend.query-sharding-total-shards", 16, "The amount of shards to use when doing parallelisation via query sharding by tenant. 0 to disable query sharding for tenant. Query sharding implementation will adjust the number of query shards based on compactor shards. This allows querier to not search the blocks which cannot possibly have the series for given query shard.")
 	f.IntVar(&l.QueryShardingMaxShardedQueries, "query-frontend.query-sharding-max-sharded-queries", 128, "The max number of sharded queries that can be run for a given received query. 0 to disable limit.")
+	f.IntVar(&l.QueryShardingMaxRequestedShards, "query-frontend.query-sharding-max-requested-shards", 0, "The max number of shards a query can request with the Sharding-Control header or the total_shards parameter, overriding the tenant's number of shards. The queries requesting more shards are run with this number of shards. 0 to default to the tenant's number of shards, so that a query can only request fewer shards.")
 	f.Var(&l.SplitInstantQueriesByInterval, "query-frontend.split-instant-queries-by-interval", "Split instant queries by an interval and execute in parallel. 0 to disable it.")
+	f.BoolVar(&l.SubquerySpinOffEnabled, "query-frontend.subquery-spin-off-enabled", false, "When enabled, the query-frontend spins off the expensive subqueries of the instant queries into range queries, which are split by interval, cached and sharded like the other range queries, and evaluates the rest of the query on their results. A subquery is spun off if it has an explicit step, its range has at least 10 steps, and it doesn't use the @ modifier.")
+	f.BoolVar(&l.QueryLoadSheddingEnabled, queryLoadSheddingFlag, false, "When enabled, the query-frontend rejects all the read requests for the tenant with a 503 status code, except the queries run by the ruler to evaluate the tenant's rules, identified by the X-Mimir-Query-Source or User-Agent header set by the ruler. Use it to shed the query load, for example from dashboards, while recovering from an outage.")
+	f.Var(&l.SlowQueryLogThreshold, "query-frontend.slow-query-log-threshold", "Log the queries of the tenant that are slower than the specified duration, with their query statistics when -query-frontend.query-stats-enabled is set. It overrides -query-frontend.log-queries-longer-than for the tenant. 0 to use -query-frontend.log-queries-longer-than.")
+	f.IntVar(&l.MaxFetchedChunkBytesPerMinute, maxFetchedChunkBytesPerMinuteFlag, 0, "The maximum size of all chunks in bytes that the read requests of the tenant can fetch from the ingesters and the store-gateways in the last minute. Once the limit is reached, the query-frontend rejects the read requests with a 429 status code until the bytes fetched in the last minute are below the limit again. The limit is enforced by each query-frontend on the requests it receives, from the query statistics returned by the queriers, so it requires -query-frontend.query-stats-enabled. 0 to disable.")
+	f.IntVar(&l.MaxEstimatedQueryCost, maxEstimatedQueryCostFlag, 0, "The maximum estimated cost of the instant and range queries of the tenant. The query-frontend estimates the cost of a query, before running it, as the number of samples it reads: the number of points read by each selector of the query, over all the query steps, multiplied by the number of series the selectors fetched the last time the same query was run on the same time range length and step by the query-frontend. The queries whose estimated cost exceeds the limit are rejected with a 400 status code. The fetched series are tracked from the query statistics returned by the queriers, so they are only taken into account with -query-frontend.query-stats-enabled. 0 to disable.")
+	f.Var(&l.RemoteQueryFederationURLs, "query-frontend.remote-query-federation-url", "URL of the Prometheus HTTP API prefix of a remote Mimir cluster the instant and range queries of the tenant are federated across, for example https://mimir.example.com/prometheus. The query-frontend runs the queries both on the local cluster and on the remote clusters, for the same tenant, and merges the results series by series. The failures of the remote clusters are returned as warnings. This flag can be repeated to federate the queries across multiple remote clusters.")
+	f.StringVar(&l.SecondaryQuerySourceURL, "querier.secondary-query-source-url", "", "URL of the Prometheus remote read endpoint of a secondary query source, for example the system the tenant's historical data is being migrated from. When set, the series read from the secondary query source are merged with the series queried from the ingesters and the long-term storage. Failures of the secondary query source are returned as warnings. Label names and values queries are not sent to the secondary query source.")
+	f.Var(&l.SecondaryQuerySourceTimeWindow, "querier.secondary-query-source-time-window", "Only query the secondary query source for the data within this time window ago. 0 to query the secondary query source for the whole time range of the queries.")
+	f.BoolVar(&l.StreamingPromQLEngineEnabled, "querier.streaming-promql-engine-enabled", false, "When enabled, the querier evaluates the instant and range queries of the tenant with the streaming PromQL engine, which evaluates the queries series by series to reduce the memory used by the aggregations of many series. The streaming engine supports the instant vector selectors, the rate() and increase() functions of range vector selectors, and the sum, count, min, max and avg aggregations of these: the other queries fall back to the standard PromQL engine. The queries evaluated by the ruler always use the standard PromQL engine.")
+	f.Var(&l.ExperimentalPromQLFunctions, experimentalPromQLFunctionsFlag, "Comma-separated list of experimental PromQL functions enabled for the tenant. The query-frontend, the querier and the ruler fail the queries of the tenant using experimental functions which aren't enabled, and the ruler rejects the rule groups using them. Supported values: mad_over_time, double_exponential_smoothing.")
+	f.BoolVar(&l.AutoDownsamplingEnabled, "querier.auto-downsampling-enabled", false, "When enabled, the queries of the tenant read the downsampled data of the downsampled blocks, if any, with the highest resolution not bigger than a fifth of the query step and of the range of the range selectors, and not bigger than the lookback delta for the other selectors. The time ranges not covered by blocks of that resolution are read from the blocks of the lower resolutions, down to the raw blocks. The max_source_resolution parameter of the queries (auto, raw or a duration) overrides it.")
+	f.BoolVar(&l.ZoneOutagePartialResultsEnabled, "querier.zone-outage-partial-results-enabled", false, "When enabled, the queries of the tenant are served with partial results, with a warning, instead of failing, when the ingesters or the store-gateways of entire zones are unavailable. All the healthy ingesters of the available zones are queried when the ingesters of too many zones are detected as unhealthy in the ring, and the non-queried blocks are skipped when all the store-gateways attempted for them belong to a single zone, regardless of the health of the zones in the ring. It requires zone-awareness.")
 
 	f.Var(&l.RulerEvaluationDelay, "ruler.evaluation-delay-duration", "Duration to delay the evaluation of rules to ensure the underlying metrics have been pushed.")
 	f.IntVar(&l.RulerTenantShardSize, "ruler.tenant-shard-size", 0, "The tenant's shard size when sharding is used by ruler. Value of 0 disables shuffle sharding for the tenant, and tenant rules will be sharded across all ruler replicas.")
 	f.IntVar(&l.RulerMaxRulesPerRuleGroup, "ruler.max-rules-per-rule-group", 20, "Maximum number of rules per rule group per-tenant. 0 to disable.")
 	f.IntVar(&l.RulerMaxRuleGroupsPerTenant, "ruler.max-rule-groups-per-tenant", 70, "Maximum number of rule groups per-tenant. 0 to disable.")
 
-	f.Var(&l.CompactorBlocksRetentionPeriod, "compactor.blocks-retention-period", "Delete blocks containing samples older than the specified retention period. 0 to disable.")
+	f.Var(&l.CompactorBlocksRetentionPeriod, "compactor.blocks-retention-period", "Delete blocks containing samples older than the specified retention period. Also used by the ingesters to not return samples older than the retention period from queries. 0 to disable.")
 	f.IntVar(&l.CompactorSplitAndMergeShards, "compactor.split-and-merge-shards", 0, "The number of shards to use when splitting blocks. 0 to disable splitting.")
 	f.IntVar(&l.CompactorSplitGroups, "compactor.split-groups", 1, "Number of groups that blocks for splitting should be grouped into. Each group of blocks is then split separately. Number of output split shards is controlled by -compactor.split-and-merge-shards.")
 	f.IntVar(&l.CompactorTenantShardSize, "compactor.compactor-tenant-shard-size", 0, "Max number of compactors that can compact blocks for single tenant. 0 to disable the limit and use all compactors.")
 	f.Var(&l.CompactorPartialBlockDeletionDelay, "compactor.partial-block-deletion-delay", fmt.Sprintf("If a partial block (unfinished block without %s file) hasn't been modified for this time, it will be marked for deletion. The minimum accepted value is %s: a lower value will be ignored and the feature disabled. 0 to disable.", block.MetaFilename, MinCompactorPartialBlockDeletionDelay.String()))
 	f.BoolVar(&l.CompactorBlockUploadEnabled, "compactor.block-upload-enabled", false, "Enable block upload API for the tenant.")
+	f.Var(&l.CompactorAllowedTimeWindows, "compactor.allowed-time-windows", "Comma-separated list of daily UTC time windows, in the format HH:MM-HH:MM, during which the compaction of the tenant's blocks may start (e.g. 00:00-06:00,22:00-23:30). Windows can wrap around midnight. The compaction of a tenant started within a window is not interrupted when the window ends. Empty to allow the compaction at any time.")
+	f.Var(&l.CompactorFirstLevelCompactionWaitPeriod, "compactor.first-level-compaction-wait-period", "How long the compactor waits before compacting first-level blocks, which are the blocks uploaded by the ingesters. The wait period is counted from the upload of the most recent block in the compaction job, to give the ingesters of all the zones the time to upload their blocks for the same time range, which are then compacted together. 0 to disable.")
+	f.Var(&l.CompactorColdStorageArchiveAfter, "compactor.cold-storage-archive-after", "Move the blocks containing only samples older than the specified period from the blocks storage to the cold storage. Requires -blocks-storage.cold-storage.enabled. 0 to disable.")
 
 	// Store-gateway.
 	f.IntVar(&l.StoreGatewayTenantShardSize, "store-gateway.tenant-shard-size", 0, "The tenant's shard size, used when store-gateway sharding is enabled. Value of 0 disables shuffle sharding for the tenant, that is all tenant blocks are sharded across all store-gateway replicas.")
+	f.IntVar(&l.StoreGatewayTenantReplicationFactor, "store-gateway.tenant-replication-factor", 0, "The replication factor of the tenant's blocks across the store-gateways, used when higher than -store-gateway.sharding-ring.replication-factor to get extra replicas of the blocks of the tenants with a high query load. The querier and the store-gateway must have the same value. 0 to use the ring replication factor.")
+	f.IntVar(&l.StoreGatewayTenantMaxConcurrentSeriesRequests, "store-gateway.tenant-max-concurrent-series-requests", 0, "Maximum number of concurrent series requests of the tenant executed by each store-gateway. The requests exceeding the limit wait until a running request of the tenant completes. The limit is applied on top of -blocks-storage.bucket-store.max-concurrent. 0 to disable.")
+	f.IntVar(&l.StoreGatewayTenantMaxFetchedChunkBytesPerRequest, "store-gateway.tenant-max-fetched-chunk-bytes-per-request", 0, "Maximum size of the chunks in bytes that a single series request of the tenant can fetch from the chunks cache and the bucket, in each store-gateway. When the limit is exceeded, the request fails. 0 to disable.")
 
 	// Alertmanager.
 	f.Var(&l.AlertmanagerReceiversBlockCIDRNetworks, "alertmanager.receivers-firewall-block-cidr-networks", "Comma-separated list of network CIDRs to block in Alertmanager receiver integrations.")
@@ -394,6 +513,47 @@ func (o *Overrides) DropLabels(userID string) flagext.StringSlice {
 	return o.getOverridesForUser(userID).DropLabels
 }
 
+// MaxPushRequestBytes returns the maximum size in bytes of a single uncompressed push request.
+func (o *Overrides) MaxPushRequestBytes(userID string) int {
+	return o.getOverridesForUser(userID).MaxPushRequestBytes
+}
+
+// MaxSeriesPerRequest returns the maximum number of series in a single push request.
+func (o *Overrides) MaxSeriesPerRequest(userID string) int {
+	return o.getOverridesForUser(userID).MaxSeriesPerRequest
+}
+
+// MaxLabelsBytesPerRequest returns the maximum total size in bytes of the labels of all series in a single push request.
+func (o *Overrides) MaxLabelsBytesPerRequest(userID string) int {
+	return o.getOverridesForUser(userID).MaxLabelsBytesPerRequest
+}
+
+// IngestionMaintenanceMode returns whether the ingestion for the tenant is paused for maintenance.
+func (o *Overrides) IngestionMaintenanceMode(userID string) bool {
+	return o.getOverridesForUser(userID).IngestionMaintenanceMode
+}
+
+// IngestionMaintenanceRetryAfter returns the Retry-After duration returned to clients while the tenant's
+// ingestion is in maintenance mode.
+func (o *Overrides) IngestionMaintenanceRetryAfter(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).IngestionMaintenanceRetryAfter)
+}
+
+// IngestionClientPolicies returns the ingestion policies matched against the client identity of push requests.
+func (o *Overrides) IngestionClientPolicies(userID string) []ClientPolicy {
+	return o.getOverridesForUser(userID).IngestionClientPolicies
+}
+
+// RejectedSeriesDeadLetterEnabled returns whether the series rejected by the distributor are written to the dead-letter storage.
+func (o *Overrides) RejectedSeriesDeadLetterEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).RejectedSeriesDeadLetterEnabled
+}
+
+// OTelCreatedTimestampZeroIngestionEnabled returns whether the OTLP endpoint injects a zero sample at the start timestamp of counters.
+func (o *Overrides) OTelCreatedTimestampZeroIngestionEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).OTelCreatedTimestampZeroIngestionEnabled
+}
+
 // MaxLabelNameLength returns maximum length a label name can be.
 func (o *Overrides) MaxLabelNameLength(userID string) int {
 	return o.getOverridesForUser(userID).MaxLabelNameLength
@@ -427,6 +587,28 @@ func (o *Overrides) MaxGlobalSeriesPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerUser
 }
 
+// MaxGlobalSeriesPerUserWarnOnly returns whether the series exceeding the max global series per user limit
+// are admitted, and only tracked in metrics and logs.
+func (o *Overrides) MaxGlobalSeriesPerUserWarnOnly(userID string) bool {
+	return o.getOverridesForUser(userID).MaxGlobalSeriesPerUserWarnOnly
+}
+
+// MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio returns the ratio of the new series exceeding the
+// max global series per user limit which are admitted in warn-only mode.
+func (o *Overrides) MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio(userID string) float64 {
+	return o.getOverridesForUser(userID).MaxGlobalSeriesPerUserWarnOnlyAdmissionRatio
+}
+
+// SeriesLimitScopeLabel returns the name of the label defining the scopes whose series are limited by MaxGlobalSeriesPerScope.
+func (o *Overrides) SeriesLimitScopeLabel(userID string) string {
+	return o.getOverridesForUser(userID).SeriesLimitScopeLabel
+}
+
+// MaxGlobalSeriesPerScope returns the maximum number of series allowed per scope across the cluster.
+func (o *Overrides) MaxGlobalSeriesPerScope(userID string) int {
+	return o.getOverridesForUser(userID).MaxGlobalSeriesPerScope
+}
+
 // MaxGlobalSeriesPerMetric returns the maximum number of series allowed per metric across the cluster.
 func (o *Overrides) MaxGlobalSeriesPerMetric(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerMetric
@@ -448,6 +630,17 @@ func (o *Overrides) MaxFetchedChunkBytesPerQuery(userID string) int {
 	return o.getOverridesForUser(userID).MaxFetchedChunkBytesPerQuery
 }
 
+// MaxEstimatedMemoryPerQuery returns the maximum estimated memory, in bytes, a single query can use in the querier.
+func (o *Overrides) MaxEstimatedMemoryPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedMemoryPerQuery
+}
+
+// MaxEstimatedMemoryPerTenant returns the maximum estimated memory, in bytes, the inflight queries of a tenant
+// can use in each querier.
+func (o *Overrides) MaxEstimatedMemoryPerTenant(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedMemoryPerTenant
+}
+
 // MaxQueryLookback returns the max lookback period of queries.
 func (o *Overrides) MaxQueryLookback(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxQueryLookback)
@@ -469,11 +662,76 @@ func (o *Overrides) MaxCacheFreshness(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxCacheFreshness)
 }
 
+// ResultsCacheTTLForEmptyResults returns the time to live of the cached responses of the queries returning an empty result.
+func (o *Overrides) ResultsCacheTTLForEmptyResults(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).ResultsCacheTTLForEmptyResults)
+}
+
+// ResultsCacheTTLForErrors returns the time to live of the cached responses of the queries failing with a deterministic error.
+func (o *Overrides) ResultsCacheTTLForErrors(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).ResultsCacheTTLForErrors)
+}
+
+// ResultsCacheTTLForInstantQueries returns the time to live of the cached responses of the instant queries.
+func (o *Overrides) ResultsCacheTTLForInstantQueries(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).ResultsCacheTTLForInstantQueries)
+}
+
+// ResultsCacheTTLForLabelsQuery returns the time to live of the cached responses of the label names, label values and series API requests.
+func (o *Overrides) ResultsCacheTTLForLabelsQuery(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).ResultsCacheTTLForLabelsQuery)
+}
+
+// ResultsCacheMaxLabelsQueryItems returns the maximum number of items of a cached response of the label names, label values and series API requests.
+func (o *Overrides) ResultsCacheMaxLabelsQueryItems(userID string) int {
+	return o.getOverridesForUser(userID).ResultsCacheMaxLabelsQueryItems
+}
+
+// ResultsCacheBypassEnabled returns whether the read requests of the tenant can bypass the results cache
+// with the Cache-Control: no-cache header.
+func (o *Overrides) ResultsCacheBypassEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).ResultsCacheBypassEnabled
+}
+
 // MaxQueriersPerUser returns the maximum number of queriers that can handle requests for this user.
 func (o *Overrides) MaxQueriersPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxQueriersPerTenant
 }
 
+// QuerierCapacityWeight returns the weight of the user in the sharing of the querier capacity among the users.
+func (o *Overrides) QuerierCapacityWeight(userID string) int {
+	return o.getOverridesForUser(userID).QuerierCapacityWeight
+}
+
+// MaxOutstandingRequestsPerTenant returns the max number of outstanding requests of the user in the queue of the
+// query-frontend / query-scheduler, or 0 to use the limit of the queue.
+func (o *Overrides) MaxOutstandingRequestsPerTenant(userID string) int {
+	return o.getOverridesForUser(userID).MaxOutstandingRequestsPerTenant
+}
+
+// RulerMaxOutstandingRequestsPerTenant returns the max number of outstanding requests issued by the ruler for the user
+// in the queue of the query-frontend / query-scheduler, or 0 to count them toward the max outstanding requests.
+func (o *Overrides) RulerMaxOutstandingRequestsPerTenant(userID string) int {
+	return o.getOverridesForUser(userID).RulerMaxOutstandingPerTenant
+}
+
+// MaxConcurrentUserQueries returns the max number of concurrent read requests of the user in each query-frontend,
+// except the rule evaluations. 0 to disable the limit.
+func (o *Overrides) MaxConcurrentUserQueries(userID string) int {
+	return o.getOverridesForUser(userID).MaxConcurrentUserQueries
+}
+
+// RulerMaxConcurrentQueries returns the max number of concurrent rule evaluations of the user in each query-frontend.
+// 0 to disable the limit.
+func (o *Overrides) RulerMaxConcurrentQueries(userID string) int {
+	return o.getOverridesForUser(userID).RulerMaxConcurrentQueries
+}
+
+// RulerQueryTimeout returns the timeout of the rule evaluations of the user in the query-frontend. 0 to disable it.
+func (o *Overrides) RulerQueryTimeout(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerQueryTimeout)
+}
+
 // MaxQueryParallelism returns the limit to the number of split queries the
 // frontend will process in parallel.
 func (o *Overrides) MaxQueryParallelism(userID string) int {
@@ -492,12 +750,91 @@ func (o *Overrides) QueryShardingMaxShardedQueries(userID string) int {
 	return o.getOverridesForUser(userID).QueryShardingMaxShardedQueries
 }
 
+// QueryShardingMaxRequestedShards returns the max number of shards a query can request, overriding the
+// tenant's number of shards. 0 to default to the tenant's number of shards.
+func (o *Overrides) QueryShardingMaxRequestedShards(userID string) int {
+	return o.getOverridesForUser(userID).QueryShardingMaxRequestedShards
+}
+
+// SecondaryQuerySourceURL returns the URL of the remote read endpoint of the tenant's secondary query source.
+func (o *Overrides) SecondaryQuerySourceURL(userID string) string {
+	return o.getOverridesForUser(userID).SecondaryQuerySourceURL
+}
+
+// SecondaryQuerySourceTimeWindow returns the time window within which the tenant's secondary query source is queried.
+func (o *Overrides) SecondaryQuerySourceTimeWindow(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).SecondaryQuerySourceTimeWindow)
+}
+
+// ExperimentalPromQLFunctions returns the experimental PromQL functions the tenant's queries can use.
+func (o *Overrides) ExperimentalPromQLFunctions(userID string) []string {
+	return o.getOverridesForUser(userID).ExperimentalPromQLFunctions
+}
+
+// AutoDownsamplingEnabled returns whether the tenant's queries read the downsampled data with a resolution
+// chosen from the query step and the ranges of the range selectors.
+func (o *Overrides) AutoDownsamplingEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).AutoDownsamplingEnabled
+}
+
+// ZoneOutagePartialResultsEnabled returns whether the tenant's queries are served with partial results, instead of
+// failing, when the ingesters or the store-gateways of entire zones are unavailable.
+func (o *Overrides) ZoneOutagePartialResultsEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).ZoneOutagePartialResultsEnabled
+}
+
+// StreamingPromQLEngineEnabled returns whether the tenant's queries are evaluated by the streaming PromQL engine in the querier.
+func (o *Overrides) StreamingPromQLEngineEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).StreamingPromQLEngineEnabled
+}
+
+// QueryLoadSheddingEnabled returns whether the query-frontend rejects the tenant's read requests,
+// except the ones run by the ruler to evaluate the rules.
+func (o *Overrides) QueryLoadSheddingEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).QueryLoadSheddingEnabled
+}
+
+// SlowQueryLogThreshold returns the duration above which the tenant's queries are logged as slow by the query-frontend.
+// 0 to use the query-frontend global threshold.
+func (o *Overrides) SlowQueryLogThreshold(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).SlowQueryLogThreshold)
+}
+
+// MaxFetchedChunkBytesPerMinute returns the maximum number of chunk bytes that the tenant's read requests can fetch
+// in the last minute. 0 to disable the limit.
+func (o *Overrides) MaxFetchedChunkBytesPerMinute(userID string) int {
+	return o.getOverridesForUser(userID).MaxFetchedChunkBytesPerMinute
+}
+
+// MaxEstimatedQueryCost returns the maximum estimated cost of the tenant's instant and range queries.
+// 0 to disable the limit.
+func (o *Overrides) MaxEstimatedQueryCost(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedQueryCost
+}
+
+// RemoteQueryFederationURLs returns the URLs of the remote clusters the tenant's instant and range queries
+// are federated across.
+func (o *Overrides) RemoteQueryFederationURLs(userID string) []string {
+	return o.getOverridesForUser(userID).RemoteQueryFederationURLs
+}
+
+// SubquerySpinOffEnabled returns whether the query-frontend spins off the expensive subqueries of the instant
+// queries into range queries.
+func (o *Overrides) SubquerySpinOffEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).SubquerySpinOffEnabled
+}
+
 // SplitInstantQueriesByInterval returns the split time interval to use when splitting an instant query
 // via the query-frontend. 0 to disable limit.
 func (o *Overrides) SplitInstantQueriesByInterval(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).SplitInstantQueriesByInterval)
 }
 
+// QueryResultLabelRules returns the rules applied by the query-frontend to the labels of the series in the query results.
+func (o *Overrides) QueryResultLabelRules(userID string) []ResultLabelRule {
+	return o.getOverridesForUser(userID).QueryResultLabelRules
+}
+
 // EnforceMetadataMetricName whether to enforce the presence of a metric name on metadata.
 func (o *Overrides) EnforceMetadataMetricName(userID string) bool {
 	return o.getOverridesForUser(userID).EnforceMetadataMetricName
@@ -513,11 +850,21 @@ func (o *Overrides) MaxGlobalMetadataPerMetric(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalMetadataPerMetric
 }
 
+// MaxMetadataPerBlock returns the maximum number of metric metadata persisted in each block.
+func (o *Overrides) MaxMetadataPerBlock(userID string) int {
+	return o.getOverridesForUser(userID).MaxMetadataPerBlock
+}
+
 // MaxGlobalExemplarsPerUser returns the maximum number of exemplars held in memory across the cluster.
 func (o *Overrides) MaxGlobalExemplarsPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalExemplarsPerUser
 }
 
+// MaxExemplarsPerBlock returns the maximum number of exemplars persisted in each block.
+func (o *Overrides) MaxExemplarsPerBlock(userID string) int {
+	return o.getOverridesForUser(userID).MaxExemplarsPerBlock
+}
+
 func (o *Overrides) ActiveSeriesCustomTrackersConfig(userID string) activeseries.CustomTrackersConfig {
 	return o.getOverridesForUser(userID).ActiveSeriesCustomTrackersConfig
 }
@@ -527,6 +874,16 @@ func (o *Overrides) OutOfOrderTimeWindow(userID string) model.Duration {
 	return o.getOverridesForUser(userID).OutOfOrderTimeWindow
 }
 
+// TSDBBlockDuration returns the duration of the TSDB blocks created by the ingesters for the user.
+func (o *Overrides) TSDBBlockDuration(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).TSDBBlockDuration)
+}
+
+// TSDBHeadCompactionIdleTimeout returns the period of inactivity after which the user's TSDB head is compacted.
+func (o *Overrides) TSDBHeadCompactionIdleTimeout(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).TSDBHeadCompactionIdleTimeout)
+}
+
 // IngestionTenantShardSize returns the ingesters shard size for a given user.
 func (o *Overrides) IngestionTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).IngestionTenantShardSize
@@ -576,6 +933,21 @@ func (o *Overrides) CompactorBlockUploadEnabled(tenantID string) bool {
 	return o.getOverridesForUser(tenantID).CompactorBlockUploadEnabled
 }
 
+// CompactorAllowedTimeWindows returns the daily UTC time windows during which the compaction of the tenant's blocks may start.
+func (o *Overrides) CompactorAllowedTimeWindows(userID string) TimeWindows {
+	return o.getOverridesForUser(userID).CompactorAllowedTimeWindows
+}
+
+// CompactorFirstLevelCompactionWaitPeriod returns how long to wait before compacting first-level blocks for a given user.
+func (o *Overrides) CompactorFirstLevelCompactionWaitPeriod(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).CompactorFirstLevelCompactionWaitPeriod)
+}
+
+// CompactorColdStorageArchiveAfter returns how old the blocks of a given user are when moved to the cold storage.
+func (o *Overrides) CompactorColdStorageArchiveAfter(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).CompactorColdStorageArchiveAfter)
+}
+
 // MetricRelabelConfigs returns the metric relabel configs for a given user.
 func (o *Overrides) MetricRelabelConfigs(userID string) []*relabel.Config {
 	return o.getOverridesForUser(userID).MetricRelabelConfigs
@@ -596,11 +968,34 @@ func (o *Overrides) RulerMaxRuleGroupsPerTenant(userID string) int {
 	return o.getOverridesForUser(userID).RulerMaxRuleGroupsPerTenant
 }
 
+// RulerAlertmanagerClientConfig returns the Alertmanager client configuration used by the ruler to send
+// the alerts of a given user.
+func (o *Overrides) RulerAlertmanagerClientConfig(userID string) notifier.AlertmanagerClientConfig {
+	return o.getOverridesForUser(userID).RulerAlertmanagerClientConfig
+}
+
 // StoreGatewayTenantShardSize returns the store-gateway shard size for a given user.
 func (o *Overrides) StoreGatewayTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).StoreGatewayTenantShardSize
 }
 
+// StoreGatewayTenantReplicationFactor returns the store-gateway blocks replication factor for a given user.
+func (o *Overrides) StoreGatewayTenantReplicationFactor(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayTenantReplicationFactor
+}
+
+// StoreGatewayTenantMaxConcurrentSeriesRequests returns the maximum number of concurrent series requests of a given
+// user executed by each store-gateway.
+func (o *Overrides) StoreGatewayTenantMaxConcurrentSeriesRequests(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayTenantMaxConcurrentSeriesRequests
+}
+
+// StoreGatewayTenantMaxFetchedChunkBytesPerRequest returns the maximum size of the chunks in bytes fetched by a single
+// series request of a given user in each store-gateway.
+func (o *Overrides) StoreGatewayTenantMaxFetchedChunkBytesPerRequest(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayTenantMaxFetchedChunkBytesPerRequest
+}
+
 // MaxHAClusters returns maximum number of clusters that HA tracker will track for a user.
 func (o *Overrides) MaxHAClusters(user string) int {
 	return o.getOverridesForUser(user).HAMaxClusters
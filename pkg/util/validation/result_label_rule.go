@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ResultLabelRuleActionDrop removes the label from the query results.
+	ResultLabelRuleActionDrop = "drop"
+	// ResultLabelRuleActionHash replaces the label value with its hex-encoded HMAC-SHA256, keyed with the query-frontend key.
+	ResultLabelRuleActionHash = "hash"
+	// ResultLabelRuleActionRename renames the label to the target label.
+	ResultLabelRuleActionRename = "rename"
+)
+
+// ResultLabelRule is a rule applied by the query-frontend to the labels of the series in the query results,
+// before the results are returned to the client.
+type ResultLabelRule struct {
+	Label       string `yaml:"label" json:"label"`
+	Action      string `yaml:"action" json:"action"`
+	TargetLabel string `yaml:"target_label,omitempty" json:"target_label,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (r *ResultLabelRule) UnmarshalYAML(value *yaml.Node) error {
+	type plain ResultLabelRule
+	if err := value.DecodeWithOptions((*plain)(r), yaml.DecodeOptions{KnownFields: true}); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *ResultLabelRule) UnmarshalJSON(data []byte) error {
+	type plain ResultLabelRule
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+func (r *ResultLabelRule) validate() error {
+	if !model.LabelName(r.Label).IsValid() {
+		return fmt.Errorf("invalid query result label rule label %q", r.Label)
+	}
+
+	switch r.Action {
+	case ResultLabelRuleActionDrop, ResultLabelRuleActionHash:
+		if r.TargetLabel != "" {
+			return fmt.Errorf("query result label rule with action %q doesn't support a target label", r.Action)
+		}
+	case ResultLabelRuleActionRename:
+		if !model.LabelName(r.TargetLabel).IsValid() {
+			return fmt.Errorf("invalid query result label rule target label %q", r.TargetLabel)
+		}
+		if r.TargetLabel == r.Label {
+			return fmt.Errorf("query result label rule with action %q requires a target label different from the label", r.Action)
+		}
+	default:
+		return fmt.Errorf("unsupported query result label rule action %q, supported values are: %s, %s, %s", r.Action, ResultLabelRuleActionDrop, ResultLabelRuleActionHash, ResultLabelRuleActionRename)
+	}
+	return nil
+}
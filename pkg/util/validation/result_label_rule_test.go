@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResultLabelRule_Unmarshal(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    ResultLabelRule
+		expectedErr string
+	}{
+		"drop": {
+			input:    `{"label": "user_email", "action": "drop"}`,
+			expected: ResultLabelRule{Label: "user_email", Action: ResultLabelRuleActionDrop},
+		},
+		"hash": {
+			input:    `{"label": "user_email", "action": "hash"}`,
+			expected: ResultLabelRule{Label: "user_email", Action: ResultLabelRuleActionHash},
+		},
+		"rename": {
+			input:    `{"label": "customer", "action": "rename", "target_label": "tenant"}`,
+			expected: ResultLabelRule{Label: "customer", Action: ResultLabelRuleActionRename, TargetLabel: "tenant"},
+		},
+		"invalid label": {
+			input:       `{"label": "user-email", "action": "drop"}`,
+			expectedErr: `invalid query result label rule label "user-email"`,
+		},
+		"drop with target label": {
+			input:       `{"label": "user_email", "action": "drop", "target_label": "other"}`,
+			expectedErr: `query result label rule with action "drop" doesn't support a target label`,
+		},
+		"rename without target label": {
+			input:       `{"label": "customer", "action": "rename"}`,
+			expectedErr: `invalid query result label rule target label ""`,
+		},
+		"rename to the same label": {
+			input:       `{"label": "customer", "action": "rename", "target_label": "customer"}`,
+			expectedErr: `query result label rule with action "rename" requires a target label different from the label`,
+		},
+		"unsupported action": {
+			input:       `{"label": "customer", "action": "replace"}`,
+			expectedErr: `unsupported query result label rule action "replace", supported values are: drop, hash, rename`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			// JSON is a subset of YAML, so the same input can be used with both decoders.
+			var fromYAML, fromJSON ResultLabelRule
+			errYAML := yaml.Unmarshal([]byte(testData.input), &fromYAML)
+			errJSON := json.Unmarshal([]byte(testData.input), &fromJSON)
+
+			if testData.expectedErr != "" {
+				require.Error(t, errYAML)
+				require.Error(t, errJSON)
+				assert.Contains(t, errYAML.Error(), testData.expectedErr)
+				assert.Contains(t, errJSON.Error(), testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, errYAML)
+			require.NoError(t, errJSON)
+			assert.Equal(t, testData.expected, fromYAML)
+			assert.Equal(t, testData.expected, fromJSON)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// TimeWindow is a daily time window in UTC, expressed as offsets from midnight. The start is included and
+// the end excluded. The window wraps around midnight when End is before Start.
+type TimeWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains returns whether the time of the day of t, in UTC, falls within the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+func (w TimeWindow) String() string {
+	return formatTimeOfDay(w.Start) + "-" + formatTimeOfDay(w.End)
+}
+
+// TimeWindows is a list of daily time windows in UTC, configured as a comma-separated
+// list of HH:MM-HH:MM ranges (e.g. "00:00-06:00,22:00-23:30").
+type TimeWindows []TimeWindow
+
+// Contains returns whether t falls within any of the windows. An empty list of windows contains any time.
+func (w TimeWindows) Contains(t time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+
+	for _, window := range w {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// String implements flag.Value.
+func (w TimeWindows) String() string {
+	windows := make([]string, 0, len(w))
+	for _, window := range w {
+		windows = append(windows, window.String())
+	}
+	return strings.Join(windows, ",")
+}
+
+// Set implements flag.Value.
+func (w *TimeWindows) Set(s string) error {
+	if s == "" {
+		*w = nil
+		return nil
+	}
+
+	windows := TimeWindows{}
+	for _, part := range strings.Split(s, ",") {
+		window, err := parseTimeWindow(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		windows = append(windows, window)
+	}
+
+	*w = windows
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (w *TimeWindows) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return w.Set(s)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (w TimeWindows) MarshalYAML() (interface{}, error) {
+	return w.String(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (w *TimeWindows) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return w.Set(s)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w TimeWindows) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.String())
+}
+
+func parseTimeWindow(s string) (TimeWindow, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q, expected format is HH:MM-HH:MM", s)
+	}
+
+	start, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	end, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if start == day {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: start must be before 24:00", s)
+	}
+	if start == end {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: start and end must be different", s)
+	}
+
+	return TimeWindow{Start: start, End: end}, nil
+}
+
+// parseTimeOfDay parses a HH:MM time of the day, allowing 24:00 as the end of the day.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	var hours, minutes int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hours, &minutes); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time of the day %q, expected format is HH:MM", s)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time of the day %q", s)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int((d%time.Hour)/time.Minute))
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTimeWindows_Set(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    TimeWindows
+		expectedErr bool
+	}{
+		"empty": {
+			input:    "",
+			expected: nil,
+		},
+		"single window": {
+			input:    "00:00-06:00",
+			expected: TimeWindows{{Start: 0, End: 6 * time.Hour}},
+		},
+		"multiple windows": {
+			input:    "01:30-02:45, 22:00-24:00",
+			expected: TimeWindows{{Start: 90 * time.Minute, End: 165 * time.Minute}, {Start: 22 * time.Hour, End: 24 * time.Hour}},
+		},
+		"window wrapping around midnight": {
+			input:    "22:00-02:00",
+			expected: TimeWindows{{Start: 22 * time.Hour, End: 2 * time.Hour}},
+		},
+		"missing end": {
+			input:       "22:00",
+			expectedErr: true,
+		},
+		"invalid format": {
+			input:       "2:00-3:00",
+			expectedErr: true,
+		},
+		"invalid minutes": {
+			input:       "02:60-03:00",
+			expectedErr: true,
+		},
+		"invalid hours": {
+			input:       "02:00-25:00",
+			expectedErr: true,
+		},
+		"start at the end of the day": {
+			input:       "24:00-02:00",
+			expectedErr: true,
+		},
+		"empty window": {
+			input:       "02:00-02:00",
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var windows TimeWindows
+			err := windows.Set(tc.input)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, windows)
+		})
+	}
+}
+
+func TestTimeWindows_Contains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2022, 6, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	var windows TimeWindows
+	require.NoError(t, windows.Set("01:00-02:00,23:00-00:30"))
+
+	assert.False(t, windows.Contains(at(0, 30)))
+	assert.True(t, windows.Contains(at(1, 0)))
+	assert.True(t, windows.Contains(at(1, 59)))
+	assert.False(t, windows.Contains(at(2, 0)))
+	assert.False(t, windows.Contains(at(22, 59)))
+	assert.True(t, windows.Contains(at(23, 0)))
+	assert.True(t, windows.Contains(at(0, 29)))
+
+	// The time of the day is evaluated in UTC.
+	assert.True(t, windows.Contains(at(1, 30).In(time.FixedZone("UTC+5", 5*3600))))
+
+	// No windows means no restriction.
+	assert.True(t, TimeWindows(nil).Contains(at(12, 0)))
+}
+
+func TestTimeWindows_MarshalUnmarshal(t *testing.T) {
+	var windows TimeWindows
+	require.NoError(t, windows.Set("01:00-02:00,23:00-00:30"))
+	assert.Equal(t, "01:00-02:00,23:00-00:30", windows.String())
+
+	out, err := yaml.Marshal(windows)
+	require.NoError(t, err)
+	var fromYAML TimeWindows
+	require.NoError(t, yaml.Unmarshal(out, &fromYAML))
+	assert.Equal(t, windows, fromYAML)
+
+	out, err = json.Marshal(windows)
+	require.NoError(t, err)
+	assert.Equal(t, `"01:00-02:00,23:00-00:30"`, string(out))
+	var fromJSON TimeWindows
+	require.NoError(t, json.Unmarshal(out, &fromJSON))
+	assert.Equal(t, windows, fromJSON)
+
+	require.Error(t, yaml.Unmarshal([]byte(`"01:00"`), &fromYAML))
+}
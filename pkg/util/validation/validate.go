@@ -58,6 +58,17 @@ var (
 
 	// ReasonTooManyHAClusters is one of the reasons for discarding samples.
 	ReasonTooManyHAClusters = "too_many_ha_clusters"
+
+	// ReasonMaxPushRequestBytes, ReasonMaxSeriesPerRequest and ReasonMaxLabelsBytesPerRequest are the reasons
+	// for discarding requests (and their samples) which exceed the per-tenant request size limits.
+	ReasonMaxPushRequestBytes      = metricReasonFromErrorID(globalerror.MaxPushRequestBytes)
+	ReasonMaxSeriesPerRequest      = metricReasonFromErrorID(globalerror.MaxSeriesPerRequest)
+	ReasonMaxLabelsBytesPerRequest = metricReasonFromErrorID(globalerror.MaxLabelsBytesPerRequest)
+
+	// ReasonIngestionClientDenied and ReasonIngestionClientRateLimited are the reasons for discarding
+	// requests (and their samples) rejected by the per-tenant ingestion client policies.
+	ReasonIngestionClientDenied      = metricReasonFromErrorID(globalerror.IngestionClientDenied)
+	ReasonIngestionClientRateLimited = metricReasonFromErrorID(globalerror.IngestionClientRateLimited)
 )
 
 func metricReasonFromErrorID(id globalerror.ID) string {
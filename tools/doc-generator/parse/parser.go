@@ -23,6 +23,7 @@ import (
 	"github.com/weaveworks/common/logging"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
+	"github.com/grafana/mimir/pkg/ruler/notifier"
 	"github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/util/fieldcategory"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -344,8 +345,16 @@ func getFieldCustomType(t reflect.Type) (string, bool) {
 		return "string", true
 	case reflect.TypeOf(flagext.CIDRSliceCSV{}).String():
 		return "string", true
+	case reflect.TypeOf(validation.TimeWindows{}).String():
+		return "string", true
 	case reflect.TypeOf([]*relabel.Config{}).String():
 		return "relabel_config...", true
+	case reflect.TypeOf([]validation.ClientPolicy{}).String():
+		return "list of client policies", true
+	case reflect.TypeOf([]validation.ResultLabelRule{}).String():
+		return "list of result label rules", true
+	case reflect.TypeOf(notifier.AlertmanagerClientConfig{}).String():
+		return "alertmanager client config", true
 	case reflect.TypeOf(activeseries.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
 	default:
@@ -424,8 +433,16 @@ func getCustomFieldType(t reflect.Type) (string, bool) {
 		return "string", true
 	case reflect.TypeOf(flagext.CIDRSliceCSV{}).String():
 		return "string", true
+	case reflect.TypeOf(validation.TimeWindows{}).String():
+		return "string", true
 	case reflect.TypeOf([]*relabel.Config{}).String():
 		return "relabel_config...", true
+	case reflect.TypeOf([]validation.ClientPolicy{}).String():
+		return "list of client policies", true
+	case reflect.TypeOf([]validation.ResultLabelRule{}).String():
+		return "list of result label rules", true
+	case reflect.TypeOf(notifier.AlertmanagerClientConfig{}).String():
+		return "alertmanager client config", true
 	case reflect.TypeOf(activeseries.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
 	default:
@@ -457,6 +474,12 @@ func ReflectType(typ string) reflect.Type {
 		return reflect.TypeOf(map[string]string{})
 	case "relabel_config...":
 		return reflect.TypeOf([]*relabel.Config{})
+	case "list of client policies":
+		return reflect.TypeOf([]validation.ClientPolicy{})
+	case "list of result label rules":
+		return reflect.TypeOf([]validation.ResultLabelRule{})
+	case "alertmanager client config":
+		return reflect.TypeOf(notifier.AlertmanagerClientConfig{})
 	case "map of string to float64":
 		return reflect.TypeOf(map[string]float64{})
 	case "list of durations":
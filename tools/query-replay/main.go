@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	gokitlog "github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/frontend/queryrecorder"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+type config struct {
+	bucket         bucket.Config
+	userID         string
+	targetURL      string
+	rate           float64
+	concurrency    int
+	timeout        time.Duration
+	minTime        flagext.Time
+	maxTime        flagext.Time
+	shiftTime      bool
+	topRegressions int
+}
+
+// result is the outcome of the replay of a recorded query.
+type result struct {
+	query        queryrecorder.Query
+	responseTime time.Duration
+	statusCode   int
+}
+
+func main() {
+	cfg := config{}
+	cfg.bucket.RegisterFlagsWithPrefixAndDefaultDirectory("storage.", "query-recorder", flag.CommandLine)
+	flag.StringVar(&cfg.userID, "user", "", "User (tenant) whose recorded queries are replayed.")
+	flag.StringVar(&cfg.targetURL, "target-url", "", "URL of the query-frontend of the cluster to replay the queries against, for example http://query-frontend:8080.")
+	flag.Float64Var(&cfg.rate, "rate", 10, "Maximum number of queries replayed per second.")
+	flag.IntVar(&cfg.concurrency, "concurrency", 8, "Maximum number of queries replayed concurrently.")
+	flag.DurationVar(&cfg.timeout, "timeout", 2*time.Minute, "Timeout of each replayed query.")
+	flag.Var(&cfg.minTime, "min-time", "If set, only the queries recorded at or after this time are replayed.")
+	flag.Var(&cfg.maxTime, "max-time", "If set, only the queries recorded at or before this time are replayed.")
+	flag.BoolVar(&cfg.shiftTime, "shift-time", true, "Shift the time range of each query by the time elapsed since it was recorded, so that it queries the same relative time range.")
+	flag.IntVar(&cfg.topRegressions, "top-regressions", 10, "Number of query fingerprints with the highest latency regression to report.")
+	flag.Parse()
+
+	if cfg.userID == "" {
+		log.Fatalln("no user specified")
+	}
+	target, err := url.Parse(cfg.targetURL)
+	if err != nil || target.Host == "" {
+		log.Fatalln("invalid target URL:", cfg.targetURL)
+	}
+	if cfg.rate <= 0 || cfg.concurrency <= 0 {
+		log.Fatalln("rate and concurrency must be greater than 0")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	bkt, err := bucket.NewClient(ctx, cfg.bucket, "query-recorder", gokitlog.NewNopLogger(), nil)
+	if err != nil {
+		log.Fatalln("failed to create bucket:", err)
+	}
+
+	var queries []queryrecorder.Query
+	err = queryrecorder.ReadQueries(ctx, bkt, cfg.userID, time.Time(cfg.minTime), time.Time(cfg.maxTime), func(q queryrecorder.Query) error {
+		queries = append(queries, q)
+		return nil
+	})
+	if err != nil {
+		log.Fatalln("failed to read the recorded queries:", err)
+	}
+	if len(queries) == 0 {
+		log.Fatalln("no recorded queries found")
+	}
+	log.Println("replaying", len(queries), "recorded queries")
+
+	results := replay(ctx, cfg, target, queries)
+	report(os.Stdout, results, cfg.topRegressions)
+}
+
+// replay replays the queries against the target at the configured rate, and returns the results of the
+// queries replayed before the context is canceled.
+func replay(ctx context.Context, cfg config, target *url.URL, queries []queryrecorder.Query) []result {
+	var (
+		client  = &http.Client{Timeout: cfg.timeout}
+		limiter = rate.NewLimiter(rate.Limit(cfg.rate), 1)
+		jobs    = make(chan queryrecorder.Query)
+		mtx     sync.Mutex
+		results = make([]result, 0, len(queries))
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range jobs {
+				res := replayQuery(ctx, client, target, cfg.userID, q, cfg.shiftTime)
+
+				mtx.Lock()
+				results = append(results, res)
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	for _, q := range queries {
+		if err := limiter.Wait(ctx); err != nil {
+			break
+		}
+		jobs <- q
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func replayQuery(ctx context.Context, client *http.Client, target *url.URL, userID string, q queryrecorder.Query, shiftTime bool) result {
+	params := url.Values(q.Params)
+	if shiftTime {
+		params = shiftTimeParams(params, time.Since(time.UnixMilli(q.RecordedAt)))
+	}
+
+	u := *target
+	u.Path = strings.TrimSuffix(u.Path, "/") + q.Path
+
+	var (
+		req *http.Request
+		err error
+	)
+	if q.Method == http.MethodPost {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(params.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		u.RawQuery = params.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	}
+	if err != nil {
+		return result{query: q}
+	}
+	req.Header.Set("X-Scope-OrgID", userID)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{query: q, responseTime: time.Since(start)}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	return result{query: q, responseTime: time.Since(start), statusCode: resp.StatusCode}
+}
+
+// shiftTimeParams returns a copy of the params with the start, end and time params shifted by the offset.
+// The params that are not Unix timestamps nor RFC3339 times are not changed.
+func shiftTimeParams(params url.Values, offset time.Duration) url.Values {
+	shifted := make(url.Values, len(params))
+	for k, v := range params {
+		shifted[k] = v
+	}
+
+	for _, k := range []string{"start", "end", "time"} {
+		v := params.Get(k)
+		if v == "" {
+			continue
+		}
+		t, ok := parseTime(v)
+		if !ok {
+			continue
+		}
+		shifted.Set(k, strconv.FormatFloat(float64(t.Add(offset).UnixMilli())/1000, 'f', -1, 64))
+	}
+	return shifted
+}
+
+func parseTime(s string) (time.Time, bool) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// report writes the latency and error deltas between the recorded and the replayed queries.
+func report(w io.Writer, results []result, topRegressions int) {
+	var (
+		recordedLatencies = make([]float64, 0, len(results))
+		replayedLatencies = make([]float64, 0, len(results))
+		recordedErrors    int
+		replayedErrors    int
+		statusMismatches  int
+		byFingerprint     = map[string]*fingerprintStats{}
+	)
+
+	for _, res := range results {
+		recorded := res.query.ResponseTimeSeconds
+		replayed := res.responseTime.Seconds()
+		recordedLatencies = append(recordedLatencies, recorded)
+		replayedLatencies = append(replayedLatencies, replayed)
+
+		if isError(res.query.StatusCode) {
+			recordedErrors++
+		}
+		if isError(res.statusCode) {
+			replayedErrors++
+		}
+		if res.query.StatusCode != res.statusCode {
+			statusMismatches++
+		}
+
+		s, ok := byFingerprint[res.query.Fingerprint]
+		if !ok {
+			s = &fingerprintStats{fingerprint: res.query.Fingerprint, path: res.query.Path, query: res.query.Params["query"]}
+			byFingerprint[res.query.Fingerprint] = s
+		}
+		s.count++
+		s.recordedSeconds += recorded
+		s.replayedSeconds += replayed
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "Queries replayed:\t%d\n", len(results))
+	fmt.Fprintf(tw, "Errors (5xx or failed):\trecorded %d\treplayed %d\tdelta %+d\n", recordedErrors, replayedErrors, replayedErrors-recordedErrors)
+	fmt.Fprintf(tw, "Status code mismatches:\t%d\n", statusMismatches)
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Latency\tRecorded\tReplayed\tDelta")
+	sort.Float64s(recordedLatencies)
+	sort.Float64s(replayedLatencies)
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		recorded, replayed := quantile(recordedLatencies, q), quantile(replayedLatencies, q)
+		fmt.Fprintf(tw, "p%g\t%.3fs\t%.3fs\t%+.3fs\n", q*100, recorded, replayed, replayed-recorded)
+	}
+	fmt.Fprintln(tw)
+
+	stats := make([]*fingerprintStats, 0, len(byFingerprint))
+	for _, s := range byFingerprint {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].meanDelta() > stats[j].meanDelta() })
+	if len(stats) > topRegressions {
+		stats = stats[:topRegressions]
+	}
+
+	fmt.Fprintln(tw, "Fingerprint\tCount\tMean recorded\tMean replayed\tDelta\tPath\tQuery")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%.3fs\t%.3fs\t%+.3fs\t%s\t%s\n", s.fingerprint, s.count, s.recordedSeconds/float64(s.count), s.replayedSeconds/float64(s.count), s.meanDelta(), s.path, strings.Join(s.query, ","))
+	}
+}
+
+type fingerprintStats struct {
+	fingerprint     string
+	path            string
+	query           []string
+	count           int
+	recordedSeconds float64
+	replayedSeconds float64
+}
+
+func (s *fingerprintStats) meanDelta() float64 {
+	return (s.replayedSeconds - s.recordedSeconds) / float64(s.count)
+}
+
+// isError returns whether the status code is a server error, or 0 for the queries which failed without response.
+func isError(statusCode int) bool {
+	return statusCode == 0 || statusCode/100 == 5
+}
+
+// quantile returns the q quantile of the sorted values, using the nearest-rank method.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
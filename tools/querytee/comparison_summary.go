@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRecentComparisonFailures is the number of the most recent comparison failures kept for each route.
+const maxRecentComparisonFailures = 20
+
+// ComparisonSummary keeps track of the results of the responses comparison, by route, and the most recent
+// failures, to be looked at through an HTTP API while validating a backend against production traffic.
+type ComparisonSummary struct {
+	mtx    sync.Mutex
+	routes map[string]*routeComparisonSummary
+}
+
+type routeComparisonSummary struct {
+	Route          string              `json:"route"`
+	Success        int64               `json:"success"`
+	Fail           int64               `json:"fail"`
+	Skip           int64               `json:"skip"`
+	RecentFailures []comparisonFailure `json:"recent_failures"`
+}
+
+type comparisonFailure struct {
+	Time  time.Time `json:"time"`
+	Query string    `json:"query"`
+	Error string    `json:"error"`
+}
+
+func NewComparisonSummary() *ComparisonSummary {
+	return &ComparisonSummary{routes: map[string]*routeComparisonSummary{}}
+}
+
+// record the result of a comparison. The error is the reason of the failure, if the comparison failed.
+func (s *ComparisonSummary) record(routeName, result, query string, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	route, ok := s.routes[routeName]
+	if !ok {
+		route = &routeComparisonSummary{Route: routeName, RecentFailures: []comparisonFailure{}}
+		s.routes[routeName] = route
+	}
+
+	switch result {
+	case comparisonSuccess:
+		route.Success++
+	case comparisonSkipped:
+		route.Skip++
+	case comparisonFailed:
+		route.Fail++
+
+		if len(route.RecentFailures) == maxRecentComparisonFailures {
+			route.RecentFailures = append(route.RecentFailures[:0], route.RecentFailures[1:]...)
+		}
+		route.RecentFailures = append(route.RecentFailures, comparisonFailure{Time: time.Now(), Query: query, Error: err.Error()})
+	}
+}
+
+// ServeHTTP returns the summary as JSON, sorted by route.
+func (s *ComparisonSummary) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mtx.Lock()
+	routes := make([]routeComparisonSummary, 0, len(s.routes))
+	for _, route := range s.routes {
+		r := *route
+		r.RecentFailures = append([]comparisonFailure(nil), route.RecentFailures...)
+		routes = append(routes, r)
+	}
+	s.mtx.Unlock()
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Route < routes[j].Route
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Routes []routeComparisonSummary `json:"routes"`
+	}{Routes: routes})
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/flagext"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaveworks/common/server"
@@ -36,6 +37,8 @@ type ProxyConfig struct {
 	UseRelativeError               bool
 	PassThroughNonRegisteredRoutes bool
 	SkipRecentSamples              time.Duration
+	CompareSkipFunctions           flagext.StringSliceCSV
+	CompareFunctionTolerances      FunctionTolerances
 }
 
 func (cfg *ProxyConfig) RegisterFlags(f *flag.FlagSet) {
@@ -48,6 +51,8 @@ func (cfg *ProxyConfig) RegisterFlags(f *flag.FlagSet) {
 	f.Float64Var(&cfg.ValueComparisonTolerance, "proxy.value-comparison-tolerance", 0.000001, "The tolerance to apply when comparing floating point values in the responses. 0 to disable tolerance and require exact match (not recommended).")
 	f.BoolVar(&cfg.UseRelativeError, "proxy.compare-use-relative-error", false, "Use relative error tolerance when comparing floating point values.")
 	f.DurationVar(&cfg.SkipRecentSamples, "proxy.compare-skip-recent-samples", 60*time.Second, "The window from now to skip comparing samples. 0 to disable.")
+	f.Var(&cfg.CompareSkipFunctions, "proxy.compare-skip-functions", "Comma-separated list of PromQL functions and aggregations, like topk or bottomk, whose query results are not compared because they're not deterministic.")
+	f.Var(&cfg.CompareFunctionTolerances, "proxy.compare-function-tolerances", "Comma-separated list of function=tolerance pairs, like histogram_quantile=0.001, overriding -proxy.value-comparison-tolerance for the queries using the PromQL functions or aggregations. The highest tolerance applies to the queries using more of them.")
 	f.BoolVar(&cfg.PassThroughNonRegisteredRoutes, "proxy.passthrough-non-registered-routes", false, "Passthrough requests for non-registered routes to preferred backend.")
 }
 
@@ -64,6 +69,7 @@ type Proxy struct {
 	logger     log.Logger
 	registerer prometheus.Registerer
 	metrics    *ProxyMetrics
+	summary    *ComparisonSummary
 	routes     []Route
 
 	// The HTTP and gRPC servers used to run the proxy service.
@@ -87,6 +93,7 @@ func NewProxy(cfg ProxyConfig, logger log.Logger, routes []Route, registerer pro
 		logger:     logger,
 		registerer: registerer,
 		metrics:    NewProxyMetrics(registerer),
+		summary:    NewComparisonSummary(),
 		routes:     routes,
 	}
 
@@ -185,13 +192,18 @@ func (p *Proxy) Start() error {
 		w.WriteHeader(http.StatusOK)
 	}))
 
+	// Summary of the responses comparison.
+	if p.cfg.CompareResponses {
+		router.Path("/api/v1/comparison_summary").Methods("GET").Handler(p.summary)
+	}
+
 	// register routes
 	for _, route := range p.routes {
 		var comparator ResponsesComparator
 		if p.cfg.CompareResponses {
 			comparator = route.ResponseComparator
 		}
-		router.Path(route.Path).Methods(route.Methods...).Handler(NewProxyEndpoint(p.backends, route.RouteName, p.metrics, p.logger, comparator))
+		router.Path(route.Path).Methods(route.Methods...).Handler(NewProxyEndpoint(p.backends, route.RouteName, p.metrics, p.summary, p.logger, comparator))
 	}
 
 	if p.cfg.PassThroughNonRegisteredRoutes {
@@ -7,6 +7,7 @@ package querytee
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -21,7 +22,9 @@ import (
 )
 
 type ResponsesComparator interface {
-	Compare(expected, actual []byte) error
+	// Compare the responses of the request with the given PromQL query, empty if the request has none. It returns
+	// ErrComparisonSkipped if the responses are not compared.
+	Compare(expected, actual []byte, query string) error
 }
 
 type ProxyEndpoint struct {
@@ -29,6 +32,7 @@ type ProxyEndpoint struct {
 	metrics    *ProxyMetrics
 	logger     log.Logger
 	comparator ResponsesComparator
+	summary    *ComparisonSummary
 
 	// Whether for this endpoint there's a preferred backend configured.
 	hasPreferredBackend bool
@@ -37,7 +41,7 @@ type ProxyEndpoint struct {
 	routeName string
 }
 
-func NewProxyEndpoint(backends []*ProxyBackend, routeName string, metrics *ProxyMetrics, logger log.Logger, comparator ResponsesComparator) *ProxyEndpoint {
+func NewProxyEndpoint(backends []*ProxyBackend, routeName string, metrics *ProxyMetrics, summary *ComparisonSummary, logger log.Logger, comparator ResponsesComparator) *ProxyEndpoint {
 	hasPreferredBackend := false
 	for _, backend := range backends {
 		if backend.preferred {
@@ -52,6 +56,7 @@ func NewProxyEndpoint(backends []*ProxyBackend, routeName string, metrics *Proxy
 		metrics:             metrics,
 		logger:              logger,
 		comparator:          comparator,
+		summary:             summary,
 		hasPreferredBackend: hasPreferredBackend,
 	}
 }
@@ -103,6 +108,11 @@ func (p *ProxyEndpoint) executeBackendRequests(r *http.Request, resCh chan *back
 		query = r.Form.Encode()
 	}
 
+	promQuery := r.Form.Get("query")
+	if r.Form == nil {
+		promQuery = r.URL.Query().Get("query")
+	}
+
 	level.Debug(p.logger).Log("msg", "Received request", "path", r.URL.Path, "query", query)
 
 	wg.Add(len(p.backends))
@@ -162,14 +172,20 @@ func (p *ProxyEndpoint) executeBackendRequests(r *http.Request, resCh chan *back
 		}
 
 		result := comparisonSuccess
-		err := p.compareResponses(expectedResponse, actualResponse)
-		if err != nil {
+		err := p.compareResponses(expectedResponse, actualResponse, promQuery)
+		if errors.Is(err, ErrComparisonSkipped) {
+			level.Debug(p.logger).Log("msg", "response comparison skipped", "route-name", p.routeName, "query", r.URL.RawQuery)
+			result = comparisonSkipped
+		} else if err != nil {
 			level.Error(util_log.Logger).Log("msg", "response comparison failed", "route-name", p.routeName,
 				"query", r.URL.RawQuery, "err", err)
 			result = comparisonFailed
 		}
 
 		p.metrics.responsesComparedTotal.WithLabelValues(p.routeName, result).Inc()
+		if p.summary != nil {
+			p.summary.record(p.routeName, result, query, err)
+		}
 	}
 }
 
@@ -208,7 +224,7 @@ func (p *ProxyEndpoint) waitBackendResponseForDownstream(resCh chan *backendResp
 	return responses[0]
 }
 
-func (p *ProxyEndpoint) compareResponses(expectedResponse, actualResponse *backendResponse) error {
+func (p *ProxyEndpoint) compareResponses(expectedResponse, actualResponse *backendResponse, query string) error {
 	// compare response body only if we get a 200
 	if expectedResponse.status != 200 {
 		return fmt.Errorf("skipped comparison of response because we got status code %d from preferred backend's response", expectedResponse.status)
@@ -222,7 +238,7 @@ func (p *ProxyEndpoint) compareResponses(expectedResponse, actualResponse *backe
 		return fmt.Errorf("expected status code %d but got %d", expectedResponse.status, actualResponse.status)
 	}
 
-	return p.comparator.Compare(expectedResponse.body, actualResponse.body)
+	return p.comparator.Compare(expectedResponse.body, actualResponse.body, query)
 }
 
 type backendResponse struct {
@@ -6,6 +6,7 @@
 package querytee
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -100,7 +101,7 @@ func Test_ProxyEndpoint_waitBackendResponseForDownstream(t *testing.T) {
 		testData := testData
 
 		t.Run(testName, func(t *testing.T) {
-			endpoint := NewProxyEndpoint(testData.backends, "test", NewProxyMetrics(nil), log.NewNopLogger(), nil)
+			endpoint := NewProxyEndpoint(testData.backends, "test", NewProxyMetrics(nil), nil, log.NewNopLogger(), nil)
 
 			// Send the responses from a dedicated goroutine.
 			resCh := make(chan *backendResponse)
@@ -144,7 +145,7 @@ func Test_ProxyEndpoint_Requests(t *testing.T) {
 		NewProxyBackend("backend-1", backendURL1, time.Second, true),
 		NewProxyBackend("backend-2", backendURL2, time.Second, false),
 	}
-	endpoint := NewProxyEndpoint(backends, "test", NewProxyMetrics(nil), log.NewNopLogger(), nil)
+	endpoint := NewProxyEndpoint(backends, "test", NewProxyMetrics(nil), nil, log.NewNopLogger(), nil)
 
 	for _, tc := range []struct {
 		name    string
@@ -306,3 +307,61 @@ func Test_backendResponse_statusCode(t *testing.T) {
 		})
 	}
 }
+
+func Test_ProxyEndpoint_ComparisonSummary(t *testing.T) {
+	newBackend := func(value string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"foo":"bar"},"value":[1,"` + value + `"]}]}}`))
+		}))
+	}
+	backend1 := newBackend("1")
+	defer backend1.Close()
+	backend2 := newBackend("2")
+	defer backend2.Close()
+
+	backendURL1, err := url.Parse(backend1.URL)
+	require.NoError(t, err)
+	backendURL2, err := url.Parse(backend2.URL)
+	require.NoError(t, err)
+
+	backends := []*ProxyBackend{
+		NewProxyBackend("backend-1", backendURL1, time.Second, true),
+		NewProxyBackend("backend-2", backendURL2, time.Second, false),
+	}
+	comparator := NewSamplesComparator(SampleComparisonOptions{
+		SkipFunctions:      []string{"topk"},
+		FunctionTolerances: FunctionTolerances{"histogram_quantile": 1},
+	})
+	summary := NewComparisonSummary()
+	endpoint := NewProxyEndpoint(backends, "api_v1_query", NewProxyMetrics(nil), summary, log.NewNopLogger(), comparator)
+
+	for _, query := range []string{`topk(1, foo)`, `histogram_quantile(0.9, foo)`, `foo`} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?"+url.Values{"query": []string{query}}.Encode(), nil)
+		endpoint.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The responses are compared in background, once received from all the backends.
+	require.Eventually(t, func() bool {
+		summary.mtx.Lock()
+		defer summary.mtx.Unlock()
+		route, ok := summary.routes["api_v1_query"]
+		return ok && route.Success+route.Fail+route.Skip == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	summary.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/comparison_summary", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var actual struct {
+		Routes []routeComparisonSummary `json:"routes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	require.Len(t, actual.Routes, 1)
+	assert.Equal(t, "api_v1_query", actual.Routes[0].Route)
+	assert.Equal(t, int64(1), actual.Routes[0].Success)
+	assert.Equal(t, int64(1), actual.Routes[0].Fail)
+	assert.Equal(t, int64(1), actual.Routes[0].Skip)
+	require.Len(t, actual.Routes[0].RecentFailures, 1)
+	assert.Equal(t, "query=foo", actual.Routes[0].RecentFailures[0].Query)
+	assert.Contains(t, actual.Routes[0].RecentFailures[0].Error, "expected value 1")
+}
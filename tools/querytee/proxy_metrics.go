@@ -14,6 +14,7 @@ const (
 	queryTeeMetricsNamespace = "cortex_querytee"
 	comparisonSuccess        = "success"
 	comparisonFailed         = "fail"
+	comparisonSkipped        = "skip"
 )
 
 type ProxyMetrics struct {
@@ -33,7 +33,7 @@ var testRoutes = []Route{
 
 type testComparator struct{}
 
-func (testComparator) Compare(expected, actual []byte) error { return nil }
+func (testComparator) Compare(expected, actual []byte, query string) error { return nil }
 
 func Test_NewProxy(t *testing.T) {
 	cfg := ProxyConfig{}
@@ -9,15 +9,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log/level"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
 
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
+// ErrComparisonSkipped is returned by a ResponsesComparator when the responses are not compared, because the
+// results of the query are not deterministic.
+var ErrComparisonSkipped = errors.New("comparison skipped")
+
 // SamplesComparatorFunc helps with comparing different types of samples coming from /api/v1/query and /api/v1/query_range routes.
 type SamplesComparatorFunc func(expected, actual json.RawMessage, opts SampleComparisonOptions) error
 
@@ -33,6 +41,90 @@ type SampleComparisonOptions struct {
 	Tolerance         float64
 	UseRelativeError  bool
 	SkipRecentSamples time.Duration
+
+	// The PromQL functions and aggregations, like topk, whose results are not compared because they're not
+	// deterministic.
+	SkipFunctions []string
+
+	// The tolerances of the queries using the PromQL functions or aggregations, overriding Tolerance. The
+	// highest tolerance applies to the queries using more of them.
+	FunctionTolerances FunctionTolerances
+}
+
+// forQuery returns the options to compare the results of the query, and false if the results are not compared.
+func (o SampleComparisonOptions) forQuery(query string) (SampleComparisonOptions, bool) {
+	if query == "" || (len(o.SkipFunctions) == 0 && len(o.FunctionTolerances) == 0) {
+		return o, true
+	}
+
+	// The backends fail on the invalid queries, so there's nothing to compare but the errors.
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return o, true
+	}
+
+	var functions []string
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.Call:
+			functions = append(functions, n.Func.Name)
+		case *parser.AggregateExpr:
+			functions = append(functions, n.Op.String())
+		}
+		return nil
+	})
+
+	tolerance, overridden := 0.0, false
+	for _, function := range functions {
+		for _, skipped := range o.SkipFunctions {
+			if function == skipped {
+				return o, false
+			}
+		}
+		if t, ok := o.FunctionTolerances[function]; ok && (!overridden || t > tolerance) {
+			tolerance, overridden = t, true
+		}
+	}
+	if overridden {
+		o.Tolerance = tolerance
+	}
+	return o, true
+}
+
+// FunctionTolerances is the tolerance by PromQL function or aggregation, set from a comma-separated list of
+// function=tolerance pairs.
+type FunctionTolerances map[string]float64
+
+// String implements flag.Value.
+func (t FunctionTolerances) String() string {
+	pairs := make([]string, 0, len(t))
+	for function, tolerance := range t {
+		pairs = append(pairs, function+"="+strconv.FormatFloat(tolerance, 'g', -1, 64))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value.
+func (t *FunctionTolerances) Set(s string) error {
+	tolerances := FunctionTolerances{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		function, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid function tolerance %q: expected function=tolerance", pair)
+		}
+		tolerance, err := strconv.ParseFloat(value, 64)
+		if err != nil || tolerance < 0 {
+			return fmt.Errorf("invalid tolerance %q of the function %s: expected a non-negative number", value, function)
+		}
+		tolerances[strings.TrimSpace(function)] = tolerance
+	}
+	*t = tolerances
+	return nil
 }
 
 func NewSamplesComparator(opts SampleComparisonOptions) *SamplesComparator {
@@ -56,7 +148,12 @@ func (s *SamplesComparator) RegisterSamplesType(samplesType string, comparator S
 	s.sampleTypesComparator[samplesType] = comparator
 }
 
-func (s *SamplesComparator) Compare(expectedResponse, actualResponse []byte) error {
+func (s *SamplesComparator) Compare(expectedResponse, actualResponse []byte, query string) error {
+	opts, compare := s.opts.forQuery(query)
+	if !compare {
+		return ErrComparisonSkipped
+	}
+
 	var expected, actual SamplesResponse
 
 	err := json.Unmarshal(expectedResponse, &expected)
@@ -82,7 +179,7 @@ func (s *SamplesComparator) Compare(expectedResponse, actualResponse []byte) err
 		return fmt.Errorf("resultType %s not registered for comparison", expected.Data.ResultType)
 	}
 
-	return comparator(expected.Data.Result, actual.Data.Result, s.opts)
+	return comparator(expected.Data.Result, actual.Data.Result, opts)
 }
 
 func compareMatrix(expectedRaw, actualRaw json.RawMessage, opts SampleComparisonOptions) error {
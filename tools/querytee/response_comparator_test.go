@@ -413,7 +413,7 @@ func TestCompareSamplesResponse(t *testing.T) {
 				UseRelativeError:  bool(tc.useRelativeError),
 				SkipRecentSamples: tc.skipRecentSamples,
 			})
-			err := samplesComparator.Compare(tc.expected, tc.actual)
+			err := samplesComparator.Compare(tc.expected, tc.actual, "")
 			if tc.err == nil {
 				require.NoError(t, err)
 				return
@@ -423,3 +423,50 @@ func TestCompareSamplesResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestSamplesComparator_ComparisonRules(t *testing.T) {
+	expected := []byte(`{"status":"success","data":{"resultType":"scalar","result":[1,"1"]}}`)
+	actual := []byte(`{"status":"success","data":{"resultType":"scalar","result":[1,"1.05"]}}`)
+
+	comparator := NewSamplesComparator(SampleComparisonOptions{
+		Tolerance:          0.000001,
+		SkipFunctions:      []string{"topk", "timestamp"},
+		FunctionTolerances: FunctionTolerances{"histogram_quantile": 0.1, "sum": 0.01},
+	})
+
+	for query, expectedErr := range map[string]error{
+		// The default tolerance applies.
+		"":                    errors.New("expected value 1 for timestamp 1 but got 1.05"),
+		"vector(1)":           errors.New("expected value 1 for timestamp 1 but got 1.05"),
+		"invalid(":            errors.New("expected value 1 for timestamp 1 but got 1.05"),
+		"sum(foo)":            errors.New("expected value 1 for timestamp 1 but got 1.05"),
+		"topk(1, foo)":        ErrComparisonSkipped,
+		"sum(timestamp(foo))": ErrComparisonSkipped,
+		// The highest tolerance of the functions applies.
+		"histogram_quantile(0.9, sum(foo))": nil,
+	} {
+		t.Run(query, func(t *testing.T) {
+			err := comparator.Compare(expected, actual, query)
+			if expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Equal(t, expectedErr.Error(), err.Error())
+		})
+	}
+}
+
+func TestFunctionTolerances_Set(t *testing.T) {
+	var tolerances FunctionTolerances
+	require.NoError(t, tolerances.Set("histogram_quantile=0.001, stddev_over_time=1e-9"))
+	require.Equal(t, FunctionTolerances{"histogram_quantile": 0.001, "stddev_over_time": 1e-9}, tolerances)
+	require.Equal(t, "histogram_quantile=0.001,stddev_over_time=1e-09", tolerances.String())
+
+	require.NoError(t, tolerances.Set(""))
+	require.Empty(t, tolerances)
+
+	require.Error(t, tolerances.Set("histogram_quantile"))
+	require.Error(t, tolerances.Set("histogram_quantile=foo"))
+	require.Error(t, tolerances.Set("histogram_quantile=-1"))
+}